@@ -53,7 +53,7 @@ fun ToolCallCard(
     var loadedInput by remember(call.use.toolUseID) { mutableStateOf<JsonElement?>(null) }
     var loadingInput by remember(call.use.toolUseID) { mutableStateOf(false) }
     val scope = rememberCoroutineScope()
-    val detail = remember(call.use.toolUseID) { toolCallDetail(call.use.name, call.use.input) }
+    val detail = remember(call.use.toolUseID) { toolCallDetail(call.use.name, call.use.input, call.use.call) }
     val hasError = call.result?.error != null
 
     Column(modifier = modifier.fillMaxWidth()) {
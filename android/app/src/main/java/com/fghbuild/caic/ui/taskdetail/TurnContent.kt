@@ -30,6 +30,7 @@ import com.fghbuild.caic.ui.theme.markdownTypography
 import com.fghbuild.caic.util.GroupKind
 import com.fghbuild.caic.util.MessageGroup
 import com.fghbuild.caic.util.Turn
+import com.fghbuild.caic.util.epochMillis
 import com.fghbuild.caic.util.formatTokens
 import com.fghbuild.caic.util.imageDataToBitmap
 import kotlinx.serialization.json.JsonElement
@@ -166,14 +167,10 @@ fun MessageGroupContent(
                     val rl = event.rateLimit
                     if (rl != null) {
                         val usingOverage = rl.isUsingOverage == true
-                        val resetsEpoch = if (usingOverage && (rl.overageResetsAt ?: 0.0) > 0) {
-                            rl.overageResetsAt ?: 0.0
-                        } else {
-                            rl.resetsAt
-                        }
-                        val resetsLabel = if (resetsEpoch > 0) {
+                        val resetsMs = epochMillis(if (usingOverage && rl.overageResetsAt != null) rl.overageResetsAt else rl.resetsAt)
+                        val resetsLabel = if (resetsMs > 0) {
                             val fmt = java.text.SimpleDateFormat("HH:mm", java.util.Locale.getDefault())
-                            " · resets ${fmt.format(java.util.Date((resetsEpoch * 1000).toLong()))}"
+                            " · resets ${fmt.format(java.util.Date(resetsMs))}"
                         } else ""
                         val isRejected = rl.status == "rejected"
                         val text = if (isRejected && usingOverage) {
@@ -57,6 +57,7 @@ import com.fghbuild.caic.ui.theme.appColors
 import com.fghbuild.caic.ui.theme.isCacheStale
 import com.fghbuild.caic.ui.theme.staleStateColor
 import com.fghbuild.caic.ui.theme.stateColor
+import com.fghbuild.caic.util.epochMillis
 import com.fghbuild.caic.util.formatCost
 import com.fghbuild.caic.util.formatElapsed
 import com.fghbuild.caic.util.formatTokens
@@ -377,7 +378,7 @@ private fun TimingBadges(task: Task, autoFixPR: Boolean) {
         horizontalArrangement = Arrangement.spacedBy(4.dp),
         verticalAlignment = Alignment.CenterVertically,
     ) {
-        val showTimes = (task.state !in TerminalStates && task.stateUpdatedAt > 0) || task.duration > 0
+        val showTimes = (task.state !in TerminalStates && task.stateUpdatedAt.isNotEmpty()) || task.duration > 0
         if (showTimes) {
             Icon(
                 Icons.Outlined.Timer,
@@ -385,8 +386,8 @@ private fun TimingBadges(task: Task, autoFixPR: Boolean) {
                 modifier = Modifier.size(11.dp),
                 tint = MaterialTheme.colorScheme.onSurfaceVariant,
             )
-            if (task.state !in TerminalStates && task.stateUpdatedAt > 0) {
-                TickingElapsed(stateUpdatedAt = task.stateUpdatedAt)
+            if (task.state !in TerminalStates && task.stateUpdatedAt.isNotEmpty()) {
+                TickingElapsed(stateUpdatedAt = epochMillis(task.stateUpdatedAt))
                 if (task.duration > 0 || task.state == "running") {
                     Text(
                         "/",
@@ -399,8 +400,8 @@ private fun TimingBadges(task: Task, autoFixPR: Boolean) {
                 TickingThinkTime(
                     duration = task.duration,
                     state = task.state,
-                    stateUpdatedAt = task.stateUpdatedAt,
-                    turnStartedAt = task.turnStartedAt ?: 0.0,
+                    stateUpdatedAt = epochMillis(task.stateUpdatedAt),
+                    turnStartedAt = epochMillis(task.turnStartedAt),
                 )
             }
         }
@@ -438,7 +439,7 @@ private fun StatusBadges(task: Task, autoFixPR: Boolean) {
 
 @Composable
 private fun TimingIndicator(task: Task) {
-    val showTimes = (task.state !in TerminalStates && task.stateUpdatedAt > 0) || task.duration > 0
+    val showTimes = (task.state !in TerminalStates && task.stateUpdatedAt.isNotEmpty()) || task.duration > 0
     if (showTimes) {
         Row(
             horizontalArrangement = Arrangement.spacedBy(4.dp),
@@ -450,8 +451,8 @@ private fun TimingIndicator(task: Task) {
                 modifier = Modifier.size(11.dp),
                 tint = MaterialTheme.colorScheme.onSurfaceVariant,
             )
-            if (task.state !in TerminalStates && task.stateUpdatedAt > 0) {
-                TickingElapsed(stateUpdatedAt = task.stateUpdatedAt)
+            if (task.state !in TerminalStates && task.stateUpdatedAt.isNotEmpty()) {
+                TickingElapsed(stateUpdatedAt = epochMillis(task.stateUpdatedAt))
                 if (task.duration > 0 || task.state == "running") {
                     Text(
                         "/",
@@ -464,8 +465,8 @@ private fun TimingIndicator(task: Task) {
                 TickingThinkTime(
                     duration = task.duration,
                     state = task.state,
-                    stateUpdatedAt = task.stateUpdatedAt,
-                    turnStartedAt = task.turnStartedAt ?: 0.0,
+                    stateUpdatedAt = epochMillis(task.stateUpdatedAt),
+                    turnStartedAt = epochMillis(task.turnStartedAt),
                 )
             }
         }
@@ -473,7 +474,7 @@ private fun TimingIndicator(task: Task) {
 }
 
 @Composable
-private fun TickingElapsed(stateUpdatedAt: Double) {
+private fun TickingElapsed(stateUpdatedAt: Long) {
     var now by remember { mutableLongStateOf(System.currentTimeMillis()) }
     LaunchedEffect(Unit) {
         while (true) {
@@ -481,7 +482,7 @@ private fun TickingElapsed(stateUpdatedAt: Double) {
             now = System.currentTimeMillis()
         }
     }
-    val elapsedSec = (now - (stateUpdatedAt * 1000).toLong()).coerceAtLeast(0) / 1000.0
+    val elapsedSec = (now - stateUpdatedAt).coerceAtLeast(0) / 1000.0
     Text(
         text = formatElapsed(elapsedSec),
         style = MaterialTheme.typography.bodySmall,
@@ -490,7 +491,7 @@ private fun TickingElapsed(stateUpdatedAt: Double) {
 }
 
 @Composable
-private fun TickingThinkTime(duration: Double, state: String, stateUpdatedAt: Double, turnStartedAt: Double) {
+private fun TickingThinkTime(duration: Double, state: String, stateUpdatedAt: Long, turnStartedAt: Long) {
     var now by remember { mutableLongStateOf(System.currentTimeMillis()) }
     LaunchedEffect(state) {
         if (state == "running") {
@@ -502,7 +503,7 @@ private fun TickingThinkTime(duration: Double, state: String, stateUpdatedAt: Do
     }
     val totalSec = if (state == "running") {
         val turnStart = if (turnStartedAt > 0) turnStartedAt else stateUpdatedAt
-        duration + (now - (turnStart * 1000).toLong()).coerceAtLeast(0) / 1000.0
+        duration + (now - turnStart).coerceAtLeast(0) / 1000.0
     } else {
         duration
     }
@@ -22,6 +22,7 @@ import com.fghbuild.caic.data.TaskNotifier
 import com.fghbuild.caic.data.TaskRepository
 import com.fghbuild.caic.ui.common.RepoEntry
 import com.fghbuild.caic.ui.theme.terminalStates
+import com.fghbuild.caic.util.epochMillis
 import dagger.hilt.android.lifecycle.HiltViewModel
 import kotlinx.coroutines.flow.MutableStateFlow
 import kotlinx.coroutines.flow.SharingStarted
@@ -42,7 +43,7 @@ private val taskIdDesc = compareByDescending<Task> { it.id?.length ?: 0 }
     .thenByDescending { it.id ?: "" }
 
 // Compare by last state change time descending (most recently active first).
-private val taskStateUpdatedDesc = compareByDescending<Task> { it.stateUpdatedAt }
+private val taskStateUpdatedDesc = compareByDescending<Task> { epochMillis(it.stateUpdatedAt) }
 
 data class TaskGroup(
     val repo: String,
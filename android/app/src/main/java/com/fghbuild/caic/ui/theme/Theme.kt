@@ -10,6 +10,7 @@ import androidx.compose.runtime.CompositionLocalProvider
 import androidx.compose.runtime.ReadOnlyComposable
 import androidx.compose.runtime.staticCompositionLocalOf
 import androidx.compose.ui.graphics.Color
+import com.fghbuild.caic.util.epochMillis
 import com.mikepenz.markdown.m3.markdownTypography
 import com.mikepenz.markdown.model.MarkdownTypography
 
@@ -38,12 +39,12 @@ fun staleStateColor(state: String): Color {
     )
 }
 
-private const val STALE_THRESHOLD_SEC = 3600.0
+private const val STALE_THRESHOLD_MS = 3_600_000L
 
 /** True when the last state change is older than 1 hour. */
-fun isCacheStale(state: String, stateUpdatedAt: Double): Boolean =
+fun isCacheStale(state: String, stateUpdatedAt: String): Boolean =
     state !in terminalStates && state != "stopped" && state != "stopping" && state != "purging" && state != "running" &&
-        stateUpdatedAt > 0 && System.currentTimeMillis() / 1000.0 - stateUpdatedAt > STALE_THRESHOLD_SEC
+        epochMillis(stateUpdatedAt).let { it > 0 && System.currentTimeMillis() - it > STALE_THRESHOLD_MS }
 
 val activeStates = setOf(
     "running", "branching", "provisioning", "starting",
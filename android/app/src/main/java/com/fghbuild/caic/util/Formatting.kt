@@ -1,10 +1,12 @@
 // Display formatting utilities for tasks: tokens, cost, elapsed time, and tool detail.
 package com.fghbuild.caic.util
 
+import com.caic.sdk.v1.ToolCall as ApiToolCall
 import kotlinx.serialization.json.JsonElement
 import kotlinx.serialization.json.JsonObject
 import kotlinx.serialization.json.JsonPrimitive
 import kotlinx.serialization.json.jsonPrimitive
+import java.time.OffsetDateTime
 import java.util.Locale
 
 fun formatTokens(n: Int): String = when {
@@ -32,18 +34,24 @@ fun formatDuration(seconds: Double): String = when {
     else -> "${String.format(Locale.US, "%.1f", seconds)}s"
 }
 
+/** Parses an RFC 3339 API timestamp into Unix epoch milliseconds; 0 when unset or invalid. */
+fun epochMillis(ts: String?): Long =
+    if (ts.isNullOrEmpty()) 0L else runCatching { OffsetDateTime.parse(ts).toInstant().toEpochMilli() }.getOrDefault(0L)
+
 private const val MAX_BASH_DETAIL = 60
 private const val BASH_TRUNCATE_AT = 57
 
-/** Extracts a brief detail string for a tool call, matching the web frontend. */
-fun toolCallDetail(name: String, input: JsonElement): String? {
+/**
+ * Extracts a brief detail string for a tool call, matching the web frontend.
+ * The normalized call is used when present; name and input are the fallback
+ * for events recorded before the server normalized tool calls.
+ */
+fun toolCallDetail(name: String, input: JsonElement, call: ApiToolCall? = null): String? {
+    if (call != null) return toolCallDetail(call)
     val obj = input as? JsonObject ?: return null
     return when (name.lowercase()) {
         "read", "write", "edit", "notebookedit" -> obj.stringField("file_path")?.substringAfterLast('/')
-        "bash" -> {
-            val cmd = obj.stringField("command")?.trimStart() ?: return null
-            if (cmd.length > MAX_BASH_DETAIL) cmd.take(BASH_TRUNCATE_AT) + "..." else cmd
-        }
+        "bash" -> obj.stringField("command")?.let(::shortCommand)
         "grep", "glob" -> obj.stringField("pattern")
         "task" -> obj.stringField("description")
         "webfetch" -> obj.stringField("url")
@@ -52,6 +60,25 @@ fun toolCallDetail(name: String, input: JsonElement): String? {
     }
 }
 
+/** Extracts a brief detail string for a normalized tool call of any harness. */
+fun toolCallDetail(call: ApiToolCall): String? = when (call.kind) {
+    "read", "write", "edit" -> call.paths?.takeIf { it.isNotEmpty() }?.let { paths ->
+        val name = paths.first().substringAfterLast('/')
+        if (paths.size > 1) "$name +${paths.size - 1}" else name
+    }
+    "shell" -> call.command?.let(::shortCommand)
+    "search" -> call.pattern
+    "fetch" -> call.url
+    "webSearch" -> call.query
+    "agent" -> call.description
+    else -> null
+}
+
+private fun shortCommand(command: String): String {
+    val cmd = command.trimStart()
+    return if (cmd.length > MAX_BASH_DETAIL) cmd.take(BASH_TRUNCATE_AT) + "..." else cmd
+}
+
 private fun JsonObject.stringField(key: String): String? {
     val el = get(key) ?: return null
     return if (el is JsonPrimitive && el.isString) el.jsonPrimitive.content else null
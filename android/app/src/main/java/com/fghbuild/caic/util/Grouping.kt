@@ -461,8 +461,8 @@ fun groupTurns(groups: List<MessageGroup>): List<Turn> {
             else -> {}
         }
         for (ev in g.events) {
-            if (!hasTs) { firstTs = ev.ts; hasTs = true }
-            lastTs = ev.ts
+            if (!hasTs) { firstTs = epochMillis(ev.ts); hasTs = true }
+            lastTs = epochMillis(ev.ts)
             if (ev.kind == EventKinds.Result) {
                 hasResultEvent = true
                 resultPayload = ev.result
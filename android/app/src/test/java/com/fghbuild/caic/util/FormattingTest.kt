@@ -1,6 +1,7 @@
 // Unit tests for formatting utilities.
 package com.fghbuild.caic.util
 
+import com.caic.sdk.v1.ToolCall as ApiToolCall
 import kotlinx.serialization.json.JsonObject
 import kotlinx.serialization.json.JsonPrimitive
 import org.junit.Assert.assertEquals
@@ -48,6 +49,17 @@ class FormattingTest {
             assertEquals("kotlin coroutines", toolCallDetail("WebSearch", input))
         }
 
+        t.run("normalized call takes precedence") {
+            val input = JsonObject(mapOf("cmd" to JsonPrimitive("ignored")))
+            val call = ApiToolCall(kind = "shell", command = "  go test ./...")
+            assertEquals("go test ./...", toolCallDetail("exec_command", input, call))
+        }
+
+        t.run("normalized edit counts extra paths") {
+            val call = ApiToolCall(kind = "edit", paths = listOf("/src/a.go", "/src/b.go"))
+            assertEquals("a.go +1", toolCallDetail(call))
+        }
+
         t.run("unknown tool returns null") {
             val input = JsonObject(mapOf("x" to JsonPrimitive("y")))
             assertNull(toolCallDetail("Unknown", input))
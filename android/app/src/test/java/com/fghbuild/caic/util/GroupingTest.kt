@@ -26,28 +26,28 @@ import org.junit.Assert.assertTrue
 import org.junit.Test
 
 class GroupingTest {
-    private fun textDeltaEvent(text: String, ts: Long = 0) = EventMessage(
+    private fun textDeltaEvent(text: String, ts: String = "2026-01-01T00:00:00Z") = EventMessage(
         kind = EventKinds.TextDelta, ts = ts,
         textDelta = EventTextDelta(text = text),
     )
 
-    private fun textEvent(text: String, ts: Long = 0) = EventMessage(
+    private fun textEvent(text: String, ts: String = "2026-01-01T00:00:00Z") = EventMessage(
         kind = EventKinds.Text, ts = ts,
         text = EventText(text = text),
     )
 
-    private fun toolUseEvent(id: String, name: String, ts: Long = 0) = EventMessage(
+    private fun toolUseEvent(id: String, name: String, ts: String = "2026-01-01T00:00:00Z") = EventMessage(
         kind = EventKinds.ToolUse, ts = ts,
         toolUse = EventToolUse(toolUseID = id, name = name, input = JsonObject(emptyMap())),
     )
 
-    private fun toolResultEvent(id: String, duration: Double = 0.1, ts: Long = 0) = EventMessage(
+    private fun toolResultEvent(id: String, duration: Double = 0.1, ts: String = "2026-01-01T00:00:00Z") = EventMessage(
         kind = EventKinds.ToolResult, ts = ts,
         toolResult = EventToolResult(toolUseID = id, duration = duration),
     )
 
     @Suppress("LongMethod")
-    private fun resultEvent(ts: Long = 0) = EventMessage(
+    private fun resultEvent(ts: String = "2026-01-01T00:00:00Z") = EventMessage(
         kind = EventKinds.Result, ts = ts,
         result = EventResult(
             subtype = "success", isError = false, result = "done",
@@ -59,7 +59,7 @@ class GroupingTest {
         ),
     )
 
-    private fun askEvent(id: String, question: String, ts: Long = 0) = EventMessage(
+    private fun askEvent(id: String, question: String, ts: String = "2026-01-01T00:00:00Z") = EventMessage(
         kind = EventKinds.Ask, ts = ts,
         ask = EventAsk(
             toolUseID = id,
@@ -67,7 +67,7 @@ class GroupingTest {
         ),
     )
 
-    private fun userInputEvent(text: String, ts: Long = 0) = EventMessage(
+    private fun userInputEvent(text: String, ts: String = "2026-01-01T00:00:00Z") = EventMessage(
         kind = EventKinds.UserInput, ts = ts,
         userInput = EventUserInput(text = text),
     )
@@ -154,7 +154,7 @@ class GroupingTest {
             val groups = groupMessages(listOf(
                 toolUseEvent("t1", "Read"),
                 EventMessage(
-                    kind = EventKinds.Usage, ts = 0,
+                    kind = EventKinds.Usage, ts = "2026-01-01T00:00:00Z",
                     usage = EventUsage(
                         inputTokens = 100, outputTokens = 50,
                         cacheCreationInputTokens = 0, cacheReadInputTokens = 0, model = "test",
@@ -184,7 +184,7 @@ class GroupingTest {
             val groups = groupMessages(listOf(
                 toolUseEvent("t1", "Read"),
                 EventMessage(
-                    kind = EventKinds.Usage, ts = 0,
+                    kind = EventKinds.Usage, ts = "2026-01-01T00:00:00Z",
                     usage = EventUsage(
                         inputTokens = 100, outputTokens = 50,
                         cacheCreationInputTokens = 0, cacheReadInputTokens = 0, model = "test",
@@ -201,10 +201,9 @@ class GroupingTest {
 
         t.run("rateLimit warning creates OTHER group") {
             val groups = groupMessages(listOf(EventMessage(
-                kind = EventKinds.RateLimit, ts = 1,
+                kind = EventKinds.RateLimit, ts = "2026-01-01T00:00:01Z",
                 rateLimit = EventRateLimit(
-                    status = "allowed_warning", resetsAt = 0.0,
-                    rateLimitType = "five_hour", utilization = 0.8,
+                    status = "allowed_warning",                     rateLimitType = "five_hour", utilization = 0.8,
                 ),
             )))
             assertEquals(1, groups.size)
@@ -213,10 +212,9 @@ class GroupingTest {
 
         t.run("rateLimit allowed is filtered out") {
             val groups = groupMessages(listOf(EventMessage(
-                kind = EventKinds.RateLimit, ts = 1,
+                kind = EventKinds.RateLimit, ts = "2026-01-01T00:00:01Z",
                 rateLimit = EventRateLimit(
-                    status = "allowed", resetsAt = 0.0,
-                    rateLimitType = "five_hour", utilization = 0.3,
+                    status = "allowed",                     rateLimitType = "five_hour", utilization = 0.3,
                 ),
             )))
             assertEquals(0, groups.size)
@@ -224,9 +222,9 @@ class GroupingTest {
 
         t.run("rateLimit rejected creates OTHER group") {
             val groups = groupMessages(listOf(EventMessage(
-                kind = EventKinds.RateLimit, ts = 1,
+                kind = EventKinds.RateLimit, ts = "2026-01-01T00:00:01Z",
                 rateLimit = EventRateLimit(
-                    status = "rejected", resetsAt = 1711000000.0,
+                    status = "rejected", resetsAt = "2024-03-21T05:46:40Z",
                     rateLimitType = "seven_day", utilization = 1.0,
                 ),
             )))
@@ -264,7 +262,7 @@ class GroupingTest {
         t.run("durationMs uses result.duration directly (per-invocation, not cumulative)") {
             // ResultMessage.DurationMs is per-invocation wall-clock time for that turn.
             fun makeResult(duration: Double) = EventMessage(
-                kind = EventKinds.Result, ts = 0,
+                kind = EventKinds.Result, ts = "2026-01-01T00:00:00Z",
                 result = EventResult(
                     subtype = "success", isError = false, result = "done",
                     totalCostUSD = 0.01, duration = duration, durationAPI = duration * 0.9,
@@ -304,7 +302,7 @@ class GroupingTest {
             val groups = groupMessages(listOf(
                 toolUseEvent("t1", "Read"),
                 EventMessage(
-                    kind = EventKinds.Usage, ts = 0,
+                    kind = EventKinds.Usage, ts = "2026-01-01T00:00:00Z",
                     usage = EventUsage(
                         inputTokens = 100, outputTokens = 50,
                         cacheCreationInputTokens = 0, cacheReadInputTokens = 0, model = "test",
@@ -313,7 +311,7 @@ class GroupingTest {
                 textDeltaEvent("commentary"),
                 toolUseEvent("t2", "Bash"),
                 EventMessage(
-                    kind = EventKinds.Usage, ts = 0,
+                    kind = EventKinds.Usage, ts = "2026-01-01T00:00:00Z",
                     usage = EventUsage(
                         inputTokens = 200, outputTokens = 100,
                         cacheCreationInputTokens = 0, cacheReadInputTokens = 0, model = "test",
@@ -332,7 +330,7 @@ class GroupingTest {
             // In practice, an ask is always followed by a usage event from the
             // next assistant turn. The ask + usage together form a hard boundary.
             val usage = EventMessage(
-                kind = EventKinds.Usage, ts = 0,
+                kind = EventKinds.Usage, ts = "2026-01-01T00:00:00Z",
                 usage = EventUsage(
                     inputTokens = 100, outputTokens = 50,
                     cacheCreationInputTokens = 0, cacheReadInputTokens = 0, model = "test",
@@ -355,7 +353,7 @@ class GroupingTest {
         t.run("todo events are skipped and don't split tool groups") {
             val groups = groupMessages(listOf(
                 toolUseEvent("t1", "Read"),
-                EventMessage(kind = EventKinds.Todo, ts = 0),
+                EventMessage(kind = EventKinds.Todo, ts = "2026-01-01T00:00:00Z"),
                 toolUseEvent("t2", "Bash"),
             ))
             assertEquals(1, groups.size)
@@ -367,11 +365,11 @@ class GroupingTest {
             // prevents the merge pass from absorbing thinking into the tool group.
             val groups = groupMessages(listOf(
                 EventMessage(
-                    kind = EventKinds.ThinkingDelta, ts = 0,
+                    kind = EventKinds.ThinkingDelta, ts = "2026-01-01T00:00:00Z",
                     thinkingDelta = EventThinkingDelta(text = "thinking..."),
                 ),
                 EventMessage(
-                    kind = EventKinds.Usage, ts = 0,
+                    kind = EventKinds.Usage, ts = "2026-01-01T00:00:00Z",
                     usage = EventUsage(
                         inputTokens = 100, outputTokens = 50,
                         cacheCreationInputTokens = 0, cacheReadInputTokens = 0, model = "test",
@@ -392,16 +390,16 @@ class GroupingTest {
             val groups = groupMessages(listOf(
                 toolUseEvent("t1", "Read"),
                 EventMessage(
-                    kind = EventKinds.Usage, ts = 0,
+                    kind = EventKinds.Usage, ts = "2026-01-01T00:00:00Z",
                     usage = EventUsage(
                         inputTokens = 100, outputTokens = 50,
                         cacheCreationInputTokens = 0, cacheReadInputTokens = 0, model = "test",
                     ),
                 ),
-                EventMessage(kind = EventKinds.Thinking, ts = 0, thinking = EventThinking("hmm")),
-                EventMessage(kind = EventKinds.SubagentStart, ts = 0),
+                EventMessage(kind = EventKinds.Thinking, ts = "2026-01-01T00:00:00Z", thinking = EventThinking("hmm")),
+                EventMessage(kind = EventKinds.SubagentStart, ts = "2026-01-01T00:00:00Z"),
                 toolUseEvent("t2", "Bash"),
-                EventMessage(kind = EventKinds.SubagentEnd, ts = 0),
+                EventMessage(kind = EventKinds.SubagentEnd, ts = "2026-01-01T00:00:00Z"),
             ))
             // Thinking is absorbed into the merged action group; no standalone thinking group.
             val toolGroup = groups.first { it.kind == GroupKind.ACTION }
@@ -417,14 +415,14 @@ class GroupingTest {
             val groups = groupMessages(listOf(
                 toolUseEvent("t1", "Read"),
                 EventMessage(
-                    kind = EventKinds.Usage, ts = 0,
+                    kind = EventKinds.Usage, ts = "2026-01-01T00:00:00Z",
                     usage = EventUsage(
                         inputTokens = 100, outputTokens = 50,
                         cacheCreationInputTokens = 0, cacheReadInputTokens = 0, model = "test",
                     ),
                 ),
                 EventMessage(
-                    kind = EventKinds.ThinkingDelta, ts = 0,
+                    kind = EventKinds.ThinkingDelta, ts = "2026-01-01T00:00:00Z",
                     thinkingDelta = EventThinkingDelta(text = "analyzing..."),
                 ),
             ))
@@ -440,7 +438,7 @@ class GroupingTest {
             // ThinkingCard outside the tool group. The merge pass should extract
             // thinking events from the text group into the preceding tool group.
             val usage = EventMessage(
-                kind = EventKinds.Usage, ts = 0,
+                kind = EventKinds.Usage, ts = "2026-01-01T00:00:00Z",
                 usage = EventUsage(
                     inputTokens = 100, outputTokens = 50,
                     cacheCreationInputTokens = 0, cacheReadInputTokens = 0, model = "test",
@@ -450,7 +448,7 @@ class GroupingTest {
                 toolUseEvent("t1", "Read"),
                 usage,
                 EventMessage(
-                    kind = EventKinds.Thinking, ts = 0,
+                    kind = EventKinds.Thinking, ts = "2026-01-01T00:00:00Z",
                     thinking = EventThinking(text = "reflecting"),
                 ),
                 textDeltaEvent("The result is..."),
@@ -467,7 +465,7 @@ class GroupingTest {
             // Thinking block; it should be embedded inside the text group instead.
             val groups = groupMessages(listOf(
                 EventMessage(
-                    kind = EventKinds.ThinkingDelta, ts = 0,
+                    kind = EventKinds.ThinkingDelta, ts = "2026-01-01T00:00:00Z",
                     thinkingDelta = EventThinkingDelta(text = "thinking..."),
                 ),
                 textDeltaEvent("hello"),
@@ -484,11 +482,11 @@ class GroupingTest {
         t.run("widgetDelta events create a widget group") {
             val groups = groupMessages(listOf(
                 EventMessage(
-                    kind = EventKinds.WidgetDelta, ts = 0,
+                    kind = EventKinds.WidgetDelta, ts = "2026-01-01T00:00:00Z",
                     widgetDelta = EventWidgetDelta(toolUseID = "w1", delta = "<h1>"),
                 ),
                 EventMessage(
-                    kind = EventKinds.WidgetDelta, ts = 0,
+                    kind = EventKinds.WidgetDelta, ts = "2026-01-01T00:00:00Z",
                     widgetDelta = EventWidgetDelta(toolUseID = "w1", delta = "Hi</h1>"),
                 ),
             ))
@@ -502,11 +500,11 @@ class GroupingTest {
         t.run("widget event finalises widget group from deltas") {
             val groups = groupMessages(listOf(
                 EventMessage(
-                    kind = EventKinds.WidgetDelta, ts = 0,
+                    kind = EventKinds.WidgetDelta, ts = "2026-01-01T00:00:00Z",
                     widgetDelta = EventWidgetDelta(toolUseID = "w1", delta = "<h1>"),
                 ),
                 EventMessage(
-                    kind = EventKinds.Widget, ts = 0,
+                    kind = EventKinds.Widget, ts = "2026-01-01T00:00:00Z",
                     widget = EventWidget(toolUseID = "w1", title = "Chart", html = "<h1>Done</h1>"),
                 ),
             ))
@@ -519,7 +517,7 @@ class GroupingTest {
         t.run("widget event alone creates a widget group (replay)") {
             val groups = groupMessages(listOf(
                 EventMessage(
-                    kind = EventKinds.Widget, ts = 0,
+                    kind = EventKinds.Widget, ts = "2026-01-01T00:00:00Z",
                     widget = EventWidget(toolUseID = "w1", title = "Test", html = "<p>hi</p>"),
                 ),
             ))
@@ -532,7 +530,7 @@ class GroupingTest {
         t.run("toolResult for widget marks widgetDone") {
             val groups = groupMessages(listOf(
                 EventMessage(
-                    kind = EventKinds.WidgetDelta, ts = 0,
+                    kind = EventKinds.WidgetDelta, ts = "2026-01-01T00:00:00Z",
                     widgetDelta = EventWidgetDelta(toolUseID = "w1", delta = "<p>x</p>"),
                 ),
                 toolResultEvent("w1"),
@@ -547,21 +545,21 @@ class GroupingTest {
     fun testNextGrouped() {
         t.run("currentSessionCompletedTurns reference is stable across incremental live-turn updates") {
             // One completed turn then a live turn message arrives.
-            val turn1Msgs = listOf(textDeltaEvent("first"), resultEvent(ts = 1))
+            val turn1Msgs = listOf(textDeltaEvent("first"), resultEvent(ts = "2026-01-01T00:00:01Z"))
             val state1 = nextGrouped(IncrementalGrouped(), turn1Msgs)
             assertEquals(1, state1.currentSessionCompletedTurns.size)
             assertEquals(null, state1.currentTurn)
 
             // Add a live message — currentSessionCompletedTurns must be the same list reference.
-            val state2 = nextGrouped(state1, turn1Msgs + textDeltaEvent("live", ts = 2))
+            val state2 = nextGrouped(state1, turn1Msgs + textDeltaEvent("live", ts = "2026-01-01T00:00:02Z"))
             assertSame(state1.currentSessionCompletedTurns, state2.currentSessionCompletedTurns)
             assertEquals(1, state2.currentSessionCompletedTurns.size)
         }
 
         t.run("currentSessionCompletedTurns grows on result event") {
-            val turn1 = listOf(textDeltaEvent("first"), resultEvent(ts = 1))
+            val turn1 = listOf(textDeltaEvent("first"), resultEvent(ts = "2026-01-01T00:00:01Z"))
             val state1 = nextGrouped(IncrementalGrouped(), turn1)
-            val live = turn1 + listOf(textDeltaEvent("second"), resultEvent(ts = 2))
+            val live = turn1 + listOf(textDeltaEvent("second"), resultEvent(ts = "2026-01-01T00:00:02Z"))
             val state2 = nextGrouped(state1, live)
             assertEquals(2, state2.currentSessionCompletedTurns.size)
             assertEquals(null, state2.currentTurn)
@@ -572,13 +570,13 @@ class GroupingTest {
             // the userInput must not be placed in a null-boundary completedSession
             // and rendered as a phantom "Compacted session".
             val msgs = listOf(
-                userInputEvent("initial prompt", ts = 0),
+                userInputEvent("initial prompt", ts = "2026-01-01T00:00:00Z"),
                 EventMessage(
-                    kind = EventKinds.Init, ts = 1L,
+                    kind = EventKinds.Init, ts = "2026-01-01T00:00:01Z",
                     init = EventInit(sessionID = "s1", model = "m", agentVersion = "1", tools = emptyList(), cwd = "/", harness = "claude"),
                 ),
-                textDeltaEvent("response", ts = 2),
-                resultEvent(ts = 3),
+                textDeltaEvent("response", ts = "2026-01-01T00:00:02Z"),
+                resultEvent(ts = "2026-01-01T00:00:03Z"),
             )
             val state = nextGrouped(IncrementalGrouped(), msgs)
             // completedSessions must contain no null-boundary sessions
@@ -589,7 +587,7 @@ class GroupingTest {
         t.run("per-turn duration is correct across incremental updates") {
             // Simulate turn 1 completing, then turn 2 completing incrementally.
             // Both result events have per-invocation DurationMs (1s and 3s).
-            fun makeResult(duration: Double, ts: Long) = EventMessage(
+            fun makeResult(duration: Double, ts: String) = EventMessage(
                 kind = EventKinds.Result, ts = ts,
                 result = EventResult(
                     subtype = "success", isError = false, result = "done",
@@ -601,13 +599,13 @@ class GroupingTest {
                 ),
             )
             // Turn 1 arrives.
-            val turn1Msgs = listOf(textDeltaEvent("first", ts = 1), makeResult(1.0, ts = 2))
+            val turn1Msgs = listOf(textDeltaEvent("first", ts = "2026-01-01T00:00:01Z"), makeResult(1.0, ts = "2026-01-01T00:00:02Z"))
             val state1 = nextGrouped(IncrementalGrouped(), turn1Msgs)
             assertEquals(1, state1.currentSessionCompletedTurns.size)
             assertEquals(1000L, state1.currentSessionCompletedTurns[0].durationMs) // 1.0s → 1000ms
 
             // Turn 2 arrives incrementally.
-            val allMsgs = turn1Msgs + listOf(textDeltaEvent("second", ts = 3), makeResult(3.0, ts = 4))
+            val allMsgs = turn1Msgs + listOf(textDeltaEvent("second", ts = "2026-01-01T00:00:03Z"), makeResult(3.0, ts = "2026-01-01T00:00:04Z"))
             val state2 = nextGrouped(state1, allMsgs)
             assertEquals(2, state2.currentSessionCompletedTurns.size)
             assertEquals(1000L, state2.currentSessionCompletedTurns[0].durationMs) // unchanged
@@ -615,7 +613,7 @@ class GroupingTest {
         }
 
         t.run("reset on shrinking message list clears completed turns") {
-            val turn1 = listOf(textDeltaEvent("first"), resultEvent(ts = 1))
+            val turn1 = listOf(textDeltaEvent("first"), resultEvent(ts = "2026-01-01T00:00:01Z"))
             val state1 = nextGrouped(IncrementalGrouped(), turn1)
             assertEquals(1, state1.currentSessionCompletedTurns.size)
             // Reconnect: message list shrinks to empty.
@@ -629,11 +627,11 @@ class GroupingTest {
             // completed turn because buildLiveItems was only called with the live turn.
             // The fix shows the last completed turn expanded when currentTurn is null.
             val msgs = listOf(
-                textDeltaEvent("agent output", ts = 1),
-                toolUseEvent("t1", "Read", ts = 2),
-                toolResultEvent("t1", ts = 3),
-                textDeltaEvent("done", ts = 4),
-                resultEvent(ts = 5),
+                textDeltaEvent("agent output", ts = "2026-01-01T00:00:01Z"),
+                toolUseEvent("t1", "Read", ts = "2026-01-01T00:00:02Z"),
+                toolResultEvent("t1", ts = "2026-01-01T00:00:03Z"),
+                textDeltaEvent("done", ts = "2026-01-01T00:00:04Z"),
+                resultEvent(ts = "2026-01-01T00:00:05Z"),
             )
             val state = nextGrouped(IncrementalGrouped(), msgs)
             assertEquals(null, state.currentTurn)
@@ -647,13 +645,13 @@ class GroupingTest {
         t.run("currentTurn becomes non-null when user reply arrives after result") {
             // After the agent completes a turn (result event), the user sends a reply
             // (userInput event). A new turn begins: currentTurn must be non-null.
-            val turn1 = listOf(textDeltaEvent("agent output", ts = 1), resultEvent(ts = 2))
+            val turn1 = listOf(textDeltaEvent("agent output", ts = "2026-01-01T00:00:01Z"), resultEvent(ts = "2026-01-01T00:00:02Z"))
             val state1 = nextGrouped(IncrementalGrouped(), turn1)
             assertEquals(null, state1.currentTurn)
 
             val withReply = turn1 + listOf(
-                userInputEvent("user reply", ts = 3),
-                textDeltaEvent("second agent output", ts = 4),
+                userInputEvent("user reply", ts = "2026-01-01T00:00:03Z"),
+                textDeltaEvent("second agent output", ts = "2026-01-01T00:00:04Z"),
             )
             val state2 = nextGrouped(state1, withReply)
             // The first turn is still complete; a new live turn has started.
@@ -668,13 +666,13 @@ class GroupingTest {
             // turn 2, both currentSessionCompletedTurns and currentTurn are populated.
             // The UI must use different key prefixes for these to avoid LazyColumn key
             // collisions (regression: crash "Key g:0 was already used").
-            val turn1 = listOf(textDeltaEvent("agent output", ts = 1), resultEvent(ts = 2))
+            val turn1 = listOf(textDeltaEvent("agent output", ts = "2026-01-01T00:00:01Z"), resultEvent(ts = "2026-01-01T00:00:02Z"))
             val state1 = nextGrouped(IncrementalGrouped(), turn1)
 
             val withReply = turn1 + listOf(
-                userInputEvent("user reply", ts = 3),
-                textDeltaEvent("second agent output", ts = 4),
-                toolUseEvent("t1", "Read", ts = 5),
+                userInputEvent("user reply", ts = "2026-01-01T00:00:03Z"),
+                textDeltaEvent("second agent output", ts = "2026-01-01T00:00:04Z"),
+                toolUseEvent("t1", "Read", ts = "2026-01-01T00:00:05Z"),
             )
             val state2 = nextGrouped(state1, withReply)
             // Both must be non-empty — this is the precondition for the key collision.
@@ -686,11 +684,11 @@ class GroupingTest {
             // Two full turns. After the second result the last completed turn must have
             // the second turn's content (not the first) and currentTurn must be null.
             val allMsgs = listOf(
-                textDeltaEvent("turn 1", ts = 1),
-                resultEvent(ts = 2),
-                userInputEvent("reply", ts = 3),
-                textDeltaEvent("turn 2", ts = 4),
-                resultEvent(ts = 5),
+                textDeltaEvent("turn 1", ts = "2026-01-01T00:00:01Z"),
+                resultEvent(ts = "2026-01-01T00:00:02Z"),
+                userInputEvent("reply", ts = "2026-01-01T00:00:03Z"),
+                textDeltaEvent("turn 2", ts = "2026-01-01T00:00:04Z"),
+                resultEvent(ts = "2026-01-01T00:00:05Z"),
             )
             val state = nextGrouped(IncrementalGrouped(), allMsgs)
             assertEquals(null, state.currentTurn)
@@ -0,0 +1,31 @@
+// "caic doctor" subcommand: prints environment findings and fails when a check fails.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/caic-xyz/caic/backend/internal/server"
+)
+
+// runDoctor prints one line per doctor finding, followed by the suggested fix
+// for failures. It returns an error when at least one check failed.
+func runDoctor(ctx context.Context, w io.Writer, root string, cfg *server.Config) error {
+	failed := 0
+	for _, f := range server.Doctor(ctx, root, cfg) {
+		status := "ok"
+		if !f.OK {
+			status = "FAIL"
+			failed++
+		}
+		_, _ = fmt.Fprintf(w, "%-4s  %-22s %s\n", status, f.Check, f.Detail)
+		if f.Fix != "" {
+			_, _ = fmt.Fprintf(w, "      %-22s fix: %s\n", "", f.Fix)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("doctor: %d checks failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// "caic eval run" subcommand: runs an eval suite on a running server and prints the report.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+// evalPollInterval is how often the report is polled while the run executes.
+const evalPollInterval = 5 * time.Second
+
+// targetsFlag collects repeated -target harness[:model] flags.
+type targetsFlag []v1.EvalTarget
+
+func (f *targetsFlag) String() string {
+	s := make([]string, len(*f))
+	for i, t := range *f {
+		s[i] = string(t.Harness)
+		if t.Model != "" {
+			s[i] += ":" + t.Model
+		}
+	}
+	return strings.Join(s, ",")
+}
+
+func (f *targetsFlag) Set(v string) error {
+	h, m, _ := strings.Cut(v, ":")
+	if h == "" {
+		return errors.New("harness is required")
+	}
+	*f = append(*f, v1.EvalTarget{Harness: v1.Harness(h), Model: m})
+	return nil
+}
+
+// runEval parses the "eval run" arguments, submits the suite to the caic
+// server at baseURL, waits for every case to complete and prints the report.
+// It returns an error when a case did not pass.
+func runEval(ctx context.Context, w io.Writer, baseURL string, args []string) error {
+	if len(args) == 0 || args[0] != "run" {
+		return errors.New("usage: caic eval run [-target harness[:model]]... [-parallel N] <suite.json>")
+	}
+	fs := flag.NewFlagSet("eval run", flag.ContinueOnError)
+	var targets targetsFlag
+	fs.Var(&targets, "target", "harness[:model] to evaluate; repeat for several")
+	parallel := fs.Int("parallel", 1, "number of cases run concurrently")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("eval run: exactly one suite file is required")
+	}
+	if len(targets) == 0 {
+		return errors.New("eval run: at least one -target is required")
+	}
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	req := v1.CreateEvalReq{Targets: targets, Parallel: *parallel}
+	if err := json.Unmarshal(data, &req.Suite); err != nil {
+		return fmt.Errorf("decode %s: %w", fs.Arg(0), err)
+	}
+	var report v1.EvalReport
+	if err := evalCall(ctx, http.MethodPost, baseURL+"/api/v1/evals", &req, &report); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "eval %s: %d runs\n", report.ID, len(report.Results))
+	for report.Status != "done" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(evalPollInterval):
+		}
+		if err := evalCall(ctx, http.MethodGet, baseURL+"/api/v1/evals/"+report.ID.String(), nil, &report); err != nil {
+			return err
+		}
+	}
+	return printEvalReport(w, &report)
+}
+
+// printEvalReport prints one line per result and per target summary. It
+// returns an error when a case did not pass.
+func printEvalReport(w io.Writer, report *v1.EvalReport) error {
+	notPassed := 0
+	for _, r := range report.Results {
+		if r.Status != "passed" {
+			notPassed++
+		}
+		_, _ = fmt.Fprintf(w, "%-6s  %-24s %-24s $%.2f  %6.0fs", r.Status, r.Case, evalTargetName(r.Target), r.CostUSD, r.Duration)
+		if r.Error != "" {
+			_, _ = fmt.Fprintf(w, "  %s", firstLine(r.Error))
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+	_, _ = fmt.Fprintln(w)
+	for _, s := range report.Summary {
+		_, _ = fmt.Fprintf(w, "%-24s passed %d/%d  errored %d  $%.2f  %.0fs\n",
+			evalTargetName(s.Target), s.Passed, s.Passed+s.Failed+s.Errored, s.Errored, s.CostUSD, s.Duration)
+	}
+	if notPassed > 0 {
+		return fmt.Errorf("eval: %d runs did not pass", notPassed)
+	}
+	return nil
+}
+
+func evalTargetName(t v1.EvalTarget) string {
+	if t.Model == "" {
+		return string(t.Harness)
+	}
+	return string(t.Harness) + ":" + t.Model
+}
+
+func firstLine(s string) string {
+	s, _, _ = strings.Cut(s, "\n")
+	return s
+}
+
+// evalCall sends a JSON request to the caic API and decodes the response.
+func evalCall(ctx context.Context, method, url string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s: %s", method, url, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, out)
+}
@@ -144,10 +144,22 @@ func (*fakeContainer) Diff(_ context.Context, _ md.Repo, _ ...string) (string, e
 	return "", nil
 }
 
-func (*fakeContainer) Fetch(_ context.Context, _ []md.Repo) error            { return nil }
-func (*fakeContainer) Stop(_ context.Context, _ string) error                { return nil }
-func (*fakeContainer) Purge(_ context.Context, _ string, _ []md.Repo) error  { return nil }
-func (*fakeContainer) Revive(_ context.Context, _ string, _ []md.Repo) error { return nil }
+func (*fakeContainer) Fetch(_ context.Context, _ []md.Repo) error             { return nil }
+func (*fakeContainer) Exec(_ context.Context, _, _, _ string) (string, error) { return "", nil }
+func (*fakeContainer) SetPaused(_ context.Context, _ string, _ bool) error    { return nil }
+func (*fakeContainer) Stop(_ context.Context, _ string) error                 { return nil }
+func (*fakeContainer) Purge(_ context.Context, _ string, _ []md.Repo) error   { return nil }
+func (*fakeContainer) Revive(_ context.Context, _ string, _ []md.Repo) error  { return nil }
+
+func (*fakeContainer) RestrictNetwork(_ context.Context, _ string, _ []string) error { return nil }
+
+func (*fakeContainer) Console(_ context.Context, _ string, _ int, _ func(task.ConsoleLine)) error {
+	return nil
+}
+
+func (*fakeContainer) PullImage(_ context.Context, _ *task.StartOptions, _ func()) (bool, error) {
+	return false, nil
+}
 
 func (*fakeContainer) Fork(_ context.Context, _ string, _ []md.Repo, _ *task.ForkOptions) (string, []md.Repo, error) {
 	return "fake-fork", nil, fmt.Errorf("fork not supported in fake mode")
@@ -200,10 +212,20 @@ func (*fakeBackend) NewParser() func([]byte) ([]agent.Message, error) {
 	return claudecode.New().NewParser()
 }
 
+func (*fakeBackend) Binary() string { return "fake" }
+
 func (*fakeBackend) Models() []string { return []string{"fake-model"} }
 
 func (*fakeBackend) SupportsImages() bool { return true }
 
 func (*fakeBackend) SupportsCompact() bool { return true }
 
+func (*fakeBackend) SupportsInterrupt() bool { return false }
+
+func (*fakeBackend) SupportsPlanOnly() bool { return true }
+
+func (*fakeBackend) SupportsThinking() bool { return false }
+
+func (*fakeBackend) CommitsChanges() bool { return false }
+
 func (*fakeBackend) ContextWindowLimit(string) int { return 180_000 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"flag"
@@ -22,6 +23,8 @@ import (
 	"github.com/caic-xyz/caic/backend/internal/auth"
 	"github.com/caic-xyz/caic/backend/internal/autoupdate"
 	"github.com/caic-xyz/caic/backend/internal/forge/github"
+	"github.com/caic-xyz/caic/backend/internal/ha"
+	"github.com/caic-xyz/caic/backend/internal/logctx"
 	"github.com/caic-xyz/caic/backend/internal/server"
 	"github.com/fsnotify/fsnotify"
 	"github.com/lmittmann/tint"
@@ -70,11 +73,22 @@ func mainImpl() error {
 
 	flag.Usage = func() {
 		w := flag.CommandLine.Output()
-		_, _ = fmt.Fprintf(w, `Usage: caic [flags]
+		_, _ = fmt.Fprintf(w, `Usage: caic [flags] [doctor | eval run [-target harness[:model]]... [-parallel N] <suite.json> | repos clone <url> [<path>]]
 
 caic manages multiple coding agents in parallel. Each task runs in an isolated
 container with the agent communicating over SSH.
 
+Commands:
+  doctor    Validate the environment (git, container runtime, directories,
+            preferences, harnesses in the base image) and exit
+  eval run  Run an eval suite (JSON: name, cases of name, repo, ref, prompt,
+            verify) on the caic server listening on -http across each
+            -target, then print pass/fail, cost and duration per case
+  repos clone
+            Create a managed mirror of a repo under -root: a bare clone,
+            at the host and path of the URL by default (e.g.
+            github.com/org/repo), that the server fetches periodically
+
 Flags:
 `)
 		flag.PrintDefaults()
@@ -83,14 +97,35 @@ Environment variables (flags take precedence when set):
 
   Core:
     CAIC_HTTP                   HTTP listen address (e.g. :8080)
-    CAIC_ROOT                   Parent directory containing git repos
-    CAIC_LOG_LEVEL              Log level: debug, info, warn, error (default: info)
+    CAIC_ROOT                   Parent directory containing git repos; more source roots can be listed in roots.json in the config directory
+    CAIC_LOG_LEVEL              Log level: debug, info, warn, error (default: info); changeable at runtime via /api/v1/server/log-level
+    CAIC_LOG_FORMAT             Log format: text (default) or json
     CAIC_EXTERNAL_URL           Public base URL; "auto" (default) locks hostname from first FQDN request
+    CAIC_FRONTEND_DIR           Serve the web UI from this directory instead of the embedded build
+    CAIC_TLS_CERT               PEM certificate file; with CAIC_TLS_KEY serves HTTPS and HTTP/2 (default: plaintext HTTP/1.1 and h2c)
+    CAIC_TLS_KEY                PEM private key file of CAIC_TLS_CERT
+    CAIC_HTTP3                  Set to any value to also serve HTTP/3 over QUIC on the same UDP port; requires TLS
+    CAIC_SSE_PING               Interval of keep-alive pings on event streams, below the idle timeout of proxies (default: 20s)
+    CAIC_MIRROR_FETCH           Interval at which the mirrors created by "caic repos clone" are fetched (default: 10m)
+    CAIC_FEED_TOKEN             Enables the Atom feed /feeds/tasks.xml and calendar /feeds/tasks.ics of all tasks, read with ?token=<value>
+
+  High availability (several instances behind a load balancer):
+    CAIC_HA_DIR                 Directory shared by the instances holding the leader lease; the leader executes the tasks, the others forward to it
+    CAIC_HA_URL                 Base URL the other instances reach this one at (e.g. http://10.0.0.2:8080); required with CAIC_HA_DIR
+    CAIC_EVENT_BUS              Event bus of the task changes and transitions (topics caic.tasks.*): redis://[:password@]host:port; unset keeps them in process
 
   LLM features (title generation, commit descriptions):
     CAIC_LLM_PROVIDER           Provider: anthropic, gemini, openaichat, etc.
     CAIC_LLM_MODEL              Model name (e.g. claude-haiku-4-5-20251001)
 
+  Semantic code search (optional):
+    CAIC_EMBEDDING_URL          OpenAI-compatible API base URL (e.g. https://api.openai.com/v1 or http://localhost:11434/v1)
+    CAIC_EMBEDDING_MODEL        Embedding model (e.g. text-embedding-3-small); required with CAIC_EMBEDDING_URL
+    CAIC_EMBEDDING_API_KEY      Bearer token for the embedding API, if required
+
+  Local models (offline):
+    CAIC_LOCAL_URL              OpenAI-compatible API base URL of a local inference server (e.g. http://localhost:11434/v1); enables the "local" harness
+
   GitHub — choose one of PAT or OAuth; GitHub App is independent:
     GITHUB_TOKEN                PAT for PR/CI; single-user (mutually exclusive with GITHUB_OAUTH_CLIENT_ID); auto-detected from gh CLI if unset
     GITHUB_OAUTH_CLIENT_ID      OAuth app client ID; multi-user login (mutually exclusive with GITHUB_TOKEN)
@@ -109,6 +144,17 @@ Environment variables (flags take precedence when set):
     GITLAB_URL                  GitLab instance URL (default: https://gitlab.com)
     GITLAB_WEBHOOK_SECRET       Shared secret; enables POST /webhooks/gitlab
 
+  Issue trackers — tasks mentioning an issue key (e.g. PROJ-123) comment on it:
+    JIRA_URL                    Jira site URL (e.g. https://example.atlassian.net); requires JIRA_EMAIL and JIRA_API_TOKEN
+    JIRA_EMAIL                  Jira account email of JIRA_API_TOKEN
+    JIRA_API_TOKEN              Jira API token
+    LINEAR_API_KEY              Linear personal API key
+
+  Slack app — "/caic run <repo> <prompt>" creates a task reporting in a thread:
+    SLACK_SIGNING_SECRET        Signing secret of the app; enables POST /webhooks/slack/{commands,events}
+    SLACK_BOT_TOKEN             Bot token (xoxb-...) with the chat:write scope
+    SLACK_ALLOWED_USERS         Comma-separated Slack user IDs allowed to use the app (required with the app)
+
   Agents:
     GEMINI_API_KEY              Gemini API key for the Gemini Live voice agent
     TAILSCALE_API_KEY           Tailscale API key for Tailscale ephemeral node
@@ -119,6 +165,7 @@ Environment variables (flags take precedence when set):
 
   Profiling:
     CAIC_PPROF                  Set to any value to expose /debug/pprof/* endpoints
+    CAIC_ADMIN_USERS            Comma-separated usernames allowed to use /api/v1/admin/* (pprof, expvar, runtime, debug bundle) with OAuth
 
   IP geolocation (optional):
     CAIC_IPGEO_DB               Path to a MaxMind MMDB file; relative paths resolve against ~/.config/caic/ (e.g. GeoLite2-Country.mmdb)
@@ -136,13 +183,21 @@ See contrib/caic.env for a template with all variables and documentation.
 	memProfile := flag.String("memprofile", "", "write heap profile to file on shutdown")
 	traceFile := flag.String("trace", "", "write execution trace to file")
 	noLogTime := flag.Bool("no-log-time", false, "omit timestamps from log output")
+	logFormat := flag.String("log-format", envDefault("CAIC_LOG_FORMAT", "text"), "log output format (text, json)")
+	frontendDir := flag.String("frontend-dir", os.Getenv("CAIC_FRONTEND_DIR"), "serve the web UI from this directory (e.g. the output of vite build --watch) instead of the embedded build, picking up rebuilds")
 	versionFlag := flag.Bool("version", false, "print version and exit")
 	flag.Parse()
 	if *versionFlag {
 		fmt.Println(autoupdate.Version)
 		return nil
 	}
-	if args := flag.Args(); len(args) > 0 {
+	args := flag.Args()
+	if len(args) > 0 && args[0] == "eval" {
+		return runEval(ctx, os.Stdout, "http://"+localizeAddr(*addr), args[1:])
+	}
+	doctor := len(args) == 1 && args[0] == "doctor"
+	repos := len(args) > 0 && args[0] == "repos"
+	if len(args) > 0 && !doctor && !repos {
 		return fmt.Errorf("unexpected arguments: %v", args)
 	}
 
@@ -191,14 +246,25 @@ See contrib/caic.env for a template with all variables and documentation.
 	if *root, err = expandTilde(*root); err != nil {
 		return err
 	}
+	if repos {
+		return runRepos(ctx, os.Stdout, *root, args[1:])
+	}
 
-	initLogging(*logLevel, *noLogTime)
+	logRing := logctx.NewRing(5000)
+	logLevelVar, err := initLogging(*logLevel, *logFormat, *noLogTime, logRing)
+	if err != nil {
+		return err
+	}
 
 	cfg := &server.Config{
 		GeminiAPIKey:            os.Getenv("GEMINI_API_KEY"),
 		TailscaleAPIKey:         os.Getenv("TAILSCALE_API_KEY"),
 		LLMProvider:             os.Getenv("CAIC_LLM_PROVIDER"),
 		LLMModel:                os.Getenv("CAIC_LLM_MODEL"),
+		EmbeddingURL:            os.Getenv("CAIC_EMBEDDING_URL"),
+		EmbeddingModel:          os.Getenv("CAIC_EMBEDDING_MODEL"),
+		EmbeddingAPIKey:         os.Getenv("CAIC_EMBEDDING_API_KEY"),
+		LocalURL:                os.Getenv("CAIC_LOCAL_URL"),
 		ConfigDir:               configDir(),
 		CacheDir:                cacheDir(),
 		GitHubToken:             resolveGitHubToken(),
@@ -211,21 +277,46 @@ See contrib/caic.env for a template with all variables and documentation.
 		GitLabURL:               os.Getenv("GITLAB_URL"),
 		GitHubOAuthAllowedUsers: os.Getenv("GITHUB_OAUTH_ALLOWED_USERS"),
 		GitLabOAuthAllowedUsers: os.Getenv("GITLAB_OAUTH_ALLOWED_USERS"),
+		AdminUsers:              os.Getenv("CAIC_ADMIN_USERS"),
 		GitHubWebhookSecret:     []byte(os.Getenv("GITHUB_WEBHOOK_SECRET")),
 		GitHubAppID:             parseInt64(os.Getenv("GITHUB_APP_ID")),
 		GitHubAppPrivateKeyPEM:  []byte(readFileFromEnv("GITHUB_APP_PRIVATE_KEY_PEM")),
 		GitHubAppAllowedOwners:  os.Getenv("GITHUB_APP_ALLOWED_OWNERS"),
 		GitLabWebhookSecret:     []byte(os.Getenv("GITLAB_WEBHOOK_SECRET")),
+		JiraURL:                 os.Getenv("JIRA_URL"),
+		JiraEmail:               os.Getenv("JIRA_EMAIL"),
+		JiraAPIToken:            os.Getenv("JIRA_API_TOKEN"),
+		LinearAPIKey:            os.Getenv("LINEAR_API_KEY"),
+		SlackSigningSecret:      []byte(os.Getenv("SLACK_SIGNING_SECRET")),
+		SlackBotToken:           os.Getenv("SLACK_BOT_TOKEN"),
+		SlackAllowedUsers:       os.Getenv("SLACK_ALLOWED_USERS"),
 		IPGeoDB:                 resolvePathFromEnv("CAIC_IPGEO_DB"),
 		IPGeoAllowlist:          envDefault("CAIC_IPGEO_ALLOWLIST", "local,tailscale,github"),
 		WebRTCPort:              parseInt(os.Getenv("CAIC_WEBRTC_PORT")),
+		FrontendDir:             *frontendDir,
+		TLSCertFile:             resolvePathFromEnv("CAIC_TLS_CERT"),
+		TLSKeyFile:              resolvePathFromEnv("CAIC_TLS_KEY"),
+		HTTP3:                   os.Getenv("CAIC_HTTP3") != "",
+		SSEPing:                 parseDuration(os.Getenv("CAIC_SSE_PING")),
+		FeedToken:               os.Getenv("CAIC_FEED_TOKEN"),
+		MirrorFetch:             parseDuration(os.Getenv("CAIC_MIRROR_FETCH")),
+		StaticCacheMB:           parseInt(os.Getenv("CAIC_STATIC_CACHE_MB")),
 		Pprof:                   *pprofFlag,
+		LogLevel:                logLevelVar,
+		LogRing:                 logRing,
+		OrphanPolicy:            os.Getenv("CAIC_ORPHAN_POLICY"),
+		OrphanGrace:             parseDuration(os.Getenv("CAIC_ORPHAN_GRACE")),
+		MinFreeDiskGB:           parseInt(os.Getenv("CAIC_MIN_FREE_DISK_GB")),
+		EventBusURL:             os.Getenv("CAIC_EVENT_BUS"),
 	}
 
 	slog.Info("gemini", "apikey", auth.MaskedToken(cfg.GeminiAPIKey))       //nolint:gosec // G706
 	slog.Info("tailscale", "apikey", auth.MaskedToken(cfg.TailscaleAPIKey)) //nolint:gosec // G706
 	slog.Info("LLM", "provider", cfg.LLMProvider, "model", cfg.LLMModel)    //nolint:gosec // G706
 
+	if doctor {
+		return runDoctor(ctx, os.Stdout, *root, cfg)
+	}
 	if err := cfg.Validate(); err != nil {
 		return err
 	}
@@ -248,6 +339,9 @@ See contrib/caic.env for a template with all variables and documentation.
 	if v := autoupdate.Version; v != "" && !strings.HasPrefix(v, "devel-") && os.Getenv("CAIC_AUTO_UPDATE") != "0" {
 		go autoupdate.Run(ctx, github.NewClient(cfg.GitHubToken, http.DefaultTransport))
 	}
+	if dir := os.Getenv("CAIC_HA_DIR"); dir != "" {
+		return serveHA(ctx, *addr, *root, dir, cfg)
+	}
 	return serveHTTP(ctx, *addr, *root, cfg)
 }
 
@@ -264,10 +358,13 @@ func roundDur(d time.Duration) time.Duration {
 	return time.Duration((ns + unit/2) / unit * unit)
 }
 
-// initLogging configures slog with tint for colored, concise output.
-// Timestamps are omitted when noLogTime is true, and zero-value
-// attributes are dropped.
-func initLogging(level string, noLogTime bool) {
+// initLogging configures slog with tint for colored, concise output, or
+// with one JSON object per line when format is "json". Timestamps are omitted
+// when noLogTime is true, and zero-value attributes are dropped. Attributes
+// carried by the context, like request and task IDs, are added to each line.
+// Records are also kept as JSON in ring for debug bundles. The returned level
+// can be changed at runtime.
+func initLogging(level, format string, noLogTime bool, ring *logctx.Ring) (*slog.LevelVar, error) {
 	ll := &slog.LevelVar{}
 	switch level {
 	case "debug":
@@ -280,46 +377,58 @@ func initLogging(level string, noLogTime bool) {
 		ll.Set(slog.LevelError)
 	}
 	homeDir, _ := os.UserHomeDir()
-	slog.SetDefault(slog.New(tint.NewHandler(colorable.NewColorable(os.Stderr), &tint.Options{
-		Level:      ll,
-		TimeFormat: "15:04:05.000",
-		NoColor:    !isatty.IsTerminal(os.Stderr.Fd()),
-		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
-			if noLogTime && a.Key == slog.TimeKey && len(groups) == 0 {
-				return slog.Attr{}
-			}
-			val := a.Value.Any()
-			skip := false
-			switch t := val.(type) {
-			case string:
-				skip = t == ""
-				if !skip && homeDir != "" && strings.HasPrefix(t, homeDir) {
-					a = slog.String(a.Key, "~"+t[len(homeDir):])
-				}
-			case bool:
-				skip = !t
-			case uint64:
-				skip = t == 0
-			case int64:
-				skip = t == 0
-			case float64:
-				skip = t == 0
-			case time.Time:
-				skip = t.IsZero()
-			case time.Duration:
-				skip = t == 0
-				if !skip {
-					a = slog.Duration(a.Key, roundDur(t))
-				}
-			case nil:
-				skip = true
+	replaceAttr := func(groups []string, a slog.Attr) slog.Attr {
+		if noLogTime && a.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		val := a.Value.Any()
+		skip := false
+		switch t := val.(type) {
+		case string:
+			skip = t == ""
+			if !skip && homeDir != "" && strings.HasPrefix(t, homeDir) {
+				a = slog.String(a.Key, "~"+t[len(homeDir):])
 			}
-			if skip {
-				return slog.Attr{}
+		case bool:
+			skip = !t
+		case uint64:
+			skip = t == 0
+		case int64:
+			skip = t == 0
+		case float64:
+			skip = t == 0
+		case time.Time:
+			skip = t.IsZero()
+		case time.Duration:
+			skip = t == 0
+			if !skip {
+				a = slog.Duration(a.Key, roundDur(t))
 			}
-			return a
-		},
-	})))
+		case nil:
+			skip = true
+		}
+		if skip {
+			return slog.Attr{}
+		}
+		return a
+	}
+	var h slog.Handler
+	switch format {
+	case "text":
+		h = tint.NewHandler(colorable.NewColorable(os.Stderr), &tint.Options{
+			Level:       ll,
+			TimeFormat:  "15:04:05.000",
+			NoColor:     !isatty.IsTerminal(os.Stderr.Fd()),
+			ReplaceAttr: replaceAttr,
+		})
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: ll, ReplaceAttr: replaceAttr})
+	default:
+		return nil, fmt.Errorf("invalid log format %q: want text or json", format)
+	}
+	h = logctx.Tee(h, slog.NewJSONHandler(ring, &slog.HandlerOptions{Level: ll}))
+	slog.SetDefault(slog.New(logctx.NewHandler(h)))
+	return ll, nil
 }
 
 func serveHTTP(ctx context.Context, addr, rootDir string, cfg *server.Config) error {
@@ -334,6 +443,32 @@ func serveHTTP(ctx context.Context, addr, rootDir string, cfg *server.Config) er
 	return err
 }
 
+// serveHA forwards the requests to the leader of the instances sharing dir
+// until this instance is elected, then serves as the leader. It returns
+// ha.ErrLeaseLost when the leader loses its lease, so that it stops executing
+// tasks another instance takes over; the service manager restarts it as a
+// follower.
+func serveHA(ctx context.Context, addr, rootDir, dir string, cfg *server.Config) error {
+	host, _ := os.Hostname()
+	e, err := ha.NewElector(dir, fmt.Sprintf("%s-%d", host, os.Getpid()), os.Getenv("CAIC_HA_URL"))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() { cancel(e.Run(ctx)) }()
+	if err := e.Follow(ctx, addr, cfg.TLSCertFile, cfg.TLSKeyFile); err != nil {
+		return cmp.Or(context.Cause(ctx), err)
+	}
+	if err := serveHTTP(ctx, addr, rootDir, cfg); err != nil {
+		return err
+	}
+	if err := context.Cause(ctx); errors.Is(err, ha.ErrLeaseLost) {
+		return err
+	}
+	return nil
+}
+
 func main() {
 	if err := mainImpl(); err != nil && !errors.Is(err, context.Canceled) {
 		fmt.Fprintf(os.Stderr, "caic: %v\n", err)
@@ -417,6 +552,18 @@ func parseInt64(s string) int64 {
 	return id
 }
 
+func parseDuration(s string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		slog.Warn("invalid duration env value", "val", s) //nolint:gosec // G706: config value from env, not user input
+		return 0
+	}
+	return d
+}
+
 // resolvePathFromEnv returns the path stored in the given env var, resolving
 // relative paths against the config directory (~/.config/caic/).
 // Returns "" if the env var is unset.
@@ -0,0 +1,47 @@
+// "caic repos clone" subcommand: creates a managed mirror under the source root.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/caic-xyz/caic/backend/internal/server"
+)
+
+// runRepos runs the "repos clone <url> [<path>]" arguments: it creates a bare
+// mirror of url at path under root, the host and path of the URL by default.
+// A running server picks it up on restart; the API clones without restarting.
+func runRepos(ctx context.Context, w io.Writer, root string, args []string) error {
+	if len(args) < 2 || len(args) > 3 || args[0] != "clone" {
+		return errors.New("usage: caic repos clone <url> [<path>]")
+	}
+	if root == "" {
+		return errors.New("root directory is required: set -root flag or CAIC_ROOT env var")
+	}
+	rawURL := args[1]
+	var rel string
+	if len(args) == 3 {
+		rel = filepath.Clean(args[2])
+		if !filepath.IsLocal(rel) {
+			return fmt.Errorf("path must be relative to the root: %s", args[2])
+		}
+	} else {
+		var err error
+		if rel, err = server.MirrorPath(rawURL); err != nil {
+			return err
+		}
+	}
+	abs := filepath.Join(root, rel)
+	if err := os.MkdirAll(filepath.Dir(abs), 0o750); err != nil {
+		return err
+	}
+	if err := server.CloneMirror(ctx, rawURL, abs); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "mirrored %s to %s\n", rawURL, abs)
+	return nil
+}
@@ -40,6 +40,7 @@ import (
 	"time"
 
 	"github.com/caic-xyz/caic/backend/internal/agent/relay"
+	"github.com/caic-xyz/caic/backend/internal/logctx"
 )
 
 // ImageData carries a single base64-encoded image for multi-modal input.
@@ -61,7 +62,18 @@ type Options struct {
 	Model           string // Model alias ("opus", "sonnet", "haiku") or full ID. Empty = default.
 	InitialPrompt   Prompt // Initial prompt; never mutated after creation.
 	ResumeSessionID string
-	RelayOffset     int64 // Byte offset into relay output.jsonl for AttachRelay.
+	RelayOffset     int64     // Byte offset into relay output.jsonl for AttachRelay.
+	PlanOnly        bool      // Read-only plan mode: the agent explores and proposes a plan without modifying files.
+	ReadOnly        bool      // Deny file-writing tools where the harness supports it; the repo is also locked in the container.
+	Thinking        bool      // Request extended thinking where the harness supports it.
+	Endpoint        *Endpoint // API of harnesses configured per user (generic); nil for the others.
+	Instructions    string    // Custom instructions appended to the system prompt; see InstructionsFlag.
+}
+
+// Endpoint is an OpenAI-compatible API.
+type Endpoint struct {
+	BaseURL string // e.g. "https://api.example.com/v1".
+	APIKey  string // Optional bearer token.
 }
 
 // WireFormat defines the wire protocol for a backend's stdin/stdout
@@ -84,6 +96,20 @@ type CompactCommand interface {
 	WriteCompact(w io.Writer, instructions string, logW io.Writer) error
 }
 
+// Interrupter is an optional interface for WireFormat implementations that
+// can cancel the current turn without ending the session. The agent then
+// ends the turn with a ResultMessage and waits for the next prompt.
+type Interrupter interface {
+	WriteInterrupt(w io.Writer, logW io.Writer) error
+}
+
+// InstructionsFlag is an optional interface for WireFormat implementations
+// whose agent takes Options.Instructions on its command line. Sessions of the
+// other harnesses prepend them to their first prompt instead.
+type InstructionsFlag interface {
+	HasInstructionsFlag()
+}
+
 // Session manages a running agent process.
 type Session struct {
 	cmd       *exec.Cmd
@@ -96,6 +122,8 @@ type Session struct {
 	done      chan struct{} // closed when readMessages goroutine exits
 	result    *ResultMessage
 	err       error
+	// instructions are prepended to the next prompt; see PrependInstructions.
+	instructions string
 }
 
 // NewSession creates a Session from an already-started command. Messages read
@@ -158,9 +186,31 @@ func NewSession(cmd *exec.Cmd, stdin io.WriteCloser, stdout io.Reader, msgCh cha
 func (s *Session) Send(p Prompt) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.instructions != "" {
+		p.Text = "<instructions>\n" + s.instructions + "\n</instructions>\n\n" + p.Text
+		s.instructions = ""
+	}
 	return s.wire.WritePrompt(s.stdin, p, s.logW)
 }
 
+// PrependInstructions makes the first prompt of a new session start with
+// opts.Instructions, for harnesses that cannot append them to the system
+// prompt. A resumed session already received them.
+func (s *Session) PrependInstructions(opts *Options) {
+	if opts.ResumeSessionID != "" {
+		return
+	}
+	s.mu.Lock()
+	s.instructions = opts.Instructions
+	s.mu.Unlock()
+}
+
+// ShellQuote quotes s for a POSIX shell, e.g. for an argument of a command
+// run through ssh.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 // SendRaw writes pre-encoded NDJSON bytes to the agent's stdin. It is safe for
 // concurrent use. Use this for control messages that bypass WritePrompt.
 func (s *Session) SendRaw(data []byte) error {
@@ -187,6 +237,18 @@ func (s *Session) SendCompact(instructions string) error {
 	return cc.WriteCompact(s.stdin, instructions, s.logW)
 }
 
+// SendInterrupt asks the agent to cancel its current turn. Returns an error if
+// the backend's wire format does not implement Interrupter.
+func (s *Session) SendInterrupt() error {
+	in, ok := s.wire.(Interrupter)
+	if !ok {
+		return errors.New("interrupt not supported by this backend")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return in.WriteInterrupt(s.stdin, s.logW)
+}
+
 // Close sends the null-byte sentinel to the relay daemon (triggering graceful
 // subprocess shutdown) and then closes stdin. Idempotent.
 //
@@ -274,6 +336,9 @@ const (
 	RelaySockPath   = RelayDir + "/relay.sock"
 	RelayOutputPath = RelayDir + "/output.jsonl"
 	RelayLogPath    = RelayDir + "/relay.log"
+	// RelayConsolePath holds the stderr of the agent process: what it and
+	// its children print outside of the structured message stream.
+	RelayConsolePath = RelayDir + "/console.log"
 )
 
 // DeployRelay uploads the relay script into the container. Idempotent.
@@ -450,13 +515,13 @@ func StartRelay(ctx context.Context, opts *Options, agentArgs []string, msgCh ch
 	if err := DeployRelay(ctx, opts.Container); err != nil {
 		return nil, err
 	}
-	slog.Debug("startup", "phase", "deploy_relay", "ctr", opts.Container, "dur", time.Since(tStart))
+	slog.DebugContext(ctx, "startup", "phase", "deploy_relay", "ctr", opts.Container, "dur", time.Since(tStart))
 
 	sshArgs := make([]string, 0, 7+len(agentArgs))
 	sshArgs = append(sshArgs, opts.Container, "python3", RelayScriptPath, "serve-attach", "--dir", opts.Dir, "--")
 	sshArgs = append(sshArgs, agentArgs...)
 
-	slog.Debug("relay", "msg", "launch", "ctr", opts.Container, "args", agentArgs)
+	slog.DebugContext(ctx, "relay", "msg", "launch", "ctr", opts.Container, "args", agentArgs)
 	cmd := exec.CommandContext(ctx, "ssh", sshArgs...) //nolint:gosec // args are not user-controlled.
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -470,10 +535,13 @@ func StartRelay(ctx context.Context, opts *Options, agentArgs []string, msgCh ch
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("start relay: %w", err)
 	}
-	slog.Info("startup", "phase", "relay_started", "ctr", opts.Container, "dur", time.Since(tStart))
+	slog.InfoContext(ctx, "startup", "phase", "relay_started", "ctr", opts.Container, "dur", time.Since(tStart))
 
-	log := slog.With("ctr", opts.Container)
+	log := logctx.Logger(ctx).With("ctr", opts.Container)
 	s := NewSession(cmd, stdin, stdout, msgCh, logW, wire, log)
+	if _, ok := wire.(InstructionsFlag); !ok {
+		s.PrependInstructions(opts)
+	}
 	if opts.InitialPrompt.Text != "" || len(opts.InitialPrompt.Images) > 0 {
 		if err := s.Send(opts.InitialPrompt); err != nil {
 			s.Close()
@@ -533,7 +601,7 @@ func AttachRelaySession(ctx context.Context, container string, offset int64, msg
 		return nil, fmt.Errorf("attach relay: %w", err)
 	}
 
-	log := slog.With("ctr", container)
+	log := logctx.Logger(ctx).With("ctr", container)
 	return NewSession(cmd, stdin, stdout, msgCh, logW, wire, log), nil
 }
 
@@ -251,6 +251,31 @@ func TestSession(t *testing.T) {
 			}
 		})
 	})
+	t.Run("PrependInstructions", func(t *testing.T) {
+		stdinR, stdinW := io.Pipe()
+		s := &Session{
+			stdin: stdinW,
+			wire:  testWire{},
+			done:  make(chan struct{}),
+		}
+		stdinBuf := make(chan string, 1)
+		go func() {
+			data, _ := io.ReadAll(stdinR)
+			stdinBuf <- string(data)
+		}()
+		s.PrependInstructions(&Options{Instructions: "Be terse."})
+		if err := s.Send(Prompt{Text: "first"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Send(Prompt{Text: "second"}); err != nil {
+			t.Fatal(err)
+		}
+		s.Close()
+		got := <-stdinBuf
+		if !strings.Contains(got, `"content":"\u003cinstructions\u003e\nBe terse.\n\u003c/instructions\u003e\n\nfirst"`) || !strings.Contains(got, `"content":"second"`) {
+			t.Errorf("stdin = %s, want the instructions in the first prompt only", got)
+		}
+	})
 	t.Run("CloseIdempotent", func(t *testing.T) {
 		stdinR, stdinW := io.Pipe()
 		go func() { _, _ = io.Copy(io.Discard, stdinR) }()
@@ -0,0 +1,93 @@
+// Package aider implements agent.Backend for aider.
+//
+// aider has no streaming JSON protocol, so an embedded Python bridge
+// (bridge.py) runs its scripting mode (--message-file) once per prompt and
+// translates its output to Claude Code stream-json, parsed by the claudecode
+// parser. aider commits each of its edits itself.
+package aider
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/agent/claudecode"
+)
+
+const bridgeScriptPath = agent.RelayDir + "/aider_bridge.py"
+
+// Backend implements agent.Backend for aider.
+type Backend struct {
+	agent.Base
+}
+
+var _ agent.Backend = (*Backend)(nil)
+
+// New creates an aider backend. Models are aider's aliases; API keys are read
+// from ~/.aider/.env, mounted from the host.
+func New() *Backend {
+	b := &Backend{}
+	b.Base = agent.Base{
+		HarnessID:     agent.Aider,
+		BinaryName:    "aider",
+		ModelList:     []string{"sonnet", "opus", "haiku", "gpt-4o", "o3-mini", "deepseek", "gemini"},
+		ContextWindow: 200_000,
+		Commits:       true,
+		Wire:          newWire(),
+	}
+	return b
+}
+
+// NewParser implements agent.Backend.
+func (*Backend) NewParser() func([]byte) ([]agent.Message, error) {
+	return claudecode.New().NewParser()
+}
+
+// Start deploys the bridge script and launches it via relay serve-attach.
+func (b *Backend) Start(ctx context.Context, opts *agent.Options, msgCh chan<- agent.Message, logW io.Writer) (*agent.Session, error) {
+	cmd := exec.CommandContext(ctx, "ssh", opts.Container, //nolint:gosec // container is not user-controlled
+		"mkdir -p "+agent.RelayDir+" && cat > "+bridgeScriptPath)
+	cmd.Stdin = bytes.NewReader(BridgeScript)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("deploy aider bridge: %w: %s", err, out)
+	}
+	return agent.StartRelay(ctx, opts, buildArgs(opts), msgCh, logW, newWire())
+}
+
+// buildArgs constructs the command to run the bridge.
+func buildArgs(opts *agent.Options) []string {
+	args := []string{"python3", "-u", bridgeScriptPath}
+	if opts.Model != "" {
+		args = append(args, "--model", opts.Model)
+	}
+	if opts.ResumeSessionID != "" {
+		args = append(args, "--resume", opts.ResumeSessionID)
+	}
+	if opts.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	return args
+}
+
+// wireFormat speaks Claude Code's stream-json protocol, like the bridge,
+// without claiming support for /compact.
+type wireFormat struct {
+	parse func([]byte) ([]agent.Message, error)
+}
+
+func newWire() *wireFormat {
+	return &wireFormat{parse: claudecode.New().NewParser()}
+}
+
+// WritePrompt implements agent.WireFormat.
+func (*wireFormat) WritePrompt(w io.Writer, p agent.Prompt, logW io.Writer) error {
+	return claudecode.Wire.WritePrompt(w, p, logW)
+}
+
+// ParseMessage implements agent.WireFormat.
+func (f *wireFormat) ParseMessage(line []byte) ([]agent.Message, error) {
+	return f.parse(line)
+}
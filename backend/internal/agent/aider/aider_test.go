@@ -0,0 +1,94 @@
+// Tests for the aider backend.
+package aider
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// fakeAider prints the output of an aider run that edited and committed a
+// file.
+const fakeAider = `#!/bin/sh
+cat <<'EOF'
+Aider v0.86.1
+Main model: anthropic/claude-sonnet-4 with diff edit format
+Git repo: .git with 3 files
+Repo-map: using 4096 tokens, auto refresh
+
+I'll add the function.
+
+Tokens: 2.3k sent, 120 received. Cost: $0.01 message, $0.01 session.
+Applied edit to main.go
+Commit 1a2b3c4 feat: Add hello function
+EOF
+`
+
+func TestBridge(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not found")
+	}
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "aider"), []byte(fakeAider), 0o700); err != nil { //nolint:gosec // must be executable
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "bridge.py")
+	if err := os.WriteFile(script, BridgeScript, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.CommandContext(t.Context(), "python3", script, "--model", "sonnet")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "HOME="+dir, "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	cmd.Stdin = strings.NewReader(`{"type":"user","message":{"role":"user","content":"add hello"}}` + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parse := New().NewParser()
+	var got []string
+	var tools []string
+	var result *agent.ResultMessage
+	for l := range bytes.Lines(out) {
+		msgs, err := parse(bytes.TrimSpace(l))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range msgs {
+			got = append(got, strings.TrimPrefix(reflect.TypeOf(m).String(), "*agent."))
+			switch m := m.(type) {
+			case *agent.ToolUseMessage:
+				tools = append(tools, m.Name)
+			case *agent.ResultMessage:
+				result = m
+			}
+		}
+	}
+	want := []string{"InitMessage", "TextDeltaMessage", "TextDeltaMessage", "ToolUseMessage", "ToolResultMessage", "ToolUseMessage", "ToolResultMessage", "TextMessage", "UsageMessage", "ResultMessage"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !reflect.DeepEqual(tools, []string{"Edit", "Commit"}) {
+		t.Errorf("tools = %q", tools)
+	}
+	if result == nil || result.IsError || result.Result != "I'll add the function." || result.TotalCostUSD != 0.01 || result.Usage.InputTokens != 2300 || result.Usage.OutputTokens != 120 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	got := buildArgs(&agent.Options{Model: "sonnet", ResumeSessionID: "s1", ReadOnly: true})
+	want := []string{"python3", "-u", bridgeScriptPath, "--model", "sonnet", "--resume", "s1", "--read-only"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,309 @@
+# Bridge between relay stdin/stdout NDJSON and aider's scripting mode,
+# emitting Claude Code streaming JSON.
+#
+# Reads Claude Code stream-json user messages from stdin (one per line) and
+# runs `aider --message-file` for each of them in the working directory. The
+# conversation is kept in a per-session chat history file restored on every
+# run, so the session survives a container restart via --resume.
+#
+#   Go Backend <-SSH-> relay.py <-stdin/stdout-> bridge.py <-exec-> aider
+#
+# aider's plain output is mapped to messages:
+#   "Applied edit to <path>"  -> Edit tool call
+#   "Commit <sha> <message>"  -> Commit tool call; aider commits its own edits
+#   "Tokens: ... Cost: ..."   -> usage and cost of the result
+#   anything else             -> assistant text
+#
+# aider's own files (history, repo map cache) are kept out of the worktree or
+# excluded via .git/info/exclude so they are never committed.
+#
+# Usage: python3 bridge.py [--model M] [--resume ID] [--read-only]
+#
+# Uses only Python stdlib (no pip dependencies).
+
+import argparse
+import json
+import os
+import re
+import shutil
+import signal
+import subprocess
+import sys
+import tempfile
+import time
+import uuid
+
+SESSIONS_DIR = os.path.expanduser("~/.local/share/caic-aider/sessions")
+
+# API keys, in dotenv format, e.g. ANTHROPIC_API_KEY=... The directory is
+# mounted from the host.
+ENV_FILE = os.path.expanduser("~/.aider/.env")
+
+EDIT_RE = re.compile(r"^Applied edit to (.+)$")
+COMMIT_RE = re.compile(r"^Commit ([0-9a-f]{7,40}) (.+)$")
+TOKENS_RE = re.compile(r"^Tokens: ([0-9.]+[kM]?) sent, (?:.*?, )?([0-9.]+[kM]?) received\.")
+COST_RE = re.compile(r"Cost: \$([0-9.]+) message")
+
+# Startup banner lines, dropped from the transcript.
+BANNER_PREFIXES = (
+    "Aider v",
+    "Main model:",
+    "Model:",
+    "Weak model:",
+    "Editor model:",
+    "Git repo:",
+    "Repo-map:",
+    "Restored previous conversation history.",
+    "Use /help",
+    "https://aider.chat/",
+    "Cur working dir:",
+    "Git working dir:",
+)
+
+
+def emit(obj: dict) -> None:
+    sys.stdout.write(json.dumps(obj, separators=(",", ":")) + "\n")
+    sys.stdout.flush()
+
+
+def log(msg: str) -> None:
+    sys.stderr.write("aider_bridge: " + msg + "\n")
+    sys.stderr.flush()
+
+
+def tokens(s: str) -> int:
+    """Parses aider's abbreviated token counts, e.g. 2.3k."""
+    mult = {"k": 1000, "M": 1000000}.get(s[-1:], 1)
+    return int(float(s.rstrip("kM")) * mult)
+
+
+def exclude_aider_files(cwd: str) -> None:
+    """Keeps aider's files in the worktree out of commits without touching
+    the tracked .gitignore."""
+    try:
+        gitdir = subprocess.run(
+            ["git", "rev-parse", "--git-dir"], cwd=cwd, capture_output=True, text=True, check=True
+        ).stdout.strip()
+    except (OSError, subprocess.CalledProcessError):
+        return
+    path = os.path.join(cwd, gitdir, "info", "exclude")
+    try:
+        with open(path) as f:
+            if ".aider*" in f.read().split("\n"):
+                return
+    except OSError:
+        pass
+    os.makedirs(os.path.dirname(path), exist_ok=True)
+    with open(path, "a") as f:
+        f.write("\n.aider*\n")
+
+
+class Session:
+    def __init__(self, args: argparse.Namespace) -> None:
+        self.model = args.model
+        self.read_only = args.read_only
+        self.cwd = os.getcwd()
+        self.id = args.resume or str(uuid.uuid4())
+        self.dir = os.path.join(SESSIONS_DIR, self.id)
+        os.makedirs(self.dir, exist_ok=True)
+        self.proc = None
+        exclude_aider_files(self.cwd)
+
+    def command(self, message_file: str) -> list:
+        cmd = [
+            "aider",
+            "--message-file", message_file,
+            "--yes-always",
+            "--no-pretty",
+            "--no-fancy-input",
+            "--no-check-update",
+            "--no-show-release-notes",
+            "--analytics-disable",
+            "--no-gitignore",
+            "--chat-history-file", os.path.join(self.dir, "chat.md"),
+            "--input-history-file", os.path.join(self.dir, "input"),
+            "--restore-chat-history",
+        ]
+        if self.model:
+            cmd += ["--model", self.model]
+        if os.path.exists(ENV_FILE):
+            cmd += ["--env-file", ENV_FILE]
+        if self.read_only:
+            cmd += ["--chat-mode", "ask"]
+        return cmd
+
+    def turn(self, prompt: str) -> None:
+        start = time.monotonic()
+        usage = {"input_tokens": 0, "output_tokens": 0}
+        cost = 0.0
+        text = ""
+        error = ""
+        edits = commits = 0
+        if not shutil.which("aider"):
+            error = "aider is not installed in the container; use a base image with aider-chat"
+        else:
+            with tempfile.NamedTemporaryFile("w", suffix=".txt", dir=self.dir, delete=False) as f:
+                f.write(prompt)
+            try:
+                self.proc = subprocess.Popen(
+                    self.command(f.name),
+                    cwd=self.cwd,
+                    stdin=subprocess.DEVNULL,
+                    stdout=subprocess.PIPE,
+                    stderr=subprocess.STDOUT,
+                    text=True,
+                    errors="replace",
+                )
+                for line in self.proc.stdout:
+                    line = line.rstrip("\n")
+                    m = EDIT_RE.match(line)
+                    if m:
+                        edits += 1
+                        self.tool("Edit", {"file_path": os.path.join(self.cwd, m.group(1))}, line)
+                        continue
+                    m = COMMIT_RE.match(line)
+                    if m:
+                        commits += 1
+                        self.tool("Commit", {"sha": m.group(1), "message": m.group(2)}, line)
+                        continue
+                    m = TOKENS_RE.match(line)
+                    if m:
+                        usage["input_tokens"] += tokens(m.group(1))
+                        usage["output_tokens"] += tokens(m.group(2))
+                        c = COST_RE.search(line)
+                        if c:
+                            cost += float(c.group(1))
+                        continue
+                    if line.startswith(BANNER_PREFIXES) or (not text and not line.strip()):
+                        continue
+                    text += line + "\n"
+                    emit(
+                        {
+                            "type": "stream_event",
+                            "event": {
+                                "type": "content_block_delta",
+                                "index": 0,
+                                "delta": {"type": "text_delta", "text": line + "\n"},
+                            },
+                        }
+                    )
+                if self.proc.wait():
+                    error = "aider exited with status %d" % self.proc.returncode
+            except OSError as e:
+                error = "aider: %s" % e
+            finally:
+                self.proc = None
+                os.unlink(f.name)
+        text = text.strip()
+        if text:
+            emit(
+                {
+                    "type": "assistant",
+                    "session_id": self.id,
+                    "uuid": str(uuid.uuid4()),
+                    "message": {
+                        "id": "msg_" + uuid.uuid4().hex[:24],
+                        "role": "assistant",
+                        "model": self.model,
+                        "content": [{"type": "text", "text": text}],
+                        "usage": usage,
+                    },
+                }
+            )
+        summary = text
+        if not summary and (edits or commits):
+            summary = "%d edits, %d commits" % (edits, commits)
+        emit(
+            {
+                "type": "result",
+                "subtype": "error_during_execution" if error else "success",
+                "is_error": bool(error),
+                "result": error or summary,
+                "num_turns": 1,
+                "session_id": self.id,
+                "total_cost_usd": cost,
+                "duration_ms": int((time.monotonic() - start) * 1000),
+                "usage": usage,
+            }
+        )
+
+    def tool(self, name: str, args: dict, out: str) -> None:
+        """Emits a tool call aider already made along with its result."""
+        tool_id = "toolu_" + uuid.uuid4().hex[:24]
+        emit(
+            {
+                "type": "assistant",
+                "session_id": self.id,
+                "uuid": str(uuid.uuid4()),
+                "message": {
+                    "id": "msg_" + uuid.uuid4().hex[:24],
+                    "role": "assistant",
+                    "model": self.model,
+                    "content": [{"type": "tool_use", "id": tool_id, "name": name, "input": args}],
+                },
+            }
+        )
+        emit(
+            {
+                "type": "user",
+                "session_id": self.id,
+                "message": {
+                    "role": "user",
+                    "content": [
+                        {
+                            "type": "tool_result",
+                            "tool_use_id": tool_id,
+                            "content": [{"type": "text", "text": out}],
+                            "is_error": False,
+                        }
+                    ],
+                },
+            }
+        )
+
+    def stop(self, *_) -> None:
+        if self.proc:
+            self.proc.terminate()
+        sys.exit(0)
+
+
+def prompt_text(line: str) -> str:
+    """Extracts the text of a Claude Code stream-json user message."""
+    try:
+        msg = json.loads(line)
+    except ValueError:
+        return line
+    content = (msg.get("message") or {}).get("content")
+    if isinstance(content, str):
+        return content
+    return "\n".join(b.get("text", "") for b in content or [] if b.get("type") == "text")
+
+
+def main() -> None:
+    p = argparse.ArgumentParser()
+    p.add_argument("--model", default="")
+    p.add_argument("--resume", default="")
+    p.add_argument("--read-only", action="store_true")
+    args = p.parse_args()
+    s = Session(args)
+    signal.signal(signal.SIGTERM, s.stop)
+    emit(
+        {
+            "type": "system",
+            "subtype": "init",
+            "session_id": s.id,
+            "cwd": s.cwd,
+            "model": s.model,
+            "tools": ["Edit", "Commit"],
+        }
+    )
+    for line in sys.stdin:
+        line = line.strip()
+        if line:
+            text = prompt_text(line)
+            if text:
+                s.turn(text)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,10 @@
+// Package aider embeds the bridge script driving aider.
+package aider
+
+import _ "embed"
+
+// BridgeScript is the Python bridge that runs aider's scripting mode for each
+// prompt and translates its output to Claude Code stream-json.
+//
+//go:embed bridge.py
+var BridgeScript []byte
@@ -30,6 +30,9 @@ type Backend interface {
 	// Harness returns the harness identifier ("claude", "gemini", etc.)
 	Harness() Harness
 
+	// Binary returns the name of the harness executable inside the container.
+	Binary() string
+
 	// Models returns the list of model names supported by this backend.
 	Models() []string
 
@@ -39,6 +42,21 @@ type Backend interface {
 	// SupportsCompact reports whether this backend supports context compaction.
 	SupportsCompact() bool
 
+	// SupportsInterrupt reports whether this backend can cancel a turn
+	// without ending the session.
+	SupportsInterrupt() bool
+
+	// SupportsPlanOnly reports whether this backend honors Options.PlanOnly.
+	SupportsPlanOnly() bool
+
+	// SupportsThinking reports whether this backend honors Options.Thinking.
+	SupportsThinking() bool
+
+	// CommitsChanges reports whether the harness commits its own changes.
+	// The runner then refreshes the diff after the harness's commits rather
+	// than after each file edit, so its fetch doesn't commit the edits first.
+	CommitsChanges() bool
+
 	// ContextWindowLimit returns the API prompt token limit for the given model.
 	// The model parameter is the model name reported by the agent at runtime.
 	ContextWindowLimit(model string) int
@@ -53,8 +71,12 @@ type Backend interface {
 // its own Start method.
 type Base struct {
 	HarnessID     Harness
+	BinaryName    string // Executable name inside the container, e.g. "claude".
 	ModelList     []string
 	Images        bool
+	PlanOnly      bool // Honors Options.PlanOnly.
+	Thinking      bool // Honors Options.Thinking.
+	Commits       bool // Commits its own changes.
 	ContextWindow int
 	Wire          WireFormat // Used by StartRelay, AttachRelay, ReadRelayOutput.
 }
@@ -62,6 +84,9 @@ type Base struct {
 // Harness implements Backend.
 func (b *Base) Harness() Harness { return b.HarnessID }
 
+// Binary implements Backend.
+func (b *Base) Binary() string { return b.BinaryName }
+
 // Models implements Backend.
 func (b *Base) Models() []string { return b.ModelList }
 
@@ -74,6 +99,21 @@ func (b *Base) SupportsCompact() bool {
 	return ok
 }
 
+// SupportsInterrupt implements Backend by checking if Wire implements Interrupter.
+func (b *Base) SupportsInterrupt() bool {
+	_, ok := b.Wire.(Interrupter)
+	return ok
+}
+
+// SupportsPlanOnly implements Backend.
+func (b *Base) SupportsPlanOnly() bool { return b.PlanOnly }
+
+// SupportsThinking implements Backend.
+func (b *Base) SupportsThinking() bool { return b.Thinking }
+
+// CommitsChanges implements Backend.
+func (b *Base) CommitsChanges() bool { return b.Commits }
+
 // ContextWindowLimit implements Backend.
 func (b *Base) ContextWindowLimit(string) int { return b.ContextWindow }
 
@@ -8,6 +8,8 @@ import (
 	"io"
 	"io/fs"
 	"os"
+	"strconv"
+	"sync/atomic"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	"github.com/caic-xyz/caic/backend/internal/jsonutil"
@@ -19,6 +21,7 @@ type Backend struct {
 	agent.Base
 	widgetTracker *WidgetTracker
 	fieldWarner   *jsonutil.FieldWarner
+	controlID     atomic.Int64 // Sequence of control request IDs.
 }
 
 // ParseMessage wraps ParseMessage with widget tracking for streaming deltas.
@@ -42,8 +45,11 @@ func New() *Backend {
 	}
 	b.Base = agent.Base{
 		HarnessID:     agent.Claude,
+		BinaryName:    "claude",
 		ModelList:     []string{"opus", "sonnet", "haiku"},
 		Images:        true,
+		PlanOnly:      true,
+		Thinking:      true,
 		ContextWindow: 180_000,
 	}
 	b.Wire = b
@@ -133,16 +139,62 @@ func (b *Backend) WriteCompact(w io.Writer, instructions string, logW io.Writer)
 	return b.WritePrompt(w, agent.Prompt{Text: text}, logW)
 }
 
+// WriteInterrupt implements agent.Interrupter by sending an interrupt control
+// request, Claude Code's equivalent of pressing ESC. It ends the turn with a
+// result and keeps the session open.
+func (b *Backend) WriteInterrupt(w io.Writer, logW io.Writer) error {
+	msg := cc.InputControlRequestMsg{
+		Type:      cc.InputControlRequest,
+		RequestID: "caic-" + strconv.FormatInt(b.controlID.Add(1), 10),
+		Request:   cc.ControlReqInterrupt{Subtype: cc.ControlInterrupt},
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if logW != nil {
+		_, _ = logW.Write(data)
+	}
+	return nil
+}
+
+// HasInstructionsFlag implements agent.InstructionsFlag: buildArgs appends
+// the instructions to Claude Code's system prompt.
+func (*Backend) HasInstructionsFlag() {}
+
+// thinkingTokens is the extended thinking budget requested with
+// Options.Thinking, Claude Code's largest.
+const thinkingTokens = "31999"
+
 // buildArgs constructs the Claude Code CLI arguments.
 func buildArgs(opts *agent.Options) []string {
-	args := []string{
+	var args []string
+	if opts.Thinking {
+		// Claude Code has no flag for the thinking budget, only a variable.
+		args = append(args, "env", "MAX_THINKING_TOKENS="+thinkingTokens)
+	}
+	args = append(args,
 		"claude", "-p",
 		"--input-format", "stream-json",
 		"--output-format", "stream-json",
 		"--verbose",
-		"--dangerously-skip-permissions",
+	)
+	if opts.PlanOnly {
+		// Plan mode lets the agent read and research but denies edits.
+		args = append(args, "--permission-mode", "plan")
+	} else {
+		args = append(args, "--dangerously-skip-permissions")
+	}
+	args = append(args,
 		"--include-partial-messages",
 		"--plugin-dir", agent.WidgetPluginDir,
+	)
+	if opts.ReadOnly {
+		args = append(args, "--disallowedTools", "Edit,MultiEdit,Write,NotebookEdit")
 	}
 	if opts.Model != "" {
 		args = append(args, "--model", opts.Model)
@@ -150,5 +202,9 @@ func buildArgs(opts *agent.Options) []string {
 	if opts.ResumeSessionID != "" {
 		args = append(args, "--resume", opts.ResumeSessionID)
 	}
+	if opts.Instructions != "" {
+		// ssh runs the command line in the container's shell.
+		args = append(args, "--append-system-prompt", agent.ShellQuote(opts.Instructions))
+	}
 	return args
 }
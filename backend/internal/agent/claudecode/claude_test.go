@@ -105,6 +105,18 @@ func TestWritePrompt(t *testing.T) {
 	})
 }
 
+func TestWriteInterrupt(t *testing.T) {
+	var buf bytes.Buffer
+	var b Backend
+	if err := b.WriteInterrupt(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"type":"control_request","request_id":"caic-1","request":{"subtype":"interrupt"}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
 func TestStart(t *testing.T) {
 	t.Run("EnvVarInjection", func(t *testing.T) {
 		// Verify that the InputUpdateEnvVarsMsg message produced by Start
@@ -133,3 +145,36 @@ func TestStart(t *testing.T) {
 		}
 	})
 }
+
+func TestBuildArgs(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		args := strings.Join(buildArgs(&agent.Options{}), " ")
+		if !strings.Contains(args, "--dangerously-skip-permissions") || strings.Contains(args, "--permission-mode") {
+			t.Errorf("args = %q, want --dangerously-skip-permissions only", args)
+		}
+	})
+	t.Run("PlanOnly", func(t *testing.T) {
+		args := strings.Join(buildArgs(&agent.Options{PlanOnly: true}), " ")
+		if !strings.Contains(args, "--permission-mode plan") || strings.Contains(args, "--dangerously-skip-permissions") {
+			t.Errorf("args = %q, want --permission-mode plan without skipping permissions", args)
+		}
+	})
+	t.Run("ReadOnly", func(t *testing.T) {
+		args := strings.Join(buildArgs(&agent.Options{ReadOnly: true}), " ")
+		if !strings.Contains(args, "--disallowedTools Edit,MultiEdit,Write,NotebookEdit") {
+			t.Errorf("args = %q, want write tools disallowed", args)
+		}
+	})
+	t.Run("Instructions", func(t *testing.T) {
+		args := strings.Join(buildArgs(&agent.Options{Instructions: "Don't push."}), " ")
+		if !strings.HasSuffix(args, ` --append-system-prompt 'Don'\''t push.'`) {
+			t.Errorf("args = %q, want the quoted instructions", args)
+		}
+	})
+	t.Run("Thinking", func(t *testing.T) {
+		args := buildArgs(&agent.Options{Thinking: true})
+		if len(args) < 3 || args[0] != "env" || args[1] != "MAX_THINKING_TOKENS="+thinkingTokens || args[2] != "claude" {
+			t.Errorf("args = %q, want claude run with a thinking budget", args)
+		}
+	})
+}
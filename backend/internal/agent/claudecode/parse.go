@@ -155,6 +155,10 @@ func (wt *WidgetTracker) handleStreamEvent(w *cc.OutputStreamEventMsg) ([]agent.
 //   - TodoMessage          — TodoWrite tool_use block
 //   - ToolResultMessage    — user message with parent_tool_use_id
 //   - UserInputMessage     — user message without parent_tool_use_id
+//
+// Text, thinking, tool use and tool result messages of a sub-agent (spawned by
+// the Task tool) carry its parent_tool_use_id as ParentToolUseID. Sub-agents'
+// streaming deltas are dropped; their complete messages follow.
 //   - UsageMessage         — assistant message usage counters
 //   - ResultMessage        — result record
 //   - DiffStatMessage      — caic_diff_stat injection
@@ -191,6 +195,7 @@ func parseMessageWithTracker(line []byte, wt *WidgetTracker, fw *jsonutil.FieldW
 			MessageType:   string(w.Type),
 			Subtype:       w.Subtype,
 			IsError:       w.IsError,
+			RateLimited:   w.IsError && agent.IsRateLimitError(w.Result),
 			DurationMs:    w.DurationMs,
 			DurationAPIMs: w.DurationAPIMs,
 			NumTurns:      w.NumTurns,
@@ -278,13 +283,18 @@ func parseAssistant(line []byte, fw *jsonutil.FieldWarner) ([]agent.Message, err
 		switch b.Type {
 		case "text":
 			if b.Text != "" {
-				msgs = append(msgs, &agent.TextMessage{Text: b.Text})
+				msgs = append(msgs, &agent.TextMessage{Text: b.Text, ParentToolUseID: w.ParentToolUseID})
 			}
 		case "tool_use":
-			msgs = append(msgs, parseToolUseBlock(b)...)
+			for _, m := range parseToolUseBlock(b) {
+				if tu, ok := m.(*agent.ToolUseMessage); ok {
+					tu.ParentToolUseID = w.ParentToolUseID
+				}
+				msgs = append(msgs, m)
+			}
 		case "thinking":
 			if b.Thinking != "" {
-				msgs = append(msgs, &agent.ThinkingMessage{Text: b.Thinking})
+				msgs = append(msgs, &agent.ThinkingMessage{Text: b.Thinking, ParentToolUseID: w.ParentToolUseID})
 			}
 		case "server_tool_use", "web_search_tool_result", "tool_result":
 			continue
@@ -334,6 +344,7 @@ func parseToolUseBlock(b *cc.OutputContentBlock) []agent.Message {
 		ToolUseID: b.ID,
 		Name:      b.Name,
 		Input:     b.Input,
+		Call:      agent.NewToolCall(b.Name, b.Input),
 	}}
 }
 
@@ -349,8 +360,15 @@ func parseUser(line []byte, fw *jsonutil.FieldWarner) ([]agent.Message, error) {
 		return nil, nil
 	}
 
-	// Standard tool result: parent_tool_use_id set at the top level.
 	if w.ParentToolUseID != "" {
+		// Results of the tools a sub-agent called.
+		if msgs := parseUserMessage(w.Message); len(msgs) == 1 {
+			if tr, ok := msgs[0].(*agent.ToolResultMessage); ok {
+				tr.ParentToolUseID = w.ParentToolUseID
+				return msgs, nil
+			}
+		}
+		// Standard tool result: parent_tool_use_id set at the top level.
 		return []agent.Message{extractToolResult(w.ParentToolUseID, w.Message)}, nil
 	}
 
@@ -437,6 +455,10 @@ func parseStreamEvent(line []byte, wt *WidgetTracker, fw *jsonutil.FieldWarner)
 	if err := unmarshalOutput(line, &w, "OutputStreamEventMsg", fw); err != nil {
 		return nil, err
 	}
+	if w.ParentToolUseID != "" {
+		// A sub-agent's deltas would interleave with the main agent's text.
+		return nil, nil
+	}
 
 	// Let the widget tracker handle the event first (if present).
 	if wt != nil {
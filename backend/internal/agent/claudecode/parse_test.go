@@ -234,6 +234,53 @@ func TestParseMessage(t *testing.T) {
 			t.Errorf("error = %q, want %q", tr.Error, "tool failed")
 		}
 	})
+	t.Run("Subagent", func(t *testing.T) {
+		// Messages of a sub-agent spawned by the Task tool carry the Task's
+		// tool_use ID as parent_tool_use_id.
+		parse := func(line string) []agent.Message {
+			msgs, err := parseMessage([]byte(line), &jsonutil.FieldWarner{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return msgs
+		}
+		t.Run("Assistant", func(t *testing.T) {
+			msgs := parse(`{"type":"assistant","message":{"role":"assistant","content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"Looking."},{"type":"tool_use","id":"toolu_sub","name":"Grep","input":{"pattern":"auth"}}]},"parent_tool_use_id":"toolu_task"}`)
+			if len(msgs) != 3 {
+				t.Fatalf("got %d messages, want 3", len(msgs))
+			}
+			if m, ok := msgs[0].(*agent.ThinkingMessage); !ok || m.ParentToolUseID != "toolu_task" {
+				t.Errorf("msgs[0] = %#v", msgs[0])
+			}
+			if m, ok := msgs[1].(*agent.TextMessage); !ok || m.ParentToolUseID != "toolu_task" {
+				t.Errorf("msgs[1] = %#v", msgs[1])
+			}
+			if m, ok := msgs[2].(*agent.ToolUseMessage); !ok || m.ParentToolUseID != "toolu_task" || m.ToolUseID != "toolu_sub" {
+				t.Errorf("msgs[2] = %#v", msgs[2])
+			}
+		})
+		t.Run("ToolResult", func(t *testing.T) {
+			msgs := parse(`{"type":"user","message":{"role":"user","content":[{"tool_use_id":"toolu_sub","type":"tool_result","content":[{"type":"text","text":"a.go"}]}]},"parent_tool_use_id":"toolu_task"}`)
+			if len(msgs) != 1 {
+				t.Fatalf("got %d messages, want 1", len(msgs))
+			}
+			tr, ok := msgs[0].(*agent.ToolResultMessage)
+			if !ok || tr.ToolUseID != "toolu_sub" || tr.ParentToolUseID != "toolu_task" {
+				t.Errorf("msgs[0] = %#v, want the sub-agent's tool result", msgs[0])
+			}
+		})
+		t.Run("MainAgent", func(t *testing.T) {
+			msgs := parse(`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Done."}]},"parent_tool_use_id":null}`)
+			if m, ok := msgs[0].(*agent.TextMessage); !ok || m.ParentToolUseID != "" {
+				t.Errorf("msgs[0] = %#v", msgs[0])
+			}
+		})
+		t.Run("StreamEventDropped", func(t *testing.T) {
+			if msgs := parse(`{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Look"}},"parent_tool_use_id":"toolu_task"}`); len(msgs) != 0 {
+				t.Errorf("got %#v, want no messages", msgs)
+			}
+		})
+	})
 	t.Run("Result", func(t *testing.T) {
 		line := `{"type":"result","subtype":"success","is_error":false,"duration_ms":1234,"num_turns":3,"result":"done","total_cost_usd":0.05,"usage":{"input_tokens":100,"output_tokens":50}}`
 		msgs, err := parseMessage([]byte(line), &jsonutil.FieldWarner{})
@@ -250,6 +297,19 @@ func TestParseMessage(t *testing.T) {
 		if m.NumTurns != 3 {
 			t.Errorf("turns = %d, want 3", m.NumTurns)
 		}
+		if m.RateLimited {
+			t.Error("RateLimited should be false")
+		}
+	})
+	t.Run("ResultRateLimited", func(t *testing.T) {
+		line := `{"type":"result","subtype":"success","is_error":true,"duration_ms":1234,"num_turns":1,"result":"API Error: 529 {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}","total_cost_usd":0,"usage":{"input_tokens":0,"output_tokens":0}}`
+		msgs, err := parseMessage([]byte(line), &jsonutil.FieldWarner{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m, ok := msgs[0].(*agent.ResultMessage); !ok || !m.RateLimited {
+			t.Errorf("got %#v, want a rate limited result", msgs[0])
+		}
 	})
 	t.Run("StreamEventTextDelta", func(t *testing.T) {
 		line := `{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}}`
@@ -45,8 +45,11 @@ func (*Backend) NewParser() func([]byte) ([]agent.Message, error) {
 func New() *Backend {
 	return &Backend{Base: agent.Base{
 		HarnessID:     agent.Codex,
+		BinaryName:    "codex",
 		ModelList:     []string{"gpt-5.4"},
 		Images:        true,
+		PlanOnly:      true,
+		Thinking:      true,
 		ContextWindow: 200_000,
 	}}
 }
@@ -113,6 +116,7 @@ func (b *Backend) Start(ctx context.Context, opts *agent.Options, msgCh chan<- a
 
 	log := slog.With("container", opts.Container)
 	s := agent.NewSession(cmd, stdin, br, msgCh, logW, wire, log)
+	s.PrependInstructions(opts)
 	if opts.InitialPrompt.Text != "" {
 		if err := s.Send(opts.InitialPrompt); err != nil {
 			s.Close()
@@ -139,11 +143,17 @@ func (b *Backend) AttachRelay(ctx context.Context, opts *agent.Options, msgCh ch
 	return agent.AttachRelaySession(ctx, opts.Container, opts.RelayOffset, msgCh, logW, wire)
 }
 
+// SupportsInterrupt implements agent.Backend. The per-session wireFormat
+// implements agent.Interrupter.
+func (b *Backend) SupportsInterrupt() bool { return true }
+
 // wireFormat implements agent.WireFormat for the codex app-server JSON-RPC
-// protocol. It holds per-session state: the thread ID, a request ID counter,
-// and accumulated token usage from thread/tokenUsage/updated.
+// protocol. It holds per-session state: the thread ID, the ID of the running
+// turn, a request ID counter, and accumulated token usage from
+// thread/tokenUsage/updated.
 type wireFormat struct {
 	threadID   string
+	turnID     string // Set by turn/started, cleared by turn/completed.
 	nextID     atomic.Int64
 	mu         sync.Mutex
 	totalUsage agent.Usage // accumulated per-turn from thread/tokenUsage/updated
@@ -194,6 +204,24 @@ func (w *wireFormat) WriteCompact(wr io.Writer, _ string, _ io.Writer) error {
 	return writeJSON(wr, req)
 }
 
+// WriteInterrupt implements agent.Interrupter by sending a turn/interrupt
+// JSON-RPC request for the running turn. Codex then completes the turn with
+// status "interrupted".
+func (w *wireFormat) WriteInterrupt(wr io.Writer, _ io.Writer) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.turnID == "" {
+		return errors.New("codex: no turn in progress")
+	}
+	req := cx.JSONRPCRequest{
+		JSONRPC: "2.0",
+		ID:      w.nextID.Add(1),
+		Method:  "turn/interrupt",
+		Params:  cx.TurnInterruptParams{ThreadID: w.threadID, TurnID: w.turnID},
+	}
+	return writeJSON(wr, req)
+}
+
 // ParseMessage wraps the package-level parseMessage with two interceptions:
 //
 //   - thread/tokenUsage/updated → emits UsageMessage (incremental Last
@@ -202,12 +230,27 @@ func (w *wireFormat) WriteCompact(wr io.Writer, _ string, _ io.Writer) error {
 //   - ResultMessage (from turn/completed) has Usage populated from totalUsage,
 //     then totalUsage is reset for the next turn.
 //
-// It also captures the thread ID from InitMessage (thread/started).
+// It also captures the thread ID from InitMessage (thread/started) and the
+// running turn ID from turn/started.
 func (w *wireFormat) ParseMessage(line []byte) ([]agent.Message, error) {
 	// Intercept thread/tokenUsage/updated: emit a UsageMessage with the
 	// incremental (Last) usage and accumulate into totalUsage.
 	var probe cx.MethodProbe
 	_ = json.Unmarshal(line, &probe)
+	if probe.Method == cx.MethodTurnStarted {
+		var msg cx.JSONRPCMessage
+		if err := json.Unmarshal(line, &msg); err != nil {
+			return nil, fmt.Errorf("turn/started: %w", err)
+		}
+		var p cx.TurnStartedNotification
+		if err := unmarshalNotification(msg.Params, &p, "TurnStartedNotification", w.fw); err != nil {
+			return nil, fmt.Errorf("turn/started params: %w", err)
+		}
+		w.mu.Lock()
+		w.turnID = p.Turn.ID
+		w.mu.Unlock()
+		return nil, nil
+	}
 	if probe.Method == cx.MethodTokenUsageUpdated {
 		var msg cx.JSONRPCMessage
 		if err := json.Unmarshal(line, &msg); err != nil {
@@ -252,6 +295,7 @@ func (w *wireFormat) ParseMessage(line []byte) ([]agent.Message, error) {
 			w.mu.Lock()
 			rm.Usage = w.totalUsage
 			w.totalUsage = agent.Usage{}
+			w.turnID = ""
 			w.mu.Unlock()
 		}
 	}
@@ -443,12 +487,19 @@ func readJSONRPCResponse(ctx context.Context, r *bufio.Reader) (*cx.JSONRPCMessa
 // }
 
 // buildArgs constructs the Codex CLI app-server arguments.
-func buildArgs(_ *agent.Options) []string {
+func buildArgs(opts *agent.Options) []string {
 	// TODO: re-enable widget MCP plugin once it's fixed for codex
 	// return []string{
 	// 	"codex", "app-server",
 	// 	"-c", `mcp_servers.widget.command="python3"`,
 	// 	"-c", `mcp_servers.widget.args=["` + widgetMCPServerPath + `"]`,
 	// }
-	return []string{"codex", "app-server"}
+	args := []string{"codex", "app-server"}
+	if opts.PlanOnly || opts.ReadOnly {
+		args = append(args, "-c", `sandbox_mode="read-only"`)
+	}
+	if opts.Thinking {
+		args = append(args, "-c", `model_reasoning_effort="high"`)
+	}
+	return args
 }
@@ -118,6 +118,7 @@ func parseMessage(line []byte, fw *jsonutil.FieldWarner) ([]agent.Message, error
 				Subtype:     "result",
 				IsError:     true,
 				Result:      errMsg,
+				RateLimited: p.Turn.Status == "failed" && rateLimited(p.Turn.Error),
 			}}, nil
 		default: // "completed", "inProgress"
 			return []agent.Message{&agent.ResultMessage{
@@ -155,6 +156,7 @@ func parseMessage(line []byte, fw *jsonutil.FieldWarner) ([]agent.Message, error
 			Subtype:     "result",
 			IsError:     true,
 			Result:      p.Error.Message,
+			RateLimited: rateLimited(p.Error),
 		}}, nil
 
 	case cx.MethodReasoningSummaryTextDelta:
@@ -230,6 +232,7 @@ func parseItemStarted(msg *cx.JSONRPCMessage, fw *jsonutil.FieldWarner) ([]agent
 			ToolUseID: item.ID,
 			Name:      "Bash",
 			Input:     input,
+			Call:      &agent.ToolCall{Kind: agent.ToolShell, Command: item.Command},
 		}}, nil
 
 	case cx.ItemTypeFileChange:
@@ -238,10 +241,16 @@ func parseItemStarted(msg *cx.JSONRPCMessage, fw *jsonutil.FieldWarner) ([]agent
 			return nil, fmt.Errorf("item/started fileChange: %w", err)
 		}
 		input, _ := json.Marshal(item.Changes)
+		name := toolNameForChanges(item.Changes)
+		call := agent.NewToolCall(name, nil)
+		for _, c := range item.Changes {
+			call.Paths = append(call.Paths, c.Path)
+		}
 		return []agent.Message{&agent.ToolUseMessage{
 			ToolUseID: item.ID,
-			Name:      toolNameForChanges(item.Changes),
+			Name:      name,
 			Input:     input,
+			Call:      call,
 		}}, nil
 
 	case cx.ItemTypeMCPToolCall:
@@ -256,6 +265,7 @@ func parseItemStarted(msg *cx.JSONRPCMessage, fw *jsonutil.FieldWarner) ([]agent
 			ToolUseID: item.ID,
 			Name:      item.Tool,
 			Input:     item.Arguments,
+			Call:      agent.NewToolCall(item.Tool, item.Arguments),
 		}}, nil
 
 	case cx.ItemTypeDynamicToolCall:
@@ -267,6 +277,7 @@ func parseItemStarted(msg *cx.JSONRPCMessage, fw *jsonutil.FieldWarner) ([]agent
 			ToolUseID: item.ID,
 			Name:      item.Tool,
 			Input:     item.Arguments,
+			Call:      agent.NewToolCall(item.Tool, item.Arguments),
 		}}, nil
 
 	case cx.ItemTypeCollabAgentToolCall:
@@ -283,6 +294,7 @@ func parseItemStarted(msg *cx.JSONRPCMessage, fw *jsonutil.FieldWarner) ([]agent
 			ToolUseID: item.ID,
 			Name:      toolName,
 			Input:     input,
+			Call:      &agent.ToolCall{Kind: agent.ToolAgent, Description: item.Prompt},
 		}}, nil
 
 	case cx.ItemTypeImageGeneration:
@@ -295,6 +307,7 @@ func parseItemStarted(msg *cx.JSONRPCMessage, fw *jsonutil.FieldWarner) ([]agent
 			ToolUseID: item.ID,
 			Name:      "ImageGeneration",
 			Input:     input,
+			Call:      &agent.ToolCall{Kind: agent.ToolOther},
 		}}, nil
 
 	default:
@@ -391,7 +404,7 @@ func parseItemCompleted(msg *cx.JSONRPCMessage, fw *jsonutil.FieldWarner) ([]age
 		}
 		input, _ := json.Marshal(map[string]string{"query": item.Query})
 		return []agent.Message{
-			&agent.ToolUseMessage{ToolUseID: item.ID, Name: "WebSearch", Input: input},
+			&agent.ToolUseMessage{ToolUseID: item.ID, Name: "WebSearch", Input: input, Call: &agent.ToolCall{Kind: agent.ToolWebSearch, Query: item.Query}},
 			&agent.ToolResultMessage{ToolUseID: item.ID},
 		}, nil
 
@@ -420,3 +433,14 @@ func toolNameForChanges(changes []cx.FileUpdateChange) string {
 	}
 	return "Edit"
 }
+
+// rateLimited reports whether a turn error is a rate limit: codex classifies
+// usage limits and HTTP 429 responses in CodexErrorInfo, other providers only
+// in the message.
+func rateLimited(e *cx.TurnError) bool {
+	if e == nil {
+		return false
+	}
+	info := string(e.CodexErrorInfo)
+	return strings.Contains(info, "usageLimitExceeded") || strings.Contains(info, "429") || agent.IsRateLimitError(e.Message)
+}
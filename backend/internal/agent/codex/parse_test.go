@@ -3,6 +3,8 @@ package codex
 import (
 	"bytes"
 	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
@@ -80,6 +82,9 @@ func TestParseMessage(t *testing.T) {
 		if rm.Result != "rate limit exceeded" {
 			t.Errorf("Result = %q", rm.Result)
 		}
+		if !rm.RateLimited {
+			t.Error("RateLimited should be true")
+		}
 	})
 	t.Run("ItemStartedCommandExecution", func(t *testing.T) {
 		const input = `{"jsonrpc":"2.0","method":"item/started","params":{"item":{"id":"item_1","type":"commandExecution","command":"bash -lc ls","cwd":"/repo","status":"inProgress"},"threadId":"t1","turnId":"turn_1"}}`
@@ -201,6 +206,9 @@ func TestParseMessage(t *testing.T) {
 		if tu.ToolUseID != "item_4" {
 			t.Errorf("ToolUseID = %q, want item_4", tu.ToolUseID)
 		}
+		if want := (agent.ToolCall{Kind: agent.ToolWrite, Paths: []string{"docs/foo.md"}}); !reflect.DeepEqual(*tu.Call, want) {
+			t.Errorf("Call = %+v, want %+v", *tu.Call, want)
+		}
 	})
 	t.Run("ItemCompletedFileChangeAdd", func(t *testing.T) {
 		const input = `{"jsonrpc":"2.0","method":"item/completed","params":{"item":{"id":"item_4","type":"fileChange","changes":[{"path":"docs/foo.md","kind":{"type":"add"},"diff":""}],"status":"completed"},"threadId":"t1","turnId":"turn_1"}}`
@@ -679,6 +687,20 @@ func TestBuildArgs(t *testing.T) {
 			}
 		}
 	})
+	t.Run("ReadOnlySandbox", func(t *testing.T) {
+		for _, opts := range []*agent.Options{{PlanOnly: true}, {ReadOnly: true}} {
+			args := strings.Join(buildArgs(opts), " ")
+			if !strings.Contains(args, `-c sandbox_mode="read-only"`) {
+				t.Errorf("args = %q, want a read-only sandbox", args)
+			}
+		}
+	})
+	t.Run("Thinking", func(t *testing.T) {
+		args := strings.Join(buildArgs(&agent.Options{Thinking: true}), " ")
+		if !strings.Contains(args, `-c model_reasoning_effort="high"`) {
+			t.Errorf("args = %q, want high reasoning effort", args)
+		}
+	})
 }
 
 func TestWireFormat(t *testing.T) {
@@ -826,4 +848,35 @@ func TestWireFormat(t *testing.T) {
 			t.Errorf("totalUsage not reset after ResultMessage: %+v", reset)
 		}
 	})
+	t.Run("Interrupt", func(t *testing.T) {
+		w := &wireFormat{threadID: "t1"}
+		var buf bytes.Buffer
+		if err := w.WriteInterrupt(&buf, nil); err == nil {
+			t.Fatal("expected error without a running turn")
+		}
+		const started = `{"jsonrpc":"2.0","method":"turn/started","params":{"threadId":"t1","turn":{"id":"turn_1","status":"inProgress"}}}`
+		if msgs, err := w.ParseMessage([]byte(started)); err != nil || len(msgs) != 0 {
+			t.Fatalf("msgs = %v, err = %v", msgs, err)
+		}
+		if err := w.WriteInterrupt(&buf, nil); err != nil {
+			t.Fatal(err)
+		}
+		var req struct {
+			Method string                 `json:"method"`
+			Params cx.TurnInterruptParams `json:"params"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &req); err != nil {
+			t.Fatal(err)
+		}
+		if req.Method != "turn/interrupt" || req.Params != (cx.TurnInterruptParams{ThreadID: "t1", TurnID: "turn_1"}) {
+			t.Errorf("req = %+v", req)
+		}
+		const completed = `{"jsonrpc":"2.0","method":"turn/completed","params":{"threadId":"t1","turn":{"id":"turn_1","status":"interrupted"}}}`
+		if _, err := w.ParseMessage([]byte(completed)); err != nil {
+			t.Fatal(err)
+		}
+		if w.turnID != "" {
+			t.Errorf("turnID = %q after turn/completed", w.turnID)
+		}
+	})
 }
@@ -4,6 +4,8 @@ package gemini
 import (
 	"context"
 	"io"
+	"strings"
+	"sync"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	"github.com/caic-xyz/caic/backend/internal/jsonutil"
@@ -12,47 +14,97 @@ import (
 // Backend implements agent.Backend for Gemini CLI.
 type Backend struct {
 	agent.Base
-	fw *jsonutil.FieldWarner
 }
 
 var _ agent.Backend = (*Backend)(nil)
 
-// NewParser implements agent.Backend.
+// NewParser implements agent.Backend. The parser is stateful so replayed
+// logs reconcile streamed text like live sessions do.
 func (*Backend) NewParser() func([]byte) ([]agent.Message, error) {
-	fw := &jsonutil.FieldWarner{}
-	return func(line []byte) ([]agent.Message, error) { return parseMessage(line, fw) }
+	return newWireFormat().ParseMessage
 }
 
 // New creates a Gemini CLI backend with wire format and parser configured.
 func New() *Backend {
-	b := &Backend{
-		fw: &jsonutil.FieldWarner{},
-	}
+	b := &Backend{}
 	b.Base = agent.Base{
 		HarnessID:     agent.Gemini,
+		BinaryName:    "gemini",
 		ModelList:     []string{"gemini-3.1-pro", "gemini-3-flash"},
 		ContextWindow: 1_000_000,
 	}
-	b.Wire = b
 	return b
 }
 
-// ParseMessage implements agent.WireFormat.
-func (b *Backend) ParseMessage(line []byte) ([]agent.Message, error) {
-	return parseMessage(line, b.fw)
-}
-
 // Start launches a Gemini CLI process via the relay daemon.
+// A fresh wireFormat is used so no text accumulates across sessions.
 func (b *Backend) Start(ctx context.Context, opts *agent.Options, msgCh chan<- agent.Message, logW io.Writer) (*agent.Session, error) {
-	return agent.StartRelay(ctx, opts, buildArgs(opts), msgCh, logW, b)
+	return agent.StartRelay(ctx, opts, buildArgs(opts), msgCh, logW, newWireFormat())
+}
+
+// AttachRelay connects to an already-running relay using a fresh wireFormat.
+func (b *Backend) AttachRelay(ctx context.Context, opts *agent.Options, msgCh chan<- agent.Message, logW io.Writer) (*agent.Session, error) {
+	return agent.AttachRelaySession(ctx, opts.Container, opts.RelayOffset, msgCh, logW, newWireFormat())
+}
+
+// ReadRelayOutput reads output.jsonl using a fresh wireFormat.
+func (b *Backend) ReadRelayOutput(ctx context.Context, container string) ([]agent.Message, int64, error) {
+	return agent.ReadRelayOutput(ctx, container, newWireFormat().ParseMessage)
+}
+
+// wireFormat is a stateful WireFormat for a Gemini CLI session.
+//
+// Gemini CLI streams assistant text as delta messages and never sends the
+// complete message. The deltas are forwarded as TextDeltaMessage so the UI
+// renders text as it is generated, and accumulated; the accumulated text is
+// emitted as a TextMessage before the next record that ends the text block
+// (tool use, result, user message), replacing the deltas like the final
+// assistant message of the other harnesses does.
+type wireFormat struct {
+	mu   sync.Mutex
+	text strings.Builder
+	fw   *jsonutil.FieldWarner
+}
+
+func newWireFormat() *wireFormat {
+	return &wireFormat{fw: &jsonutil.FieldWarner{}}
 }
 
 // WritePrompt writes a single user message to Gemini CLI's stdin.
 // Gemini CLI in -p mode reads plain text lines from stdin. Images are ignored.
-func (*Backend) WritePrompt(w io.Writer, p agent.Prompt, logW io.Writer) error {
+func (*wireFormat) WritePrompt(w io.Writer, p agent.Prompt, logW io.Writer) error {
 	return agent.PlainTextWritePrompt(w, p, logW)
 }
 
+// ParseMessage implements agent.WireFormat.
+func (w *wireFormat) ParseMessage(line []byte) ([]agent.Message, error) {
+	msgs, err := parseMessage(line, w.fw)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	out := make([]agent.Message, 0, len(msgs)+1)
+	for _, msg := range msgs {
+		switch m := msg.(type) {
+		case *agent.TextDeltaMessage:
+			w.text.WriteString(m.Text)
+		case *agent.TextMessage:
+			// A complete message supersedes the deltas.
+			w.text.Reset()
+		case *agent.DiffStatMessage, *agent.RawMessage:
+			// Metadata; the text block continues.
+		default:
+			if w.text.Len() != 0 {
+				out = append(out, &agent.TextMessage{Text: w.text.String()})
+				w.text.Reset()
+			}
+		}
+		out = append(out, msg)
+	}
+	return out, nil
+}
+
 // buildArgs constructs the Gemini CLI arguments.
 func buildArgs(opts *agent.Options) []string {
 	args := []string{
@@ -41,6 +41,7 @@ func normalizeToolName(name string) string {
 // Emitted agent.Message types:
 //   - InitMessage       — type=init
 //   - TextMessage       — type=message role=assistant
+//   - TextDeltaMessage  — type=message role=assistant delta=true
 //   - UserInputMessage  — type=message role=user
 //   - ToolUseMessage    — type=tool_use (generic tools)
 //   - AskMessage        — type=tool_use name=ask_user
@@ -74,6 +75,9 @@ func parseMessage(line []byte, fw *jsonutil.FieldWarner) ([]agent.Message, error
 		fw.WarnOverflows("MessageRecord", r)
 		switch r.Role {
 		case "assistant":
+			if r.Delta {
+				return []agent.Message{&agent.TextDeltaMessage{Text: r.Content}}, nil
+			}
 			return []agent.Message{&agent.TextMessage{Text: r.Content}}, nil
 		case "user":
 			return []agent.Message{&agent.UserInputMessage{Text: r.Content}}, nil
@@ -154,5 +158,5 @@ func dispatchToolUse(id, name string, input json.RawMessage) agent.Message {
 			return &agent.TodoMessage{ToolUseID: id, Todos: parsed.Todos}
 		}
 	}
-	return &agent.ToolUseMessage{ToolUseID: id, Name: name, Input: input}
+	return &agent.ToolUseMessage{ToolUseID: id, Name: name, Input: input, Call: agent.NewToolCall(name, input)}
 }
@@ -1,6 +1,7 @@
 package gemini
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
@@ -28,7 +29,7 @@ func TestParseMessage(t *testing.T) {
 			t.Errorf("Model = %q", init.Model)
 		}
 	})
-	t.Run("AssistantText", func(t *testing.T) {
+	t.Run("AssistantDelta", func(t *testing.T) {
 		const input = `{"type":"message","timestamp":"2026-02-13T19:00:10.729Z","role":"assistant","content":"Hello.","delta":true}`
 		msgs, err := parseMessage([]byte(input), &jsonutil.FieldWarner{})
 		if err != nil {
@@ -37,9 +38,9 @@ func TestParseMessage(t *testing.T) {
 		if len(msgs) != 1 {
 			t.Fatalf("msgs = %d, want 1", len(msgs))
 		}
-		tm, ok := msgs[0].(*agent.TextMessage)
+		tm, ok := msgs[0].(*agent.TextDeltaMessage)
 		if !ok {
-			t.Fatalf("type = %T, want *agent.TextMessage", msgs[0])
+			t.Fatalf("type = %T, want *agent.TextDeltaMessage", msgs[0])
 		}
 		if tm.Text != "Hello." {
 			t.Errorf("Text = %q", tm.Text)
@@ -192,3 +193,56 @@ func TestNormalizeToolName(t *testing.T) {
 		})
 	}
 }
+
+func TestWireFormat(t *testing.T) {
+	t.Run("ReconcilesDeltas", func(t *testing.T) {
+		w := newWireFormat()
+		var got []agent.Message
+		for _, line := range []string{
+			`{"type":"message","role":"assistant","content":"Let me ","delta":true}`,
+			`{"type":"message","role":"assistant","content":"look.","delta":true}`,
+			`{"type":"tool_use","tool_name":"read_file","tool_id":"r1","parameters":{"file_path":"/a"}}`,
+			`{"type":"tool_result","tool_id":"r1","status":"success"}`,
+			`{"type":"message","role":"assistant","content":"Done.","delta":true}`,
+			`{"type":"result","status":"success"}`,
+		} {
+			msgs, err := w.ParseMessage([]byte(line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, msgs...)
+		}
+		var types []string
+		for _, m := range got {
+			types = append(types, m.Type())
+		}
+		want := []string{"text_delta", "text_delta", "text", "tool_use", "tool_result", "text_delta", "text", "result"}
+		if !slices.Equal(types, want) {
+			t.Fatalf("types = %q, want %q", types, want)
+		}
+		if tm := got[2].(*agent.TextMessage); tm.Text != "Let me look." {
+			t.Errorf("Text = %q", tm.Text)
+		}
+		if tm := got[6].(*agent.TextMessage); tm.Text != "Done." {
+			t.Errorf("Text = %q", tm.Text)
+		}
+	})
+	t.Run("CompleteMessage", func(t *testing.T) {
+		w := newWireFormat()
+		for _, line := range []string{
+			`{"type":"message","role":"assistant","content":"Hel","delta":true}`,
+			`{"type":"message","role":"assistant","content":"Hello."}`,
+		} {
+			if _, err := w.ParseMessage([]byte(line)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		msgs, err := w.ParseMessage([]byte(`{"type":"result","status":"success"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) != 1 {
+			t.Fatalf("msgs = %d, want only the result", len(msgs))
+		}
+	})
+}
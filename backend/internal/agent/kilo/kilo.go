@@ -55,6 +55,7 @@ func New() *Backend {
 	b := &Backend{}
 	b.Base = agent.Base{
 		HarnessID:     agent.Kilo,
+		BinaryName:    "kilo",
 		ModelList:     defaultModels,
 		ContextWindow: 200_000,
 	}
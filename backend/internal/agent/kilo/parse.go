@@ -174,7 +174,7 @@ func dispatchToolUse(id, name string, input json.RawMessage) agent.Message {
 			return &agent.TodoMessage{ToolUseID: id, Todos: parsed.Todos}
 		}
 	}
-	return &agent.ToolUseMessage{ToolUseID: id, Name: name, Input: input}
+	return &agent.ToolUseMessage{ToolUseID: id, Name: name, Input: input, Call: agent.NewToolCall(name, input)}
 }
 
 // parseStepFinish converts a step-finish part into a ResultMessage with usage/cost.
@@ -230,6 +230,7 @@ func parseSessionError(rec *Record) ([]agent.Message, error) {
 		Subtype:     "result",
 		IsError:     true,
 		Result:      msg,
+		RateLimited: agent.IsRateLimitError(msg),
 	}}, nil
 }
 
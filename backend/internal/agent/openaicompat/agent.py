@@ -0,0 +1,437 @@
+# Minimal coding agent driving an OpenAI-compatible chat completions API
+# (a local Ollama or llama.cpp server, or a hosted provider), emitting Claude
+# Code streaming JSON.
+#
+# Reads Claude Code stream-json user messages from stdin (one per line) and
+# runs a tool loop against /chat/completions for each of them. Runs under the
+# relay like the other harnesses. The endpoint is read from --endpoint-file,
+# a JSON object with base_url and an optional api_key, so the key never
+# appears in the process list. The conversation is saved after every model
+# call so the session survives a container restart via --resume.
+
+import argparse
+import glob
+import json
+import os
+import socket
+import struct
+import subprocess
+import sys
+import time
+import urllib.error
+import urllib.parse
+import urllib.request
+import uuid
+
+SESSIONS_DIR = os.path.expanduser("~/.local/share/caic-agent/sessions")
+
+# Maximum number of model calls for a single prompt.
+MAX_STEPS = 100
+
+# Tool output is truncated to keep small context windows usable.
+MAX_OUTPUT = 30000
+
+SYSTEM_PROMPT = """You are a coding agent working in the repository at {cwd}.
+Use the tools to inspect and modify files and to run commands. Prefer small,
+focused changes and verify them by running the relevant tests. When the task is
+done, reply with a short summary of what you changed."""
+
+TOOLS = [
+    {
+        "name": "Bash",
+        "description": "Run a shell command in the working directory and return its combined output.",
+        "parameters": {
+            "type": "object",
+            "properties": {
+                "command": {"type": "string", "description": "The command to run."},
+                "timeout": {"type": "integer", "description": "Timeout in milliseconds, default 120000."},
+            },
+            "required": ["command"],
+        },
+        "writes": False,
+    },
+    {
+        "name": "Read",
+        "description": "Read a text file and return its lines prefixed by their line number.",
+        "parameters": {
+            "type": "object",
+            "properties": {
+                "file_path": {"type": "string", "description": "Absolute path of the file."},
+                "offset": {"type": "integer", "description": "First line to read, starting at 1."},
+                "limit": {"type": "integer", "description": "Number of lines to read."},
+            },
+            "required": ["file_path"],
+        },
+        "writes": False,
+    },
+    {
+        "name": "Glob",
+        "description": "List the files matching a glob pattern, e.g. src/**/*.go.",
+        "parameters": {
+            "type": "object",
+            "properties": {"pattern": {"type": "string", "description": "The glob pattern."}},
+            "required": ["pattern"],
+        },
+        "writes": False,
+    },
+    {
+        "name": "Write",
+        "description": "Create or overwrite a file with the given content.",
+        "parameters": {
+            "type": "object",
+            "properties": {
+                "file_path": {"type": "string", "description": "Absolute path of the file."},
+                "content": {"type": "string", "description": "The full content of the file."},
+            },
+            "required": ["file_path", "content"],
+        },
+        "writes": True,
+    },
+    {
+        "name": "Edit",
+        "description": "Replace an exact, unique string in a file.",
+        "parameters": {
+            "type": "object",
+            "properties": {
+                "file_path": {"type": "string", "description": "Absolute path of the file."},
+                "old_string": {"type": "string", "description": "The text to replace."},
+                "new_string": {"type": "string", "description": "The replacement text."},
+                "replace_all": {"type": "boolean", "description": "Replace every occurrence."},
+            },
+            "required": ["file_path", "old_string", "new_string"],
+        },
+        "writes": True,
+    },
+]
+
+
+def emit(obj: dict) -> None:
+    sys.stdout.write(json.dumps(obj, separators=(",", ":")) + "\n")
+    sys.stdout.flush()
+
+
+def log(msg: str) -> None:
+    sys.stderr.write("openai_agent: " + msg + "\n")
+    sys.stderr.flush()
+
+
+def gateway() -> str:
+    """Returns the default gateway, i.e. the host running the container."""
+    try:
+        with open("/proc/net/route") as f:
+            for line in f.readlines()[1:]:
+                fields = line.split()
+                if len(fields) > 2 and fields[1] == "00000000":
+                    return socket.inet_ntoa(struct.pack("<L", int(fields[2], 16)))
+    except OSError:
+        pass
+    return ""
+
+
+def container_url(url: str) -> str:
+    """Rewrites a loopback URL to reach the host from inside the container."""
+    u = urllib.parse.urlsplit(url)
+    if u.hostname in ("localhost", "127.0.0.1", "::1"):
+        gw = gateway()
+        if gw:
+            netloc = gw + (":%d" % u.port if u.port else "")
+            return urllib.parse.urlunsplit(u._replace(netloc=netloc))
+    return url
+
+
+def resolve(cwd: str, path: str) -> str:
+    return os.path.join(cwd, os.path.expanduser(path))
+
+
+def truncate(s: str) -> str:
+    if len(s) > MAX_OUTPUT:
+        return s[:MAX_OUTPUT] + "\n[truncated %d bytes]" % (len(s) - MAX_OUTPUT)
+    return s
+
+
+def run_tool(cwd: str, name: str, args: dict) -> tuple:
+    """Runs a tool and returns (output, is_error)."""
+    try:
+        if name == "Bash":
+            timeout = args.get("timeout") or 120000
+            p = subprocess.run(
+                ["bash", "-c", args["command"]],
+                cwd=cwd,
+                stdin=subprocess.DEVNULL,
+                stdout=subprocess.PIPE,
+                stderr=subprocess.STDOUT,
+                timeout=timeout / 1000,
+            )
+            out = p.stdout.decode("utf-8", "replace")
+            if p.returncode:
+                return truncate(out + "\nexit status %d" % p.returncode), True
+            return truncate(out), False
+        if name == "Read":
+            with open(resolve(cwd, args["file_path"]), encoding="utf-8", errors="replace") as f:
+                lines = f.read().splitlines()
+            start = max(int(args.get("offset") or 1), 1)
+            end = start - 1 + int(args.get("limit") or 2000)
+            return truncate("\n".join("%6d\t%s" % (i, lines[i - 1]) for i in range(start, min(end, len(lines)) + 1))), False
+        if name == "Glob":
+            matches = sorted(glob.glob(resolve(cwd, args["pattern"]), recursive=True))
+            return truncate("\n".join(matches) or "no match"), False
+        if name == "Write":
+            path = resolve(cwd, args["file_path"])
+            os.makedirs(os.path.dirname(path), exist_ok=True)
+            with open(path, "w", encoding="utf-8") as f:
+                f.write(args["content"])
+            return "wrote " + path, False
+        if name == "Edit":
+            path = resolve(cwd, args["file_path"])
+            with open(path, encoding="utf-8") as f:
+                content = f.read()
+            n = content.count(args["old_string"])
+            if n == 0:
+                return "old_string not found in " + path, True
+            if n > 1 and not args.get("replace_all"):
+                return "old_string is not unique in %s (%d matches)" % (path, n), True
+            content = content.replace(args["old_string"], args["new_string"], -1 if args.get("replace_all") else 1)
+            with open(path, "w", encoding="utf-8") as f:
+                f.write(content)
+            return "edited " + path, False
+    except subprocess.TimeoutExpired:
+        return "command timed out", True
+    except (OSError, KeyError, ValueError, TypeError) as e:
+        return "%s: %s" % (type(e).__name__, e), True
+    return "unknown tool " + name, True
+
+
+def chat(url: str, key: str, model: str, messages: list, tools: list) -> tuple:
+    """Streams a chat completion. Returns (text, tool_calls, usage)."""
+    body = {
+        "model": model,
+        "messages": messages,
+        "tools": [
+            {"type": "function", "function": {"name": t["name"], "description": t["description"], "parameters": t["parameters"]}}
+            for t in tools
+        ],
+        "stream": True,
+        "stream_options": {"include_usage": True},
+    }
+    headers = {"Content-Type": "application/json"}
+    if key:
+        headers["Authorization"] = "Bearer " + key
+    req = urllib.request.Request(url.rstrip("/") + "/chat/completions", data=json.dumps(body).encode(), headers=headers)
+    text = ""
+    calls = {}
+    usage = {}
+    with urllib.request.urlopen(req, timeout=600) as resp:
+        for raw in resp:
+            line = raw.decode("utf-8", "replace").strip()
+            if not line.startswith("data:"):
+                continue
+            data = line[5:].strip()
+            if data == "[DONE]":
+                break
+            chunk = json.loads(data)
+            if chunk.get("usage"):
+                usage = chunk["usage"]
+            for choice in chunk.get("choices") or []:
+                delta = choice.get("delta") or {}
+                if delta.get("content"):
+                    text += delta["content"]
+                    emit(
+                        {
+                            "type": "stream_event",
+                            "event": {
+                                "type": "content_block_delta",
+                                "index": 0,
+                                "delta": {"type": "text_delta", "text": delta["content"]},
+                            },
+                        }
+                    )
+                for tc in delta.get("tool_calls") or []:
+                    c = calls.setdefault(tc.get("index", len(calls)), {"id": "", "name": "", "arguments": ""})
+                    c["id"] = tc.get("id") or c["id"]
+                    fn = tc.get("function") or {}
+                    c["name"] += fn.get("name") or ""
+                    c["arguments"] += fn.get("arguments") or ""
+    tool_calls = []
+    for i in sorted(calls):
+        c = calls[i]
+        tool_calls.append(
+            {
+                "id": c["id"] or "call_" + uuid.uuid4().hex[:24],
+                "type": "function",
+                "function": {"name": c["name"], "arguments": c["arguments"] or "{}"},
+            }
+        )
+    return text, tool_calls, usage
+
+
+class Session:
+    def __init__(self, args: argparse.Namespace) -> None:
+        # A missing endpoint fails every prompt rather than the process, so
+        # the user sees why.
+        self.error = ""
+        self.url = self.key = ""
+        try:
+            with open(args.endpoint_file) as f:
+                endpoint = json.load(f)
+            self.url = container_url(endpoint["base_url"])
+            self.key = endpoint.get("api_key", "")
+        except (OSError, ValueError, KeyError) as e:
+            self.error = "no endpoint configured: %s" % e
+        self.model = args.model
+        self.cwd = os.getcwd()
+        self.tools = [t for t in TOOLS if not (args.read_only and t["writes"])]
+        self.id = args.resume or str(uuid.uuid4())
+        self.messages = []
+        if args.resume:
+            try:
+                with open(self.path()) as f:
+                    self.messages = json.load(f)
+            except (OSError, ValueError) as e:
+                log("cannot resume %s: %s" % (args.resume, e))
+        if not self.messages:
+            self.messages = [{"role": "system", "content": SYSTEM_PROMPT.format(cwd=self.cwd)}]
+
+    def path(self) -> str:
+        return os.path.join(SESSIONS_DIR, self.id + ".json")
+
+    def save(self) -> None:
+        os.makedirs(SESSIONS_DIR, exist_ok=True)
+        tmp = self.path() + ".tmp"
+        with open(tmp, "w") as f:
+            json.dump(self.messages, f)
+        os.replace(tmp, self.path())
+
+    def turn(self, prompt: str) -> None:
+        start = time.monotonic()
+        api = 0.0
+        total = {"input_tokens": 0, "output_tokens": 0}
+        self.messages.append({"role": "user", "content": prompt})
+        text = ""
+        steps = 0
+        error = self.error
+        while not error and steps < MAX_STEPS:
+            steps += 1
+            t = time.monotonic()
+            try:
+                text, tool_calls, usage = chat(self.url, self.key, self.model, self.messages, self.tools)
+            except (OSError, ValueError, urllib.error.URLError) as e:
+                error = "%s: %s" % (self.url, e)
+                break
+            api += time.monotonic() - t
+            u = {"input_tokens": usage.get("prompt_tokens", 0), "output_tokens": usage.get("completion_tokens", 0)}
+            total["input_tokens"] += u["input_tokens"]
+            total["output_tokens"] += u["output_tokens"]
+            msg = {"role": "assistant", "content": text}
+            if tool_calls:
+                msg["tool_calls"] = tool_calls
+            self.messages.append(msg)
+            self.save()
+            content = [{"type": "text", "text": text}] if text else []
+            for tc in tool_calls:
+                try:
+                    args = json.loads(tc["function"]["arguments"])
+                except ValueError:
+                    args = {}
+                tc["parsed"] = args
+                content.append({"type": "tool_use", "id": tc["id"], "name": tc["function"]["name"], "input": args})
+            emit(
+                {
+                    "type": "assistant",
+                    "session_id": self.id,
+                    "uuid": str(uuid.uuid4()),
+                    "message": {
+                        "id": "msg_" + uuid.uuid4().hex[:24],
+                        "role": "assistant",
+                        "model": self.model,
+                        "content": content,
+                        "usage": u,
+                    },
+                }
+            )
+            if not tool_calls:
+                break
+            for tc in tool_calls:
+                name = tc["function"]["name"]
+                if any(t["name"] == name for t in self.tools):
+                    out, is_error = run_tool(self.cwd, name, tc.pop("parsed"))
+                else:
+                    tc.pop("parsed")
+                    out, is_error = "unknown tool " + name, True
+                self.messages.append({"role": "tool", "tool_call_id": tc["id"], "content": out})
+                emit(
+                    {
+                        "type": "user",
+                        "session_id": self.id,
+                        "message": {
+                            "role": "user",
+                            "content": [
+                                {
+                                    "type": "tool_result",
+                                    "tool_use_id": tc["id"],
+                                    "content": [{"type": "text", "text": out}],
+                                    "is_error": is_error,
+                                }
+                            ],
+                        },
+                    }
+                )
+            self.save()
+        else:
+            error = "stopped after %d steps" % MAX_STEPS
+        # Local inference is free: only the token counts are reported.
+        emit(
+            {
+                "type": "result",
+                "subtype": "error_during_execution" if error else "success",
+                "is_error": bool(error),
+                "result": error or text,
+                "num_turns": steps,
+                "session_id": self.id,
+                "total_cost_usd": 0,
+                "duration_ms": int((time.monotonic() - start) * 1000),
+                "duration_api_ms": int(api * 1000),
+                "usage": total,
+            }
+        )
+
+
+def prompt_text(line: str) -> str:
+    """Extracts the text of a Claude Code stream-json user message."""
+    try:
+        msg = json.loads(line)
+    except ValueError:
+        return line
+    content = (msg.get("message") or {}).get("content")
+    if isinstance(content, str):
+        return content
+    return "\n".join(b.get("text", "") for b in content or [] if b.get("type") == "text")
+
+
+def main() -> None:
+    p = argparse.ArgumentParser()
+    p.add_argument("--endpoint-file", required=True, help="JSON file with base_url and api_key")
+    p.add_argument("--model", required=True)
+    p.add_argument("--resume", default="")
+    p.add_argument("--read-only", action="store_true")
+    args = p.parse_args()
+    s = Session(args)
+    emit(
+        {
+            "type": "system",
+            "subtype": "init",
+            "session_id": s.id,
+            "cwd": s.cwd,
+            "model": s.model,
+            "tools": [t["name"] for t in s.tools],
+        }
+    )
+    for line in sys.stdin:
+        line = line.strip()
+        if line:
+            text = prompt_text(line)
+            if text:
+                s.turn(text)
+
+
+if __name__ == "__main__":
+    main()
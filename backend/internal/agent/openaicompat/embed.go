@@ -0,0 +1,10 @@
+// Package openaicompat embeds the agent script driving OpenAI-compatible APIs.
+package openaicompat
+
+import _ "embed"
+
+// AgentScript is the Python agent that runs a tool loop against an
+// OpenAI-compatible chat completions endpoint and emits Claude Code NDJSON.
+//
+//go:embed agent.py
+var AgentScript []byte
@@ -0,0 +1,54 @@
+// Tests for model discovery from OpenAI-compatible APIs.
+package openaicompat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+func TestListModels(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/models" {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(`{"object":"list","data":[{"id":"qwen2.5-coder:7b","object":"model"},{"id":"llama3.1:8b"},{"id":""}]}`))
+		}))
+		defer srv.Close()
+		b := NewLocal(srv.URL + "/v1/")
+		if err := b.RefreshModels(t.Context()); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := b.Models(), []string{"llama3.1:8b", "qwen2.5-coder:7b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+	t.Run("APIKey", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer sk-test" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":[{"id":"m"}]}`))
+		}))
+		defer srv.Close()
+		got, err := ListModels(t.Context(), srv.Client(), &agent.Endpoint{BaseURL: srv.URL, APIKey: "sk-test"})
+		if err != nil || !reflect.DeepEqual(got, []string{"m"}) {
+			t.Fatal(got, err)
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+		if _, err := ListModels(t.Context(), srv.Client(), &agent.Endpoint{BaseURL: srv.URL}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,190 @@
+// Package openaicompat implements agent.Backend for harnesses without a CLI
+// of their own, driving any OpenAI-compatible chat completions API:
+//
+//   - "local": a local inference server such as Ollama or llama.cpp, so tasks
+//     can run without any cloud service. Local inference is free, so results
+//     carry token counts and a zero cost.
+//   - "generic": a provider configured by the user, e.g. one we use that has
+//     no dedicated CLI. The endpoint comes from agent.Options.Endpoint.
+//
+// An embedded Python agent (agent.py) runs the tool loop against the API's
+// /chat/completions endpoint and emits Claude Code stream-json, which is
+// parsed by the claudecode parser.
+package openaicompat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/agent/claudecode"
+)
+
+const (
+	agentScriptPath = agent.RelayDir + "/openai_agent.py"
+	// endpointDir holds the endpoint in the container. It lives outside the
+	// relay directory so a restarted container can resume without the server
+	// resending the endpoint. md containers run as user in /home/user.
+	endpointDir  = "/home/user/.config/caic-agent"
+	endpointPath = endpointDir + "/endpoint.json"
+)
+
+// Backend implements agent.Backend for an OpenAI-compatible API.
+type Backend struct {
+	agent.Base
+	// URL is the API base URL of a local harness as seen from the host, e.g.
+	// "http://localhost:11434/v1". The agent rewrites a loopback host to the
+	// container's gateway, so the server must listen on that interface. Empty
+	// for the generic harness.
+	URL      string
+	modelsMu sync.RWMutex
+}
+
+var _ agent.Backend = (*Backend)(nil)
+
+// NewLocal creates the local harness for the inference server at url. The
+// model list is empty until RefreshModels succeeds.
+func NewLocal(url string) *Backend {
+	b := &Backend{URL: url}
+	b.Base = agent.Base{
+		HarnessID:  agent.Local,
+		BinaryName: "python3",
+		// Local models are usually served with a small context; the real
+		// limit depends on the server's configuration.
+		ContextWindow: 32_768,
+		Wire:          newWire(),
+	}
+	return b
+}
+
+// NewGeneric creates the generic harness. Its endpoint and model are
+// configured per user and passed in agent.Options.
+func NewGeneric() *Backend {
+	b := &Backend{}
+	b.Base = agent.Base{
+		HarnessID:     agent.Generic,
+		BinaryName:    "python3",
+		ContextWindow: 128_000,
+		Wire:          newWire(),
+	}
+	return b
+}
+
+// Models returns the available model list (thread-safe).
+func (b *Backend) Models() []string {
+	b.modelsMu.RLock()
+	defer b.modelsMu.RUnlock()
+	return b.ModelList
+}
+
+// SetModels replaces the model list (thread-safe).
+func (b *Backend) SetModels(models []string) {
+	b.modelsMu.Lock()
+	defer b.modelsMu.Unlock()
+	b.ModelList = models
+}
+
+// RefreshModels replaces the model list with the models the local server
+// serves.
+func (b *Backend) RefreshModels(ctx context.Context) error {
+	if b.URL == "" {
+		return errors.New("no fixed endpoint to list models from")
+	}
+	models, err := ListModels(ctx, nil, &agent.Endpoint{BaseURL: b.URL})
+	if err != nil {
+		return err
+	}
+	b.SetModels(models)
+	return nil
+}
+
+// NewParser implements agent.Backend.
+func (*Backend) NewParser() func([]byte) ([]agent.Message, error) {
+	return claudecode.New().NewParser()
+}
+
+// Start deploys the agent script and the endpoint, then launches the agent
+// via relay serve-attach. Without an endpoint, e.g. for a task restored
+// after a server restart, the agent reuses the one deployed previously.
+func (b *Backend) Start(ctx context.Context, opts *agent.Options, msgCh chan<- agent.Message, logW io.Writer) (*agent.Session, error) {
+	ep := opts.Endpoint
+	if b.URL != "" {
+		ep = &agent.Endpoint{BaseURL: b.URL}
+	}
+	model := opts.Model
+	if model == "" {
+		models := b.Models()
+		if len(models) == 0 {
+			return nil, fmt.Errorf("%s harness: no model configured", b.HarnessID)
+		}
+		model = models[0]
+	}
+	if err := deploy(ctx, opts.Container, ep); err != nil {
+		return nil, err
+	}
+	return agent.StartRelay(ctx, opts, buildArgs(model, opts), msgCh, logW, newWire())
+}
+
+// deploy uploads the agent script into the container, and the endpoint when
+// ep is not nil. Idempotent.
+func deploy(ctx context.Context, container string, ep *agent.Endpoint) error {
+	cmd := exec.CommandContext(ctx, "ssh", container, //nolint:gosec // container is not user-controlled
+		"mkdir -p "+agent.RelayDir+" && cat > "+agentScriptPath)
+	cmd.Stdin = bytes.NewReader(AgentScript)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("deploy agent: %w: %s", err, out)
+	}
+	if ep == nil {
+		return nil
+	}
+	data, err := json.Marshal(map[string]string{"base_url": ep.BaseURL, "api_key": ep.APIKey})
+	if err != nil {
+		return err
+	}
+	// The key is sent on stdin so that it never appears in a command line.
+	cmd = exec.CommandContext(ctx, "ssh", container, //nolint:gosec // container is not user-controlled
+		"umask 077 && mkdir -p "+endpointDir+" && cat > "+endpointPath)
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("deploy endpoint: %w: %s", err, out)
+	}
+	return nil
+}
+
+// buildArgs constructs the command to run the agent script.
+func buildArgs(model string, opts *agent.Options) []string {
+	args := []string{"python3", "-u", agentScriptPath, "--endpoint-file", endpointPath, "--model", model}
+	if opts.ResumeSessionID != "" {
+		args = append(args, "--resume", opts.ResumeSessionID)
+	}
+	if opts.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	return args
+}
+
+// wireFormat speaks Claude Code's stream-json protocol, like the agent
+// script, without claiming support for /compact.
+type wireFormat struct {
+	parse func([]byte) ([]agent.Message, error)
+}
+
+func newWire() *wireFormat {
+	return &wireFormat{parse: claudecode.New().NewParser()}
+}
+
+// WritePrompt implements agent.WireFormat.
+func (*wireFormat) WritePrompt(w io.Writer, p agent.Prompt, logW io.Writer) error {
+	return claudecode.Wire.WritePrompt(w, p, logW)
+}
+
+// ParseMessage implements agent.WireFormat.
+func (f *wireFormat) ParseMessage(line []byte) ([]agent.Message, error) {
+	return f.parse(line)
+}
@@ -0,0 +1,61 @@
+// Tests for the OpenAI-compatible backends.
+package openaicompat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+func TestParser(t *testing.T) {
+	// Output of agent.py for a prompt that ran one command.
+	lines := []string{
+		`{"type":"system","subtype":"init","session_id":"s1","cwd":"/home/user/src/repo","model":"qwen2.5-coder:7b","tools":["Bash","Read","Glob","Write","Edit"]}`,
+		`{"type":"assistant","session_id":"s1","uuid":"u1","message":{"id":"msg_1","role":"assistant","model":"qwen2.5-coder:7b","content":[{"type":"tool_use","id":"c1","name":"Bash","input":{"command":"echo hi"}}],"usage":{"input_tokens":10,"output_tokens":3}}}`,
+		`{"type":"user","session_id":"s1","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"c1","content":[{"type":"text","text":"hi\n"}],"is_error":false}]}}`,
+		`{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Done"}}}`,
+		`{"type":"assistant","session_id":"s1","uuid":"u2","message":{"id":"msg_2","role":"assistant","model":"qwen2.5-coder:7b","content":[{"type":"text","text":"Done"}],"usage":{"input_tokens":20,"output_tokens":1}}}`,
+		`{"type":"result","subtype":"success","is_error":false,"result":"Done","num_turns":2,"session_id":"s1","total_cost_usd":0,"duration_ms":8,"duration_api_ms":5,"usage":{"input_tokens":30,"output_tokens":4}}`,
+	}
+	parse := NewLocal("http://localhost:11434/v1").NewParser()
+	var got []string
+	var result *agent.ResultMessage
+	for _, l := range lines {
+		msgs, err := parse([]byte(l))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range msgs {
+			got = append(got, strings.TrimPrefix(reflect.TypeOf(m).String(), "*agent."))
+			if r, ok := m.(*agent.ResultMessage); ok {
+				result = r
+			}
+		}
+	}
+	want := []string{"InitMessage", "ToolUseMessage", "UsageMessage", "ToolResultMessage", "TextDeltaMessage", "TextMessage", "UsageMessage", "ResultMessage"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if result == nil || result.TotalCostUSD != 0 || result.Usage.InputTokens != 30 || result.Usage.OutputTokens != 4 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	got := buildArgs("llama3.1:8b", &agent.Options{ResumeSessionID: "s1", ReadOnly: true})
+	want := []string{"python3", "-u", agentScriptPath, "--endpoint-file", endpointPath, "--model", "llama3.1:8b", "--resume", "s1", "--read-only"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestStart(t *testing.T) {
+	t.Run("NoModel", func(t *testing.T) {
+		_, err := NewLocal("http://localhost:11434/v1").Start(t.Context(), &agent.Options{Container: "c", Dir: "/home/user/src/r"}, nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "no model") {
+			t.Fatal(err)
+		}
+	})
+}
@@ -42,6 +42,7 @@ func (*Backend) NewParser() func([]byte) ([]agent.Message, error) {
 func New() *Backend {
 	return &Backend{Base: agent.Base{
 		HarnessID:     agent.OpenCode,
+		BinaryName:    "opencode",
 		ModelList:     []string{"anthropic/claude-sonnet-4"},
 		Images:        true,
 		ContextWindow: 200_000,
@@ -126,6 +127,7 @@ func (b *Backend) Start(ctx context.Context, opts *agent.Options, msgCh chan<- a
 		}
 	}
 
+	s.PrependInstructions(opts)
 	if opts.InitialPrompt.Text != "" || len(opts.InitialPrompt.Images) > 0 {
 		if err := s.Send(opts.InitialPrompt); err != nil {
 			s.Close()
@@ -156,6 +158,15 @@ type caicInit struct {
 	Version   string `json:"version,omitzero"`
 }
 
+// SupportsInterrupt implements agent.Backend. The per-session wireFormat
+// implements agent.Interrupter.
+func (b *Backend) SupportsInterrupt() bool { return true }
+
+// cancelParams holds the params of the session/cancel notification.
+type cancelParams struct {
+	SessionID string `json:"sessionId"`
+}
+
 // wireFormat implements agent.WireFormat for the ACP JSON-RPC protocol.
 // It holds per-session state: the session ID, a request ID counter,
 // accumulated token usage, and image support flag.
@@ -217,6 +228,21 @@ func (w *wireFormat) WriteCompact(wr io.Writer, _ string, logW io.Writer) error
 	return w.WritePrompt(wr, agent.Prompt{Text: "/compact"}, logW)
 }
 
+// WriteInterrupt implements agent.Interrupter by sending a session/cancel
+// notification. The pending session/prompt request then completes with
+// stopReason "cancelled".
+func (w *wireFormat) WriteInterrupt(wr io.Writer, _ io.Writer) error {
+	if w.sessionID == "" {
+		return errors.New("opencode: no session ID (handshake not completed)")
+	}
+	req := oc.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  oc.MethodSessionCancel,
+		Params:  cancelParams{SessionID: w.sessionID},
+	}
+	return writeJSON(wr, req)
+}
+
 // ParseMessage wraps the package-level parseMessage with interceptions:
 //
 //   - usage_update → emits UsageMessage and accumulates into totalUsage.
@@ -304,6 +330,7 @@ func (w *wireFormat) handlePromptResponseLocked(line []byte) ([]agent.Message, e
 	if resp.Error != nil {
 		rm.IsError = true
 		rm.Result = resp.Error.Message
+		rm.RateLimited = agent.IsRateLimitError(rm.Result)
 	} else if resp.Result != nil {
 		var pr oc.PromptResult
 		if err := json.Unmarshal(resp.Result, &pr); err == nil {
@@ -202,10 +202,12 @@ func parseToolCall(data json.RawMessage, fw *jsonutil.FieldWarner) ([]agent.Mess
 		return []agent.Message{agent.NewWidgetMessage(u.ToolCallID, u.RawInput)}, nil
 	}
 
+	name := normalizeToolName(u.Title, u.Kind)
 	return []agent.Message{&agent.ToolUseMessage{
 		ToolUseID: u.ToolCallID,
-		Name:      normalizeToolName(u.Title, u.Kind),
+		Name:      name,
 		Input:     u.RawInput,
+		Call:      agent.NewToolCall(name, u.RawInput),
 	}}, nil
 }
 
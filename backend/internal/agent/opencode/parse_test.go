@@ -1,6 +1,7 @@
 package opencode
 
 import (
+	"bytes"
 	"encoding/json"
 	"testing"
 
@@ -805,6 +806,18 @@ func TestWireFormatPromptResponse(t *testing.T) {
 	})
 }
 
+func TestWireFormatInterrupt(t *testing.T) {
+	w := &wireFormat{sessionID: "ses_1"}
+	var buf bytes.Buffer
+	if err := w.WriteInterrupt(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	const want = `{"jsonrpc":"2.0","method":"session/cancel","params":{"sessionId":"ses_1"}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
 // mustJSON marshals v to []byte, failing the test on error.
 func mustJSON(t *testing.T, v any) []byte {
 	t.Helper()
@@ -0,0 +1,14 @@
+// Detection of the provider rate-limit and overload errors ending a turn.
+package agent
+
+import "regexp"
+
+// rateLimitRe matches the error messages of the providers when they throttle:
+// HTTP 429 and Anthropic's 529, and their textual forms.
+var rateLimitRe = regexp.MustCompile(`(?i)\b(429|529)\b|rate[ _-]?limit|too many requests|overloaded|resource[ _]exhausted|usage limit|quota exceeded`)
+
+// IsRateLimitError reports whether msg, the error of a failed turn, says that
+// the provider throttled the request. Such turns can be retried later.
+func IsRateLimitError(msg string) bool {
+	return rateLimitRe.MatchString(msg)
+}
@@ -0,0 +1,29 @@
+// Tests for the rate-limit error detection.
+package agent
+
+import "testing"
+
+func TestIsRateLimitError(t *testing.T) {
+	for _, msg := range []string{
+		`API Error: 429 {"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your per-minute rate limit"}}`,
+		`API Error: 529 {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+		"You've hit your usage limit. Upgrade to Pro or try again in 2 hours.",
+		"RESOURCE_EXHAUSTED: Quota exceeded for quota metric",
+		"Too Many Requests",
+	} {
+		if !IsRateLimitError(msg) {
+			t.Errorf("%q: not detected", msg)
+		}
+	}
+	for _, msg := range []string{
+		"",
+		"Model not found: gpt-9",
+		"exit status 1",
+		"cancelled",
+		"wrote 14290 bytes",
+	} {
+		if IsRateLimitError(msg) {
+			t.Errorf("%q: false positive", msg)
+		}
+	}
+}
@@ -47,6 +47,7 @@ RELAY_DIR = os.environ.get("CAIC_RELAY_DIR", "/tmp/caic-relay")
 SOCK_PATH = os.path.join(RELAY_DIR, "relay.sock")
 OUTPUT_PATH = os.path.join(RELAY_DIR, "output.jsonl")
 PID_PATH = os.path.join(RELAY_DIR, "pid")
+CONSOLE_PATH = os.path.join(RELAY_DIR, "console.log")
 
 # Max size of a single read from subprocess stdout.
 BUF_SIZE = 65536
@@ -192,12 +193,15 @@ def serve(cmd_args, work_dir, log_stdin=True):
     )
     logging.info("subprocess started pid=%d", proc.pid)
 
-    # Drain subprocess stderr to relay log so bridge diagnostics are visible.
+    # Drain subprocess stderr to relay log so bridge diagnostics are visible,
+    # and verbatim to the console log, which the server streams to users.
     def _drain_stderr():
-        for raw in proc.stderr:
-            line = raw.decode("utf-8", errors="replace").rstrip()
-            if line:
-                logging.info("bridge: %s", line)
+        with open(CONSOLE_PATH, "ab", buffering=0) as console:
+            for raw in proc.stderr:
+                console.write(raw)
+                line = raw.decode("utf-8", errors="replace").rstrip()
+                if line:
+                    logging.info("bridge: %s", line)
 
     threading.Thread(target=_drain_stderr, daemon=True).start()
 
@@ -243,6 +243,44 @@ def test_ssh_drop_keeps_subprocess():
         _cleanup(relay_dir)
 
 
+def test_console_log():
+    """The subprocess stderr is copied verbatim to console.log."""
+    relay_dir = tempfile.mkdtemp(prefix="caic-relay-test-")
+    env = _make_env(relay_dir)
+    console_path = os.path.join(relay_dir, "console.log")
+
+    try:
+        proc = subprocess.Popen(
+            [sys.executable, RELAY_PY, "serve-attach", "--dir", relay_dir, "--",
+             "/bin/sh", "-c", "echo 'npm WARN deprecated' >&2; cat >/dev/null"],
+            stdin=subprocess.PIPE,
+            stdout=subprocess.PIPE,
+            stderr=subprocess.PIPE,
+            env=env,
+        )
+        deadline = time.monotonic() + 5
+        content = b""
+        while time.monotonic() < deadline:
+            if os.path.exists(console_path):
+                with open(console_path, "rb") as f:
+                    content = f.read()
+                if content:
+                    break
+            time.sleep(0.05)
+        assert content == b"npm WARN deprecated\n", f"console.log = {content!r}"
+
+        proc.stdin.write(b"\x00")
+        proc.stdin.flush()
+        proc.stdin.close()
+        proc.wait(timeout=10)
+    finally:
+        try:
+            proc.kill()
+        except OSError:
+            pass
+        _cleanup(relay_dir)
+
+
 def test_parse_numstat():
     """Test _parse_numstat parses git diff --numstat output correctly."""
     # Import the module under test.
@@ -292,4 +330,8 @@ if __name__ == "__main__":
     test_ssh_drop_keeps_subprocess()
     print("OK")
 
+    print("test_console_log...", end=" ", flush=True)
+    test_console_log()
+    print("OK")
+
     print("All tests passed.")
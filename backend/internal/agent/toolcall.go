@@ -0,0 +1,99 @@
+// Harness-neutral description of tool calls.
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// ToolKind classifies what a tool call does, independently of the harness's
+// tool names.
+type ToolKind string
+
+// Tool kinds.
+const (
+	ToolShell     ToolKind = "shell"     // Runs a command.
+	ToolRead      ToolKind = "read"      // Reads files.
+	ToolWrite     ToolKind = "write"     // Creates or overwrites files.
+	ToolEdit      ToolKind = "edit"      // Modifies files.
+	ToolSearch    ToolKind = "search"    // Lists or searches files.
+	ToolFetch     ToolKind = "fetch"     // Fetches a URL.
+	ToolWebSearch ToolKind = "webSearch" // Searches the web.
+	ToolAgent     ToolKind = "agent"     // Starts a sub-agent.
+	ToolOther     ToolKind = "other"     // Anything else, e.g. MCP tools.
+)
+
+// ToolCall is the harness-neutral form of a tool call's input, so that
+// clients need not know each harness's input shapes. Only the fields relevant
+// to Kind are set.
+type ToolCall struct {
+	Kind        ToolKind `json:"kind"`
+	Command     string   `json:"command,omitempty"`     // ToolShell.
+	Paths       []string `json:"paths,omitempty"`       // Files for ToolRead, ToolWrite and ToolEdit; the searched directory for ToolSearch.
+	Pattern     string   `json:"pattern,omitempty"`     // ToolSearch: glob or regexp.
+	URL         string   `json:"url,omitempty"`         // ToolFetch.
+	Query       string   `json:"query,omitempty"`       // ToolWebSearch.
+	Description string   `json:"description,omitempty"` // ToolAgent.
+}
+
+// toolKinds maps lowercase tool names, as normalized by the parsers, to their
+// kind.
+var toolKinds = map[string]ToolKind{
+	"bash":          ToolShell,
+	"shell":         ToolShell,
+	"read":          ToolRead,
+	"notebookread":  ToolRead,
+	"write":         ToolWrite,
+	"edit":          ToolEdit,
+	"multiedit":     ToolEdit,
+	"notebookedit":  ToolEdit,
+	"glob":          ToolSearch,
+	"grep":          ToolSearch,
+	"ls":            ToolSearch,
+	"listdirectory": ToolSearch,
+	"webfetch":      ToolFetch,
+	"websearch":     ToolWebSearch,
+	"task":          ToolAgent,
+	"agent":         ToolAgent,
+}
+
+// NewToolCall describes the call of tool name with input. It understands the
+// input keys of the supported harnesses, e.g. file_path (Claude Code),
+// filePath (OpenCode) and absolute_path (Gemini CLI). Parsers whose input
+// doesn't follow these conventions build the ToolCall themselves.
+func NewToolCall(name string, input json.RawMessage) *ToolCall {
+	var in map[string]json.RawMessage
+	_ = json.Unmarshal(input, &in)
+	str := func(keys ...string) string {
+		for _, k := range keys {
+			var s string
+			if json.Unmarshal(in[k], &s) == nil && s != "" {
+				return s
+			}
+		}
+		return ""
+	}
+	c := &ToolCall{Kind: toolKinds[strings.ToLower(name)]}
+	switch c.Kind {
+	case ToolShell:
+		c.Command = str("command", "cmd")
+	case ToolRead, ToolWrite, ToolEdit:
+		if p := str("file_path", "filePath", "absolute_path", "notebook_path", "path"); p != "" {
+			c.Paths = []string{p}
+		}
+	case ToolSearch:
+		c.Pattern = str("pattern", "query")
+		if p := str("path", "dir_path", "directory"); p != "" {
+			c.Paths = []string{p}
+		}
+	case ToolFetch:
+		c.URL = str("url")
+	case ToolWebSearch:
+		c.Query = str("query")
+	case ToolAgent:
+		c.Description = str("description", "prompt")
+	default:
+		c.Kind = ToolOther
+	}
+	return c
+}
@@ -0,0 +1,32 @@
+// Tests for the harness-neutral description of tool calls.
+package agent
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNewToolCall(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+		want  ToolCall
+	}{
+		{"Bash", `{"command":"go test ./...","description":"Run tests"}`, ToolCall{Kind: ToolShell, Command: "go test ./..."}},
+		{"Read", `{"file_path":"/src/a.go","limit":10}`, ToolCall{Kind: ToolRead, Paths: []string{"/src/a.go"}}},
+		{"Edit", `{"filePath":"/src/a.go","oldString":"a","newString":"b"}`, ToolCall{Kind: ToolEdit, Paths: []string{"/src/a.go"}}},
+		{"Read", `{"absolute_path":"/src/b.go"}`, ToolCall{Kind: ToolRead, Paths: []string{"/src/b.go"}}},
+		{"NotebookEdit", `{"notebook_path":"/n.ipynb"}`, ToolCall{Kind: ToolEdit, Paths: []string{"/n.ipynb"}}},
+		{"Grep", `{"pattern":"func main","path":"/src"}`, ToolCall{Kind: ToolSearch, Pattern: "func main", Paths: []string{"/src"}}},
+		{"WebFetch", `{"url":"https://example.com"}`, ToolCall{Kind: ToolFetch, URL: "https://example.com"}},
+		{"WebSearch", `{"query":"go 1.26"}`, ToolCall{Kind: ToolWebSearch, Query: "go 1.26"}},
+		{"Agent", `{"description":"Explore","prompt":"Find X"}`, ToolCall{Kind: ToolAgent, Description: "Explore"}},
+		{"mcp__github__get_issue", `{"number":1}`, ToolCall{Kind: ToolOther}},
+		{"Bash", `["not","an","object"]`, ToolCall{Kind: ToolShell}},
+	} {
+		if got := NewToolCall(tc.name, json.RawMessage(tc.input)); !reflect.DeepEqual(*got, tc.want) {
+			t.Errorf("%s %s: got %+v, want %+v", tc.name, tc.input, *got, tc.want)
+		}
+	}
+}
@@ -12,21 +12,37 @@ type Harness string
 
 // Supported agent harnesses.
 const (
+	Aider    Harness = "aider"
 	Claude   Harness = "claude"
 	Codex    Harness = "codex"
+	Generic  Harness = "generic"
 	Gemini   Harness = "gemini"
 	Kilo     Harness = "kilo"
+	Local    Harness = "local"
 	OpenCode Harness = "opencode"
 )
 
 // DiffFileStat describes changes to a single file.
 type DiffFileStat struct {
-	Path    string `json:"path"`
-	Added   int    `json:"added"`
-	Deleted int    `json:"deleted"`
-	Binary  bool   `json:"binary,omitempty"`
+	Path    string         `json:"path"`
+	Added   int            `json:"added"`
+	Deleted int            `json:"deleted"`
+	Binary  bool           `json:"binary,omitempty"`
+	Status  DiffFileStatus `json:"status,omitempty"`   // Empty when unknown, e.g. from the relay's diff_watcher.
+	OldPath string         `json:"old_path,omitempty"` // Source path of a rename.
 }
 
+// DiffFileStatus is how a file changed, from git diff --name-status.
+type DiffFileStatus string
+
+// Supported file statuses.
+const (
+	FileAdded    DiffFileStatus = "added"
+	FileModified DiffFileStatus = "modified"
+	FileDeleted  DiffFileStatus = "deleted"
+	FileRenamed  DiffFileStatus = "renamed"
+)
+
 // DiffStat summarises the changes in a branch relative to its base.
 type DiffStat []DiffFileStat
 
@@ -63,8 +79,9 @@ func (m *SystemMessage) Type() string { return "system" }
 
 // TextMessage is emitted when the agent produces text output.
 type TextMessage struct {
-	Text  string `json:"text"`
-	Phase string `json:"phase,omitempty"` // Codex only: "commentary" | "final_answer" | "".
+	Text            string `json:"text"`
+	Phase           string `json:"phase,omitempty"`              // Codex only: "commentary" | "final_answer" | "".
+	ParentToolUseID string `json:"parent_tool_use_id,omitempty"` // See ToolUseMessage.ParentToolUseID.
 }
 
 // Type implements Message.
@@ -75,8 +92,12 @@ func (m *TextMessage) Type() string { return "text" }
 type ToolUseMessage struct {
 	ToolUseID   string          `json:"id"`
 	Name        string          `json:"name"`
-	Input       json.RawMessage `json:"input,omitempty"`
-	PlanContent string          `json:"-"` // Snapshot of plan content; set by task on ExitPlanMode.
+	Input       json.RawMessage `json:"input,omitempty"` // Raw input, in the harness's format.
+	Call        *ToolCall       `json:"call,omitempty"`  // Normalized Input; set by the parsers.
+	PlanContent string          `json:"-"`               // Snapshot of plan content; set by task on ExitPlanMode.
+	// ParentToolUseID is the ID of the tool call (e.g. Claude Code's Task)
+	// that spawned the sub-agent making this call; empty for the main agent.
+	ParentToolUseID string `json:"parent_tool_use_id,omitempty"`
 }
 
 // Type implements Message.
@@ -113,8 +134,9 @@ func (m *UserInputMessage) Type() string { return "user_input" }
 
 // ToolResultMessage is emitted when a tool returns its result.
 type ToolResultMessage struct {
-	ToolUseID string `json:"tool_use_id"`
-	Error     string `json:"error,omitempty"` // Non-empty when the tool reported an error.
+	ToolUseID       string `json:"tool_use_id"`
+	Error           string `json:"error,omitempty"`              // Non-empty when the tool reported an error.
+	ParentToolUseID string `json:"parent_tool_use_id,omitempty"` // See ToolUseMessage.ParentToolUseID.
 }
 
 // Type implements Message.
@@ -186,6 +208,9 @@ type ResultMessage struct {
 	Usage         Usage    `json:"usage"`
 	UUID          string   `json:"uuid"`
 	DiffStat      DiffStat `json:"diff_stat,omitzero"` // Set by caic after running container diff.
+	// RateLimited is set by the adapters when the turn failed because the
+	// provider throttled it; the turn can be retried later.
+	RateLimited bool `json:"rate_limited,omitempty"`
 }
 
 // Type implements Message.
@@ -203,7 +228,8 @@ func (m *TextDeltaMessage) Type() string { return "text_delta" }
 
 // ThinkingMessage is emitted when the agent produces a thinking block.
 type ThinkingMessage struct {
-	Text string `json:"text"`
+	Text            string `json:"text"`
+	ParentToolUseID string `json:"parent_tool_use_id,omitempty"` // See ToolUseMessage.ParentToolUseID.
 }
 
 // Type implements Message.
@@ -365,6 +391,7 @@ type MetaRepo struct {
 	Name       string `json:"name"`
 	BaseBranch string `json:"base_branch,omitempty"`
 	Branch     string `json:"branch"`
+	PushRemote string `json:"push_remote,omitempty"` // Remote the branch is pushed to; empty means origin.
 }
 
 // MetaMessage is written as the first line of a JSONL log file. It captures
@@ -372,6 +399,8 @@ type MetaRepo struct {
 type MetaMessage struct {
 	MessageType string     `json:"type"`
 	Version     int        `json:"version"`
+	TaskID      string     `json:"task_id,omitempty"` // Persistent ksid task ID; older logs only have it in the filename.
+	Alias       string     `json:"alias,omitempty"`   // Short human-friendly task alias, e.g. "w42".
 	Prompt      string     `json:"prompt"`
 	Title       string     `json:"title,omitempty"`
 	Repos       []MetaRepo `json:"repos"`
@@ -382,6 +411,42 @@ type MetaMessage struct {
 	Tailscale   bool       `json:"tailscale,omitempty"`
 	USB         bool       `json:"usb,omitempty"`
 	Display     bool       `json:"display,omitempty"`
+	PlanOnly    bool       `json:"plan_only,omitempty"`
+	RequirePlan bool       `json:"require_plan,omitempty"`
+	ReadOnly    bool       `json:"read_only,omitempty"`
+	Chat        bool       `json:"chat,omitempty"`
+	Thinking    bool       `json:"thinking,omitempty"`
+	// Network is the container's network mode ("full", "none" or
+	// "allowlist"); empty means full.
+	Network      string   `json:"network,omitempty"`
+	NetworkAllow []string `json:"network_allow,omitempty"`
+	// IdleAction and IdleHours are the task's override of the idle policy;
+	// an empty IdleAction means none.
+	IdleAction string `json:"idle_action,omitempty"`
+	IdleHours  int    `json:"idle_hours,omitempty"`
+	// PushPolicy is the task's override of the push policy ("auto", "ask"
+	// or "never"); empty means none.
+	PushPolicy string `json:"push_policy,omitempty"`
+	// IssueTracker, IssueKey and IssueURL identify the Jira or Linear issue
+	// the task is linked to; an empty IssueKey means none.
+	IssueTracker string `json:"issue_tracker,omitempty"`
+	IssueKey     string `json:"issue_key,omitempty"`
+	IssueURL     string `json:"issue_url,omitempty"`
+	// SlackChannel and SlackThreadTS identify the Slack thread the task
+	// reports to; an empty SlackThreadTS means none.
+	SlackChannel  string `json:"slack_channel,omitempty"`
+	SlackThreadTS string `json:"slack_thread_ts,omitempty"`
+	// Env holds the task's environment variables.
+	Env map[string]string `json:"env,omitempty"`
+	// Instructions are the custom instructions appended to the agent's
+	// system prompt: the repo's, the user's and the task's, merged.
+	Instructions string `json:"instructions,omitempty"`
+	// FailoverHarness and FailoverModel are the harness and model the task
+	// failed over from at session start, FailoverError why; an empty
+	// FailoverHarness means none.
+	FailoverHarness Harness `json:"failover_harness,omitempty"`
+	FailoverModel   string  `json:"failover_model,omitempty"`
+	FailoverError   string  `json:"failover_error,omitempty"`
 }
 
 // Type implements Message.
@@ -437,6 +502,18 @@ type MetaPRMessage struct {
 // Type implements Message.
 func (m *MetaPRMessage) Type() string { return "caic_pr" }
 
+// MetaStateMessage is written to the JSONL log on each task state transition
+// so that the history can be restored on server restart.
+type MetaStateMessage struct {
+	MessageType string  `json:"type"`
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Ts          float64 `json:"ts"` // Unix epoch seconds (ms precision).
+}
+
+// Type implements Message.
+func (m *MetaStateMessage) Type() string { return "caic_state" }
+
 // MarshalMessage serializes a Message to JSON. For RawMessage, returns the
 // original bytes to preserve unknown fields. For typed messages, uses
 // json.Marshal.
@@ -56,7 +56,14 @@ type TaskRequest struct {
 	Repo        string // repo relative path
 	Prompt      string
 	OwnerID     string
-	IssueNumber int // originating issue/PR number for completion comment callbacks
+	IssueNumber int    // originating issue/PR number for completion comment callbacks
+	Branch      string // existing branch to continue, e.g. a PR's head; empty allocates a new one
+	PR          int    // PR the task continues; the task pushes to it instead of opening one
+
+	// SlackChannel and SlackThreadTS identify the Slack thread the task
+	// reports to; empty for tasks not created from Slack.
+	SlackChannel  string
+	SlackThreadTS string
 }
 
 // Commenter posts a comment on an issue or merge request.
@@ -195,6 +195,7 @@ func generateTS(outDir string, _ *docRegistry) error {
     public status: number,
     public code: string,
     public details?: Record<string, unknown>,
+    public params?: Record<string, string>,
   ) {
     super(code);
   }
@@ -212,7 +213,7 @@ function makeRequester(fetchFn: FetchFn) {
     const res = await fetchFn(path, init);
     if (!res.ok) {
       const err = (await res.json()) as ErrorResponse;
-      const e = new APIError(res.status, err.error.code, err.details);
+      const e = new APIError(res.status, err.error.code, err.details, err.error.params);
       e.message = err.error.message;
       throw e;
     }
@@ -343,9 +344,12 @@ var kotlinAliases = []kotlinTypeAlias{
 	{
 		name: "Harness",
 		constants: []kotlinConstant{
+			{"Aider", string(v1.HarnessAider)},
 			{"Claude", string(v1.HarnessClaude)},
 			{"Codex", string(v1.HarnessCodex)},
+			{"Generic", string(v1.HarnessGeneric)},
 			{"Gemini", string(v1.HarnessGemini)},
+			{"Local", string(v1.HarnessLocal)},
 			{"OpenCode", string(v1.HarnessOpenCode)},
 		},
 	},
@@ -738,6 +742,7 @@ class ApiException(
     val code: String,
     message: String,
     val details: Map<String, kotlinx.serialization.json.JsonElement>? = null,
+    val params: Map<String, String>? = null,
 ) : Exception(message)
 
 class ApiClient(
@@ -774,7 +779,7 @@ class ApiClient(
                             try {
                                 val err = json.decodeFromString<ErrorResponse>(responseBody)
                                 cont.resumeWithException(
-                                    ApiException(resp.code, err.error.code, err.error.message, err.details)
+                                    ApiException(resp.code, err.error.code, err.error.message, err.details, err.error.params)
                                 )
                             } catch (_: Exception) {
                                 cont.resumeWithException(
@@ -1039,7 +1044,10 @@ func generateDoc(outDir string, docs *docRegistry) error {
 	b.WriteString("All errors return:\n\n")
 	b.WriteString("```json\n")
 	b.WriteString("{\n")
-	b.WriteString("  \"error\": { \"code\": \"<CODE>\", \"message\": \"...\" },\n")
+	b.WriteString("  \"error\": {\n")
+	b.WriteString("    \"code\": \"<CODE>\", \"message\": \"...\", \"params\": { ... },\n")
+	b.WriteString("    \"fields\": [{ \"field\": \"repos[0].name\", \"rule\": \"required\", \"message\": \"...\" }]\n")
+	b.WriteString("  },\n")
 	b.WriteString("  \"details\": { ... }\n")
 	b.WriteString("}\n")
 	b.WriteString("```\n\n")
@@ -1050,6 +1058,14 @@ func generateDoc(outDir string, docs *docRegistry) error {
 	b.WriteString("| 409 | `CONFLICT` |\n")
 	b.WriteString("| 500 | `INTERNAL_ERROR` |\n\n")
 
+	// Versioning section.
+	b.WriteString("## Versioning\n\n")
+	b.WriteString("Routes are served under `/api/v1/` and every `/api/` response carries an\n")
+	b.WriteString("`API-Version` header. Unversioned paths like `/api/tasks` are deprecated\n")
+	b.WriteString("aliases served by the version the `API-Version` request header asks for, `v1`\n")
+	b.WriteString("by default, with `Deprecation` and `Link: <...>; rel=\"successor-version\"`\n")
+	b.WriteString("headers.\n\n")
+
 	// Types section.
 	b.WriteString("## Types\n\n")
 	for _, t := range discoverDocTypes() {
@@ -1221,9 +1237,12 @@ var swiftAliases = []kotlinTypeAlias{
 	{
 		name: "Harness",
 		constants: []kotlinConstant{
+			{"Aider", string(v1.HarnessAider)},
 			{"Claude", string(v1.HarnessClaude)},
 			{"Codex", string(v1.HarnessCodex)},
+			{"Generic", string(v1.HarnessGeneric)},
 			{"Gemini", string(v1.HarnessGemini)},
+			{"Local", string(v1.HarnessLocal)},
 			{"OpenCode", string(v1.HarnessOpenCode)},
 		},
 	},
@@ -1602,6 +1621,7 @@ public struct ApiError: Error {
     public let code: String
     public let message: String
     public let details: [String: JSONValue]?
+    public var params: [String: String]? = nil
 }
 
 public final class ApiClient {
@@ -1635,7 +1655,7 @@ public final class ApiClient {
         guard (200..<300).contains(httpResponse.statusCode) else {
             if let errResp = try? decoder.decode(ErrorResponse.self, from: data) {
                 throw ApiError(statusCode: httpResponse.statusCode, code: errResp.error.code,
-                               message: errResp.error.message, details: nil)
+                               message: errResp.error.message, details: nil, params: errResp.error.params)
             }
             throw ApiError(statusCode: httpResponse.statusCode, code: "UNKNOWN",
                            message: String(data: data, encoding: .utf8) ?? "", details: nil)
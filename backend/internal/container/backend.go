@@ -5,10 +5,14 @@ package container
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	"github.com/caic-xyz/caic/backend/internal/task"
@@ -25,16 +29,22 @@ type Backend struct {
 	pendingContainers map[string]*md.Container // keyed by container name
 }
 
+// harnessPaths lists the configuration directories to mount for each
+// supported harness, mostly known to md. The OpenAI-compatible harnesses
+// need none.
+var harnessPaths = map[agent.Harness][]md.AgentPaths{
+	agent.Aider:    {{Description: "aider", HomePaths: []string{".aider"}}},
+	agent.Claude:   {md.HarnessMounts[md.HarnessClaude]},
+	agent.Codex:    {md.HarnessMounts[md.HarnessCodex]},
+	agent.Gemini:   {md.HarnessMounts[md.HarnessGemini]},
+	agent.Generic:  nil,
+	agent.Kilo:     {md.HarnessMounts[md.HarnessKilo]},
+	agent.Local:    nil,
+	agent.OpenCode: {md.HarnessMounts[md.HarnessOpencode]},
+}
+
 func (b *Backend) mdStartOpts(labels []string, opts *task.StartOptions) (client *md.Client, mdOpts *md.StartOpts) {
-	harnessMap := map[agent.Harness]md.Harness{
-		agent.Claude:   md.HarnessClaude,
-		agent.Codex:    md.HarnessCodex,
-		agent.Gemini:   md.HarnessGemini,
-		agent.Kilo:     md.HarnessKilo,
-		agent.OpenCode: md.HarnessOpencode,
-	}
-	mdHarness := harnessMap[opts.Harness]
-	harnessPaths := md.HarnessMounts[mdHarness]
+	agentPaths := harnessPaths[opts.Harness]
 	image := opts.DockerImage
 	if image == "" {
 		image = md.DefaultBaseImage + ":latest"
@@ -44,10 +54,11 @@ func (b *Backend) mdStartOpts(labels []string, opts *task.StartOptions) (client
 	if opts.GitHubToken != "" {
 		extraEnv = append(extraEnv, "GITHUB_TOKEN="+opts.GitHubToken)
 	}
+	extraEnv = append(extraEnv, opts.ExtraEnv...)
 	mdOpts = &md.StartOpts{
 		BaseImage:  image,
 		Labels:     labels,
-		AgentPaths: []md.AgentPaths{harnessPaths},
+		AgentPaths: agentPaths,
 		USB:        opts.USB,
 		Tailscale:  opts.Tailscale,
 		Display:    opts.Display,
@@ -59,17 +70,11 @@ func (b *Backend) mdStartOpts(labels []string, opts *task.StartOptions) (client
 // Launch implements task.ContainerBackend.
 func (b *Backend) Launch(ctx context.Context, repos []md.Repo, labels []string, opts *task.StartOptions) (string, error) {
 	if len(repos) > 0 {
-		slog.Info("md", "phase", "launch", "dir", repos[0].GitRoot, "br", repos[0].Branch, "hns", opts.Harness)
+		slog.InfoContext(ctx, "md", "phase", "launch", "dir", repos[0].GitRoot, "br", repos[0].Branch, "hns", opts.Harness)
 	} else {
-		slog.Info("md", "phase", "launch", "hns", opts.Harness)
-	}
-	if _, ok := map[agent.Harness]md.Harness{
-		agent.Claude:   md.HarnessClaude,
-		agent.Codex:    md.HarnessCodex,
-		agent.Gemini:   md.HarnessGemini,
-		agent.Kilo:     md.HarnessKilo,
-		agent.OpenCode: md.HarnessOpencode,
-	}[opts.Harness]; !ok {
+		slog.InfoContext(ctx, "md", "phase", "launch", "hns", opts.Harness)
+	}
+	if _, ok := harnessPaths[opts.Harness]; !ok {
 		return "", fmt.Errorf("unknown harness %q", opts.Harness)
 	}
 	client, mdOpts := b.mdStartOpts(labels, opts)
@@ -87,10 +92,24 @@ func (b *Backend) Launch(ctx context.Context, repos []md.Repo, labels []string,
 	return c.Name, nil
 }
 
+// PullImage implements task.ContainerBackend.
+func (b *Backend) PullImage(ctx context.Context, opts *task.StartOptions, pulling func()) (bool, error) {
+	_, mdOpts := b.mdStartOpts(nil, opts)
+	image := mdOpts.BaseImage
+	if _, err := ImageID(ctx, b.Client.Runtime, image); err == nil {
+		return false, nil
+	}
+	slog.InfoContext(ctx, "md", "phase", "pull", "img", image)
+	pulling()
+	stdout, _ := logWriters(opts.LogWriter, "pull")
+	_, _ = fmt.Fprintf(stdout, "pulling image %s\n", image)
+	return true, PullImage(ctx, b.Client.Runtime, image, stdout)
+}
+
 // Connect implements task.ContainerBackend.
 func (b *Backend) Connect(ctx context.Context, name string, repos []md.Repo, opts *task.StartOptions) (tailscaleFQDN string, err error) {
 	if len(repos) > 0 {
-		slog.Info("md", "phase", "connect", "dir", repos[0].GitRoot, "br", repos[0].Branch)
+		slog.InfoContext(ctx, "md", "phase", "connect", "dir", repos[0].GitRoot, "br", repos[0].Branch)
 	}
 	b.mu.Lock()
 	c, ok := b.pendingContainers[name]
@@ -107,12 +126,89 @@ func (b *Backend) Connect(ctx context.Context, name string, repos []md.Repo, opt
 	if err != nil {
 		return "", err
 	}
+	if opts.ReadOnly && len(repos) > 0 {
+		dirs := make([]string, len(repos))
+		for i, r := range repos {
+			dirs[i] = "/home/user/src/" + r.Name()
+		}
+		if err := LockDirs(ctx, b.Client.Runtime, name, dirs); err != nil {
+			return "", fmt.Errorf("lock repos read-only: %w", err)
+		}
+	}
 	return sr.TailscaleFQDN, nil
 }
 
+// Exec implements task.ContainerBackend.
+func (b *Backend) Exec(ctx context.Context, name, dir, script string) (string, error) {
+	slog.InfoContext(ctx, "md exec", "ctr", name, "dir", dir)
+	return Exec(ctx, b.Client.Runtime, name, dir, script)
+}
+
+// SetPaused implements task.ContainerBackend.
+func (b *Backend) SetPaused(ctx context.Context, name string, paused bool) error {
+	slog.InfoContext(ctx, "md pause", "ctr", name, "paused", paused)
+	return SetPaused(ctx, b.Client.Runtime, name, paused)
+}
+
+// RestrictNetwork implements task.ContainerBackend. Hosts are resolved here,
+// so connections to addresses a host starts using later are dropped.
+func (b *Backend) RestrictNetwork(ctx context.Context, name string, hosts []string) error {
+	slog.InfoContext(ctx, "md restrict network", "ctr", name, "hosts", hosts)
+	var ips []net.IP
+	for _, h := range hosts {
+		addrs, err := net.DefaultResolver.LookupIP(ctx, "ip", h)
+		if err != nil {
+			slog.WarnContext(ctx, "resolve allowed host", "host", h, "err", err)
+			continue
+		}
+		ips = append(ips, addrs...)
+	}
+	return RestrictNetwork(ctx, b.Client.Runtime, name, ips)
+}
+
+// Console implements task.ContainerBackend. It follows the container's own
+// output and the console log of the relay concurrently.
+func (b *Backend) Console(ctx context.Context, name string, tail int, fn func(task.ConsoleLine)) error {
+	slog.InfoContext(ctx, "md console", "ctr", name, "tail", tail)
+	var mu sync.Mutex
+	errs := make(chan error, 2)
+	go func() {
+		errs <- Logs(ctx, b.Client.Runtime, name, tail, &consoleWriter{source: task.ConsoleContainer, mu: &mu, fn: fn})
+	}()
+	go func() {
+		errs <- TailFile(ctx, b.Client.Runtime, name, agent.RelayConsolePath, tail, &consoleWriter{source: task.ConsoleAgent, mu: &mu, fn: fn})
+	}()
+	return errors.Join(<-errs, <-errs)
+}
+
+// consoleWriter is an io.Writer that passes each complete line to fn while
+// holding mu, shared by the writers of the other sources.
+type consoleWriter struct {
+	source string
+	mu     *sync.Mutex
+	fn     func(task.ConsoleLine)
+	buf    []byte
+}
+
+func (w *consoleWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := strings.TrimRight(string(w.buf[:i]), "\r")
+		w.buf = w.buf[i+1:]
+		w.mu.Lock()
+		w.fn(task.ConsoleLine{Source: w.source, Text: line, At: time.Now().UTC()})
+		w.mu.Unlock()
+	}
+	return len(p), nil
+}
+
 // Diff implements task.ContainerBackend.
 func (b *Backend) Diff(ctx context.Context, repo md.Repo, args ...string) (string, error) {
-	slog.Info("md diff", "dir", repo.GitRoot, "br", repo.Branch, "args", args)
+	slog.InfoContext(ctx, "md diff", "dir", repo.GitRoot, "br", repo.Branch, "args", args)
 	var stdout bytes.Buffer
 	if err := b.Client.Container(repo).Diff(ctx, &stdout, &SlogWriter{Phase: "diff"}, 0, args); err != nil {
 		return "", err
@@ -123,7 +219,7 @@ func (b *Backend) Diff(ctx context.Context, repo md.Repo, args ...string) (strin
 // Fetch implements task.ContainerBackend.
 func (b *Backend) Fetch(ctx context.Context, repos []md.Repo) error {
 	if len(repos) > 0 {
-		slog.Info("md fetch", "dir", repos[0].GitRoot, "br", repos[0].Branch)
+		slog.InfoContext(ctx, "md fetch", "dir", repos[0].GitRoot, "br", repos[0].Branch)
 	}
 	ct := b.Client.Container(repos...)
 	for i := range repos {
@@ -136,7 +232,7 @@ func (b *Backend) Fetch(ctx context.Context, repos []md.Repo) error {
 
 // Stop implements task.ContainerBackend.
 func (b *Backend) Stop(ctx context.Context, name string) error {
-	slog.Info("md stop", "name", name)
+	slog.InfoContext(ctx, "md stop", "name", name)
 	ct := b.Client.Container()
 	ct.Name = name
 	return ct.Stop(ctx)
@@ -145,9 +241,9 @@ func (b *Backend) Stop(ctx context.Context, name string) error {
 // Purge implements task.ContainerBackend.
 func (b *Backend) Purge(ctx context.Context, name string, repos []md.Repo) error {
 	if len(repos) > 0 {
-		slog.Info("md purge", "dir", repos[0].GitRoot, "br", repos[0].Branch)
+		slog.InfoContext(ctx, "md purge", "dir", repos[0].GitRoot, "br", repos[0].Branch)
 	} else {
-		slog.Info("md purge", "name", name)
+		slog.InfoContext(ctx, "md purge", "name", name)
 	}
 	ct := b.Client.Container(repos...)
 	if len(repos) == 0 {
@@ -159,9 +255,9 @@ func (b *Backend) Purge(ctx context.Context, name string, repos []md.Repo) error
 // Revive implements task.ContainerBackend.
 func (b *Backend) Revive(ctx context.Context, name string, repos []md.Repo) error {
 	if len(repos) > 0 {
-		slog.Info("md revive", "dir", repos[0].GitRoot, "br", repos[0].Branch, "ctr", name)
+		slog.InfoContext(ctx, "md revive", "dir", repos[0].GitRoot, "br", repos[0].Branch, "ctr", name)
 	} else {
-		slog.Info("md revive", "name", name)
+		slog.InfoContext(ctx, "md revive", "name", name)
 	}
 	ct := b.Client.Container(repos...)
 	if len(repos) == 0 {
@@ -173,22 +269,12 @@ func (b *Backend) Revive(ctx context.Context, name string, repos []md.Repo) erro
 // Fork implements task.ContainerBackend.
 func (b *Backend) Fork(ctx context.Context, name string, repos []md.Repo, opts *task.ForkOptions) (string, []md.Repo, error) {
 	if len(repos) > 0 {
-		slog.Info("md", "phase", "fork", "src", name, "dir", repos[0].GitRoot, "br", repos[0].Branch)
+		slog.InfoContext(ctx, "md", "phase", "fork", "src", name, "dir", repos[0].GitRoot, "br", repos[0].Branch)
 	}
 	ct := b.Client.Container(repos...)
 	ct.Name = name
 	ct.State = "running"
-	harnessMap := map[agent.Harness]md.Harness{
-		agent.Claude:   md.HarnessClaude,
-		agent.Codex:    md.HarnessCodex,
-		agent.Gemini:   md.HarnessGemini,
-		agent.Kilo:     md.HarnessKilo,
-		agent.OpenCode: md.HarnessOpencode,
-	}
-	var agentPaths []md.AgentPaths
-	if mdH, ok := harnessMap[opts.Harness]; ok {
-		agentPaths = []md.AgentPaths{md.HarnessMounts[mdH]}
-	}
+	agentPaths := harnessPaths[opts.Harness]
 	forkOpts := &md.ForkOpts{
 		ExtraRepos: opts.ExtraRepos,
 		Display:    opts.Display,
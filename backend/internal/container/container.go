@@ -6,9 +6,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/caic-xyz/md"
@@ -49,21 +53,230 @@ func (w *SlogWriter) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
-// LabelValue returns the value of a Docker label on a running container.
-//
-// Returns empty string if the label is not set.
-func LabelValue(ctx context.Context, containerName, label string) (string, error) {
-	format := fmt.Sprintf("{{index .Config.Labels %q}}", label)
-	cmd := exec.CommandContext(ctx, "docker", "inspect", containerName, "--format", format) //nolint:gosec // containerName and format are not user-controlled.
+// Labels returns the Docker labels of a container.
+func Labels(ctx context.Context, containerName string) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "docker", "inspect", containerName, "--format", "{{json .Config.Labels}}") //nolint:gosec // containerName is not user-controlled.
 	out, err := cmd.Output()
 	if err != nil {
-		return "", fmt.Errorf("docker inspect label %q on %s: %w", label, containerName, err)
+		return nil, fmt.Errorf("docker inspect labels on %s: %w", containerName, err)
 	}
-	v := strings.TrimSpace(string(out))
-	if v == "<no value>" {
-		return "", nil
+	var labels map[string]string
+	if err := json.Unmarshal(out, &labels); err != nil {
+		return nil, fmt.Errorf("decode labels of %s: %w", containerName, err)
 	}
-	return v, nil
+	return labels, nil
+}
+
+// DiskUsage returns the size in bytes of the writable layer of each named
+// container, running or not. Containers that no longer exist are omitted.
+func DiskUsage(ctx context.Context, runtime string, names []string) (map[string]int64, error) {
+	if len(names) == 0 {
+		return map[string]int64{}, nil
+	}
+	args := append([]string{"inspect", "--size", "--format", "{{.Name}}\t{{json .SizeRw}}"}, names...)
+	// A missing container makes inspect exit non-zero but the others are still
+	// printed on stdout.
+	out, err := exec.CommandContext(ctx, runtime, args...).Output() //nolint:gosec // runtime and container names are not user-controlled.
+	sizes := parseSizes(out)
+	if err != nil && len(sizes) == 0 {
+		return nil, fmt.Errorf("%s inspect --size: %w", runtime, err)
+	}
+	return sizes, nil
+}
+
+// parseSizes parses "name\tsize" lines as printed by DiskUsage's inspect.
+func parseSizes(out []byte) map[string]int64 {
+	sizes := map[string]int64{}
+	for line := range strings.SplitSeq(string(out), "\n") {
+		name, size, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if !ok || size == "null" {
+			continue
+		}
+		var sz int64
+		if json.Unmarshal([]byte(size), &sz) != nil {
+			continue
+		}
+		sizes[strings.TrimPrefix(name, "/")] = sz
+	}
+	return sizes
+}
+
+// RuntimeVersion returns the server version reported by the container
+// runtime ("docker" or "podman"). It fails when the daemon is unreachable.
+func RuntimeVersion(ctx context.Context, runtime string) (string, error) {
+	cmd := exec.CommandContext(ctx, runtime, "version", "--format", "{{.Server.Version}}") //nolint:gosec // runtime is not user-controlled.
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s version: %w", runtime, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// ImageID returns the ID of image if it is present locally.
+func ImageID(ctx context.Context, runtime, image string) (string, error) {
+	cmd := exec.CommandContext(ctx, runtime, "image", "inspect", "--format", "{{.Id}}", image) //nolint:gosec // runtime and image are not user-controlled.
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s image inspect %s: %w", runtime, image, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// PullImage pulls image from its registry, writing the progress to w.
+func PullImage(ctx context.Context, runtime, image string, w io.Writer) error {
+	cmd := exec.CommandContext(ctx, runtime, "pull", image) //nolint:gosec // runtime and image are not user-controlled.
+	cmd.Stdout, cmd.Stderr = w, w
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s pull %s: %w", runtime, image, err)
+	}
+	return nil
+}
+
+// ImageDigest returns the registry digest of the local image, e.g.
+// "sha256:0123...". It fails for images that were built locally rather than
+// pulled.
+func ImageDigest(ctx context.Context, runtime, image string) (string, error) {
+	cmd := exec.CommandContext(ctx, runtime, "image", "inspect", "--format", "{{json .RepoDigests}}", image) //nolint:gosec // runtime and image are not user-controlled.
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("%s image inspect %s: %w", runtime, image, err)
+	}
+	return parseRepoDigest(out)
+}
+
+// parseRepoDigest returns the digest of the first "name@digest" entry of the
+// JSON list of repo digests.
+func parseRepoDigest(out []byte) (string, error) {
+	var refs []string
+	if err := json.Unmarshal(out, &refs); err != nil {
+		return "", fmt.Errorf("parse repo digests: %w", err)
+	}
+	for _, ref := range refs {
+		if _, d, ok := strings.Cut(ref, "@"); ok {
+			return d, nil
+		}
+	}
+	return "", errors.New("image has no registry digest")
+}
+
+// ImageExec runs a bash login script in a throwaway container created from
+// image and returns its combined output. The image is never pulled, so this
+// only works against images already present locally.
+func ImageExec(ctx context.Context, runtime, image, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, runtime, "run", "--rm", "--pull=never", "--entrypoint", "bash", image, "-lc", script) //nolint:gosec // runtime and image are not user-controlled.
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// LockDirs makes dirs inside the running container read-only for the
+// unprivileged container user: ownership moves to root and write bits are
+// cleared, so neither the agent nor its tools can modify or chmod them back.
+func LockDirs(ctx context.Context, runtime, containerName string, dirs []string) error {
+	args := append([]string{"exec", "-u", "root", containerName, "sh", "-c", `chown -R root:root "$@" && chmod -R a-w "$@"`, "sh"}, dirs...)
+	out, err := exec.CommandContext(ctx, runtime, args...).CombinedOutput() //nolint:gosec // runtime and container name are not user-controlled.
+	if err != nil {
+		return fmt.Errorf("%s exec %s: %w: %s", runtime, containerName, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Exec runs a bash login script as the container user in dir inside the
+// running container and returns its combined output.
+func Exec(ctx context.Context, runtime, containerName, dir, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, runtime, "exec", "-w", dir, containerName, "bash", "-lc", script) //nolint:gosec // runtime and container name are not user-controlled.
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
+// Logs writes the last tail lines of the stdout and stderr of a container to
+// w, then follows them until ctx is done or the container exits.
+func Logs(ctx context.Context, runtime, containerName string, tail int, w io.Writer) error {
+	cmd := exec.CommandContext(ctx, runtime, "logs", "--follow", "--tail", strconv.Itoa(tail), containerName) //nolint:gosec // runtime and container name are not user-controlled.
+	cmd.Stdout, cmd.Stderr = w, w
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%s logs %s: %w", runtime, containerName, err)
+	}
+	return nil
+}
+
+// TailFile writes the last tail lines of a file inside the running container
+// to w, then follows it until ctx is done or the container stops. The file
+// may not exist yet.
+func TailFile(ctx context.Context, runtime, containerName, path string, tail int, w io.Writer) error {
+	cmd := exec.CommandContext(ctx, runtime, "exec", containerName, "tail", "-n", strconv.Itoa(tail), "-F", path) //nolint:gosec // runtime and container name are not user-controlled.
+	cmd.Stdout = w
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%s exec %s tail: %w: %s", runtime, containerName, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// SetPaused freezes or thaws all the processes of a running container.
+func SetPaused(ctx context.Context, runtime, containerName string, paused bool) error {
+	verb := "unpause"
+	if paused {
+		verb = "pause"
+	}
+	out, err := exec.CommandContext(ctx, runtime, verb, containerName).CombinedOutput() //nolint:gosec // runtime and container name are not user-controlled.
+	if err != nil {
+		return fmt.Errorf("%s %s %s: %w: %s", runtime, verb, containerName, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// RestrictNetwork drops the outgoing connections of a running container
+// except to ips, the container's nameservers and loopback. Connections the container accepts, like
+// SSH, keep working. The rules are installed by a throwaway container sharing
+// the target's network namespace, so the target needs no extra capability,
+// but its image must ship iptables.
+func RestrictNetwork(ctx context.Context, runtime, containerName string, ips []net.IP) error {
+	image, err := exec.CommandContext(ctx, runtime, "inspect", "--format", "{{.Config.Image}}", containerName).Output() //nolint:gosec // runtime and container name are not user-controlled.
+	if err != nil {
+		return fmt.Errorf("%s inspect %s: %w", runtime, containerName, err)
+	}
+	cmd := exec.CommandContext(ctx, runtime, "run", "--rm", "--pull=never", "--network=container:"+containerName, //nolint:gosec // runtime and container name are not user-controlled.
+		"--cap-add=NET_ADMIN", "--user=root", "--entrypoint=sh", strings.TrimSpace(string(image)), "-c", networkScript(ips))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("restrict network of %s: %w: %s", containerName, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// resolvers expands to the nameservers of the resolv.conf shared by the
+// containers of a network namespace, without IPv6 zones.
+const resolvers = `$(sed -n 's/^nameserver[[:space:]]*\([^%[:space:]]*\).*/\1/p' /etc/resolv.conf)`
+
+// networkScript returns the shell script installing the rules of
+// RestrictNetwork. IPv6 is skipped when the namespace doesn't support it.
+// DNS is only allowed to the nameservers, otherwise port 53 would be a way
+// around the allowlist; Docker's embedded DNS is reached through loopback.
+func networkScript(ips []net.IP) string {
+	var b strings.Builder
+	b.WriteString("set -e\ncommand -v iptables >/dev/null || { echo 'iptables not found in image' >&2; exit 1; }\n")
+	for _, ipt := range []string{"iptables", "ip6tables"} {
+		if ipt == "ip6tables" {
+			b.WriteString("if command -v ip6tables >/dev/null && ip6tables -L OUTPUT >/dev/null 2>&1; then\n")
+		}
+		fmt.Fprintf(&b, "%[1]s -F OUTPUT\n%[1]s -A OUTPUT -o lo -j ACCEPT\n%[1]s -A OUTPUT -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT\n", ipt)
+		skip := "*:*) continue ;;"
+		if ipt == "ip6tables" {
+			skip = "*:*) ;; *) continue ;;"
+		}
+		b.WriteString("for ns in " + resolvers + "; do\ncase $ns in " + skip + " esac\n")
+		fmt.Fprintf(&b, "%[1]s -A OUTPUT -d \"$ns\" -p udp --dport 53 -j ACCEPT\n%[1]s -A OUTPUT -d \"$ns\" -p tcp --dport 53 -j ACCEPT\ndone\n", ipt)
+		for _, ip := range ips {
+			if (ip.To4() != nil) == (ipt == "iptables") {
+				fmt.Fprintf(&b, "%s -A OUTPUT -d %s -j ACCEPT\n", ipt, ip)
+			}
+		}
+		fmt.Fprintf(&b, "%s -P OUTPUT DROP\n", ipt)
+		if ipt == "ip6tables" {
+			b.WriteString("fi\n")
+		}
+	}
+	return b.String()
 }
 
 // Event represents a Docker container lifecycle event.
@@ -119,13 +332,3 @@ func WatchEvents(ctx context.Context, labelFilter string) (<-chan Event, error)
 	}()
 	return ch, nil
 }
-
-// BranchFromContainer derives the git branch name from a container name by
-// stripping the "md-<repo>-" prefix.
-func BranchFromContainer(containerName, repoName string) (string, bool) {
-	prefix := "md-" + repoName + "-"
-	if !strings.HasPrefix(containerName, prefix) {
-		return "", false
-	}
-	return containerName[len(prefix):], true
-}
@@ -1,8 +1,16 @@
 package container
 
 import (
+	"maps"
+	"net"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/task"
 )
 
 func TestNew(t *testing.T) {
@@ -20,66 +28,73 @@ func TestNew(t *testing.T) {
 	}
 }
 
-func TestBranchFromContainer(t *testing.T) {
-	tests := []struct {
-		name      string
-		container string
-		repo      string
-		wantBr    string
-		wantOK    bool
-	}{
-		{
-			name:      "standard caic branch",
-			container: "md-caic-caic-fix-auth",
-			repo:      "caic",
-			wantBr:    "caic-fix-auth",
-			wantOK:    true,
-		},
-		{
-			name:      "non-caic branch",
-			container: "md-caic-feature-xyz",
-			repo:      "caic",
-			wantBr:    "feature-xyz",
-			wantOK:    true,
-		},
-		{
-			name:      "wrong repo prefix",
-			container: "md-other-caic-fix",
-			repo:      "caic",
-			wantBr:    "",
-			wantOK:    false,
-		},
-		{
-			name:      "no md prefix",
-			container: "notmd-caic-fix",
-			repo:      "caic",
-			wantBr:    "",
-			wantOK:    false,
-		},
-		{
-			name:      "different repo",
-			container: "md-myrepo-caic-fix",
-			repo:      "myrepo",
-			wantBr:    "caic-fix",
-			wantOK:    true,
-		},
-		{
-			name:      "numeric caic branch",
-			container: "md-myrepo-caic-2",
-			repo:      "myrepo",
-			wantBr:    "caic-2",
-			wantOK:    true,
-		},
+func TestParseSizes(t *testing.T) {
+	out := []byte("/md-caic-w1\t1048576\nmd-caic-w2\t0\n\n/md-caic-w3\tnull\ngarbage\n")
+	want := map[string]int64{"md-caic-w1": 1 << 20, "md-caic-w2": 0}
+	if got := parseSizes(out); !maps.Equal(got, want) {
+		t.Errorf("parseSizes() = %v, want %v", got, want)
+	}
+}
+
+func TestParseRepoDigest(t *testing.T) {
+	d, err := parseRepoDigest([]byte(`["ghcr.io/caic-xyz/md-root@sha256:0123abcd"]`))
+	if err != nil || d != "sha256:0123abcd" {
+		t.Errorf("parseRepoDigest() = %q, %v", d, err)
+	}
+	if _, err := parseRepoDigest([]byte(`[]`)); err == nil {
+		t.Error("expected an error for a local image")
+	}
+}
+
+func TestNetworkScript(t *testing.T) {
+	s := networkScript([]net.IP{net.ParseIP("104.18.0.1"), net.ParseIP("2606:4700::1")})
+	for _, want := range []string{
+		"iptables -A OUTPUT -d 104.18.0.1 -j ACCEPT\n",
+		"ip6tables -A OUTPUT -d 2606:4700::1 -j ACCEPT\n",
+		"iptables -P OUTPUT DROP\n",
+		"ip6tables -P OUTPUT DROP\nfi\n",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("script lacks %q:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "iptables -A OUTPUT -d 2606") || strings.Contains(s, "ip6tables -A OUTPUT -d 104") {
+		t.Errorf("address in the wrong family:\n%s", s)
+	}
+	if strings.Contains(s, "-A OUTPUT -p udp") || strings.Contains(s, "-A OUTPUT -p tcp") {
+		t.Errorf("DNS allowed to any host:\n%s", s)
+	}
+	if !strings.Contains(s, "iptables -A OUTPUT -d \"$ns\" -p udp --dport 53 -j ACCEPT\n") {
+		t.Errorf("script lacks the nameserver rule:\n%s", s)
+	}
+	t.Run("Resolvers", func(t *testing.T) {
+		if _, err := exec.LookPath("sed"); err != nil {
+			t.Skip(err)
+		}
+		conf := filepath.Join(t.TempDir(), "resolv.conf")
+		if err := os.WriteFile(conf, []byte("# nameserver 6.6.6.6\nnameserver 10.0.0.2\nnameserver\tfe80::1%eth0\noptions ndots:0\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		out, err := exec.Command("sh", "-c", "echo "+strings.ReplaceAll(resolvers, "/etc/resolv.conf", conf)).Output()
+		if got := strings.TrimSpace(string(out)); err != nil || got != "10.0.0.2 fe80::1" {
+			t.Errorf("resolvers = %q, %v", got, err)
+		}
+	})
+}
+
+func TestConsoleWriter(t *testing.T) {
+	var mu sync.Mutex
+	var got []task.ConsoleLine
+	w := &consoleWriter{source: task.ConsoleAgent, mu: &mu, fn: func(l task.ConsoleLine) { got = append(got, l) }}
+	_, _ = w.Write([]byte("npm WARN dep"))
+	_, _ = w.Write([]byte("recated\r\n\nadded 12 packages\npartial"))
+	want := []string{"npm WARN deprecated", "", "added 12 packages"}
+	if len(got) != len(want) {
+		t.Fatalf("lines = %+v", got)
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			br, ok := BranchFromContainer(tt.container, tt.repo)
-			if ok != tt.wantOK {
-				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
-			}
-			if br != tt.wantBr {
-				t.Errorf("branch = %q, want %q", br, tt.wantBr)
-			}
-		})
+	for i, l := range got {
+		if l.Source != task.ConsoleAgent || l.Text != want[i] || l.At.IsZero() {
+			t.Errorf("line %d = %+v, want %q", i, l, want[i])
+		}
 	}
 }
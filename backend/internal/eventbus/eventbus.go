@@ -0,0 +1,100 @@
+// Package eventbus publishes the server's events to subscribers: in process by
+// default, or through Redis pub/sub so that other caic instances and external
+// consumers receive them.
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// subscriberBuffer is the number of events a subscriber may lag behind before
+// events are dropped for it.
+const subscriberBuffer = 64
+
+// Bus delivers the events published on a topic to the subscribers of the
+// topic. Delivery is at most once: an event is dropped for a subscriber that
+// lags behind, so events should describe state to re-read rather than carry it
+// incrementally.
+type Bus interface {
+	// Publish sends data to the subscribers of topic.
+	Publish(ctx context.Context, topic string, data []byte) error
+	// Subscribe returns the channel receiving the events of topic, closed
+	// once ctx is done.
+	Subscribe(ctx context.Context, topic string) (<-chan []byte, error)
+	// Close releases the resources of the bus.
+	Close() error
+}
+
+// Open returns the Bus described by rawURL: the in-process Memory bus when
+// empty, or a Redis bus for "redis://[:password@]host:port".
+func Open(rawURL string) (Bus, error) {
+	if rawURL == "" {
+		return NewMemory(), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event bus URL: %w", err)
+	}
+	switch u.Scheme {
+	case "redis":
+		return NewRedis(u), nil
+	default:
+		return nil, fmt.Errorf("unsupported event bus %q; supported: redis", u.Scheme)
+	}
+}
+
+// Memory is the in-process Bus.
+type Memory struct {
+	mu   sync.Mutex
+	subs map[string]map[chan []byte]struct{}
+}
+
+// NewMemory returns an in-process Bus.
+func NewMemory() *Memory {
+	return &Memory{subs: map[string]map[chan []byte]struct{}{}}
+}
+
+// Publish implements Bus.
+func (m *Memory) Publish(_ context.Context, topic string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for ch := range m.subs[topic] {
+		deliver(ch, data)
+	}
+	return nil
+}
+
+// Subscribe implements Bus.
+func (m *Memory) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	ch := make(chan []byte, subscriberBuffer)
+	m.mu.Lock()
+	if m.subs[topic] == nil {
+		m.subs[topic] = map[chan []byte]struct{}{}
+	}
+	m.subs[topic][ch] = struct{}{}
+	m.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.subs[topic], ch)
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// Close implements Bus.
+func (m *Memory) Close() error {
+	return nil
+}
+
+// deliver sends data on ch unless the subscriber lags behind.
+func deliver(ch chan []byte, data []byte) {
+	select {
+	case ch <- data:
+	default:
+	}
+}
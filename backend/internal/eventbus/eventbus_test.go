@@ -0,0 +1,129 @@
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBus(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		testBus(t, NewMemory())
+	})
+	t.Run("Redis", func(t *testing.T) {
+		addr := fakeRedis(t, "secret")
+		u, _ := url.Parse("redis://:secret@" + addr)
+		b := NewRedis(u)
+		t.Cleanup(func() { _ = b.Close() })
+		testBus(t, b)
+	})
+	t.Run("RedisAuth", func(t *testing.T) {
+		u, _ := url.Parse("redis://:wrong@" + fakeRedis(t, "secret"))
+		if err := NewRedis(u).Publish(t.Context(), "a", nil); err == nil {
+			t.Error("publish with a wrong password succeeded")
+		}
+	})
+	t.Run("Open", func(t *testing.T) {
+		if b, err := Open(""); err != nil || b == nil {
+			t.Errorf("Open(\"\") = %v, %v", b, err)
+		}
+		if _, err := Open("kafka://host"); err == nil {
+			t.Error("Open(kafka) succeeded")
+		}
+	})
+}
+
+func testBus(t *testing.T, b Bus) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	a, err := b.Subscribe(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := b.Subscribe(ctx, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish(t.Context(), "a", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-a:
+		if string(got) != "hello" {
+			t.Errorf("got %q", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	select {
+	case got := <-other:
+		t.Errorf("other topic got %q", got)
+	default:
+	}
+	cancel()
+	for range a {
+	}
+}
+
+// fakeRedis serves the subset of the protocol the bus uses.
+func fakeRedis(t *testing.T, password string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	var mu sync.Mutex
+	subs := map[string][]net.Conn{}
+	bulk := func(s string) string { return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n" }
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = c.Close() })
+			go func() {
+				r := bufio.NewReader(c)
+				for {
+					v, err := readRESP(r)
+					if err != nil {
+						return
+					}
+					args, _ := v.([]any)
+					if len(args) < 2 {
+						return
+					}
+					var reply string
+					switch cmd, arg := args[0], args[1].(string); cmd {
+					case "AUTH":
+						reply = "+OK\r\n"
+						if arg != password {
+							reply = "-ERR invalid password\r\n"
+						}
+					case "SUBSCRIBE":
+						mu.Lock()
+						subs[arg] = append(subs[arg], c)
+						mu.Unlock()
+						reply = "*3\r\n" + bulk("subscribe") + bulk(arg) + ":1\r\n"
+					case "PUBLISH":
+						mu.Lock()
+						for _, s := range subs[arg] {
+							_, _ = s.Write([]byte("*3\r\n" + bulk("message") + bulk(arg) + bulk(args[2].(string))))
+						}
+						reply = ":" + strconv.Itoa(len(subs[arg])) + "\r\n"
+						mu.Unlock()
+					}
+					if _, err := c.Write([]byte(reply)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
@@ -0,0 +1,245 @@
+// Redis pub/sub Bus, speaking the RESP protocol directly.
+package eventbus
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	redisDialTimeout = 5 * time.Second
+	// redisRetry is the delay before a lost subscription reconnects.
+	redisRetry = 2 * time.Second
+)
+
+// Redis is a Bus over Redis pub/sub. Publishing shares a connection; each
+// subscription holds its own, reconnected when lost. Events published while a
+// subscription reconnects are lost.
+type Redis struct {
+	addr     string
+	password string
+
+	mu   sync.Mutex
+	conn *redisConn // Publishing connection; nil until first used.
+}
+
+// NewRedis returns a Redis bus for the server at u, e.g.
+// redis://:password@localhost:6379. No connection is made until used.
+func NewRedis(u *url.URL) *Redis {
+	r := &Redis{addr: u.Host}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if p, ok := u.User.Password(); ok {
+		r.password = p
+	}
+	return r
+}
+
+// Publish implements Bus. A broken connection is redialed once.
+func (r *Redis) Publish(ctx context.Context, topic string, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var err error
+	for range 2 {
+		if r.conn == nil {
+			if r.conn, err = r.dial(ctx); err != nil {
+				return err
+			}
+		}
+		if _, err = r.conn.do(ctx, "PUBLISH", topic, string(data)); err == nil {
+			return nil
+		}
+		_ = r.conn.Close()
+		r.conn = nil
+	}
+	return err
+}
+
+// Subscribe implements Bus.
+func (r *Redis) Subscribe(ctx context.Context, topic string) (<-chan []byte, error) {
+	c, err := r.subscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan []byte, subscriberBuffer)
+	go func() {
+		defer close(ch)
+		for {
+			err := c.receive(ctx, topic, ch)
+			_ = c.Close()
+			if ctx.Err() != nil {
+				return
+			}
+			slog.WarnContext(ctx, "eventbus", "msg", "redis subscription lost", "topic", topic, "err", err)
+			if c = r.resubscribe(ctx, topic); c == nil {
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// resubscribe retries subscribe until it succeeds. It returns nil once ctx is
+// done.
+func (r *Redis) resubscribe(ctx context.Context, topic string) *redisConn {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(redisRetry):
+		}
+		if c, err := r.subscribe(ctx, topic); err == nil {
+			return c
+		}
+	}
+}
+
+// Close implements Bus.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+func (r *Redis) dial(ctx context.Context) (*redisConn, error) {
+	d := net.Dialer{Timeout: redisDialTimeout}
+	nc, err := d.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+	if r.password != "" {
+		if _, err := c.do(ctx, "AUTH", r.password); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// subscribe dials a connection subscribed to topic, closed when ctx is done.
+func (r *Redis) subscribe(ctx context.Context, topic string) (*redisConn, error) {
+	c, err := r.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.do(ctx, "SUBSCRIBE", topic); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	c.stop = context.AfterFunc(ctx, func() { _ = c.Conn.Close() })
+	return c, nil
+}
+
+// redisConn is a connection to the server.
+type redisConn struct {
+	net.Conn
+	r    *bufio.Reader
+	stop func() bool
+}
+
+func (c *redisConn) Close() error {
+	if c.stop != nil {
+		c.stop()
+	}
+	return c.Conn.Close()
+}
+
+// do sends a command and reads its reply.
+func (c *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	if dl, ok := ctx.Deadline(); ok {
+		_ = c.SetDeadline(dl)
+	} else {
+		_ = c.SetDeadline(time.Now().Add(redisDialTimeout))
+	}
+	defer func() { _ = c.SetDeadline(time.Time{}) }()
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(a))+"\r\n"+a+"\r\n"...)
+	}
+	if _, err := c.Write(buf); err != nil {
+		return nil, err
+	}
+	return readRESP(c.r)
+}
+
+// receive forwards the messages of topic to ch until the connection fails.
+func (c *redisConn) receive(ctx context.Context, topic string, ch chan []byte) error {
+	for {
+		v, err := readRESP(c.r)
+		if err != nil {
+			return err
+		}
+		// A message is the array ["message", topic, data].
+		msg, ok := v.([]any)
+		if !ok || len(msg) != 3 || msg[0] != "message" || msg[1] != topic {
+			continue
+		}
+		if data, ok := msg[2].(string); ok {
+			deliver(ch, []byte(data))
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// readRESP reads a reply: a string, an int64, nil or a []any of them. An
+// error reply is returned as the error.
+func readRESP(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, rest := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return rest, nil
+	case '-':
+		return nil, errors.New("redis: " + rest)
+	case ':':
+		return strconv.ParseInt(rest, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(rest)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		out := make([]any, n)
+		for i := range out {
+			if out[i], err = readRESP(r); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
@@ -0,0 +1,72 @@
+// Package filelock provides an exclusive lock file shared by processes.
+//
+// The lock is a file created with O_EXCL, which works across caic instances
+// sharing a directory, including over network file systems.
+package filelock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// pollInterval is the pause between two attempts to take a busy lock.
+const pollInterval = 10 * time.Millisecond
+
+// Acquire creates the lock file at path, waiting until ctx is done for the
+// process holding it. A lock file older than stale was left by a crashed
+// process and is broken. It returns the function releasing the lock.
+func Acquire(ctx context.Context, path string, stale time.Duration) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if st, err := os.Stat(path); err == nil && time.Since(st.ModTime()) > stale {
+			if err := breakStale(path, stale); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("lock %s: %w", path, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// breakStale removes the stale lock file at path. Two processes may find the
+// same stale lock, and one of them may already have replaced it with its own
+// by the time the other removes it, so the lock is first moved aside under a
+// unique name, an atomic operation, then checked again: a fresh lock moved
+// by mistake is put back, unless another process took the lock meanwhile.
+func breakStale(path string, stale time.Duration) error {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	aside := path + ".stale-" + hex.EncodeToString(b[:])
+	if err := os.Rename(path, aside); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// Another process broke it first.
+			return nil
+		}
+		return err
+	}
+	st, err := os.Stat(aside)
+	if err != nil {
+		return err
+	}
+	if time.Since(st.ModTime()) <= stale {
+		// os.Link fails rather than replace a lock taken meanwhile.
+		_ = os.Link(aside, path)
+	}
+	return os.Remove(aside)
+}
@@ -0,0 +1,86 @@
+package filelock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Run("Exclusive", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "x.lock")
+		var mu sync.Mutex
+		held := false
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Go(func() {
+				unlock, err := Acquire(t.Context(), path, time.Minute)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				if held {
+					t.Error("lock held twice")
+				}
+				held = true
+				mu.Unlock()
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				held = false
+				mu.Unlock()
+				unlock()
+			})
+		}
+		wg.Wait()
+	})
+	t.Run("Busy", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "x.lock")
+		unlock, err := Acquire(t.Context(), path, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer unlock()
+		ctx, cancel := context.WithTimeout(t.Context(), 30*time.Millisecond)
+		defer cancel()
+		if _, err := Acquire(ctx, path, time.Minute); err == nil {
+			t.Error("acquired a held lock")
+		}
+	})
+	t.Run("Stale", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "x.lock")
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-2 * time.Minute)
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+		unlock, err := Acquire(t.Context(), path, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unlock()
+		if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+			t.Errorf("left %v, %v; want an empty directory", entries, err)
+		}
+	})
+	t.Run("FreshLockMovedAside", func(t *testing.T) {
+		// The lock was replaced by a fresh one between the staleness check
+		// and breakStale: it must survive.
+		path := filepath.Join(t.TempDir(), "x.lock")
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := breakStale(path, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("fresh lock removed: %v", err)
+		}
+	})
+}
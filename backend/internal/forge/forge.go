@@ -114,6 +114,26 @@ func CheckFromRun(owner, repo string, r *CheckRun) Check {
 	}
 }
 
+// ForkHead returns the head of a pull request from branch of the fork
+// owner/repo, for Forge.CreatePR.
+func ForkHead(owner, repo, branch string) string {
+	return owner + "/" + repo + ":" + branch
+}
+
+// SplitForkHead splits a head built by ForkHead. ok is false for a plain
+// branch, which can't contain ':'.
+func SplitForkHead(head string) (owner, repo, branch string, ok bool) {
+	path, branch, ok := strings.Cut(head, ":")
+	if !ok {
+		return "", "", head, false
+	}
+	i := strings.LastIndexByte(path, '/')
+	if i <= 0 || i == len(path)-1 || branch == "" {
+		return "", "", head, false
+	}
+	return path[:i], path[i+1:], branch, true
+}
+
 // CIStatus is the aggregate CI outcome for a commit SHA.
 type CIStatus string
 
@@ -125,9 +145,29 @@ const (
 	CIStatusFailure CIStatus = "failure"
 )
 
+// CommitState is the state of a commit status.
+type CommitState string
+
+// Commit status states.
+const (
+	CommitStatePending CommitState = "pending"
+	CommitStateSuccess CommitState = "success"
+	CommitStateFailure CommitState = "failure"
+)
+
+// CommitStatus is a status posted on a commit. Forges show it next to the
+// commit and in the checks of the PRs whose head it is.
+type CommitStatus struct {
+	State       CommitState
+	Context     string // Identifies the reporter, e.g. "caic"; a new status replaces the one with the same context.
+	Description string // Short summary; GitHub truncates it at 140 characters.
+	TargetURL   string // Details page; may be empty.
+}
+
 // Forge is the interface for interacting with a code hosting forge.
 type Forge interface {
-	// CreatePR creates a pull/merge request and returns its metadata.
+	// CreatePR creates a pull/merge request and returns its metadata. head is
+	// a branch of owner/repo, or one of a fork as built by ForkHead.
 	CreatePR(ctx context.Context, owner, repo, head, base, title, body string) (PR, error)
 	// FindPRByBranch returns the PR for the given head branch, or ErrNotFound
 	// if no PR exists for that branch.
@@ -162,6 +202,8 @@ type Forge interface {
 	// and message. Returns an error if the merge cannot be completed (e.g.
 	// merge conflict, branch-protection rule, or already merged).
 	MergePR(ctx context.Context, owner, repo string, prNumber int, commitTitle, commitMessage string) error
+	// SetCommitStatus posts a status on the commit sha of owner/repo.
+	SetCommitStatus(ctx context.Context, owner, repo, sha string, st *CommitStatus) error
 }
 
 // Remote URL regex patterns for supported forges.
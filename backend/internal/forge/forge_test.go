@@ -6,6 +6,24 @@ import (
 	"testing"
 )
 
+func TestForkHead(t *testing.T) {
+	head := ForkHead("me", "caic", "caic-1")
+	if head != "me/caic:caic-1" {
+		t.Errorf("ForkHead = %q", head)
+	}
+	if owner, repo, branch, ok := SplitForkHead(head); !ok || owner != "me" || repo != "caic" || branch != "caic-1" {
+		t.Errorf("SplitForkHead(%q) = %q, %q, %q, %t", head, owner, repo, branch, ok)
+	}
+	if owner, repo, branch, ok := SplitForkHead("group/sub/caic:fix/x"); !ok || owner != "group/sub" || repo != "caic" || branch != "fix/x" {
+		t.Errorf("nested = %q, %q, %q, %t", owner, repo, branch, ok)
+	}
+	for _, h := range []string{"caic-1", "me:caic-1", "/caic:b", "me/:b"} {
+		if _, _, _, ok := SplitForkHead(h); ok {
+			t.Errorf("SplitForkHead(%q) ok", h)
+		}
+	}
+}
+
 func TestReadLog(t *testing.T) {
 	t.Run("strips ANSI color codes", func(t *testing.T) {
 		input := "\x1b[31mERROR\x1b[0m: build failed\n\x1b[32mOK\x1b[0m"
@@ -119,6 +119,10 @@ var actionsRunRe = regexp.MustCompile(`/actions/runs/(\d+)/job/\d+`)
 
 // CreatePR creates a pull request on GitHub and returns its metadata.
 func (c *Client) CreatePR(ctx context.Context, owner, repo, head, base, title, body string) (forge.PR, error) {
+	if forkOwner, _, branch, ok := forge.SplitForkHead(head); ok {
+		// GitHub finds the fork from its owner.
+		head = forkOwner + ":" + branch
+	}
 	payload, err := json.Marshal(createPRRequest{Title: title, Body: body, Head: head, Base: base})
 	if err != nil {
 		return forge.PR{}, err
@@ -427,6 +431,37 @@ func (c *Client) PostComment(ctx context.Context, owner, repo string, issueNumbe
 	return nil
 }
 
+// commitStatusRequest is the JSON body for POST /repos/{owner}/{repo}/statuses/{sha}.
+type commitStatusRequest struct {
+	State       forge.CommitState `json:"state"`
+	TargetURL   string            `json:"target_url,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Context     string            `json:"context"`
+}
+
+// SetCommitStatus posts a commit status on GitHub.
+func (c *Client) SetCommitStatus(ctx context.Context, owner, repo, sha string, st *forge.CommitStatus) error {
+	payload, err := json.Marshal(commitStatusRequest{State: st.State, TargetURL: st.TargetURL, Description: st.Description, Context: st.Context})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/repos/%s/%s/statuses/%s", c.apiBase(), owner, repo, sha)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusCreated {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("github set commit status: status %d: %s", resp.StatusCode, data)
+	}
+	return nil
+}
+
 // Name returns "GitHub".
 func (c *Client) Name() string { return "GitHub" }
 
@@ -1,11 +1,14 @@
-// Tests for GitHub-specific log extraction.
+// Tests for GitHub-specific log extraction and API calls.
 package github
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/forge"
 )
 
 // NewClientForTest creates a Client pointing at baseURL instead of api.github.com.
@@ -50,6 +53,31 @@ func TestGetJobLog(t *testing.T) {
 	})
 }
 
+func TestSetCommitStatus(t *testing.T) {
+	var gotPath string
+	var got commitStatusRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+	client := NewClientForTest("token", srv.URL)
+	st := forge.CommitStatus{State: forge.CommitStateSuccess, Context: "caic", Description: "Agent task w42", TargetURL: "https://caic.example.com/task/@x"}
+	if err := client.SetCommitStatus(t.Context(), "owner", "repo", "abc123", &st); err != nil {
+		t.Fatal(err)
+	}
+	if gotPath != "/repos/owner/repo/statuses/abc123" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if want := (commitStatusRequest{State: "success", Context: "caic", Description: "Agent task w42", TargetURL: st.TargetURL}); got != want {
+		t.Errorf("body = %+v, want %+v", got, want)
+	}
+}
+
 func TestExtractGitHubSteps(t *testing.T) {
 	t.Run("extracts failing step", func(t *testing.T) {
 		log := strings.Join([]string{
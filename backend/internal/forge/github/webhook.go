@@ -45,6 +45,7 @@ type WebhookRepo struct {
 // WebhookUser carries user identity from a webhook payload.
 type WebhookUser struct {
 	Login string `json:"login"`
+	Type  string `json:"type"` // "User" or "Bot"
 }
 
 // WebhookLabel carries a label from a webhook payload.
@@ -85,6 +86,23 @@ type WebhookComment struct {
 	HTMLURL string      `json:"html_url"`
 }
 
+// WebhookReview carries the pull request review fields used from webhook payloads.
+type WebhookReview struct {
+	Body    string      `json:"body"`
+	State   string      `json:"state"` // "commented", "changes_requested" or "approved"
+	User    WebhookUser `json:"user"`
+	HTMLURL string      `json:"html_url"`
+}
+
+// WebhookReviewComment carries the fields used from an inline review comment.
+type WebhookReviewComment struct {
+	Body    string      `json:"body"`
+	Path    string      `json:"path"`
+	Line    int         `json:"line"`
+	User    WebhookUser `json:"user"`
+	HTMLURL string      `json:"html_url"`
+}
+
 // IssuesEvent is the payload for X-GitHub-Event: issues.
 type IssuesEvent struct {
 	Action       string              `json:"action"`
@@ -110,6 +128,25 @@ type IssueCommentEvent struct {
 	Installation WebhookInstallation `json:"installation"`
 }
 
+// PullRequestReviewEvent is the payload for X-GitHub-Event: pull_request_review.
+type PullRequestReviewEvent struct {
+	Action       string              `json:"action"`
+	Review       WebhookReview       `json:"review"`
+	PullRequest  WebhookPR           `json:"pull_request"`
+	Repository   WebhookRepo         `json:"repository"`
+	Installation WebhookInstallation `json:"installation"`
+}
+
+// PullRequestReviewCommentEvent is the payload for X-GitHub-Event:
+// pull_request_review_comment.
+type PullRequestReviewCommentEvent struct {
+	Action       string               `json:"action"`
+	Comment      WebhookReviewComment `json:"comment"`
+	PullRequest  WebhookPR            `json:"pull_request"`
+	Repository   WebhookRepo          `json:"repository"`
+	Installation WebhookInstallation  `json:"installation"`
+}
+
 // InstallationEvent is the payload for X-GitHub-Event: installation.
 type InstallationEvent struct {
 	Action       string `json:"action"` // "created", "deleted", "suspend", "unsuspend"
@@ -50,10 +50,11 @@ func projectID(owner, repo string) string {
 
 // createMRRequest is the JSON body for POST /projects/{id}/merge_requests.
 type createMRRequest struct {
-	SourceBranch string `json:"source_branch"`
-	TargetBranch string `json:"target_branch"`
-	Title        string `json:"title"`
-	Description  string `json:"description"`
+	SourceBranch    string `json:"source_branch"`
+	TargetBranch    string `json:"target_branch"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	TargetProjectID int64  `json:"target_project_id,omitempty"` // Set when the source is a fork.
 }
 
 // createMRResponse is the relevant subset of the GitLab MR creation response.
@@ -81,16 +82,28 @@ type commitStatus struct {
 
 // CreatePR creates a merge request on GitLab and returns its metadata.
 func (c *Client) CreatePR(ctx context.Context, owner, repo, head, base, title, body string) (forge.PR, error) {
-	payload, err := json.Marshal(createMRRequest{
+	mr := createMRRequest{
 		SourceBranch: head,
 		TargetBranch: base,
 		Title:        title,
 		Description:  body,
-	})
+	}
+	// A merge request from a fork is created on the fork, targeting the
+	// upstream project by its numeric ID.
+	source := projectID(owner, repo)
+	if forkOwner, forkRepo, branch, ok := forge.SplitForkHead(head); ok {
+		id, err := c.projectNumericID(ctx, owner, repo)
+		if err != nil {
+			return forge.PR{}, err
+		}
+		mr.SourceBranch, mr.TargetProjectID = branch, id
+		source = projectID(forkOwner, forkRepo)
+	}
+	payload, err := json.Marshal(mr)
 	if err != nil {
 		return forge.PR{}, err
 	}
-	apiURL := fmt.Sprintf("%s/projects/%s/merge_requests", apiBase, projectID(owner, repo))
+	apiURL := fmt.Sprintf("%s/projects/%s/merge_requests", apiBase, source)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(payload))
 	if err != nil {
 		return forge.PR{}, err
@@ -114,6 +127,34 @@ func (c *Client) CreatePR(ctx context.Context, owner, repo, head, base, title, b
 	return forge.PR{Number: r.IID, HeadSHA: r.SHA}, nil
 }
 
+// projectNumericID returns the numeric ID of the project owner/repo.
+func (c *Client) projectNumericID(ctx context.Context, owner, repo string) (int64, error) {
+	apiURL := fmt.Sprintf("%s/projects/%s", apiBase, projectID(owner, repo))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, http.NoBody)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("gitlab get project: status %d: %s", resp.StatusCode, data)
+	}
+	var r struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return 0, err
+	}
+	return r.ID, nil
+}
+
 // FindPRByBranch returns the MR for the given source branch, or ErrNotFound
 // if no MR exists for that branch.
 func (c *Client) FindPRByBranch(ctx context.Context, owner, repo, sourceBranch string) (forge.PR, error) {
@@ -322,6 +363,41 @@ func (c *Client) MergePR(ctx context.Context, owner, repo string, prNumber int,
 	return nil
 }
 
+// setCommitStatusRequest is the JSON body for POST /projects/{id}/statuses/{sha}.
+type setCommitStatusRequest struct {
+	State       string `json:"state"` // "pending", "running", "success", "failed" or "canceled".
+	Name        string `json:"name"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// SetCommitStatus posts a commit status on GitLab.
+func (c *Client) SetCommitStatus(ctx context.Context, owner, repo, sha string, st *forge.CommitStatus) error {
+	state := string(st.State)
+	if st.State == forge.CommitStateFailure {
+		state = "failed"
+	}
+	payload, err := json.Marshal(setCommitStatusRequest{State: state, Name: st.Context, TargetURL: st.TargetURL, Description: st.Description})
+	if err != nil {
+		return err
+	}
+	apiURL := fmt.Sprintf("%s/projects/%s/statuses/%s", apiBase, projectID(owner, repo), sha)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusCreated {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("gitlab set commit status: status %d: %s", resp.StatusCode, data)
+	}
+	return nil
+}
+
 // GetJobLabels returns nil for GitLab; the commit statuses API does not
 // expose runner labels.
 func (c *Client) GetJobLabels(_ context.Context, _, _ string, _ int64) ([]string, error) {
@@ -0,0 +1,292 @@
+// Package ha coordinates caic instances sharing a directory, e.g. behind a
+// load balancer: the instances elect the leader executing the tasks through a
+// lease renewed in the directory, and the followers forward the requests they
+// receive, event streams included, to it.
+package ha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/filelock"
+)
+
+// DefaultTTL is the validity of the lease when Elector.TTL is 0. The leader
+// renews it three times per TTL; a follower takes over within a TTL of the
+// leader's death.
+const DefaultTTL = 15 * time.Second
+
+// ErrLeaseLost is returned by Elector.Run when the leader failed to renew its
+// lease in time, or found another instance holding it. The leader must stop
+// executing tasks, since another instance may take them over.
+var ErrLeaseLost = errors.New("ha: leader lease lost")
+
+// Lease is the leadership record kept in the shared directory.
+type Lease struct {
+	Instance string    `json:"instance"`
+	URL      string    `json:"url"` // Where the followers forward the requests.
+	Expires  time.Time `json:"expires"`
+}
+
+func (l *Lease) valid(now time.Time) bool {
+	return l.Instance != "" && now.Before(l.Expires)
+}
+
+// Elector takes part in the leader election of the instances sharing Dir.
+type Elector struct {
+	// Dir is the directory shared by the instances.
+	Dir string
+	// Instance identifies this instance; it must be unique among them.
+	Instance string
+	// URL is the base URL the other instances reach this one at.
+	URL string
+	// TTL is the validity of the lease; 0 uses DefaultTTL.
+	TTL time.Duration
+
+	elected chan struct{}
+
+	mu      sync.Mutex
+	current Lease
+}
+
+// NewElector returns an Elector for the directory dir, created as needed.
+func NewElector(dir, instance, baseURL string) (*Elector, error) {
+	if instance == "" {
+		return nil, errors.New("ha: instance is required")
+	}
+	if _, err := url.Parse(baseURL); err != nil || baseURL == "" {
+		return nil, fmt.Errorf("ha: invalid instance URL %q", baseURL)
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Elector{Dir: dir, Instance: instance, URL: baseURL, elected: make(chan struct{})}, nil
+}
+
+// Run campaigns for the lease until ctx is done, and renews it once elected.
+// A leader releases the lease when ctx is done, so that a follower takes over
+// immediately. It returns ErrLeaseLost when the leader lost the lease.
+func (e *Elector) Run(ctx context.Context) error {
+	ttl := e.ttl()
+	t := time.NewTicker(ttl / 3)
+	defer t.Stop()
+	for {
+		if err := e.step(time.Now()); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			if e.IsLeader() {
+				if err := e.release(); err != nil {
+					slog.Warn("ha", "msg", "release lease", "err", err)
+				}
+			}
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Elected returns a channel closed once this instance is the leader.
+func (e *Elector) Elected() <-chan struct{} {
+	return e.elected
+}
+
+// IsLeader reports whether this instance holds the lease.
+func (e *Elector) IsLeader() bool {
+	select {
+	case <-e.elected:
+		return true
+	default:
+		return false
+	}
+}
+
+// Leader returns the lease last seen, which may have expired.
+func (e *Elector) Leader() Lease {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.current
+}
+
+// step acquires or renews the lease when it is free, expired or ours.
+func (e *Elector) step(now time.Time) error {
+	wasLeader := e.IsLeader()
+	l, err := e.acquire(now)
+	if err != nil {
+		slog.Warn("ha", "msg", "lease store unavailable", "err", err)
+		if l := e.Leader(); wasLeader && !l.valid(now) {
+			return ErrLeaseLost
+		}
+		return nil
+	}
+	e.mu.Lock()
+	prev := e.current
+	e.current = l
+	e.mu.Unlock()
+	switch {
+	case l.Instance == e.Instance && !wasLeader:
+		slog.Info("ha", "msg", "elected leader", "instance", e.Instance)
+		close(e.elected)
+	case l.Instance != e.Instance && wasLeader:
+		return ErrLeaseLost
+	case l.Instance != prev.Instance:
+		slog.Info("ha", "msg", "following", "leader", l.Instance, "url", l.URL)
+	}
+	return nil
+}
+
+func (e *Elector) acquire(now time.Time) (Lease, error) {
+	unlock, err := e.lock()
+	if err != nil {
+		return Lease{}, err
+	}
+	defer unlock()
+	l, err := e.read()
+	if err != nil {
+		return Lease{}, err
+	}
+	if l.valid(now) && l.Instance != e.Instance {
+		return l, nil
+	}
+	l = Lease{Instance: e.Instance, URL: e.URL, Expires: now.Add(e.ttl())}
+	return l, e.write(&l)
+}
+
+func (e *Elector) release() error {
+	unlock, err := e.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if l, err := e.read(); err != nil || l.Instance != e.Instance {
+		return err
+	}
+	return os.Remove(e.path())
+}
+
+// lock creates the lock file guarding the lease. A lock older than the TTL
+// was left by a crashed instance and is broken.
+func (e *Elector) lock() (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl()/3)
+	defer cancel()
+	unlock, err := filelock.Acquire(ctx, e.path()+".lock", e.ttl())
+	if err != nil {
+		return nil, fmt.Errorf("ha: lease lock: %w", err)
+	}
+	return unlock, nil
+}
+
+func (e *Elector) read() (Lease, error) {
+	var l Lease
+	data, err := os.ReadFile(e.path())
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		// A torn lease is as good as none.
+		slog.Warn("ha", "msg", "malformed lease", "err", err)
+		return Lease{}, nil
+	}
+	return l, nil
+}
+
+func (e *Elector) write(l *Lease) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	tmp := e.path() + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, e.path()); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (e *Elector) path() string {
+	return filepath.Join(e.Dir, "leader.json")
+}
+
+func (e *Elector) ttl() time.Duration {
+	if e.TTL > 0 {
+		return e.TTL
+	}
+	return DefaultTTL
+}
+
+// Proxy returns the handler of a follower: it forwards every request to the
+// leader, streaming the responses as they come so that event streams flow.
+// It answers 503 while no leader is known.
+func (e *Elector) Proxy() http.Handler {
+	rp := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			u, _ := url.Parse(e.Leader().URL)
+			pr.SetURL(u)
+			pr.SetXForwarded()
+			// Keep the public host, e.g. for the OAuth redirects.
+			pr.Out.Host = pr.In.Host
+		},
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.WarnContext(r.Context(), "ha", "msg", "forward to leader", "err", err)
+			http.Error(w, "leader unavailable", http.StatusBadGateway)
+		},
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l := e.Leader()
+		if !l.valid(time.Now()) || l.Instance == e.Instance {
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "no leader elected", http.StatusServiceUnavailable)
+			return
+		}
+		rp.ServeHTTP(w, r)
+	})
+}
+
+// Follow serves Proxy on addr, over TLS with certFile and keyFile when set,
+// until this instance is elected. It returns nil once elected and the
+// listener closed, so that the leader can listen on addr.
+func (e *Elector) Follow(ctx context.Context, addr, certFile, keyFile string) error {
+	srv := &http.Server{Addr: addr, Handler: e.Proxy(), ReadHeaderTimeout: 10 * time.Second}
+	done := make(chan error, 1)
+	go func() {
+		slog.Info("ha", "msg", "following", "addr", addr)
+		if certFile != "" {
+			done <- srv.ListenAndServeTLS(certFile, keyFile)
+		} else {
+			done <- srv.ListenAndServe()
+		}
+	}()
+	var err error
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-e.elected:
+	}
+	// Hand the streams over quickly: the clients reconnect to the leader.
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Second)
+	defer cancel()
+	_ = srv.Shutdown(shutdownCtx)
+	_ = srv.Close()
+	<-done
+	return err
+}
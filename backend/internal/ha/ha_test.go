@@ -0,0 +1,99 @@
+package ha
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestElector(t *testing.T) {
+	newElector := func(t *testing.T, dir, instance, baseURL string) *Elector {
+		t.Helper()
+		e, err := NewElector(dir, instance, baseURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return e
+	}
+	t.Run("Election", func(t *testing.T) {
+		dir := t.TempDir()
+		a := newElector(t, dir, "a", "http://a")
+		b := newElector(t, dir, "b", "http://b")
+		now := time.Now()
+		if err := a.step(now); err != nil || !a.IsLeader() {
+			t.Fatalf("a: leader = %t, %v", a.IsLeader(), err)
+		}
+		if err := b.step(now); err != nil || b.IsLeader() || b.Leader().Instance != "a" {
+			t.Fatalf("b: leader = %t, lease = %+v, %v", b.IsLeader(), b.Leader(), err)
+		}
+		// The leader renews its lease; b keeps following.
+		now = now.Add(DefaultTTL / 2)
+		if err := a.step(now); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.step(now.Add(DefaultTTL / 2)); err != nil || b.IsLeader() {
+			t.Fatalf("b took over a renewed lease: %v", err)
+		}
+		// a died: b takes over once the lease expired, and a, back, lost it.
+		now = now.Add(2 * DefaultTTL)
+		if err := b.step(now); err != nil || !b.IsLeader() {
+			t.Fatalf("b: leader = %t, %v", b.IsLeader(), err)
+		}
+		if err := a.step(now); !errors.Is(err, ErrLeaseLost) {
+			t.Errorf("a: err = %v, want ErrLeaseLost", err)
+		}
+	})
+	t.Run("Release", func(t *testing.T) {
+		dir := t.TempDir()
+		a := newElector(t, dir, "a", "http://a")
+		b := newElector(t, dir, "b", "http://b")
+		if err := a.step(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.release(); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.step(time.Now()); err != nil || !b.IsLeader() {
+			t.Errorf("b: leader = %t, %v", b.IsLeader(), err)
+		}
+	})
+	t.Run("Proxy", func(t *testing.T) {
+		leader := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, r.Method+" "+r.URL.Path)
+		}))
+		defer leader.Close()
+		dir := t.TempDir()
+		f := newElector(t, dir, "f", "http://f")
+		follower := httptest.NewServer(f.Proxy())
+		defer follower.Close()
+
+		// No leader yet.
+		resp, err := http.Post(follower.URL+"/api/v1/tasks", "application/json", nil) //nolint:noctx // test
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("status = %d, want 503", resp.StatusCode)
+		}
+
+		if err := newElector(t, dir, "l", leader.URL).step(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.step(time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		resp, err = http.Post(follower.URL+"/api/v1/tasks", "application/json", nil) //nolint:noctx // test
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if string(body) != "POST /api/v1/tasks" {
+			t.Errorf("body = %q", body)
+		}
+	})
+}
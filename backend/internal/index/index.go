@@ -0,0 +1,468 @@
+// Package index maintains in-memory trigram indexes of repository contents
+// for fast literal code search.
+//
+// An index covers the text files of one commit, normally the tip of the
+// remote base branch. Manager rebuilds it lazily when that ref moves, so the
+// index follows every fetch without hooking into the fetch itself.
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caic-xyz/md/gitutil"
+)
+
+const (
+	// maxFileSize is the size above which files are left out of the index;
+	// they are mostly generated or data files.
+	maxFileSize = 512 << 10
+	// maxIndexBytes caps the content held by one index.
+	maxIndexBytes = 256 << 20
+	// binarySniffLen is the prefix checked for NUL bytes to skip binaries.
+	binarySniffLen = 8000
+	// maxLineLen truncates the line text returned in matches.
+	maxLineLen = 300
+)
+
+// Index is an immutable trigram index of the text files of a commit.
+// Trigrams are computed on ASCII-lowercased content so searches are case
+// insensitive.
+type Index struct {
+	Commit    string    // Indexed commit SHA.
+	Built     time.Time // When the index was built.
+	Truncated bool      // Some files were skipped because maxIndexBytes was reached.
+
+	paths    []string
+	contents [][]byte
+	trigrams map[uint32][]uint32 // Trigram to the sorted IDs of the files containing it.
+	size     int64
+}
+
+// Files returns the number of indexed files.
+func (x *Index) Files() int { return len(x.paths) }
+
+// Size returns the number of indexed content bytes.
+func (x *Index) Size() int64 { return x.size }
+
+// Build indexes the text files of ref in the git repository at dir.
+func Build(ctx context.Context, dir, ref string) (*Index, error) {
+	commit, err := gitutil.RevParse(ctx, dir, ref+"^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	blobs, err := listBlobs(ctx, dir, commit)
+	if err != nil {
+		return nil, err
+	}
+	x := &Index{Commit: commit, Built: time.Now().UTC(), trigrams: map[uint32][]uint32{}}
+	err = readBlobs(ctx, dir, blobs, func(path string, content []byte) bool {
+		if x.size+int64(len(content)) > maxIndexBytes {
+			x.Truncated = true
+			return false
+		}
+		if bytes.IndexByte(content[:min(len(content), binarySniffLen)], 0) >= 0 {
+			return true
+		}
+		x.add(path, content)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// add indexes one file.
+func (x *Index) add(path string, content []byte) {
+	id := uint32(len(x.paths)) //nolint:gosec // bounded by maxIndexBytes.
+	x.paths = append(x.paths, path)
+	x.contents = append(x.contents, content)
+	x.size += int64(len(content))
+	seen := map[uint32]struct{}{}
+	for i := 0; i+3 <= len(content); i++ {
+		t := trigram(content[i:])
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		x.trigrams[t] = append(x.trigrams[t], id)
+	}
+}
+
+// Query is a search request.
+type Query struct {
+	Text string // Literal text; case insensitive.
+	// Word only matches Text delimited by non-identifier characters in file
+	// contents.
+	Word bool
+	// Max caps the returned paths and matches each; 0 means no limit.
+	Max int
+}
+
+// Match is a line containing the query.
+type Match struct {
+	Path string
+	Line int    // 1-based.
+	Text string // The line, truncated to maxLineLen bytes.
+}
+
+// Result is the outcome of a search.
+type Result struct {
+	Paths     []string // Indexed paths containing the query, sorted.
+	Matches   []Match  // Lines containing the query, by path then line.
+	Truncated bool     // Query.Max was reached.
+}
+
+// Search returns the paths and lines containing q.Text. Queries shorter
+// than a trigram only match paths.
+func (x *Index) Search(q Query) *Result {
+	res := &Result{}
+	needle := lowerASCII([]byte(q.Text))
+	if len(needle) == 0 {
+		return res
+	}
+	for _, p := range x.paths {
+		if bytes.Contains(lowerASCII([]byte(p)), needle) {
+			res.Paths = append(res.Paths, p)
+		}
+	}
+	slices.Sort(res.Paths)
+	if q.Max > 0 && len(res.Paths) > q.Max {
+		res.Paths = res.Paths[:q.Max]
+		res.Truncated = true
+	}
+	if len(needle) < 3 {
+		return res
+	}
+	for _, id := range x.candidates(needle) {
+		lower := lowerASCII(x.contents[id])
+		for off := 0; off < len(lower); {
+			i := bytes.Index(lower[off:], needle)
+			if i < 0 {
+				break
+			}
+			i += off
+			if q.Word && !isWord(lower, i, len(needle)) {
+				off = i + 1
+				continue
+			}
+			res.Matches = append(res.Matches, x.lineAt(id, i))
+			// Report each line once.
+			nl := bytes.IndexByte(lower[i:], '\n')
+			if nl < 0 {
+				break
+			}
+			off = i + nl + 1
+		}
+	}
+	slices.SortStableFunc(res.Matches, func(a, b Match) int { return strings.Compare(a.Path, b.Path) })
+	if q.Max > 0 && len(res.Matches) > q.Max {
+		res.Matches = res.Matches[:q.Max]
+		res.Truncated = true
+	}
+	return res
+}
+
+// candidates returns the IDs of the files containing every trigram of
+// needle.
+func (x *Index) candidates(needle []byte) []uint32 {
+	var out []uint32
+	for i := 0; i+3 <= len(needle); i++ {
+		ids := x.trigrams[trigram(needle[i:])]
+		if i == 0 {
+			out = slices.Clone(ids)
+		} else {
+			out = intersect(out, ids)
+		}
+		if len(out) == 0 {
+			return nil
+		}
+	}
+	return out
+}
+
+// lineAt returns the line of file id containing offset off.
+func (x *Index) lineAt(id uint32, off int) Match {
+	c := x.contents[id]
+	start := bytes.LastIndexByte(c[:off], '\n') + 1
+	end := bytes.IndexByte(c[off:], '\n')
+	if end < 0 {
+		end = len(c)
+	} else {
+		end += off
+	}
+	line := strings.TrimRight(string(c[start:min(end, start+maxLineLen)]), "\r")
+	return Match{Path: x.paths[id], Line: bytes.Count(c[:start], []byte{'\n'}) + 1, Text: line}
+}
+
+// Manager keeps one index per repository and rebuilds it when the indexed
+// ref moves.
+type Manager struct {
+	embedder Embedder // nil disables semantic search.
+
+	mu    sync.Mutex
+	repos map[string]*repoIndex // Keyed by repository directory.
+}
+
+type repoIndex struct {
+	mu  sync.Mutex // Serializes builds.
+	idx *Index
+
+	semMu sync.Mutex // Serializes semantic builds, which are much slower.
+	sem   *SemanticIndex
+}
+
+// NewManager returns an empty Manager. e enables semantic search; it may be
+// nil.
+func NewManager(e Embedder) *Manager {
+	return &Manager{embedder: e, repos: map[string]*repoIndex{}}
+}
+
+// Semantic reports whether semantic search is enabled.
+func (m *Manager) Semantic() bool {
+	return m.embedder != nil
+}
+
+// Get returns the index of the base branch of the repository at dir,
+// building or refreshing it when the branch moved. The remote tracking ref
+// origin/<branch> is preferred; the local branch is used when the branch was
+// never pushed.
+func (m *Manager) Get(ctx context.Context, dir, branch string) (*Index, error) {
+	commit, err := gitutil.RevParse(ctx, dir, "origin/"+branch+"^{commit}")
+	if err != nil {
+		if commit, err = gitutil.RevParse(ctx, dir, branch+"^{commit}"); err != nil {
+			return nil, err
+		}
+	}
+	r := m.repo(dir)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.idx != nil && r.idx.Commit == commit {
+		return r.idx, nil
+	}
+	idx, err := Build(ctx, dir, commit)
+	if err != nil {
+		return nil, err
+	}
+	r.idx = idx
+	return idx, nil
+}
+
+// SemanticSearch returns the k chunks of the base branch of the repository
+// at dir most similar in meaning to query, along with the indexed commit.
+// The first search of a commit embeds the changed chunks, which can take a
+// while on a large repository.
+func (m *Manager) SemanticSearch(ctx context.Context, dir, branch, query string, k int) (string, []SemanticMatch, error) {
+	if m.embedder == nil {
+		return "", nil, ErrNoEmbedder
+	}
+	x, err := m.Get(ctx, dir, branch)
+	if err != nil {
+		return "", nil, err
+	}
+	r := m.repo(dir)
+	r.semMu.Lock()
+	sem := r.sem
+	if sem == nil || sem.Commit != x.Commit {
+		if sem, err = buildSemantic(ctx, x, m.embedder, r.sem); err != nil {
+			r.semMu.Unlock()
+			return "", nil, err
+		}
+		r.sem = sem
+	}
+	r.semMu.Unlock()
+	vecs, err := m.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return "", nil, err
+	}
+	return sem.Commit, sem.search(normalize(vecs[0]), k), nil
+}
+
+// repo returns the entry of the repository at dir, creating it.
+func (m *Manager) repo(dir string) *repoIndex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := m.repos[dir]
+	if r == nil {
+		r = &repoIndex{}
+		m.repos[dir] = r
+	}
+	return r
+}
+
+// blob is a file of the indexed tree.
+type blob struct {
+	sha, path string
+}
+
+// listBlobs lists the regular files of commit no larger than maxFileSize.
+func listBlobs(ctx context.Context, dir, commit string) ([]blob, error) {
+	out, err := runGit(ctx, dir, "ls-tree", "-r", "-z", "-l", commit)
+	if err != nil {
+		return nil, err
+	}
+	var blobs []blob
+	for entry := range bytes.SplitSeq(out, []byte{0}) {
+		// <mode> SP <type> SP <sha> SP+ <size> TAB <path>
+		meta, path, ok := bytes.Cut(entry, []byte{'\t'})
+		if !ok {
+			continue
+		}
+		f := strings.Fields(string(meta))
+		if len(f) != 4 || f[1] != "blob" || (f[0] != "100644" && f[0] != "100755") {
+			continue
+		}
+		if size, err := strconv.ParseInt(f[3], 10, 64); err != nil || size > maxFileSize {
+			continue
+		}
+		blobs = append(blobs, blob{sha: f[2], path: string(path)})
+	}
+	return blobs, nil
+}
+
+// readBlobs streams the contents of blobs through git cat-file --batch and
+// calls fn for each, stopping early when fn returns false.
+func readBlobs(ctx context.Context, dir string, blobs []blob, fn func(path string, content []byte) bool) error {
+	if len(blobs) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var in bytes.Buffer
+	for _, b := range blobs {
+		in.WriteString(b.sha + "\n")
+	}
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "--batch")
+	cmd.Dir = dir
+	cmd.Stdin = &in
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	rd := bufio.NewReader(stdout)
+	stopped := false
+	for _, b := range blobs {
+		header, err := rd.ReadString('\n')
+		if err != nil {
+			_ = cmd.Wait()
+			return fmt.Errorf("git cat-file: %w: %s", err, stderr.String())
+		}
+		// <sha> SP <type> SP <size> LF <content> LF
+		f := strings.Fields(header)
+		if len(f) != 3 {
+			// "<sha> missing"; nothing follows.
+			continue
+		}
+		size, err := strconv.Atoi(f[2])
+		if err != nil {
+			_ = cmd.Wait()
+			return fmt.Errorf("git cat-file: bad header %q", header)
+		}
+		content := make([]byte, size)
+		if _, err := io.ReadFull(rd, content); err != nil {
+			_ = cmd.Wait()
+			return fmt.Errorf("git cat-file: %w", err)
+		}
+		if _, err := rd.Discard(1); err != nil {
+			_ = cmd.Wait()
+			return fmt.Errorf("git cat-file: %w", err)
+		}
+		if !fn(b.path, content) {
+			stopped = true
+			break
+		}
+	}
+	if stopped {
+		cancel()
+		_ = cmd.Wait()
+		return nil
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("git cat-file: %w: %s", err, stderr.String())
+	}
+	return nil
+}
+
+// runGit runs git in dir and returns its stdout.
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "LANG=C")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// trigram packs the first three bytes of b, ASCII-lowercased.
+func trigram(b []byte) uint32 {
+	return uint32(lowerByte(b[0]))<<16 | uint32(lowerByte(b[1]))<<8 | uint32(lowerByte(b[2]))
+}
+
+// lowerASCII returns a copy of b with ASCII letters lowercased. Unlike
+// bytes.ToLower it preserves offsets.
+func lowerASCII(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[i] = lowerByte(c)
+	}
+	return out
+}
+
+func lowerByte(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// isWord reports whether b[i:i+n] is not adjacent to identifier characters.
+func isWord(b []byte, i, n int) bool {
+	return (i == 0 || !isIdentByte(b[i-1])) && (i+n == len(b) || !isIdentByte(b[i+n]))
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// intersect returns the IDs present in both sorted slices, reusing a.
+func intersect(a, b []uint32) []uint32 {
+	out := a[:0]
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
@@ -0,0 +1,202 @@
+// Tests for the trigram index.
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	dir := t.TempDir()
+	runGit := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...) //nolint:gosec // test helper with controlled args
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit("init", "-q", "-b", "main")
+	write("client/client.go", "package client\n\nfunc newClient() *Client {\n\treturn &Client{policy: RetryPolicy{}}\n}\n")
+	write("client/retry.go", "package client\n\n// RetryPolicy backs off.\ntype RetryPolicy struct{}\n\ntype RetryPolicyX struct{}\n")
+	write("bin.dat", "abc\x00RetryPolicy")
+	runGit("add", ".")
+	runGit("commit", "-q", "-m", "init")
+
+	m := NewManager(nil)
+	x, err := m.Get(t.Context(), dir, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Files() != 2 {
+		t.Errorf("Files() = %d, want 2 (binary skipped)", x.Files())
+	}
+	t.Run("Search", func(t *testing.T) {
+		for _, tc := range []struct {
+			name string
+			q    Query
+			want Result
+		}{
+			{"content", Query{Text: "retrypolicy{"}, Result{Matches: []Match{{Path: "client/client.go", Line: 4, Text: "\treturn &Client{policy: RetryPolicy{}}"}}}},
+			{"word", Query{Text: "RetryPolicy", Word: true}, Result{Matches: []Match{
+				{Path: "client/client.go", Line: 4, Text: "\treturn &Client{policy: RetryPolicy{}}"},
+				{Path: "client/retry.go", Line: 3, Text: "// RetryPolicy backs off."},
+				{Path: "client/retry.go", Line: 4, Text: "type RetryPolicy struct{}"},
+			}}},
+			{"max", Query{Text: "RetryPolicy", Max: 1}, Result{
+				Matches:   []Match{{Path: "client/client.go", Line: 4, Text: "\treturn &Client{policy: RetryPolicy{}}"}},
+				Truncated: true,
+			}},
+			{"path", Query{Text: "retry.go"}, Result{Paths: []string{"client/retry.go"}}},
+			{"short", Query{Text: "cl"}, Result{Paths: []string{"client/client.go", "client/retry.go"}}},
+			{"none", Query{Text: "nothing here"}, Result{}},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				if got := x.Search(tc.q); !reflect.DeepEqual(*got, tc.want) {
+					t.Errorf("got %+v\nwant %+v", *got, tc.want)
+				}
+			})
+		}
+	})
+	t.Run("refresh", func(t *testing.T) {
+		if again, err := m.Get(t.Context(), dir, "main"); err != nil || again != x {
+			t.Fatalf("unchanged ref rebuilt the index: %v", err)
+		}
+		write("server.go", "package main // RetryPolicy\n")
+		runGit("add", ".")
+		runGit("commit", "-q", "-m", "more")
+		y, err := m.Get(t.Context(), dir, "main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if y == x || y.Files() != 3 {
+			t.Errorf("index not refreshed: %d files", y.Files())
+		}
+	})
+}
+
+// fakeEmbedder embeds texts as keyword counts, one dimension per keyword.
+type fakeEmbedder struct {
+	keywords []string
+	calls    int
+	texts    int
+}
+
+func (f *fakeEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	f.calls++
+	f.texts += len(texts)
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, len(f.keywords)+1)
+		v[len(f.keywords)] = 0.01 // Avoid zero vectors.
+		for j, k := range f.keywords {
+			v[j] = float32(strings.Count(strings.ToLower(text), k))
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func TestSemanticSearch(t *testing.T) {
+	dir := t.TempDir()
+	runGit := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...) //nolint:gosec // test helper with controlled args
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runGit("init", "-q", "-b", "main")
+	write("auth.go", "package x\n// login checks the password\nfunc login() {}\n")
+	write("net.go", "package x\n// dial retries the socket connection\nfunc dial() {}\n")
+	runGit("add", ".")
+	runGit("commit", "-q", "-m", "init")
+
+	if _, _, err := NewManager(nil).SemanticSearch(t.Context(), dir, "main", "q", 1); !errors.Is(err, ErrNoEmbedder) {
+		t.Fatalf("err = %v, want ErrNoEmbedder", err)
+	}
+	e := &fakeEmbedder{keywords: []string{"password", "socket"}}
+	m := NewManager(e)
+	_, got, err := m.SemanticSearch(t.Context(), dir, "main", "where is the password verified", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Path != "auth.go" || got[0].StartLine != 1 || got[0].EndLine != 3 {
+		t.Errorf("got %+v", got)
+	}
+	if e.texts != 3 {
+		t.Errorf("embedded %d texts, want 2 chunks + 1 query", e.texts)
+	}
+	// Only the changed file is embedded again after the branch moves.
+	write("net.go", "package x\n// dial opens the socket\nfunc dial() {}\n")
+	runGit("commit", "-q", "-am", "more")
+	if _, got, err = m.SemanticSearch(t.Context(), dir, "main", "socket", 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Path != "net.go" {
+		t.Errorf("got %+v", got)
+	}
+	if e.texts != 5 {
+		t.Errorf("embedded %d texts, want 5", e.texts)
+	}
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer k" {
+			http.Error(w, "bad request", http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "m" {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		// Answer out of order; the index field is authoritative.
+		_, _ = w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+	e := &OpenAIEmbedder{BaseURL: srv.URL + "/v1/", Model: "m", APIKey: "k"}
+	got, err := e.Embed(t.Context(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]float32{{1, 0}, {0, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	e.APIKey = "wrong"
+	if _, err := e.Embed(t.Context(), []string{"a"}); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -0,0 +1,160 @@
+// Semantic search: files are split into line chunks whose embeddings are
+// compared with the embedding of the query.
+package index
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math"
+	"slices"
+)
+
+const (
+	// chunkLines is the number of lines per embedded chunk.
+	chunkLines = 40
+	// maxChunkBytes truncates chunks with very long lines.
+	maxChunkBytes = 4000
+	// maxChunks caps the chunks embedded per repository.
+	maxChunks = 10_000
+	// embedBatch is the number of chunks sent per embedding request.
+	embedBatch = 64
+)
+
+// ErrNoEmbedder is returned by semantic searches when no embedding provider
+// is configured.
+var ErrNoEmbedder = errors.New("semantic search is not configured")
+
+// SemanticIndex holds the embeddings of the chunks of an Index.
+type SemanticIndex struct {
+	Commit    string
+	Truncated bool // Some chunks were skipped because maxChunks was reached.
+
+	chunks []chunk
+	vecs   [][]float32 // Normalized; parallel to chunks.
+}
+
+// chunk is a range of lines of a file.
+type chunk struct {
+	path       string
+	start, end int // 1-based, inclusive.
+	hash       [sha256.Size]byte
+}
+
+// SemanticMatch is a chunk similar to the query.
+type SemanticMatch struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Score     float32 // Cosine similarity.
+}
+
+// buildSemantic embeds the chunks of x. Embeddings of unchanged chunks are
+// reused from prev, so a refresh after a fetch only embeds what changed.
+func buildSemantic(ctx context.Context, x *Index, e Embedder, prev *SemanticIndex) (*SemanticIndex, error) {
+	cached := map[[sha256.Size]byte][]float32{}
+	if prev != nil {
+		for i, c := range prev.chunks {
+			cached[c.hash] = prev.vecs[i]
+		}
+	}
+	s := &SemanticIndex{Commit: x.Commit}
+	var texts []string
+	var missing []int
+files:
+	for id, content := range x.contents {
+		for _, c := range splitChunks(x.paths[id], content) {
+			if len(s.chunks) == maxChunks {
+				s.Truncated = true
+				break files
+			}
+			i := len(s.chunks)
+			s.chunks = append(s.chunks, chunk{path: c.path, start: c.start, end: c.end, hash: sha256.Sum256([]byte(c.text))})
+			s.vecs = append(s.vecs, cached[s.chunks[i].hash])
+			if s.vecs[i] == nil {
+				texts = append(texts, c.text)
+				missing = append(missing, i)
+			}
+		}
+	}
+	for len(texts) > 0 {
+		n := min(len(texts), embedBatch)
+		vecs, err := e.Embed(ctx, texts[:n])
+		if err != nil {
+			return nil, err
+		}
+		for j, v := range vecs {
+			s.vecs[missing[j]] = normalize(v)
+		}
+		texts, missing = texts[n:], missing[n:]
+	}
+	return s, nil
+}
+
+// chunkText is a chunk with the text to embed.
+type chunkText struct {
+	path       string
+	start, end int
+	text       string
+}
+
+// splitChunks splits content in chunks of chunkLines lines, skipping blank
+// ones. The path prefixes each text since it is often the best hint of what
+// the code does.
+func splitChunks(path string, content []byte) []chunkText {
+	var out []chunkText
+	lines := bytes.SplitAfter(content, []byte{'\n'})
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	for start := 0; start < len(lines); start += chunkLines {
+		end := min(start+chunkLines, len(lines))
+		body := bytes.Join(lines[start:end], nil)
+		if len(bytes.TrimSpace(body)) == 0 {
+			continue
+		}
+		text := path + "\n" + string(body[:min(len(body), maxChunkBytes)])
+		out = append(out, chunkText{path: path, start: start + 1, end: end, text: text})
+	}
+	return out
+}
+
+// search returns the k chunks most similar to the normalized vector q,
+// ignoring unrelated ones with a non-positive similarity.
+func (s *SemanticIndex) search(q []float32, k int) []SemanticMatch {
+	out := make([]SemanticMatch, 0, len(s.chunks))
+	for i, c := range s.chunks {
+		if len(s.vecs[i]) != len(q) {
+			continue
+		}
+		var dot float32
+		for j, v := range s.vecs[i] {
+			dot += v * q[j]
+		}
+		if dot <= 0 {
+			continue
+		}
+		out = append(out, SemanticMatch{Path: c.path, StartLine: c.start, EndLine: c.end, Score: dot})
+	}
+	slices.SortFunc(out, func(a, b SemanticMatch) int { return cmp.Compare(b.Score, a.Score) })
+	return out[:min(k, len(out))]
+}
+
+// normalize scales v to unit length in place so cosine similarity is a dot
+// product.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i := range v {
+		v[i] *= inv
+	}
+	return v
+}
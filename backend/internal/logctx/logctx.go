@@ -0,0 +1,77 @@
+// Package logctx carries slog attributes in a context.Context, so that log
+// lines emitted with the *Context slog functions deep in a call chain include
+// the request ID and task ID of the operation they belong to.
+package logctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+)
+
+type ctxKey struct{}
+
+// With returns a copy of ctx whose log lines include args, which are
+// key-value pairs as accepted by slog.Logger.Info. A key already present in
+// ctx is replaced.
+func With(ctx context.Context, args ...any) context.Context {
+	add := slog.Group("", args...).Value.Group()
+	prev := Attrs(ctx)
+	attrs := make([]slog.Attr, 0, len(prev)+len(add))
+	for _, a := range prev {
+		if !slices.ContainsFunc(add, func(b slog.Attr) bool { return b.Key == a.Key }) {
+			attrs = append(attrs, a)
+		}
+	}
+	return context.WithValue(ctx, ctxKey{}, append(attrs, add...))
+}
+
+// Attrs returns the attributes added to ctx with With.
+func Attrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	return attrs
+}
+
+// Logger returns the default logger with the attributes of ctx bound, for
+// long-lived work that outlives calls carrying ctx.
+func Logger(ctx context.Context) *slog.Logger {
+	return slog.New(slog.Default().Handler().WithAttrs(Attrs(ctx)))
+}
+
+// NewRequestID returns a random ID identifying one HTTP request in the logs.
+func NewRequestID() string {
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Handler wraps a slog.Handler to add the attributes of the record's context.
+type Handler struct {
+	slog.Handler
+}
+
+// NewHandler returns a Handler wrapping h.
+func NewHandler(h slog.Handler) *Handler {
+	return &Handler{Handler: h}
+}
+
+// Handle adds the attributes of ctx to r and passes it to the wrapped handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs := Attrs(ctx); len(attrs) != 0 {
+		r = r.Clone()
+		r.AddAttrs(attrs...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,67 @@
+package logctx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil))).With("repo", "caic")
+	ctx := With(context.Background(), "req", "r1", "task", "t1")
+	ctx = With(ctx, "task", "t2")
+	log.InfoContext(ctx, "hello", "n", 1)
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]any{"msg": "hello", "repo": "caic", "req": "r1", "task": "t2", "n": float64(1)} {
+		if got[k] != want {
+			t.Errorf("%s = %v, want %v", k, got[k], want)
+		}
+	}
+
+	t.Run("NoContextAttrs", func(t *testing.T) {
+		buf.Reset()
+		log.Info("bare")
+		got := map[string]any{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := got["req"]; ok {
+			t.Errorf("unexpected req in %v", got)
+		}
+	})
+}
+
+func TestNewRequestID(t *testing.T) {
+	a, b := NewRequestID(), NewRequestID()
+	if len(a) != 12 || a == b {
+		t.Errorf("NewRequestID() = %q, %q", a, b)
+	}
+}
+
+func TestRing(t *testing.T) {
+	r := NewRing(3)
+	log := slog.New(Tee(slog.NewTextHandler(io.Discard, nil), slog.NewJSONHandler(r, nil)))
+	for i := range 5 {
+		log.Info("line", "i", i)
+	}
+	lines := r.Lines()
+	if len(lines) != 3 {
+		t.Fatalf("len(Lines()) = %d, want 3", len(lines))
+	}
+	for i, l := range lines {
+		var m map[string]any
+		if err := json.Unmarshal(l, &m); err != nil {
+			t.Fatal(err)
+		}
+		if m["i"] != float64(i+2) {
+			t.Errorf("line %d: i = %v, want %d", i, m["i"], i+2)
+		}
+	}
+}
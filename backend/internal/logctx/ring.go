@@ -0,0 +1,96 @@
+// Recent log retention and fan-out to several handlers.
+package logctx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// Ring is an io.Writer keeping the last lines written to it, for handlers
+// that write one record per Write such as slog.JSONHandler.
+type Ring struct {
+	mu    sync.Mutex
+	lines [][]byte
+	next  int
+	full  bool
+}
+
+// NewRing returns a Ring keeping the last n lines.
+func NewRing(n int) *Ring {
+	return &Ring{lines: make([][]byte, n)}
+}
+
+// Write stores a copy of p as one line.
+func (r *Ring) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines[r.next] = append(r.lines[r.next][:0], p...)
+	r.next++
+	if r.next == len(r.lines) {
+		r.next = 0
+		r.full = true
+	}
+	return len(p), nil
+}
+
+// Lines returns copies of the kept lines, oldest first.
+func (r *Ring) Lines() [][]byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out [][]byte
+	if r.full {
+		for _, l := range r.lines[r.next:] {
+			out = append(out, append([]byte(nil), l...))
+		}
+	}
+	for _, l := range r.lines[:r.next] {
+		out = append(out, append([]byte(nil), l...))
+	}
+	return out
+}
+
+// tee is a slog.Handler sending records to several handlers.
+type tee []slog.Handler
+
+// Tee returns a handler sending each record to every handler enabled for its
+// level.
+func Tee(handlers ...slog.Handler) slog.Handler {
+	return tee(handlers)
+}
+
+func (t tee) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t tee) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t tee) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(tee, len(t))
+	for i, h := range t {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (t tee) WithGroup(name string) slog.Handler {
+	out := make(tee, len(t))
+	for i, h := range t {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+// Matrix rooms through the client-server API.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Matrix posts to a Matrix room as the account of its access token, which
+// must have joined the room.
+type Matrix struct {
+	HTTPClient *http.Client
+	Homeserver string // e.g. "https://matrix.example.org"
+	Token      string
+	Room       string // Room ID, e.g. "!abc:example.org".
+}
+
+var _ Channel = (*Matrix)(nil)
+
+// matrixTxn makes the transaction IDs of a process unique.
+var matrixTxn atomic.Int64
+
+// Send implements Channel.
+func (m *Matrix) Send(ctx context.Context, text string) error {
+	// The transaction ID makes retries of the same PUT idempotent.
+	txn := "caic." + strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatInt(matrixTxn.Add(1), 36)
+	apiURL := strings.TrimSuffix(m.Homeserver, "/") + "/_matrix/client/v3/rooms/" + url.PathEscape(m.Room) + "/send/m.room.message/" + txn
+	header := http.Header{"Authorization": {"Bearer " + m.Token}}
+	body := map[string]string{"msgtype": "m.text", "body": text}
+	if _, err := sendJSON(ctx, m.HTTPClient, http.MethodPut, apiURL, header, body); err != nil {
+		return fmt.Errorf("matrix: %w", err)
+	}
+	return nil
+}
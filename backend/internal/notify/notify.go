@@ -0,0 +1,138 @@
+// Package notify sends task notifications to chat channels: Slack, Discord,
+// Matrix and Telegram.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/maruel/roundtrippers"
+)
+
+// Kind identifies a chat service.
+type Kind string
+
+// Supported chat services.
+const (
+	KindSlack    Kind = "slack"
+	KindDiscord  Kind = "discord"
+	KindMatrix   Kind = "matrix"
+	KindTelegram Kind = "telegram"
+)
+
+// Channel is a destination of notifications.
+type Channel interface {
+	// Send posts the plain text message.
+	Send(ctx context.Context, text string) error
+}
+
+// Config configures a Channel. The fields used depend on Kind:
+//
+//   - slack, discord: Secret is the incoming webhook URL.
+//   - matrix: URL is the homeserver, Secret an access token of the sending
+//     account and Target the room ID, e.g. "!abc:example.org".
+//   - telegram: Secret is the bot token and Target the chat ID; the chat of
+//     a user with the bot sends direct messages.
+type Config struct {
+	Kind   Kind
+	URL    string
+	Secret string
+	Target string
+}
+
+// Validate checks that the fields Kind needs are set.
+func (c *Config) Validate() error {
+	switch c.Kind {
+	case KindSlack, KindDiscord:
+		if err := validateURL(c.Secret); err != nil {
+			return fmt.Errorf("webhook URL: %w", err)
+		}
+	case KindMatrix:
+		if err := validateURL(c.URL); err != nil {
+			return fmt.Errorf("homeserver URL: %w", err)
+		}
+		if c.Secret == "" {
+			return errors.New("access token required")
+		}
+		if c.Target == "" {
+			return errors.New("room ID required")
+		}
+	case KindTelegram:
+		if c.Secret == "" {
+			return errors.New("bot token required")
+		}
+		if c.Target == "" {
+			return errors.New("chat ID required")
+		}
+	default:
+		return fmt.Errorf("unknown kind %q", c.Kind)
+	}
+	return nil
+}
+
+// New returns the Channel configured by c.
+func New(c *Config) (Channel, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: &roundtrippers.Retry{Transport: http.DefaultTransport}}
+	switch c.Kind {
+	case KindSlack:
+		return &Slack{HTTPClient: client, WebhookURL: c.Secret}, nil
+	case KindDiscord:
+		return &Discord{HTTPClient: client, WebhookURL: c.Secret}, nil
+	case KindMatrix:
+		return &Matrix{HTTPClient: client, Homeserver: c.URL, Token: c.Secret, Room: c.Target}, nil
+	default:
+		return &Telegram{HTTPClient: client, APIURL: telegramAPI, Token: c.Secret, ChatID: c.Target}, nil
+	}
+}
+
+// validateURL checks that s is an absolute http or https URL.
+func validateURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an http or https URL")
+	}
+	return nil
+}
+
+// sendJSON sends body as JSON and returns the response body. Any status
+// other than 2xx is an error. Errors omit apiURL, which may hold a secret.
+func sendJSON(ctx context.Context, client *http.Client, method, apiURL string, header http.Header, body any) ([]byte, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			return nil, uerr.Err
+		}
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, data)
+	}
+	return data, nil
+}
@@ -0,0 +1,104 @@
+// Tests for the notification channels.
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recorder is a chat service stub recording the last request.
+type recorder struct {
+	method, path, auth string
+	body               map[string]string
+	reply              string
+}
+
+func (r *recorder) serve(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.method, r.path, r.auth = req.Method, req.URL.Path, req.Header.Get("Authorization")
+		r.body = nil
+		_ = json.NewDecoder(req.Body).Decode(&r.body)
+		_, _ = io.WriteString(w, r.reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChannels(t *testing.T) {
+	t.Run("Slack", func(t *testing.T) {
+		r := &recorder{reply: "ok"}
+		srv := r.serve(t)
+		c, err := New(&Config{Kind: KindSlack, Secret: srv.URL + "/services/T/B/x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Send(t.Context(), "hi"); err != nil {
+			t.Fatal(err)
+		}
+		if r.method != "POST" || r.path != "/services/T/B/x" || r.body["text"] != "hi" {
+			t.Errorf("request = %s %s %v", r.method, r.path, r.body)
+		}
+	})
+	t.Run("Discord", func(t *testing.T) {
+		r := &recorder{}
+		srv := r.serve(t)
+		c, err := New(&Config{Kind: KindDiscord, Secret: srv.URL + "/api/webhooks/1/x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Send(t.Context(), strings.Repeat("é", 3000)); err != nil {
+			t.Fatal(err)
+		}
+		if got := []rune(r.body["content"]); len(got) != maxDiscordContent {
+			t.Errorf("content length = %d, want %d", len(got), maxDiscordContent)
+		}
+	})
+	t.Run("Matrix", func(t *testing.T) {
+		r := &recorder{reply: `{"event_id":"$1"}`}
+		srv := r.serve(t)
+		c, err := New(&Config{Kind: KindMatrix, URL: srv.URL + "/", Secret: "syt_x", Target: "!room:example.org"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Send(t.Context(), "hi"); err != nil {
+			t.Fatal(err)
+		}
+		if r.method != "PUT" || !strings.HasPrefix(r.path, "/_matrix/client/v3/rooms/!room:example.org/send/m.room.message/caic.") || r.auth != "Bearer syt_x" {
+			t.Errorf("request = %s %s auth %q", r.method, r.path, r.auth)
+		}
+		if r.body["msgtype"] != "m.text" || r.body["body"] != "hi" {
+			t.Errorf("body = %v", r.body)
+		}
+	})
+	t.Run("Telegram", func(t *testing.T) {
+		r := &recorder{reply: `{"ok":true}`}
+		srv := r.serve(t)
+		c := &Telegram{HTTPClient: srv.Client(), APIURL: srv.URL, Token: "123:abc", ChatID: "42"}
+		if err := c.Send(t.Context(), "hi"); err != nil {
+			t.Fatal(err)
+		}
+		if r.path != "/bot123:abc/sendMessage" || r.body["chat_id"] != "42" || r.body["text"] != "hi" {
+			t.Errorf("request = %s %v", r.path, r.body)
+		}
+		r.reply = `{"ok":false,"description":"Bad Request: chat not found"}`
+		if err := c.Send(t.Context(), "hi"); err == nil || err.Error() != "telegram: Bad Request: chat not found" {
+			t.Errorf("err = %v", err)
+		}
+	})
+	t.Run("Validate", func(t *testing.T) {
+		for _, c := range []Config{
+			{Kind: "irc"},
+			{Kind: KindSlack, Secret: "hooks.slack.com/x"},
+			{Kind: KindMatrix, URL: "https://m.org", Secret: "t"},
+			{Kind: KindTelegram, Secret: "t"},
+		} {
+			if err := c.Validate(); err == nil {
+				t.Errorf("Validate(%+v) succeeded", c)
+			}
+		}
+	})
+}
@@ -0,0 +1,48 @@
+// Telegram chats through the Bot API.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Telegram posts to a Telegram chat as a bot.
+type Telegram struct {
+	HTTPClient *http.Client
+	APIURL     string // Bot API base URL; defaults to telegramAPI.
+	Token      string
+	ChatID     string // Chat ID, or "@channelusername" for public channels.
+}
+
+var _ Channel = (*Telegram)(nil)
+
+const telegramAPI = "https://api.telegram.org"
+
+// maxTelegramText is the message length limit of Telegram.
+const maxTelegramText = 4096
+
+// Send implements Channel. Longer messages are truncated to the Telegram
+// limit.
+func (t *Telegram) Send(ctx context.Context, text string) error {
+	if r := []rune(text); len(r) > maxTelegramText {
+		text = string(r[:maxTelegramText-1]) + "…"
+	}
+	body := map[string]string{"chat_id": t.ChatID, "text": text}
+	data, err := sendJSON(ctx, t.HTTPClient, http.MethodPost, t.APIURL+"/bot"+t.Token+"/sendMessage", nil, body)
+	if err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+	var r struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+	if !r.OK {
+		return fmt.Errorf("telegram: %s", r.Description)
+	}
+	return nil
+}
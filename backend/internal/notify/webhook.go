@@ -0,0 +1,47 @@
+// Slack and Discord incoming webhooks, which post to the channel they were
+// created for.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Slack posts to a Slack incoming webhook.
+type Slack struct {
+	HTTPClient *http.Client
+	WebhookURL string
+}
+
+var _ Channel = (*Slack)(nil)
+
+// Send implements Channel.
+func (s *Slack) Send(ctx context.Context, text string) error {
+	if _, err := sendJSON(ctx, s.HTTPClient, http.MethodPost, s.WebhookURL, nil, map[string]string{"text": text}); err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	return nil
+}
+
+// Discord posts to a Discord webhook.
+type Discord struct {
+	HTTPClient *http.Client
+	WebhookURL string
+}
+
+var _ Channel = (*Discord)(nil)
+
+// maxDiscordContent is the message length limit of Discord.
+const maxDiscordContent = 2000
+
+// Send implements Channel. Longer messages are truncated to the Discord limit.
+func (d *Discord) Send(ctx context.Context, text string) error {
+	if r := []rune(text); len(r) > maxDiscordContent {
+		text = string(r[:maxDiscordContent-1]) + "…"
+	}
+	if _, err := sendJSON(ctx, d.HTTPClient, http.MethodPost, d.WebhookURL, nil, map[string]string{"content": text}); err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// Package policy evaluates agent tool calls against user-defined rules, e.g.
+// "deny network access except registry.npmjs.org" or "deny rm -rf outside the
+// workspace". It works on tool names and inputs normalized by the agent
+// package, so it is independent of the harness.
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Kind is the kind of a rule.
+type Kind string
+
+// Rule kinds.
+const (
+	// KindNetwork denies network access to hosts not matched by Allow. Hosts
+	// are taken from URLs in tool inputs and shell commands, and from the
+	// registries implied by package manager commands.
+	KindNetwork Kind = "network"
+	// KindCommand denies shell commands matching Pattern, a regular
+	// expression matched against each simple command of a command line.
+	KindCommand Kind = "command"
+	// KindPath denies file writes and deletions outside the workspace and the
+	// directories listed in Allow.
+	KindPath Kind = "path"
+)
+
+// homeDir is the home directory of the agent in the container.
+const homeDir = "/home/user"
+
+// Rule is a user-defined restriction on tool calls.
+type Rule struct {
+	// Name identifies the rule in violations, e.g. "no-network".
+	Name string `json:"name"`
+	Kind Kind   `json:"kind"`
+	// Pattern is the regular expression of a KindCommand rule.
+	Pattern string `json:"pattern,omitempty"`
+	// Allow lists exceptions: host globs such as "*.github.com" for
+	// KindNetwork, absolute directories for KindPath.
+	Allow []string `json:"allow,omitempty"`
+}
+
+// Validate checks that the rule is well-formed.
+func (r *Rule) Validate() error {
+	if r.Name == "" {
+		return errors.New("empty name")
+	}
+	switch r.Kind {
+	case KindNetwork:
+		for _, a := range r.Allow {
+			if _, err := path.Match(a, ""); err != nil || a == "" {
+				return fmt.Errorf("invalid host %q", a)
+			}
+		}
+	case KindCommand:
+		if r.Pattern == "" {
+			return errors.New("empty pattern")
+		}
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+	case KindPath:
+		for _, a := range r.Allow {
+			if !path.IsAbs(a) {
+				return fmt.Errorf("allowed path %q is not absolute", a)
+			}
+		}
+	default:
+		return fmt.Errorf("invalid kind %q", r.Kind)
+	}
+	return nil
+}
+
+// Violation is a tool call denied by a rule.
+type Violation struct {
+	Rule   string // Name of the violated rule.
+	Tool   string
+	Detail string // What was denied, e.g. the host or the command.
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s: %s denied by rule %q", v.Tool, v.Detail, v.Rule)
+}
+
+// Policy is a compiled set of rules. It is immutable and safe for concurrent
+// use.
+type Policy struct {
+	rules     []Rule
+	patterns  []*regexp.Regexp // Indexed like rules; nil except for KindCommand.
+	workspace string
+}
+
+// New compiles rules. workspace is the absolute path of the repository in the
+// container; relative paths in shell commands are resolved against it.
+// Returns nil when there are no rules.
+func New(rules []Rule, workspace string) (*Policy, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	p := &Policy{rules: rules, patterns: make([]*regexp.Regexp, len(rules)), workspace: path.Clean(workspace)}
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		if rules[i].Kind == KindCommand {
+			p.patterns[i] = regexp.MustCompile(rules[i].Pattern)
+		}
+	}
+	return p, nil
+}
+
+// Check returns the first rule violated by a tool call, or nil. input is the
+// tool's JSON input.
+func (p *Policy) Check(tool string, input json.RawMessage) *Violation {
+	if p == nil {
+		return nil
+	}
+	var in map[string]any
+	_ = json.Unmarshal(input, &in)
+	var a access
+	switch tool {
+	case "Bash":
+		a = p.parseShell(stringField(in, "command", "cmd"))
+	case "Write", "Edit", "MultiEdit", "NotebookEdit":
+		if f := stringField(in, "file_path", "path", "filePath", "notebook_path"); f != "" {
+			a.writes = append(a.writes, p.resolve(f))
+		}
+	case "WebFetch":
+		if h := urlHost(stringField(in, "url")); h != "" {
+			a.hosts = append(a.hosts, h)
+		}
+	case "WebSearch":
+		// The search provider is unknown; any network rule denies searches.
+		a.search = true
+	}
+	for i := range p.rules {
+		if d := p.violates(i, &a); d != "" {
+			return &Violation{Rule: p.rules[i].Name, Tool: tool, Detail: d}
+		}
+	}
+	return nil
+}
+
+// access is what a tool call touches.
+type access struct {
+	commands []string // Simple commands, words joined by spaces.
+	hosts    []string
+	writes   []string // Cleaned absolute paths, or "" when unresolvable.
+	search   bool
+}
+
+// violates returns what rule i denies in a, or "".
+func (p *Policy) violates(i int, a *access) string {
+	r := &p.rules[i]
+	switch r.Kind {
+	case KindNetwork:
+		if a.search {
+			return "web search"
+		}
+		for _, h := range a.hosts {
+			if !matchHost(r.Allow, h) {
+				return "network access to " + h
+			}
+		}
+	case KindCommand:
+		for _, c := range a.commands {
+			if p.patterns[i].MatchString(c) {
+				return "command " + strings.TrimSpace(c)
+			}
+		}
+	case KindPath:
+		for _, w := range a.writes {
+			if w == "" {
+				return "write to an unresolvable path"
+			}
+			if !inDir(w, p.workspace) && !matchDir(r.Allow, w) {
+				return "write to " + w
+			}
+		}
+	}
+	return ""
+}
+
+// resolve returns the absolute cleaned form of a path relative to the
+// workspace, or "" when it depends on shell expansion.
+func (p *Policy) resolve(f string) string {
+	switch {
+	case f == "~" || f == "$HOME" || f == "${HOME}":
+		return homeDir
+	case strings.HasPrefix(f, "~/"):
+		f = homeDir + f[1:]
+	case strings.HasPrefix(f, "$HOME/"):
+		f = homeDir + f[len("$HOME"):]
+	case strings.HasPrefix(f, "${HOME}/"):
+		f = homeDir + f[len("${HOME}"):]
+	}
+	if strings.ContainsAny(f, "$`") {
+		return ""
+	}
+	if !path.IsAbs(f) {
+		f = path.Join(p.workspace, f)
+	}
+	return path.Clean(f)
+}
+
+func matchHost(allow []string, host string) bool {
+	for _, a := range allow {
+		if ok, _ := path.Match(strings.ToLower(a), host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchDir(allow []string, p string) bool {
+	for _, a := range allow {
+		if inDir(p, path.Clean(a)) {
+			return true
+		}
+	}
+	return false
+}
+
+// inDir reports whether p is dir or below it. Writing to dir itself, e.g.
+// "rm -rf ." in the workspace, is allowed.
+func inDir(p, dir string) bool {
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// stringField returns the first non-empty string value among keys.
+func stringField(in map[string]any, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := in[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// urlHost returns the lowercase host of a URL, or "".
+func urlHost(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	h := u.Host
+	if host, _, err := net.SplitHostPort(h); err == nil {
+		h = host
+	}
+	return strings.ToLower(strings.Trim(h, "[]"))
+}
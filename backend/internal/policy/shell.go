@@ -0,0 +1,245 @@
+// Best-effort analysis of shell command lines run by agents.
+package policy
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// urlRe matches URLs embedded in a word, e.g. in "--url=https://x".
+var urlRe = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s'"]+`)
+
+// scpRe matches scp-like git remotes, e.g. "git@github.com:org/repo".
+var scpRe = regexp.MustCompile(`^[A-Za-z0-9._-]+@([A-Za-z0-9.-]+):`)
+
+// registries maps package managers to the subcommands that download packages
+// and the registry they use. An empty subcommand list means any invocation.
+var registries = map[string]struct {
+	subcommands []string
+	host        string
+}{
+	"npm":   {[]string{"install", "i", "ci", "add", "update", "exec"}, "registry.npmjs.org"},
+	"npx":   {nil, "registry.npmjs.org"},
+	"pnpm":  {[]string{"install", "i", "add", "update", "dlx"}, "registry.npmjs.org"},
+	"yarn":  {[]string{"", "install", "add", "upgrade", "dlx"}, "registry.yarnpkg.com"},
+	"pip":   {[]string{"install", "download"}, "pypi.org"},
+	"pip3":  {[]string{"install", "download"}, "pypi.org"},
+	"uv":    {[]string{"pip", "add", "sync", "tool"}, "pypi.org"},
+	"cargo": {[]string{"build", "fetch", "install", "add", "update", "run", "test"}, "crates.io"},
+	"gem":   {[]string{"install", "update"}, "rubygems.org"},
+	"go":    {[]string{"get", "install", "mod"}, "proxy.golang.org"},
+}
+
+// wrappers are commands that run their arguments as a command.
+var wrappers = map[string]bool{"sudo": true, "env": true, "time": true, "nohup": true, "command": true, "exec": true, "nice": true, "timeout": true}
+
+// deleters are commands whose non-flag arguments are modified or deleted.
+var deleters = map[string]bool{"rm": true, "rmdir": true, "unlink": true, "shred": true, "truncate": true}
+
+// parseShell returns what a command line touches.
+func (p *Policy) parseShell(line string) access {
+	var a access
+	p.addShell(&a, line, 0)
+	return a
+}
+
+func (p *Policy) addShell(a *access, line string, depth int) {
+	cmds, redirects := splitCommands(line)
+	for _, r := range redirects {
+		if r != "/dev/null" && r != "/dev/stdout" && r != "/dev/stderr" {
+			a.writes = append(a.writes, p.resolve(r))
+		}
+	}
+	for _, words := range cmds {
+		words = unwrap(words)
+		if len(words) == 0 {
+			continue
+		}
+		a.commands = append(a.commands, strings.Join(words, " "))
+		for _, w := range words {
+			for _, u := range urlRe.FindAllString(w, -1) {
+				if h := urlHost(u); h != "" {
+					a.hosts = append(a.hosts, h)
+				}
+			}
+			if m := scpRe.FindStringSubmatch(w); m != nil {
+				a.hosts = append(a.hosts, strings.ToLower(m[1]))
+			}
+		}
+		name := words[0][strings.LastIndexByte(words[0], '/')+1:]
+		if reg, ok := registries[name]; ok {
+			sub := ""
+			if len(words) > 1 {
+				sub = words[1]
+			}
+			if reg.subcommands == nil || slices.Contains(reg.subcommands, sub) {
+				a.hosts = append(a.hosts, reg.host)
+			}
+		}
+		switch {
+		case deleters[name], name == "mv":
+			for _, w := range operands(words[1:]) {
+				a.writes = append(a.writes, p.resolve(w))
+			}
+		case name == "cp" || name == "ln" || name == "install":
+			if ops := operands(words[1:]); len(ops) > 1 {
+				a.writes = append(a.writes, p.resolve(ops[len(ops)-1]))
+			}
+		case (name == "bash" || name == "sh" || name == "zsh") && depth < 3:
+			for i := 1; i+1 < len(words); i++ {
+				if words[i] == "-c" {
+					p.addShell(a, words[i+1], depth+1)
+					break
+				}
+			}
+		}
+	}
+}
+
+// unwrap strips environment assignments and wrapper commands like sudo.
+func unwrap(words []string) []string {
+	for len(words) != 0 {
+		w := words[0]
+		switch {
+		case strings.Contains(w, "=") && !strings.HasPrefix(w, "=") && !strings.HasPrefix(w, "-"):
+			words = words[1:]
+		case wrappers[w]:
+			words = words[1:]
+			// Skip the wrapper's flags, and timeout's duration.
+			for len(words) != 0 && (strings.HasPrefix(words[0], "-") || (w == "timeout" && startsWithDigit(words[0]))) {
+				words = words[1:]
+			}
+		default:
+			return words
+		}
+	}
+	return words
+}
+
+// operands returns the arguments that are not flags.
+func operands(args []string) []string {
+	var out []string
+	flags := true
+	for _, a := range args {
+		switch {
+		case flags && a == "--":
+			flags = false
+		case flags && strings.HasPrefix(a, "-") && a != "-":
+		default:
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// splitCommands splits a command line into simple commands, each a list of
+// words with quotes removed, and returns the targets of output redirections.
+// Substitutions are not expanded; their text stays in the words.
+func splitCommands(line string) ([][]string, []string) {
+	var cmds [][]string
+	var redirects []string
+	var words []string
+	var cur strings.Builder
+	inWord, redirect := false, false
+	endWord := func() {
+		if !inWord {
+			return
+		}
+		if redirect {
+			redirects = append(redirects, cur.String())
+			redirect = false
+		} else {
+			words = append(words, cur.String())
+		}
+		cur.Reset()
+		inWord = false
+	}
+	endCmd := func() {
+		endWord()
+		if len(words) != 0 {
+			cmds = append(cmds, words)
+		}
+		words = nil
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch c {
+		case '\'':
+			inWord = true
+			j := strings.IndexByte(line[i+1:], '\'')
+			if j < 0 {
+				j = len(line) - i - 1
+			}
+			cur.WriteString(line[i+1 : i+1+j])
+			i += j + 1
+		case '"':
+			inWord = true
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`", line[i+1]) >= 0 {
+					i++
+				}
+				cur.WriteByte(line[i])
+			}
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				if line[i] != '\n' {
+					inWord = true
+					cur.WriteByte(line[i])
+				}
+			}
+		case ' ', '\t':
+			endWord()
+		case '\n', ';', '&', '|', '(', ')':
+			endCmd()
+		case '>', '<':
+			// A number right before the operator is a file descriptor.
+			if inWord && isDigits(cur.String()) {
+				cur.Reset()
+				inWord = false
+			}
+			endWord()
+			out := c == '>'
+			for i+1 < len(line) && line[i+1] == '>' {
+				i++
+			}
+			if i+1 < len(line) && line[i+1] == '&' {
+				// Duplicating a descriptor, e.g. 2>&1.
+				i++
+				for i+1 < len(line) && (line[i+1] >= '0' && line[i+1] <= '9' || line[i+1] == '-') {
+					i++
+				}
+				continue
+			}
+			redirect = out
+			if !out {
+				// Input redirection: skip the file name.
+				for i+1 < len(line) && line[i+1] == ' ' {
+					i++
+				}
+				for i+1 < len(line) && strings.IndexByte(" \t\n;&|", line[i+1]) < 0 {
+					i++
+				}
+			}
+		default:
+			inWord = true
+			cur.WriteByte(c)
+		}
+	}
+	endCmd()
+	return cmds, redirects
+}
+
+func isDigits(s string) bool {
+	for i := range len(s) {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
@@ -8,11 +8,16 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/policy"
+	"github.com/caic-xyz/caic/backend/internal/pricing"
 )
 
 // CacheMapping maps a host directory to a container path for cache/state sharing.
@@ -36,6 +41,33 @@ type Preferences struct {
 	Models map[string]string `json:"models,omitempty"`
 	// Settings holds user-configurable behavioral settings.
 	Settings Settings `json:"settings,omitempty"`
+	// Prompts is the history of task prompts, most recent first, without
+	// duplicates within a repo and capped at maxPromptsPerRepo per repo.
+	Prompts []PromptHistory `json:"prompts,omitempty"`
+	// Views are the saved filters of the task list, in the user's order.
+	Views []TaskView `json:"views,omitempty"`
+}
+
+// TaskView is a named combination of task list filters.
+type TaskView struct {
+	// Name is the name shown to the user, unique per user.
+	Name string `json:"name"`
+	// Query holds the query parameters of the task list, e.g.
+	// "state=failed&createdWithin=168h".
+	Query string `json:"query,omitempty"`
+	// Default marks the view the task list opens with. At most one view is
+	// the default.
+	Default bool `json:"default,omitempty"`
+}
+
+// PromptHistory is a previously used task prompt.
+type PromptHistory struct {
+	// Repo is the primary repository of the task; empty for no-repo tasks.
+	Repo string `json:"repo,omitempty"`
+	// Text is the prompt text.
+	Text string `json:"text"`
+	// LastUsed is the Unix timestamp (seconds) of the last use.
+	LastUsed int64 `json:"lastUsed,omitempty"`
 }
 
 // Validate checks that the preferences are well-formed.
@@ -53,11 +85,40 @@ func (p *Preferences) Validate() error {
 		}
 		seen[r.Path] = struct{}{}
 	}
+	for i, h := range p.Prompts {
+		if h.Text == "" {
+			return fmt.Errorf("prompts[%d]: empty text", i)
+		}
+	}
+	views := make(map[string]bool, len(p.Views))
+	defaults := 0
+	for i, v := range p.Views {
+		if v.Name == "" {
+			return fmt.Errorf("views[%d]: empty name", i)
+		}
+		if views[v.Name] {
+			return fmt.Errorf("views[%d]: duplicate name %q", i, v.Name)
+		}
+		views[v.Name] = true
+		if v.Default {
+			if defaults++; defaults > 1 {
+				return fmt.Errorf("views[%d]: more than one default view", i)
+			}
+		}
+	}
 	switch p.Settings.GitHubTokenAccess {
 	case "", GitHubTokenReadWrite, GitHubTokenNone:
 	default:
 		return fmt.Errorf("invalid gitHubTokenAccess: %q", p.Settings.GitHubTokenAccess)
 	}
+	for model, pr := range p.Settings.ModelPrices {
+		if model == "" {
+			return errors.New("modelPrices: empty model")
+		}
+		if pr.Input < 0 || pr.Output < 0 || pr.CacheWrite < 0 || pr.CacheRead < 0 {
+			return fmt.Errorf("modelPrices[%q]: negative price", model)
+		}
+	}
 	for i, m := range p.Settings.CacheMappings {
 		if m.HostPath == "" {
 			return fmt.Errorf("cacheMappings[%d]: empty hostPath", i)
@@ -66,6 +127,94 @@ func (p *Preferences) Validate() error {
 			return fmt.Errorf("cacheMappings[%d]: empty containerPath", i)
 		}
 	}
+	switch p.Settings.Network {
+	case "", "full", "none", "allowlist":
+	default:
+		return fmt.Errorf("invalid network: %q", p.Settings.Network)
+	}
+	for i := range p.Settings.ToolPolicy {
+		if err := p.Settings.ToolPolicy[i].Validate(); err != nil {
+			return fmt.Errorf("toolPolicy[%d]: %w", i, err)
+		}
+	}
+	if g := p.Settings.GenericHarness; g != nil {
+		if u, err := url.Parse(g.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("genericHarness: invalid baseURL %q", g.BaseURL)
+		}
+		if g.Model == "" {
+			return errors.New("genericHarness: empty model")
+		}
+	}
+	if ip := p.Settings.IdlePolicy; ip != nil {
+		if err := ip.validate(); err != nil {
+			return fmt.Errorf("idlePolicy: %w", err)
+		}
+	}
+	for repo, ip := range p.Settings.RepoIdlePolicies {
+		if err := ip.validate(); err != nil {
+			return fmt.Errorf("repoIdlePolicies[%q]: %w", repo, err)
+		}
+	}
+	if sp := p.Settings.StallPolicy; sp != nil && sp.Minutes < 0 {
+		return fmt.Errorf("stallPolicy: negative minutes %d", sp.Minutes)
+	}
+	if fb := p.Settings.Fallback; fb != nil && fb.Harness == "" && fb.Model == "" {
+		return errors.New("fallback: empty harness and model")
+	}
+	for repo, pp := range p.Settings.RepoPushPolicies {
+		switch pp {
+		case "auto", "ask", "never":
+		default:
+			return fmt.Errorf("repoPushPolicies[%q]: invalid policy %q", repo, pp)
+		}
+	}
+	names := make(map[string]bool, len(p.Settings.NotifyChannels))
+	for i, c := range p.Settings.NotifyChannels {
+		if c.Name == "" {
+			return fmt.Errorf("notifyChannels[%d]: empty name", i)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("notifyChannels[%d]: duplicate name %q", i, c.Name)
+		}
+		names[c.Name] = true
+	}
+	for state, chans := range p.Settings.NotifyRoutes {
+		for _, name := range chans {
+			if !names[name] {
+				return fmt.Errorf("notifyRoutes[%q]: unknown channel %q", state, name)
+			}
+		}
+	}
+	if pi := p.Settings.PendingBaseImage; pi != nil {
+		if err := pi.validate(); err != nil {
+			return fmt.Errorf("pendingBaseImage: %w", err)
+		}
+	}
+	if w := p.Settings.ExecutionWindow; w != nil {
+		if err := w.validate(); err != nil {
+			return fmt.Errorf("executionWindow: %w", err)
+		}
+	}
+	for repo, project := range p.Settings.RepoProjects {
+		if project == "" {
+			return fmt.Errorf("repoProjects[%q]: empty project", repo)
+		}
+	}
+	for image, digest := range p.Settings.ImageDigests {
+		if image == "" || !strings.HasPrefix(digest, "sha256:") {
+			return fmt.Errorf("imageDigests[%q]: invalid digest %q", image, digest)
+		}
+	}
+	for repo, rules := range p.Settings.RepoToolPolicies {
+		if repo == "" {
+			return errors.New("repoToolPolicies: empty repo")
+		}
+		for i := range rules {
+			if err := rules[i].Validate(); err != nil {
+				return fmt.Errorf("repoToolPolicies[%q][%d]: %w", repo, i, err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -127,12 +276,86 @@ func (p *Preferences) RecentRepos(now time.Time) []RepoPrefs {
 	return result
 }
 
+// TouchPrompt moves text to the front of the prompt history of repo, adding
+// it if new and dropping the oldest entries of repo beyond
+// maxPromptsPerRepo. Blank prompts and prompts longer than maxPromptLen are
+// not recorded.
+func (p *Preferences) TouchPrompt(repo, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" || len(text) > maxPromptLen {
+		return
+	}
+	out := make([]PromptHistory, 0, len(p.Prompts)+1)
+	out = append(out, PromptHistory{Repo: repo, Text: text, LastUsed: time.Now().Unix()})
+	n := 1
+	for _, h := range p.Prompts {
+		if h.Repo != repo {
+			out = append(out, h)
+		} else if h.Text != text && n < maxPromptsPerRepo {
+			out = append(out, h)
+			n++
+		}
+	}
+	p.Prompts = out
+}
+
+// SearchPrompts returns up to limit prompts of repo, most recent first, that
+// start with prefix, ignoring case. An empty repo searches every repo; a
+// prompt used in several repos is then returned once.
+func (p *Preferences) SearchPrompts(repo, prefix string, limit int) []PromptHistory {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	seen := map[string]struct{}{}
+	var out []PromptHistory
+	for _, h := range p.Prompts {
+		if len(out) >= limit {
+			break
+		}
+		if repo != "" && h.Repo != repo {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToLower(h.Text), prefix) {
+			continue
+		}
+		if _, ok := seen[h.Text]; ok {
+			continue
+		}
+		seen[h.Text] = struct{}{}
+		out = append(out, h)
+	}
+	return out
+}
+
+// SaveView adds v or replaces the view of the same name in place. When v is
+// the default, the other views stop being.
+func (p *Preferences) SaveView(v TaskView) {
+	if v.Default {
+		for i := range p.Views {
+			p.Views[i].Default = false
+		}
+	}
+	if i := slices.IndexFunc(p.Views, func(o TaskView) bool { return o.Name == v.Name }); i >= 0 {
+		p.Views[i] = v
+		return
+	}
+	p.Views = append(p.Views, v)
+}
+
+// DeleteView removes the view named name. It reports whether it existed.
+func (p *Preferences) DeleteView(name string) bool {
+	n := len(p.Views)
+	p.Views = slices.DeleteFunc(p.Views, func(v TaskView) bool { return v.Name == name })
+	return len(p.Views) != n
+}
+
 func (p *Preferences) clone() Preferences {
 	c := *p
 	c.Repositories = slices.Clone(p.Repositories)
+	c.Prompts = slices.Clone(p.Prompts)
+	c.Views = slices.Clone(p.Views)
 	c.Models = maps.Clone(p.Models)
 	c.Settings.CacheMappings = slices.Clone(p.Settings.CacheMappings)
 	c.Settings.WellKnownCaches = maps.Clone(p.Settings.WellKnownCaches)
+	c.Settings.ModelPrices = maps.Clone(p.Settings.ModelPrices)
 	return c
 }
 
@@ -155,9 +378,23 @@ type Settings struct {
 	// AutoFixOnPROpen automatically creates a task to review and fix a pull
 	// request when it is opened or reopened via a forge webhook.
 	AutoFixOnPROpen bool `json:"autoFixOnPROpen,omitempty"`
+	// AutoRespondToReviews hands review feedback left on the PR of a task
+	// back to its agent, or to a follow-up task on the PR branch when the
+	// agent can no longer receive input.
+	AutoRespondToReviews bool `json:"autoRespondToReviews,omitempty"`
 	// BaseImage overrides the default container base image. Empty means use
 	// the default.
 	BaseImage string `json:"baseImage,omitempty"`
+	// ValidateBaseImage smoke tests a changed BaseImage before new tasks use
+	// it: the new image waits in PendingBaseImage until it passes.
+	ValidateBaseImage bool `json:"validateBaseImage,omitempty"`
+	// PendingBaseImage is the base image being validated, or that failed
+	// validation.
+	PendingBaseImage *PendingImage `json:"pendingBaseImage,omitempty"`
+	// PersistWorkspace saves the workspace of a task, uncommitted files
+	// included, into the host repo before its container is stopped or purged,
+	// so that the task can be rehydrated into a fresh container.
+	PersistWorkspace bool `json:"persistWorkspace,omitempty"`
 	// GitHubTokenAccess controls the GitHub token injected into containers.
 	// Default ("" or "none") injects no token.
 	// "read-write" passes the parent token.
@@ -170,6 +407,200 @@ type Settings struct {
 	WellKnownCaches map[string]bool `json:"wellKnownCaches,omitempty"`
 	// CacheMappings are custom directory mappings to mount into the container.
 	CacheMappings []CacheMapping `json:"cacheMappings,omitempty"`
+	// ModelPrices overrides the built-in price table, keyed by model name
+	// prefix, e.g. for enterprise pricing. Prices are USD per million tokens.
+	ModelPrices map[string]pricing.Price `json:"modelPrices,omitempty"`
+	// Network is the default network mode of new tasks: "full", "none" or
+	// "allowlist". Empty means full.
+	Network string `json:"network,omitempty"`
+	// NetworkAllow is the default host allowlist of the "allowlist" mode.
+	NetworkAllow []string `json:"networkAllow,omitempty"`
+	// ToolPolicy are the rules applied to the tool calls of every task. A
+	// violating tool call pauses the task until the user approves it.
+	ToolPolicy []policy.Rule `json:"toolPolicy,omitempty"`
+	// RepoToolPolicies are additional rules keyed by repository path.
+	RepoToolPolicies map[string][]policy.Rule `json:"repoToolPolicies,omitempty"`
+	// GenericHarness configures the "generic" harness. Nil disables it.
+	GenericHarness *GenericHarness `json:"genericHarness,omitempty"`
+	// IdlePolicy acts on tasks left waiting for input. Nil disables it.
+	IdlePolicy *IdlePolicy `json:"idlePolicy,omitempty"`
+	// RepoIdlePolicies override IdlePolicy, keyed by repository path.
+	RepoIdlePolicies map[string]IdlePolicy `json:"repoIdlePolicies,omitempty"`
+	// StallPolicy flags running tasks that stay silent. Nil disables it.
+	StallPolicy *StallPolicy `json:"stallPolicy,omitempty"`
+	// Fallback is the harness and model a task switches to when its own fail
+	// to start. Nil disables the failover.
+	Fallback *Fallback `json:"fallback,omitempty"`
+	// RepoPushPolicies are the push policies ("auto", "ask" or "never") keyed
+	// by repository path; the other repos use "auto".
+	RepoPushPolicies map[string]string `json:"repoPushPolicies,omitempty"`
+	// RepoPrePushChecks are local CI scripts keyed by repository path, run in
+	// the task's container before its branch is pushed. A failure blocks the
+	// push.
+	RepoPrePushChecks map[string]string `json:"repoPrePushChecks,omitempty"`
+	// RepoInstructions are custom instructions appended to the system prompt
+	// of the agents, keyed by repository path.
+	RepoInstructions map[string]string `json:"repoInstructions,omitempty"`
+	// RepoProjects maps repository paths to the name of the project they
+	// belong to, to group the repos of a product.
+	RepoProjects map[string]string `json:"repoProjects,omitempty"`
+	// ImageDigests pins base images to a registry digest, e.g.
+	// "sha256:0123...", keyed by image reference. Tasks use the pinned
+	// digest until it is pinned again.
+	ImageDigests map[string]string `json:"imageDigests,omitempty"`
+	// ExecutionWindow holds new tasks until it is open. Nil runs them
+	// immediately.
+	ExecutionWindow *ExecutionWindow `json:"executionWindow,omitempty"`
+	// NotifyChannels are the chat channels task notifications can be sent to.
+	NotifyChannels []NotifyChannel `json:"notifyChannels,omitempty"`
+	// NotifyRoutes maps a task state, e.g. "waiting" or "failed", to the names
+	// of the channels notified when a task enters it. The "stalled" route
+	// is notified when a task is flagged by the stall policy.
+	NotifyRoutes map[string][]string `json:"notifyRoutes,omitempty"`
+	// EnvAllowlist are the names, or path.Match patterns, of the environment
+	// variables tasks may set.
+	EnvAllowlist []string `json:"envAllowlist,omitempty"`
+	// Timezone is the IANA time zone of the user's notifications and exports.
+	// Empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// NotifyChannel is a named chat channel; see notify.Config for the fields
+// each kind uses.
+type NotifyChannel struct {
+	Name   string `json:"name"`
+	Kind   string `json:"kind"` // "slack", "discord", "matrix" or "telegram".
+	URL    string `json:"url,omitempty"`
+	Secret string `json:"secret,omitempty"`
+	Target string `json:"target,omitempty"`
+}
+
+// PendingImage is a base image waiting to pass its smoke test. Status is
+// "validating" or "failed".
+type PendingImage struct {
+	Image  string `json:"image"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// validate checks the image and status.
+func (p *PendingImage) validate() error {
+	if p.Image == "" {
+		return errors.New("empty image")
+	}
+	switch p.Status {
+	case "validating", "failed":
+		return nil
+	default:
+		return fmt.Errorf("invalid status %q", p.Status)
+	}
+}
+
+// IdlePolicy acts on a task after it waited Hours for input: "notify" posts a
+// notice, "finish" pushes the branch and stops the task.
+type IdlePolicy struct {
+	Hours  int    `json:"hours"` // 0 disables the policy.
+	Action string `json:"action"`
+}
+
+// validate checks the hours and action.
+func (p *IdlePolicy) validate() error {
+	if p.Hours < 0 {
+		return fmt.Errorf("negative hours %d", p.Hours)
+	}
+	switch p.Action {
+	case "notify", "finish":
+		return nil
+	default:
+		return fmt.Errorf("invalid action %q", p.Action)
+	}
+}
+
+// StallPolicy flags a running task whose agent sent nothing for Minutes and,
+// with Interrupt, interrupts its turn.
+type StallPolicy struct {
+	Minutes   int  `json:"minutes"` // 0 disables the policy.
+	Interrupt bool `json:"interrupt,omitempty"`
+}
+
+// Fallback is the harness and model used when those of a task fail to start.
+// An empty Harness keeps the task's harness.
+type Fallback struct {
+	Harness string `json:"harness,omitempty"`
+	Model   string `json:"model,omitempty"`
+}
+
+// ExecutionWindow is the time of day during which new tasks may start, e.g.
+// 20:00 to 08:00 to use off-peak API limits. Start and End are "15:04" clock
+// times in Timezone; an End before Start spans midnight.
+type ExecutionWindow struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone,omitempty"` // IANA name; empty is the server's local time.
+}
+
+// OpensAt returns now when the window is open, else when it next opens.
+func (w *ExecutionWindow) OpensAt(now time.Time) time.Time {
+	loc := time.Local
+	if w.Timezone != "" {
+		if l, err := time.LoadLocation(w.Timezone); err == nil {
+			loc = l
+		}
+	}
+	start, _ := parseClock(w.Start)
+	end, _ := parseClock(w.End)
+	n := now.In(loc)
+	m := n.Hour()*60 + n.Minute()
+	if (start < end && m >= start && m < end) || (start > end && (m >= start || m < end)) {
+		return now
+	}
+	y, mo, d := n.Date()
+	at := time.Date(y, mo, d, start/60, start%60, 0, 0, loc)
+	if !at.After(n) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at
+}
+
+// validate checks the clock times and the time zone.
+func (w *ExecutionWindow) validate() error {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return fmt.Errorf("start: %w", err)
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return fmt.Errorf("end: %w", err)
+	}
+	if start == end {
+		return errors.New("start and end are equal")
+	}
+	if w.Timezone != "" {
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("timezone: %w", err)
+		}
+	}
+	return nil
+}
+
+// parseClock returns the minutes since midnight of a "15:04" clock time.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid clock time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// GenericHarness configures the "generic" harness: an agent loop against an
+// OpenAI-compatible chat completions API, for providers without a CLI.
+type GenericHarness struct {
+	// BaseURL is the API base URL, e.g. "https://api.example.com/v1".
+	BaseURL string `json:"baseURL"`
+	// APIKey is the bearer token, if the API requires one.
+	APIKey string `json:"apiKey,omitempty"`
+	// Model is the model of the tasks.
+	Model string `json:"model"`
 }
 
 // RepoPrefs stores per-repository user preferences. Fields override the
@@ -268,6 +699,13 @@ const currentVersion = 1
 // recentWindow is how far back we consider a repo "recent".
 const recentWindow = 7 * 24 * time.Hour
 
+// maxPromptsPerRepo caps the prompt history of each repo.
+const maxPromptsPerRepo = 50
+
+// maxPromptLen is the length in bytes beyond which a prompt is not recorded
+// in the history; such prompts are pasted content rather than reusable.
+const maxPromptLen = 4096
+
 // minRecentRepos is the minimum number of repos always shown as recent,
 // regardless of last-used time.
 const minRecentRepos = 10
@@ -282,7 +720,7 @@ type usersFile struct {
 }
 
 // BaseImages returns all distinct non-empty base images configured across all
-// users' global preferences.
+// users' global preferences, and the pinned ones as "image@digest".
 func (s *Store) BaseImages() []string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -291,10 +729,27 @@ func (s *Store) BaseImages() []string {
 		if s.cached[k].Settings.BaseImage != "" {
 			seen[s.cached[k].Settings.BaseImage] = struct{}{}
 		}
+		for image, digest := range s.cached[k].Settings.ImageDigests {
+			seen[image+"@"+digest] = struct{}{}
+		}
 	}
 	return slices.Sorted(maps.Keys(seen))
 }
 
+// PendingBaseImages returns the base images being validated, keyed by user
+// ID.
+func (s *Store) PendingBaseImages() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]string)
+	for k := range s.cached {
+		if pi := s.cached[k].Settings.PendingBaseImage; pi != nil && pi.Status == "validating" {
+			out[k] = pi.Image
+		}
+	}
+	return out
+}
+
 // Validate checks that the on-disk format is well-formed.
 func (f *usersFile) Validate() error {
 	for id := range f.Users {
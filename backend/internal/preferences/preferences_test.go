@@ -1,10 +1,16 @@
 package preferences
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/policy"
+	"github.com/caic-xyz/caic/backend/internal/pricing"
 )
 
 func TestValidate(t *testing.T) {
@@ -41,6 +47,12 @@ func TestValidate(t *testing.T) {
 			t.Fatal("expected error for empty repo path")
 		}
 	})
+	t.Run("two_default_views", func(t *testing.T) {
+		p := &Preferences{Version: 1, Views: []TaskView{{Name: "a", Default: true}, {Name: "b", Default: true}}}
+		if err := p.Validate(); err == nil {
+			t.Fatal("expected error for two default views")
+		}
+	})
 	t.Run("duplicate_repo_path", func(t *testing.T) {
 		p := &Preferences{
 			Version: 1,
@@ -92,6 +104,64 @@ func TestValidate(t *testing.T) {
 			t.Fatal("expected error for empty containerPath")
 		}
 	})
+	t.Run("model_price_negative", func(t *testing.T) {
+		p := &Preferences{
+			Version: 1,
+			Settings: Settings{
+				ModelPrices: map[string]pricing.Price{"claude": {Input: -1}},
+			},
+		}
+		if err := p.Validate(); err == nil {
+			t.Fatal("expected error for negative price")
+		}
+	})
+	t.Run("generic_harness_invalid", func(t *testing.T) {
+		for _, g := range []GenericHarness{
+			{BaseURL: "api.example.com/v1", Model: "m"},
+			{BaseURL: "ftp://api.example.com", Model: "m"},
+			{BaseURL: "https://api.example.com/v1"},
+		} {
+			p := &Preferences{Version: 1, Settings: Settings{GenericHarness: &g}}
+			if err := p.Validate(); err == nil {
+				t.Errorf("%+v: expected error", g)
+			}
+		}
+	})
+	t.Run("execution_window_invalid", func(t *testing.T) {
+		for _, w := range []ExecutionWindow{
+			{Start: "20:00", End: "8am"},
+			{Start: "25:00", End: "08:00"},
+			{Start: "08:00", End: "08:00"},
+			{Start: "20:00", End: "08:00", Timezone: "Mars/Olympus"},
+		} {
+			p := &Preferences{Version: 1, Settings: Settings{ExecutionWindow: &w}}
+			if err := p.Validate(); err == nil {
+				t.Errorf("%+v: expected error", w)
+			}
+		}
+	})
+	t.Run("pending_base_image_invalid", func(t *testing.T) {
+		for _, pi := range []PendingImage{
+			{Status: "validating"},
+			{Image: "ghcr.io/acme/dev:2", Status: "ok"},
+		} {
+			p := &Preferences{Version: 1, Settings: Settings{PendingBaseImage: &pi}}
+			if err := p.Validate(); err == nil {
+				t.Errorf("%+v: expected error", pi)
+			}
+		}
+	})
+	t.Run("repo_tool_policy_invalid", func(t *testing.T) {
+		p := &Preferences{
+			Version: 1,
+			Settings: Settings{
+				RepoToolPolicies: map[string][]policy.Rule{"github/caic": {{Name: "x", Kind: policy.KindCommand, Pattern: "("}}},
+			},
+		}
+		if err := p.Validate(); err == nil {
+			t.Fatal("expected error for invalid pattern")
+		}
+	})
 }
 
 func TestUsersFileValidate(t *testing.T) {
@@ -489,3 +559,132 @@ func TestRecentRepos(t *testing.T) {
 		}
 	})
 }
+
+func TestTouchPrompt(t *testing.T) {
+	texts := func(hs []PromptHistory) []string {
+		out := make([]string, len(hs))
+		for i, h := range hs {
+			out[i] = h.Repo + ":" + h.Text
+		}
+		return out
+	}
+	t.Run("dedup_and_move_to_front", func(t *testing.T) {
+		p := &Preferences{Version: currentVersion}
+		p.TouchPrompt("a", "fix the tests")
+		p.TouchPrompt("b", "fix the tests")
+		p.TouchPrompt("a", "add docs")
+		p.TouchPrompt("a", "  fix the tests\n")
+		got := texts(p.Prompts)
+		want := []string{"a:fix the tests", "a:add docs", "b:fix the tests"}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+	t.Run("ignored", func(t *testing.T) {
+		p := &Preferences{Version: currentVersion}
+		p.TouchPrompt("a", "  ")
+		p.TouchPrompt("a", strings.Repeat("x", maxPromptLen+1))
+		if len(p.Prompts) != 0 {
+			t.Errorf("got %v", texts(p.Prompts))
+		}
+	})
+	t.Run("cap_per_repo", func(t *testing.T) {
+		p := &Preferences{Version: currentVersion}
+		p.TouchPrompt("b", "other")
+		for i := range maxPromptsPerRepo + 5 {
+			p.TouchPrompt("a", fmt.Sprintf("prompt %d", i))
+		}
+		if len(p.Prompts) != maxPromptsPerRepo+1 {
+			t.Fatalf("got %d prompts", len(p.Prompts))
+		}
+		if p.Prompts[0].Text != fmt.Sprintf("prompt %d", maxPromptsPerRepo+4) || p.Prompts[len(p.Prompts)-1].Text != "other" {
+			t.Errorf("got %v", texts(p.Prompts))
+		}
+	})
+}
+
+func TestSearchPrompts(t *testing.T) {
+	p := &Preferences{Version: currentVersion}
+	p.TouchPrompt("b", "Fix lint")
+	p.TouchPrompt("a", "fix the tests")
+	p.TouchPrompt("a", "add docs")
+	p.TouchPrompt("b", "fix the tests")
+	tests := []struct {
+		repo, prefix string
+		limit        int
+		want         []string
+	}{
+		{"a", "", 10, []string{"add docs", "fix the tests"}},
+		{"a", "FIX", 10, []string{"fix the tests"}},
+		{"", "fix", 10, []string{"fix the tests", "Fix lint"}},
+		{"", "", 2, []string{"fix the tests", "add docs"}},
+		{"c", "", 10, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, h := range p.SearchPrompts(tt.repo, tt.prefix, tt.limit) {
+			got = append(got, h.Text)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("SearchPrompts(%q, %q, %d) = %v, want %v", tt.repo, tt.prefix, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestViews(t *testing.T) {
+	p := &Preferences{Version: currentVersion}
+	p.SaveView(TaskView{Name: "failed", Query: "state=failed", Default: true})
+	p.SaveView(TaskView{Name: "waiting", Query: "state=waiting"})
+	p.SaveView(TaskView{Name: "waiting", Query: "state=waiting&stateOlderThan=24h", Default: true})
+	want := []TaskView{
+		{Name: "failed", Query: "state=failed"},
+		{Name: "waiting", Query: "state=waiting&stateOlderThan=24h", Default: true},
+	}
+	if !slices.Equal(p.Views, want) {
+		t.Errorf("got %+v, want %+v", p.Views, want)
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !p.DeleteView("failed") || p.DeleteView("failed") {
+		t.Error("DeleteView should report whether the view existed")
+	}
+	if len(p.Views) != 1 || p.Views[0].Name != "waiting" {
+		t.Errorf("got %+v", p.Views)
+	}
+}
+
+func TestExecutionWindowOpensAt(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2026, 3, 10, h, m, 0, 0, time.UTC) }
+	t.Run("overnight", func(t *testing.T) {
+		w := &ExecutionWindow{Start: "20:00", End: "08:00", Timezone: "UTC"}
+		for _, tt := range []struct {
+			now, want time.Time
+		}{
+			{at(21, 30), at(21, 30)},
+			{at(7, 59), at(7, 59)},
+			{at(8, 0), at(20, 0)},
+			{at(19, 59), at(20, 0)},
+		} {
+			if got := w.OpensAt(tt.now); !got.Equal(tt.want) {
+				t.Errorf("OpensAt(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		}
+	})
+	t.Run("daytime", func(t *testing.T) {
+		w := &ExecutionWindow{Start: "09:00", End: "17:00", Timezone: "UTC"}
+		if got, want := w.OpensAt(at(18, 0)), at(9, 0).AddDate(0, 0, 1); !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if got, want := w.OpensAt(at(6, 0)), at(9, 0); !got.Equal(want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+	t.Run("timezone", func(t *testing.T) {
+		w := &ExecutionWindow{Start: "09:00", End: "17:00", Timezone: "Asia/Tokyo"}
+		// 01:00 UTC is 10:00 in Tokyo.
+		if got := w.OpensAt(at(1, 0)); !got.Equal(at(1, 0)) {
+			t.Errorf("got %v, want open", got)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+// Package pricing estimates the USD cost of agent token usage from a per-model
+// price table, independently of the cost the harness reports.
+package pricing
+
+import (
+	"math"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// Price is the list price of a model in USD per million tokens.
+type Price struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cacheWrite,omitempty"`
+	CacheRead  float64 `json:"cacheRead,omitempty"`
+	// CacheReadIncluded is set when the harness counts cache reads as part of
+	// the input tokens (OpenAI convention) instead of separately (Anthropic).
+	CacheReadIncluded bool `json:"cacheReadIncluded,omitempty"`
+}
+
+// Cost returns the USD cost of u at price p. Reasoning tokens are part of the
+// output tokens and are not charged twice.
+func (p *Price) Cost(u *agent.Usage) float64 {
+	input := u.InputTokens
+	if p.CacheReadIncluded {
+		input = max(0, input-u.CacheReadInputTokens)
+	}
+	return (float64(input)*p.Input +
+		float64(u.OutputTokens)*p.Output +
+		float64(u.CacheCreationInputTokens)*p.CacheWrite +
+		float64(u.CacheReadInputTokens)*p.CacheRead) / 1e6
+}
+
+// Defaults is the built-in price table keyed by model name prefix. Entries are
+// public list prices; enterprise agreements override them via preferences.
+var Defaults = map[string]Price{
+	"claude-opus-4":     {Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.5},
+	"claude-opus-4-5":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5},
+	"claude-opus-4-6":   {Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5},
+	"claude-sonnet-4":   {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-haiku-4-5":  {Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.1},
+	"claude-3-7-sonnet": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4, CacheWrite: 1, CacheRead: 0.08},
+	"gpt-5":             {Input: 1.25, Output: 10, CacheRead: 0.125, CacheReadIncluded: true},
+	"gpt-5-mini":        {Input: 0.25, Output: 2, CacheRead: 0.025, CacheReadIncluded: true},
+	"gpt-5-nano":        {Input: 0.05, Output: 0.4, CacheRead: 0.005, CacheReadIncluded: true},
+	"gemini-2-5-pro":    {Input: 1.25, Output: 10, CacheRead: 0.31, CacheReadIncluded: true},
+	"gemini-2-5-flash":  {Input: 0.3, Output: 2.5, CacheRead: 0.075, CacheReadIncluded: true},
+	"gemini-3-pro":      {Input: 2, Output: 12, CacheRead: 0.2, CacheReadIncluded: true},
+	"gemini-3-flash":    {Input: 0.5, Output: 3, CacheRead: 0.05, CacheReadIncluded: true},
+}
+
+// Lookup returns the price of model. overrides take precedence over Defaults;
+// within each table the longest matching prefix wins. Model names are
+// normalized so that "anthropic/claude-opus-4.6" matches "claude-opus-4-6".
+func Lookup(overrides map[string]Price, model string) (Price, bool) {
+	model = normalize(model)
+	if model == "" {
+		return Price{}, false
+	}
+	for _, table := range []map[string]Price{overrides, Defaults} {
+		best := -1
+		var found Price
+		for k, p := range table {
+			k = normalize(k)
+			if len(k) > best && strings.HasPrefix(model, k) {
+				best, found = len(k), p
+			}
+		}
+		if best >= 0 {
+			return found, true
+		}
+	}
+	return Price{}, false
+}
+
+func normalize(model string) string {
+	if i := strings.LastIndexByte(model, '/'); i >= 0 {
+		model = model[i+1:]
+	}
+	return strings.ReplaceAll(strings.ToLower(model), ".", "-")
+}
+
+// Discrepancy thresholds: costs differing by less than either are considered
+// consistent.
+const (
+	discrepancyRatio = 0.25
+	discrepancyUSD   = 0.05
+)
+
+// Discrepant reports whether a computed estimate and the harness-reported
+// cost disagree enough to flag. A zero reported cost means the harness does
+// not report one and is never flagged.
+func Discrepant(computed, reported float64) bool {
+	if reported <= 0 || computed <= 0 {
+		return false
+	}
+	d := math.Abs(computed - reported)
+	return d > discrepancyUSD && d > discrepancyRatio*max(computed, reported)
+}
@@ -0,0 +1,77 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+func TestLookup(t *testing.T) {
+	t.Run("LongestPrefix", func(t *testing.T) {
+		for _, tc := range []struct {
+			model string
+			input float64
+		}{
+			{"claude-opus-4-1-20250805", 15},
+			{"claude-opus-4-6", 5},
+			{"anthropic/claude-opus-4.6", 5},
+			{"gpt-5-mini", 0.25},
+			{"gpt-5.4", 1.25},
+		} {
+			t.Run(tc.model, func(t *testing.T) {
+				p, ok := Lookup(nil, tc.model)
+				if !ok || p.Input != tc.input {
+					t.Errorf("Lookup(%q) = (%+v, %v), want input %v", tc.model, p, ok, tc.input)
+				}
+			})
+		}
+	})
+	t.Run("Unknown", func(t *testing.T) {
+		if p, ok := Lookup(nil, "mystery-1"); ok {
+			t.Errorf("Lookup = %+v, want none", p)
+		}
+		if _, ok := Lookup(nil, ""); ok {
+			t.Error("Lookup of empty model matched")
+		}
+	})
+	t.Run("Override", func(t *testing.T) {
+		overrides := map[string]Price{"claude": {Input: 1, Output: 2}}
+		if p, ok := Lookup(overrides, "claude-opus-4-6"); !ok || p.Input != 1 {
+			t.Errorf("Lookup = (%+v, %v), want the override", p, ok)
+		}
+	})
+}
+
+func TestCost(t *testing.T) {
+	u := agent.Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheCreationInputTokens: 200_000, CacheReadInputTokens: 500_000}
+	t.Run("Separate", func(t *testing.T) {
+		p := Price{Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.5}
+		if got, want := p.Cost(&u), 5+2.5+1.25+0.25; math.Abs(got-want) > 1e-9 {
+			t.Errorf("Cost = %v, want %v", got, want)
+		}
+	})
+	t.Run("CacheReadIncluded", func(t *testing.T) {
+		p := Price{Input: 1, Output: 10, CacheRead: 0.1, CacheReadIncluded: true}
+		if got, want := p.Cost(&u), 0.5+1+0.05; math.Abs(got-want) > 1e-9 {
+			t.Errorf("Cost = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestDiscrepant(t *testing.T) {
+	for _, tc := range []struct {
+		computed, reported float64
+		want               bool
+	}{
+		{1, 1.1, false},
+		{1, 2, true},
+		{0.01, 0.03, false},
+		{1, 0, false},
+		{0, 1, false},
+	} {
+		if got := Discrepant(tc.computed, tc.reported); got != tc.want {
+			t.Errorf("Discrepant(%v, %v) = %v, want %v", tc.computed, tc.reported, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Admin role and the runtime diagnostics endpoints it gates.
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"expvar"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/auth"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+// maxGoroutineGroups caps the stacks returned by getRuntime; the tail is
+// mostly one-off goroutines and the full dump is in the debug bundle.
+const maxGoroutineGroups = 100
+
+// registerAdmin adds the admin-only diagnostics endpoints to mux: pprof,
+// expvar, the runtime snapshot, the frontend build and the debug bundle.
+func (s *Server) registerAdmin(mux *http.ServeMux) {
+	pprofMux := http.NewServeMux()
+	registerPprof(pprofMux)
+	mux.Handle("GET /api/v1/admin/debug/pprof/", s.requireAdmin(http.StripPrefix("/api/v1/admin", pprofMux)))
+	mux.Handle("GET /api/v1/admin/debug/vars", s.requireAdmin(expvar.Handler()))
+	mux.Handle("GET /api/v1/admin/runtime", s.requireAdmin(handle(s.getRuntime)))
+	mux.Handle("GET /api/v1/admin/frontend", s.requireAdmin(handle(s.getFrontendBuild)))
+	mux.Handle("GET /api/v1/admin/debugbundle", s.requireAdmin(http.HandlerFunc(s.handleDebugBundle)))
+}
+
+// isAdmin reports whether the request's user may use the admin endpoints.
+// Without OAuth the server is single-user and that user is the admin.
+func (s *Server) isAdmin(ctx context.Context) bool {
+	if !s.authEnabled() {
+		return true
+	}
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return false
+	}
+	_, ok = s.adminUsers[strings.ToLower(u.Username)]
+	return ok
+}
+
+// requireAdmin rejects requests from non-admin users with 403.
+func (s *Server) requireAdmin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdmin(r.Context()) {
+			writeError(w, dto.Forbidden("admin endpoint"))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// getRuntime returns a goroutine and heap snapshot.
+func (s *Server) getRuntime(_ context.Context, _ *dto.EmptyReq) (*v1.RuntimeResp, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return nil, dto.InternalError(err.Error())
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	s.mu.Lock()
+	tasks := len(s.tasks)
+	s.mu.Unlock()
+	groups := parseGoroutineGroups(buf.Bytes())
+	if len(groups) > maxGoroutineGroups {
+		groups = groups[:maxGoroutineGroups]
+	}
+	return &v1.RuntimeResp{
+		Goroutines:      runtime.NumGoroutine(),
+		HeapAllocBytes:  ms.HeapAlloc,
+		HeapInuseBytes:  ms.HeapInuse,
+		HeapObjects:     ms.HeapObjects,
+		SysBytes:        ms.Sys,
+		NumGC:           ms.NumGC,
+		GCPauseTotal:    float64(ms.PauseTotalNs) / 1e9,
+		Tasks:           tasks,
+		GoroutineGroups: groups,
+	}, nil
+}
+
+// parseGoroutineGroups parses a goroutine profile written with debug=1. Each
+// group starts with "<count> @ <pcs>" and lists its frames as
+// "#\t<pc>\t<func>+<off>\t<file>:<line>". The profile is already sorted by
+// decreasing count.
+func parseGoroutineGroups(b []byte) []v1.GoroutineGroup {
+	var out []v1.GoroutineGroup
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, 1<<20)
+	cur := -1 // Index of the group being parsed in out.
+	for sc.Scan() {
+		line := sc.Text()
+		if countStr, _, ok := strings.Cut(line, " @ "); ok {
+			n, err := strconv.Atoi(countStr)
+			if err != nil {
+				cur = -1
+				continue
+			}
+			out = append(out, v1.GoroutineGroup{Count: n})
+			cur = len(out) - 1
+			continue
+		}
+		if cur < 0 || !strings.HasPrefix(line, "#\t") {
+			continue
+		}
+		// Columns are aligned with extra tabs; frames without symbols only
+		// have the pc.
+		fields := strings.FieldsFunc(line[2:], func(r rune) bool { return r == '\t' })
+		switch len(fields) {
+		case 1:
+			out[cur].Stack = append(out[cur].Stack, fields[0])
+		case 3:
+			fn, _, _ := strings.Cut(fields[1], "+0x")
+			out[cur].Stack = append(out[cur].Stack, fn+" "+fields[2])
+		}
+	}
+	return out
+}
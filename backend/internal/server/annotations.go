@@ -0,0 +1,184 @@
+// Transcript annotations: notes and bookmarks reviewers attach to messages of a task transcript.
+package server
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/maruel/ksid"
+)
+
+// annotationStore holds the annotations of each task, persisted as one JSON
+// object keyed by task ID. It is guarded by Server.mu.
+type annotationStore struct {
+	path   string
+	byTask map[string][]v1.Annotation // Sorted by message index, then creation.
+}
+
+// loadAnnotations reads the store from path. A missing file is an empty store.
+func loadAnnotations(path string) (*annotationStore, error) {
+	a := &annotationStore{path: path, byTask: map[string][]v1.Annotation{}}
+	data, err := os.ReadFile(path) //nolint:gosec // G304: internal cache path
+	if err != nil {
+		if os.IsNotExist(err) {
+			return a, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &a.byTask); err != nil {
+		// Stores written before timestamps were RFC 3339 have createdAt in
+		// Unix epoch seconds.
+		var legacy map[string][]struct {
+			v1.Annotation
+			CreatedAt float64 `json:"createdAt"`
+		}
+		if json.Unmarshal(data, &legacy) != nil {
+			return nil, err
+		}
+		a.byTask = make(map[string][]v1.Annotation, len(legacy))
+		for id, l := range legacy {
+			out := make([]v1.Annotation, len(l))
+			for i := range l {
+				out[i] = l[i].Annotation
+				out[i].CreatedAt = epochTime(l[i].CreatedAt)
+			}
+			a.byTask[id] = out
+		}
+	}
+	return a, nil
+}
+
+// list returns the annotations of the messages in [from, to) of the task. A
+// nil store is empty.
+func (a *annotationStore) list(taskID string, from, to int) []v1.Annotation {
+	if a == nil {
+		return nil
+	}
+	var out []v1.Annotation
+	for _, an := range a.byTask[taskID] {
+		if an.Index >= from && an.Index < to {
+			out = append(out, an)
+		}
+	}
+	return out
+}
+
+// add appends an annotation to the task and persists the store. On a write
+// failure the store is left unchanged.
+func (a *annotationStore) add(taskID string, an v1.Annotation) error {
+	prev := a.byTask[taskID]
+	l := append(slices.Clip(prev), an)
+	slices.SortStableFunc(l, func(x, y v1.Annotation) int { return cmp.Compare(x.Index, y.Index) })
+	a.byTask[taskID] = l
+	if err := a.write(); err != nil {
+		a.byTask[taskID] = prev
+		return err
+	}
+	return nil
+}
+
+// remove deletes the annotation id of the task and persists the store. It
+// reports whether the annotation existed.
+func (a *annotationStore) remove(taskID, id string) (bool, error) {
+	prev := a.byTask[taskID]
+	i := slices.IndexFunc(prev, func(an v1.Annotation) bool { return an.ID.String() == id })
+	if i < 0 {
+		return false, nil
+	}
+	if l := slices.Delete(slices.Clone(prev), i, i+1); len(l) > 0 {
+		a.byTask[taskID] = l
+	} else {
+		delete(a.byTask, taskID)
+	}
+	if err := a.write(); err != nil {
+		a.byTask[taskID] = prev
+		return false, err
+	}
+	return true, nil
+}
+
+// write writes the store to its path via a temp file + rename.
+func (a *annotationStore) write() error {
+	data, err := json.MarshalIndent(a.byTask, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o700); err != nil {
+		return err
+	}
+	tmp := a.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, a.path)
+}
+
+// taskAnnotations returns the annotations of the messages in [from, to) of
+// the task.
+func (s *Server) taskAnnotations(entry *taskEntry, from, to int) []v1.Annotation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.annotations.list(entry.task.ID.String(), from, to)
+}
+
+// annotateMessage attaches a note or bookmark to the history message at the
+// request's index.
+func (s *Server) annotateMessage(ctx context.Context, entry *taskEntry, req *v1.AnnotateMessageReq) (*v1.Annotation, error) {
+	index, _ := strconv.Atoi(req.Index) // Checked by Validate.
+	if msgs, _ := entry.task.MessagesPage(index, 1); len(msgs) == 0 {
+		return nil, dto.NotFound("message")
+	}
+	an := v1.Annotation{
+		ID:        ksid.NewID(),
+		Index:     index,
+		Note:      req.Note,
+		Bookmark:  req.Bookmark,
+		Author:    userIDFromCtx(ctx),
+		CreatedAt: time.Now().UTC(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.annotations.add(entry.task.ID.String(), an); err != nil {
+		return nil, dto.InternalError("save annotations: " + err.Error())
+	}
+	return &an, nil
+}
+
+// deleteAnnotation reverts annotateMessage.
+func (s *Server) deleteAnnotation(_ context.Context, entry *taskEntry, req *v1.DeleteAnnotationReq) (*v1.StatusResp, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found, err := s.annotations.remove(entry.task.ID.String(), req.AnnotationID)
+	if err != nil {
+		return nil, dto.InternalError("save annotations: " + err.Error())
+	}
+	if !found {
+		return nil, dto.NotFound("annotation")
+	}
+	return &v1.StatusResp{Status: "deleted"}, nil
+}
+
+// handleListAnnotations returns all the annotations of the task, by message
+// index.
+func (s *Server) handleListAnnotations(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	out := s.taskAnnotations(entry, 0, math.MaxInt)
+	if out == nil {
+		out = []v1.Annotation{}
+	}
+	writeJSONResponse(w, &out, nil)
+}
@@ -202,6 +202,7 @@ func (s *Server) handleGetMe(w http.ResponseWriter, r *http.Request) {
 		Provider:  string(u.Provider),
 		Username:  u.Username,
 		AvatarURL: u.AvatarURL,
+		Admin:     s.isAdmin(r.Context()),
 	}, nil)
 }
 
@@ -122,7 +122,7 @@ func (s *Server) monitorCI(ctx context.Context, entry *taskEntry, f forge.Forge,
 		case <-ticker.C:
 		}
 		st := t.GetState()
-		if st != task.StateWaiting && st != task.StateAsking && st != task.StateHasPlan {
+		if st != task.StateWaiting && st != task.StateAsking && st != task.StateHasPlan && st != task.StatePlanReview && st != task.StateRateLimited {
 			return
 		}
 		if checkOnce() {
@@ -155,9 +155,14 @@ func (s *Server) waitForAgentResult(ctx context.Context, t *task.Task) bool {
 // autoResync waits for the agent to finish its current turn, then pushes the
 // latest branch commits to origin and starts a new CI monitoring goroutine.
 // Called after a CI failure so the loop closes: CI fails → agent fixes →
-// auto-push → CI re-runs → (repeat or merge on success).
+// auto-push → CI re-runs → (repeat or merge on success). Tasks whose push
+// policy is not auto wait for the user to push.
 func (s *Server) autoResync(ctx context.Context, entry *taskEntry, f forge.Forge, owner, repo string) {
 	t := entry.task
+	if !s.pushPolicy(t).Automatic() {
+		slog.Info("autoResync: push policy requires a manual push", "task", t.ID)
+		return
+	}
 	if !s.waitForAgentResult(ctx, t) {
 		return
 	}
@@ -179,11 +184,19 @@ func (s *Server) autoResync(ctx context.Context, entry *taskEntry, f forge.Forge
 		return
 	}
 
+	verified, issues := s.prePushChecks(ctx, t)
+	if len(issues) != 0 {
+		slog.Info("autoResync: blocked by the pre-push check", "task", t.ID)
+		return
+	}
 	slog.Info("autoResync: syncing branch", "task", t.ID, "br", p.Branch)
-	if _, _, err := runner.SyncToOrigin(ctx, p.Branch, t.Container, false, t.ExtraMDRepos()); err != nil {
+	if _, _, err := runner.SyncToOrigin(ctx, p.Branch, p.PushRemote, t.Container, false, t.ExtraMDRepos()); err != nil {
 		slog.Warn("autoResync: sync failed", "task", t.ID, "err", err)
 		return
 	}
+	if info := s.repoInfoFor(p.Name); info != nil {
+		s.postCommitStatus(ctx, f, t, info, p.PushRemote, p.Branch, verified)
+	}
 
 	// Fetch the new branch HEAD SHA from the forge after the push.
 	newSHA, err := f.GetDefaultBranchSHA(ctx, owner, repo, p.Branch)
@@ -0,0 +1,84 @@
+// Commit statuses: provenance of the pushed task branches, shown by the forge in the PR.
+package server
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/forge"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md/gitutil"
+)
+
+// commitStatusContext identifies the commit statuses posted by caic.
+const commitStatusContext = "caic"
+
+// maxCommitStatusDescription is GitHub's limit on the description length.
+const maxCommitStatusDescription = 140
+
+// commitStatusDescription summarizes the task that produced a pushed branch,
+// e.g. "Agent task w42 (claude, opus), cost $1.23, verified: pre-push check
+// passed".
+func commitStatusDescription(t *task.Task, verified bool) string {
+	snap := t.Snapshot()
+	var b strings.Builder
+	b.WriteString("Agent task " + cmp.Or(t.Alias, t.ID.String()))
+	if snap.Harness != "" {
+		b.WriteString(" (" + string(snap.Harness))
+		if snap.Model != "" {
+			b.WriteString(", " + snap.Model)
+		}
+		b.WriteString(")")
+	}
+	fmt.Fprintf(&b, ", cost $%.2f", snap.CostUSD)
+	if verified {
+		b.WriteString(", verified: pre-push check passed")
+	}
+	d := b.String()
+	if r := []rune(d); len(r) > maxCommitStatusDescription {
+		d = string(r[:maxCommitStatusDescription-1]) + "…"
+	}
+	return d
+}
+
+// taskURL returns the link to the task in the web UI, or "" when the
+// external URL of the server is unknown.
+func (s *Server) taskURL(t *task.Task) string {
+	if s.hostState == nil {
+		return ""
+	}
+	u := s.hostState.ExternalURL()
+	if u == "" {
+		return ""
+	}
+	return strings.TrimSuffix(u, "/") + "/task/@" + t.ID.String()
+}
+
+// postCommitStatus posts the provenance of t on the head of branch, just
+// pushed from its container to remote, so that reviewers see it in the PR.
+// verified reports that the pre-push checks passed. Failures are only
+// logged; the status is informational.
+func (s *Server) postCommitStatus(ctx context.Context, f forge.Forge, t *task.Task, info *repoInfo, remote, branch string, verified bool) {
+	sha, err := gitutil.RunGit(ctx, info.AbsPath, "rev-parse", "refs/remotes/"+t.Container+"/"+branch)
+	if err != nil {
+		slog.WarnContext(ctx, "commit status: resolve branch head", "task", t.ID, "br", branch, "err", err)
+		return
+	}
+	// The commit of a branch pushed to a fork only exists in the fork.
+	owner, repo := info.ForgeOwner, info.ForgeRepo
+	if o, r, _, ok := forge.SplitForkHead(prHead(ctx, info, remote, branch)); ok {
+		owner, repo = o, r
+	}
+	st := &forge.CommitStatus{
+		State:       forge.CommitStateSuccess,
+		Context:     commitStatusContext,
+		Description: commitStatusDescription(t, verified),
+		TargetURL:   s.taskURL(t),
+	}
+	if err := f.SetCommitStatus(ctx, owner, repo, sha, st); err != nil {
+		slog.WarnContext(ctx, "commit status", "task", t.ID, "owner", owner, "repo", repo, "err", err)
+	}
+}
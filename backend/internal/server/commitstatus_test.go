@@ -0,0 +1,59 @@
+// Tests for the commit statuses of the pushed task branches.
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/forge"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md/gitutil"
+)
+
+func TestCommitStatus(t *testing.T) {
+	t.Run("Description", func(t *testing.T) {
+		tk := &task.Task{Alias: "w42", Harness: agent.Claude}
+		if got, want := commitStatusDescription(tk, true), "Agent task w42 (claude), cost $0.00, verified: pre-push check passed"; got != want {
+			t.Errorf("description = %q, want %q", got, want)
+		}
+		tk.Alias = strings.Repeat("x", 200)
+		if got := commitStatusDescription(tk, false); len([]rune(got)) != maxCommitStatusDescription || !strings.HasSuffix(got, "…") {
+			t.Errorf("description = %q, want truncated", got)
+		}
+	})
+	t.Run("Post", func(t *testing.T) {
+		dir := newGitRepo(t, map[string]string{"README.md": "hello\n"})
+		head, err := gitutil.RunGit(t.Context(), dir, "rev-parse", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{
+			{"update-ref", "refs/remotes/md-repo-caic-0/caic-0", head},
+			{"remote", "add", "fork", "git@github.com:me/repo.git"},
+		} {
+			if _, err := gitutil.RunGit(t.Context(), dir, args...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		s := newTestServer(t)
+		info := &repoInfo{RelPath: "org/repo", AbsPath: dir, ForgeKind: forge.KindGitHub, ForgeOwner: "org", ForgeRepo: "repo"}
+		tk := &task.Task{Alias: "w42", Container: "md-repo-caic-0"}
+		f := &stubForge{}
+		s.postCommitStatus(t.Context(), f, tk, info, "", "caic-0", false)
+		s.postCommitStatus(t.Context(), f, tk, info, "fork", "caic-0", false)
+		if len(f.statuses) != 2 {
+			t.Fatalf("statuses = %+v, want 2", f.statuses)
+		}
+		if got := f.statuses[0]; got.owner != "org" || got.repo != "repo" || got.sha != head || got.st.Context != "caic" || got.st.State != forge.CommitStateSuccess {
+			t.Errorf("origin status = %+v", got)
+		}
+		if got := f.statuses[1]; got.owner != "me" || got.repo != "repo" {
+			t.Errorf("fork status = %+v, want on me/repo", got)
+		}
+		s.postCommitStatus(t.Context(), f, tk, info, "", "missing", false)
+		if len(f.statuses) != 2 {
+			t.Errorf("status posted for a missing branch")
+		}
+	})
+}
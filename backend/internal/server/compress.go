@@ -55,6 +55,16 @@ type compressWriter struct {
 }
 
 func (cw *compressWriter) WriteHeader(code int) {
+	// Informational responses precede the final headers.
+	if code < http.StatusOK {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	// Partial and bodiless responses describe a representation the handler
+	// chose; compressing them would change it.
+	if !cw.headerSent && (code == http.StatusPartialContent || code == http.StatusNotModified) {
+		cw.headerSent, cw.skipCompress = true, true
+	}
 	cw.initOnce()
 	cw.ResponseWriter.WriteHeader(code)
 }
@@ -111,15 +121,21 @@ func (cw *compressWriter) finish() {
 // Flush flushes compressed data to the wire. Calls initOnce so that
 // Content-Encoding is set before the first flush sends headers.
 func (cw *compressWriter) Flush() {
+	_ = cw.FlushError()
+}
+
+// FlushError is Flush reporting write errors, used by
+// http.ResponseController.
+func (cw *compressWriter) FlushError() error {
 	cw.initOnce()
 	if cw.writer != nil {
 		if f, ok := cw.writer.(interface{ Flush() error }); ok {
-			_ = f.Flush()
+			if err := f.Flush(); err != nil {
+				return err
+			}
 		}
 	}
-	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
-		f.Flush()
-	}
+	return http.NewResponseController(cw.ResponseWriter).Flush()
 }
 
 // Unwrap returns the underlying ResponseWriter for http.ResponseController.
@@ -161,6 +161,25 @@ func TestCompressMiddleware(t *testing.T) {
 		}
 	})
 
+	t.Run("SkipsPartial", func(t *testing.T) {
+		h := compressMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Range", "bytes 0-3/10")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write([]byte("part"))
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		req.Header.Set("Accept-Encoding", "zstd")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want empty", got)
+		}
+		if got := w.Body.String(); got != "part" {
+			t.Errorf("body = %q, want %q", got, "part")
+		}
+	})
+
 	t.Run("NoAcceptEncoding", func(t *testing.T) {
 		h := compressMiddleware(jsonHandler())
 		req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
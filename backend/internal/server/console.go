@@ -0,0 +1,109 @@
+// Task console streaming: the raw output of a task's container, for the noise
+// that never reaches the agent's message stream.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// Console history replayed per source.
+const (
+	defaultConsoleTail = 200
+	maxConsoleTail     = 10000
+)
+
+// consoleBuffer is the number of lines buffered for a slow subscriber before
+// the container streams are throttled.
+const consoleBuffer = 256
+
+// handleTaskConsole streams the console output of the task's container as SSE
+// message events until the container stops or the subscriber leaves.
+func (s *Server) handleTaskConsole(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	tail := defaultConsoleTail
+	if v := r.URL.Query().Get("tail"); v != "" {
+		if tail, err = strconv.Atoi(v); err != nil || tail < 0 || tail > maxConsoleTail {
+			writeError(w, dto.BadRequest("invalid tail: "+v+" (lines, at most 10000)"))
+			return
+		}
+	}
+	runner := s.taskRunner(entry.task)
+	if runner == nil {
+		writeError(w, dto.InternalError("no runner for task"))
+		return
+	}
+	if entry.task.Container == "" {
+		writeError(w, dto.Conflict("task has no container"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, dto.InternalError("streaming not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher.Flush()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	lines := make(chan task.ConsoleLine, consoleBuffer)
+	go func() {
+		defer close(lines)
+		err := runner.Console(ctx, entry.task, tail, func(l task.ConsoleLine) {
+			select {
+			case lines <- l:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "console", "task", entry.task.ID, "err", err)
+		}
+	}()
+
+	write := func(l *task.ConsoleLine) {
+		data, _ := json.Marshal(&v1.ConsoleLine{Source: l.Source, Text: l.Text, At: l.At})
+		_, _ = fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	}
+	ka := s.newSSEKeepAlive(w)
+	defer ka.stop()
+	for {
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				return
+			}
+			write(&l)
+			// Write the lines queued meanwhile before flushing, so a burst
+			// like an install log costs one flush.
+			for n := len(lines); n > 0; n-- {
+				if l, ok = <-lines; !ok {
+					break
+				}
+				write(&l)
+			}
+			flusher.Flush()
+		case <-ka.C():
+			if err := ka.ping(); err != nil {
+				slog.DebugContext(r.Context(), "SSE subscriber gone", "stream", "console", "task", entry.task.ID, "err", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Tests for the task console stream.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// consoleBackend is a container backend that only implements Console.
+type consoleBackend struct {
+	task.ContainerBackend
+	lines []task.ConsoleLine
+	tail  int
+}
+
+func (c *consoleBackend) Console(_ context.Context, _ string, tail int, fn func(task.ConsoleLine)) error {
+	c.tail = tail
+	for _, l := range c.lines {
+		fn(l)
+	}
+	return nil
+}
+
+func TestHandleTaskConsole(t *testing.T) {
+	newServer := func(t *testing.T, backend *consoleBackend, container string) *Server {
+		s := newTestServer(t)
+		s.runners["org/repo"] = &task.Runner{Dir: "/src/repo", BaseBranch: "main", Container: backend}
+		tk := &task.Task{Repos: []task.RepoMount{{Name: "org/repo", Branch: "caic-0"}}, Container: container}
+		s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+		return s
+	}
+	get := func(s *Server, query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/t1/console"+query, http.NoBody)
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		s.handleTaskConsole(w, req)
+		return w
+	}
+	t.Run("Stream", func(t *testing.T) {
+		at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		backend := &consoleBackend{lines: []task.ConsoleLine{
+			{Source: task.ConsoleContainer, Text: "sshd started", At: at},
+			{Source: task.ConsoleAgent, Text: "npm http fetch GET 200", At: at},
+		}}
+		w := get(newServer(t, backend, "md-repo-caic-0"), "?tail=5")
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("status = %d: %s", w.Code, w.Body)
+		}
+		if backend.tail != 5 {
+			t.Errorf("tail = %d, want 5", backend.tail)
+		}
+		var got []v1.ConsoleLine
+		for line := range strings.Lines(w.Body.String()) {
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var l v1.ConsoleLine
+				if err := json.Unmarshal([]byte(data), &l); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, l)
+			}
+		}
+		if len(got) != 2 || got[0] != (v1.ConsoleLine{Source: "container", Text: "sshd started", At: at}) || got[1].Source != "agent" || got[1].Text != "npm http fetch GET 200" {
+			t.Errorf("lines = %+v", got)
+		}
+	})
+	t.Run("NoContainer", func(t *testing.T) {
+		if w := get(newServer(t, &consoleBackend{}, ""), ""); w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want 409", w.Code)
+		}
+	})
+	t.Run("InvalidTail", func(t *testing.T) {
+		if w := get(newServer(t, &consoleBackend{}, "md-repo-caic-0"), "?tail=-1"); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+	})
+}
@@ -0,0 +1,209 @@
+// Debug bundle: a zip of sanitized server state to attach to bug reports.
+package server
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/autoupdate"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+// debugBundleTaskOmit lists the Task fields left out of debug bundles on top
+// of the summary ones: agent output may quote code or secrets.
+var debugBundleTaskOmit = append(slices.Clone(taskSummaryOmit), "result")
+
+// secretSuffixes identifies the Config fields redacted from debug bundles.
+var secretSuffixes = []string{"Key", "Token", "Secret", "PEM"}
+
+// redacted returns the configuration as a map for debug bundles, with the
+// value of secret fields and the credentials of URLs replaced by "redacted"
+// and runtime handles dropped.
+func (c *Config) redacted() map[string]any {
+	out := map[string]any{}
+	v := reflect.ValueOf(c).Elem()
+	for i := range v.NumField() {
+		f := v.Type().Field(i)
+		fv := v.Field(i)
+		switch {
+		case fv.Kind() == reflect.Pointer:
+			continue
+		case slices.ContainsFunc(secretSuffixes, func(s string) bool { return strings.HasSuffix(f.Name, s) }):
+			if !fv.IsZero() {
+				out[f.Name] = "redacted"
+			} else {
+				out[f.Name] = ""
+			}
+		case fv.Kind() == reflect.String && strings.HasSuffix(f.Name, "URL"):
+			out[f.Name] = redactURL(fv.String())
+		case fv.Type() == reflect.TypeFor[time.Duration]():
+			out[f.Name] = fv.Interface().(time.Duration).String()
+		default:
+			out[f.Name] = fv.Interface()
+		}
+	}
+	return out
+}
+
+// redactURL replaces the userinfo of rawURL, e.g. the password of
+// "redis://:password@host:6379", by "redacted". A value that doesn't parse is
+// redacted whole.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "redacted"
+	}
+	if u.User != nil {
+		u.User = url.User("redacted")
+	}
+	return u.String()
+}
+
+// handleDebugBundle serves GET /api/v1/admin/debugbundle: a zip with the
+// server version, redacted configuration, recent logs, goroutine dump, task
+// summaries and container list. It is not in v1.Routes since the response is
+// not JSON.
+func (s *Server) handleDebugBundle(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC()
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="caic-debug-%s.zip"`, now.Format("20060102-150405")))
+	if err := s.writeDebugBundle(r.Context(), w, now); err != nil {
+		// Headers are sent; the truncated zip is the only signal left.
+		slog.WarnContext(r.Context(), "debug bundle", "err", err)
+	}
+}
+
+// writeDebugBundle writes the debug bundle zip to w. A failure to collect one
+// part is recorded in errors.txt instead of failing the bundle.
+func (s *Server) writeDebugBundle(ctx context.Context, w io.Writer, now time.Time) error {
+	zw := zip.NewWriter(w)
+	var zipErr error
+	var problems []string
+	add := func(name string, write func(io.Writer) error) {
+		if zipErr != nil {
+			return
+		}
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			zipErr = err
+			return
+		}
+		if err := write(f); err != nil {
+			problems = append(problems, name+": "+err.Error())
+		}
+	}
+	addJSON := func(name string, get func() (any, error)) {
+		add(name, func(w io.Writer) error {
+			v, err := get()
+			if err != nil {
+				return err
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "  ")
+			return e.Encode(v)
+		})
+	}
+
+	addJSON("version.json", func() (any, error) {
+		return map[string]any{
+			"version":    autoupdate.Version,
+			"go":         runtime.Version(),
+			"os":         runtime.GOOS,
+			"arch":       runtime.GOARCH,
+			"goroutines": runtime.NumGoroutine(),
+			"time":       now,
+		}, nil
+	})
+	addJSON("config.json", func() (any, error) { return s.debugConfig, nil })
+	add("logs.jsonl", func(w io.Writer) error {
+		if s.logRing == nil {
+			return nil
+		}
+		for _, l := range s.logRing.Lines() {
+			if _, err := w.Write(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	add("goroutines.txt", func(w io.Writer) error {
+		return pprof.Lookup("goroutine").WriteTo(w, 2)
+	})
+	addJSON("tasks.json", func() (any, error) { return s.debugTasks() })
+	addJSON("containers.json", func() (any, error) { return s.debugContainers(ctx) })
+	if len(problems) != 0 {
+		add("errors.txt", func(w io.Writer) error {
+			_, err := io.WriteString(w, strings.Join(problems, "\n")+"\n")
+			return err
+		})
+	}
+	if zipErr != nil {
+		return zipErr
+	}
+	return zw.Close()
+}
+
+// debugTasks returns the tasks without prompts, plans and results, sorted by
+// ID.
+func (s *Server) debugTasks() ([]json.RawMessage, error) {
+	s.mu.Lock()
+	tasks := make([]v1.Task, 0, len(s.tasks))
+	for _, e := range s.tasks {
+		tasks = append(tasks, s.toJSON(e))
+	}
+	s.mu.Unlock()
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	proj := &taskProjection{omit: debugBundleTaskOmit}
+	out := make([]json.RawMessage, len(tasks))
+	for i := range tasks {
+		var err error
+		if out[i], err = proj.marshal(&tasks[i]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// debugContainer is the debug bundle summary of a container.
+type debugContainer struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	CreatedAt time.Time `json:"createdAt"`
+	Branches  []string  `json:"branches,omitempty"`
+	Tailscale bool      `json:"tailscale,omitempty"`
+	USB       bool      `json:"usb,omitempty"`
+	Display   bool      `json:"display,omitempty"`
+}
+
+// debugContainers lists the md containers.
+func (s *Server) debugContainers(ctx context.Context) ([]debugContainer, error) {
+	if s.mdClient == nil {
+		return nil, nil
+	}
+	containers, err := s.mdClient.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]debugContainer, 0, len(containers))
+	for _, c := range containers {
+		d := debugContainer{Name: c.Name, State: c.State, CreatedAt: c.CreatedAt, Tailscale: c.Tailscale, USB: c.USB, Display: c.Display}
+		for _, r := range c.Repos {
+			d.Branches = append(d.Branches, r.Branch)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
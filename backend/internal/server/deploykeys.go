@@ -0,0 +1,222 @@
+// Per-repo SSH deploy keys, so that repos are fetched and pushed without the user's personal credentials.
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/forge"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md/gitutil"
+	"golang.org/x/crypto/ssh"
+)
+
+// deployKeyFile returns the path of the private key of the repo rel; the
+// public key is next to it with a .pub suffix.
+func (s *Server) deployKeyFile(rel string) string {
+	return filepath.Join(s.deployKeysDir, url.PathEscape(rel))
+}
+
+// existingDeployKey returns the private key file of the repo rel, or "" if
+// it has none.
+func (s *Server) existingDeployKey(rel string) string {
+	p := s.deployKeyFile(rel)
+	if _, err := os.Stat(p); err != nil {
+		return ""
+	}
+	return p
+}
+
+// repoDeployKey returns the deploy key file of the repo rel, or "".
+func (s *Server) repoDeployKey(rel string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.repos {
+		if s.repos[i].RelPath == rel {
+			return s.repos[i].DeployKey
+		}
+	}
+	return ""
+}
+
+// sshRemoteURL returns the SSH form of an HTTPS remote URL, e.g.
+// "git@github.com:org/repo.git" for "https://github.com/org/repo.git". It
+// returns false for the other URLs, which need no rewrite.
+func sshRemoteURL(remote string) (string, bool) {
+	u, err := url.Parse(remote)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return "", false
+	}
+	p := strings.Trim(u.Path, "/")
+	if p == "" {
+		return "", false
+	}
+	return "git@" + u.Hostname() + ":" + p, true
+}
+
+// deployKeySettingsURL returns the forge page deploy keys are added on.
+func deployKeySettingsURL(ri *repoInfo) string {
+	base := gitutil.RemoteToHTTPS(ri.Remote)
+	if !strings.HasPrefix(base, "https://") {
+		return ""
+	}
+	switch ri.ForgeKind {
+	case forge.KindGitHub:
+		return base + "/settings/keys/new"
+	case forge.KindGitLab:
+		return base + "/-/settings/repository#js-deploy-keys-settings"
+	default:
+		return ""
+	}
+}
+
+// generateDeployKey writes a new ed25519 key pair at keyFile and keyFile.pub.
+func generateDeployKey(keyFile, comment string) error {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	block, err := ssh.MarshalPrivateKey(priv, comment)
+	if err != nil {
+		return err
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0o700); err != nil {
+		return err
+	}
+	authorized := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub))) + " " + comment + "\n"
+	if err := os.WriteFile(keyFile+".pub", []byte(authorized), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600)
+}
+
+// configureDeployKey makes git use keyFile for the remotes of the repo at
+// abs, rewriting an HTTPS origin to SSH. An empty keyFile reverts it.
+func configureDeployKey(ctx context.Context, abs, remote, keyFile string) error {
+	sshURL, rewrite := sshRemoteURL(remote)
+	if keyFile == "" {
+		var errs []error
+		if _, err := gitutil.RunGit(ctx, abs, "config", "--unset", "core.sshCommand"); err != nil {
+			errs = append(errs, err)
+		}
+		if rewrite {
+			// The section is absent when the remote was already SSH.
+			_, _ = gitutil.RunGit(ctx, abs, "config", "--remove-section", "url."+sshURL)
+		}
+		return errors.Join(errs...)
+	}
+	if _, err := gitutil.RunGit(ctx, abs, "config", "core.sshCommand", task.DeployKeySSHCommand(keyFile)); err != nil {
+		return err
+	}
+	if rewrite {
+		if _, err := gitutil.RunGit(ctx, abs, "config", "url."+sshURL+".insteadOf", remote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createDeployKey generates the deploy key of a repo, unless it has one and
+// req.Rotate is false, and configures the repo to fetch and push with it.
+// The tasks started afterwards get the key in their container.
+func (s *Server) createDeployKey(ctx context.Context, req *v1.DeployKeyReq) (*v1.DeployKeyResp, error) {
+	s.mu.Lock()
+	var ri repoInfo
+	found := false
+	for i := range s.repos {
+		if s.repos[i].RelPath == req.Repo {
+			ri, found = s.repos[i], true
+			break
+		}
+	}
+	s.mu.Unlock()
+	if !found {
+		return nil, dto.NotFound("repo")
+	}
+	keyFile := s.deployKeyFile(ri.RelPath)
+	if ri.DeployKey == "" || req.Rotate {
+		if err := generateDeployKey(keyFile, "caic deploy key for "+ri.RelPath); err != nil {
+			return nil, dto.InternalError("generate deploy key: " + err.Error())
+		}
+	}
+	if err := configureDeployKey(ctx, ri.AbsPath, ri.Remote, keyFile); err != nil {
+		return nil, dto.InternalError("configure deploy key: " + err.Error())
+	}
+	authorized, err := os.ReadFile(keyFile + ".pub") //nolint:gosec // G304: path derived from the repo path
+	if err != nil {
+		return nil, dto.InternalError("read deploy key: " + err.Error())
+	}
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(authorized)
+	if err != nil {
+		return nil, dto.InternalError("parse deploy key: " + err.Error())
+	}
+	s.setRepoDeployKey(ri.RelPath, keyFile)
+	slog.InfoContext(ctx, "deploy key configured", "repo", ri.RelPath, "rotate", req.Rotate)
+	return &v1.DeployKeyResp{
+		Repo:        ri.RelPath,
+		PublicKey:   strings.TrimSpace(string(authorized)),
+		Fingerprint: ssh.FingerprintSHA256(pub),
+		SettingsURL: deployKeySettingsURL(&ri),
+	}, nil
+}
+
+// deleteDeployKey deletes the deploy key of a repo and reverts its git
+// configuration. Running tasks keep the key in their container.
+func (s *Server) deleteDeployKey(ctx context.Context, req *v1.DeleteDeployKeyReq) (*v1.StatusResp, error) {
+	s.mu.Lock()
+	var ri repoInfo
+	for i := range s.repos {
+		if s.repos[i].RelPath == req.Repo {
+			ri = s.repos[i]
+			break
+		}
+	}
+	s.mu.Unlock()
+	if ri.DeployKey == "" {
+		return nil, dto.NotFound("deploy key")
+	}
+	if err := configureDeployKey(ctx, ri.AbsPath, ri.Remote, ""); err != nil {
+		slog.WarnContext(ctx, "revert deploy key configuration", "repo", ri.RelPath, "err", err)
+	}
+	for _, p := range []string{ri.DeployKey, ri.DeployKey + ".pub"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return nil, dto.InternalError("delete deploy key: " + err.Error())
+		}
+	}
+	s.setRepoDeployKey(ri.RelPath, "")
+	return &v1.StatusResp{Status: "deleted"}, nil
+}
+
+// setRepoDeployKey records the deploy key file of the repo rel and notifies
+// the clients of the repo list change.
+func (s *Server) setRepoDeployKey(rel, keyFile string) {
+	s.mu.Lock()
+	for i := range s.repos {
+		if s.repos[i].RelPath == rel {
+			s.repos[i].DeployKey = keyFile
+		}
+	}
+	s.mu.Unlock()
+	s.notifyTaskChange()
+}
+
+// deployKeyMounts fills the deploy keys of the mounts from their repos.
+func (s *Server) deployKeyMounts(mounts []task.RepoMount) {
+	for i := range mounts {
+		mounts[i].DeployKey = s.repoDeployKey(mounts[i].Name)
+	}
+}
@@ -0,0 +1,84 @@
+// Tests for the per-repo deploy keys.
+package server
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/forge"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md/gitutil"
+)
+
+func TestDeployKeys(t *testing.T) {
+	t.Run("sshRemoteURL", func(t *testing.T) {
+		if got, ok := sshRemoteURL("https://github.com/org/repo.git"); !ok || got != "git@github.com:org/repo.git" {
+			t.Errorf("https = %q, %t", got, ok)
+		}
+		for _, in := range []string{"git@github.com:org/repo.git", "ssh://git@host/repo", "/srv/repo.git", "https://host/"} {
+			if got, ok := sshRemoteURL(in); ok {
+				t.Errorf("sshRemoteURL(%q) = %q, want no rewrite", in, got)
+			}
+		}
+	})
+	t.Run("CreateAndDelete", func(t *testing.T) {
+		dir := newGitRepo(t, map[string]string{"README.md": "hello\n"})
+		const remote = "https://github.com/org/repo.git"
+		if _, err := gitutil.RunGit(t.Context(), dir, "remote", "add", "origin", remote); err != nil {
+			t.Fatal(err)
+		}
+		s := newTestServer(t)
+		s.deployKeysDir = t.TempDir()
+		s.repos = []repoInfo{{RelPath: "org/repo", AbsPath: dir, Remote: remote, ForgeKind: forge.KindGitHub}}
+
+		resp, err := s.createDeployKey(t.Context(), &v1.DeployKeyReq{Repo: "org/repo"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(resp.PublicKey, "ssh-ed25519 ") || !strings.HasPrefix(resp.Fingerprint, "SHA256:") {
+			t.Errorf("resp = %+v", resp)
+		}
+		if resp.SettingsURL != "https://github.com/org/repo/settings/keys/new" {
+			t.Errorf("settings URL = %q", resp.SettingsURL)
+		}
+		if got, err := gitutil.RunGit(t.Context(), dir, "remote", "get-url", "--push", "origin"); err != nil || got != "git@github.com:org/repo.git" {
+			t.Errorf("push URL = %q, %v", got, err)
+		}
+		keyFile := s.deployKeyFile("org/repo")
+		if got, err := gitutil.RunGit(t.Context(), dir, "config", "core.sshCommand"); err != nil || got != task.DeployKeySSHCommand(keyFile) {
+			t.Errorf("core.sshCommand = %q, %v", got, err)
+		}
+		mounts := []task.RepoMount{{Name: "org/repo"}, {Name: "other"}}
+		s.deployKeyMounts(mounts)
+		if mounts[0].DeployKey != keyFile || mounts[1].DeployKey != "" {
+			t.Errorf("mounts = %+v", mounts)
+		}
+
+		again, err := s.createDeployKey(t.Context(), &v1.DeployKeyReq{Repo: "org/repo"})
+		if err != nil || again.PublicKey != resp.PublicKey {
+			t.Errorf("second create = %+v, %v; want the same key", again, err)
+		}
+		rotated, err := s.createDeployKey(t.Context(), &v1.DeployKeyReq{Repo: "org/repo", Rotate: true})
+		if err != nil || rotated.PublicKey == resp.PublicKey {
+			t.Errorf("rotate = %+v, %v; want a new key", rotated, err)
+		}
+
+		if _, err := s.deleteDeployKey(t.Context(), &v1.DeleteDeployKeyReq{Repo: "org/repo"}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(keyFile); !os.IsNotExist(err) {
+			t.Errorf("key not deleted: %v", err)
+		}
+		if got, err := gitutil.RunGit(t.Context(), dir, "remote", "get-url", "--push", "origin"); err != nil || got != remote {
+			t.Errorf("push URL after delete = %q, %v", got, err)
+		}
+		if _, err := s.deleteDeployKey(t.Context(), &v1.DeleteDeployKeyReq{Repo: "org/repo"}); err == nil {
+			t.Error("second delete succeeded")
+		}
+		if _, err := s.createDeployKey(t.Context(), &v1.DeployKeyReq{Repo: "missing"}); err == nil {
+			t.Error("create on a missing repo succeeded")
+		}
+	})
+}
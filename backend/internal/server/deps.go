@@ -0,0 +1,159 @@
+// Task dependencies: dependent tasks stay pending until their prerequisites are done.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// depStatus is the progress of a prerequisite as seen by its dependents.
+type depStatus int
+
+const (
+	depPending depStatus = iota // Still working, stopped, or waiting on a user.
+	depDone                     // Finished a turn successfully.
+	depFailed                   // Will never be done.
+)
+
+// depPollInterval bounds how long a dependent waits to notice a state change
+// driven by the agent rather than by a server mutation.
+const depPollInterval = 2 * time.Second
+
+// errWaitCanceled is returned by waitForDependencies when the dependent task
+// was purged while pending.
+var errWaitCanceled = errors.New("purged while waiting on dependencies")
+
+// prerequisiteStatus reports whether t is done. A task is done once its last
+// turn ended with a successful result and it is idle (waiting for input or
+// purged). A failed task, or one purged before finishing, is failed.
+func prerequisiteStatus(t *task.Task) depStatus {
+	state := t.GetState()
+	switch state {
+	case task.StateFailed:
+		return depFailed
+	case task.StateWaiting, task.StatePurging, task.StatePurged:
+		if lastResultOK(t) {
+			return depDone
+		}
+		if state == task.StateWaiting {
+			return depPending
+		}
+		return depFailed
+	default:
+		return depPending
+	}
+}
+
+// lastResultOK reports whether the last result message of t is a success.
+func lastResultOK(t *task.Task) bool {
+	rm := lastResult(t)
+	return rm != nil && !rm.IsError
+}
+
+// resolveDependencies looks up the prerequisites of req and checks that an
+// inherited branch is usable.
+func (s *Server) resolveDependencies(req *v1.CreateTaskReq) ([]*taskEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deps := make([]*taskEntry, len(req.DependsOn))
+	for i, id := range req.DependsOn {
+		e, ok := s.tasks[id]
+		if !ok {
+			return nil, dto.BadRequest("unknown dependency: " + id)
+		}
+		deps[i] = e
+	}
+	if req.InheritBranch {
+		dep := deps[0].task
+		if dep.PlanOnly || dep.ReadOnly || dep.Chat {
+			return nil, dto.BadRequest("dependency " + req.DependsOn[0] + " has no branch to inherit")
+		}
+		if p := dep.Primary(); p == nil || p.Name != req.Repos[0].Name {
+			return nil, dto.BadRequest("inheritBranch requires the dependency to use repo " + req.Repos[0].Name)
+		}
+	}
+	return deps, nil
+}
+
+// waitForDependencies blocks until every prerequisite is done. When one fails,
+// it returns an error under the cancel policy and keeps waiting under the
+// hold policy, in case the prerequisite is revived. It returns
+// errWaitCanceled when ctx is canceled.
+func (s *Server) waitForDependencies(ctx context.Context, entry *taskEntry, deps []*taskEntry, policy v1.DependencyFailurePolicy) error {
+	held := false
+	ticker := time.NewTicker(depPollInterval)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+		done := true
+		var failed *taskEntry
+		for _, d := range deps {
+			switch prerequisiteStatus(d.task) {
+			case depFailed:
+				failed = d
+				done = false
+			case depPending:
+				done = false
+			case depDone:
+			}
+		}
+		if done {
+			return nil
+		}
+		if failed != nil {
+			if policy != v1.DependencyFailureHold {
+				return fmt.Errorf("dependency %s failed", failed.task.ID)
+			}
+			if !held {
+				held = true
+				slog.Info("dependency failed, holding task", "task", entry.task.ID, "dep", failed.task.ID)
+			}
+		}
+		select {
+		case <-changed:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return errWaitCanceled
+		}
+	}
+}
+
+// inheritBranch pushes the prerequisite's primary branch to its push remote
+// and makes it the base branch of t's primary repo. The push policy of the
+// prerequisite must be auto.
+func (s *Server) inheritBranch(ctx context.Context, t *task.Task, dep *task.Task) error {
+	p := dep.Primary()
+	runner := s.runners[p.Name]
+	if !s.pushPolicy(dep).Automatic() {
+		return fmt.Errorf("dependency %s: its push policy forbids pushing branch %s automatically", dep.ID, p.Branch)
+	}
+	if dep.Container != "" && dep.GetState() == task.StateWaiting {
+		if _, issues := s.prePushChecks(ctx, dep); len(issues) != 0 {
+			return fmt.Errorf("push dependency branch %s: %s", p.Branch, issues[0].Detail)
+		}
+		_, issues, err := runner.SyncToOrigin(ctx, p.Branch, p.PushRemote, dep.Container, false, dep.ExtraMDRepos())
+		if err != nil {
+			return fmt.Errorf("push dependency branch %s: %w", p.Branch, err)
+		}
+		if len(issues) > 0 {
+			return fmt.Errorf("push dependency branch %s: blocked by %d safety issue(s)", p.Branch, len(issues))
+		}
+		if p.PushRemote != "" && p.PushRemote != "origin" {
+			if err := runner.TrackPushedBranch(ctx, p.Branch, dep.Container); err != nil {
+				return fmt.Errorf("track dependency branch %s: %w", p.Branch, err)
+			}
+		}
+	}
+	t.Repos[0].BaseBranch = p.Branch
+	return nil
+}
@@ -0,0 +1,41 @@
+// Tests for task dependencies.
+package server
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func TestInheritBranch(t *testing.T) {
+	newTasks := func(push task.PushPolicy) (*task.Task, *task.Task) {
+		dep := &task.Task{Repos: []task.RepoMount{{Name: "org/repo", Branch: "caic-3"}}, Push: push}
+		tk := &task.Task{Repos: []task.RepoMount{{Name: "org/repo"}}}
+		return tk, dep
+	}
+	t.Run("Auto", func(t *testing.T) {
+		s := newTestServer(t)
+		s.runners["org/repo"] = &task.Runner{Dir: "/src/repo", BaseBranch: "main"}
+		tk, dep := newTasks(task.PushAuto)
+		if err := s.inheritBranch(t.Context(), tk, dep); err != nil {
+			t.Fatal(err)
+		}
+		if got := tk.Repos[0].BaseBranch; got != "caic-3" {
+			t.Errorf("BaseBranch = %q, want caic-3", got)
+		}
+	})
+	for _, push := range []task.PushPolicy{task.PushAsk, task.PushNever} {
+		t.Run(string(push), func(t *testing.T) {
+			s := newTestServer(t)
+			s.runners["org/repo"] = &task.Runner{Dir: "/src/repo", BaseBranch: "main"}
+			tk, dep := newTasks(push)
+			if err := s.inheritBranch(t.Context(), tk, dep); err == nil || !strings.Contains(err.Error(), "push policy") {
+				t.Errorf("err = %v, want a push policy error", err)
+			}
+			if tk.Repos[0].BaseBranch != "" {
+				t.Errorf("BaseBranch = %q, want unchanged", tk.Repos[0].BaseBranch)
+			}
+		})
+	}
+}
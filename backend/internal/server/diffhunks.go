@@ -0,0 +1,281 @@
+// Server-side parsing of task diffs into files and hunks, so clients don't
+// have to process large changesets themselves.
+package server
+
+import (
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+const (
+	// intralineMaxLen skips word diffs of long lines, typically minified or
+	// generated content where they are both slow and useless.
+	intralineMaxLen = 500
+	// intralineMaxPairs caps the line pairs word-diffed per request.
+	intralineMaxPairs = 5000
+)
+
+// hunkHeaderRe matches "@@ -<start>[,<lines>] +<start>[,<lines>] @@[ section]".
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@ ?(.*)$`)
+
+// parseDiff splits a unified git diff into files and hunks. Line numbers are
+// tracked from the hunk headers and the hunk lengths decide where a hunk
+// ends, so deleted lines starting with "--" are not mistaken for headers.
+func parseDiff(raw string) []v1.DiffFile {
+	var files []v1.DiffFile
+	var f *v1.DiffFile
+	var h *v1.DiffHunk
+	prefixed := false
+	oldLeft, newLeft, oldLine, newLine := 0, 0, 0, 0
+	for line := range strings.SplitSeq(strings.TrimSuffix(raw, "\n"), "\n") {
+		if h != nil && strings.HasPrefix(line, `\`) {
+			// "\ No newline at end of file" applies to the preceding line.
+			if len(h.Lines) != 0 {
+				h.Lines[len(h.Lines)-1].NoEOL = true
+			}
+			continue
+		}
+		if h != nil && (oldLeft > 0 || newLeft > 0) {
+			l := v1.DiffLine{Kind: v1.DiffLineContext}
+			if line != "" {
+				switch line[0] {
+				case '+':
+					l.Kind = v1.DiffLineAdded
+				case '-':
+					l.Kind = v1.DiffLineDeleted
+				}
+				l.Text = line[1:]
+			}
+			if l.Kind != v1.DiffLineAdded {
+				l.OldLine = oldLine
+				oldLine++
+				oldLeft--
+			}
+			if l.Kind != v1.DiffLineDeleted {
+				l.NewLine = newLine
+				newLine++
+				newLeft--
+			}
+			h.Lines = append(h.Lines, l)
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			a, b := splitGitHeader(line[len("diff --git "):])
+			// Prefixes are "a/" and "b/" by default, or "i/", "w/", "c/" with
+			// diff.mnemonicPrefix; they are absent with diff.noprefix.
+			prefixed = hasDiffPrefix(a) && hasDiffPrefix(b) && a[0] != b[0]
+			files = append(files, v1.DiffFile{Path: stripDiffPrefix(b, prefixed), Status: v1.FileModified})
+			f, h = &files[len(files)-1], nil
+		case f == nil:
+		case strings.HasPrefix(line, "new file mode"):
+			f.Status = v1.FileAdded
+		case strings.HasPrefix(line, "deleted file mode"):
+			f.Status = v1.FileDeleted
+		case strings.HasPrefix(line, "rename from "):
+			f.Status, f.OldPath = v1.FileRenamed, line[len("rename from "):]
+		case strings.HasPrefix(line, "rename to "):
+			f.Path = line[len("rename to "):]
+		case strings.HasPrefix(line, "copy to "):
+			f.Status, f.Path = v1.FileAdded, line[len("copy to "):]
+		case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+			f.Binary = true
+		case strings.HasPrefix(line, "+++ "):
+			if p := line[len("+++ "):]; p != "/dev/null" {
+				f.Path = stripDiffPrefix(p, prefixed)
+			}
+		case strings.HasPrefix(line, "@@ "):
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			hunk := v1.DiffHunk{OldStart: atoiOr(m[1], 0), OldLines: atoiOr(m[2], 1), NewStart: atoiOr(m[3], 0), NewLines: atoiOr(m[4], 1), Section: m[5]}
+			f.Hunks = append(f.Hunks, hunk)
+			h = &f.Hunks[len(f.Hunks)-1]
+			oldLeft, newLeft, oldLine, newLine = hunk.OldLines, hunk.NewLines, hunk.OldStart, hunk.NewStart
+		}
+	}
+	for i := range files {
+		files[i].Language = diffLanguage(files[i].Path)
+	}
+	return files
+}
+
+// splitGitHeader splits the two paths of a "diff --git" header. Both have the
+// same length unless the file was renamed, in which case the rename lines
+// provide the paths anyway.
+func splitGitHeader(s string) (string, string) {
+	if n := len(s) / 2; len(s)%2 == 1 && s[n] == ' ' && s[2:n] == s[n+3:] {
+		return s[:n], s[n+1:]
+	}
+	a, b, _ := strings.Cut(s, " ")
+	return a, b
+}
+
+func hasDiffPrefix(p string) bool {
+	return len(p) > 2 && p[1] == '/' && p[0] >= 'a' && p[0] <= 'z'
+}
+
+func stripDiffPrefix(p string, prefixed bool) string {
+	if prefixed && hasDiffPrefix(p) {
+		return p[2:]
+	}
+	return p
+}
+
+func atoiOr(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// langByExt maps file extensions to the language names used by common
+// syntax highlighters.
+var langByExt = map[string]string{
+	".bash": "bash", ".c": "c", ".cc": "cpp", ".cpp": "cpp", ".cs": "csharp", ".css": "css",
+	".dart": "dart", ".ex": "elixir", ".exs": "elixir", ".go": "go", ".h": "c", ".hpp": "cpp",
+	".hs": "haskell", ".html": "html", ".java": "java", ".js": "javascript", ".json": "json",
+	".jsx": "jsx", ".kt": "kotlin", ".kts": "kotlin", ".lua": "lua", ".md": "markdown",
+	".mjs": "javascript", ".php": "php", ".proto": "protobuf", ".py": "python", ".rb": "ruby",
+	".rs": "rust", ".scala": "scala", ".scss": "scss", ".sh": "bash", ".sql": "sql",
+	".svelte": "svelte", ".swift": "swift", ".tf": "hcl", ".toml": "toml", ".ts": "typescript",
+	".tsx": "tsx", ".vue": "vue", ".xml": "xml", ".yaml": "yaml", ".yml": "yaml", ".zig": "zig",
+}
+
+// diffLanguage guesses the language of a file from its name.
+func diffLanguage(p string) string {
+	base := path.Base(p)
+	switch {
+	case base == "Makefile" || base == "GNUmakefile":
+		return "makefile"
+	case base == "Dockerfile" || strings.HasPrefix(base, "Dockerfile."):
+		return "dockerfile"
+	}
+	return langByExt[strings.ToLower(path.Ext(base))]
+}
+
+// addIntraline sets the word segments of the lines that replace each other:
+// within each run of deleted lines followed by added lines, the n-th deleted
+// line is paired with the n-th added line. budget is decremented per pair.
+func addIntraline(h *v1.DiffHunk, budget *int) {
+	lines := h.Lines
+	for i := 0; i < len(lines); {
+		if lines[i].Kind != v1.DiffLineDeleted {
+			i++
+			continue
+		}
+		del := i
+		for i < len(lines) && lines[i].Kind == v1.DiffLineDeleted {
+			i++
+		}
+		add := i
+		for i < len(lines) && lines[i].Kind == v1.DiffLineAdded {
+			i++
+		}
+		for j := 0; j < min(add-del, i-add) && *budget > 0; j++ {
+			*budget--
+			lines[del+j].Segments, lines[add+j].Segments = wordDiff(lines[del+j].Text, lines[add+j].Text)
+		}
+	}
+}
+
+// wordDiff splits two lines into changed and unchanged segments using the
+// longest common subsequence of their words. Returns nil when the lines are
+// too long or share nothing but whitespace, since highlighting every word
+// is no better than the line highlight.
+func wordDiff(a, b string) ([]v1.DiffSegment, []v1.DiffSegment) {
+	if len(a) > intralineMaxLen || len(b) > intralineMaxLen {
+		return nil, nil
+	}
+	ta, tb := diffTokens(a), diffTokens(b)
+	// lcs[i][j] is the length of the LCS of ta[i:] and tb[j:].
+	lcs := make([][]int, len(ta)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(tb)+1)
+	}
+	for i := len(ta) - 1; i >= 0; i-- {
+		for j := len(tb) - 1; j >= 0; j-- {
+			if ta[i] == tb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var sa, sb []v1.DiffSegment
+	common := false
+	i, j := 0, 0
+	for i < len(ta) || j < len(tb) {
+		switch {
+		case i < len(ta) && j < len(tb) && ta[i] == tb[j]:
+			common = common || strings.TrimSpace(ta[i]) != ""
+			sa = appendSegment(sa, ta[i], false)
+			sb = appendSegment(sb, tb[j], false)
+			i++
+			j++
+		case j == len(tb) || (i < len(ta) && lcs[i+1][j] >= lcs[i][j+1]):
+			sa = appendSegment(sa, ta[i], true)
+			i++
+		default:
+			sb = appendSegment(sb, tb[j], true)
+			j++
+		}
+	}
+	if !common {
+		return nil, nil
+	}
+	return sa, sb
+}
+
+// appendSegment appends text to segs, merging it with the last segment when
+// both have the same state.
+func appendSegment(segs []v1.DiffSegment, text string, changed bool) []v1.DiffSegment {
+	if n := len(segs); n != 0 && segs[n-1].Changed == changed {
+		segs[n-1].Text += text
+		return segs
+	}
+	return append(segs, v1.DiffSegment{Text: text, Changed: changed})
+}
+
+// diffTokens splits s into words, runs of whitespace and single punctuation
+// characters.
+func diffTokens(s string) []string {
+	var out []string
+	for s != "" {
+		r, n := utf8.DecodeRuneInString(s)
+		class := tokenClass(r)
+		if class != 0 {
+			for n < len(s) {
+				r2, m := utf8.DecodeRuneInString(s[n:])
+				if tokenClass(r2) != class {
+					break
+				}
+				n += m
+			}
+		}
+		out = append(out, s[:n])
+		s = s[n:]
+	}
+	return out
+}
+
+// tokenClass returns 1 for word characters, 2 for whitespace and 0 for
+// anything else, which stands alone.
+func tokenClass(r rune) int {
+	switch {
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 1
+	case unicode.IsSpace(r):
+		return 2
+	default:
+		return 0
+	}
+}
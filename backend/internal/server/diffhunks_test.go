@@ -0,0 +1,90 @@
+// Tests for server-side diff parsing.
+package server
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+func TestParseDiff(t *testing.T) {
+	raw := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,3 @@ package main
+ func main() {
+--- old comment
++	println("hi")
+ }
+\ No newline at end of file
+diff --git a/old.txt b/docs/new.txt
+similarity index 90%
+rename from old.txt
+rename to docs/new.txt
+diff --git a/img.png b/img.png
+new file mode 100644
+index 0000000..3333333
+Binary files /dev/null and b/img.png differ
+diff --git a/gone.py b/gone.py
+deleted file mode 100644
+--- a/gone.py
++++ /dev/null
+@@ -1 +0,0 @@
+-print(1)
+`
+	want := []v1.DiffFile{
+		{Path: "main.go", Status: v1.FileModified, Language: "go", Hunks: []v1.DiffHunk{{
+			OldStart: 1, OldLines: 3, NewStart: 1, NewLines: 3, Section: "package main",
+			Lines: []v1.DiffLine{
+				{Kind: v1.DiffLineContext, Text: "func main() {", OldLine: 1, NewLine: 1},
+				{Kind: v1.DiffLineDeleted, Text: "-- old comment", OldLine: 2},
+				{Kind: v1.DiffLineAdded, Text: "\tprintln(\"hi\")", NewLine: 2},
+				{Kind: v1.DiffLineContext, Text: "}", OldLine: 3, NewLine: 3, NoEOL: true},
+			},
+		}}},
+		{Path: "docs/new.txt", OldPath: "old.txt", Status: v1.FileRenamed},
+		{Path: "img.png", Status: v1.FileAdded, Binary: true},
+		{Path: "gone.py", Status: v1.FileDeleted, Language: "python", Hunks: []v1.DiffHunk{{
+			OldStart: 1, OldLines: 1, NewStart: 0, NewLines: 0,
+			Lines: []v1.DiffLine{{Kind: v1.DiffLineDeleted, Text: "print(1)", OldLine: 1}},
+		}}},
+	}
+	got := parseDiff(raw)
+	if len(got) != len(want) {
+		t.Fatalf("got %d files, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("file %d:\ngot  %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+	t.Run("NoPrefix", func(t *testing.T) {
+		got := parseDiff("diff --git a/x.ts a/x.ts\n--- a/x.ts\n+++ a/x.ts\n@@ -1 +1 @@\n-a\n+b\n")
+		if len(got) != 1 || got[0].Path != "a/x.ts" || got[0].Language != "typescript" {
+			t.Errorf("got %+v", got)
+		}
+	})
+	t.Run("Intraline", func(t *testing.T) {
+		h := &v1.DiffHunk{Lines: []v1.DiffLine{
+			{Kind: v1.DiffLineDeleted, Text: "x := foo(a, b)"},
+			{Kind: v1.DiffLineDeleted, Text: "return"},
+			{Kind: v1.DiffLineAdded, Text: "x := bar(a, b)"},
+			{Kind: v1.DiffLineAdded, Text: "}"},
+		}}
+		budget := 10
+		addIntraline(h, &budget)
+		wantDel := []v1.DiffSegment{{Text: "x := "}, {Text: "foo", Changed: true}, {Text: "(a, b)"}}
+		wantAdd := []v1.DiffSegment{{Text: "x := "}, {Text: "bar", Changed: true}, {Text: "(a, b)"}}
+		if !reflect.DeepEqual(h.Lines[0].Segments, wantDel) || !reflect.DeepEqual(h.Lines[2].Segments, wantAdd) {
+			t.Errorf("got %+v / %+v", h.Lines[0].Segments, h.Lines[2].Segments)
+		}
+		if h.Lines[1].Segments != nil || h.Lines[3].Segments != nil {
+			t.Errorf("unrelated lines got segments: %+v / %+v", h.Lines[1].Segments, h.Lines[3].Segments)
+		}
+		if budget != 8 {
+			t.Errorf("budget = %d, want 8", budget)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+// Disk usage tracking of task containers and logs, and the free space quota.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/container"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// diskPollInterval is how often pollDisk measures disk usage. Sizing
+// container layers walks their files, so it runs less often than pollStats.
+const diskPollInterval = time.Minute
+
+// pollDisk measures disk usage every diskPollInterval until ctx is done.
+func (s *Server) pollDisk(ctx context.Context) {
+	s.measureDisk(ctx)
+	ticker := time.NewTicker(diskPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.measureDisk(ctx)
+		}
+	}
+}
+
+// measureDisk sizes the containers and logs of every task and the free space
+// left, then warns when free space crosses the quota.
+func (s *Server) measureDisk(ctx context.Context) {
+	s.mu.Lock()
+	var names []string
+	for _, e := range s.tasks {
+		if e.task.Container != "" && e.task.GetState() != task.StatePurged {
+			names = append(names, e.task.Container)
+		}
+	}
+	s.mu.Unlock()
+
+	var ctrSizes map[string]int64
+	if s.mdClient != nil {
+		var err error
+		if ctrSizes, err = container.DiskUsage(ctx, s.mdClient.Runtime, names); err != nil {
+			slog.Warn("disk usage", "err", err)
+		}
+	}
+	logSizes, err := logSizes(s.logDir)
+	if err != nil {
+		slog.Warn("disk usage", "err", err)
+	}
+	free, total, err := diskSpace(s.logDir)
+	if err != nil {
+		slog.Warn("disk usage", "path", s.logDir, "err", err)
+	}
+
+	s.mu.Lock()
+	wasLow := s.disk.Low
+	s.setDiskUsageLocked(ctrSizes, logSizes, free, total)
+	d := s.disk
+	s.mu.Unlock()
+
+	switch {
+	case d.Low && !wasLow:
+		slog.Warn("disk space low", "free", d.FreeBytes, "min", d.MinFreeBytes)
+		s.emitWarning(fmt.Sprintf("Low disk space: %s free, below the %s quota; task creation is paused", formatBytes(d.FreeBytes), formatBytes(d.MinFreeBytes)))
+	case !d.Low && wasLow:
+		slog.Info("disk space recovered", "free", d.FreeBytes, "min", d.MinFreeBytes)
+		s.emitWarning(fmt.Sprintf("Disk space recovered: %s free; task creation resumed", formatBytes(d.FreeBytes)))
+	}
+}
+
+// setDiskUsageLocked stores a measurement in the task entries and s.disk and
+// notifies watchers when it changed. A nil map or a zero total means that
+// part could not be measured and the previous values are kept. The caller
+// must hold s.mu.
+func (s *Server) setDiskUsageLocked(ctrSizes, logSizes map[string]int64, free, total int64) {
+	d := v1.DiskUsage{MinFreeBytes: s.minFreeDisk, FreeBytes: s.disk.FreeBytes, TotalBytes: s.disk.TotalBytes}
+	if total > 0 {
+		d.FreeBytes, d.TotalBytes = free, total
+	}
+	d.Low = d.MinFreeBytes > 0 && d.TotalBytes > 0 && d.FreeBytes < d.MinFreeBytes
+	if logSizes == nil {
+		d.LogBytes = s.disk.LogBytes
+	}
+	for _, sz := range logSizes {
+		d.LogBytes += sz
+	}
+	changed := false
+	for id, e := range s.tasks {
+		ctr := e.diskBytes
+		if sz, ok := ctrSizes[e.task.Container]; ok {
+			ctr = sz
+		} else if ctrSizes != nil || e.task.GetState() == task.StatePurged {
+			// The container is gone.
+			ctr = 0
+		}
+		logs := e.logBytes
+		if logSizes != nil {
+			logs = logSizes[id]
+		}
+		d.ContainerBytes += ctr
+		if ctr != e.diskBytes || logs != e.logBytes {
+			e.diskBytes, e.logBytes = ctr, logs
+			changed = true
+		}
+	}
+	if changed || d != s.disk {
+		s.disk = d
+		s.taskChanged()
+	}
+}
+
+// logSizes returns the total size of the log files of each task ID found in
+// dir. Log file names start with the task ID followed by a dash.
+func logSizes(dir string) (map[string]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]int64{}, nil
+		}
+		return nil, fmt.Errorf("read log dir: %w", err)
+	}
+	sizes := make(map[string]int64, len(entries))
+	for _, de := range entries {
+		id, _, ok := strings.Cut(de.Name(), "-")
+		if !ok || de.IsDir() || !strings.HasSuffix(de.Name(), ".jsonl") {
+			continue
+		}
+		if fi, err := de.Info(); err == nil {
+			sizes[id] += fi.Size()
+		}
+	}
+	return sizes, nil
+}
+
+// diskUsage returns the latest disk usage measurement, or nil before the
+// first one.
+func (s *Server) diskUsage() *v1.DiskUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disk.TotalBytes == 0 {
+		return nil
+	}
+	d := s.disk
+	return &d
+}
+
+// checkDiskQuota returns an error when free disk space is below the quota,
+// in which case no new task may start.
+func (s *Server) checkDiskQuota() error {
+	s.mu.Lock()
+	d := s.disk
+	s.mu.Unlock()
+	if d.Low {
+		return dto.InsufficientStorage(fmt.Sprintf("disk space low: %s free, below the %s quota", formatBytes(d.FreeBytes), formatBytes(d.MinFreeBytes)))
+	}
+	return nil
+}
+
+// formatBytes formats n with a binary unit, e.g. "1.5 GiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,18 @@
+// Free disk space on Unix.
+
+//go:build !windows
+
+package server
+
+import "syscall"
+
+// diskSpace returns the free (for unprivileged users) and total bytes of the
+// filesystem holding path.
+func diskSpace(path string) (free, total int64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	bsize := int64(st.Bsize)                                       //nolint:unconvert // int64 on Linux, uint32 on macOS.
+	return int64(st.Bavail) * bsize, int64(st.Blocks) * bsize, nil //nolint:gosec // G115: block counts fit in int64.
+}
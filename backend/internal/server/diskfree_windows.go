@@ -0,0 +1,21 @@
+// Free disk space on Windows.
+
+//go:build windows
+
+package server
+
+import "golang.org/x/sys/windows"
+
+// diskSpace returns the free (for the current user) and total bytes of the
+// volume holding path.
+func diskSpace(path string) (free, total int64, err error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	var avail, tot uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &avail, &tot, nil); err != nil {
+		return 0, 0, err
+	}
+	return int64(avail), int64(tot), nil //nolint:gosec // G115: volume sizes fit in int64.
+}
@@ -0,0 +1,158 @@
+// Environment self-test behind "caic doctor": validates everything a task needs before serving.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/container"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/caic/backend/internal/usage"
+	"github.com/caic-xyz/md"
+	"github.com/caic-xyz/md/gitutil"
+)
+
+// doctorTimeout bounds each individual doctor probe except harness checks.
+const doctorTimeout = 10 * time.Second
+
+// Finding is the outcome of one doctor check.
+type Finding struct {
+	Check  string // Short name, e.g. "git".
+	OK     bool
+	Detail string // What was found, or the error.
+	Fix    string // Actionable suggestion; set when !OK.
+}
+
+// Doctor validates the environment caic needs to run tasks: git, the
+// container runtime, the source root, the log directory, preferences and each
+// harness in the base image. It never starts the server and returns one
+// finding per check so the caller can print them all.
+func Doctor(ctx context.Context, rootDir string, cfg *Config) []Finding {
+	var out []Finding
+	add := func(check string, err error, detail, fix string) {
+		f := Finding{Check: check, OK: err == nil, Detail: detail}
+		if err != nil {
+			f.Detail = err.Error()
+			f.Fix = fix
+		}
+		out = append(out, f)
+	}
+
+	add("config", cfg.Validate(), "valid", "fix the environment variables listed by caic -help")
+
+	gitVersion, err := doctorGit(ctx)
+	add("git", err, gitVersion, "install git and make sure it is in PATH")
+
+	repos, err := doctorRoot(rootDir)
+	add("source root", err, fmt.Sprintf("%s: %d git repositories", rootDir, repos), "set -root or CAIC_ROOT to a readable directory containing git repositories")
+
+	logDir := filepath.Join(cfg.CacheDir, "tasks")
+	add("log dir", doctorWritable(logDir), logDir, "make "+logDir+" writable by this user or set XDG_CACHE_HOME")
+
+	prefsPath := filepath.Join(cfg.ConfigDir, "preferences.json")
+	_, err = preferences.Open(prefsPath)
+	add("preferences", err, prefsPath, "fix or delete "+prefsPath)
+
+	runtime := ""
+	mdClient, err := container.New(cfg.TailscaleAPIKey, cfg.GitHubToken)
+	if err == nil {
+		rctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+		var v string
+		v, err = container.RuntimeVersion(rctx, mdClient.Runtime)
+		cancel()
+		if err == nil {
+			runtime = mdClient.Runtime
+			add("container runtime", nil, mdClient.Runtime+" "+v, "")
+		}
+	}
+	if runtime == "" {
+		add("container runtime", err, "", "install docker or podman and start its daemon; the current user must be able to run containers")
+	}
+
+	image := md.DefaultBaseImage + ":latest"
+	if runtime != "" {
+		rctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+		id, err := container.ImageID(rctx, runtime, image)
+		cancel()
+		add("base image", err, image+" "+id, "run: "+runtime+" pull "+image)
+		if err != nil {
+			// Harness probes would all fail the same way.
+			runtime = ""
+		}
+	}
+
+	r := &task.Runner{LogDir: logDir}
+	_ = r.Init(ctx) // Populates the default Backends; no-op without a repository.
+	harnesses := make([]agent.Harness, 0, len(r.Backends))
+	for h := range r.Backends {
+		harnesses = append(harnesses, h)
+	}
+	slices.Sort(harnesses)
+	uctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	claude, codex := usage.NewClaudeFetcher(uctx), usage.NewCodexFetcher(uctx)
+	for _, h := range harnesses {
+		hh := checkHarness(ctx, runtime, h, r.Backends[h], image, claude, codex)
+		if runtime != "" {
+			add("harness "+hh.Name, errorOrNil(hh.InstallError), hh.Binary+" "+hh.Version, "use a base image that ships "+hh.Binary)
+		}
+		add("harness "+hh.Name+" auth", errorOrNil(hh.CredentialsError), "credentials valid", "log in with "+hh.Binary+" on this host; its credentials are mounted into containers")
+	}
+	return out
+}
+
+// doctorGit returns the git version.
+func doctorGit(ctx context.Context) (string, error) {
+	p, err := exec.LookPath("git")
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+	v, err := exec.CommandContext(ctx, p, "--version").Output() //nolint:gosec // p resolved via LookPath.
+	if err != nil {
+		return "", fmt.Errorf("git --version: %w", err)
+	}
+	return strings.TrimSpace(string(v)), nil
+}
+
+// doctorRoot verifies rootDir is readable and returns the number of
+// repositories caic would serve.
+func doctorRoot(rootDir string) (int, error) {
+	if _, err := os.ReadDir(rootDir); err != nil {
+		return 0, err
+	}
+	repos, err := gitutil.DiscoverRepos(rootDir, 3)
+	return len(repos), err
+}
+
+// doctorWritable creates dir if needed and verifies a file can be written in it.
+func doctorWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	err = f.Close()
+	return errors.Join(err, os.Remove(name))
+}
+
+// errorOrNil converts a non-empty error string back to an error.
+func errorOrNil(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,48 @@
+// Tests for the environment self-test.
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDoctor(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "repo", ".git"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{CacheDir: filepath.Join(home, "cache"), ConfigDir: filepath.Join(home, "config")}
+	got := map[string]Finding{}
+	for _, f := range Doctor(t.Context(), root, cfg) {
+		got[f.Check] = f
+	}
+	for _, check := range []string{"config", "git", "source root", "log dir", "preferences"} {
+		f, ok := got[check]
+		if !ok {
+			t.Errorf("missing %q finding", check)
+			continue
+		}
+		if !f.OK {
+			t.Errorf("%s: %+v, want ok", check, f)
+		}
+	}
+	if f := got["source root"]; f.Detail != root+": 1 git repositories" {
+		t.Errorf("source root detail = %q", f.Detail)
+	}
+	if f := got["harness claude auth"]; f.OK || f.Fix == "" {
+		t.Errorf("claude auth = %+v, want a failure with a fix", f)
+	}
+
+	t.Run("UnwritableLogDir", func(t *testing.T) {
+		cache := filepath.Join(t.TempDir(), "file")
+		if err := os.WriteFile(cache, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := doctorWritable(filepath.Join(cache, "tasks")); err == nil {
+			t.Error("expected an error when the cache dir is a file")
+		}
+	})
+}
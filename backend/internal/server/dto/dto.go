@@ -13,3 +13,10 @@ type EmptyReq struct{}
 
 // Validate is a no-op for empty requests.
 func (EmptyReq) Validate() error { return nil }
+
+// Version names a major version of the HTTP API. Its request and response
+// types live in the sub-package of the same name.
+type Version string
+
+// V1 is the API version whose types are in dto/v1.
+const V1 Version = "v1"
@@ -11,29 +11,35 @@ type ErrorCode string
 
 // Standard error codes.
 const (
-	CodeBadRequest    ErrorCode = "BAD_REQUEST"
-	CodeUnauthorized  ErrorCode = "UNAUTHORIZED"
-	CodeForbidden     ErrorCode = "FORBIDDEN"
-	CodeNotFound      ErrorCode = "NOT_FOUND"
-	CodeConflict      ErrorCode = "CONFLICT"
-	CodeInternalError ErrorCode = "INTERNAL_ERROR"
+	CodeBadRequest          ErrorCode = "BAD_REQUEST"
+	CodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	CodeForbidden           ErrorCode = "FORBIDDEN"
+	CodeNotFound            ErrorCode = "NOT_FOUND"
+	CodeConflict            ErrorCode = "CONFLICT"
+	CodeInternalError       ErrorCode = "INTERNAL_ERROR"
+	CodeInsufficientStorage ErrorCode = "INSUFFICIENT_STORAGE"
 )
 
 // ErrorWithStatus is an error that carries an HTTP status code, error code,
-// and optional details map.
+// optional message parameters, optional field errors and optional details map.
 type ErrorWithStatus interface {
 	error
 	StatusCode() int
 	Code() ErrorCode
+	Params() map[string]string
+	Fields() []FieldError
 	Details() map[string]any
 }
 
 // APIError is a concrete error type with status code, error code, optional
-// details, and optional wrapped error.
+// message parameters, optional field errors, optional details, and optional
+// wrapped error.
 type APIError struct {
 	statusCode int
 	code       ErrorCode
 	message    string
+	params     map[string]string
+	fields     []FieldError
 	details    map[string]any
 	wrappedErr error
 }
@@ -55,6 +61,17 @@ func (e *APIError) Code() ErrorCode {
 	return e.code
 }
 
+// Params returns the values interpolated in the message, keyed by name, so
+// clients can render a translated message from the code and the params.
+func (e *APIError) Params() map[string]string {
+	return e.params
+}
+
+// Fields returns the invalid fields of a validation error.
+func (e *APIError) Fields() []FieldError {
+	return e.fields
+}
+
 // Details returns the optional details map.
 func (e *APIError) Details() map[string]any {
 	return e.details
@@ -74,6 +91,15 @@ func (e *APIError) WithDetail(key string, value any) *APIError {
 	return e
 }
 
+// WithParam adds a single value interpolated in the message.
+func (e *APIError) WithParam(key, value string) *APIError {
+	if e.params == nil {
+		e.params = make(map[string]string)
+	}
+	e.params[key] = value
+	return e
+}
+
 // Wrap wraps an underlying error.
 func (e *APIError) Wrap(err error) *APIError {
 	e.wrappedErr = err
@@ -87,14 +113,28 @@ func BadRequest(msg string) *APIError {
 	return &APIError{statusCode: http.StatusBadRequest, code: CodeBadRequest, message: msg}
 }
 
+// Required creates a 400 error for a missing field.
+func Required(field string) *APIError {
+	var v Validator
+	v.Required(field)
+	return v.apiError()
+}
+
+// Invalid creates a 400 error for a field holding an invalid value.
+func Invalid(field, value string) *APIError {
+	var v Validator
+	v.Invalid(field, value)
+	return v.apiError()
+}
+
 // NotFound creates a 404 error.
 func NotFound(resource string) *APIError {
-	return &APIError{statusCode: http.StatusNotFound, code: CodeNotFound, message: resource + " not found"}
+	return (&APIError{statusCode: http.StatusNotFound, code: CodeNotFound, message: resource + " not found"}).WithParam("resource", resource)
 }
 
 // Forbidden creates a 403 error.
 func Forbidden(resource string) *APIError {
-	return &APIError{statusCode: http.StatusForbidden, code: CodeForbidden, message: resource + " access denied"}
+	return (&APIError{statusCode: http.StatusForbidden, code: CodeForbidden, message: resource + " access denied"}).WithParam("resource", resource)
 }
 
 // Conflict creates a 409 error.
@@ -107,6 +147,11 @@ func InternalError(msg string) *APIError {
 	return &APIError{statusCode: http.StatusInternalServerError, code: CodeInternalError, message: msg}
 }
 
+// InsufficientStorage creates a 507 error.
+func InsufficientStorage(msg string) *APIError {
+	return &APIError{statusCode: http.StatusInsufficientStorage, code: CodeInsufficientStorage, message: msg}
+}
+
 // ErrorResponse is the JSON envelope for error responses.
 type ErrorResponse struct {
 	Error   ErrorDetails   `json:"error"`
@@ -115,6 +160,8 @@ type ErrorResponse struct {
 
 // ErrorDetails holds the code and message within an error response.
 type ErrorDetails struct {
-	Code    ErrorCode `json:"code"`
-	Message string    `json:"message"`
+	Code    ErrorCode         `json:"code"`
+	Message string            `json:"message"`          // English message, for clients that don't localize.
+	Params  map[string]string `json:"params,omitempty"` // Values interpolated in Message, e.g. {"field": "repo"}.
+	Fields  []FieldError      `json:"fields,omitempty"` // Every invalid field of a rejected request.
 }
@@ -8,7 +8,10 @@
 // backend produced the stream.
 package v1
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 // EventKind identifies the type of SSE event.
 type EventKind string
@@ -38,13 +41,18 @@ const (
 	EventKindWidgetDelta     EventKind = "widgetDelta"
 	EventKindRateLimit       EventKind = "rateLimit"
 	EventKindStats           EventKind = "stats"
+	EventKindOverflow        EventKind = "overflow"
 )
 
 // EventMessage is a single SSE event in the backend-neutral stream
 // (/api/v1/tasks/{id}/events). All backends produce these events.
 type EventMessage struct {
-	Kind            EventKind             `json:"kind"`
-	Ts              int64                 `json:"ts"`
+	Kind EventKind `json:"kind"`
+	Ts   time.Time `json:"ts"`
+	// ParentToolUseID is set on the text, thinking, toolUse and toolResult
+	// events of a sub-agent to the toolUseID of the tool call that spawned
+	// it, so clients can nest the sub-agent's activity under that call.
+	ParentToolUseID string                `json:"parentToolUseID,omitempty"`
 	Init            *EventInit            `json:"init,omitempty"`
 	Text            *EventText            `json:"text,omitempty"`
 	TextDelta       *EventTextDelta       `json:"textDelta,omitempty"`
@@ -68,6 +76,11 @@ type EventMessage struct {
 	WidgetDelta     *EventWidgetDelta     `json:"widgetDelta,omitempty"`
 	RateLimit       *EventRateLimit       `json:"rateLimit,omitempty"`
 	Stats           *EventStats           `json:"stats,omitempty"`
+	Overflow        *EventOverflow        `json:"overflow,omitempty"`
+	// ContentRef is set when the event's text, thinking or tool output was
+	// truncated to a preview; fetch the full content with
+	// GET /api/v1/tasks/{id}/messages/{index}/content.
+	ContentRef *ContentRef `json:"contentRef,omitempty"`
 }
 
 // EventInit is emitted once at the start of a session. It includes a Harness
@@ -91,16 +104,47 @@ type EventTextDelta struct {
 	Text string `json:"text"`
 }
 
-// EventToolUse is emitted when the assistant invokes a tool.
+// EventToolUse is emitted when the assistant invokes a tool. Tool names are
+// normalized to Claude Code's where the harness has an equivalent, but Input
+// keeps the harness's own shape for debugging; clients should use Call.
 type EventToolUse struct {
 	ToolUseID      string          `json:"toolUseID"`
 	Name           string          `json:"name"`
 	Input          json.RawMessage `json:"input"`
+	Call           *ToolCall       `json:"call,omitempty"`
 	PlanContent    string          `json:"planContent,omitempty"`    // Snapshot of plan content for ExitPlanMode events.
 	InputTruncated bool            `json:"inputTruncated,omitempty"` // True when Input was omitted due to size; fetch via GET /api/v1/tasks/{id}/tool/{toolUseID}.
 	Background     bool            `json:"background,omitempty"`     // True when the tool runs in the background (Bash/Agent run_in_background).
 }
 
+// ToolKind classifies what a tool call does.
+type ToolKind string
+
+// Tool kinds.
+const (
+	ToolKindShell     ToolKind = "shell"
+	ToolKindRead      ToolKind = "read"
+	ToolKindWrite     ToolKind = "write"
+	ToolKindEdit      ToolKind = "edit"
+	ToolKindSearch    ToolKind = "search"
+	ToolKindFetch     ToolKind = "fetch"
+	ToolKindWebSearch ToolKind = "webSearch"
+	ToolKindAgent     ToolKind = "agent"
+	ToolKindOther     ToolKind = "other"
+)
+
+// ToolCall is the harness-neutral form of a tool call's input. Only the
+// fields relevant to Kind are set.
+type ToolCall struct {
+	Kind        ToolKind `json:"kind"`
+	Command     string   `json:"command,omitempty"`     // shell.
+	Paths       []string `json:"paths,omitempty"`       // Files for read, write and edit; the searched directory for search.
+	Pattern     string   `json:"pattern,omitempty"`     // search: glob or regexp.
+	URL         string   `json:"url,omitempty"`         // fetch.
+	Query       string   `json:"query,omitempty"`       // webSearch.
+	Description string   `json:"description,omitempty"` // agent.
+}
+
 // EventToolResult is emitted when a tool call completes.
 type EventToolResult struct {
 	ToolUseID string  `json:"toolUseID"`
@@ -239,24 +283,38 @@ type EventWidgetDelta struct {
 
 // EventRateLimit is emitted when the agent's rate limit status changes.
 type EventRateLimit struct {
-	Status          string  `json:"status"`                    // "allowed", "allowed_warning", "rejected".
-	ResetsAt        float64 `json:"resetsAt"`                  // Unix epoch seconds; 0 if unknown.
-	RateLimitType   string  `json:"rateLimitType"`             // "five_hour", "seven_day", etc.
-	Utilization     float64 `json:"utilization"`               // 0.0–1.0.
-	IsUsingOverage  bool    `json:"isUsingOverage,omitempty"`  // True when extra/overage usage is active.
-	OverageResetsAt float64 `json:"overageResetsAt,omitempty"` // Unix epoch seconds; 0 if not using overage.
+	Status          string    `json:"status"`                   // "allowed", "allowed_warning", "rejected".
+	ResetsAt        time.Time `json:"resetsAt,omitzero"`        // Unset if unknown.
+	RateLimitType   string    `json:"rateLimitType"`            // "five_hour", "seven_day", etc.
+	Utilization     float64   `json:"utilization"`              // 0.0–1.0.
+	IsUsingOverage  bool      `json:"isUsingOverage,omitempty"` // True when extra/overage usage is active.
+	OverageResetsAt time.Time `json:"overageResetsAt,omitzero"` // Unset if not using overage.
+}
+
+// EventOverflow is emitted when the client fell too far behind the live
+// stream and Dropped messages were skipped. The client should refetch the
+// history, e.g. by reconnecting.
+type EventOverflow struct {
+	Dropped int `json:"dropped"`
+}
+
+// ContentRef points at the task history message holding the full content of
+// a truncated event.
+type ContentRef struct {
+	Index int `json:"index"` // Message index in the task history.
+	Size  int `json:"size"`  // Full content length in bytes.
 }
 
 // EventStats is a container resource usage snapshot emitted periodically.
 type EventStats struct {
-	Ts         int64   `json:"ts"`
-	CPUPerc    float64 `json:"cpuPerc"`
-	MemUsed    uint64  `json:"memUsed"`
-	MemLimit   uint64  `json:"memLimit"`
-	MemPerc    float64 `json:"memPerc"`
-	NetRx      uint64  `json:"netRx"`
-	NetTx      uint64  `json:"netTx"`
-	BlockRead  uint64  `json:"blockRead"`
-	BlockWrite uint64  `json:"blockWrite"`
-	DiskUsed   int64   `json:"diskUsed"`
+	Ts         time.Time `json:"ts"`
+	CPUPerc    float64   `json:"cpuPerc"`
+	MemUsed    uint64    `json:"memUsed"`
+	MemLimit   uint64    `json:"memLimit"`
+	MemPerc    float64   `json:"memPerc"`
+	NetRx      uint64    `json:"netRx"`
+	NetTx      uint64    `json:"netTx"`
+	BlockRead  uint64    `json:"blockRead"`
+	BlockWrite uint64    `json:"blockWrite"`
+	DiskUsed   int64     `json:"diskUsed"`
 }
@@ -86,6 +86,23 @@ var Routes = []Route{
 		Req:    reflect.TypeFor[UpdatePreferencesReq](),
 		Resp:   reflect.TypeFor[PreferencesResp](),
 	},
+	{
+		Name:        "listRecentPrompts",
+		Doc:         "Lists previously used prompts, most recent first. ?repo= limits to a repo; ?prefix= keeps prompts starting with it, ignoring case; ?limit= defaults to 20.",
+		Method:      "GET",
+		Path:        "/api/v1/prompts/recent",
+		Resp:        reflect.TypeFor[RecentPrompt](),
+		IsArray:     true,
+		QueryParams: []string{"repo", "prefix", "limit"},
+	},
+	{
+		Name:   "lintPrompt",
+		Doc:    "Analyzes a draft prompt before creating a task and returns suggestions to improve it.",
+		Method: "POST",
+		Path:   "/api/v1/prompts/lint",
+		Req:    reflect.TypeFor[LintPromptReq](),
+		Resp:   reflect.TypeFor[LintPromptResp](),
+	},
 	{
 		Name:    "listHarnesses",
 		Doc:     "Lists available coding agent harnesses.",
@@ -94,6 +111,82 @@ var Routes = []Route{
 		Resp:    reflect.TypeFor[HarnessInfo](),
 		IsArray: true,
 	},
+	{
+		Name:   "getHealth",
+		Doc:    "Reports server liveness and readiness; answers 503 when not ready.",
+		Method: "GET",
+		Path:   "/api/v1/health",
+		Resp:   reflect.TypeFor[HealthResp](),
+	},
+	{
+		Name:   "getRuntime",
+		Doc:    "Returns a goroutine and heap snapshot of the server; admin only.",
+		Method: "GET",
+		Path:   "/api/v1/admin/runtime",
+		Resp:   reflect.TypeFor[RuntimeResp](),
+	},
+	{
+		Name:   "getFrontendBuild",
+		Doc:    "Reports the source and hash of the served web UI build; admin only.",
+		Method: "GET",
+		Path:   "/api/v1/admin/frontend",
+		Resp:   reflect.TypeFor[FrontendBuildResp](),
+	},
+	{
+		Name:    "listHarnessHealth",
+		Doc:     "Checks each harness binary in the container image and its API credentials.",
+		Method:  "GET",
+		Path:    "/api/v1/health/harnesses",
+		Resp:    reflect.TypeFor[HarnessHealth](),
+		IsArray: true,
+	},
+	{
+		Name:   "validateBaseImage",
+		Doc:    "Smoke tests a base image in a throwaway container; a passing pending base image becomes the default.",
+		Method: "POST",
+		Path:   "/api/v1/server/image/validate",
+		Req:    reflect.TypeFor[ValidateImageReq](),
+		Resp:   reflect.TypeFor[ImageValidationResp](),
+	},
+	{
+		Name:   "listImagePins",
+		Doc:    "Lists the base images the caller pinned to a registry digest.",
+		Method: "GET",
+		Path:   "/api/v1/server/image/pins",
+		Resp:   reflect.TypeFor[ImagePinsResp](),
+	},
+	{
+		Name:   "pinImage",
+		Doc:    "Pulls a base image, the caller's by default, and pins the new tasks using it to the digest it resolves to. Pinning it again adopts the latest digest.",
+		Method: "POST",
+		Path:   "/api/v1/server/image/pins",
+		Req:    reflect.TypeFor[ImagePinReq](),
+		Resp:   reflect.TypeFor[ImagePinsResp](),
+	},
+	{
+		Name:   "unpinImage",
+		Doc:    "Removes the digest pin of a base image; new tasks use its tag again.",
+		Method: "POST",
+		Path:   "/api/v1/server/image/pins/delete",
+		Req:    reflect.TypeFor[ImagePinReq](),
+		Resp:   reflect.TypeFor[ImagePinsResp](),
+	},
+	{
+		Name:   "checkImageUpdate",
+		Doc:    "Pulls a base image, the caller's by default, and reports whether its tag now resolves to another digest than the pinned one.",
+		Method: "POST",
+		Path:   "/api/v1/server/image/check-update",
+		Req:    reflect.TypeFor[ImagePinReq](),
+		Resp:   reflect.TypeFor[ImageUpdateResp](),
+	},
+	{
+		Name:    "listPrices",
+		Doc:     "Returns the effective model price table, including preference overrides.",
+		Method:  "GET",
+		Path:    "/api/v1/server/prices",
+		Resp:    reflect.TypeFor[PriceEntry](),
+		IsArray: true,
+	},
 	{
 		Name:   "listCaches",
 		Doc:    "Lists well-known cache configurations.",
@@ -101,6 +194,21 @@ var Routes = []Route{
 		Path:   "/api/v1/server/caches",
 		Resp:   reflect.TypeFor[WellKnownCachesResp](),
 	},
+	{
+		Name:   "getLogLevel",
+		Doc:    "Returns the server log level.",
+		Method: "GET",
+		Path:   "/api/v1/server/log-level",
+		Resp:   reflect.TypeFor[LogLevelResp](),
+	},
+	{
+		Name:   "setLogLevel",
+		Doc:    "Changes the server log level until the next restart.",
+		Method: "POST",
+		Path:   "/api/v1/server/log-level",
+		Req:    reflect.TypeFor[LogLevelReq](),
+		Resp:   reflect.TypeFor[LogLevelResp](),
+	},
 	{
 		Name:    "listRepos",
 		Doc:     "Lists all discovered repositories.",
@@ -117,6 +225,46 @@ var Routes = []Route{
 		Req:    reflect.TypeFor[CloneRepoReq](),
 		Resp:   reflect.TypeFor[Repo](),
 	},
+	{
+		Name:        "listRepoRemotes",
+		Doc:         "Lists the git remotes of a repository and the one task branches are pushed to by default.",
+		Method:      "GET",
+		Path:        "/api/v1/server/repos/remotes",
+		Resp:        reflect.TypeFor[RepoRemotesResp](),
+		QueryParams: []string{"repo"},
+	},
+	{
+		Name:   "setRepoRemote",
+		Doc:    "Adds a git remote to a repository, e.g. a fork, or changes its URL, and optionally makes it the default push target of the task branches. PRs of branches pushed to a fork are opened against origin.",
+		Method: "POST",
+		Path:   "/api/v1/server/repos/remotes",
+		Req:    reflect.TypeFor[SetRepoRemoteReq](),
+		Resp:   reflect.TypeFor[RepoRemotesResp](),
+	},
+	{
+		Name:   "deleteRepoRemote",
+		Doc:    "Removes a git remote of a repository. Task branches go back to origin when it was their push target.",
+		Method: "POST",
+		Path:   "/api/v1/server/repos/remotes/delete",
+		Req:    reflect.TypeFor[DeleteRepoRemoteReq](),
+		Resp:   reflect.TypeFor[RepoRemotesResp](),
+	},
+	{
+		Name:   "createDeployKey",
+		Doc:    "Generates the SSH deploy key of a repository, or returns the existing one, and uses it for the repository's fetches and pushes, on the server and in task containers. Add the public key on the forge with write access.",
+		Method: "POST",
+		Path:   "/api/v1/server/repos/deploykey",
+		Req:    reflect.TypeFor[DeployKeyReq](),
+		Resp:   reflect.TypeFor[DeployKeyResp](),
+	},
+	{
+		Name:   "deleteDeployKey",
+		Doc:    "Deletes the deploy key of a repository, which goes back to the server's credentials.",
+		Method: "POST",
+		Path:   "/api/v1/server/repos/deploykey/delete",
+		Req:    reflect.TypeFor[DeleteDeployKeyReq](),
+		Resp:   reflect.TypeFor[StatusResp](),
+	},
 	{
 		Name:        "listRepoBranches",
 		Doc:         "Lists branches for a repository.",
@@ -125,6 +273,22 @@ var Routes = []Route{
 		Resp:        reflect.TypeFor[RepoBranchesResp](),
 		QueryParams: []string{"repo"},
 	},
+	{
+		Name:        "searchRepo",
+		Doc:         "Searches the files of a repository's base branch for a literal, case-insensitive query. Queries shorter than 3 bytes only match paths.",
+		Method:      "GET",
+		Path:        "/api/v1/server/repos/search",
+		Resp:        reflect.TypeFor[RepoSearchResp](),
+		QueryParams: []string{"repo", "q", "limit"},
+	},
+	{
+		Name:        "semanticSearchRepo",
+		Doc:         "Searches a repository's base branch for code similar in meaning to the query. Requires an embedding provider; see Config.SemanticSearch.",
+		Method:      "GET",
+		Path:        "/api/v1/server/repos/semantic-search",
+		Resp:        reflect.TypeFor[RepoSemanticSearchResp](),
+		QueryParams: []string{"repo", "q", "limit"},
+	},
 	{
 		Name:   "botFixCI",
 		Doc:    "Creates a task to fix a failing CI pipeline.",
@@ -141,14 +305,92 @@ var Routes = []Route{
 		Req:    reflect.TypeFor[BotFixPRReq](),
 		Resp:   reflect.TypeFor[StatusResp](),
 	},
+	{
+		Name:    "listEvals",
+		Doc:     "Lists the eval runs since the server started.",
+		Method:  "GET",
+		Path:    "/api/v1/evals",
+		Resp:    reflect.TypeFor[EvalReport](),
+		IsArray: true,
+	},
+	{
+		Name:   "createEval",
+		Doc:    "Runs an eval suite across harness/model targets.",
+		Method: "POST",
+		Path:   "/api/v1/evals",
+		Req:    reflect.TypeFor[CreateEvalReq](),
+		Resp:   reflect.TypeFor[EvalReport](),
+	},
+	{
+		Name:   "getEval",
+		Doc:    "Returns the report of an eval run.",
+		Method: "GET",
+		Path:   "/api/v1/evals/{id}",
+		Resp:   reflect.TypeFor[EvalReport](),
+	},
+	{
+		Name:    "listPipelines",
+		Doc:     "Lists the pipeline definitions saved as JSON or YAML files.",
+		Method:  "GET",
+		Path:    "/api/v1/pipelines",
+		Resp:    reflect.TypeFor[Pipeline](),
+		IsArray: true,
+	},
+	{
+		Name:   "listTaskViews",
+		Doc:    "Lists the user's saved task list views.",
+		Method: "GET",
+		Path:   "/api/v1/views",
+		Resp:   reflect.TypeFor[TaskViewsResp](),
+	},
+	{
+		Name:   "saveTaskView",
+		Doc:    "Saves a named task list view, replacing the view of the same name. Its query holds the filter and sort query parameters of listTasks. Making it the default unsets the previous default.",
+		Method: "POST",
+		Path:   "/api/v1/views",
+		Req:    reflect.TypeFor[TaskView](),
+		Resp:   reflect.TypeFor[TaskViewsResp](),
+	},
+	{
+		Name:   "deleteTaskView",
+		Doc:    "Deletes a saved task list view.",
+		Method: "POST",
+		Path:   "/api/v1/views/delete",
+		Req:    reflect.TypeFor[DeleteTaskViewReq](),
+		Resp:   reflect.TypeFor[TaskViewsResp](),
+	},
 	{
 		Name:    "listTasks",
-		Doc:     "Returns all tasks.",
+		Doc:     "Returns all tasks except archived ones, pinned first. ?includeArchived=true includes them; ?pinned=true keeps only pinned tasks; ?state=a,b keeps the tasks in these states; ?repo= keeps the tasks of a repo; ?project= keeps the tasks with a repo of the project in the user's settings; ?createdWithin=168h and ?stateOlderThan=24h keep the tasks created less than, or in their state for more than, a Go duration; ?sort=created|updated|cost|duration|state with ?order=asc|desc sorts them, by creation ascending by default; ?fields=a,b keeps only the listed fields; ?view=summary drops the prompt and plan.",
 		Method:  "GET",
 		Path:    "/api/v1/tasks",
 		Resp:    reflect.TypeFor[Task](),
 		IsArray: true,
 	},
+	{
+		Name:        "exportTasks",
+		Doc:         "Exports the metadata of the tasks created in [?from, ?to), dates or RFC 3339 times, for reporting. ?project= keeps the tasks of a project of the user's settings. ?format=csv returns a CSV file with the same columns instead of JSON.",
+		Method:      "GET",
+		Path:        "/api/v1/tasks/export",
+		Resp:        reflect.TypeFor[TaskExport](),
+		IsArray:     true,
+		QueryParams: []string{"format", "from", "to", "project"},
+	},
+	{
+		Name:   "getTask",
+		Doc:    "Returns a task with every field, including those dropped from the summary view.",
+		Method: "GET",
+		Path:   "/api/v1/tasks/{id}",
+		Resp:   reflect.TypeFor[Task](),
+	},
+	{
+		Name:   "updateTask",
+		Doc:    "Updates the mutable attributes of a task, e.g. archives it.",
+		Method: "PATCH",
+		Path:   "/api/v1/tasks/{id}",
+		Req:    reflect.TypeFor[UpdateTaskReq](),
+		Resp:   reflect.TypeFor[Task](),
+	},
 	{
 		Name:   "createTask",
 		Doc:    "Creates and starts a new coding agent task.",
@@ -167,7 +409,7 @@ var Routes = []Route{
 	},
 	{
 		Name:   "taskEvents",
-		Doc:    "Streams backend-neutral task events via SSE.",
+		Doc:    "Streams backend-neutral task events via SSE. ?from=N starts the history replay at message index N; a negative N replays only the last -N messages. ?batch=MS coalesces events into \"batch\" events holding JSON arrays, written at most every MS milliseconds. ?replay=realtime replays the history with its original pacing, sped up by ?speed=X (at most 100), for watching a past run. The \"ready\" event ends the replay with the index it started at, the history length and the transcript annotations.",
 		Method: "GET",
 		Path:   "/api/v1/tasks/{id}/events",
 		Resp:   reflect.TypeFor[EventMessage](),
@@ -189,6 +431,14 @@ var Routes = []Route{
 		Req:    reflect.TypeFor[RestartReq](),
 		Resp:   reflect.TypeFor[StatusResp](),
 	},
+	{
+		Name:   "rewindTask",
+		Doc:    "Replaces the last user input of a waiting task and restarts its session from the turn before.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/rewind",
+		Req:    reflect.TypeFor[RewindReq](),
+		Resp:   reflect.TypeFor[StatusResp](),
+	},
 	{
 		Name:   "clearContext",
 		Doc:    "Clears context and restarts the agent session without a prompt.",
@@ -204,6 +454,21 @@ var Routes = []Route{
 		Req:    reflect.TypeFor[CompactReq](),
 		Resp:   reflect.TypeFor[StatusResp](),
 	},
+	{
+		Name:   "interruptTask",
+		Doc:    "Cancels the agent's current turn; the task returns to waiting for input.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/interrupt",
+		Resp:   reflect.TypeFor[StatusResp](),
+	},
+	{
+		Name:   "setTaskPriority",
+		Doc:    "Changes the priority of a pending task, moving it in the start queue.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/priority",
+		Req:    reflect.TypeFor[SetPriorityReq](),
+		Resp:   reflect.TypeFor[StatusResp](),
+	},
 	{
 		Name:   "stopTask",
 		Doc:    "Requests graceful stop of a running task.",
@@ -218,6 +483,28 @@ var Routes = []Route{
 		Path:   "/api/v1/tasks/{id}/purge",
 		Resp:   reflect.TypeFor[StatusResp](),
 	},
+	{
+		Name:   "quickCreateTask",
+		Doc:    "Creates a task from a prompt alone, inferring the repo, harness and model from recent use.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/quick",
+		Req:    reflect.TypeFor[QuickCreateTaskReq](),
+		Resp:   reflect.TypeFor[QuickCreateTaskResp](),
+	},
+	{
+		Name:   "pinTask",
+		Doc:    "Pins a task to the top of the task list.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/pin",
+		Resp:   reflect.TypeFor[Task](),
+	},
+	{
+		Name:   "unpinTask",
+		Doc:    "Unpins a task.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/unpin",
+		Resp:   reflect.TypeFor[Task](),
+	},
 	{
 		Name:   "reviveTask",
 		Doc:    "Reconnects to an orphaned task container.",
@@ -225,6 +512,13 @@ var Routes = []Route{
 		Path:   "/api/v1/tasks/{id}/revive",
 		Resp:   reflect.TypeFor[StatusResp](),
 	},
+	{
+		Name:   "rehydrateTask",
+		Doc:    "Starts a fresh container for a task whose container is gone and restores its saved workspace. Requires the persistWorkspace setting when the task ended.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/rehydrate",
+		Resp:   reflect.TypeFor[StatusResp](),
+	},
 	{
 		Name:        "getTaskCILog",
 		Doc:         "Returns the log tail of a failed CI check run.",
@@ -241,6 +535,14 @@ var Routes = []Route{
 		Req:    reflect.TypeFor[SyncReq](),
 		Resp:   reflect.TypeFor[SyncResp](),
 	},
+	{
+		Name:   "pushTask",
+		Doc:    "Pushes the task branch to its push remote without opening a PR, for the tasks whose push policy is ask. Rejected when the policy is never.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/push",
+		Req:    reflect.TypeFor[PushReq](),
+		Resp:   reflect.TypeFor[SyncResp](),
+	},
 	{
 		Name:   "forkTask",
 		Doc:    "Forks a task by snapshotting its container and creating a new task on a derived branch.",
@@ -250,11 +552,152 @@ var Routes = []Route{
 		Resp:   reflect.TypeFor[CreateTaskResp](),
 	},
 	{
-		Name:   "getTaskDiff",
-		Doc:    "Returns the unified diff for a task's branch.",
+		Name:   "approvePlan",
+		Doc:    "Approves, optionally edited, the plan of a task in plan_review and lets the agent make changes.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/plan",
+		Req:    reflect.TypeFor[ApprovePlanReq](),
+		Resp:   reflect.TypeFor[StatusResp](),
+	},
+	{
+		Name:   "promoteTask",
+		Doc:    "Creates a real task from a plan-only task, seeded with its approved plan.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/promote",
+		Req:    reflect.TypeFor[PromoteTaskReq](),
+		Resp:   reflect.TypeFor[CreateTaskResp](),
+	},
+	{
+		Name:        "getTaskDiff",
+		Doc:         "Returns the unified diff for a task's branch, computed inside its container, optionally limited to one path. ?live=true also refreshes the task's diff stat and broadcasts it as a diffStat event when it changed, so running tasks show progress.",
+		Method:      "GET",
+		Path:        "/api/v1/tasks/{id}/diff",
+		Resp:        reflect.TypeFor[DiffResp](),
+		QueryParams: []string{"path", "live"},
+	},
+	{
+		Name:        "getTaskDiffHunks",
+		Doc:         "Returns the task diff parsed into files and hunks, with each file's language, optionally limited to one path. ?intraline=true also splits paired changed lines into changed and unchanged words.",
+		Method:      "GET",
+		Path:        "/api/v1/tasks/{id}/diff/hunks",
+		Resp:        reflect.TypeFor[DiffHunksResp](),
+		QueryParams: []string{"path", "intraline"},
+	},
+	{
+		Name:    "getTaskCommits",
+		Doc:     "Returns the commits on the task branch that are not on its base branch, newest first, with the files each one changed.",
+		Method:  "GET",
+		Path:    "/api/v1/tasks/{id}/commits",
+		Resp:    reflect.TypeFor[TaskCommit](),
+		IsArray: true,
+	},
+	{
+		Name:   "revertTaskCommit",
+		Doc:    "Reverts one commit of the task branch inside the container with a new commit, and tells the agent so it doesn't reintroduce the change.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/commits/{sha}/revert",
+		Req:    reflect.TypeFor[RevertCommitReq](),
+		Resp:   reflect.TypeFor[RevertCommitResp](),
+	},
+	{
+		Name:   "createTaskSnapshot",
+		Doc:    "Saves the task's workspace, including uncommitted and untracked files, as a named snapshot inside the container.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/snapshots",
+		Req:    reflect.TypeFor[CreateSnapshotReq](),
+		Resp:   reflect.TypeFor[TaskSnapshot](),
+	},
+	{
+		Name:    "listTaskSnapshots",
+		Doc:     "Returns the task's workspace snapshots, oldest first.",
+		Method:  "GET",
+		Path:    "/api/v1/tasks/{id}/snapshots",
+		Resp:    reflect.TypeFor[TaskSnapshot](),
+		IsArray: true,
+	},
+	{
+		Name:   "restoreTaskSnapshot",
+		Doc:    "Resets the task's workspace to a snapshot, discarding the commits and changes made since, and tells the agent. The task must not be running.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/snapshots/{name}/restore",
+		Req:    reflect.TypeFor[RestoreSnapshotReq](),
+		Resp:   reflect.TypeFor[StatusResp](),
+	},
+	{
+		Name:   "approveTaskPolicy",
+		Doc:    "Approves the tool call the task is paused on for violating the tool policy and resumes the task.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/policy/approve",
+		Resp:   reflect.TypeFor[StatusResp](),
+	},
+	{
+		Name:   "denyTaskPolicy",
+		Doc:    "Denies the tool call the task is paused on for violating the tool policy and stops the task.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/policy/deny",
+		Resp:   reflect.TypeFor[StatusResp](),
+	},
+	{
+		Name:    "getTaskTransitions",
+		Doc:     "Returns the task's state transition history, oldest first.",
+		Method:  "GET",
+		Path:    "/api/v1/tasks/{id}/transitions",
+		Resp:    reflect.TypeFor[StateTransition](),
+		IsArray: true,
+	},
+	{
+		Name:   "getTaskTimeline",
+		Doc:    "Returns the phases of the task with their durations: queue, container start, each turn with its time to first output, verification, push.",
+		Method: "GET",
+		Path:   "/api/v1/tasks/{id}/timeline",
+		Resp:   reflect.TypeFor[TaskTimeline](),
+	},
+	{
+		Name:   "taskConsole",
+		Doc:    "Streams the raw console output of the task's container via SSE: its stdout/stderr and the stderr of the agent process, starting with the last ?tail=N lines of each (default 200). The output of the commands the agent runs as tools is not included; it is in the tool results of the task's messages. The stream ends when the container stops.",
+		Method: "GET",
+		Path:   "/api/v1/tasks/{id}/console",
+		Resp:   reflect.TypeFor[ConsoleLine](),
+		IsSSE:  true,
+	},
+	{
+		Name:        "getTaskMessages",
+		Doc:         "Returns a page of the task transcript: up to limit messages from message index after, with their annotations.",
+		Method:      "GET",
+		Path:        "/api/v1/tasks/{id}/messages",
+		Resp:        reflect.TypeFor[TaskMessagesResp](),
+		QueryParams: []string{"after", "limit"},
+	},
+	{
+		Name:   "getTaskMessageContent",
+		Doc:    "Returns the full content of a message whose events were truncated to a preview.",
 		Method: "GET",
-		Path:   "/api/v1/tasks/{id}/diff",
-		Resp:   reflect.TypeFor[DiffResp](),
+		Path:   "/api/v1/tasks/{id}/messages/{index}/content",
+		Resp:   reflect.TypeFor[MessageContentResp](),
+	},
+	{
+		Name:   "annotateTaskMessage",
+		Doc:    "Attaches a note or a bookmark to a message of the task transcript.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/messages/{index}/annotations",
+		Req:    reflect.TypeFor[AnnotateMessageReq](),
+		Resp:   reflect.TypeFor[Annotation](),
+	},
+	{
+		Name:    "listTaskAnnotations",
+		Doc:     "Returns the annotations of the task transcript, by message index.",
+		Method:  "GET",
+		Path:    "/api/v1/tasks/{id}/annotations",
+		Resp:    reflect.TypeFor[Annotation](),
+		IsArray: true,
+	},
+	{
+		Name:   "deleteTaskAnnotation",
+		Doc:    "Deletes an annotation of the task transcript.",
+		Method: "POST",
+		Path:   "/api/v1/tasks/{id}/annotations/{annotationID}/delete",
+		Req:    reflect.TypeFor[DeleteAnnotationReq](),
+		Resp:   reflect.TypeFor[StatusResp](),
 	},
 	{
 		Name:   "getTaskToolInput",
@@ -265,7 +708,7 @@ var Routes = []Route{
 	},
 	{
 		Name:   "globalTaskEvents",
-		Doc:    "Streams task list updates for all tasks via SSE.",
+		Doc:    "Streams task list updates for all tasks via SSE. ?includeArchived, ?pinned, ?state, ?repo, ?project, ?createdWithin and ?stateOlderThan filter tasks as for listTasks.",
 		Method: "GET",
 		Path:   "/api/v1/server/tasks/events",
 		Resp:   reflect.TypeFor[TaskListEvent](),
@@ -273,7 +716,7 @@ var Routes = []Route{
 	},
 	{
 		Name:   "globalUsageEvents",
-		Doc:    "Streams usage quota updates via SSE.",
+		Doc:    "Streams usage quota updates via SSE. ?project= counts only the tasks with a repo of the project, as for getUsage.",
 		Method: "GET",
 		Path:   "/api/v1/server/usage/events",
 		Resp:   reflect.TypeFor[UsageResp](),
@@ -281,7 +724,7 @@ var Routes = []Route{
 	},
 	{
 		Name:   "getUsage",
-		Doc:    "Returns current usage quota statistics.",
+		Doc:    "Returns current usage quota statistics. ?project= sums the cost and tokens of, and lists the rate limited, tasks with a repo of the project in the user's settings only; the quota utilization stays account-wide.",
 		Method: "GET",
 		Path:   "/api/v1/usage",
 		Resp:   reflect.TypeFor[UsageResp](),
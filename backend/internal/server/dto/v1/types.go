@@ -28,10 +28,13 @@ type Harness string
 
 // Supported agent harnesses.
 const (
+	HarnessAider    Harness = "aider"
 	HarnessClaude   Harness = "claude"
 	HarnessCodex    Harness = "codex"
+	HarnessGeneric  Harness = "generic"
 	HarnessGemini   Harness = "gemini"
 	HarnessKilo     Harness = "kilo"
+	HarnessLocal    Harness = "local"
 	HarnessOpenCode Harness = "opencode"
 )
 
@@ -41,6 +44,143 @@ type HarnessInfo struct {
 	Models          []string `json:"models"`
 	SupportsImages  bool     `json:"supportsImages"`
 	SupportsCompact bool     `json:"supportsCompact"`
+	// SupportsInterrupt reports whether a running turn can be interrupted.
+	SupportsInterrupt bool `json:"supportsInterrupt"`
+	// SupportsThinking reports whether CreateTaskReq.Thinking is honored.
+	SupportsThinking bool `json:"supportsThinking"`
+}
+
+// HealthResp is returned by GET /api/v1/health. The endpoint answers 200 when
+// the server is ready to run tasks and 503 otherwise.
+type HealthResp struct {
+	Status  string        `json:"status"` // "ok" or "unavailable".
+	Version string        `json:"version,omitempty"`
+	Live    bool          `json:"live"`  // Always true when the server answers.
+	Ready   bool          `json:"ready"` // All checks passed.
+	Checks  []HealthCheck `json:"checks"`
+}
+
+// RuntimeResp is the response for GET /api/v1/admin/runtime: a snapshot of
+// the Go runtime to diagnose hangs and leaks.
+type RuntimeResp struct {
+	Goroutines      int              `json:"goroutines"`
+	HeapAllocBytes  uint64           `json:"heapAllocBytes"`
+	HeapInuseBytes  uint64           `json:"heapInuseBytes"`
+	HeapObjects     uint64           `json:"heapObjects"`
+	SysBytes        uint64           `json:"sysBytes"` // Memory obtained from the OS.
+	NumGC           uint32           `json:"numGC"`
+	GCPauseTotal    float64          `json:"gcPauseTotal"` // Seconds.
+	Tasks           int              `json:"tasks"`
+	GoroutineGroups []GoroutineGroup `json:"goroutineGroups"` // Most common stacks first.
+}
+
+// FrontendSource is where the served web UI comes from.
+type FrontendSource string
+
+// Frontend sources.
+const (
+	FrontendEmbedded FrontendSource = "embedded" // Compiled into the server.
+	FrontendDir      FrontendSource = "dir"      // A local directory; see the -frontend-dir flag.
+)
+
+// FrontendBuildResp identifies the served web UI build.
+type FrontendBuildResp struct {
+	Source    FrontendSource `json:"source"`
+	Dir       string         `json:"dir,omitempty"`
+	Hash      string         `json:"hash"`               // Short SHA-256 of the build's files.
+	ChangedAt time.Time      `json:"changedAt,omitzero"` // Last rebuild seen; dir only.
+}
+
+// GoroutineGroup is a set of goroutines sharing the same stack.
+type GoroutineGroup struct {
+	Count int      `json:"count"`
+	Stack []string `json:"stack"` // Innermost frame first, as "function file:line"; empty for goroutines not started yet.
+}
+
+// HealthCheck is the outcome of a single readiness probe.
+type HealthCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// HarnessHealth reports whether a harness can run tasks: its binary is
+// installed in the container image and its API credentials are valid.
+type HarnessHealth struct {
+	Name             string `json:"name"`
+	Image            string `json:"image"`
+	Binary           string `json:"binary"`
+	Installed        bool   `json:"installed"`
+	Version          string `json:"version,omitempty"` // First line of "<binary> --version".
+	InstallError     string `json:"installError,omitempty"`
+	CredentialsOK    bool   `json:"credentialsOK"`
+	CredentialsError string `json:"credentialsError,omitempty"`
+	OK               bool   `json:"ok"` // Installed && CredentialsOK.
+}
+
+// ImageCheck is one step of a base image smoke test.
+type ImageCheck struct {
+	Name   string `json:"name"` // "pull", "clone" or "harness <name>".
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"` // What was found, or the error.
+}
+
+// ValidateImageReq is the request body for POST /api/v1/server/image/validate.
+type ValidateImageReq struct {
+	// Image is the base image to smoke test. Empty means the caller's
+	// pending base image.
+	Image string `json:"image,omitempty"`
+}
+
+// ImageValidationResp is the outcome of a base image smoke test.
+type ImageValidationResp struct {
+	Image    string       `json:"image"`
+	OK       bool         `json:"ok"` // All checks passed.
+	Checks   []ImageCheck `json:"checks"`
+	Promoted bool         `json:"promoted,omitempty"` // The pending base image passed and became the base image.
+}
+
+// ImageStatus is the validation status of a pending base image.
+type ImageStatus string
+
+// Image validation statuses.
+const (
+	ImageValidating ImageStatus = "validating"
+	ImageFailed     ImageStatus = "failed"
+)
+
+// PendingImage is a new base image that is smoke tested before tasks use it.
+type PendingImage struct {
+	Image  string      `json:"image"`
+	Status ImageStatus `json:"status"`
+	Error  string      `json:"error,omitempty"` // Why the validation failed.
+}
+
+// ImagePinReq is the request body of the /api/v1/server/image/pins and
+// /api/v1/server/image/check-update endpoints.
+type ImagePinReq struct {
+	// Image is the base image reference, e.g. "ghcr.io/org/img:latest".
+	// Empty means the caller's base image.
+	Image string `json:"image,omitempty"`
+}
+
+// ImagePin is a base image pinned to a registry digest.
+type ImagePin struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest"` // e.g. "sha256:0123..."
+}
+
+// ImagePinsResp is the response of the /api/v1/server/image/pins endpoints.
+type ImagePinsResp struct {
+	Pins []ImagePin `json:"pins"`
+}
+
+// ImageUpdateResp is the response for POST /api/v1/server/image/check-update.
+type ImageUpdateResp struct {
+	Image           string `json:"image"`
+	Pinned          string `json:"pinned,omitempty"` // Pinned digest; empty when the image is not pinned.
+	Latest          string `json:"latest"`           // Digest the image reference currently resolves to.
+	UpdateAvailable bool   `json:"updateAvailable"`  // The image is pinned to another digest than Latest.
 }
 
 // ImageData carries a single base64-encoded image.
@@ -62,7 +202,8 @@ type Config struct {
 	USBAvailable       bool     `json:"usbAvailable"`
 	DisplayAvailable   bool     `json:"displayAvailable"`
 	GitHubAppEnabled   bool     `json:"gitHubAppEnabled,omitempty"`
-	AuthProviders      []string `json:"authProviders,omitempty"` // e.g. ["github","gitlab"]
+	AuthProviders      []string `json:"authProviders,omitempty"`  // e.g. ["github","gitlab"]
+	SemanticSearch     bool     `json:"semanticSearch,omitempty"` // GET /api/v1/server/repos/semantic-search is available.
 }
 
 // UserResp is returned by GET /api/v1/auth/me.
@@ -71,6 +212,7 @@ type UserResp struct {
 	Provider  string `json:"provider"`
 	Username  string `json:"username"`
 	AvatarURL string `json:"avatarURL,omitempty"`
+	Admin     bool   `json:"admin,omitempty"` // May use the /api/v1/admin endpoints.
 }
 
 // CIStatus is the CI check state for a task or repo default branch.
@@ -124,7 +266,11 @@ type ForgeCheck struct {
 
 // Repo is the JSON representation of a discovered repo.
 type Repo struct {
-	Path                  string       `json:"path"`
+	Path                  string       `json:"path"`                 // "<root>:<path>" for the repos of a source root other than the primary one.
+	Root                  string       `json:"root,omitempty"`       // Name of the source root; empty for the primary root.
+	Mirror                bool         `json:"mirror,omitempty"`     // Bare clone managed and fetched by caic.
+	DeployKey             bool         `json:"deployKey,omitempty"`  // Fetched and pushed with a deploy key; see createDeployKey.
+	PushRemote            string       `json:"pushRemote,omitempty"` // Remote task branches are pushed to by default, e.g. a fork; empty means origin.
 	BaseBranch            BranchInfo   `json:"baseBranch"`
 	RemoteURL             string       `json:"remoteURL,omitempty"`
 	Forge                 Forge        `json:"forge,omitempty"` // "github", "gitlab", or empty if unknown.
@@ -136,6 +282,9 @@ type Repo struct {
 type RepoSpec struct {
 	Name       string `json:"name"`
 	BaseBranch string `json:"baseBranch,omitempty"`
+	// PushRemote is the remote the task branch is pushed to, e.g. a fork, from
+	// which PRs are opened against origin. Defaults to the repo's push remote.
+	PushRemote string `json:"pushRemote,omitempty"`
 }
 
 // TaskRepo describes a repository associated with a task in the API response.
@@ -144,21 +293,27 @@ type TaskRepo struct {
 	BaseBranch string `json:"baseBranch,omitempty"`
 	Branch     string `json:"branch"`
 	RemoteURL  string `json:"remoteURL,omitempty"`
-	Forge      Forge  `json:"forge,omitempty"` // "github", "gitlab", or empty if unknown.
+	Forge      Forge  `json:"forge,omitempty"`      // "github", "gitlab", or empty if unknown.
+	PushRemote string `json:"pushRemote,omitempty"` // Remote the branch is pushed to; empty means origin.
 }
 
 // Task is the JSON representation sent to the frontend.
 type Task struct {
 	ID                                 ksid.ID      `json:"id"`
+	Alias                              string       `json:"alias,omitempty"` // Short human-friendly alias, e.g. "w42"; also accepted in place of the ID.
 	InitialPrompt                      string       `json:"initialPrompt"`
 	Title                              string       `json:"title"`
 	Repos                              []TaskRepo   `json:"repos,omitempty"`
 	Container                          string       `json:"container"`
 	State                              string       `json:"state"`
-	StateUpdatedAt                     float64      `json:"stateUpdatedAt"` // Unix epoch seconds (ms precision) of last state change.
+	SubState                           string       `json:"subState,omitempty"` // Step within state: "image_pull" while provisioning pulls the base image.
+	StateUpdatedAt                     time.Time    `json:"stateUpdatedAt"`     // Last state change.
+	Revision                           uint64       `json:"revision"`           // Advanced by each successful mutation; send as optional If-Match to detect concurrent changes.
 	DiffStat                           DiffStat     `json:"diffStat,omitzero"`
-	CostUSD                            float64      `json:"costUSD"`
-	Duration                           float64      `json:"duration"` // Seconds.
+	CostUSD                            float64      `json:"costUSD"`                    // As reported by the harness.
+	EstimatedCostUSD                   float64      `json:"estimatedCostUSD,omitempty"` // Computed from token usage and the price table; zero when the model has no price.
+	CostDiscrepancy                    bool         `json:"costDiscrepancy,omitempty"`  // The reported and estimated costs disagree.
+	Duration                           float64      `json:"duration"`                   // Seconds.
 	NumTurns                           int          `json:"numTurns"`
 	CumulativeInputTokens              int          `json:"cumulativeInputTokens"`
 	CumulativeOutputTokens             int          `json:"cumulativeOutputTokens"`
@@ -173,21 +328,59 @@ type Task struct {
 	ForgeRepo                          string       `json:"forgeRepo,omitempty"`
 	ForgePR                            int          `json:"forgePR,omitempty"`
 	ForgeIssue                         int          `json:"forgeIssue,omitempty"`
+	Issue                              *IssueLink   `json:"issue,omitempty"` // Linked Jira or Linear issue.
 	CIStatus                           CIStatus     `json:"ciStatus,omitempty"`
 	CIChecks                           []ForgeCheck `json:"ciChecks,omitempty"`
-	Owner                              string       `json:"owner,omitempty"` // username of creator; omitted in no-auth mode
+	Owner                              string       `json:"owner,omitempty"`     // username of creator; omitted in no-auth mode
+	DiskBytes                          int64        `json:"diskBytes,omitempty"` // Size of the container's writable layer.
+	LogBytes                           int64        `json:"logBytes,omitempty"`  // Size of the task's log files.
+	Archived                           bool         `json:"archived,omitempty"`  // Hidden from the task list unless includeArchived is set.
+	Pinned                             bool         `json:"pinned,omitempty"`    // Sorted first in the task list.
 	// Per-task harness/container metadata.
-	Harness       Harness `json:"harness"`
-	Model         string  `json:"model,omitempty"`
-	AgentVersion  string  `json:"agentVersion,omitempty"`
-	SessionID     string  `json:"sessionID,omitempty"`
-	StartedAt     float64 `json:"startedAt,omitempty"`     // Unix epoch seconds (ms precision) when the container started.
-	TurnStartedAt float64 `json:"turnStartedAt,omitempty"` // Unix epoch seconds; non-zero only while state is "running".
-	InPlanMode    bool    `json:"inPlanMode,omitempty"`
-	PlanContent   string  `json:"planContent,omitempty"`
-	Tailscale     string  `json:"tailscale,omitempty"` // Tailscale URL (https://fqdn) or "true" if enabled but FQDN unknown.
-	USB           bool    `json:"usb,omitempty"`
-	Display       bool    `json:"display,omitempty"`
+	Harness       Harness           `json:"harness"`
+	Model         string            `json:"model,omitempty"`
+	AgentVersion  string            `json:"agentVersion,omitempty"`
+	SessionID     string            `json:"sessionID,omitempty"`
+	StartedAt     time.Time         `json:"startedAt,omitzero"`     // When the container started.
+	TurnStartedAt time.Time         `json:"turnStartedAt,omitzero"` // Set only while state is "running".
+	ResumesAt     time.Time         `json:"resumesAt,omitzero"`     // When a "rate_limited" task resumes.
+	InPlanMode    bool              `json:"inPlanMode,omitempty"`
+	PlanContent   string            `json:"planContent,omitempty"`
+	Tailscale     string            `json:"tailscale,omitempty"` // Tailscale URL (https://fqdn) or "true" if enabled but FQDN unknown.
+	USB           bool              `json:"usb,omitempty"`
+	Display       bool              `json:"display,omitempty"`
+	PlanOnly      bool              `json:"planOnly,omitempty"`    // Read-only plan task; promote it to implement the plan.
+	RequirePlan   bool              `json:"requirePlan,omitempty"` // Two-phase task; in state "plan_review" until the plan is approved.
+	ReadOnly      bool              `json:"readOnly,omitempty"`    // Repos are read-only in the container; nothing to sync.
+	Chat          bool              `json:"chat,omitempty"`        // Conversation only; never enters branching, pulling or pushing.
+	Thinking      bool              `json:"thinking,omitempty"`    // Extended thinking was requested.
+	IdlePolicy    *IdlePolicy       `json:"idlePolicy,omitempty"`  // The task's override of the idle policy.
+	PushPolicy    PushPolicy        `json:"pushPolicy,omitempty"`  // Effective push policy; "ask" shows a push action.
+	DependsOn     []ksid.ID         `json:"dependsOn,omitempty"`   // Prerequisites; the task stays "pending" until they are done.
+	HeldReason    string            `json:"heldReason,omitempty"`  // Why the "pending" task has not started yet.
+	Priority      TaskPriority      `json:"priority,omitempty"`    // Start order while "pending"; omitted when normal.
+	Pipeline      *PipelineProgress `json:"pipeline,omitempty"`
+	Review        *ReviewProgress   `json:"review,omitempty"`
+	Network       NetworkMode       `json:"network,omitempty"` // Omitted when unrestricted.
+	// PolicyViolation is set while the task's container is paused on a tool
+	// call denied by the tool policy, pending approval.
+	PolicyViolation *PolicyViolation `json:"policyViolation,omitempty"`
+	// LastActivityAt is when the agent of a running task last sent a
+	// message, or when its turn started.
+	LastActivityAt time.Time `json:"lastActivityAt,omitzero"`
+	// Stalled is set while a running task has been silent for longer than
+	// the stall policy allows.
+	Stalled bool `json:"stalled,omitempty"`
+	// Failover is set when the task switched to the fallback harness and
+	// model because its own failed to start.
+	Failover *Failover `json:"failover,omitempty"`
+}
+
+// PolicyViolation is a tool call denied by a tool policy rule.
+type PolicyViolation struct {
+	Rule   string `json:"rule"`
+	Tool   string `json:"tool"`
+	Detail string `json:"detail"`
 }
 
 // TaskListEvent is a discriminated-union event for the task list SSE stream.
@@ -241,6 +434,355 @@ type CreateTaskReq struct {
 	Tailscale     bool       `json:"tailscale,omitempty"`
 	USB           bool       `json:"usb,omitempty"`
 	Display       bool       `json:"display,omitempty"`
+	PlanOnly      bool       `json:"planOnly,omitempty"`    // Explore and propose a plan without writing; no diff or push.
+	RequirePlan   bool       `json:"requirePlan,omitempty"` // Plan first; changes start only after POST /api/v1/tasks/{id}/plan.
+	ReadOnly      bool       `json:"readOnly,omitempty"`    // Mount repos read-only and deny write tools, for questions about the code.
+	Chat          bool       `json:"chat,omitempty"`        // Lightweight conversation over the repo: no branch, diff or push.
+	Thinking      bool       `json:"thinking,omitempty"`    // Request extended thinking; see HarnessInfo.SupportsThinking.
+	// IdlePolicy overrides the idle policy of the preferences for this task;
+	// hours 0 exempts it.
+	IdlePolicy *IdlePolicy `json:"idlePolicy,omitempty"`
+	// PushPolicy overrides the push policy of the preferences for this task.
+	PushPolicy PushPolicy `json:"pushPolicy,omitempty"`
+	// GatherContext searches the primary repo for code-like terms of the
+	// prompt and prepends a short list of the relevant files to it.
+	GatherContext bool `json:"gatherContext,omitempty"`
+	// DependsOn lists task IDs that must reach done (finished a turn
+	// successfully) before this task starts; until then it stays pending.
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// InheritBranch pushes the first prerequisite's branch and uses it as the
+	// base branch of this task's primary repo, stacking the changes.
+	InheritBranch       bool                    `json:"inheritBranch,omitempty"`
+	OnDependencyFailure DependencyFailurePolicy `json:"onDependencyFailure,omitempty"`
+	// Priority orders the start of queued tasks: a task held by its
+	// dependencies or the execution window starts after the ready tasks of
+	// higher priority. Empty means normal.
+	Priority TaskPriority `json:"priority,omitempty"`
+	// Pipeline runs the steps as chained tasks: this task runs the first step
+	// and each next step's task is stacked on the previous one's branch like
+	// with inheritBranch. The initial prompt text is the pipeline input; a
+	// review runs on the last step's task.
+	Pipeline *Pipeline `json:"pipeline,omitempty"`
+	// Review has a second agent review the diff after each turn; its
+	// feedback is sent back to this task until it approves.
+	Review *ReviewSpec `json:"review,omitempty"`
+	// Network restricts the container's outgoing connections. Empty uses the
+	// network setting of the user's preferences.
+	Network NetworkMode `json:"network,omitempty"`
+	// NetworkAllow lists the hosts reachable with the "allowlist" mode.
+	NetworkAllow []string `json:"networkAllow,omitempty"`
+	// Issue links the task to a Jira or Linear issue key, e.g. "ENG-123";
+	// an empty prompt is filled from the issue. Without it, the task is
+	// linked to the first issue its prompt mentions.
+	Issue string `json:"issue,omitempty"`
+	// Env sets environment variables in the container and the agent, e.g. a
+	// feature flag or a staging API URL. Each name must match the envAllowlist
+	// of the user's settings.
+	Env map[string]string `json:"env,omitempty"`
+	// Instructions are appended to the agent's system prompt after the repo's
+	// .caic/instructions.md and the repoInstructions of the user's settings,
+	// so they take precedence.
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// IssueLink is the Jira or Linear issue a task is linked to; the task
+// comments there when it starts, opens a PR and finishes.
+type IssueLink struct {
+	Tracker string `json:"tracker"` // "jira" or "linear"
+	Key     string `json:"key"`
+	URL     string `json:"url,omitempty"`
+}
+
+// QuickCreateTaskReq is the request body for POST /api/v1/tasks/quick. The
+// repo, harness and model are inferred from the user's recent preferences.
+type QuickCreateTaskReq struct {
+	InitialPrompt Prompt `json:"initialPrompt"`
+}
+
+// QuickCreateTaskResp is the response for POST /api/v1/tasks/quick. It
+// reports what was inferred so the client can confirm it to the user.
+type QuickCreateTaskResp struct {
+	Status     string  `json:"status"`
+	ID         ksid.ID `json:"id"`
+	Repo       string  `json:"repo,omitempty"` // Empty for a no-repo task.
+	BaseBranch string  `json:"baseBranch,omitempty"`
+	Harness    Harness `json:"harness"`
+	Model      string  `json:"model,omitempty"` // Empty means the harness default.
+}
+
+// ReviewSpec configures the agent-to-agent review loop.
+type ReviewSpec struct {
+	Harness   Harness `json:"harness"`             // Reviewer harness; may differ from the task's.
+	Model     string  `json:"model,omitempty"`     // Reviewer model; empty means the harness default.
+	MaxRounds int     `json:"maxRounds,omitempty"` // Review rounds before giving up; 0 means 3.
+}
+
+// ReviewProgress reports the state of a task's review loop.
+type ReviewProgress struct {
+	Round     int       `json:"round"` // 1-based round of the current or last review.
+	MaxRounds int       `json:"maxRounds"`
+	Status    string    `json:"status"`              // "pending", "reviewing", "changes_requested", "approved", "exhausted", "failed", or "aborted"
+	Reviewers []ksid.ID `json:"reviewers,omitempty"` // Reviewer task per round; their transcripts are kept.
+	Feedback  string    `json:"feedback,omitempty"`  // Last feedback from the reviewer.
+	Error     string    `json:"error,omitempty"`
+}
+
+// Pipeline is a reusable multi-step workflow, e.g. write tests, implement,
+// lint, then write the changelog.
+type Pipeline struct {
+	Name  string         `json:"name"`
+	Steps []PipelineStep `json:"steps"`
+}
+
+// PipelineStep is one task of a pipeline.
+//
+// Prompt is a Go text/template rendered with .Input (the task's initial
+// prompt), .Previous (the previous step's result), .Verification (the
+// previous step's verification output) and .Step (0-based index).
+type PipelineStep struct {
+	Name   string `json:"name,omitempty"`
+	Prompt string `json:"prompt"`
+	// Verify is an optional shell command run in the repo inside the
+	// container after the step's first turn; a non-zero exit fails the
+	// pipeline.
+	Verify string `json:"verify,omitempty"`
+}
+
+// PipelineProgress reports how far a pipeline got.
+type PipelineProgress struct {
+	Name   string    `json:"name"`
+	Step   int       `json:"step"` // 0-based index of the current or last step.
+	Steps  int       `json:"steps"`
+	Status string    `json:"status"` // "running", "done", "failed", or "aborted"
+	Error  string    `json:"error,omitempty"`
+	Tasks  []ksid.ID `json:"tasks,omitempty"` // Task of each started step, in order.
+}
+
+// StateTransition is one entry of a task's state history.
+type StateTransition struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// TimelinePhase is a span of a task's timeline.
+type TimelinePhase struct {
+	// Name is queued, branching, container_start, agent_start, turn,
+	// rate_limited, awaiting_input, pull, push, teardown or verification.
+	Name       string    `json:"name"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end,omitzero"` // Unset while in progress.
+	DurationMs int64     `json:"durationMs"`   // Up to now while in progress.
+	Turn       int       `json:"turn,omitempty"`
+	// FirstOutputMs is the time from the start of a turn to the agent's first
+	// output; unset when unknown.
+	FirstOutputMs int64  `json:"firstOutputMs,omitempty"`
+	Error         string `json:"error,omitempty"` // Failed verification.
+}
+
+// TaskTimeline is where the wall-clock time of a task went.
+type TaskTimeline struct {
+	Phases []TimelinePhase `json:"phases"`
+	// TotalMs is the time from the first phase to the end of the last one,
+	// or now.
+	TotalMs int64 `json:"totalMs"`
+	// PhaseMs sums the durations per phase name. Verification runs overlap
+	// the other phases.
+	PhaseMs map[string]int64 `json:"phaseMs"`
+}
+
+// ConsoleLine is a line of the raw console output of a task's container,
+// outside of the agent's message stream.
+type ConsoleLine struct {
+	// Source is "container" for the stdout/stderr of the container, or
+	// "agent" for the stderr of the agent process.
+	Source string    `json:"source"`
+	Text   string    `json:"text"`
+	At     time.Time `json:"at"`
+}
+
+// EvalSuite is a corpus of benchmark cases run against harness/model
+// combinations.
+type EvalSuite struct {
+	Name  string     `json:"name"`
+	Cases []EvalCase `json:"cases"`
+}
+
+// EvalCase is one benchmark: a prompt run on a repo at a given ref, judged by
+// a verification script.
+type EvalCase struct {
+	Name string `json:"name"`
+	Repo string `json:"repo"`          // Repo path relative to the root, as in RepoSpec.Name.
+	Ref  string `json:"ref,omitempty"` // Branch or commit the task starts from; empty means the default branch.
+	// Prompt is sent as the task's initial prompt.
+	Prompt string `json:"prompt"`
+	// Verify is a shell command run in the repo inside the container after
+	// the turn; exit code 0 means the case passed.
+	Verify string `json:"verify"`
+}
+
+// EvalTarget is a harness/model combination an eval suite runs against.
+type EvalTarget struct {
+	Harness Harness `json:"harness"`
+	Model   string  `json:"model,omitempty"` // Empty means the harness default.
+}
+
+// CreateEvalReq is the request body for POST /api/v1/evals.
+type CreateEvalReq struct {
+	Suite   EvalSuite    `json:"suite"`
+	Targets []EvalTarget `json:"targets"`
+	// Parallel is the number of cases run concurrently; 0 means 1.
+	Parallel int `json:"parallel,omitempty"`
+}
+
+// GetEvalReq is the request for GET /api/v1/evals/{id}.
+type GetEvalReq struct {
+	ID string `json:"-" path:"id"`
+}
+
+// EvalReport is the outcome of an eval run, updated as cases complete.
+type EvalReport struct {
+	ID         ksid.ID       `json:"id"`
+	Suite      string        `json:"suite"`
+	Status     string        `json:"status"` // "running" or "done"
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt,omitzero"` // Unset while running.
+	Results    []EvalResult  `json:"results"`             // One per case and target, in suite order.
+	Summary    []EvalSummary `json:"summary"`             // One per target.
+}
+
+// EvalResult is the outcome of one case on one target.
+type EvalResult struct {
+	Case     string     `json:"case"`
+	Target   EvalTarget `json:"target"`
+	Status   string     `json:"status"` // "pending", "running", "passed", "failed", or "error"
+	TaskID   ksid.ID    `json:"taskId,omitempty"`
+	CostUSD  float64    `json:"costUSD"`
+	Duration float64    `json:"duration"`         // Seconds, from task creation to verification.
+	Output   string     `json:"output,omitempty"` // Tail of the verification output.
+	Error    string     `json:"error,omitempty"`
+}
+
+// EvalSummary aggregates the results of one target.
+type EvalSummary struct {
+	Target   EvalTarget `json:"target"`
+	Passed   int        `json:"passed"`
+	Failed   int        `json:"failed"`
+	Errored  int        `json:"errored"`
+	CostUSD  float64    `json:"costUSD"`
+	Duration float64    `json:"duration"` // Seconds, summed over cases.
+}
+
+// DependencyFailurePolicy selects what happens to a pending task when one of
+// its prerequisites fails.
+type DependencyFailurePolicy string
+
+// Supported dependency failure policies.
+const (
+	DependencyFailureCancel DependencyFailurePolicy = "cancel" // Fail the dependent task (default).
+	DependencyFailureHold   DependencyFailurePolicy = "hold"   // Keep the dependent pending until it is purged.
+)
+
+// TaskPriority orders the start of queued tasks.
+type TaskPriority string
+
+// Supported task priorities.
+const (
+	PriorityLow    TaskPriority = "low"
+	PriorityNormal TaskPriority = "normal"
+	PriorityHigh   TaskPriority = "high"
+)
+
+// NetworkMode controls the outgoing connections of a task's container. The
+// agent's model API stays reachable in every mode.
+type NetworkMode string
+
+// Supported network modes.
+const (
+	NetworkFull      NetworkMode = "full"      // No restriction (default).
+	NetworkNone      NetworkMode = "none"      // Only the agent's model API.
+	NetworkAllowlist NetworkMode = "allowlist" // The model API and networkAllow.
+)
+
+// IdleAction is what happens to a task left waiting for input longer than
+// its idle policy allows.
+type IdleAction string
+
+// Supported idle actions.
+const (
+	IdleNotify IdleAction = "notify" // Post a notice in the transcript.
+	IdleFinish IdleAction = "finish" // Push the branch and stop the task, after a warning.
+)
+
+// IdlePolicy acts on tasks left waiting for input.
+type IdlePolicy struct {
+	Hours  int        `json:"hours"` // Hours waiting before the action; 0 disables the policy.
+	Action IdleAction `json:"action"`
+}
+
+// StallPolicy flags the running tasks whose agent sent no message for a
+// while: a hung harness otherwise looks like one thinking for long.
+type StallPolicy struct {
+	Minutes   int  `json:"minutes"`             // Minutes of silence before the task is flagged; 0 disables the policy.
+	Interrupt bool `json:"interrupt,omitempty"` // Also interrupt the turn of a stalled task.
+}
+
+// Fallback is the harness and model used when those of a task fail to start,
+// e.g. during a provider outage.
+type Fallback struct {
+	Harness Harness `json:"harness,omitempty"` // Empty keeps the task's harness.
+	Model   string  `json:"model,omitempty"`   // Empty uses the harness default.
+}
+
+// Failover records that a task switched to the fallback harness and model.
+type Failover struct {
+	FromHarness Harness   `json:"fromHarness"`
+	FromModel   string    `json:"fromModel,omitempty"`
+	ToHarness   Harness   `json:"toHarness"`
+	ToModel     string    `json:"toModel,omitempty"`
+	Error       string    `json:"error"` // Last start error of the replaced harness and model.
+	At          time.Time `json:"at"`
+}
+
+// PushPolicy controls when the branch of a task is pushed.
+type PushPolicy string
+
+// Supported push policies.
+const (
+	PushAuto  PushPolicy = "auto"  // Push on request and when the idle policy finishes the task or after a CI fix (default).
+	PushAsk   PushPolicy = "ask"   // Push only on request, through sync or push.
+	PushNever PushPolicy = "never" // Never push; sync and push are rejected.
+)
+
+// ExecutionWindow is the time of day during which new tasks may start.
+type ExecutionWindow struct {
+	Start    string `json:"start"`              // "15:04" clock time.
+	End      string `json:"end"`                // "15:04" clock time; before Start spans midnight.
+	Timezone string `json:"timezone,omitempty"` // IANA name; empty is the server's local time.
+}
+
+// NotifyKind is the chat service of a notification channel.
+type NotifyKind string
+
+// Supported notification channel kinds.
+const (
+	NotifySlack    NotifyKind = "slack"    // Slack incoming webhook.
+	NotifyDiscord  NotifyKind = "discord"  // Discord webhook.
+	NotifyMatrix   NotifyKind = "matrix"   // Matrix room.
+	NotifyTelegram NotifyKind = "telegram" // Telegram chat with a bot.
+)
+
+// NotifyChannel is a named chat channel. Secret is the webhook URL of Slack
+// and Discord, the access token of Matrix and the bot token of Telegram. It
+// is write-only: it is never returned, and an empty secret keeps the saved
+// one of the channel with the same name and kind.
+type NotifyChannel struct {
+	Name      string     `json:"name"`
+	Kind      NotifyKind `json:"kind"`
+	URL       string     `json:"url,omitempty"` // Matrix homeserver URL.
+	Secret    string     `json:"secret,omitempty"`
+	HasSecret bool       `json:"hasSecret,omitempty"` // Response only.
+	Target    string     `json:"target,omitempty"`    // Matrix room ID or Telegram chat ID.
 }
 
 // ForkTaskReq is the request body for POST /api/v1/tasks/{id}/fork.
@@ -251,6 +793,16 @@ type ForkTaskReq struct {
 	ExtraRepos []RepoSpec `json:"extraRepos,omitempty"` // Additional repos to map into the fork.
 }
 
+// PromoteTaskReq is the request body for POST /api/v1/tasks/{id}/promote.
+type PromoteTaskReq struct {
+	Prompt Prompt `json:"prompt,omitempty"` // Approved plan; empty means use the plan the task proposed.
+}
+
+// ApprovePlanReq is the request body for POST /api/v1/tasks/{id}/plan.
+type ApprovePlanReq struct {
+	Prompt Prompt `json:"prompt,omitempty"` // Edited plan; empty means approve the plan as proposed.
+}
+
 // BotFixCIReq is the request body for POST /api/v1/bot/fix-ci.
 // The server fetches CI logs, builds a prompt, and creates a fix task.
 type BotFixCIReq struct {
@@ -273,6 +825,17 @@ type RestartReq struct {
 	Prompt Prompt `json:"prompt"`
 }
 
+// RewindReq is the request body for POST /api/v1/tasks/{id}/rewind.
+type RewindReq struct {
+	// Prompt replaces the last user input.
+	Prompt Prompt `json:"prompt"`
+}
+
+// SetPriorityReq is the request body for POST /api/v1/tasks/{id}/priority.
+type SetPriorityReq struct {
+	Priority TaskPriority `json:"priority"`
+}
+
 // CompactReq is the request body for POST /api/v1/tasks/{id}/compact.
 type CompactReq struct {
 	Instructions string `json:"instructions,omitempty"`
@@ -280,19 +843,44 @@ type CompactReq struct {
 
 // DiffFileStat describes changes to a single file.
 type DiffFileStat struct {
-	Path    string `json:"path"`
-	Added   int    `json:"added"`
-	Deleted int    `json:"deleted"`
-	Binary  bool   `json:"binary,omitempty"`
+	Path    string         `json:"path"`
+	Added   int            `json:"added"`
+	Deleted int            `json:"deleted"`
+	Binary  bool           `json:"binary,omitempty"`
+	Status  DiffFileStatus `json:"status,omitempty"`  // Empty when unknown.
+	OldPath string         `json:"oldPath,omitempty"` // Source path of a rename.
 }
 
+// DiffFileStatus is how a file changed.
+type DiffFileStatus string
+
+// File change statuses.
+const (
+	FileAdded    DiffFileStatus = "added"
+	FileModified DiffFileStatus = "modified"
+	FileDeleted  DiffFileStatus = "deleted"
+	FileRenamed  DiffFileStatus = "renamed"
+)
+
 // DiffStat summarises the changes in a branch relative to its base.
 type DiffStat []DiffFileStat
 
+// DiffDir is a directory of a DiffStat laid out as a tree, with the counts of
+// all the files below it rolled up. Directories with a single subdirectory
+// and no files are folded into their child, e.g. "backend/internal".
+type DiffDir struct {
+	Name    string         `json:"name"` // Empty for the root.
+	Path    string         `json:"path"` // Empty for the root.
+	Added   int            `json:"added"`
+	Deleted int            `json:"deleted"`
+	Dirs    []DiffDir      `json:"dirs,omitempty"`  // Sorted by name.
+	Files   []DiffFileStat `json:"files,omitempty"` // Sorted by path.
+}
+
 // SafetyIssue describes a potential problem detected before pushing to origin.
 type SafetyIssue struct {
 	File   string `json:"file"`
-	Kind   string `json:"kind"`   // "large_binary" or "secret"
+	Kind   string `json:"kind"`   // "large_binary", "secret" or "pre_push_check"
 	Detail string `json:"detail"` // Human-readable description.
 }
 
@@ -311,13 +899,33 @@ type SyncReq struct {
 	Target SyncTarget `json:"target,omitempty"`
 }
 
-// SyncResp is the response for POST /api/v1/tasks/{id}/sync.
+// PushReq is the request body for POST /api/v1/tasks/{id}/push.
+type PushReq struct {
+	Force bool `json:"force,omitempty"` // Push despite safety issues.
+}
+
+// SyncResp is the response for POST /api/v1/tasks/{id}/sync and
+// POST /api/v1/tasks/{id}/push.
 type SyncResp struct {
-	Status       string        `json:"status"` // "synced", "blocked", or "empty"
+	Status       string        `json:"status"` // "synced", "blocked", "empty", or "failed" (multi-repo only)
 	Branch       string        `json:"branch,omitempty"`
 	DiffStat     DiffStat      `json:"diffStat,omitzero"`
 	SafetyIssues []SafetyIssue `json:"safetyIssues,omitempty"`
 	PRNumber     int           `json:"prNumber,omitempty"` // non-zero if a PR/MR was created
+	// Repos holds one result per repo of a multi-repo task, primary first.
+	// The top-level fields describe the primary repo; Status is combined.
+	Repos []RepoSyncResult `json:"repos,omitempty"`
+}
+
+// RepoSyncResult is the sync outcome for a single repo of a multi-repo task.
+type RepoSyncResult struct {
+	Name         string        `json:"name"`
+	Status       string        `json:"status"` // "synced", "blocked", "empty", or "failed"
+	Branch       string        `json:"branch,omitempty"`
+	DiffStat     DiffStat      `json:"diffStat,omitzero"`
+	SafetyIssues []SafetyIssue `json:"safetyIssues,omitempty"`
+	PRNumber     int           `json:"prNumber,omitempty"`
+	Error        string        `json:"error,omitempty"`
 }
 
 // ClaudeUsage holds local task cost and rate-limit quota data for Claude.
@@ -349,10 +957,10 @@ type ClaudeExtraUsage struct {
 
 // CodexRateLimitWindow represents a single Codex rate-limit window snapshot.
 type CodexRateLimitWindow struct {
-	UsedPercent        int `json:"usedPercent"`
-	LimitWindowSeconds int `json:"limitWindowSeconds"`
-	ResetAfterSeconds  int `json:"resetAfterSeconds"`
-	ResetAt            int `json:"resetAt"` // unix timestamp
+	UsedPercent        int       `json:"usedPercent"`
+	LimitWindowSeconds int       `json:"limitWindowSeconds"`
+	ResetAfterSeconds  int       `json:"resetAfterSeconds"`
+	ResetAt            time.Time `json:"resetAt"`
 }
 
 // CodexCredits represents Codex credit/balance information.
@@ -370,10 +978,38 @@ type CodexUsage struct {
 	Credits   CodexCredits          `json:"credits"`
 }
 
+// DiskUsage reports the disk space used by tasks and left on the filesystem
+// holding the logs.
+type DiskUsage struct {
+	ContainerBytes int64 `json:"containerBytes"` // Sum of the writable layers of task containers.
+	LogBytes       int64 `json:"logBytes"`       // Sum of the task log files.
+	FreeBytes      int64 `json:"freeBytes"`
+	TotalBytes     int64 `json:"totalBytes"`
+	MinFreeBytes   int64 `json:"minFreeBytes,omitempty"` // Quota; task creation is paused below it.
+	Low            bool  `json:"low,omitempty"`          // FreeBytes is below MinFreeBytes.
+}
+
 // UsageResp is the response for GET /api/v1/usage.
 type UsageResp struct {
 	Claude *ClaudeUsage `json:"claude,omitempty"`
 	Codex  *CodexUsage  `json:"codex,omitempty"`
+	Disk   *DiskUsage   `json:"disk,omitempty"`
+	// RateLimits is the rate limit headroom of the harnesses that reported
+	// one or have rate limited tasks.
+	RateLimits []HarnessRateLimit `json:"rateLimits,omitempty"`
+}
+
+// HarnessRateLimit is the provider rate limit headroom of a harness, as last
+// reported by its agents, and the tasks backing off from it.
+type HarnessRateLimit struct {
+	Harness       Harness   `json:"harness"`
+	Status        string    `json:"status,omitempty"`        // "allowed", "allowed_warning", "rejected"; empty when never reported.
+	RateLimitType string    `json:"rateLimitType,omitempty"` // "five_hour", "seven_day", etc.
+	Utilization   float64   `json:"utilization,omitempty"`   // 0.0–1.0.
+	ResetsAt      time.Time `json:"resetsAt,omitzero"`
+	ReportedAt    time.Time `json:"reportedAt,omitzero"`
+	LimitedTasks  int       `json:"limitedTasks"`       // Tasks in state "rate_limited".
+	ResumesAt     time.Time `json:"resumesAt,omitzero"` // Earliest automatic resume of those tasks.
 }
 
 // VoiceTokenResp is the response for GET /api/v1/voice/token.
@@ -383,9 +1019,176 @@ type VoiceTokenResp struct {
 	Ephemeral bool   `json:"ephemeral"`
 }
 
+// TaskExport is a row of GET /api/v1/tasks/export: the task metadata used
+// for reporting.
+type TaskExport struct {
+	ID             ksid.ID   `json:"id"`
+	Alias          string    `json:"alias,omitempty"`
+	Title          string    `json:"title"`
+	Repo           string    `json:"repo,omitempty"`   // Primary repository.
+	Branch         string    `json:"branch,omitempty"` // Task branch of the primary repository.
+	State          string    `json:"state"`
+	Harness        Harness   `json:"harness"`
+	Model          string    `json:"model,omitempty"`
+	Owner          string    `json:"owner,omitempty"`
+	StartedAt      time.Time `json:"startedAt"`      // When the task was created.
+	StateUpdatedAt time.Time `json:"stateUpdatedAt"` // Last state change.
+	Duration       float64   `json:"duration"`       // Seconds.
+	NumTurns       int       `json:"numTurns"`
+	CostUSD        float64   `json:"costUSD"`
+	PRURL          string    `json:"prURL,omitempty"`
+	Timezone       string    `json:"timezone"`          // IANA time zone of the timestamps, from ?tz= or the user's settings.
+	Project        string    `json:"project,omitempty"` // Project of the primary repository, from the user's settings.
+}
+
+// TaskMessagesResp is the response for GET /api/v1/tasks/{id}/messages: a
+// page of the transcript for lazy-loading older history.
+type TaskMessagesResp struct {
+	Events []EventMessage `json:"events"`
+	Next   int            `json:"next"`  // Message index to pass as after for the following page.
+	Total  int            `json:"total"` // Number of messages in the task history.
+	// Annotations are the annotations of the messages in the page.
+	Annotations []Annotation `json:"annotations,omitempty"`
+}
+
+// MessageContentResp is the response for
+// GET /api/v1/tasks/{id}/messages/{index}/content: the full content of a
+// message whose events were truncated.
+type MessageContentResp struct {
+	Content  string `json:"content"`
+	Encoding string `json:"encoding,omitempty"` // "base64" when the content is not valid UTF-8.
+}
+
+// Annotation is a note or bookmark a reviewer attached to a message of a task
+// transcript, e.g. to mark where the agent went wrong.
+type Annotation struct {
+	ID        ksid.ID   `json:"id"`
+	Index     int       `json:"index"` // History index of the annotated message.
+	Note      string    `json:"note,omitempty"`
+	Bookmark  bool      `json:"bookmark,omitempty"`
+	Author    string    `json:"author"` // ID of the user who created it.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AnnotateMessageReq is the request for POST
+// /api/v1/tasks/{id}/messages/{index}/annotations.
+type AnnotateMessageReq struct {
+	Index    string `json:"-" path:"index"`
+	Note     string `json:"note,omitempty"`
+	Bookmark bool   `json:"bookmark,omitempty"`
+}
+
+// DeleteAnnotationReq is the request for POST
+// /api/v1/tasks/{id}/annotations/{annotationID}/delete.
+type DeleteAnnotationReq struct {
+	AnnotationID string `json:"-" path:"annotationID"`
+}
+
 // DiffResp is the response for GET /api/v1/tasks/{id}/diff.
 type DiffResp struct {
 	Diff string `json:"diff"`
+	// DiffStat is the stat computed along the diff; only set with ?live=true.
+	DiffStat DiffStat `json:"diffStat,omitempty"`
+	// Tree is the task's diff stat as a file tree; not set when the diff is
+	// limited to a path.
+	Tree *DiffDir `json:"tree,omitempty"`
+}
+
+// TaskCommit is a commit the agent made on the task branch.
+type TaskCommit struct {
+	SHA         string    `json:"sha"`
+	Author      string    `json:"author"`
+	Message     string    `json:"message"` // Full message, subject first.
+	AuthoredAt  time.Time `json:"authoredAt"`
+	CommittedAt time.Time `json:"committedAt"`
+	Files       DiffStat  `json:"files,omitempty"`
+}
+
+// RevertCommitReq is the request for POST
+// /api/v1/tasks/{id}/commits/{sha}/revert.
+type RevertCommitReq struct {
+	SHA string `json:"-" path:"sha"`
+}
+
+// RevertCommitResp is the response for POST
+// /api/v1/tasks/{id}/commits/{sha}/revert.
+type RevertCommitResp struct {
+	Revert string `json:"revert"` // Hash of the new revert commit.
+	// Notified is true when the agent session was told about the revert.
+	Notified bool `json:"notified"`
+}
+
+// TaskSnapshot is a named snapshot of a task's workspace, including the
+// uncommitted files.
+type TaskSnapshot struct {
+	Name    string    `json:"name"`
+	Commit  string    `json:"commit"` // Snapshot commit, whose parent is the branch head at the time.
+	Created time.Time `json:"created"`
+}
+
+// CreateSnapshotReq is the request for POST /api/v1/tasks/{id}/snapshots.
+type CreateSnapshotReq struct {
+	Name string `json:"name"`
+}
+
+// RestoreSnapshotReq is the request for POST
+// /api/v1/tasks/{id}/snapshots/{name}/restore.
+type RestoreSnapshotReq struct {
+	Name string `json:"-" path:"name"`
+}
+
+// DiffHunksResp is the response for GET /api/v1/tasks/{id}/diff/hunks.
+type DiffHunksResp struct {
+	Files []DiffFile `json:"files"`
+}
+
+// DiffFile is the parsed diff of one file.
+type DiffFile struct {
+	Path     string         `json:"path"`
+	OldPath  string         `json:"oldPath,omitempty"` // Source path of a rename.
+	Status   DiffFileStatus `json:"status"`
+	Language string         `json:"language,omitempty"` // e.g. "go" or "typescript"; empty when unknown.
+	Binary   bool           `json:"binary,omitempty"`
+	Hunks    []DiffHunk     `json:"hunks,omitempty"`
+}
+
+// DiffHunk is one "@@" section of a file diff.
+type DiffHunk struct {
+	OldStart int        `json:"oldStart"`
+	OldLines int        `json:"oldLines"`
+	NewStart int        `json:"newStart"`
+	NewLines int        `json:"newLines"`
+	Section  string     `json:"section,omitempty"` // Enclosing function or section git shows after the "@@".
+	Lines    []DiffLine `json:"lines"`
+}
+
+// DiffLineKind is the kind of a diff line.
+type DiffLineKind string
+
+// Diff line kinds.
+const (
+	DiffLineContext DiffLineKind = "context"
+	DiffLineAdded   DiffLineKind = "added"
+	DiffLineDeleted DiffLineKind = "deleted"
+)
+
+// DiffLine is a line of a hunk, without its +/-/space prefix.
+type DiffLine struct {
+	Kind    DiffLineKind `json:"kind"`
+	Text    string       `json:"text"`
+	OldLine int          `json:"oldLine,omitempty"` // 1-based; 0 for added lines.
+	NewLine int          `json:"newLine,omitempty"` // 1-based; 0 for deleted lines.
+	NoEOL   bool         `json:"noEOL,omitempty"`   // The file does not end with a newline after this line.
+	// Segments splits Text into changed and unchanged words when this line
+	// replaces, or is replaced by, a similar line. Only set with
+	// ?intraline=true.
+	Segments []DiffSegment `json:"segments,omitempty"`
+}
+
+// DiffSegment is a run of a DiffLine's text.
+type DiffSegment struct {
+	Text    string `json:"text"`
+	Changed bool   `json:"changed,omitempty"`
 }
 
 // RepoPrefsResp holds per-repository preferences.
@@ -396,6 +1199,55 @@ type RepoPrefsResp struct {
 	Model      string `json:"model,omitempty"`
 }
 
+// LintPromptReq is the request body for POST /api/v1/prompts/lint.
+type LintPromptReq struct {
+	Text   string `json:"text"`
+	Repo   string `json:"repo,omitempty"`   // Primary repo; enables file reference checks.
+	UseLLM bool   `json:"useLLM,omitempty"` // Also ask the configured LLM for suggestions.
+}
+
+// LintPromptResp is the response for POST /api/v1/prompts/lint.
+type LintPromptResp struct {
+	Suggestions []PromptSuggestion `json:"suggestions"`
+	LLM         bool               `json:"llm,omitempty"` // The LLM was consulted.
+}
+
+// PromptSuggestionKind identifies the issue found in a draft prompt.
+type PromptSuggestionKind string
+
+// Prompt suggestion kinds.
+const (
+	PromptVague           PromptSuggestionKind = "vague"
+	PromptLargePaste      PromptSuggestionKind = "large_paste"
+	PromptNoFileReference PromptSuggestionKind = "no_file_reference"
+	PromptUnknownFile     PromptSuggestionKind = "unknown_file"
+	PromptLLM             PromptSuggestionKind = "llm"
+)
+
+// SuggestionSeverity ranks a prompt suggestion.
+type SuggestionSeverity string
+
+// Suggestion severities.
+const (
+	SeverityInfo    SuggestionSeverity = "info"
+	SeverityWarning SuggestionSeverity = "warning"
+)
+
+// PromptSuggestion is one improvement proposed for a draft prompt.
+type PromptSuggestion struct {
+	Kind     PromptSuggestionKind `json:"kind"`
+	Severity SuggestionSeverity   `json:"severity"`
+	Message  string               `json:"message"`
+}
+
+// RecentPrompt is a previously used task prompt, returned by GET
+// /api/v1/prompts/recent.
+type RecentPrompt struct {
+	Repo     string    `json:"repo,omitempty"` // Empty for no-repo tasks.
+	Text     string    `json:"text"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
 // CacheMappingResp represents a directory mapping for cache/state sharing.
 type CacheMappingResp struct {
 	HostPath      string `json:"hostPath"`
@@ -411,9 +1263,26 @@ type UserSettings struct {
 	// AutoFixOnPROpen automatically creates a task to review and fix a pull
 	// request when it is opened or reopened via a forge webhook.
 	AutoFixOnPROpen bool `json:"autoFixOnPROpen"`
+	// AutoRespondToReviews hands review feedback left on the PR of a task
+	// back to its agent, or to a follow-up task on the PR branch when the
+	// agent can no longer receive input. Only effective with the GitHub App
+	// webhook.
+	AutoRespondToReviews bool `json:"autoRespondToReviews"`
 	// BaseImage overrides the default container base image. Empty means use
 	// the default.
 	BaseImage string `json:"baseImage,omitempty"`
+	// ValidateBaseImage smoke tests a changed BaseImage in a throwaway
+	// container before new tasks use it.
+	ValidateBaseImage bool `json:"validateBaseImage,omitempty"`
+	// PendingBaseImage is the changed BaseImage while it is validated or
+	// after it failed; BaseImage keeps the previous image meanwhile.
+	// Response only.
+	PendingBaseImage *PendingImage `json:"pendingBaseImage,omitempty"`
+	// PersistWorkspace saves the workspace of a task, uncommitted files
+	// included, before its container is stopped or purged, so that the task
+	// can be rehydrated into a fresh container with
+	// POST /api/v1/tasks/{id}/rehydrate.
+	PersistWorkspace bool `json:"persistWorkspace,omitempty"`
 	// GitHubTokenAccess controls the GitHub token injected into containers.
 	// "none" (default): no token. "read-write": passes the parent token.
 	GitHubTokenAccess string `json:"gitHubTokenAccess,omitempty"`
@@ -425,6 +1294,107 @@ type UserSettings struct {
 	WellKnownCaches map[string]bool `json:"wellKnownCaches,omitempty"`
 	// CacheMappings are custom host-to-container directory mappings.
 	CacheMappings []CacheMappingResp `json:"cacheMappings,omitempty"`
+	// ModelPrices overrides the built-in price table, keyed by model name
+	// prefix, e.g. for enterprise pricing.
+	ModelPrices map[string]ModelPrice `json:"modelPrices,omitempty"`
+	// Network is the default network mode of new tasks; empty means full.
+	Network NetworkMode `json:"network,omitempty"`
+	// NetworkAllow is the default host allowlist of new tasks.
+	NetworkAllow []string `json:"networkAllow,omitempty"`
+	// ToolPolicy are the rules applied to the tool calls of every task.
+	ToolPolicy []PolicyRule `json:"toolPolicy,omitempty"`
+	// RepoToolPolicies are additional rules keyed by repository path.
+	RepoToolPolicies map[string][]PolicyRule `json:"repoToolPolicies,omitempty"`
+	// IdlePolicy acts on tasks left waiting for input. Nil disables it.
+	IdlePolicy *IdlePolicy `json:"idlePolicy,omitempty"`
+	// RepoIdlePolicies override IdlePolicy, keyed by repository path.
+	RepoIdlePolicies map[string]IdlePolicy `json:"repoIdlePolicies,omitempty"`
+	// StallPolicy flags running tasks whose agent stays silent. Nil disables
+	// it.
+	StallPolicy *StallPolicy `json:"stallPolicy,omitempty"`
+	// Fallback is the harness and model a task switches to when its own fail
+	// to start. Nil disables the failover.
+	Fallback *Fallback `json:"fallback,omitempty"`
+	// RepoPushPolicies are the push policies keyed by repository path; the
+	// other repos use auto.
+	RepoPushPolicies map[string]PushPolicy `json:"repoPushPolicies,omitempty"`
+	// RepoPrePushChecks are local CI scripts keyed by repository path, e.g.
+	// "make test" or "act push". Each runs in the task's container before its
+	// branch is pushed; a failure blocks the push unless forced. The output is
+	// posted in the task's transcript.
+	RepoPrePushChecks map[string]string `json:"repoPrePushChecks,omitempty"`
+	// RepoInstructions are custom instructions appended to the system prompt
+	// of the agents of a repo's tasks, keyed by repository path, after the
+	// repo's own .caic/instructions.md.
+	RepoInstructions map[string]string `json:"repoInstructions,omitempty"`
+	// RepoProjects maps repository paths to the name of their project. The
+	// task list and the export filter by project with ?project=.
+	RepoProjects map[string]string `json:"repoProjects,omitempty"`
+	// ExecutionWindow holds new tasks in "pending" until it is open. Nil runs
+	// them immediately.
+	ExecutionWindow *ExecutionWindow `json:"executionWindow,omitempty"`
+	// NotifyChannels are the chat channels task notifications can be sent to.
+	NotifyChannels []NotifyChannel `json:"notifyChannels,omitempty"`
+	// NotifyRoutes maps a task state to the names of the channels notified
+	// when a task enters it, e.g. "waiting" to a direct message channel and
+	// "failed" to the team's channel. "stalled" routes the tasks flagged by
+	// the stall policy.
+	NotifyRoutes map[string][]string `json:"notifyRoutes,omitempty"`
+	// EnvAllowlist are the names of the environment variables tasks may set
+	// through CreateTaskReq.Env, or glob patterns like "FEATURE_*".
+	EnvAllowlist []string `json:"envAllowlist,omitempty"`
+	// Timezone is the IANA time zone, e.g. "Europe/Paris", the notifications
+	// and the exports render times in. Empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// GenericHarness configures the "generic" harness. Nil disables it.
+	GenericHarness *GenericHarness `json:"genericHarness,omitempty"`
+}
+
+// GenericHarness configures the "generic" harness, an agent loop against an
+// OpenAI-compatible chat completions API. The API key is write-only: it is
+// never returned, and an empty key keeps the saved one for the same URL.
+type GenericHarness struct {
+	BaseURL   string `json:"baseURL"`
+	APIKey    string `json:"apiKey,omitempty"`
+	HasAPIKey bool   `json:"hasAPIKey,omitempty"` // Response only.
+	Model     string `json:"model"`
+}
+
+// PolicyRuleKind is the kind of a tool policy rule.
+type PolicyRuleKind string
+
+// Tool policy rule kinds.
+const (
+	PolicyRuleNetwork PolicyRuleKind = "network" // Denies network access to hosts not in allow.
+	PolicyRuleCommand PolicyRuleKind = "command" // Denies shell commands matching pattern.
+	PolicyRulePath    PolicyRuleKind = "path"    // Denies writes outside the workspace and allow.
+)
+
+// PolicyRule is a tool policy rule. A tool call violating it pauses the task
+// until the user approves or denies it.
+type PolicyRule struct {
+	Name    string         `json:"name"`
+	Kind    PolicyRuleKind `json:"kind"`
+	Pattern string         `json:"pattern,omitempty"` // Regular expression for "command" rules.
+	Allow   []string       `json:"allow,omitempty"`   // Host globs for "network" rules, absolute directories for "path" rules.
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cacheWrite,omitempty"`
+	CacheRead  float64 `json:"cacheRead,omitempty"`
+	// CacheReadIncluded is set when the harness counts cache reads as part of
+	// the input tokens (OpenAI convention).
+	CacheReadIncluded bool `json:"cacheReadIncluded,omitempty"`
+}
+
+// PriceEntry is one row of the effective price table.
+type PriceEntry struct {
+	Model    string     `json:"model"` // Model name prefix.
+	Price    ModelPrice `json:"price"`
+	Override bool       `json:"override,omitempty"` // Set from the user's preferences.
 }
 
 // PreferencesResp is the response for GET /api/v1/server/preferences.
@@ -440,11 +1410,74 @@ type UpdatePreferencesReq struct {
 	Settings UserSettings `json:"settings"`
 }
 
+// UpdateTaskReq is the request body for PATCH /api/v1/tasks/{id}. Omitted
+// fields are left unchanged.
+type UpdateTaskReq struct {
+	Archived *bool `json:"archived,omitempty"`
+}
+
 // CloneRepoReq is the request body for POST /api/v1/server/repos.
 type CloneRepoReq struct {
 	URL   string `json:"url"`            // Git clone URL (HTTPS or SSH).
-	Path  string `json:"path,omitempty"` // Target subdirectory under rootDir; defaults to repo basename.
+	Path  string `json:"path,omitempty"` // Target subdirectory under rootDir, or "<root>:<subdirectory>" for another source root; defaults to repo basename.
 	Depth int    `json:"depth,omitempty"`
+	// Mirror creates a managed mirror instead: a full bare clone that caic
+	// fetches periodically. Path then defaults to the host and path of the URL,
+	// e.g. "github.com/org/repo".
+	Mirror bool `json:"mirror,omitempty"`
+}
+
+// RepoRemote is a git remote of a repository.
+type RepoRemote struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// RepoRemotesResp is the response for the /api/v1/server/repos/remotes
+// endpoints.
+type RepoRemotesResp struct {
+	Remotes    []RepoRemote `json:"remotes"`
+	PushRemote string       `json:"pushRemote"` // Remote task branches are pushed to by default.
+}
+
+// SetRepoRemoteReq is the request body for POST /api/v1/server/repos/remotes.
+type SetRepoRemoteReq struct {
+	Repo string `json:"repo"`
+	Name string `json:"name"`
+	// URL adds the remote or changes its URL; empty keeps an existing
+	// remote's.
+	URL string `json:"url,omitempty"`
+	// Push makes the remote the default push target of the task branches.
+	Push bool `json:"push,omitempty"`
+}
+
+// DeleteRepoRemoteReq is the request body for POST
+// /api/v1/server/repos/remotes/delete.
+type DeleteRepoRemoteReq struct {
+	Repo string `json:"repo"`
+	Name string `json:"name"`
+}
+
+// DeployKeyReq is the request body for POST /api/v1/server/repos/deploykey.
+type DeployKeyReq struct {
+	Repo string `json:"repo"`
+	// Rotate replaces the existing key; the new one must be added to the
+	// forge again.
+	Rotate bool `json:"rotate,omitempty"`
+}
+
+// DeployKeyResp is the response for POST /api/v1/server/repos/deploykey.
+type DeployKeyResp struct {
+	Repo        string `json:"repo"`
+	PublicKey   string `json:"publicKey"`             // OpenSSH authorized_keys line to add as a deploy key.
+	Fingerprint string `json:"fingerprint"`           // SHA256 fingerprint, as shown by the forge.
+	SettingsURL string `json:"settingsURL,omitempty"` // Forge page to add the key on; empty if the forge is unknown.
+}
+
+// DeleteDeployKeyReq is the request body for POST
+// /api/v1/server/repos/deploykey/delete.
+type DeleteDeployKeyReq struct {
+	Repo string `json:"repo"`
 }
 
 // WebFetchReq is the request body for POST /api/v1/web/fetch.
@@ -469,6 +1502,36 @@ type RepoBranchesResp struct {
 	Branches []BranchInfo `json:"branches"`
 }
 
+// RepoSearchResp is the response for GET /api/v1/server/repos/search.
+type RepoSearchResp struct {
+	Commit    string            `json:"commit"`              // Indexed commit of the base branch.
+	Paths     []string          `json:"paths"`               // File paths containing the query.
+	Matches   []RepoSearchMatch `json:"matches"`             // Lines containing the query.
+	Truncated bool              `json:"truncated,omitempty"` // The limit was reached.
+}
+
+// RepoSemanticSearchResp is the response for GET
+// /api/v1/server/repos/semantic-search.
+type RepoSemanticSearchResp struct {
+	Commit  string                 `json:"commit"` // Indexed commit of the base branch.
+	Results []SemanticSearchResult `json:"results"`
+}
+
+// SemanticSearchResult is a range of lines similar in meaning to the query.
+type SemanticSearchResult struct {
+	Path      string  `json:"path"`
+	StartLine int     `json:"startLine"` // 1-based.
+	EndLine   int     `json:"endLine"`   // Inclusive.
+	Score     float64 `json:"score"`     // Cosine similarity; higher is closer.
+}
+
+// RepoSearchMatch is a line of a file containing the search query.
+type RepoSearchMatch struct {
+	Path string `json:"path"`
+	Line int    `json:"line"` // 1-based.
+	Text string `json:"text"`
+}
+
 // WellKnownCache describes a single well-known cache.
 type WellKnownCache struct {
 	Name        string   `json:"name"`
@@ -482,6 +1545,33 @@ type WellKnownCachesResp struct {
 	WellKnown     []WellKnownCache `json:"wellKnown"`
 }
 
+// LogLevelReq is the request body for POST /api/v1/server/log-level.
+type LogLevelReq struct {
+	Level string `json:"level"` // "debug", "info", "warn" or "error".
+}
+
+// LogLevelResp is the response for GET and POST /api/v1/server/log-level.
+type LogLevelResp struct {
+	Level string `json:"level"`
+}
+
+// TaskView is a saved combination of task list filters.
+type TaskView struct {
+	Name    string `json:"name"`
+	Query   string `json:"query,omitempty"`   // Query parameters of GET /api/v1/tasks, e.g. "state=failed&createdWithin=168h".
+	Default bool   `json:"default,omitempty"` // The task list opens with this view; at most one view is the default.
+}
+
+// TaskViewsResp is the response of the /api/v1/views endpoints.
+type TaskViewsResp struct {
+	Views []TaskView `json:"views"`
+}
+
+// DeleteTaskViewReq is the request body for POST /api/v1/views/delete.
+type DeleteTaskViewReq struct {
+	Name string `json:"name"`
+}
+
 // VoiceRTCOfferReq is the request body for POST /api/v1/voice/rtc/offer.
 type VoiceRTCOfferReq struct {
 	SDP string `json:"sdp"`
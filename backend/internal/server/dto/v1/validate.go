@@ -2,51 +2,416 @@
 package v1
 
 import (
+	"errors"
+	"maps"
 	"net/url"
+	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/caic-xyz/caic/backend/internal/server/dto"
 )
 
+// Each Validate method records every invalid field in a dto.Validator rather
+// than stopping at the first one, so clients can highlight all of them at
+// once. Fields whose checks depend on each other report only their first
+// failure.
+
 // Validate checks that prompt or images are provided.
 func (r *InputReq) Validate() error {
-	if r.Prompt.Text == "" && len(r.Prompt.Images) == 0 {
-		return dto.BadRequest("prompt or images required")
-	}
-	return validateImages(r.Prompt.Images)
+	var v dto.Validator
+	validatePrompt(&v, &r.Prompt, "prompt")
+	return v.Err()
 }
 
 // Validate is a no-op; prompt is optional (read from container plan file if empty).
 func (r *RestartReq) Validate() error { return nil }
 
+// Validate checks that prompt or images are provided.
+func (r *RewindReq) Validate() error {
+	var v dto.Validator
+	validatePrompt(&v, &r.Prompt, "prompt")
+	return v.Err()
+}
+
+// validatePrompt checks that p has text or images and that the images are
+// valid. field is the JSON path of p.
+func validatePrompt(v *dto.Validator, p *Prompt, field string) {
+	if p.Text == "" && len(p.Images) == 0 {
+		v.Add(field, dto.RuleRequired, "prompt or images required")
+	}
+	validateImages(v, p.Images, field+".images")
+}
+
+// Validate checks the image reference.
+func (r *ValidateImageReq) Validate() error {
+	var v dto.Validator
+	if err := validateImageRef(r.Image); err != nil {
+		v.Add("image", dto.RuleInvalid, "image: "+err.Error())
+	}
+	return v.Err()
+}
+
+func (r *ImagePinReq) Validate() error {
+	var v dto.Validator
+	if err := validateImageRef(r.Image); err != nil {
+		v.Add("image", dto.RuleInvalid, "image: "+err.Error())
+	} else if strings.Contains(r.Image, "@") {
+		v.Add("image", dto.RuleInvalid, "image: already a digest reference")
+	}
+	return v.Err()
+}
+
+// validateImageRef rejects image references that the container runtime would
+// parse as a flag or that contain whitespace. Empty is valid.
+func validateImageRef(image string) error {
+	if strings.HasPrefix(image, "-") || strings.ContainsFunc(image, unicode.IsSpace) {
+		return errors.New("invalid image reference " + strconv.Quote(image))
+	}
+	return nil
+}
+
+// Validate checks the priority.
+func (r *SetPriorityReq) Validate() error {
+	var v dto.Validator
+	r.Priority.validate(&v)
+	return v.Err()
+}
+
+// validate checks that p is a supported priority.
+func (p TaskPriority) validate(v *dto.Validator) {
+	switch p {
+	case PriorityLow, PriorityNormal, PriorityHigh:
+	default:
+		v.Invalid("priority", string(p))
+	}
+}
+
 // Validate is a no-op; instructions are optional.
 func (r *CompactReq) Validate() error { return nil }
 
+// Validate checks that the log level is known.
+func (r *LogLevelReq) Validate() error {
+	var v dto.Validator
+	switch r.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		v.Add("level", dto.RuleInvalid, "invalid log level: "+r.Level)
+	}
+	return v.Err()
+}
+
 // Validate checks that the sync target is valid.
 func (r SyncReq) Validate() error {
+	var v dto.Validator
 	switch r.Target {
 	case "", SyncTargetBranch, SyncTargetDefault:
-		return nil
 	default:
-		return dto.BadRequest("invalid sync target: " + string(r.Target))
+		v.Add("target", dto.RuleInvalid, "invalid sync target: "+string(r.Target))
+	}
+	return v.Err()
+}
+
+// Validate is a no-op; force is the only field.
+func (r *PushReq) Validate() error {
+	return nil
+}
+
+// validatePushPolicy checks a push policy. name is the field name in errors.
+func validatePushPolicy(v *dto.Validator, p PushPolicy, name string) {
+	switch p {
+	case "", PushAuto, PushAsk, PushNever:
+	default:
+		v.Invalid(name, string(p))
 	}
 }
 
 // Validate checks that prompt and harness are valid. Repos is optional (empty
 // means no git repository is associated with the task).
 func (r *CreateTaskReq) Validate() error {
-	if r.InitialPrompt.Text == "" && len(r.InitialPrompt.Images) == 0 {
-		return dto.BadRequest("prompt or images required")
+	var v dto.Validator
+	if r.InitialPrompt.Text == "" && len(r.InitialPrompt.Images) == 0 && r.Issue == "" {
+		v.Add("initialPrompt", dto.RuleRequired, "prompt, images or issue required")
+	}
+	if r.Issue != "" && !issueKeyRe.MatchString(r.Issue) {
+		v.Add("issue", dto.RuleInvalid, "invalid issue key: "+r.Issue)
 	}
 	if r.Harness == "" {
-		return dto.BadRequest("harness is required")
+		v.Required("harness")
+	}
+	if r.PlanOnly && r.RequirePlan {
+		v.Add("requirePlan", dto.RuleConflict, "planOnly and requirePlan are mutually exclusive")
+	}
+	if r.Chat && len(r.Repos) > 1 {
+		v.Add("repos", dto.RuleConflict, "chat tasks support at most one repo")
+	}
+	if r.GatherContext && len(r.Repos) == 0 {
+		v.Add("gatherContext", dto.RuleConflict, "gatherContext requires a repo")
+	}
+	validateRepoSpecs(&v, r.Repos, "repos")
+	for i, id := range r.DependsOn {
+		field := "dependsOn[" + strconv.Itoa(i) + "]"
+		if id == "" {
+			v.Add(field, dto.RuleRequired, field+" is empty")
+		} else if slices.Contains(r.DependsOn[:i], id) {
+			v.Add(field, dto.RuleDuplicate, "dependsOn lists "+id+" twice")
+		}
+	}
+	if r.InheritBranch {
+		if len(r.DependsOn) == 0 {
+			v.Add("inheritBranch", dto.RuleConflict, "inheritBranch requires dependsOn")
+		}
+		if len(r.Repos) == 0 {
+			v.Add("inheritBranch", dto.RuleConflict, "inheritBranch requires a repo")
+		} else if r.Repos[0].BaseBranch != "" {
+			v.Add("repos[0].baseBranch", dto.RuleConflict, "inheritBranch and repos[0].baseBranch are mutually exclusive")
+		}
+	}
+	switch r.OnDependencyFailure {
+	case "", DependencyFailureCancel, DependencyFailureHold:
+	default:
+		v.Invalid("onDependencyFailure", string(r.OnDependencyFailure))
+	}
+	if r.Priority != "" {
+		r.Priority.validate(&v)
+	}
+	if r.Pipeline != nil {
+		if r.PlanOnly || r.RequirePlan || r.Chat {
+			v.Add("pipeline", dto.RuleConflict, "pipeline cannot be combined with planOnly, requirePlan or chat")
+		}
+		if len(r.Pipeline.Steps) > 1 && (r.ReadOnly || len(r.Repos) == 0) {
+			v.Add("pipeline", dto.RuleConflict, "steps after the first are stacked on the previous step's branch; pipeline requires a repo and no readOnly")
+		}
+		r.Pipeline.validate(&v, "pipeline")
+	}
+	if r.Review != nil {
+		if r.PlanOnly || r.ReadOnly || r.Chat || len(r.Repos) == 0 {
+			v.Add("review", dto.RuleConflict, "review requires a task that changes a repo")
+		}
+		if r.Review.Harness == "" {
+			v.Required("review.harness")
+		}
+		if r.Review.MaxRounds < 0 {
+			v.Add("review.maxRounds", dto.RuleRange, "review.maxRounds must be non-negative")
+		}
+	}
+	validateNetwork(&v, r.Network, r.NetworkAllow, "")
+	if len(r.NetworkAllow) != 0 && r.Network != NetworkAllowlist {
+		v.Add("networkAllow", dto.RuleConflict, "networkAllow requires the allowlist network mode")
+	}
+	if r.Tailscale && (r.Network == NetworkNone || r.Network == NetworkAllowlist) {
+		v.Add("tailscale", dto.RuleConflict, "tailscale requires full network access")
 	}
-	if err := validateRepoSpecs(r.Repos, "repos"); err != nil {
-		return err
+	if r.IdlePolicy != nil {
+		r.IdlePolicy.validate(&v, "idlePolicy")
+	}
+	validatePushPolicy(&v, r.PushPolicy, "pushPolicy")
+	validateEnv(&v, r.Env)
+	if len(r.Instructions) > MaxInstructions {
+		v.Add("instructions", dto.RuleTooLong, "instructions exceed "+strconv.Itoa(MaxInstructions>>10)+" KiB")
+	}
+	validateImages(&v, r.InitialPrompt.Images, "initialPrompt.images")
+	return v.Err()
+}
+
+// MaxInstructions is the maximum size of custom agent instructions. Claude
+// Code receives them as a single command line argument.
+const MaxInstructions = 32 << 10
+
+// envNameRe matches environment variable names.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnv are the environment variables the container and caic manage,
+// which tasks cannot override even when allowed.
+var reservedEnv = []string{"GITHUB_TOKEN", "HOME", "LD_LIBRARY_PATH", "LD_PRELOAD", "PATH", "SHELL", "USER"}
+
+// validateEnv checks the names and values of task environment variables.
+// Values are written to the container's ~/.env, one per line.
+func validateEnv(v *dto.Validator, env map[string]string) {
+	for _, k := range slices.Sorted(maps.Keys(env)) {
+		field := "env[" + k + "]"
+		switch {
+		case !envNameRe.MatchString(k):
+			v.Add(field, dto.RuleInvalid, "invalid env name: "+k)
+		case slices.Contains(reservedEnv, k):
+			v.Add(field, dto.RuleUnsupported, "env "+k+" is reserved")
+		case strings.ContainsAny(env[k], "\n\r\x00"):
+			v.Add(field, dto.RuleInvalid, "env "+k+" contains a newline or NUL")
+		}
 	}
-	return validateImages(r.InitialPrompt.Images)
+}
+
+// validate checks the hours and action of an idle policy. name is the field
+// name in errors.
+func (p *IdlePolicy) validate(v *dto.Validator, name string) {
+	if p.Hours < 0 {
+		v.Add(name+".hours", dto.RuleRange, name+".hours must be non-negative")
+	}
+	switch p.Action {
+	case IdleNotify, IdleFinish:
+	default:
+		v.Invalid(name+".action", string(p.Action))
+	}
+}
+
+// hostRe matches a DNS host name.
+var hostRe = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)+$`)
+
+// validateNetwork checks a network mode and its allowlist. prefix is
+// prepended to the field names in errors.
+func validateNetwork(v *dto.Validator, mode NetworkMode, allow []string, prefix string) {
+	switch mode {
+	case "", NetworkFull, NetworkNone, NetworkAllowlist:
+	default:
+		v.Invalid(prefix+"network", string(mode))
+	}
+	for i, h := range allow {
+		if !hostRe.MatchString(h) {
+			field := prefix + "networkAllow[" + strconv.Itoa(i) + "]"
+			v.Add(field, dto.RuleInvalid, field+" is not a host name: "+h)
+		}
+	}
+}
+
+// Validate checks that the pipeline has steps and every step has a prompt.
+func (p *Pipeline) Validate() error {
+	var v dto.Validator
+	p.validate(&v, "")
+	return v.Err()
+}
+
+// validate checks the pipeline. field is its JSON path, empty when the
+// pipeline is the request itself.
+func (p *Pipeline) validate(v *dto.Validator, field string) {
+	if field != "" {
+		field += "."
+	}
+	if len(p.Steps) == 0 {
+		v.Add(field+"steps", dto.RuleRequired, "pipeline has no steps")
+	}
+	for i := range p.Steps {
+		if strings.TrimSpace(p.Steps[i].Prompt) == "" {
+			v.Add(field+"steps["+strconv.Itoa(i)+"].prompt", dto.RuleRequired, "pipeline step "+strconv.Itoa(i)+" has no prompt")
+		}
+	}
+}
+
+// Validate checks that the suite has runnable cases and at least one target.
+func (r *CreateEvalReq) Validate() error {
+	var v dto.Validator
+	if len(r.Suite.Cases) == 0 {
+		v.Add("suite.cases", dto.RuleRequired, "suite has no cases")
+	}
+	for i := range r.Suite.Cases {
+		c := &r.Suite.Cases[i]
+		field := "suite.cases[" + strconv.Itoa(i) + "]"
+		if c.Name == "" {
+			v.Add(field+".name", dto.RuleRequired, "case "+strconv.Itoa(i)+" has no name")
+		} else if slices.ContainsFunc(r.Suite.Cases[:i], func(o EvalCase) bool { return o.Name == c.Name }) {
+			v.Add(field+".name", dto.RuleDuplicate, "case "+c.Name+" is listed twice")
+		}
+		if c.Repo == "" {
+			v.Add(field+".repo", dto.RuleRequired, "case "+c.Name+": repo is required")
+		}
+		if strings.TrimSpace(c.Prompt) == "" {
+			v.Add(field+".prompt", dto.RuleRequired, "case "+c.Name+": prompt is required")
+		}
+		if strings.TrimSpace(c.Verify) == "" {
+			v.Add(field+".verify", dto.RuleRequired, "case "+c.Name+": verify is required")
+		}
+	}
+	if len(r.Targets) == 0 {
+		v.Add("targets", dto.RuleRequired, "at least one target is required")
+	}
+	for i, tg := range r.Targets {
+		field := "targets[" + strconv.Itoa(i) + "]"
+		if tg.Harness == "" {
+			v.Add(field+".harness", dto.RuleRequired, field+": harness is required")
+		}
+		if slices.Contains(r.Targets[:i], tg) {
+			v.Add(field, dto.RuleDuplicate, "targets lists "+string(tg.Harness)+" "+tg.Model+" twice")
+		}
+	}
+	if r.Parallel < 0 {
+		v.Add("parallel", dto.RuleRange, "parallel must not be negative")
+	}
+	return v.Err()
+}
+
+// Validate checks that the eval ID is present.
+func (r *GetEvalReq) Validate() error {
+	var v dto.Validator
+	if r.ID == "" {
+		v.Required("id")
+	}
+	return v.Err()
+}
+
+// commitSHARe matches an abbreviated or full commit hash.
+var commitSHARe = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// Validate checks that the commit hash is well formed.
+func (r *RevertCommitReq) Validate() error {
+	var v dto.Validator
+	if !commitSHARe.MatchString(r.SHA) {
+		v.Add("sha", dto.RuleInvalid, "invalid commit sha")
+	}
+	return v.Err()
+}
+
+// snapshotNameRe matches valid snapshot names.
+var snapshotNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Validate checks the snapshot name.
+func (r *CreateSnapshotReq) Validate() error {
+	var v dto.Validator
+	if !snapshotNameRe.MatchString(r.Name) {
+		v.Add("name", dto.RuleInvalid, "name must be 1-64 letters, digits, '.', '_' or '-'")
+	}
+	return v.Err()
+}
+
+// Validate checks the snapshot name.
+func (r *RestoreSnapshotReq) Validate() error {
+	var v dto.Validator
+	if !snapshotNameRe.MatchString(r.Name) {
+		v.Add("name", dto.RuleInvalid, "invalid snapshot name")
+	}
+	return v.Err()
+}
+
+// maxAnnotationNote bounds the length of an annotation note.
+const maxAnnotationNote = 4096
+
+// Validate checks the message index and that the annotation carries a note
+// or a bookmark.
+func (r *AnnotateMessageReq) Validate() error {
+	var v dto.Validator
+	if i, err := strconv.Atoi(r.Index); err != nil || i < 0 {
+		v.Invalid("index", r.Index)
+	}
+	r.Note = strings.TrimSpace(r.Note)
+	if r.Note == "" && !r.Bookmark {
+		v.Add("note", dto.RuleRequired, "note or bookmark is required")
+	}
+	if len(r.Note) > maxAnnotationNote {
+		v.TooLong("note", maxAnnotationNote)
+	}
+	return v.Err()
+}
+
+// Validate checks that the annotation ID is present.
+func (r *DeleteAnnotationReq) Validate() error {
+	var v dto.Validator
+	if r.AnnotationID == "" {
+		v.Add("annotationID", dto.RuleRequired, "annotation id is required")
+	}
+	return v.Err()
 }
 
 // allowedImageTypes is the set of MIME types accepted for image uploads.
@@ -60,121 +425,374 @@ var allowedImageTypes = map[string]bool{
 // pathSegmentRe matches valid path segments: starts with alphanumeric, then alphanumeric, dots, hyphens, or underscores.
 var pathSegmentRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
 
+// remoteNameRe matches the names of the remotes managed through the API.
+var remoteNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// issueKeyRe matches Jira and Linear issue keys, e.g. "PROJ-123".
+var issueKeyRe = regexp.MustCompile(`^[A-Z][A-Z0-9]{1,9}-[1-9][0-9]{0,6}$`)
+
+// validRemoteName reports whether name is a valid remote name; "md-" is
+// reserved for the remotes of the containers.
+func validRemoteName(name string) bool {
+	return remoteNameRe.MatchString(name) && !strings.HasPrefix(name, "md-")
+}
+
+func (r *SetRepoRemoteReq) Validate() error {
+	var v dto.Validator
+	if r.Repo == "" {
+		v.Required("repo")
+	}
+	if !validRemoteName(r.Name) {
+		v.Invalid("name", r.Name)
+	}
+	if strings.HasPrefix(r.URL, "-") || strings.ContainsAny(r.URL, " \t\n") {
+		v.Add("url", dto.RuleInvalid, "invalid url")
+	} else if r.URL == "" && !r.Push {
+		v.Add("url", dto.RuleRequired, "url or push is required")
+	}
+	return v.Err()
+}
+
+func (r *DeleteRepoRemoteReq) Validate() error {
+	var v dto.Validator
+	if r.Repo == "" {
+		v.Required("repo")
+	}
+	if r.Name == "origin" {
+		v.Add("name", dto.RuleUnsupported, "origin cannot be removed")
+	} else if !validRemoteName(r.Name) {
+		v.Invalid("name", r.Name)
+	}
+	return v.Err()
+}
+
+// maxProjectName is the maximum length of a project name.
+const maxProjectName = 64
+
+// maxTaskViewName and maxTaskViewQuery bound the fields of a TaskView.
+const (
+	maxTaskViewName  = 64
+	maxTaskViewQuery = 1024
+)
+
+func (r *TaskView) Validate() error {
+	var v dto.Validator
+	if strings.TrimSpace(r.Name) == "" {
+		v.Required("name")
+	} else if len(r.Name) > maxTaskViewName {
+		v.TooLong("name", maxTaskViewName)
+	}
+	if len(r.Query) > maxTaskViewQuery {
+		v.TooLong("query", maxTaskViewQuery)
+	} else if _, err := url.ParseQuery(r.Query); err != nil {
+		v.Invalid("query", r.Query)
+	}
+	return v.Err()
+}
+
+func (r *DeleteTaskViewReq) Validate() error {
+	if r.Name == "" {
+		return dto.Required("name")
+	}
+	return nil
+}
+
+func (r *DeployKeyReq) Validate() error {
+	var v dto.Validator
+	if r.Repo == "" {
+		v.Required("repo")
+	}
+	return v.Err()
+}
+
+func (r *DeleteDeployKeyReq) Validate() error {
+	var v dto.Validator
+	if r.Repo == "" {
+		v.Required("repo")
+	}
+	return v.Err()
+}
+
 // Validate checks that the clone URL is provided and the optional path is safe.
 func (r *CloneRepoReq) Validate() error {
+	var v dto.Validator
 	if r.URL == "" {
-		return dto.BadRequest("url is required")
+		v.Required("url")
 	}
 	if r.Depth < 0 {
-		return dto.BadRequest("depth must be non-negative")
+		v.Add("depth", dto.RuleRange, "depth must be non-negative")
+	} else if r.Mirror && r.Depth != 0 {
+		v.Add("depth", dto.RuleConflict, "depth does not apply to mirrors, which are full clones")
 	}
 	if r.Path != "" {
-		if filepath.IsAbs(r.Path) {
-			return dto.BadRequest("path must be relative")
-		}
-		cleaned := filepath.Clean(r.Path)
-		if cleaned != r.Path {
-			return dto.BadRequest("path must be clean (use filepath.Clean form)")
-		}
-		if strings.Contains(cleaned, "..") {
-			return dto.BadRequest("path must not contain '..' segments")
+		if msg := validateRepoPath(r.Path); msg != "" {
+			v.Add("path", dto.RuleInvalid, msg)
 		}
-		if len(r.Path) > 255 {
-			return dto.BadRequest("path too long (max 255 characters)")
-		}
-		segments := strings.Split(cleaned, string(filepath.Separator))
-		if len(segments) > 3 {
-			return dto.BadRequest("path too deep (max 3 segments)")
-		}
-		for _, seg := range segments {
-			if !pathSegmentRe.MatchString(seg) {
-				return dto.BadRequest("path segment contains invalid characters: " + seg)
-			}
+	}
+	return v.Err()
+}
+
+// validateRepoPath returns why p is not a safe relative repo path, or "".
+func validateRepoPath(p string) string {
+	if filepath.IsAbs(p) {
+		return "path must be relative"
+	}
+	cleaned := filepath.Clean(p)
+	if cleaned != p {
+		return "path must be clean (use filepath.Clean form)"
+	}
+	if strings.Contains(cleaned, "..") {
+		return "path must not contain '..' segments"
+	}
+	if len(p) > 255 {
+		return "path too long (max 255 characters)"
+	}
+	segments := strings.Split(cleaned, string(filepath.Separator))
+	if len(segments) > 3 {
+		return "path too deep (max 3 segments)"
+	}
+	for _, seg := range segments {
+		if !pathSegmentRe.MatchString(seg) {
+			return "path segment contains invalid characters: " + seg
 		}
 	}
-	return nil
+	return ""
 }
 
 // Validate checks that the URL is non-empty and has an http or https scheme.
 func (r *WebFetchReq) Validate() error {
+	var v dto.Validator
 	if r.URL == "" {
-		return dto.BadRequest("url is required")
-	}
-	u, err := url.Parse(r.URL)
-	if err != nil {
-		return dto.BadRequest("invalid url")
-	}
-	if u.Scheme != "http" && u.Scheme != "https" {
-		return dto.BadRequest("url must have http or https scheme")
+		v.Required("url")
+	} else if u, err := url.Parse(r.URL); err != nil {
+		v.Add("url", dto.RuleInvalid, "invalid url")
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		v.Add("url", dto.RuleUnsupported, "url must have http or https scheme")
 	}
-	return nil
+	return v.Err()
 }
 
 // Validate checks that the repo field is provided.
 func (r *BotFixCIReq) Validate() error {
+	var v dto.Validator
 	if r.Repo == "" {
-		return dto.BadRequest("repo is required")
+		v.Required("repo")
 	}
-	return nil
+	return v.Err()
 }
 
 // Validate checks that the taskId field is provided.
 func (r *BotFixPRReq) Validate() error {
+	var v dto.Validator
 	if r.TaskID == "" {
-		return dto.BadRequest("taskId is required")
+		v.Required("taskId")
 	}
-	return nil
+	return v.Err()
 }
 
 // Validate checks that a prompt is provided, images are valid, and extra repos have no duplicates.
 func (r *ForkTaskReq) Validate() error {
-	if r.Prompt.Text == "" && len(r.Prompt.Images) == 0 {
-		return dto.BadRequest("prompt or images required")
+	var v dto.Validator
+	validatePrompt(&v, &r.Prompt, "prompt")
+	validateRepoSpecs(&v, r.ExtraRepos, "extraRepos")
+	return v.Err()
+}
+
+// Validate checks that images are valid; the plan text is optional.
+func (r *PromoteTaskReq) Validate() error {
+	var v dto.Validator
+	validateImages(&v, r.Prompt.Images, "prompt.images")
+	return v.Err()
+}
+
+// Validate rejects images; the approved plan is text only.
+func (r *ApprovePlanReq) Validate() error {
+	var v dto.Validator
+	if len(r.Prompt.Images) > 0 {
+		v.Add("prompt.images", dto.RuleUnsupported, "images are not supported when approving a plan")
+	}
+	return v.Err()
+}
+
+// Validate checks that model price overrides are named and non-negative.
+func (r *UpdatePreferencesReq) Validate() error {
+	var v dto.Validator
+	s := &r.Settings
+	if err := validateImageRef(s.BaseImage); err != nil {
+		v.Add("settings.baseImage", dto.RuleInvalid, "settings.baseImage: "+err.Error())
+	}
+	for _, model := range slices.Sorted(maps.Keys(s.ModelPrices)) {
+		p := s.ModelPrices[model]
+		if model == "" {
+			v.Add("settings.modelPrices", dto.RuleRequired, "modelPrices contains an empty model")
+		} else if p.Input < 0 || p.Output < 0 || p.CacheWrite < 0 || p.CacheRead < 0 {
+			v.Add("settings.modelPrices["+model+"]", dto.RuleRange, "modelPrices["+model+"] has a negative price")
+		}
+	}
+	if g := s.GenericHarness; g != nil {
+		if u, err := url.Parse(g.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.Add("settings.genericHarness.baseURL", dto.RuleInvalid, "settings.genericHarness.baseURL must be an http or https URL")
+		}
+		if g.Model == "" {
+			v.Required("settings.genericHarness.model")
+		}
+	}
+	if p := s.IdlePolicy; p != nil {
+		p.validate(&v, "settings.idlePolicy")
+	}
+	for _, repo := range slices.Sorted(maps.Keys(s.RepoIdlePolicies)) {
+		p := s.RepoIdlePolicies[repo]
+		p.validate(&v, "settings.repoIdlePolicies["+repo+"]")
+	}
+	if p := s.StallPolicy; p != nil && p.Minutes < 0 {
+		v.Add("settings.stallPolicy.minutes", dto.RuleRange, "settings.stallPolicy.minutes must be non-negative")
+	}
+	if f := s.Fallback; f != nil && f.Harness == "" && f.Model == "" {
+		v.Add("settings.fallback", dto.RuleRequired, "settings.fallback needs a harness or a model")
+	}
+	for _, repo := range slices.Sorted(maps.Keys(s.RepoPushPolicies)) {
+		field := "settings.repoPushPolicies[" + repo + "]"
+		if p := s.RepoPushPolicies[repo]; p == "" {
+			v.Add(field, dto.RuleRequired, field+" is empty")
+		} else {
+			validatePushPolicy(&v, p, field)
+		}
+	}
+	for _, repo := range slices.Sorted(maps.Keys(s.RepoPrePushChecks)) {
+		if field := "settings.repoPrePushChecks[" + repo + "]"; strings.TrimSpace(s.RepoPrePushChecks[repo]) == "" {
+			v.Add(field, dto.RuleRequired, field+" is empty")
+		}
+	}
+	for _, repo := range slices.Sorted(maps.Keys(s.RepoInstructions)) {
+		field := "settings.repoInstructions[" + repo + "]"
+		if text := s.RepoInstructions[repo]; strings.TrimSpace(text) == "" {
+			v.Add(field, dto.RuleRequired, field+" is empty")
+		} else if len(text) > MaxInstructions {
+			v.Add(field, dto.RuleTooLong, field+" exceeds "+strconv.Itoa(MaxInstructions>>10)+" KiB")
+		}
+	}
+	for _, repo := range slices.Sorted(maps.Keys(s.RepoProjects)) {
+		field := "settings.repoProjects[" + repo + "]"
+		if project := s.RepoProjects[repo]; strings.TrimSpace(project) == "" {
+			v.Add(field, dto.RuleRequired, field+" is empty")
+		} else if len(project) > maxProjectName {
+			v.TooLong(field, maxProjectName)
+		}
+	}
+	if w := s.ExecutionWindow; w != nil {
+		for _, c := range []struct{ field, value string }{{"start", w.Start}, {"end", w.End}} {
+			if _, err := time.Parse("15:04", c.value); err != nil {
+				v.Add("settings.executionWindow."+c.field, dto.RuleInvalid, "settings.executionWindow: invalid clock time "+c.value)
+			}
+		}
+		if w.Start == w.End {
+			v.Add("settings.executionWindow.end", dto.RuleConflict, "settings.executionWindow: start and end are equal")
+		}
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			v.Add("settings.executionWindow.timezone", dto.RuleInvalid, "settings.executionWindow: unknown timezone "+w.Timezone)
+		}
+	}
+	names := make(map[string]bool, len(s.NotifyChannels))
+	for i, c := range s.NotifyChannels {
+		field := "settings.notifyChannels[" + strconv.Itoa(i) + "]"
+		if c.Name == "" {
+			v.Add(field+".name", dto.RuleRequired, "settings.notifyChannels contains an entry with an empty name")
+		} else if names[c.Name] {
+			v.Add(field+".name", dto.RuleDuplicate, "settings.notifyChannels contains duplicate name: "+c.Name)
+		}
+		names[c.Name] = true
+		switch c.Kind {
+		case NotifySlack, NotifyDiscord, NotifyMatrix, NotifyTelegram:
+		default:
+			v.Add(field+".kind", dto.RuleInvalid, "settings.notifyChannels["+c.Name+"]: invalid kind "+string(c.Kind))
+		}
+	}
+	for i, p := range s.EnvAllowlist {
+		if _, err := path.Match(p, ""); err != nil || p == "" {
+			v.Add("settings.envAllowlist["+strconv.Itoa(i)+"]", dto.RuleInvalid, "settings.envAllowlist: invalid pattern "+p)
+		}
+	}
+	if tz := s.Timezone; tz != "" {
+		if _, err := time.LoadLocation(tz); err != nil {
+			v.Add("settings.timezone", dto.RuleInvalid, "settings.timezone: unknown timezone "+tz)
+		}
+	}
+	for _, state := range slices.Sorted(maps.Keys(s.NotifyRoutes)) {
+		for _, name := range s.NotifyRoutes[state] {
+			if !names[name] {
+				v.Add("settings.notifyRoutes["+state+"]", dto.RuleInvalid, "settings.notifyRoutes["+state+"]: unknown channel "+name)
+			}
+		}
 	}
-	if err := validateRepoSpecs(r.ExtraRepos, "extraRepos"); err != nil {
-		return err
+	validateNetwork(&v, s.Network, s.NetworkAllow, "settings.")
+	return v.Err()
+}
+
+// Validate checks that the prompt text is provided.
+func (r *LintPromptReq) Validate() error {
+	var v dto.Validator
+	if strings.TrimSpace(r.Text) == "" {
+		v.Required("text")
 	}
-	return validateImages(r.Prompt.Images)
+	return v.Err()
 }
 
-// Validate is a no-op; all settings values are accepted.
-func (r *UpdatePreferencesReq) Validate() error { return nil }
+// Validate checks that a prompt is provided.
+func (r *QuickCreateTaskReq) Validate() error {
+	var v dto.Validator
+	if r.InitialPrompt.Text == "" && len(r.InitialPrompt.Images) == 0 {
+		v.Add("initialPrompt", dto.RuleRequired, "prompt or images required")
+	}
+	return v.Err()
+}
+
+// Validate checks that at least one field is updated.
+func (r *UpdateTaskReq) Validate() error {
+	var v dto.Validator
+	if r.Archived == nil {
+		v.Add("archived", dto.RuleRequired, "nothing to update")
+	}
+	return v.Err()
+}
 
 // Validate checks that the SDP offer is provided.
 func (r *VoiceRTCOfferReq) Validate() error {
+	var v dto.Validator
 	if r.SDP == "" {
-		return dto.BadRequest("sdp is required")
+		v.Required("sdp")
 	}
-	return nil
+	return v.Err()
 }
 
 // validateRepoSpecs checks that each RepoSpec has a non-empty name and no duplicates.
-func validateRepoSpecs(specs []RepoSpec, field string) error {
+func validateRepoSpecs(v *dto.Validator, specs []RepoSpec, field string) {
 	seen := make(map[string]struct{}, len(specs))
-	for _, rs := range specs {
+	for i, rs := range specs {
+		item := field + "[" + strconv.Itoa(i) + "]"
 		if rs.Name == "" {
-			return dto.BadRequest(field + " contains entry with empty name")
-		}
-		if _, dup := seen[rs.Name]; dup {
-			return dto.BadRequest(field + " contains duplicate name: " + rs.Name)
+			v.Add(item+".name", dto.RuleRequired, field+" contains entry with empty name")
+		} else if _, dup := seen[rs.Name]; dup {
+			v.Add(item+".name", dto.RuleDuplicate, field+" contains duplicate name: "+rs.Name)
 		}
 		seen[rs.Name] = struct{}{}
+		if rs.PushRemote != "" && !validRemoteName(rs.PushRemote) {
+			v.Add(item+".pushRemote", dto.RuleInvalid, "invalid "+field+" pushRemote: "+rs.PushRemote)
+		}
 	}
-	return nil
 }
 
-// validateImages checks that each ImageData entry has a valid media type and non-empty data.
-func validateImages(images []ImageData) error {
-	for _, img := range images {
+// validateImages checks that each ImageData entry has a valid media type and
+// non-empty data. field is the JSON path of images.
+func validateImages(v *dto.Validator, images []ImageData, field string) {
+	for i, img := range images {
+		item := field + "[" + strconv.Itoa(i) + "]"
 		if img.MediaType == "" {
-			return dto.BadRequest("image mediaType is required")
-		}
-		if !allowedImageTypes[img.MediaType] {
-			return dto.BadRequest("unsupported image mediaType: " + img.MediaType)
+			v.Add(item+".mediaType", dto.RuleRequired, "image mediaType is required")
+		} else if !allowedImageTypes[img.MediaType] {
+			v.Add(item+".mediaType", dto.RuleUnsupported, "unsupported image mediaType: "+img.MediaType)
 		}
 		if img.Data == "" {
-			return dto.BadRequest("image data is required")
+			v.Add(item+".data", dto.RuleRequired, "image data is required")
 		}
 	}
-	return nil
 }
@@ -3,6 +3,7 @@ package v1
 import (
 	"errors"
 	"net/http"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -59,6 +60,27 @@ func TestValidate(t *testing.T) {
 		})
 	})
 
+	t.Run("AnnotateMessageReq", func(t *testing.T) {
+		t.Run("Valid", func(t *testing.T) {
+			r := &AnnotateMessageReq{Index: "3", Note: "  went wrong here "}
+			if err := r.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r.Note != "went wrong here" {
+				t.Errorf("note = %q, want it trimmed", r.Note)
+			}
+			if err := (&AnnotateMessageReq{Index: "0", Bookmark: true}).Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("Invalid", func(t *testing.T) {
+			assertBadRequest(t, (&AnnotateMessageReq{Index: "-1", Bookmark: true}).Validate(), "invalid index: -1")
+			assertBadRequest(t, (&AnnotateMessageReq{Index: "x", Bookmark: true}).Validate(), "invalid index: x")
+			assertBadRequest(t, (&AnnotateMessageReq{Index: "1", Note: " "}).Validate(), "note or bookmark is required")
+			assertBadRequest(t, (&AnnotateMessageReq{Index: "1", Note: strings.Repeat("x", maxAnnotationNote+1)}).Validate(), "note is too long")
+		})
+	})
+
 	t.Run("SyncReq", func(t *testing.T) {
 		t.Run("Empty", func(t *testing.T) {
 			if err := (SyncReq{}).Validate(); err != nil {
@@ -80,6 +102,98 @@ func TestValidate(t *testing.T) {
 		})
 	})
 
+	t.Run("SetPriorityReq", func(t *testing.T) {
+		t.Run("Valid", func(t *testing.T) {
+			if err := (&SetPriorityReq{Priority: PriorityHigh}).Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("Empty", func(t *testing.T) {
+			assertBadRequest(t, (&SetPriorityReq{}).Validate(), "invalid priority: ")
+		})
+		t.Run("Invalid", func(t *testing.T) {
+			assertBadRequest(t, (&SetPriorityReq{Priority: "urgent"}).Validate(), "invalid priority: urgent")
+		})
+	})
+
+	t.Run("ValidateImageReq", func(t *testing.T) {
+		t.Run("Valid", func(t *testing.T) {
+			if err := (&ValidateImageReq{Image: "ghcr.io/acme/dev:2"}).Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+		t.Run("Flag", func(t *testing.T) {
+			assertBadRequest(t, (&ValidateImageReq{Image: "--privileged"}).Validate(), `image: invalid image reference "--privileged"`)
+		})
+	})
+
+	t.Run("DeployKeyReq", func(t *testing.T) {
+		assertBadRequest(t, (&DeployKeyReq{Rotate: true}).Validate(), "repo is required")
+		assertBadRequest(t, (&DeleteDeployKeyReq{}).Validate(), "repo is required")
+		var apiErr *dto.APIError
+		if errors.As((&DeleteDeployKeyReq{}).Validate(), &apiErr); apiErr.Params()["field"] != "repo" {
+			t.Errorf("params = %v, want field=repo", apiErr.Params())
+		}
+		if err := (&DeployKeyReq{Repo: "org/repo"}).Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("PushPolicy", func(t *testing.T) {
+		r := &CreateTaskReq{InitialPrompt: Prompt{Text: "x"}, Harness: "claude", PushPolicy: "sometimes"}
+		assertBadRequest(t, r.Validate(), "invalid pushPolicy: sometimes")
+		r.PushPolicy = PushAsk
+		if err := r.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		u := &UpdatePreferencesReq{Settings: UserSettings{RepoPushPolicies: map[string]PushPolicy{"r": ""}}}
+		assertBadRequest(t, u.Validate(), "settings.repoPushPolicies[r] is empty")
+	})
+	t.Run("RepoPrePushChecks", func(t *testing.T) {
+		u := &UpdatePreferencesReq{Settings: UserSettings{RepoPrePushChecks: map[string]string{"r": " "}}}
+		assertBadRequest(t, u.Validate(), "settings.repoPrePushChecks[r] is empty")
+	})
+	t.Run("Env", func(t *testing.T) {
+		r := &CreateTaskReq{InitialPrompt: Prompt{Text: "x"}, Harness: "claude", Env: map[string]string{"1X": "y"}}
+		assertBadRequest(t, r.Validate(), "invalid env name: 1X")
+		r.Env = map[string]string{"PATH": "/tmp"}
+		assertBadRequest(t, r.Validate(), "env PATH is reserved")
+		r.Env = map[string]string{"API_URL": "a\nb"}
+		assertBadRequest(t, r.Validate(), "env API_URL contains a newline or NUL")
+		r.Env = map[string]string{"API_URL": "https://staging.example.com"}
+		if err := r.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		u := &UpdatePreferencesReq{Settings: UserSettings{EnvAllowlist: []string{"FEATURE_["}}}
+		assertBadRequest(t, u.Validate(), "settings.envAllowlist: invalid pattern FEATURE_[")
+	})
+	t.Run("Instructions", func(t *testing.T) {
+		r := &CreateTaskReq{InitialPrompt: Prompt{Text: "x"}, Harness: "claude", Instructions: strings.Repeat("x", MaxInstructions+1)}
+		assertBadRequest(t, r.Validate(), "instructions exceed 32 KiB")
+		u := &UpdatePreferencesReq{Settings: UserSettings{RepoInstructions: map[string]string{"org/repo": " "}}}
+		assertBadRequest(t, u.Validate(), "settings.repoInstructions[org/repo] is empty")
+	})
+	t.Run("NotifyChannels", func(t *testing.T) {
+		u := &UpdatePreferencesReq{Settings: UserSettings{NotifyChannels: []NotifyChannel{{Name: "me", Kind: "irc"}}}}
+		assertBadRequest(t, u.Validate(), "settings.notifyChannels[me]: invalid kind irc")
+		u.Settings.NotifyChannels[0].Kind = NotifyTelegram
+		u.Settings.NotifyRoutes = map[string][]string{"failed": {"team"}}
+		assertBadRequest(t, u.Validate(), "settings.notifyRoutes[failed]: unknown channel team")
+		u.Settings.NotifyRoutes = map[string][]string{"waiting": {"me"}}
+		if err := u.Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("RepoRemoteReq", func(t *testing.T) {
+		assertBadRequest(t, (&SetRepoRemoteReq{Name: "fork", URL: "u"}).Validate(), "repo is required")
+		assertBadRequest(t, (&SetRepoRemoteReq{Repo: "r", Name: "md-x", URL: "u"}).Validate(), "invalid name: md-x")
+		assertBadRequest(t, (&SetRepoRemoteReq{Repo: "r", Name: "fork", URL: "--upload-pack=x"}).Validate(), "invalid url")
+		assertBadRequest(t, (&SetRepoRemoteReq{Repo: "r", Name: "fork"}).Validate(), "url or push is required")
+		assertBadRequest(t, (&DeleteRepoRemoteReq{Repo: "r", Name: "origin"}).Validate(), "origin cannot be removed")
+		if err := (&SetRepoRemoteReq{Repo: "r", Name: "origin", Push: true}).Validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertBadRequest(t, (&ForkTaskReq{Prompt: Prompt{Text: "x"}, ExtraRepos: []RepoSpec{{Name: "r", PushRemote: "a b"}}}).Validate(), "invalid extraRepos pushRemote: a b")
+	})
 	t.Run("CloneRepoReq", func(t *testing.T) {
 		t.Run("Valid_URLOnly", func(t *testing.T) {
 			r := &CloneRepoReq{URL: "https://github.com/org/repo.git"}
@@ -100,6 +214,10 @@ func TestValidate(t *testing.T) {
 			r := &CloneRepoReq{URL: "https://example.com/repo.git", Depth: -1}
 			assertBadRequest(t, r.Validate(), "depth must be non-negative")
 		})
+		t.Run("MirrorDepth", func(t *testing.T) {
+			r := &CloneRepoReq{URL: "https://example.com/repo.git", Mirror: true, Depth: 1}
+			assertBadRequest(t, r.Validate(), "depth does not apply to mirrors, which are full clones")
+		})
 		t.Run("PathWithDotDot", func(t *testing.T) {
 			r := &CloneRepoReq{URL: "https://example.com/repo.git", Path: "foo/../bar"}
 			assertBadRequest(t, r.Validate(), "path must be clean (use filepath.Clean form)")
@@ -149,7 +267,46 @@ func TestValidate(t *testing.T) {
 		t.Run("MissingPrompt", func(t *testing.T) {
 			r := valid
 			r.InitialPrompt = Prompt{}
-			assertBadRequest(t, r.Validate(), "prompt or images required")
+			assertBadRequest(t, r.Validate(), "prompt, images or issue required")
+		})
+		t.Run("Issue", func(t *testing.T) {
+			r := valid
+			r.InitialPrompt, r.Issue = Prompt{}, "ENG-123"
+			if err := r.Validate(); err != nil {
+				t.Errorf("issue without prompt: %v", err)
+			}
+			r.Issue = "eng-123"
+			assertBadRequest(t, r.Validate(), "invalid issue key: eng-123")
+		})
+		t.Run("Network", func(t *testing.T) {
+			r := valid
+			r.Network, r.NetworkAllow = NetworkAllowlist, []string{"registry.npmjs.org", "GitHub.com"}
+			if err := r.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r.NetworkAllow = []string{"https://x.com"}
+			assertBadRequest(t, r.Validate(), "networkAllow[0] is not a host name: https://x.com")
+			r.Network, r.NetworkAllow = NetworkNone, []string{"x.com"}
+			assertBadRequest(t, r.Validate(), "networkAllow requires the allowlist network mode")
+			r.Network, r.NetworkAllow, r.Tailscale = NetworkNone, nil, true
+			assertBadRequest(t, r.Validate(), "tailscale requires full network access")
+			r.Network = "lan"
+			assertBadRequest(t, r.Validate(), "invalid network: lan")
+		})
+		t.Run("Pipeline", func(t *testing.T) {
+			r := valid
+			r.Pipeline = &Pipeline{Name: "p", Steps: []PipelineStep{{Prompt: "a"}, {Prompt: "b"}}}
+			if err := r.Validate(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			r.ReadOnly = true
+			assertBadRequest(t, r.Validate(), "steps after the first are stacked on the previous step's branch; pipeline requires a repo and no readOnly")
+			r.ReadOnly, r.Repos = false, nil
+			assertBadRequest(t, r.Validate(), "steps after the first are stacked on the previous step's branch; pipeline requires a repo and no readOnly")
+			r.Pipeline.Steps = r.Pipeline.Steps[:1]
+			if err := r.Validate(); err != nil {
+				t.Errorf("single step without repo: %v", err)
+			}
 		})
 		t.Run("EmptyRepoName", func(t *testing.T) {
 			r := CreateTaskReq{
@@ -172,6 +329,22 @@ func TestValidate(t *testing.T) {
 			r.Harness = ""
 			assertBadRequest(t, r.Validate(), "harness is required")
 		})
+		t.Run("AllFields", func(t *testing.T) {
+			r := valid
+			r.InitialPrompt, r.Harness, r.Network = Prompt{}, "", "lan"
+			err := r.Validate()
+			assertBadRequest(t, err, "prompt, images or issue required; harness is required; invalid network: lan")
+			var apiErr *dto.APIError
+			errors.As(err, &apiErr)
+			want := []dto.FieldError{
+				{Field: "initialPrompt", Rule: dto.RuleRequired, Message: "prompt, images or issue required"},
+				{Field: "harness", Rule: dto.RuleRequired, Message: "harness is required", Params: map[string]string{"field": "harness"}},
+				{Field: "network", Rule: dto.RuleInvalid, Message: "invalid network: lan", Params: map[string]string{"field": "network", "value": "lan"}},
+			}
+			if got := apiErr.Fields(); !reflect.DeepEqual(got, want) {
+				t.Errorf("fields = %+v\nwant %+v", got, want)
+			}
+		})
 	})
 }
 
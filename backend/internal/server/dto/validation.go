@@ -0,0 +1,88 @@
+// Field-level validation errors accumulated across all the fields of a request.
+package dto
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FieldError describes one invalid field of a request.
+type FieldError struct {
+	Field   string            `json:"field"`            // JSON path of the field, e.g. "repos[1].name".
+	Rule    string            `json:"rule"`             // Rule the value breaks, one of the Rule constants.
+	Message string            `json:"message"`          // English message.
+	Params  map[string]string `json:"params,omitempty"` // Values interpolated in Message.
+}
+
+// Validation rules of a FieldError.
+const (
+	RuleRequired    = "required"    // The field is missing or empty.
+	RuleInvalid     = "invalid"     // The value is malformed or not one of the allowed values.
+	RuleRange       = "range"       // The number is out of its allowed range.
+	RuleTooLong     = "too_long"    // The value exceeds its maximum size.
+	RuleDuplicate   = "duplicate"   // The value is listed more than once.
+	RuleConflict    = "conflict"    // The value is incompatible with another field.
+	RuleUnsupported = "unsupported" // The value is well formed but not supported.
+)
+
+// Validator accumulates the field errors of a request so that clients can
+// highlight every invalid field at once. The zero value is ready to use.
+type Validator struct {
+	fields []FieldError
+}
+
+// Add records that field breaks rule.
+func (v *Validator) Add(field, rule, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Rule: rule, Message: message})
+}
+
+// Required records that field is missing.
+func (v *Validator) Required(field string) {
+	v.fields = append(v.fields, FieldError{
+		Field: field, Rule: RuleRequired, Message: field + " is required",
+		Params: map[string]string{"field": field},
+	})
+}
+
+// Invalid records that field holds the invalid value.
+func (v *Validator) Invalid(field, value string) {
+	v.fields = append(v.fields, FieldError{
+		Field: field, Rule: RuleInvalid, Message: "invalid " + field + ": " + value,
+		Params: map[string]string{"field": field, "value": value},
+	})
+}
+
+// TooLong records that field exceeds maxLen bytes.
+func (v *Validator) TooLong(field string, maxLen int) {
+	v.fields = append(v.fields, FieldError{
+		Field: field, Rule: RuleTooLong, Message: field + " is too long",
+		Params: map[string]string{"field": field, "max": strconv.Itoa(maxLen)},
+	})
+}
+
+// Fields returns the field errors recorded so far.
+func (v *Validator) Fields() []FieldError {
+	return v.fields
+}
+
+// Err returns a 400 error listing the recorded field errors, or nil when
+// there is none. Its message joins the messages of the fields.
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return v.apiError()
+}
+
+func (v *Validator) apiError() *APIError {
+	msgs := make([]string, len(v.fields))
+	for i := range v.fields {
+		msgs[i] = v.fields[i].Message
+	}
+	e := BadRequest(strings.Join(msgs, "; "))
+	e.fields = v.fields
+	if len(v.fields) == 1 {
+		e.params = v.fields[0].Params
+	}
+	return e
+}
@@ -0,0 +1,206 @@
+// Eval runs: a suite of benchmark cases executed as tasks across harness/model targets.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/auth"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+)
+
+// evalRun holds an eval report while its cases execute. Guarded by Server.mu.
+type evalRun struct {
+	report v1.EvalReport
+}
+
+// toJSON returns a deep copy of the report with an up to date summary.
+func (e *evalRun) toJSON() v1.EvalReport {
+	out := e.report
+	out.Results = slices.Clone(e.report.Results)
+	out.Summary = summarizeEval(out.Results)
+	return out
+}
+
+// summarizeEval aggregates results per target, in order of first appearance.
+func summarizeEval(results []v1.EvalResult) []v1.EvalSummary {
+	out := []v1.EvalSummary{}
+	for i := range results {
+		r := &results[i]
+		j := slices.IndexFunc(out, func(s v1.EvalSummary) bool { return s.Target == r.Target })
+		if j < 0 {
+			out = append(out, v1.EvalSummary{Target: r.Target})
+			j = len(out) - 1
+		}
+		s := &out[j]
+		switch r.Status {
+		case "passed":
+			s.Passed++
+		case "failed":
+			s.Failed++
+		case "error":
+			s.Errored++
+		}
+		s.CostUSD += r.CostUSD
+		s.Duration += r.Duration
+	}
+	return out
+}
+
+// createEval validates the suite against the configured repos and harnesses,
+// then runs every case on every target in the background. Poll getEval for
+// the report.
+func (s *Server) createEval(ctx context.Context, req *v1.CreateEvalReq) (*v1.EvalReport, error) {
+	var ownerID string
+	if u, ok := auth.UserFromContext(ctx); ok {
+		ownerID = u.ID
+	}
+	for _, c := range req.Suite.Cases {
+		r, ok := s.runners[c.Repo]
+		if !ok {
+			return nil, dto.BadRequest("case " + c.Name + ": unknown repo: " + c.Repo)
+		}
+		for _, tg := range req.Targets {
+			b, ok := r.Backends[toAgentHarness(tg.Harness)]
+			if !ok {
+				return nil, dto.BadRequest("unknown harness: " + string(tg.Harness))
+			}
+			if tg.Model != "" && !slices.Contains(b.Models(), tg.Model) {
+				return nil, dto.BadRequest("unsupported model for " + string(tg.Harness) + ": " + tg.Model)
+			}
+		}
+	}
+	run := &evalRun{report: v1.EvalReport{
+		ID:        ksid.NewID(),
+		Suite:     req.Suite.Name,
+		Status:    "running",
+		StartedAt: time.Now().UTC(),
+		Results:   make([]v1.EvalResult, 0, len(req.Suite.Cases)*len(req.Targets)),
+	}}
+	for _, c := range req.Suite.Cases {
+		for _, tg := range req.Targets {
+			run.report.Results = append(run.report.Results, v1.EvalResult{Case: c.Name, Target: tg, Status: "pending"})
+		}
+	}
+	s.mu.Lock()
+	s.evals = append(s.evals, run)
+	out := run.toJSON()
+	s.mu.Unlock()
+	slog.Info("eval started", "id", run.report.ID, "suite", req.Suite.Name, "runs", len(run.report.Results))
+
+	parallel := max(req.Parallel, 1)
+	go func() {
+		sem := make(chan struct{}, parallel)
+		var wg sync.WaitGroup
+		for i, c := range req.Suite.Cases {
+			for j, tg := range req.Targets {
+				wg.Go(func() {
+					sem <- struct{}{}
+					defer func() { <-sem }()
+					s.runEvalCase(run, i*len(req.Targets)+j, c, tg, ownerID)
+				})
+			}
+		}
+		wg.Wait()
+		s.mu.Lock()
+		run.report.Status = "done"
+		run.report.FinishedAt = time.Now().UTC()
+		s.mu.Unlock()
+		slog.Info("eval done", "id", run.report.ID, "suite", req.Suite.Name)
+	}()
+	return &out, nil
+}
+
+// runEvalCase runs one case on one target as a task, verifies the result and
+// purges the task. The task's transcript is kept like any purged task's.
+func (s *Server) runEvalCase(run *evalRun, i int, c v1.EvalCase, tg v1.EvalTarget, ownerID string) {
+	if s.ctx.Err() != nil {
+		s.setEvalResult(run, i, func(r *v1.EvalResult) { r.Status, r.Error = "error", "server shutting down" })
+		return
+	}
+	start := time.Now()
+	entry, err := s.startTask(s.ctx, &v1.CreateTaskReq{
+		InitialPrompt: v1.Prompt{Text: c.Prompt},
+		Repos:         []v1.RepoSpec{{Name: c.Repo, BaseBranch: c.Ref}},
+		Harness:       tg.Harness,
+		Model:         tg.Model,
+	}, ownerID, nil)
+	if err != nil {
+		s.setEvalResult(run, i, func(r *v1.EvalResult) { r.Status, r.Error = "error", err.Error() })
+		return
+	}
+	s.setEvalResult(run, i, func(r *v1.EvalResult) { r.Status, r.TaskID = "running", entry.task.ID })
+	defer s.purgeAfterUse(entry)
+	status, output, err := s.verifyEvalCase(entry, c)
+	cost := entry.task.Snapshot().CostUSD
+	s.setEvalResult(run, i, func(r *v1.EvalResult) {
+		r.Status = status
+		r.CostUSD = cost
+		r.Duration = time.Since(start).Seconds()
+		r.Output = output
+		if err != nil {
+			r.Error = err.Error()
+		}
+	})
+}
+
+// verifyEvalCase waits for the task's turn and runs the case's verification
+// script. A failing script means "failed"; anything that prevented judging the
+// case means "error".
+func (s *Server) verifyEvalCase(entry *taskEntry, c v1.EvalCase) (string, string, error) {
+	if state := s.waitForTurn(entry); state != task.StateWaiting {
+		return "error", "", errors.New("task is " + state.String())
+	}
+	if rm := lastResult(entry.task); rm == nil || rm.IsError {
+		return "error", "", errors.New("turn ended with an error")
+	}
+	out, err := s.runners[c.Repo].Verify(s.ctx, entry.task, c.Verify)
+	out = tail(out, maxVerifyOutput)
+	if err != nil {
+		return "failed", out, err
+	}
+	return "passed", out, nil
+}
+
+func (s *Server) setEvalResult(run *evalRun, i int, fn func(*v1.EvalResult)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &run.report.Results[i]
+	fn(r)
+	if r.Status == "error" {
+		slog.Warn("eval case errored", "id", run.report.ID, "case", r.Case, "harness", r.Target.Harness, "model", r.Target.Model, "err", r.Error)
+	}
+}
+
+// listEvals returns the reports of the eval runs since the server started,
+// most recent first.
+func (s *Server) listEvals(_ context.Context, _ *dto.EmptyReq) (*[]v1.EvalReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]v1.EvalReport, 0, len(s.evals))
+	for _, e := range slices.Backward(s.evals) {
+		out = append(out, e.toJSON())
+	}
+	return &out, nil
+}
+
+// getEval returns the report of one eval run.
+func (s *Server) getEval(_ context.Context, req *v1.GetEvalReq) (*v1.EvalReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.evals {
+		if e.report.ID.String() == req.ID {
+			out := e.toJSON()
+			return &out, nil
+		}
+	}
+	return nil, dto.NotFound("eval")
+}
@@ -0,0 +1,105 @@
+// Server events on the event bus: the task change signal, shared with the
+// instances on the same bus, and the task transitions for external consumers.
+package server
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+)
+
+// Event bus topics.
+const (
+	// topicTasksChanged signals that the tasks may have changed. The data is
+	// the ID of the publishing instance, whose own signals are ignored.
+	topicTasksChanged = "caic.tasks.changed"
+	// topicTaskTransition carries a transitionEvent per task state change.
+	topicTaskTransition = "caic.tasks.transition"
+)
+
+// transitionEvent is the data of topicTaskTransition.
+type transitionEvent struct {
+	TaskID  ksid.ID   `json:"taskId"`
+	Alias   string    `json:"alias,omitempty"`
+	Repo    string    `json:"repo,omitempty"`
+	OwnerID string    `json:"ownerId,omitempty"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	At      time.Time `json:"at"`
+}
+
+// taskChanged wakes the local watchers of the tasks by closing the current
+// changed channel, replaces it, and schedules the signal for the other
+// instances on the bus. Must be called while holding s.mu.
+func (s *Server) taskChanged() {
+	s.wakeWatchersLocked()
+	select {
+	case s.busKick <- struct{}{}:
+	default:
+	}
+}
+
+// wakeWatchersLocked closes the current changed channel and replaces it. Must
+// be called while holding s.mu.
+func (s *Server) wakeWatchersLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// startEventBus publishes the task change signals, coalescing those raised
+// while one is in flight, and relays the signals of the other instances to the
+// local watchers, until the server's context is done.
+func (s *Server) startEventBus() {
+	s.busID = rand.Text()
+	s.busKick = make(chan struct{}, 1)
+	go func() {
+		defer func() { _ = s.bus.Close() }()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-s.busKick:
+			}
+			if err := s.bus.Publish(s.ctx, topicTasksChanged, []byte(s.busID)); err != nil {
+				slog.Warn("eventbus", "msg", "publish", "topic", topicTasksChanged, "err", err)
+			}
+		}
+	}()
+	changes, err := s.bus.Subscribe(s.ctx, topicTasksChanged)
+	if err != nil {
+		slog.Warn("eventbus", "msg", "subscribe", "topic", topicTasksChanged, "err", err)
+		return
+	}
+	go func() {
+		for id := range changes {
+			if string(id) == s.busID {
+				continue
+			}
+			s.mu.Lock()
+			s.wakeWatchersLocked()
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// publishTransition publishes a state change of t.
+func (s *Server) publishTransition(t *task.Task, tr task.Transition) {
+	if s.bus == nil {
+		return
+	}
+	ev := transitionEvent{TaskID: t.ID, Alias: t.Alias, OwnerID: t.OwnerID, From: tr.From.String(), To: tr.To.String(), At: tr.At}
+	if p := t.Primary(); p != nil {
+		ev.Repo = p.Name
+	}
+	data, err := json.Marshal(&ev)
+	if err == nil {
+		err = s.bus.Publish(s.ctx, topicTaskTransition, data)
+	}
+	if err != nil {
+		slog.Warn("eventbus", "msg", "publish", "topic", topicTaskTransition, "task", t.ID, "err", err)
+	}
+}
@@ -0,0 +1,68 @@
+// Tests for the server events on the event bus.
+package server
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/eventbus"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+)
+
+func TestEventBus(t *testing.T) {
+	s := newTestServer(t)
+	s.bus = eventbus.NewMemory()
+	s.startEventBus()
+	ctx := t.Context()
+	recv := func(t *testing.T, ch <-chan []byte) []byte {
+		t.Helper()
+		select {
+		case data := <-ch:
+			return data
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return nil
+		}
+	}
+	t.Run("Publish", func(t *testing.T) {
+		changes, err := s.bus.Subscribe(ctx, topicTasksChanged)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.notifyTaskChange()
+		if got := recv(t, changes); string(got) != s.busID {
+			t.Errorf("changed = %q, want the instance ID", got)
+		}
+	})
+	t.Run("Relay", func(t *testing.T) {
+		// Another instance's signal wakes the local watchers.
+		s.mu.Lock()
+		ch := s.changed
+		s.mu.Unlock()
+		if err := s.bus.Publish(ctx, topicTasksChanged, []byte("other")); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal("watchers not woken")
+		}
+	})
+	t.Run("Transition", func(t *testing.T) {
+		transitions, err := s.bus.Subscribe(ctx, topicTaskTransition)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := &task.Task{ID: ksid.NewID(), Alias: "w1", Repos: []task.RepoMount{{Name: "org/repo"}}}
+		s.publishTransition(tk, task.Transition{From: task.StatePending, To: task.StateRunning, At: time.Now()})
+		var ev transitionEvent
+		if err := json.Unmarshal(recv(t, transitions), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.TaskID != tk.ID || ev.Repo != "org/repo" || ev.From != task.StatePending.String() || ev.To != task.StateRunning.String() {
+			t.Errorf("event = %+v", ev)
+		}
+	})
+}
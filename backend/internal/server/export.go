@@ -0,0 +1,170 @@
+// Export of the task history as CSV or JSON for spreadsheets and cost reports.
+package server
+
+import (
+	"context"
+	"encoding/csv"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/auth"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+// exportColumns is the header of the CSV export, the JSON names of the
+// v1.TaskExport fields.
+var exportColumns = []string{
+	"id", "alias", "title", "repo", "branch", "state", "harness", "model", "owner",
+	"startedAt", "stateUpdatedAt", "duration", "numTurns", "costUSD", "prURL", "timezone", "project",
+}
+
+// parseExportTime parses a bound of the export range: a date, taken in loc,
+// or an RFC 3339 time.
+func parseExportTime(name, v string, loc *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, v, loc); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return t, dto.BadRequest("invalid " + name + ": " + v)
+	}
+	return t, nil
+}
+
+// taskPRURL returns the web URL of the task's pull request as built by the
+// forge of its primary repo, or "" when there is none or no forge client.
+func (s *Server) taskPRURL(ctx context.Context, t *v1.Task) string {
+	if t.ForgePR == 0 || t.ForgeOwner == "" || t.ForgeRepo == "" || len(t.Repos) == 0 {
+		return ""
+	}
+	info := s.repoInfoFor(t.Repos[0].Name)
+	if info == nil {
+		return ""
+	}
+	f := s.forge.forgeForInfo(ctx, info)
+	if f == nil {
+		return ""
+	}
+	return f.PRURL(t.ForgeOwner, t.ForgeRepo, t.ForgePR)
+}
+
+// csvCell neutralizes a user-controlled CSV cell that a spreadsheet would
+// evaluate as a formula by prefixing it with a quote.
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// handleExportTasks returns the metadata of the tasks created in [from, to),
+// archived ones included, oldest first, optionally only those of a project of
+// the user's RepoProjects. format is "json" (the default) or
+// "csv". Times are rendered in the time zone of requestLocation.
+func (s *Server) handleExportTasks(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeError(w, dto.BadRequest("invalid format: "+format))
+		return
+	}
+	loc, err := s.requestLocation(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var from, to time.Time
+	if v := q.Get("from"); v != "" {
+		if from, err = parseExportTime("from", v, loc); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if to, err = parseExportTime("to", v, loc); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+	var ownerID string
+	if s.authEnabled() {
+		if u, ok := auth.UserFromContext(r.Context()); ok {
+			ownerID = u.ID
+		}
+	}
+	projects := s.prefs.Get(userIDFromCtx(r.Context())).Settings.RepoProjects
+	project := q.Get("project")
+	s.mu.Lock()
+	tasks := make([]v1.Task, 0, len(s.tasks))
+	for _, e := range s.tasks {
+		if ownerID != "" && e.task.OwnerID != "" && e.task.OwnerID != ownerID {
+			continue
+		}
+		if at := e.task.StartedAt; at.Before(from) || (!to.IsZero() && !at.Before(to)) {
+			continue
+		}
+		if p := e.task.Primary(); project != "" && (p == nil || projects[p.Name] != project) {
+			continue
+		}
+		tasks = append(tasks, s.toJSON(e))
+	}
+	s.mu.Unlock()
+	slices.SortFunc(tasks, func(a, b v1.Task) int { return a.ID.Compare(b.ID) })
+
+	out := make([]v1.TaskExport, len(tasks))
+	for i := range tasks {
+		t := &tasks[i]
+		out[i] = v1.TaskExport{
+			ID:             t.ID,
+			Alias:          t.Alias,
+			Title:          t.Title,
+			State:          t.State,
+			Harness:        t.Harness,
+			Model:          t.Model,
+			Owner:          t.Owner,
+			StartedAt:      t.StartedAt.In(loc),
+			StateUpdatedAt: t.StateUpdatedAt.In(loc),
+			Duration:       t.Duration,
+			NumTurns:       t.NumTurns,
+			CostUSD:        t.CostUSD,
+			PRURL:          s.taskPRURL(r.Context(), t),
+			Timezone:       loc.String(),
+		}
+		if len(t.Repos) > 0 {
+			out[i].Repo, out[i].Branch = t.Repos[0].Name, t.Repos[0].Branch
+			out[i].Project = projects[out[i].Repo]
+		}
+	}
+	if format != "csv" {
+		writeJSONResponse(w, &out, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="tasks.csv"`)
+	cw := csv.NewWriter(w)
+	_ = cw.Write(exportColumns)
+	rfc3339 := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+	for i := range out {
+		e := &out[i]
+		_ = cw.Write([]string{
+			e.ID.String(), csvCell(e.Alias), csvCell(e.Title), csvCell(e.Repo), csvCell(e.Branch), e.State, string(e.Harness), csvCell(e.Model), csvCell(e.Owner),
+			rfc3339(e.StartedAt), rfc3339(e.StateUpdatedAt),
+			strconv.FormatFloat(math.Round(e.Duration), 'f', -1, 64),
+			strconv.Itoa(e.NumTurns),
+			strconv.FormatFloat(e.CostUSD, 'f', 4, 64),
+			e.PRURL, e.Timezone, csvCell(e.Project),
+		})
+	}
+	cw.Flush()
+}
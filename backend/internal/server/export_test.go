@@ -0,0 +1,158 @@
+// Tests for the task history export.
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/forge"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+)
+
+func TestHandleExportTasks(t *testing.T) {
+	s := newTestServer(t)
+	may := &task.Task{
+		ID:            ksid.NewID(),
+		InitialPrompt: agent.Prompt{Text: "fix, the build"},
+		Repos:         []task.RepoMount{{Name: "org/repo", Branch: "caic-0"}},
+		Harness:       "claude",
+		StartedAt:     time.Date(2026, 5, 10, 8, 0, 0, 0, time.UTC),
+	}
+	may.SetTitle("fix, the build")
+	june := &task.Task{ID: ksid.NewID(), Harness: "codex", StartedAt: time.Date(2026, 6, 2, 8, 0, 0, 0, time.UTC)}
+	s.tasks["may"] = &taskEntry{task: may, done: make(chan struct{})}
+	s.tasks["june"] = &taskEntry{task: june, done: make(chan struct{})}
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleExportTasks(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/export"+query, http.NoBody))
+		return w
+	}
+
+	t.Run("JSON", func(t *testing.T) {
+		var got []v1.TaskExport
+		if err := json.Unmarshal(get("?from=2026-05-01&to=2026-06-01").Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].ID != may.ID || got[0].Repo != "org/repo" || got[0].Branch != "caic-0" {
+			t.Errorf("export = %+v, want the May task", got)
+		}
+		if err := json.Unmarshal(get("?from=2026-05-10T08:00:00Z").Body.Bytes(), &got); err != nil || len(got) != 2 {
+			t.Errorf("export from = %+v, %v; want both tasks", got, err)
+		}
+	})
+	t.Run("CSV", func(t *testing.T) {
+		w := get("?format=csv&to=2026-06-01")
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+			t.Errorf("content type = %q", ct)
+		}
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[0][0] != "id" || len(rows[1]) != len(exportColumns) {
+			t.Fatalf("rows = %q", rows)
+		}
+		if r := rows[1]; r[2] != "fix, the build" || r[9] != "2026-05-10T08:00:00Z" || r[13] != "0.0000" || r[15] != "UTC" {
+			t.Errorf("row = %q", r)
+		}
+	})
+	t.Run("Timezone", func(t *testing.T) {
+		// The May task started on May 10 at 1:00 in Los Angeles; the range
+		// dates are taken there too.
+		var got []v1.TaskExport
+		if err := json.Unmarshal(get("?tz=America/Los_Angeles&from=2026-05-10&to=2026-05-11").Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Timezone != "America/Los_Angeles" {
+			t.Fatalf("export = %+v, want the May task", got)
+		}
+		rows, err := csv.NewReader(get("?format=csv&tz=America/Los_Angeles&to=2026-06-01").Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[1][9] != "2026-05-10T01:00:00-07:00" {
+			t.Errorf("rows = %q", rows)
+		}
+	})
+	t.Run("Project", func(t *testing.T) {
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.RepoProjects = map[string]string{"org/repo": "product"}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		var got []v1.TaskExport
+		if err := json.Unmarshal(get("?project=product").Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].ID != may.ID || got[0].Project != "product" {
+			t.Errorf("export = %+v, want the May task", got)
+		}
+		rows, err := csv.NewReader(get("?format=csv&project=product").Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[0][len(exportColumns)-1] != "project" || rows[1][len(exportColumns)-1] != "product" {
+			t.Errorf("rows = %q", rows)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, q := range []string{"?format=xml", "?from=yesterday", "?to=2026-13-01", "?tz=Mars/Olympus"} {
+			if w := get(q); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want 400", q, w.Code)
+			}
+		}
+	})
+	t.Run("PRURL", func(t *testing.T) {
+		s := newTestServer(t)
+		s.forge = newForgeManager("ghp_test", "glpat_test", nil)
+		s.repos = []repoInfo{{RelPath: "gh", ForgeKind: forge.KindGitHub}, {RelPath: "gl", ForgeKind: forge.KindGitLab}, {RelPath: "local"}}
+		pr := &v1.Task{ForgeOwner: "org", ForgeRepo: "repo", ForgePR: 7, Repos: []v1.TaskRepo{{Name: "gh"}}}
+		if got := s.taskPRURL(t.Context(), pr); got != "https://github.com/org/repo/pull/7" {
+			t.Errorf("github = %q", got)
+		}
+		pr.Repos[0].Name = "gl"
+		if got := s.taskPRURL(t.Context(), pr); got != "https://gitlab.com/org/repo/-/merge_requests/7" {
+			t.Errorf("gitlab = %q", got)
+		}
+		pr.Repos[0].Name = "local"
+		if got := s.taskPRURL(t.Context(), pr); got != "" {
+			t.Errorf("no forge = %q", got)
+		}
+	})
+	t.Run("CSVFormula", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := &task.Task{
+			ID:        ksid.NewID(),
+			Repos:     []task.RepoMount{{Name: "org/repo", Branch: "-caic"}},
+			StartedAt: time.Date(2026, 5, 10, 8, 0, 0, 0, time.UTC),
+		}
+		tk.SetTitle(`=HYPERLINK("https://evil.example","x")`)
+		s.tasks["f"] = &taskEntry{task: tk, done: make(chan struct{})}
+		w := httptest.NewRecorder()
+		s.handleExportTasks(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks/export?format=csv", http.NoBody))
+		rows, err := csv.NewReader(w.Body).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[1][2] != `'=HYPERLINK("https://evil.example","x")` || rows[1][4] != "'-caic" {
+			t.Errorf("rows = %q", rows)
+		}
+		for _, v := range []string{"+1", "@SUM(A1)", "\tx", "\rx"} {
+			if got := csvCell(v); got != "'"+v {
+				t.Errorf("csvCell(%q) = %q", v, got)
+			}
+		}
+		if got := csvCell("fix -1"); got != "fix -1" {
+			t.Errorf("csvCell = %q", got)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+// Session start failover: tasks switch to the user's fallback harness and
+// model when their own fail to start.
+package server
+
+import (
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// taskFallback returns the fallback of a task created with prefs, nil when
+// the user configured none.
+func taskFallback(prefs *preferences.Preferences) *task.Fallback {
+	fb := prefs.Settings.Fallback
+	if fb == nil {
+		return nil
+	}
+	h := agent.Harness(fb.Harness)
+	return &task.Fallback{Harness: h, Model: fb.Model, Endpoint: harnessEndpoint(prefs, h)}
+}
+
+func toV1Failover(f *task.Failover) *v1.Failover {
+	if f == nil {
+		return nil
+	}
+	return &v1.Failover{
+		FromHarness: toV1Harness(f.FromHarness),
+		FromModel:   f.FromModel,
+		ToHarness:   toV1Harness(f.ToHarness),
+		ToModel:     f.ToModel,
+		Error:       f.Err,
+		At:          f.At.UTC(),
+	}
+}
+
+func prefsToV1Fallback(f *preferences.Fallback) *v1.Fallback {
+	if f == nil {
+		return nil
+	}
+	return &v1.Fallback{Harness: v1.Harness(f.Harness), Model: f.Model}
+}
+
+func prefsFromV1Fallback(f *v1.Fallback) *preferences.Fallback {
+	if f == nil {
+		return nil
+	}
+	return &preferences.Fallback{Harness: string(f.Harness), Model: f.Model}
+}
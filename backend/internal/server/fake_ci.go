@@ -21,7 +21,7 @@ func (s *Server) maybeFakeCI(t *task.Task) {
 		case <-time.After(100 * time.Millisecond):
 		}
 		switch t.GetState() {
-		case task.StateWaiting, task.StateAsking, task.StateHasPlan:
+		case task.StateWaiting, task.StateAsking, task.StateHasPlan, task.StatePlanReview:
 			goto ready
 		case task.StatePurged, task.StateFailed:
 			return
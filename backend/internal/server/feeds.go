@@ -0,0 +1,229 @@
+// Atom feed of finished tasks and iCalendar of the tasks held by the execution window.
+package server
+
+import (
+	"cmp"
+	"crypto/subtle"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// maxFeedEntries bounds the number of entries of the Atom feed.
+const maxFeedEntries = 50
+
+// requireFeedToken serves next only when the token query parameter matches
+// Config.FeedToken. Feed readers and calendar apps can't log in, hence the
+// token in the URL.
+func (s *Server) requireFeedToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.feedToken == "" {
+			http.Error(w, "feeds not configured", http.StatusNotFound)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(s.feedToken)) != 1 {
+			http.Error(w, "invalid feed token", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Cache-Control", "private, no-cache")
+		next(w, r)
+	}
+}
+
+// feedBaseURL returns the URL the task links of the feeds are relative to:
+// the external URL when known, else the one the request was sent to.
+func (s *Server) feedBaseURL(r *http.Request) string {
+	if s.hostState != nil {
+		if u := s.hostState.ExternalURL(); u != "" {
+			return strings.TrimSuffix(u, "/")
+		}
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// feedTasks returns a snapshot of the task entries.
+func (s *Server) feedTasks() []*taskEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*taskEntry, 0, len(s.tasks))
+	for _, e := range s.tasks {
+		out = append(out, e)
+	}
+	return out
+}
+
+// feedTitle returns the title of the task, falling back to the start of its
+// prompt.
+func feedTitle(t *task.Task) string {
+	if title := t.Title(); title != "" {
+		return title
+	}
+	p, _, _ := strings.Cut(strings.TrimSpace(t.InitialPrompt.Text), "\n")
+	if r := []rune(p); len(r) > 80 {
+		p = string(r[:80]) + "…"
+	}
+	return cmp.Or(p, t.ID.String())
+}
+
+// feedSummary describes the task for feed entries.
+func feedSummary(t *task.Task) string {
+	var b strings.Builder
+	if len(t.Repos) > 0 {
+		b.WriteString(t.Repos[0].Name + ": ")
+	}
+	snap := t.Snapshot()
+	b.WriteString(string(snap.Harness))
+	if snap.Model != "" {
+		b.WriteString(" (" + snap.Model + ")")
+	}
+	b.WriteString("\n\n" + t.InitialPrompt.Text)
+	return b.String()
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Updated  string       `xml:"updated"`
+	Link     atomLink     `xml:"link"`
+	Category atomCategory `xml:"category"`
+	Summary  string       `xml:"summary"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// handleTaskFeed serves GET /feeds/tasks.xml: an Atom feed with an entry each
+// time a task completed a turn or failed, newest first.
+func (s *Server) handleTaskFeed(w http.ResponseWriter, r *http.Request) {
+	base := s.feedBaseURL(r)
+	type event struct {
+		t   *task.Task
+		tr  task.Transition
+		key string // "completed" or "failed".
+	}
+	var events []event
+	for _, e := range s.feedTasks() {
+		for _, tr := range e.task.Transitions() {
+			switch {
+			case tr.To == task.StateFailed:
+				events = append(events, event{e.task, tr, "failed"})
+			case tr.To == task.StateWaiting && tr.From == task.StateRunning:
+				events = append(events, event{e.task, tr, "completed"})
+			}
+		}
+	}
+	slices.SortFunc(events, func(a, b event) int { return b.tr.At.Compare(a.tr.At) })
+	events = events[:min(len(events), maxFeedEntries)]
+
+	feed := atomFeed{
+		ID:      base + "/feeds/tasks.xml",
+		Title:   "caic tasks",
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: "caic"},
+		Links:   []atomLink{{Rel: "self", Href: base + "/feeds/tasks.xml"}, {Href: base + "/"}},
+		Entries: make([]atomEntry, len(events)),
+	}
+	if len(events) > 0 {
+		feed.Updated = events[0].tr.At.UTC().Format(time.RFC3339)
+	}
+	for i, ev := range events {
+		at := ev.tr.At.UTC()
+		feed.Entries[i] = atomEntry{
+			ID:       "urn:caic:task:" + ev.t.ID.String() + ":" + at.Format("20060102T150405.000Z"),
+			Title:    feedTitle(ev.t) + ": " + ev.key,
+			Updated:  at.Format(time.RFC3339),
+			Link:     atomLink{Href: base + "/task/@" + ev.t.ID.String()},
+			Category: atomCategory{Term: ev.key},
+			Summary:  feedSummary(ev.t),
+		}
+	}
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	_, _ = io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	_ = enc.Encode(&feed)
+}
+
+// handleTaskCalendar serves GET /feeds/tasks.ics: an iCalendar with an event
+// for each pending task held by its owner's execution window, at the time the
+// window opens.
+func (s *Server) handleTaskCalendar(w http.ResponseWriter, r *http.Request) {
+	base := s.feedBaseURL(r)
+	now := time.Now()
+	stamp := now.UTC().Format("20060102T150405Z")
+	var b strings.Builder
+	line := func(name, value string) { writeICalLine(&b, name+":"+value) }
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//caic//tasks//EN")
+	line("X-WR-CALNAME", "caic tasks")
+	entries := s.feedTasks()
+	slices.SortFunc(entries, func(a, b *taskEntry) int { return a.task.ID.Compare(b.task.ID) })
+	for _, e := range entries {
+		if e.task.GetState() != task.StatePending {
+			continue
+		}
+		at := s.windowOpensAt(e.task, now)
+		if !at.After(now) {
+			continue
+		}
+		line("BEGIN", "VEVENT")
+		line("UID", e.task.ID.String()+"@caic")
+		line("DTSTAMP", stamp)
+		line("DTSTART", at.UTC().Format("20060102T150405Z"))
+		line("DURATION", "PT15M")
+		line("SUMMARY", icalText(feedTitle(e.task)))
+		line("DESCRIPTION", icalText(feedSummary(e.task)))
+		line("URL", base+"/task/@"+e.task.ID.String())
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	_, _ = io.WriteString(w, b.String())
+}
+
+// icalText escapes s as an iCalendar TEXT value.
+var icalText = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace
+
+// writeICalLine writes an iCalendar content line, folded every 75 octets
+// without splitting UTF-8 sequences.
+func writeICalLine(b *strings.Builder, l string) {
+	for n := 75; len(l) > n; n = 74 {
+		i := n
+		for i > 0 && l[i]&0xC0 == 0x80 {
+			i--
+		}
+		b.WriteString(l[:i] + "\r\n ")
+		l = l[i:]
+	}
+	b.WriteString(l + "\r\n")
+}
@@ -0,0 +1,117 @@
+// Tests for the task feeds.
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+)
+
+func TestFeeds(t *testing.T) {
+	s := newTestServer(t)
+	s.feedToken = "s3cret"
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	done := &task.Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "fix the build\nplease"}, Harness: "claude"}
+	done.RestoreTransitions([]task.Transition{
+		{From: task.StateStarting, To: task.StateRunning, At: at},
+		{From: task.StateRunning, To: task.StateWaiting, At: at.Add(time.Minute)},
+		{From: task.StateWaiting, To: task.StateFailed, At: at.Add(2 * time.Minute)},
+	})
+	done.SetStateAt(task.StateFailed, at.Add(2*time.Minute))
+	held := &task.Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "nightly, refactor; all"}}
+	s.tasks["done"] = &taskEntry{task: done, done: make(chan struct{})}
+	s.tasks["held"] = &taskEntry{task: held, done: make(chan struct{})}
+	h, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, http.NoBody))
+		return w
+	}
+
+	t.Run("Token", func(t *testing.T) {
+		for _, target := range []string{"/feeds/tasks.xml", "/feeds/tasks.xml?token=nope", "/feeds/tasks.ics?token="} {
+			if w := get(target); w.Code != http.StatusUnauthorized {
+				t.Errorf("%s: status = %d, want 401", target, w.Code)
+			}
+		}
+		s.feedToken = ""
+		defer func() { s.feedToken = "s3cret" }()
+		if w := get("/feeds/tasks.xml?token="); w.Code != http.StatusNotFound {
+			t.Errorf("disabled: status = %d, want 404", w.Code)
+		}
+	})
+	t.Run("Atom", func(t *testing.T) {
+		w := get("/feeds/tasks.xml?token=s3cret")
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/atom+xml") {
+			t.Fatalf("status = %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+		}
+		var feed atomFeed
+		if err := xml.Unmarshal(w.Body.Bytes(), &feed); err != nil {
+			t.Fatal(err)
+		}
+		if len(feed.Entries) != 2 {
+			t.Fatalf("entries = %+v, want 2", feed.Entries)
+		}
+		if e := feed.Entries[0]; e.Title != "fix the build: failed" || e.Updated != "2026-05-01T12:02:00Z" {
+			t.Errorf("first entry = %+v", e)
+		}
+		if e := feed.Entries[1]; e.Category.Term != "completed" || e.Link.Href != "http://example.com/task/@"+done.ID.String() {
+			t.Errorf("second entry = %+v", e)
+		}
+		if feed.Updated != "2026-05-01T12:02:00Z" {
+			t.Errorf("updated = %q", feed.Updated)
+		}
+	})
+	t.Run("Calendar", func(t *testing.T) {
+		if body := get("/feeds/tasks.ics?token=s3cret").Body.String(); strings.Contains(body, "BEGIN:VEVENT") {
+			t.Errorf("event without execution window:\n%s", body)
+		}
+		now := time.Now().UTC()
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.ExecutionWindow = &preferences.ExecutionWindow{
+				Start:    now.Add(2 * time.Hour).Format("15:04"),
+				End:      now.Add(3 * time.Hour).Format("15:04"),
+				Timezone: "UTC",
+			}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		w := get("/feeds/tasks.ics?token=s3cret")
+		body := w.Body.String()
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/calendar") || !strings.HasPrefix(body, "BEGIN:VCALENDAR\r\n") {
+			t.Fatalf("content type %q, body:\n%s", w.Header().Get("Content-Type"), body)
+		}
+		// Only the pending task is held.
+		if n := strings.Count(body, "BEGIN:VEVENT"); n != 1 {
+			t.Fatalf("%d events, want 1:\n%s", n, body)
+		}
+		for _, want := range []string{"UID:" + held.ID.String() + "@caic\r\n", "SUMMARY:nightly\\, refactor\\; all\r\n", "DTSTART:" + s.windowOpensAt(held, now).UTC().Format("20060102T150405Z")} {
+			if !strings.Contains(body, want) {
+				t.Errorf("missing %q:\n%s", want, body)
+			}
+		}
+	})
+	t.Run("Fold", func(t *testing.T) {
+		var b strings.Builder
+		writeICalLine(&b, "DESCRIPTION:"+strings.Repeat("é", 80))
+		for l := range strings.SplitSeq(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+			if len(l) > 75 {
+				t.Errorf("line of %d octets: %q", len(l), l)
+			}
+		}
+		if got := strings.ReplaceAll(b.String(), "\r\n ", ""); got != "DESCRIPTION:"+strings.Repeat("é", 80)+"\r\n" {
+			t.Errorf("unfolded = %q", got)
+		}
+	})
+}
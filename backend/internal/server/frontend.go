@@ -0,0 +1,277 @@
+// Frontend build: the embedded one or a local directory watched for rebuilds.
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/frontend"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/fsnotify/fsnotify"
+)
+
+// frontendSettle is how long the frontend directory must stay unchanged
+// before its hash is recomputed, so that a rebuild is hashed once.
+const frontendSettle = 500 * time.Millisecond
+
+// frontendBuild is the frontend build being served and its hash.
+type frontendBuild struct {
+	dir   string // Empty when the embedded build is served.
+	fsys  fs.FS
+	cache *transcodeCache // Nil when dir is set.
+
+	mu        sync.Mutex
+	hash      string // Empty until computed.
+	changedAt time.Time
+}
+
+// newFrontendBuild returns the build in dir, or the embedded one when dir is
+// empty. The embedded build keeps up to cacheBytes of transcoded files.
+func newFrontendBuild(dir string, cacheBytes int64) (*frontendBuild, error) {
+	if dir != "" {
+		return &frontendBuild{dir: dir, fsys: os.DirFS(dir)}, nil
+	}
+	dist, err := fs.Sub(frontend.Files, "dist")
+	if err != nil {
+		return nil, err
+	}
+	return &frontendBuild{fsys: dist, cache: newTranscodeCache(cacheBytes)}, nil
+}
+
+// handler returns the handler serving the build. A local directory is read on
+// every request so that rebuilds are served without a restart.
+func (b *frontendBuild) handler() http.HandlerFunc {
+	if b.dir == "" {
+		return newStaticHandler(b.fsys, b.cache)
+	}
+	return newDirHandler(b.fsys)
+}
+
+// warm transcodes the critical assets of the embedded build ahead of the
+// first requests. A local directory may change at any time and is not cached.
+func (b *frontendBuild) warm(ctx context.Context) {
+	if b.cache != nil {
+		b.cache.warm(ctx, b.fsys)
+	}
+}
+
+// Hash returns the hash of the build, computing it on first use.
+func (b *frontendBuild) Hash() (string, time.Time, error) {
+	b.mu.Lock()
+	h, at := b.hash, b.changedAt
+	b.mu.Unlock()
+	if h != "" {
+		return h, at, nil
+	}
+	if err := b.refresh(); err != nil {
+		return "", time.Time{}, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.hash, b.changedAt, nil
+}
+
+// refresh recomputes the hash, updating changedAt when it differs.
+func (b *frontendBuild) refresh() error {
+	h, err := hashFS(b.fsys)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if h != b.hash {
+		b.hash, b.changedAt = h, time.Now()
+	}
+	return nil
+}
+
+// watch rehashes the local directory after each rebuild until ctx is done.
+// New subdirectories are watched as they appear.
+func (b *frontendBuild) watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := addDirs(w, b.dir); err != nil {
+		_ = w.Close()
+		return err
+	}
+	go func() {
+		defer func() { _ = w.Close() }()
+		settle := time.NewTimer(frontendSettle)
+		settle.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				settle.Stop()
+				return
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if event.Has(fsnotify.Create) {
+					if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+						_ = addDirs(w, event.Name)
+					}
+				}
+				settle.Reset(frontendSettle)
+			case <-settle.C:
+				if err := b.refresh(); err != nil {
+					slog.WarnContext(ctx, "frontend reload", "dir", b.dir, "err", err)
+					continue
+				}
+				b.mu.Lock()
+				h := b.hash
+				b.mu.Unlock()
+				slog.InfoContext(ctx, "frontend reloaded", "dir", b.dir, "hash", h)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				slog.WarnContext(ctx, "error watching frontend", "dir", b.dir, "err", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// addDirs watches root and all the directories below it.
+func addDirs(w *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return w.Add(p)
+		}
+		return nil
+	})
+}
+
+// hashFS returns a short hex SHA-256 of the paths and contents of the files
+// in fsys.
+func hashFS(fsys fs.FS) (string, error) {
+	h := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(h, "%s\x00%d\x00", p, len(data))
+		_, _ = h.Write(data)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// newDirHandler serves a frontend build from a local directory with SPA
+// fallback to index.html. Both the plain output of "vite build --watch" and
+// the brotli-compressed output of "pnpm build" are served; the latter is
+// transcoded for clients not accepting brotli.
+func newDirHandler(dist fs.FS) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		p := r.URL.Path
+		if p == "/" {
+			p = "/index.html"
+		}
+		clean := strings.TrimPrefix(path.Clean(p), "/")
+		if !dirHasFile(dist, clean) {
+			clean = "index.html"
+		}
+		ct := mime.TypeByExtension(filepath.Ext(clean))
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		if clean == "index.html" {
+			// The manifest is reread as a rebuild may have replaced it.
+			if links, err := preloadLinks(dist); err == nil {
+				sendEarlyHints(w, r, links)
+			}
+		}
+		if f, err := dist.Open(clean); err == nil {
+			defer func() { _ = f.Close() }()
+			stat, err := f.Stat()
+			rs, ok := f.(io.ReadSeeker)
+			if err != nil || !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", ct)
+			w.Header().Set("Cache-Control", "no-cache")
+			http.ServeContent(w, r, clean, stat.ModTime(), rs)
+			return
+		}
+		// The files may change at any time, so nothing is cached.
+		etag, err := brETag(dist, clean)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if parseAcceptEncoding(r.Header.Get("Accept-Encoding"))["br"] {
+			serveBrotli(w, r, dist, clean, ct, etag)
+			return
+		}
+		data, err := doTranscode(dist, clean, "identity")
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		serveVariant(w, r, clean, ct, "identity", etag, data)
+	}
+}
+
+// dirHasFile reports whether name exists in dist, plain or compressed.
+func dirHasFile(dist fs.FS, name string) bool {
+	for _, n := range []string{name, name + ".br"} {
+		if fi, err := fs.Stat(dist, n); err == nil && !fi.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// getFrontendBuild reports where the served frontend comes from and its hash.
+func (s *Server) getFrontendBuild(_ context.Context, _ *dto.EmptyReq) (*v1.FrontendBuildResp, error) {
+	h, at, err := s.frontend.Hash()
+	if err != nil {
+		return nil, dto.InternalError("hash frontend: " + err.Error())
+	}
+	resp := &v1.FrontendBuildResp{Source: v1.FrontendEmbedded, Hash: h}
+	if s.frontend.dir != "" {
+		resp.Source, resp.Dir, resp.ChangedAt = v1.FrontendDir, s.frontend.dir, at.UTC()
+	}
+	return resp, nil
+}
+
+// validateFrontendDir checks that dir holds a frontend build.
+func validateFrontendDir(dir string) error {
+	if !dirHasFile(os.DirFS(dir), "index.html") {
+		return errors.New("no index.html or index.html.br in " + dir)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Tests for serving the frontend from a local directory.
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDirHandler(t *testing.T) {
+	// "vite build --watch" leaves plain files; "pnpm build" only .br files.
+	fsys := fstest.MapFS{
+		"index.html":       {Data: indexContent},
+		"assets/app.js.br": {Data: brCompress(t, appContent)},
+	}
+	h := newDirHandler(fsys)
+	get := func(t *testing.T, p, enc string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, p, http.NoBody)
+		if enc != "" {
+			req.Header.Set("Accept-Encoding", enc)
+		}
+		w := httptest.NewRecorder()
+		h(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want 200", p, w.Code)
+		}
+		return w
+	}
+	t.Run("Plain", func(t *testing.T) {
+		w := get(t, "/", "br")
+		if got := w.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("Content-Encoding = %q, want none", got)
+		}
+		if !bytes.Equal(w.Body.Bytes(), indexContent) {
+			t.Errorf("body = %q, want %q", w.Body.Bytes(), indexContent)
+		}
+	})
+	t.Run("Brotli", func(t *testing.T) {
+		w := get(t, "/assets/app.js", "br")
+		if got := w.Header().Get("Content-Encoding"); got != "br" {
+			t.Errorf("Content-Encoding = %q, want br", got)
+		}
+		if body := decompressBrotli(t, w.Body.Bytes()); !bytes.Equal(body, appContent) {
+			t.Errorf("body = %q, want %q", body, appContent)
+		}
+	})
+	t.Run("Identity", func(t *testing.T) {
+		if w := get(t, "/assets/app.js", ""); !bytes.Equal(w.Body.Bytes(), appContent) {
+			t.Errorf("body = %q, want %q", w.Body.Bytes(), appContent)
+		}
+	})
+	t.Run("SPAFallback", func(t *testing.T) {
+		if w := get(t, "/task/abc", ""); !bytes.Equal(w.Body.Bytes(), indexContent) {
+			t.Errorf("body = %q, want %q", w.Body.Bytes(), indexContent)
+		}
+	})
+}
+
+func TestFrontendBuild(t *testing.T) {
+	t.Run("Embedded", func(t *testing.T) {
+		s := newTestServer(t)
+		if _, err := s.buildHandler(); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := s.getFrontendBuild(t.Context(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Source != "embedded" || len(resp.Hash) != 16 || !resp.ChangedAt.IsZero() {
+			t.Errorf("resp = %+v", resp)
+		}
+	})
+	t.Run("Dir", func(t *testing.T) {
+		dir := t.TempDir()
+		index := filepath.Join(dir, "index.html")
+		if err := os.WriteFile(index, []byte("<html>v1</html>"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := validateFrontendDir(dir); err != nil {
+			t.Fatal(err)
+		}
+		if err := validateFrontendDir(t.TempDir()); err == nil {
+			t.Error("empty directory accepted")
+		}
+		b, err := newFrontendBuild(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h1, _, err := b.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(index, []byte("<html>v2</html>"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.refresh(); err != nil {
+			t.Fatal(err)
+		}
+		if h2, _, _ := b.Hash(); h2 == h1 {
+			t.Errorf("hash unchanged after a rebuild: %s", h2)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+// Automatic context gathering: search the repo for terms in a prompt and
+// prepend the matching files so the agent explores less on its first turn.
+package server
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/index"
+)
+
+const (
+	// gatherMaxTerms caps the search terms extracted from a prompt.
+	gatherMaxTerms = 8
+	// gatherMaxFiles caps the files listed in the relevant files section.
+	gatherMaxFiles = 10
+	// gatherSemanticFiles is the number of files found by meaning, when
+	// semantic search is enabled.
+	gatherSemanticFiles = 5
+	// gatherSemanticMaxChars truncates the prompt embedded as the query.
+	gatherSemanticMaxChars = 8000
+	// gatherMaxTermsPerFile caps the terms listed next to each file.
+	gatherMaxTermsPerFile = 4
+	// gatherTimeout bounds the search, including building the index on first
+	// use, so task creation stays fast.
+	gatherTimeout = 5 * time.Second
+)
+
+// gatherHeader introduces the section prepended to the prompt.
+const gatherHeader = "Relevant files found by searching the repository for terms in this request (a starting point, not exhaustive):\n"
+
+var (
+	// backtickRe matches `code spans`.
+	backtickRe = regexp.MustCompile("`([^`\n]{3,64})`")
+	// identRe matches identifier-like words.
+	identRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]{3,63}`)
+)
+
+// gatherContext returns text prefixed with the files of the repo at repoDir
+// relevant to it, or text unchanged when nothing relevant is found. Code is
+// searched in the index of branch, by keyword and, when enabled, by meaning.
+// Search failures are logged and ignored; the task must start regardless.
+func gatherContext(ctx context.Context, indexes *index.Manager, repoDir, branch, text string) string {
+	ctx, cancel := context.WithTimeout(ctx, gatherTimeout)
+	defer cancel()
+	var files []relevantFile
+	seen := map[string]bool{}
+	add := func(f relevantFile) {
+		if !seen[f.path] {
+			seen[f.path] = true
+			files = append(files, f)
+		}
+	}
+	// Paths named in the prompt come first.
+	for _, ref := range fileRefs(strings.Fields(text)) {
+		if !strings.Contains(ref, "/") || !filepath.IsLocal(ref) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(repoDir, filepath.FromSlash(ref))); err == nil {
+			add(relevantFile{path: ref})
+		}
+	}
+	// Both searches run concurrently since either may wait for a build.
+	var semantic []index.SemanticMatch
+	var semErr error
+	var wg sync.WaitGroup
+	if indexes.Semantic() {
+		wg.Go(func() {
+			semantic, semErr = detached(ctx, func(ctx context.Context) ([]index.SemanticMatch, error) {
+				_, m, err := indexes.SemanticSearch(ctx, repoDir, branch, text[:min(len(text), gatherSemanticMaxChars)], gatherSemanticFiles)
+				return m, err
+			})
+		})
+	}
+	var keyword []relevantFile
+	if terms := searchTerms(text); len(terms) > 0 {
+		idx, err := detached(ctx, func(ctx context.Context) (*index.Index, error) {
+			return indexes.Get(ctx, repoDir, branch)
+		})
+		if err != nil {
+			slog.WarnContext(ctx, "gather context", "dir", repoDir, "err", err)
+		} else {
+			keyword = indexTerms(idx, terms)
+		}
+	}
+	wg.Wait()
+	if semErr != nil {
+		slog.WarnContext(ctx, "gather context: semantic search", "dir", repoDir, "err", semErr)
+	}
+	if len(semantic) > 0 && len(keyword) > gatherMaxFiles-gatherSemanticFiles {
+		// Leave room for the semantic matches.
+		keyword = keyword[:gatherMaxFiles-gatherSemanticFiles]
+	}
+	for _, f := range keyword {
+		add(f)
+	}
+	for _, m := range semantic {
+		add(relevantFile{path: m.Path, lines: fmt.Sprintf("lines %d-%d", m.StartLine, m.EndLine)})
+	}
+	if len(files) == 0 {
+		return text
+	}
+	if len(files) > gatherMaxFiles {
+		files = files[:gatherMaxFiles]
+	}
+	var b strings.Builder
+	b.WriteString(gatherHeader)
+	for _, f := range files {
+		b.WriteString("- " + f.path)
+		switch {
+		case len(f.terms) > 0:
+			b.WriteString(" (" + strings.Join(f.terms[:min(len(f.terms), gatherMaxTermsPerFile)], ", ") + ")")
+		case f.lines != "":
+			b.WriteString(" (" + f.lines + ")")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(text)
+	return b.String()
+}
+
+// detached runs fn and waits for its result until ctx is done. fn itself is
+// not canceled so a slow first index build still serves later tasks.
+func detached[T any](ctx context.Context, fn func(context.Context) (T, error)) (T, error) {
+	type result struct {
+		v   T
+		err error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		v, err := fn(context.WithoutCancel(ctx))
+		ch <- result{v, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// relevantFile is a repo file and why it is relevant.
+type relevantFile struct {
+	path  string
+	terms []string // Search terms it contains.
+	hits  int      // Occurrences of the terms.
+	lines string   // Line range similar to the prompt.
+}
+
+// searchTerms extracts the code-like terms of text: code spans and
+// identifiers in camelCase, PascalCase or snake_case. Plain words are
+// skipped since they match too broadly to be useful.
+func searchTerms(text string) []string {
+	var out []string
+	add := func(t string) {
+		if len(out) < gatherMaxTerms && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	for _, m := range backtickRe.FindAllStringSubmatch(text, -1) {
+		// Keep the last component of qualified names like pkg.Func.
+		t := m[1][strings.LastIndexAny(m[1], ".:")+1:]
+		if identRe.FindString(t) == t {
+			add(t)
+		}
+	}
+	for _, w := range identRe.FindAllString(text, -1) {
+		if isCodeIdent(w) {
+			add(w)
+		}
+	}
+	return out
+}
+
+// isCodeIdent reports whether w is unlikely to be an English word: it
+// contains an underscore, a digit after a letter or an inner capital.
+func isCodeIdent(w string) bool {
+	if strings.Trim(w, "_") != w || strings.ToUpper(w) == w {
+		// Leading underscores and ALLCAPS words are usually not symbols.
+		return false
+	}
+	if strings.Contains(w, "_") {
+		return true
+	}
+	for i := 1; i < len(w); i++ {
+		if c := w[i]; c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			return true
+		}
+	}
+	return false
+}
+
+// indexTerms searches idx for the terms as whole words and returns the
+// matching files, most distinct terms first.
+func indexTerms(idx *index.Index, terms []string) []relevantFile {
+	byPath := map[string]*relevantFile{}
+	for _, term := range terms {
+		for _, m := range idx.Search(index.Query{Text: term, Word: true}).Matches {
+			f := byPath[m.Path]
+			if f == nil {
+				f = &relevantFile{path: m.Path}
+				byPath[m.Path] = f
+			}
+			f.hits++
+			if !slices.Contains(f.terms, term) {
+				f.terms = append(f.terms, term)
+			}
+		}
+	}
+	files := make([]relevantFile, 0, len(byPath))
+	for _, f := range byPath {
+		files = append(files, *f)
+	}
+	slices.SortFunc(files, func(a, b relevantFile) int {
+		return cmp.Or(
+			cmp.Compare(len(b.terms), len(a.terms)),
+			cmp.Compare(b.hits, a.hits),
+			strings.Compare(a.path, b.path),
+		)
+	})
+	return files
+}
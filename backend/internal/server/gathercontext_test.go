@@ -0,0 +1,132 @@
+// Tests for automatic context gathering and repo code search.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/index"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func TestGatherContext(t *testing.T) {
+	t.Run("searchTerms", func(t *testing.T) {
+		got := searchTerms("Make `pkg.RetryPolicy` back off in newClient; see max_retries, HTTP and the docs.")
+		want := []string{"RetryPolicy", "newClient", "max_retries"}
+		if !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+	t.Run("gatherContext", func(t *testing.T) {
+		dir := newGitRepo(t, map[string]string{
+			"client/client.go": "package client\n\nfunc newClient() *Client { return &Client{policy: RetryPolicy{}} }\n",
+			"client/retry.go":  "package client\n\ntype RetryPolicy struct{}\n",
+			"README.md":        "Unrelated.\n",
+		})
+		prompt := "Make newClient use a RetryPolicy, like README.md says."
+		want := gatherHeader +
+			"- client/client.go (newClient, RetryPolicy)\n" +
+			"- client/retry.go (RetryPolicy)\n" +
+			"\n" + prompt
+		indexes := index.NewManager(nil)
+		if got := gatherContext(t.Context(), indexes, dir, "main", prompt); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+		if got := gatherContext(t.Context(), indexes, dir, "main", "Fix NoSuchSymbol"); got != "Fix NoSuchSymbol" {
+			t.Errorf("no match: got %q", got)
+		}
+		t.Run("semantic", func(t *testing.T) {
+			// The query matches retry.go by meaning only.
+			indexes := index.NewManager(keywordEmbedder{"backoff", "struct"})
+			prompt := "Add exponential backoff to the client"
+			want := gatherHeader + "- client/retry.go (lines 1-3)\n\n" + prompt
+			if got := gatherContext(t.Context(), indexes, dir, "main", prompt); got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	})
+}
+
+// keywordEmbedder embeds texts by whether they contain any of its words,
+// deemed to mean the same.
+type keywordEmbedder []string
+
+func (e keywordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		if slices.ContainsFunc(e, func(w string) bool { return strings.Contains(text, w) }) {
+			out[i] = []float32{1, 0}
+		} else {
+			out[i] = []float32{0, 1}
+		}
+	}
+	return out, nil
+}
+
+func TestSearchRepo(t *testing.T) {
+	s := newTestServer(t)
+	s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: newGitRepo(t, map[string]string{
+		"a/retry.go": "package a\n\ntype RetryPolicy struct{}\n",
+	})}
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleSearchRepo(w, httptest.NewRequest(http.MethodGet, "/api/v1/server/repos/search"+query, http.NoBody))
+		return w
+	}
+	w := get("?repo=r&q=retrypolicy")
+	var resp v1.RepoSearchResp
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	want := []v1.RepoSearchMatch{{Path: "a/retry.go", Line: 3, Text: "type RetryPolicy struct{}"}}
+	if !slices.Equal(resp.Matches, want) || len(resp.Paths) != 0 || resp.Commit == "" {
+		t.Errorf("got %+v", resp)
+	}
+	for query, code := range map[string]int{
+		"?repo=r":             http.StatusBadRequest,
+		"?repo=r&q=x&limit=0": http.StatusBadRequest,
+		"?repo=nope&q=retry":  http.StatusNotFound,
+	} {
+		if w := get(query); w.Code != code {
+			t.Errorf("%s: status = %d, want %d", query, w.Code, code)
+		}
+	}
+	w = httptest.NewRecorder()
+	s.handleSemanticSearchRepo(w, httptest.NewRequest(http.MethodGet, "/api/v1/server/repos/semantic-search?repo=r&q=retry", http.NoBody))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("semantic search without embedder: status = %d, want 400", w.Code)
+	}
+}
+
+// newGitRepo creates a git repository with files committed on main.
+func newGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "-q", "-b", "main"}, {"add", "."}, {"commit", "-q", "-m", "init"}} {
+		cmd := exec.Command("git", args...) //nolint:gosec // test helper with controlled args
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	return dir
+}
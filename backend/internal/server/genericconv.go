@@ -5,7 +5,11 @@ package server
 
 import (
 	"encoding/json"
+	"path"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
@@ -17,6 +21,15 @@ import (
 // GET /api/v1/tasks/{id}/tool/{toolUseID}.
 const inputTruncateThreshold = 4096
 
+// contentTruncateThreshold is the maximum byte length of a text, thinking or
+// tool output event before it is replaced by a contentPreviewSize preview in
+// the SSE stream. Clients fetch the full content on demand via
+// GET /api/v1/tasks/{id}/messages/{index}/content.
+const (
+	contentTruncateThreshold = 64 << 10
+	contentPreviewSize       = 4 << 10
+)
+
 // toolInputProbe extracts run_in_background from a tool's raw JSON input.
 type toolInputProbe struct {
 	RunInBackground bool `json:"run_in_background"`
@@ -36,7 +49,7 @@ func newToolTimingTracker(harness agent.Harness) *toolTimingTracker {
 
 // convertMessage converts an agent.Message into zero or more EventMessages.
 func (tt *toolTimingTracker) convertMessage(msg agent.Message, now time.Time) []v1.EventMessage {
-	ts := now.UnixMilli()
+	ts := now.UTC()
 	switch m := msg.(type) {
 	case *agent.InitMessage:
 		return []v1.EventMessage{{
@@ -61,9 +74,10 @@ func (tt *toolTimingTracker) convertMessage(msg agent.Message, now time.Time) []
 		if m.Text != "" {
 			// TODO: propagate m.Phase to EventText once EventText has a Phase field.
 			return []v1.EventMessage{{
-				Kind: v1.EventKindText,
-				Ts:   ts,
-				Text: &v1.EventText{Text: m.Text},
+				Kind:            v1.EventKindText,
+				Ts:              ts,
+				ParentToolUseID: m.ParentToolUseID,
+				Text:            &v1.EventText{Text: m.Text},
 			}}
 		}
 		return nil
@@ -84,12 +98,14 @@ func (tt *toolTimingTracker) convertMessage(msg agent.Message, now time.Time) []
 			}
 		}
 		return []v1.EventMessage{{
-			Kind: v1.EventKindToolUse,
-			Ts:   ts,
+			Kind:            v1.EventKindToolUse,
+			Ts:              ts,
+			ParentToolUseID: m.ParentToolUseID,
 			ToolUse: &v1.EventToolUse{
 				ToolUseID:      m.ToolUseID,
 				Name:           m.Name,
 				Input:          input,
+				Call:           toV1ToolCall(m.Call),
 				PlanContent:    m.PlanContent,
 				InputTruncated: truncated,
 				Background:     bg,
@@ -135,8 +151,9 @@ func (tt *toolTimingTracker) convertMessage(msg agent.Message, now time.Time) []
 			delete(tt.pending, m.ToolUseID)
 		}
 		return []v1.EventMessage{{
-			Kind: v1.EventKindToolResult,
-			Ts:   ts,
+			Kind:            v1.EventKindToolResult,
+			Ts:              ts,
+			ParentToolUseID: m.ParentToolUseID,
 			ToolResult: &v1.EventToolResult{
 				ToolUseID: m.ToolUseID,
 				Duration:  duration,
@@ -190,9 +207,10 @@ func (tt *toolTimingTracker) convertMessage(msg agent.Message, now time.Time) []
 	case *agent.ThinkingMessage:
 		if m.Text != "" {
 			return []v1.EventMessage{{
-				Kind:     v1.EventKindThinking,
-				Ts:       ts,
-				Thinking: &v1.EventThinking{Text: m.Text},
+				Kind:            v1.EventKindThinking,
+				Ts:              ts,
+				ParentToolUseID: m.ParentToolUseID,
+				Thinking:        &v1.EventThinking{Text: m.Text},
 			}}
 		}
 		return nil
@@ -282,11 +300,11 @@ func (tt *toolTimingTracker) convertMessage(msg agent.Message, now time.Time) []
 			Ts:   ts,
 			RateLimit: &v1.EventRateLimit{
 				Status:          m.Status,
-				ResetsAt:        m.ResetsAt,
+				ResetsAt:        epochTime(m.ResetsAt),
 				RateLimitType:   m.RateLimitType,
 				Utilization:     m.Utilization,
 				IsUsingOverage:  m.IsUsingOverage,
-				OverageResetsAt: m.OverageResetsAt,
+				OverageResetsAt: epochTime(m.OverageResetsAt),
 			},
 		}}
 	default:
@@ -295,6 +313,21 @@ func (tt *toolTimingTracker) convertMessage(msg agent.Message, now time.Time) []
 }
 
 // toV1AskQuestions converts agent.AskQuestion to v1.AskQuestion.
+func toV1ToolCall(c *agent.ToolCall) *v1.ToolCall {
+	if c == nil {
+		return nil
+	}
+	return &v1.ToolCall{
+		Kind:        v1.ToolKind(c.Kind),
+		Command:     c.Command,
+		Paths:       c.Paths,
+		Pattern:     c.Pattern,
+		URL:         c.URL,
+		Query:       c.Query,
+		Description: c.Description,
+	}
+}
+
 func toV1AskQuestions(qs []agent.AskQuestion) []v1.AskQuestion {
 	if len(qs) == 0 {
 		return nil
@@ -367,12 +400,79 @@ func toV1SafetyIssues(issues []task.SafetyIssue) []v1.SafetyIssue {
 	return out
 }
 
+// convertIndexedMessage is convertMessage for the message at index in the
+// task history, with oversized content truncated by truncateContent.
+func (tt *toolTimingTracker) convertIndexedMessage(msg agent.Message, now time.Time, index int) []v1.EventMessage {
+	events := tt.convertMessage(msg, now)
+	truncateContent(events, index)
+	return events
+}
+
+// truncateContent replaces the text, thinking or tool output of events longer
+// than contentTruncateThreshold with a preview and points ContentRef at the
+// history message at index, which keeps the full payload.
+func truncateContent(events []v1.EventMessage, index int) {
+	for i := range events {
+		ev := &events[i]
+		var p *string
+		switch {
+		case ev.Text != nil:
+			p = &ev.Text.Text
+		case ev.Thinking != nil:
+			p = &ev.Thinking.Text
+		case ev.ToolOutputDelta != nil:
+			p = &ev.ToolOutputDelta.Delta
+		}
+		if p == nil || len(*p) <= contentTruncateThreshold {
+			continue
+		}
+		ev.ContentRef = &v1.ContentRef{Index: index, Size: len(*p)}
+		*p = previewContent(*p)
+	}
+}
+
+// previewContent returns the first contentPreviewSize bytes of s, cut back to
+// a rune boundary so valid UTF-8 stays valid.
+func previewContent(s string) string {
+	n := contentPreviewSize
+	for n > 0 && n > contentPreviewSize-utf8.UTFMax && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// messageContent returns the full content that truncateContent may cut from
+// the events of msg.
+func messageContent(msg agent.Message) (string, bool) {
+	switch m := msg.(type) {
+	case *agent.TextMessage:
+		return m.Text, true
+	case *agent.ThinkingMessage:
+		return m.Text, true
+	case *agent.ToolOutputDeltaMessage:
+		return m.Delta, true
+	}
+	return "", false
+}
+
 // filterHistoryForReplay removes streaming delta messages that have a
-// corresponding final message later in the history. TextDeltaMessage runs
-// preceding a TextMessage and ThinkingDeltaMessage runs preceding a
-// ThinkingMessage are omitted — the frontend uses only the final message when
-// available, so the deltas are pure waste during history replay.
+// corresponding final message later in the history. See replaySkips.
 func filterHistoryForReplay(msgs []agent.Message) []agent.Message {
+	skip := replaySkips(msgs)
+	out := make([]agent.Message, 0, len(msgs))
+	for i, msg := range msgs {
+		if !skip[i] {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// replaySkips reports which messages to omit during history replay.
+// TextDeltaMessage runs preceding a TextMessage and ThinkingDeltaMessage runs
+// preceding a ThinkingMessage are omitted — the frontend uses only the final
+// message when available, so the deltas are pure waste during history replay.
+func replaySkips(msgs []agent.Message) []bool {
 	skip := make([]bool, len(msgs))
 	for i, msg := range msgs {
 		switch msg.(type) {
@@ -402,13 +502,7 @@ func filterHistoryForReplay(msgs []agent.Message) []agent.Message {
 			}
 		}
 	}
-	out := make([]agent.Message, 0, len(msgs))
-	for i, msg := range msgs {
-		if !skip[i] {
-			out = append(out, msg)
-		}
-	}
-	return out
+	return skip
 }
 
 // toV1DiffStat converts agent.DiffStat to v1.DiffStat at the server boundary.
@@ -418,7 +512,51 @@ func toV1DiffStat(ds agent.DiffStat) v1.DiffStat {
 	}
 	out := make(v1.DiffStat, len(ds))
 	for i, f := range ds {
-		out[i] = v1.DiffFileStat{Path: f.Path, Added: f.Added, Deleted: f.Deleted, Binary: f.Binary}
+		out[i] = v1.DiffFileStat{Path: f.Path, Added: f.Added, Deleted: f.Deleted, Binary: f.Binary, Status: v1.DiffFileStatus(f.Status), OldPath: f.OldPath}
 	}
 	return out
 }
+
+// toV1DiffTree lays out a diff stat as a directory tree. Returns nil when
+// there are no changes.
+func toV1DiffTree(ds v1.DiffStat) *v1.DiffDir {
+	if len(ds) == 0 {
+		return nil
+	}
+	root := &v1.DiffDir{}
+	for _, f := range ds {
+		d := root
+		parts := strings.Split(f.Path, "/")
+		for _, name := range parts[:len(parts)-1] {
+			d.Added += f.Added
+			d.Deleted += f.Deleted
+			i := slices.IndexFunc(d.Dirs, func(c v1.DiffDir) bool { return c.Name == name })
+			if i < 0 {
+				i = len(d.Dirs)
+				d.Dirs = append(d.Dirs, v1.DiffDir{Name: name, Path: path.Join(d.Path, name)})
+			}
+			d = &d.Dirs[i]
+		}
+		d.Added += f.Added
+		d.Deleted += f.Deleted
+		d.Files = append(d.Files, f)
+	}
+	sortDiffDir(root)
+	return root
+}
+
+// sortDiffDir sorts d recursively and folds chains of directories that only
+// hold a single directory.
+func sortDiffDir(d *v1.DiffDir) {
+	for i := range d.Dirs {
+		c := &d.Dirs[i]
+		for len(c.Files) == 0 && len(c.Dirs) == 1 {
+			name := c.Name + "/" + c.Dirs[0].Name
+			*c = c.Dirs[0]
+			c.Name = name
+		}
+		sortDiffDir(c)
+	}
+	slices.SortFunc(d.Dirs, func(a, b v1.DiffDir) int { return strings.Compare(a.Name, b.Name) })
+	slices.SortFunc(d.Files, func(a, b v1.DiffFileStat) int { return strings.Compare(a.Path, b.Path) })
+}
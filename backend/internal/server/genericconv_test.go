@@ -2,6 +2,8 @@ package server
 
 import (
 	"encoding/json"
+	"reflect"
+	"slices"
 	"testing"
 	"time"
 
@@ -287,6 +289,24 @@ func TestGenericConvertSubagentEnd(t *testing.T) {
 	}
 }
 
+func TestGenericConvertSubagentActivity(t *testing.T) {
+	gt := newToolTimingTracker(agent.Claude)
+	for _, msg := range []agent.Message{
+		&agent.TextMessage{Text: "Looking.", ParentToolUseID: "toolu_task"},
+		&agent.ThinkingMessage{Text: "hmm", ParentToolUseID: "toolu_task"},
+		&agent.ToolUseMessage{ToolUseID: "toolu_sub", Name: "Grep", ParentToolUseID: "toolu_task"},
+		&agent.ToolResultMessage{ToolUseID: "toolu_sub", ParentToolUseID: "toolu_task"},
+	} {
+		events := gt.convertMessage(msg, time.Now())
+		if len(events) != 1 {
+			t.Fatalf("%T: got %d events, want 1", msg, len(events))
+		}
+		if events[0].ParentToolUseID != "toolu_task" {
+			t.Errorf("%s: parentToolUseID = %q, want toolu_task", events[0].Kind, events[0].ParentToolUseID)
+		}
+	}
+}
+
 func TestGenericConvertRawMessageFiltered(t *testing.T) {
 	gt := newToolTimingTracker(agent.Claude)
 	msg := &agent.RawMessage{
@@ -319,8 +339,8 @@ func TestToolInputTruncation(t *testing.T) {
 		for i := range largeContent {
 			largeContent[i] = 'x'
 		}
-		bigInput := json.RawMessage(`{"content":"` + string(largeContent) + `"}`)
-		msg := &agent.ToolUseMessage{ToolUseID: "t2", Name: "Write", Input: bigInput}
+		bigInput := json.RawMessage(`{"file_path":"/a.txt","content":"` + string(largeContent) + `"}`)
+		msg := &agent.ToolUseMessage{ToolUseID: "t2", Name: "Write", Input: bigInput, Call: agent.NewToolCall("Write", bigInput)}
 		events := gt.convertMessage(msg, time.Now())
 		if len(events) != 1 {
 			t.Fatalf("got %d events, want 1", len(events))
@@ -331,6 +351,10 @@ func TestToolInputTruncation(t *testing.T) {
 		if events[0].ToolUse.Input != nil {
 			t.Error("truncated input should be nil")
 		}
+		// The normalized call is small and always sent.
+		if c := events[0].ToolUse.Call; c == nil || c.Kind != v1.ToolKindWrite || !slices.Equal(c.Paths, []string{"/a.txt"}) {
+			t.Errorf("call = %+v", c)
+		}
 	})
 	t.Run("BackgroundTrue", func(t *testing.T) {
 		msg := &agent.ToolUseMessage{ToolUseID: "t3", Name: "Bash", Input: json.RawMessage(`{"command":"sleep 60","run_in_background":true}`)}
@@ -505,3 +529,43 @@ func TestFilterHistoryForReplay(t *testing.T) {
 		}
 	})
 }
+
+func TestToV1DiffTree(t *testing.T) {
+	ds := v1.DiffStat{
+		{Path: "backend/internal/task/runner.go", Added: 3, Deleted: 1, Status: v1.FileModified},
+		{Path: "README.md", Added: 1, Status: v1.FileModified},
+		{Path: "backend/internal/task/diffstat.go", Added: 10, Status: v1.FileAdded},
+		{Path: "backend/internal/server/old.go", Deleted: 4, Status: v1.FileDeleted},
+		{Path: "backend/go.mod", Added: 1, Deleted: 1, Status: v1.FileModified},
+	}
+	got := toV1DiffTree(ds)
+	want := &v1.DiffDir{
+		Added: 15, Deleted: 6,
+		Dirs: []v1.DiffDir{{
+			Name: "backend", Path: "backend", Added: 14, Deleted: 6,
+			Dirs: []v1.DiffDir{{
+				Name: "internal", Path: "backend/internal", Added: 13, Deleted: 5,
+				Dirs: []v1.DiffDir{
+					{Name: "server", Path: "backend/internal/server", Deleted: 4, Files: []v1.DiffFileStat{ds[3]}},
+					{Name: "task", Path: "backend/internal/task", Added: 13, Deleted: 1, Files: []v1.DiffFileStat{ds[2], ds[0]}},
+				},
+			}},
+			Files: []v1.DiffFileStat{ds[4]},
+		}},
+		Files: []v1.DiffFileStat{ds[1]},
+	}
+	if !reflect.DeepEqual(got, want) {
+		g, _ := json.MarshalIndent(got, "", "  ")
+		t.Errorf("got %s", g)
+	}
+	t.Run("Folded", func(t *testing.T) {
+		got := toV1DiffTree(v1.DiffStat{{Path: "a/b/c/x.go", Added: 1}, {Path: "a/b/c/y.go", Added: 2}})
+		if len(got.Dirs) != 1 || got.Dirs[0].Name != "a/b/c" || got.Dirs[0].Path != "a/b/c" || got.Dirs[0].Added != 3 || len(got.Dirs[0].Files) != 2 {
+			g, _ := json.MarshalIndent(got, "", "  ")
+			t.Errorf("got %s", g)
+		}
+	})
+	if toV1DiffTree(nil) != nil {
+		t.Error("empty diff stat should have no tree")
+	}
+}
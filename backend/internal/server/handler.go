@@ -11,7 +11,9 @@ import (
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 
+	"github.com/caic-xyz/caic/backend/internal/logctx"
 	"github.com/caic-xyz/caic/backend/internal/server/dto"
 )
 
@@ -39,6 +41,16 @@ func handle[In any, PtrIn interface {
 
 // handleWithTask wraps a typed handler that also needs the resolved *taskEntry.
 // It parses {id}, looks up the task via s.getTask, then proceeds like handle.
+//
+// Every successful call is a mutation and advances the task revision. When
+// the request carries If-Match, the revision must match the one the client
+// last saw or the request fails with 409 and the current revision in details.
+//
+// If-Match is optional: the Android app, voice commands, Slack and scripts act
+// on whatever the task's current state is and have no revision to send, so
+// requiring it would break them without protecting anything. The web UI,
+// where several sessions show the same task, sends it on every mutation a
+// stale view could get wrong, like stop, purge and sync.
 func handleWithTask[In any, PtrIn interface {
 	*In
 	dto.Validatable
@@ -58,14 +70,47 @@ func handleWithTask[In any, PtrIn interface {
 			writeError(w, err)
 			return
 		}
-		out, err := fn(r.Context(), entry, in)
-		if err == nil {
+		want, err := parseIfMatch(r.Header.Get("If-Match"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		// Bumping before fn makes a concurrent request against the same
+		// revision fail instead of running alongside.
+		rev, ok := entry.task.BumpRevision(want)
+		if !ok {
+			writeError(w, dto.Conflict("task was modified concurrently").WithDetail("revision", rev))
+			return
+		}
+		out, err := fn(logctx.With(r.Context(), "task", entry.task.ID), entry, in)
+		if err != nil {
+			// A failed mutation keeps the revisions other clients hold valid.
+			rev = entry.task.RevertRevision(rev)
+		} else {
 			s.notifyTaskChange()
 		}
+		w.Header().Set("ETag", `"`+strconv.FormatUint(rev, 10)+`"`)
 		writeJSONResponse(w, out, err)
 	}
 }
 
+// parseIfMatch parses an If-Match header holding a task revision. It returns
+// nil when the header is absent or "*", i.e. the request is unconditional.
+// Weak and unquoted tags are accepted, as are ETags from GET
+// /api/v1/tasks/{id}, whose content hash suffix is ignored.
+func parseIfMatch(h string) (*uint64, error) {
+	h = strings.TrimSpace(h)
+	if h == "" || h == "*" {
+		return nil, nil
+	}
+	v, _, _ := strings.Cut(strings.Trim(strings.TrimPrefix(h, "W/"), `"`), "-")
+	rev, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return nil, dto.BadRequest("invalid If-Match: " + h)
+	}
+	return &rev, nil
+}
+
 // readAndDecodeBody reads the request body and decodes JSON into input. It
 // skips decoding for EmptyReq. Unknown JSON fields are rejected. Returns false
 // if an error was written to the response.
@@ -0,0 +1,239 @@
+// HTTP handlers for GET /api/v1/health (liveness, readiness) and per-harness diagnostics.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/agent/openaicompat"
+	"github.com/caic-xyz/caic/backend/internal/autoupdate"
+	"github.com/caic-xyz/caic/backend/internal/container"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/caic/backend/internal/usage"
+	"github.com/caic-xyz/md"
+)
+
+const (
+	// healthCheckTimeout bounds the readiness probes of GET /api/v1/health.
+	healthCheckTimeout = 5 * time.Second
+	// harnessCheckTimeout bounds each harness probe; starting a throwaway
+	// container is much slower than the readiness probes.
+	harnessCheckTimeout = 30 * time.Second
+)
+
+// handleHealth reports liveness and readiness. It is served without
+// authentication so load balancers and supervisors can probe it.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+	resp := s.checkHealth(ctx)
+	w.Header().Set("Content-Type", "application/json")
+	if !resp.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		slog.Warn("failed to encode health response", "err", err)
+	}
+}
+
+// subscriberLag summarizes how well live task stream subscribers keep up.
+// It is informational and never fails readiness.
+func (s *Server) subscriberLag() string {
+	s.mu.Lock()
+	tasks := make([]*task.Task, 0, len(s.tasks))
+	for _, e := range s.tasks {
+		tasks = append(tasks, e.task)
+	}
+	s.mu.Unlock()
+	var total task.SubscriberStats
+	for _, t := range tasks {
+		st := t.SubscriberStats()
+		total.Subscribers += st.Subscribers
+		total.MaxLag = max(total.MaxLag, st.MaxLag)
+		total.Dropped += st.Dropped
+	}
+	return fmt.Sprintf("%d subscribers, max lag %d, %d dropped", total.Subscribers, total.MaxLag, total.Dropped)
+}
+
+// checkHealth runs the readiness probes.
+func (s *Server) checkHealth(ctx context.Context) *v1.HealthResp {
+	resp := &v1.HealthResp{Version: autoupdate.Version, Live: true, Ready: true}
+	add := func(name string, err error, detail string) {
+		c := v1.HealthCheck{Name: name, OK: err == nil, Detail: detail}
+		if err != nil {
+			c.Detail = err.Error()
+			resp.Ready = false
+		}
+		resp.Checks = append(resp.Checks, c)
+	}
+	add("server", s.ctx.Err(), "")
+	if s.mdClient != nil {
+		v, err := container.RuntimeVersion(ctx, s.mdClient.Runtime)
+		add("runtime", err, s.mdClient.Runtime+" "+v)
+	}
+	s.mu.Lock()
+	n := len(s.runners)
+	s.mu.Unlock()
+	var err error
+	if n == 0 {
+		err = errors.New("no runner initialized")
+	}
+	add("runners", err, fmt.Sprintf("%d runners", n))
+	add("subscribers", nil, s.subscriberLag())
+	resp.Status = "ok"
+	if !resp.Ready {
+		resp.Status = "unavailable"
+	}
+	return resp
+}
+
+// listHarnessHealth verifies, for each configured harness, that its binary
+// exists in the default container image and that its credentials are valid,
+// so misconfiguration is caught before a task wastes a container start.
+func (s *Server) listHarnessHealth(ctx context.Context, _ *dto.EmptyReq) (*[]v1.HarnessHealth, error) {
+	seen := s.harnessBackends()
+	image := md.DefaultBaseImage + ":latest"
+	runtime := ""
+	if s.mdClient != nil {
+		runtime = s.mdClient.Runtime
+	}
+	out := make([]v1.HarnessHealth, 0, len(seen))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for h, b := range seen {
+		wg.Go(func() {
+			hh := checkHarness(ctx, runtime, h, b, image, s.usage, s.codexUsage)
+			mu.Lock()
+			out = append(out, hh)
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+	slices.SortFunc(out, func(a, b v1.HarnessHealth) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return &out, nil
+}
+
+// harnessBackends returns the backend of every harness configured in any
+// runner.
+func (s *Server) harnessBackends() map[agent.Harness]agent.Backend {
+	seen := make(map[agent.Harness]agent.Backend)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.runners {
+		for h, b := range r.Backends {
+			seen[h] = b
+		}
+	}
+	return seen
+}
+
+// checkHarness probes a single harness. An empty runtime means no container
+// runtime is available.
+func checkHarness(ctx context.Context, runtime string, h agent.Harness, b agent.Backend, image string, claude *usage.ClaudeFetcher, codex *usage.CodexFetcher) v1.HarnessHealth {
+	ctx, cancel := context.WithTimeout(ctx, harnessCheckTimeout)
+	defer cancel()
+	hh := v1.HarnessHealth{Name: string(h), Image: image, Binary: b.Binary()}
+	if err := harnessInstalled(ctx, runtime, &hh); err != nil {
+		hh.InstallError = err.Error()
+	} else {
+		hh.Installed = true
+	}
+	if err := harnessCredentials(ctx, h, b, claude, codex); err != nil {
+		hh.CredentialsError = err.Error()
+	} else {
+		hh.CredentialsOK = true
+	}
+	hh.OK = hh.Installed && hh.CredentialsOK
+	return hh
+}
+
+// harnessInstalled runs "<binary> --version" in a throwaway container and
+// records the version on success.
+func harnessInstalled(ctx context.Context, runtime string, hh *v1.HarnessHealth) error {
+	if runtime == "" {
+		return errors.New("container runtime unavailable")
+	}
+	if hh.Binary == "" {
+		return errors.New("harness has no binary")
+	}
+	// The binary name comes from the backend, never from user input.
+	script := "command -v " + hh.Binary + " >/dev/null || exit 127; " + hh.Binary + " --version 2>&1 | head -n 1"
+	out, err := container.ImageExec(ctx, runtime, hh.Image, script)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 127 {
+			return fmt.Errorf("%s not found in %s", hh.Binary, hh.Image)
+		}
+		if out != "" {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return err
+	}
+	hh.Version = out
+	return nil
+}
+
+// harnessCredentials checks the credentials mounted into containers for the
+// harness. Claude Code and Codex tokens are validated against their usage
+// API; the local harness requires its inference server to list models; aider
+// requires its API keys file; other harnesses only require their
+// configuration directories to exist.
+func harnessCredentials(ctx context.Context, h agent.Harness, b agent.Backend, claude *usage.ClaudeFetcher, codex *usage.CodexFetcher) error {
+	switch h {
+	case agent.Claude:
+		return claude.CheckCredentials(ctx)
+	case agent.Codex:
+		return codex.CheckCredentials(ctx)
+	case agent.Local:
+		if lb, ok := b.(*openaicompat.Backend); ok {
+			return lb.RefreshModels(ctx)
+		}
+	}
+	paths, ok := md.HarnessMounts[md.Harness(h)]
+	if !ok && h != agent.Aider {
+		return nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	if h == agent.Aider {
+		env := filepath.Join(home, ".aider", ".env")
+		if _, err := os.Stat(env); err != nil {
+			return fmt.Errorf("no API keys in %s", env)
+		}
+		return nil
+	}
+	var candidates []string
+	for _, p := range paths.HomePaths {
+		candidates = append(candidates, filepath.Join(home, p))
+	}
+	for _, p := range paths.XDGConfigPaths {
+		candidates = append(candidates, filepath.Join(home, ".config", p))
+	}
+	for _, p := range paths.LocalSharePaths {
+		candidates = append(candidates, filepath.Join(home, ".local", "share", p))
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(c); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no configuration found in %s", strings.Join(candidates, ", "))
+}
@@ -0,0 +1,90 @@
+// Tests for the health check handlers.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func TestHandleHealth(t *testing.T) {
+	t.Run("Ready", func(t *testing.T) {
+		s := newTestServer(t)
+		s.runners[""] = &task.Runner{}
+		w := httptest.NewRecorder()
+		s.handleHealth(w, httptest.NewRequest(http.MethodGet, "/api/v1/health", http.NoBody))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var resp v1.HealthResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Status != "ok" || !resp.Live || !resp.Ready {
+			t.Errorf("resp = %+v, want ok/live/ready", resp)
+		}
+	})
+
+	t.Run("NoRunners", func(t *testing.T) {
+		s := newTestServer(t)
+		w := httptest.NewRecorder()
+		s.handleHealth(w, httptest.NewRequest(http.MethodGet, "/api/v1/health", http.NoBody))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+		}
+		var resp v1.HealthResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Ready || !resp.Live || resp.Status != "unavailable" {
+			t.Errorf("resp = %+v, want live but not ready", resp)
+		}
+	})
+}
+
+func TestListHarnessHealth(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if err := os.Mkdir(filepath.Join(home, ".gemini"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t)
+	s.runners[""] = &task.Runner{Backends: map[agent.Harness]agent.Backend{
+		agent.Claude: stubBackend{},
+		agent.Gemini: stubBackend{},
+	}}
+	out, err := s.listHarnessHealth(t.Context(), &dto.EmptyReq{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*out) != 2 {
+		t.Fatalf("got %d harnesses, want 2", len(*out))
+	}
+	claude, gemini := (*out)[0], (*out)[1]
+	if claude.Name != "claude" || gemini.Name != "gemini" {
+		t.Fatalf("names = %q, %q", claude.Name, gemini.Name)
+	}
+	for _, hh := range *out {
+		// No container runtime in tests.
+		if hh.Installed || hh.InstallError == "" || hh.OK {
+			t.Errorf("%s: %+v, want not installed", hh.Name, hh)
+		}
+		if hh.Binary != "stub" {
+			t.Errorf("%s: binary = %q, want stub", hh.Name, hh.Binary)
+		}
+	}
+	if claude.CredentialsOK || claude.CredentialsError == "" {
+		t.Errorf("claude credentials = %+v, want missing", claude)
+	}
+	if !gemini.CredentialsOK {
+		t.Errorf("gemini credentials = %+v, want ok", gemini)
+	}
+}
@@ -4,10 +4,12 @@ package server
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strings"
 	"time"
@@ -61,7 +63,10 @@ type responseWriter struct {
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
-	rw.status = code
+	// Informational responses such as 103 Early Hints are not the status.
+	if code >= http.StatusOK {
+		rw.status = code
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
 
@@ -78,6 +83,12 @@ func (rw *responseWriter) Flush() {
 	}
 }
 
+// FlushError is Flush reporting write errors, used by
+// http.ResponseController.
+func (rw *responseWriter) FlushError() error {
+	return http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
 // Unwrap returns the underlying ResponseWriter so http.NewResponseController
 // can discover interfaces like http.Flusher.
 func (rw *responseWriter) Unwrap() http.ResponseWriter {
@@ -135,6 +146,12 @@ func userIDFromCtx(ctx context.Context) string {
 	return "default"
 }
 
+// taskOwnerID returns the ID the preferences of t's owner are stored under:
+// t.OwnerID, or "default" in no-auth mode like userIDFromCtx.
+func taskOwnerID(t *task.Task) string {
+	return cmp.Or(t.OwnerID, "default")
+}
+
 // computeTaskPatch returns a sparse map containing only the fields that differ
 // between oldJSON and newJSON, always including "id". Fields present in oldJSON
 // but absent in newJSON are set to null so clients can clear them.
@@ -160,8 +177,17 @@ func computeTaskPatch(oldJSON, newJSON []byte) (map[string]json.RawMessage, erro
 	return patch, nil
 }
 
-// emitTaskListEvent marshals ev and writes it as an SSE message event.
-func emitTaskListEvent(w http.ResponseWriter, flusher http.Flusher, ev v1.TaskListEvent) error { //nolint:gocritic // struct size grew with Repos field; refactor not worth it
+// rawTaskListEvent is a v1.TaskListEvent carrying tasks already marshalled
+// through a taskProjection.
+type rawTaskListEvent struct {
+	Kind  string            `json:"kind"`
+	Tasks []json.RawMessage `json:"tasks,omitempty"`
+	Task  json.RawMessage   `json:"task,omitempty"`
+}
+
+// emitTaskListEvent marshals ev, a v1.TaskListEvent or rawTaskListEvent, and
+// writes it as an SSE message event.
+func emitTaskListEvent(w http.ResponseWriter, flusher http.Flusher, ev any) error {
 	data, err := json.Marshal(ev)
 	if err != nil {
 		return err
@@ -234,6 +260,15 @@ func tailscaleURL(t *task.Task) string {
 	return ""
 }
 
+// epochTime converts Unix epoch seconds, as the harnesses report them, to a
+// UTC time with millisecond precision. 0 is unset.
+func epochTime(sec float64) time.Time {
+	if sec <= 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(math.Round(sec * 1e3))).UTC()
+}
+
 // roundDuration rounds d to 3 significant digits with minimum 1us precision.
 func roundDuration(d time.Duration) time.Duration {
 	for t := 100 * time.Second; t >= 100*time.Microsecond; t /= 10 {
@@ -0,0 +1,180 @@
+// Idle policy: tasks left waiting for input are reported or finished.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// idlePollInterval is how often pollIdle looks for idle tasks.
+const idlePollInterval = time.Minute
+
+// pollIdle applies the idle policy every idlePollInterval until ctx is done.
+func (s *Server) pollIdle(ctx context.Context) {
+	ticker := time.NewTicker(idlePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.checkIdle(ctx, now)
+		}
+	}
+}
+
+// checkIdle acts on the tasks waiting for input longer than their idle
+// policy allows. The finish action is preceded by a warning in the transcript
+// so that the user can send a message to keep the task, which ends the
+// waiting period.
+func (s *Server) checkIdle(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	entries := make([]*taskEntry, 0, len(s.tasks))
+	for _, e := range s.tasks {
+		entries = append(entries, e)
+	}
+	s.mu.Unlock()
+	for _, e := range entries {
+		snap := e.task.Snapshot()
+		switch snap.State {
+		case task.StateWaiting, task.StateAsking, task.StateHasPlan, task.StatePlanReview:
+		default:
+			continue
+		}
+		p := s.idlePolicy(e.task)
+		timeout := p.Timeout()
+		if timeout <= 0 {
+			continue
+		}
+		idle := now.Sub(snap.StateUpdatedAt)
+		s.mu.Lock()
+		if !e.idleSince.Equal(snap.StateUpdatedAt) {
+			e.idleSince, e.idleWarnedAt, e.idleActed = snap.StateUpdatedAt, time.Time{}, false
+		}
+		warn := p.Action == task.IdleFinish && e.idleWarnedAt.IsZero() && idle >= timeout-p.Warning()
+		act := !warn && !e.idleActed && idle >= timeout && (p.Action != task.IdleFinish || now.Sub(e.idleWarnedAt) >= p.Warning())
+		if warn {
+			e.idleWarnedAt = now
+		}
+		if act {
+			e.idleActed = true
+		}
+		s.mu.Unlock()
+		switch {
+		case warn:
+			left := max(timeout-idle, p.Warning()).Round(time.Minute)
+			what := "pushed and stopped"
+			if !s.pushPolicy(e.task).Automatic() {
+				what = "stopped without pushing its branch"
+			}
+			e.task.AddIdleNotice(ctx, fmt.Sprintf("No input for %s: the task will be %s in %s. Send a message to keep it.", idle.Round(time.Minute), what, left))
+			s.notifyTaskChange()
+		case act && p.Action == task.IdleFinish:
+			go s.finishIdle(ctx, e)
+		case act:
+			e.task.AddIdleNotice(ctx, fmt.Sprintf("No input for %s.", idle.Round(time.Minute)))
+			s.notifyTaskChange()
+		}
+	}
+}
+
+// finishIdle pushes the branch of an idle task, opening its PR, and stops
+// it. The task is kept when the push fails or is blocked. The branch is not
+// pushed when the push policy is not auto; it stays in the stopped container.
+func (s *Server) finishIdle(ctx context.Context, e *taskEntry) {
+	t := e.task
+	slog.InfoContext(ctx, "finishing idle task", "task", t.ID)
+	if len(t.Repos) != 0 && !t.PlanOnly && !t.ReadOnly && !t.Chat && s.pushPolicy(t).Automatic() {
+		resp, err := s.syncTask(ctx, e, &v1.SyncReq{})
+		if err == nil && resp.Status == "blocked" {
+			err = fmt.Errorf("%d safety issues", len(resp.SafetyIssues))
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "idle task push failed", "task", t.ID, "err", err)
+			t.AddIdleNotice(ctx, "The idle task was kept: push failed: "+err.Error())
+			s.notifyTaskChange()
+			return
+		}
+	}
+	if _, err := s.stopTask(ctx, e, nil); err != nil {
+		slog.WarnContext(ctx, "idle task stop failed", "task", t.ID, "err", err)
+	}
+}
+
+// idlePolicy returns the idle policy of t: its own, else its repo's, else the
+// owner's global one.
+func (s *Server) idlePolicy(t *task.Task) task.IdlePolicy {
+	if t.Idle != nil {
+		return *t.Idle
+	}
+	ownerID := t.OwnerID
+	if ownerID == "" {
+		ownerID = "default"
+	}
+	settings := s.prefs.Get(ownerID).Settings
+	if p := t.Primary(); p != nil {
+		if ip, ok := settings.RepoIdlePolicies[p.Name]; ok {
+			return task.IdlePolicy{Hours: ip.Hours, Action: task.IdleAction(ip.Action)}
+		}
+	}
+	if ip := settings.IdlePolicy; ip != nil {
+		return task.IdlePolicy{Hours: ip.Hours, Action: task.IdleAction(ip.Action)}
+	}
+	return task.IdlePolicy{}
+}
+
+func toV1IdlePolicy(p *task.IdlePolicy) *v1.IdlePolicy {
+	if p == nil {
+		return nil
+	}
+	return &v1.IdlePolicy{Hours: p.Hours, Action: v1.IdleAction(p.Action)}
+}
+
+func fromV1IdlePolicy(p *v1.IdlePolicy) *task.IdlePolicy {
+	if p == nil {
+		return nil
+	}
+	return &task.IdlePolicy{Hours: p.Hours, Action: task.IdleAction(p.Action)}
+}
+
+func prefsToV1IdlePolicy(p *preferences.IdlePolicy) *v1.IdlePolicy {
+	if p == nil {
+		return nil
+	}
+	return &v1.IdlePolicy{Hours: p.Hours, Action: v1.IdleAction(p.Action)}
+}
+
+func prefsFromV1IdlePolicy(p *v1.IdlePolicy) *preferences.IdlePolicy {
+	if p == nil {
+		return nil
+	}
+	return &preferences.IdlePolicy{Hours: p.Hours, Action: string(p.Action)}
+}
+
+func prefsToV1RepoIdlePolicies(m map[string]preferences.IdlePolicy) map[string]v1.IdlePolicy {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]v1.IdlePolicy, len(m))
+	for repo, p := range m {
+		out[repo] = v1.IdlePolicy{Hours: p.Hours, Action: v1.IdleAction(p.Action)}
+	}
+	return out
+}
+
+func prefsFromV1RepoIdlePolicies(m map[string]v1.IdlePolicy) map[string]preferences.IdlePolicy {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]preferences.IdlePolicy, len(m))
+	for repo, p := range m {
+		out[repo] = preferences.IdlePolicy{Hours: p.Hours, Action: string(p.Action)}
+	}
+	return out
+}
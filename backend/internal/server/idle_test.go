@@ -0,0 +1,86 @@
+// Tests for the idle policy.
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func idleNotices(tk *task.Task) []string {
+	var out []string
+	for _, m := range tk.Messages() {
+		if sm, ok := m.(*agent.SystemMessage); ok && sm.Subtype == "caic_idle" {
+			out = append(out, sm.Detail)
+		}
+	}
+	return out
+}
+
+func TestCheckIdle(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newIdleTask := func(s *Server, p *task.IdlePolicy) *task.Task {
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}, Idle: p}
+		tk.SetStateAt(task.StateWaiting, start)
+		s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+		return tk
+	}
+	t.Run("Notify", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := newIdleTask(s, &task.IdlePolicy{Hours: 2, Action: task.IdleNotify})
+		s.checkIdle(t.Context(), start.Add(time.Hour))
+		if got := idleNotices(tk); len(got) != 0 {
+			t.Fatalf("notices before timeout = %q", got)
+		}
+		s.checkIdle(t.Context(), start.Add(2*time.Hour))
+		s.checkIdle(t.Context(), start.Add(3*time.Hour))
+		got := idleNotices(tk)
+		if len(got) != 1 || got[0] != "No input for 2h0m0s." {
+			t.Errorf("notices = %q, want one", got)
+		}
+	})
+	t.Run("FinishWarnsFirst", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := newIdleTask(s, &task.IdlePolicy{Hours: 8, Action: task.IdleFinish})
+		// The task went idle long ago, e.g. while the server was down: it
+		// is still warned before being finished.
+		s.checkIdle(t.Context(), start.Add(24*time.Hour))
+		got := idleNotices(tk)
+		if len(got) != 1 {
+			t.Fatalf("notices = %q, want one warning", got)
+		}
+		if want := "No input for 24h0m0s: the task will be pushed and stopped in 1h0m0s. Send a message to keep it."; got[0] != want {
+			t.Errorf("warning = %q, want %q", got[0], want)
+		}
+		if st := tk.GetState(); st != task.StateWaiting {
+			t.Errorf("state = %v, want waiting", st)
+		}
+	})
+	t.Run("NewInputResets", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := newIdleTask(s, &task.IdlePolicy{Hours: 1, Action: task.IdleNotify})
+		s.checkIdle(t.Context(), start.Add(time.Hour))
+		tk.SetStateAt(task.StateWaiting, start.Add(2*time.Hour))
+		s.checkIdle(t.Context(), start.Add(3*time.Hour))
+		if got := idleNotices(tk); len(got) != 2 {
+			t.Errorf("notices = %q, want two", got)
+		}
+	})
+	t.Run("RepoOverridesGlobal", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := newIdleTask(s, nil)
+		tk.Repos = []task.RepoMount{{Name: "org/repo"}}
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.IdlePolicy = &preferences.IdlePolicy{Hours: 1, Action: "finish"}
+			p.Settings.RepoIdlePolicies = map[string]preferences.IdlePolicy{"org/repo": {Hours: 0, Action: "notify"}}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if p := s.idlePolicy(tk); p.Timeout() != 0 {
+			t.Errorf("policy = %+v, want disabled", p)
+		}
+	})
+}
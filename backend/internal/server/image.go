@@ -0,0 +1,163 @@
+// Canary validation of base images: a changed base image is smoke tested in throwaway containers before tasks use it.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/container"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/md"
+)
+
+// imageCheckTimeout bounds a whole smoke test, including the image pull.
+const imageCheckTimeout = 15 * time.Minute
+
+// cloneScript commits in a new repository and clones it, which needs a
+// working git and a writable home.
+const cloneScript = `set -e; d=$(mktemp -d); git init -q "$d/src"; ` +
+	`git -C "$d/src" -c user.name=caic -c user.email=caic@localhost commit -q --allow-empty -m canary; ` +
+	`git clone -q "$d/src" "$d/clone"; git -C "$d/clone" log --oneline | head -n 1`
+
+// validateBaseImage smoke tests the requested image, or the caller's pending
+// base image when none is given.
+func (s *Server) validateBaseImage(ctx context.Context, req *v1.ValidateImageReq) (*v1.ImageValidationResp, error) {
+	ownerID := userIDFromCtx(ctx)
+	image := req.Image
+	if image == "" {
+		pi := s.prefs.Get(ownerID).Settings.PendingBaseImage
+		if pi == nil {
+			return nil, dto.BadRequest("no pending base image to validate")
+		}
+		image = pi.Image
+	}
+	// A failed pending image is being retried.
+	if err := s.prefs.Update(ownerID, func(p *preferences.Preferences) {
+		if pi := p.Settings.PendingBaseImage; pi != nil && pi.Image == image {
+			p.Settings.PendingBaseImage = &preferences.PendingImage{Image: image, Status: string(v1.ImageValidating)}
+		}
+	}); err != nil {
+		return nil, dto.InternalError("save preferences: " + err.Error())
+	}
+	return s.validateImage(ctx, ownerID, image)
+}
+
+// validateImage smoke tests image. When it is ownerID's pending base image,
+// it becomes the base image if all checks pass, else it is marked failed.
+func (s *Server) validateImage(ctx context.Context, ownerID, image string) (*v1.ImageValidationResp, error) {
+	slog.InfoContext(ctx, "validating base image", "user", ownerID, "image", image)
+	resp := &v1.ImageValidationResp{Image: image, OK: true, Checks: s.checkImage(ctx, image)}
+	var failed []string
+	for _, c := range resp.Checks {
+		if !c.OK {
+			resp.OK = false
+			failed = append(failed, c.Name+": "+c.Detail)
+		}
+	}
+	promoted, err := s.settlePendingImage(ownerID, image, strings.Join(failed, "; "))
+	if err != nil {
+		slog.WarnContext(ctx, "base image validation not saved", "user", ownerID, "image", image, "err", err)
+		return nil, dto.InternalError("save preferences: " + err.Error())
+	}
+	resp.Promoted = promoted
+	slog.InfoContext(ctx, "validated base image", "user", ownerID, "image", image, "ok", resp.OK, "promoted", promoted)
+	return resp, nil
+}
+
+// settlePendingImage ends the validation of ownerID's pending base image if
+// it is image: with an empty errMsg it becomes the base image, else it is
+// marked failed. It reports whether the image was promoted.
+func (s *Server) settlePendingImage(ownerID, image, errMsg string) (bool, error) {
+	promoted := false
+	err := s.prefs.Update(ownerID, func(p *preferences.Preferences) {
+		if pi := p.Settings.PendingBaseImage; pi == nil || pi.Image != image {
+			return
+		}
+		if errMsg == "" {
+			p.Settings.BaseImage, p.Settings.PendingBaseImage, promoted = image, nil, true
+			return
+		}
+		p.Settings.PendingBaseImage = &preferences.PendingImage{Image: image, Status: string(v1.ImageFailed), Error: errMsg}
+	})
+	return promoted, err
+}
+
+// resumeImageValidations restarts the validations interrupted by a restart.
+func (s *Server) resumeImageValidations(ctx context.Context) {
+	for ownerID, image := range s.prefs.PendingBaseImages() {
+		go func() {
+			_, _ = s.validateImage(ctx, ownerID, image)
+		}()
+	}
+}
+
+// checkImage pulls image and builds the layer caic adds on top, then, each in
+// a throwaway container, clones a repository and runs every harness binary.
+// Later checks are skipped when the pull fails.
+func (s *Server) checkImage(ctx context.Context, image string) []v1.ImageCheck {
+	ctx, cancel := context.WithTimeout(ctx, imageCheckTimeout)
+	defer cancel()
+	var out []v1.ImageCheck
+	add := func(name string, err error, detail string) bool {
+		c := v1.ImageCheck{Name: name, OK: err == nil, Detail: detail}
+		if err != nil {
+			c.Detail = err.Error()
+		}
+		out = append(out, c)
+		return err == nil
+	}
+	if s.mdClient == nil {
+		add("pull", errors.New("container runtime unavailable"), "")
+		return out
+	}
+	w := &container.SlogWriter{Phase: "canary"}
+	if _, err := s.mdClient.Warmup(ctx, w, w, &md.WarmupOpts{BaseImage: image, Quiet: true}); !add("pull", err, image) {
+		return out
+	}
+	runtime := s.mdClient.Runtime
+	commit, err := container.ImageExec(ctx, runtime, image, cloneScript)
+	if err != nil && commit != "" {
+		err = fmt.Errorf("%w: %s", err, commit)
+	}
+	add("clone", err, commit)
+	backends := s.harnessBackends()
+	for _, h := range slices.Sorted(maps.Keys(backends)) {
+		hh := v1.HarnessHealth{Name: string(h), Image: image, Binary: backends[h].Binary()}
+		err := harnessInstalled(ctx, runtime, &hh)
+		add("harness "+hh.Name, err, hh.Binary+" "+hh.Version)
+	}
+	return out
+}
+
+// applyBaseImage sets the base image in st. With validation enabled, a
+// changed image only becomes pending and applyBaseImage reports that it must
+// be validated; the default image is always trusted. Requesting the current
+// base image keeps a pending one, so that clients unaware of it do not cancel
+// it.
+func applyBaseImage(st *preferences.Settings, image string, validate bool) bool {
+	st.ValidateBaseImage = validate
+	if !validate || image == "" {
+		st.BaseImage, st.PendingBaseImage = image, nil
+		return false
+	}
+	if image == st.BaseImage || (st.PendingBaseImage != nil && st.PendingBaseImage.Image == image) {
+		return false
+	}
+	st.PendingBaseImage = &preferences.PendingImage{Image: image, Status: string(v1.ImageValidating)}
+	return true
+}
+
+func prefsToV1PendingImage(p *preferences.PendingImage) *v1.PendingImage {
+	if p == nil {
+		return nil
+	}
+	return &v1.PendingImage{Image: p.Image, Status: v1.ImageStatus(p.Status), Error: p.Error}
+}
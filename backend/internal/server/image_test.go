@@ -0,0 +1,96 @@
+// Tests for the base image validation.
+package server
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+func TestApplyBaseImage(t *testing.T) {
+	pending := func(image string) *preferences.PendingImage {
+		return &preferences.PendingImage{Image: image, Status: "validating"}
+	}
+	tests := []struct {
+		name      string
+		st        preferences.Settings
+		image     string
+		validate  bool
+		want      preferences.Settings
+		wantCheck bool
+	}{
+		{"Direct", preferences.Settings{BaseImage: "a"}, "b", false, preferences.Settings{BaseImage: "b"}, false},
+		{"Pending", preferences.Settings{BaseImage: "a"}, "b", true, preferences.Settings{BaseImage: "a", ValidateBaseImage: true, PendingBaseImage: pending("b")}, true},
+		{"KeepPending", preferences.Settings{BaseImage: "a", PendingBaseImage: pending("b")}, "a", true, preferences.Settings{BaseImage: "a", ValidateBaseImage: true, PendingBaseImage: pending("b")}, false},
+		{"SamePending", preferences.Settings{BaseImage: "a", PendingBaseImage: pending("b")}, "b", true, preferences.Settings{BaseImage: "a", ValidateBaseImage: true, PendingBaseImage: pending("b")}, false},
+		{"Default", preferences.Settings{BaseImage: "a", PendingBaseImage: pending("b")}, "", true, preferences.Settings{ValidateBaseImage: true}, false},
+		{"Disabled", preferences.Settings{BaseImage: "a", PendingBaseImage: pending("b")}, "c", false, preferences.Settings{BaseImage: "c"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := tt.st
+			if got := applyBaseImage(&st, tt.image, tt.validate); got != tt.wantCheck {
+				t.Errorf("validate = %v, want %v", got, tt.wantCheck)
+			}
+			if st.BaseImage != tt.want.BaseImage || st.ValidateBaseImage != tt.want.ValidateBaseImage {
+				t.Errorf("got %+v, want %+v", st, tt.want)
+			}
+			if (st.PendingBaseImage == nil) != (tt.want.PendingBaseImage == nil) || (st.PendingBaseImage != nil && *st.PendingBaseImage != *tt.want.PendingBaseImage) {
+				t.Errorf("pending = %+v, want %+v", st.PendingBaseImage, tt.want.PendingBaseImage)
+			}
+		})
+	}
+}
+
+func TestValidateBaseImage(t *testing.T) {
+	setPending := func(t *testing.T, s *Server, pi *preferences.PendingImage) {
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.BaseImage = "ghcr.io/acme/dev:1"
+			p.Settings.PendingBaseImage = pi
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Run("NothingPending", func(t *testing.T) {
+		s := newTestServer(t)
+		_, err := s.validateBaseImage(t.Context(), &v1.ValidateImageReq{})
+		var apiErr *dto.APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode() != http.StatusBadRequest {
+			t.Fatalf("err = %v, want bad request", err)
+		}
+	})
+	t.Run("Failed", func(t *testing.T) {
+		// Without a container runtime the pull check fails.
+		s := newTestServer(t)
+		setPending(t, s, &preferences.PendingImage{Image: "ghcr.io/acme/dev:2", Status: "validating"})
+		resp, err := s.validateBaseImage(t.Context(), &v1.ValidateImageReq{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.OK || resp.Promoted || len(resp.Checks) != 1 || resp.Checks[0].Name != "pull" {
+			t.Errorf("resp = %+v", resp)
+		}
+		st := s.prefs.Get("default").Settings
+		if st.BaseImage != "ghcr.io/acme/dev:1" || st.PendingBaseImage == nil || st.PendingBaseImage.Status != "failed" || st.PendingBaseImage.Error == "" {
+			t.Errorf("settings = %+v, pending = %+v", st, st.PendingBaseImage)
+		}
+	})
+	t.Run("Promoted", func(t *testing.T) {
+		s := newTestServer(t)
+		setPending(t, s, &preferences.PendingImage{Image: "ghcr.io/acme/dev:2", Status: "validating"})
+		if promoted, err := s.settlePendingImage("default", "ghcr.io/acme/dev:3", ""); err != nil || promoted {
+			t.Fatalf("other image: promoted = %v, err = %v", promoted, err)
+		}
+		if promoted, err := s.settlePendingImage("default", "ghcr.io/acme/dev:2", ""); err != nil || !promoted {
+			t.Fatalf("promoted = %v, err = %v", promoted, err)
+		}
+		st := s.prefs.Get("default").Settings
+		if st.BaseImage != "ghcr.io/acme/dev:2" || st.PendingBaseImage != nil {
+			t.Errorf("settings = %+v", st)
+		}
+	})
+}
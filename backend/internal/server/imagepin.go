@@ -0,0 +1,121 @@
+// Digest pinning of base images: new tasks use the pinned digest of an image until the user adopts a newer one.
+package server
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/caic-xyz/caic/backend/internal/container"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/md"
+)
+
+// defaultImage is the base image of the tasks when none is configured.
+const defaultImage = md.DefaultBaseImage + ":latest"
+
+// pinnedImage returns image, the default image when empty, pinned to its
+// digest in st. An image that isn't pinned is returned unchanged.
+func pinnedImage(st *preferences.Settings, image string) string {
+	ref := cmp.Or(image, defaultImage)
+	if d := st.ImageDigests[ref]; d != "" {
+		return ref + "@" + d
+	}
+	return image
+}
+
+// pullDigest pulls image and returns the registry digest it resolves to.
+func (s *Server) pullDigest(ctx context.Context, image string) (string, error) {
+	if s.mdClient == nil {
+		return "", dto.InternalError("container runtime unavailable")
+	}
+	ctx, cancel := context.WithTimeout(ctx, imageCheckTimeout)
+	defer cancel()
+	w := &container.SlogWriter{Phase: "pin"}
+	if err := container.PullImage(ctx, s.mdClient.Runtime, image, w); err != nil {
+		return "", dto.InternalError(err.Error())
+	}
+	d, err := container.ImageDigest(ctx, s.mdClient.Runtime, image)
+	if err != nil {
+		return "", dto.BadRequest("cannot pin " + image + ": " + err.Error())
+	}
+	return d, nil
+}
+
+// toV1ImagePins converts the pinned digests for the API, sorted by image.
+func toV1ImagePins(digests map[string]string) *v1.ImagePinsResp {
+	resp := &v1.ImagePinsResp{Pins: make([]v1.ImagePin, 0, len(digests))}
+	for _, image := range slices.Sorted(maps.Keys(digests)) {
+		resp.Pins = append(resp.Pins, v1.ImagePin{Image: image, Digest: digests[image]})
+	}
+	return resp
+}
+
+func (s *Server) listImagePins(ctx context.Context, _ *dto.EmptyReq) (*v1.ImagePinsResp, error) {
+	return toV1ImagePins(s.prefs.Get(userIDFromCtx(ctx)).Settings.ImageDigests), nil
+}
+
+// pinImage pins the requested image, else the caller's base image, to the
+// digest its reference currently resolves to.
+func (s *Server) pinImage(ctx context.Context, req *v1.ImagePinReq) (*v1.ImagePinsResp, error) {
+	ownerID := userIDFromCtx(ctx)
+	image := cmp.Or(req.Image, s.prefs.Get(ownerID).Settings.BaseImage, defaultImage)
+	digest, err := s.pullDigest(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	var digests map[string]string
+	if err := s.prefs.Update(ownerID, func(p *preferences.Preferences) {
+		p.Settings.ImageDigests = maps.Clone(p.Settings.ImageDigests)
+		if p.Settings.ImageDigests == nil {
+			p.Settings.ImageDigests = map[string]string{}
+		}
+		p.Settings.ImageDigests[image] = digest
+		digests = p.Settings.ImageDigests
+	}); err != nil {
+		return nil, dto.InternalError("save preferences: " + err.Error())
+	}
+	slog.InfoContext(ctx, "pinned base image", "user", ownerID, "image", image, "digest", digest)
+	go s.warmupImage(image + "@" + digest) //nolint:contextcheck // outlives the request
+	return toV1ImagePins(digests), nil
+}
+
+// unpinImage removes the pin of the requested image, else of the caller's
+// base image.
+func (s *Server) unpinImage(ctx context.Context, req *v1.ImagePinReq) (*v1.ImagePinsResp, error) {
+	ownerID := userIDFromCtx(ctx)
+	image := cmp.Or(req.Image, s.prefs.Get(ownerID).Settings.BaseImage, defaultImage)
+	found := false
+	var digests map[string]string
+	if err := s.prefs.Update(ownerID, func(p *preferences.Preferences) {
+		if _, found = p.Settings.ImageDigests[image]; found {
+			p.Settings.ImageDigests = maps.Clone(p.Settings.ImageDigests)
+			delete(p.Settings.ImageDigests, image)
+		}
+		digests = p.Settings.ImageDigests
+	}); err != nil {
+		return nil, dto.InternalError("save preferences: " + err.Error())
+	}
+	if !found {
+		return nil, dto.NotFound("image pin").WithParam("image", image)
+	}
+	return toV1ImagePins(digests), nil
+}
+
+// checkImageUpdate pulls the requested image, else the caller's base image,
+// and compares the digest its reference resolves to with the pinned one. The
+// pull also prefetches the update for when it is pinned.
+func (s *Server) checkImageUpdate(ctx context.Context, req *v1.ImagePinReq) (*v1.ImageUpdateResp, error) {
+	settings := s.prefs.Get(userIDFromCtx(ctx)).Settings
+	image := cmp.Or(req.Image, settings.BaseImage, defaultImage)
+	latest, err := s.pullDigest(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+	pinned := settings.ImageDigests[image]
+	return &v1.ImageUpdateResp{Image: image, Pinned: pinned, Latest: latest, UpdateAvailable: pinned != "" && pinned != latest}, nil
+}
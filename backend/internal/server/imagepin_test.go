@@ -0,0 +1,72 @@
+// Tests for the digest pinning of base images.
+package server
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+func TestPinnedImage(t *testing.T) {
+	st := &preferences.Settings{ImageDigests: map[string]string{
+		"org/img:1":  "sha256:aa",
+		defaultImage: "sha256:bb",
+	}}
+	for _, tt := range []struct{ image, want string }{
+		{"org/img:1", "org/img:1@sha256:aa"},
+		{"org/img:2", "org/img:2"},
+		{"", defaultImage + "@sha256:bb"},
+	} {
+		if got := pinnedImage(st, tt.image); got != tt.want {
+			t.Errorf("pinnedImage(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+	if got := pinnedImage(&preferences.Settings{}, ""); got != "" {
+		t.Errorf("pinnedImage(\"\") = %q, want the default", got)
+	}
+}
+
+func TestImagePins(t *testing.T) {
+	s := newTestServer(t)
+	ctx := t.Context()
+	if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+		p.Settings.BaseImage = "org/img:1"
+		p.Settings.ImageDigests = map[string]string{"org/img:1": "sha256:aa", "org/other:1": "sha256:bb"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := s.listImagePins(ctx, nil)
+	if err != nil || len(resp.Pins) != 2 || resp.Pins[0] != (v1.ImagePin{Image: "org/img:1", Digest: "sha256:aa"}) {
+		t.Fatalf("list = %+v, %v", resp, err)
+	}
+	t.Run("Unpin", func(t *testing.T) {
+		// An empty image is the caller's base image.
+		resp, err := s.unpinImage(ctx, &v1.ImagePinReq{})
+		if err != nil || len(resp.Pins) != 1 || resp.Pins[0].Image != "org/other:1" {
+			t.Fatalf("unpin = %+v, %v", resp, err)
+		}
+		var apiErr *dto.APIError
+		if _, err := s.unpinImage(ctx, &v1.ImagePinReq{}); !errors.As(err, &apiErr) || apiErr.StatusCode() != http.StatusNotFound {
+			t.Errorf("unpin again = %v, want 404", err)
+		}
+	})
+	t.Run("NoRuntime", func(t *testing.T) {
+		if _, err := s.pinImage(ctx, &v1.ImagePinReq{Image: "org/img:2"}); err == nil {
+			t.Error("pin without a container runtime succeeded")
+		}
+		if _, err := s.checkImageUpdate(ctx, &v1.ImagePinReq{}); err == nil {
+			t.Error("check without a container runtime succeeded")
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, image := range []string{"-rm", "org/img@sha256:aa"} {
+			if err := (&v1.ImagePinReq{Image: image}).Validate(); err == nil {
+				t.Errorf("%q: expected a validation error", image)
+			}
+		}
+	})
+}
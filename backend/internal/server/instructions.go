@@ -0,0 +1,35 @@
+// Custom agent instructions merged from the repo, the user's settings and the task.
+package server
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// taskInstructions merges the custom instructions of a new task on repo: the
+// repo's task.InstructionsPath on baseBranch, the user's RepoInstructions,
+// then the task's own, so the later ones take precedence. repo is empty for
+// tasks without one.
+func taskInstructions(ctx context.Context, runner *task.Runner, baseBranch string, settings *preferences.Settings, repo, own string) (string, error) {
+	var fromRepo string
+	if repo != "" {
+		fromRepo = runner.RepoInstructions(ctx, baseBranch)
+	}
+	var parts []string
+	for _, p := range []string{fromRepo, settings.RepoInstructions[repo], own} {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	merged := strings.Join(parts, "\n\n")
+	if len(merged) > v1.MaxInstructions {
+		return "", dto.BadRequest("the merged instructions of " + task.InstructionsPath + ", the settings and the task exceed " + strconv.Itoa(v1.MaxInstructions>>10) + " KiB")
+	}
+	return merged, nil
+}
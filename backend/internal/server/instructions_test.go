@@ -0,0 +1,54 @@
+// Tests for the custom agent instructions.
+package server
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func TestTaskInstructions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ".caic"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, task.InstructionsPath), []byte("From the repo.\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"-c", "user.name=Test", "-c", "user.email=test@test.com", "add", "."},
+		{"-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args, err, out)
+		}
+	}
+	runner := &task.Runner{BaseBranch: "main", Dir: dir}
+	settings := &preferences.Settings{RepoInstructions: map[string]string{"org/repo": "From the settings."}}
+	t.Run("Merged", func(t *testing.T) {
+		got, err := taskInstructions(t.Context(), runner, "", settings, "org/repo", " From the task. ")
+		if want := "From the repo.\n\nFrom the settings.\n\nFrom the task."; err != nil || got != want {
+			t.Errorf("got %q, %v; want %q", got, err, want)
+		}
+	})
+	t.Run("NoRepo", func(t *testing.T) {
+		got, err := taskInstructions(t.Context(), runner, "", settings, "", "")
+		if err != nil || got != "" {
+			t.Errorf("got %q, %v", got, err)
+		}
+	})
+	t.Run("TooLarge", func(t *testing.T) {
+		if _, err := taskInstructions(t.Context(), runner, "", settings, "org/repo", strings.Repeat("x", v1.MaxInstructions)); err == nil {
+			t.Error("expected an error")
+		}
+	})
+}
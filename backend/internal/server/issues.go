@@ -0,0 +1,160 @@
+// Jira and Linear issue linking: tasks linked to an issue post their progress on it.
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/caic/backend/internal/tracker"
+)
+
+const (
+	// maxIssueKeys caps the issue keys of a prompt looked up in the trackers.
+	maxIssueKeys = 3
+	// issueLookupTimeout bounds the issue lookups of a task creation.
+	issueLookupTimeout = 10 * time.Second
+	// maxIssueResult caps the agent result quoted in the finished comment.
+	maxIssueResult = 2000
+)
+
+// resolveIssue returns the issue a new task is linked to: req.Issue, else
+// the first issue mentioned in the prompt that a tracker knows. An empty
+// prompt is filled from the issue. It returns nil without trackers or issue.
+func (s *Server) resolveIssue(ctx context.Context, req *v1.CreateTaskReq) (*task.IssueLink, error) {
+	if len(s.trackers) == 0 {
+		if req.Issue != "" {
+			return nil, dto.BadRequest("no issue tracker configured")
+		}
+		return nil, nil
+	}
+	keys := []string{req.Issue}
+	if req.Issue == "" {
+		if keys = tracker.FindKeys(req.InitialPrompt.Text); len(keys) == 0 {
+			return nil, nil
+		}
+		keys = keys[:min(len(keys), maxIssueKeys)]
+	}
+	lookupCtx, cancel := context.WithTimeout(ctx, issueLookupTimeout)
+	defer cancel()
+	tr, issue, err := tracker.Resolve(lookupCtx, s.trackers, keys)
+	if issue == nil {
+		if req.Issue == "" {
+			if err != nil {
+				slog.WarnContext(ctx, "issue lookup", "keys", keys, "err", err)
+			}
+			return nil, nil
+		}
+		if err != nil {
+			return nil, dto.InternalError("issue lookup: " + err.Error())
+		}
+		return nil, dto.NotFound("issue").WithParam("issue", req.Issue)
+	}
+	if req.InitialPrompt.Text == "" && len(req.InitialPrompt.Images) == 0 {
+		req.InitialPrompt.Text = issuePrompt(issue)
+	}
+	return &task.IssueLink{Tracker: string(tr.Kind()), Key: issue.Key, URL: issue.URL}, nil
+}
+
+// issuePrompt returns the prompt of a task created from issue.
+func issuePrompt(issue *tracker.Issue) string {
+	p := issue.Key + ": " + issue.Title
+	if d := strings.TrimSpace(issue.Description); d != "" {
+		p += "\n\n" + d
+	}
+	if issue.URL != "" {
+		p += "\n\n" + issue.URL
+	}
+	return p
+}
+
+// trackerOf returns the tracker of the issue linked to t, or nil.
+func (s *Server) trackerOf(t *task.Task) tracker.Tracker {
+	if t.Issue == nil {
+		return nil
+	}
+	for _, tr := range s.trackers {
+		if string(tr.Kind()) == t.Issue.Tracker {
+			return tr
+		}
+	}
+	return nil
+}
+
+// commentIssue posts body on the issue linked to t, if any.
+func (s *Server) commentIssue(ctx context.Context, t *task.Task, body string) {
+	tr := s.trackerOf(t)
+	if tr == nil {
+		return
+	}
+	if err := tr.Comment(ctx, t.Issue.Key, body); err != nil {
+		slog.WarnContext(ctx, "issue comment", "task", t.ID, "issue", t.Issue.Key, "err", err)
+	}
+}
+
+// taskRef names t in issue comments and notifications, with a link when
+// the server knows its external URL.
+func (s *Server) taskRef(t *task.Task) string {
+	name := "caic task " + cmp.Or(t.Alias, t.ID.String())
+	if u := s.taskURL(t); u != "" {
+		name += " (" + u + ")"
+	}
+	return name
+}
+
+// watchIssue posts on the issue linked to the task of entry when it starts,
+// unless resumed after a restart, and when its agent finishes.
+func (s *Server) watchIssue(entry *taskEntry, resumed bool) {
+	t := entry.task
+	if s.trackerOf(t) == nil {
+		return
+	}
+	if !resumed {
+		s.commentIssue(s.ctx, t, s.taskRef(t)+" started working on this issue.")
+	}
+	state, result, err := s.WatchTaskCompletion(s.ctx, t.ID.String())
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			slog.Warn("issue: watch task", "task", t.ID, "err", err)
+		}
+		return
+	}
+	body := fmt.Sprintf("%s finished: %s.", s.taskRef(t), state)
+	if result = strings.TrimSpace(result); result != "" {
+		if len(result) > maxIssueResult {
+			result = result[:maxIssueResult] + "…"
+		}
+		body += "\n\n" + result
+	}
+	s.commentIssue(s.ctx, t, body)
+}
+
+// resumeIssueWatchers re-attaches watchIssue to the unfinished tasks linked
+// to an issue after a restart.
+func (s *Server) resumeIssueWatchers() {
+	if len(s.trackers) == 0 {
+		return
+	}
+	s.mu.Lock()
+	var entries []*taskEntry
+	for _, e := range s.tasks {
+		switch e.task.GetState() { //nolint:exhaustive // only unfinished states are relevant
+		case task.StateWaiting, task.StateStopped, task.StateFailed, task.StatePurged:
+		default:
+			if e.task.Issue != nil {
+				entries = append(entries, e)
+			}
+		}
+	}
+	s.mu.Unlock()
+	for _, e := range entries {
+		go s.watchIssue(e, true)
+	}
+}
@@ -0,0 +1,86 @@
+// Tests for the Jira and Linear issue linking.
+package server
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/caic/backend/internal/tracker"
+	"github.com/maruel/ksid"
+)
+
+// stubTracker is a tracker.Tracker holding issues in memory.
+type stubTracker struct {
+	issues map[string]tracker.Issue
+
+	mu       sync.Mutex
+	comments []string
+}
+
+func (s *stubTracker) Kind() tracker.Kind { return tracker.KindLinear }
+
+func (s *stubTracker) Issue(_ context.Context, key string) (*tracker.Issue, error) {
+	if is, ok := s.issues[key]; ok {
+		return &is, nil
+	}
+	return nil, tracker.ErrNotFound
+}
+
+func (s *stubTracker) Comment(_ context.Context, key, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.comments = append(s.comments, key+": "+body)
+	return nil
+}
+
+func TestIssues(t *testing.T) {
+	tr := &stubTracker{issues: map[string]tracker.Issue{
+		"ENG-7": {Key: "ENG-7", Title: "Slow login", Description: "It takes 5s.", URL: "https://linear.app/x/issue/ENG-7"},
+	}}
+	t.Run("resolveIssue", func(t *testing.T) {
+		s := newTestServer(t)
+		req := &v1.CreateTaskReq{InitialPrompt: v1.Prompt{Text: "fix ENG-7"}}
+		if got, err := s.resolveIssue(t.Context(), req); got != nil || err != nil {
+			t.Errorf("without trackers = %+v, %v; want nil", got, err)
+		}
+		s.trackers = []tracker.Tracker{tr}
+
+		got, err := s.resolveIssue(t.Context(), req)
+		if err != nil || got == nil || *got != (task.IssueLink{Tracker: "linear", Key: "ENG-7", URL: "https://linear.app/x/issue/ENG-7"}) {
+			t.Errorf("from prompt = %+v, %v", got, err)
+		}
+		if got, err := s.resolveIssue(t.Context(), &v1.CreateTaskReq{InitialPrompt: v1.Prompt{Text: "see ENG-8"}}); got != nil || err != nil {
+			t.Errorf("unknown key in prompt = %+v, %v; want nil", got, err)
+		}
+		if _, err := s.resolveIssue(t.Context(), &v1.CreateTaskReq{Issue: "ENG-8"}); err == nil {
+			t.Error("unknown explicit issue succeeded")
+		}
+
+		req = &v1.CreateTaskReq{Issue: "ENG-7"}
+		if got, err := s.resolveIssue(t.Context(), req); err != nil || got == nil {
+			t.Fatalf("explicit issue = %+v, %v", got, err)
+		}
+		if want := "ENG-7: Slow login\n\nIt takes 5s.\n\nhttps://linear.app/x/issue/ENG-7"; req.InitialPrompt.Text != want {
+			t.Errorf("prompt = %q, want %q", req.InitialPrompt.Text, want)
+		}
+	})
+	t.Run("watchIssue", func(t *testing.T) {
+		s := newTestServer(t)
+		s.trackers = []tracker.Tracker{tr}
+		tk := &task.Task{ID: ksid.NewID(), Alias: "w3", Issue: &task.IssueLink{Tracker: "linear", Key: "ENG-7"}}
+		tk.SetState(task.StateWaiting)
+		entry := &taskEntry{task: tk, done: make(chan struct{})}
+		s.tasks[tk.ID.String()] = entry
+		s.watchIssue(entry, false)
+		if len(tr.comments) != 2 || tr.comments[0] != "ENG-7: caic task w3 started working on this issue." || !strings.HasPrefix(tr.comments[1], "ENG-7: caic task w3 finished: waiting.") {
+			t.Errorf("comments = %q", tr.comments)
+		}
+		if s.trackerOf(&task.Task{Issue: &task.IssueLink{Tracker: "jira", Key: "PROJ-1"}}) != nil {
+			t.Error("trackerOf found an unconfigured tracker")
+		}
+	})
+}
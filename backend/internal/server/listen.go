@@ -0,0 +1,61 @@
+// HTTP protocols served: HTTP/1.1 and HTTP/2, with or without TLS, and optionally HTTP/3 over QUIC.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// httpProtocols returns the protocols of the TCP listener. Browsers only use
+// HTTP/2 over TLS; h2c serves reverse proxies forwarding HTTP/2 in plaintext.
+func httpProtocols() *http.Protocols {
+	p := &http.Protocols{}
+	p.SetHTTP1(true)
+	p.SetHTTP2(true)
+	p.SetUnencryptedHTTP2(true)
+	return p
+}
+
+// listenHTTP3 serves handler over HTTP/3 on the UDP port of addr until the
+// returned server is shut down, and returns the UDP port. The port is bound
+// before returning so that an error is reported at startup rather than
+// advertised to browsers.
+func listenHTTP3(ctx context.Context, addr string, handler http.Handler, cert tls.Certificate) (*http3.Server, int, error) {
+	conn, err := (&net.ListenConfig{}).ListenPacket(ctx, "udp", addr)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listen HTTP/3: %w", err)
+	}
+	h3 := &http3.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS13}),
+	}
+	go func() {
+		if err := h3.Serve(conn); err != nil && !errors.Is(err, quic.ErrServerClosed) && !errors.Is(err, http.ErrServerClosed) {
+			slog.ErrorContext(ctx, "HTTP/3 server stopped", "addr", addr, "err", err)
+		}
+		_ = conn.Close()
+	}()
+	return h3, conn.LocalAddr().(*net.UDPAddr).Port, nil
+}
+
+// advertiseHTTP3 adds the Alt-Svc header announcing HTTP/3 on the UDP port
+// to the responses of the TCP listener, so that browsers switch to it on later
+// requests.
+func advertiseHTTP3(port int, next http.Handler) http.Handler {
+	altSvc := fmt.Sprintf(`%s=":%d"; ma=86400`, http3.NextProtoH3, port)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			w.Header().Set("Alt-Svc", altSvc)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
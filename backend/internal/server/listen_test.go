@@ -0,0 +1,73 @@
+// Tests for the HTTP protocols served.
+package server
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestListen(t *testing.T) {
+	proto := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.Proto)
+	})
+	get := func(t *testing.T, c *http.Client, url string) string {
+		resp, err := c.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	t.Run("H2C", func(t *testing.T) {
+		ts := httptest.NewUnstartedServer(proto)
+		ts.Config.Protocols = httpProtocols()
+		ts.Start()
+		t.Cleanup(ts.Close)
+		p := &http.Protocols{}
+		p.SetUnencryptedHTTP2(true)
+		c := &http.Client{Transport: &http.Transport{Protocols: p}}
+		if got := get(t, c, ts.URL); got != "HTTP/2.0" {
+			t.Errorf("proto = %q, want HTTP/2.0", got)
+		}
+		if got := get(t, ts.Client(), ts.URL); got != "HTTP/1.1" {
+			t.Errorf("proto = %q, want HTTP/1.1", got)
+		}
+	})
+	t.Run("HTTP3", func(t *testing.T) {
+		// The test server provides a certificate for 127.0.0.1 and the pool
+		// trusting it.
+		ts := httptest.NewUnstartedServer(proto)
+		ts.StartTLS()
+		t.Cleanup(ts.Close)
+		h3, port, err := listenHTTP3(t.Context(), "127.0.0.1:0", proto, ts.TLS.Certificates[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = h3.Close() })
+
+		w := httptest.NewRecorder()
+		advertiseHTTP3(port, proto).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		m := regexp.MustCompile(`h3=":(\d+)"`).FindStringSubmatch(w.Header().Get("Alt-Svc"))
+		if m == nil {
+			t.Fatalf("Alt-Svc = %q, want h3", w.Header().Get("Alt-Svc"))
+		}
+		rt := &http3.Transport{TLSClientConfig: &tls.Config{RootCAs: ts.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs, MinVersion: tls.VersionTLS13}}
+		t.Cleanup(func() { _ = rt.Close() })
+		c := &http.Client{Transport: rt, Timeout: 10 * time.Second}
+		if got := get(t, c, "https://127.0.0.1:"+m[1]); got != "HTTP/3.0" {
+			t.Errorf("proto = %q, want HTTP/3.0", got)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+// Managed bare mirrors of repos, cloned under a source root and fetched periodically.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/md/gitutil"
+)
+
+// defaultMirrorFetch is how often the managed mirrors are fetched when
+// Config.MirrorFetch is unset.
+const defaultMirrorFetch = 10 * time.Minute
+
+// mirrorSegmentRe matches the path segments derived from clone URLs.
+var mirrorSegmentRe = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// MirrorPath returns the path of the mirror of a clone URL relative to the
+// root: the host and the path of the URL without the .git suffix, e.g.
+// "github.com/org/repo" for both https://github.com/org/repo.git and
+// git@github.com:org/repo.git.
+func MirrorPath(rawURL string) (string, error) {
+	var host, p string
+	if u, err := url.Parse(rawURL); err == nil && u.Scheme != "" && u.Host != "" {
+		host, p = u.Hostname(), u.Path
+	} else if h, rest, ok := strings.Cut(rawURL, ":"); ok && !strings.Contains(h, "/") {
+		// scp-like syntax: [user@]host:path.
+		if _, after, ok := strings.Cut(h, "@"); ok {
+			h = after
+		}
+		host, p = h, rest
+	}
+	p = strings.TrimSuffix(strings.Trim(p, "/"), ".git")
+	if host == "" || p == "" {
+		return "", fmt.Errorf("cannot derive a path from %q", rawURL)
+	}
+	rel := host + "/" + p
+	segs := strings.Split(rel, "/")
+	if len(segs) > defaultRootDepth {
+		return "", fmt.Errorf("%q is nested too deep to be discovered; give a path", rawURL)
+	}
+	for _, seg := range segs {
+		if !mirrorSegmentRe.MatchString(seg) {
+			return "", fmt.Errorf("cannot derive a path from %q: invalid segment %q", rawURL, seg)
+		}
+	}
+	return rel, nil
+}
+
+// CloneMirror creates a managed mirror of rawURL at abs: a bare clone whose
+// branches are fetched as remote-tracking branches like in a regular clone,
+// so that tasks branch off origin/<default branch>. The partial clone is
+// removed on failure.
+func CloneMirror(ctx context.Context, rawURL, abs string) error {
+	if _, err := os.Stat(abs); err == nil {
+		return fmt.Errorf("%s already exists", abs)
+	}
+	err := func() error {
+		if _, err := gitutil.RunGit(ctx, "", "clone", "--bare", "--", rawURL, abs); err != nil {
+			return err
+		}
+		for _, args := range [][]string{
+			{"config", "remote.origin.fetch", "+refs/heads/*:refs/remotes/origin/*"},
+			{"fetch", "--prune", "origin"},
+			{"remote", "set-head", "origin", "--auto"},
+		} {
+			if _, err := gitutil.RunGit(ctx, abs, args...); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		_ = os.RemoveAll(abs)
+	}
+	return err
+}
+
+// isMirror reports whether the repo at abs is a bare clone.
+func isMirror(ctx context.Context, abs string) bool {
+	out, err := gitutil.RunGit(ctx, abs, "rev-parse", "--is-bare-repository")
+	return err == nil && out == "true"
+}
+
+// pollMirrors fetches the managed mirrors every interval until ctx is done.
+func (s *Server) pollMirrors(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.fetchMirrors(ctx)
+		}
+	}
+}
+
+// fetchMirrors fetches the remote-tracking branches of every managed mirror.
+// The task branches, local to the mirror, are left alone.
+func (s *Server) fetchMirrors(ctx context.Context) {
+	s.mu.Lock()
+	var dirs []string
+	for i := range s.repos {
+		if s.repos[i].Mirror {
+			dirs = append(dirs, s.repos[i].AbsPath)
+		}
+	}
+	s.mu.Unlock()
+	for _, dir := range dirs {
+		if _, err := gitutil.RunGit(ctx, dir, "fetch", "--prune", "origin"); err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+			slog.Warn("mirror fetch failed", "path", dir, "err", err)
+		}
+	}
+}
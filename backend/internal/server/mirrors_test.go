@@ -0,0 +1,64 @@
+// Tests for the managed mirrors.
+package server
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/caic-xyz/md/gitutil"
+)
+
+func TestMirrors(t *testing.T) {
+	t.Run("MirrorPath", func(t *testing.T) {
+		for in, want := range map[string]string{
+			"https://github.com/org/repo.git":     "github.com/org/repo",
+			"https://github.com/org/repo":         "github.com/org/repo",
+			"git@github.com:org/repo.git":         "github.com/org/repo",
+			"ssh://git@gitlab.example.com/g/repo": "gitlab.example.com/g/repo",
+		} {
+			if got, err := MirrorPath(in); err != nil || got != want {
+				t.Errorf("MirrorPath(%q) = %q, %v; want %q", in, got, err, want)
+			}
+		}
+		for _, in := range []string{"repo", "https://github.com/", "https://gitlab.com/g/sub/repo", "git@host:../x"} {
+			if got, err := MirrorPath(in); err == nil {
+				t.Errorf("MirrorPath(%q) = %q, want error", in, got)
+			}
+		}
+	})
+	t.Run("CloneAndFetch", func(t *testing.T) {
+		upstream := newGitRepo(t, map[string]string{"README.md": "hello\n"})
+		abs := filepath.Join(t.TempDir(), "mirror")
+		if err := CloneMirror(t.Context(), upstream, abs); err != nil {
+			t.Fatal(err)
+		}
+		if !isMirror(t.Context(), abs) {
+			t.Error("isMirror = false")
+		}
+		if br, err := gitutil.DefaultBranch(t.Context(), abs, "origin"); err != nil || br != "main" {
+			t.Errorf("DefaultBranch = %q, %v; want main", br, err)
+		}
+		if err := CloneMirror(t.Context(), upstream, abs); err == nil {
+			t.Error("cloning over an existing mirror succeeded")
+		}
+
+		cmd := exec.Command("git", "commit", "-q", "--allow-empty", "-m", "next")
+		cmd.Dir = upstream
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t", "GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git commit: %v\n%s", err, out)
+		}
+		want, err := gitutil.RunGit(t.Context(), upstream, "rev-parse", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newTestServer(t)
+		s.repos = []repoInfo{{RelPath: "mirror", AbsPath: abs, Mirror: true}}
+		s.fetchMirrors(t.Context())
+		if got, err := gitutil.RunGit(t.Context(), abs, "rev-parse", "refs/remotes/origin/main"); err != nil || got != want {
+			t.Errorf("origin/main = %q, %v; want %q", got, err, want)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+// Chat notifications: task state changes and stalls are sent to the channels their owner routes each of them to.
+package server
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/notify"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// notifyTimeout bounds the delivery of one notification to all its channels.
+const notifyTimeout = 30 * time.Second
+
+// notifyTransition sends the state t just entered to the channels its owner
+// routes that state to, if any, with the time in the owner's time zone.
+func (s *Server) notifyTransition(t *task.Task, tr task.Transition) {
+	at := tr.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	s.notifyRoute(t, tr.To.String(), func(loc *time.Location) string {
+		return stateVerb(tr.To) + " at " + at.In(loc).Format("15:04 MST")
+	})
+}
+
+// notifyStalled sends the stall of t to the channels its owner routes
+// "stalled" to, if any.
+func (s *Server) notifyStalled(t *task.Task, silent time.Duration) {
+	s.notifyRoute(t, notifyRouteStalled, func(*time.Location) string {
+		return "has sent nothing for " + silent.Round(time.Minute).String() + " and may be hung"
+	})
+}
+
+// notifyRoute sends "<task> <what>: <title>" to the channels the owner of t
+// routes route to. what is given the owner's time zone.
+func (s *Server) notifyRoute(t *task.Task, route string, what func(loc *time.Location) string) {
+	settings := s.prefs.Get(taskOwnerID(t)).Settings
+	names := settings.NotifyRoutes[route]
+	if len(names) == 0 {
+		return
+	}
+	text := s.taskRef(t) + " " + what(userLocation(&settings))
+	if title := t.Title(); title != "" {
+		text += ": " + title
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, notifyTimeout)
+	defer cancel()
+	for _, c := range settings.NotifyChannels {
+		if !slices.Contains(names, c.Name) {
+			continue
+		}
+		ch, err := notify.New(&notify.Config{Kind: notify.Kind(c.Kind), URL: c.URL, Secret: c.Secret, Target: c.Target})
+		if err == nil {
+			err = ch.Send(ctx, text)
+		}
+		if err != nil {
+			slog.Warn("notify", "task", t.ID, "channel", c.Name, "route", route, "err", err)
+		}
+	}
+}
+
+// stateVerb describes entering state in a notification.
+func stateVerb(state task.State) string {
+	switch state { //nolint:exhaustive // the other states use the generic form
+	case task.StateWaiting:
+		return "is waiting for input"
+	case task.StateAsking:
+		return "asked a question"
+	case task.StateHasPlan, task.StatePlanReview:
+		return "has a plan to review"
+	case task.StateFailed:
+		return "failed"
+	case task.StateStopped:
+		return "stopped"
+	case task.StatePurged:
+		return "was purged"
+	default:
+		return "is " + strings.ReplaceAll(state.String(), "_", " ")
+	}
+}
+
+func prefsToV1NotifyChannels(chans []preferences.NotifyChannel) []v1.NotifyChannel {
+	if len(chans) == 0 {
+		return nil
+	}
+	out := make([]v1.NotifyChannel, len(chans))
+	for i, c := range chans {
+		out[i] = v1.NotifyChannel{Name: c.Name, Kind: v1.NotifyKind(c.Kind), URL: c.URL, HasSecret: c.Secret != "", Target: c.Target}
+	}
+	return out
+}
+
+// prefsFromV1NotifyChannels returns the channels to save. An empty secret
+// keeps the one of the previous channel with the same name and kind, so
+// clients can update the routes without resending the secrets.
+func prefsFromV1NotifyChannels(chans []v1.NotifyChannel, prev []preferences.NotifyChannel) ([]preferences.NotifyChannel, error) {
+	if len(chans) == 0 {
+		return nil, nil
+	}
+	out := make([]preferences.NotifyChannel, len(chans))
+	for i, c := range chans {
+		out[i] = preferences.NotifyChannel{Name: c.Name, Kind: string(c.Kind), URL: c.URL, Secret: c.Secret, Target: c.Target}
+		if out[i].Secret == "" {
+			for _, p := range prev {
+				if p.Name == c.Name && p.Kind == string(c.Kind) {
+					out[i].Secret = p.Secret
+				}
+			}
+		}
+		cfg := notify.Config{Kind: notify.Kind(c.Kind), URL: c.URL, Secret: out[i].Secret, Target: c.Target}
+		if err := cfg.Validate(); err != nil {
+			return nil, dto.BadRequest("notifyChannels[" + c.Name + "]: " + err.Error())
+		}
+	}
+	return out, nil
+}
+
+// validateNotifyRoutes checks that routes are keyed by task states or
+// "stalled".
+func validateNotifyRoutes(routes map[string][]string) error {
+	for state := range routes {
+		if _, ok := task.ParseState(state); !ok && state != notifyRouteStalled {
+			return dto.BadRequest("notifyRoutes: unknown task state " + state)
+		}
+	}
+	return nil
+}
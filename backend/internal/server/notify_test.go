@@ -0,0 +1,67 @@
+// Tests for the chat notifications.
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+)
+
+func TestNotify(t *testing.T) {
+	t.Run("notifyTransition", func(t *testing.T) {
+		got := map[string]string{}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var b struct{ Text string }
+			_ = json.NewDecoder(r.Body).Decode(&b)
+			got[r.URL.Path] = b.Text
+		}))
+		defer srv.Close()
+		s := newTestServer(t)
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.NotifyChannels = []preferences.NotifyChannel{
+				{Name: "me", Kind: "slack", Secret: srv.URL + "/me"},
+				{Name: "team", Kind: "slack", Secret: srv.URL + "/team"},
+			}
+			p.Settings.NotifyRoutes = map[string][]string{"waiting": {"me"}, "failed": {"me", "team"}}
+			p.Settings.Timezone = "America/New_York"
+		}); err != nil {
+			t.Fatal(err)
+		}
+		tk := &task.Task{ID: ksid.NewID(), Alias: "w3"}
+		tk.SetTitle("Fix login")
+		at := time.Date(2026, 7, 1, 18, 30, 0, 0, time.UTC)
+		s.notifyTransition(tk, task.Transition{From: task.StateRunning, To: task.StateWaiting, At: at})
+		if len(got) != 1 || got["/me"] != "caic task w3 is waiting for input at 14:30 EDT: Fix login" {
+			t.Errorf("waiting: sent %q", got)
+		}
+		s.notifyTransition(tk, task.Transition{From: task.StateRunning, To: task.StateFailed, At: at})
+		if got["/team"] != "caic task w3 failed at 14:30 EDT: Fix login" {
+			t.Errorf("failed: sent %q", got)
+		}
+		clear(got)
+		s.notifyTransition(tk, task.Transition{From: task.StateWaiting, To: task.StateRunning})
+		if len(got) != 0 {
+			t.Errorf("unrouted state: sent %q", got)
+		}
+	})
+	t.Run("prefsFromV1NotifyChannels", func(t *testing.T) {
+		prev := []preferences.NotifyChannel{{Name: "me", Kind: "telegram", Secret: "123:abc", Target: "42"}}
+		got, err := prefsFromV1NotifyChannels([]v1.NotifyChannel{{Name: "me", Kind: v1.NotifyTelegram, Target: "43"}}, prev)
+		if err != nil || len(got) != 1 || got[0].Secret != "123:abc" || got[0].Target != "43" {
+			t.Errorf("kept secret = %+v, %v", got, err)
+		}
+		if _, err := prefsFromV1NotifyChannels([]v1.NotifyChannel{{Name: "me", Kind: v1.NotifyDiscord}}, prev); err == nil {
+			t.Error("a kind change kept the secret")
+		}
+		if err := validateNotifyRoutes(map[string][]string{"done": {"me"}}); err == nil {
+			t.Error("unknown state accepted")
+		}
+	})
+}
@@ -0,0 +1,98 @@
+// Harnesses driving OpenAI-compatible APIs: a local inference server on the
+// host, and the generic harness configured per user.
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/agent/openaicompat"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// localModelsTimeout bounds a model listing from the local inference server.
+const localModelsTimeout = 5 * time.Second
+
+// newLocalBackend returns the local harness backend when url is set. The
+// server starts even when the inference server is down; the model list is
+// refreshed whenever harnesses are listed.
+func newLocalBackend(ctx context.Context, url string) *openaicompat.Backend {
+	if url == "" {
+		return nil
+	}
+	b := openaicompat.NewLocal(url)
+	refreshLocalModels(ctx, b)
+	return b
+}
+
+// refreshLocalModels updates the model list of b, keeping the previous list
+// when the inference server is unreachable.
+func refreshLocalModels(ctx context.Context, b *openaicompat.Backend) {
+	ctx, cancel := context.WithTimeout(ctx, localModelsTimeout)
+	defer cancel()
+	if err := b.RefreshModels(ctx); err != nil {
+		slog.WarnContext(ctx, "local models unavailable", "url", b.URL, "err", err)
+	}
+}
+
+// addCompatBackends registers the OpenAI-compatible harnesses on r, after
+// r.Init populated its default backends. The generic harness is always
+// registered; it is only offered to users who configured it.
+func (s *Server) addCompatBackends(r *task.Runner) {
+	if r.Backends == nil {
+		return
+	}
+	r.Backends[agent.Generic] = s.generic
+	if s.local != nil {
+		r.Backends[agent.Local] = s.local
+	}
+}
+
+// harnessEndpoint returns the API of harness h configured in prefs: the
+// user's generic harness, nil for the other harnesses.
+func harnessEndpoint(prefs *preferences.Preferences, h agent.Harness) *agent.Endpoint {
+	if g := prefs.Settings.GenericHarness; g != nil && h == agent.Generic {
+		return &agent.Endpoint{BaseURL: g.BaseURL, APIKey: g.APIKey}
+	}
+	return nil
+}
+
+// harnessModels returns the models of backend b offered to the user with
+// prefs. The generic harness offers the configured model, or nothing when
+// the user has not configured it.
+func harnessModels(prefs *preferences.Preferences, b agent.Backend) []string {
+	if b.Harness() != agent.Generic {
+		return b.Models()
+	}
+	if g := prefs.Settings.GenericHarness; g != nil {
+		return []string{g.Model}
+	}
+	return nil
+}
+
+// toV1GenericHarness returns the generic harness settings without the API key,
+// which is write-only.
+func toV1GenericHarness(g *preferences.GenericHarness) *v1.GenericHarness {
+	if g == nil {
+		return nil
+	}
+	return &v1.GenericHarness{BaseURL: g.BaseURL, HasAPIKey: g.APIKey != "", Model: g.Model}
+}
+
+// fromV1GenericHarness returns the generic harness settings to save. An empty
+// API key keeps the previous one when the base URL is unchanged, so clients
+// can update the model without resending the key.
+func fromV1GenericHarness(g *v1.GenericHarness, prev *preferences.GenericHarness) *preferences.GenericHarness {
+	if g == nil {
+		return nil
+	}
+	out := &preferences.GenericHarness{BaseURL: g.BaseURL, APIKey: g.APIKey, Model: g.Model}
+	if out.APIKey == "" && prev != nil && prev.BaseURL == g.BaseURL {
+		out.APIKey = prev.APIKey
+	}
+	return out
+}
@@ -0,0 +1,159 @@
+// Periodic reconciliation of caic containers that no task owns.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/container"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md"
+)
+
+// Orphan policies for Config.OrphanPolicy.
+const (
+	orphanAdopt = "adopt" // Register the container's task as if found at startup.
+	orphanKill  = "kill"  // Remove the container.
+	orphanOff   = "off"   // Leave orphans alone.
+)
+
+const (
+	// defaultOrphanGrace is how long a container must stay orphaned before
+	// the policy applies, so containers of tasks being created or purged are
+	// left alone.
+	defaultOrphanGrace = 10 * time.Minute
+	// orphanGCInterval is how often collectOrphans lists containers.
+	orphanGCInterval = 5 * time.Minute
+)
+
+// orphanTracker remembers since when each container has been orphaned.
+type orphanTracker struct {
+	grace time.Duration
+	since map[string]time.Time // Container name → first pass it was seen orphaned.
+}
+
+// observe records the containers orphaned in the current pass and returns
+// those that have been orphaned for at least the grace period. Containers
+// missing from orphans are forgotten.
+func (o *orphanTracker) observe(orphans []string, now time.Time) []string {
+	seen := make(map[string]time.Time, len(orphans))
+	var due []string
+	for _, name := range orphans {
+		first, ok := o.since[name]
+		if !ok {
+			first = now
+		}
+		if now.Sub(first) >= o.grace {
+			due = append(due, name)
+			continue
+		}
+		seen[name] = first
+	}
+	o.since = seen
+	return due
+}
+
+// collectOrphans periodically applies the orphan policy to caic containers
+// that no task owns until ctx is done.
+func (s *Server) collectOrphans(ctx context.Context) {
+	tracker := &orphanTracker{grace: s.orphanGrace}
+	ticker := time.NewTicker(orphanGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reconcileOrphans(ctx, tracker); err != nil {
+				slog.Warn("orphan gc", "err", err)
+			}
+		}
+	}
+}
+
+// reconcileOrphans runs one orphan collection pass.
+func (s *Server) reconcileOrphans(ctx context.Context, tracker *orphanTracker) error {
+	containers, err := s.mdClient.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list containers: %w", err)
+	}
+	byName := map[string]*md.Container{}
+	refs := map[string]adoptRef{}
+	var orphans []string
+	for _, c := range s.unownedContainers(containers) {
+		labels, err := container.Labels(ctx, c.Name)
+		if err != nil {
+			slog.Warn("orphan gc", "ctr", c.Name, "err", err)
+			continue
+		}
+		ref, ok, err := s.parseAdoptLabels(c, labels)
+		if err != nil || !ok || s.ownsTask(ref) {
+			continue
+		}
+		byName[c.Name] = c
+		refs[c.Name] = ref
+		orphans = append(orphans, c.Name)
+	}
+	for _, name := range tracker.observe(orphans, time.Now()) {
+		s.applyOrphanPolicy(ctx, byName[name], refs[name])
+	}
+	return nil
+}
+
+// unownedContainers returns the containers that are not the container of a
+// task in s.tasks.
+func (s *Server) unownedContainers(containers []*md.Container) []*md.Container {
+	s.mu.Lock()
+	owned := make(map[string]bool, len(s.tasks))
+	for _, e := range s.tasks {
+		if e.task.Container != "" && e.task.GetState() != task.StatePurged {
+			owned[e.task.Container] = true
+		}
+	}
+	s.mu.Unlock()
+	var out []*md.Container
+	for _, c := range containers {
+		if !owned[c.Name] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// ownsTask reports whether the task named by a container's labels is known
+// and not purged; a purged task's leftover container is an orphan.
+func (s *Server) ownsTask(ref adoptRef) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.tasks[ref.taskID.String()]
+	return ok && e.task.GetState() != task.StatePurged
+}
+
+// applyOrphanPolicy adopts or removes orphaned container c and reports the
+// action to the user as a warning on the task list event stream.
+func (s *Server) applyOrphanPolicy(ctx context.Context, c *md.Container, ref adoptRef) {
+	switch s.orphanPolicy {
+	case orphanAdopt:
+		allLogs, err := task.LoadLogs(s.logDir)
+		if err != nil {
+			slog.Warn("orphan gc: load logs", "err", err)
+		}
+		if err := s.adoptLabeled(ctx, c, ref, s.branchTaskIDs(), allLogs); err != nil {
+			slog.Warn("orphan gc: adopt failed", "ctr", c.Name, "task", ref.taskID, "err", err)
+			s.emitWarning(fmt.Sprintf("Failed to adopt orphaned container %s: %v", c.Name, err))
+			return
+		}
+		slog.Info("orphan gc: adopted", "ctr", c.Name, "task", ref.taskID)
+		s.emitWarning("Adopted orphaned container " + c.Name)
+	case orphanKill:
+		if err := s.backend.Purge(ctx, c.Name, nil); err != nil {
+			slog.Warn("orphan gc: remove failed", "ctr", c.Name, "task", ref.taskID, "err", err)
+			s.emitWarning(fmt.Sprintf("Failed to remove orphaned container %s: %v", c.Name, err))
+			return
+		}
+		slog.Info("orphan gc: removed", "ctr", c.Name, "task", ref.taskID)
+		s.emitWarning("Removed orphaned container " + c.Name)
+	}
+}
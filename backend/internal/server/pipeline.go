@@ -0,0 +1,294 @@
+// Pipelines: multi-step workflows run as chained tasks stacked on one branch.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+	"gopkg.in/yaml.v3"
+)
+
+// maxVerifyOutput caps the verification output kept in errors and fed to the
+// next step.
+const maxVerifyOutput = 4000
+
+// pipelineRun tracks the progress of a pipeline across the tasks of its
+// steps. Guarded by Server.mu.
+type pipelineRun struct {
+	def    v1.Pipeline
+	tmpls  []*template.Template
+	req    v1.CreateTaskReq // Request of the first step; its prompt text is the input.
+	tasks  []ksid.ID        // Task of each started step.
+	step   int
+	status string // "running", "done", "failed", or "aborted"
+	err    string
+}
+
+func (p *pipelineRun) toJSON() *v1.PipelineProgress {
+	return &v1.PipelineProgress{Name: p.def.Name, Step: p.step, Steps: len(p.def.Steps), Status: p.status, Error: p.err, Tasks: slices.Clone(p.tasks)}
+}
+
+// pipelineVars is the data a step prompt template is rendered with.
+type pipelineVars struct {
+	Input        string // The initial prompt text of the first step's task.
+	Previous     string // Result of the previous step.
+	Verification string // Verification output of the previous step.
+	Step         int
+}
+
+// parsePipeline parses every step prompt template so that errors surface at
+// creation time instead of mid-run.
+func parsePipeline(p *v1.Pipeline) ([]*template.Template, error) {
+	tmpls := make([]*template.Template, len(p.Steps))
+	for i, st := range p.Steps {
+		tmpl, err := template.New(strconv.Itoa(i)).Option("missingkey=error").Parse(st.Prompt)
+		if err != nil {
+			return nil, dto.BadRequest("pipeline step " + strconv.Itoa(i) + ": " + err.Error())
+		}
+		tmpls[i] = tmpl
+	}
+	return tmpls, nil
+}
+
+// newPipelineRun parses the pipeline of req and returns its run and the
+// prompt of the first step.
+func newPipelineRun(req *v1.CreateTaskReq) (*pipelineRun, string, error) {
+	tmpls, err := parsePipeline(req.Pipeline)
+	if err != nil {
+		return nil, "", err
+	}
+	first, err := renderStep(tmpls[0], &pipelineVars{Input: req.InitialPrompt.Text})
+	if err != nil {
+		return nil, "", dto.BadRequest("pipeline step 0: " + err.Error())
+	}
+	run := &pipelineRun{def: *req.Pipeline, tmpls: tmpls, req: *req, status: "running"}
+	run.req.Pipeline = nil
+	return run, first, nil
+}
+
+func renderStep(tmpl *template.Template, vars *pipelineVars) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// runPipelineStep waits for the turn of the task of a pipeline step and runs
+// the step's verification. Unless it was the last step, it then starts the
+// task of the next one with the results fed forward. It reports whether the
+// pipeline completed.
+func (s *Server) runPipelineStep(entry *taskEntry, runner *task.Runner) bool {
+	t := entry.task
+	s.mu.Lock()
+	run, i := entry.pipeline, entry.pipelineStep
+	s.mu.Unlock()
+	st := run.def.Steps[i]
+	if state := s.waitForTurn(entry); state != task.StateWaiting {
+		s.setPipelineStatus(entry, i, "aborted", "task is "+state.String())
+		return false
+	}
+	rm := lastResult(t)
+	if rm == nil || rm.IsError {
+		s.setPipelineStatus(entry, i, "failed", "step "+stepLabel(i, st)+" ended with an error")
+		return false
+	}
+	vars := pipelineVars{Input: run.req.InitialPrompt.Text, Previous: rm.Result, Step: i + 1}
+	if st.Verify != "" {
+		out, err := runner.Verify(s.ctx, t, st.Verify)
+		out = tail(out, maxVerifyOutput)
+		if err != nil {
+			s.setPipelineStatus(entry, i, "failed", fmt.Sprintf("step %s: verify: %v\n%s", stepLabel(i, st), err, out))
+			return false
+		}
+		vars.Verification = out
+	}
+	if i == len(run.def.Steps)-1 {
+		s.setPipelineStatus(entry, i, "done", "")
+		return true
+	}
+	if err := s.startNextStep(entry, &vars); err != nil {
+		s.setPipelineStatus(entry, i+1, "failed", "step "+stepLabel(i+1, run.def.Steps[i+1])+": "+err.Error())
+	}
+	return false
+}
+
+// startNextStep starts the task of the step after entry's, with the prompt
+// rendered from vars. Like a task created with inheritBranch, it depends on
+// entry's task and is stacked on its branch.
+func (s *Server) startNextStep(entry *taskEntry, vars *pipelineVars) error {
+	s.mu.Lock()
+	run, step := entry.pipeline, entry.pipelineStep+1
+	s.mu.Unlock()
+	prompt, err := renderStep(run.tmpls[step], vars)
+	if err != nil {
+		return err
+	}
+	req := run.req
+	req.InitialPrompt = v1.Prompt{Text: prompt}
+	req.Repos = slices.Clone(req.Repos)
+	req.Repos[0].BaseBranch = ""
+	req.DependsOn = []string{entry.task.ID.String()}
+	req.InheritBranch = true
+	req.OnDependencyFailure = ""
+	req.GatherContext = false
+	req.Issue = ""
+	_, err = s.startTaskStep(s.ctx, &req, entry.task.OwnerID, nil, run, step)
+	return err
+}
+
+// failPipelineStep records that the task of a pipeline step ended before its
+// first turn because of err.
+func (s *Server) failPipelineStep(entry *taskEntry, err error) {
+	s.mu.Lock()
+	run, step := entry.pipeline, entry.pipelineStep
+	s.mu.Unlock()
+	if run == nil {
+		return
+	}
+	status := "failed"
+	if errors.Is(err, errWaitCanceled) {
+		status = "aborted"
+	}
+	s.setPipelineStatus(entry, step, status, err.Error())
+}
+
+// waitForTurn blocks until the task finished its turn (waiting for input) or
+// can no longer run, and returns that state. Questions and plans leave the
+// pipeline paused until the user answers.
+func (s *Server) waitForTurn(entry *taskEntry) task.State {
+	ticker := time.NewTicker(depPollInterval)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		changed := s.changed
+		s.mu.Unlock()
+		switch state := entry.task.GetState(); state {
+		case task.StateWaiting, task.StateStopping, task.StateStopped, task.StatePurging, task.StatePurged, task.StateFailed:
+			return state
+		case task.StatePending, task.StateBranching, task.StateProvisioning, task.StateStarting, task.StateRunning, task.StateAsking, task.StateHasPlan, task.StatePlanReview, task.StateRateLimited, task.StatePulling, task.StatePushing:
+		}
+		select {
+		case <-changed:
+		case <-ticker.C:
+		case <-s.ctx.Done():
+			return task.StateStopped
+		}
+	}
+}
+
+func (s *Server) setPipelineStatus(entry *taskEntry, step int, status, errMsg string) {
+	if status == "failed" || status == "aborted" {
+		slog.Warn("pipeline stopped", "task", entry.task.ID, "step", step, "status", status, "err", errMsg)
+	}
+	s.mu.Lock()
+	entry.pipeline.step = step
+	entry.pipeline.status = status
+	entry.pipeline.err = errMsg
+	s.taskChanged()
+	s.mu.Unlock()
+}
+
+// lastResult returns the last result message of t, or nil.
+func lastResult(t *task.Task) *agent.ResultMessage {
+	msgs := t.Messages()
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if rm, ok := msgs[i].(*agent.ResultMessage); ok {
+			return rm
+		}
+	}
+	return nil
+}
+
+func stepLabel(i int, st v1.PipelineStep) string {
+	if st.Name != "" {
+		return strconv.Itoa(i) + " (" + st.Name + ")"
+	}
+	return strconv.Itoa(i)
+}
+
+// tail returns the last n bytes of s.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "…" + s[len(s)-n:]
+}
+
+// listPipelines returns the pipeline definitions saved as JSON or YAML files
+// in the pipelines config directory. A file's name is used when the
+// definition has none; invalid files are skipped.
+func (s *Server) listPipelines(_ context.Context, _ *dto.EmptyReq) (*[]v1.Pipeline, error) {
+	out := []v1.Pipeline{}
+	if s.pipelinesDir == "" {
+		return &out, nil
+	}
+	entries, err := os.ReadDir(s.pipelinesDir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &out, nil
+		}
+		return nil, dto.InternalError("read pipelines: " + err.Error())
+	}
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(s.pipelinesDir, e.Name())
+		data, err := os.ReadFile(path) //nolint:gosec // G304: path is within the config directory
+		if err != nil {
+			slog.Warn("read pipeline", "path", path, "err", err)
+			continue
+		}
+		p, err := decodePipeline(data, ext != ".json")
+		if err != nil {
+			slog.Warn("decode pipeline", "path", path, "err", err)
+			continue
+		}
+		if p.Name == "" {
+			p.Name = strings.TrimSuffix(e.Name(), ext)
+		}
+		if err := p.Validate(); err != nil {
+			slog.Warn("invalid pipeline", "path", path, "err", err)
+			continue
+		}
+		out = append(out, p)
+	}
+	slices.SortFunc(out, func(a, b v1.Pipeline) int { return strings.Compare(a.Name, b.Name) })
+	return &out, nil
+}
+
+// decodePipeline decodes a pipeline definition. YAML is converted to JSON
+// first so that both use the JSON field names.
+func decodePipeline(data []byte, isYAML bool) (v1.Pipeline, error) {
+	var p v1.Pipeline
+	if isYAML {
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return p, err
+		}
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return p, err
+		}
+	}
+	err := json.Unmarshal(data, &p)
+	return p, err
+}
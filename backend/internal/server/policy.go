@@ -0,0 +1,80 @@
+// Tool call policies: configuration and approval of the tool calls they deny.
+package server
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/caic-xyz/caic/backend/internal/policy"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// toolPolicy returns the tool policy of a new task of repo: the global rules
+// followed by the repo's. Rules are validated when saved, so a compilation
+// error only happens with a hand-edited preferences file; it is logged and
+// the task runs unrestricted.
+func toolPolicy(ctx context.Context, prefs *preferences.Preferences, repo string, runner *task.Runner) *policy.Policy {
+	rules := append(append([]policy.Rule(nil), prefs.Settings.ToolPolicy...), prefs.Settings.RepoToolPolicies[repo]...)
+	p, err := runner.Policy(rules)
+	if err != nil {
+		slog.WarnContext(ctx, "invalid tool policy", "repo", repo, "err", err)
+	}
+	return p
+}
+
+// onPolicyViolation pushes the paused task to the task list subscribers.
+func (s *Server) onPolicyViolation(*task.Task, *policy.Violation) {
+	s.notifyTaskChange()
+}
+
+// taskRunner returns the runner of the task's primary repo.
+func (s *Server) taskRunner(t *task.Task) *task.Runner {
+	name := ""
+	if p := t.Primary(); p != nil {
+		name = p.Name
+	}
+	return s.runners[name]
+}
+
+func (s *Server) approvePolicy(ctx context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.StatusResp, error) {
+	v, err := s.taskRunner(entry.task).ResolvePolicyViolation(ctx, entry.task)
+	if err != nil {
+		return nil, dto.Conflict(err.Error())
+	}
+	slog.InfoContext(ctx, "policy violation approved", "task", entry.task.ID, "rule", v.Rule)
+	s.notifyTaskChange()
+	return &v1.StatusResp{Status: "approved"}, nil
+}
+
+func (s *Server) denyPolicy(ctx context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.StatusResp, error) {
+	if entry.task.Snapshot().PolicyViolation == nil {
+		return nil, dto.Conflict("task is not paused on a policy violation")
+	}
+	// StopTask resumes the container first so the agent can exit cleanly.
+	return s.stopTask(ctx, entry, nil)
+}
+
+func toV1PolicyRules(rules []policy.Rule) []v1.PolicyRule {
+	if rules == nil {
+		return nil
+	}
+	out := make([]v1.PolicyRule, len(rules))
+	for i, r := range rules {
+		out[i] = v1.PolicyRule{Name: r.Name, Kind: v1.PolicyRuleKind(r.Kind), Pattern: r.Pattern, Allow: r.Allow}
+	}
+	return out
+}
+
+func fromV1PolicyRules(rules []v1.PolicyRule) []policy.Rule {
+	if rules == nil {
+		return nil
+	}
+	out := make([]policy.Rule, len(rules))
+	for i, r := range rules {
+		out[i] = policy.Rule{Name: r.Name, Kind: policy.Kind(r.Kind), Pattern: r.Pattern, Allow: r.Allow}
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+// Early hints for the entry bundles of the frontend.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// assetManifest is the manifest vite writes in the build, see build.manifest
+// in vite.config.ts. It is not named manifest.json, which is the PWA
+// manifest.
+const assetManifest = "asset-manifest.json"
+
+// manifestChunk is an entry of vite's build manifest.
+type manifestChunk struct {
+	File    string   `json:"file"`
+	IsEntry bool     `json:"isEntry"`
+	CSS     []string `json:"css"`
+	Imports []string `json:"imports"`
+}
+
+// preloadLinks returns the Link header value preloading the entry assets of
+// the build in dist. It returns "" when dist has no asset manifest, as with
+// builds predating it.
+func preloadLinks(dist fs.FS) (string, error) {
+	files, err := entryAssets(dist)
+	if err != nil {
+		return "", err
+	}
+	links := make([]string, 0, len(files))
+	for _, f := range files {
+		rel := "rel=modulepreload"
+		if path.Ext(f) == ".css" {
+			rel = "rel=preload; as=style"
+		}
+		links = append(links, "</"+f+">; "+rel)
+	}
+	return strings.Join(links, ", "), nil
+}
+
+// entryAssets returns the entry chunks of the build in dist, their static
+// imports and their stylesheets, as listed in the asset manifest. It returns
+// nothing when dist has no manifest.
+func entryAssets(dist fs.FS) ([]string, error) {
+	data, err := readAsset(dist, assetManifest)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var chunks map[string]manifestChunk
+	if err := json.Unmarshal(data, &chunks); err != nil {
+		return nil, err
+	}
+	var files []string
+	seen := map[string]bool{}
+	add := func(file string) {
+		if file != "" && !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	var walk func(key string)
+	walk = func(key string) {
+		c, ok := chunks[key]
+		if !ok || seen[c.File] {
+			return
+		}
+		add(c.File)
+		for _, css := range c.CSS {
+			add(css)
+		}
+		for _, imp := range c.Imports {
+			walk(imp)
+		}
+	}
+	var entries []string
+	for k, c := range chunks {
+		if c.IsEntry {
+			entries = append(entries, k)
+		}
+	}
+	slices.Sort(entries)
+	for _, k := range entries {
+		walk(k)
+	}
+	return files, nil
+}
+
+// readAsset returns the content of name in dist, decompressing name.br when
+// only the compressed file exists.
+func readAsset(dist fs.FS, name string) ([]byte, error) {
+	data, err := fs.ReadFile(dist, name)
+	if !errors.Is(err, fs.ErrNotExist) {
+		return data, err
+	}
+	f, err := dist.Open(name + ".br")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return io.ReadAll(brotli.NewReader(f))
+}
+
+// sendEarlyHints sets the Link header to links and sends it in a 103 Early
+// Hints response, so that the browser fetches the bundles while index.html is
+// on its way. The header stays on the final response for clients and proxies
+// ignoring 1xx responses.
+func sendEarlyHints(w http.ResponseWriter, r *http.Request, links string) {
+	if links == "" {
+		return
+	}
+	w.Header().Set("Link", links)
+	// HTTP/1.0 clients do not expect interim responses.
+	if r.ProtoAtLeast(1, 1) {
+		w.WriteHeader(http.StatusEarlyHints)
+	}
+}
@@ -0,0 +1,110 @@
+// Tests for the early hints sent before index.html.
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/textproto"
+	"testing"
+	"testing/fstest"
+)
+
+const testManifest = `{
+  "index.html": {"file": "assets/index-a.js", "isEntry": true, "imports": ["_vendor.js"], "css": ["assets/index-b.css"]},
+  "_vendor.js": {"file": "assets/vendor-c.js", "css": ["assets/index-b.css"]},
+  "lazy.tsx": {"file": "assets/lazy-d.js", "isDynamicEntry": true}
+}`
+
+const testLinks = "</assets/index-a.js>; rel=modulepreload, </assets/index-b.css>; rel=preload; as=style, </assets/vendor-c.js>; rel=modulepreload"
+
+func TestPreloadLinks(t *testing.T) {
+	t.Run("Brotli", func(t *testing.T) {
+		dist := testFS(t)
+		dist[assetManifest+".br"] = &fstest.MapFile{Data: brCompress(t, []byte(testManifest))}
+		got, err := preloadLinks(dist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != testLinks {
+			t.Errorf("links = %q, want %q", got, testLinks)
+		}
+	})
+	t.Run("Plain", func(t *testing.T) {
+		got, err := preloadLinks(fstest.MapFS{assetManifest: {Data: []byte(testManifest)}})
+		if err != nil || got != testLinks {
+			t.Errorf("links = %q, %v; want %q", got, err, testLinks)
+		}
+	})
+	t.Run("NoManifest", func(t *testing.T) {
+		if got, err := preloadLinks(testFS(t)); got != "" || err != nil {
+			t.Errorf("links = %q, %v; want none", got, err)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := preloadLinks(fstest.MapFS{assetManifest: {Data: []byte("{")}}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestEarlyHints(t *testing.T) {
+	dist := testFS(t)
+	dist[assetManifest+".br"] = &fstest.MapFile{Data: brCompress(t, []byte(testManifest))}
+	ts := httptest.NewServer(compressMiddleware(newStaticHandler(dist, newTranscodeCache(1<<20))))
+	t.Cleanup(ts.Close)
+
+	get := func(t *testing.T, p string) (*http.Response, []string) {
+		var hints []string
+		trace := &httptrace.ClientTrace{
+			Got1xxResponse: func(code int, h textproto.MIMEHeader) error {
+				if code == http.StatusEarlyHints {
+					hints = append(hints, h.Get("Link"))
+				}
+				return nil
+			},
+		}
+		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(t.Context(), trace), http.MethodGet, ts.URL+p, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp, hints
+	}
+
+	t.Run("Index", func(t *testing.T) {
+		resp, hints := get(t, "/")
+		if len(hints) != 1 || hints[0] != testLinks {
+			t.Errorf("early hints = %q, want one with %q", hints, testLinks)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("status = %d, want 200", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Link"); got != testLinks {
+			t.Errorf("Link = %q, want %q", got, testLinks)
+		}
+		// The transcoded file is served as is, not compressed again.
+		if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+			t.Errorf("Content-Encoding = %q, want gzip", got)
+		}
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body := decompressGzip(t, data); string(body) != string(indexContent) {
+			t.Errorf("body = %q, want %q", body, indexContent)
+		}
+	})
+	t.Run("Asset", func(t *testing.T) {
+		resp, hints := get(t, "/assets/app.js")
+		if len(hints) != 0 || resp.Header.Get("Link") != "" {
+			t.Errorf("early hints = %q, Link = %q; want none", hints, resp.Header.Get("Link"))
+		}
+	})
+}
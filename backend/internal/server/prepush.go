@@ -0,0 +1,49 @@
+// Pre-push checks: a local CI script run against the task branch before it is pushed.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// prePushChecks runs the pre-push check of each repo of t that has one, in
+// the repo's directory inside the task's container, and posts the output in
+// the transcript. It returns one issue per failed check, which block the push,
+// and whether at least one check ran and all passed.
+func (s *Server) prePushChecks(ctx context.Context, t *task.Task) (bool, []v1.SafetyIssue) {
+	ownerID := t.OwnerID
+	if ownerID == "" {
+		ownerID = "default"
+	}
+	checks := s.prefs.Get(ownerID).Settings.RepoPrePushChecks
+	if len(checks) == 0 {
+		return false, nil
+	}
+	var issues []v1.SafetyIssue
+	ran := false
+	for _, rm := range t.Repos {
+		script := checks[rm.Name]
+		runner := s.runners[rm.Name]
+		if script == "" || runner == nil {
+			continue
+		}
+		ran = true
+		slog.InfoContext(ctx, "running pre-push check", "task", t.ID, "repo", rm.Name)
+		out, err := runner.Verify(ctx, t, script)
+		out = tail(out, maxVerifyOutput)
+		if err != nil {
+			t.AddPrePushNotice(ctx, fmt.Sprintf("Pre-push check of %s failed, the branch was not pushed: %v\n%s", rm.Name, err, out))
+			issues = append(issues, v1.SafetyIssue{Kind: "pre_push_check", Detail: "pre-push check of " + rm.Name + " failed: " + err.Error()})
+			continue
+		}
+		t.AddPrePushNotice(ctx, fmt.Sprintf("Pre-push check of %s passed.\n%s", rm.Name, out))
+	}
+	if ran {
+		s.notifyTaskChange()
+	}
+	return ran && len(issues) == 0, issues
+}
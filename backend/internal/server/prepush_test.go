@@ -0,0 +1,97 @@
+// Tests for the pre-push checks.
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// execBackend is a container backend that only implements Exec.
+type execBackend struct {
+	task.ContainerBackend
+	out     string
+	err     error
+	scripts []string
+}
+
+func (e *execBackend) Exec(_ context.Context, _, _, script string) (string, error) {
+	e.scripts = append(e.scripts, script)
+	return e.out, e.err
+}
+
+func prePushNotices(tk *task.Task) []string {
+	var out []string
+	for _, m := range tk.Messages() {
+		if sm, ok := m.(*agent.SystemMessage); ok && sm.Subtype == "caic_prepush" {
+			out = append(out, sm.Detail)
+		}
+	}
+	return out
+}
+
+func TestPrePushChecks(t *testing.T) {
+	newServer := func(t *testing.T, backend *execBackend) (*Server, *task.Task) {
+		s := newTestServer(t)
+		s.runners["org/repo"] = &task.Runner{Dir: "/src/repo", BaseBranch: "main", Container: backend}
+		tk := &task.Task{
+			InitialPrompt: agent.Prompt{Text: "test"},
+			Repos:         []task.RepoMount{{Name: "org/repo", Branch: "caic-0"}},
+			Container:     "md-repo-caic-0",
+		}
+		tk.SetState(task.StateWaiting)
+		s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.RepoPrePushChecks = map[string]string{"org/repo": "make test"}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return s, tk
+	}
+	t.Run("Passed", func(t *testing.T) {
+		backend := &execBackend{out: "ok"}
+		s, tk := newServer(t, backend)
+		if verified, issues := s.prePushChecks(t.Context(), tk); !verified || len(issues) != 0 {
+			t.Errorf("issues = %+v, want none", issues)
+		}
+		if len(backend.scripts) != 1 || backend.scripts[0] != "make test" {
+			t.Errorf("scripts = %q", backend.scripts)
+		}
+		if got := prePushNotices(tk); len(got) != 1 || got[0] != "Pre-push check of org/repo passed.\nok" {
+			t.Errorf("notices = %q", got)
+		}
+	})
+	t.Run("FailureBlocksSync", func(t *testing.T) {
+		backend := &execBackend{out: "FAIL: TestX", err: errors.New("exit status 1")}
+		s, tk := newServer(t, backend)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/sync", strings.NewReader(`{}`))
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		handleWithTask(s, s.syncTask)(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, body = %s", w.Code, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), `"status":"blocked"`) || !strings.Contains(w.Body.String(), `"kind":"pre_push_check"`) {
+			t.Errorf("body = %s", w.Body)
+		}
+		got := prePushNotices(tk)
+		if len(got) != 1 || !strings.Contains(got[0], "failed") || !strings.HasSuffix(got[0], "FAIL: TestX") {
+			t.Errorf("notices = %q", got)
+		}
+	})
+	t.Run("NoCheck", func(t *testing.T) {
+		backend := &execBackend{}
+		s, tk := newServer(t, backend)
+		tk.Repos[0].Name = "other"
+		if verified, issues := s.prePushChecks(t.Context(), tk); verified || issues != nil || len(backend.scripts) != 0 {
+			t.Errorf("issues = %+v, scripts = %q", issues, backend.scripts)
+		}
+	})
+}
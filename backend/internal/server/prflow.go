@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +19,7 @@ import (
 	"github.com/caic-xyz/caic/backend/internal/forge/github"
 	"github.com/caic-xyz/caic/backend/internal/forge/gitlab"
 	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md/gitutil"
 	"github.com/maruel/ksid"
 	"github.com/maruel/roundtrippers"
 )
@@ -186,7 +188,11 @@ func (s *Server) startPRFlow(ctx context.Context, entry *taskEntry, f forge.Forg
 	if entry.result != nil {
 		body = entry.result.AgentResult
 	}
-	pr, err := f.CreatePR(ctx, info.ForgeOwner, info.ForgeRepo, branch, baseBranch, title, body)
+	head := branch
+	if p := t.Primary(); p != nil {
+		head = prHead(ctx, info, p.PushRemote, branch)
+	}
+	pr, err := f.CreatePR(ctx, info.ForgeOwner, info.ForgeRepo, head, baseBranch, title, body)
 	if err != nil {
 		return 0, err
 	}
@@ -202,10 +208,59 @@ func (s *Server) startPRFlow(ctx context.Context, entry *taskEntry, f forge.Forg
 	entry.monitorBranch = branch
 	s.mu.Unlock()
 	s.notifyTaskChange()
+	if t.Issue != nil {
+		if u := f.PRURL(info.ForgeOwner, info.ForgeRepo, pr.Number); u != "" {
+			go s.commentIssue(s.ctx, t, s.taskRef(t)+" opened "+u) //nolint:contextcheck // must outlive the request
+		}
+	}
 	go s.monitorCI(s.ctx, entry, f, info.ForgeOwner, info.ForgeRepo, pr.HeadSHA) //nolint:contextcheck // CI monitoring must outlive the request
 	return pr.Number, nil
 }
 
+// createCompanionPR opens a PR for an extra repo of a multi-repo task, whose
+// branch was pushed to remote. Unlike startPRFlow it leaves the task's PR and
+// CI monitoring on the primary repo; the body links back to the primary PR
+// when there is one.
+func (s *Server) createCompanionPR(ctx context.Context, entry *taskEntry, f forge.Forge, info *repoInfo, branch, remote, baseBranch string, primary *repoInfo, primaryPR int) (int, error) {
+	t := entry.task
+	title := t.Title()
+	if title == "" {
+		title = t.InitialPrompt.Text
+	}
+	var body string
+	if entry.result != nil {
+		body = entry.result.AgentResult
+	}
+	if primary != nil && primaryPR != 0 {
+		body = strings.TrimSpace(body + "\n\nCompanion of " + primary.ForgeOwner + "/" + primary.ForgeRepo + "#" + strconv.Itoa(primaryPR) + ".")
+	}
+	pr, err := f.CreatePR(ctx, info.ForgeOwner, info.ForgeRepo, prHead(ctx, info, remote, branch), baseBranch, title, body)
+	if err != nil {
+		return 0, err
+	}
+	slog.Info("companion PR created", "task", t.ID, "forge", f.Name(), "owner", info.ForgeOwner, "repo", info.ForgeRepo, "pr", pr.Number)
+	return pr.Number, nil
+}
+
+// prHead returns the head of the PR of branch pushed to remote of the repo:
+// the branch itself for origin, else the branch of the fork the remote points
+// to on the same forge.
+func prHead(ctx context.Context, info *repoInfo, remote, branch string) string {
+	if remote == "" || remote == "origin" {
+		return branch
+	}
+	u, err := gitutil.RunGit(ctx, info.AbsPath, "remote", "get-url", remote)
+	if err != nil {
+		slog.WarnContext(ctx, "PR head: unknown push remote", "repo", info.RelPath, "remote", remote, "err", err)
+		return branch
+	}
+	kind, owner, repo, err := forge.ParseRemoteURL(u)
+	if err != nil || kind != info.ForgeKind || (owner == info.ForgeOwner && repo == info.ForgeRepo) {
+		return branch
+	}
+	return forge.ForkHead(owner, repo, branch)
+}
+
 // repoInfoFor returns the repoInfo for relPath, or nil if not found.
 // Safe to call without the mutex (s.repos is immutable after construction).
 func (s *Server) repoInfoFor(relPath string) *repoInfo {
@@ -259,14 +314,17 @@ func (s *Server) CreateTask(ctx context.Context, req bot.TaskRequest) (string, e
 	if !ok {
 		return "", fmt.Errorf("runner not found for repo %s", req.Repo)
 	}
-	// Pick harness: prefer agent.Claude if available, otherwise take the first one.
+	// Pick harness: prefer agent.Claude if available, otherwise take the first
+	// one that needs no per-user configuration.
 	var harness agent.Harness
 	if _, ok := runner.Backends[agent.Claude]; ok {
 		harness = agent.Claude
 	} else {
 		for h := range runner.Backends {
-			harness = h
-			break
+			if h != agent.Generic {
+				harness = h
+				break
+			}
 		}
 	}
 	if harness == "" {
@@ -278,19 +336,30 @@ func (s *Server) CreateTask(ctx context.Context, req bot.TaskRequest) (string, e
 	t := &task.Task{
 		ID:            ksid.NewID(),
 		InitialPrompt: agent.Prompt{Text: req.Prompt},
-		Repos:         []task.RepoMount{{Name: req.Repo, GitRoot: runner.Dir}},
+		Repos:         []task.RepoMount{{Name: req.Repo, GitRoot: runner.Dir, DeployKey: s.repoDeployKey(req.Repo), PushRemote: s.defaultPushRemote(req.Repo)}},
 		Harness:       harness,
 		GitHubToken:   ghToken,
 		StartedAt:     time.Now().UTC(),
 		Provider:      s.provider,
 		OwnerID:       req.OwnerID,
 		ForgeIssue:    req.IssueNumber,
+		Network:       task.NetworkMode(ownerPrefs.Settings.Network),
+		NetworkAllow:  ownerPrefs.Settings.NetworkAllow,
+		Policy:        toolPolicy(ctx, &ownerPrefs, req.Repo, runner),
 	}
-	if req.IssueNumber > 0 {
+	if req.SlackThreadTS != "" {
+		t.Slack = &task.SlackThread{Channel: req.SlackChannel, TS: req.SlackThreadTS}
+	}
+	if req.Branch != "" {
+		// The runner continues a preset branch instead of allocating one.
+		t.Repos[0].BaseBranch = req.Branch
+		t.Repos[0].Branch = req.Branch
+	}
+	if req.IssueNumber > 0 || req.PR > 0 {
 		// Set forge owner/repo so ListPendingBotTasks can resolve the commenter.
 		for i := range s.repos {
 			if s.repos[i].RelPath == req.Repo && s.repos[i].ForgeOwner != "" {
-				t.SetPR(s.repos[i].ForgeOwner, s.repos[i].ForgeRepo, 0)
+				t.SetPR(s.repos[i].ForgeOwner, s.repos[i].ForgeRepo, req.PR)
 				break
 			}
 		}
@@ -299,7 +368,7 @@ func (s *Server) CreateTask(ctx context.Context, req bot.TaskRequest) (string, e
 	go t.GenerateTitle(s.ctx) //nolint:contextcheck // fire-and-forget; must outlive request
 	entry := &taskEntry{task: t, done: make(chan struct{})}
 	s.mu.Lock()
-	s.tasks[t.ID.String()] = entry
+	s.addNewTask(entry)
 	s.taskChanged()
 	s.mu.Unlock()
 	go func() {
@@ -315,7 +384,7 @@ func (s *Server) CreateTask(ctx context.Context, req bot.TaskRequest) (string, e
 		}
 		s.watchSession(entry, runner, h)
 	}()
-	slog.Info("bot task created", "id", t.ID, "repo", req.Repo, "harness", harness)
+	slog.Info("bot task created", "task", t.ID, "repo", req.Repo, "harness", harness)
 	return t.ID.String(), nil
 }
 
@@ -329,19 +398,28 @@ func (s *Server) WatchTaskCompletion(ctx context.Context, taskID string) (state,
 	if !ok {
 		return "", "", fmt.Errorf("task %s not found", taskID)
 	}
+	st, err := s.waitTaskState(ctx, entry.task, func(st task.State) bool {
+		return st == task.StateWaiting || st == task.StateStopped || st == task.StateFailed || st == task.StatePurged
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return st.String(), lastResultText(entry.task), nil
+}
+
+// waitTaskState blocks until the state of t satisfies ok and returns it.
+func (s *Server) waitTaskState(ctx context.Context, t *task.Task, ok func(task.State) bool) (task.State, error) {
 	for {
-		st := entry.task.GetState()
-		switch st { //nolint:exhaustive // only terminal/idle states are relevant
-		case task.StateWaiting, task.StateStopped, task.StateFailed, task.StatePurged:
-			return st.String(), lastResultText(entry.task), nil
-		}
 		s.mu.Lock()
 		ch := s.changed
 		s.mu.Unlock()
+		if st := t.GetState(); ok(st) {
+			return st, nil
+		}
 		select {
 		case <-ch:
 		case <-ctx.Done():
-			return "", "", ctx.Err()
+			return 0, ctx.Err()
 		}
 	}
 }
@@ -0,0 +1,87 @@
+// Task list projections: the fields and view query parameters that trim the
+// Task JSON sent to list clients.
+package server
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+// taskSummaryOmit lists the Task fields dropped by view=summary: the
+// free-form prompt and plan, which dominate the payload with many tasks.
+// Clients fetch them with GET /api/v1/tasks/{id} for the task on screen.
+var taskSummaryOmit = []string{"initialPrompt", "planContent"}
+
+// taskJSONFields is the set of JSON field names of v1.Task.
+var taskJSONFields = func() map[string]bool {
+	m := map[string]bool{}
+	t := reflect.TypeFor[v1.Task]()
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			m[name] = true
+		}
+	}
+	return m
+}()
+
+// taskProjection selects the Task JSON fields sent to a client. A nil
+// *taskProjection keeps every field.
+type taskProjection struct {
+	keep map[string]bool // When non-nil, only these fields (and "id") are kept.
+	omit []string
+}
+
+// parseTaskProjection parses the "fields" (comma separated JSON field names)
+// and "view" ("full" or "summary") query parameters. It returns nil when the
+// client wants full tasks.
+func parseTaskProjection(q url.Values) (*taskProjection, error) {
+	fields := q.Get("fields")
+	view := q.Get("view")
+	switch {
+	case fields != "" && view != "":
+		return nil, dto.BadRequest("fields and view are mutually exclusive")
+	case fields != "":
+		p := &taskProjection{keep: map[string]bool{"id": true}}
+		for f := range strings.SplitSeq(fields, ",") {
+			f = strings.TrimSpace(f)
+			if !taskJSONFields[f] {
+				return nil, dto.BadRequest("unknown task field: " + f)
+			}
+			p.keep[f] = true
+		}
+		return p, nil
+	case view == "" || view == "full":
+		return nil, nil
+	case view == "summary":
+		return &taskProjection{omit: taskSummaryOmit}, nil
+	default:
+		return nil, dto.Invalid("view", view)
+	}
+}
+
+// marshal returns the JSON encoding of t reduced to the projection.
+func (p *taskProjection) marshal(t *v1.Task) (json.RawMessage, error) {
+	data, err := json.Marshal(t)
+	if err != nil || p == nil {
+		return data, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for k := range m {
+		if p.keep != nil && !p.keep[k] {
+			delete(m, k)
+		}
+	}
+	for _, k := range p.omit {
+		delete(m, k)
+	}
+	return json.Marshal(m)
+}
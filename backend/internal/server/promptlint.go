@@ -0,0 +1,166 @@
+// Pre-flight analysis of draft task prompts with structured suggestions.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/maruel/genai"
+)
+
+const (
+	// lintMinWords is the word count below which a prompt is deemed vague.
+	lintMinWords = 6
+	// lintMaxPasteBytes and lintMaxPasteLines flag prompts that are mostly
+	// pasted content.
+	lintMaxPasteBytes = 20_000
+	lintMaxPasteLines = 300
+	// lintMaxUnknownFiles caps the unknown_file suggestions.
+	lintMaxUnknownFiles = 5
+	// lintLLMMaxChars truncates the prompt sent to the LLM.
+	lintLLMMaxChars = 20_000
+	// lintLLMTimeout bounds the LLM review so the pre-flight stays fast.
+	lintLLMTimeout = 20 * time.Second
+)
+
+// lintSystemPrompt asks the LLM for terse, actionable feedback.
+const lintSystemPrompt = "You review prompts written for an autonomous coding agent before it starts. " +
+	"List at most 3 concrete improvements that would most help the agent succeed, one per line, each under 25 words. " +
+	"Reply with ONLY the list, or with OK if the prompt is already clear."
+
+// codeExts are the file extensions that make a bare word a file reference.
+var codeExts = map[string]bool{
+	".c": true, ".cc": true, ".cpp": true, ".css": true, ".go": true, ".h": true, ".html": true,
+	".java": true, ".js": true, ".json": true, ".kt": true, ".md": true, ".py": true, ".rs": true,
+	".sh": true, ".swift": true, ".toml": true, ".ts": true, ".tsx": true, ".yaml": true, ".yml": true,
+}
+
+// lintPrompt analyzes a draft prompt with local heuristics and, when asked and
+// configured, the LLM provider. An LLM failure only drops its suggestions.
+func (s *Server) lintPrompt(ctx context.Context, req *v1.LintPromptReq) (*v1.LintPromptResp, error) {
+	var repoDir string
+	if req.Repo != "" {
+		dir, ok := s.repoAbsPath(req.Repo)
+		if !ok {
+			return nil, dto.BadRequest("unknown repo: " + req.Repo)
+		}
+		repoDir = dir
+	}
+	resp := &v1.LintPromptResp{Suggestions: lintPromptText(req.Text, repoDir)}
+	if req.UseLLM && s.provider != nil {
+		sugg, err := lintPromptLLM(ctx, s.provider, req.Text)
+		if err != nil {
+			slog.WarnContext(ctx, "prompt lint", "err", err)
+		} else {
+			resp.LLM = true
+			resp.Suggestions = append(resp.Suggestions, sugg...)
+		}
+	}
+	return resp, nil
+}
+
+// lintPromptText runs the local heuristics on text. repoDir is the checkout
+// of the task's primary repo, used to check file references; empty for
+// no-repo tasks.
+func lintPromptText(text, repoDir string) []v1.PromptSuggestion {
+	out := []v1.PromptSuggestion{}
+	words := strings.Fields(text)
+	vague := len(words) < lintMinWords
+	if vague {
+		out = append(out, v1.PromptSuggestion{
+			Kind:     v1.PromptVague,
+			Severity: v1.SeverityWarning,
+			Message:  "The prompt is short; describe the expected behavior and how to verify it.",
+		})
+	}
+	if lines := strings.Count(text, "\n") + 1; len(text) > lintMaxPasteBytes || lines > lintMaxPasteLines {
+		out = append(out, v1.PromptSuggestion{
+			Kind:     v1.PromptLargePaste,
+			Severity: v1.SeverityWarning,
+			Message:  fmt.Sprintf("The prompt is %s over %d lines; reference the file or paste a trimmed excerpt instead.", formatBytes(int64(len(text))), lines),
+		})
+	}
+	refs := fileRefs(words)
+	if repoDir == "" {
+		return out
+	}
+	if len(refs) == 0 && !vague {
+		out = append(out, v1.PromptSuggestion{
+			Kind:     v1.PromptNoFileReference,
+			Severity: v1.SeverityInfo,
+			Message:  "No file or directory is named; pointing at where to look saves the agent exploration time.",
+		})
+	}
+	unknown := 0
+	for _, ref := range refs {
+		// Bare file names may live anywhere in the tree; only check paths.
+		if !strings.Contains(ref, "/") || !filepath.IsLocal(ref) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(repoDir, filepath.FromSlash(ref))); err == nil {
+			continue
+		}
+		if unknown++; unknown > lintMaxUnknownFiles {
+			break
+		}
+		out = append(out, v1.PromptSuggestion{
+			Kind:     v1.PromptUnknownFile,
+			Severity: v1.SeverityInfo,
+			Message:  ref + " does not exist in the repository.",
+		})
+	}
+	return out
+}
+
+// fileRefs returns the words that look like file paths, without surrounding
+// punctuation or a :line suffix, deduplicated in order of appearance.
+func fileRefs(words []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, w := range words {
+		if strings.Contains(w, "://") {
+			continue
+		}
+		w = strings.Trim(w, "\"'`()[]{}<>,;!?")
+		w = strings.TrimRight(w, ".:")
+		if i := strings.IndexByte(w, ':'); i > 0 {
+			w = w[:i]
+		}
+		isPath := strings.Contains(strings.Trim(w, "/"), "/") || codeExts[strings.ToLower(filepath.Ext(w))]
+		if w == "" || !isPath || seen[w] {
+			continue
+		}
+		seen[w] = true
+		out = append(out, w)
+	}
+	return out
+}
+
+// lintPromptLLM asks provider for improvements to text.
+func lintPromptLLM(ctx context.Context, provider genai.Provider, text string) ([]v1.PromptSuggestion, error) {
+	ctx, cancel := context.WithTimeout(ctx, lintLLMTimeout)
+	defer cancel()
+	if len(text) > lintLLMMaxChars {
+		text = text[:lintLLMMaxChars]
+	}
+	res, err := provider.GenSync(ctx, genai.Messages{genai.NewTextMessage(text)}, &genai.GenOptionText{SystemPrompt: lintSystemPrompt})
+	if err != nil {
+		return nil, err
+	}
+	var out []v1.PromptSuggestion
+	for line := range strings.SplitSeq(res.String(), "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•0123456789.)"))
+		if line == "" || strings.EqualFold(strings.TrimRight(line, "."), "ok") {
+			continue
+		}
+		out = append(out, v1.PromptSuggestion{Kind: v1.PromptLLM, Severity: v1.SeverityInfo, Message: line})
+	}
+	return out, nil
+}
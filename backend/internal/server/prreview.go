@@ -0,0 +1,178 @@
+// PR review responder: review feedback on the PR of a task goes back to its agent or to a follow-up task.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/bot"
+	"github.com/caic-xyz/caic/backend/internal/forge/github"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// prReviewDelay is how long review feedback waits for the rest of its
+// review: GitHub sends one event per inline comment besides the review's.
+const prReviewDelay = 10 * time.Second
+
+// prFeedback is a review or an inline review comment left on a PR.
+type prFeedback struct {
+	Author string
+	Body   string
+	Path   string // File of an inline comment; empty for a review.
+	Line   int
+	URL    string
+}
+
+// prReviewBatch is the feedback received on a PR within prReviewDelay.
+type prReviewBatch struct {
+	owner, repo string
+	pr          int
+	branch      string // PR head branch.
+	feedback    []prFeedback
+}
+
+// handlePullRequestReviewEvent queues the body of a submitted review.
+// Approvals and reviews made only of inline comments, which have their own
+// events, are skipped.
+func (s *Server) handlePullRequestReviewEvent(ev *github.PullRequestReviewEvent) {
+	if ev.Action != "submitted" || ev.Review.User.Type == "Bot" || ev.Review.State == "approved" || strings.TrimSpace(ev.Review.Body) == "" {
+		return
+	}
+	s.storeInstallationIDFromFullName(ev.Repository.FullName, ev.Installation.ID)
+	s.queuePRFeedback(ev.Repository.FullName, ev.PullRequest.Number, ev.PullRequest.Head.Ref, prFeedback{
+		Author: ev.Review.User.Login,
+		Body:   ev.Review.Body,
+		URL:    ev.Review.HTMLURL,
+	})
+}
+
+// handlePullRequestReviewCommentEvent queues a new inline review comment.
+func (s *Server) handlePullRequestReviewCommentEvent(ev *github.PullRequestReviewCommentEvent) {
+	if ev.Action != "created" || ev.Comment.User.Type == "Bot" || strings.TrimSpace(ev.Comment.Body) == "" {
+		return
+	}
+	s.storeInstallationIDFromFullName(ev.Repository.FullName, ev.Installation.ID)
+	s.queuePRFeedback(ev.Repository.FullName, ev.PullRequest.Number, ev.PullRequest.Head.Ref, prFeedback{
+		Author: ev.Comment.User.Login,
+		Body:   ev.Comment.Body,
+		Path:   ev.Comment.Path,
+		Line:   ev.Comment.Line,
+		URL:    ev.Comment.HTMLURL,
+	})
+}
+
+// queuePRFeedback adds fb to the batch of the PR, starting one that is
+// handed to respondToReview after prReviewDelay.
+func (s *Server) queuePRFeedback(fullName string, pr int, branch string, fb prFeedback) {
+	owner, repo, _ := strings.Cut(fullName, "/")
+	if owner == "" || repo == "" || pr == 0 || branch == "" {
+		return
+	}
+	key := fmt.Sprintf("%s/%s#%d", owner, repo, pr)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b := s.prReviews[key]; b != nil {
+		b.feedback = append(b.feedback, fb)
+		return
+	}
+	if s.prReviews == nil {
+		s.prReviews = map[string]*prReviewBatch{}
+	}
+	b := &prReviewBatch{owner: owner, repo: repo, pr: pr, branch: branch, feedback: []prFeedback{fb}}
+	s.prReviews[key] = b
+	time.AfterFunc(prReviewDelay, func() {
+		s.mu.Lock()
+		delete(s.prReviews, key)
+		s.mu.Unlock()
+		s.respondToReview(s.ctx, b)
+	})
+}
+
+// prTask returns the latest task of the PR of b, or nil when caic did not
+// open it.
+func (s *Server) prTask(b *prReviewBatch) *taskEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var latest *taskEntry
+	for _, e := range s.tasks {
+		snap := e.task.Snapshot()
+		if snap.ForgeOwner != b.owner || snap.ForgeRepo != b.repo || snap.ForgePR != b.pr {
+			continue
+		}
+		if p := e.task.Primary(); p == nil || p.Branch != b.branch {
+			continue
+		}
+		if latest == nil || e.task.StartedAt.After(latest.task.StartedAt) {
+			latest = e
+		}
+	}
+	return latest
+}
+
+// respondToReview sends the feedback of b to the agent of the PR's task
+// when enabled in its owner's preferences. When the agent can no longer
+// receive input, a follow-up task continues the PR branch with the feedback
+// as its prompt.
+func (s *Server) respondToReview(ctx context.Context, b *prReviewBatch) {
+	entry := s.prTask(b)
+	if entry == nil {
+		return
+	}
+	t := entry.task
+	ownerID := t.OwnerID
+	if ownerID == "" {
+		ownerID = "default"
+	}
+	if !s.prefs.Get(ownerID).Settings.AutoRespondToReviews {
+		return
+	}
+	switch t.GetState() {
+	case task.StatePending, task.StateBranching, task.StateProvisioning, task.StateStarting:
+		// Likely the follow-up of a previous review; wait for its session.
+		for _, fb := range b.feedback {
+			s.queuePRFeedback(b.owner+"/"+b.repo, b.pr, b.branch, fb)
+		}
+		return
+	default:
+	}
+	prompt := prReviewPrompt(b)
+	if err := t.SendInput(ctx, agent.Prompt{Text: prompt}); err == nil {
+		slog.InfoContext(ctx, "review: sent feedback to task", "task", t.ID, "pr", b.pr, "n", len(b.feedback))
+		return
+	}
+	p := t.Primary()
+	if p == nil {
+		return
+	}
+	id, err := s.CreateTask(ctx, bot.TaskRequest{Repo: p.Name, Prompt: prompt, OwnerID: t.OwnerID, Branch: b.branch, PR: b.pr})
+	if err != nil {
+		slog.WarnContext(ctx, "review: create follow-up task", "task", t.ID, "pr", b.pr, "err", err)
+		return
+	}
+	slog.InfoContext(ctx, "review: created follow-up task", "task", id, "src", t.ID, "pr", b.pr, "br", b.branch)
+}
+
+// prReviewPrompt returns the prompt asking the agent to address the
+// feedback of b.
+func prReviewPrompt(b *prReviewBatch) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Review feedback was left on PR #%d. Please address it on branch %q and push the fix:\n", b.pr, b.branch)
+	for _, fb := range b.feedback {
+		sb.WriteString("\n@" + fb.Author)
+		if fb.Path != "" {
+			sb.WriteString(" on " + fb.Path)
+			if fb.Line > 0 {
+				fmt.Fprintf(&sb, ":%d", fb.Line)
+			}
+		}
+		if fb.URL != "" {
+			sb.WriteString(" (" + fb.URL + ")")
+		}
+		sb.WriteString(":\n" + strings.TrimSpace(fb.Body) + "\n")
+	}
+	return sb.String()
+}
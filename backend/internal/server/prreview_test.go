@@ -0,0 +1,88 @@
+// Tests for the PR review responder.
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/forge/github"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+)
+
+func TestPRReview(t *testing.T) {
+	t.Run("Queue", func(t *testing.T) {
+		s := newTestServer(t)
+		review := func(user, typ, state, body string) *github.PullRequestReviewEvent {
+			ev := &github.PullRequestReviewEvent{Action: "submitted"}
+			ev.Review = github.WebhookReview{Body: body, State: state, User: github.WebhookUser{Login: user, Type: typ}}
+			ev.PullRequest.Number = 7
+			ev.PullRequest.Head.Ref = "caic-3"
+			ev.Repository.FullName = "org/repo"
+			return ev
+		}
+		s.handlePullRequestReviewEvent(review("caic[bot]", "Bot", "commented", "done"))
+		s.handlePullRequestReviewEvent(review("alice", "User", "approved", "LGTM"))
+		s.handlePullRequestReviewEvent(review("alice", "User", "commented", " "))
+		if len(s.prReviews) != 0 {
+			t.Fatalf("prReviews = %v, want none", s.prReviews)
+		}
+		s.handlePullRequestReviewEvent(review("alice", "User", "changes_requested", "Needs tests."))
+		ev := &github.PullRequestReviewCommentEvent{Action: "created"}
+		ev.Comment = github.WebhookReviewComment{Body: "Typo.", Path: "main.go", Line: 12, User: github.WebhookUser{Login: "alice", Type: "User"}}
+		ev.PullRequest.Number = 7
+		ev.PullRequest.Head.Ref = "caic-3"
+		ev.Repository.FullName = "org/repo"
+		s.handlePullRequestReviewCommentEvent(ev)
+		s.mu.Lock()
+		b := s.prReviews["org/repo#7"]
+		s.mu.Unlock()
+		if b == nil || len(b.feedback) != 2 || b.branch != "caic-3" {
+			t.Fatalf("batch = %+v, want the review and its comment", b)
+		}
+	})
+	t.Run("Prompt", func(t *testing.T) {
+		b := &prReviewBatch{pr: 7, branch: "caic-3", feedback: []prFeedback{
+			{Author: "alice", Body: "Needs tests.\n", URL: "https://github.com/org/repo/pull/7#r1"},
+			{Author: "bob", Body: "Typo.", Path: "main.go", Line: 12},
+		}}
+		got := prReviewPrompt(b)
+		for _, want := range []string{`PR #7`, `branch "caic-3"`, "@alice (https://github.com/org/repo/pull/7#r1):\nNeeds tests.\n", "@bob on main.go:12:\nTypo.\n"} {
+			if !strings.Contains(got, want) {
+				t.Errorf("prompt = %q, want %q in it", got, want)
+			}
+		}
+	})
+	t.Run("Respond", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := &task.Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "fix"}, Repos: []task.RepoMount{{Name: "org/repo", Branch: "caic-3"}}, StartedAt: time.Now()}
+		tk.SetPR("org", "repo", 7)
+		tk.SetState(task.StateProvisioning)
+		s.tasks[tk.ID.String()] = &taskEntry{task: tk, done: make(chan struct{})}
+		b := &prReviewBatch{owner: "org", repo: "repo", pr: 7, branch: "caic-3", feedback: []prFeedback{{Author: "alice", Body: "Needs tests."}}}
+		if s.prTask(b) == nil {
+			t.Fatal("prTask = nil")
+		}
+		if s.prTask(&prReviewBatch{owner: "org", repo: "repo", pr: 8, branch: "caic-3"}) != nil {
+			t.Error("prTask matched another PR")
+		}
+
+		s.respondToReview(t.Context(), b)
+		if len(s.prReviews) != 0 {
+			t.Error("feedback queued while disabled")
+		}
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) { p.Settings.AutoRespondToReviews = true }); err != nil {
+			t.Fatal(err)
+		}
+		s.respondToReview(t.Context(), b)
+		s.mu.Lock()
+		requeued := s.prReviews["org/repo#7"]
+		s.mu.Unlock()
+		if requeued == nil || len(requeued.feedback) != 1 {
+			t.Errorf("batch = %+v; want the feedback requeued while the task starts", requeued)
+		}
+	})
+}
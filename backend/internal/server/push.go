@@ -0,0 +1,64 @@
+// Push policy: whether task branches are pushed automatically, only on request, or never.
+package server
+
+import (
+	"context"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// pushPolicy returns the push policy of t: its own, else its repo's, else
+// auto.
+func (s *Server) pushPolicy(t *task.Task) task.PushPolicy {
+	if t.Push != "" {
+		return t.Push
+	}
+	ownerID := t.OwnerID
+	if ownerID == "" {
+		ownerID = "default"
+	}
+	if p := t.Primary(); p != nil {
+		if pp, ok := s.prefs.Get(ownerID).Settings.RepoPushPolicies[p.Name]; ok {
+			return task.PushPolicy(pp)
+		}
+	}
+	return task.PushAuto
+}
+
+// pushTask pushes the task branch without opening a PR. It is how the tasks
+// whose push policy is ask get their branch pushed.
+func (s *Server) pushTask(ctx context.Context, entry *taskEntry, req *v1.PushReq) (*v1.SyncResp, error) {
+	return s.syncBranch(ctx, entry, &v1.SyncReq{Force: req.Force}, false)
+}
+
+// checkPushPolicy rejects pushing the branch of t when its policy is never.
+func (s *Server) checkPushPolicy(t *task.Task) error {
+	if s.pushPolicy(t) == task.PushNever {
+		return dto.Conflict("the push policy of the task is never")
+	}
+	return nil
+}
+
+func prefsToV1RepoPushPolicies(m map[string]string) map[string]v1.PushPolicy {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]v1.PushPolicy, len(m))
+	for repo, p := range m {
+		out[repo] = v1.PushPolicy(p)
+	}
+	return out
+}
+
+func prefsFromV1RepoPushPolicies(m map[string]v1.PushPolicy) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for repo, p := range m {
+		out[repo] = string(p)
+	}
+	return out
+}
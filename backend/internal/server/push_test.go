@@ -0,0 +1,77 @@
+// Tests for the push policy.
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func TestPushPolicy(t *testing.T) {
+	newServer := func(t *testing.T, p task.PushPolicy) (*Server, *task.Task) {
+		s := newTestServer(t)
+		tk := &task.Task{
+			InitialPrompt: agent.Prompt{Text: "test"},
+			Repos:         []task.RepoMount{{Name: "org/repo", Branch: "caic-0"}},
+			Container:     "md-repo-caic-0",
+			Push:          p,
+		}
+		tk.SetState(task.StateWaiting)
+		s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+		return s, tk
+	}
+	t.Run("Resolution", func(t *testing.T) {
+		s, tk := newServer(t, "")
+		if got := s.pushPolicy(tk); got != task.PushAuto {
+			t.Errorf("default = %q, want auto", got)
+		}
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.RepoPushPolicies = map[string]string{"org/repo": "ask"}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.pushPolicy(tk); got != task.PushAsk {
+			t.Errorf("repo = %q, want ask", got)
+		}
+		tk.Push = task.PushNever
+		if got := s.pushPolicy(tk); got != task.PushNever {
+			t.Errorf("task override = %q, want never", got)
+		}
+		if got := s.toJSON(s.tasks["t1"]).PushPolicy; got != "never" {
+			t.Errorf("JSON pushPolicy = %q, want never", got)
+		}
+	})
+	t.Run("NeverRejected", func(t *testing.T) {
+		s, _ := newServer(t, task.PushNever)
+		for _, path := range []string{"sync", "push"} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/"+path, strings.NewReader(`{}`))
+			req.SetPathValue("id", "t1")
+			w := httptest.NewRecorder()
+			if path == "sync" {
+				handleWithTask(s, s.syncTask)(w, req)
+			} else {
+				handleWithTask(s, s.pushTask)(w, req)
+			}
+			if w.Code != http.StatusConflict {
+				t.Errorf("%s status = %d, want %d", path, w.Code, http.StatusConflict)
+			}
+		}
+	})
+	t.Run("IdleFinishWarnsNoPush", func(t *testing.T) {
+		s, tk := newServer(t, task.PushAsk)
+		start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		tk.Idle = &task.IdlePolicy{Hours: 8, Action: task.IdleFinish}
+		tk.SetStateAt(task.StateWaiting, start)
+		s.checkIdle(t.Context(), start.Add(8*time.Hour))
+		got := idleNotices(tk)
+		if want := "No input for 8h0m0s: the task will be stopped without pushing its branch in 1h0m0s. Send a message to keep it."; len(got) != 1 || got[0] != want {
+			t.Errorf("notices = %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+// Start queue: ready pending tasks start in priority order.
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// priorityRank orders the priorities; the empty one of tasks not created by
+// createTask is normal.
+var priorityRank = map[v1.TaskPriority]int{
+	v1.PriorityLow:    -1,
+	v1.PriorityNormal: 0,
+	"":                0,
+	v1.PriorityHigh:   1,
+}
+
+// waitInQueue blocks while another ready task outranks entry's, so that a
+// batch of tasks released together, e.g. when the execution window opens,
+// starts in priority order. It returns errWaitCanceled when ctx is canceled.
+func (s *Server) waitInQueue(ctx context.Context, entry *taskEntry) error {
+	ticker := time.NewTicker(depPollInterval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		s.mu.Lock()
+		changed := s.changed
+		blocked := s.outrankedLocked(entry, now)
+		s.mu.Unlock()
+		if !blocked {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ticker.C:
+		case <-ctx.Done():
+			return errWaitCanceled
+		}
+	}
+}
+
+// outrankedLocked reports whether a ready task whose execution window is open
+// comes before entry: it has a higher priority, or the same priority and was
+// created earlier. s.mu must be held.
+func (s *Server) outrankedLocked(entry *taskEntry, now time.Time) bool {
+	rank := priorityRank[entry.priority]
+	for _, e := range s.tasks {
+		if e == entry || !e.ready {
+			continue
+		}
+		r := priorityRank[e.priority]
+		if r < rank || (r == rank && !e.task.StartedAt.Before(entry.task.StartedAt)) {
+			continue
+		}
+		if s.windowOpensAt(e.task, now).After(now) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (s *Server) setTaskPriority(_ context.Context, entry *taskEntry, req *v1.SetPriorityReq) (*v1.StatusResp, error) {
+	if entry.task.GetState() != task.StatePending {
+		return nil, dto.Conflict("task already started")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.priority = req.Priority
+	s.taskChanged()
+	return &v1.StatusResp{Status: "ok"}, nil
+}
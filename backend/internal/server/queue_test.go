@@ -0,0 +1,83 @@
+// Tests for the start queue.
+package server
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func TestOutranked(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newEntry := func(s *Server, id string, p v1.TaskPriority, created time.Duration) *taskEntry {
+		e := &taskEntry{
+			task:     &task.Task{InitialPrompt: agent.Prompt{Text: id}, StartedAt: start.Add(created)},
+			done:     make(chan struct{}),
+			priority: p,
+			ready:    true,
+		}
+		s.tasks[id] = e
+		return e
+	}
+	s := newTestServer(t)
+	low := newEntry(s, "low", v1.PriorityLow, 0)
+	first := newEntry(s, "first", v1.PriorityNormal, time.Second)
+	second := newEntry(s, "second", v1.PriorityNormal, 2*time.Second)
+	high := newEntry(s, "high", v1.PriorityHigh, 3*time.Second)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := start.Add(time.Minute)
+	for _, tt := range []struct {
+		e    *taskEntry
+		want bool
+	}{
+		{high, false},
+		{first, true},
+		{second, true},
+		{low, true},
+	} {
+		if got := s.outrankedLocked(tt.e, now); got != tt.want {
+			t.Errorf("%s: outranked = %t, want %t", tt.e.task.InitialPrompt.Text, got, tt.want)
+		}
+	}
+	// Once the high priority task started, the older normal one goes first.
+	high.ready = false
+	if s.outrankedLocked(first, now) {
+		t.Error("first: outranked after high started")
+	}
+	if !s.outrankedLocked(second, now) {
+		t.Error("second: not outranked by first")
+	}
+}
+
+func TestHandleTaskPriority(t *testing.T) {
+	t.Run("Pending", func(t *testing.T) {
+		s := newTestServer(t)
+		e := &taskEntry{task: &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}, done: make(chan struct{}), priority: v1.PriorityNormal}
+		s.tasks["t1"] = e
+		if _, err := s.setTaskPriority(t.Context(), e, &v1.SetPriorityReq{Priority: v1.PriorityHigh}); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if got := s.toJSON(e).Priority; got != v1.PriorityHigh {
+			t.Errorf("priority = %q, want high", got)
+		}
+	})
+	t.Run("Started", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
+		tk.SetState(task.StateRunning)
+		e := &taskEntry{task: tk, done: make(chan struct{})}
+		_, err := s.setTaskPriority(t.Context(), e, &v1.SetPriorityReq{Priority: v1.PriorityHigh})
+		var apiErr *dto.APIError
+		if !errors.As(err, &apiErr) || apiErr.Code() != dto.CodeConflict {
+			t.Errorf("err = %v, want conflict", err)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+// Automatic resume of the tasks whose turn failed on a provider rate limit.
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// rateLimitPollInterval is how often pollRateLimited looks for rate limited
+// tasks due for a resume.
+const rateLimitPollInterval = 10 * time.Second
+
+// pollRateLimited resumes the rate limited tasks once their backoff elapsed,
+// every rateLimitPollInterval until ctx is done.
+func (s *Server) pollRateLimited(ctx context.Context) {
+	ticker := time.NewTicker(rateLimitPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.resumeRateLimited(ctx, now)
+		}
+	}
+}
+
+// resumeRateLimited resumes the rate limited tasks due at now. A task whose
+// session is gone goes back to waiting for the user.
+func (s *Server) resumeRateLimited(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	var due []*taskEntry
+	for _, e := range s.tasks {
+		if at := e.task.RateLimitResumeAt(); !at.IsZero() && !at.After(now) {
+			due = append(due, e)
+		}
+	}
+	s.mu.Unlock()
+	for _, e := range due {
+		slog.InfoContext(ctx, "resuming rate limited task", "task", e.task.ID)
+		if err := e.task.ResumeRateLimited(ctx); err != nil {
+			slog.WarnContext(ctx, "rate limited task resume failed", "task", e.task.ID, "err", err)
+			e.task.SetStateIf(task.StateRateLimited, task.StateWaiting)
+		}
+		s.notifyTaskChange()
+	}
+}
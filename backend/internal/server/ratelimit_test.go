@@ -0,0 +1,49 @@
+// Tests for the rate limit backoff.
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func TestRateLimits(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	newEntry := func(s *Server, id string, h agent.Harness) *taskEntry {
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}, Harness: h}
+		tk.SetStateAt(task.StateRateLimited, since)
+		e := &taskEntry{task: tk, done: make(chan struct{})}
+		s.tasks[id] = e
+		return e
+	}
+	t.Run("Compute", func(t *testing.T) {
+		s := newTestServer(t)
+		newEntry(s, "t1", agent.Codex)
+		newEntry(s, "t2", agent.Claude)
+		newEntry(s, "t3", agent.Claude)
+		got := computeRateLimits(s.tasks)
+		if len(got) != 2 {
+			t.Fatalf("got %d harnesses, want 2: %+v", len(got), got)
+		}
+		if got[0].Harness != "claude" || got[0].LimitedTasks != 2 || got[1].Harness != "codex" || got[1].LimitedTasks != 1 {
+			t.Errorf("got %+v", got)
+		}
+		if want := since.Add(time.Minute); !got[0].ResumesAt.Equal(want) {
+			t.Errorf("ResumesAt = %v, want %v", got[0].ResumesAt, want)
+		}
+	})
+	t.Run("ResumeWithoutSession", func(t *testing.T) {
+		s := newTestServer(t)
+		e := newEntry(s, "t1", agent.Claude)
+		s.resumeRateLimited(t.Context(), since.Add(30*time.Second))
+		if st := e.task.GetState(); st != task.StateRateLimited {
+			t.Fatalf("state = %v before the backoff elapsed", st)
+		}
+		s.resumeRateLimited(t.Context(), since.Add(time.Minute))
+		if st := e.task.GetState(); st != task.StateWaiting {
+			t.Errorf("state = %v, want waiting", st)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+// Git remotes of the repos and the push target of the task branches, for fork-based workflows.
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md/gitutil"
+)
+
+// repoPushRemote returns the default push remote of the repo at abs, or ""
+// for origin.
+func repoPushRemote(ctx context.Context, abs string) string {
+	if r := task.PushRemote(ctx, abs); r != "origin" {
+		return r
+	}
+	return ""
+}
+
+// repoRemotes returns the remotes of the repo at abs.
+func repoRemotes(ctx context.Context, abs string) (*v1.RepoRemotesResp, error) {
+	remotes, err := task.ListRemotes(ctx, abs)
+	if err != nil {
+		return nil, dto.InternalError("list remotes: " + err.Error())
+	}
+	resp := &v1.RepoRemotesResp{Remotes: make([]v1.RepoRemote, 0, len(remotes)), PushRemote: task.PushRemote(ctx, abs)}
+	for _, r := range remotes {
+		resp.Remotes = append(resp.Remotes, v1.RepoRemote{Name: r.Name, URL: r.URL})
+	}
+	return resp, nil
+}
+
+func (s *Server) handleListRepoRemotes(w http.ResponseWriter, r *http.Request) {
+	repo := r.URL.Query().Get("repo")
+	if repo == "" {
+		writeError(w, dto.Required("repo"))
+		return
+	}
+	s.mu.Lock()
+	absPath, ok := s.repoAbsPath(repo)
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, dto.NotFound("repo"))
+		return
+	}
+	resp, err := repoRemotes(r.Context(), absPath)
+	writeJSONResponse(w, resp, err)
+}
+
+// setRepoRemote adds or updates a remote of a repo and optionally makes it
+// the default push remote of the tasks created afterwards.
+func (s *Server) setRepoRemote(ctx context.Context, req *v1.SetRepoRemoteReq) (*v1.RepoRemotesResp, error) {
+	s.mu.Lock()
+	absPath, ok := s.repoAbsPath(req.Repo)
+	s.mu.Unlock()
+	if !ok {
+		return nil, dto.NotFound("repo")
+	}
+	if req.URL != "" {
+		if err := task.SetRemote(ctx, absPath, req.Name, req.URL); err != nil {
+			return nil, dto.InternalError("set remote: " + err.Error())
+		}
+	}
+	if req.Push {
+		if err := task.SetPushRemote(ctx, absPath, req.Name); err != nil {
+			return nil, dto.BadRequest(err.Error())
+		}
+	}
+	slog.InfoContext(ctx, "remote configured", "repo", req.Repo, "remote", req.Name, "push", req.Push)
+	s.setRepoPushRemote(req.Repo, repoPushRemote(ctx, absPath))
+	return repoRemotes(ctx, absPath)
+}
+
+// deleteRepoRemote removes a remote of a repo. Running tasks keep pushing to
+// it.
+func (s *Server) deleteRepoRemote(ctx context.Context, req *v1.DeleteRepoRemoteReq) (*v1.RepoRemotesResp, error) {
+	s.mu.Lock()
+	absPath, ok := s.repoAbsPath(req.Repo)
+	s.mu.Unlock()
+	if !ok {
+		return nil, dto.NotFound("repo")
+	}
+	if _, err := gitutil.RunGit(ctx, absPath, "remote", "get-url", req.Name); err != nil {
+		return nil, dto.NotFound("remote")
+	}
+	if err := task.RemoveRemote(ctx, absPath, req.Name); err != nil {
+		return nil, dto.InternalError("remove remote: " + err.Error())
+	}
+	s.setRepoPushRemote(req.Repo, repoPushRemote(ctx, absPath))
+	return repoRemotes(ctx, absPath)
+}
+
+// defaultPushRemote returns the default push remote of the repo rel, or ""
+// for origin.
+func (s *Server) defaultPushRemote(rel string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.repos {
+		if s.repos[i].RelPath == rel {
+			return s.repos[i].PushRemote
+		}
+	}
+	return ""
+}
+
+// setRepoPushRemote records the default push remote of the repo rel and
+// notifies the clients of the repo list change.
+func (s *Server) setRepoPushRemote(rel, remote string) {
+	s.mu.Lock()
+	for i := range s.repos {
+		if s.repos[i].RelPath == rel {
+			s.repos[i].PushRemote = remote
+		}
+	}
+	s.mu.Unlock()
+	s.notifyTaskChange()
+}
+
+// pushRemoteMounts fills the push remotes of the mounts from the request,
+// defaulting to their repo's. It fails when a remote doesn't exist.
+func (s *Server) pushRemoteMounts(ctx context.Context, mounts []task.RepoMount, specs []v1.RepoSpec) error {
+	for i := range mounts {
+		remote := specs[i].PushRemote
+		if remote == "" {
+			remote = s.defaultPushRemote(mounts[i].Name)
+		} else if _, err := gitutil.RunGit(ctx, mounts[i].GitRoot, "remote", "get-url", remote); err != nil {
+			return dto.BadRequest("unknown push remote " + remote + " for " + mounts[i].Name)
+		}
+		mounts[i].PushRemote = remote
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Tests for the repo remotes and the push target of the task branches.
+package server
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/forge"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md/gitutil"
+)
+
+func TestRepoRemotes(t *testing.T) {
+	t.Run("SetAndDelete", func(t *testing.T) {
+		dir := newGitRepo(t, map[string]string{"README.md": "hello\n"})
+		fork := filepath.Join(t.TempDir(), "fork.git")
+		if _, err := gitutil.RunGit(t.Context(), "", "init", "--bare", fork); err != nil {
+			t.Fatal(err)
+		}
+		s := newTestServer(t)
+		s.repos = []repoInfo{{RelPath: "org/repo", AbsPath: dir}}
+
+		resp, err := s.setRepoRemote(t.Context(), &v1.SetRepoRemoteReq{Repo: "org/repo", Name: "fork", URL: fork, Push: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.PushRemote != "fork" || len(resp.Remotes) != 1 || resp.Remotes[0] != (v1.RepoRemote{Name: "fork", URL: fork}) {
+			t.Errorf("resp = %+v", resp)
+		}
+		if got := s.defaultPushRemote("org/repo"); got != "fork" {
+			t.Errorf("defaultPushRemote = %q, want fork", got)
+		}
+
+		mounts := []task.RepoMount{{Name: "org/repo", GitRoot: dir}}
+		if err := s.pushRemoteMounts(t.Context(), mounts, []v1.RepoSpec{{Name: "org/repo"}}); err != nil || mounts[0].PushRemote != "fork" {
+			t.Errorf("default mount = %+v, %v", mounts[0], err)
+		}
+		if err := s.pushRemoteMounts(t.Context(), mounts, []v1.RepoSpec{{Name: "org/repo", PushRemote: "nope"}}); err == nil {
+			t.Error("unknown push remote accepted")
+		}
+
+		if _, err := s.setRepoRemote(t.Context(), &v1.SetRepoRemoteReq{Repo: "org/repo", Name: "origin", Push: true}); err != nil {
+			t.Fatal(err)
+		}
+		if got := s.defaultPushRemote("org/repo"); got != "" {
+			t.Errorf("defaultPushRemote after reset = %q, want origin", got)
+		}
+		if _, err := s.setRepoRemote(t.Context(), &v1.SetRepoRemoteReq{Repo: "org/repo", Name: "fork", Push: true}); err != nil {
+			t.Fatal(err)
+		}
+		resp, err = s.deleteRepoRemote(t.Context(), &v1.DeleteRepoRemoteReq{Repo: "org/repo", Name: "fork"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.PushRemote != "origin" || len(resp.Remotes) != 0 || s.defaultPushRemote("org/repo") != "" {
+			t.Errorf("after delete = %+v", resp)
+		}
+		if _, err := s.deleteRepoRemote(t.Context(), &v1.DeleteRepoRemoteReq{Repo: "org/repo", Name: "fork"}); err == nil {
+			t.Error("second delete succeeded")
+		}
+		if _, err := s.setRepoRemote(t.Context(), &v1.SetRepoRemoteReq{Repo: "missing", Name: "fork", URL: fork}); err == nil {
+			t.Error("set on a missing repo succeeded")
+		}
+	})
+	t.Run("prHead", func(t *testing.T) {
+		dir := newGitRepo(t, map[string]string{"README.md": "hello\n"})
+		if _, err := gitutil.RunGit(t.Context(), dir, "remote", "add", "fork", "git@github.com:me/repo.git"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := gitutil.RunGit(t.Context(), dir, "remote", "add", "mirror", "https://github.com/org/repo.git"); err != nil {
+			t.Fatal(err)
+		}
+		info := &repoInfo{RelPath: "org/repo", AbsPath: dir, ForgeKind: forge.KindGitHub, ForgeOwner: "org", ForgeRepo: "repo"}
+		for remote, want := range map[string]string{
+			"":       "caic-1",
+			"origin": "caic-1",
+			"fork":   "me/repo:caic-1",
+			"mirror": "caic-1",
+			"nope":   "caic-1",
+		} {
+			if got := prHead(t.Context(), info, remote, "caic-1"); got != want {
+				t.Errorf("prHead(%q) = %q, want %q", remote, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,83 @@
+// Replay of a task transcript with its original pacing.
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+const (
+	// replayStep spaces the messages that no timestamped record bounds.
+	replayStep = 100 * time.Millisecond
+	// maxReplayGap shortens idle periods, e.g. a task waiting for input, so
+	// that a replay does not stall for hours.
+	maxReplayGap = time.Minute
+	// maxReplaySpeed caps the speed query parameter.
+	maxReplaySpeed = 100
+)
+
+// replayTimes estimates when each message in msgs was received. Messages
+// carry no timestamp, but the relay's diff stat records do and anchor the
+// timeline: the messages between two anchors are spread evenly, the others
+// replayStep apart. Without any anchor the timeline starts at base.
+func replayTimes(msgs []agent.Message, base time.Time) []time.Time {
+	out := make([]time.Time, len(msgs))
+	prev := -1 // Index of the previous anchor.
+	for i, m := range msgs {
+		ds, ok := m.(*agent.DiffStatMessage)
+		if !ok || ds.Ts <= 0 {
+			continue
+		}
+		at := epochTime(ds.Ts)
+		if prev >= 0 && !at.Before(out[prev]) {
+			step := at.Sub(out[prev]) / time.Duration(i-prev)
+			for j := prev + 1; j < i; j++ {
+				out[j] = out[prev].Add(time.Duration(j-prev) * step)
+			}
+		} else {
+			// Leading messages, or a clock going backwards.
+			for j := prev + 1; j < i; j++ {
+				out[j] = at.Add(-time.Duration(i-j) * replayStep)
+			}
+		}
+		out[i], prev = at, i
+	}
+	if prev < 0 {
+		for j := range out {
+			out[j] = base.Add(time.Duration(j) * replayStep)
+		}
+		return out
+	}
+	for j := prev + 1; j < len(out); j++ {
+		out[j] = out[prev].Add(time.Duration(j-prev) * replayStep)
+	}
+	return out
+}
+
+// replayDelay returns how long to wait between messages received at prev and
+// at when replaying at speed.
+func replayDelay(prev, at time.Time, speed float64) time.Duration {
+	gap := min(max(at.Sub(prev), 0), maxReplayGap)
+	return time.Duration(float64(gap) / speed)
+}
+
+// sleepReplay waits d, pinging the stream meanwhile. It returns false when
+// the client is gone.
+func sleepReplay(ctx context.Context, ka *sseKeepAlive, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-timer.C:
+			return true
+		case <-ka.C():
+			if ka.ping() != nil {
+				return false
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// Tests for the realtime replay of task transcripts.
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+func TestReplayTimes(t *testing.T) {
+	text := func() agent.Message { return &agent.TextMessage{Text: "hi"} }
+	anchor := func(sec float64) agent.Message {
+		return &agent.DiffStatMessage{MessageType: "caic_diff_stat", Ts: sec}
+	}
+	base := time.Unix(1000, 0)
+
+	t.Run("Anchors", func(t *testing.T) {
+		msgs := []agent.Message{text(), anchor(10), text(), text(), text(), anchor(14), text()}
+		got := replayTimes(msgs, base)
+		want := []time.Duration{
+			10*time.Second - replayStep, 10 * time.Second, 11 * time.Second, 12 * time.Second,
+			13 * time.Second, 14 * time.Second, 14*time.Second + replayStep,
+		}
+		for i := range want {
+			if d := got[i].Sub(time.Unix(0, 0)); d != want[i] {
+				t.Errorf("msg %d at %s, want %s", i, d, want[i])
+			}
+		}
+	})
+	t.Run("NoAnchor", func(t *testing.T) {
+		got := replayTimes([]agent.Message{text(), text()}, base)
+		if !got[0].Equal(base) || got[1].Sub(got[0]) != replayStep {
+			t.Errorf("times = %v, want %s apart from %s", got, replayStep, base)
+		}
+	})
+	t.Run("Delay", func(t *testing.T) {
+		if d := replayDelay(base, base.Add(8*time.Second), 4); d != 2*time.Second {
+			t.Errorf("delay = %s, want 2s", d)
+		}
+		if d := replayDelay(base, base.Add(time.Hour), 1); d != maxReplayGap {
+			t.Errorf("idle delay = %s, want %s", d, maxReplayGap)
+		}
+		if d := replayDelay(base, base.Add(-time.Second), 1); d != 0 {
+			t.Errorf("backwards delay = %s, want 0", d)
+		}
+	})
+}
@@ -4,24 +4,31 @@ package server
 import (
 	"encoding/json"
 	"errors"
+	"hash/fnv"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/caic-xyz/caic/backend/internal/server/dto"
 )
 
 // writeError writes a structured JSON error response. If err implements
-// dto.ErrorWithStatus, the HTTP status, error code and details are taken from
-// it; otherwise 500 is used.
+// dto.ErrorWithStatus, the HTTP status, error code, message parameters, field
+// errors and details are taken from it; otherwise 500 is used.
 func writeError(w http.ResponseWriter, err error) {
 	statusCode := http.StatusInternalServerError
 	code := dto.CodeInternalError
+	var params map[string]string
+	var fields []dto.FieldError
 	var details map[string]any
 
 	var ews dto.ErrorWithStatus
 	if errors.As(err, &ews) {
 		statusCode = ews.StatusCode()
 		code = ews.Code()
+		params = ews.Params()
+		fields = ews.Fields()
 		details = ews.Details()
 	}
 
@@ -29,7 +36,7 @@ func writeError(w http.ResponseWriter, err error) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	resp := dto.ErrorResponse{
-		Error:   dto.ErrorDetails{Code: code, Message: err.Error()},
+		Error:   dto.ErrorDetails{Code: code, Message: err.Error(), Params: params, Fields: fields},
 		Details: details,
 	}
 	if encErr := json.NewEncoder(w).Encode(resp); encErr != nil {
@@ -49,3 +56,43 @@ func writeJSONResponse[Out any](w http.ResponseWriter, output *Out, err error) {
 		slog.Warn("failed to encode JSON response", "err", encErr)
 	}
 }
+
+// writeConditionalJSON writes output like writeJSONResponse with an ETag
+// computed from the encoded body, prefixed by version when non-empty. When
+// the request's If-None-Match already holds that ETag, it writes 304 Not
+// Modified instead so polling clients skip re-downloading identical JSON.
+func writeConditionalJSON[Out any](w http.ResponseWriter, r *http.Request, output *Out, version string) {
+	data, err := json.Marshal(output)
+	if err != nil {
+		writeError(w, dto.InternalError("failed to encode response"))
+		return
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	tag := strconv.FormatUint(h.Sum64(), 16)
+	if version != "" {
+		tag = version + "-" + tag
+	}
+	etag := `"` + tag + `"`
+	w.Header().Set("ETag", etag)
+	if etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		slog.Warn("failed to write JSON response", "err", err)
+	}
+}
+
+// etagMatch reports whether the If-None-Match header h lists etag, using the
+// weak comparison RFC 9110 requires for If-None-Match.
+func etagMatch(h, etag string) bool {
+	for c := range strings.SplitSeq(h, ",") {
+		c = strings.TrimSpace(c)
+		if c == "*" || strings.TrimPrefix(c, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
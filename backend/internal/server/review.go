@@ -0,0 +1,196 @@
+// Agent-to-agent review: a reviewer session checks each turn's diff and sends feedback back to the task.
+
+package server
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+)
+
+const (
+	// defaultReviewRounds is used when ReviewSpec.MaxRounds is zero.
+	defaultReviewRounds = 3
+	// maxReviewDiff caps the diff embedded in the reviewer prompt.
+	maxReviewDiff = 100_000
+	// reviewApproved and reviewChanges are the verdict lines the reviewer
+	// must end its answer with.
+	reviewApproved = "VERDICT: APPROVE"
+	reviewChanges  = "VERDICT: CHANGES"
+)
+
+// reviewRun tracks the review loop of a task. Guarded by Server.mu.
+type reviewRun struct {
+	spec      v1.ReviewSpec
+	round     int
+	status    string
+	reviewers []ksid.ID
+	feedback  string
+	err       string
+}
+
+func (r *reviewRun) toJSON() *v1.ReviewProgress {
+	return &v1.ReviewProgress{
+		Round:     r.round,
+		MaxRounds: r.spec.MaxRounds,
+		Status:    r.status,
+		Reviewers: r.reviewers,
+		Feedback:  r.feedback,
+		Error:     r.err,
+	}
+}
+
+// reviewPrompt builds the reviewer's instructions for the task's diff.
+func reviewPrompt(request, diff string) string {
+	if len(diff) > maxReviewDiff {
+		diff = diff[:maxReviewDiff] + "\n[diff truncated]\n"
+	}
+	var b strings.Builder
+	b.WriteString("You are reviewing changes another coding agent made for this request:\n\n")
+	b.WriteString(request)
+	b.WriteString("\n\nThe repository is checked out at the base branch; the proposed diff is:\n\n```diff\n")
+	b.WriteString(diff)
+	b.WriteString("```\n\nReview the diff for correctness, missing tests and style consistent with the repository. ")
+	b.WriteString("Do not modify files. List concrete, actionable feedback, then end your answer with a final line that is exactly ")
+	b.WriteString(reviewApproved + " if the change is ready to merge, or " + reviewChanges + " otherwise.")
+	return b.String()
+}
+
+// parseVerdict reports whether the review approves the change and returns the
+// feedback without the verdict line. A review without a verdict counts as
+// requesting changes.
+func parseVerdict(review string) (approved bool, feedback string) {
+	review = strings.TrimSpace(review)
+	i := strings.LastIndexByte(review, '\n')
+	last := strings.TrimSpace(strings.Trim(review[i+1:], "*_` "))
+	switch last {
+	case reviewApproved:
+		return true, strings.TrimSpace(review[:max(i, 0)])
+	case reviewChanges:
+		return false, strings.TrimSpace(review[:max(i, 0)])
+	default:
+		return false, review
+	}
+}
+
+// runReview reviews the task after each successful turn, feeding requested
+// changes back until the reviewer approves or the rounds run out.
+func (s *Server) runReview(entry *taskEntry, runner *task.Runner) {
+	t := entry.task
+	s.mu.Lock()
+	spec := entry.review.spec
+	s.mu.Unlock()
+	p := t.Primary()
+	for round := 1; ; round++ {
+		if state := s.waitForTurn(entry); state != task.StateWaiting {
+			s.setReviewStatus(entry, round, "aborted", "", "task is "+state.String())
+			return
+		}
+		if rm := lastResult(t); rm == nil || rm.IsError {
+			s.setReviewStatus(entry, round, "failed", "", "turn ended with an error")
+			return
+		}
+		diff, err := runner.DiffContent(s.ctx, p.Branch, "")
+		if err != nil {
+			s.setReviewStatus(entry, round, "failed", "", "diff: "+err.Error())
+			return
+		}
+		if strings.TrimSpace(diff) == "" {
+			s.setReviewStatus(entry, round, "failed", "", "no changes to review")
+			return
+		}
+		s.setReviewStatus(entry, round, "reviewing", "", "")
+		approved, feedback, err := s.reviewOnce(entry, diff)
+		if err != nil {
+			s.setReviewStatus(entry, round, "failed", "", err.Error())
+			return
+		}
+		if approved {
+			s.setReviewStatus(entry, round, "approved", feedback, "")
+			return
+		}
+		if round >= spec.MaxRounds {
+			s.setReviewStatus(entry, round, "exhausted", feedback, "")
+			return
+		}
+		s.setReviewStatus(entry, round, "changes_requested", feedback, "")
+		prompt := "A reviewer requested changes:\n\n" + feedback + "\n\nAddress the feedback."
+		if err := t.SendInput(s.ctx, agent.Prompt{Text: prompt}); err != nil {
+			s.setReviewStatus(entry, round, "failed", feedback, err.Error())
+			return
+		}
+	}
+}
+
+// reviewOnce starts a read-only reviewer task on the primary repo, waits for
+// its verdict and purges its container. The reviewer's transcript is kept
+// like any purged task's.
+func (s *Server) reviewOnce(entry *taskEntry, diff string) (bool, string, error) {
+	t := entry.task
+	s.mu.Lock()
+	spec := entry.review.spec
+	s.mu.Unlock()
+	p := t.Primary()
+	rev, err := s.startTask(s.ctx, &v1.CreateTaskReq{
+		InitialPrompt: v1.Prompt{Text: reviewPrompt(t.InitialPrompt.Text, diff)},
+		Repos:         []v1.RepoSpec{{Name: p.Name, BaseBranch: p.BaseBranch}},
+		Harness:       spec.Harness,
+		Model:         spec.Model,
+		ReadOnly:      true,
+		Chat:          true,
+	}, t.OwnerID, nil)
+	if err != nil {
+		return false, "", err
+	}
+	s.mu.Lock()
+	entry.review.reviewers = append(entry.review.reviewers, rev.task.ID)
+	s.taskChanged()
+	s.mu.Unlock()
+	defer s.purgeAfterUse(rev)
+	if state := s.waitForTurn(rev); state != task.StateWaiting {
+		return false, "", errors.New("reviewer is " + state.String())
+	}
+	rm := lastResult(rev.task)
+	if rm == nil || rm.IsError {
+		return false, "", errors.New("reviewer turn ended with an error")
+	}
+	approved, feedback := parseVerdict(rm.Result)
+	return approved, feedback, nil
+}
+
+// purgeAfterUse purges a helper task started by the server, such as a
+// reviewer once its verdict is in.
+func (s *Server) purgeAfterUse(entry *taskEntry) {
+	switch entry.task.GetState() {
+	case task.StatePurging, task.StatePurged, task.StateFailed:
+		return
+	default:
+	}
+	entry.task.SetState(task.StatePurging)
+	s.notifyTaskChange()
+	var name string
+	if p := entry.task.Primary(); p != nil {
+		name = p.Name
+	}
+	go s.cleanupTask(entry, s.runners[name], task.StatePurged)
+}
+
+func (s *Server) setReviewStatus(entry *taskEntry, round int, status, feedback, errMsg string) {
+	if errMsg != "" {
+		slog.Warn("review stopped", "task", entry.task.ID, "round", round, "status", status, "err", errMsg)
+	}
+	s.mu.Lock()
+	entry.review.round = round
+	entry.review.status = status
+	if feedback != "" {
+		entry.review.feedback = feedback
+	}
+	entry.review.err = errMsg
+	s.taskChanged()
+	s.mu.Unlock()
+}
@@ -0,0 +1,188 @@
+// Source roots: the directories repos are discovered under.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md/gitutil"
+)
+
+// defaultRootDepth is how deep repos are searched for under a root.
+const defaultRootDepth = 3
+
+// rootNameRe matches valid source root names.
+var rootNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// sourceRoot is a directory repos are discovered under.
+//
+// The repos of the primary root, whose Name is empty, are identified by their
+// path relative to it, e.g. "github/caic". The ones of the other roots by
+// "<name>:<relative path>", e.g. "shared:github/caic".
+type sourceRoot struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// MaxDepth is how many directory levels are searched; 0 means 3.
+	MaxDepth int `json:"maxDepth,omitempty"`
+	// Exclude skips the repos whose relative path, or a parent of it, matches
+	// one of these path.Match patterns, e.g. "archive/*".
+	Exclude []string `json:"exclude,omitempty"`
+	// Defaults override the user preferences for the tasks whose primary repo
+	// is in the root.
+	Defaults rootDefaults `json:"defaults,omitzero"`
+}
+
+// rootDefaults are the settings of new tasks on the repos of a source root.
+// The settings of the task creation request take precedence.
+type rootDefaults struct {
+	BaseImage    string   `json:"baseImage,omitempty"`
+	Network      string   `json:"network,omitempty"` // "full", "none" or "allowlist".
+	NetworkAllow []string `json:"networkAllow,omitempty"`
+}
+
+// loadSourceRoots returns the primary root at primary followed by the roots
+// listed in the JSON file at path. A missing file lists no other root.
+func loadSourceRoots(primary, path string) ([]sourceRoot, error) {
+	abs, err := filepath.Abs(primary)
+	if err != nil {
+		return nil, err
+	}
+	roots := []sourceRoot{{Path: abs}}
+	data, err := os.ReadFile(path) //nolint:gosec // G304: internal config path
+	if err != nil {
+		if os.IsNotExist(err) {
+			return roots, nil
+		}
+		return nil, err
+	}
+	var extra []sourceRoot
+	if err := json.Unmarshal(data, &extra); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range extra {
+		r := &extra[i]
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("%s: root %d: %w", path, i, err)
+		}
+		if slices.ContainsFunc(roots, func(o sourceRoot) bool { return o.Name == r.Name }) {
+			return nil, fmt.Errorf("%s: duplicate root %q", path, r.Name)
+		}
+		roots = append(roots, *r)
+	}
+	return roots, nil
+}
+
+// validate checks the root and makes its path absolute.
+func (r *sourceRoot) validate() error {
+	if !rootNameRe.MatchString(r.Name) {
+		return fmt.Errorf("invalid name %q: use 1-32 lowercase letters, digits, '_' or '-'", r.Name)
+	}
+	if r.Path == "" {
+		return errors.New("path is required")
+	}
+	if strings.HasPrefix(r.Path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		r.Path = filepath.Join(home, r.Path[2:])
+	}
+	var err error
+	if r.Path, err = filepath.Abs(r.Path); err != nil {
+		return err
+	}
+	if r.MaxDepth < 0 {
+		return fmt.Errorf("negative maxDepth %d", r.MaxDepth)
+	}
+	for _, p := range r.Exclude {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q", p)
+		}
+	}
+	switch task.NetworkMode(r.Defaults.Network) {
+	case "", task.NetworkFull, task.NetworkNone, task.NetworkAllowlist:
+	default:
+		return fmt.Errorf("invalid network %q", r.Defaults.Network)
+	}
+	return nil
+}
+
+// discover returns the absolute paths of the repos in the root that are not
+// excluded.
+func (r *sourceRoot) discover() ([]string, error) {
+	depth := r.MaxDepth
+	if depth == 0 {
+		depth = defaultRootDepth
+	}
+	paths, err := gitutil.DiscoverRepos(r.Path, depth)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(paths, func(abs string) bool {
+		rel, err := filepath.Rel(r.Path, abs)
+		return err == nil && r.excluded(filepath.ToSlash(rel))
+	}), nil
+}
+
+// excluded reports whether rel or one of its parents matches an exclude
+// pattern.
+func (r *sourceRoot) excluded(rel string) bool {
+	for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+		for _, pat := range r.Exclude {
+			if ok, _ := path.Match(pat, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// repoPath returns the API path of the repo at abs, in the root.
+func (r *sourceRoot) repoPath(abs string) string {
+	rel, err := filepath.Rel(r.Path, abs)
+	if err != nil {
+		rel = filepath.Base(abs)
+	}
+	return r.qualify(filepath.ToSlash(rel))
+}
+
+// qualify prefixes the path relative to the root with the root name.
+func (r *sourceRoot) qualify(rel string) string {
+	if r.Name == "" {
+		return rel
+	}
+	return r.Name + ":" + rel
+}
+
+// rootDefaults returns the task defaults of the root of a repo API path.
+func (s *Server) rootDefaults(repoPath string) rootDefaults {
+	if r, _, ok := s.rootFor(repoPath); ok {
+		return r.Defaults
+	}
+	return rootDefaults{}
+}
+
+// rootFor returns the root of a repo API path and the path relative to it.
+func (s *Server) rootFor(repoPath string) (*sourceRoot, string, bool) {
+	name, rel, ok := strings.Cut(repoPath, ":")
+	if !ok {
+		if len(s.roots) == 0 {
+			return nil, "", false
+		}
+		return &s.roots[0], repoPath, true
+	}
+	for i := range s.roots {
+		if s.roots[i].Name != "" && s.roots[i].Name == name {
+			return &s.roots[i], rel, true
+		}
+	}
+	return nil, "", false
+}
@@ -0,0 +1,87 @@
+// Tests for the source roots.
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSourceRoots(t *testing.T) {
+	mkRepos := func(t *testing.T, root string, rels ...string) {
+		for _, rel := range rels {
+			if err := os.MkdirAll(filepath.Join(root, rel, ".git"), 0o700); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	writeRoots := func(t *testing.T, content string) string {
+		p := filepath.Join(t.TempDir(), "roots.json")
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	t.Run("Missing", func(t *testing.T) {
+		primary := t.TempDir()
+		roots, err := loadSourceRoots(primary, filepath.Join(t.TempDir(), "roots.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(roots) != 1 || roots[0].Name != "" || roots[0].Path != primary {
+			t.Errorf("roots = %+v, want only the primary root", roots)
+		}
+	})
+	t.Run("Discover", func(t *testing.T) {
+		primary, shared := t.TempDir(), t.TempDir()
+		mkRepos(t, primary, "github/caic")
+		mkRepos(t, shared, "team/api", "archive/old", "team/deep/er/repo")
+		roots, err := loadSourceRoots(primary, writeRoots(t, `[{"name":"shared","path":"`+shared+`","maxDepth":2,"exclude":["archive"],"defaults":{"baseImage":"img"}}]`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for i := range roots {
+			paths, err := roots[i].discover()
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, abs := range paths {
+				got = append(got, roots[i].repoPath(abs))
+			}
+		}
+		if want := []string{"github/caic", "shared:team/api"}; !slices.Equal(got, want) {
+			t.Errorf("repos = %q, want %q", got, want)
+		}
+
+		s := &Server{roots: roots}
+		if r, rel, ok := s.rootFor("shared:team/api"); !ok || r.Name != "shared" || rel != "team/api" {
+			t.Errorf("rootFor(shared:team/api) = %+v, %q, %t", r, rel, ok)
+		}
+		if r, rel, ok := s.rootFor("github/caic"); !ok || r.Name != "" || rel != "github/caic" {
+			t.Errorf("rootFor(github/caic) = %+v, %q, %t", r, rel, ok)
+		}
+		if _, _, ok := s.rootFor("other:x"); ok {
+			t.Error("rootFor(other:x) found a root")
+		}
+		if got := s.rootDefaults("shared:team/api").BaseImage; got != "img" {
+			t.Errorf("baseImage = %q, want img", got)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"name":      `[{"name":"Shared","path":"/srv"}]`,
+			"path":      `[{"name":"shared"}]`,
+			"duplicate": `[{"name":"a","path":"/a"},{"name":"a","path":"/b"}]`,
+			"pattern":   `[{"name":"a","path":"/a","exclude":["["]}]`,
+			"network":   `[{"name":"a","path":"/a","defaults":{"network":"lan"}}]`,
+			"json":      `{"name":"a"}`,
+		} {
+			if _, err := loadSourceRoots(t.TempDir(), writeRoots(t, content)); err == nil {
+				t.Errorf("%s: no error", name)
+			}
+		}
+	})
+}
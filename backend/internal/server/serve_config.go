@@ -22,7 +22,10 @@ import (
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	"github.com/caic-xyz/caic/backend/internal/autoupdate"
 	"github.com/caic-xyz/caic/backend/internal/forge"
+	"github.com/caic-xyz/caic/backend/internal/index"
+	"github.com/caic-xyz/caic/backend/internal/policy"
 	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/pricing"
 	"github.com/caic-xyz/caic/backend/internal/server/dto"
 	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
 	"github.com/caic-xyz/caic/backend/internal/task"
@@ -37,6 +40,7 @@ func (s *Server) getConfig(_ context.Context, _ *dto.EmptyReq) (*v1.Config, erro
 		USBAvailable:       runtime.GOOS == "linux",
 		DisplayAvailable:   true,
 		GitHubAppEnabled:   s.forge.githubApp != nil,
+		SemanticSearch:     s.indexes.Semantic(),
 	}
 	if s.authEnabled() {
 		cfg.AuthProviders = s.authProviders()
@@ -63,30 +67,134 @@ func (s *Server) getPreferences(ctx context.Context, _ *dto.EmptyReq) (*v1.Prefe
 			ContainerPath: m.ContainerPath,
 		}
 	}
+	var repoPolicies map[string][]v1.PolicyRule
+	if len(prefs.Settings.RepoToolPolicies) != 0 {
+		repoPolicies = make(map[string][]v1.PolicyRule, len(prefs.Settings.RepoToolPolicies))
+		for repo, rules := range prefs.Settings.RepoToolPolicies {
+			repoPolicies[repo] = toV1PolicyRules(rules)
+		}
+	}
 	return &v1.PreferencesResp{
 		Repositories: repos,
 		Harness:      prefs.Harness,
 		Models:       prefs.Models,
 		Settings: v1.UserSettings{
-			AutoFixOnCIFailure: prefs.Settings.AutoFixOnCIFailure,
-			AutoFixOnPROpen:    prefs.Settings.AutoFixOnPROpen,
-			BaseImage:          prefs.Settings.BaseImage,
-			GitHubTokenAccess:  string(prefs.Settings.GitHubTokenAccess),
-			UseDefaultCaches:   prefs.Settings.UseDefaultCaches,
-			WellKnownCaches:    prefs.Settings.WellKnownCaches,
-			CacheMappings:      cacheMappings,
+			AutoFixOnCIFailure:   prefs.Settings.AutoFixOnCIFailure,
+			AutoFixOnPROpen:      prefs.Settings.AutoFixOnPROpen,
+			AutoRespondToReviews: prefs.Settings.AutoRespondToReviews,
+			BaseImage:            prefs.Settings.BaseImage,
+			ValidateBaseImage:    prefs.Settings.ValidateBaseImage,
+			PendingBaseImage:     prefsToV1PendingImage(prefs.Settings.PendingBaseImage),
+			PersistWorkspace:     prefs.Settings.PersistWorkspace,
+			GitHubTokenAccess:    string(prefs.Settings.GitHubTokenAccess),
+			UseDefaultCaches:     prefs.Settings.UseDefaultCaches,
+			WellKnownCaches:      prefs.Settings.WellKnownCaches,
+			CacheMappings:        cacheMappings,
+			ModelPrices:          toV1Prices(prefs.Settings.ModelPrices),
+			Network:              v1.NetworkMode(prefs.Settings.Network),
+			NetworkAllow:         prefs.Settings.NetworkAllow,
+			ToolPolicy:           toV1PolicyRules(prefs.Settings.ToolPolicy),
+			RepoToolPolicies:     repoPolicies,
+			GenericHarness:       toV1GenericHarness(prefs.Settings.GenericHarness),
+			IdlePolicy:           prefsToV1IdlePolicy(prefs.Settings.IdlePolicy),
+			RepoIdlePolicies:     prefsToV1RepoIdlePolicies(prefs.Settings.RepoIdlePolicies),
+			StallPolicy:          prefsToV1StallPolicy(prefs.Settings.StallPolicy),
+			Fallback:             prefsToV1Fallback(prefs.Settings.Fallback),
+			RepoPushPolicies:     prefsToV1RepoPushPolicies(prefs.Settings.RepoPushPolicies),
+			RepoPrePushChecks:    prefs.Settings.RepoPrePushChecks,
+			RepoInstructions:     prefs.Settings.RepoInstructions,
+			RepoProjects:         prefs.Settings.RepoProjects,
+			ExecutionWindow:      prefsToV1ExecutionWindow(prefs.Settings.ExecutionWindow),
+			NotifyChannels:       prefsToV1NotifyChannels(prefs.Settings.NotifyChannels),
+			NotifyRoutes:         prefs.Settings.NotifyRoutes,
+			EnvAllowlist:         prefs.Settings.EnvAllowlist,
+			Timezone:             prefs.Settings.Timezone,
 		},
 	}, nil
 }
 
+// defaultRecentPrompts and maxRecentPrompts bound the limit query parameter
+// of GET /api/v1/prompts/recent.
+const (
+	defaultRecentPrompts = 20
+	maxRecentPrompts     = 200
+)
+
+func (s *Server) handleListRecentPrompts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := defaultRecentPrompts
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxRecentPrompts {
+			writeError(w, dto.BadRequest("limit must be between 1 and "+strconv.Itoa(maxRecentPrompts)))
+			return
+		}
+		limit = n
+	}
+	prefs := s.prefs.Get(userIDFromCtx(r.Context()))
+	hist := prefs.SearchPrompts(q.Get("repo"), q.Get("prefix"), limit)
+	out := make([]v1.RecentPrompt, len(hist))
+	for i, h := range hist {
+		out[i] = v1.RecentPrompt{Repo: h.Repo, Text: h.Text, LastUsed: time.Unix(h.LastUsed, 0).UTC()}
+	}
+	writeJSONResponse(w, &out, nil)
+}
+
 func (s *Server) updatePreferences(ctx context.Context, req *v1.UpdatePreferencesReq) (*v1.PreferencesResp, error) {
-	if err := s.prefs.Update(userIDFromCtx(ctx), func(p *preferences.Preferences) {
+	// Rules are checked here so that invalid ones are reported as such rather
+	// than as a failure to save.
+	globalRules := fromV1PolicyRules(req.Settings.ToolPolicy)
+	if _, err := policy.New(globalRules, "/"); err != nil {
+		return nil, dto.BadRequest("toolPolicy: " + err.Error())
+	}
+	var repoPolicies map[string][]policy.Rule
+	if len(req.Settings.RepoToolPolicies) != 0 {
+		repoPolicies = make(map[string][]policy.Rule, len(req.Settings.RepoToolPolicies))
+		for repo, rules := range req.Settings.RepoToolPolicies {
+			repoPolicies[repo] = fromV1PolicyRules(rules)
+			if _, err := policy.New(repoPolicies[repo], "/"); err != nil {
+				return nil, dto.BadRequest("repoToolPolicies[" + repo + "]: " + err.Error())
+			}
+		}
+	}
+	if err := validateNotifyRoutes(req.Settings.NotifyRoutes); err != nil {
+		return nil, err
+	}
+	ownerID := userIDFromCtx(ctx)
+	notifyChannels, err := prefsFromV1NotifyChannels(req.Settings.NotifyChannels, s.prefs.Get(ownerID).Settings.NotifyChannels)
+	if err != nil {
+		return nil, err
+	}
+	prevImage := s.prefs.Get(ownerID).Settings.BaseImage
+	validate := false
+	if err := s.prefs.Update(ownerID, func(p *preferences.Preferences) {
 		p.Settings.AutoFixOnCIFailure = req.Settings.AutoFixOnCIFailure
 		p.Settings.AutoFixOnPROpen = req.Settings.AutoFixOnPROpen
-		p.Settings.BaseImage = req.Settings.BaseImage
+		p.Settings.AutoRespondToReviews = req.Settings.AutoRespondToReviews
+		validate = applyBaseImage(&p.Settings, req.Settings.BaseImage, req.Settings.ValidateBaseImage)
+		p.Settings.PersistWorkspace = req.Settings.PersistWorkspace
 		p.Settings.GitHubTokenAccess = preferences.GitHubTokenAccess(req.Settings.GitHubTokenAccess)
 		p.Settings.UseDefaultCaches = req.Settings.UseDefaultCaches
 		p.Settings.WellKnownCaches = req.Settings.WellKnownCaches
+		p.Settings.ModelPrices = fromV1Prices(req.Settings.ModelPrices)
+		p.Settings.Network = string(req.Settings.Network)
+		p.Settings.NetworkAllow = req.Settings.NetworkAllow
+		p.Settings.ToolPolicy = globalRules
+		p.Settings.RepoToolPolicies = repoPolicies
+		p.Settings.GenericHarness = fromV1GenericHarness(req.Settings.GenericHarness, p.Settings.GenericHarness)
+		p.Settings.IdlePolicy = prefsFromV1IdlePolicy(req.Settings.IdlePolicy)
+		p.Settings.RepoIdlePolicies = prefsFromV1RepoIdlePolicies(req.Settings.RepoIdlePolicies)
+		p.Settings.StallPolicy = prefsFromV1StallPolicy(req.Settings.StallPolicy)
+		p.Settings.Fallback = prefsFromV1Fallback(req.Settings.Fallback)
+		p.Settings.RepoPushPolicies = prefsFromV1RepoPushPolicies(req.Settings.RepoPushPolicies)
+		p.Settings.RepoPrePushChecks = req.Settings.RepoPrePushChecks
+		p.Settings.RepoInstructions = req.Settings.RepoInstructions
+		p.Settings.RepoProjects = req.Settings.RepoProjects
+		p.Settings.ExecutionWindow = prefsFromV1ExecutionWindow(req.Settings.ExecutionWindow)
+		p.Settings.NotifyChannels = notifyChannels
+		p.Settings.NotifyRoutes = req.Settings.NotifyRoutes
+		p.Settings.EnvAllowlist = req.Settings.EnvAllowlist
+		p.Settings.Timezone = req.Settings.Timezone
 		if req.Settings.CacheMappings != nil {
 			p.Settings.CacheMappings = make([]preferences.CacheMapping, len(req.Settings.CacheMappings))
 			for i, m := range req.Settings.CacheMappings {
@@ -99,11 +207,67 @@ func (s *Server) updatePreferences(ctx context.Context, req *v1.UpdatePreference
 	}); err != nil {
 		return nil, dto.InternalError("save preferences: " + err.Error())
 	}
+	if validate {
+		go func() {
+			_, _ = s.validateImage(s.ctx, ownerID, req.Settings.BaseImage) //nolint:contextcheck // outlives the request
+		}()
+	} else if img := req.Settings.BaseImage; img != "" && img != prevImage && s.mdClient != nil {
+		// Pull the new base image now rather than when the next task starts.
+		settings := s.prefs.Get(ownerID).Settings
+		go s.warmupImage(pinnedImage(&settings, img))
+	}
 	// Return the updated preferences.
 	return s.getPreferences(ctx, nil)
 }
 
-func (s *Server) listHarnesses(_ context.Context, _ *dto.EmptyReq) (*[]v1.HarnessInfo, error) {
+// listPrices returns the built-in price table merged with the user's
+// overrides, sorted by model.
+func (s *Server) listPrices(ctx context.Context, _ *dto.EmptyReq) (*[]v1.PriceEntry, error) {
+	overrides := s.prefs.Get(userIDFromCtx(ctx)).Settings.ModelPrices
+	out := make([]v1.PriceEntry, 0, len(pricing.Defaults)+len(overrides))
+	for model, p := range overrides {
+		out = append(out, v1.PriceEntry{Model: model, Price: toV1Price(p), Override: true})
+	}
+	for model, p := range pricing.Defaults {
+		if _, ok := overrides[model]; !ok {
+			out = append(out, v1.PriceEntry{Model: model, Price: toV1Price(p)})
+		}
+	}
+	slices.SortFunc(out, func(a, b v1.PriceEntry) int { return strings.Compare(a.Model, b.Model) })
+	return &out, nil
+}
+
+func toV1Price(p pricing.Price) v1.ModelPrice {
+	return v1.ModelPrice{Input: p.Input, Output: p.Output, CacheWrite: p.CacheWrite, CacheRead: p.CacheRead, CacheReadIncluded: p.CacheReadIncluded}
+}
+
+func toV1Prices(m map[string]pricing.Price) map[string]v1.ModelPrice {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]v1.ModelPrice, len(m))
+	for k, p := range m {
+		out[k] = toV1Price(p)
+	}
+	return out
+}
+
+func fromV1Prices(m map[string]v1.ModelPrice) map[string]pricing.Price {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make(map[string]pricing.Price, len(m))
+	for k, p := range m {
+		out[k] = pricing.Price{Input: p.Input, Output: p.Output, CacheWrite: p.CacheWrite, CacheRead: p.CacheRead, CacheReadIncluded: p.CacheReadIncluded}
+	}
+	return out
+}
+
+func (s *Server) listHarnesses(ctx context.Context, _ *dto.EmptyReq) (*[]v1.HarnessInfo, error) {
+	if s.local != nil {
+		// Pick up models pulled since the last listing.
+		refreshLocalModels(ctx, s.local)
+	}
 	// Collect unique harness backends from all runners.
 	seen := make(map[agent.Harness]agent.Backend)
 	for _, r := range s.runners {
@@ -111,9 +275,14 @@ func (s *Server) listHarnesses(_ context.Context, _ *dto.EmptyReq) (*[]v1.Harnes
 			seen[h] = b
 		}
 	}
+	prefs := s.prefs.Get(userIDFromCtx(ctx))
 	out := make([]v1.HarnessInfo, 0, len(seen))
 	for h, b := range seen {
-		out = append(out, v1.HarnessInfo{Name: string(h), Models: b.Models(), SupportsImages: b.SupportsImages(), SupportsCompact: b.SupportsCompact()})
+		models := harnessModels(&prefs, b)
+		if h == agent.Generic && len(models) == 0 {
+			continue
+		}
+		out = append(out, v1.HarnessInfo{Name: string(h), Models: models, SupportsImages: b.SupportsImages(), SupportsCompact: b.SupportsCompact(), SupportsInterrupt: b.SupportsInterrupt(), SupportsThinking: b.SupportsThinking()})
 	}
 	slices.SortFunc(out, func(a, b v1.HarnessInfo) int {
 		return strings.Compare(a.Name, b.Name)
@@ -153,6 +322,23 @@ func (s *Server) listCaches(_ context.Context, _ *dto.EmptyReq) (*v1.WellKnownCa
 	}, nil
 }
 
+func (s *Server) getLogLevel(_ context.Context, _ *dto.EmptyReq) (*v1.LogLevelResp, error) {
+	return &v1.LogLevelResp{Level: strings.ToLower(s.logLevel.Level().String())}, nil
+}
+
+// setLogLevel changes the level of the default logger. It is not persisted;
+// CAIC_LOG_LEVEL applies again on restart.
+func (s *Server) setLogLevel(ctx context.Context, req *v1.LogLevelReq) (*v1.LogLevelResp, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(req.Level)); err != nil {
+		return nil, dto.BadRequest("invalid log level: " + req.Level)
+	}
+	prev := s.logLevel.Level()
+	s.logLevel.Set(l)
+	slog.InfoContext(ctx, "log level changed", "from", prev, "to", l)
+	return &v1.LogLevelResp{Level: strings.ToLower(l.String())}, nil
+}
+
 func (s *Server) listRepos(_ context.Context, _ *dto.EmptyReq) (*[]v1.Repo, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -162,7 +348,7 @@ func (s *Server) listRepos(_ context.Context, _ *dto.EmptyReq) (*[]v1.Repo, erro
 func (s *Server) handleListRepoBranches(w http.ResponseWriter, r *http.Request) {
 	repo := r.URL.Query().Get("repo")
 	if repo == "" {
-		writeError(w, dto.BadRequest("repo is required"))
+		writeError(w, dto.Required("repo"))
 		return
 	}
 	absPath, ok := s.repoAbsPath(repo)
@@ -206,10 +392,99 @@ func (s *Server) handleListRepoBranches(w http.ResponseWriter, r *http.Request)
 	writeJSONResponse(w, &v1.RepoBranchesResp{Branches: branches}, nil)
 }
 
+// defaultRepoSearchResults and maxRepoSearchResults bound the limit query
+// parameter of the repo search endpoints.
+const (
+	defaultRepoSearchResults = 50
+	maxRepoSearchResults     = 500
+)
+
+func (s *Server) handleSearchRepo(w http.ResponseWriter, r *http.Request) {
+	runner, text, limit, err := s.parseRepoSearch(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	idx, err := s.indexes.Get(r.Context(), runner.Dir, runner.BaseBranch)
+	if err != nil {
+		writeError(w, dto.InternalError("index: "+err.Error()))
+		return
+	}
+	res := idx.Search(index.Query{Text: text, Max: limit})
+	resp := v1.RepoSearchResp{
+		Commit:    idx.Commit,
+		Paths:     res.Paths,
+		Matches:   make([]v1.RepoSearchMatch, len(res.Matches)),
+		Truncated: res.Truncated,
+	}
+	if resp.Paths == nil {
+		resp.Paths = []string{}
+	}
+	for i, m := range res.Matches {
+		resp.Matches[i] = v1.RepoSearchMatch{Path: m.Path, Line: m.Line, Text: m.Text}
+	}
+	writeJSONResponse(w, &resp, nil)
+}
+
+func (s *Server) handleSemanticSearchRepo(w http.ResponseWriter, r *http.Request) {
+	if !s.indexes.Semantic() {
+		writeError(w, dto.BadRequest(index.ErrNoEmbedder.Error()))
+		return
+	}
+	runner, text, limit, err := s.parseRepoSearch(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	commit, matches, err := s.indexes.SemanticSearch(r.Context(), runner.Dir, runner.BaseBranch, text, limit)
+	if err != nil {
+		writeError(w, dto.InternalError("semantic search: "+err.Error()))
+		return
+	}
+	resp := v1.RepoSemanticSearchResp{Commit: commit, Results: make([]v1.SemanticSearchResult, len(matches))}
+	for i, m := range matches {
+		resp.Results[i] = v1.SemanticSearchResult{Path: m.Path, StartLine: m.StartLine, EndLine: m.EndLine, Score: float64(m.Score)}
+	}
+	writeJSONResponse(w, &resp, nil)
+}
+
+// parseRepoSearch parses the repo, q and limit query parameters of the repo
+// search endpoints.
+func (s *Server) parseRepoSearch(r *http.Request) (*task.Runner, string, int, error) {
+	q := r.URL.Query()
+	repo, text := q.Get("repo"), q.Get("q")
+	if repo == "" || text == "" {
+		return nil, "", 0, dto.BadRequest("repo and q are required")
+	}
+	limit := defaultRepoSearchResults
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxRepoSearchResults {
+			return nil, "", 0, dto.BadRequest("limit must be between 1 and " + strconv.Itoa(maxRepoSearchResults))
+		}
+		limit = n
+	}
+	runner, ok := s.runners[repo]
+	if !ok {
+		return nil, "", 0, dto.NotFound("repo")
+	}
+	return runner, text, limit, nil
+}
+
 func (s *Server) cloneRepo(ctx context.Context, req *v1.CloneRepoReq) (*v1.Repo, error) {
-	// Derive target relative path.
-	targetPath := req.Path
-	if targetPath == "" {
+	// Derive target relative path, optionally prefixed with a source root
+	// name.
+	root, targetPath, ok := s.rootFor(req.Path)
+	if !ok {
+		return nil, dto.BadRequest("unknown source root: " + req.Path)
+	}
+	if targetPath == "" && req.Mirror {
+		p, err := MirrorPath(req.URL)
+		if err != nil {
+			return nil, dto.BadRequest(err.Error() + "; specify path explicitly")
+		}
+		targetPath = p
+	} else if targetPath == "" {
 		// Extract basename from URL, stripping .git suffix.
 		base := filepath.Base(req.URL)
 		base = strings.TrimSuffix(base, ".git")
@@ -219,11 +494,12 @@ func (s *Server) cloneRepo(ctx context.Context, req *v1.CloneRepoReq) (*v1.Repo,
 		targetPath = base
 	}
 
-	absTarget := filepath.Join(s.absRoot, targetPath)
-	// Defense-in-depth: ensure the resolved path is under absRoot.
-	if rel, err := filepath.Rel(s.absRoot, absTarget); err != nil || strings.HasPrefix(rel, "..") {
+	absTarget := filepath.Join(root.Path, targetPath)
+	// Defense-in-depth: ensure the resolved path is under the root.
+	if rel, err := filepath.Rel(root.Path, absTarget); err != nil || strings.HasPrefix(rel, "..") {
 		return nil, dto.BadRequest("path escapes root directory")
 	}
+	targetPath = root.repoPath(absTarget)
 
 	// Check if directory already exists.
 	if _, err := os.Stat(absTarget); err == nil {
@@ -235,22 +511,31 @@ func (s *Server) cloneRepo(ctx context.Context, req *v1.CloneRepoReq) (*v1.Repo,
 		return nil, dto.Conflict("repo already registered: " + targetPath)
 	}
 
-	// Determine clone depth.
-	depth := req.Depth
-	if depth == 0 {
-		depth = 1
-	}
-
 	// Run git clone with timeout.
 	cloneCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
-	args := []string{"clone", "--depth", strconv.Itoa(depth), "--recurse-submodules", "--shallow-submodules", req.URL, absTarget}
-	cmd := exec.CommandContext(cloneCtx, "git", args...) //nolint:gosec // args are validated: depth is an int, URL is user-provided input, absTarget is validated above
-	if out, err := cmd.CombinedOutput(); err != nil {
-		// Clean up partial clone.
-		_ = os.RemoveAll(absTarget)
-		slog.Warn("git clone failed", "url", req.URL, "err", err, "out", string(out))
-		return nil, dto.InternalError("git clone failed: " + err.Error())
+	if req.Mirror {
+		if err := os.MkdirAll(filepath.Dir(absTarget), 0o750); err != nil {
+			return nil, dto.InternalError("create parent directory: " + err.Error())
+		}
+		if err := CloneMirror(cloneCtx, req.URL, absTarget); err != nil {
+			slog.Warn("git clone failed", "url", req.URL, "err", err)
+			return nil, dto.InternalError("git clone failed: " + err.Error())
+		}
+	} else {
+		// Determine clone depth.
+		depth := req.Depth
+		if depth == 0 {
+			depth = 1
+		}
+		args := []string{"clone", "--depth", strconv.Itoa(depth), "--recurse-submodules", "--shallow-submodules", req.URL, absTarget}
+		cmd := exec.CommandContext(cloneCtx, "git", args...) //nolint:gosec // args are validated: depth is an int, URL is user-provided input, absTarget is validated above
+		if out, err := cmd.CombinedOutput(); err != nil {
+			// Clean up partial clone.
+			_ = os.RemoveAll(absTarget)
+			slog.Warn("git clone failed", "url", req.URL, "err", err, "out", string(out))
+			return nil, dto.InternalError("git clone failed: " + err.Error())
+		}
 	}
 
 	// Discover repo info.
@@ -272,23 +557,29 @@ func (s *Server) cloneRepo(ctx context.Context, req *v1.CloneRepoReq) (*v1.Repo,
 		Dir:        absTarget,
 		LogDir:     s.logDir,
 		Container:  s.backend,
+		Journal:    s.journal,
+		BranchIDs:  s.branchIDs,
+
+		OnSessionRestarted: s.watchRestartedSession,
+		OnPolicyViolation:  s.onPolicyViolation,
 	}
 	if err := runner.Init(ctx); err != nil {
 		_ = os.RemoveAll(absTarget)
 		return nil, dto.InternalError("failed to init runner: " + err.Error())
 	}
+	s.addCompatBackends(runner)
 
 	var cloneForgeKind forge.Kind
 	var cloneForgeOwner, cloneForgeRepo string
 	if rawURL, err := forge.RemoteURL(ctx, absTarget); err == nil {
 		cloneForgeKind, cloneForgeOwner, cloneForgeRepo, _ = forge.ParseRemoteURL(rawURL)
 	}
-	info := repoInfo{RelPath: targetPath, AbsPath: absTarget, BaseBranch: branch, BaseBranchRemote: remoteName, Remote: remote, ForgeKind: cloneForgeKind, ForgeOwner: cloneForgeOwner, ForgeRepo: cloneForgeRepo}
+	info := repoInfo{RelPath: targetPath, Root: root.Name, AbsPath: absTarget, BaseBranch: branch, BaseBranchRemote: remoteName, Remote: remote, ForgeKind: cloneForgeKind, ForgeOwner: cloneForgeOwner, ForgeRepo: cloneForgeRepo, Mirror: req.Mirror}
 	s.repos = append(s.repos, info)
 	s.runners[targetPath] = runner
 	slog.Info("cloned repo", "url", req.URL, "path", targetPath)
 
-	return &v1.Repo{Path: targetPath, BaseBranch: v1.BranchInfo{Name: branch, Remote: remoteName}, RemoteURL: gitutil.RemoteToHTTPS(remote), Forge: v1.Forge(cloneForgeKind)}, nil
+	return &v1.Repo{Path: targetPath, Root: root.Name, Mirror: req.Mirror, BaseBranch: v1.BranchInfo{Name: branch, Remote: remoteName}, RemoteURL: gitutil.RemoteToHTTPS(remote), Forge: v1.Forge(cloneForgeKind)}, nil
 }
 
 // getVoiceToken returns a Gemini API credential for the Android voice client.
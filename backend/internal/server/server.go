@@ -4,9 +4,9 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"io/fs"
 	"log/slog"
 	"net"
 	"net/http"
@@ -15,24 +15,31 @@ import (
 	"sync"
 	"time"
 
-	"github.com/caic-xyz/caic/backend/frontend"
+	"github.com/caic-xyz/caic/backend/internal/agent/openaicompat"
 	"github.com/caic-xyz/caic/backend/internal/auth"
 	"github.com/caic-xyz/caic/backend/internal/bot"
 	"github.com/caic-xyz/caic/backend/internal/container"
+	"github.com/caic-xyz/caic/backend/internal/eventbus"
 	"github.com/caic-xyz/caic/backend/internal/forge"
 	"github.com/caic-xyz/caic/backend/internal/forge/forgecache"
+	"github.com/caic-xyz/caic/backend/internal/index"
+	"github.com/caic-xyz/caic/backend/internal/logctx"
 	"github.com/caic-xyz/caic/backend/internal/preferences"
 	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
 	"github.com/caic-xyz/caic/backend/internal/server/ipgeo"
 	"github.com/caic-xyz/caic/backend/internal/server/voicertc"
+	"github.com/caic-xyz/caic/backend/internal/slack"
 	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/caic/backend/internal/tracker"
 	"github.com/caic-xyz/caic/backend/internal/usage"
 	"github.com/caic-xyz/md"
 	"github.com/maruel/genai"
+	"github.com/quic-go/quic-go/http3"
 )
 
 type repoInfo struct {
-	RelPath          string // e.g. "github/caic" — used as API ID.
+	RelPath          string // e.g. "github/caic" — used as API ID; see sourceRoot.
+	Root             string // Name of the source root; empty for the primary root.
 	AbsPath          string
 	BaseBranch       string
 	BaseBranchRemote string     // Git remote name (e.g. "origin") used to determine BaseBranch.
@@ -40,6 +47,9 @@ type repoInfo struct {
 	ForgeKind        forge.Kind // empty if remote is not a recognized forge
 	ForgeOwner       string     // empty if remote is not a recognized forge
 	ForgeRepo        string     // empty if remote is not a recognized forge
+	Mirror           bool       // Bare clone managed by caic; see CloneMirror.
+	DeployKey        string     // SSH private key file the repo is fetched and pushed with; empty uses the host's credentials.
+	PushRemote       string     // Remote task branches are pushed to by default; empty means origin.
 }
 
 // githubAppClient is the interface used by the server to interact with a GitHub App.
@@ -61,11 +71,20 @@ type Config struct {
 	// Agent backends.
 	GeminiAPIKey    string // required for Gemini Live audio
 	TailscaleAPIKey string // required for Tailscale networking inside containers
+	// LocalURL is the OpenAI-compatible API base URL of a local inference
+	// server (e.g. http://localhost:11434/v1); it enables the local harness.
+	LocalURL string
 
 	// LLM features (title generation, commit descriptions).
 	LLMProvider string
 	LLMModel    string
 
+	// Semantic code search; enabled when EmbeddingURL and EmbeddingModel are
+	// set. See index.OpenAIEmbedder.
+	EmbeddingURL    string
+	EmbeddingModel  string
+	EmbeddingAPIKey string
+
 	// GitHub — PAT and OAuth are mutually exclusive; App is independent.
 	GitHubToken             string // PAT; mutually exclusive with GitHubOAuthClientID
 	GitHubOAuthClientID     string // OAuth app client ID; mutually exclusive with GitHubToken
@@ -84,6 +103,24 @@ type Config struct {
 	GitLabURL               string // default "https://gitlab.com"
 	GitLabWebhookSecret     []byte // X-Gitlab-Token secret; enables POST /webhooks/gitlab
 
+	// Issue trackers: tasks mentioning an issue key are linked to the issue
+	// and post their progress on it. Jira needs the three values.
+	JiraURL      string // e.g. https://example.atlassian.net
+	JiraEmail    string // Account of JiraAPIToken.
+	JiraAPIToken string
+	LinearAPIKey string
+
+	// Slack app: "/caic run <repo> <prompt>" creates a task reporting in a
+	// thread, whose replies are sent to the task. All values are needed.
+	SlackSigningSecret []byte // Enables POST /webhooks/slack/{commands,events}
+	SlackBotToken      string // "xoxb-..." token to post messages.
+	SlackAllowedUsers  string // Comma-separated Slack user IDs allowed to use the app.
+
+	// AdminUsers is a comma-separated list of usernames allowed to use the
+	// /api/v1/admin endpoints when OAuth login is enabled. Without OAuth the
+	// single local user is an admin.
+	AdminUsers string
+
 	// ExternalURL is the public base URL (e.g. https://caic.example.com).
 	// "auto" (the default) locks the hostname from the first FQDN request.
 	// Required for OAuth login and webhook delivery.
@@ -92,8 +129,59 @@ type Config struct {
 	// WebRTC voice bridge (optional).
 	WebRTCPort int // UDP port for ICE; 0 disables WebRTC
 
+	// FrontendDir serves the web UI from this directory instead of the
+	// embedded build, e.g. backend/frontend/dist while iterating on the
+	// frontend. Files are read on every request and rebuilds are detected.
+	FrontendDir string
+	// StaticCacheMB caps the memory holding the embedded frontend files
+	// transcoded for clients not accepting brotli. 0 uses the default.
+	StaticCacheMB int
+
+	// TLS. With a certificate and its key the server speaks HTTPS and
+	// browsers use HTTP/2, multiplexing the SSE streams of all tabs over one
+	// connection. Without them it speaks plaintext HTTP/1.1 and h2c, HTTP/2
+	// without TLS as used by reverse proxies.
+	TLSCertFile string
+	TLSKeyFile  string
+	// HTTP3 also serves HTTP/3 over QUIC on the UDP port of the listen
+	// address, advertised with Alt-Svc. Requires TLS.
+	HTTP3 bool
+
+	// SSEPing is the interval of the keep-alive comments sent on SSE streams,
+	// under the idle timeout of proxies. 0 uses the default of 20s.
+	SSEPing time.Duration
+
+	// MirrorFetch is the interval at which the managed mirrors, the bare
+	// clones under the source roots, are fetched. 0 uses the default of 10
+	// minutes.
+	MirrorFetch time.Duration
+
+	// FeedToken enables the task feeds under /feeds/, authenticated by a
+	// token query parameter equal to it. They list the tasks of all users.
+	FeedToken string
+
+	// EventBusURL selects the bus carrying the server's events to the other
+	// instances and external consumers: "redis://[:password@]host:port", or
+	// empty for in-process only.
+	EventBusURL string
+
 	// Profiling.
 	Pprof bool // expose /debug/pprof/* endpoints
+	// LogLevel is the level of the default logger, changed at runtime by
+	// POST /api/v1/server/log-level. Nil disables the change.
+	LogLevel *slog.LevelVar
+	// LogRing keeps the recent log lines included in debug bundles.
+	LogRing *logctx.Ring
+
+	// Orphaned containers: caic containers no task owns, e.g. because the
+	// server died mid-run. OrphanPolicy is "adopt" (default), "kill" or "off";
+	// it applies once a container has been orphaned for OrphanGrace (default
+	// 10 minutes).
+	OrphanPolicy string
+	OrphanGrace  time.Duration
+	// MinFreeDiskGB pauses task creation and warns while the filesystem
+	// holding the logs has less free space, in GiB. 0 disables the quota.
+	MinFreeDiskGB int
 
 	// IP geolocation (optional).
 	// IPGeoDB is the path to a MaxMind MMDB file (e.g. GeoLite2-Country.mmdb).
@@ -132,6 +220,11 @@ func (c *Config) Validate() error {
 			return errors.New("CAIC_EXTERNAL_URL must use https:// when OAuth login is configured")
 		}
 	}
+	if c.FrontendDir != "" {
+		if err := validateFrontendDir(c.FrontendDir); err != nil {
+			return fmt.Errorf("-frontend-dir: %w", err)
+		}
+	}
 	if c.GitLabURL != "" {
 		u, err := url.Parse(c.GitLabURL)
 		if err != nil || u.Host == "" {
@@ -141,6 +234,20 @@ func (c *Config) Validate() error {
 			return fmt.Errorf("GITLAB_URL must not contain a path: %q", c.GitLabURL)
 		}
 	}
+	if (c.JiraURL == "") != (c.JiraAPIToken == "") || (c.JiraURL == "") != (c.JiraEmail == "") {
+		return errors.New("JIRA_URL, JIRA_EMAIL and JIRA_API_TOKEN must all be set or all be unset")
+	}
+	if c.JiraURL != "" {
+		if u, err := url.Parse(c.JiraURL); err != nil || u.Host == "" {
+			return fmt.Errorf("JIRA_URL is not a valid URL: %q", c.JiraURL)
+		}
+	}
+	if (len(c.SlackSigningSecret) == 0) != (c.SlackBotToken == "") {
+		return errors.New("SLACK_SIGNING_SECRET and SLACK_BOT_TOKEN must both be set or both be unset")
+	}
+	if c.SlackBotToken != "" && c.SlackAllowedUsers == "" {
+		return errors.New("SLACK_ALLOWED_USERS is required when the Slack app is configured")
+	}
 	if c.GitHubToken != "" && c.GitHubOAuthClientID != "" {
 		return errors.New("GITHUB_TOKEN and GITHUB_OAUTH_CLIENT_ID are mutually exclusive: " +
 			"remove GITHUB_TOKEN when using GitHub OAuth login")
@@ -149,6 +256,32 @@ func (c *Config) Validate() error {
 		return errors.New("GITLAB_TOKEN and GITLAB_OAUTH_CLIENT_ID are mutually exclusive: " +
 			"remove GITLAB_TOKEN when using GitLab OAuth login")
 	}
+	switch c.OrphanPolicy {
+	case "", orphanAdopt, orphanKill, orphanOff:
+	default:
+		return fmt.Errorf("CAIC_ORPHAN_POLICY must be %q, %q or %q: %q", orphanAdopt, orphanKill, orphanOff, c.OrphanPolicy)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("CAIC_TLS_CERT and CAIC_TLS_KEY must be set together")
+	}
+	if c.HTTP3 && c.TLSCertFile == "" {
+		return errors.New("CAIC_HTTP3 requires CAIC_TLS_CERT and CAIC_TLS_KEY")
+	}
+	if c.SSEPing < 0 {
+		return fmt.Errorf("CAIC_SSE_PING must not be negative: %s", c.SSEPing)
+	}
+	if c.MirrorFetch < 0 {
+		return fmt.Errorf("CAIC_MIRROR_FETCH must not be negative: %s", c.MirrorFetch)
+	}
+	if c.StaticCacheMB < 0 {
+		return fmt.Errorf("CAIC_STATIC_CACHE_MB must not be negative: %d", c.StaticCacheMB)
+	}
+	if c.MinFreeDiskGB < 0 {
+		return fmt.Errorf("CAIC_MIN_FREE_DISK_GB must not be negative: %d", c.MinFreeDiskGB)
+	}
+	if c.OrphanGrace < 0 {
+		return fmt.Errorf("CAIC_ORPHAN_GRACE must not be negative: %s", c.OrphanGrace)
+	}
 	if c.GitHubOAuthClientID != "" && c.GitHubOAuthAllowedUsers == "" {
 		return errors.New("GITHUB_OAUTH_ALLOWED_USERS is required when GitHub OAuth login is configured")
 	}
@@ -164,22 +297,54 @@ type Server struct {
 
 	// Core infrastructure.
 	ctx      context.Context // server-lifetime context; outlives individual HTTP requests
-	absRoot  string          // absolute path to the root repos directory
+	roots    []sourceRoot    // source roots; the primary one first
 	repos    []repoInfo
 	runners  map[string]*task.Runner // keyed by RelPath
 	mdClient *md.Client
 	backend  *container.Backend // container backend for runner creation
 	logDir   string
-	ciCache  *forgecache.Cache
-	provider genai.Provider // nil if LLM not configured
-	bot      *bot.Bot       // handles forge event-driven task automation
+	// pipelinesDir holds saved pipeline definitions (*.json, *.yaml).
+	pipelinesDir string
+	// deployKeysDir holds the deploy keys of the repos; see deploykeys.go.
+	deployKeysDir string
+	ciCache       *forgecache.Cache
+	provider      genai.Provider    // nil if LLM not configured
+	indexes       *index.Manager    // code search over each repo's base branch
+	bot           *bot.Bot          // handles forge event-driven task automation
+	trackers      []tracker.Tracker // Jira and Linear; see issues.go
+	slack         *slack.Client     // nil when the Slack app is not configured; see slackapp.go
+	slackSecret   []byte
+	slackUsers    map[string]struct{} // Lowercase Slack user IDs allowed to use the app.
+
+	frontend *frontendBuild // nil until buildHandler defaults it to the embedded build
+
+	// Listener; see Config.TLSCertFile and Config.HTTP3.
+	tlsCertFile, tlsKeyFile string
+	serveHTTP3              bool
+	ssePing                 time.Duration // See Config.SSEPing.
+	feedToken               string        // See Config.FeedToken.
+	mirrorFetch             time.Duration // See Config.MirrorFetch.
 
 	// Profiling.
-	pprof bool
+	pprof    bool
+	logLevel *slog.LevelVar
+	// Debug bundle inputs; see debugbundle.go.
+	logRing     *logctx.Ring
+	debugConfig map[string]any // Config with secrets redacted.
+
+	// Orphaned container reconciliation; see Config.OrphanPolicy.
+	orphanPolicy string
+	orphanGrace  time.Duration
+
+	// Disk usage; see disk.go.
+	minFreeDisk int64        // Bytes; 0 disables the quota.
+	disk        v1.DiskUsage // Latest measurement; guarded by mu.
 
 	// Agent backends.
 	geminiAPIKey string
 	voiceBridge  *voicertc.Bridge
+	local        *openaicompat.Backend // nil unless Config.LocalURL is set
+	generic      *openaicompat.Backend
 
 	// Forge client management (throttles, App client, installation cache).
 	forge *forgeManager
@@ -194,6 +359,7 @@ type Server struct {
 	gitlabWebhookSecret []byte               // nil when GitLab webhook not configured
 	gitlabOAuth         *auth.ProviderConfig // nil if not configured
 	gitlabAllowedUsers  map[string]struct{}  // nil if GitLab OAuth not configured
+	adminUsers          map[string]struct{}  // lowercase usernames; nil if none
 
 	// Auth / session.
 	authStore     *auth.Store     // nil when auth disabled
@@ -208,13 +374,29 @@ type Server struct {
 	// User preferences — all users in a single file.
 	prefs *preferences.Store
 
+	// Shared by the runners, including those of repos cloned at runtime.
+	journal   *task.Journal
+	branchIDs *task.BranchIDs
+
+	// Event bus: task change signals shared with the other instances, and
+	// task transitions for external consumers. See events.go.
+	bus     eventbus.Bus
+	busID   string        // identifies this instance's signals on the bus
+	busKick chan struct{} // schedules a change signal; nil until the bus starts
+
 	// Guarded by mu.
 	mu           sync.Mutex
 	tasks        map[string]*taskEntry
-	repoCIStatus map[string]repoCIState // keyed by repoInfo.RelPath
-	changed      chan struct{}          // closed on task mutation; replaced under mu
-	warnings     []serverWarning        // append-only ring buffer; capped at maxWarnings
-	warningSeq   uint64                 // monotonic sequence counter for warnings
+	repoCIStatus map[string]repoCIState    // keyed by repoInfo.RelPath
+	changed      chan struct{}             // closed on task mutation; replaced under mu
+	lastAlias    int                       // highest task alias number assigned or loaded from logs
+	warnings     []serverWarning           // append-only ring buffer; capped at maxWarnings
+	warningSeq   uint64                    // monotonic sequence counter for warnings
+	evals        []*evalRun                // eval runs since startup, oldest first
+	archived     *idSet                    // hidden from the task list by default
+	pinned       *idSet                    // sorted first in the task list
+	annotations  *annotationStore          // notes and bookmarks on transcript messages
+	prReviews    map[string]*prReviewBatch // PR review feedback being batched; see prreview.go
 }
 
 type taskEntry struct {
@@ -225,6 +407,22 @@ type taskEntry struct {
 	// CI monitoring: set when a PR is created; used by webhook handlers to
 	// find the task waiting for CI results.
 	monitorBranch string // branch being monitored (e.g. "caic-123"); empty when no CI monitoring active
+	// cancelWait aborts the wait on dependencies and on the execution window;
+	// set only while a new task is pending.
+	cancelWait   context.CancelFunc
+	heldReason   string          // Why the pending task is held; see waitForWindow.
+	priority     v1.TaskPriority // Start order while pending; see waitInQueue.
+	ready        bool            // Past its dependencies and not started yet.
+	pipeline     *pipelineRun    // nil unless the task runs a pipeline step
+	pipelineStep int             // Step of pipeline this task runs.
+	review       *reviewRun      // nil unless the task is reviewed by another agent
+	// Disk usage measured by pollDisk.
+	diskBytes int64 // Container writable layer.
+	logBytes  int64 // Log files.
+	// Idle policy progress of the current waiting period; see checkIdle.
+	idleSince    time.Time // StateUpdatedAt when the waiting period began.
+	idleWarnedAt time.Time // When the finish warning was posted.
+	idleActed    bool
 }
 
 // buildHandler assembles the full HTTP handler. Extracted from ListenAndServe
@@ -240,40 +438,11 @@ func (s *Server) buildHandler() (http.Handler, error) {
 	authMux.HandleFunc("GET /api/v1/auth/me", s.handleGetMe)
 	authMux.HandleFunc("POST /api/v1/auth/logout", s.handleLogout)
 
-	// Protected routes.
+	// Protected routes of every API version.
 	apiMux := http.NewServeMux()
-	apiMux.HandleFunc("GET /api/v1/server/preferences", handle(s.getPreferences))
-	apiMux.HandleFunc("POST /api/v1/server/preferences", handle(s.updatePreferences))
-	apiMux.HandleFunc("GET /api/v1/server/harnesses", handle(s.listHarnesses))
-	apiMux.HandleFunc("GET /api/v1/server/caches", handle(s.listCaches))
-	apiMux.HandleFunc("GET /api/v1/server/repos", handle(s.listRepos))
-	apiMux.HandleFunc("POST /api/v1/server/repos", handle(s.cloneRepo))
-	apiMux.HandleFunc("GET /api/v1/server/repos/branches", s.handleListRepoBranches)
-	apiMux.HandleFunc("POST /api/v1/bot/fix-ci", handle(s.botFixCI))
-	apiMux.HandleFunc("POST /api/v1/bot/fix-pr", handle(s.botFixPR))
-	apiMux.HandleFunc("GET /api/v1/tasks", handle(s.listTasks))
-	apiMux.HandleFunc("POST /api/v1/tasks", handle(s.createTask))
-	apiMux.HandleFunc("GET /api/v1/tasks/{id}/raw_events", s.handleTaskRawEvents)
-	apiMux.HandleFunc("GET /api/v1/tasks/{id}/events", s.handleTaskEvents)
-	apiMux.HandleFunc("POST /api/v1/tasks/{id}/input", handleWithTask(s, s.sendInput))
-	apiMux.HandleFunc("POST /api/v1/tasks/{id}/restart", handleWithTask(s, s.restartTask))
-	apiMux.HandleFunc("POST /api/v1/tasks/{id}/clear-context", handleWithTask(s, s.clearContext))
-	apiMux.HandleFunc("POST /api/v1/tasks/{id}/compact", handleWithTask(s, s.compactContext))
-	apiMux.HandleFunc("POST /api/v1/tasks/{id}/fork", handleWithTask(s, s.forkTask))
-	apiMux.HandleFunc("POST /api/v1/tasks/{id}/stop", handleWithTask(s, s.stopTask))
-	apiMux.HandleFunc("POST /api/v1/tasks/{id}/purge", handleWithTask(s, s.purgeTask))
-	apiMux.HandleFunc("POST /api/v1/tasks/{id}/revive", handleWithTask(s, s.reviveTask))
-	apiMux.HandleFunc("GET /api/v1/tasks/{id}/ci-log", s.handleGetCILog)
-	apiMux.HandleFunc("POST /api/v1/tasks/{id}/sync", handleWithTask(s, s.syncTask))
-	apiMux.HandleFunc("GET /api/v1/tasks/{id}/diff", s.handleGetDiff)
-	apiMux.HandleFunc("GET /api/v1/tasks/{id}/tool/{toolUseID}", s.handleTaskToolInput)
-	apiMux.HandleFunc("GET /api/v1/usage", s.handleGetUsage)
-	apiMux.HandleFunc("GET /api/v1/voice/token", handle(s.getVoiceToken))
-	apiMux.HandleFunc("POST /api/v1/voice/rtc/offer", handle(s.voiceRTCOffer))
-	apiMux.HandleFunc("DELETE /api/v1/voice/rtc/{sessionID}", s.handleVoiceRTCClose)
-	apiMux.HandleFunc("POST /api/v1/web/fetch", handle(s.webFetch))
-	apiMux.HandleFunc("GET /api/v1/server/tasks/events", s.handleTaskListEvents)
-	apiMux.HandleFunc("GET /api/v1/server/usage/events", s.handleUsageEvents)
+	for i := range apiVersions {
+		apiVersions[i].register(s, apiMux)
+	}
 
 	// Combine: auth routes first, then protected API routes (gated by RequireUser when auth enabled).
 	var protectedAPI http.Handler = apiMux
@@ -284,9 +453,16 @@ func (s *Server) buildHandler() (http.Handler, error) {
 	mux := http.NewServeMux()
 	mux.Handle("/api/v1/auth/", authMux)
 	mux.HandleFunc("GET /api/v1/server/config", handle(s.getConfig))
+	mux.HandleFunc("GET /api/v1/health", s.handleHealth)
 	mux.HandleFunc("POST /webhooks/github", s.handleGitHubWebhook)
 	mux.HandleFunc("POST /webhooks/gitlab", s.handleGitLabWebhook)
-	mux.Handle("/api/v1/", protectedAPI)
+	mux.HandleFunc("POST /webhooks/slack/commands", s.handleSlackCommand)
+	mux.HandleFunc("POST /webhooks/slack/events", s.handleSlackEvents)
+	mux.HandleFunc("GET /feeds/tasks.xml", s.requireFeedToken(s.handleTaskFeed))
+	mux.HandleFunc("GET /feeds/tasks.ics", s.requireFeedToken(s.handleTaskCalendar))
+	for i := range apiVersions {
+		mux.Handle(apiVersions[i].prefix(), protectedAPI)
+	}
 
 	// Profiling (opt-in via -pprof / CAIC_PPROF).
 	if s.pprof {
@@ -294,15 +470,21 @@ func (s *Server) buildHandler() (http.Handler, error) {
 		slog.Info("pprof enabled", "url", "/debug/pprof/")
 	}
 
-	// Serve embedded frontend with SPA fallback and precompressed variants.
-	dist, err := fs.Sub(frontend.Files, "dist")
-	if err != nil {
-		return nil, err
+	// Serve the frontend with SPA fallback and precompressed variants; the
+	// embedded build unless Config.FrontendDir is set.
+	if s.frontend == nil {
+		var err error
+		if s.frontend, err = newFrontendBuild("", defaultStaticCacheMB<<20); err != nil {
+			return nil, err
+		}
 	}
-	mux.HandleFunc("/", newStaticHandler(dist))
+	static := s.frontend.handler()
+	mux.HandleFunc("GET /task/{ref}", s.handleTaskLink(static))
+	mux.HandleFunc("/", static)
 
-	// Middleware chain: logging → host check → auth → decompress → compress → mux.
+	// Middleware chain: request ID → logging → host check → auth → decompress → compress → API version → mux.
 	var inner http.Handler = mux
+	inner = apiVersionMiddleware(inner)
 	inner = compressMiddleware(inner)
 	inner = decompressMiddleware(inner)
 	inner = auth.Middleware(s.authStore, s.sessionSecret)(inner)
@@ -311,11 +493,17 @@ func (s *Server) buildHandler() (http.Handler, error) {
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID := r.Header.Get("X-Request-ID")
+		if !validRequestID(reqID) {
+			reqID = logctx.NewRequestID()
+		}
+		w.Header().Set("X-Request-ID", reqID)
+		r = r.WithContext(logctx.With(r.Context(), "req", reqID))
 		clientIP := ipgeo.GetClientIP(r)
 		cc := s.ipgeoChecker.CountryCode(clientIP)
 		if !s.ipgeoChecker.IsAllowed(clientIP) {
 			http.Error(w, "forbidden: country not allowed", http.StatusForbidden)
-			slog.Info("http blocked", "m", r.Method, "p", r.URL.Path, "s", http.StatusForbidden, "ip", clientIP, "cc", cc) //nolint:gosec // G706: request metadata logged for audit; not used in security decisions
+			slog.InfoContext(r.Context(), "http blocked", "m", r.Method, "p", r.URL.Path, "s", http.StatusForbidden, "ip", clientIP, "cc", cc) //nolint:gosec // G706: request metadata logged for audit; not used in security decisions
 			return
 		}
 		start := time.Now()
@@ -337,6 +525,113 @@ func (s *Server) buildHandler() (http.Handler, error) {
 	}), nil
 }
 
+// registerV1 adds the protected routes of the v1 API to apiMux.
+func (s *Server) registerV1(apiMux *http.ServeMux) {
+	apiMux.HandleFunc("GET /api/v1/server/preferences", handle(s.getPreferences))
+	apiMux.HandleFunc("POST /api/v1/server/preferences", handle(s.updatePreferences))
+	apiMux.HandleFunc("GET /api/v1/server/harnesses", handle(s.listHarnesses))
+	apiMux.HandleFunc("GET /api/v1/health/harnesses", handle(s.listHarnessHealth))
+	apiMux.HandleFunc("POST /api/v1/server/image/validate", handle(s.validateBaseImage))
+	apiMux.HandleFunc("GET /api/v1/server/image/pins", handle(s.listImagePins))
+	apiMux.HandleFunc("POST /api/v1/server/image/pins", handle(s.pinImage))
+	apiMux.HandleFunc("POST /api/v1/server/image/pins/delete", handle(s.unpinImage))
+	apiMux.HandleFunc("POST /api/v1/server/image/check-update", handle(s.checkImageUpdate))
+	apiMux.HandleFunc("GET /api/v1/server/caches", handle(s.listCaches))
+	apiMux.HandleFunc("GET /api/v1/server/log-level", handle(s.getLogLevel))
+	apiMux.Handle("POST /api/v1/server/log-level", s.requireAdmin(handle(s.setLogLevel)))
+	s.registerAdmin(apiMux)
+	apiMux.HandleFunc("GET /api/v1/server/prices", handle(s.listPrices))
+	apiMux.HandleFunc("GET /api/v1/evals", handle(s.listEvals))
+	apiMux.HandleFunc("POST /api/v1/evals", handle(s.createEval))
+	apiMux.HandleFunc("GET /api/v1/evals/{id}", handle(s.getEval))
+	apiMux.HandleFunc("GET /api/v1/pipelines", handle(s.listPipelines))
+	apiMux.HandleFunc("GET /api/v1/server/repos", handle(s.listRepos))
+	apiMux.HandleFunc("POST /api/v1/server/repos", handle(s.cloneRepo))
+	apiMux.HandleFunc("GET /api/v1/server/repos/remotes", s.handleListRepoRemotes)
+	apiMux.HandleFunc("POST /api/v1/server/repos/remotes", handle(s.setRepoRemote))
+	apiMux.HandleFunc("POST /api/v1/server/repos/remotes/delete", handle(s.deleteRepoRemote))
+	apiMux.HandleFunc("POST /api/v1/server/repos/deploykey", handle(s.createDeployKey))
+	apiMux.HandleFunc("POST /api/v1/server/repos/deploykey/delete", handle(s.deleteDeployKey))
+	apiMux.HandleFunc("GET /api/v1/server/repos/branches", s.handleListRepoBranches)
+	apiMux.HandleFunc("GET /api/v1/server/repos/search", s.handleSearchRepo)
+	apiMux.HandleFunc("GET /api/v1/server/repos/semantic-search", s.handleSemanticSearchRepo)
+	apiMux.HandleFunc("GET /api/v1/prompts/recent", s.handleListRecentPrompts)
+	apiMux.HandleFunc("POST /api/v1/prompts/lint", handle(s.lintPrompt))
+	apiMux.HandleFunc("POST /api/v1/bot/fix-ci", handle(s.botFixCI))
+	apiMux.HandleFunc("POST /api/v1/bot/fix-pr", handle(s.botFixPR))
+	apiMux.HandleFunc("GET /api/v1/views", handle(s.listTaskViews))
+	apiMux.HandleFunc("POST /api/v1/views", handle(s.saveTaskView))
+	apiMux.HandleFunc("POST /api/v1/views/delete", handle(s.deleteTaskView))
+	apiMux.HandleFunc("GET /api/v1/tasks", s.handleListTasks)
+	apiMux.HandleFunc("POST /api/v1/tasks", handle(s.createTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/quick", handle(s.quickCreateTask))
+	apiMux.HandleFunc("GET /api/v1/tasks/export", s.handleExportTasks)
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}", s.handleGetTask)
+	apiMux.HandleFunc("PATCH /api/v1/tasks/{id}", handleWithTask(s, s.updateTask))
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/raw_events", s.handleTaskRawEvents)
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/events", s.handleTaskEvents)
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/input", handleWithTask(s, s.sendInput))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/restart", handleWithTask(s, s.restartTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/rewind", handleWithTask(s, s.rewindTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/clear-context", handleWithTask(s, s.clearContext))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/compact", handleWithTask(s, s.compactContext))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/interrupt", handleWithTask(s, s.interruptTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/priority", handleWithTask(s, s.setTaskPriority))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/fork", handleWithTask(s, s.forkTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/promote", handleWithTask(s, s.promoteTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/plan", handleWithTask(s, s.approvePlan))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/stop", handleWithTask(s, s.stopTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/purge", handleWithTask(s, s.purgeTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/revive", handleWithTask(s, s.reviveTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/rehydrate", handleWithTask(s, s.rehydrateTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/pin", handleWithTask(s, s.pinTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/unpin", handleWithTask(s, s.unpinTask))
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/ci-log", s.handleGetCILog)
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/sync", handleWithTask(s, s.syncTask))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/push", handleWithTask(s, s.pushTask))
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/diff", s.handleGetDiff)
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/diff/hunks", s.handleGetDiffHunks)
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/commits", s.handleGetCommits)
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/commits/{sha}/revert", handleWithTask(s, s.revertCommit))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/snapshots", handleWithTask(s, s.createSnapshot))
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/snapshots", s.handleListSnapshots)
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/snapshots/{name}/restore", handleWithTask(s, s.restoreSnapshot))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/policy/approve", handleWithTask(s, s.approvePolicy))
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/policy/deny", handleWithTask(s, s.denyPolicy))
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/transitions", s.handleGetTransitions)
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/timeline", s.handleGetTimeline)
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/console", s.handleTaskConsole)
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/messages", s.handleGetTaskMessages)
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/messages/{index}/content", s.handleGetMessageContent)
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/messages/{index}/annotations", handleWithTask(s, s.annotateMessage))
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/annotations", s.handleListAnnotations)
+	apiMux.HandleFunc("POST /api/v1/tasks/{id}/annotations/{annotationID}/delete", handleWithTask(s, s.deleteAnnotation))
+	apiMux.HandleFunc("GET /api/v1/tasks/{id}/tool/{toolUseID}", s.handleTaskToolInput)
+	apiMux.HandleFunc("GET /api/v1/usage", s.handleGetUsage)
+	apiMux.HandleFunc("GET /api/v1/voice/token", handle(s.getVoiceToken))
+	apiMux.HandleFunc("POST /api/v1/voice/rtc/offer", handle(s.voiceRTCOffer))
+	apiMux.HandleFunc("DELETE /api/v1/voice/rtc/{sessionID}", s.handleVoiceRTCClose)
+	apiMux.HandleFunc("POST /api/v1/web/fetch", handle(s.webFetch))
+	apiMux.HandleFunc("GET /api/v1/server/tasks/events", s.handleTaskListEvents)
+	apiMux.HandleFunc("GET /api/v1/server/usage/events", s.handleUsageEvents)
+}
+
+// validRequestID reports whether a client-supplied X-Request-ID is safe to
+// log and echo back: short and made of letters, digits, '-' and '_'.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // ListenAndServe starts the HTTP server on addr and blocks until ctx is cancelled.
 func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
 	handler, err := s.buildHandler()
@@ -351,6 +646,22 @@ func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
 		BaseContext: func(_ net.Listener) context.Context {
 			return ctx
 		},
+		Protocols: httpProtocols(),
+	}
+	var h3 *http3.Server
+	if s.tlsCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.tlsCertFile, s.tlsKeyFile)
+		if err != nil {
+			return fmt.Errorf("load TLS certificate: %w", err)
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		if s.serveHTTP3 {
+			var port int
+			if h3, port, err = listenHTTP3(ctx, addr, handler, cert); err != nil {
+				return err
+			}
+			srv.Handler = advertiseHTTP3(port, handler)
+		}
 	}
 	shutdownDone := make(chan struct{})
 	go func() { //nolint:gosec // G118: goroutine intentionally uses Background; parent ctx is already cancelled at shutdown
@@ -362,10 +673,18 @@ func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
 		// Use Background because the parent ctx is already cancelled.
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 		_ = srv.Shutdown(shutdownCtx) //nolint:contextcheck // parent ctx is already cancelled at shutdown time
+		if h3 != nil {
+			_ = h3.Shutdown(shutdownCtx) //nolint:contextcheck // parent ctx is already cancelled at shutdown time
+		}
 		shutdownCancel()
 	}()
-	slog.Info("listening", "addr", addr)
-	err = srv.ListenAndServe()
+	if srv.TLSConfig != nil {
+		slog.Info("listening", "addr", addr, "tls", true, "http3", h3 != nil)
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		slog.Info("listening", "addr", addr)
+		err = srv.ListenAndServe()
+	}
 	if errors.Is(err, http.ErrServerClosed) {
 		<-shutdownDone
 		return nil
@@ -442,9 +761,9 @@ func (s *Server) pushStats(ctx context.Context) {
 func statsToEvent(cs *task.ContainerStats) v1.EventMessage {
 	return v1.EventMessage{
 		Kind: v1.EventKindStats,
-		Ts:   cs.Ts.UnixMilli(),
+		Ts:   cs.Ts.UTC(),
 		Stats: &v1.EventStats{
-			Ts:         cs.Ts.UnixMilli(),
+			Ts:         cs.Ts.UTC(),
 			CPUPerc:    cs.CPUPerc,
 			MemUsed:    cs.MemUsed,
 			MemLimit:   cs.MemLimit,
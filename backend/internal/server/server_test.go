@@ -1,30 +1,46 @@
 package server
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	"github.com/caic-xyz/caic/backend/internal/agent/claudecode"
+	"github.com/caic-xyz/caic/backend/internal/agent/openaicompat"
 	"github.com/caic-xyz/caic/backend/internal/auth"
 	"github.com/caic-xyz/caic/backend/internal/forge"
+	"github.com/caic-xyz/caic/backend/internal/index"
+	"github.com/caic-xyz/caic/backend/internal/logctx"
 	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/pricing"
 	"github.com/caic-xyz/caic/backend/internal/server/dto"
 	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
 	"github.com/caic-xyz/caic/backend/internal/server/ipgeo"
 	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/md"
+	"github.com/maruel/ksid"
 )
 
 // stubBackend implements agent.Backend for test map-membership checks.
@@ -48,12 +64,22 @@ func (stubBackend) NewParser() func([]byte) ([]agent.Message, error) {
 	return claudecode.New().NewParser()
 }
 
+func (stubBackend) Binary() string { return "stub" }
+
 func (stubBackend) Models() []string { return []string{"m1", "m2"} }
 
 func (stubBackend) SupportsImages() bool { return false }
 
 func (stubBackend) SupportsCompact() bool { return false }
 
+func (stubBackend) SupportsInterrupt() bool { return false }
+
+func (stubBackend) SupportsPlanOnly() bool { return false }
+
+func (stubBackend) SupportsThinking() bool { return false }
+
+func (stubBackend) CommitsChanges() bool { return false }
+
 func (stubBackend) ContextWindowLimit(string) int { return 180_000 }
 
 func decodeError(t *testing.T, w *httptest.ResponseRecorder) dto.ErrorDetails {
@@ -86,6 +112,11 @@ func newTestServer(t *testing.T) *Server {
 		prefs:        newTestPrefs(t),
 		ipgeoChecker: checker,
 		forge:        newForgeManager("", "", nil),
+		logLevel:     &slog.LevelVar{},
+		archived:     &idSet{path: filepath.Join(t.TempDir(), "archived.json"), ids: map[string]struct{}{}},
+		pinned:       &idSet{path: filepath.Join(t.TempDir(), "pinned.json"), ids: map[string]struct{}{}},
+		annotations:  &annotationStore{path: filepath.Join(t.TempDir(), "annotations.json"), byTask: map[string][]v1.Annotation{}},
+		indexes:      index.NewManager(nil),
 	}
 }
 
@@ -100,8 +131,8 @@ func TestHandleTaskEvents(t *testing.T) {
 			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
 		}
 		e := decodeError(t, w)
-		if e.Code != dto.CodeNotFound {
-			t.Errorf("code = %q, want %q", e.Code, dto.CodeNotFound)
+		if e.Code != dto.CodeNotFound || e.Params["resource"] != "task" {
+			t.Errorf("error = %+v, want %q with resource=task", e, dto.CodeNotFound)
 		}
 	})
 
@@ -159,27 +190,23 @@ func TestHandleTaskInput(t *testing.T) {
 			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
 		e := decodeError(t, w)
-		if e.Code != dto.CodeBadRequest {
-			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
+		if e.Code != dto.CodeBadRequest || len(e.Fields) != 1 || e.Fields[0].Field != "prompt" || e.Fields[0].Rule != dto.RuleRequired {
+			t.Errorf("error = %+v, want %q with a required prompt field", e, dto.CodeBadRequest)
 		}
 	})
 }
 
-func TestHandleRestart(t *testing.T) {
-	t.Run("NotWaiting", func(t *testing.T) {
+func TestHandleTaskInterrupt(t *testing.T) {
+	t.Run("NotRunning", func(t *testing.T) {
 		s := newTestServer(t)
-		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
-		tk.SetState(task.StateRunning)
 		s.tasks["t1"] = &taskEntry{
-			task: tk,
+			task: &task.Task{InitialPrompt: agent.Prompt{Text: "test"}},
 			done: make(chan struct{}),
 		}
-
-		body := strings.NewReader(`{"prompt":{"text":"new plan"}}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/restart", body)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/interrupt", strings.NewReader(`{}`))
 		req.SetPathValue("id", "t1")
 		w := httptest.NewRecorder()
-		handleWithTask(s, s.restartTask)(w, req)
+		handleWithTask(s, s.interruptTask)(w, req)
 		if w.Code != http.StatusConflict {
 			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
 		}
@@ -188,45 +215,260 @@ func TestHandleRestart(t *testing.T) {
 			t.Errorf("code = %q, want %q", e.Code, dto.CodeConflict)
 		}
 	})
+}
 
-	t.Run("EmptyPrompt", func(t *testing.T) {
+func TestHandleTaskRewind(t *testing.T) {
+	t.Run("NotWaiting", func(t *testing.T) {
 		s := newTestServer(t)
-		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
-		tk.SetState(task.StateWaiting)
 		s.tasks["t1"] = &taskEntry{
-			task: tk,
+			task: &task.Task{InitialPrompt: agent.Prompt{Text: "test"}},
 			done: make(chan struct{}),
 		}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/rewind", strings.NewReader(`{"prompt":{"text":"hello"}}`))
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		handleWithTask(s, s.rewindTask)(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+		}
+	})
 
-		body := strings.NewReader(`{"prompt":{"text":""}}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/restart", body)
+	t.Run("EmptyPrompt", func(t *testing.T) {
+		s := newTestServer(t)
+		s.tasks["t1"] = &taskEntry{
+			task: &task.Task{InitialPrompt: agent.Prompt{Text: "test"}},
+			done: make(chan struct{}),
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/rewind", strings.NewReader(`{"prompt":{"text":""}}`))
 		req.SetPathValue("id", "t1")
 		w := httptest.NewRecorder()
-		handleWithTask(s, s.restartTask)(w, req)
+		handleWithTask(s, s.rewindTask)(w, req)
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
-		e := decodeError(t, w)
-		if e.Code != dto.CodeBadRequest {
-			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
+	})
+}
+
+func TestHandleIfMatch(t *testing.T) {
+	do := func(s *Server, h http.HandlerFunc, path, body, ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/"+path, strings.NewReader(body))
+		req.SetPathValue("id", "t1")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w
+	}
+	// pin succeeds on any task; input fails since the task is not running.
+	pin := func(s *Server, ifMatch string) *httptest.ResponseRecorder {
+		return do(s, handleWithTask(s, s.pinTask), "pin", "", ifMatch)
+	}
+	input := func(s *Server, ifMatch string) *httptest.ResponseRecorder {
+		return do(s, handleWithTask(s, s.sendInput), "input", `{"prompt":{"text":"hello"}}`, ifMatch)
+	}
+	newTask := func(t *testing.T) (*Server, *task.Task) {
+		s := newTestServer(t)
+		tk := &task.Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "test"}}
+		s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+		return s, tk
+	}
+	t.Run("Unconditional", func(t *testing.T) {
+		s, tk := newTask(t)
+		pin(s, "")
+		pin(s, "*")
+		if got := tk.Snapshot().Revision; got != 2 {
+			t.Errorf("revision = %d, want 2", got)
+		}
+	})
+	t.Run("Match", func(t *testing.T) {
+		s, tk := newTask(t)
+		tk.BumpRevision(nil)
+		w := pin(s, `W/"1"`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("ETag"); got != `"2"` {
+			t.Errorf("ETag = %q, want %q", got, `"2"`)
+		}
+		if got := tk.Snapshot().Revision; got != 2 {
+			t.Errorf("revision = %d, want 2", got)
+		}
+	})
+	t.Run("Failure", func(t *testing.T) {
+		// A failed mutation leaves the revision, and the ones other clients
+		// hold, unchanged.
+		s, tk := newTask(t)
+		tk.BumpRevision(nil)
+		w := input(s, `"1"`)
+		if w.Code == http.StatusOK {
+			t.Fatal("input succeeded on a task that is not running")
+		}
+		if got := w.Header().Get("ETag"); got != `"1"` {
+			t.Errorf("ETag = %q, want %q", got, `"1"`)
+		}
+		if got := tk.Snapshot().Revision; got != 1 {
+			t.Errorf("revision = %d, want unchanged 1", got)
+		}
+		if w := pin(s, `"1"`); w.Code != http.StatusOK {
+			t.Errorf("status = %d, want the revision to still match", w.Code)
+		}
+	})
+	t.Run("Mismatch", func(t *testing.T) {
+		s, tk := newTask(t)
+		tk.BumpRevision(nil)
+		tk.BumpRevision(nil)
+		w := pin(s, `"1"`)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusConflict)
+		}
+		var resp dto.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Details["revision"]; got != float64(2) {
+			t.Errorf("details.revision = %v, want 2", got)
+		}
+		if got := tk.Snapshot().Revision; got != 2 {
+			t.Errorf("revision = %d, want unchanged 2", got)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		s, _ := newTask(t)
+		if w := pin(s, `"abc"`); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
 	})
 }
 
-func TestHandlePurge(t *testing.T) {
+func TestTaskProjection(t *testing.T) {
+	list := func(t *testing.T, s *Server, query string) (int, []map[string]any) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.handleListTasks(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks"+query, http.NoBody))
+		var out []map[string]any
+		if w.Code == http.StatusOK {
+			if err := json.NewDecoder(w.Body).Decode(&out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, out
+	}
+	s := newTestServer(t)
+	s.tasks["t1"] = &taskEntry{
+		task: &task.Task{InitialPrompt: agent.Prompt{Text: "a long prompt"}},
+		done: make(chan struct{}),
+	}
+	t.Run("Full", func(t *testing.T) {
+		code, out := list(t, s, "")
+		if code != http.StatusOK || len(out) != 1 {
+			t.Fatalf("status = %d, tasks = %d", code, len(out))
+		}
+		if out[0]["initialPrompt"] != "a long prompt" {
+			t.Errorf("initialPrompt = %v", out[0]["initialPrompt"])
+		}
+	})
+	t.Run("Summary", func(t *testing.T) {
+		_, out := list(t, s, "?view=summary")
+		if _, ok := out[0]["initialPrompt"]; ok {
+			t.Error("summary has initialPrompt")
+		}
+		if _, ok := out[0]["state"]; !ok {
+			t.Error("summary lacks state")
+		}
+	})
+	t.Run("Fields", func(t *testing.T) {
+		_, out := list(t, s, "?fields=state,title")
+		if len(out[0]) != 3 {
+			t.Errorf("got fields %v, want id, state and title", out[0])
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, q := range []string{"?fields=nope", "?view=tiny", "?fields=state&view=summary"} {
+			if code, _ := list(t, s, q); code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want %d", q, code, http.StatusBadRequest)
+			}
+		}
+	})
+	t.Run("Detail", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/t1", http.NoBody)
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		s.handleGetTask(w, req)
+		var got v1.Task
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.InitialPrompt != "a long prompt" {
+			t.Errorf("initialPrompt = %q", got.InitialPrompt)
+		}
+	})
+}
+
+func TestConditionalGet(t *testing.T) {
+	s := newTestServer(t)
+	tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
+	s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+	get := func(h http.HandlerFunc, target, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req.SetPathValue("id", "t1")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w
+	}
+	for _, tt := range []struct {
+		name   string
+		h      http.HandlerFunc
+		target string
+	}{
+		{"List", s.handleListTasks, "/api/v1/tasks"},
+		{"Detail", s.handleGetTask, "/api/v1/tasks/t1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(tt.h, tt.target, "")
+			etag := w.Header().Get("ETag")
+			if w.Code != http.StatusOK || etag == "" {
+				t.Fatalf("status = %d, ETag = %q", w.Code, etag)
+			}
+			if w = get(tt.h, tt.target, `"other", W/`+etag); w.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNotModified)
+			}
+			if w.Body.Len() != 0 {
+				t.Errorf("304 has body %q", w.Body.String())
+			}
+			tk.SetTitle(tt.name)
+			if w = get(tt.h, tt.target, etag); w.Code != http.StatusOK {
+				t.Errorf("after change: status = %d, want %d", w.Code, http.StatusOK)
+			}
+		})
+	}
+	t.Run("IfMatch", func(t *testing.T) {
+		etag := get(s.handleGetTask, "/api/v1/tasks/t1", "").Header().Get("ETag")
+		rev, err := parseIfMatch(etag)
+		if err != nil || rev == nil || *rev != tk.Snapshot().Revision {
+			t.Errorf("parseIfMatch(%q) = %v, %v", etag, rev, err)
+		}
+	})
+}
+
+func TestHandleRestart(t *testing.T) {
 	t.Run("NotWaiting", func(t *testing.T) {
 		s := newTestServer(t)
 		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
-		// StatePending is the zero value, but set explicitly for clarity.
+		tk.SetState(task.StateRunning)
 		s.tasks["t1"] = &taskEntry{
 			task: tk,
 			done: make(chan struct{}),
 		}
 
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/purge", http.NoBody)
+		body := strings.NewReader(`{"prompt":{"text":"new plan"}}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/restart", body)
 		req.SetPathValue("id", "t1")
 		w := httptest.NewRecorder()
-		handleWithTask(s, s.purgeTask)(w, req)
+		handleWithTask(s, s.restartTask)(w, req)
 		if w.Code != http.StatusConflict {
 			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
 		}
@@ -236,366 +478,1257 @@ func TestHandlePurge(t *testing.T) {
 		}
 	})
 
-	t.Run("Waiting", func(t *testing.T) {
-		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}, Repos: []task.RepoMount{{Name: "r"}}}
-		tk.SetState(task.StateWaiting)
+	t.Run("EmptyPrompt", func(t *testing.T) {
 		s := newTestServer(t)
-		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir()}
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
+		tk.SetState(task.StateWaiting)
 		s.tasks["t1"] = &taskEntry{
 			task: tk,
 			done: make(chan struct{}),
 		}
 
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/purge", http.NoBody)
+		body := strings.NewReader(`{"prompt":{"text":""}}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/restart", body)
 		req.SetPathValue("id", "t1")
 		w := httptest.NewRecorder()
-		handleWithTask(s, s.purgeTask)(w, req)
-		if w.Code != http.StatusOK {
-			t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
-		}
-
-		// Verify the response reports purging. Don't check tk.State
-		// directly: cleanupTask runs in a goroutine and may have already
-		// transitioned the state to StatePurged by now.
-		var resp v1.StatusResp
-		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
-			t.Fatalf("failed to decode response: %v", err)
+		handleWithTask(s, s.restartTask)(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
-		if resp.Status != "purging" {
-			t.Errorf("status = %q, want %q", resp.Status, "purging")
+		e := decodeError(t, w)
+		if e.Code != dto.CodeBadRequest {
+			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
 		}
 	})
+}
 
-	t.Run("CancelledContext", func(t *testing.T) {
-		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}, Repos: []task.RepoMount{{Name: "r"}}}
-		tk.SetState(task.StateRunning)
+func TestHandlePromote(t *testing.T) {
+	newServer := func(t *testing.T, planOnly bool) *Server {
 		s := newTestServer(t)
-		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir()}
-		s.tasks["t1"] = &taskEntry{
-			task: tk,
-			done: make(chan struct{}),
-		}
-
-		// Use an already-cancelled context to simulate shutdown scenario
-		// where BaseContext is cancelled before the handler completes.
-		ctx, cancel := context.WithCancel(context.Background())
-		cancel()
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/purge", http.NoBody)
-		req = req.WithContext(ctx)
+		s.runners[""] = &task.Runner{Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "plan it"}, Harness: agent.Claude, PlanOnly: planOnly}
+		tk.SetState(task.StateWaiting)
+		s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+		return s
+	}
+	promote := func(s *Server, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/promote", strings.NewReader(body))
 		req.SetPathValue("id", "t1")
 		w := httptest.NewRecorder()
-		handleWithTask(s, s.purgeTask)(w, req)
+		handleWithTask(s, s.promoteTask)(w, req)
+		return w
+	}
+
+	t.Run("SeedsPlan", func(t *testing.T) {
+		s := newServer(t, true)
+		w := promote(s, `{"prompt":{"text":"1. do it"}}`)
 		if w.Code != http.StatusOK {
-			t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp v1.CreateTaskResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		entry := s.tasks[resp.ID.String()]
+		s.mu.Unlock()
+		if entry == nil {
+			t.Fatal("promoted task not found")
+		}
+		if entry.task.PlanOnly {
+			t.Error("promoted task is plan-only")
+		}
+		if want := "Implement the following plan:\n\n1. do it"; entry.task.InitialPrompt.Text != want {
+			t.Errorf("prompt = %q, want %q", entry.task.InitialPrompt.Text, want)
 		}
 	})
-}
 
-func TestHandleContainerDeath(t *testing.T) {
-	t.Run("ArchivesAsStopped", func(t *testing.T) {
-		s := newTestServer(t)
-		tk := &task.Task{
-			InitialPrompt: agent.Prompt{Text: "test"},
-			Repos:         []task.RepoMount{{Name: "r"}},
-			Container:     "md-repo-caic-0",
+	t.Run("NoPlan", func(t *testing.T) {
+		w := promote(newServer(t, true), `{}`)
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
 		}
-		tk.SetState(task.StateRunning)
-		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir()}
-		entry := &taskEntry{task: tk, done: make(chan struct{})}
-		s.tasks["t1"] = entry
-
-		s.handleContainerDeath("md-repo-caic-0")
+	})
 
-		if tk.GetState() != task.StateStopped {
-			t.Errorf("state = %v, want %v", tk.GetState(), task.StateStopped)
+	t.Run("NotPlanOnly", func(t *testing.T) {
+		w := promote(newServer(t, false), `{"prompt":{"text":"1. do it"}}`)
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
 		}
 	})
 
-	t.Run("UnknownContainer", func(t *testing.T) {
-		s := newTestServer(t)
-		// Should not panic or cause errors.
-		s.handleContainerDeath("unknown-container")
+	t.Run("SyncRejected", func(t *testing.T) {
+		s := newServer(t, true)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/sync", strings.NewReader(`{}`))
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		handleWithTask(s, s.syncTask)(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+		}
 	})
 }
 
-func TestHandleCreateTask(t *testing.T) {
-	t.Run("ReturnsID", func(t *testing.T) {
-		s := &Server{
-			ctx: t.Context(),
-			runners: map[string]*task.Runner{
-				"myrepo": {
-					BaseBranch: "main",
-					Dir:        t.TempDir(),
-					Backends:   map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}},
-				},
-			},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-			prefs:   newTestPrefs(t),
-		}
-		handler := handle(s.createTask)
+func TestHandleApprovePlan(t *testing.T) {
+	approve := func(s *Server) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/plan", strings.NewReader(`{"prompt":{"text":"1. do it"}}`))
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		handleWithTask(s, s.approvePlan)(w, req)
+		return w
+	}
+	for _, tt := range []struct {
+		name        string
+		requirePlan bool
+		state       task.State
+	}{
+		{"NotRequired", false, task.StateWaiting},
+		{"NotInReview", true, task.StateRunning},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}, RequirePlan: tt.requirePlan}
+			tk.SetState(tt.state)
+			s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+			w := approve(s)
+			if w.Code != http.StatusConflict {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+			}
+		})
+	}
 
-		body := strings.NewReader(`{"initialPrompt":{"text":"test task"},"repos":[{"name":"myrepo"}],"harness":"claude"}`)
+	t.Run("ExclusiveWithPlanOnly", func(t *testing.T) {
+		s := newTestServer(t)
+		body := strings.NewReader(`{"initialPrompt":{"text":"plan it"},"harness":"claude","planOnly":true,"requirePlan":true}`)
 		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
 		w := httptest.NewRecorder()
-		handler(w, req)
+		handle(s.createTask)(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
 
-		if w.Code != http.StatusOK {
-			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+func TestHandleReadOnlyTask(t *testing.T) {
+	newServer := func(t *testing.T) *Server {
+		s := newTestServer(t)
+		tk := &task.Task{
+			InitialPrompt: agent.Prompt{Text: "where is auth?"},
+			Repos:         []task.RepoMount{{Name: "r", Branch: "caic-0"}},
+			Container:     "md-r-caic-0",
+			ReadOnly:      true,
 		}
-		var resp v1.CreateTaskResp
-		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
-			t.Fatal(err)
-		}
-		if resp.ID == 0 {
-			t.Error("response has zero 'id' field")
+		tk.SetState(task.StateWaiting)
+		s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+		return s
+	}
+	t.Run("SyncRejected", func(t *testing.T) {
+		s := newServer(t)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/sync", strings.NewReader(`{}`))
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		handleWithTask(s, s.syncTask)(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
 		}
 	})
-
-	t.Run("MissingRepo", func(t *testing.T) {
-		s := newTestServer(t)
-		handler := handle(s.createTask)
-
-		body := strings.NewReader(`{"initialPrompt":{"text":"test task"}}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+	t.Run("ForkRejected", func(t *testing.T) {
+		s := newServer(t)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/fork", strings.NewReader(`{"prompt":{"text":"go"}}`))
+		req.SetPathValue("id", "t1")
 		w := httptest.NewRecorder()
-		handler(w, req)
-
+		handleWithTask(s, s.forkTask)(w, req)
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
-		e := decodeError(t, w)
-		if e.Code != dto.CodeBadRequest {
-			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
-		}
 	})
+}
 
-	t.Run("UnknownRepo", func(t *testing.T) {
+func TestHandleChatTask(t *testing.T) {
+	t.Run("SyncRejected", func(t *testing.T) {
 		s := newTestServer(t)
-		handler := handle(s.createTask)
-
-		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"nonexistent"}],"harness":"claude"}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "hi"}, Repos: []task.RepoMount{{Name: "r", Branch: "caic-chat-x"}}, Container: "md-r-caic-chat-x", Chat: true}
+		tk.SetState(task.StateWaiting)
+		s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/sync", strings.NewReader(`{}`))
+		req.SetPathValue("id", "t1")
 		w := httptest.NewRecorder()
-		handler(w, req)
-
-		if w.Code != http.StatusBadRequest {
-			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
-		}
-		e := decodeError(t, w)
-		if e.Code != dto.CodeBadRequest {
-			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
+		handleWithTask(s, s.syncTask)(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
 		}
 	})
-
-	t.Run("UnknownHarness", func(t *testing.T) {
-		s := &Server{
-			ctx: t.Context(),
-			runners: map[string]*task.Runner{
-				"myrepo": {BaseBranch: "main", Dir: t.TempDir()},
-			},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-		}
-		handler := handle(s.createTask)
-
-		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"myrepo"}],"harness":"nonexistent"}`)
+	t.Run("MultiRepoRejected", func(t *testing.T) {
+		s := newTestServer(t)
+		body := strings.NewReader(`{"initialPrompt":{"text":"hi"},"harness":"claude","chat":true,"repos":[{"name":"a"},{"name":"b"}]}`)
 		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
 		w := httptest.NewRecorder()
-		handler(w, req)
-
+		handle(s.createTask)(w, req)
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
-		e := decodeError(t, w)
-		if e.Code != dto.CodeBadRequest {
-			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
-		}
-		if !strings.Contains(e.Message, "nonexistent") {
-			t.Errorf("message = %q, want it to mention the unknown harness", e.Message)
-		}
 	})
+}
 
-	t.Run("InvalidModel", func(t *testing.T) {
-		s := &Server{
-			ctx: t.Context(),
-			runners: map[string]*task.Runner{
-				"myrepo": {
-					BaseBranch: "main",
-					Dir:        t.TempDir(),
-					Backends:   map[agent.Harness]agent.Backend{"stub": stubBackend{}},
-				},
-			},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
+func TestTaskDependencies(t *testing.T) {
+	// newServer returns a server with one repo and a prerequisite task "dep"
+	// in the given state.
+	newServer := func(t *testing.T, state task.State) *Server {
+		s := newTestServer(t)
+		s.runners["r"] = &task.Runner{
+			BaseBranch: "main",
+			Dir:        t.TempDir(),
+			Backends:   map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}},
+		}
+		dep := &task.Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "api"}, Repos: []task.RepoMount{{Name: "r", Branch: "caic-0"}}}
+		dep.SetState(state)
+		s.tasks["dep"] = &taskEntry{task: dep, done: make(chan struct{})}
+		return s
+	}
+	create := func(t *testing.T, s *Server, extra string) *taskEntry {
+		body := strings.NewReader(`{"initialPrompt":{"text":"client"},"repos":[{"name":"r"}],"harness":"claude","dependsOn":["dep"]` + extra + `}`)
+		w := httptest.NewRecorder()
+		handle(s.createTask)(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
 		}
-		handler := handle(s.createTask)
-
-		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"myrepo"}],"harness":"stub","model":"nonexistent"}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		var resp v1.CreateTaskResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.tasks[resp.ID.String()]
+	}
+	waitDone := func(t *testing.T, e *taskEntry) {
+		select {
+		case <-e.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for dependent task")
+		}
+	}
+	t.Run("UnknownDependency", func(t *testing.T) {
+		s := newServer(t, task.StateRunning)
+		body := strings.NewReader(`{"initialPrompt":{"text":"client"},"repos":[{"name":"r"}],"harness":"claude","dependsOn":["nope"]}`)
 		w := httptest.NewRecorder()
-		handler(w, req)
-
+		handle(s.createTask)(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body))
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
-		e := decodeError(t, w)
-		if e.Code != dto.CodeBadRequest {
-			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
+	})
+	t.Run("CancelOnFailure", func(t *testing.T) {
+		s := newServer(t, task.StateFailed)
+		e := create(t, s, "")
+		waitDone(t, e)
+		if got := e.task.GetState(); got != task.StateFailed {
+			t.Errorf("state = %v, want %v", got, task.StateFailed)
 		}
-		if !strings.Contains(e.Message, "nonexistent") {
-			t.Errorf("message = %q, want it to mention the invalid model", e.Message)
+		if e.result == nil || !strings.Contains(e.result.Err.Error(), "dependency") {
+			t.Errorf("result = %+v, want dependency error", e.result)
 		}
 	})
-
-	t.Run("ValidModel", func(t *testing.T) {
-		s := &Server{
-			ctx: t.Context(),
-			runners: map[string]*task.Runner{
-				"myrepo": {
-					BaseBranch: "main",
-					Dir:        t.TempDir(),
-					Backends:   map[agent.Harness]agent.Backend{"stub": stubBackend{}},
-				},
-			},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-			prefs:   newTestPrefs(t),
+	t.Run("HoldOnFailure", func(t *testing.T) {
+		s := newServer(t, task.StateFailed)
+		e := create(t, s, `,"onDependencyFailure":"hold"`)
+		select {
+		case <-e.done:
+			t.Fatal("held task must stay pending")
+		case <-time.After(50 * time.Millisecond):
 		}
-		handler := handle(s.createTask)
-
-		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"myrepo"}],"harness":"stub","model":"m1"}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		if got := e.task.GetState(); got != task.StatePending {
+			t.Errorf("state = %v, want %v", got, task.StatePending)
+		}
+	})
+	t.Run("PurgeWhilePending", func(t *testing.T) {
+		s := newServer(t, task.StateRunning)
+		e := create(t, s, "")
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/x/purge", http.NoBody)
+		req.SetPathValue("id", e.task.ID.String())
 		w := httptest.NewRecorder()
-		handler(w, req)
-
+		handleWithTask(s, s.purgeTask)(w, req)
 		if w.Code != http.StatusOK {
 			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
 		}
-		var resp v1.CreateTaskResp
-		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
-			t.Fatal(err)
+		waitDone(t, e)
+		if got := e.task.GetState(); got != task.StatePurged {
+			t.Errorf("state = %v, want %v", got, task.StatePurged)
 		}
-		if resp.ID == 0 {
-			t.Error("response has zero 'id' field")
+	})
+	t.Run("InheritBranchOtherRepo", func(t *testing.T) {
+		s := newServer(t, task.StateRunning)
+		s.runners["other"] = s.runners["r"]
+		body := strings.NewReader(`{"initialPrompt":{"text":"client"},"repos":[{"name":"other"}],"harness":"claude","dependsOn":["dep"],"inheritBranch":true}`)
+		w := httptest.NewRecorder()
+		handle(s.createTask)(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
 	})
+}
 
-	t.Run("WithImage", func(t *testing.T) {
-		s := &Server{
-			ctx: t.Context(),
-			runners: map[string]*task.Runner{
-				"myrepo": {
-					BaseBranch: "main",
-					Dir:        t.TempDir(),
-					Backends:   map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}},
-				},
-			},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-			prefs:   newTestPrefs(t),
+func TestPipeline(t *testing.T) {
+	t.Run("TemplateError", func(t *testing.T) {
+		s := newTestServer(t)
+		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir(), Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}
+		body := strings.NewReader(`{"initialPrompt":{"text":"x"},"repos":[{"name":"r"}],"harness":"claude","pipeline":{"name":"p","steps":[{"prompt":"{{.Input"}]}}`)
+		w := httptest.NewRecorder()
+		handle(s.createTask)(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
-		handler := handle(s.createTask)
-
-		// Set docker image in user preferences.
-		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
-			p.Settings.BaseImage = "ghcr.io/my/image:v1"
-		}); err != nil {
+	})
+	t.Run("Render", func(t *testing.T) {
+		tmpls, err := parsePipeline(&v1.Pipeline{Steps: []v1.PipelineStep{{Prompt: "Fix {{.Input}} after: {{.Previous}}"}}})
+		if err != nil {
 			t.Fatal(err)
 		}
-
-		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"myrepo"}],"harness":"claude"}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
-		w := httptest.NewRecorder()
-		handler(w, req)
-
-		if w.Code != http.StatusOK {
-			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		got, err := renderStep(tmpls[0], &pipelineVars{Input: "bug", Previous: "tests written"})
+		if err != nil {
+			t.Fatal(err)
 		}
-		var resp v1.CreateTaskResp
-		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		if want := "Fix bug after: tests written"; got != want {
+			t.Errorf("renderStep = %q, want %q", got, want)
+		}
+	})
+	// newStep returns the task entry of the first step of a pipeline with
+	// the given steps, finished in state with a result.
+	newStep := func(t *testing.T, s *Server, state task.State, isError bool, steps ...v1.PipelineStep) *taskEntry {
+		req := &v1.CreateTaskReq{InitialPrompt: v1.Prompt{Text: "x"}, Repos: []v1.RepoSpec{{Name: "r"}}, Harness: v1.HarnessClaude, Pipeline: &v1.Pipeline{Name: "p", Steps: steps}}
+		run, _, err := newPipelineRun(req)
+		if err != nil {
 			t.Fatal(err)
 		}
-		if resp.ID == 0 {
-			t.Error("response has zero 'id' field")
+		tk := &task.Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "x"}, Repos: []task.RepoMount{{Name: "r", Branch: "caic-0"}}}
+		tk.RestoreMessages([]agent.Message{&agent.ResultMessage{MessageType: "result", IsError: isError, Result: "tests written"}})
+		tk.SetState(state)
+		e := &taskEntry{task: tk, done: make(chan struct{}), pipeline: run}
+		run.tasks = []ksid.ID{tk.ID}
+		s.tasks[tk.ID.String()] = e
+		return e
+	}
+	run := func(t *testing.T, state task.State, isError bool) *v1.PipelineProgress {
+		s := newTestServer(t)
+		e := newStep(t, s, state, isError, v1.PipelineStep{Prompt: "go"})
+		if got := s.runPipelineStep(e, &task.Runner{}); got != (e.pipeline.status == "done") {
+			t.Errorf("runPipelineStep = %t with status %q", got, e.pipeline.status)
 		}
-
-		// Verify the task uses the image from preferences.
-		s.mu.Lock()
-		entry := s.tasks[resp.ID.String()]
-		s.mu.Unlock()
-		if entry == nil {
-			t.Fatal("task not found")
+		return e.pipeline.toJSON()
+	}
+	t.Run("Done", func(t *testing.T) {
+		if p := run(t, task.StateWaiting, false); p.Status != "done" {
+			t.Errorf("status = %q, want done (%s)", p.Status, p.Error)
 		}
-		if entry.task.DockerImage != "ghcr.io/my/image:v1" {
-			t.Errorf("Image = %q, want %q", entry.task.DockerImage, "ghcr.io/my/image:v1")
+	})
+	t.Run("StepError", func(t *testing.T) {
+		if p := run(t, task.StateWaiting, true); p.Status != "failed" {
+			t.Errorf("status = %q, want failed", p.Status)
 		}
 	})
-
-	t.Run("NoRepoTask", func(t *testing.T) {
-		// Regression: creating a task with no repos panicked with
-		// "makeslice: cap out of range" because len(req.Repos)-1 == -1.
-		s := &Server{
-			ctx: t.Context(),
-			runners: map[string]*task.Runner{
-				"": {
-					Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}},
-				},
-			},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-			prefs:   newTestPrefs(t),
+	t.Run("Aborted", func(t *testing.T) {
+		if p := run(t, task.StateFailed, false); p.Status != "aborted" {
+			t.Errorf("status = %q, want aborted", p.Status)
 		}
-		handler := handle(s.createTask)
-
-		body := strings.NewReader(`{"initialPrompt":{"text":"no repo task"},"harness":"claude"}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
-		w := httptest.NewRecorder()
-		handler(w, req)
-
-		if w.Code != http.StatusOK {
-			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	})
+	t.Run("NextStep", func(t *testing.T) {
+		s := newTestServer(t)
+		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir(), Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}
+		e := newStep(t, s, task.StateWaiting, false, v1.PipelineStep{Prompt: "write tests"}, v1.PipelineStep{Prompt: "implement {{.Input}} after: {{.Previous}}"})
+		if s.runPipelineStep(e, s.runners["r"]) {
+			t.Fatal("runPipelineStep = true before the last step")
 		}
-		var resp v1.CreateTaskResp
-		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
-			t.Fatal(err)
+		s.mu.Lock()
+		tasks := slices.Clone(e.pipeline.tasks)
+		var next *taskEntry
+		if len(tasks) == 2 {
+			next = s.tasks[tasks[1].String()]
 		}
-		if resp.ID == 0 {
-			t.Error("response has zero 'id' field")
+		s.mu.Unlock()
+		if next == nil {
+			t.Fatalf("pipeline tasks = %v, want the task of step 1", tasks)
 		}
-	})
-
-	t.Run("NoRepoRunnerMissing", func(t *testing.T) {
-		// When no repos are specified and no "" runner is configured, return
-		// a clear error instead of panicking.
-		s := newTestServer(t) // no "" runner
-		handler := handle(s.createTask)
-
-		body := strings.NewReader(`{"initialPrompt":{"text":"no repo task"},"harness":"claude"}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
-		w := httptest.NewRecorder()
-		handler(w, req)
-
-		if w.Code != http.StatusInternalServerError {
-			t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+		if next.pipeline != e.pipeline || next.pipelineStep != 1 {
+			t.Errorf("next step = %d, want 1 of the same pipeline", next.pipelineStep)
+		}
+		if got, want := next.task.InitialPrompt.Text, "implement x after: tests written"; got != want {
+			t.Errorf("prompt = %q, want %q", got, want)
+		}
+		if got := next.task.DependsOn; len(got) != 1 || got[0] != e.task.ID {
+			t.Errorf("DependsOn = %v, want [%s]", got, e.task.ID)
+		}
+		// The runner has no container backend, so the task of step 1 fails to
+		// start and so does the pipeline.
+		select {
+		case <-next.done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the task of step 1")
+		}
+		s.mu.Lock()
+		p := e.pipeline.toJSON()
+		s.mu.Unlock()
+		if p.Status != "failed" || p.Step != 1 {
+			t.Errorf("pipeline = %s at step %d, want failed at step 1", p.Status, p.Step)
 		}
 	})
-
-	t.Run("UnknownField", func(t *testing.T) {
+	t.Run("PushPolicy", func(t *testing.T) {
 		s := newTestServer(t)
-		handler := handle(s.createTask)
-
-		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repo":"r","harness":"claude","bogus":true}`)
-		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir(), Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}
+		body := strings.NewReader(`{"initialPrompt":{"text":"x"},"repos":[{"name":"r"}],"harness":"claude","pushPolicy":"never","pipeline":{"name":"p","steps":[{"prompt":"a"},{"prompt":"b"}]}}`)
 		w := httptest.NewRecorder()
-		handler(w, req)
-
+		handle(s.createTask)(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body))
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
-		e := decodeError(t, w)
-		if e.Code != dto.CodeBadRequest {
-			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
+	})
+	t.Run("List", func(t *testing.T) {
+		s := newTestServer(t)
+		s.pipelinesDir = t.TempDir()
+		files := map[string]string{
+			"tdd.json":    `{"steps":[{"prompt":"write tests"},{"prompt":"implement","verify":"go test ./..."}]}`,
+			"lint.yaml":   "name: lint\nsteps:\n  - prompt: fix the lint errors\n    verify: make lint\n",
+			"broken.json": `{"steps":[]}`,
+			"bad.yml":     "steps: [",
+			"notes.txt":   `ignored`,
+		}
+		for name, data := range files {
+			if err := os.WriteFile(filepath.Join(s.pipelinesDir, name), []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		got, err := s.listPipelines(t.Context(), &dto.EmptyReq{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(*got) != 2 || (*got)[0].Name != "lint" || (*got)[0].Steps[0].Verify != "make lint" || (*got)[1].Name != "tdd" || len((*got)[1].Steps) != 2 {
+			t.Errorf("listPipelines = %+v, want [lint, tdd with 2 steps]", *got)
+		}
+	})
+}
+
+func TestReview(t *testing.T) {
+	t.Run("ParseVerdict", func(t *testing.T) {
+		for _, tc := range []struct {
+			name     string
+			review   string
+			approved bool
+			feedback string
+		}{
+			{"Approve", "Looks good.\nVERDICT: APPROVE", true, "Looks good."},
+			{"Changes", "Add a test.\n\n**VERDICT: CHANGES**\n", false, "Add a test."},
+			{"NoVerdict", "Hmm.", false, "Hmm."},
+			{"OnlyVerdict", "VERDICT: APPROVE", true, ""},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				approved, feedback := parseVerdict(tc.review)
+				if approved != tc.approved || feedback != tc.feedback {
+					t.Errorf("parseVerdict(%q) = (%v, %q), want (%v, %q)", tc.review, approved, feedback, tc.approved, tc.feedback)
+				}
+			})
+		}
+	})
+	t.Run("UnknownHarness", func(t *testing.T) {
+		s := newTestServer(t)
+		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir(), Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}
+		body := strings.NewReader(`{"initialPrompt":{"text":"x"},"repos":[{"name":"r"}],"harness":"claude","review":{"harness":"codex"}}`)
+		w := httptest.NewRecorder()
+		handle(s.createTask)(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+	t.Run("AbortedWhenTaskFails", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "x"}, Repos: []task.RepoMount{{Name: "r", Branch: "caic-0"}}}
+		tk.SetState(task.StateFailed)
+		e := &taskEntry{task: tk, done: make(chan struct{}), review: &reviewRun{spec: v1.ReviewSpec{Harness: v1.HarnessClaude, MaxRounds: 2}, status: "pending"}}
+		s.runReview(e, &task.Runner{})
+		if p := e.review.toJSON(); p.Status != "aborted" || p.Round != 1 {
+			t.Errorf("review = %+v, want aborted in round 1", p)
+		}
+	})
+}
+
+func TestEval(t *testing.T) {
+	t.Run("UnknownRepo", func(t *testing.T) {
+		s := newTestServer(t)
+		body := strings.NewReader(`{"suite":{"name":"s","cases":[{"name":"c","repo":"nope","prompt":"x","verify":"true"}]},"targets":[{"harness":"claude"}]}`)
+		w := httptest.NewRecorder()
+		handle(s.createEval)(w, httptest.NewRequest(http.MethodPost, "/api/v1/evals", body))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		s := newTestServer(t)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/evals/x", http.NoBody)
+		req.SetPathValue("id", "x")
+		w := httptest.NewRecorder()
+		handle(s.getEval)(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+	t.Run("Summary", func(t *testing.T) {
+		claude := v1.EvalTarget{Harness: v1.HarnessClaude}
+		codex := v1.EvalTarget{Harness: v1.HarnessCodex, Model: "m"}
+		got := summarizeEval([]v1.EvalResult{
+			{Case: "a", Target: claude, Status: "passed", CostUSD: 1, Duration: 10},
+			{Case: "a", Target: codex, Status: "error"},
+			{Case: "b", Target: claude, Status: "failed", CostUSD: 2, Duration: 5},
+			{Case: "b", Target: codex, Status: "running"},
+		})
+		want := []v1.EvalSummary{
+			{Target: claude, Passed: 1, Failed: 1, CostUSD: 3, Duration: 15},
+			{Target: codex, Errored: 1},
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("summarizeEval = %+v, want %+v", got, want)
+		}
+	})
+	t.Run("ErrorWhenTaskFails", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "x"}, Repos: []task.RepoMount{{Name: "r"}}}
+		tk.SetState(task.StateFailed)
+		e := &taskEntry{task: tk, done: make(chan struct{})}
+		status, _, err := s.verifyEvalCase(e, v1.EvalCase{Name: "c", Repo: "r", Verify: "true"})
+		if status != "error" || err == nil {
+			t.Errorf("verifyEvalCase = (%q, %v), want error", status, err)
+		}
+	})
+}
+
+func TestHandlePurge(t *testing.T) {
+	t.Run("NotWaiting", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
+		// StatePending is the zero value, but set explicitly for clarity.
+		s.tasks["t1"] = &taskEntry{
+			task: tk,
+			done: make(chan struct{}),
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/purge", http.NoBody)
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		handleWithTask(s, s.purgeTask)(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+		}
+		e := decodeError(t, w)
+		if e.Code != dto.CodeConflict {
+			t.Errorf("code = %q, want %q", e.Code, dto.CodeConflict)
+		}
+	})
+
+	t.Run("Waiting", func(t *testing.T) {
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}, Repos: []task.RepoMount{{Name: "r"}}}
+		tk.SetState(task.StateWaiting)
+		s := newTestServer(t)
+		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir()}
+		s.tasks["t1"] = &taskEntry{
+			task: tk,
+			done: make(chan struct{}),
+		}
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/purge", http.NoBody)
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		handleWithTask(s, s.purgeTask)(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		// Verify the response reports purging. Don't check tk.State
+		// directly: cleanupTask runs in a goroutine and may have already
+		// transitioned the state to StatePurged by now.
+		var resp v1.StatusResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Status != "purging" {
+			t.Errorf("status = %q, want %q", resp.Status, "purging")
+		}
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}, Repos: []task.RepoMount{{Name: "r"}}}
+		tk.SetState(task.StateRunning)
+		s := newTestServer(t)
+		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir()}
+		s.tasks["t1"] = &taskEntry{
+			task: tk,
+			done: make(chan struct{}),
+		}
+
+		// Use an already-cancelled context to simulate shutdown scenario
+		// where BaseContext is cancelled before the handler completes.
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/purge", http.NoBody)
+		req = req.WithContext(ctx)
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		handleWithTask(s, s.purgeTask)(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+}
+
+func TestHandleRehydrate(t *testing.T) {
+	rehydrate := func(s *Server) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks/t1/rehydrate", http.NoBody)
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		handleWithTask(s, s.rehydrateTask)(w, req)
+		return w
+	}
+	for _, tt := range []struct {
+		name  string
+		state task.State
+	}{
+		{"Waiting", task.StateWaiting},
+		// The workspace was never saved.
+		{"NoSavedWorkspace", task.StateStopped},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tk := &task.Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "test"}, Repos: []task.RepoMount{{Name: "r"}}}
+			tk.SetState(tt.state)
+			s := newTestServer(t)
+			s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir()}
+			s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+			if w := rehydrate(s); w.Code != http.StatusConflict {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+			}
+			if got := tk.GetState(); got != tt.state {
+				t.Errorf("state = %v, want %v", got, tt.state)
+			}
+		})
+	}
+	// A repo with a saved workspace, for the accepted states.
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"-c", "user.name=Test", "-c", "user.email=test@test.com", "commit", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", args, err, out)
+		}
+	}
+	for _, state := range []task.State{task.StateStopped, task.StateFailed, task.StatePurged} {
+		t.Run(state.String(), func(t *testing.T) {
+			tk := &task.Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "test"}, Repos: []task.RepoMount{{Name: "r", Branch: "caic-0"}}}
+			tk.SetStateAt(state, time.Now())
+			cmd := exec.Command("git", "update-ref", task.WorkspaceRef(tk), "HEAD")
+			cmd.Dir = dir
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("%v\n%s", err, out)
+			}
+			s := newTestServer(t)
+			// Without a container backend, rehydration stops right after the
+			// handler moved the task to provisioning.
+			s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: dir}
+			s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+			if w := rehydrate(s); w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+			trs := tk.Transitions()
+			if len(trs) != 1 || trs[0].From != state || trs[0].To != task.StateProvisioning {
+				t.Errorf("transitions = %+v, want %v→provisioning", trs, state)
+			}
+		})
+	}
+	t.Run("Persist", func(t *testing.T) {
+		s := newTestServer(t)
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) { p.Settings.PersistWorkspace = true }); err != nil {
+			t.Fatal(err)
+		}
+		tk := &task.Task{}
+		s.setPersist(tk)
+		if !tk.Persist {
+			t.Error("Persist = false, want the owner's preference")
+		}
+	})
+}
+
+func TestHandleGetTimeline(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tk := &task.Task{ID: ksid.NewID(), StartedAt: t0, Repos: []task.RepoMount{{Name: "r"}}}
+	tk.RestoreTransitions([]task.Transition{
+		{From: task.StatePending, To: task.StateProvisioning, At: t0.Add(time.Minute)},
+		{From: task.StateProvisioning, To: task.StateRunning, At: t0.Add(2 * time.Minute)},
+		{From: task.StateRunning, To: task.StateWaiting, At: t0.Add(4 * time.Minute)},
+		{From: task.StateWaiting, To: task.StateRunning, At: t0.Add(5 * time.Minute)},
+	})
+	s := newTestServer(t)
+	s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/t1/timeline", http.NoBody)
+	req.SetPathValue("id", "t1")
+	w := httptest.NewRecorder()
+	s.handleGetTimeline(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var got v1.TaskTimeline
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Phases) != 5 || got.Phases[4].Turn != 2 || !got.Phases[4].End.IsZero() {
+		t.Fatalf("phases = %+v", got.Phases)
+	}
+	// The last turn is still running: it lasts until now.
+	if got.PhaseMs["queued"] != time.Minute.Milliseconds() || got.PhaseMs["turn"] <= 2*time.Minute.Milliseconds() || got.TotalMs <= 5*time.Minute.Milliseconds() {
+		t.Errorf("phaseMs = %v, totalMs = %d", got.PhaseMs, got.TotalMs)
+	}
+}
+
+func TestHandleContainerDeath(t *testing.T) {
+	t.Run("ArchivesAsStopped", func(t *testing.T) {
+		s := newTestServer(t)
+		tk := &task.Task{
+			InitialPrompt: agent.Prompt{Text: "test"},
+			Repos:         []task.RepoMount{{Name: "r"}},
+			Container:     "md-repo-caic-0",
+		}
+		tk.SetState(task.StateRunning)
+		s.runners["r"] = &task.Runner{BaseBranch: "main", Dir: t.TempDir()}
+		entry := &taskEntry{task: tk, done: make(chan struct{})}
+		s.tasks["t1"] = entry
+
+		s.handleContainerDeath("md-repo-caic-0")
+
+		if tk.GetState() != task.StateStopped {
+			t.Errorf("state = %v, want %v", tk.GetState(), task.StateStopped)
+		}
+	})
+
+	t.Run("UnknownContainer", func(t *testing.T) {
+		s := newTestServer(t)
+		// Should not panic or cause errors.
+		s.handleContainerDeath("unknown-container")
+	})
+}
+
+func TestInferCreateTaskReq(t *testing.T) {
+	backends := map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}, "stub": stubBackend{}}
+	prompt := v1.Prompt{Text: "fix it"}
+	tests := []struct {
+		name  string
+		prefs func(*preferences.Preferences)
+		want  v1.CreateTaskReq
+	}{
+		{
+			name:  "no history",
+			prefs: func(*preferences.Preferences) {},
+			want:  v1.CreateTaskReq{InitialPrompt: prompt, Harness: v1.HarnessClaude},
+		},
+		{
+			name: "most recent repo",
+			prefs: func(p *preferences.Preferences) {
+				p.TouchRepo("b", &preferences.RepoPrefs{Harness: "stub", Model: "m1"})
+				p.TouchRepo("a", &preferences.RepoPrefs{BaseBranch: "dev", Harness: "stub", Model: "m2"})
+			},
+			want: v1.CreateTaskReq{InitialPrompt: prompt, Repos: []v1.RepoSpec{{Name: "a", BaseBranch: "dev"}}, Harness: "stub", Model: "m2"},
+		},
+		{
+			name: "skips removed repo and stale model",
+			prefs: func(p *preferences.Preferences) {
+				p.TouchRepo("a", &preferences.RepoPrefs{Harness: "claude", Model: "gone"})
+				p.TouchRepo("removed", &preferences.RepoPrefs{Harness: "stub"})
+			},
+			want: v1.CreateTaskReq{InitialPrompt: prompt, Repos: []v1.RepoSpec{{Name: "a"}}, Harness: v1.HarnessClaude},
+		},
+		{
+			name: "global harness",
+			prefs: func(p *preferences.Preferences) {
+				p.Harness = "stub"
+				p.Models = map[string]string{"stub": "m1"}
+			},
+			want: v1.CreateTaskReq{InitialPrompt: prompt, Harness: "stub", Model: "m1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t)
+			for _, name := range []string{"", "a", "b"} {
+				s.runners[name] = &task.Runner{Backends: backends}
+			}
+			if err := s.prefs.Update("default", tt.prefs); err != nil {
+				t.Fatal(err)
+			}
+			got, err := s.inferCreateTaskReq("default", prompt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListRecentPrompts(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+		p.TouchPrompt("a", "fix the tests")
+		p.TouchPrompt("b", "fix lint")
+		p.TouchPrompt("a", "add docs")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.handleListRecentPrompts(w, httptest.NewRequest(http.MethodGet, "/api/v1/prompts/recent"+query, http.NoBody))
+		return w
+	}
+	for query, want := range map[string][]string{
+		"":                       {"add docs", "fix lint", "fix the tests"},
+		"?repo=a":                {"add docs", "fix the tests"},
+		"?prefix=Fix":            {"fix lint", "fix the tests"},
+		"?repo=a&prefix=fix":     {"fix the tests"},
+		"?limit=1":               {"add docs"},
+		"?repo=b&prefix=nomatch": {},
+	} {
+		w := get(query)
+		var out []v1.RecentPrompt
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatalf("%s: status = %d: %s", query, w.Code, w.Body.String())
+		}
+		got := []string{}
+		for _, p := range out {
+			got = append(got, p.Text)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: got %v, want %v", query, got, want)
+		}
+	}
+	if w := get("?limit=0"); w.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: status = %d, want 400", w.Code)
+	}
+}
+
+func TestLintPrompt(t *testing.T) {
+	t.Run("lintPromptText", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, "pkg"), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "pkg", "a.go"), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		kinds := func(out []v1.PromptSuggestion) []v1.PromptSuggestionKind {
+			got := []v1.PromptSuggestionKind{}
+			for _, s := range out {
+				got = append(got, s.Kind)
+			}
+			return got
+		}
+		for _, tc := range []struct {
+			name, text, repoDir string
+			want                []v1.PromptSuggestionKind
+		}{
+			{"vague", "fix it", dir, []v1.PromptSuggestionKind{v1.PromptVague}},
+			{"no repo", "fix it", "", []v1.PromptSuggestionKind{v1.PromptVague}},
+			{"no file", "make the retry logic back off exponentially on errors", dir, []v1.PromptSuggestionKind{v1.PromptNoFileReference}},
+			{"known file", "make the retry logic in pkg/a.go:12 back off exponentially", dir, []v1.PromptSuggestionKind{}},
+			{"unknown file", "make the retry logic in pkg/b.go back off exponentially", dir, []v1.PromptSuggestionKind{v1.PromptUnknownFile}},
+			{"large paste", "explain this failure log please now\n" + strings.Repeat("line\n", lintMaxPasteLines), "", []v1.PromptSuggestionKind{v1.PromptLargePaste}},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				if got := kinds(lintPromptText(tc.text, tc.repoDir)); !slices.Equal(got, tc.want) {
+					t.Errorf("got %v, want %v", got, tc.want)
+				}
+			})
+		}
+	})
+	t.Run("fileRefs", func(t *testing.T) {
+		words := strings.Fields("See (pkg/a.go:12), main.go, https://x.com/a/b and pkg/a.go again; also web/src/.")
+		want := []string{"pkg/a.go", "main.go", "web/src/"}
+		if got := fileRefs(words); !slices.Equal(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+	t.Run("unknownRepo", func(t *testing.T) {
+		s := newTestServer(t)
+		if _, err := s.lintPrompt(t.Context(), &v1.LintPromptReq{Text: "fix it", Repo: "nope"}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestHandleCreateTask(t *testing.T) {
+	t.Run("ReturnsID", func(t *testing.T) {
+		s := &Server{
+			ctx: t.Context(),
+			runners: map[string]*task.Runner{
+				"myrepo": {
+					BaseBranch: "main",
+					Dir:        t.TempDir(),
+					Backends:   map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}},
+				},
+			},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			prefs:   newTestPrefs(t),
+		}
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"test task"},"repos":[{"name":"myrepo"}],"harness":"claude"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var resp v1.CreateTaskResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.ID == 0 {
+			t.Error("response has zero 'id' field")
+		}
+	})
+
+	t.Run("Env", func(t *testing.T) {
+		s := &Server{
+			ctx: t.Context(),
+			runners: map[string]*task.Runner{
+				"myrepo": {
+					BaseBranch: "main",
+					Dir:        t.TempDir(),
+					Backends:   map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}},
+				},
+			},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			prefs:   newTestPrefs(t),
+		}
+		handler := handle(s.createTask)
+		const body = `{"initialPrompt":{"text":"test task"},"repos":[{"name":"myrepo"}],"harness":"claude","env":{"FEATURE_X":"on"}}`
+
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("without allowlist: status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.EnvAllowlist = []string{"FEATURE_*"}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		w = httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp v1.CreateTaskResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		s.mu.Lock()
+		got := s.tasks[resp.ID.String()].task.EnvPairs()
+		s.mu.Unlock()
+		if !slices.Equal(got, []string{"FEATURE_X=on"}) {
+			t.Errorf("env = %q", got)
+		}
+	})
+
+	t.Run("MissingRepo", func(t *testing.T) {
+		s := newTestServer(t)
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"test task"}}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		e := decodeError(t, w)
+		if e.Code != dto.CodeBadRequest {
+			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
+		}
+	})
+
+	t.Run("UnknownRepo", func(t *testing.T) {
+		s := newTestServer(t)
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"nonexistent"}],"harness":"claude"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		e := decodeError(t, w)
+		if e.Code != dto.CodeBadRequest {
+			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
+		}
+	})
+
+	t.Run("UnknownHarness", func(t *testing.T) {
+		s := &Server{
+			ctx: t.Context(),
+			runners: map[string]*task.Runner{
+				"myrepo": {BaseBranch: "main", Dir: t.TempDir()},
+			},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+		}
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"myrepo"}],"harness":"nonexistent"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		e := decodeError(t, w)
+		if e.Code != dto.CodeBadRequest {
+			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
+		}
+		if !strings.Contains(e.Message, "nonexistent") {
+			t.Errorf("message = %q, want it to mention the unknown harness", e.Message)
+		}
+	})
+
+	t.Run("InvalidModel", func(t *testing.T) {
+		s := &Server{
+			ctx: t.Context(),
+			runners: map[string]*task.Runner{
+				"myrepo": {
+					BaseBranch: "main",
+					Dir:        t.TempDir(),
+					Backends:   map[agent.Harness]agent.Backend{"stub": stubBackend{}},
+				},
+			},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+		}
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"myrepo"}],"harness":"stub","model":"nonexistent"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		e := decodeError(t, w)
+		if e.Code != dto.CodeBadRequest {
+			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
+		}
+		if !strings.Contains(e.Message, "nonexistent") {
+			t.Errorf("message = %q, want it to mention the invalid model", e.Message)
+		}
+	})
+
+	t.Run("ValidModel", func(t *testing.T) {
+		s := &Server{
+			ctx: t.Context(),
+			runners: map[string]*task.Runner{
+				"myrepo": {
+					BaseBranch: "main",
+					Dir:        t.TempDir(),
+					Backends:   map[agent.Harness]agent.Backend{"stub": stubBackend{}},
+				},
+			},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			prefs:   newTestPrefs(t),
+		}
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"myrepo"}],"harness":"stub","model":"m1"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var resp v1.CreateTaskResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.ID == 0 {
+			t.Error("response has zero 'id' field")
+		}
+	})
+
+	t.Run("WithImage", func(t *testing.T) {
+		s := &Server{
+			ctx: t.Context(),
+			runners: map[string]*task.Runner{
+				"myrepo": {
+					BaseBranch: "main",
+					Dir:        t.TempDir(),
+					Backends:   map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}},
+				},
+			},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			prefs:   newTestPrefs(t),
+		}
+		handler := handle(s.createTask)
+
+		// Set docker image in user preferences.
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.BaseImage = "ghcr.io/my/image:v1"
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repos":[{"name":"myrepo"}],"harness":"claude"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var resp v1.CreateTaskResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.ID == 0 {
+			t.Error("response has zero 'id' field")
+		}
+
+		// Verify the task uses the image from preferences.
+		s.mu.Lock()
+		entry := s.tasks[resp.ID.String()]
+		s.mu.Unlock()
+		if entry == nil {
+			t.Fatal("task not found")
+		}
+		if entry.task.DockerImage != "ghcr.io/my/image:v1" {
+			t.Errorf("Image = %q, want %q", entry.task.DockerImage, "ghcr.io/my/image:v1")
+		}
+	})
+
+	t.Run("NoRepoTask", func(t *testing.T) {
+		// Regression: creating a task with no repos panicked with
+		// "makeslice: cap out of range" because len(req.Repos)-1 == -1.
+		s := &Server{
+			ctx: t.Context(),
+			runners: map[string]*task.Runner{
+				"": {
+					Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}},
+				},
+			},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			prefs:   newTestPrefs(t),
+		}
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"no repo task"},"harness":"claude"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+		}
+		var resp v1.CreateTaskResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.ID == 0 {
+			t.Error("response has zero 'id' field")
+		}
+	})
+
+	t.Run("NoRepoRunnerMissing", func(t *testing.T) {
+		// When no repos are specified and no "" runner is configured, return
+		// a clear error instead of panicking.
+		s := newTestServer(t) // no "" runner
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"no repo task"},"harness":"claude"}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("PlanOnlyUnsupported", func(t *testing.T) {
+		s := &Server{
+			ctx: t.Context(),
+			runners: map[string]*task.Runner{
+				"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}},
+			},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			prefs:   newTestPrefs(t),
+		}
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"plan it"},"harness":"claude","planOnly":true}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		if e := decodeError(t, w); !strings.Contains(e.Message, "plan-only") {
+			t.Errorf("message = %q, want it to mention plan-only", e.Message)
+		}
+	})
+
+	t.Run("ThinkingUnsupported", func(t *testing.T) {
+		s := &Server{
+			ctx: t.Context(),
+			runners: map[string]*task.Runner{
+				"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}},
+			},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			prefs:   newTestPrefs(t),
+		}
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"think"},"harness":"claude","thinking":true}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		if e := decodeError(t, w); !strings.Contains(e.Message, "thinking") {
+			t.Errorf("message = %q, want it to mention thinking", e.Message)
+		}
+	})
+
+	t.Run("UnknownField", func(t *testing.T) {
+		s := newTestServer(t)
+		handler := handle(s.createTask)
+
+		body := strings.NewReader(`{"initialPrompt":{"text":"test"},"repo":"r","harness":"claude","bogus":true}`)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/tasks", body)
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		e := decodeError(t, w)
+		if e.Code != dto.CodeBadRequest {
+			t.Errorf("code = %q, want %q", e.Code, dto.CodeBadRequest)
 		}
 	})
 }
@@ -677,61 +1810,293 @@ func TestLoadPurgedTasks(t *testing.T) {
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		if len(s.tasks) != 3 {
-			t.Fatalf("len(tasks) = %d, want 3", len(s.tasks))
+		if len(s.tasks) != 3 {
+			t.Fatalf("len(tasks) = %d, want 3", len(s.tasks))
+		}
+
+		// Collect prompts sorted by ksid (time-sortable) to verify all loaded.
+		prompts := make([]string, 0, len(s.tasks))
+		var anyEntry *taskEntry
+		for _, e := range s.tasks {
+			prompts = append(prompts, e.task.InitialPrompt.Text)
+			if anyEntry == nil {
+				anyEntry = e
+			}
+		}
+		sort.Strings(prompts)
+		if prompts[0] != "task 0" || prompts[1] != "task 1" || prompts[2] != "task 2" {
+			t.Errorf("prompts = %v, want [task 0, task 1, task 2]", prompts)
+		}
+
+		// Verify result is populated on at least one entry.
+		if anyEntry.result == nil {
+			t.Fatal("result is nil on a loaded entry")
+		}
+
+		// Verify done channel is closed (task is terminal).
+		for _, e := range s.tasks {
+			select {
+			case <-e.done:
+			default:
+				t.Error("done channel not closed on a loaded entry")
+			}
+		}
+	})
+
+	t.Run("TimeLimit", func(t *testing.T) {
+		logDir := t.TempDir()
+
+		// task 0: recent (purged)
+		meta0 := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1, Prompt: "recent task", Harness: agent.Claude, StartedAt: time.Now().Add(-1 * time.Hour),
+		})
+		trailer0 := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
+		writeLogFile(t, logDir, "recent.jsonl", meta0, trailer0)
+
+		// task 1: old (purged, > 14 days)
+		meta1 := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1, Prompt: "old task", Harness: agent.Claude, StartedAt: time.Now().Add(-20 * 24 * time.Hour),
+		})
+		trailer1 := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
+		oldPath := filepath.Join(logDir, "old.jsonl")
+		writeLogFile(t, logDir, "old.jsonl", meta1, trailer1)
+		// Set mtime to 15 days ago.
+		oldTime := time.Now().Add(-15 * 24 * time.Hour)
+		if err := os.Chtimes(oldPath, oldTime, oldTime); err != nil {
+			t.Fatal(err)
+		}
+
+		s := &Server{
+			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			logDir:  logDir,
+		}
+		if err := s.loadPurgedTasks(); err != nil {
+			t.Fatal(err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.tasks) != 1 {
+			t.Fatalf("len(tasks) = %d, want 1 (old task should be filtered out)", len(s.tasks))
+		}
+		for _, e := range s.tasks {
+			if e.task.InitialPrompt.Text != "recent task" {
+				t.Errorf("prompt = %q, want \"recent task\"", e.task.InitialPrompt.Text)
+			}
+		}
+	})
+
+	t.Run("PerRepoLimit", func(t *testing.T) {
+		logDir := t.TempDir()
+
+		// Write 7 tasks for repo "a" and 3 for repo "b".
+		for i := range 7 {
+			meta := mustJSON(t, agent.MetaMessage{
+				MessageType: "caic_meta", Version: 1, Prompt: fmt.Sprintf("a-%d", i),
+				Repos: []agent.MetaRepo{{Name: "a", Branch: fmt.Sprintf("caic-%d", i)}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, i, 0, 0, 0, time.UTC),
+			})
+			trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
+			writeLogFile(t, logDir, fmt.Sprintf("a-%d.jsonl", i), meta, trailer)
+		}
+		for i := range 3 {
+			meta := mustJSON(t, agent.MetaMessage{
+				MessageType: "caic_meta", Version: 1, Prompt: fmt.Sprintf("b-%d", i),
+				Repos: []agent.MetaRepo{{Name: "b", Branch: fmt.Sprintf("caic-%d", i)}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, i+10, 0, 0, 0, time.UTC),
+			})
+			trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
+			writeLogFile(t, logDir, fmt.Sprintf("b-%d.jsonl", i), meta, trailer)
+		}
+
+		s := &Server{
+			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			logDir:  logDir,
+		}
+		if err := s.loadPurgedTasks(); err != nil {
+			t.Fatal(err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// Count tasks per repo.
+		counts := map[string]int{}
+		for _, e := range s.tasks {
+			repo := ""
+			if p := e.task.Primary(); p != nil {
+				repo = p.Name
+			}
+			counts[repo]++
+		}
+		if counts["a"] != 5 {
+			t.Errorf("repo a: got %d tasks, want 5", counts["a"])
+		}
+		if counts["b"] != 3 {
+			t.Errorf("repo b: got %d tasks, want 3", counts["b"])
+		}
+		if len(s.tasks) != 8 {
+			t.Errorf("total tasks = %d, want 8 (5 from a + 3 from b)", len(s.tasks))
+		}
+	})
+
+	t.Run("CostInJSON", func(t *testing.T) {
+		logDir := t.TempDir()
+
+		meta := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1, Prompt: "fix bug",
+			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		initMsg := mustJSON(t, map[string]any{
+			"type": "system", "subtype": "init", "model": "claude-opus-4-6",
+			"claude_code_version": "2.0", "session_id": "s1",
+		})
+		result := mustJSON(t, agent.ResultMessage{
+			MessageType: "result", Subtype: "success", Result: "done",
+			TotalCostUSD: 1.23, Usage: agent.Usage{OutputTokens: 16400}, DurationMs: 5000, NumTurns: 3,
+		})
+		trailer := mustJSON(t, agent.MetaResultMessage{
+			MessageType: "caic_result", State: "purged",
+			CostUSD: 1.23, Duration: 5, NumTurns: 3,
+		})
+		writeLogFile(t, logDir, "task.jsonl", meta, initMsg, result, trailer)
+
+		s := &Server{
+			prefs:   newTestPrefs(t),
+			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			logDir:  logDir,
+		}
+		if err := s.loadPurgedTasks(); err != nil {
+			t.Fatal(err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.tasks) != 1 {
+			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
+		}
+		for _, e := range s.tasks {
+			j := s.toJSON(e)
+			if j.CostUSD != 1.23 {
+				t.Errorf("CostUSD = %f, want 1.23", j.CostUSD)
+			}
+			if j.Duration != 5 {
+				t.Errorf("Duration = %f, want 5", j.Duration)
+			}
+			if j.NumTurns != 3 {
+				t.Errorf("NumTurns = %d, want 3", j.NumTurns)
+			}
+			if j.Model != "claude-opus-4-6" {
+				t.Errorf("Model = %q, want %q", j.Model, "claude-opus-4-6")
+			}
+			if j.AgentVersion != "2.0" {
+				t.Errorf("AgentVersion = %q, want %q", j.AgentVersion, "2.0")
+			}
+		}
+	})
+
+	t.Run("ModelPriceOverrideNoAuth", func(t *testing.T) {
+		// Without auth, tasks have no owner and preferences live under "default".
+		s := newTestServer(t)
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.ModelPrices = map[string]pricing.Price{"custom-model": {Input: 1, Output: 2}}
+		}); err != nil {
+			t.Fatal(err)
+		}
+		tk := &task.Task{ID: ksid.NewID(), Harness: agent.Claude, Model: "custom-model"}
+		tk.RestoreMessages([]agent.Message{&agent.ResultMessage{
+			MessageType: "result", Subtype: "success", Usage: agent.Usage{InputTokens: 1_000_000, OutputTokens: 1_000_000},
+		}})
+		if j := s.toJSON(&taskEntry{task: tk, done: make(chan struct{})}); j.EstimatedCostUSD != 3 {
+			t.Errorf("EstimatedCostUSD = %f, want 3", j.EstimatedCostUSD)
+		}
+	})
+
+	t.Run("BackfillsCostFromMessages", func(t *testing.T) {
+		logDir := t.TempDir()
+
+		// Trailer has zero cost (e.g. session exited without final ResultMessage),
+		// but the messages contain a ResultMessage with cost.
+		meta := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1, Prompt: "fix bug",
+			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		initMsg := mustJSON(t, map[string]any{
+			"type": "system", "subtype": "init", "model": "claude-opus-4-6",
+			"claude_code_version": "2.0", "session_id": "s1",
+		})
+		result := mustJSON(t, agent.ResultMessage{
+			MessageType: "result", Subtype: "success", Result: "done",
+			TotalCostUSD: 0.42, Usage: agent.Usage{OutputTokens: 5600}, DurationMs: 3000, NumTurns: 2,
+		})
+		trailer := mustJSON(t, agent.MetaResultMessage{
+			MessageType: "caic_result", State: "purged",
+			// CostUSD intentionally zero.
+		})
+		writeLogFile(t, logDir, "task.jsonl", meta, initMsg, result, trailer)
+
+		s := &Server{
+			prefs:   newTestPrefs(t),
+			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			logDir:  logDir,
+		}
+		if err := s.loadPurgedTasks(); err != nil {
+			t.Fatal(err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if len(s.tasks) != 1 {
+			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
 		}
-
-		// Collect prompts sorted by ksid (time-sortable) to verify all loaded.
-		prompts := make([]string, 0, len(s.tasks))
-		var anyEntry *taskEntry
 		for _, e := range s.tasks {
-			prompts = append(prompts, e.task.InitialPrompt.Text)
-			if anyEntry == nil {
-				anyEntry = e
+			j := s.toJSON(e)
+			if j.CostUSD != 0.42 {
+				t.Errorf("CostUSD = %f, want 0.42 (should be backfilled from ResultMessage)", j.CostUSD)
 			}
-		}
-		sort.Strings(prompts)
-		if prompts[0] != "task 0" || prompts[1] != "task 1" || prompts[2] != "task 2" {
-			t.Errorf("prompts = %v, want [task 0, task 1, task 2]", prompts)
-		}
-
-		// Verify result is populated on at least one entry.
-		if anyEntry.result == nil {
-			t.Fatal("result is nil on a loaded entry")
-		}
-
-		// Verify done channel is closed (task is terminal).
-		for _, e := range s.tasks {
-			select {
-			case <-e.done:
-			default:
-				t.Error("done channel not closed on a loaded entry")
+			if j.NumTurns != 2 {
+				t.Errorf("NumTurns = %d, want 2", j.NumTurns)
+			}
+			if j.Duration != 3 {
+				t.Errorf("Duration = %f, want 3", j.Duration)
 			}
 		}
 	})
 
-	t.Run("TimeLimit", func(t *testing.T) {
+	t.Run("SameBranchDifferentRepos", func(t *testing.T) {
 		logDir := t.TempDir()
 
-		// task 0: recent (purged)
-		meta0 := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1, Prompt: "recent task", Harness: agent.Claude, StartedAt: time.Now().Add(-1 * time.Hour),
+		// Two logs from different repos share the same branch name.
+		// Each must retain its own title and prompt.
+		metaA := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1,
+			Prompt: "optimize genai provider", Repos: []agent.MetaRepo{{Name: "genai", Branch: "caic-0"}},
+			Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			Title: "Skip Unnecessary MD Container Build",
 		})
-		trailer0 := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
-		writeLogFile(t, logDir, "recent.jsonl", meta0, trailer0)
+		trailerA := mustJSON(t, agent.MetaResultMessage{
+			MessageType: "caic_result", State: "purged",
+			Title: "Optimize GenAI Provider",
+		})
+		writeLogFile(t, logDir, "a.jsonl", metaA, trailerA)
 
-		// task 1: old (purged, > 14 days)
-		meta1 := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1, Prompt: "old task", Harness: agent.Claude, StartedAt: time.Now().Add(-20 * 24 * time.Hour),
+		metaB := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1,
+			Prompt: "skip docker rebuilds", Repos: []agent.MetaRepo{{Name: "md", Branch: "caic-0"}},
+			Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC),
+			Title: "Skip Docker Rebuilds",
 		})
-		trailer1 := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
-		oldPath := filepath.Join(logDir, "old.jsonl")
-		writeLogFile(t, logDir, "old.jsonl", meta1, trailer1)
-		// Set mtime to 15 days ago.
-		oldTime := time.Now().Add(-15 * 24 * time.Hour)
-		if err := os.Chtimes(oldPath, oldTime, oldTime); err != nil {
-			t.Fatal(err)
-		}
+		trailerB := mustJSON(t, agent.MetaResultMessage{
+			MessageType: "caic_result", State: "purged",
+			Title: "Skip Unnecessary Docker Image Rebuilds",
+		})
+		writeLogFile(t, logDir, "b.jsonl", metaB, trailerB)
 
 		s := &Server{
 			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
@@ -745,36 +2110,87 @@ func TestLoadPurgedTasks(t *testing.T) {
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		if len(s.tasks) != 1 {
-			t.Fatalf("len(tasks) = %d, want 1 (old task should be filtered out)", len(s.tasks))
+		if len(s.tasks) != 2 {
+			t.Fatalf("len(tasks) = %d, want 2", len(s.tasks))
 		}
+
+		// Verify each task has the title matching its own repo.
 		for _, e := range s.tasks {
-			if e.task.InitialPrompt.Text != "recent task" {
-				t.Errorf("prompt = %q, want \"recent task\"", e.task.InitialPrompt.Text)
+			var repoName string
+			if p := e.task.Primary(); p != nil {
+				repoName = p.Name
+			}
+			switch repoName {
+			case "genai":
+				if got := e.task.Title(); got != "Optimize GenAI Provider" {
+					t.Errorf("genai title = %q, want %q", got, "Optimize GenAI Provider")
+				}
+			case "md":
+				if got := e.task.Title(); got != "Skip Unnecessary Docker Image Rebuilds" {
+					t.Errorf("md title = %q, want %q", got, "Skip Unnecessary Docker Image Rebuilds")
+				}
+			default:
+				t.Errorf("unexpected repo %q", repoName)
+			}
+		}
+
+		// Verify that branchIDs scoped by repo does not lose either entry.
+		// This mirrors the branchIDs construction in adoptContainers.
+		branchIDs := make(map[string][]string, len(s.tasks))
+		for id, e := range s.tasks {
+			if p := e.task.Primary(); p != nil && p.Branch != "" {
+				key := p.Name + "\x00" + p.Branch
+				branchIDs[key] = append(branchIDs[key], id)
 			}
 		}
+		if len(branchIDs) != 2 {
+			t.Errorf("branchIDs has %d keys, want 2 (repo-scoped keys must not collide)", len(branchIDs))
+		}
 	})
 
-	t.Run("PerRepoLimit", func(t *testing.T) {
+	t.Run("EmptyDir", func(t *testing.T) {
+		s := &Server{
+			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
+			tasks:   make(map[string]*taskEntry),
+			changed: make(chan struct{}),
+			logDir:  t.TempDir(),
+		}
+		if err := s.loadPurgedTasks(); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.tasks) != 0 {
+			t.Errorf("len(tasks) = %d, want 0", len(s.tasks))
+		}
+	})
+
+	t.Run("PROutsideTailWindow", func(t *testing.T) {
+		// caic_pr early in the file with >64 KiB of messages after it.
+		// The header-only tail scan cannot see caic_pr; loadPurgedTasks
+		// must still restore it on the Task via LoadMessages.
 		logDir := t.TempDir()
+		meta := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1, Prompt: "big pr task",
+			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude,
+			StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		prMsg := mustJSON(t, agent.MetaPRMessage{
+			MessageType: "caic_pr", ForgeOwner: "acme", ForgeRepo: "widget", ForgePR: 77,
+		})
 
-		// Write 7 tasks for repo "a" and 3 for repo "b".
-		for i := range 7 {
-			meta := mustJSON(t, agent.MetaMessage{
-				MessageType: "caic_meta", Version: 1, Prompt: fmt.Sprintf("a-%d", i),
-				Repos: []agent.MetaRepo{{Name: "a", Branch: fmt.Sprintf("caic-%d", i)}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, i, 0, 0, 0, time.UTC),
-			})
-			trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
-			writeLogFile(t, logDir, fmt.Sprintf("a-%d.jsonl", i), meta, trailer)
-		}
-		for i := range 3 {
-			meta := mustJSON(t, agent.MetaMessage{
-				MessageType: "caic_meta", Version: 1, Prompt: fmt.Sprintf("b-%d", i),
-				Repos: []agent.MetaRepo{{Name: "b", Branch: fmt.Sprintf("caic-%d", i)}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, i+10, 0, 0, 0, time.UTC),
-			})
-			trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
-			writeLogFile(t, logDir, fmt.Sprintf("b-%d.jsonl", i), meta, trailer)
+		// Build filler lines (assistant messages) totalling >64 KiB.
+		bigText := strings.Repeat("x", 1024)
+		filler := mustJSON(t, map[string]any{
+			"type":    "assistant",
+			"message": map[string]any{"content": []any{map[string]any{"type": "text", "text": bigText}}},
+		})
+		lines := make([]string, 0, 83) // meta + prMsg + 80 filler + trailer
+		lines = append(lines, meta, prMsg)
+		for range 80 { // 80 KiB of filler
+			lines = append(lines, filler)
 		}
+		trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
+		lines = append(lines, trailer)
+		writeLogFile(t, logDir, "task.jsonl", lines...)
 
 		s := &Server{
 			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
@@ -788,47 +2204,51 @@ func TestLoadPurgedTasks(t *testing.T) {
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
-
-		// Count tasks per repo.
-		counts := map[string]int{}
+		if len(s.tasks) != 1 {
+			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
+		}
 		for _, e := range s.tasks {
-			repo := ""
-			if p := e.task.Primary(); p != nil {
-				repo = p.Name
+			snap := e.task.Snapshot()
+			if snap.ForgePR != 77 {
+				t.Errorf("ForgePR = %d, want 77", snap.ForgePR)
+			}
+			if snap.ForgeOwner != "acme" {
+				t.Errorf("ForgeOwner = %q, want %q", snap.ForgeOwner, "acme")
+			}
+			if snap.ForgeRepo != "widget" {
+				t.Errorf("ForgeRepo = %q, want %q", snap.ForgeRepo, "widget")
 			}
-			counts[repo]++
-		}
-		if counts["a"] != 5 {
-			t.Errorf("repo a: got %d tasks, want 5", counts["a"])
-		}
-		if counts["b"] != 3 {
-			t.Errorf("repo b: got %d tasks, want 3", counts["b"])
-		}
-		if len(s.tasks) != 8 {
-			t.Errorf("total tasks = %d, want 8 (5 from a + 3 from b)", len(s.tasks))
 		}
 	})
 
-	t.Run("CostInJSON", func(t *testing.T) {
+	t.Run("PerRepoLimit", func(t *testing.T) {
 		logDir := t.TempDir()
 
-		meta := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1, Prompt: "fix bug",
-			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
-		})
-		initMsg := mustJSON(t, map[string]any{
-			"type": "system", "subtype": "init", "model": "claude-opus-4-6",
-			"claude_code_version": "2.0", "session_id": "s1",
-		})
-		result := mustJSON(t, agent.ResultMessage{
-			MessageType: "result", Subtype: "success", Result: "done",
-			TotalCostUSD: 1.23, Usage: agent.Usage{OutputTokens: 16400}, DurationMs: 5000, NumTurns: 3,
-		})
-		trailer := mustJSON(t, agent.MetaResultMessage{
-			MessageType: "caic_result", State: "purged",
-			CostUSD: 1.23, Duration: 5, NumTurns: 3,
-		})
-		writeLogFile(t, logDir, "task.jsonl", meta, initMsg, result, trailer)
+		// Write 6 tasks for repo-a and 6 for repo-b; expect only the 5 most
+		// recently active per repo (10 total) to be loaded.
+		// Use times relative to now so all tasks pass the 7-day filter.
+		// Task i has stoppedAt = now - (11-i)*hour so task 0 is oldest, task 11 newest.
+		now := time.Now().UTC()
+		for i := range 12 {
+			repoName := "repo-a"
+			if i >= 6 {
+				repoName = "repo-b"
+			}
+			stoppedAt := now.Add(-time.Duration(11-i) * time.Hour)
+			meta := mustJSON(t, agent.MetaMessage{
+				MessageType: "caic_meta", Version: 1,
+				Prompt:    fmt.Sprintf("task %d", i),
+				Repos:     []agent.MetaRepo{{Name: repoName, Branch: fmt.Sprintf("caic-%d", i)}},
+				Harness:   agent.Claude,
+				StartedAt: stoppedAt,
+			})
+			trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
+			name := fmt.Sprintf("%02d.jsonl", i)
+			writeLogFile(t, logDir, name, meta, trailer)
+			if err := os.Chtimes(filepath.Join(logDir, name), stoppedAt, stoppedAt); err != nil {
+				t.Fatal(err)
+			}
+		}
 
 		s := &Server{
 			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
@@ -842,51 +2262,53 @@ func TestLoadPurgedTasks(t *testing.T) {
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		if len(s.tasks) != 1 {
-			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
+		if len(s.tasks) != 10 {
+			t.Fatalf("len(tasks) = %d, want 10 (5 per repo)", len(s.tasks))
 		}
+
+		// Collect prompts and verify oldest task per repo was dropped.
+		prompts := make([]string, 0, len(s.tasks))
 		for _, e := range s.tasks {
-			j := s.toJSON(e)
-			if j.CostUSD != 1.23 {
-				t.Errorf("CostUSD = %f, want 1.23", j.CostUSD)
-			}
-			if j.Duration != 5 {
-				t.Errorf("Duration = %f, want 5", j.Duration)
-			}
-			if j.NumTurns != 3 {
-				t.Errorf("NumTurns = %d, want 3", j.NumTurns)
-			}
-			if j.Model != "claude-opus-4-6" {
-				t.Errorf("Model = %q, want %q", j.Model, "claude-opus-4-6")
-			}
-			if j.AgentVersion != "2.0" {
-				t.Errorf("AgentVersion = %q, want %q", j.AgentVersion, "2.0")
+			prompts = append(prompts, e.task.InitialPrompt.Text)
+		}
+		sort.Strings(prompts)
+		// "task 0" (oldest repo-a) and "task 6" (oldest repo-b) should be excluded.
+		for _, dropped := range []string{"task 0", "task 6"} {
+			for _, p := range prompts {
+				if p == dropped {
+					t.Errorf("prompt %q should have been dropped (exceeds per-repo limit)", dropped)
+				}
 			}
 		}
 	})
 
-	t.Run("BackfillsCostFromMessages", func(t *testing.T) {
+	t.Run("StateInference", func(t *testing.T) {
+		// Tasks without a caic_result trailer always load as "failed" —
+		// we cannot distinguish purged-without-trailer from interrupted.
+		// adoptContainers replaces stale entries with live state when a
+		// container is still running.
 		logDir := t.TempDir()
 
-		// Trailer has zero cost (e.g. session exited without final ResultMessage),
-		// but the messages contain a ResultMessage with cost.
-		meta := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1, Prompt: "fix bug",
-			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
-		})
-		initMsg := mustJSON(t, map[string]any{
-			"type": "system", "subtype": "init", "model": "claude-opus-4-6",
-			"claude_code_version": "2.0", "session_id": "s1",
-		})
-		result := mustJSON(t, agent.ResultMessage{
+		meta := func(prompt string) string {
+			return mustJSON(t, agent.MetaMessage{
+				MessageType: "caic_meta", Version: 1, Prompt: prompt, Harness: agent.Claude,
+				Repos:     []agent.MetaRepo{{Name: "r", Branch: "caic-0"}},
+				StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			})
+		}
+		resultMsg := mustJSON(t, agent.ResultMessage{
 			MessageType: "result", Subtype: "success", Result: "done",
-			TotalCostUSD: 0.42, Usage: agent.Usage{OutputTokens: 5600}, DurationMs: 3000, NumTurns: 2,
-		})
-		trailer := mustJSON(t, agent.MetaResultMessage{
-			MessageType: "caic_result", State: "purged",
-			// CostUSD intentionally zero.
 		})
-		writeLogFile(t, logDir, "task.jsonl", meta, initMsg, result, trailer)
+
+		// no trailer + ResultMessage present → "failed" (container may be gone)
+		writeLogFile(t, logDir, "waiting.jsonl", meta("waiting task"), resultMsg)
+
+		// no trailer + no ResultMessage → "failed"
+		writeLogFile(t, logDir, "interrupted.jsonl", meta("interrupted task"))
+
+		// explicit trailer → "purged"
+		trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
+		writeLogFile(t, logDir, "purged.jsonl", meta("purged task"), trailer)
 
 		s := &Server{
 			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
@@ -900,51 +2322,34 @@ func TestLoadPurgedTasks(t *testing.T) {
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		if len(s.tasks) != 1 {
-			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
+		if len(s.tasks) != 3 {
+			t.Fatalf("len(tasks) = %d, want 3", len(s.tasks))
 		}
+
+		states := make(map[string]string, len(s.tasks))
 		for _, e := range s.tasks {
-			j := s.toJSON(e)
-			if j.CostUSD != 0.42 {
-				t.Errorf("CostUSD = %f, want 0.42 (should be backfilled from ResultMessage)", j.CostUSD)
-			}
-			if j.NumTurns != 2 {
-				t.Errorf("NumTurns = %d, want 2", j.NumTurns)
-			}
-			if j.Duration != 3 {
-				t.Errorf("Duration = %f, want 3", j.Duration)
-			}
+			states[e.task.InitialPrompt.Text] = e.task.Snapshot().State.String()
+		}
+		if got := states["waiting task"]; got != "failed" {
+			t.Errorf("waiting task state = %q, want \"failed\"", got)
+		}
+		if got := states["interrupted task"]; got != "failed" {
+			t.Errorf("interrupted task state = %q, want \"failed\"", got)
+		}
+		if got := states["purged task"]; got != "purged" {
+			t.Errorf("purged task state = %q, want \"purged\"", got)
 		}
 	})
-
-	t.Run("SameBranchDifferentRepos", func(t *testing.T) {
+	t.Run("FeatureFlags", func(t *testing.T) {
 		logDir := t.TempDir()
-
-		// Two logs from different repos share the same branch name.
-		// Each must retain its own title and prompt.
-		metaA := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1,
-			Prompt: "optimize genai provider", Repos: []agent.MetaRepo{{Name: "genai", Branch: "caic-0"}},
-			Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
-			Title: "Skip Unnecessary MD Container Build",
-		})
-		trailerA := mustJSON(t, agent.MetaResultMessage{
-			MessageType: "caic_result", State: "purged",
-			Title: "Optimize GenAI Provider",
-		})
-		writeLogFile(t, logDir, "a.jsonl", metaA, trailerA)
-
-		metaB := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1,
-			Prompt: "skip docker rebuilds", Repos: []agent.MetaRepo{{Name: "md", Branch: "caic-0"}},
-			Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC),
-			Title: "Skip Docker Rebuilds",
-		})
-		trailerB := mustJSON(t, agent.MetaResultMessage{
-			MessageType: "caic_result", State: "purged",
-			Title: "Skip Unnecessary Docker Image Rebuilds",
+		meta := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1, Prompt: "feat task",
+			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude,
+			StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			Tailscale: true, USB: true, Display: true,
 		})
-		writeLogFile(t, logDir, "b.jsonl", metaB, trailerB)
+		trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
+		writeLogFile(t, logDir, "feat.jsonl", meta, trailer)
 
 		s := &Server{
 			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
@@ -958,87 +2363,166 @@ func TestLoadPurgedTasks(t *testing.T) {
 
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		if len(s.tasks) != 2 {
-			t.Fatalf("len(tasks) = %d, want 2", len(s.tasks))
+		if len(s.tasks) != 1 {
+			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
 		}
-
-		// Verify each task has the title matching its own repo.
 		for _, e := range s.tasks {
-			var repoName string
-			if p := e.task.Primary(); p != nil {
-				repoName = p.Name
+			if !e.task.Tailscale {
+				t.Error("Tailscale = false, want true")
 			}
-			switch repoName {
-			case "genai":
-				if got := e.task.Title(); got != "Optimize GenAI Provider" {
-					t.Errorf("genai title = %q, want %q", got, "Optimize GenAI Provider")
-				}
-			case "md":
-				if got := e.task.Title(); got != "Skip Unnecessary Docker Image Rebuilds" {
-					t.Errorf("md title = %q, want %q", got, "Skip Unnecessary Docker Image Rebuilds")
-				}
-			default:
-				t.Errorf("unexpected repo %q", repoName)
+			if !e.task.USB {
+				t.Error("USB = false, want true")
+			}
+			if !e.task.Display {
+				t.Error("Display = false, want true")
 			}
 		}
+	})
+}
 
-		// Verify that branchIDs scoped by repo does not lose either entry.
-		// This mirrors the branchIDs construction in adoptContainers.
-		branchIDs := make(map[string][]string, len(s.tasks))
-		for id, e := range s.tasks {
-			if p := e.task.Primary(); p != nil && p.Branch != "" {
-				key := p.Name + "\x00" + p.Branch
-				branchIDs[key] = append(branchIDs[key], id)
+// parseSSEEvents extracts message-type SSE events from a response body.
+func parseSSEEvents(t *testing.T, body string) []v1.EventMessage {
+	var events []v1.EventMessage
+	eventType := "message"
+	for _, line := range strings.Split(body, "\n") {
+		if after, ok := strings.CutPrefix(line, "event: "); ok {
+			eventType = after
+			continue
+		}
+		after, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			if line == "" {
+				eventType = "message"
 			}
+			continue
 		}
-		if len(branchIDs) != 2 {
-			t.Errorf("branchIDs has %d keys, want 2 (repo-scoped keys must not collide)", len(branchIDs))
+		if eventType != "message" {
+			continue
 		}
-	})
+		var ev v1.EventMessage
+		if err := json.Unmarshal([]byte(after), &ev); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		events = append(events, ev)
+	}
+	return events
+}
 
-	t.Run("EmptyDir", func(t *testing.T) {
-		s := &Server{
-			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-			logDir:  t.TempDir(),
+func TestComputeTaskPatch(t *testing.T) {
+	t.Run("ChangedFields", func(t *testing.T) {
+		old := `{"id":"abc","state":"running","costUSD":0.0}`
+		new_ := `{"id":"abc","state":"waiting","costUSD":1.5}`
+		patch, err := computeTaskPatch([]byte(old), []byte(new_))
+		if err != nil {
+			t.Fatal(err)
 		}
-		if err := s.loadPurgedTasks(); err != nil {
+		if string(patch["id"]) != `"abc"` {
+			t.Errorf("id = %s, want \"abc\"", patch["id"])
+		}
+		if string(patch["state"]) != `"waiting"` {
+			t.Errorf("state = %s, want \"waiting\"", patch["state"])
+		}
+		if string(patch["costUSD"]) != `1.5` {
+			t.Errorf("costUSD = %s, want 1.5", patch["costUSD"])
+		}
+		// Unchanged field should not be in patch
+		if _, ok := patch["costUSD"]; !ok {
+			t.Error("costUSD should be in patch (changed from 0.0 to 1.5)")
+		}
+	})
+	t.Run("UnchangedFieldsOmitted", func(t *testing.T) {
+		old := `{"id":"abc","state":"running","repo":"myrepo"}`
+		new_ := `{"id":"abc","state":"waiting","repo":"myrepo"}`
+		patch, err := computeTaskPatch([]byte(old), []byte(new_))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := patch["repo"]; ok {
+			t.Error("repo should not be in patch (unchanged)")
+		}
+		if _, ok := patch["state"]; !ok {
+			t.Error("state should be in patch (changed)")
+		}
+	})
+	t.Run("RemovedFieldSetToNull", func(t *testing.T) {
+		old := `{"id":"abc","error":"boom"}`
+		new_ := `{"id":"abc"}`
+		patch, err := computeTaskPatch([]byte(old), []byte(new_))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(patch["error"]) != "null" {
+			t.Errorf("removed field error = %s, want null", patch["error"])
+		}
+	})
+	t.Run("AlwaysIncludesID", func(t *testing.T) {
+		old := `{"id":"xyz","state":"running"}`
+		new_ := `{"id":"xyz","state":"purged"}`
+		patch, err := computeTaskPatch([]byte(old), []byte(new_))
+		if err != nil {
 			t.Fatal(err)
 		}
-		if len(s.tasks) != 0 {
-			t.Errorf("len(tasks) = %d, want 0", len(s.tasks))
+		if string(patch["id"]) != `"xyz"` {
+			t.Errorf("id = %s, want \"xyz\"", patch["id"])
 		}
 	})
+}
 
-	t.Run("PROutsideTailWindow", func(t *testing.T) {
-		// caic_pr early in the file with >64 KiB of messages after it.
-		// The header-only tail scan cannot see caic_pr; loadPurgedTasks
-		// must still restore it on the Task via LoadMessages.
+func TestCombinedSyncStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		statuses []string
+		want     string
+	}{
+		{"AllEmpty", []string{"empty", "empty"}, "empty"},
+		{"OneSynced", []string{"empty", "synced"}, "synced"},
+		{"FailedWinsOverSynced", []string{"synced", "failed"}, "failed"},
+		{"BlockedWinsOverAll", []string{"failed", "synced", "blocked"}, "blocked"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repos := make([]v1.RepoSyncResult, len(tc.statuses))
+			for i, st := range tc.statuses {
+				repos[i] = v1.RepoSyncResult{Name: fmt.Sprintf("repo%d", i), Status: st}
+			}
+			if got := combinedSyncStatus(repos); got != tc.want {
+				t.Errorf("combinedSyncStatus(%v) = %q, want %q", tc.statuses, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHandleTaskRawEvents(t *testing.T) {
+	t.Run("PurgedTaskEvents", func(t *testing.T) {
 		logDir := t.TempDir()
+
+		// Write a purged task log with real agent messages.
 		meta := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1, Prompt: "big pr task",
-			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude,
-			StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			MessageType: "caic_meta", Version: 1, Prompt: "fix the bug",
+			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
 		})
-		prMsg := mustJSON(t, agent.MetaPRMessage{
-			MessageType: "caic_pr", ForgeOwner: "acme", ForgeRepo: "widget", ForgePR: 77,
+		initMsg := mustJSON(t, map[string]any{
+			"type": "system", "subtype": "init", "model": "claude-opus-4-6",
+			"claude_code_version": "2.0", "session_id": "s1",
 		})
-
-		// Build filler lines (assistant messages) totalling >64 KiB.
-		bigText := strings.Repeat("x", 1024)
-		filler := mustJSON(t, map[string]any{
-			"type":    "assistant",
-			"message": map[string]any{"content": []any{map[string]any{"type": "text", "text": bigText}}},
+		assistant := mustJSON(t, map[string]any{
+			"type": "assistant",
+			"message": map[string]any{
+				"model": "claude-opus-4-6",
+				"content": []map[string]any{
+					{"type": "text", "text": "I found the bug"},
+				},
+				"usage": map[string]any{
+					"input_tokens": 100, "output_tokens": 50,
+				},
+			},
 		})
-		lines := make([]string, 0, 83) // meta + prMsg + 80 filler + trailer
-		lines = append(lines, meta, prMsg)
-		for range 80 { // 80 KiB of filler
-			lines = append(lines, filler)
-		}
-		trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
-		lines = append(lines, trailer)
-		writeLogFile(t, logDir, "task.jsonl", lines...)
+		result := mustJSON(t, agent.ResultMessage{
+			MessageType: "result", Subtype: "success", Result: "done", TotalCostUSD: 0.05, DurationMs: 1000, NumTurns: 1,
+		})
+		trailer := mustJSON(t, agent.MetaResultMessage{
+			MessageType: "caic_result", State: "purged", CostUSD: 0.05, Duration: 1,
+		})
+		writeLogFile(t, logDir, "task.jsonl", meta, initMsg, assistant, result, trailer)
 
 		s := &Server{
 			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
@@ -1051,52 +2535,90 @@ func TestLoadPurgedTasks(t *testing.T) {
 		}
 
 		s.mu.Lock()
-		defer s.mu.Unlock()
 		if len(s.tasks) != 1 {
 			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
 		}
-		for _, e := range s.tasks {
-			snap := e.task.Snapshot()
-			if snap.ForgePR != 77 {
-				t.Errorf("ForgePR = %d, want 77", snap.ForgePR)
-			}
-			if snap.ForgeOwner != "acme" {
-				t.Errorf("ForgeOwner = %q, want %q", snap.ForgeOwner, "acme")
-			}
-			if snap.ForgeRepo != "widget" {
-				t.Errorf("ForgeRepo = %q, want %q", snap.ForgeRepo, "widget")
+		var taskID string
+		for id := range s.tasks {
+			taskID = id
+		}
+		s.mu.Unlock()
+
+		// Subscribe to events via SSE. The handler should return immediately for
+		// purged tasks instead of blocking until context deadline.
+		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+taskID+"/raw_events", http.NoBody).WithContext(ctx)
+		req.SetPathValue("id", taskID)
+		w := httptest.NewRecorder()
+		start := time.Now()
+		s.handleTaskRawEvents(w, req)
+		elapsed := time.Since(start)
+		if elapsed > 200*time.Millisecond {
+			t.Errorf("handleTaskRawEvents blocked for %v; purged tasks should return immediately after history replay", elapsed)
+		}
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		events := parseSSEEvents(t, w.Body.String())
+		if len(events) == 0 {
+			t.Fatal("no SSE events received for purged task with messages")
+		}
+
+		kinds := make([]v1.EventKind, len(events))
+		for i, ev := range events {
+			kinds[i] = ev.Kind
+		}
+		wantKinds := []v1.EventKind{v1.EventKindInit, v1.EventKindText, v1.EventKindUsage, v1.EventKindResult}
+		if len(kinds) != len(wantKinds) {
+			t.Fatalf("event kinds = %v, want %v", kinds, wantKinds)
+		}
+		for i := range wantKinds {
+			if kinds[i] != wantKinds[i] {
+				t.Errorf("kinds[%d] = %q, want %q", i, kinds[i], wantKinds[i])
 			}
 		}
+		if events[1].Text == nil || events[1].Text.Text != "I found the bug" {
+			t.Errorf("text event = %+v, want text 'I found the bug'", events[1].Text)
+		}
 	})
 
-	t.Run("PerRepoLimit", func(t *testing.T) {
+	t.Run("StreamEventTextDelta", func(t *testing.T) {
 		logDir := t.TempDir()
 
-		// Write 6 tasks for repo-a and 6 for repo-b; expect only the 5 most
-		// recently active per repo (10 total) to be loaded.
-		// Use times relative to now so all tasks pass the 7-day filter.
-		// Task i has stoppedAt = now - (11-i)*hour so task 0 is oldest, task 11 newest.
-		now := time.Now().UTC()
-		for i := range 12 {
-			repoName := "repo-a"
-			if i >= 6 {
-				repoName = "repo-b"
-			}
-			stoppedAt := now.Add(-time.Duration(11-i) * time.Hour)
-			meta := mustJSON(t, agent.MetaMessage{
-				MessageType: "caic_meta", Version: 1,
-				Prompt:    fmt.Sprintf("task %d", i),
-				Repos:     []agent.MetaRepo{{Name: repoName, Branch: fmt.Sprintf("caic-%d", i)}},
-				Harness:   agent.Claude,
-				StartedAt: stoppedAt,
-			})
-			trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
-			name := fmt.Sprintf("%02d.jsonl", i)
-			writeLogFile(t, logDir, name, meta, trailer)
-			if err := os.Chtimes(filepath.Join(logDir, name), stoppedAt, stoppedAt); err != nil {
-				t.Fatal(err)
-			}
-		}
+		// Write a purged task log with stream events (text deltas) followed
+		// by the final assistant message, simulating --include-partial-messages output.
+		meta := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1, Prompt: "explain streaming",
+			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+		initMsg := mustJSON(t, map[string]any{
+			"type": "system", "subtype": "init", "model": "claude-opus-4-6",
+			"claude_code_version": "2.0", "session_id": "s1",
+		})
+		delta1 := `{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello "}}}`
+		delta2 := `{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"world"}}}`
+		msgStart := `{"type":"stream_event","event":{"type":"message_start"}}`
+		assistant := mustJSON(t, map[string]any{
+			"type": "assistant",
+			"message": map[string]any{
+				"model": "claude-opus-4-6",
+				"content": []map[string]any{
+					{"type": "text", "text": "Hello world"},
+				},
+				"usage": map[string]any{
+					"input_tokens": 50, "output_tokens": 20,
+				},
+			},
+		})
+		result := mustJSON(t, agent.ResultMessage{
+			MessageType: "result", Subtype: "success", Result: "done", TotalCostUSD: 0.02, DurationMs: 200, NumTurns: 1,
+		})
+		trailer := mustJSON(t, agent.MetaResultMessage{
+			MessageType: "caic_result", State: "purged", CostUSD: 0.02, Duration: 0.2,
+		})
+		writeLogFile(t, logDir, "task.jsonl", meta, initMsg, msgStart, delta1, delta2, assistant, result, trailer)
 
 		s := &Server{
 			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
@@ -1109,516 +2631,1143 @@ func TestLoadPurgedTasks(t *testing.T) {
 		}
 
 		s.mu.Lock()
-		defer s.mu.Unlock()
-		if len(s.tasks) != 10 {
-			t.Fatalf("len(tasks) = %d, want 10 (5 per repo)", len(s.tasks))
-		}
-
-		// Collect prompts and verify oldest task per repo was dropped.
-		prompts := make([]string, 0, len(s.tasks))
-		for _, e := range s.tasks {
-			prompts = append(prompts, e.task.InitialPrompt.Text)
+		if len(s.tasks) != 1 {
+			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
 		}
-		sort.Strings(prompts)
-		// "task 0" (oldest repo-a) and "task 6" (oldest repo-b) should be excluded.
-		for _, dropped := range []string{"task 0", "task 6"} {
-			for _, p := range prompts {
-				if p == dropped {
-					t.Errorf("prompt %q should have been dropped (exceeds per-repo limit)", dropped)
-				}
-			}
+		var taskID string
+		for id := range s.tasks {
+			taskID = id
 		}
-	})
+		s.mu.Unlock()
 
-	t.Run("StateInference", func(t *testing.T) {
-		// Tasks without a caic_result trailer always load as "failed" —
-		// we cannot distinguish purged-without-trailer from interrupted.
-		// adoptContainers replaces stale entries with live state when a
-		// container is still running.
-		logDir := t.TempDir()
+		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+taskID+"/raw_events", http.NoBody).WithContext(ctx)
+		req.SetPathValue("id", taskID)
+		w := httptest.NewRecorder()
+		s.handleTaskRawEvents(w, req)
 
-		meta := func(prompt string) string {
-			return mustJSON(t, agent.MetaMessage{
-				MessageType: "caic_meta", Version: 1, Prompt: prompt, Harness: agent.Claude,
-				Repos:     []agent.MetaRepo{{Name: "r", Branch: "caic-0"}},
-				StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
-			})
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
 		}
-		resultMsg := mustJSON(t, agent.ResultMessage{
-			MessageType: "result", Subtype: "success", Result: "done",
-		})
-
-		// no trailer + ResultMessage present → "failed" (container may be gone)
-		writeLogFile(t, logDir, "waiting.jsonl", meta("waiting task"), resultMsg)
 
-		// no trailer + no ResultMessage → "failed"
-		writeLogFile(t, logDir, "interrupted.jsonl", meta("interrupted task"))
-
-		// explicit trailer → "purged"
-		trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
-		writeLogFile(t, logDir, "purged.jsonl", meta("purged task"), trailer)
+		events := parseSSEEvents(t, w.Body.String())
+		kinds := make([]v1.EventKind, len(events))
+		for i, ev := range events {
+			kinds[i] = ev.Kind
+		}
+		// Expect: init + text + usage + result. The two textDelta messages are
+		// filtered by filterHistoryForReplay because a final TextMessage follows.
+		wantKinds := []v1.EventKind{v1.EventKindInit, v1.EventKindText, v1.EventKindUsage, v1.EventKindResult}
+		if len(kinds) != len(wantKinds) {
+			t.Fatalf("event kinds = %v, want %v", kinds, wantKinds)
+		}
+		for i := range wantKinds {
+			if kinds[i] != wantKinds[i] {
+				t.Errorf("kinds[%d] = %q, want %q", i, kinds[i], wantKinds[i])
+			}
+		}
+		if events[1].Text == nil || events[1].Text.Text != "Hello world" {
+			t.Errorf("text event = %+v, want text 'Hello world'", events[1].Text)
+		}
+	})
+}
 
-		s := &Server{
-			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-			logDir:  logDir,
+func TestHandleTaskMessages(t *testing.T) {
+	s := newTestServer(t)
+	tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
+	msgs := make([]agent.Message, 5)
+	for i := range msgs {
+		msgs[i] = &agent.ResultMessage{MessageType: "result", Result: strconv.Itoa(i)}
+	}
+	tk.RestoreMessages(msgs)
+	tk.SetStateAt(task.StatePurged, time.Now())
+	s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+	get := func(h http.HandlerFunc, target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req.SetPathValue("id", "t1")
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w
+	}
+	t.Run("Page", func(t *testing.T) {
+		w := get(s.handleGetTaskMessages, "/api/v1/tasks/t1/messages?after=1&limit=2")
+		var resp v1.TaskMessagesResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
 		}
-		if err := s.loadPurgedTasks(); err != nil {
+		if resp.Next != 3 || resp.Total != 5 || len(resp.Events) != 2 {
+			t.Fatalf("next = %d, total = %d, events = %d; want 3, 5, 2", resp.Next, resp.Total, len(resp.Events))
+		}
+		if got := resp.Events[0].Result.Result; got != "1" {
+			t.Errorf("first result = %q, want %q", got, "1")
+		}
+	})
+	t.Run("PastEnd", func(t *testing.T) {
+		w := get(s.handleGetTaskMessages, "/api/v1/tasks/t1/messages?after=9")
+		var resp v1.TaskMessagesResp
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
 			t.Fatal(err)
 		}
-
-		s.mu.Lock()
-		defer s.mu.Unlock()
-		if len(s.tasks) != 3 {
-			t.Fatalf("len(tasks) = %d, want 3", len(s.tasks))
+		if resp.Next != 5 || len(resp.Events) != 0 {
+			t.Errorf("next = %d, events = %d; want 5, 0", resp.Next, len(resp.Events))
 		}
-
-		states := make(map[string]string, len(s.tasks))
-		for _, e := range s.tasks {
-			states[e.task.InitialPrompt.Text] = e.task.Snapshot().State.String()
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		for _, q := range []string{"?after=-1", "?limit=0", "?limit=x"} {
+			if w := get(s.handleGetTaskMessages, "/api/v1/tasks/t1/messages"+q); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want %d", q, w.Code, http.StatusBadRequest)
+			}
 		}
-		if got := states["waiting task"]; got != "failed" {
-			t.Errorf("waiting task state = %q, want \"failed\"", got)
+	})
+	t.Run("EventsFrom", func(t *testing.T) {
+		body := get(s.handleTaskEvents, "/api/v1/tasks/t1/events?from=-2").Body.String()
+		if n := strings.Count(body, `"kind":"result"`); n != 2 {
+			t.Errorf("replayed %d results, want 2", n)
 		}
-		if got := states["interrupted task"]; got != "failed" {
-			t.Errorf("interrupted task state = %q, want \"failed\"", got)
+		if !strings.Contains(body, `data: {"from":3,"total":5}`) {
+			t.Errorf("ready event missing from/total:\n%s", body)
 		}
-		if got := states["purged task"]; got != "purged" {
-			t.Errorf("purged task state = %q, want \"purged\"", got)
+	})
+	t.Run("EventsReplay", func(t *testing.T) {
+		body := get(s.handleTaskEvents, "/api/v1/tasks/t1/events?replay=realtime&speed=100").Body.String()
+		if n := strings.Count(body, `"kind":"result"`); n != 5 {
+			t.Errorf("replayed %d results, want 5", n)
+		}
+		for _, q := range []string{"?replay=fast", "?speed=2", "?replay=realtime&speed=0", "?replay=realtime&speed=101"} {
+			if w := get(s.handleTaskEvents, "/api/v1/tasks/t1/events"+q); w.Code != http.StatusBadRequest {
+				t.Errorf("%s: status = %d, want %d", q, w.Code, http.StatusBadRequest)
+			}
 		}
 	})
-	t.Run("FeatureFlags", func(t *testing.T) {
-		logDir := t.TempDir()
-		meta := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1, Prompt: "feat task",
-			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude,
-			StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
-			Tailscale: true, USB: true, Display: true,
-		})
-		trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
-		writeLogFile(t, logDir, "feat.jsonl", meta, trailer)
+	t.Run("EventsBatch", func(t *testing.T) {
+		body := get(s.handleTaskEvents, "/api/v1/tasks/t1/events?batch=20").Body.String()
+		if strings.Contains(body, "event: message") {
+			t.Errorf("batched stream has single events:\n%s", body)
+		}
+		if n := strings.Count(body, "event: batch"); n != 1 {
+			t.Errorf("got %d batches, want 1:\n%s", n, body)
+		}
+		if !strings.Contains(body, "\nid: 4\n") {
+			t.Errorf("batch id is not the last event's:\n%s", body)
+		}
+		if w := get(s.handleTaskEvents, "/api/v1/tasks/t1/events?batch=5000"); w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
 
-		s := &Server{
-			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-			logDir:  logDir,
+func TestHandleMessageContent(t *testing.T) {
+	s := newTestServer(t)
+	big := strings.Repeat("é", contentTruncateThreshold)
+	bin := strings.Repeat("\xff", contentTruncateThreshold+1)
+	tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
+	tk.RestoreMessages([]agent.Message{
+		&agent.TextMessage{Text: "short"},
+		&agent.TextMessage{Text: big},
+		&agent.ToolOutputDeltaMessage{ToolUseID: "tu1", Delta: bin},
+		&agent.ResultMessage{MessageType: "result"},
+	})
+	tk.SetStateAt(task.StatePurged, time.Now())
+	s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+	get := func(h http.HandlerFunc, target, index string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, http.NoBody)
+		req.SetPathValue("id", "t1")
+		req.SetPathValue("index", index)
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w
+	}
+	t.Run("Truncated", func(t *testing.T) {
+		var resp v1.TaskMessagesResp
+		if err := json.NewDecoder(get(s.handleGetTaskMessages, "/api/v1/tasks/t1/messages", "").Body).Decode(&resp); err != nil {
+			t.Fatal(err)
 		}
-		if err := s.loadPurgedTasks(); err != nil {
+		if resp.Events[0].ContentRef != nil {
+			t.Error("short text has a contentRef")
+		}
+		ev := resp.Events[1]
+		if ev.ContentRef == nil || *ev.ContentRef != (v1.ContentRef{Index: 1, Size: len(big)}) {
+			t.Fatalf("contentRef = %+v, want index 1 size %d", ev.ContentRef, len(big))
+		}
+		if n := len(ev.Text.Text); n > contentPreviewSize || !utf8.ValidString(ev.Text.Text) {
+			t.Errorf("preview is %d bytes, valid UTF-8 %t", n, utf8.ValidString(ev.Text.Text))
+		}
+		body := get(s.handleTaskEvents, "/api/v1/tasks/t1/events", "").Body.String()
+		if !strings.Contains(body, `"contentRef":{"index":2,`) {
+			t.Errorf("stream lacks the tool output contentRef")
+		}
+	})
+	t.Run("Content", func(t *testing.T) {
+		var resp v1.MessageContentResp
+		if err := json.NewDecoder(get(s.handleGetMessageContent, "/api/v1/tasks/t1/messages/1/content", "1").Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Content != big || resp.Encoding != "" {
+			t.Errorf("content is %d bytes with encoding %q, want %d bytes", len(resp.Content), resp.Encoding, len(big))
+		}
+	})
+	t.Run("Binary", func(t *testing.T) {
+		var resp v1.MessageContentResp
+		if err := json.NewDecoder(get(s.handleGetMessageContent, "/api/v1/tasks/t1/messages/2/content", "2").Body).Decode(&resp); err != nil {
 			t.Fatal(err)
 		}
+		if got, err := base64.StdEncoding.DecodeString(resp.Content); resp.Encoding != "base64" || err != nil || string(got) != bin {
+			t.Errorf("encoding = %q, err = %v", resp.Encoding, err)
+		}
+	})
+	t.Run("Errors", func(t *testing.T) {
+		for index, want := range map[string]int{"x": http.StatusBadRequest, "3": http.StatusBadRequest, "9": http.StatusNotFound} {
+			if w := get(s.handleGetMessageContent, "/api/v1/tasks/t1/messages/"+index+"/content", index); w.Code != want {
+				t.Errorf("index %s: status = %d, want %d", index, w.Code, want)
+			}
+		}
+	})
+}
 
-		s.mu.Lock()
-		defer s.mu.Unlock()
-		if len(s.tasks) != 1 {
-			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
+func TestTaskAlias(t *testing.T) {
+	logDir := t.TempDir()
+	oldID := ksid.NewID().String()
+	meta := mustJSON(t, agent.MetaMessage{
+		MessageType: "caic_meta", Version: 1, TaskID: oldID, Alias: "w7", Prompt: "old", Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	trailer := mustJSON(t, agent.MetaResultMessage{MessageType: "caic_result", State: "purged"})
+	writeLogFile(t, logDir, "legacy.jsonl", meta, trailer)
+	s := newTestServer(t)
+	s.runners = map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}}
+	s.logDir = logDir
+	if err := s.loadPurgedTasks(); err != nil {
+		t.Fatal(err)
+	}
+	entry := &taskEntry{task: &task.Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "new"}}, done: make(chan struct{})}
+	s.mu.Lock()
+	s.addNewTask(entry)
+	s.mu.Unlock()
+	t.Run("Assigned", func(t *testing.T) {
+		if entry.task.Alias != "w8" {
+			t.Errorf("alias = %q, want %q", entry.task.Alias, "w8")
+		}
+		old, ok := s.tasks[oldID]
+		if !ok || old.task.Alias != "w7" {
+			t.Fatalf("task from log not restored under its header ID with its alias")
 		}
-		for _, e := range s.tasks {
-			if !e.task.Tailscale {
-				t.Error("Tailscale = false, want true")
+	})
+	t.Run("Resolve", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/w8", http.NoBody)
+		req.SetPathValue("id", "w8")
+		got, err := s.getTask(req)
+		if err != nil || got != entry {
+			t.Errorf("getTask(w8) = %v, %v", got, err)
+		}
+	})
+	t.Run("Redirect", func(t *testing.T) {
+		static := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusTeapot) }
+		for ref, want := range map[string]string{"w7": "/task/@" + oldID, entry.task.ID.String(): "/task/@" + entry.task.ID.String(), "@w7": "", "w99": ""} {
+			req := httptest.NewRequest(http.MethodGet, "/task/"+ref, http.NoBody)
+			req.SetPathValue("ref", ref)
+			w := httptest.NewRecorder()
+			s.handleTaskLink(static)(w, req)
+			if got := w.Header().Get("Location"); got != want {
+				t.Errorf("%s: Location = %q, want %q", ref, got, want)
 			}
-			if !e.task.USB {
-				t.Error("USB = false, want true")
+		}
+	})
+}
+
+func TestParseAdoptLabels(t *testing.T) {
+	s := &Server{repos: []repoInfo{{RelPath: "org/caic", AbsPath: "/src/org/caic"}}}
+	id := ksid.NewID()
+	tests := []struct {
+		name   string
+		c      md.Container
+		labels map[string]string
+		want   adoptRef
+		ok     bool
+	}{
+		{
+			name: "Labels",
+			// The branch contains the repo name, which fooled the old
+			// container name parsing.
+			c:      md.Container{Name: "md-caic-caic-caic-3"},
+			labels: map[string]string{"caic": id.String(), "harness": "codex", "caic.repo": "org/caic", "caic.branch": "caic-caic-3"},
+			want:   adoptRef{taskID: id, harness: agent.Codex, repo: "org/caic", branch: "caic-caic-3"},
+			ok:     true,
+		},
+		{
+			name:   "MDReposFallback",
+			c:      md.Container{Name: "md-caic-fork-1", Repos: []md.Repo{{GitRoot: "/src/org/caic", Branch: "fork-1"}}},
+			labels: map[string]string{"caic": id.String()},
+			want:   adoptRef{taskID: id, repo: "org/caic", branch: "fork-1"},
+			ok:     true,
+		},
+		{
+			name:   "NoRepo",
+			c:      md.Container{Name: "md-agent-1f"},
+			labels: map[string]string{"caic": id.String(), "harness": "claude"},
+			want:   adoptRef{taskID: id, harness: agent.Claude},
+			ok:     true,
+		},
+		{
+			name:   "NotCaic",
+			c:      md.Container{Name: "md-caic-main"},
+			labels: map[string]string{"md.display": "0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := s.parseAdoptLabels(&tt.c, tt.labels)
+			if err != nil {
+				t.Fatal(err)
 			}
-			if !e.task.Display {
-				t.Error("Display = false, want true")
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("got %+v, %t; want %+v, %t", got, ok, tt.want, tt.ok)
 			}
+		})
+	}
+	t.Run("BadID", func(t *testing.T) {
+		if _, _, err := s.parseAdoptLabels(&md.Container{Name: "md-x"}, map[string]string{"caic": "!"}); err == nil {
+			t.Error("expected error")
 		}
 	})
 }
 
-// parseSSEEvents extracts message-type SSE events from a response body.
-func parseSSEEvents(t *testing.T, body string) []v1.EventMessage {
-	var events []v1.EventMessage
-	eventType := "message"
-	for _, line := range strings.Split(body, "\n") {
-		if after, ok := strings.CutPrefix(line, "event: "); ok {
-			eventType = after
-			continue
+func TestDiskUsage(t *testing.T) {
+	logDir := t.TempDir()
+	id := ksid.NewID()
+	if err := os.WriteFile(filepath.Join(logDir, id.String()+"-org-repo-caic-1.jsonl"), make([]byte, 100), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(logDir, "notes.txt"), make([]byte, 7), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	logs, err := logSizes(logDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int64{id.String(): 100}; !maps.Equal(logs, want) {
+		t.Fatalf("logSizes() = %v, want %v", logs, want)
+	}
+
+	s := newTestServer(t)
+	s.minFreeDisk = 10 << 30
+	entry := &taskEntry{task: &task.Task{ID: id, Container: "md-repo-caic-1"}, done: make(chan struct{})}
+	s.tasks[id.String()] = entry
+	t.Run("Measured", func(t *testing.T) {
+		s.mu.Lock()
+		s.setDiskUsageLocked(map[string]int64{"md-repo-caic-1": 5000}, logs, 20<<30, 100<<30)
+		s.mu.Unlock()
+		if entry.diskBytes != 5000 || entry.logBytes != 100 {
+			t.Errorf("entry = %d, %d; want 5000, 100", entry.diskBytes, entry.logBytes)
 		}
-		after, ok := strings.CutPrefix(line, "data: ")
-		if !ok {
-			if line == "" {
-				eventType = "message"
-			}
-			continue
+		want := v1.DiskUsage{ContainerBytes: 5000, LogBytes: 100, FreeBytes: 20 << 30, TotalBytes: 100 << 30, MinFreeBytes: 10 << 30}
+		if got := s.diskUsage(); got == nil || *got != want {
+			t.Errorf("diskUsage() = %+v, want %+v", got, want)
 		}
-		if eventType != "message" {
-			continue
+		if err := s.checkDiskQuota(); err != nil {
+			t.Errorf("checkDiskQuota() = %v", err)
 		}
-		var ev v1.EventMessage
-		if err := json.Unmarshal([]byte(after), &ev); err != nil {
-			t.Fatalf("unmarshal event: %v", err)
+	})
+	t.Run("Unmeasured", func(t *testing.T) {
+		// Failed measurements keep the previous values.
+		s.mu.Lock()
+		s.setDiskUsageLocked(nil, nil, 0, 0)
+		s.mu.Unlock()
+		if entry.diskBytes != 5000 || entry.logBytes != 100 || s.disk.FreeBytes != 20<<30 {
+			t.Errorf("entry = %d, %d, free %d; want previous values", entry.diskBytes, entry.logBytes, s.disk.FreeBytes)
 		}
-		events = append(events, ev)
+	})
+	t.Run("Low", func(t *testing.T) {
+		s.mu.Lock()
+		s.setDiskUsageLocked(map[string]int64{}, logs, 1<<30, 100<<30)
+		s.mu.Unlock()
+		if entry.diskBytes != 0 {
+			t.Errorf("diskBytes = %d, want 0 for a removed container", entry.diskBytes)
+		}
+		var apiErr *dto.APIError
+		if err := s.checkDiskQuota(); !errors.As(err, &apiErr) || apiErr.StatusCode() != http.StatusInsufficientStorage {
+			t.Fatalf("checkDiskQuota() = %v, want 507", err)
+		}
+		if _, err := s.startTask(t.Context(), &v1.CreateTaskReq{}, "", nil); !errors.As(err, &apiErr) || apiErr.StatusCode() != http.StatusInsufficientStorage {
+			t.Errorf("startTask() = %v, want 507", err)
+		}
+	})
+	t.Run("FormatBytes", func(t *testing.T) {
+		for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 20 << 30: "20.0 GiB"} {
+			if got := formatBytes(n); got != want {
+				t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+			}
+		}
+	})
+}
+
+func TestOrphanTracker(t *testing.T) {
+	now := time.Now()
+	o := &orphanTracker{grace: 10 * time.Minute}
+	if due := o.observe([]string{"a", "b"}, now); len(due) != 0 {
+		t.Fatalf("first pass: due = %v, want none", due)
+	}
+	// "b" got owned again; it must start over if it reappears.
+	if due := o.observe([]string{"a"}, now.Add(5*time.Minute)); len(due) != 0 {
+		t.Fatalf("within grace: due = %v, want none", due)
+	}
+	if due := o.observe([]string{"a", "b"}, now.Add(10*time.Minute)); !slices.Equal(due, []string{"a"}) {
+		t.Fatalf("after grace: due = %v, want [a]", due)
+	}
+	if _, ok := o.since["a"]; ok {
+		t.Error("due container still tracked")
+	}
+	if got := o.since["b"]; !got.Equal(now.Add(10 * time.Minute)) {
+		t.Errorf("b since = %v, want reset", got)
 	}
-	return events
 }
 
-func TestComputeTaskPatch(t *testing.T) {
-	t.Run("ChangedFields", func(t *testing.T) {
-		old := `{"id":"abc","state":"running","costUSD":0.0}`
-		new_ := `{"id":"abc","state":"waiting","costUSD":1.5}`
-		patch, err := computeTaskPatch([]byte(old), []byte(new_))
-		if err != nil {
-			t.Fatal(err)
+func TestConfigValidate(t *testing.T) {
+	t.Run("both empty is valid", func(t *testing.T) {
+		if err := (&Config{}).Validate(); err != nil {
+			t.Fatalf("Validate() unexpected error: %v", err)
 		}
-		if string(patch["id"]) != `"abc"` {
-			t.Errorf("id = %s, want \"abc\"", patch["id"])
+	})
+	t.Run("PAT only is valid", func(t *testing.T) {
+		c := &Config{GitHubToken: "ghp_abc", GitLabToken: "glpat-abc"}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("Validate() unexpected error: %v", err)
 		}
-		if string(patch["state"]) != `"waiting"` {
-			t.Errorf("state = %s, want \"waiting\"", patch["state"])
+	})
+	t.Run("OAuth with ExternalURL and allowlist is valid", func(t *testing.T) {
+		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", ExternalURL: "https://caic.example.com", GitHubOAuthAllowedUsers: "alice,bob"}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("Validate() unexpected error: %v", err)
 		}
-		if string(patch["costUSD"]) != `1.5` {
-			t.Errorf("costUSD = %s, want 1.5", patch["costUSD"])
+	})
+	t.Run("ExternalURL auto is valid", func(t *testing.T) {
+		c := &Config{ExternalURL: "auto"}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("Validate() unexpected error: %v", err)
+		}
+	})
+	t.Run("OAuth with ExternalURL auto is valid", func(t *testing.T) {
+		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", GitHubOAuthAllowedUsers: "alice", ExternalURL: "auto"}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("Validate() unexpected error: %v", err)
+		}
+	})
+	t.Run("OAuth without ExternalURL is invalid", func(t *testing.T) {
+		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", GitHubOAuthAllowedUsers: "alice"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
+		}
+	})
+	t.Run("GitHub OAuth without allowlist is invalid", func(t *testing.T) {
+		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", ExternalURL: "https://caic.example.com"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
+		}
+	})
+	t.Run("GitLab OAuth without allowlist is invalid", func(t *testing.T) {
+		c := &Config{GitLabOAuthClientID: "id", GitLabOAuthClientSecret: "sec", ExternalURL: "https://caic.example.com"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
+		}
+	})
+	t.Run("OAuth with http ExternalURL is invalid", func(t *testing.T) {
+		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", GitHubOAuthAllowedUsers: "alice", ExternalURL: "http://caic.example.com"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
+		}
+	})
+	t.Run("invalid ExternalURL is invalid", func(t *testing.T) {
+		c := &Config{ExternalURL: "not a url"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
+		}
+	})
+	t.Run("ExternalURL with subpath is invalid", func(t *testing.T) {
+		c := &Config{ExternalURL: "https://caic.example.com/sub"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
+		}
+	})
+	t.Run("ExternalURL with trailing slash is valid and stripped", func(t *testing.T) {
+		c := &Config{ExternalURL: "https://caic.example.com/"}
+		if err := c.Validate(); err != nil {
+			t.Fatalf("Validate() unexpected error: %v", err)
+		}
+		if c.ExternalURL != "https://caic.example.com" {
+			t.Fatalf("ExternalURL trailing slash not stripped: %q", c.ExternalURL)
+		}
+	})
+	t.Run("TLS cert without key is invalid", func(t *testing.T) {
+		if err := (&Config{TLSCertFile: "cert.pem"}).Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
+		}
+	})
+	t.Run("HTTP3 without TLS is invalid", func(t *testing.T) {
+		if err := (&Config{HTTP3: true}).Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
+		}
+	})
+	t.Run("invalid GitLabURL is invalid", func(t *testing.T) {
+		c := &Config{GitLabURL: "not a url"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
+		}
+	})
+	t.Run("GitLabURL with subpath is invalid", func(t *testing.T) {
+		c := &Config{GitLabURL: "https://gitlab.example.com/sub"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
 		}
-		// Unchanged field should not be in patch
-		if _, ok := patch["costUSD"]; !ok {
-			t.Error("costUSD should be in patch (changed from 0.0 to 1.5)")
+	})
+	t.Run("GitHub OAuth ID without secret is invalid", func(t *testing.T) {
+		c := &Config{GitHubOAuthClientID: "id"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
 		}
 	})
-	t.Run("UnchangedFieldsOmitted", func(t *testing.T) {
-		old := `{"id":"abc","state":"running","repo":"myrepo"}`
-		new_ := `{"id":"abc","state":"waiting","repo":"myrepo"}`
-		patch, err := computeTaskPatch([]byte(old), []byte(new_))
-		if err != nil {
-			t.Fatal(err)
+	t.Run("GitHub OAuth secret without ID is invalid", func(t *testing.T) {
+		c := &Config{GitHubOAuthClientSecret: "sec"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
 		}
-		if _, ok := patch["repo"]; ok {
-			t.Error("repo should not be in patch (unchanged)")
+	})
+	t.Run("unknown orphan policy is invalid", func(t *testing.T) {
+		c := &Config{OrphanPolicy: "delete"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
 		}
-		if _, ok := patch["state"]; !ok {
-			t.Error("state should be in patch (changed)")
+	})
+	t.Run("negative orphan grace is invalid", func(t *testing.T) {
+		c := &Config{OrphanPolicy: orphanKill, OrphanGrace: -time.Minute}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
 		}
 	})
-	t.Run("RemovedFieldSetToNull", func(t *testing.T) {
-		old := `{"id":"abc","error":"boom"}`
-		new_ := `{"id":"abc"}`
-		patch, err := computeTaskPatch([]byte(old), []byte(new_))
-		if err != nil {
-			t.Fatal(err)
+	t.Run("GitLab OAuth ID without secret is invalid", func(t *testing.T) {
+		c := &Config{GitLabOAuthClientID: "id"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
 		}
-		if string(patch["error"]) != "null" {
-			t.Errorf("removed field error = %s, want null", patch["error"])
+	})
+	t.Run("GitLab OAuth secret without ID is invalid", func(t *testing.T) {
+		c := &Config{GitLabOAuthClientSecret: "sec"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
 		}
 	})
-	t.Run("AlwaysIncludesID", func(t *testing.T) {
-		old := `{"id":"xyz","state":"running"}`
-		new_ := `{"id":"xyz","state":"purged"}`
-		patch, err := computeTaskPatch([]byte(old), []byte(new_))
-		if err != nil {
-			t.Fatal(err)
+	t.Run("GitHub PAT and OAuth together is invalid", func(t *testing.T) {
+		c := &Config{GitHubToken: "ghp_abc", GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", GitHubOAuthAllowedUsers: "alice", ExternalURL: "https://caic.example.com"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
 		}
-		if string(patch["id"]) != `"xyz"` {
-			t.Errorf("id = %s, want \"xyz\"", patch["id"])
+	})
+	t.Run("GitLab PAT and OAuth together is invalid", func(t *testing.T) {
+		c := &Config{GitLabToken: "glpat-abc", GitLabOAuthClientID: "id", GitLabOAuthClientSecret: "sec", GitLabOAuthAllowedUsers: "alice", ExternalURL: "https://caic.example.com"}
+		if err := c.Validate(); err == nil {
+			t.Fatal("Validate() expected error, got nil")
 		}
 	})
 }
 
-func TestHandleTaskRawEvents(t *testing.T) {
-	t.Run("PurgedTaskEvents", func(t *testing.T) {
-		logDir := t.TempDir()
-
-		// Write a purged task log with real agent messages.
-		meta := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1, Prompt: "fix the bug",
-			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
-		})
-		initMsg := mustJSON(t, map[string]any{
-			"type": "system", "subtype": "init", "model": "claude-opus-4-6",
-			"claude_code_version": "2.0", "session_id": "s1",
-		})
-		assistant := mustJSON(t, map[string]any{
-			"type": "assistant",
-			"message": map[string]any{
-				"model": "claude-opus-4-6",
-				"content": []map[string]any{
-					{"type": "text", "text": "I found the bug"},
-				},
-				"usage": map[string]any{
-					"input_tokens": 100, "output_tokens": 50,
-				},
-			},
-		})
-		result := mustJSON(t, agent.ResultMessage{
-			MessageType: "result", Subtype: "success", Result: "done", TotalCostUSD: 0.05, DurationMs: 1000, NumTurns: 1,
-		})
-		trailer := mustJSON(t, agent.MetaResultMessage{
-			MessageType: "caic_result", State: "purged", CostUSD: 0.05, Duration: 1,
-		})
-		writeLogFile(t, logDir, "task.jsonl", meta, initMsg, assistant, result, trailer)
-
-		s := &Server{
-			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-			logDir:  logDir,
+func TestDebugBundle(t *testing.T) {
+	s := newTestServer(t)
+	s.debugConfig = (&Config{GitHubToken: "ghp_secret", GitHubWebhookSecret: []byte("hook"), GitLabURL: "https://gitlab.example.com", EventBusURL: "redis://:buspass@redis:6379", OrphanGrace: time.Minute}).redacted()
+	s.logRing = logctx.NewRing(10)
+	_, _ = s.logRing.Write([]byte(`{"msg":"hello"}` + "\n"))
+	id := ksid.NewID()
+	s.tasks[id.String()] = &taskEntry{task: &task.Task{ID: id, InitialPrompt: agent.Prompt{Text: "secret prompt"}}, done: make(chan struct{})}
+	h, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/debugbundle", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
 		}
-		if err := s.loadPurgedTasks(); err != nil {
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
 			t.Fatal(err)
 		}
-
-		s.mu.Lock()
-		if len(s.tasks) != 1 {
-			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
+		files[f.Name] = string(data)
+	}
+	for _, name := range []string{"version.json", "config.json", "logs.jsonl", "goroutines.txt", "tasks.json", "containers.json"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
 		}
-		var taskID string
-		for id := range s.tasks {
-			taskID = id
+	}
+	for name, data := range files {
+		for _, secret := range []string{"ghp_secret", "aG9vaw", "secret prompt", "buspass"} {
+			if strings.Contains(data, secret) {
+				t.Errorf("%s leaks %q", name, secret)
+			}
 		}
-		s.mu.Unlock()
+	}
+	if !strings.Contains(files["config.json"], `"GitLabURL": "https://gitlab.example.com"`) || !strings.Contains(files["config.json"], `"OrphanGrace": "1m0s"`) ||
+		!strings.Contains(files["config.json"], `"EventBusURL": "redis://redacted@redis:6379"`) {
+		t.Errorf("config.json = %s", files["config.json"])
+	}
+	if !strings.Contains(files["logs.jsonl"], "hello") || !strings.Contains(files["tasks.json"], id.String()) {
+		t.Errorf("logs.jsonl = %q, tasks.json = %q", files["logs.jsonl"], files["tasks.json"])
+	}
+}
 
-		// Subscribe to events via SSE. The handler should return immediately for
-		// purged tasks instead of blocking until context deadline.
-		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
-		defer cancel()
-		req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+taskID+"/raw_events", http.NoBody).WithContext(ctx)
-		req.SetPathValue("id", taskID)
+func TestLogLevel(t *testing.T) {
+	s := newTestServer(t)
+	h, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/server/log-level", strings.NewReader(`{"level":"debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	if s.logLevel.Level() != slog.LevelDebug {
+		t.Errorf("level = %v, want debug", s.logLevel.Level())
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/server/log-level", http.NoBody))
+	var resp v1.LogLevelResp
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Level != "debug" {
+		t.Errorf("GET = %q, %v; want debug", w.Body.String(), err)
+	}
+	t.Run("Invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/server/log-level", strings.NewReader(`{"level":"verbose"}`))
+		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
-		start := time.Now()
-		s.handleTaskRawEvents(w, req)
-		elapsed := time.Since(start)
-		if elapsed > 200*time.Millisecond {
-			t.Errorf("handleTaskRawEvents blocked for %v; purged tasks should return immediately after history replay", elapsed)
-		}
-
-		if w.Code != http.StatusOK {
-			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
-		}
-		events := parseSSEEvents(t, w.Body.String())
-		if len(events) == 0 {
-			t.Fatal("no SSE events received for purged task with messages")
-		}
-
-		kinds := make([]v1.EventKind, len(events))
-		for i, ev := range events {
-			kinds[i] = ev.Kind
-		}
-		wantKinds := []v1.EventKind{v1.EventKindInit, v1.EventKindText, v1.EventKindUsage, v1.EventKindResult}
-		if len(kinds) != len(wantKinds) {
-			t.Fatalf("event kinds = %v, want %v", kinds, wantKinds)
-		}
-		for i := range wantKinds {
-			if kinds[i] != wantKinds[i] {
-				t.Errorf("kinds[%d] = %q, want %q", i, kinds[i], wantKinds[i])
-			}
-		}
-		if events[1].Text == nil || events[1].Text.Text != "I found the bug" {
-			t.Errorf("text event = %+v, want text 'I found the bug'", events[1].Text)
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
 		}
 	})
+}
 
-	t.Run("StreamEventTextDelta", func(t *testing.T) {
-		logDir := t.TempDir()
+func TestArchive(t *testing.T) {
+	s := newTestServer(t)
+	id := ksid.NewID()
+	s.tasks[id.String()] = &taskEntry{task: &task.Task{ID: id}, done: make(chan struct{})}
+	h, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/tasks/"+id.String(), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	list := func(query string) []v1.Task {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks"+query, http.NoBody))
+		var out []v1.Task
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatalf("list%s: status = %d: %s", query, w.Code, w.Body.String())
+		}
+		return out
+	}
 
-		// Write a purged task log with stream events (text deltas) followed
-		// by the final assistant message, simulating --include-partial-messages output.
-		meta := mustJSON(t, agent.MetaMessage{
-			MessageType: "caic_meta", Version: 1, Prompt: "explain streaming",
-			Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: agent.Claude, StartedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
-		})
-		initMsg := mustJSON(t, map[string]any{
-			"type": "system", "subtype": "init", "model": "claude-opus-4-6",
-			"claude_code_version": "2.0", "session_id": "s1",
-		})
-		delta1 := `{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello "}}}`
-		delta2 := `{"type":"stream_event","event":{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"world"}}}`
-		msgStart := `{"type":"stream_event","event":{"type":"message_start"}}`
-		assistant := mustJSON(t, map[string]any{
-			"type": "assistant",
-			"message": map[string]any{
-				"model": "claude-opus-4-6",
-				"content": []map[string]any{
-					{"type": "text", "text": "Hello world"},
-				},
-				"usage": map[string]any{
-					"input_tokens": 50, "output_tokens": 20,
-				},
-			},
-		})
-		result := mustJSON(t, agent.ResultMessage{
-			MessageType: "result", Subtype: "success", Result: "done", TotalCostUSD: 0.02, DurationMs: 200, NumTurns: 1,
-		})
-		trailer := mustJSON(t, agent.MetaResultMessage{
-			MessageType: "caic_result", State: "purged", CostUSD: 0.02, Duration: 0.2,
-		})
-		writeLogFile(t, logDir, "task.jsonl", meta, initMsg, msgStart, delta1, delta2, assistant, result, trailer)
+	w := patch(`{"archived":true}`)
+	var got v1.Task
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || !got.Archived {
+		t.Fatalf("PATCH = %d %s", w.Code, w.Body.String())
+	}
+	if l := list(""); len(l) != 0 {
+		t.Errorf("default list = %d tasks, want 0", len(l))
+	}
+	if l := list("?includeArchived=true"); len(l) != 1 || !l[0].Archived {
+		t.Errorf("includeArchived list = %+v", l)
+	}
+	archived, err := loadIDSet(s.archived.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !archived.has(id.String()) || len(archived.ids) != 1 {
+		t.Errorf("persisted = %v", archived.ids)
+	}
 
-		s := &Server{
-			runners: map[string]*task.Runner{"": {Backends: map[agent.Harness]agent.Backend{agent.Claude: stubBackend{}}}},
-			tasks:   make(map[string]*taskEntry),
-			changed: make(chan struct{}),
-			logDir:  logDir,
+	if w := patch(`{"archived":false}`); w.Code != http.StatusOK {
+		t.Fatalf("unarchive = %d %s", w.Code, w.Body.String())
+	}
+	if l := list(""); len(l) != 1 || l[0].Archived {
+		t.Errorf("list after unarchive = %+v", l)
+	}
+	t.Run("Invalid", func(t *testing.T) {
+		if w := patch(`{}`); w.Code != http.StatusBadRequest {
+			t.Errorf("empty PATCH = %d, want 400", w.Code)
 		}
-		if err := s.loadPurgedTasks(); err != nil {
-			t.Fatal(err)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks?includeArchived=maybe", http.NoBody))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("includeArchived=maybe = %d, want 400", w.Code)
 		}
+	})
+}
 
-		s.mu.Lock()
-		if len(s.tasks) != 1 {
-			t.Fatalf("len(tasks) = %d, want 1", len(s.tasks))
+func TestPin(t *testing.T) {
+	s := newTestServer(t)
+	ids := []ksid.ID{ksid.NewID(), ksid.NewID(), ksid.NewID()}
+	for _, id := range ids {
+		s.tasks[id.String()] = &taskEntry{task: &task.Task{ID: id}, done: make(chan struct{})}
+	}
+	h, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(id ksid.ID, action string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/tasks/"+id.String()+"/"+action, http.NoBody))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s = %d %s", action, id, w.Code, w.Body.String())
 		}
-		var taskID string
-		for id := range s.tasks {
-			taskID = id
+	}
+	list := func(query string) []ksid.ID {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks"+query, http.NoBody))
+		var out []v1.Task
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatalf("list%s: status = %d: %s", query, w.Code, w.Body.String())
 		}
-		s.mu.Unlock()
+		got := make([]ksid.ID, len(out))
+		for i := range out {
+			got[i] = out[i].ID
+		}
+		return got
+	}
+	slices.Sort(ids)
+	post(ids[2], "pin")
+	if got, want := list(""), []ksid.ID{ids[2], ids[0], ids[1]}; !slices.Equal(got, want) {
+		t.Errorf("list = %v, want %v", got, want)
+	}
+	if got := list("?pinned=true"); !slices.Equal(got, []ksid.ID{ids[2]}) {
+		t.Errorf("pinned list = %v", got)
+	}
+	pinned, err := loadIDSet(s.pinned.path)
+	if err != nil || !pinned.has(ids[2].String()) {
+		t.Errorf("persisted = %v, %v", pinned, err)
+	}
+	post(ids[2], "unpin")
+	if got := list(""); !slices.Equal(got, ids) {
+		t.Errorf("list after unpin = %v, want %v", got, ids)
+	}
+}
 
-		ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
-		defer cancel()
-		req := httptest.NewRequest(http.MethodGet, "/api/v1/tasks/"+taskID+"/raw_events", http.NoBody).WithContext(ctx)
-		req.SetPathValue("id", taskID)
+func TestGetUsageProject(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now().UTC()
+	for i, repo := range []string{"repo0", "repo1", "repo1"} {
+		tk := &task.Task{ID: ksid.NewID(), Repos: []task.RepoMount{{Name: repo}}, StartedAt: now}
+		s.tasks[tk.ID.String()] = &taskEntry{task: tk, done: make(chan struct{}), result: &task.Result{CostUSD: float64(i + 1), Usage: agent.Usage{OutputTokens: 10}}}
+	}
+	if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+		p.Settings.RepoProjects = map[string]string{"repo1": "product"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	get := func(query string) v1.ClaudeUsage {
 		w := httptest.NewRecorder()
-		s.handleTaskRawEvents(w, req)
-
-		if w.Code != http.StatusOK {
-			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		s.handleGetUsage(w, httptest.NewRequest(http.MethodGet, "/api/v1/usage"+query, http.NoBody))
+		var resp v1.UsageResp
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Claude == nil {
+			t.Fatalf("%s: status = %d: %s", query, w.Code, w.Body)
 		}
+		return *resp.Claude
+	}
+	for _, tc := range []struct {
+		name, query string
+		cost        float64
+		tokens      int
+	}{
+		{"All", "", 6, 30},
+		{"Project", "?project=product", 5, 20},
+		{"UnknownProject", "?project=other", 0, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := get(tc.query)
+			if got.SevenDay.CostUSD != tc.cost || got.FiveHour.OutputTokens != tc.tokens {
+				t.Errorf("7d cost = %v, 5h output tokens = %d, want %v, %d", got.SevenDay.CostUSD, got.FiveHour.OutputTokens, tc.cost, tc.tokens)
+			}
+		})
+	}
+}
 
-		events := parseSSEEvents(t, w.Body.String())
-		kinds := make([]v1.EventKind, len(events))
-		for i, ev := range events {
-			kinds[i] = ev.Kind
+func TestTaskFilter(t *testing.T) {
+	s := newTestServer(t)
+	ids := []ksid.ID{ksid.NewID(), ksid.NewID(), ksid.NewID()}
+	slices.Sort(ids)
+	for i, id := range ids {
+		tk := &task.Task{ID: id, Repos: []task.RepoMount{{Name: "repo" + strconv.Itoa(i%2)}}}
+		s.tasks[id.String()] = &taskEntry{task: tk, done: make(chan struct{})}
+	}
+	s.tasks[ids[0].String()].task.SetStateAt(task.StateFailed, time.Now().Add(-48*time.Hour))
+	s.tasks[ids[1].String()].task.SetState(task.StateWaiting)
+	s.tasks[ids[2].String()].task.SetStateAt(task.StateWaiting, time.Now().Add(-48*time.Hour))
+	if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+		p.Settings.RepoProjects = map[string]string{"repo1": "product"}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	h, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := func(query string) []ksid.ID {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks?"+query, http.NoBody))
+		var out []v1.Task
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatalf("list?%s: status = %d: %s", query, w.Code, w.Body.String())
 		}
-		// Expect: init + text + usage + result. The two textDelta messages are
-		// filtered by filterHistoryForReplay because a final TextMessage follows.
-		wantKinds := []v1.EventKind{v1.EventKindInit, v1.EventKindText, v1.EventKindUsage, v1.EventKindResult}
-		if len(kinds) != len(wantKinds) {
-			t.Fatalf("event kinds = %v, want %v", kinds, wantKinds)
+		got := make([]ksid.ID, len(out))
+		for i := range out {
+			got[i] = out[i].ID
 		}
-		for i := range wantKinds {
-			if kinds[i] != wantKinds[i] {
-				t.Errorf("kinds[%d] = %q, want %q", i, kinds[i], wantKinds[i])
+		return got
+	}
+	for _, tc := range []struct {
+		query string
+		want  []ksid.ID
+	}{
+		{"state=failed", []ksid.ID{ids[0]}},
+		{"state=failed,waiting&repo=repo0", []ksid.ID{ids[0], ids[2]}},
+		{"state=waiting&stateOlderThan=24h", []ksid.ID{ids[2]}},
+		{"createdWithin=1h&repo=repo1", []ksid.ID{ids[1]}},
+		{"project=product", []ksid.ID{ids[1]}},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			if got := list(tc.query); !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
 			}
-		}
-		if events[1].Text == nil || events[1].Text.Text != "Hello world" {
-			t.Errorf("text event = %+v, want text 'Hello world'", events[1].Text)
+		})
+	}
+	t.Run("Invalid", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks?state=done&createdWithin=week", http.NoBody))
+		var resp dto.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusBadRequest || len(resp.Error.Fields) != 2 {
+			t.Errorf("status = %d: %s", w.Code, w.Body.String())
 		}
 	})
 }
 
-func TestConfigValidate(t *testing.T) {
-	t.Run("both empty is valid", func(t *testing.T) {
-		if err := (&Config{}).Validate(); err != nil {
-			t.Fatalf("Validate() unexpected error: %v", err)
+func TestTaskViews(t *testing.T) {
+	s := newTestServer(t)
+	h, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	post := func(path, body string) (*v1.TaskViewsResp, int) {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		var resp v1.TaskViewsResp
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
 		}
-	})
-	t.Run("PAT only is valid", func(t *testing.T) {
-		c := &Config{GitHubToken: "ghp_abc", GitLabToken: "glpat-abc"}
-		if err := c.Validate(); err != nil {
-			t.Fatalf("Validate() unexpected error: %v", err)
+		return &resp, w.Code
+	}
+	post("/api/v1/views", `{"name":"failed","query":"state=failed&createdWithin=168h","default":true}`)
+	resp, code := post("/api/v1/views", `{"name":"stale","query":"state=waiting&stateOlderThan=24h","default":true}`)
+	want := []v1.TaskView{
+		{Name: "failed", Query: "state=failed&createdWithin=168h"},
+		{Name: "stale", Query: "state=waiting&stateOlderThan=24h", Default: true},
+	}
+	if code != http.StatusOK || !slices.Equal(resp.Views, want) {
+		t.Errorf("save = %d %+v, want %+v", code, resp.Views, want)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/views", http.NoBody))
+	var got v1.TaskViewsResp
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || !slices.Equal(got.Views, want) {
+		t.Errorf("list = %s, want %+v", w.Body.String(), want)
+	}
+	if views := s.prefs.Get("default").Views; len(views) != 2 {
+		t.Errorf("persisted = %+v", views)
+	}
+	if resp, code := post("/api/v1/views/delete", `{"name":"failed"}`); code != http.StatusOK || len(resp.Views) != 1 {
+		t.Errorf("delete = %d %+v", code, resp.Views)
+	}
+	t.Run("Invalid", func(t *testing.T) {
+		for _, body := range []string{
+			`{"name":"","query":"state=failed"}`,
+			`{"name":"x","query":"state=done"}`,
+			`{"name":"x","query":"fields=id"}`,
+			`{"name":"x","query":"sort=name"}`,
+		} {
+			if _, code := post("/api/v1/views", body); code != http.StatusBadRequest {
+				t.Errorf("%s = %d, want 400", body, code)
+			}
 		}
-	})
-	t.Run("OAuth with ExternalURL and allowlist is valid", func(t *testing.T) {
-		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", ExternalURL: "https://caic.example.com", GitHubOAuthAllowedUsers: "alice,bob"}
-		if err := c.Validate(); err != nil {
-			t.Fatalf("Validate() unexpected error: %v", err)
+		if _, code := post("/api/v1/views/delete", `{"name":"failed"}`); code != http.StatusNotFound {
+			t.Errorf("delete missing = %d, want 404", code)
 		}
 	})
-	t.Run("ExternalURL auto is valid", func(t *testing.T) {
-		c := &Config{ExternalURL: "auto"}
-		if err := c.Validate(); err != nil {
-			t.Fatalf("Validate() unexpected error: %v", err)
+}
+
+func TestTaskSort(t *testing.T) {
+	ids := []ksid.ID{ksid.NewID(), ksid.NewID(), ksid.NewID()}
+	slices.Sort(ids)
+	now := time.Now()
+	tasks := func() []v1.Task {
+		return []v1.Task{
+			{ID: ids[0], State: "waiting", StateUpdatedAt: now, CostUSD: 2, Duration: 10},
+			{ID: ids[1], State: "running", StateUpdatedAt: now.Add(time.Minute), CostUSD: 1, Duration: 30},
+			{ID: ids[2], State: "running", StateUpdatedAt: now.Add(-time.Minute), CostUSD: 3, Duration: 20},
 		}
-	})
-	t.Run("OAuth with ExternalURL auto is valid", func(t *testing.T) {
-		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", GitHubOAuthAllowedUsers: "alice", ExternalURL: "auto"}
-		if err := c.Validate(); err != nil {
-			t.Fatalf("Validate() unexpected error: %v", err)
+	}
+	for _, tc := range []struct {
+		query string
+		want  []ksid.ID
+	}{
+		{"", []ksid.ID{ids[0], ids[1], ids[2]}},
+		{"sort=created&order=desc", []ksid.ID{ids[2], ids[1], ids[0]}},
+		{"sort=updated", []ksid.ID{ids[2], ids[0], ids[1]}},
+		{"sort=cost&order=desc", []ksid.ID{ids[2], ids[0], ids[1]}},
+		{"sort=duration", []ksid.ID{ids[0], ids[2], ids[1]}},
+		{"sort=state", []ksid.ID{ids[1], ids[2], ids[0]}},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			order, err := parseTaskSort(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l := tasks()
+			order.apply(l)
+			got := make([]ksid.ID, len(l))
+			for i := range l {
+				got[i] = l[i].ID
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+	t.Run("PinnedFirst", func(t *testing.T) {
+		order, err := parseTaskSort(url.Values{"sort": {"cost"}})
+		if err != nil {
+			t.Fatal(err)
 		}
-	})
-	t.Run("OAuth without ExternalURL is invalid", func(t *testing.T) {
-		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", GitHubOAuthAllowedUsers: "alice"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+		l := tasks()
+		l[2].Pinned = true
+		order.apply(l)
+		if l[0].ID != ids[2] || l[1].ID != ids[1] {
+			t.Errorf("got %v, %v, want the pinned task first", l[0].ID, l[1].ID)
 		}
 	})
-	t.Run("GitHub OAuth without allowlist is invalid", func(t *testing.T) {
-		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", ExternalURL: "https://caic.example.com"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := parseTaskSort(url.Values{"sort": {"name"}, "order": {"up"}})
+		var apiErr *dto.APIError
+		if !errors.As(err, &apiErr) || len(apiErr.Fields()) != 2 {
+			t.Fatalf("err = %v, want 2 invalid fields", err)
 		}
-	})
-	t.Run("GitLab OAuth without allowlist is invalid", func(t *testing.T) {
-		c := &Config{GitLabOAuthClientID: "id", GitLabOAuthClientSecret: "sec", ExternalURL: "https://caic.example.com"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+		s := newTestServer(t)
+		h, err := s.buildHandler()
+		if err != nil {
+			t.Fatal(err)
 		}
-	})
-	t.Run("OAuth with http ExternalURL is invalid", func(t *testing.T) {
-		c := &Config{GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", GitHubOAuthAllowedUsers: "alice", ExternalURL: "http://caic.example.com"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tasks?sort=name", http.NoBody))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("sort=name = %d, want 400", w.Code)
 		}
 	})
-	t.Run("invalid ExternalURL is invalid", func(t *testing.T) {
-		c := &Config{ExternalURL: "not a url"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+}
+
+func TestAnnotations(t *testing.T) {
+	s := newTestServer(t)
+	id := ksid.NewID()
+	tk := &task.Task{ID: id, InitialPrompt: agent.Prompt{Text: "test"}}
+	msgs := make([]agent.Message, 4)
+	for i := range msgs {
+		msgs[i] = &agent.ResultMessage{MessageType: "result", Result: strconv.Itoa(i)}
+	}
+	tk.RestoreMessages(msgs)
+	tk.SetStateAt(task.StatePurged, time.Now())
+	s.tasks[id.String()] = &taskEntry{task: tk, done: make(chan struct{})}
+	h, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(method, "/api/v1/tasks/"+id.String()+path, strings.NewReader(body)))
+		return w
+	}
+	annotate := func(index int, body string) v1.Annotation {
+		w := do(http.MethodPost, "/messages/"+strconv.Itoa(index)+"/annotations", body)
+		var an v1.Annotation
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &an) != nil {
+			t.Fatalf("annotate %d = %d %s", index, w.Code, w.Body.String())
 		}
-	})
-	t.Run("ExternalURL with subpath is invalid", func(t *testing.T) {
-		c := &Config{ExternalURL: "https://caic.example.com/sub"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+		return an
+	}
+
+	second := annotate(2, `{"note":"went wrong here"}`)
+	first := annotate(1, `{"bookmark":true}`)
+	if second.Index != 2 || second.Note != "went wrong here" || second.Author != "default" || second.CreatedAt.IsZero() {
+		t.Errorf("annotation = %+v", second)
+	}
+	t.Run("Invalid", func(t *testing.T) {
+		for path, want := range map[string]int{
+			"/messages/9/annotations": http.StatusNotFound,
+			"/messages/x/annotations": http.StatusBadRequest,
+		} {
+			if w := do(http.MethodPost, path, `{"bookmark":true}`); w.Code != want {
+				t.Errorf("%s: status = %d, want %d", path, w.Code, want)
+			}
+		}
+		if w := do(http.MethodPost, "/messages/0/annotations", `{}`); w.Code != http.StatusBadRequest {
+			t.Errorf("empty annotation: status = %d, want 400", w.Code)
 		}
 	})
-	t.Run("ExternalURL with trailing slash is valid and stripped", func(t *testing.T) {
-		c := &Config{ExternalURL: "https://caic.example.com/"}
-		if err := c.Validate(); err != nil {
-			t.Fatalf("Validate() unexpected error: %v", err)
+	t.Run("List", func(t *testing.T) {
+		var got []v1.Annotation
+		if err := json.Unmarshal(do(http.MethodGet, "/annotations", "").Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
 		}
-		if c.ExternalURL != "https://caic.example.com" {
-			t.Fatalf("ExternalURL trailing slash not stripped: %q", c.ExternalURL)
+		if len(got) != 2 || got[0].ID != first.ID || got[1].ID != second.ID {
+			t.Errorf("annotations = %+v, want sorted by index", got)
 		}
-	})
-	t.Run("invalid GitLabURL is invalid", func(t *testing.T) {
-		c := &Config{GitLabURL: "not a url"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+		persisted, err := loadAnnotations(s.annotations.path)
+		if err != nil || len(persisted.list(id.String(), 0, 4)) != 2 {
+			t.Errorf("persisted = %+v, %v", persisted, err)
 		}
 	})
-	t.Run("GitLabURL with subpath is invalid", func(t *testing.T) {
-		c := &Config{GitLabURL: "https://gitlab.example.com/sub"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+	t.Run("LegacyStore", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "annotations.json")
+		if err := os.WriteFile(path, []byte(`{"t1":[{"index":3,"note":"old","createdAt":1778374800.5}]}`), 0o600); err != nil {
+			t.Fatal(err)
 		}
-	})
-	t.Run("GitHub OAuth ID without secret is invalid", func(t *testing.T) {
-		c := &Config{GitHubOAuthClientID: "id"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+		a, err := loadAnnotations(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := a.list("t1", 0, 4)
+		if want := time.Date(2026, 5, 10, 1, 0, 0, 5e8, time.UTC); len(got) != 1 || got[0].Note != "old" || !got[0].CreatedAt.Equal(want) {
+			t.Errorf("annotations = %+v", got)
 		}
 	})
-	t.Run("GitHub OAuth secret without ID is invalid", func(t *testing.T) {
-		c := &Config{GitHubOAuthClientSecret: "sec"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+	t.Run("History", func(t *testing.T) {
+		var resp v1.TaskMessagesResp
+		if err := json.Unmarshal(do(http.MethodGet, "/messages?after=2", "").Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Annotations) != 1 || resp.Annotations[0].ID != second.ID {
+			t.Errorf("page annotations = %+v", resp.Annotations)
+		}
+		body := do(http.MethodGet, "/events", "").Body.String()
+		if !strings.Contains(body, `"annotations":[{"id":"`+first.ID.String()+`"`) {
+			t.Errorf("ready event missing annotations:\n%s", body)
 		}
 	})
-	t.Run("GitLab OAuth ID without secret is invalid", func(t *testing.T) {
-		c := &Config{GitLabOAuthClientID: "id"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+	t.Run("Delete", func(t *testing.T) {
+		if w := do(http.MethodPost, "/annotations/"+first.ID.String()+"/delete", ""); w.Code != http.StatusOK {
+			t.Fatalf("delete = %d %s", w.Code, w.Body.String())
+		}
+		if w := do(http.MethodPost, "/annotations/"+first.ID.String()+"/delete", ""); w.Code != http.StatusNotFound {
+			t.Errorf("second delete = %d, want 404", w.Code)
+		}
+		if got := s.taskAnnotations(s.tasks[id.String()], 0, 4); len(got) != 1 || got[0].ID != second.ID {
+			t.Errorf("annotations after delete = %+v", got)
 		}
 	})
-	t.Run("GitLab OAuth secret without ID is invalid", func(t *testing.T) {
-		c := &Config{GitLabOAuthClientSecret: "sec"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+}
+
+func TestAdmin(t *testing.T) {
+	t.Run("requireAdmin", func(t *testing.T) {
+		s := newTestServer(t)
+		ok := s.requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+		serve := func(u *auth.User) int {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/runtime", http.NoBody)
+			if u != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), u))
+			}
+			w := httptest.NewRecorder()
+			ok.ServeHTTP(w, req)
+			return w.Code
+		}
+		if got := serve(nil); got != http.StatusOK {
+			t.Errorf("auth disabled: status = %d, want 200", got)
+		}
+		store, err := auth.Open(filepath.Join(t.TempDir(), "users.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.authStore = store
+		s.adminUsers = parseAllowedUsers("Alice")
+		for _, tc := range []struct {
+			user *auth.User
+			want int
+		}{
+			{nil, http.StatusForbidden},
+			{&auth.User{Username: "bob"}, http.StatusForbidden},
+			{&auth.User{Username: "alice"}, http.StatusOK},
+		} {
+			if got := serve(tc.user); got != tc.want {
+				t.Errorf("user %+v: status = %d, want %d", tc.user, got, tc.want)
+			}
 		}
 	})
-	t.Run("GitHub PAT and OAuth together is invalid", func(t *testing.T) {
-		c := &Config{GitHubToken: "ghp_abc", GitHubOAuthClientID: "id", GitHubOAuthClientSecret: "sec", GitHubOAuthAllowedUsers: "alice", ExternalURL: "https://caic.example.com"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+	t.Run("runtime", func(t *testing.T) {
+		s := newTestServer(t)
+		h, err := s.buildHandler()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/runtime", http.NoBody))
+		var resp v1.RuntimeResp
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("status = %d: %s: %v", w.Code, w.Body.String(), err)
+		}
+		hasStack := slices.ContainsFunc(resp.GoroutineGroups, func(g v1.GoroutineGroup) bool { return len(g.Stack) != 0 })
+		if resp.Goroutines == 0 || resp.HeapAllocBytes == 0 || !hasStack {
+			t.Errorf("resp = %+v", resp)
+		}
+		for _, path := range []string{"/api/v1/admin/debug/pprof/", "/api/v1/admin/debug/vars"} {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: status = %d", path, w.Code)
+			}
 		}
 	})
-	t.Run("GitLab PAT and OAuth together is invalid", func(t *testing.T) {
-		c := &Config{GitLabToken: "glpat-abc", GitLabOAuthClientID: "id", GitLabOAuthClientSecret: "sec", GitLabOAuthAllowedUsers: "alice", ExternalURL: "https://caic.example.com"}
-		if err := c.Validate(); err == nil {
-			t.Fatal("Validate() expected error, got nil")
+	t.Run("parseGoroutineGroups", func(t *testing.T) {
+		in := "goroutine profile: total 3\n" +
+			"2 @ 0x1 0x2\n" +
+			"#\t0x1\truntime.gopark+0x10\t/go/src/runtime/proc.go:402\n" +
+			"#\t0x2\tmain.wait+0x20\t\t\t/src/main.go:12\n" +
+			"#\t0x4\n" +
+			"\n" +
+			"1 @ 0x3\n" +
+			"#\t0x3\tmain.main+0x5\t/src/main.go:5\n"
+		got := parseGoroutineGroups([]byte(in))
+		want := []v1.GoroutineGroup{
+			{Count: 2, Stack: []string{"runtime.gopark /go/src/runtime/proc.go:402", "main.wait /src/main.go:12", "0x4"}},
+			{Count: 1, Stack: []string{"main.main /src/main.go:5"}},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
 		}
 	})
 }
@@ -1648,6 +3797,26 @@ func TestBuildHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("request ID", func(t *testing.T) {
+		s := newTestServer(t)
+		h, err := s.buildHandler()
+		if err != nil {
+			t.Fatalf("buildHandler() error = %v", err)
+		}
+		for in, keep := range map[string]bool{"": false, "client-42_a": true, "bad id\n": false} {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/health", http.NoBody)
+			if in != "" {
+				req.Header.Set("X-Request-ID", in)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			got := w.Header().Get("X-Request-ID")
+			if keep && got != in || !keep && (got == "" || got == in) {
+				t.Errorf("X-Request-ID %q: response header = %q", in, got)
+			}
+		}
+	})
+
 	t.Run("static handler rejects non-GET", func(t *testing.T) {
 		s := newTestServer(t)
 		h, err := s.buildHandler()
@@ -2055,3 +4224,46 @@ func TestPrefsPerUser(t *testing.T) {
 		}
 	})
 }
+
+func TestGenericHarness(t *testing.T) {
+	s := newTestServer(t)
+	s.generic = openaicompat.NewGeneric()
+	t.Run("Unconfigured", func(t *testing.T) {
+		prefs := s.prefs.Get("default")
+		if got := harnessModels(&prefs, s.generic); got != nil {
+			t.Errorf("models = %v", got)
+		}
+	})
+	t.Run("APIKeyWriteOnly", func(t *testing.T) {
+		update := func(g *v1.GenericHarness) *v1.GenericHarness {
+			resp, err := s.updatePreferences(t.Context(), &v1.UpdatePreferencesReq{Settings: v1.UserSettings{GenericHarness: g}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return resp.Settings.GenericHarness
+		}
+		want := &v1.GenericHarness{BaseURL: "https://api.example.com/v1", HasAPIKey: true, Model: "m1"}
+		if got := update(&v1.GenericHarness{BaseURL: "https://api.example.com/v1", APIKey: "secret", Model: "m1"}); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+		// An empty key keeps the saved one.
+		want.Model = "m2"
+		if got := update(&v1.GenericHarness{BaseURL: "https://api.example.com/v1", Model: "m2"}); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+		prefs := s.prefs.Get("default")
+		if ep := harnessEndpoint(&prefs, agent.Generic); ep == nil || ep.APIKey != "secret" {
+			t.Errorf("endpoint = %+v", ep)
+		}
+		if m := harnessModels(&prefs, s.generic); !slices.Equal(m, []string{"m2"}) {
+			t.Errorf("models = %v", m)
+		}
+		// But not for another URL.
+		if got := update(&v1.GenericHarness{BaseURL: "https://other.example.com/v1", Model: "m2"}); got.HasAPIKey {
+			t.Errorf("got %+v", got)
+		}
+		if got := update(nil); got != nil {
+			t.Errorf("got %+v", got)
+		}
+	})
+}
@@ -0,0 +1,272 @@
+// Slack app: creates tasks with "/caic run" and sends thread replies to them.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/bot"
+	"github.com/caic-xyz/caic/backend/internal/slack"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+const (
+	// slackUsage is the reply to an invalid slash command.
+	slackUsage = "Usage: `/caic run <repo> <prompt>`, then reply in the task's thread to send it input."
+	// maxSlackResult caps the agent result quoted in a thread.
+	maxSlackResult = 3000
+)
+
+// handleSlackCommand serves the "/caic" slash command. Slack expects a reply
+// within 3 seconds, so the task is created asynchronously.
+func (s *Server) handleSlackCommand(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.readSlackRequest(w, r)
+	if !ok {
+		return
+	}
+	cmd, err := slack.ParseCommand(body)
+	if err != nil {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+	reply := func(text string) {
+		writeJSONResponse(w, &map[string]string{"response_type": "ephemeral", "text": text}, nil)
+	}
+	if !s.slackAllowed(cmd.UserID) {
+		slog.Warn("slack command from a user not allowed", "user", cmd.UserID, "channel", cmd.ChannelID)
+		reply("You are not allowed to use caic.")
+		return
+	}
+	verb, rest, _ := strings.Cut(strings.TrimSpace(cmd.Text), " ")
+	repoArg, prompt, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	if prompt = strings.TrimSpace(prompt); verb != "run" || prompt == "" {
+		reply(slackUsage)
+		return
+	}
+	repo, err := s.slackRepo(repoArg)
+	if err != nil {
+		reply(err.Error())
+		return
+	}
+	slog.Info("slack command", "user", cmd.UserID, "channel", cmd.ChannelID, "repo", repo)
+	go s.startSlackTask(cmd.ChannelID, cmd.UserID, repo, prompt)
+	reply("Starting a task on " + repo + "…")
+}
+
+// slackRepo resolves the repository named in a slash command: its path, its
+// forge name ("owner/repo") or its base name when unambiguous.
+func (s *Server) slackRepo(name string) (string, error) {
+	if _, ok := s.runners[name]; ok && name != "" {
+		return name, nil
+	}
+	if info := s.ResolveRepo(name); info != nil {
+		return info.RelPath, nil
+	}
+	var match string
+	for i := range s.repos {
+		if path.Base(s.repos[i].RelPath) == name {
+			if match != "" {
+				return "", fmt.Errorf("repo %q is ambiguous: %s or %s", name, match, s.repos[i].RelPath)
+			}
+			match = s.repos[i].RelPath
+		}
+	}
+	if match == "" {
+		return "", fmt.Errorf("unknown repo %q", name)
+	}
+	return match, nil
+}
+
+// startSlackTask posts the thread of a new task in channel, creates the task
+// and reports its results in the thread.
+func (s *Server) startSlackTask(channel, user, repo, prompt string) {
+	ctx := s.ctx
+	ts, err := s.slack.PostMessage(ctx, channel, "", fmt.Sprintf("<@%s> started a task on %s:\n> %s", user, repo, strings.ReplaceAll(prompt, "\n", "\n> ")))
+	if err != nil {
+		slog.Warn("slack: post task thread", "channel", channel, "err", err)
+		return
+	}
+	id, err := s.CreateTask(ctx, bot.TaskRequest{Repo: repo, Prompt: prompt, SlackChannel: channel, SlackThreadTS: ts})
+	if err != nil {
+		s.postSlack(ctx, &task.SlackThread{Channel: channel, TS: ts}, "Failed to create the task: "+err.Error())
+		return
+	}
+	s.mu.Lock()
+	entry := s.tasks[id]
+	s.mu.Unlock()
+	if entry == nil {
+		return
+	}
+	s.postSlack(ctx, entry.task.Slack, s.taskRef(entry.task)+" created. Reply in this thread to send it input.")
+	s.watchSlackThread(entry, false)
+}
+
+// postSlack posts text in thread, logging failures.
+func (s *Server) postSlack(ctx context.Context, thread *task.SlackThread, text string) {
+	if _, err := s.slack.PostMessage(ctx, thread.Channel, thread.TS, text); err != nil {
+		slog.Warn("slack: post", "channel", thread.Channel, "thread", thread.TS, "err", err)
+	}
+}
+
+// slackIdle reports whether the agent of a task in state is done with its
+// turn: the result is posted in the thread when the task enters such a state.
+func slackIdle(state task.State) bool {
+	switch state { //nolint:exhaustive // only idle states are relevant
+	case task.StateWaiting, task.StateAsking, task.StateHasPlan, task.StatePlanReview, task.StateStopped, task.StateFailed, task.StatePurged:
+		return true
+	default:
+		return false
+	}
+}
+
+// watchSlackThread posts in the thread of the task of entry each time its
+// agent finishes a turn, until the task ends. skip first waits for the next
+// turn, when the current one was already posted before a restart.
+func (s *Server) watchSlackThread(entry *taskEntry, skip bool) {
+	t := entry.task
+	for {
+		if skip {
+			if _, err := s.waitTaskState(s.ctx, t, func(st task.State) bool { return !slackIdle(st) }); err != nil {
+				return
+			}
+		}
+		state, err := s.waitTaskState(s.ctx, t, slackIdle)
+		if err != nil {
+			return
+		}
+		text := s.taskRef(t) + " " + stateVerb(state) + "."
+		if result := strings.TrimSpace(lastResultText(t)); result != "" {
+			if r := []rune(result); len(r) > maxSlackResult {
+				result = string(r[:maxSlackResult]) + "…"
+			}
+			text += "\n\n" + result
+		}
+		s.postSlack(s.ctx, t.Slack, text)
+		switch state { //nolint:exhaustive // only final states end the thread
+		case task.StateStopped, task.StateFailed, task.StatePurged:
+			return
+		}
+		skip = true
+	}
+}
+
+// resumeSlackWatchers re-attaches watchSlackThread to the tasks created from
+// Slack that are not finished after a restart.
+func (s *Server) resumeSlackWatchers() {
+	if s.slack == nil {
+		return
+	}
+	s.mu.Lock()
+	var entries []*taskEntry
+	for _, e := range s.tasks {
+		switch e.task.GetState() { //nolint:exhaustive // only unfinished states are relevant
+		case task.StateStopped, task.StateFailed, task.StatePurged:
+		default:
+			if e.task.Slack != nil {
+				entries = append(entries, e)
+			}
+		}
+	}
+	s.mu.Unlock()
+	for _, e := range entries {
+		go s.watchSlackThread(e, slackIdle(e.task.GetState()))
+	}
+}
+
+// handleSlackEvents serves the Events API: replies in the thread of a task
+// are sent to it as input.
+func (s *Server) handleSlackEvents(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.readSlackRequest(w, r)
+	if !ok {
+		return
+	}
+	var env slack.Envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		http.Error(w, "bad payload", http.StatusBadRequest)
+		return
+	}
+	if env.Type == "url_verification" {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, env.Challenge)
+		return
+	}
+	// Slack retries events not acknowledged within 3 seconds; the first
+	// delivery was already handled.
+	if r.Header.Get("X-Slack-Retry-Num") != "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if env.Type == "event_callback" && env.Event.IsThreadReply() {
+		go s.handleSlackReply(env.Event)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleSlackReply sends a reply in the thread of a task to the task.
+func (s *Server) handleSlackReply(ev slack.MessageEvent) {
+	t := s.slackTask(ev.Channel, ev.ThreadTS)
+	if t == nil {
+		return
+	}
+	if !s.slackAllowed(ev.User) {
+		slog.Warn("slack reply from a user not allowed", "task", t.ID, "user", ev.User)
+		return
+	}
+	text := strings.TrimSpace(ev.Text)
+	if text == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(s.ctx, time.Minute)
+	defer cancel()
+	if err := t.SendInput(ctx, agent.Prompt{Text: text}); err != nil {
+		s.postSlack(ctx, t.Slack, "Could not send the reply to "+s.taskRef(t)+": "+err.Error())
+		return
+	}
+	slog.Info("slack reply sent", "task", t.ID, "user", ev.User)
+}
+
+// slackAllowed reports whether the Slack user is in SLACK_ALLOWED_USERS.
+func (s *Server) slackAllowed(user string) bool {
+	_, ok := s.slackUsers[strings.ToLower(user)]
+	return ok && user != ""
+}
+
+// slackTask returns the task reporting in the thread ts of channel, or nil.
+func (s *Server) slackTask(channel, ts string) *task.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.tasks {
+		if th := e.task.Slack; th != nil && th.Channel == channel && th.TS == ts {
+			return e.task
+		}
+	}
+	return nil
+}
+
+// readSlackRequest reads the body of a request from Slack and verifies its
+// signature, replying with an error when it fails.
+func (s *Server) readSlackRequest(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if s.slack == nil {
+		http.Error(w, "slack app not configured", http.StatusNotFound)
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "read body: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if err := slack.VerifySignature(s.slackSecret, body, r.Header.Get("X-Slack-Request-Timestamp"), r.Header.Get("X-Slack-Signature"), time.Now()); err != nil {
+		slog.Warn("slack signature mismatch", "err", err)
+		http.Error(w, "signature verification failed", http.StatusUnauthorized)
+		return nil, false
+	}
+	return body, true
+}
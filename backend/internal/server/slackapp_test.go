@@ -0,0 +1,132 @@
+// Tests for the Slack app.
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/slack"
+	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/maruel/ksid"
+)
+
+// slackStub is a Slack Web API recording the posted messages.
+type slackStub struct {
+	mu    sync.Mutex
+	posts []string
+}
+
+func (st *slackStub) serve(t *testing.T) *slack.Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m struct {
+			Channel  string `json:"channel"`
+			ThreadTS string `json:"thread_ts"`
+			Text     string `json:"text"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&m)
+		st.mu.Lock()
+		st.posts = append(st.posts, m.Channel+"/"+m.ThreadTS+": "+m.Text)
+		st.mu.Unlock()
+		_, _ = io.WriteString(w, `{"ok":true,"ts":"9.9"}`)
+	}))
+	t.Cleanup(srv.Close)
+	c := slack.NewClient("xoxb-test")
+	c.APIURL = srv.URL
+	return c
+}
+
+// signedSlackRequest returns a request to path signed with secret.
+func signedSlackRequest(secret []byte, path, body string) *http.Request {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("v0:" + ts + ":" + body))
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestSlackApp(t *testing.T) {
+	secret := []byte("s3cret")
+	t.Run("NotConfigured", func(t *testing.T) {
+		s := newTestServer(t)
+		w := httptest.NewRecorder()
+		s.handleSlackCommand(w, signedSlackRequest(secret, "/webhooks/slack/commands", "text=run"))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", w.Code)
+		}
+	})
+	t.Run("Command", func(t *testing.T) {
+		s := newTestServer(t)
+		s.slack, s.slackSecret, s.slackUsers = (&slackStub{}).serve(t), secret, parseAllowedUsers("U1")
+		s.repos = []repoInfo{{RelPath: "github/caic"}, {RelPath: "gitlab/caic"}, {RelPath: "github/other"}}
+
+		w := httptest.NewRecorder()
+		s.handleSlackCommand(w, signedSlackRequest([]byte("wrong"), "/webhooks/slack/commands", "text=run"))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("bad signature: status = %d, want 401", w.Code)
+		}
+		for _, c := range []struct{ body, want string }{
+			{"user_id=U1&text=help", "Usage: "},
+			{"user_id=U1&text=run+other", "Usage: "},
+			{"user_id=U1&text=run+nope+fix+it", `unknown repo \"nope\"`},
+			{"user_id=U1&text=run+caic+fix+it", `repo \"caic\" is ambiguous`},
+			{"user_id=U2&text=run+github%2Fother+fix+it", "not allowed"},
+			{"text=run+github%2Fother+fix+it", "not allowed"},
+		} {
+			w := httptest.NewRecorder()
+			s.handleSlackCommand(w, signedSlackRequest(secret, "/webhooks/slack/commands", c.body))
+			if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), c.want) {
+				t.Errorf("%s: %d %s, want %q", c.body, w.Code, w.Body.String(), c.want)
+			}
+		}
+		s.runners["github/caic"] = &task.Runner{}
+		if got, err := s.slackRepo("github/caic"); err != nil || got != "github/caic" {
+			t.Errorf("slackRepo(github/caic) = %q, %v", got, err)
+		}
+		if got, err := s.slackRepo("other"); err != nil || got != "github/other" {
+			t.Errorf("slackRepo(other) = %q, %v", got, err)
+		}
+	})
+	t.Run("Events", func(t *testing.T) {
+		s := newTestServer(t)
+		s.slack, s.slackSecret = (&slackStub{}).serve(t), secret
+		w := httptest.NewRecorder()
+		s.handleSlackEvents(w, signedSlackRequest(secret, "/webhooks/slack/events", `{"type":"url_verification","challenge":"abc"}`))
+		if w.Code != http.StatusOK || w.Body.String() != "abc" {
+			t.Errorf("url_verification: %d %q", w.Code, w.Body.String())
+		}
+	})
+	t.Run("Thread", func(t *testing.T) {
+		stub := &slackStub{}
+		s := newTestServer(t)
+		s.slack, s.slackSecret, s.slackUsers = stub.serve(t), secret, parseAllowedUsers("U1")
+		tk := &task.Task{ID: ksid.NewID(), Alias: "w5", Slack: &task.SlackThread{Channel: "C1", TS: "1.0"}}
+		tk.SetState(task.StateFailed)
+		s.tasks[tk.ID.String()] = &taskEntry{task: tk, done: make(chan struct{})}
+		if s.slackTask("C1", "1.0") != tk || s.slackTask("C1", "2.0") != nil {
+			t.Error("slackTask did not match the thread")
+		}
+
+		// Replies of the users not allowed are ignored; a reply to a task that
+		// cannot receive input is reported in the thread.
+		s.handleSlackReply(slack.MessageEvent{Type: "message", Channel: "C1", User: "U2", Text: "rm -rf", TS: "1.5", ThreadTS: "1.0"})
+		s.handleSlackReply(slack.MessageEvent{Type: "message", Channel: "C1", User: "U1", Text: "go on", TS: "2.0", ThreadTS: "1.0"})
+		s.watchSlackThread(s.tasks[tk.ID.String()], false)
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		if len(stub.posts) != 2 || !strings.HasPrefix(stub.posts[0], "C1/1.0: Could not send the reply to caic task w5") || stub.posts[1] != "C1/1.0: caic task w5 failed." {
+			t.Errorf("posts = %q", stub.posts)
+		}
+	})
+}
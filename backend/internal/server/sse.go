@@ -1,12 +1,16 @@
-// SSE streaming handlers for task list events and usage events.
+// SSE streaming handlers for task list events and usage events, the task
+// event writer that coalesces high-frequency events into batches, and the
+// keep-alive pings of all SSE streams.
 
 package server
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"time"
@@ -20,13 +24,27 @@ import (
 // iteration it sends a full snapshot; thereafter it sends only upsert/delete
 // events for changed or removed tasks. It pushes immediately when a
 // server-handled mutation fires the changed channel, and falls back to a
-// 2-second ticker to catch runner-internal state transitions.
+// 2-second ticker to catch runner-internal state transitions. The fields and
+// view query parameters trim each task and the ones of parseTaskFilter filter
+// tasks as for the task list; a task leaving the filter, e.g. when archived,
+// emits a delete.
 func (s *Server) handleTaskListEvents(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		writeError(w, dto.InternalError("streaming not supported"))
 		return
 	}
+	proj, err := parseTaskProjection(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	settings := s.prefs.Get(userIDFromCtx(r.Context())).Settings
+	filter, err := parseTaskFilter(r.URL.Query(), &settings)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -35,6 +53,8 @@ func (s *Server) handleTaskListEvents(w http.ResponseWriter, r *http.Request) {
 
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
+	ka := s.newSSEKeepAlive(w)
+	defer ka.stop()
 
 	// With GitHub App configured, CI updates arrive via check_suite webhooks;
 	// use a nil channel so the ticker case is never selected.
@@ -59,6 +79,9 @@ func (s *Server) handleTaskListEvents(w http.ResponseWriter, r *http.Request) {
 		s.mu.Lock()
 		out := make([]v1.Task, 0, len(s.tasks))
 		for _, e := range s.tasks {
+			if !filter.matchLocked(s, e) {
+				continue
+			}
 			out = append(out, s.toJSON(e))
 		}
 		repos := s.reposLocked()
@@ -69,7 +92,17 @@ func (s *Server) handleTaskListEvents(w http.ResponseWriter, r *http.Request) {
 		reposJSON, _ := json.Marshal(repos)
 
 		if first {
-			if err := emitTaskListEvent(w, flusher, v1.TaskListEvent{Kind: "snapshot", Tasks: out}); err != nil {
+			snapshot := make([]json.RawMessage, 0, len(out))
+			for i := range out {
+				data, err := proj.marshal(&out[i])
+				if err != nil {
+					slog.Warn("marshal task", "id", out[i].ID, "err", err)
+					continue
+				}
+				snapshot = append(snapshot, data)
+				prevByID[out[i].ID.String()] = data
+			}
+			if err := emitTaskListEvent(w, flusher, rawTaskListEvent{Kind: "snapshot", Tasks: snapshot}); err != nil {
 				slog.Warn("marshal task list snapshot", "err", err)
 				return
 			}
@@ -77,10 +110,6 @@ func (s *Server) handleTaskListEvents(w http.ResponseWriter, r *http.Request) {
 				slog.Warn("marshal repos snapshot", "err", err)
 				return
 			}
-			for i := range out {
-				data, _ := json.Marshal(&out[i])
-				prevByID[out[i].ID.String()] = data
-			}
 			prevReposJSON = reposJSON
 			first = false
 		} else {
@@ -89,7 +118,7 @@ func (s *Server) handleTaskListEvents(w http.ResponseWriter, r *http.Request) {
 			for i := range out {
 				id := out[i].ID.String()
 				currentIDs[id] = struct{}{}
-				data, err := json.Marshal(&out[i])
+				data, err := proj.marshal(&out[i])
 				if err != nil {
 					slog.Warn("marshal task", "id", id, "err", err)
 					continue
@@ -99,7 +128,7 @@ func (s *Server) handleTaskListEvents(w http.ResponseWriter, r *http.Request) {
 					prevByID[id] = data
 					if prev == nil {
 						// New task: emit full object.
-						if err := emitTaskListEvent(w, flusher, v1.TaskListEvent{Kind: "upsert", Task: &out[i]}); err != nil {
+						if err := emitTaskListEvent(w, flusher, rawTaskListEvent{Kind: "upsert", Task: data}); err != nil {
 							slog.Warn("marshal task upsert", "id", id, "err", err)
 							return
 						}
@@ -153,6 +182,11 @@ func (s *Server) handleTaskListEvents(w http.ResponseWriter, r *http.Request) {
 		case <-ticker.C:
 		case <-ciTickerC:
 			go s.pollCIForActiveRepos(context.WithoutCancel(r.Context()))
+		case <-ka.C():
+			if err := ka.ping(); err != nil {
+				slog.DebugContext(r.Context(), "SSE subscriber gone", "stream", "tasks", "err", err)
+				return
+			}
 		}
 	}
 }
@@ -174,12 +208,16 @@ func (s *Server) handleUsageEvents(w http.ResponseWriter, r *http.Request) {
 
 	ticker := time.NewTicker(usage.CacheTTL)
 	defer ticker.Stop()
+	ka := s.newSSEKeepAlive(w)
+	defer ka.stop()
 
 	var prev []byte
 
 	for {
 		s.mu.Lock()
-		claude := computeClaudeUsage(s.tasks, time.Now())
+		tasks := s.usageTasksLocked(r)
+		claude := computeClaudeUsage(tasks, time.Now())
+		rateLimits := computeRateLimits(tasks)
 		ch := s.changed
 		s.mu.Unlock()
 
@@ -193,7 +231,7 @@ func (s *Server) handleUsageEvents(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		resp := v1.UsageResp{Claude: &claude}
+		resp := v1.UsageResp{Claude: &claude, Disk: s.diskUsage(), RateLimits: rateLimits}
 		if s.codexUsage != nil {
 			resp.Codex = s.codexUsage.Get(r.Context())
 		}
@@ -210,14 +248,22 @@ func (s *Server) handleUsageEvents(w http.ResponseWriter, r *http.Request) {
 			return
 		case <-ch:
 		case <-ticker.C:
+		case <-ka.C():
+			if err := ka.ping(); err != nil {
+				slog.DebugContext(r.Context(), "SSE subscriber gone", "stream", "usage", "err", err)
+				return
+			}
 		}
 	}
 }
 
-// handleGetUsage returns a one-shot usage snapshot as JSON.
+// handleGetUsage returns a one-shot usage snapshot as JSON. ?project= keeps
+// the tasks of a project.
 func (s *Server) handleGetUsage(w http.ResponseWriter, r *http.Request) {
 	s.mu.Lock()
-	claude := computeClaudeUsage(s.tasks, time.Now())
+	tasks := s.usageTasksLocked(r)
+	claude := computeClaudeUsage(tasks, time.Now())
+	rateLimits := computeRateLimits(tasks)
 	s.mu.Unlock()
 
 	if s.usage != nil {
@@ -230,7 +276,7 @@ func (s *Server) handleGetUsage(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	resp := v1.UsageResp{Claude: &claude}
+	resp := v1.UsageResp{Claude: &claude, Disk: s.diskUsage(), RateLimits: rateLimits}
 	if s.codexUsage != nil {
 		resp.Codex = s.codexUsage.Get(r.Context())
 	}
@@ -238,3 +284,154 @@ func (s *Server) handleGetUsage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
 }
+
+// defaultSSEPing is the interval of the keep-alive comments sent on SSE
+// streams when Config.SSEPing is unset. Some proxies cut connections idle for
+// 60s.
+const defaultSSEPing = 20 * time.Second
+
+// sseKeepAlive writes a comment on an SSE stream every interval, which
+// clients ignore, so that proxies do not cut it while idle. Writes must not
+// block for more than two intervals: a subscriber not reading, e.g. behind a
+// dead connection, makes ping fail so the handler returns and releases its
+// subscriptions.
+type sseKeepAlive struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	every  time.Duration
+	ticker *time.Ticker
+}
+
+// newSSEKeepAlive starts pinging w. Call stop when done.
+func (s *Server) newSSEKeepAlive(w http.ResponseWriter) *sseKeepAlive {
+	every := cmp.Or(s.ssePing, defaultSSEPing)
+	k := &sseKeepAlive{w: w, rc: http.NewResponseController(w), every: every, ticker: time.NewTicker(every)}
+	k.extendDeadline()
+	return k
+}
+
+// C fires when a ping is due.
+func (k *sseKeepAlive) C() <-chan time.Time {
+	return k.ticker.C
+}
+
+// ping writes and flushes a comment. An error means the subscriber is gone.
+func (k *sseKeepAlive) ping() error {
+	if _, err := io.WriteString(k.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	if err := k.rc.Flush(); err != nil {
+		return err
+	}
+	k.extendDeadline()
+	return nil
+}
+
+// extendDeadline lets writes block for two intervals. Writers not supporting
+// deadlines, e.g. in tests, are only checked for errors.
+func (k *sseKeepAlive) extendDeadline() {
+	_ = k.rc.SetWriteDeadline(time.Now().Add(2 * k.every))
+}
+
+// stop stops pinging and clears the deadline, which would otherwise apply
+// to the next requests on the connection.
+func (k *sseKeepAlive) stop() {
+	k.ticker.Stop()
+	_ = k.rc.SetWriteDeadline(time.Time{})
+}
+
+// maxSSEBatch caps the events coalesced into one "batch" SSE event.
+const maxSSEBatch = 256
+
+// taskEventWriter writes task events to an SSE stream: one "message" event
+// each or, when every is non-zero, JSON arrays in "batch" events written at
+// most every interval or maxSSEBatch events.
+type taskEventWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	every   time.Duration
+	idx     int
+	pending [][]byte
+	timer   *time.Timer
+	armed   bool
+}
+
+func newTaskEventWriter(w http.ResponseWriter, flusher http.Flusher, every time.Duration) *taskEventWriter {
+	tw := &taskEventWriter{w: w, flusher: flusher, every: every}
+	if every > 0 {
+		tw.timer = time.NewTimer(every)
+		tw.timer.Stop()
+	}
+	return tw
+}
+
+// write writes ev, or queues it when coalescing.
+func (tw *taskEventWriter) write(ev *v1.EventMessage) {
+	data, err := marshalEvent(ev)
+	if err != nil {
+		slog.Warn("marshal SSE event", "err", err)
+		return
+	}
+	if tw.every == 0 {
+		_, _ = fmt.Fprintf(tw.w, "event: message\ndata: %s\nid: %d\n\n", data, tw.idx)
+		tw.idx++
+		return
+	}
+	tw.pending = append(tw.pending, data)
+	tw.idx++
+	if len(tw.pending) >= maxSSEBatch {
+		tw.writeBatch()
+	}
+}
+
+// writeBatch writes the queued events as one "batch" event whose id is the
+// last event's.
+func (tw *taskEventWriter) writeBatch() {
+	if len(tw.pending) == 0 {
+		return
+	}
+	_, _ = fmt.Fprintf(tw.w, "event: batch\ndata: [%s]\nid: %d\n\n", bytes.Join(tw.pending, []byte(",")), tw.idx-1)
+	tw.pending = tw.pending[:0]
+}
+
+// flush writes any queued events and flushes the stream.
+func (tw *taskEventWriter) flush() {
+	tw.writeBatch()
+	if tw.armed {
+		tw.timer.Stop()
+		tw.armed = false
+	}
+	tw.flusher.Flush()
+}
+
+// flushSoon flushes now when not coalescing; otherwise it arms the batch
+// timer so queued events go out within the interval.
+func (tw *taskEventWriter) flushSoon() {
+	if tw.every == 0 {
+		tw.flusher.Flush()
+		return
+	}
+	if len(tw.pending) == 0 {
+		// writeBatch already sent everything; push it to the client.
+		tw.flusher.Flush()
+		return
+	}
+	if !tw.armed {
+		tw.timer.Reset(tw.every)
+		tw.armed = true
+	}
+}
+
+// timerC returns a channel that fires when queued events are due, or nil.
+func (tw *taskEventWriter) timerC() <-chan time.Time {
+	if !tw.armed {
+		return nil
+	}
+	return tw.timer.C
+}
+
+// onTimer handles a value received from timerC.
+func (tw *taskEventWriter) onTimer() {
+	tw.armed = false
+	tw.flush()
+}
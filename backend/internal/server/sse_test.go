@@ -0,0 +1,70 @@
+// Tests for the keep-alive pings of SSE streams.
+package server
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// brokenWriter is a streaming response whose client is gone.
+type brokenWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (brokenWriter) Write([]byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+func (w brokenWriter) WriteString(string) (int, error) {
+	return w.Write(nil)
+}
+
+func TestSSEKeepAlive(t *testing.T) {
+	t.Run("Ping", func(t *testing.T) {
+		s := newTestServer(t)
+		s.ssePing = 10 * time.Millisecond
+		ts := httptest.NewServer(http.HandlerFunc(s.handleUsageEvents))
+		t.Cleanup(ts.Close)
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, ts.URL, http.NoBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		sc := bufio.NewScanner(resp.Body)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			if sc.Text() == ": ping" {
+				return
+			}
+			if !strings.HasPrefix(sc.Text(), "event:") && !strings.HasPrefix(sc.Text(), "data:") && sc.Text() != "" {
+				t.Fatalf("unexpected line %q", sc.Text())
+			}
+		}
+		t.Fatalf("stream ended without ping: %v", sc.Err())
+	})
+	t.Run("DeadSubscriber", func(t *testing.T) {
+		s := newTestServer(t)
+		s.ssePing = time.Millisecond
+		for name, h := range map[string]http.HandlerFunc{"tasks": s.handleTaskListEvents, "usage": s.handleUsageEvents} {
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				h(brokenWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatalf("%s: handler still running after its client is gone", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,96 @@
+// Stall policy: running tasks whose agent stays silent are flagged, and
+// optionally interrupted.
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// stallPollInterval is how often pollStalls looks for stalled tasks.
+const stallPollInterval = 30 * time.Second
+
+// notifyRouteStalled is the notification route of the stalled tasks, next to
+// the task states.
+const notifyRouteStalled = "stalled"
+
+// pollStalls applies the stall policy every stallPollInterval until ctx is
+// done.
+func (s *Server) pollStalls(ctx context.Context) {
+	ticker := time.NewTicker(stallPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.checkStalls(ctx, now)
+		}
+	}
+}
+
+// checkStalls flags the running tasks silent for longer than their owner's
+// stall policy allows. The flag clears on the next message of the agent. The
+// tasks paused on a policy violation are silent by design and are skipped.
+func (s *Server) checkStalls(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	entries := make([]*taskEntry, 0, len(s.tasks))
+	for _, e := range s.tasks {
+		entries = append(entries, e)
+	}
+	s.mu.Unlock()
+	for _, e := range entries {
+		snap := e.task.Snapshot()
+		if snap.State != task.StateRunning || snap.Stalled || snap.PolicyViolation != nil || snap.LastActivityAt.IsZero() {
+			continue
+		}
+		p := s.stallPolicy(e.task)
+		silent := now.Sub(snap.LastActivityAt)
+		if timeout := p.Timeout(); timeout <= 0 || silent < timeout {
+			continue
+		}
+		detail := fmt.Sprintf("No output from the agent for %s: it may be hung.", silent.Round(time.Minute))
+		if p.Interrupt {
+			detail += " Interrupting the turn."
+		}
+		if !e.task.MarkStalled(ctx, snap.LastActivityAt, detail) {
+			continue
+		}
+		slog.WarnContext(ctx, "task stalled", "task", e.task.ID, "silent", silent.Round(time.Second))
+		s.notifyTaskChange()
+		go s.notifyStalled(e.task, silent)
+		if p.Interrupt {
+			if err := e.task.Interrupt(ctx); err != nil {
+				slog.WarnContext(ctx, "stalled task interrupt failed", "task", e.task.ID, "err", err)
+			}
+		}
+	}
+}
+
+// stallPolicy returns the stall policy of the owner of t.
+func (s *Server) stallPolicy(t *task.Task) task.StallPolicy {
+	if sp := s.prefs.Get(taskOwnerID(t)).Settings.StallPolicy; sp != nil {
+		return task.StallPolicy{Minutes: sp.Minutes, Interrupt: sp.Interrupt}
+	}
+	return task.StallPolicy{}
+}
+
+func prefsToV1StallPolicy(p *preferences.StallPolicy) *v1.StallPolicy {
+	if p == nil {
+		return nil
+	}
+	return &v1.StallPolicy{Minutes: p.Minutes, Interrupt: p.Interrupt}
+}
+
+func prefsFromV1StallPolicy(p *v1.StallPolicy) *preferences.StallPolicy {
+	if p == nil {
+		return nil
+	}
+	return &preferences.StallPolicy{Minutes: p.Minutes, Interrupt: p.Interrupt}
+}
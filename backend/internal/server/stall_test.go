@@ -0,0 +1,69 @@
+// Tests for the stall policy.
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func stallNotices(tk *task.Task) []string {
+	var out []string
+	for _, m := range tk.Messages() {
+		if sm, ok := m.(*agent.SystemMessage); ok && sm.Subtype == "caic_stalled" {
+			out = append(out, sm.Detail)
+		}
+	}
+	return out
+}
+
+func TestCheckStalls(t *testing.T) {
+	newStallServer := func(t *testing.T, p *preferences.StallPolicy) (*Server, *task.Task) {
+		s := newTestServer(t)
+		if err := s.prefs.Update("default", func(prefs *preferences.Preferences) {
+			prefs.Settings.StallPolicy = p
+		}); err != nil {
+			t.Fatal(err)
+		}
+		tk := &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}
+		tk.SetState(task.StateRunning)
+		s.tasks["t1"] = &taskEntry{task: tk, done: make(chan struct{})}
+		return s, tk
+	}
+	t.Run("Flag", func(t *testing.T) {
+		s, tk := newStallServer(t, &preferences.StallPolicy{Minutes: 10})
+		now := time.Now()
+		s.checkStalls(t.Context(), now.Add(5*time.Minute))
+		if got := stallNotices(tk); len(got) != 0 {
+			t.Fatalf("notices before timeout = %q", got)
+		}
+		s.checkStalls(t.Context(), now.Add(12*time.Minute))
+		s.checkStalls(t.Context(), now.Add(20*time.Minute))
+		got := stallNotices(tk)
+		if len(got) != 1 || got[0] != "No output from the agent for 12m0s: it may be hung." {
+			t.Errorf("notices = %q, want one", got)
+		}
+		if j := s.toJSON(s.tasks["t1"]); !j.Stalled || j.LastActivityAt.IsZero() {
+			t.Errorf("task JSON stalled = %v at %v", j.Stalled, j.LastActivityAt)
+		}
+	})
+	t.Run("Interrupt", func(t *testing.T) {
+		s, tk := newStallServer(t, &preferences.StallPolicy{Minutes: 10, Interrupt: true})
+		// The task has no session: the interrupt fails but the task is
+		// still flagged.
+		s.checkStalls(t.Context(), time.Now().Add(time.Hour))
+		if got := stallNotices(tk); len(got) != 1 || got[0] != "No output from the agent for 1h0m0s: it may be hung. Interrupting the turn." {
+			t.Errorf("notices = %q", got)
+		}
+	})
+	t.Run("Disabled", func(t *testing.T) {
+		s, tk := newStallServer(t, nil)
+		s.checkStalls(t.Context(), time.Now().Add(24*time.Hour))
+		if got := stallNotices(tk); len(got) != 0 || tk.Snapshot().Stalled {
+			t.Errorf("notices = %q", got)
+		}
+	})
+}
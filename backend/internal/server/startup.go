@@ -3,6 +3,7 @@
 package server
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -15,16 +16,21 @@ import (
 	"time"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/agent/openaicompat"
 	"github.com/caic-xyz/caic/backend/internal/auth"
 	"github.com/caic-xyz/caic/backend/internal/bot"
 	"github.com/caic-xyz/caic/backend/internal/container"
+	"github.com/caic-xyz/caic/backend/internal/eventbus"
 	"github.com/caic-xyz/caic/backend/internal/forge"
 	"github.com/caic-xyz/caic/backend/internal/forge/forgecache"
 	"github.com/caic-xyz/caic/backend/internal/forge/github"
+	"github.com/caic-xyz/caic/backend/internal/index"
 	"github.com/caic-xyz/caic/backend/internal/preferences"
 	"github.com/caic-xyz/caic/backend/internal/server/ipgeo"
 	"github.com/caic-xyz/caic/backend/internal/server/voicertc"
+	"github.com/caic-xyz/caic/backend/internal/slack"
 	"github.com/caic-xyz/caic/backend/internal/task"
+	"github.com/caic-xyz/caic/backend/internal/tracker"
 	"github.com/caic-xyz/caic/backend/internal/usage"
 	"github.com/caic-xyz/md"
 	"github.com/caic-xyz/md/gitutil"
@@ -33,7 +39,8 @@ import (
 	"github.com/maruel/ksid"
 )
 
-// New creates a new Server. It discovers repos under rootDir, creates a Runner
+// New creates a new Server. It discovers repos under rootDir and the other
+// source roots listed in roots.json in the config directory, creates a Runner
 // per repo, and adopts preexisting containers.
 //
 // Startup sequence:
@@ -51,11 +58,32 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 	logDir := filepath.Join(cfg.CacheDir, "tasks")
 	migrateTaskLogs(cfg.CacheDir, logDir)
 
-	absRoot, err := filepath.Abs(rootDir)
+	// Reconcile what the previous run left half done before loading the logs:
+	// a task whose container started before its log was written has none.
+	journal, err := task.OpenJournal(filepath.Join(cfg.CacheDir, "journal.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	if err := journal.Replay(ctx, logDir); err != nil {
+		slog.Warn("replay journal", "err", err)
+	}
+	// The caic-N sequences outlive the branches, which users delete once
+	// merged, so that numbers are never reused.
+	branchIDs, err := task.OpenBranchIDs(filepath.Join(cfg.CacheDir, "branch_ids.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open branch IDs: %w", err)
+	}
+
+	bus, err := eventbus.Open(cfg.EventBusURL)
 	if err != nil {
 		return nil, err
 	}
 
+	roots, err := loadSourceRoots(rootDir, filepath.Join(cfg.ConfigDir, "roots.json"))
+	if err != nil {
+		return nil, fmt.Errorf("load source roots: %w", err)
+	}
+
 	// container.New is instant; run it serially to simplify.
 	mdClient, err := container.New(cfg.TailscaleAPIKey, cfg.GitHubToken)
 	if err != nil {
@@ -64,8 +92,12 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 	mdClient.DigestCacheTTL = warmupInterval
 
 	// Phase 1: Parallel I/O — repos discovery, logs loading, and container listing.
+	type discoveredRepo struct {
+		root *sourceRoot
+		abs  string
+	}
 	type reposResult struct {
-		paths []string
+		repos []discoveredRepo
 		err   error
 	}
 	type logsResult struct {
@@ -82,8 +114,23 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 	contCh := make(chan containersResult, 1)
 
 	go func() {
-		paths, err := gitutil.DiscoverRepos(rootDir, 3)
-		repoCh <- reposResult{paths, err}
+		var res reposResult
+		for i := range roots {
+			paths, err := roots[i].discover()
+			if err != nil {
+				if i == 0 {
+					res.err = err
+					break
+				}
+				// Other roots may be on a network share; don't fail startup.
+				slog.Warn("skipping source root", "root", roots[i].Name, "path", roots[i].Path, "err", err)
+				continue
+			}
+			for _, abs := range paths {
+				res.repos = append(res.repos, discoveredRepo{&roots[i], abs})
+			}
+		}
+		repoCh <- res
 	}()
 	go func() {
 		logs, err := task.LoadLogs(logDir)
@@ -108,6 +155,19 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 		return nil, fmt.Errorf("load settings: %w", err)
 	}
 
+	archived, err := loadIDSet(filepath.Join(cfg.CacheDir, "archived.json"))
+	if err != nil {
+		return nil, fmt.Errorf("load archived tasks: %w", err)
+	}
+	pinned, err := loadIDSet(filepath.Join(cfg.CacheDir, "pinned.json"))
+	if err != nil {
+		return nil, fmt.Errorf("load pinned tasks: %w", err)
+	}
+	annotations, err := loadAnnotations(filepath.Join(cfg.CacheDir, "annotations.json"))
+	if err != nil {
+		return nil, fmt.Errorf("load annotations: %w", err)
+	}
+
 	// Initialize host checking and external URL state.
 	var hostState *auth.HostState
 	isAuto := strings.EqualFold(cfg.ExternalURL, "auto")
@@ -173,23 +233,72 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 		}
 	}
 
+	var embedder index.Embedder
+	switch {
+	case cfg.EmbeddingURL != "" && cfg.EmbeddingModel != "":
+		embedder = &index.OpenAIEmbedder{BaseURL: cfg.EmbeddingURL, Model: cfg.EmbeddingModel, APIKey: cfg.EmbeddingAPIKey}
+	case cfg.EmbeddingURL != "":
+		slog.Warn("CAIC_EMBEDDING_URL requires CAIC_EMBEDDING_MODEL; semantic search disabled")
+	}
+
+	staticCacheMB := cfg.StaticCacheMB
+	if staticCacheMB == 0 {
+		staticCacheMB = defaultStaticCacheMB
+	}
+	fe, err := newFrontendBuild(cfg.FrontendDir, int64(staticCacheMB)<<20)
+	if err != nil {
+		return nil, fmt.Errorf("frontend: %w", err)
+	}
+	if cfg.FrontendDir != "" {
+		if err := fe.watch(ctx); err != nil {
+			return nil, fmt.Errorf("watch frontend: %w", err)
+		}
+		slog.Info("frontend", "dir", cfg.FrontendDir)
+	} else {
+		go fe.warm(ctx)
+	}
+
 	s := &Server{
 		ctx:                ctx,
-		absRoot:            absRoot,
-		runners:            make(map[string]*task.Runner, len(repoRes.paths)),
+		roots:              roots,
+		local:              newLocalBackend(ctx, cfg.LocalURL),
+		generic:            openaicompat.NewGeneric(),
+		runners:            make(map[string]*task.Runner, len(repoRes.repos)),
 		mdClient:           mdClient,
 		logDir:             logDir,
+		pipelinesDir:       filepath.Join(cfg.ConfigDir, "pipelines"),
+		deployKeysDir:      filepath.Join(cfg.ConfigDir, "deploykeys"),
+		indexes:            index.NewManager(embedder),
+		archived:           archived,
+		pinned:             pinned,
+		annotations:        annotations,
 		prefs:              prefsStore,
+		journal:            journal,
+		branchIDs:          branchIDs,
 		authStore:          authStore,
 		sessionSecret:      sessionSecret,
 		githubOAuth:        githubOAuth,
 		gitlabOAuth:        gitlabOAuth,
 		githubAllowedUsers: githubAllowedUsers,
 		gitlabAllowedUsers: gitlabAllowedUsers,
+		adminUsers:         parseAllowedUsers(cfg.AdminUsers),
 		hostState:          hostState,
 		usage:              usage.NewClaudeFetcher(ctx),
 		codexUsage:         usage.NewCodexFetcher(ctx),
+		frontend:           fe,
+		tlsCertFile:        cfg.TLSCertFile,
+		tlsKeyFile:         cfg.TLSKeyFile,
+		serveHTTP3:         cfg.HTTP3,
+		ssePing:            cfg.SSEPing,
+		feedToken:          cfg.FeedToken,
+		mirrorFetch:        cmp.Or(cfg.MirrorFetch, defaultMirrorFetch),
 		pprof:              cfg.Pprof,
+		logLevel:           cmp.Or(cfg.LogLevel, &slog.LevelVar{}),
+		logRing:            cfg.LogRing,
+		debugConfig:        cfg.redacted(),
+		orphanPolicy:       cmp.Or(cfg.OrphanPolicy, orphanAdopt),
+		orphanGrace:        cmp.Or(cfg.OrphanGrace, defaultOrphanGrace),
+		minFreeDisk:        int64(cfg.MinFreeDiskGB) << 30,
 		geminiAPIKey:       cfg.GeminiAPIKey,
 		voiceBridge:        voiceBridge,
 		forge:              newForgeManager(cfg.GitHubToken, cfg.GitLabToken, nil),
@@ -198,7 +307,9 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 		tasks:              make(map[string]*taskEntry),
 		repoCIStatus:       make(map[string]repoCIState),
 		changed:            make(chan struct{}),
+		bus:                bus,
 	}
+	s.startEventBus()
 	s.githubWebhookSecret = cfg.GitHubWebhookSecret
 	s.gitlabWebhookSecret = cfg.GitLabWebhookSecret
 
@@ -238,14 +349,12 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 		info   repoInfo
 		runner *task.Runner
 	}
-	results := make([]repoResult, len(repoRes.paths))
+	results := make([]repoResult, len(repoRes.repos))
 	var wg sync.WaitGroup
-	for i, abs := range repoRes.paths {
+	for i, dr := range repoRes.repos {
 		wg.Go(func() {
-			rel, err := filepath.Rel(absRoot, abs)
-			if err != nil {
-				rel = filepath.Base(abs)
-			}
+			abs := dr.abs
+			rel := dr.root.repoPath(abs)
 			remoteName, err := gitutil.DefaultRemote(ctx, abs)
 			if err != nil {
 				slog.Warn("skipping repo, cannot determine default remote", "path", abs, "err", err)
@@ -262,10 +371,16 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 				Dir:        abs,
 				LogDir:     logDir,
 				Container:  backend,
+				Journal:    journal,
+				BranchIDs:  branchIDs,
+
+				OnSessionRestarted: s.watchRestartedSession,
+				OnPolicyViolation:  s.onPolicyViolation,
 			}
 			if err := runner.Init(ctx); err != nil {
 				slog.Warn("runner init failed", "path", abs, "err", err)
 			}
+			s.addCompatBackends(runner)
 			var forgeKind forge.Kind
 			var forgeOwner, forgeRepo string
 			if rawURL, err := forge.RemoteURL(ctx, abs); err == nil {
@@ -273,8 +388,9 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 			}
 			results[i] = repoResult{
 				info: repoInfo{
-					RelPath: rel, AbsPath: abs, BaseBranch: branch, BaseBranchRemote: remoteName, Remote: remote,
-					ForgeKind: forgeKind, ForgeOwner: forgeOwner, ForgeRepo: forgeRepo,
+					RelPath: rel, Root: dr.root.Name, AbsPath: abs, BaseBranch: branch, BaseBranchRemote: remoteName, Remote: remote,
+					ForgeKind: forgeKind, ForgeOwner: forgeOwner, ForgeRepo: forgeRepo, Mirror: isMirror(ctx, abs),
+					DeployKey: s.existingDeployKey(rel), PushRemote: repoPushRemote(ctx, abs),
 				},
 				runner: runner,
 			}
@@ -293,11 +409,23 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 	// Wire the bot with the server as its client.
 	// Eventually we may want to use a clearer observer pattern.
 	s.bot = bot.New(ctx, s)
+	if cfg.JiraURL != "" {
+		s.trackers = append(s.trackers, tracker.NewJira(cfg.JiraURL, cfg.JiraEmail, cfg.JiraAPIToken))
+	}
+	if cfg.LinearAPIKey != "" {
+		s.trackers = append(s.trackers, tracker.NewLinear(cfg.LinearAPIKey))
+	}
+	if cfg.SlackBotToken != "" {
+		s.slack = slack.NewClient(cfg.SlackBotToken)
+		s.slackSecret = cfg.SlackSigningSecret
+		s.slackUsers = parseAllowedUsers(cfg.SlackAllowedUsers)
+	}
 
 	// Always register a no-repo runner (keyed by "") for tasks that don't
 	// need a git repository.
-	noRepoRunner := &task.Runner{LogDir: logDir, Container: backend}
+	noRepoRunner := &task.Runner{LogDir: logDir, Container: backend, Journal: journal, OnSessionRestarted: s.watchRestartedSession, OnPolicyViolation: s.onPolicyViolation}
 	_ = noRepoRunner.Init(ctx) // populates Backends; no-op for no-repo (no branches to scan)
+	s.addCompatBackends(noRepoRunner)
 	s.runners[""] = noRepoRunner
 
 	// Phase 3: Load purged tasks from pre-loaded logs.
@@ -320,6 +448,8 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 
 	// Resume bot comment watchers for adopted tasks with pending forge issues.
 	s.bot.ResumePendingComments()
+	s.resumeIssueWatchers()
+	s.resumeSlackWatchers()
 
 	s.ipgeoChecker, err = ipgeo.NewChecker(ctx, cfg.IPGeoAllowlist, cfg.IPGeoDB)
 	if err != nil {
@@ -331,7 +461,16 @@ func New(ctx context.Context, rootDir string, cfg *Config) (*Server, error) {
 
 	s.watchContainerEvents(ctx)
 	go s.warmupImages()
-	go s.pollStats(s.ctx) //nolint:contextcheck // server-lifetime context is intentional
+	go s.pollStats(s.ctx)                  //nolint:contextcheck // server-lifetime context is intentional
+	go s.pollDisk(s.ctx)                   //nolint:contextcheck // server-lifetime context is intentional
+	go s.pollIdle(s.ctx)                   //nolint:contextcheck // server-lifetime context is intentional
+	go s.pollStalls(s.ctx)                 //nolint:contextcheck // server-lifetime context is intentional
+	go s.pollRateLimited(s.ctx)            //nolint:contextcheck // server-lifetime context is intentional
+	go s.pollMirrors(s.ctx, s.mirrorFetch) //nolint:contextcheck // server-lifetime context is intentional
+	if s.orphanPolicy != orphanOff && contRes.err == nil {
+		go s.collectOrphans(s.ctx) //nolint:contextcheck // server-lifetime context is intentional
+	}
+	s.resumeImageValidations(s.ctx) //nolint:contextcheck // server-lifetime context is intentional
 	return s, nil
 }
 
@@ -430,6 +569,11 @@ func (s *Server) loadPurgedTasksFrom(all []*task.LoadedTask) error {
 		}
 	}
 	purged = kept
+	s.mu.Lock()
+	for _, lt := range all {
+		s.reserveAlias(lt.Alias)
+	}
+	s.mu.Unlock()
 	if len(purged) == 0 {
 		slog.Info("no purged tasks to load", "candidates", len(all))
 		return nil
@@ -437,18 +581,9 @@ func (s *Server) loadPurgedTasksFrom(all []*task.LoadedTask) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for _, lt := range purged {
-		taskID := ksid.NewID()
-		// The original ID is embedded in the log filename as the prefix before the
-		// first '-'. Real server IDs are 10–12 chars (current-era timestamps in
-		// base32). Reject short strings (e.g. "a" from test filenames) that parse
-		// to implausibly small values.
-		if len(lt.TaskID) >= 9 {
-			if parsed, parseErr := ksid.Parse(lt.TaskID); parseErr == nil && parsed != 0 {
-				taskID = parsed
-			}
-		}
 		t := &task.Task{
-			ID:            taskID,
+			ID:            lt.ID(),
+			Alias:         lt.Alias,
 			InitialPrompt: agent.Prompt{Text: lt.Prompt},
 			Repos:         lt.Repos, // GitRoot is empty for purged tasks
 			Harness:       lt.Harness,
@@ -456,8 +591,22 @@ func (s *Server) loadPurgedTasksFrom(all []*task.LoadedTask) error {
 			Tailscale:     lt.Tailscale,
 			USB:           lt.USB,
 			Display:       lt.Display,
+			PlanOnly:      lt.PlanOnly,
+			RequirePlan:   lt.RequirePlan,
+			ReadOnly:      lt.ReadOnly,
+			Chat:          lt.Chat,
+			Thinking:      lt.Thinking,
+			Idle:          lt.Idle,
+			Push:          lt.Push,
+			Issue:         lt.Issue,
+			Slack:         lt.Slack,
+			Env:           lt.Env,
+			Instructions:  lt.Instructions,
+			Network:       lt.Network,
+			NetworkAllow:  lt.NetworkAllow,
 		}
 		t.SetStateAt(lt.State, lt.LastStateUpdateAt)
+		t.SetFailover(lt.Failover)
 		if lt.Title != "" {
 			t.SetTitle(lt.Title)
 		} else {
@@ -476,6 +625,7 @@ func (s *Server) loadPurgedTasksFrom(all []*task.LoadedTask) error {
 		if lt.Msgs != nil {
 			t.RestoreMessages(lt.Msgs)
 		}
+		t.RestoreTransitions(lt.Transitions)
 		// For tasks without a caic_result trailer (lt.State == StateRunning
 		// sentinel), any state RestoreMessages inferred is unreliable — the
 		// task may have been purged or interrupted without a trailer.
@@ -498,7 +648,7 @@ func (s *Server) loadPurgedTasksFrom(all []*task.LoadedTask) error {
 		done := make(chan struct{})
 		close(done)
 		entry := &taskEntry{task: t, result: lt.Result, done: done}
-		s.tasks[t.ID.String()] = entry
+		s.addTask(entry)
 	}
 	s.taskChanged()
 	slog.Info("loaded purged tasks from logs", "n", len(purged))
@@ -511,7 +661,7 @@ func (s *Server) loadPurgedTasksFrom(all []*task.LoadedTask) error {
 // Flow:
 //  1. Map branches from purged tasks to their IDs so live containers
 //     can replace stale entries.
-//  2. For each container matching a caic repo, call adoptOne concurrently.
+//  2. For each container, concurrently read its labels and call adoptOne.
 //
 // containers and allLogs are pre-loaded to avoid redundant I/O. If containers
 // is nil (due to a container client error), adoption is skipped.
@@ -520,15 +670,32 @@ func (s *Server) adoptContainers(ctx context.Context, containers []*md.Container
 		return nil
 	}
 
-	// Map repo+branch loaded from purged task logs to their ID in
-	// s.tasks so we can replace stale entries with live containers.
-	// The key is "repo\x00branch" because different repos can share a
-	// branch name.
+	branchIDs := s.branchTaskIDs()
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for _, c := range containers {
+		wg.Go(func() {
+			if err := s.adoptContainer(ctx, c, branchIDs, allLogs); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// branchTaskIDs maps repo+branch of the tasks in s.tasks to their IDs so
+// adoptOne can replace stale entries with live containers. The key is
+// "repo\x00branch" because different repos can share a branch name. A key
+// may map to several IDs: there may be multiple log files per branch when a
+// branch was reused or when trailer-less tasks were loaded alongside
+// properly-purged ones with the same branch.
+func (s *Server) branchTaskIDs() map[string][]string {
 	s.mu.Lock()
-	// Map repo+branch → all stale task IDs so adoptOne can remove every
-	// matching entry (there may be multiple log files per branch when a
-	// branch was reused or when trailer-less tasks were loaded alongside
-	// properly-purged ones with the same branch).
+	defer s.mu.Unlock()
 	branchIDs := make(map[string][]string, len(s.tasks))
 	for id, e := range s.tasks {
 		if p := e.task.Primary(); p != nil && p.Branch != "" {
@@ -536,77 +703,102 @@ func (s *Server) adoptContainers(ctx context.Context, containers []*md.Container
 			branchIDs[key] = append(branchIDs[key], id)
 		}
 	}
-	s.mu.Unlock()
+	return branchIDs
+}
 
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var errs []error
-	claimed := make(map[string]bool, len(containers))
+// adoptRef is what the labels of a container say about the task in it.
+type adoptRef struct {
+	taskID  ksid.ID
+	harness agent.Harness // Empty when the container predates the label.
+	repo    string        // Primary repository RelPath; empty for no-repo tasks.
+	branch  string
+}
 
-	for i := range s.repos {
-		ri := &s.repos[i]
-		repoName := filepath.Base(ri.AbsPath)
-		runner := s.runners[ri.RelPath]
-		for _, c := range containers {
-			branch, ok := container.BranchFromContainer(c.Name, repoName)
-			if !ok {
-				continue
-			}
-			claimed[c.Name] = true
-			wg.Go(func() {
-				if err := s.adoptOne(ctx, *ri, runner, c, branch, branchIDs, allLogs); err != nil {
-					mu.Lock()
-					errs = append(errs, err)
-					mu.Unlock()
+// parseAdoptLabels reconstructs the task reference from the labels caic wrote
+// at container creation. Containers created before the repo and branch
+// labels existed, and forks, whose branch md picks, fall back to md's own
+// record of the repositories it pushed. ok is false for containers caic did
+// not start.
+func (s *Server) parseAdoptLabels(c *md.Container, labels map[string]string) (ref adoptRef, ok bool, err error) {
+	v := labels[task.LabelTaskID]
+	if v == "" {
+		return ref, false, nil
+	}
+	if ref.taskID, err = ksid.Parse(v); err != nil {
+		return ref, false, fmt.Errorf("parse %s label %q on %s: %w", task.LabelTaskID, v, c.Name, err)
+	}
+	ref.harness = agent.Harness(labels[task.LabelHarness])
+	ref.repo = labels[task.LabelRepo]
+	ref.branch = labels[task.LabelBranch]
+	if len(c.Repos) > 0 {
+		if ref.repo == "" {
+			for i := range s.repos {
+				if s.repos[i].AbsPath == c.Repos[0].GitRoot {
+					ref.repo = s.repos[i].RelPath
+					break
 				}
-			})
-		}
-	}
-	wg.Wait()
-
-	// Adopt no-repo containers. md names them "md-agent-<hex>" when started
-	// with no repos (md.Client.Container with zero Repo arguments).
-	if noRepoRunner := s.runners[""]; noRepoRunner != nil {
-		for _, c := range containers {
-			if claimed[c.Name] || !strings.HasPrefix(c.Name, "md-agent-") {
-				continue
 			}
-			wg.Go(func() {
-				if err := s.adoptOne(ctx, repoInfo{}, noRepoRunner, c, "", branchIDs, allLogs); err != nil {
-					mu.Lock()
-					errs = append(errs, err)
-					mu.Unlock()
-				}
-			})
 		}
-		wg.Wait()
+		if ref.branch == "" {
+			ref.branch = c.Repos[0].Branch
+		}
 	}
-
-	return errors.Join(errs...)
+	return ref, true, nil
 }
 
-// adoptOne investigates a single container and registers it as a task.
-//
-// It verifies the container has a "caic" label (proving caic started it),
-// restores messages from either the relay output or JSONL logs, checks
-// whether the relay is alive, and registers the task. If the relay is
-// alive, it spawns a background goroutine to reattach. allLogs is the
-// pre-loaded set of JSONL log files (shared across all adoptOne calls).
-func (s *Server) adoptOne(ctx context.Context, ri repoInfo, runner *task.Runner, c *md.Container, branch string, branchIDs map[string][]string, allLogs []*task.LoadedTask) error { //nolint:gocritic // repoInfo size increase from GitHub fields; refactor not worth it
-	// Only adopt containers that caic started. The caic label is set at
-	// container creation and is the authoritative proof of ownership.
-	labelVal, err := container.LabelValue(ctx, c.Name, "caic")
+// adoptContainer reads the labels of c and adopts it when caic started it for
+// a repository it still serves.
+func (s *Server) adoptContainer(ctx context.Context, c *md.Container, branchIDs map[string][]string, allLogs []*task.LoadedTask) error {
+	labels, err := container.Labels(ctx, c.Name)
 	if err != nil {
-		return fmt.Errorf("label check for %s: %w", c.Name, err)
+		return err
 	}
-	if labelVal == "" {
-		slog.Info("container", "msg", "skipping non-caic", "repo", ri.RelPath, "ctr", c.Name, "br", branch)
+	ref, ok, err := s.parseAdoptLabels(c, labels)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		slog.Info("container", "msg", "skipping non-caic", "ctr", c.Name)
 		return nil
 	}
-	taskID, err := ksid.Parse(labelVal)
-	if err != nil {
-		return fmt.Errorf("parse caic label %q on %s: %w", labelVal, c.Name, err)
+	return s.adoptLabeled(ctx, c, ref, branchIDs, allLogs)
+}
+
+// adoptLabeled adopts container c, which runs the task described by ref,
+// when caic still serves the task's repository.
+func (s *Server) adoptLabeled(ctx context.Context, c *md.Container, ref adoptRef, branchIDs map[string][]string, allLogs []*task.LoadedTask) error {
+	var ri *repoInfo
+	for i := range s.repos {
+		if s.repos[i].RelPath == ref.repo {
+			ri = &s.repos[i]
+			break
+		}
+	}
+	switch {
+	case ri != nil:
+	case ref.repo == "":
+		ri = &repoInfo{}
+	default:
+		slog.Warn("container", "msg", "skipping container of unknown repo", "ctr", c.Name, "repo", ref.repo, "task", ref.taskID)
+		return nil
 	}
+	runner := s.runners[ri.RelPath]
+	if runner == nil {
+		slog.Warn("container", "msg", "no runner for container", "ctr", c.Name, "repo", ri.RelPath, "task", ref.taskID)
+		return nil
+	}
+	return s.adoptOne(ctx, *ri, runner, c, ref, branchIDs, allLogs)
+}
+
+// adoptOne registers the task described by ref, running in container c.
+//
+// It restores messages from either the relay output or the task's JSONL log,
+// checks whether the relay is alive, and registers the task. If the relay is
+// alive, it spawns a background goroutine to reattach. allLogs is the
+// pre-loaded set of JSONL log files (shared across all adoptOne calls).
+func (s *Server) adoptOne(ctx context.Context, ri repoInfo, runner *task.Runner, c *md.Container, ref adoptRef, branchIDs map[string][]string, allLogs []*task.LoadedTask) error { //nolint:gocritic // repoInfo size increase from GitHub fields; refactor not worth it
+	taskID := ref.taskID
+	branch := ref.branch
 
 	// Exited containers are adopted as stopped tasks. The user can
 	// explicitly revive them via the UI or API when ready.
@@ -615,26 +807,12 @@ func (s *Server) adoptOne(ctx context.Context, ri repoInfo, runner *task.Runner,
 		slog.Info("container", "msg", "adopting exited container as stopped", "ctr", c.Name, "br", branch)
 	}
 
-	// Find the log file for this task. For repo-based tasks, match by repo+branch
-	// (most recent first) since different repos can share branch names. For no-repo
-	// tasks (branch==""), match by task ID parsed from the filename, which is the
-	// only reliable disambiguator when multiple no-repo tasks share the same empty
-	// repo+branch values.
-	taskIDStr := taskID.String()
+	// Find the task's log file by its ID.
 	var lt *task.LoadedTask
 	for i := len(allLogs) - 1; i >= 0; i-- {
-		log := allLogs[i]
-		if branch == "" && ri.RelPath == "" {
-			if log.TaskID == taskIDStr {
-				lt = log
-				break
-			}
-		} else {
-			lp := log.Primary()
-			if lp != nil && lp.Branch == branch && lp.Name == ri.RelPath {
-				lt = log
-				break
-			}
+		if allLogs[i].ID() == taskID {
+			lt = allLogs[i]
+			break
 		}
 	}
 
@@ -642,10 +820,9 @@ func (s *Server) adoptOne(ctx context.Context, ri repoInfo, runner *task.Runner,
 	var startedAt time.Time
 	var stateUpdatedAt time.Time
 
-	// Read the harness from the container label (authoritative), falling
-	// back to the log file, then to Claude as the default.
-	harnessLabel, _ := container.LabelValue(ctx, c.Name, "harness")
-	harnessName := agent.Harness(harnessLabel)
+	// The harness label is authoritative, falling back to the log file, then
+	// to Claude as the default.
+	harnessName := ref.harness
 	if harnessName == "" && lt != nil {
 		harnessName = lt.Harness
 	}
@@ -689,21 +866,47 @@ func (s *Server) adoptOne(ctx context.Context, ri repoInfo, runner *task.Runner,
 		// Primary mount from repoInfo; extra mounts from log.
 		adoptRepos = []task.RepoMount{{Name: ri.RelPath, GitRoot: ri.AbsPath, Branch: branch}}
 		if lt != nil {
+			adoptRepos[0].PushRemote = lt.Repos[0].PushRemote
 			for _, lm := range lt.Repos[1:] {
 				gitRoot := ""
 				if er, ok := s.runners[lm.Name]; ok {
 					gitRoot = er.Dir
 				}
-				adoptRepos = append(adoptRepos, task.RepoMount{Name: lm.Name, BaseBranch: lm.BaseBranch, Branch: lm.Branch, GitRoot: gitRoot})
+				adoptRepos = append(adoptRepos, task.RepoMount{Name: lm.Name, BaseBranch: lm.BaseBranch, Branch: lm.Branch, GitRoot: gitRoot, PushRemote: lm.PushRemote})
 			}
 		}
 	}
 	var forgeIssue int
+	var planOnly, requirePlan, readOnly, chat, thinking bool
+	var alias string
+	var network task.NetworkMode
+	var networkAllow []string
+	var idle *task.IdlePolicy
+	var push task.PushPolicy
+	var issue *task.IssueLink
+	var slackThread *task.SlackThread
+	var env map[string]string
+	var instructions string
 	if lt != nil {
+		alias = lt.Alias
 		forgeIssue = lt.ForgeIssue
+		planOnly = lt.PlanOnly
+		requirePlan = lt.RequirePlan
+		readOnly = lt.ReadOnly
+		chat = lt.Chat
+		thinking = lt.Thinking
+		network = lt.Network
+		networkAllow = lt.NetworkAllow
+		idle = lt.Idle
+		push = lt.Push
+		issue = lt.Issue
+		slackThread = lt.Slack
+		env = lt.Env
+		instructions = lt.Instructions
 	}
 	t := &task.Task{
 		ID:            taskID,
+		Alias:         alias,
 		InitialPrompt: agent.Prompt{Text: prompt},
 		Repos:         adoptRepos,
 		Harness:       harnessName,
@@ -715,8 +918,24 @@ func (s *Server) adoptOne(ctx context.Context, ri repoInfo, runner *task.Runner,
 		Display:       c.Display,
 		Provider:      s.provider,
 		ForgeIssue:    forgeIssue,
+		PlanOnly:      planOnly,
+		RequirePlan:   requirePlan,
+		ReadOnly:      readOnly,
+		Chat:          chat,
+		Thinking:      thinking,
+		Idle:          idle,
+		Push:          push,
+		Issue:         issue,
+		Slack:         slackThread,
+		Env:           env,
+		Instructions:  instructions,
+		Network:       network,
+		NetworkAllow:  networkAllow,
 	}
 	t.SetStateAt(task.StateRunning, stateUpdatedAt)
+	if lt != nil {
+		t.SetFailover(lt.Failover)
+	}
 	// Set an immediate fallback title; GenerateTitle is fired async below
 	// after messages are restored so the LLM sees the full conversation.
 	if lt != nil && lt.Title != "" {
@@ -765,6 +984,11 @@ func (s *Server) adoptOne(ctx context.Context, ri repoInfo, runner *task.Runner,
 			slog.Warn("relay", "msg", "restored from log", "repo", ri.RelPath, "br", branch, "ctr", c.Name, "msgs", len(lt.Msgs))
 		}
 	}
+	// Restore the state history last: RestoreMessages records the states it
+	// infers, which already happened before the restart.
+	if lt != nil && lt.LoadMessages() == nil {
+		t.RestoreTransitions(lt.Transitions)
+	}
 	// RestoreMessages may infer a new state (e.g. waiting) from trailing
 	// messages, but setState stamps time.Now(). Re-apply the original
 	// timestamp so the UI timer reflects when the agent actually stopped
@@ -835,7 +1059,7 @@ func (s *Server) adoptOne(ctx context.Context, ri repoInfo, runner *task.Runner,
 					delete(s.tasks, oldID)
 				}
 			}
-			s.tasks[t.ID.String()] = entry
+			s.addTask(entry)
 			s.taskChanged()
 			s.mu.Unlock()
 			entryRegistered = true
@@ -863,7 +1087,7 @@ func (s *Server) adoptOne(ctx context.Context, ri repoInfo, runner *task.Runner,
 				delete(s.tasks, oldID)
 			}
 		}
-		s.tasks[t.ID.String()] = entry
+		s.addTask(entry)
 		s.taskChanged()
 		s.mu.Unlock()
 	}
@@ -969,30 +1193,21 @@ func (s *Server) watchContainerEvents(ctx context.Context) {
 const warmupInterval = 6 * time.Hour
 
 // warmupImages periodically calls md.Client.Warmup for the default base image
-// and any custom images configured in user preferences. This ensures the image
-// is pulled and the md-user layer is built before a task needs it.
+// and any custom or pinned images configured in user preferences. This ensures
+// the image is pulled and the md-user layer is built before a task needs it.
 func (s *Server) warmupImages() {
 	// Run immediately on startup, then every warmupInterval.
 	ticker := time.NewTicker(warmupInterval)
 	defer ticker.Stop()
 	for {
-		images := []string{md.DefaultBaseImage + ":latest"}
+		images := []string{defaultImage}
 		for _, img := range s.prefs.BaseImages() {
 			if !slices.Contains(images, img) {
 				images = append(images, img)
 			}
 		}
 		for _, img := range images {
-			w := &container.SlogWriter{Phase: "warmup"}
-			built, err := s.mdClient.Warmup(s.ctx, w, w, &md.WarmupOpts{
-				BaseImage: img,
-				Quiet:     true,
-			})
-			if err != nil {
-				slog.Warn("warmup", "image", img, "err", err)
-			} else if built {
-				slog.Info("warmup", "image", img, "built", true)
-			}
+			s.warmupImage(img)
 		}
 		select {
 		case <-ticker.C:
@@ -1002,6 +1217,20 @@ func (s *Server) warmupImages() {
 	}
 }
 
+// warmupImage pulls img and builds the md-user layer on top of it.
+func (s *Server) warmupImage(img string) {
+	w := &container.SlogWriter{Phase: "warmup"}
+	built, err := s.mdClient.Warmup(s.ctx, w, w, &md.WarmupOpts{
+		BaseImage: img,
+		Quiet:     true,
+	})
+	if err != nil {
+		slog.Warn("warmup", "image", img, "err", err)
+	} else if built {
+		slog.Info("warmup", "image", img, "built", true)
+	}
+}
+
 // handleContainerDeath looks up a task by container name and archives it.
 // The container is not destroyed — it transitions to StateStopped so it
 // can be revived on the next server restart (e.g. after a Docker or
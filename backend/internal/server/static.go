@@ -3,41 +3,59 @@
 // At build time, each file in dist/ is brotli-compressed at maximum quality
 // and the original is deleted, so only .br files are embedded. This handler
 // serves .br directly when the client accepts it, and lazily transcodes to
-// gzip, zstd, or uncompressed for other clients, caching the result.
+// gzip, zstd, or uncompressed for other clients, caching the result in a
+// size-bounded LRU. Every variant has a strong ETag derived from the .br
+// content, so conditional and Range requests work the same whatever the
+// encoding.
 package server
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"io/fs"
+	"log/slog"
 	"mime"
 	"net/http"
 	"path"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/andybalholm/brotli"
 	"github.com/klauspost/compress/gzip"
 	"github.com/klauspost/compress/zstd"
 )
 
-// transcodeEntry holds a lazily-computed transcoded variant.
-type transcodeEntry struct {
-	once sync.Once
-	data []byte
-	err  error
-}
-
 // newStaticHandler returns an http.HandlerFunc that serves precompressed
 // static files from dist with SPA fallback to index.html.
 //
 // Only .br files exist on disk. The handler serves brotli directly when
-// accepted, and lazily transcodes to zstd/gzip/identity otherwise.
-func newStaticHandler(dist fs.FS) http.HandlerFunc {
-	// cache maps "path\x00encoding" → *transcodeEntry.
-	var cache sync.Map
+// accepted, and lazily transcodes to zstd/gzip/identity otherwise, keeping
+// the result in cache.
+func newStaticHandler(dist fs.FS, cache *transcodeCache) http.HandlerFunc {
+	// etags maps path → ETag of the .br file; the embedded files never change.
+	var etags sync.Map
+	etagOf := func(clean string) (string, error) {
+		if v, ok := etags.Load(clean); ok {
+			return v.(string), nil
+		}
+		tag, err := brETag(dist, clean)
+		if err == nil {
+			etags.Store(clean, tag)
+		}
+		return tag, err
+	}
+	// links preloads the entry bundles when serving index.html.
+	links := sync.OnceValue(func() string {
+		l, err := preloadLinks(dist)
+		if err != nil {
+			slog.Warn("frontend asset manifest", "err", err)
+		}
+		return l
+	})
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet && r.Method != http.MethodHead {
@@ -62,9 +80,18 @@ func newStaticHandler(dist fs.FS) http.HandlerFunc {
 
 		accepted := parseAcceptEncoding(r.Header.Get("Accept-Encoding"))
 
+		etag, err := etagOf(clean)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if clean == "index.html" {
+			sendEarlyHints(w, r, links())
+		}
+
 		// Fast path: serve .br directly.
 		if accepted["br"] {
-			serveBrotli(w, r, dist, clean, ct)
+			serveBrotli(w, r, dist, clean, ct, etag)
 			return
 		}
 
@@ -77,26 +104,17 @@ func newStaticHandler(dist fs.FS) http.HandlerFunc {
 			}
 		}
 
-		data, err := transcode(&cache, dist, clean, enc)
+		data, err := cache.get(dist, clean, enc)
 		if err != nil {
 			http.NotFound(w, r)
 			return
 		}
-
-		w.Header().Set("Content-Type", ct)
-		if enc != "identity" {
-			w.Header().Set("Content-Encoding", enc)
-		}
-		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
-		w.Header().Set("Vary", "Accept-Encoding")
-		setStaticCacheControl(w, clean)
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write(data) //nolint:gosec // data from embedded FS, not user input
+		serveVariant(w, r, clean, ct, enc, etag, data)
 	}
 }
 
 // serveBrotli serves a .br file directly from the embedded FS.
-func serveBrotli(w http.ResponseWriter, r *http.Request, dist fs.FS, clean, ct string) {
+func serveBrotli(w http.ResponseWriter, r *http.Request, dist fs.FS, clean, ct, etag string) {
 	f, err := dist.Open(clean + ".br")
 	if err != nil {
 		http.NotFound(w, r)
@@ -112,22 +130,45 @@ func serveBrotli(w http.ResponseWriter, r *http.Request, dist fs.FS, clean, ct s
 
 	w.Header().Set("Content-Type", ct)
 	w.Header().Set("Content-Encoding", "br")
-	w.Header().Set("Content-Length", strconv.FormatInt(stat.Size(), 10))
 	w.Header().Set("Vary", "Accept-Encoding")
+	w.Header().Set("ETag", variantETag(etag, "br"))
 	setStaticCacheControl(w, clean)
+	// ServeContent sets Content-Length and handles Range and the conditional
+	// headers.
 	http.ServeContent(w, r, clean, stat.ModTime(), f.(io.ReadSeeker))
 }
 
-// transcode decompresses the .br file and re-compresses to the target
-// encoding, caching the result for subsequent requests.
-func transcode(cache *sync.Map, dist fs.FS, clean, enc string) ([]byte, error) {
-	key := clean + "\x00" + enc
-	val, _ := cache.LoadOrStore(key, &transcodeEntry{})
-	entry := val.(*transcodeEntry)
-	entry.once.Do(func() {
-		entry.data, entry.err = doTranscode(dist, clean, enc)
-	})
-	return entry.data, entry.err
+// serveVariant serves data, the transcoded variant of clean in encoding enc.
+// Like serveBrotli, it answers Range and conditional requests.
+func serveVariant(w http.ResponseWriter, r *http.Request, clean, ct, enc, etag string, data []byte) {
+	w.Header().Set("Content-Type", ct)
+	if enc != "identity" {
+		w.Header().Set("Content-Encoding", enc)
+	}
+	w.Header().Set("Vary", "Accept-Encoding")
+	w.Header().Set("ETag", variantETag(etag, enc))
+	setStaticCacheControl(w, clean)
+	http.ServeContent(w, r, clean, time.Time{}, bytes.NewReader(data))
+}
+
+// brETag returns the hash of the .br file of clean, the base of the ETags of
+// all its variants.
+func brETag(dist fs.FS, clean string) (string, error) {
+	data, err := fs.ReadFile(dist, clean+".br")
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:12]), nil
+}
+
+// variantETag returns the strong ETag of the variant of a file in encoding
+// enc. Each encoding is a different representation, hence a different tag.
+func variantETag(etag, enc string) string {
+	if enc == "identity" {
+		return `"` + etag + `"`
+	}
+	return `"` + etag + "-" + enc + `"`
 }
 
 // doTranscode performs the actual decompress-then-recompress.
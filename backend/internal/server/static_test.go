@@ -5,6 +5,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -44,7 +46,7 @@ func testFS(t *testing.T) fstest.MapFS {
 }
 
 func TestStaticHandler(t *testing.T) {
-	h := newStaticHandler(testFS(t))
+	h := newStaticHandler(testFS(t), newTranscodeCache(1<<20))
 
 	t.Run("BrotliDirect", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/assets/app.js", http.NoBody)
@@ -181,6 +183,59 @@ func TestStaticHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("ETag", func(t *testing.T) {
+		tags := map[string]bool{}
+		for _, enc := range []string{"br", "zstd", "gzip", ""} {
+			req := httptest.NewRequest(http.MethodGet, "/assets/app.js", http.NoBody)
+			req.Header.Set("Accept-Encoding", enc)
+			w := httptest.NewRecorder()
+			h(w, req)
+			tag := w.Header().Get("ETag")
+			if !strings.HasPrefix(tag, `"`) || tags[tag] {
+				t.Errorf("%q: ETag = %q, want a distinct strong ETag", enc, tag)
+			}
+			tags[tag] = true
+
+			req = httptest.NewRequest(http.MethodGet, "/assets/app.js", http.NoBody)
+			req.Header.Set("Accept-Encoding", enc)
+			req.Header.Set("If-None-Match", tag)
+			w = httptest.NewRecorder()
+			h(w, req)
+			if w.Code != http.StatusNotModified {
+				t.Errorf("%q: conditional status = %d, want 304", enc, w.Code)
+			}
+		}
+	})
+
+	t.Run("RangeTranscoded", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/assets/app.js", http.NoBody)
+		req.Header.Set("Range", "bytes=0-6")
+		w := httptest.NewRecorder()
+		h(w, req)
+
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("status = %d, want 206", w.Code)
+		}
+		if got, want := w.Header().Get("Content-Range"), "bytes 0-6/"+strconv.Itoa(len(appContent)); got != want {
+			t.Errorf("Content-Range = %q, want %q", got, want)
+		}
+		if got := w.Body.String(); got != string(appContent[:7]) {
+			t.Errorf("body = %q, want %q", got, appContent[:7])
+		}
+	})
+
+	t.Run("RangeBrotli", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/assets/app.js", http.NoBody)
+		req.Header.Set("Accept-Encoding", "br")
+		req.Header.Set("Range", "bytes=0-1")
+		w := httptest.NewRecorder()
+		h(w, req)
+
+		if w.Code != http.StatusPartialContent || w.Body.Len() != 2 {
+			t.Errorf("status = %d, %d bytes; want 206, 2 bytes", w.Code, w.Body.Len())
+		}
+	})
+
 	t.Run("BrotliPreferredOverZstd", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/assets/app.js", http.NoBody)
 		req.Header.Set("Accept-Encoding", "zstd, br, gzip")
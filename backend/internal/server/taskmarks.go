@@ -0,0 +1,235 @@
+// Task marks set by users: archived tasks are hidden from the task list but
+// keep their logs; pinned tasks sort first.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// idSet is a set of task IDs persisted as a sorted JSON list. It is guarded
+// by Server.mu.
+type idSet struct {
+	path string
+	ids  map[string]struct{}
+}
+
+// loadIDSet reads the set from path. A missing file is an empty set.
+func loadIDSet(path string) (*idSet, error) {
+	m := &idSet{path: path, ids: map[string]struct{}{}}
+	data, err := os.ReadFile(path) //nolint:gosec // G304: internal cache path
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		m.ids[id] = struct{}{}
+	}
+	return m, nil
+}
+
+// has reports whether id is in the set. A nil set is empty.
+func (m *idSet) has(id string) bool {
+	if m == nil {
+		return false
+	}
+	_, ok := m.ids[id]
+	return ok
+}
+
+// set adds or removes id and persists the set. It reports whether the set
+// changed; on a write failure the set is left unchanged.
+func (m *idSet) set(id string, on bool) (bool, error) {
+	if m.has(id) == on {
+		return false, nil
+	}
+	if on {
+		m.ids[id] = struct{}{}
+	} else {
+		delete(m.ids, id)
+	}
+	if err := m.write(); err != nil {
+		if on {
+			delete(m.ids, id)
+		} else {
+			m.ids[id] = struct{}{}
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// write writes the set to its path via a temp file + rename.
+func (m *idSet) write() error {
+	ids := make([]string, 0, len(m.ids))
+	for id := range m.ids {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	data, err := json.MarshalIndent(ids, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o700); err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// taskFilter selects the tasks of the task list and its event stream.
+type taskFilter struct {
+	includeArchived bool              // Also return archived tasks.
+	pinnedOnly      bool              // Only return pinned tasks.
+	states          []task.State      // Only return tasks in one of these states; all when empty.
+	repo            string            // Only return tasks with this repo.
+	project         string            // Only return tasks with a repo of this project.
+	repoProjects    map[string]string // The user's RepoProjects, to match project.
+	createdWithin   time.Duration     // Only return tasks created less than this ago; all when zero.
+	stateOlderThan  time.Duration     // Only return tasks whose state changed more than this ago.
+}
+
+// parseTaskFilter parses the includeArchived, pinned, state, repo, project,
+// createdWithin and stateOlderThan query parameters. state is a comma
+// separated list of states; the durations use the Go syntax, e.g. "24h".
+// project is looked up in the RepoProjects of settings.
+func parseTaskFilter(q url.Values, settings *preferences.Settings) (taskFilter, error) {
+	var f taskFilter
+	var v dto.Validator
+	for _, name := range []string{"includeArchived", "pinned"} {
+		if s := q.Get(name); s != "" {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				v.Invalid(name, s)
+				continue
+			}
+			if name == "pinned" {
+				f.pinnedOnly = b
+			} else {
+				f.includeArchived = b
+			}
+		}
+	}
+	if s := q.Get("state"); s != "" {
+		for name := range strings.SplitSeq(s, ",") {
+			st, ok := task.ParseState(strings.TrimSpace(name))
+			if !ok {
+				v.Invalid("state", name)
+				continue
+			}
+			f.states = append(f.states, st)
+		}
+	}
+	f.repo = q.Get("repo")
+	if f.project = q.Get("project"); f.project != "" {
+		f.repoProjects = settings.RepoProjects
+	}
+	for name, dst := range map[string]*time.Duration{"createdWithin": &f.createdWithin, "stateOlderThan": &f.stateOlderThan} {
+		if s := q.Get(name); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				v.Invalid(name, s)
+				continue
+			}
+			*dst = d
+		}
+	}
+	return f, v.Err()
+}
+
+// matchLocked reports whether the filter keeps e. The caller must hold s.mu.
+func (f taskFilter) matchLocked(s *Server, e *taskEntry) bool {
+	id := e.task.ID.String()
+	if !f.includeArchived && s.archived.has(id) {
+		return false
+	}
+	if f.pinnedOnly && !s.pinned.has(id) {
+		return false
+	}
+	if f.repo != "" && !slices.ContainsFunc(e.task.Repos, func(m task.RepoMount) bool { return m.Name == f.repo }) {
+		return false
+	}
+	if f.project != "" && !slices.ContainsFunc(e.task.Repos, func(m task.RepoMount) bool { return f.repoProjects[m.Name] == f.project }) {
+		return false
+	}
+	if f.createdWithin > 0 && time.Since(e.task.ID.Time()) >= f.createdWithin {
+		return false
+	}
+	if len(f.states) == 0 && f.stateOlderThan == 0 {
+		return true
+	}
+	snap := e.task.Snapshot()
+	if len(f.states) != 0 && !slices.Contains(f.states, snap.State) {
+		return false
+	}
+	return f.stateOlderThan == 0 || time.Since(snap.StateUpdatedAt) > f.stateOlderThan
+}
+
+// updateTask applies the fields set in req to the task.
+func (s *Server) updateTask(_ context.Context, entry *taskEntry, req *v1.UpdateTaskReq) (*v1.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Archived != nil {
+		if err := s.markLocked(s.archived, entry, *req.Archived); err != nil {
+			return nil, err
+		}
+	}
+	t := s.toJSON(entry)
+	return &t, nil
+}
+
+// pinTask pins the task to the top of the task list.
+func (s *Server) pinTask(_ context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.markLocked(s.pinned, entry, true); err != nil {
+		return nil, err
+	}
+	t := s.toJSON(entry)
+	return &t, nil
+}
+
+// unpinTask reverts pinTask.
+func (s *Server) unpinTask(_ context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.markLocked(s.pinned, entry, false); err != nil {
+		return nil, err
+	}
+	t := s.toJSON(entry)
+	return &t, nil
+}
+
+// markLocked adds or removes the task from set and notifies watchers. The
+// caller must hold s.mu.
+func (s *Server) markLocked(set *idSet, entry *taskEntry, on bool) error {
+	changed, err := set.set(entry.task.ID.String(), on)
+	if err != nil {
+		return dto.InternalError("save task marks: " + err.Error())
+	}
+	if changed {
+		s.taskChanged()
+	}
+	return nil
+}
@@ -3,20 +3,28 @@
 package server
 
 import (
+	"cmp"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
+	"path"
 	"slices"
-	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	"github.com/caic-xyz/caic/backend/internal/auth"
 	"github.com/caic-xyz/caic/backend/internal/forge"
 	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/pricing"
 	"github.com/caic-xyz/caic/backend/internal/server/dto"
 	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
 	"github.com/caic-xyz/caic/backend/internal/task"
@@ -31,7 +39,7 @@ func (s *Server) reposLocked() *[]v1.Repo {
 	out := make([]v1.Repo, len(s.repos))
 	for i := range s.repos {
 		r := &s.repos[i]
-		repo := v1.Repo{Path: r.RelPath, BaseBranch: v1.BranchInfo{Name: r.BaseBranch, Remote: r.BaseBranchRemote}, RemoteURL: gitutil.RemoteToHTTPS(r.Remote), Forge: v1.Forge(r.ForgeKind)}
+		repo := v1.Repo{Path: r.RelPath, Root: r.Root, Mirror: r.Mirror, DeployKey: r.DeployKey != "", PushRemote: r.PushRemote, BaseBranch: v1.BranchInfo{Name: r.BaseBranch, Remote: r.BaseBranchRemote}, RemoteURL: gitutil.RemoteToHTTPS(r.Remote), Forge: v1.Forge(r.ForgeKind)}
 		if ci, ok := s.repoCIStatus[r.RelPath]; ok {
 			repo.DefaultBranchCIStatus = v1.CIStatus(ci.Status)
 			repo.DefaultBranchChecks = ci.Checks
@@ -41,27 +49,189 @@ func (s *Server) reposLocked() *[]v1.Repo {
 	return &out
 }
 
-func (s *Server) listTasks(ctx context.Context, _ *dto.EmptyReq) (*[]v1.Task, error) {
+// handleListTasks returns the tasks visible to the user, pinned first, in the
+// order of the sort and order query parameters; see parseTaskSort. The fields
+// and view query parameters trim each task; see parseTaskProjection.
+// It supports conditional GETs with If-None-Match.
+func (s *Server) handleListTasks(w http.ResponseWriter, r *http.Request) {
+	proj, err := parseTaskProjection(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	settings := s.prefs.Get(userIDFromCtx(r.Context())).Settings
+	filter, err := parseTaskFilter(r.URL.Query(), &settings)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	order, err := parseTaskSort(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 	var ownerID string
 	if s.authEnabled() {
-		if u, ok := auth.UserFromContext(ctx); ok {
+		if u, ok := auth.UserFromContext(r.Context()); ok {
 			ownerID = u.ID
 		}
 	}
 	s.mu.Lock()
-	out := make([]v1.Task, 0, len(s.tasks))
+	tasks := make([]v1.Task, 0, len(s.tasks))
 	for _, e := range s.tasks {
 		if ownerID != "" && e.task.OwnerID != "" && e.task.OwnerID != ownerID {
 			continue
 		}
-		out = append(out, s.toJSON(e))
+		if !filter.matchLocked(s, e) {
+			continue
+		}
+		tasks = append(tasks, s.toJSON(e))
+	}
+	s.mu.Unlock()
+	order.apply(tasks)
+	out := make([]json.RawMessage, len(tasks))
+	for i := range tasks {
+		if out[i], err = proj.marshal(&tasks[i]); err != nil {
+			writeError(w, dto.InternalError("marshal task"))
+			return
+		}
+	}
+	writeConditionalJSON(w, r, &out, "")
+}
+
+// handleGetTask returns a single task with every field, including the ones
+// dropped from the summary task list. Its ETag starts with the task revision
+// so it can be sent back as If-Match on mutations.
+func (s *Server) handleGetTask(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
+	s.mu.Lock()
+	t := s.toJSON(entry)
 	s.mu.Unlock()
-	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
-	return &out, nil
+	writeConditionalJSON(w, r, &t, strconv.FormatUint(t.Revision, 10))
 }
 
 func (s *Server) createTask(ctx context.Context, req *v1.CreateTaskReq) (*v1.CreateTaskResp, error) {
+	var ownerID string
+	if u, ok := auth.UserFromContext(ctx); ok {
+		ownerID = u.ID
+	}
+	issue, err := s.resolveIssue(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := s.startTask(ctx, req, ownerID, issue)
+	if err != nil {
+		return nil, err
+	}
+
+	var repo string
+	if len(req.Repos) > 0 {
+		repo = req.Repos[0].Name
+	}
+	if err := s.prefs.Update(userIDFromCtx(ctx), func(p *preferences.Preferences) {
+		p.TouchPrompt(repo, req.InitialPrompt.Text)
+		if repo == "" {
+			return
+		}
+		p.TouchRepo(repo, &preferences.RepoPrefs{
+			BaseBranch: req.Repos[0].BaseBranch,
+			Harness:    string(req.Harness),
+			Model:      req.Model,
+		})
+		// When the user selects the default model (empty string),
+		// TouchRepo won't clear the old value because empty means
+		// "don't override". Clear it explicitly so the stale
+		// non-default model doesn't persist.
+		if req.Model == "" {
+			p.Repositories[0].Model = ""
+			delete(p.Models, string(req.Harness))
+		}
+	}); err != nil {
+		return nil, dto.InternalError("save preferences: " + err.Error())
+	}
+
+	return &v1.CreateTaskResp{Status: "accepted", ID: entry.task.ID}, nil
+}
+
+// quickCreateTask creates a task from a prompt alone; see inferCreateTaskReq.
+func (s *Server) quickCreateTask(ctx context.Context, req *v1.QuickCreateTaskReq) (*v1.QuickCreateTaskResp, error) {
+	full, err := s.inferCreateTaskReq(userIDFromCtx(ctx), req.InitialPrompt)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.createTask(ctx, full)
+	if err != nil {
+		return nil, err
+	}
+	out := &v1.QuickCreateTaskResp{Status: resp.Status, ID: resp.ID, Harness: full.Harness, Model: full.Model}
+	if len(full.Repos) > 0 {
+		out.Repo = full.Repos[0].Name
+		out.BaseBranch = full.Repos[0].BaseBranch
+	}
+	return out, nil
+}
+
+// inferCreateTaskReq fills a create request from the user's preferences: the
+// most recently used repo that still exists (or no repo), its harness and
+// model, falling back to the last used harness and its model, then to claude
+// or the first available harness. Stale models fall back to the default.
+func (s *Server) inferCreateTaskReq(userID string, prompt v1.Prompt) (*v1.CreateTaskReq, error) {
+	p := s.prefs.Get(userID)
+	req := &v1.CreateTaskReq{InitialPrompt: prompt}
+	var rp preferences.RepoPrefs
+	for _, r := range p.Repositories {
+		if _, ok := s.runners[r.Path]; ok && r.Path != "" {
+			rp = r
+			req.Repos = []v1.RepoSpec{{Name: r.Path, BaseBranch: r.BaseBranch}}
+			break
+		}
+	}
+	runner, ok := s.runners[rp.Path]
+	if !ok {
+		return nil, dto.InternalError("no-repo runner not available")
+	}
+	var backend agent.Backend
+	for _, h := range []string{rp.Harness, p.Harness, string(agent.Claude)} {
+		if b, ok := runner.Backends[agent.Harness(h)]; ok && h != "" {
+			req.Harness, backend = v1.Harness(h), b
+			break
+		}
+	}
+	if backend == nil {
+		harnesses := slices.Sorted(maps.Keys(runner.Backends))
+		if len(harnesses) == 0 {
+			return nil, dto.BadRequest("no harness available")
+		}
+		req.Harness, backend = toV1Harness(harnesses[0]), runner.Backends[harnesses[0]]
+	}
+	model := p.Models[string(req.Harness)]
+	if rp.Harness == string(req.Harness) && rp.Model != "" {
+		model = rp.Model
+	}
+	if slices.Contains(harnessModels(&p, backend), model) {
+		req.Model = model
+	}
+	return req, nil
+}
+
+// startTask validates req, registers the task owned by ownerID and starts it
+// in the background. Unlike createTask it leaves user preferences untouched,
+// so the server can start helper tasks such as reviewers. issue is the
+// linked issue, if any.
+func (s *Server) startTask(ctx context.Context, req *v1.CreateTaskReq, ownerID string, issue *task.IssueLink) (*taskEntry, error) {
+	return s.startTaskStep(ctx, req, ownerID, issue, nil, 0)
+}
+
+// startTaskStep is startTask for the task of step of pipeline run. A nil run
+// starts the pipeline of req, if any.
+func (s *Server) startTaskStep(ctx context.Context, req *v1.CreateTaskReq, ownerID string, issue *task.IssueLink, run *pipelineRun, step int) (*taskEntry, error) {
+	if err := s.checkDiskQuota(); err != nil {
+		return nil, err
+	}
 	// Resolve primary runner (first repo, or no-repo).
 	var primaryRunner *task.Runner
 	if len(req.Repos) > 0 {
@@ -94,7 +264,17 @@ func (s *Server) createTask(ctx context.Context, req *v1.CreateTaskReq) (*v1.Cre
 		return nil, dto.BadRequest("unknown harness: " + string(req.Harness))
 	}
 
-	if req.Model != "" && !slices.Contains(backend.Models(), req.Model) {
+	models := backend.Models()
+	if harness == agent.Generic {
+		prefs := s.prefs.Get(userIDFromCtx(ctx))
+		if models = harnessModels(&prefs, backend); len(models) == 0 {
+			return nil, dto.BadRequest("the generic harness is not configured; set it up in settings")
+		}
+		if req.Model == "" {
+			req.Model = models[0]
+		}
+	}
+	if req.Model != "" && !slices.Contains(models, req.Model) {
 		return nil, dto.BadRequest("unsupported model for " + string(req.Harness) + ": " + req.Model)
 	}
 
@@ -102,9 +282,50 @@ func (s *Server) createTask(ctx context.Context, req *v1.CreateTaskReq) (*v1.Cre
 		return nil, dto.BadRequest(string(req.Harness) + " does not support images")
 	}
 
-	var ownerID string
-	if u, ok := auth.UserFromContext(ctx); ok {
-		ownerID = u.ID
+	if (req.PlanOnly || req.RequirePlan) && !backend.SupportsPlanOnly() {
+		return nil, dto.BadRequest(string(req.Harness) + " does not support plan-only mode")
+	}
+
+	if req.Thinking && !backend.SupportsThinking() {
+		return nil, dto.BadRequest(string(req.Harness) + " does not support extended thinking")
+	}
+
+	deps, err := s.resolveDependencies(req)
+	if err != nil {
+		return nil, err
+	}
+	initialPrompt := v1PromptToAgent(req.InitialPrompt)
+	if run == nil && req.Pipeline != nil {
+		var first string
+		if run, first, err = newPipelineRun(req); err != nil {
+			return nil, err
+		}
+		// The first step replaces the initial prompt; its text is the input.
+		initialPrompt.Text = first
+	}
+	if req.GatherContext {
+		branch := primaryRunner.BaseBranch
+		if b := req.Repos[0].BaseBranch; b != "" {
+			branch = b
+		}
+		initialPrompt.Text = gatherContext(ctx, s.indexes, primaryRunner.Dir, branch, initialPrompt.Text)
+	}
+	var review *reviewRun
+	if req.Review != nil {
+		if _, ok := primaryRunner.Backends[toAgentHarness(req.Review.Harness)]; !ok {
+			return nil, dto.BadRequest("unknown review harness: " + string(req.Review.Harness))
+		}
+		// A pipeline is reviewed once, on the task of its last step.
+		if run == nil || step == len(run.def.Steps)-1 {
+			review = &reviewRun{spec: *req.Review, status: "pending"}
+			if review.spec.MaxRounds == 0 {
+				review.spec.MaxRounds = defaultReviewRounds
+			}
+		}
+	}
+	dependsOn := make([]ksid.ID, len(deps))
+	for i, d := range deps {
+		dependsOn[i] = d.task.ID
 	}
 
 	// Build RepoMount slice — GitRoot filled immediately from runner.Dir.
@@ -113,38 +334,139 @@ func (s *Server) createTask(ctx context.Context, req *v1.CreateTaskReq) (*v1.Cre
 		r := s.runners[rs.Name]
 		mounts[i] = task.RepoMount{Name: rs.Name, BaseBranch: rs.BaseBranch, GitRoot: r.Dir}
 	}
+	s.deployKeyMounts(mounts)
+	if err := s.pushRemoteMounts(ctx, mounts, req.Repos); err != nil {
+		return nil, err
+	}
 
 	// Resolve docker image and GitHub token access from user preferences.
 	prefs := s.prefs.Get(userIDFromCtx(ctx))
-	dockerImage := prefs.Settings.BaseImage
+	primaryRepo := ""
+	if len(mounts) > 0 {
+		primaryRepo = mounts[0].Name
+	}
+	rootDefaults := s.rootDefaults(primaryRepo)
+	network, networkAllow := task.NetworkMode(req.Network), req.NetworkAllow
+	if network == "" && rootDefaults.Network != "" {
+		network, networkAllow = task.NetworkMode(rootDefaults.Network), rootDefaults.NetworkAllow
+	}
+	if network == "" {
+		network, networkAllow = task.NetworkMode(prefs.Settings.Network), prefs.Settings.NetworkAllow
+	}
+	if req.Tailscale && network.Restricted() {
+		return nil, dto.BadRequest("tailscale requires full network access; the default network mode is " + string(network))
+	}
+	for k := range req.Env {
+		if !envAllowed(prefs.Settings.EnvAllowlist, k) {
+			return nil, dto.BadRequest("env " + k + " is not in the envAllowlist of the settings")
+		}
+	}
+	baseBranch := ""
+	if len(req.Repos) > 0 {
+		baseBranch = req.Repos[0].BaseBranch
+	}
+	instructions, err := taskInstructions(ctx, primaryRunner, baseBranch, &prefs.Settings, primaryRepo, req.Instructions)
+	if err != nil {
+		return nil, err
+	}
+	dockerImage := pinnedImage(&prefs.Settings, cmp.Or(rootDefaults.BaseImage, prefs.Settings.BaseImage))
 	ghToken := s.resolveGitHubContainerToken(ctx, prefs.Settings.GitHubTokenAccess)
 
 	t := &task.Task{
 		ID:            ksid.NewID(),
-		InitialPrompt: v1PromptToAgent(req.InitialPrompt),
+		InitialPrompt: initialPrompt,
 		Repos:         mounts,
 		Harness:       harness,
 		Model:         req.Model,
+		Endpoint:      harnessEndpoint(&prefs, harness),
+		Fallback:      taskFallback(&prefs),
 		DockerImage:   dockerImage,
 		GitHubToken:   ghToken,
 		Tailscale:     req.Tailscale,
 		USB:           req.USB,
 		Display:       req.Display,
+		PlanOnly:      req.PlanOnly,
+		RequirePlan:   req.RequirePlan,
+		ReadOnly:      req.ReadOnly,
+		Chat:          req.Chat,
+		Thinking:      req.Thinking,
+		Idle:          fromV1IdlePolicy(req.IdlePolicy),
+		Push:          task.PushPolicy(req.PushPolicy),
+		Issue:         issue,
+		Env:           req.Env,
+		Instructions:  instructions,
+		DependsOn:     dependsOn,
 		StartedAt:     time.Now().UTC(),
 		OwnerID:       ownerID,
 		Provider:      s.provider,
+		Network:       network,
+		NetworkAllow:  networkAllow,
+		Policy:        toolPolicy(ctx, &prefs, primaryRepo, primaryRunner),
+	}
+	if run != nil && step == 0 && len(run.def.Steps) > 1 && !s.pushPolicy(t).Automatic() {
+		return nil, dto.BadRequest("each pipeline step is stacked on the pushed branch of the previous one; the push policy must be auto")
 	}
 	t.SetTitle(req.InitialPrompt.Text)
 	go t.GenerateTitle(s.ctx) //nolint:contextcheck // fire-and-forget; must outlive request
-	entry := &taskEntry{task: t, done: make(chan struct{})}
+	priority := req.Priority
+	if priority == "" {
+		priority = v1.PriorityNormal
+	}
+	entry := &taskEntry{task: t, done: make(chan struct{}), pipeline: run, pipelineStep: step, review: review, priority: priority}
+	waitCtx, cancelWait := context.WithCancel(s.ctx)
+	entry.cancelWait = cancelWait
 
 	s.mu.Lock()
-	s.tasks[t.ID.String()] = entry
+	if run != nil {
+		run.tasks = append(run.tasks, t.ID)
+		run.step = step
+	}
+	s.addNewTask(entry)
 	s.taskChanged()
 	s.mu.Unlock()
+	if issue != nil {
+		go s.watchIssue(entry, false)
+	}
 
 	// Run in background using the server context, not the request context.
 	go func() {
+		var err error
+		if len(deps) > 0 {
+			err = s.waitForDependencies(waitCtx, entry, deps, req.OnDependencyFailure)
+		}
+		if err == nil {
+			s.mu.Lock()
+			entry.ready = true
+			s.mu.Unlock()
+			err = s.waitForWindow(waitCtx, entry)
+		}
+		if err == nil {
+			err = s.waitInQueue(waitCtx, entry)
+		}
+		if err == nil && req.InheritBranch {
+			err = s.inheritBranch(s.ctx, t, deps[0].task)
+		}
+		s.mu.Lock()
+		entry.cancelWait()
+		entry.cancelWait = nil
+		entry.ready = false
+		s.taskChanged()
+		s.mu.Unlock()
+		if err != nil {
+			state := task.StateFailed
+			if errors.Is(err, errWaitCanceled) {
+				state = task.StatePurged
+			}
+			t.SetState(state)
+			result := task.Result{State: state, Err: err}
+			s.mu.Lock()
+			entry.result = &result
+			s.taskChanged()
+			s.mu.Unlock()
+			s.failPipelineStep(entry, err)
+			close(entry.done)
+			return
+		}
 		// Allocate branches for extra repos before starting the container.
 		for i, er := range extraRunners {
 			branch, err := er.AllocateBranch(s.ctx)
@@ -154,6 +476,7 @@ func (s *Server) createTask(ctx context.Context, req *v1.CreateTaskReq) (*v1.Cre
 				entry.result = &result
 				s.taskChanged()
 				s.mu.Unlock()
+				s.failPipelineStep(entry, result.Err)
 				close(entry.done)
 				return
 			}
@@ -167,35 +490,24 @@ func (s *Server) createTask(ctx context.Context, req *v1.CreateTaskReq) (*v1.Cre
 			entry.result = &result
 			s.taskChanged()
 			s.mu.Unlock()
+			s.failPipelineStep(entry, err)
 			close(entry.done)
 			return
 		}
 		s.watchSession(entry, primaryRunner, h)
-	}()
-
-	go s.maybeFakeCI(t)
-
-	if len(req.Repos) > 0 {
-		if err := s.prefs.Update(userIDFromCtx(ctx), func(p *preferences.Preferences) {
-			p.TouchRepo(req.Repos[0].Name, &preferences.RepoPrefs{
-				BaseBranch: req.Repos[0].BaseBranch,
-				Harness:    string(req.Harness),
-				Model:      req.Model,
-			})
-			// When the user selects the default model (empty string),
-			// TouchRepo won't clear the old value because empty means
-			// "don't override". Clear it explicitly so the stale
-			// non-default model doesn't persist.
-			if req.Model == "" {
-				p.Repositories[0].Model = ""
-				delete(p.Models, string(req.Harness))
+		if run != nil && !s.runPipelineStep(entry, primaryRunner) {
+			if review != nil {
+				s.setReviewStatus(entry, 0, "aborted", "", "pipeline did not complete")
 			}
-		}); err != nil {
-			return nil, dto.InternalError("save preferences: " + err.Error())
+			return
 		}
-	}
+		if review != nil {
+			s.runReview(entry, primaryRunner)
+		}
+	}()
 
-	return &v1.CreateTaskResp{Status: "accepted", ID: t.ID}, nil
+	go s.maybeFakeCI(t)
+	return entry, nil
 }
 
 // handleTaskRawEvents delegates to handleTaskEvents — both endpoints now
@@ -204,14 +516,66 @@ func (s *Server) handleTaskRawEvents(w http.ResponseWriter, r *http.Request) {
 	s.handleTaskEvents(w, r)
 }
 
+// readyEvent is the data of the "ready" event that ends the history replay of
+// handleTaskEvents.
+type readyEvent struct {
+	From        int             `json:"from"`
+	Total       int             `json:"total"`
+	Annotations []v1.Annotation `json:"annotations,omitempty"`
+}
+
 // handleTaskEvents streams agent messages as SSE using backend-neutral
 // EventMessage DTOs. All tool invocations are emitted as toolUse events.
+//
+// The history replay starts at the message index in the from query
+// parameter; a negative from replays only the last -from messages. The
+// "ready" event reports the index replay started at and the history length,
+// so clients can fetch older messages from handleGetTaskMessages, along with
+// the transcript annotations.
+//
+// The batch query parameter, in milliseconds, coalesces events into "batch"
+// events holding JSON arrays; see taskEventWriter.
+//
+// With replay=realtime the history is replayed with its original pacing,
+// divided by the speed query parameter, and events carry the estimated
+// original timestamps; see replayTimes.
 func (s *Server) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
 	entry, err := s.getTask(r)
 	if err != nil {
 		writeError(w, err)
 		return
 	}
+	q := r.URL.Query()
+	from := 0
+	if v := q.Get("from"); v != "" {
+		if from, err = strconv.Atoi(v); err != nil {
+			writeError(w, dto.BadRequest("invalid from: "+v))
+			return
+		}
+	}
+	batchMS := 0
+	if v := q.Get("batch"); v != "" {
+		if batchMS, err = strconv.Atoi(v); err != nil || batchMS < 0 || batchMS > 1000 {
+			writeError(w, dto.BadRequest("invalid batch: "+v+" (milliseconds, at most 1000)"))
+			return
+		}
+	}
+	realtime := false
+	switch v := q.Get("replay"); v {
+	case "":
+	case "realtime":
+		realtime = true
+	default:
+		writeError(w, dto.BadRequest("invalid replay: "+v+" (realtime)"))
+		return
+	}
+	speed := 1.0
+	if v := q.Get("speed"); v != "" {
+		if speed, err = strconv.ParseFloat(v, 64); err != nil || !realtime || !(speed > 0 && speed <= maxReplaySpeed) {
+			writeError(w, dto.BadRequest("invalid speed: "+v+" (replay=realtime only, at most 100)"))
+			return
+		}
+	}
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -229,34 +593,51 @@ func (s *Server) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
 	statsHistory, statsLive, statsUnsub := entry.task.SubscribeStats(r.Context())
 	defer statsUnsub()
 
-	tracker := newToolTimingTracker(entry.task.Harness)
-	idx := 0
-
+	tracker := newToolTimingTracker(entry.task.Snapshot().Harness)
+	tw := newTaskEventWriter(w, flusher, time.Duration(batchMS)*time.Millisecond)
 	writeEvents := func(events []v1.EventMessage) {
 		for i := range events {
-			data, err := marshalEvent(&events[i])
-			if err != nil {
-				slog.Warn("marshal SSE event", "err", err)
-				continue
-			}
-			_, _ = fmt.Fprintf(w, "event: message\ndata: %s\nid: %d\n\n", data, idx)
-			idx++
+			tw.write(&events[i])
 		}
 	}
 
+	ka := s.newSSEKeepAlive(w)
+	defer ka.stop()
+	if from < 0 {
+		from += len(history)
+	}
+	from = min(max(from, 0), len(history))
 	now := time.Now()
-	for _, msg := range filterHistoryForReplay(history) {
-		writeEvents(tracker.convertMessage(msg, now))
+	skip := replaySkips(history[from:])
+	var times []time.Time
+	if realtime {
+		times = replayTimes(history[from:], now)
+	}
+	var prev time.Time
+	for i, msg := range history[from:] {
+		if skip[i] {
+			continue
+		}
+		at := now
+		if realtime {
+			at = times[i]
+			if !prev.IsZero() && !sleepReplay(r.Context(), ka, replayDelay(prev, at, speed)) {
+				return
+			}
+			prev = at
+		}
+		writeEvents(tracker.convertIndexedMessage(msg, at, from+i))
+		if realtime {
+			tw.flush()
+		}
 	}
 	for i := range statsHistory {
 		ev := statsToEvent(&statsHistory[i])
-		data, err := marshalEvent(&ev)
-		if err == nil {
-			_, _ = fmt.Fprintf(w, "event: message\ndata: %s\nid: %d\n\n", data, idx)
-			idx++
-		}
+		tw.write(&ev)
 	}
-	_, _ = fmt.Fprint(w, "event: ready\ndata: {}\n\n")
+	tw.flush()
+	ready, _ := json.Marshal(readyEvent{From: from, Total: len(history), Annotations: s.taskAnnotations(entry, 0, len(history))})
+	_, _ = fmt.Fprintf(w, "event: ready\ndata: %s\n\n", ready)
 	flusher.Flush()
 
 	state := entry.task.GetState()
@@ -264,6 +645,7 @@ func (s *Server) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	next := len(history) // History index of the next live message.
 	liveCh := live
 	statsCh := statsLive
 	for liveCh != nil || statsCh != nil {
@@ -273,22 +655,33 @@ func (s *Server) handleTaskEvents(w http.ResponseWriter, r *http.Request) {
 				liveCh = nil
 				continue
 			}
-			writeEvents(tracker.convertMessage(msg, time.Now()))
-			flusher.Flush()
+			if ov, ok := msg.(*task.OverflowMessage); ok {
+				slog.Warn("SSE client fell behind", "task", entry.task.ID, "dropped", ov.Dropped)
+				writeEvents([]v1.EventMessage{{Kind: v1.EventKindOverflow, Ts: time.Now().UTC(), Overflow: &v1.EventOverflow{Dropped: ov.Dropped}}})
+				next += ov.Dropped
+			} else {
+				writeEvents(tracker.convertIndexedMessage(msg, time.Now(), next))
+				next++
+			}
+			tw.flushSoon()
 		case cs, ok := <-statsCh:
 			if !ok {
 				statsCh = nil
 				continue
 			}
 			ev := statsToEvent(&cs)
-			data, err := marshalEvent(&ev)
-			if err == nil {
-				_, _ = fmt.Fprintf(w, "event: message\ndata: %s\nid: %d\n\n", data, idx)
-				idx++
+			tw.write(&ev)
+			tw.flushSoon()
+		case <-tw.timerC():
+			tw.onTimer()
+		case <-ka.C():
+			if err := ka.ping(); err != nil {
+				slog.DebugContext(r.Context(), "SSE subscriber gone", "task", entry.task.ID, "err", err)
+				return
 			}
-			flusher.Flush()
 		}
 	}
+	tw.flush()
 }
 
 // handleTaskToolInput returns the full (untruncated) input for a tool call.
@@ -316,6 +709,137 @@ func (s *Server) handleTaskToolInput(w http.ResponseWriter, r *http.Request) {
 	writeError(w, dto.NotFound("tool use"))
 }
 
+// Page sizes for handleGetTaskMessages.
+const (
+	defaultMessagesLimit = 500
+	maxMessagesLimit     = 5000
+)
+
+// handleGetTaskMessages returns a page of the task transcript as events, so
+// clients that replayed only the tail of the history can lazy-load older
+// chunks. after is the index of the first message and limit the page size.
+func (s *Server) handleGetTaskMessages(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	q := r.URL.Query()
+	after, limit := 0, defaultMessagesLimit
+	if v := q.Get("after"); v != "" {
+		if after, err = strconv.Atoi(v); err != nil || after < 0 {
+			writeError(w, dto.BadRequest("invalid after: "+v))
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			writeError(w, dto.BadRequest("invalid limit: "+v))
+			return
+		}
+		limit = min(limit, maxMessagesLimit)
+	}
+	msgs, total := entry.task.MessagesPage(after, limit)
+	resp := v1.TaskMessagesResp{Events: []v1.EventMessage{}, Next: min(after, total) + len(msgs), Total: total}
+	resp.Annotations = s.taskAnnotations(entry, after, resp.Next)
+	tracker := newToolTimingTracker(entry.task.Snapshot().Harness)
+	now := time.Now()
+	skip := replaySkips(msgs)
+	for i, msg := range msgs {
+		if !skip[i] {
+			resp.Events = append(resp.Events, tracker.convertIndexedMessage(msg, now, after+i)...)
+		}
+	}
+	writeJSONResponse(w, &resp, nil)
+}
+
+// handleGetMessageContent returns the full content of the history message at
+// index, whose events were truncated to a preview in the event stream. Content
+// that is not valid UTF-8 is base64 encoded.
+func (s *Server) handleGetMessageContent(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	v := r.PathValue("index")
+	index, err := strconv.Atoi(v)
+	if err != nil || index < 0 {
+		writeError(w, dto.BadRequest("invalid index: "+v))
+		return
+	}
+	msgs, _ := entry.task.MessagesPage(index, 1)
+	if len(msgs) == 0 {
+		writeError(w, dto.NotFound("message"))
+		return
+	}
+	content, ok := messageContent(msgs[0])
+	if !ok {
+		writeError(w, dto.BadRequest("message has no content").WithDetail("type", msgs[0].Type()))
+		return
+	}
+	resp := v1.MessageContentResp{Content: content}
+	if !utf8.ValidString(content) {
+		resp.Content = base64.StdEncoding.EncodeToString([]byte(content))
+		resp.Encoding = "base64"
+	}
+	writeJSONResponse(w, &resp, nil)
+}
+
+// handleGetTransitions returns the task's state transition history.
+func (s *Server) handleGetTransitions(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	trs := entry.task.Transitions()
+	out := make([]v1.StateTransition, len(trs))
+	for i, tr := range trs {
+		out[i] = v1.StateTransition{From: tr.From.String(), To: tr.To.String(), At: tr.At.UTC()}
+	}
+	writeJSONResponse(w, &out, nil)
+}
+
+// handleGetTimeline returns where the task's time went, phase by phase.
+func (s *Server) handleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSONResponse(w, toV1Timeline(entry.task.Timeline(), time.Now().UTC()), nil)
+}
+
+// toV1Timeline converts the phases of a task, measuring those in progress up
+// to now.
+func toV1Timeline(phases []task.Phase, now time.Time) *v1.TaskTimeline {
+	out := &v1.TaskTimeline{Phases: make([]v1.TimelinePhase, len(phases)), PhaseMs: map[string]int64{}}
+	var first, last time.Time
+	for i, p := range phases {
+		end := p.End
+		if end.IsZero() {
+			end = now
+		}
+		d := end.Sub(p.Start).Milliseconds()
+		out.Phases[i] = v1.TimelinePhase{Name: p.Name, Start: p.Start, End: p.End, DurationMs: d, Turn: p.Turn, Error: p.Err}
+		if !p.FirstOutput.IsZero() {
+			out.Phases[i].FirstOutputMs = max(1, p.FirstOutput.Sub(p.Start).Milliseconds())
+		}
+		out.PhaseMs[p.Name] += d
+		if first.IsZero() || p.Start.Before(first) {
+			first = p.Start
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+	if !first.IsZero() {
+		out.TotalMs = last.Sub(first).Milliseconds()
+	}
+	return out
+}
+
 // sendInput forwards user input to the agent session. On failure, it probes
 // the relay daemon's liveness over SSH and returns diagnostic details in the
 // 409 response so the frontend can show the user what went wrong.
@@ -330,8 +854,9 @@ func (s *Server) sendInput(ctx context.Context, entry *taskEntry, req *v1.InputR
 			primaryName = p.Name
 		}
 		runner := s.runners[primaryName]
-		if b := runner.Backends[entry.task.Harness]; b != nil && !b.SupportsImages() {
-			return nil, dto.BadRequest(string(entry.task.Harness) + " does not support images")
+		harness := entry.task.Snapshot().Harness
+		if b := runner.Backends[harness]; b != nil && !b.SupportsImages() {
+			return nil, dto.BadRequest(string(harness) + " does not support images")
 		}
 	}
 	if err := entry.task.SendInput(ctx, v1PromptToAgent(req.Prompt)); err != nil {
@@ -371,7 +896,7 @@ func (s *Server) sendInput(ctx context.Context, entry *taskEntry, req *v1.InputR
 
 func (s *Server) restartTask(_ context.Context, entry *taskEntry, req *v1.RestartReq) (*v1.StatusResp, error) {
 	t := entry.task
-	if state := t.GetState(); state != task.StateWaiting && state != task.StateAsking && state != task.StateHasPlan {
+	if state := t.GetState(); state != task.StateWaiting && state != task.StateAsking && state != task.StateHasPlan && state != task.StatePlanReview {
 		return nil, dto.Conflict("task is not waiting or asking")
 	}
 	prompt := v1PromptToAgent(req.Prompt)
@@ -401,9 +926,30 @@ func (s *Server) restartTask(_ context.Context, entry *taskEntry, req *v1.Restar
 	return &v1.StatusResp{Status: "restarted"}, nil
 }
 
+func (s *Server) rewindTask(_ context.Context, entry *taskEntry, req *v1.RewindReq) (*v1.StatusResp, error) {
+	t := entry.task
+	if state := t.GetState(); state != task.StateWaiting && state != task.StateAsking && state != task.StateHasPlan && state != task.StatePlanReview {
+		return nil, dto.Conflict("task is not waiting or asking")
+	}
+	runner := s.taskRunner(t)
+	// Use the server-lifetime context, not the HTTP request context.
+	h, err := runner.RewindSession(s.ctx, t, v1PromptToAgent(req.Prompt)) //nolint:contextcheck // intentionally using server context
+	if errors.Is(err, task.ErrNothingToRewind) {
+		return nil, dto.Conflict(err.Error())
+	}
+	if err != nil {
+		return nil, dto.InternalError(err.Error())
+	}
+	s.watchSession(entry, runner, h)
+	s.mu.Lock()
+	s.taskChanged()
+	s.mu.Unlock()
+	return &v1.StatusResp{Status: "rewound"}, nil
+}
+
 func (s *Server) clearContext(_ context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.StatusResp, error) {
 	t := entry.task
-	if state := t.GetState(); state != task.StateWaiting && state != task.StateAsking && state != task.StateHasPlan {
+	if state := t.GetState(); state != task.StateWaiting && state != task.StateAsking && state != task.StateHasPlan && state != task.StatePlanReview {
 		return nil, dto.Conflict("task is not waiting or asking")
 	}
 	primaryName := ""
@@ -430,9 +976,16 @@ func (s *Server) compactContext(ctx context.Context, entry *taskEntry, req *v1.C
 	return &v1.StatusResp{Status: "compacting"}, nil
 }
 
+func (s *Server) interruptTask(ctx context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.StatusResp, error) {
+	if err := entry.task.Interrupt(ctx); err != nil {
+		return nil, dto.Conflict(err.Error())
+	}
+	return &v1.StatusResp{Status: "interrupting"}, nil
+}
+
 func (s *Server) stopTask(_ context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.StatusResp, error) {
 	state := entry.task.GetState()
-	if state != task.StateWaiting && state != task.StateAsking && state != task.StateHasPlan && state != task.StateRunning {
+	if state != task.StateWaiting && state != task.StateAsking && state != task.StateHasPlan && state != task.StatePlanReview && state != task.StateRateLimited && state != task.StateRunning {
 		return nil, dto.Conflict("task is not running or waiting")
 	}
 	entry.task.SetState(task.StateStopping)
@@ -444,6 +997,7 @@ func (s *Server) stopTask(_ context.Context, entry *taskEntry, _ *dto.EmptyReq)
 		stopPrimaryName = p.Name
 	}
 	runner := s.runners[stopPrimaryName]
+	s.setPersist(entry.task)
 	go func() {
 		runner.StopTask(s.ctx, entry.task)
 		s.mu.Lock()
@@ -455,7 +1009,19 @@ func (s *Server) stopTask(_ context.Context, entry *taskEntry, _ *dto.EmptyReq)
 
 func (s *Server) purgeTask(_ context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.StatusResp, error) {
 	state := entry.task.GetState()
-	if state != task.StateWaiting && state != task.StateAsking && state != task.StateHasPlan && state != task.StateRunning && state != task.StateStopping && state != task.StateStopped {
+	if state == task.StatePending {
+		s.mu.Lock()
+		cancel := entry.cancelWait
+		s.mu.Unlock()
+		if cancel == nil {
+			return nil, dto.Conflict("task is not running or waiting")
+		}
+		// Task still waiting on dependencies or the execution window: abort
+		// the wait; it never started.
+		cancel()
+		return &v1.StatusResp{Status: "purging"}, nil
+	}
+	if state != task.StateWaiting && state != task.StateAsking && state != task.StateHasPlan && state != task.StatePlanReview && state != task.StateRateLimited && state != task.StateRunning && state != task.StateStopping && state != task.StateStopped {
 		return nil, dto.Conflict("task is not running or waiting")
 	}
 	entry.task.SetState(task.StatePurging)
@@ -471,14 +1037,58 @@ func (s *Server) purgeTask(_ context.Context, entry *taskEntry, _ *dto.EmptyReq)
 	return &v1.StatusResp{Status: "purging"}, nil
 }
 
-func (s *Server) reviveTask(_ context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.StatusResp, error) {
-	state := entry.task.GetState()
-	if state != task.StateStopped {
-		return nil, dto.Conflict("task is not stopped")
+// rehydrateTask restores the saved workspace of a task that ended into a
+// fresh container, for when the previous container is gone and cannot be
+// revived.
+func (s *Server) rehydrateTask(ctx context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.StatusResp, error) {
+	t := entry.task
+	state := t.GetState()
+	switch state {
+	case task.StateStopped, task.StateFailed, task.StatePurged:
+	default:
+		return nil, dto.Conflict("task is not stopped, failed or purged")
 	}
-	revivePrimaryName := ""
-	if p := entry.task.Primary(); p != nil {
-		revivePrimaryName = p.Name
+	primaryName := ""
+	if p := t.Primary(); p != nil {
+		primaryName = p.Name
+	}
+	runner := s.runners[primaryName]
+	if runner == nil || !runner.HasSavedWorkspace(ctx, t) {
+		return nil, dto.Conflict("task has no saved workspace")
+	}
+	if err := s.checkDiskQuota(); err != nil {
+		return nil, err
+	}
+	if !t.SetStateIf(state, task.StateProvisioning) {
+		return nil, dto.Conflict("task state changed")
+	}
+	s.mu.Lock()
+	// Reset done channel so watchSession works on the rehydrated task.
+	entry.done = make(chan struct{})
+	entry.result = nil
+	entry.cleanupOnce = sync.Once{}
+	s.taskChanged()
+	s.mu.Unlock()
+	go func() {
+		h, err := runner.RehydrateTask(s.ctx, t)
+		if err != nil {
+			slog.Warn("rehydrate failed", "task", t.ID, "err", err)
+			return
+		}
+		s.watchSession(entry, runner, h)
+		s.notifyTaskChange()
+	}()
+	return &v1.StatusResp{Status: "provisioning"}, nil
+}
+
+func (s *Server) reviveTask(_ context.Context, entry *taskEntry, _ *dto.EmptyReq) (*v1.StatusResp, error) {
+	state := entry.task.GetState()
+	if state != task.StateStopped {
+		return nil, dto.Conflict("task is not stopped")
+	}
+	revivePrimaryName := ""
+	if p := entry.task.Primary(); p != nil {
+		revivePrimaryName = p.Name
 	}
 	runner := s.runners[revivePrimaryName]
 	entry.task.SetState(task.StateProvisioning)
@@ -504,7 +1114,7 @@ func (s *Server) forkTask(ctx context.Context, entry *taskEntry, req *v1.ForkTas
 	source := entry.task
 	state := source.GetState()
 	switch state {
-	case task.StateRunning, task.StateWaiting, task.StateAsking, task.StateHasPlan:
+	case task.StateRunning, task.StateWaiting, task.StateAsking, task.StateHasPlan, task.StatePlanReview, task.StateRateLimited:
 	default:
 		return nil, dto.Conflict("task must be running or waiting to fork")
 	}
@@ -514,11 +1124,22 @@ func (s *Server) forkTask(ctx context.Context, entry *taskEntry, req *v1.ForkTas
 	if len(source.Repos) == 0 {
 		return nil, dto.BadRequest("cannot fork a no-repo task")
 	}
+	if source.ReadOnly {
+		// The forked container would inherit repos it cannot check out a branch in.
+		return nil, dto.BadRequest("cannot fork a read-only task")
+	}
+	if source.Chat {
+		return nil, dto.BadRequest("cannot fork a chat task")
+	}
+	if err := s.checkDiskQuota(); err != nil {
+		return nil, err
+	}
 
 	primaryName := source.Primary().Name
 	runner := s.runners[primaryName]
 
 	// Resolve harness and model: use overrides from the request, falling back to source.
+	prefs := s.prefs.Get(userIDFromCtx(ctx))
 	forkHarness := source.Harness
 	forkModel := source.Model
 	if req.Harness != "" {
@@ -527,17 +1148,24 @@ func (s *Server) forkTask(ctx context.Context, entry *taskEntry, req *v1.ForkTas
 		if !ok {
 			return nil, dto.BadRequest("unknown harness: " + string(req.Harness))
 		}
-		if req.Model != "" && !slices.Contains(backend.Models(), req.Model) {
+		models := harnessModels(&prefs, backend)
+		if forkHarness == agent.Generic && len(models) == 0 {
+			return nil, dto.BadRequest("the generic harness is not configured; set it up in settings")
+		}
+		if req.Model != "" && !slices.Contains(models, req.Model) {
 			return nil, dto.BadRequest("unsupported model for " + string(req.Harness) + ": " + req.Model)
 		}
 		forkModel = req.Model
+		if forkModel == "" && forkHarness == agent.Generic {
+			forkModel = models[0]
+		}
 	} else if req.Model != "" {
 		// Model override without harness override: validate against source harness.
 		backend, ok := runner.Backends[forkHarness]
 		if !ok {
 			return nil, dto.BadRequest("unknown harness: " + string(source.Harness))
 		}
-		if !slices.Contains(backend.Models(), req.Model) {
+		if !slices.Contains(harnessModels(&prefs, backend), req.Model) {
 			return nil, dto.BadRequest("unsupported model for " + string(source.Harness) + ": " + req.Model)
 		}
 		forkModel = req.Model
@@ -574,7 +1202,6 @@ func (s *Server) forkTask(ctx context.Context, entry *taskEntry, req *v1.ForkTas
 	}
 	mounts = append(mounts, extraMounts...)
 
-	prefs := s.prefs.Get(userIDFromCtx(ctx))
 	ghToken := s.resolveGitHubContainerToken(ctx, prefs.Settings.GitHubTokenAccess)
 
 	prompt := v1PromptToAgent(req.Prompt)
@@ -584,28 +1211,44 @@ func (s *Server) forkTask(ctx context.Context, entry *taskEntry, req *v1.ForkTas
 		Repos:         mounts,
 		Harness:       forkHarness,
 		Model:         forkModel,
+		Endpoint:      harnessEndpoint(&prefs, forkHarness),
 		DockerImage:   source.DockerImage,
 		GitHubToken:   ghToken,
 		Tailscale:     source.Tailscale,
 		USB:           source.USB,
 		Display:       source.Display,
+		PlanOnly:      source.PlanOnly,
+		RequirePlan:   source.RequirePlan,
+		Thinking:      source.Thinking,
+		Idle:          source.Idle,
+		Push:          source.Push,
+		Issue:         source.Issue,
+		Env:           source.Env,
+		Instructions:  source.Instructions,
 		StartedAt:     time.Now().UTC(),
 		OwnerID:       ownerID,
 		Provider:      s.provider,
+		Network:       source.Network,
+		NetworkAllow:  source.NetworkAllow,
+		Policy:        source.Policy,
 	}
 	t.SetTitle(req.Prompt.Text)
 	go t.GenerateTitle(s.ctx) //nolint:contextcheck // fire-and-forget; must outlive request
 	forkEntry := &taskEntry{task: t, done: make(chan struct{})}
 
 	s.mu.Lock()
-	s.tasks[t.ID.String()] = forkEntry
+	s.addNewTask(forkEntry)
 	s.taskChanged()
 	s.mu.Unlock()
+	if t.Issue != nil {
+		go s.watchIssue(forkEntry, false)
+	}
 
 	var extraEnv []string
 	if ghToken != "" {
 		extraEnv = append(extraEnv, "GITHUB_TOKEN="+ghToken)
 	}
+	extraEnv = append(extraEnv, t.EnvPairs()...)
 
 	go func() {
 		forkOpts := &task.ForkOptions{
@@ -613,7 +1256,7 @@ func (s *Server) forkTask(ctx context.Context, entry *taskEntry, req *v1.ForkTas
 			Display:    source.Display,
 			Tailscale:  source.Tailscale,
 			USB:        source.USB,
-			Labels:     []string{"caic=" + t.ID.String(), "harness=" + string(forkHarness)},
+			Labels:     task.ContainerLabels(t),
 			Harness:    forkHarness,
 			ExtraEnv:   extraEnv,
 		}
@@ -633,78 +1276,312 @@ func (s *Server) forkTask(ctx context.Context, entry *taskEntry, req *v1.ForkTas
 	return &v1.CreateTaskResp{Status: "accepted", ID: t.ID}, nil
 }
 
+// promoteTask turns an approved plan-only task into a real task. The new task
+// inherits the source's repos and settings and is seeded with the plan: the
+// request's prompt when given, else the plan the agent proposed.
+func (s *Server) promoteTask(ctx context.Context, entry *taskEntry, req *v1.PromoteTaskReq) (*v1.CreateTaskResp, error) {
+	source := entry.task
+	if !source.PlanOnly {
+		return nil, dto.Conflict("task is not plan-only")
+	}
+	plan := req.Prompt.Text
+	if plan == "" {
+		plan = proposedPlan(source)
+	}
+	if plan == "" {
+		return nil, dto.Conflict("task has not proposed a plan yet")
+	}
+	repos := make([]v1.RepoSpec, len(source.Repos))
+	for i, r := range source.Repos {
+		repos[i] = v1.RepoSpec{Name: r.Name, BaseBranch: r.BaseBranch}
+	}
+	return s.createTask(ctx, &v1.CreateTaskReq{
+		InitialPrompt: v1.Prompt{Text: "Implement the following plan:\n\n" + plan, Images: req.Prompt.Images},
+		Repos:         repos,
+		Model:         source.Model,
+		Harness:       toV1Harness(source.Harness),
+		Tailscale:     source.Tailscale,
+		USB:           source.USB,
+		Display:       source.Display,
+	})
+}
+
+// approvePlan ends the review phase of a RequirePlan task. The plan is the
+// request's prompt when given, else the plan the agent proposed.
+func (s *Server) approvePlan(_ context.Context, entry *taskEntry, req *v1.ApprovePlanReq) (*v1.StatusResp, error) {
+	t := entry.task
+	if !t.RequirePlan {
+		return nil, dto.Conflict("task does not require plan approval")
+	}
+	if state := t.GetState(); state != task.StatePlanReview {
+		return nil, dto.Conflict("task is not in plan review").WithDetail("state", state.String())
+	}
+	plan := req.Prompt.Text
+	if plan == "" {
+		plan = proposedPlan(t)
+	}
+	if plan == "" {
+		return nil, dto.BadRequest("no plan provided and the task has not proposed one")
+	}
+	primaryName := ""
+	if p := t.Primary(); p != nil {
+		primaryName = p.Name
+	}
+	runner := s.runners[primaryName]
+	// Use the server-lifetime context; the new session must outlive this request.
+	h, err := runner.ApprovePlan(s.ctx, t, plan) //nolint:contextcheck // intentionally using server context
+	if err != nil {
+		return nil, dto.InternalError(err.Error())
+	}
+	s.watchSession(entry, runner, h)
+	s.mu.Lock()
+	s.taskChanged()
+	s.mu.Unlock()
+	return &v1.StatusResp{Status: "approved"}, nil
+}
+
+// proposedPlan returns the plan the agent proposed: the captured plan file,
+// falling back to the text of its last result.
+func proposedPlan(t *task.Task) string {
+	if plan := t.Snapshot().PlanContent; plan != "" {
+		return plan
+	}
+	msgs := t.Messages()
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if rm, ok := msgs[i].(*agent.ResultMessage); ok {
+			return rm.Result
+		}
+	}
+	return ""
+}
+
 func (s *Server) syncTask(ctx context.Context, entry *taskEntry, req *v1.SyncReq) (*v1.SyncResp, error) {
+	return s.syncBranch(ctx, entry, req, true)
+}
+
+// syncBranch pushes the task's changes as requested and, when openPR is set,
+// opens the PRs of the pushed branches.
+func (s *Server) syncBranch(ctx context.Context, entry *taskEntry, req *v1.SyncReq, openPR bool) (*v1.SyncResp, error) {
 	t := entry.task
+	if t.PlanOnly {
+		return nil, dto.Conflict("plan-only task has nothing to sync; promote it first")
+	}
+	if t.ReadOnly {
+		return nil, dto.Conflict("read-only task has nothing to sync")
+	}
+	if t.Chat {
+		return nil, dto.Conflict("chat task has no branch to sync")
+	}
 	switch t.GetState() {
 	case task.StatePending:
 		return nil, dto.Conflict("task has no container yet")
 	case task.StateStopping, task.StateStopped, task.StatePurging, task.StateFailed, task.StatePurged:
 		return nil, dto.Conflict("task is in a terminal state")
-	case task.StateBranching, task.StateProvisioning, task.StateStarting, task.StateRunning, task.StateWaiting, task.StateAsking, task.StateHasPlan, task.StatePulling, task.StatePushing:
+	case task.StateBranching, task.StateProvisioning, task.StateStarting, task.StateRunning, task.StateWaiting, task.StateAsking, task.StateHasPlan, task.StatePlanReview, task.StateRateLimited, task.StatePulling, task.StatePushing:
+	}
+	if err := s.checkPushPolicy(t); err != nil {
+		return nil, err
 	}
 	syncPrimaryName := ""
 	syncPrimaryBranch := ""
+	syncPushRemote := ""
 	if p := t.Primary(); p != nil {
 		syncPrimaryName = p.Name
 		syncPrimaryBranch = p.Branch
+		syncPushRemote = p.PushRemote
 	}
 	runner := s.runners[syncPrimaryName]
-
-	if req.Target == v1.SyncTargetDefault {
-		if req.Force {
-			return nil, dto.BadRequest("force is not supported for default-branch sync")
-		}
-		// Look up the base branch for the response.
-		baseBranch := runner.BaseBranch
-		// Build commit message from task title, falling back to prompt.
-		message := t.Title()
-		if message == "" {
-			message = t.InitialPrompt.Text
+	toDefault := req.Target == v1.SyncTargetDefault
+	if toDefault && req.Force {
+		return nil, dto.BadRequest("force is not supported for default-branch sync")
+	}
+	// Force skips the pre-push checks like the safety checks.
+	verified := false
+	if !req.Force {
+		var issues []v1.SafetyIssue
+		if verified, issues = s.prePushChecks(ctx, t); len(issues) != 0 {
+			branch := syncPrimaryBranch
+			if toDefault {
+				branch = runner.BaseBranch
+			}
+			return &v1.SyncResp{Status: "blocked", Branch: branch, SafetyIssues: issues}, nil
 		}
+	}
+	// Build commit message from task title, falling back to prompt.
+	message := t.Title()
+	if message == "" {
+		message = t.InitialPrompt.Text
+	}
+
+	var resp *v1.SyncResp
+	if toDefault {
 		ds, issues, err := runner.SyncToDefault(ctx, syncPrimaryBranch, t.Container, message, t.ExtraMDRepos())
 		if err != nil {
 			return nil, dto.InternalError(err.Error())
 		}
-		status := "synced"
-		if len(ds) == 0 {
-			status = "empty"
-		} else if len(issues) > 0 {
-			status = "blocked"
+		resp = &v1.SyncResp{Status: syncStatus(ds, issues, false), Branch: runner.BaseBranch, DiffStat: toV1DiffStat(ds), SafetyIssues: toV1SafetyIssues(issues)}
+	} else {
+		// Default: push to the task's own branch.
+		ds, issues, err := runner.SyncToOrigin(ctx, syncPrimaryBranch, syncPushRemote, t.Container, req.Force, t.ExtraMDRepos())
+		if err != nil {
+			return nil, dto.InternalError(err.Error())
 		}
-		return &v1.SyncResp{Status: status, Branch: baseBranch, DiffStat: toV1DiffStat(ds), SafetyIssues: toV1SafetyIssues(issues)}, nil
-	}
-
-	// Default: push to the task's own branch.
-	ds, issues, err := runner.SyncToOrigin(ctx, syncPrimaryBranch, t.Container, req.Force, t.ExtraMDRepos())
-	if err != nil {
-		return nil, dto.InternalError(err.Error())
-	}
-	status := "synced"
-	if len(ds) == 0 {
-		status = "empty"
-	} else if len(issues) > 0 && !req.Force {
-		status = "blocked"
-	}
-	resp := &v1.SyncResp{Status: status, Branch: syncPrimaryBranch, DiffStat: toV1DiffStat(ds), SafetyIssues: toV1SafetyIssues(issues)}
-	if status != "blocked" {
-		if info := s.repoInfoFor(syncPrimaryName); info != nil {
-			if f := s.forge.forgeForInfo(ctx, info); f != nil {
-				prNumber, err := s.startPRFlow(ctx, entry, f, info, syncPrimaryBranch, s.effectiveBaseBranch(t))
-				if err != nil {
-					slog.Warn("sync: create PR", "repo", info.ForgeRepo, "branch", syncPrimaryBranch, "err", err)
+		resp = &v1.SyncResp{Status: syncStatus(ds, issues, req.Force), Branch: syncPrimaryBranch, DiffStat: toV1DiffStat(ds), SafetyIssues: toV1SafetyIssues(issues)}
+		if resp.Status != "blocked" {
+			if info := s.repoInfoFor(syncPrimaryName); info != nil {
+				if f := s.forge.forgeForInfo(ctx, info); f != nil {
+					s.postCommitStatus(ctx, f, t, info, syncPushRemote, syncPrimaryBranch, verified)
+					// A task continuing a PR pushes to it.
+					if openPR && t.Snapshot().ForgePR == 0 {
+						prNumber, err := s.startPRFlow(ctx, entry, f, info, syncPrimaryBranch, s.effectiveBaseBranch(t))
+						if err != nil {
+							slog.Warn("sync: create PR", "repo", info.ForgeRepo, "branch", syncPrimaryBranch, "err", err)
+						} else {
+							resp.PRNumber = prNumber
+						}
+					}
 				} else {
-					resp.PRNumber = prNumber
+					slog.Warn("sync: no forge client available, skipping PR flow", "repo", syncPrimaryName, "forge", info.ForgeKind)
 				}
 			} else {
-				slog.Warn("sync: no forge client available, skipping PR flow", "repo", syncPrimaryName, "forge", info.ForgeKind)
+				slog.Warn("sync: repo not found in server list, skipping PR flow", "repo", syncPrimaryName)
 			}
-		} else {
-			slog.Warn("sync: repo not found in server list, skipping PR flow", "repo", syncPrimaryName)
 		}
 	}
+	if len(t.Repos) <= 1 {
+		return resp, nil
+	}
+
+	// Multi-repo task: the primary's fetch brought every repo's branch from
+	// the container; push each extra repo and report a combined status.
+	resp.Repos = append(resp.Repos, v1.RepoSyncResult{
+		Name:         syncPrimaryName,
+		Status:       resp.Status,
+		Branch:       resp.Branch,
+		DiffStat:     resp.DiffStat,
+		SafetyIssues: resp.SafetyIssues,
+		PRNumber:     resp.PRNumber,
+	})
+	for _, rm := range t.Repos[1:] {
+		resp.Repos = append(resp.Repos, s.syncExtraRepo(ctx, entry, rm, req, message, openPR, verified, resp.PRNumber))
+	}
+	resp.Status = combinedSyncStatus(resp.Repos)
 	return resp, nil
 }
 
+// syncExtraRepo pushes one extra repo of a multi-repo task and, for branch
+// syncs, posts its commit status and, with openPR, opens a companion PR
+// referencing the primary repo's PR.
+func (s *Server) syncExtraRepo(ctx context.Context, entry *taskEntry, rm task.RepoMount, req *v1.SyncReq, message string, openPR, verified bool, primaryPR int) v1.RepoSyncResult {
+	t := entry.task
+	res := v1.RepoSyncResult{Name: rm.Name, Branch: rm.Branch}
+	runner := s.runners[rm.Name]
+	if runner == nil {
+		res.Status = "failed"
+		res.Error = "repo is not configured on this server"
+		return res
+	}
+	var ds agent.DiffStat
+	var issues []task.SafetyIssue
+	var err error
+	if req.Target == v1.SyncTargetDefault {
+		res.Branch = runner.BaseBranch
+		ds, issues, err = runner.SyncFetchedToDefault(ctx, rm.Branch, t.Container, message)
+	} else {
+		ds, issues, err = runner.SyncFetchedToOrigin(ctx, rm.Branch, rm.PushRemote, t.Container, req.Force)
+	}
+	res.DiffStat = toV1DiffStat(ds)
+	res.SafetyIssues = toV1SafetyIssues(issues)
+	if err != nil {
+		res.Status = "failed"
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = syncStatus(ds, issues, req.Force && req.Target != v1.SyncTargetDefault)
+	if req.Target == v1.SyncTargetDefault || res.Status != "synced" {
+		return res
+	}
+	info := s.repoInfoFor(rm.Name)
+	if info == nil {
+		slog.Warn("sync: repo not found in server list, skipping PR flow", "repo", rm.Name)
+		return res
+	}
+	f := s.forge.forgeForInfo(ctx, info)
+	if f == nil {
+		slog.Warn("sync: no forge client available, skipping PR flow", "repo", rm.Name, "forge", info.ForgeKind)
+		return res
+	}
+	s.postCommitStatus(ctx, f, t, info, rm.PushRemote, rm.Branch, verified)
+	if !openPR {
+		return res
+	}
+	baseBranch := rm.BaseBranch
+	if baseBranch == "" {
+		baseBranch = runner.BaseBranch
+	}
+	var primary *repoInfo
+	if p := t.Primary(); p != nil && primaryPR != 0 {
+		primary = s.repoInfoFor(p.Name)
+	}
+	prNumber, err := s.createCompanionPR(ctx, entry, f, info, rm.Branch, rm.PushRemote, baseBranch, primary, primaryPR)
+	if err != nil {
+		slog.Warn("sync: create PR", "repo", info.ForgeRepo, "branch", rm.Branch, "err", err)
+	} else {
+		res.PRNumber = prNumber
+	}
+	return res
+}
+
+// syncStatus returns "synced", "blocked" or "empty" for a single repo sync.
+func syncStatus(ds agent.DiffStat, issues []task.SafetyIssue, force bool) string {
+	switch {
+	case len(ds) == 0:
+		return "empty"
+	case len(issues) > 0 && !force:
+		return "blocked"
+	default:
+		return "synced"
+	}
+}
+
+// combinedSyncStatus folds per-repo statuses: any blocked repo blocks the
+// task, then any failure, then any synced repo; otherwise it is empty.
+func combinedSyncStatus(repos []v1.RepoSyncResult) string {
+	status := "empty"
+	for _, r := range repos {
+		switch r.Status {
+		case "blocked":
+			return "blocked"
+		case "failed":
+			status = "failed"
+		case "synced":
+			if status == "empty" {
+				status = "synced"
+			}
+		}
+	}
+	return status
+}
+
+// diffRunner returns the runner and branch to diff a task's primary repo.
+func (s *Server) diffRunner(t *task.Task) (*task.Runner, string, error) {
+	if t.Container == "" {
+		return nil, "", dto.Conflict("task has no container")
+	}
+	if t.Chat {
+		return nil, "", dto.Conflict("chat task has no diff")
+	}
+	name, branch := "", ""
+	if p := t.Primary(); p != nil {
+		name, branch = p.Name, p.Branch
+	}
+	runner, ok := s.runners[name]
+	if !ok {
+		return nil, "", dto.InternalError("unknown repo")
+	}
+	return runner, branch, nil
+}
+
 func (s *Server) handleGetDiff(w http.ResponseWriter, r *http.Request) {
 	entry, err := s.getTask(r)
 	if err != nil {
@@ -712,29 +1589,204 @@ func (s *Server) handleGetDiff(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	t := entry.task
-	if t.Container == "" {
-		writeError(w, dto.Conflict("task has no container"))
+	runner, diffPrimaryBranch, err := s.diffRunner(t)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
-	diffPrimaryName := ""
-	diffPrimaryBranch := ""
+	q := r.URL.Query()
+	path := q.Get("path")
+	live := false
+	if v := q.Get("live"); v != "" {
+		if live, err = strconv.ParseBool(v); err != nil {
+			writeError(w, dto.BadRequest("invalid live: "+v))
+			return
+		}
+	}
+	diff, err := runner.DiffContent(r.Context(), diffPrimaryBranch, path)
+	if err != nil {
+		writeError(w, dto.InternalError(err.Error()))
+		return
+	}
+	resp := v1.DiffResp{Diff: diff}
+	if live {
+		ds, err := runner.RefreshDiffStat(r.Context(), t, diffPrimaryBranch)
+		if err != nil {
+			writeError(w, dto.InternalError(err.Error()))
+			return
+		}
+		resp.DiffStat = toV1DiffStat(ds)
+	}
+	if path == "" {
+		ds := resp.DiffStat
+		if !live {
+			s.mu.Lock()
+			ds = s.toJSON(entry).DiffStat
+			s.mu.Unlock()
+		}
+		resp.Tree = toV1DiffTree(ds)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleGetDiffHunks(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	runner, branch, err := s.diffRunner(entry.task)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	q := r.URL.Query()
+	intraline := false
+	if v := q.Get("intraline"); v != "" {
+		if intraline, err = strconv.ParseBool(v); err != nil {
+			writeError(w, dto.BadRequest("invalid intraline: "+v))
+			return
+		}
+	}
+	diff, err := runner.DiffContent(r.Context(), branch, q.Get("path"))
+	if err != nil {
+		writeError(w, dto.InternalError(err.Error()))
+		return
+	}
+	resp := v1.DiffHunksResp{Files: parseDiff(diff)}
+	if resp.Files == nil {
+		resp.Files = []v1.DiffFile{}
+	}
+	if intraline {
+		budget := intralineMaxPairs
+		for i := range resp.Files {
+			for j := range resp.Files[i].Hunks {
+				addIntraline(&resp.Files[i].Hunks[j], &budget)
+			}
+		}
+	}
+	writeJSONResponse(w, &resp, nil)
+}
+
+// handleGetCommits lists the commits of the task's primary branch.
+func (s *Server) handleGetCommits(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	t := entry.task
+	runner, branch, err := s.diffRunner(t)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	base := ""
 	if p := t.Primary(); p != nil {
-		diffPrimaryName = p.Name
-		diffPrimaryBranch = p.Branch
+		base = p.BaseBranch
 	}
-	runner, ok := s.runners[diffPrimaryName]
-	if !ok {
-		writeError(w, dto.InternalError("unknown repo"))
+	commits, err := runner.Commits(r.Context(), branch, base, t.Container, t.ExtraMDRepos())
+	if err != nil {
+		writeError(w, dto.InternalError(err.Error()))
 		return
 	}
-	path := r.URL.Query().Get("path")
-	diff, err := runner.DiffContent(r.Context(), diffPrimaryBranch, path)
+	out := make([]v1.TaskCommit, len(commits))
+	for i, c := range commits {
+		out[i] = v1.TaskCommit{
+			SHA:         c.SHA,
+			Author:      c.Author,
+			Message:     c.Message,
+			AuthoredAt:  c.Authored.UTC(),
+			CommittedAt: c.Committed.UTC(),
+			Files:       toV1DiffStat(c.Files),
+		}
+	}
+	writeJSONResponse(w, &out, nil)
+}
+
+// revertCommit reverts one of the agent's commits and tells the agent, so it
+// doesn't redo the change in a later turn.
+func (s *Server) revertCommit(ctx context.Context, entry *taskEntry, req *v1.RevertCommitReq) (*v1.RevertCommitResp, error) {
+	t := entry.task
+	runner, branch, err := s.diffRunner(t)
+	if err != nil {
+		return nil, err
+	}
+	revert, subject, err := runner.RevertCommit(ctx, t, req.SHA)
+	if err != nil {
+		return nil, dto.Conflict(err.Error())
+	}
+	if _, err := runner.RefreshDiffStat(ctx, t, branch); err != nil {
+		slog.WarnContext(ctx, "revert: refresh diff stat", "task", t.ID, "err", err)
+	}
+	resp := &v1.RevertCommitResp{Revert: revert}
+	msg := fmt.Sprintf("The user reverted your commit %s (%q) in commit %s; that change is not wanted. Do not reintroduce it.", req.SHA, subject, revert)
+	if err := t.SendInput(ctx, agent.Prompt{Text: msg}); err != nil {
+		slog.InfoContext(ctx, "revert: agent not notified", "task", t.ID, "err", err)
+	} else {
+		resp.Notified = true
+	}
+	return resp, nil
+}
+
+func (s *Server) createSnapshot(ctx context.Context, entry *taskEntry, req *v1.CreateSnapshotReq) (*v1.TaskSnapshot, error) {
+	runner, _, err := s.diffRunner(entry.task)
+	if err != nil {
+		return nil, err
+	}
+	snap, err := runner.CreateSnapshot(ctx, entry.task, req.Name)
+	if err != nil {
+		return nil, dto.Conflict(err.Error())
+	}
+	return &v1.TaskSnapshot{Name: snap.Name, Commit: snap.Commit, Created: snap.Created.UTC()}, nil
+}
+
+func (s *Server) handleListSnapshots(w http.ResponseWriter, r *http.Request) {
+	entry, err := s.getTask(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	runner, _, err := s.diffRunner(entry.task)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	snaps, err := runner.WorkspaceSnapshots(r.Context(), entry.task)
 	if err != nil {
 		writeError(w, dto.InternalError(err.Error()))
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(v1.DiffResp{Diff: diff})
+	out := make([]v1.TaskSnapshot, len(snaps))
+	for i, snap := range snaps {
+		out[i] = v1.TaskSnapshot{Name: snap.Name, Commit: snap.Commit, Created: snap.Created.UTC()}
+	}
+	writeJSONResponse(w, &out, nil)
+}
+
+// restoreSnapshot rolls the workspace back to a snapshot. A running agent
+// would keep editing files from under the restore, so it must be idle.
+func (s *Server) restoreSnapshot(ctx context.Context, entry *taskEntry, req *v1.RestoreSnapshotReq) (*v1.StatusResp, error) {
+	t := entry.task
+	if t.GetState() == task.StateRunning {
+		return nil, dto.Conflict("task is running; wait for the agent to finish its turn")
+	}
+	runner, branch, err := s.diffRunner(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := runner.RestoreSnapshot(ctx, t, req.Name); err != nil {
+		return nil, dto.Conflict(err.Error())
+	}
+	if _, err := runner.RefreshDiffStat(ctx, t, branch); err != nil {
+		slog.WarnContext(ctx, "restore snapshot: refresh diff stat", "task", t.ID, "err", err)
+	}
+	msg := fmt.Sprintf("The user restored the workspace to snapshot %q. Commits and changes made after it were discarded; check the current state of the files before continuing.", req.Name)
+	if err := t.SendInput(ctx, agent.Prompt{Text: msg}); err != nil {
+		slog.InfoContext(ctx, "restore snapshot: agent not notified", "task", t.ID, "err", err)
+	}
+	return &v1.StatusResp{Status: "restored"}, nil
 }
 
 // watchSession monitors a single active session. When the session's SSH
@@ -789,10 +1841,35 @@ func (s *Server) watchSession(entry *taskEntry, runner *task.Runner, h *task.Ses
 	}()
 }
 
+// watchRestartedSession starts a session watcher for a session the runner
+// restarted on its own, e.g. after automatic context compaction.
+func (s *Server) watchRestartedSession(t *task.Task, h *task.SessionHandle) {
+	primaryName := ""
+	if p := t.Primary(); p != nil {
+		primaryName = p.Name
+	}
+	s.mu.Lock()
+	entry := s.tasks[t.ID.String()]
+	runner := s.runners[primaryName]
+	s.mu.Unlock()
+	if entry == nil {
+		return
+	}
+	s.watchSession(entry, runner, h)
+	s.notifyTaskChange()
+}
+
+// setPersist sets whether the task's workspace is saved when its container
+// goes away from its owner's current preferences.
+func (s *Server) setPersist(t *task.Task) {
+	t.Persist = s.prefs.Get(taskOwnerID(t)).Settings.PersistWorkspace
+}
+
 // cleanupTask runs runner.Cleanup exactly once per task (guarded by
 // entry.cleanupOnce), stores the result, notifies SSE, and closes entry.done.
 func (s *Server) cleanupTask(entry *taskEntry, runner *task.Runner, reason task.State) {
 	entry.cleanupOnce.Do(func() {
+		s.setPersist(entry.task)
 		result := runner.Cleanup(s.ctx, entry.task, reason)
 		s.mu.Lock()
 		entry.result = &result
@@ -802,6 +1879,17 @@ func (s *Server) cleanupTask(entry *taskEntry, runner *task.Runner, reason task.
 	})
 }
 
+// envAllowed reports whether the environment variable name matches one of
+// the names or patterns of allowlist.
+func envAllowed(allowlist []string, name string) bool {
+	for _, p := range allowlist {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // resolveGitHubContainerToken returns the GitHub token to inject into a
 // container based on the user's access preference. Default ("" or "none")
 // returns empty. "read-write" passes the parent token.
@@ -823,10 +1911,9 @@ func (s *Server) resolveGitHubContainerToken(ctx context.Context, access prefere
 // getTask looks up a task by the {id} path parameter.
 // When auth is enabled, returns 403 if the task belongs to a different user.
 func (s *Server) getTask(r *http.Request) (*taskEntry, error) {
-	id := r.PathValue("id")
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	entry, ok := s.tasks[id]
+	entry, ok := s.lookupTask(r.PathValue("id"))
 	if !ok {
 		return nil, dto.NotFound("task")
 	}
@@ -840,11 +1927,77 @@ func (s *Server) getTask(r *http.Request) (*taskEntry, error) {
 	return entry, nil
 }
 
-// taskChanged closes the current changed channel and replaces it. Must be
-// called while holding s.mu.
-func (s *Server) taskChanged() {
-	close(s.changed)
-	s.changed = make(chan struct{})
+// lookupTask returns the task whose ID or alias is ref. Must be called while
+// holding s.mu.
+func (s *Server) lookupTask(ref string) (*taskEntry, bool) {
+	if entry, ok := s.tasks[ref]; ok {
+		return entry, true
+	}
+	if !strings.HasPrefix(ref, aliasPrefix) {
+		return nil, false
+	}
+	for _, e := range s.tasks {
+		if e.task.Alias == ref {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// handleTaskLink redirects old /task/{ref} links, where ref is a task ID or
+// alias, to the task's canonical /task/@{id} page. Anything else, including
+// canonical pages, is served by next, the frontend.
+func (s *Server) handleTaskLink(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ref := r.PathValue("ref"); !strings.HasPrefix(ref, "@") {
+			u, hasUser := auth.UserFromContext(r.Context())
+			s.mu.Lock()
+			entry, ok := s.lookupTask(ref)
+			s.mu.Unlock()
+			if ok && s.authEnabled() {
+				// Only reveal the ID to users allowed to see the task.
+				ok = hasUser && (entry.task.OwnerID == "" || entry.task.OwnerID == u.ID)
+			}
+			if ok {
+				http.Redirect(w, r, "/task/@"+entry.task.ID.String(), http.StatusFound)
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// addTask registers entry and notifies watchers and the owner's chat
+// channels on each of its state transitions, including those driven by the
+// agent. The caller must hold s.mu.
+func (s *Server) addTask(entry *taskEntry) {
+	entry.task.OnTransition(func(tr task.Transition) {
+		go s.notifyTaskChange()
+		go s.notifyTransition(entry.task, tr)
+		go s.publishTransition(entry.task, tr)
+	})
+	s.tasks[entry.task.ID.String()] = entry
+}
+
+// aliasPrefix starts every task alias; the rest is a sequence number.
+const aliasPrefix = "w"
+
+// addNewTask gives a newly created task the next alias and registers it.
+// Must be called while holding s.mu.
+func (s *Server) addNewTask(entry *taskEntry) {
+	s.lastAlias++
+	entry.task.Alias = aliasPrefix + strconv.Itoa(s.lastAlias)
+	s.addTask(entry)
+}
+
+// reserveAlias records an alias loaded from a log so new aliases never reuse
+// it. Must be called while holding s.mu.
+func (s *Server) reserveAlias(alias string) {
+	if n, ok := strings.CutPrefix(alias, aliasPrefix); ok {
+		if v, err := strconv.Atoi(n); err == nil {
+			s.lastAlias = max(s.lastAlias, v)
+		}
+	}
 }
 
 // notifyTaskChange signals that task data may have changed.
@@ -862,7 +2015,7 @@ func (s *Server) toJSON(e *taskEntry) v1.Task {
 	// Build Repos slice for API response.
 	taskRepos := make([]v1.TaskRepo, len(e.task.Repos))
 	for i, r := range e.task.Repos {
-		taskRepos[i] = v1.TaskRepo{Name: r.Name, BaseBranch: r.BaseBranch, Branch: r.Branch, RemoteURL: s.repoURL(r.Name), Forge: s.repoForge(r.Name)}
+		taskRepos[i] = v1.TaskRepo{Name: r.Name, BaseBranch: r.BaseBranch, Branch: r.Branch, RemoteURL: s.repoURL(r.Name), Forge: s.repoForge(r.Name), PushRemote: r.PushRemote}
 	}
 	if len(taskRepos) == 0 {
 		taskRepos = nil
@@ -876,13 +2029,16 @@ func (s *Server) toJSON(e *taskEntry) v1.Task {
 
 	j := v1.Task{
 		ID:             e.task.ID,
+		Alias:          e.task.Alias,
 		InitialPrompt:  e.task.InitialPrompt.Text,
 		Title:          snap.Title,
 		Repos:          taskRepos,
 		Container:      e.task.Container,
 		State:          snap.State.String(),
-		StateUpdatedAt: float64(snap.StateUpdatedAt.UnixMilli()) / 1e3,
-		Harness:        toV1Harness(e.task.Harness),
+		SubState:       string(snap.SubState),
+		StateUpdatedAt: snap.StateUpdatedAt.UTC(),
+		Revision:       snap.Revision,
+		Harness:        toV1Harness(snap.Harness),
 		Model:          snap.Model,
 		AgentVersion:   snap.AgentVersion,
 		SessionID:      snap.SessionID,
@@ -891,15 +2047,50 @@ func (s *Server) toJSON(e *taskEntry) v1.Task {
 		Tailscale:      tailscaleURL(e.task),
 		USB:            e.task.USB,
 		Display:        e.task.Display,
+		PlanOnly:       e.task.PlanOnly,
+		RequirePlan:    e.task.RequirePlan,
+		ReadOnly:       e.task.ReadOnly,
+		Chat:           e.task.Chat,
+		Thinking:       e.task.Thinking,
+		IdlePolicy:     toV1IdlePolicy(e.task.Idle),
+		PushPolicy:     v1.PushPolicy(s.pushPolicy(e.task)),
+		DependsOn:      e.task.DependsOn,
+		HeldReason:     e.heldReason,
 		CostUSD:        snap.CostUSD,
 		NumTurns:       snap.NumTurns,
 		Duration:       snap.Duration.Seconds(),
+		DiskBytes:      e.diskBytes,
+		LogBytes:       e.logBytes,
+		Archived:       s.archived.has(e.task.ID.String()),
+		Pinned:         s.pinned.has(e.task.ID.String()),
+	}
+	// Local models are free; their names may collide with priced ones.
+	if p, ok := pricing.Lookup(s.prefs.Get(taskOwnerID(e.task)).Settings.ModelPrices, snap.Model); ok && snap.Harness != agent.Local {
+		j.EstimatedCostUSD = p.Cost(&snap.Usage)
+		j.CostDiscrepancy = pricing.Discrepant(j.EstimatedCostUSD, j.CostUSD)
+	}
+	if e.priority != v1.PriorityNormal {
+		j.Priority = e.priority
 	}
 	if !e.task.StartedAt.IsZero() {
-		j.StartedAt = float64(e.task.StartedAt.UnixMilli()) / 1e3
+		j.StartedAt = e.task.StartedAt.UTC()
+	}
+	if e.pipeline != nil {
+		j.Pipeline = e.pipeline.toJSON()
+	}
+	if e.review != nil {
+		j.Review = e.review.toJSON()
 	}
 	if !snap.TurnStartedAt.IsZero() {
-		j.TurnStartedAt = float64(snap.TurnStartedAt.UnixMilli()) / 1e3
+		j.TurnStartedAt = snap.TurnStartedAt.UTC()
+	}
+	if !snap.LastActivityAt.IsZero() {
+		j.LastActivityAt = snap.LastActivityAt.UTC()
+	}
+	j.Stalled = snap.Stalled
+	j.Failover = toV1Failover(snap.Failover)
+	if at := e.task.RateLimitResumeAt(); !at.IsZero() {
+		j.ResumesAt = at.UTC()
 	}
 	j.CumulativeInputTokens = snap.Usage.InputTokens
 	j.CumulativeOutputTokens = snap.Usage.OutputTokens
@@ -912,7 +2103,7 @@ func (s *Server) toJSON(e *taskEntry) v1.Task {
 		j.ContextWindowLimit = snap.ContextWindowLimit
 	} else if primaryName != "" {
 		if r := s.runners[primaryName]; r != nil {
-			if b := r.Backends[e.task.Harness]; b != nil {
+			if b := r.Backends[snap.Harness]; b != nil {
 				j.ContextWindowLimit = b.ContextWindowLimit(snap.Model)
 			}
 		}
@@ -930,6 +2121,9 @@ func (s *Server) toJSON(e *taskEntry) v1.Task {
 	j.ForgeRepo = snap.ForgeRepo
 	j.ForgePR = snap.ForgePR
 	j.ForgeIssue = snap.ForgeIssue
+	if is := e.task.Issue; is != nil {
+		j.Issue = &v1.IssueLink{Tracker: is.Tracker, Key: is.Key, URL: is.URL}
+	}
 	j.CIStatus = v1.CIStatus(snap.CIStatus)
 	if len(snap.CIChecks) > 0 {
 		j.CIChecks = make([]v1.ForgeCheck, len(snap.CIChecks))
@@ -937,6 +2131,12 @@ func (s *Server) toJSON(e *taskEntry) v1.Task {
 			j.CIChecks[i] = checkToDTO(&snap.CIChecks[i])
 		}
 	}
+	if e.task.Network.Restricted() {
+		j.Network = v1.NetworkMode(e.task.Network)
+	}
+	if v := snap.PolicyViolation; v != nil {
+		j.PolicyViolation = &v1.PolicyViolation{Rule: v.Rule, Tool: v.Tool, Detail: v.Detail}
+	}
 	if s.authStore != nil && e.task.OwnerID != "" {
 		if u, ok := s.authStore.FindByID(e.task.OwnerID); ok {
 			j.Owner = u.Username
@@ -0,0 +1,76 @@
+// Sort order of the task list: the sort and order query parameters.
+package server
+
+import (
+	"cmp"
+	"net/url"
+	"sort"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// taskSortKeys compares two tasks by each key of the sort query parameter.
+// Task IDs are time-sortable so "created" compares them.
+var taskSortKeys = map[string]func(a, b *v1.Task) int{
+	"created":  func(a, b *v1.Task) int { return cmp.Compare(a.ID, b.ID) },
+	"updated":  func(a, b *v1.Task) int { return a.StateUpdatedAt.Compare(b.StateUpdatedAt) },
+	"cost":     func(a, b *v1.Task) int { return cmp.Compare(a.CostUSD, b.CostUSD) },
+	"duration": func(a, b *v1.Task) int { return cmp.Compare(a.Duration, b.Duration) },
+	"state":    func(a, b *v1.Task) int { return cmp.Compare(stateRank(a.State), stateRank(b.State)) },
+}
+
+// stateRank orders the states by lifecycle, unknown states last.
+func stateRank(s string) int {
+	if st, ok := task.ParseState(s); ok {
+		return int(st)
+	}
+	return int(task.StatePurged) + 1
+}
+
+// taskSort is the sort order of the task list. Pinned tasks always come
+// first; ties are broken by ID.
+type taskSort struct {
+	key  func(a, b *v1.Task) int
+	desc bool
+}
+
+// parseTaskSort parses the sort and order query parameters. sort is one of
+// the taskSortKeys, "created" by default; order is "asc", the default, or
+// "desc".
+func parseTaskSort(q url.Values) (taskSort, error) {
+	var v dto.Validator
+	s := taskSort{key: taskSortKeys["created"]}
+	if name := q.Get("sort"); name != "" {
+		if s.key = taskSortKeys[name]; s.key == nil {
+			v.Invalid("sort", name)
+		}
+	}
+	switch order := q.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		s.desc = true
+	default:
+		v.Invalid("order", order)
+	}
+	return s, v.Err()
+}
+
+// apply sorts tasks in place.
+func (s taskSort) apply(tasks []v1.Task) {
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := &tasks[i], &tasks[j]
+		if a.Pinned != b.Pinned {
+			return a.Pinned
+		}
+		c := s.key(a, b)
+		if c == 0 {
+			c = cmp.Compare(a.ID, b.ID)
+		}
+		if s.desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
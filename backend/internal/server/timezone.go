@@ -0,0 +1,36 @@
+// Time zone of the formatted timestamps: the ?tz= query parameter, else the user's settings, else UTC.
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+)
+
+// userLocation returns the time zone of the user's settings, UTC when unset.
+func userLocation(settings *preferences.Settings) *time.Location {
+	if settings.Timezone != "" {
+		if loc, err := time.LoadLocation(settings.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// requestLocation returns the time zone the formatted timestamps and the
+// exports of a response are rendered in: the IANA name of the tz query
+// parameter, else the time zone of the user's settings. The timestamps of the
+// other JSON responses are always in UTC.
+func (s *Server) requestLocation(r *http.Request) (*time.Location, error) {
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, dto.Invalid("tz", tz)
+		}
+		return loc, nil
+	}
+	settings := s.prefs.Get(userIDFromCtx(r.Context())).Settings
+	return userLocation(&settings), nil
+}
@@ -0,0 +1,127 @@
+// Size-bounded LRU cache of the static files transcoded from brotli, with hit and miss counters published in expvar.
+package server
+
+import (
+	"container/list"
+	"context"
+	"expvar"
+	"io/fs"
+	"log/slog"
+	"sync"
+)
+
+// defaultStaticCacheMB is the transcode cache size when Config.StaticCacheMB
+// is unset.
+const defaultStaticCacheMB = 32
+
+// warmEncodings are the encodings critical assets are transcoded to at
+// startup, for clients and proxies not accepting brotli.
+var warmEncodings = []string{"gzip", "zstd"}
+
+// transcodeVars are the counters of the transcode caches, in
+// /api/v1/admin/debug/vars.
+var transcodeVars = expvar.NewMap("static_transcode_cache")
+
+// transcodeCache holds transcoded variants of static files, evicting the
+// least recently used ones beyond maxBytes.
+type transcodeCache struct {
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // Of *transcodeEntry, keyed "path\x00encoding".
+	lru     list.List                // Front is most recently used.
+	size    int64
+}
+
+// transcodeEntry holds a lazily-computed transcoded variant.
+type transcodeEntry struct {
+	key  string
+	once sync.Once
+	data []byte
+	err  error
+	size int64 // Accounted for in the cache size; guarded by transcodeCache.mu.
+}
+
+// newTranscodeCache returns a cache holding up to maxBytes of transcoded data.
+func newTranscodeCache(maxBytes int64) *transcodeCache {
+	return &transcodeCache{maxBytes: maxBytes, entries: map[string]*list.Element{}}
+}
+
+// get returns clean transcoded to enc, transcoding it on a miss. Concurrent
+// misses for the same variant transcode it once.
+func (c *transcodeCache) get(dist fs.FS, clean, enc string) ([]byte, error) {
+	key := clean + "\x00" + enc
+	c.mu.Lock()
+	el, ok := c.entries[key]
+	if ok {
+		c.lru.MoveToFront(el)
+	} else {
+		el = c.lru.PushFront(&transcodeEntry{key: key})
+		c.entries[key] = el
+	}
+	c.mu.Unlock()
+	if ok {
+		transcodeVars.Add("hits", 1)
+	} else {
+		transcodeVars.Add("misses", 1)
+	}
+	e := el.Value.(*transcodeEntry)
+	e.once.Do(func() {
+		e.data, e.err = doTranscode(dist, clean, enc)
+		c.settle(el)
+	})
+	return e.data, e.err
+}
+
+// settle accounts for the newly transcoded entry in el and evicts entries
+// until the cache fits. Failures are not kept so that they are retried.
+func (c *transcodeCache) settle(el *list.Element) {
+	e := el.Value.(*transcodeEntry)
+	n := int64(len(e.data))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[e.key] != el {
+		// Evicted while being transcoded.
+		return
+	}
+	if e.err != nil || n > c.maxBytes {
+		c.remove(el)
+		return
+	}
+	e.size = n
+	c.size += n
+	transcodeVars.Add("bytes", n)
+	for c.size > c.maxBytes {
+		c.remove(c.lru.Back())
+		transcodeVars.Add("evictions", 1)
+	}
+}
+
+// remove drops el from the cache. Entries still being transcoded have no size
+// yet. c.mu must be held.
+func (c *transcodeCache) remove(el *list.Element) {
+	e := el.Value.(*transcodeEntry)
+	delete(c.entries, e.key)
+	c.lru.Remove(el)
+	c.size -= e.size
+	transcodeVars.Add("bytes", -e.size)
+}
+
+// warm transcodes index.html and the entry assets of the build in dist, so
+// that the first page load of clients not accepting brotli is not delayed.
+func (c *transcodeCache) warm(ctx context.Context, dist fs.FS) {
+	files, err := entryAssets(dist)
+	if err != nil {
+		slog.WarnContext(ctx, "frontend asset manifest", "err", err)
+	}
+	for _, f := range append([]string{"index.html"}, files...) {
+		for _, enc := range warmEncodings {
+			if ctx.Err() != nil {
+				return
+			}
+			if _, err := c.get(dist, f, enc); err != nil {
+				slog.WarnContext(ctx, "transcode static asset", "path", f, "encoding", enc, "err", err)
+			}
+		}
+	}
+}
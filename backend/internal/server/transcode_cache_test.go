@@ -0,0 +1,90 @@
+// Tests for the transcode cache.
+package server
+
+import (
+	"bytes"
+	"expvar"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTranscodeCache(t *testing.T) {
+	counter := func(name string) int64 {
+		if v, ok := transcodeVars.Get(name).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+
+	t.Run("HitsAndMisses", func(t *testing.T) {
+		c := newTranscodeCache(1 << 20)
+		dist := testFS(t)
+		hits, misses := counter("hits"), counter("misses")
+		for range 2 {
+			data, err := c.get(dist, "assets/app.js", "identity")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, appContent) {
+				t.Errorf("data = %q, want %q", data, appContent)
+			}
+		}
+		if got := counter("hits") - hits; got != 1 {
+			t.Errorf("hits = %d, want 1", got)
+		}
+		if got := counter("misses") - misses; got != 1 {
+			t.Errorf("misses = %d, want 1", got)
+		}
+	})
+	t.Run("Evicts", func(t *testing.T) {
+		// Room for two of the three identity variants.
+		c := newTranscodeCache(int64(len(indexContent) + len(appContent)))
+		dist := testFS(t)
+		for _, f := range []string{"index.html", "assets/app.js", "assets/style.css"} {
+			if _, err := c.get(dist, f, "identity"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, ok := c.entries["index.html\x00identity"]; ok {
+			t.Error("least recently used entry kept")
+		}
+		if c.size > c.maxBytes || c.lru.Len() != 2 {
+			t.Errorf("size = %d for %d entries, want at most %d for 2", c.size, c.lru.Len(), c.maxBytes)
+		}
+	})
+	t.Run("TooLarge", func(t *testing.T) {
+		c := newTranscodeCache(4)
+		if data, err := c.get(testFS(t), "assets/app.js", "identity"); err != nil || !bytes.Equal(data, appContent) {
+			t.Errorf("data = %q, %v", data, err)
+		}
+		if c.lru.Len() != 0 || c.size != 0 {
+			t.Errorf("%d entries of %d bytes cached, want none", c.lru.Len(), c.size)
+		}
+	})
+	t.Run("ErrorNotKept", func(t *testing.T) {
+		c := newTranscodeCache(1 << 20)
+		dist := fstest.MapFS{"bad.js.br": {Data: []byte("not brotli")}}
+		if _, err := c.get(dist, "bad.js", "gzip"); err == nil {
+			t.Fatal("expected error")
+		}
+		if c.lru.Len() != 0 {
+			t.Error("failure cached")
+		}
+	})
+	t.Run("Warm", func(t *testing.T) {
+		c := newTranscodeCache(1 << 20)
+		dist := testFS(t)
+		dist[assetManifest+".br"] = &fstest.MapFile{Data: brCompress(t, []byte(testManifest))}
+		dist["assets/index-a.js.br"] = &fstest.MapFile{Data: brCompress(t, appContent)}
+		dist["assets/index-b.css.br"] = &fstest.MapFile{Data: brCompress(t, cssContent)}
+		dist["assets/vendor-c.js.br"] = &fstest.MapFile{Data: brCompress(t, appContent)}
+		c.warm(t.Context(), dist)
+		for _, f := range []string{"index.html", "assets/index-a.js", "assets/index-b.css", "assets/vendor-c.js"} {
+			for _, enc := range warmEncodings {
+				if _, ok := c.entries[f+"\x00"+enc]; !ok {
+					t.Errorf("%s in %s not transcoded", f, enc)
+				}
+			}
+		}
+	})
+}
@@ -2,12 +2,33 @@
 package server
 
 import (
+	"net/http"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
 )
 
+// usageTasksLocked returns the tasks whose usage r reports: all of them, or
+// those with a repo of the project named by ?project=. The caller must hold
+// s.mu.
+func (s *Server) usageTasksLocked(r *http.Request) map[string]*taskEntry {
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		return s.tasks
+	}
+	f := taskFilter{includeArchived: true, project: project, repoProjects: s.prefs.Get(userIDFromCtx(r.Context())).Settings.RepoProjects}
+	out := make(map[string]*taskEntry)
+	for id, e := range s.tasks {
+		if f.matchLocked(s, e) {
+			out[id] = e
+		}
+	}
+	return out
+}
+
 // computeClaudeUsage aggregates task cost and token usage within rolling
 // 5-hour and 7-day windows. Tasks are attributed to the window that contains
 // their StartedAt time. For running tasks without a final result, the current
@@ -41,3 +62,44 @@ func computeClaudeUsage(tasks map[string]*taskEntry, now time.Time) v1.ClaudeUsa
 	}
 	return out
 }
+
+// computeRateLimits returns, per harness, the most recent rate limit status
+// reported by a task and the tasks backing off from a rate limit, sorted by
+// harness.
+func computeRateLimits(tasks map[string]*taskEntry) []v1.HarnessRateLimit {
+	byHarness := map[agent.Harness]*v1.HarnessRateLimit{}
+	get := func(h agent.Harness) *v1.HarnessRateLimit {
+		rl := byHarness[h]
+		if rl == nil {
+			rl = &v1.HarnessRateLimit{Harness: toV1Harness(h)}
+			byHarness[h] = rl
+		}
+		return rl
+	}
+	for _, e := range tasks {
+		harness := e.task.Snapshot().Harness
+		if m, at := e.task.RateLimit(); !at.IsZero() {
+			rl := get(harness)
+			if at.After(rl.ReportedAt) {
+				rl.Status = m.Status
+				rl.RateLimitType = m.RateLimitType
+				rl.Utilization = m.Utilization
+				rl.ResetsAt = epochTime(m.ResetsAt)
+				rl.ReportedAt = at.UTC()
+			}
+		}
+		if at := e.task.RateLimitResumeAt(); !at.IsZero() {
+			rl := get(harness)
+			rl.LimitedTasks++
+			if rl.ResumesAt.IsZero() || at.Before(rl.ResumesAt) {
+				rl.ResumesAt = at.UTC()
+			}
+		}
+	}
+	out := make([]v1.HarnessRateLimit, 0, len(byHarness))
+	for _, rl := range byHarness {
+		out = append(out, *rl)
+	}
+	slices.SortFunc(out, func(a, b v1.HarnessRateLimit) int { return strings.Compare(string(a.Harness), string(b.Harness)) })
+	return out
+}
@@ -0,0 +1,97 @@
+// API versions: the registry of the versioned route sets and the negotiation of unversioned paths.
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+)
+
+// apiVersion is a major version of the HTTP API, served under /api/<name>/.
+//
+// A new major version gets its own dto sub-package and registration function
+// and is appended to apiVersions, while the previous ones stay served and
+// stable for the clients migrating. Its handlers convert the shared internal
+// state to its own dto types, like the v1 handlers do with dto/v1.
+type apiVersion struct {
+	name     dto.Version
+	register func(s *Server, mux *http.ServeMux)
+}
+
+// prefix returns the path prefix of the version's routes.
+func (v *apiVersion) prefix() string {
+	return "/api/" + string(v.name) + "/"
+}
+
+// apiVersions is the registry of the served API versions, oldest first.
+var apiVersions = []apiVersion{
+	{name: dto.V1, register: (*Server).registerV1},
+}
+
+// legacyDeprecated is when the unversioned /api/... paths were deprecated.
+var legacyDeprecated = time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+// findAPIVersion returns the registered version named name, or nil.
+func findAPIVersion(name dto.Version) *apiVersion {
+	for i := range apiVersions {
+		if apiVersions[i].name == name {
+			return &apiVersions[i]
+		}
+	}
+	return nil
+}
+
+// apiVersionMiddleware reports the API version of every /api/ response in the
+// API-Version header. Unversioned paths like /api/tasks are deprecated
+// aliases: they are served by the version the API-Version request header
+// asks for, v1 by default, with the Deprecation and Link headers pointing at
+// the versioned path. Unknown versions are rejected.
+func apiVersionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, "/api/")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		seg, _, _ := strings.Cut(rest, "/")
+		if isVersionSegment(seg) {
+			if findAPIVersion(dto.Version(seg)) == nil {
+				writeError(w, dto.NotFound("API version "+seg).WithParam("version", seg))
+				return
+			}
+			w.Header().Set("API-Version", seg)
+			next.ServeHTTP(w, r)
+			return
+		}
+		name := dto.Version(r.Header.Get("API-Version"))
+		if name == "" {
+			name = apiVersions[0].name
+		}
+		v := findAPIVersion(name)
+		if v == nil {
+			writeError(w, dto.BadRequest("unknown API-Version "+string(name)).WithParam("version", string(name)))
+			return
+		}
+		target := v.prefix() + rest
+		h := w.Header()
+		h.Set("API-Version", string(v.name))
+		h.Set("Deprecation", "@"+strconv.FormatInt(legacyDeprecated.Unix(), 10))
+		h.Set("Link", "<"+target+`>; rel="successor-version"`)
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = target
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// isVersionSegment reports whether seg names an API version, e.g. "v1".
+func isVersionSegment(seg string) bool {
+	if len(seg) < 2 || seg[0] != 'v' {
+		return false
+	}
+	_, err := strconv.ParseUint(seg[1:], 10, 16)
+	return err == nil
+}
@@ -0,0 +1,70 @@
+// Tests for the API version registry and the negotiation of unversioned paths.
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestAPIVersions(t *testing.T) {
+	// A second version, as the next major one would be registered.
+	saved := apiVersions
+	t.Cleanup(func() { apiVersions = saved })
+	apiVersions = append(slices.Clone(saved), apiVersion{name: "v2", register: func(_ *Server, mux *http.ServeMux) {
+		mux.HandleFunc("GET /api/v2/ping", func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, "pong")
+		})
+	}})
+	s := newTestServer(t)
+	h, err := s.buildHandler()
+	if err != nil {
+		t.Fatal(err)
+	}
+	get := func(path, version string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, http.NoBody)
+		if version != "" {
+			req.Header.Set("API-Version", version)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+	t.Run("Versioned", func(t *testing.T) {
+		w := get("/api/v1/tasks", "")
+		if w.Code != http.StatusOK || w.Header().Get("API-Version") != "v1" || w.Header().Get("Deprecation") != "" {
+			t.Errorf("status = %d, headers = %v", w.Code, w.Header())
+		}
+	})
+	t.Run("SecondVersion", func(t *testing.T) {
+		w := get("/api/v2/ping", "")
+		if w.Code != http.StatusOK || w.Body.String() != "pong" || w.Header().Get("API-Version") != "v2" {
+			t.Errorf("status = %d, body = %q, headers = %v", w.Code, w.Body, w.Header())
+		}
+		w = get("/api/ping", "v2")
+		if w.Code != http.StatusOK || w.Body.String() != "pong" || w.Header().Get("Link") != `</api/v2/ping>; rel="successor-version"` {
+			t.Errorf("legacy: status = %d, body = %q, headers = %v", w.Code, w.Body, w.Header())
+		}
+	})
+	t.Run("Legacy", func(t *testing.T) {
+		for _, version := range []string{"", "v1"} {
+			w := get("/api/tasks", version)
+			if w.Code != http.StatusOK || w.Header().Get("API-Version") != "v1" {
+				t.Errorf("%q: status = %d, headers = %v", version, w.Code, w.Header())
+			}
+			if w.Header().Get("Deprecation") == "" || w.Header().Get("Link") != `</api/v1/tasks>; rel="successor-version"` {
+				t.Errorf("%q: missing deprecation headers: %v", version, w.Header())
+			}
+		}
+	})
+	t.Run("Unknown", func(t *testing.T) {
+		if w := get("/api/v9/tasks", ""); w.Code != http.StatusNotFound {
+			t.Errorf("/api/v9: status = %d, want 404", w.Code)
+		}
+		if w := get("/api/tasks", "v9"); w.Code != http.StatusBadRequest {
+			t.Errorf("API-Version v9: status = %d, want 400", w.Code)
+		}
+	})
+}
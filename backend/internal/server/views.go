@@ -0,0 +1,78 @@
+// Saved task list views: named filter and sort combinations persisted in the user's preferences.
+package server
+
+import (
+	"context"
+	"net/url"
+	"slices"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/server/dto"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+)
+
+// taskViewParams are the task list query parameters a view can hold.
+var taskViewParams = []string{"includeArchived", "pinned", "state", "repo", "project", "createdWithin", "stateOlderThan", "sort", "order"}
+
+// validateViewQuery checks that query only holds task list filters and that
+// the task list accepts them.
+func validateViewQuery(query string, settings *preferences.Settings) error {
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return dto.Invalid("query", query)
+	}
+	for name := range q {
+		if !slices.Contains(taskViewParams, name) {
+			return dto.Invalid("query", query).WithParam("param", name)
+		}
+	}
+	if _, err := parseTaskFilter(q, settings); err != nil {
+		return err
+	}
+	_, err = parseTaskSort(q)
+	return err
+}
+
+// toV1TaskViews converts the saved views for the API.
+func toV1TaskViews(views []preferences.TaskView) *v1.TaskViewsResp {
+	resp := &v1.TaskViewsResp{Views: make([]v1.TaskView, len(views))}
+	for i, v := range views {
+		resp.Views[i] = v1.TaskView{Name: v.Name, Query: v.Query, Default: v.Default}
+	}
+	return resp
+}
+
+func (s *Server) listTaskViews(ctx context.Context, _ *dto.EmptyReq) (*v1.TaskViewsResp, error) {
+	return toV1TaskViews(s.prefs.Get(userIDFromCtx(ctx)).Views), nil
+}
+
+// saveTaskView adds or replaces the view of the same name.
+func (s *Server) saveTaskView(ctx context.Context, req *v1.TaskView) (*v1.TaskViewsResp, error) {
+	settings := s.prefs.Get(userIDFromCtx(ctx)).Settings
+	if err := validateViewQuery(req.Query, &settings); err != nil {
+		return nil, err
+	}
+	var views []preferences.TaskView
+	if err := s.prefs.Update(userIDFromCtx(ctx), func(p *preferences.Preferences) {
+		p.SaveView(preferences.TaskView{Name: req.Name, Query: req.Query, Default: req.Default})
+		views = p.Views
+	}); err != nil {
+		return nil, dto.InternalError("save view: " + err.Error())
+	}
+	return toV1TaskViews(views), nil
+}
+
+func (s *Server) deleteTaskView(ctx context.Context, req *v1.DeleteTaskViewReq) (*v1.TaskViewsResp, error) {
+	found := false
+	var views []preferences.TaskView
+	if err := s.prefs.Update(userIDFromCtx(ctx), func(p *preferences.Preferences) {
+		found = p.DeleteView(req.Name)
+		views = p.Views
+	}); err != nil {
+		return nil, dto.InternalError("delete view: " + err.Error())
+	}
+	if !found {
+		return nil, dto.NotFound("view")
+	}
+	return toV1TaskViews(views), nil
+}
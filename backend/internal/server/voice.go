@@ -31,7 +31,7 @@ func (s *Server) handleVoiceRTCClose(w http.ResponseWriter, r *http.Request) {
 	}
 	sessionID := r.PathValue("sessionID")
 	if sessionID == "" {
-		writeError(w, dto.BadRequest("sessionID is required"))
+		writeError(w, dto.Required("sessionID"))
 		return
 	}
 	s.voiceBridge.Close(sessionID)
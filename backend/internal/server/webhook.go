@@ -61,6 +61,20 @@ func (s *Server) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		s.handleIssueCommentEvent(r.Context(), &ev)
+	case "pull_request_review":
+		var ev github.PullRequestReviewEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+			return
+		}
+		s.handlePullRequestReviewEvent(&ev)
+	case "pull_request_review_comment":
+		var ev github.PullRequestReviewCommentEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			http.Error(w, "bad payload", http.StatusBadRequest)
+			return
+		}
+		s.handlePullRequestReviewCommentEvent(&ev)
 	case "installation":
 		var ev github.InstallationEvent
 		if err := json.Unmarshal(body, &ev); err != nil {
@@ -41,6 +41,7 @@ func (s *stubAppClient) PostComment(_ context.Context, _ int64, _, _ string, _ i
 type stubForge struct {
 	headSHA   string
 	checkRuns []forge.CheckRun
+	statuses  []postedStatus
 }
 
 func (f *stubForge) GetDefaultBranchSHA(_ context.Context, _, _, _ string) (string, error) {
@@ -70,6 +71,16 @@ func (f *stubForge) GetJobLabels(_ context.Context, _, _ string, _ int64) ([]str
 func (f *stubForge) MergePR(_ context.Context, _, _ string, _ int, _, _ string) error {
 	return nil
 }
+func (f *stubForge) SetCommitStatus(_ context.Context, owner, repo, sha string, st *forge.CommitStatus) error {
+	f.statuses = append(f.statuses, postedStatus{owner, repo, sha, *st})
+	return nil
+}
+
+// postedStatus is a commit status recorded by stubForge.
+type postedStatus struct {
+	owner, repo, sha string
+	st               forge.CommitStatus
+}
 
 // signGitHub computes X-Hub-Signature-256 for the given body and secret.
 func signGitHub(body, secret []byte) string {
@@ -0,0 +1,75 @@
+// Execution window: new tasks stay pending until the owner's window is open.
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	v1 "github.com/caic-xyz/caic/backend/internal/server/dto/v1"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+// windowPollInterval bounds how long a held task takes to notice a change of
+// the execution window in the preferences.
+const windowPollInterval = time.Minute
+
+// waitForWindow blocks until the execution window of the owner of entry's
+// task is open, exposing why the task is held in its JSON meanwhile. It
+// returns errWaitCanceled when ctx is canceled.
+func (s *Server) waitForWindow(ctx context.Context, entry *taskEntry) error {
+	defer s.setHeldReason(entry, "")
+	for {
+		now := time.Now()
+		opensAt := s.windowOpensAt(entry.task, now)
+		if !opensAt.After(now) {
+			return nil
+		}
+		s.setHeldReason(entry, "outside the execution window; starts at "+opensAt.Format(time.RFC3339))
+		timer := time.NewTimer(min(opensAt.Sub(now), windowPollInterval))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errWaitCanceled
+		}
+	}
+}
+
+// windowOpensAt returns now when t may start, else when the execution window
+// of its owner next opens.
+func (s *Server) windowOpensAt(t *task.Task, now time.Time) time.Time {
+	ownerID := t.OwnerID
+	if ownerID == "" {
+		ownerID = "default"
+	}
+	w := s.prefs.Get(ownerID).Settings.ExecutionWindow
+	if w == nil {
+		return now
+	}
+	return w.OpensAt(now)
+}
+
+// setHeldReason records why entry's pending task has not started.
+func (s *Server) setHeldReason(entry *taskEntry, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry.heldReason != reason {
+		entry.heldReason = reason
+		s.taskChanged()
+	}
+}
+
+func prefsToV1ExecutionWindow(w *preferences.ExecutionWindow) *v1.ExecutionWindow {
+	if w == nil {
+		return nil
+	}
+	return &v1.ExecutionWindow{Start: w.Start, End: w.End, Timezone: w.Timezone}
+}
+
+func prefsFromV1ExecutionWindow(w *v1.ExecutionWindow) *preferences.ExecutionWindow {
+	if w == nil {
+		return nil
+	}
+	return &preferences.ExecutionWindow{Start: w.Start, End: w.End, Timezone: w.Timezone}
+}
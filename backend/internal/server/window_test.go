@@ -0,0 +1,65 @@
+// Tests for the execution window.
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/preferences"
+	"github.com/caic-xyz/caic/backend/internal/task"
+)
+
+func TestWaitForWindow(t *testing.T) {
+	newEntry := func(s *Server, w *preferences.ExecutionWindow) *taskEntry {
+		if err := s.prefs.Update("default", func(p *preferences.Preferences) {
+			p.Settings.ExecutionWindow = w
+		}); err != nil {
+			t.Fatal(err)
+		}
+		e := &taskEntry{task: &task.Task{InitialPrompt: agent.Prompt{Text: "test"}}, done: make(chan struct{})}
+		s.tasks["t1"] = e
+		return e
+	}
+	t.Run("NoWindow", func(t *testing.T) {
+		s := newTestServer(t)
+		e := newEntry(s, nil)
+		if err := s.waitForWindow(t.Context(), e); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("Held", func(t *testing.T) {
+		s := newTestServer(t)
+		// A one-hour window starting in two hours.
+		now := time.Now().UTC()
+		w := &preferences.ExecutionWindow{
+			Start:    now.Add(2 * time.Hour).Format("15:04"),
+			End:      now.Add(3 * time.Hour).Format("15:04"),
+			Timezone: "UTC",
+		}
+		e := newEntry(s, w)
+		ctx, cancel := context.WithCancel(t.Context())
+		errc := make(chan error)
+		go func() { errc <- s.waitForWindow(ctx, e) }()
+		for {
+			s.mu.Lock()
+			reason := s.toJSON(e).HeldReason
+			s.mu.Unlock()
+			if reason != "" {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+		if err := <-errc; !errors.Is(err, errWaitCanceled) {
+			t.Errorf("err = %v, want errWaitCanceled", err)
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if reason := s.toJSON(e).HeldReason; reason != "" {
+			t.Errorf("heldReason = %q after the wait", reason)
+		}
+	})
+}
@@ -0,0 +1,160 @@
+// Package slack implements the Slack app of caic: request verification, the
+// payloads of slash commands and the Events API, and posting messages.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maruel/roundtrippers"
+)
+
+// maxSkew is the maximum age of a signed request, to reject replays.
+const maxSkew = 5 * time.Minute
+
+// VerifySignature verifies the signature of a request from Slack. ts and sig
+// are its X-Slack-Request-Timestamp and X-Slack-Signature headers.
+func VerifySignature(secret, body []byte, ts, sig string, now time.Time) error {
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("slack signature: invalid timestamp %q", ts)
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > maxSkew || d < -maxSkew {
+		return errors.New("slack signature: stale timestamp")
+	}
+	hexSig, ok := strings.CutPrefix(sig, "v0=")
+	if !ok {
+		return fmt.Errorf("slack signature: invalid format %q (expected v0=<hex>)", sig)
+	}
+	got, err := hex.DecodeString(hexSig)
+	if err != nil {
+		return fmt.Errorf("slack signature: invalid hex: %w", err)
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return errors.New("slack signature: HMAC mismatch")
+	}
+	return nil
+}
+
+// Command is a slash command invocation, e.g. "/caic run repo prompt".
+type Command struct {
+	Command   string // e.g. "/caic"
+	Text      string // Arguments after the command.
+	UserID    string
+	ChannelID string
+}
+
+// ParseCommand parses the form encoded body of a slash command request.
+func ParseCommand(body []byte) (*Command, error) {
+	v, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+	return &Command{Command: v.Get("command"), Text: v.Get("text"), UserID: v.Get("user_id"), ChannelID: v.Get("channel_id")}, nil
+}
+
+// Envelope is a request of the Events API.
+type Envelope struct {
+	Type      string       `json:"type"` // "url_verification" or "event_callback"
+	Challenge string       `json:"challenge"`
+	Event     MessageEvent `json:"event"`
+}
+
+// MessageEvent is a "message" event. Messages of bots have a BotID, edits
+// and other changes a Subtype.
+type MessageEvent struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	Channel  string `json:"channel"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+}
+
+// IsThreadReply reports whether e is a message of a user replying in a
+// thread.
+func (e *MessageEvent) IsThreadReply() bool {
+	return e.Type == "message" && e.Subtype == "" && e.BotID == "" && e.ThreadTS != "" && e.ThreadTS != e.TS
+}
+
+// Client posts messages as the bot of a Slack app.
+type Client struct {
+	HTTPClient *http.Client
+	APIURL     string // Web API base URL; defaults to apiURL.
+}
+
+const apiURL = "https://slack.com/api"
+
+// NewClient returns a client authenticated with the bot token, "xoxb-...".
+func NewClient(token string) *Client {
+	return &Client{
+		HTTPClient: &http.Client{
+			Transport: &roundtrippers.Header{
+				Transport: &roundtrippers.Retry{Transport: http.DefaultTransport},
+				Header: http.Header{
+					"Authorization": {"Bearer " + token},
+					"Content-Type":  {"application/json; charset=utf-8"},
+				},
+			},
+		},
+		APIURL: apiURL,
+	}
+}
+
+// PostMessage posts text in channel, as a reply in the thread threadTS unless
+// empty, and returns the timestamp of the message.
+func (c *Client) PostMessage(ctx context.Context, channel, threadTS, text string) (string, error) {
+	payload, err := json.Marshal(struct {
+		Channel  string `json:"channel"`
+		ThreadTS string `json:"thread_ts,omitempty"`
+		Text     string `json:"text"`
+	}{channel, threadTS, text})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.APIURL+"/chat.postMessage", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("slack post message: status %d: %s", resp.StatusCode, data)
+	}
+	var r struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return "", err
+	}
+	if !r.OK {
+		return "", fmt.Errorf("slack post message: %s", r.Error)
+	}
+	return r.TS, nil
+}
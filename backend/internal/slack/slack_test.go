@@ -0,0 +1,92 @@
+// Tests for the Slack app helpers.
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("8f742231b10e8888abcd99yyyzzz85a5")
+	body := []byte("token=x&command=%2Fcaic&text=run+caic+fix")
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	sig := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if err := VerifySignature(secret, body, ts, sig, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifySignature(secret, body, ts, sig, now.Add(10*time.Minute)); err == nil {
+		t.Error("stale request accepted")
+	}
+	if err := VerifySignature([]byte("other"), body, ts, sig, now); err == nil {
+		t.Error("wrong secret accepted")
+	}
+	if err := VerifySignature(secret, body, ts, "sha256=00", now); err == nil {
+		t.Error("invalid format accepted")
+	}
+}
+
+func TestParse(t *testing.T) {
+	cmd, err := ParseCommand([]byte("command=%2Fcaic&text=run+caic+fix+it&user_id=U1&channel_id=C1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Command{Command: "/caic", Text: "run caic fix it", UserID: "U1", ChannelID: "C1"}); *cmd != want {
+		t.Errorf("ParseCommand = %+v, want %+v", *cmd, want)
+	}
+	var env Envelope
+	if err := json.Unmarshal([]byte(`{"type":"event_callback","event":{"type":"message","channel":"C1","user":"U1","text":"go on","ts":"2.0","thread_ts":"1.0"}}`), &env); err != nil {
+		t.Fatal(err)
+	}
+	if !env.Event.IsThreadReply() {
+		t.Errorf("IsThreadReply(%+v) = false", env.Event)
+	}
+	for _, e := range []MessageEvent{
+		{Type: "message", TS: "1.0"},
+		{Type: "message", TS: "1.0", ThreadTS: "1.0"},
+		{Type: "message", TS: "2.0", ThreadTS: "1.0", BotID: "B1"},
+		{Type: "message", TS: "2.0", ThreadTS: "1.0", Subtype: "message_changed"},
+	} {
+		if e.IsThreadReply() {
+			t.Errorf("IsThreadReply(%+v) = true", e)
+		}
+	}
+}
+
+func TestPostMessage(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" || r.Header.Get("Authorization") != "Bearer xoxb-1" {
+			_, _ = io.WriteString(w, `{"ok":false,"error":"invalid_auth"}`)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = io.WriteString(w, `{"ok":true,"ts":"1.5"}`)
+	}))
+	defer srv.Close()
+	c := NewClient("xoxb-1")
+	c.APIURL = srv.URL
+	ts, err := c.PostMessage(t.Context(), "C1", "1.0", "done")
+	if err != nil || ts != "1.5" {
+		t.Fatalf("PostMessage = %q, %v", ts, err)
+	}
+	if got["channel"] != "C1" || got["thread_ts"] != "1.0" || got["text"] != "done" {
+		t.Errorf("posted %v", got)
+	}
+	c = NewClient("xoxb-2")
+	c.APIURL = srv.URL
+	if _, err := c.PostMessage(t.Context(), "C1", "", "x"); err == nil || err.Error() != "slack post message: invalid_auth" {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Persistent allocation of the sequence numbers of caic-N work branches,
+// shared by the repos and the caic instances pushing to the same remote.
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/filelock"
+)
+
+const (
+	// branchIDsLockTimeout bounds the wait for another instance to release
+	// the lock of the store.
+	branchIDsLockTimeout = 10 * time.Second
+	// branchIDsStaleLock is the age past which a lock is deemed left by a
+	// crashed instance and broken.
+	branchIDsStaleLock = time.Minute
+)
+
+// BranchIDs hands out the sequence numbers of work branches from a JSON file
+// of counters keyed by remote, so that clones of the same remote draw from the
+// same sequence. Every increment re-reads the file under an exclusive lock
+// file, so caic instances sharing the file never hand out the same number.
+// All methods are safe for concurrent use.
+type BranchIDs struct {
+	path string
+	mu   sync.Mutex
+}
+
+// OpenBranchIDs opens the store at path, creating its directory as needed.
+// The file itself is created by the first allocation.
+func OpenBranchIDs(path string) (*BranchIDs, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	return &BranchIDs{path: path}, nil
+}
+
+// Next atomically allocates the next number of the sequence key, at least
+// floor, and returns it.
+func (b *BranchIDs) Next(ctx context.Context, key string, floor int) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	unlock, err := b.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	counters, err := b.read()
+	if err != nil {
+		return 0, err
+	}
+	n := max(counters[key], floor)
+	counters[key] = n + 1
+	if err := b.write(counters); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// lock creates the lock file, waiting for the instance holding it.
+func (b *BranchIDs) lock(ctx context.Context) (func(), error) {
+	ctx, cancel := context.WithTimeout(ctx, branchIDsLockTimeout)
+	defer cancel()
+	unlock, err := filelock.Acquire(ctx, b.path+".lock", branchIDsStaleLock)
+	if err != nil {
+		return nil, fmt.Errorf("lock branch IDs: %w", err)
+	}
+	return unlock, nil
+}
+
+func (b *BranchIDs) read() (map[string]int, error) {
+	counters := map[string]int{}
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return counters, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &counters); err != nil {
+		return nil, fmt.Errorf("parse branch IDs: %w", err)
+	}
+	return counters, nil
+}
+
+func (b *BranchIDs) write(counters map[string]int) error {
+	data, err := json.MarshalIndent(counters, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/maruel/ksid"
+)
+
+func TestBranchIDs(t *testing.T) {
+	open := func(t *testing.T, path string) *BranchIDs {
+		t.Helper()
+		b, err := OpenBranchIDs(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	t.Run("Next", func(t *testing.T) {
+		b := open(t, filepath.Join(t.TempDir(), "ids.json"))
+		for _, tt := range []struct {
+			key         string
+			floor, want int
+		}{
+			{"a", 0, 0},
+			{"a", 0, 1},
+			{"b", 0, 0},
+			{"a", 5, 5},
+			{"a", 3, 6},
+		} {
+			if got, err := b.Next(t.Context(), tt.key, tt.floor); err != nil || got != tt.want {
+				t.Errorf("Next(%q, %d) = %d, %v; want %d", tt.key, tt.floor, got, err, tt.want)
+			}
+		}
+	})
+	t.Run("SharedFile", func(t *testing.T) {
+		// Two instances sharing the file never hand out the same number.
+		path := filepath.Join(t.TempDir(), "ids.json")
+		stores := []*BranchIDs{open(t, path), open(t, path)}
+		var mu sync.Mutex
+		seen := map[int]bool{}
+		var wg sync.WaitGroup
+		for i := range 20 {
+			wg.Go(func() {
+				n, err := stores[i%2].Next(t.Context(), "r", 0)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if seen[n] {
+					t.Errorf("%d allocated twice", n)
+				}
+				seen[n] = true
+			})
+		}
+		wg.Wait()
+		if len(seen) != 20 {
+			t.Errorf("allocated %d numbers, want 20", len(seen))
+		}
+	})
+	t.Run("StaleLock", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ids.json")
+		if err := os.WriteFile(path+".lock", nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		old := time.Now().Add(-2 * branchIDsStaleLock)
+		if err := os.Chtimes(path+".lock", old, old); err != nil {
+			t.Fatal(err)
+		}
+		if n, err := open(t, path).Next(t.Context(), "r", 0); err != nil || n != 0 {
+			t.Errorf("Next = %d, %v", n, err)
+		}
+	})
+	t.Run("SharedRemote", func(t *testing.T) {
+		// Two clones of the same remote draw from the same sequence and skip
+		// the branches the remote already has.
+		clone := initTestRepo(t, "main")
+		other := filepath.Join(t.TempDir(), "other")
+		bare := filepath.Join(filepath.Dir(clone), "remote.git")
+		runGit(t, "", "clone", "-q", bare, other)
+		runGit(t, clone, "branch", "caic-1")
+		runGit(t, clone, "push", "-q", "origin", "caic-1")
+		runGit(t, other, "fetch", "-q")
+
+		b := open(t, filepath.Join(t.TempDir(), "ids.json"))
+		var branches []string
+		for _, dir := range []string{other, clone, other} {
+			r := &Runner{BaseBranch: "main", Dir: dir, BranchIDs: b}
+			if err := r.Init(t.Context()); err != nil {
+				t.Fatal(err)
+			}
+			tk := &Task{ID: ksid.NewID(), Repos: []RepoMount{{Name: "r"}}, Harness: agent.Claude}
+			if err := r.reserveBranch(t.Context(), tk); err != nil {
+				t.Fatal(err)
+			}
+			branches = append(branches, tk.Repos[0].Branch)
+		}
+		if want := []string{"caic-2", "caic-3", "caic-4"}; !slices.Equal(branches, want) {
+			t.Errorf("branches = %v, want %v", branches, want)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+// Chat tasks: a containerized conversation over a repo without a branch, diff or push.
+package task
+
+import (
+	"context"
+	"strings"
+
+	"github.com/caic-xyz/md/gitutil"
+)
+
+// chatRef returns the name pushed into the container for a chat task. It lives
+// directly under refs/ rather than refs/heads/ so it never shows up as a
+// branch or consumes a caic-N number, and git resolves it like a branch name.
+// The task ID keeps the derived container name unique.
+func chatRef(t *Task) string {
+	return "caic-chat-" + strings.ToLower(t.ID.String())
+}
+
+// deleteChatRef removes the ref created for a chat task. Errors are only
+// logged: a leftover ref is harmless.
+func (r *Runner) deleteChatRef(ctx context.Context, ref string) {
+	if r.Dir == "" || ref == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	if _, err := gitutil.RunGit(ctx, r.Dir, "update-ref", "-d", "refs/"+ref); err != nil {
+		r.log.WarnContext(ctx, "delete chat ref failed", "ref", ref, "err", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Listing of the commits the agent made on a task branch.
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/logctx"
+	"github.com/caic-xyz/md"
+)
+
+// maxCommits caps the commits returned by Commits.
+const maxCommits = 500
+
+// Commit is a commit of a task branch.
+type Commit struct {
+	SHA       string
+	Author    string
+	Message   string // Full message, subject first.
+	Authored  time.Time
+	Committed time.Time
+	Files     agent.DiffStat
+}
+
+// Commits fetches from the container and returns the commits of the task
+// branch that are not on base, newest first. When the fetch fails, e.g.
+// because the container is stopped, the last fetched ref is used.
+func (r *Runner) Commits(ctx context.Context, branch, base, container string, extraRepos []md.Repo) ([]Commit, error) {
+	r.initDefaults()
+	if r.Container == nil || r.Dir == "" {
+		return nil, errors.New("commits are not supported for no-repo tasks")
+	}
+	if base == "" {
+		base = r.BaseBranch
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	r.branchMu.Lock()
+	defer r.branchMu.Unlock()
+	if err := r.Container.Fetch(ctx, append([]md.Repo{{GitRoot: r.Dir, Branch: branch}}, extraRepos...)); err != nil {
+		r.log.WarnContext(ctx, "fetch for commits failed", "br", branch, "err", err)
+	}
+	baseRef := "origin/" + base
+	if err := exec.CommandContext(ctx, "git", "-C", r.Dir, "rev-parse", "--verify", "-q", baseRef).Run(); err != nil { //nolint:gosec // branch names are from internal git state.
+		baseRef = base
+	}
+	cmd := exec.CommandContext(ctx, "git", "log", "--max-count="+strconv.Itoa(maxCommits), //nolint:gosec // branch names are from internal git state.
+		"--format=%x1e%H%x1f%an%x1f%aI%x1f%cI%x1f%B%x1f", "--numstat",
+		"refs/remotes/"+container+"/"+branch, "--not", baseRef, "--")
+	cmd.Dir = r.Dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git log: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	return parseCommitLog(string(out)), nil
+}
+
+// parseCommitLog parses the output of the git log invocation in Commits:
+// each commit starts with \x1e and its fields are separated by \x1f, the
+// last one being the --numstat lines.
+func parseCommitLog(out string) []Commit {
+	commits := []Commit{}
+	for rec := range strings.SplitSeq(out, "\x1e") {
+		f := strings.SplitN(rec, "\x1f", 6)
+		if len(f) != 6 {
+			continue
+		}
+		c := Commit{SHA: f[0], Author: f[1], Message: strings.TrimSpace(f[4]), Files: ParseDiffNumstat(f[5])}
+		c.Authored, _ = time.Parse(time.RFC3339, f[2])
+		c.Committed, _ = time.Parse(time.RFC3339, f[3])
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// shaRe matches an abbreviated or full commit hash.
+var shaRe = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// revertScript reverts a commit of the task branch in the container and
+// prints the revert commit's hash and the reverted commit's subject. It
+// refuses commits that are not on the branch or that came from the base
+// (the "base" ref md creates), and aborts on conflicts so the tree stays
+// clean.
+const revertScript = `set -e
+sha=%[1]s
+git merge-base --is-ancestor "$sha" HEAD 2>/dev/null || { echo "commit $sha is not on the task branch" >&2; exit 1; }
+if git merge-base --is-ancestor "$sha" base; then echo "commit $sha is on the base branch" >&2; exit 1; fi
+subject=$(git log -1 --format=%%s "$sha")
+if ! git revert --no-edit "$sha" >/dev/null 2>&1; then
+	git revert --abort >/dev/null 2>&1 || true
+	echo "reverting $sha conflicts with later changes" >&2
+	exit 1
+fi
+git rev-parse HEAD
+printf '%%s\n' "$subject"
+`
+
+// RevertCommit reverts a commit of the task branch inside the container with
+// a new commit. It returns the revert commit's hash and the subject of the
+// reverted commit.
+func (r *Runner) RevertCommit(ctx context.Context, t *Task, sha string) (string, string, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
+	r.initDefaults()
+	if r.Container == nil || t.Container == "" || r.Dir == "" {
+		return "", "", errors.New("task has no container")
+	}
+	if !shaRe.MatchString(sha) {
+		return "", "", fmt.Errorf("invalid commit %q", sha)
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	r.branchMu.Lock()
+	defer r.branchMu.Unlock()
+	out, err := r.Container.Exec(ctx, t.Container, r.containerDir(), fmt.Sprintf(revertScript, sha))
+	if err != nil {
+		if out != "" {
+			return "", "", errors.New(out)
+		}
+		return "", "", err
+	}
+	revert, subject, _ := strings.Cut(out, "\n")
+	r.log.InfoContext(ctx, "reverted commit", "sha", sha, "revert", revert)
+	return revert, subject, nil
+}
@@ -0,0 +1,152 @@
+// Automatic context compaction triggered when the agent's context window fills up.
+package task
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// DefaultAutoCompactThreshold is the context window fill ratio at which the
+// runner compacts the session when Runner.AutoCompactThreshold is zero.
+const DefaultAutoCompactThreshold = 0.85
+
+// compactSummaryPrompt asks the agent to summarize its state before the
+// session is restarted. Used for harnesses without a native compact command.
+const compactSummaryPrompt = "Your context window is almost full and this session is about to be restarted. " +
+	"Write a self-contained summary of the task, the decisions taken, the work done so far, " +
+	"the current state of the code and the remaining steps, so that a fresh session can continue " +
+	"exactly where you left off. Reply with only the summary."
+
+// compactPhase tracks an in-flight automatic compaction.
+type compactPhase int
+
+const (
+	compactIdle        compactPhase = iota
+	compactPending                  // Compact command sent; waiting for the turn to end.
+	compactSummarizing              // Summary prompt sent; waiting for the summary.
+)
+
+// syntheticAutoCompact creates a SystemMessage marking the point in the
+// transcript where caic compacted the session automatically.
+func syntheticAutoCompact(detail string) *agent.SystemMessage {
+	return &agent.SystemMessage{
+		MessageType: "system",
+		Subtype:     "caic_auto_compact",
+		Detail:      detail,
+	}
+}
+
+// contextFill returns the number of prompt tokens used by the last API call
+// and the context window limit. The limit is 0 when unknown.
+func (t *Task) contextFill(fallbackLimit int) (used, limit int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u := t.lastAPIUsage
+	used = u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	limit = t.reportedContextWindow
+	if limit <= 0 {
+		limit = fallbackLimit
+	}
+	return used, limit
+}
+
+// swapCompactPhase sets the compaction phase and returns the previous one.
+func (t *Task) swapCompactPhase(p compactPhase) compactPhase {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev := t.compactPhase
+	t.compactPhase = p
+	return prev
+}
+
+// autoCompactThreshold returns the effective threshold; 0 means disabled.
+func (r *Runner) autoCompactThreshold() float64 {
+	switch {
+	case r.AutoCompactThreshold < 0:
+		return 0
+	case r.AutoCompactThreshold == 0:
+		return DefaultAutoCompactThreshold
+	default:
+		return r.AutoCompactThreshold
+	}
+}
+
+// maybeAutoCompact is called by the dispatch goroutine after a ResultMessage
+// was recorded. When the context window is above the threshold, it either
+// sends the harness's compact command or, for harnesses without one, asks the
+// agent for a summary and restarts the session with it once the summary
+// arrives.
+func (r *Runner) maybeAutoCompact(ctx context.Context, t *Task, rm *agent.ResultMessage) {
+	switch t.swapCompactPhase(compactIdle) {
+	case compactPending:
+		// This result ends the compaction turn itself.
+		return
+	case compactSummarizing:
+		summary := strings.TrimSpace(rm.Result)
+		if rm.IsError || summary == "" {
+			r.log.WarnContext(ctx, "auto compact: no summary, keeping session")
+			return
+		}
+		// RestartSession waits for this dispatch goroutine to drain, so it
+		// must run asynchronously.
+		go r.restartWithSummary(context.WithoutCancel(ctx), t, summary)
+		return
+	case compactIdle:
+	}
+	threshold := r.autoCompactThreshold()
+	if threshold == 0 {
+		return
+	}
+	b := r.backend(t.Harness)
+	if b == nil {
+		return
+	}
+	used, limit := t.contextFill(b.ContextWindowLimit(t.Model))
+	if limit <= 0 || float64(used) < threshold*float64(limit) {
+		return
+	}
+	detail := fmt.Sprintf("context %d/%d tokens (%.0f%%)", used, limit, 100*float64(used)/float64(limit))
+	marker := syntheticAutoCompact(detail)
+	if b.SupportsCompact() {
+		t.swapCompactPhase(compactPending)
+		t.addMessage(ctx, marker, true)
+		t.WriteToLog(marker)
+		if err := t.SendCompact(ctx, ""); err != nil {
+			t.swapCompactPhase(compactIdle)
+			r.log.WarnContext(ctx, "auto compact failed", "err", err)
+			return
+		}
+		r.log.InfoContext(ctx, "auto compact", "used", used, "limit", limit)
+		return
+	}
+	t.swapCompactPhase(compactSummarizing)
+	t.addMessage(ctx, marker, true)
+	t.WriteToLog(marker)
+	if err := t.SendInput(ctx, agent.Prompt{Text: compactSummaryPrompt}); err != nil {
+		t.swapCompactPhase(compactIdle)
+		r.log.WarnContext(ctx, "auto compact summary failed", "err", err)
+		return
+	}
+	r.log.InfoContext(ctx, "auto compact: requesting summary", "used", used, "limit", limit)
+}
+
+// restartWithSummary restarts the agent session with the summary produced by
+// the previous one and notifies OnSessionRestarted.
+func (r *Runner) restartWithSummary(ctx context.Context, t *Task, summary string) {
+	prompt := agent.Prompt{Text: "This session continues a previous one that ran out of context. " +
+		"Summary of the previous session:\n\n" + summary}
+	h, err := r.RestartSession(ctx, t, prompt)
+	if err != nil {
+		r.log.WarnContext(ctx, "auto compact restart failed", "err", err)
+		return
+	}
+	marker := syntheticAutoCompact("session restarted with summary")
+	t.addMessage(ctx, marker, true)
+	t.WriteToLog(marker)
+	if r.OnSessionRestarted != nil {
+		r.OnSessionRestarted(t, h)
+	}
+}
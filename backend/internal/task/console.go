@@ -0,0 +1,32 @@
+// Task console: the raw output of the container and of the agent process,
+// outside of the structured message stream.
+package task
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Console line sources.
+const (
+	ConsoleContainer = "container" // stdout/stderr of the container.
+	ConsoleAgent     = "agent"     // stderr of the agent process, not of the commands it runs as tools.
+)
+
+// ConsoleLine is a line of console output.
+type ConsoleLine struct {
+	Source string
+	Text   string
+	At     time.Time
+}
+
+// Console streams the last tail lines of each console source of the task's
+// container to fn, then the new lines as they come, until ctx is done or the
+// container stops.
+func (r *Runner) Console(ctx context.Context, t *Task, tail int, fn func(ConsoleLine)) error {
+	if r.Container == nil || t.Container == "" {
+		return errors.New("task has no container")
+	}
+	return r.Container.Console(ctx, t.Container, tail, fn)
+}
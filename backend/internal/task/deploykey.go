@@ -0,0 +1,49 @@
+// Deploy keys: per-repo SSH keys used by the container's git instead of the user's credentials.
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/md"
+)
+
+// DeployKeySSHCommand returns the git core.sshCommand authenticating with the
+// private key at keyFile only.
+func DeployKeySSHCommand(keyFile string) string {
+	return "ssh -i " + agent.ShellQuote(keyFile) + " -o IdentitiesOnly=yes -o StrictHostKeyChecking=accept-new"
+}
+
+// installDeployKeys copies the deploy key of each repo of the task into the
+// container name and configures the repo's clone there to use it. The HTTPS
+// origin set by md is rewritten to SSH, which deploy keys require.
+func (r *Runner) installDeployKeys(ctx context.Context, t *Task, name string) error {
+	for i := range t.Repos {
+		m := &t.Repos[i]
+		if m.DeployKey == "" {
+			continue
+		}
+		key, err := os.ReadFile(m.DeployKey)
+		if err != nil {
+			return fmt.Errorf("deploy key of %s: %w", m.Name, err)
+		}
+		// ssh expands the tilde of -i.
+		dst := "~/.ssh/caic_deploy_key_" + strconv.Itoa(i)
+		script := "set -e\n" +
+			"install -d -m 700 ~/.ssh\n" +
+			"umask 077\n" +
+			"printf '%s' " + agent.ShellQuote(string(key)) + " > " + dst + "\n" +
+			"git config core.sshCommand " + agent.ShellQuote(DeployKeySSHCommand(dst)) + "\n" +
+			`u=$(git remote get-url origin 2>/dev/null || true)` + "\n" +
+			`case "$u" in https://*) h=${u#https://}; git config "url.git@${h%%/*}:${h#*/}.insteadOf" "$u";; esac` + "\n"
+		dir := "/home/user/src/" + md.Repo{GitRoot: m.GitRoot}.Name()
+		r.log.InfoContext(ctx, "installing deploy key", "ctr", name, "repo", m.Name)
+		if out, err := r.Container.Exec(ctx, name, dir, script); err != nil {
+			return fmt.Errorf("install deploy key of %s: %w: %s", m.Name, err, out)
+		}
+	}
+	return nil
+}
@@ -37,3 +37,78 @@ func ParseDiffNumstat(numstat string) agent.DiffStat {
 	}
 	return files
 }
+
+// diffStatArgs are the git diff arguments whose output ParseDiffRawNumstat
+// parses: the per-file status of --name-status along with the counts.
+var diffStatArgs = []string{"--raw", "--numstat", "-z"}
+
+// ParseDiffRawNumstat parses git diff --raw --numstat -z output into a
+// DiffStat with each file's status. The raw records come first:
+// ":<modes> <shas> <status>\0<path>\0", with a second path for renames and
+// copies. Numstat records follow: "<added>\t<deleted>\t<path>\0", or
+// "<added>\t<deleted>\t\0<old>\0<new>\0" for renames.
+func ParseDiffRawNumstat(out string) agent.DiffStat {
+	tokens := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	statuses := map[string]agent.DiffFileStatus{}
+	var files agent.DiffStat
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if strings.HasPrefix(tok, ":") {
+			fields := strings.Fields(tok)
+			if len(fields) == 0 || i+1 >= len(tokens) {
+				break
+			}
+			code := fields[len(fields)-1]
+			path := tokens[i+1]
+			i++
+			if code[0] == 'R' || code[0] == 'C' {
+				if i+1 >= len(tokens) {
+					break
+				}
+				path = tokens[i+1]
+				i++
+			}
+			statuses[path] = fileStatus(code[0])
+			continue
+		}
+		parts := strings.SplitN(tok, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		fs := agent.DiffFileStat{Path: parts[2]}
+		if fs.Path == "" {
+			if i+2 >= len(tokens) {
+				break
+			}
+			fs.OldPath, fs.Path = tokens[i+1], tokens[i+2]
+			i += 2
+		}
+		if parts[0] == "-" && parts[1] == "-" {
+			fs.Binary = true
+		} else {
+			fs.Added, _ = strconv.Atoi(parts[0])
+			fs.Deleted, _ = strconv.Atoi(parts[1])
+		}
+		fs.Status = statuses[fs.Path]
+		if fs.Status == "" {
+			fs.Status = agent.FileModified
+		}
+		files = append(files, fs)
+	}
+	return files
+}
+
+// fileStatus maps a git status letter to a DiffFileStatus. Copies are new
+// files; type changes and unmerged entries count as modifications.
+func fileStatus(code byte) agent.DiffFileStatus {
+	switch code {
+	case 'A', 'C':
+		return agent.FileAdded
+	case 'D':
+		return agent.FileDeleted
+	case 'R':
+		return agent.FileRenamed
+	default:
+		return agent.FileModified
+	}
+}
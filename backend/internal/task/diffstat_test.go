@@ -1,6 +1,7 @@
 package task
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
@@ -62,3 +63,26 @@ func TestParseDiffNumstat(t *testing.T) {
 		}
 	})
 }
+
+func TestParseDiffRawNumstat(t *testing.T) {
+	input := ":000000 100644 0000000 3e75765 A\x00added.go\x00" +
+		":100644 100644 bdc955b 8835708 M\x00bin.dat\x00" +
+		":100644 000000 587be6b 0000000 D\x00del.txt\x00" +
+		":100644 100644 0fdf397 f9d9a01 R085\x00old.txt\x00sub/new.txt\x00" +
+		"1\t0\tadded.go\x00" +
+		"-\t-\tbin.dat\x00" +
+		"0\t1\tdel.txt\x00" +
+		"1\t0\t\x00old.txt\x00sub/new.txt\x00"
+	want := agent.DiffStat{
+		{Path: "added.go", Added: 1, Status: agent.FileAdded},
+		{Path: "bin.dat", Binary: true, Status: agent.FileModified},
+		{Path: "del.txt", Deleted: 1, Status: agent.FileDeleted},
+		{Path: "sub/new.txt", Added: 1, Status: agent.FileRenamed, OldPath: "old.txt"},
+	}
+	if got := ParseDiffRawNumstat(input); !slices.Equal(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if got := ParseDiffRawNumstat(""); got != nil {
+		t.Errorf("empty: got %+v", got)
+	}
+}
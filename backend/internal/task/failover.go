@@ -0,0 +1,144 @@
+// Session start failover: a harness or model that keeps failing to start,
+// e.g. during a provider outage, is replaced by the configured fallback.
+package task
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// startAttempts is the number of times a session start is tried with a
+// harness and model before failing over.
+const startAttempts = 2
+
+// startRetryDelay is the pause between two session start attempts.
+var startRetryDelay = 5 * time.Second
+
+// Fallback is the harness and model a task switches to when its own keep
+// failing to start. An empty Harness keeps the task's harness; an empty Model
+// uses the harness default.
+type Fallback struct {
+	Harness  agent.Harness
+	Model    string
+	Endpoint *agent.Endpoint // API of the generic harness; nil for the others.
+}
+
+// Failover records the substitution of the harness and model of a task.
+type Failover struct {
+	FromHarness agent.Harness
+	FromModel   string
+	ToHarness   agent.Harness
+	ToModel     string
+	Err         string // Last start error of the replaced harness and model.
+	At          time.Time
+}
+
+// SetFailover records a substitution, e.g. restored from the task's log.
+func (t *Task) SetFailover(f *Failover) {
+	t.mu.Lock()
+	t.failover = f
+	t.mu.Unlock()
+}
+
+// startAgent starts the agent session of t, retrying a failed start, then
+// failing over to t.Fallback. On failover, the log is recreated so that its
+// header names the harness and model in use; *logW is replaced.
+func (r *Runner) startAgent(ctx context.Context, t *Task, msgCh chan agent.Message, logW *io.WriteCloser, tlog *slog.Logger) (*agent.Session, *Failover, error) {
+	session, err := r.tryStart(ctx, t, msgCh, *logW, tlog)
+	fb := t.Fallback
+	if err == nil || fb == nil || ctx.Err() != nil {
+		return session, nil, err
+	}
+	harness := cmp.Or(fb.Harness, t.Harness)
+	if harness == t.Harness && fb.Model == t.Model {
+		return nil, nil, err
+	}
+	if r.backend(harness) == nil {
+		return nil, nil, fmt.Errorf("%w; fallback harness %s is not available", err, harness)
+	}
+	fo := &Failover{FromHarness: t.Harness, FromModel: t.Model, ToHarness: harness, ToModel: fb.Model, Err: err.Error(), At: time.Now().UTC()}
+	tlog.WarnContext(ctx, "failing over", "from", fo.FromHarness, "from_model", fo.FromModel, "to", fo.ToHarness, "to_model", fo.ToModel)
+	_ = (*logW).Close()
+	if err := os.Remove(filepath.Join(r.LogDir, logName(t))); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("fail over: %w", err)
+	}
+	// Concurrent readers use Snapshot, which holds t.mu.
+	t.mu.Lock()
+	if harness != t.Harness {
+		t.Endpoint = fb.Endpoint
+	}
+	t.Harness, t.Model = harness, fb.Model
+	t.failover = fo
+	t.mu.Unlock()
+	if harness != fo.FromHarness {
+		// The egress allowlist depends on the harness.
+		if err := r.restrictNetwork(ctx, t, t.Container); err != nil {
+			return nil, nil, fmt.Errorf("fail over: restrict network: %w", err)
+		}
+	}
+	w, err := r.openLog(t)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fail over: %w", err)
+	}
+	*logW = w
+	if session, err = r.tryStart(ctx, t, msgCh, w, tlog); err != nil {
+		return nil, nil, fmt.Errorf("fallback %s: %w (after %s)", harness, err, fo.Err)
+	}
+	return session, fo, nil
+}
+
+// tryStart starts the agent session of t up to startAttempts times.
+func (r *Runner) tryStart(ctx context.Context, t *Task, msgCh chan agent.Message, logW io.Writer, tlog *slog.Logger) (*agent.Session, error) {
+	var err error
+	for i := range startAttempts {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, err
+			case <-time.After(startRetryDelay):
+			}
+		}
+		var s *agent.Session
+		s, err = r.backend(t.Harness).Start(ctx, &agent.Options{
+			Container:     t.Container,
+			Dir:           r.containerDir(),
+			Model:         t.Model,
+			PlanOnly:      t.planMode(),
+			ReadOnly:      t.ReadOnly,
+			Thinking:      t.Thinking,
+			InitialPrompt: t.InitialPrompt,
+			Endpoint:      t.Endpoint,
+			Instructions:  t.Instructions,
+		}, msgCh, logW)
+		if err == nil {
+			return s, nil
+		}
+		tlog.WarnContext(ctx, "session start attempt failed", "hns", t.Harness, "model", t.Model, "attempt", i+1, "err", err)
+	}
+	return nil, err
+}
+
+// syntheticFailover is the transcript notice of a substitution.
+func syntheticFailover(f *Failover) *agent.SystemMessage {
+	from, to := string(f.FromHarness), string(f.ToHarness)
+	if f.FromModel != "" {
+		from += " (" + f.FromModel + ")"
+	}
+	if f.ToModel != "" {
+		to += " (" + f.ToModel + ")"
+	}
+	return &agent.SystemMessage{
+		MessageType: "system",
+		Subtype:     "caic_failover",
+		Detail:      fmt.Sprintf("%s failed to start: %s. Switched to %s.", from, f.Err, to),
+	}
+}
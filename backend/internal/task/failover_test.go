@@ -0,0 +1,147 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/maruel/ksid"
+)
+
+// failingBackend is a test backend whose sessions never start.
+type failingBackend struct {
+	testBackend
+	harness agent.Harness
+	starts  int
+}
+
+func (b *failingBackend) Harness() agent.Harness { return b.harness }
+
+func (b *failingBackend) Start(context.Context, *agent.Options, chan<- agent.Message, io.Writer) (*agent.Session, error) {
+	b.starts++
+	return nil, errors.New("overloaded")
+}
+
+func TestStartAgent(t *testing.T) {
+	startRetryDelay = 0
+	start := func(t *testing.T, r *Runner, tk *Task) (*agent.Session, *Failover, error) {
+		logW, err := r.openLog(tk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgCh := make(chan agent.Message, 16)
+		session, fo, err := r.startAgent(t.Context(), tk, msgCh, &logW, slog.Default())
+		_ = logW.Close()
+		if session != nil {
+			t.Cleanup(func() {
+				session.Close()
+				<-session.Done()
+			})
+		}
+		return session, fo, err
+	}
+	newTask := func(fb *Fallback) *Task {
+		return &Task{
+			ID:            ksid.NewID(),
+			InitialPrompt: agent.Prompt{Text: "test"},
+			Repos:         []RepoMount{{Name: "org/repo", Branch: "caic-0"}},
+			Harness:       "broken",
+			Model:         "big",
+			Container:     "fake-container",
+			Fallback:      fb,
+		}
+	}
+	t.Run("Failover", func(t *testing.T) {
+		broken := &failingBackend{harness: "broken"}
+		r := &Runner{LogDir: t.TempDir(), Backends: map[agent.Harness]agent.Backend{"broken": broken, "test": &testBackend{}}}
+		tk := newTask(&Fallback{Harness: "test", Model: "small"})
+		session, fo, err := start(t, r, tk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if session == nil {
+			t.Fatal("nil session")
+		}
+		if broken.starts != startAttempts {
+			t.Errorf("starts = %d, want %d", broken.starts, startAttempts)
+		}
+		want := Failover{FromHarness: "broken", FromModel: "big", ToHarness: "test", ToModel: "small", Err: "overloaded"}
+		if fo == nil || fo.At.IsZero() {
+			t.Fatalf("failover = %+v", fo)
+		}
+		got := *fo
+		got.At = want.At
+		if got != want {
+			t.Errorf("failover = %+v, want %+v", got, want)
+		}
+		if snap := tk.Snapshot(); snap.Harness != "test" || snap.Model != "small" || snap.Failover != fo {
+			t.Errorf("task harness = %s %s, failover = %+v", snap.Harness, snap.Model, snap.Failover)
+		}
+		if d := syntheticFailover(fo).Detail; d != "broken (big) failed to start: overloaded. Switched to test (small)." {
+			t.Errorf("notice = %q", d)
+		}
+
+		// The log is recreated: its header names the fallback and what it
+		// replaced, and a reload restores the failover.
+		data, err := os.ReadFile(filepath.Join(r.LogDir, logName(tk)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(data), `"caic_meta"`); n != 1 {
+			t.Errorf("%d headers in the log, want 1", n)
+		}
+		lt, err := loadLogHeader(filepath.Join(r.LogDir, logName(tk)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lt.Harness != "test" || lt.Failover == nil || lt.Failover.FromHarness != "broken" || lt.Failover.FromModel != "big" || lt.Failover.Err != "overloaded" {
+			t.Errorf("loaded harness = %s, failover = %+v", lt.Harness, lt.Failover)
+		}
+	})
+	t.Run("RestrictedNetwork", func(t *testing.T) {
+		stub := &stubContainer{}
+		r := &Runner{LogDir: t.TempDir(), Container: stub, Backends: map[agent.Harness]agent.Backend{
+			agent.Claude: &failingBackend{harness: agent.Claude},
+			agent.Codex:  &testBackend{},
+		}}
+		r.initDefaults()
+		tk := newTask(&Fallback{Harness: agent.Codex})
+		tk.Harness = agent.Claude
+		tk.Network = NetworkNone
+		if _, _, err := start(t, r, tk); err != nil {
+			t.Fatal(err)
+		}
+		if want := harnessHosts[agent.Codex]; !slices.Equal(stub.allowedHosts, want) {
+			t.Errorf("hosts = %q, want %q", stub.allowedHosts, want)
+		}
+	})
+	t.Run("NoFallback", func(t *testing.T) {
+		broken := &failingBackend{harness: "broken"}
+		r := &Runner{LogDir: t.TempDir(), Backends: map[agent.Harness]agent.Backend{"broken": broken}}
+		tk := newTask(nil)
+		if _, _, err := start(t, r, tk); err == nil || err.Error() != "overloaded" {
+			t.Errorf("err = %v, want overloaded", err)
+		}
+		if tk.Harness != "broken" || tk.Snapshot().Failover != nil {
+			t.Errorf("task harness = %s, failover = %+v", tk.Harness, tk.Snapshot().Failover)
+		}
+	})
+	t.Run("FallbackFails", func(t *testing.T) {
+		broken := &failingBackend{harness: "broken"}
+		r := &Runner{LogDir: t.TempDir(), Backends: map[agent.Harness]agent.Backend{"broken": broken}}
+		tk := newTask(&Fallback{Model: "small"})
+		if _, _, err := start(t, r, tk); err == nil || !strings.Contains(err.Error(), "fallback broken") {
+			t.Errorf("err = %v", err)
+		}
+		if broken.starts != 2*startAttempts {
+			t.Errorf("starts = %d, want %d", broken.starts, 2*startAttempts)
+		}
+	})
+}
@@ -0,0 +1,196 @@
+// Live message fanout to subscribers through per-subscriber ring buffers.
+
+package task
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// subRingSize is how many messages a subscriber may fall behind before the
+// oldest pending ones are dropped.
+const subRingSize = 1024
+
+// OverflowMessage is delivered to a subscriber in place of messages dropped
+// because it fell more than subRingSize messages behind. The subscriber's
+// view of the history has a gap; it should refetch the history.
+type OverflowMessage struct {
+	Dropped int
+}
+
+// Type implements agent.Message.
+func (*OverflowMessage) Type() string { return "caic_overflow" }
+
+// sub is a live message subscriber. addMessage pushes into its ring without
+// ever blocking; pump forwards the ring to ch at the pace the reader consumes.
+type sub struct {
+	ch     chan agent.Message
+	notify chan struct{} // Signals pump that the ring is non-empty.
+	done   chan struct{} // Closed once by close to stop pump.
+	once   sync.Once
+
+	mu      sync.Mutex
+	ring    [subRingSize]agent.Message
+	head    int
+	n       int
+	dropped int // Dropped since the last OverflowMessage.
+	total   int // Dropped over the subscriber's lifetime.
+}
+
+func newSub() *sub {
+	s := &sub{ch: make(chan agent.Message), notify: make(chan struct{}, 1), done: make(chan struct{})}
+	go s.pump()
+	return s
+}
+
+// push enqueues m, dropping the oldest pending message when the ring is full.
+// When it drops one, it returns the subscriber's lifetime drop count, else 0.
+func (s *sub) push(m agent.Message) int {
+	s.mu.Lock()
+	dropped := 0
+	if s.n == subRingSize {
+		s.ring[s.head] = nil
+		s.head = (s.head + 1) % subRingSize
+		s.n--
+		s.dropped++
+		s.total++
+		dropped = s.total
+	}
+	s.ring[(s.head+s.n)%subRingSize] = m
+	s.n++
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return dropped
+}
+
+// next dequeues the next message to deliver, reporting dropped messages
+// first since they precede everything still in the ring.
+func (s *sub) next() agent.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dropped > 0 {
+		m := &OverflowMessage{Dropped: s.dropped}
+		s.dropped = 0
+		return m
+	}
+	if s.n == 0 {
+		return nil
+	}
+	m := s.ring[s.head]
+	s.ring[s.head] = nil
+	s.head = (s.head + 1) % subRingSize
+	s.n--
+	return m
+}
+
+// pump forwards queued messages to ch until close is called, then closes ch.
+func (s *sub) pump() {
+	defer close(s.ch)
+	for {
+		m := s.next()
+		if m == nil {
+			select {
+			case <-s.notify:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case s.ch <- m:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// lag returns the number of messages pushed but not yet delivered.
+func (s *sub) lag() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n
+}
+
+func (s *sub) close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// Subscribe returns a snapshot of the message history and a channel that
+// receives only live messages arriving after the snapshot. The caller must
+// write the history to the client first, then range over the channel.
+//
+// A subscriber that falls more than subRingSize messages behind loses the
+// oldest pending ones and receives an *OverflowMessage in their place. The
+// channel is closed when ctx is done or the returned function, which must be
+// called exactly once, unsubscribes.
+func (t *Task) Subscribe(ctx context.Context) (history []agent.Message, live <-chan agent.Message, unsubFn func()) {
+	s := newSub()
+
+	t.mu.Lock()
+	// Snapshot history under lock — no channel writes, so no deadlock risk
+	// regardless of history size.
+	history = append([]agent.Message(nil), t.msgs...)
+	t.subs = append(t.subs, s)
+	t.mu.Unlock()
+
+	unsub := func() {
+		t.mu.Lock()
+		for i, ss := range t.subs {
+			if ss == s {
+				t.subs = append(t.subs[:i], t.subs[i+1:]...)
+				break
+			}
+		}
+		t.mu.Unlock()
+		s.close()
+	}
+
+	// Close channel when context is done.
+	go func() {
+		select {
+		case <-ctx.Done():
+			unsub()
+		case <-s.done:
+		}
+	}()
+
+	return history, s.ch, unsub
+}
+
+// SubscriberStats describes how well live subscribers keep up.
+type SubscriberStats struct {
+	Subscribers int
+	MaxLag      int // Largest number of messages a subscriber has pending.
+	Dropped     int // Messages dropped by current subscribers.
+}
+
+// SubscriberStats returns the lag of the task's live subscribers.
+func (t *Task) SubscriberStats() SubscriberStats {
+	t.mu.Lock()
+	subs := append([]*sub(nil), t.subs...)
+	t.mu.Unlock()
+	st := SubscriberStats{Subscribers: len(subs)}
+	for _, s := range subs {
+		st.MaxLag = max(st.MaxLag, s.lag())
+		s.mu.Lock()
+		st.Dropped += s.total
+		s.mu.Unlock()
+	}
+	return st
+}
+
+// fanOut delivers m to every live subscriber without blocking. Must be called
+// with t.mu held.
+func (t *Task) fanOut(m agent.Message) {
+	for _, s := range t.subs {
+		if s.push(m) == 1 {
+			slog.Warn("task subscriber fell behind; dropping messages", "task", t.ID, "ring", subRingSize)
+		}
+	}
+}
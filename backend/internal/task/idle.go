@@ -0,0 +1,46 @@
+// Idle policy of the tasks waiting for input.
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// IdleAction is what happens to a task left waiting for input too long.
+type IdleAction string
+
+// Idle actions.
+const (
+	// IdleNotify only posts a notice in the transcript.
+	IdleNotify IdleAction = "notify"
+	// IdleFinish pushes the branch and stops the task.
+	IdleFinish IdleAction = "finish"
+)
+
+// IdlePolicy acts on a task after it waited Hours for input.
+type IdlePolicy struct {
+	Hours  int // 0 disables the policy.
+	Action IdleAction
+}
+
+// Timeout returns how long the task may wait, 0 when the policy is disabled.
+func (p IdlePolicy) Timeout() time.Duration {
+	return time.Duration(p.Hours) * time.Hour
+}
+
+// Warning returns how long before finishing an idle task the user is warned:
+// an hour, or a quarter of a shorter timeout.
+func (p IdlePolicy) Warning() time.Duration {
+	return min(time.Hour, p.Timeout()/4)
+}
+
+// AddIdleNotice posts detail about the task's inactivity in its transcript.
+func (t *Task) AddIdleNotice(ctx context.Context, detail string) {
+	t.addMessage(ctx, &agent.SystemMessage{
+		MessageType: "system",
+		Subtype:     "caic_idle",
+		Detail:      detail,
+	}, false)
+}
@@ -0,0 +1,31 @@
+// Repo instructions: custom agent instructions committed in .caic/instructions.md.
+package task
+
+import (
+	"context"
+	"os/exec"
+)
+
+// InstructionsPath is the file of a repo holding the custom instructions
+// appended to the system prompt of its tasks' agents.
+const InstructionsPath = ".caic/instructions.md"
+
+// RepoInstructions returns the content of InstructionsPath on baseBranch, or
+// the runner's base branch when empty. It prefers origin's branch, like new
+// task branches, and returns "" when the file doesn't exist.
+func (r *Runner) RepoInstructions(ctx context.Context, baseBranch string) string {
+	if baseBranch == "" {
+		baseBranch = r.BaseBranch
+	}
+	if r.Dir == "" || baseBranch == "" {
+		return ""
+	}
+	for _, ref := range []string{"origin/" + baseBranch, baseBranch} {
+		cmd := exec.CommandContext(ctx, "git", "show", ref+":"+InstructionsPath) //nolint:gosec // ref is a branch of the runner's repo.
+		cmd.Dir = r.Dir
+		if out, err := cmd.Output(); err == nil {
+			return string(out)
+		}
+	}
+	return ""
+}
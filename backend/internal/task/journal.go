@@ -0,0 +1,276 @@
+// Write-ahead journal of the Runner's intents, replayed at startup to reconcile
+// what a crash interrupted with the recorded state.
+package task
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/md/gitutil"
+)
+
+// JournalOp is the operation an intent of the journal announces.
+type JournalOp string
+
+// JournalOp values.
+const (
+	// JournalStart announces the start of a task, until its log holds the
+	// task's metadata. A crash in between leaves a container, or a branch,
+	// that no log refers to.
+	JournalStart JournalOp = "start"
+	// JournalPush announces a push to a remote branch. A crash in between
+	// leaves the remote-tracking ref unaware of whether the push landed.
+	JournalPush JournalOp = "push"
+)
+
+// journalReplayTimeout bounds the reconciliation of an intent.
+const journalReplayTimeout = time.Minute
+
+// JournalEntry is a line of the journal: an intent, or the completion of the
+// intent of the same Seq.
+type JournalEntry struct {
+	Seq    int64              `json:"seq"`
+	Done   bool               `json:"done,omitempty"`
+	Op     JournalOp          `json:"op,omitempty"`
+	Time   time.Time          `json:"time,omitzero"`
+	Dir    string             `json:"dir,omitempty"`    // Host repo; empty for no-repo tasks.
+	Branch string             `json:"branch,omitempty"` // Pushed branch.
+	Remote string             `json:"remote,omitempty"` // Push remote; empty means origin.
+	Log    string             `json:"log,omitempty"`    // Start: name of the task's log file.
+	Meta   *agent.MetaMessage `json:"meta,omitempty"`   // Start: metadata header of the log.
+}
+
+// Journal is an append-only file of the intents of the runners. An intent is
+// synced to disk before the operation it announces and marked done after it,
+// so the intents pending at startup are the operations a crash interrupted.
+type Journal struct {
+	path string
+
+	mu      sync.Mutex
+	f       *os.File
+	seq     int64
+	pending map[int64]JournalEntry
+}
+
+// OpenJournal opens the journal at path, creating it as needed. The journal is
+// compacted to the intents left pending by the previous run.
+func OpenJournal(path string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, err
+	}
+	j := &Journal{path: path, pending: map[int64]JournalEntry{}}
+	if err := j.read(); err != nil {
+		return nil, err
+	}
+	if err := j.compact(); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	j.f = f
+	return j, nil
+}
+
+// read loads the pending intents. A line cut short by a crash is skipped: the
+// operation it announced didn't start.
+func (j *Journal) read() error {
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e JournalEntry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			slog.Warn("journal", "msg", "skipping malformed entry", "err", err)
+			continue
+		}
+		j.seq = max(j.seq, e.Seq)
+		if e.Done {
+			delete(j.pending, e.Seq)
+		} else {
+			j.pending[e.Seq] = e
+		}
+	}
+	return s.Err()
+}
+
+// compact rewrites the journal with the pending intents only.
+func (j *Journal) compact() error {
+	tmp := j.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, e := range j.Pending() {
+		if err = enc.Encode(&e); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, j.path)
+}
+
+// Pending returns the intents not done yet, oldest first.
+func (j *Journal) Pending() []JournalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	out := make([]JournalEntry, 0, len(j.pending))
+	for _, seq := range slices.Sorted(maps.Keys(j.pending)) {
+		out = append(out, j.pending[seq])
+	}
+	return out
+}
+
+// Begin records the intent e and syncs it to disk. It returns the sequence
+// number to pass to Done once the operation completed, or failed.
+func (j *Journal) Begin(e *JournalEntry) (int64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.seq++
+	e.Seq, e.Done = j.seq, false
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if err := j.write(e); err != nil {
+		return 0, err
+	}
+	if err := j.f.Sync(); err != nil {
+		return 0, err
+	}
+	j.pending[e.Seq] = *e
+	return e.Seq, nil
+}
+
+// Done marks the intent seq done. The journal is truncated once no intent is
+// pending.
+func (j *Journal) Done(seq int64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, ok := j.pending[seq]; !ok {
+		return nil
+	}
+	delete(j.pending, seq)
+	if len(j.pending) == 0 {
+		return j.f.Truncate(0)
+	}
+	return j.write(&JournalEntry{Seq: seq, Done: true})
+}
+
+func (j *Journal) write(e *JournalEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = j.f.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.f.Close()
+}
+
+// Replay reconciles the pending intents with the state on disk, then marks
+// them done:
+//   - JournalStart writes the log header the task never got, so that the
+//     task is loaded from its log: failed, or adopted with its options when
+//     its container runs.
+//   - JournalPush fetches the pushed branch so that the remote-tracking ref
+//     tells whether the push landed.
+//
+// Must be called before the logs in logDir are loaded.
+func (j *Journal) Replay(ctx context.Context, logDir string) error {
+	var errs []error
+	for _, e := range j.Pending() {
+		slog.InfoContext(ctx, "journal", "msg", "replaying", "op", e.Op, "seq", e.Seq, "time", e.Time)
+		if err := replayEntry(ctx, logDir, &e); err != nil {
+			errs = append(errs, fmt.Errorf("replay %s #%d: %w", e.Op, e.Seq, err))
+		}
+		if err := j.Done(e.Seq); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func replayEntry(ctx context.Context, logDir string, e *JournalEntry) error {
+	switch e.Op {
+	case JournalStart:
+		if e.Meta == nil || e.Log == "" {
+			return nil
+		}
+		path := filepath.Join(logDir, filepath.Base(e.Log))
+		if st, err := os.Stat(path); err == nil && st.Size() > 0 {
+			return nil
+		}
+		data, err := json.Marshal(e.Meta)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(logDir, 0o750); err != nil {
+			return err
+		}
+		slog.WarnContext(ctx, "journal", "msg", "recovered the log of an interrupted start", "task", e.Meta.TaskID)
+		return os.WriteFile(path, append(data, '\n'), 0o600)
+	case JournalPush:
+		if e.Dir == "" || e.Branch == "" {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(ctx, journalReplayTimeout)
+		defer cancel()
+		remote := cmp.Or(e.Remote, "origin")
+		_, err := gitutil.RunGit(ctx, e.Dir, "fetch", "-q", remote, "+refs/heads/"+e.Branch+":refs/remotes/"+remote+"/"+e.Branch)
+		return err
+	default:
+		return fmt.Errorf("unknown op %q", e.Op)
+	}
+}
+
+// journal records the intent e in r.Journal and returns the function marking
+// it done. Without a journal, or when recording fails, it is a no-op.
+func (r *Runner) journal(ctx context.Context, e *JournalEntry) func() {
+	if r.Journal == nil {
+		return func() {}
+	}
+	seq, err := r.Journal.Begin(e)
+	if err != nil {
+		r.log.WarnContext(ctx, "journal", "msg", "record failed", "op", e.Op, "err", err)
+		return func() {}
+	}
+	return func() {
+		if err := r.Journal.Done(seq); err != nil {
+			r.log.WarnContext(ctx, "journal", "msg", "record failed", "op", e.Op, "err", err)
+		}
+	}
+}
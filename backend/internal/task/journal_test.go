@@ -0,0 +1,105 @@
+package task
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/maruel/ksid"
+)
+
+func TestJournal(t *testing.T) {
+	open := func(t *testing.T, path string) *Journal {
+		t.Helper()
+		j, err := OpenJournal(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = j.Close() })
+		return j
+	}
+	t.Run("Pending", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "journal.jsonl")
+		j := open(t, path)
+		push, err := j.Begin(&JournalEntry{Op: JournalPush, Dir: "/repo", Branch: "caic-1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		start, err := j.Begin(&JournalEntry{Op: JournalStart, Log: "x.jsonl"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := j.Done(push); err != nil {
+			t.Fatal(err)
+		}
+		// A crash cut the last line short.
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.WriteString(`{"seq":3,"op":"pu`); err != nil {
+			t.Fatal(err)
+		}
+		_ = f.Close()
+
+		p := open(t, path).Pending()
+		if len(p) != 1 || p[0].Seq != start || p[0].Op != JournalStart || p[0].Time.IsZero() {
+			t.Fatalf("pending = %+v", p)
+		}
+	})
+	t.Run("Truncate", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "journal.jsonl")
+		j := open(t, path)
+		seq, err := j.Begin(&JournalEntry{Op: JournalPush})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := j.Done(seq); err != nil {
+			t.Fatal(err)
+		}
+		if st, err := os.Stat(path); err != nil || st.Size() != 0 {
+			t.Errorf("journal not truncated: %v, %v", st, err)
+		}
+		// Sequence numbers keep increasing within a run.
+		if next, err := j.Begin(&JournalEntry{Op: JournalPush}); err != nil || next != seq+1 {
+			t.Errorf("seq = %d, %v; want %d", next, err, seq+1)
+		}
+	})
+	t.Run("Replay", func(t *testing.T) {
+		dir := t.TempDir()
+		logDir := filepath.Join(dir, "tasks")
+		j := open(t, filepath.Join(dir, "journal.jsonl"))
+		tk := &Task{ID: ksid.NewID(), InitialPrompt: agent.Prompt{Text: "fix it"}, Harness: agent.Claude, Repos: []RepoMount{{Name: "r", Branch: "caic-1"}}, PlanOnly: true, StartedAt: time.Now().UTC()}
+		meta := logMeta(tk)
+		if _, err := j.Begin(&JournalEntry{Op: JournalStart, Log: logName(tk), Meta: meta}); err != nil {
+			t.Fatal(err)
+		}
+		// The log of this one was written before the crash.
+		if err := os.MkdirAll(logDir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+		written := `{"type":"not_meta"}`
+		writeLogFile(t, logDir, "written.jsonl", written)
+		if _, err := j.Begin(&JournalEntry{Op: JournalStart, Log: "written.jsonl", Meta: meta}); err != nil {
+			t.Fatal(err)
+		}
+		if err := j.Replay(t.Context(), logDir); err != nil {
+			t.Fatal(err)
+		}
+		if p := j.Pending(); len(p) != 0 {
+			t.Errorf("pending after replay = %+v", p)
+		}
+		tasks, err := LoadLogs(logDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tasks) != 1 || tasks[0].ID() != tk.ID || tasks[0].Prompt != "fix it" || !tasks[0].PlanOnly || tasks[0].Result != nil {
+			t.Fatalf("tasks = %+v", tasks)
+		}
+		if got, err := os.ReadFile(filepath.Join(logDir, "written.jsonl")); err != nil || string(got) != written+"\n" {
+			t.Errorf("existing log rewritten: %q, %v", got, err)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	"github.com/caic-xyz/caic/backend/internal/jsonutil"
+	"github.com/maruel/ksid"
 )
 
 // errNotLogFile is returned when a file doesn't contain a valid caic_meta header.
@@ -29,7 +31,8 @@ var resultKnown = jsonutil.KnownFields(agent.MetaResultMessage{})
 
 // LoadedTask holds the data reconstructed from a single JSONL log file.
 type LoadedTask struct {
-	TaskID            string // Task ID parsed from log filename; empty if unparseable.
+	TaskID            string // Task ID from the header, else parsed from the log filename.
+	Alias             string
 	Prompt            string
 	Title             string
 	Repos             []RepoMount // GitRoot will be empty for purged tasks loaded from logs.
@@ -44,7 +47,22 @@ type LoadedTask struct {
 	Tailscale         bool
 	USB               bool
 	Display           bool
+	PlanOnly          bool
+	RequirePlan       bool
+	ReadOnly          bool
+	Chat              bool
+	Thinking          bool
+	Idle              *IdlePolicy
+	Failover          *Failover
+	Push              PushPolicy
+	Issue             *IssueLink
+	Slack             *SlackThread
+	Env               map[string]string
+	Instructions      string
+	Network           NetworkMode
+	NetworkAllow      []string
 	Msgs              []agent.Message
+	Transitions       []Transition // State history from caic_state records; set by LoadMessages.
 	Result            *Result
 
 	path    string                                // Absolute path for lazy message loading via LoadMessages.
@@ -59,6 +77,22 @@ func (lt *LoadedTask) Primary() *RepoMount {
 	return &lt.Repos[0]
 }
 
+// ID returns the task's persistent ID. Real server IDs are 10–12 chars
+// (current-era timestamps in base32); short strings (e.g. "a" from test
+// filenames) parse to implausibly small values and are rejected. Logs without
+// a usable ID get one derived from the log path, so the ID, and links using
+// it, stay the same across server restarts.
+func (lt *LoadedTask) ID() ksid.ID {
+	if len(lt.TaskID) >= 9 {
+		if id, err := ksid.Parse(lt.TaskID); err == nil && id != 0 {
+			return id
+		}
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(filepath.Base(lt.path)))
+	return ksid.ID(h.Sum64() >> 1) // ksid IDs keep bit 63 clear.
+}
+
 // LoadLogs scans logDir for *.jsonl files and loads task metadata.
 // Only the header and result trailer are parsed; call LoadMessages for
 // full conversation history. Call SetParser on each task before LoadMessages.
@@ -133,6 +167,7 @@ func (lt *LoadedTask) LoadMessages() error {
 		return err
 	}
 	lt.Msgs = full.Msgs
+	lt.Transitions = full.Transitions
 	if full.ForgePR > 0 {
 		lt.ForgeOwner = full.ForgeOwner
 		lt.ForgeRepo = full.ForgeRepo
@@ -188,20 +223,25 @@ func loadLogHeader(path string) (_ *LoadedTask, retErr error) {
 		return nil, err
 	}
 
-	// Parse task ID from filename: "<taskID>-<safeRepo>-<safeBranch>.jsonl".
-	base := strings.TrimSuffix(filepath.Base(path), ".jsonl")
-	taskIDStr := base
-	if i := strings.IndexByte(base, '-'); i >= 0 {
-		taskIDStr = base[:i]
+	// Older headers lack the task ID; parse it from the filename:
+	// "<taskID>-<safeRepo>-<safeBranch>.jsonl".
+	taskIDStr := meta.TaskID
+	if taskIDStr == "" {
+		base := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+		taskIDStr = base
+		if i := strings.IndexByte(base, '-'); i >= 0 {
+			taskIDStr = base[:i]
+		}
 	}
 
 	repos := make([]RepoMount, len(meta.Repos))
 	for i, mr := range meta.Repos {
-		repos[i] = RepoMount{Name: mr.Name, BaseBranch: mr.BaseBranch, Branch: mr.Branch}
+		repos[i] = RepoMount{Name: mr.Name, BaseBranch: mr.BaseBranch, Branch: mr.Branch, PushRemote: mr.PushRemote}
 	}
 	lt := &LoadedTask{
 		path:              path,
 		TaskID:            taskIDStr,
+		Alias:             meta.Alias,
 		Prompt:            meta.Prompt,
 		Title:             meta.Title,
 		Repos:             repos,
@@ -213,6 +253,28 @@ func loadLogHeader(path string) (_ *LoadedTask, retErr error) {
 		Tailscale:         meta.Tailscale,
 		USB:               meta.USB,
 		Display:           meta.Display,
+		PlanOnly:          meta.PlanOnly,
+		RequirePlan:       meta.RequirePlan,
+		ReadOnly:          meta.ReadOnly,
+		Chat:              meta.Chat,
+		Thinking:          meta.Thinking,
+		Network:           NetworkMode(meta.Network),
+		NetworkAllow:      meta.NetworkAllow,
+		Push:              PushPolicy(meta.PushPolicy),
+		Env:               meta.Env,
+		Instructions:      meta.Instructions,
+	}
+	if meta.IssueKey != "" {
+		lt.Issue = &IssueLink{Tracker: meta.IssueTracker, Key: meta.IssueKey, URL: meta.IssueURL}
+	}
+	if meta.SlackThreadTS != "" {
+		lt.Slack = &SlackThread{Channel: meta.SlackChannel, TS: meta.SlackThreadTS}
+	}
+	if meta.IdleAction != "" {
+		lt.Idle = &IdlePolicy{Hours: meta.IdleHours, Action: IdleAction(meta.IdleAction)}
+	}
+	if meta.FailoverHarness != "" {
+		lt.Failover = &Failover{FromHarness: meta.FailoverHarness, FromModel: meta.FailoverModel, ToHarness: meta.Harness, ToModel: meta.Model, Err: meta.FailoverError, At: meta.StartedAt}
 	}
 
 	// Read the tail of the file to find caic_pr, caic_result, and
@@ -321,7 +383,7 @@ func loadLogFile(path string, parseFn func([]byte) ([]agent.Message, error)) (_
 
 	repos := make([]RepoMount, len(meta.Repos))
 	for i, mr := range meta.Repos {
-		repos[i] = RepoMount{Name: mr.Name, BaseBranch: mr.BaseBranch, Branch: mr.Branch}
+		repos[i] = RepoMount{Name: mr.Name, BaseBranch: mr.BaseBranch, Branch: mr.Branch, PushRemote: mr.PushRemote}
 	}
 	lt := &LoadedTask{
 		Prompt:            meta.Prompt,
@@ -358,6 +420,18 @@ func loadLogFile(path string, parseFn func([]byte) ([]agent.Message, error)) (_
 			continue
 		}
 
+		if envelope.Type == "caic_state" {
+			var ms agent.MetaStateMessage
+			if json.Unmarshal(line, &ms) == nil {
+				from, ok1 := ParseState(ms.From)
+				to, ok2 := ParseState(ms.To)
+				if ok1 && ok2 {
+					lt.Transitions = append(lt.Transitions, Transition{From: from, To: to, At: tsToTime(ms.Ts)})
+				}
+			}
+			continue
+		}
+
 		if envelope.Type == "caic_diff_stat" {
 			var ds agent.DiffStatMessage
 			if json.Unmarshal(line, &ds) == nil && ds.Ts > 0 {
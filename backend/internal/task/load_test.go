@@ -180,6 +180,25 @@ func TestLoadLogs(t *testing.T) {
 			t.Error("Display = false, want true")
 		}
 	})
+	t.Run("StableIDWithoutHeaderID", func(t *testing.T) {
+		dir := t.TempDir()
+		meta := mustJSON(t, agent.MetaMessage{
+			MessageType: "caic_meta", Version: 1, Prompt: "legacy task", Harness: "claude",
+		})
+		writeLogFile(t, dir, "legacy.jsonl", meta)
+
+		var ids []string
+		for range 2 {
+			tasks, err := LoadLogs(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, tasks[0].ID().String())
+		}
+		if ids[0] != ids[1] || ids[0] == "0" {
+			t.Errorf("IDs across loads = %v, want one stable non-zero ID", ids)
+		}
+	})
 	t.Run("FeatureFlagsOmitted", func(t *testing.T) {
 		dir := t.TempDir()
 		meta := mustJSON(t, agent.MetaMessage{
@@ -0,0 +1,65 @@
+// Per-task network egress restrictions of the container.
+package task
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// NetworkMode controls the outgoing connections a task's container may make.
+type NetworkMode string
+
+// Network modes.
+const (
+	// NetworkFull allows all connections. It is the default.
+	NetworkFull NetworkMode = "full"
+	// NetworkNone only allows the agent's model API.
+	NetworkNone NetworkMode = "none"
+	// NetworkAllowlist allows the agent's model API and Task.NetworkAllow.
+	NetworkAllowlist NetworkMode = "allowlist"
+)
+
+// Restricted reports whether m limits connections. "" means NetworkFull.
+func (m NetworkMode) Restricted() bool {
+	return m == NetworkNone || m == NetworkAllowlist
+}
+
+// harnessHosts lists the hosts each harness needs to reach its model API and
+// refresh its credentials; they stay reachable in restricted modes.
+var harnessHosts = map[agent.Harness][]string{
+	agent.Aider:    {"api.anthropic.com", "api.openai.com", "api.deepseek.com", "generativelanguage.googleapis.com", "openrouter.ai"},
+	agent.Claude:   {"api.anthropic.com", "console.anthropic.com", "claude.ai"},
+	agent.Codex:    {"api.openai.com", "auth.openai.com", "chatgpt.com"},
+	agent.Gemini:   {"generativelanguage.googleapis.com", "cloudcode-pa.googleapis.com", "oauth2.googleapis.com"},
+	agent.Kilo:     {"api.kilocode.ai", "openrouter.ai"},
+	agent.OpenCode: {"opencode.ai", "models.dev", "openrouter.ai"},
+}
+
+// networkHosts returns the hosts the task's container may connect to when its
+// network is restricted.
+func (t *Task) networkHosts() []string {
+	hosts := append([]string(nil), harnessHosts[t.Harness]...)
+	if t.Endpoint != nil {
+		if u, err := url.Parse(t.Endpoint.BaseURL); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	if t.Network == NetworkAllowlist {
+		hosts = append(hosts, t.NetworkAllow...)
+	}
+	return hosts
+}
+
+// restrictNetwork applies the task's network mode to the container name. The
+// rules live in the container's network namespace, which is recreated when
+// the container restarts, so they are applied on every start.
+func (r *Runner) restrictNetwork(ctx context.Context, t *Task, name string) error {
+	if !t.Network.Restricted() {
+		return nil
+	}
+	hosts := t.networkHosts()
+	r.log.InfoContext(ctx, "restricting network", "ctr", name, "mode", t.Network, "hosts", hosts)
+	return r.Container.RestrictNetwork(ctx, name, hosts)
+}
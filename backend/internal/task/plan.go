@@ -0,0 +1,56 @@
+// Two-phase plan approval: RequirePlan tasks plan read-only until ApprovePlan.
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/logctx"
+)
+
+// planApprovedSubtype marks the point in the log where the plan of a
+// RequirePlan task was approved, so RestoreMessages keeps it approved.
+const planApprovedSubtype = "caic_plan_approved"
+
+// syntheticPlanApproved creates the SystemMessage recording plan approval.
+func syntheticPlanApproved() *agent.SystemMessage {
+	return &agent.SystemMessage{MessageType: "system", Subtype: planApprovedSubtype}
+}
+
+// PlanApproved reports whether a RequirePlan task's plan was approved.
+func (t *Task) PlanApproved() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.planApproved
+}
+
+// planMode reports whether the next agent session must run read-only.
+func (t *Task) planMode() bool {
+	return t.PlanOnly || (t.RequirePlan && !t.PlanApproved())
+}
+
+// ApprovePlan ends the review phase of a RequirePlan task: it marks the plan
+// approved and restarts the session out of plan mode, seeded with the plan.
+// Returns the new SessionHandle so the caller can start a session watcher.
+func (r *Runner) ApprovePlan(ctx context.Context, t *Task, plan string) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
+	if !t.RequirePlan {
+		return nil, errors.New("task does not require plan approval")
+	}
+	if state := t.GetState(); state != StatePlanReview {
+		return nil, fmt.Errorf("cannot approve plan in state %s", state)
+	}
+	t.mu.Lock()
+	t.planApproved = true
+	t.mu.Unlock()
+	h, err := r.RestartSession(ctx, t, agent.Prompt{Text: "The following plan was approved. Implement it now.\n\n" + plan})
+	if err != nil {
+		return nil, err
+	}
+	marker := syntheticPlanApproved()
+	t.addMessage(ctx, marker, true)
+	t.WriteToLog(marker)
+	return h, nil
+}
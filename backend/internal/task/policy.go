@@ -0,0 +1,84 @@
+// Enforcement of the tool call policy of a task: a violating tool call pauses
+// the task's container until the user approves or denies it.
+package task
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/policy"
+)
+
+// syntheticPolicyViolation creates a SystemMessage marking the tool call
+// that paused the task.
+func syntheticPolicyViolation(v *policy.Violation) *agent.SystemMessage {
+	return &agent.SystemMessage{
+		MessageType: "system",
+		Subtype:     "caic_policy_violation",
+		Detail:      v.Error(),
+	}
+}
+
+// Policy compiles tool policy rules for the tasks of this runner, resolving
+// relative paths against the repository in the container.
+func (r *Runner) Policy(rules []policy.Rule) (*policy.Policy, error) {
+	return policy.New(rules, r.containerDir())
+}
+
+// enforcePolicy checks a tool call against the task's policy and pauses the
+// container on a violation. The harness may already have started the tool;
+// pausing freezes it along with the agent.
+func (r *Runner) enforcePolicy(ctx context.Context, t *Task, msg *agent.ToolUseMessage) {
+	v := t.Policy.Check(msg.Name, msg.Input)
+	if v == nil || r.Container == nil || t.Container == "" {
+		return
+	}
+	r.log.WarnContext(ctx, "policy violation", "rule", v.Rule, "tool", v.Tool, "detail", v.Detail)
+	t.addMessage(ctx, syntheticPolicyViolation(v), false)
+	if err := r.Container.SetPaused(ctx, t.Container, true); err != nil {
+		r.log.ErrorContext(ctx, "pause on policy violation failed", "err", err)
+		return
+	}
+	t.mu.Lock()
+	t.policyViolation = v
+	t.mu.Unlock()
+	if r.OnPolicyViolation != nil {
+		r.OnPolicyViolation(t, v)
+	}
+}
+
+// ResolvePolicyViolation resumes a task paused by a policy violation and
+// returns the violation.
+func (r *Runner) ResolvePolicyViolation(ctx context.Context, t *Task) (*policy.Violation, error) {
+	t.mu.Lock()
+	v := t.policyViolation
+	t.mu.Unlock()
+	if v == nil {
+		return nil, errors.New("task is not paused on a policy violation")
+	}
+	if err := r.Container.SetPaused(ctx, t.Container, false); err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.policyViolation = nil
+	// The pause is not silence of the agent; see MarkStalled.
+	t.lastActivityAt = time.Now().UTC()
+	t.mu.Unlock()
+	return v, nil
+}
+
+// releasePolicyPause resumes the container before it is stopped so the agent
+// can shut down gracefully.
+func (r *Runner) releasePolicyPause(ctx context.Context, t *Task) {
+	t.mu.Lock()
+	paused := t.policyViolation != nil
+	t.mu.Unlock()
+	if !paused {
+		return
+	}
+	if _, err := r.ResolvePolicyViolation(ctx, t); err != nil {
+		r.log.WarnContext(ctx, "unpause failed", "err", err)
+	}
+}
@@ -0,0 +1,18 @@
+// Pre-push checks: local CI run in the container before a task's branch is pushed.
+package task
+
+import (
+	"context"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// AddPrePushNotice posts the outcome and output of a pre-push check in the
+// task's transcript.
+func (t *Task) AddPrePushNotice(ctx context.Context, detail string) {
+	t.addMessage(ctx, &agent.SystemMessage{
+		MessageType: "system",
+		Subtype:     "caic_prepush",
+		Detail:      detail,
+	}, false)
+}
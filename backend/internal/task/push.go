@@ -0,0 +1,22 @@
+// Push policy: when the branch of a task may be pushed.
+package task
+
+// PushPolicy controls when the branch of a task is pushed.
+type PushPolicy string
+
+// Push policies.
+const (
+	// PushAuto pushes the branch on request and automatically: when the idle
+	// policy finishes the task and after a CI fix. It is the default.
+	PushAuto PushPolicy = "auto"
+	// PushAsk only pushes the branch on request, e.g. for repos whose pre-push
+	// hooks must not run unattended.
+	PushAsk PushPolicy = "ask"
+	// PushNever never pushes the branch; the work stays in the container.
+	PushNever PushPolicy = "never"
+)
+
+// Automatic reports whether p pushes without a request. "" means PushAuto.
+func (p PushPolicy) Automatic() bool {
+	return p == "" || p == PushAuto
+}
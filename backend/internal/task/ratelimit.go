@@ -0,0 +1,56 @@
+// Backoff of the tasks whose turn failed on a provider rate limit.
+package task
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// Backoff bounds of a rate limited task. The delay doubles with each
+// consecutive rate limited turn.
+const (
+	rateLimitMinBackoff = time.Minute
+	rateLimitMaxBackoff = time.Hour
+)
+
+// rateLimitResumePrompt is sent to resume a turn cut short by a rate limit.
+const rateLimitResumePrompt = "Your previous turn was interrupted by a provider rate limit. Continue where you left off."
+
+// RateLimitResumeAt returns when a rate limited task should be resumed, or
+// the zero time when it is not rate limited. It waits for the reset time the
+// agent reported for a rejected request, else backs off exponentially.
+func (t *Task) RateLimitResumeAt() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != StateRateLimited {
+		return time.Time{}
+	}
+	backoff := rateLimitMinBackoff << min(max(t.rateLimitStreak-1, 0), 6)
+	at := t.stateUpdatedAt.Add(min(backoff, rateLimitMaxBackoff))
+	if t.rateLimit.Status == "rejected" && t.rateLimit.ResetsAt > 0 {
+		if reset := time.Unix(int64(t.rateLimit.ResetsAt), 0); reset.After(at) {
+			at = reset
+		}
+	}
+	return at
+}
+
+// RateLimit returns the last rate limit status reported by the agent and when
+// it was received; the time is zero when the agent reported none.
+func (t *Task) RateLimit() (agent.RateLimitMessage, time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.rateLimit, t.rateLimitSeenAt
+}
+
+// ResumeRateLimited asks the agent to continue the turn that failed on a rate
+// limit.
+func (t *Task) ResumeRateLimited(ctx context.Context) error {
+	if t.GetState() != StateRateLimited {
+		return errors.New("task is not rate limited")
+	}
+	return t.SendInput(ctx, agent.Prompt{Text: rateLimitResumePrompt})
+}
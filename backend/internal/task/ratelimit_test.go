@@ -0,0 +1,66 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+func TestRateLimit(t *testing.T) {
+	limited := &agent.ResultMessage{MessageType: "result", IsError: true, Result: "API Error: 429", RateLimited: true}
+	t.Run("Backoff", func(t *testing.T) {
+		tk := &Task{}
+		tk.SetState(StateRunning)
+		tk.addMessage(t.Context(), limited, true)
+		if st := tk.GetState(); st != StateRateLimited {
+			t.Fatalf("state = %v, want rate_limited", st)
+		}
+		since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		tk.SetStateAt(StateRateLimited, since)
+		if got, want := tk.RateLimitResumeAt(), since.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("first resume = %v, want %v", got, want)
+		}
+		tk.SetStateAt(StateRunning, since)
+		tk.addMessage(t.Context(), limited, true)
+		tk.SetStateAt(StateRateLimited, since)
+		if got, want := tk.RateLimitResumeAt(), since.Add(2*time.Minute); !got.Equal(want) {
+			t.Errorf("second resume = %v, want %v", got, want)
+		}
+		// A successful turn resets the backoff.
+		tk.SetStateAt(StateRunning, since)
+		tk.addMessage(t.Context(), &agent.ResultMessage{MessageType: "result"}, true)
+		if st := tk.GetState(); st != StateWaiting {
+			t.Fatalf("state = %v, want waiting", st)
+		}
+		if !tk.RateLimitResumeAt().IsZero() {
+			t.Error("resume time set while waiting")
+		}
+		tk.SetStateAt(StateRunning, since)
+		tk.addMessage(t.Context(), limited, true)
+		tk.SetStateAt(StateRateLimited, since)
+		if got, want := tk.RateLimitResumeAt(), since.Add(time.Minute); !got.Equal(want) {
+			t.Errorf("resume after success = %v, want %v", got, want)
+		}
+	})
+	t.Run("ResetsAt", func(t *testing.T) {
+		tk := &Task{}
+		tk.SetState(StateRunning)
+		reset := time.Now().Add(3 * time.Hour).Truncate(time.Second)
+		tk.addMessage(t.Context(), &agent.RateLimitMessage{Status: "rejected", ResetsAt: float64(reset.Unix()), RateLimitType: "five_hour"}, true)
+		tk.addMessage(t.Context(), limited, true)
+		if got := tk.RateLimitResumeAt(); !got.Equal(reset) {
+			t.Errorf("resume = %v, want %v", got, reset)
+		}
+		if m, at := tk.RateLimit(); at.IsZero() || m.RateLimitType != "five_hour" {
+			t.Errorf("RateLimit() = %+v, %v", m, at)
+		}
+	})
+	t.Run("Restore", func(t *testing.T) {
+		tk := &Task{}
+		tk.RestoreMessages([]agent.Message{&agent.UserInputMessage{Text: "go"}, limited})
+		if st := tk.GetState(); st != StateRateLimited {
+			t.Errorf("state = %v, want rate_limited", st)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+// Git remotes of a repo and the push target of the task branches, e.g. a fork of origin.
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/caic-xyz/md/gitutil"
+)
+
+// RemoteNameRe matches the names of the remotes managed through caic. The
+// remotes md creates for the containers are named after them, "md-...".
+var RemoteNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// Remote is a git remote of a repo.
+type Remote struct {
+	Name string
+	URL  string
+}
+
+// ListRemotes returns the remotes of the repo at dir sorted by name, without
+// the ones of the containers.
+func ListRemotes(ctx context.Context, dir string) ([]Remote, error) {
+	out, err := gitutil.RunGit(ctx, dir, "config", "--get-regexp", `^remote\..*\.url$`)
+	if err != nil {
+		// Exit code 1 means no remote.
+		if strings.Contains(err.Error(), "exit status 1") {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var remotes []Remote
+	for line := range strings.SplitSeq(out, "\n") {
+		key, url, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+		if strings.HasPrefix(name, "md-") {
+			continue
+		}
+		remotes = append(remotes, Remote{Name: name, URL: url})
+	}
+	slices.SortFunc(remotes, func(a, b Remote) int { return strings.Compare(a.Name, b.Name) })
+	return remotes, nil
+}
+
+// SetRemote adds the remote name of the repo at dir, or changes its URL.
+func SetRemote(ctx context.Context, dir, name, url string) error {
+	if !RemoteNameRe.MatchString(name) || strings.HasPrefix(name, "md-") {
+		return fmt.Errorf("invalid remote name %q", name)
+	}
+	if _, err := gitutil.RunGit(ctx, dir, "remote", "get-url", name); err == nil {
+		_, err = gitutil.RunGit(ctx, dir, "remote", "set-url", name, url)
+		return err
+	}
+	_, err := gitutil.RunGit(ctx, dir, "remote", "add", name, url)
+	return err
+}
+
+// RemoveRemote removes the remote name of the repo at dir, resetting the
+// push remote to origin when it was the one.
+func RemoveRemote(ctx context.Context, dir, name string) error {
+	if name == "origin" {
+		return errors.New("origin cannot be removed")
+	}
+	if PushRemote(ctx, dir) == name {
+		if err := SetPushRemote(ctx, dir, ""); err != nil {
+			return err
+		}
+	}
+	_, err := gitutil.RunGit(ctx, dir, "remote", "remove", name)
+	return err
+}
+
+// PushRemote returns the remote the task branches of the repo at dir are
+// pushed to by default: git's remote.pushDefault, else origin.
+func PushRemote(ctx context.Context, dir string) string {
+	if out, err := gitutil.RunGit(ctx, dir, "config", "remote.pushDefault"); err == nil && out != "" {
+		return out
+	}
+	return "origin"
+}
+
+// SetPushRemote sets the default push remote of the repo at dir. An empty
+// name or origin resets it.
+func SetPushRemote(ctx context.Context, dir, name string) error {
+	if name == "" || name == "origin" {
+		if _, err := gitutil.RunGit(ctx, dir, "config", "--unset", "remote.pushDefault"); err != nil && !strings.Contains(err.Error(), "exit status 5") {
+			return err
+		}
+		return nil
+	}
+	if _, err := gitutil.RunGit(ctx, dir, "remote", "get-url", name); err != nil {
+		return fmt.Errorf("unknown remote %q", name)
+	}
+	_, err := gitutil.RunGit(ctx, dir, "config", "remote.pushDefault", name)
+	return err
+}
+
+// pushRef force-pushes ref to the branch of remote, origin when empty.
+func pushRef(ctx context.Context, dir, remote, ref, branch string) error {
+	if remote == "" || remote == "origin" {
+		return gitutil.PushRef(ctx, dir, ref, branch, true)
+	}
+	_, err := gitutil.RunGit(ctx, dir, "push", "--force", remote, ref+":refs/heads/"+branch)
+	return err
+}
+
+// TrackPushedBranch points the local branch at the container ref SyncToOrigin
+// pushed. A branch pushed to a fork has no origin ref, so the tasks branching
+// off it start from the local branch instead.
+func (r *Runner) TrackPushedBranch(ctx context.Context, branch, container string) error {
+	r.branchMu.Lock()
+	defer r.branchMu.Unlock()
+	_, err := gitutil.RunGit(ctx, r.Dir, "update-ref", "refs/heads/"+branch, "refs/remotes/"+container+"/"+branch)
+	return err
+}
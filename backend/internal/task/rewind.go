@@ -0,0 +1,66 @@
+// Rewinding a session by one user turn: the agent restarts with the
+// conversation before the last user input replayed ahead of the new input.
+package task
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// ErrNothingToRewind is returned by RewindSession when the current session has
+// no user input.
+var ErrNothingToRewind = errors.New("no user input to rewind")
+
+// RewindSession withdraws the last user input and its answer, then restarts
+// the session with prompt in its place. Harnesses can't drop a turn from a
+// live session, so the new session's first message replays the conversation
+// before the withdrawn input. File changes made by the withdrawn turn stay in
+// the container.
+func (r *Runner) RewindSession(ctx context.Context, t *Task, prompt agent.Prompt) (*SessionHandle, error) {
+	replay, err := rewindPrompt(t.Messages(), prompt)
+	if err != nil {
+		return nil, err
+	}
+	return r.restartSession(ctx, t, replay, prompt)
+}
+
+// rewindPrompt returns p preceded by a transcript of the current session in
+// msgs up to its last user input. Tool calls are left out; the agent can
+// inspect the container for their effects.
+func rewindPrompt(msgs []agent.Message, p agent.Prompt) (agent.Prompt, error) {
+	start, last := 0, -1
+	for i, m := range msgs {
+		switch m := m.(type) {
+		case *agent.SystemMessage:
+			if m.Subtype == "context_cleared" {
+				start, last = i+1, -1
+			}
+		case *agent.UserInputMessage:
+			last = i
+		}
+	}
+	if last == -1 {
+		return agent.Prompt{}, ErrNothingToRewind
+	}
+	var b strings.Builder
+	for _, m := range msgs[start:last] {
+		switch m := m.(type) {
+		case *agent.UserInputMessage:
+			b.WriteString("<user>\n" + m.Text + "\n</user>\n")
+		case *agent.TextMessage:
+			if m.ParentToolUseID == "" {
+				b.WriteString("<assistant>\n" + m.Text + "\n</assistant>\n")
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return p, nil
+	}
+	return agent.Prompt{
+		Text:   "This conversation was restarted to replace my last request. Here is the conversation before it:\n\n" + b.String() + "\nContinue from there with my new request:\n\n" + p.Text,
+		Images: p.Images,
+	}, nil
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+func TestRewindPrompt(t *testing.T) {
+	next := agent.Prompt{Text: "fix the typo"}
+	t.Run("Replay", func(t *testing.T) {
+		msgs := []agent.Message{
+			&agent.UserInputMessage{Text: "add a flag"},
+			&agent.TextMessage{Text: "Added --verbose."},
+			&agent.ToolUseMessage{ToolUseID: "t1", Name: "Task"},
+			&agent.TextMessage{Text: "sub-agent notes", ParentToolUseID: "t1"},
+			&agent.ResultMessage{},
+			&agent.UserInputMessage{Text: "fix teh tpyo"},
+			&agent.TextMessage{Text: "Which typo?"},
+			&agent.ResultMessage{},
+		}
+		got, err := rewindPrompt(msgs, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "This conversation was restarted to replace my last request. Here is the conversation before it:\n\n" +
+			"<user>\nadd a flag\n</user>\n<assistant>\nAdded --verbose.\n</assistant>\n" +
+			"\nContinue from there with my new request:\n\nfix the typo"
+		if got.Text != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got.Text, want)
+		}
+	})
+	t.Run("FirstTurn", func(t *testing.T) {
+		msgs := []agent.Message{
+			&agent.UserInputMessage{Text: "old"},
+			&agent.SystemMessage{MessageType: "system", Subtype: "context_cleared"},
+			&agent.UserInputMessage{Text: "fix teh tpyo"},
+			&agent.ResultMessage{},
+		}
+		got, err := rewindPrompt(msgs, next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Text != next.Text {
+			t.Errorf("got %q, want %q", got.Text, next.Text)
+		}
+	})
+	t.Run("NoInput", func(t *testing.T) {
+		msgs := []agent.Message{
+			&agent.UserInputMessage{Text: "old"},
+			&agent.SystemMessage{MessageType: "system", Subtype: "context_cleared"},
+		}
+		if _, err := rewindPrompt(msgs, next); !errors.Is(err, ErrNothingToRewind) {
+			t.Errorf("err = %v, want ErrNothingToRewind", err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package task
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,15 +12,19 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/agent/aider"
 	"github.com/caic-xyz/caic/backend/internal/agent/claudecode"
 	"github.com/caic-xyz/caic/backend/internal/agent/codex"
 	"github.com/caic-xyz/caic/backend/internal/agent/opencode"
+	"github.com/caic-xyz/caic/backend/internal/logctx"
+	"github.com/caic-xyz/caic/backend/internal/policy"
 	"github.com/caic-xyz/md"
 	"github.com/caic-xyz/md/gitutil"
 	"golang.org/x/sync/errgroup"
@@ -32,9 +37,14 @@ type StartOptions struct {
 	Tailscale   bool
 	USB         bool
 	Display     bool
+	// ReadOnly locks the pushed repos read-only inside the container.
+	ReadOnly bool
 	// GitHubToken is the resolved GitHub token to inject into the container's
 	// environment. Empty means no token is injected.
 	GitHubToken string
+	// ExtraEnv are KEY=VALUE pairs added to the environment of the container
+	// and the agent.
+	ExtraEnv []string
 	// LogWriter receives provisioning log lines from the container backend.
 	// Must not be nil.
 	LogWriter io.Writer
@@ -46,11 +56,29 @@ type ContainerBackend interface {
 	// writes SSH config. Does NOT wait for SSH. Repos must have branches set.
 	// Returns the container name assigned during launch.
 	Launch(ctx context.Context, repos []md.Repo, labels []string, opts *StartOptions) (name string, err error)
+	// PullImage pulls the base image of opts when it is missing locally and
+	// reports whether it did. pulling is called when the pull starts; the
+	// progress is written to opts.LogWriter.
+	PullImage(ctx context.Context, opts *StartOptions, pulling func()) (bool, error)
 	// Connect waits for SSH and pushes repos into the container identified
 	// by name (returned by Launch). Returns the optional Tailscale FQDN.
 	Connect(ctx context.Context, name string, repos []md.Repo, opts *StartOptions) (tailscaleFQDN string, err error)
 	Diff(ctx context.Context, repo md.Repo, args ...string) (string, error)
 	Fetch(ctx context.Context, repos []md.Repo) error
+	// Exec runs a shell script in dir inside the running container identified
+	// by name and returns its combined output.
+	Exec(ctx context.Context, name, dir, script string) (string, error)
+	// SetPaused freezes or thaws the processes of the running container
+	// identified by name.
+	SetPaused(ctx context.Context, name string, paused bool) error
+	// RestrictNetwork limits the outgoing connections of the running
+	// container identified by name to hosts. Connections the container
+	// accepts, like SSH, are unaffected.
+	RestrictNetwork(ctx context.Context, name string, hosts []string) error
+	// Console streams the last tail lines of the console output of the
+	// running container identified by name, then the new lines, until ctx is
+	// done or the container stops. fn is never called concurrently.
+	Console(ctx context.Context, name string, tail int, fn func(ConsoleLine)) error
 	// Stop gracefully stops the container without removing it. The container
 	// can be restarted later with Revive.
 	Stop(ctx context.Context, name string) error
@@ -80,6 +108,9 @@ type ForkOptions struct {
 	LogWriter  io.Writer // Provisioning log output.
 }
 
+// verifyTimeout bounds a pipeline verification script (tests, linters).
+const verifyTimeout = 10 * time.Minute
+
 // Result holds the outcome of a completed task.
 type Result struct {
 	State       State
@@ -103,14 +134,32 @@ type Runner struct {
 	// Container provides md container lifecycle operations. Must be set before
 	// calling Start.
 	Container ContainerBackend
+	// Journal records the intents a crash would leave half done, e.g. a task
+	// whose container started before its log was written. Nil disables it.
+	Journal *Journal
+	// BranchIDs allocates the sequence numbers of the caic-N branches, shared
+	// with the repos and the caic instances pushing to the same remote. Nil
+	// keeps the sequence in memory, seeded by Init.
+	BranchIDs *BranchIDs
 	// Backends maps harness names to their Backend implementations. The runner
 	// selects the backend matching Task.Harness.
 	Backends map[agent.Harness]agent.Backend
+	// AutoCompactThreshold is the context window fill ratio (0-1) at which the
+	// session is compacted automatically. 0 uses DefaultAutoCompactThreshold;
+	// a negative value disables automatic compaction.
+	AutoCompactThreshold float64
+	// OnSessionRestarted is called when the runner restarts a session on its
+	// own (automatic compaction) so the caller can watch the new session.
+	OnSessionRestarted func(t *Task, h *SessionHandle)
+	// OnPolicyViolation is called after a tool call violating Task.Policy
+	// paused the task's container.
+	OnPolicyViolation func(t *Task, v *policy.Violation)
 
 	log      *slog.Logger
 	initOnce sync.Once
 	branchMu sync.Mutex // Serializes branch creation (nextID + git branch) to avoid duplicate names.
 	nextID   int        // Next branch sequence number (protected by branchMu).
+	idKey    string     // Key of the branch sequence in BranchIDs: the origin URL, else Dir.
 }
 
 // provisioningWriter is an io.Writer that converts line-by-line output from the
@@ -141,6 +190,7 @@ func (r *Runner) initDefaults() {
 	r.initOnce.Do(func() {
 		if r.Backends == nil {
 			r.Backends = map[agent.Harness]agent.Backend{
+				agent.Aider:    aider.New(),
 				agent.Claude:   claudecode.New(),
 				agent.Codex:    codex.New(),
 				agent.OpenCode: opencode.New(),
@@ -175,7 +225,8 @@ func (r *Runner) containerDir() string {
 }
 
 // Init sets nextID past any existing caic-* branches so that restarts don't
-// waste attempts on branches that already exist. No-op for no-repo runners.
+// waste attempts on branches that already exist, and keys the runner's
+// sequence in BranchIDs by its origin. No-op for no-repo runners.
 func (r *Runner) Init(ctx context.Context) error {
 	r.initDefaults()
 	if r.Dir == "" {
@@ -185,6 +236,7 @@ func (r *Runner) Init(ctx context.Context) error {
 	defer cancel()
 	r.branchMu.Lock()
 	defer r.branchMu.Unlock()
+	r.idKey = cmp.Or(gitutil.RemoteOriginURL(ctx, r.Dir), r.Dir)
 	highest, err := maxBranchSeqNum(ctx, r.Dir)
 	if err != nil {
 		return err
@@ -214,6 +266,7 @@ func (r *Runner) Init(ctx context.Context) error {
 //     process is started.
 //   - All-fail: reverts to StateWaiting.
 func (r *Runner) Reconnect(ctx context.Context, t *Task, skipSideEffects bool) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
 	r.initDefaults()
 	if t.HasSession() {
 		return nil, errors.New("session already active")
@@ -242,8 +295,9 @@ func (r *Runner) Reconnect(ctx context.Context, t *Task, skipSideEffects bool) (
 	// Only transition to StateRunning if the restored messages indicate
 	// the agent was still producing output (no trailing ResultMessage).
 	// If the agent had already completed its turn, keep the inferred
-	// StateWaiting/StateAsking so the UI shows the correct status.
-	if prevState != StateWaiting && prevState != StateAsking {
+	// StateWaiting/StateAsking/StateRateLimited so the UI shows the correct
+	// status.
+	if prevState != StateWaiting && prevState != StateAsking && prevState != StateRateLimited {
 		t.SetState(StateRunning)
 	}
 	session, err := r.backend(t.Harness).AttachRelay(ctx, &agent.Options{
@@ -256,7 +310,7 @@ func (r *Runner) Reconnect(ctx context.Context, t *Task, skipSideEffects bool) (
 		close(msgCh)
 		<-dispatchDone
 		t.SetState(StateWaiting)
-		r.log.Error("attach relay failed", "br", primaryBranch, "ctr", t.Container, "err", err)
+		r.log.ErrorContext(ctx, "attach relay failed", "br", primaryBranch, "ctr", t.Container, "err", err)
 		return nil, fmt.Errorf("reconnect: %w", err)
 	}
 
@@ -279,19 +333,27 @@ func (r *Runner) Reconnect(ctx context.Context, t *Task, skipSideEffects bool) (
 //
 // The session is left open for follow-up messages via SendInput.
 func (r *Runner) Start(ctx context.Context, t *Task) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
 	r.initDefaults()
 	if r.Container == nil {
 		return nil, errors.New("runner has no container backend configured")
 	}
-	if r.Dir != "" {
+	if r.Dir != "" && !t.Chat {
 		t.SetState(StateBranching)
 	}
 
 	tStart := time.Now()
-	// 1. Create branch (serialized) + start container (concurrent).
-	r.log.Info("setup task")
-	sr, err := r.setup(ctx, t, []string{"caic=" + t.ID.String(), "harness=" + string(t.Harness)})
+	// 1. Create branch (serialized) + start container (concurrent). Until the
+	// log holds the task's metadata, only the journal knows about the task.
+	if err := r.reserveBranch(ctx, t); err != nil {
+		t.SetState(StateFailed)
+		return nil, err
+	}
+	journaled := r.journal(ctx, &JournalEntry{Op: JournalStart, Dir: r.Dir, Log: logName(t), Meta: logMeta(t)})
+	r.log.InfoContext(ctx, "setup task")
+	sr, err := r.setup(ctx, t)
 	if err != nil {
+		journaled()
 		t.SetState(StateFailed)
 		return nil, err
 	}
@@ -301,12 +363,13 @@ func (r *Runner) Start(ctx context.Context, t *Task) (*SessionHandle, error) {
 	if p := t.Primary(); p != nil {
 		primaryBranch = p.Branch
 	}
-	r.log.Info("container ready", "br", primaryBranch, "ctr", t.Container, "dur", time.Since(tStart))
+	r.log.InfoContext(ctx, "container ready", "br", primaryBranch, "ctr", t.Container, "dur", time.Since(tStart))
 
 	// 2. Start the agent session.
 	t.SetState(StateStarting)
 	msgCh, dispatchDone := r.startMessageDispatch(ctx, t, false)
 	logW, err := r.openLog(t)
+	journaled()
 	if err != nil {
 		close(msgCh)
 		<-dispatchDone
@@ -316,29 +379,29 @@ func (r *Runner) Start(ctx context.Context, t *Task) (*SessionHandle, error) {
 
 	tSession := time.Now()
 	tlog := r.log.With("br", primaryBranch, "ctr", t.Container)
-	tlog.Info("starting session", "hns", t.Harness)
-	session, err := r.backend(t.Harness).Start(ctx, &agent.Options{
-		Container:     t.Container,
-		Dir:           r.containerDir(),
-		Model:         t.Model,
-		InitialPrompt: t.InitialPrompt,
-	}, msgCh, logW)
+	tlog.InfoContext(ctx, "starting session", "hns", t.Harness)
+	session, failover, err := r.startAgent(ctx, t, msgCh, &logW, tlog)
 	if err != nil {
 		_ = logW.Close()
 		close(msgCh)
 		<-dispatchDone
 		t.SetState(StateFailed)
-		tlog.Error("session start failed", "err", err)
+		tlog.ErrorContext(ctx, "session start failed", "err", err)
 		return nil, err
 	}
 
 	// Store handle so SendInput can reach it.
 	h := &SessionHandle{Session: session, MsgCh: msgCh, DispatchDone: dispatchDone, LogW: logW}
 	t.AttachSession(h)
+	if failover != nil {
+		notice := syntheticFailover(failover)
+		t.addMessage(ctx, notice, false)
+		t.WriteToLog(notice)
+	}
 
 	t.addMessage(ctx, syntheticUserInput(t.InitialPrompt), false)
 	t.SetState(StateRunning)
-	tlog.Info("agent running", "session_dur", time.Since(tSession), "total_startup_dur", time.Since(tStart))
+	tlog.InfoContext(ctx, "agent running", "session_dur", time.Since(tSession), "total_startup_dur", time.Since(tStart))
 	return h, nil
 }
 
@@ -354,12 +417,14 @@ func (r *Runner) Start(ctx context.Context, t *Task) (*SessionHandle, error) {
 //  1. Detach the session handle from the task.
 //  2. If a session exists: Session.Close sends \x00 + closes stdin, wait up to 10s.
 //  3. Set task state to reason (StatePurged or StateFailed).
-//  4. Kill the container.
+//  4. Save the workspace when t.Persist is set, then kill the container.
 //  5. If graceful wait timed out, drain session now (container dead, SSH severed).
 //  6. Close msgCh and logW, write log trailer.
 //  7. Build and return Result.
 func (r *Runner) Cleanup(ctx context.Context, t *Task, reason State) Result {
+	ctx = logctx.With(ctx, "task", t.ID)
 	r.initDefaults()
+	r.releasePolicyPause(ctx, t)
 	h := t.DetachSession()
 
 	name := t.Container
@@ -380,18 +445,22 @@ func (r *Runner) Cleanup(ctx context.Context, t *Task, reason State) Result {
 			timer.Stop()
 			result, _ = h.Session.Wait()
 		case <-timer.C:
-			tlog.Warn("session timeout, killing")
+			tlog.WarnContext(ctx, "session timeout, killing")
 		}
 	}
 
 	t.SetState(reason)
 
-	tlog.Info("purge container")
+	r.saveWorkspace(ctx, t)
+	tlog.InfoContext(ctx, "purge container")
 	if name != "" && r.Container != nil {
 		if err := r.PurgeContainer(ctx, name, primaryBranch, t.ExtraMDRepos()); err != nil {
-			tlog.Warn("purge failed", "err", err)
+			tlog.WarnContext(ctx, "purge failed", "err", err)
 		}
 	}
+	if t.Chat {
+		r.deleteChatRef(ctx, primaryBranch)
+	}
 
 	// If the graceful wait timed out, wait for the session to drain now
 	// that the container is dead and the SSH connection is severed.
@@ -439,7 +508,7 @@ func (r *Runner) Cleanup(ctx context.Context, t *Task, reason State) Result {
 		var reopenErr error
 		logW, reopenErr = r.reopenLog(t)
 		if reopenErr != nil {
-			tlog.Warn("reopen log for trailer failed", "err", reopenErr)
+			tlog.WarnContext(ctx, "reopen log for trailer failed", "err", reopenErr)
 		}
 	}
 	writeLogTrailer(logW, t.Title(), &res)
@@ -453,7 +522,9 @@ func (r *Runner) Cleanup(ctx context.Context, t *Task, reason State) Result {
 // without removing it. The container can be revived later. Unlike Cleanup,
 // this preserves git remotes and SSH config.
 func (r *Runner) StopTask(ctx context.Context, t *Task) {
+	ctx = logctx.With(ctx, "task", t.ID)
 	r.initDefaults()
+	r.releasePolicyPause(ctx, t)
 	h := t.DetachSession()
 
 	name := t.Container
@@ -471,16 +542,17 @@ func (r *Runner) StopTask(ctx context.Context, t *Task) {
 		case <-h.Session.Done():
 			timer.Stop()
 		case <-timer.C:
-			tlog.Warn("session timeout during stop")
+			tlog.WarnContext(ctx, "session timeout during stop")
 		}
 	}
 
 	t.SetState(StateStopping)
 
-	tlog.Info("stop container")
+	r.saveWorkspace(ctx, t)
+	tlog.InfoContext(ctx, "stop container")
 	if name != "" && r.Container != nil {
 		if err := r.Container.Stop(ctx, name); err != nil {
-			tlog.Warn("stop failed", "err", err)
+			tlog.WarnContext(ctx, "stop failed", "err", err)
 		}
 	}
 
@@ -509,6 +581,7 @@ func (r *Runner) StopTask(ctx context.Context, t *Task) {
 // docker-start + SSH, a new relay is started with --resume to continue
 // the previous session.
 func (r *Runner) ReviveTask(ctx context.Context, t *Task) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
 	r.initDefaults()
 	if r.Container == nil {
 		return nil, errors.New("runner has no container backend configured")
@@ -525,18 +598,22 @@ func (r *Runner) ReviveTask(ctx context.Context, t *Task) (*SessionHandle, error
 	// 1. Revive the container (docker start + SSH).
 	t.SetState(StateProvisioning)
 	repos := t.MDRepos()
-	tlog.Info("reviving container")
+	tlog.InfoContext(ctx, "reviving container")
 	if err := r.Container.Revive(ctx, t.Container, repos); err != nil {
 		t.SetState(StateFailed)
 		return nil, fmt.Errorf("revive container: %w", err)
 	}
+	if err := r.restrictNetwork(ctx, t, t.Container); err != nil {
+		t.SetState(StateFailed)
+		return nil, fmt.Errorf("restrict network: %w", err)
+	}
 
 	// 2. Start a new relay with --resume to continue the previous session.
 	// skipSideEffects=true: --resume replays all historical messages and
 	// each would trigger fetch+diff+title if side effects were enabled.
 	// Instead we do a single BranchDiffStat at the end.
 	t.SetState(StateStarting)
-	tlog.Info("resuming session after revive", "sess", t.GetSessionID())
+	tlog.InfoContext(ctx, "resuming session after revive", "sess", t.GetSessionID())
 
 	msgCh, dispatchDone := r.startMessageDispatch(ctx, t, true)
 	logW, err := r.openLog(t)
@@ -552,7 +629,12 @@ func (r *Runner) ReviveTask(ctx context.Context, t *Task) (*SessionHandle, error
 		Container:       t.Container,
 		Dir:             r.containerDir(),
 		Model:           t.Model,
+		PlanOnly:        t.planMode(),
+		ReadOnly:        t.ReadOnly,
+		Thinking:        t.Thinking,
 		ResumeSessionID: t.GetSessionID(),
+		Endpoint:        t.Endpoint,
+		Instructions:    t.Instructions,
 	}, msgCh, logW)
 	if err != nil {
 		_ = logW.Close()
@@ -577,7 +659,7 @@ func (r *Runner) ReviveTask(ctx context.Context, t *Task) (*SessionHandle, error
 	if ds := r.BranchDiffStat(ctx, primaryBranch, t.ExtraMDRepos()); len(ds) > 0 {
 		t.SetLiveDiffStat(ds)
 	}
-	tlog.Info("agent ready after revive", "state", t.GetState())
+	tlog.InfoContext(ctx, "agent ready after revive", "state", t.GetState())
 	return h, nil
 }
 
@@ -585,6 +667,7 @@ func (r *Runner) ReviveTask(ctx context.Context, t *Task) (*SessionHandle, error
 // exits within 10 seconds (agent had already finished), it detaches and
 // starts a fresh idle relay so the task can accept new prompts.
 func (r *Runner) EnsureSession(ctx context.Context, t *Task, h *SessionHandle, tlog *slog.Logger) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
 	select {
 	case <-h.Session.Done():
 		// Session exited immediately (agent was already done).
@@ -597,7 +680,7 @@ func (r *Runner) EnsureSession(ctx context.Context, t *Task, h *SessionHandle, t
 		if result != nil {
 			sub = result.Subtype
 		}
-		tlog.Info("attached session exited, starting idle relay", "result", sub)
+		tlog.InfoContext(ctx, "attached session exited, starting idle relay", "result", sub)
 		if s := t.GetState(); s == StateStopping || s == StateStopped || s == StatePurged {
 			return nil, fmt.Errorf("task is %s", s)
 		}
@@ -616,6 +699,7 @@ func (r *Runner) EnsureSession(ctx context.Context, t *Task, h *SessionHandle, t
 // transitions to StateRunning. If prompt is empty, the agent starts idle
 // and the task stays in its current state (typically StateWaiting).
 func (r *Runner) StartSession(ctx context.Context, t *Task, prompt agent.Prompt) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
 	r.initDefaults()
 	if t.Container == "" {
 		return nil, errors.New("no container")
@@ -634,18 +718,23 @@ func (r *Runner) StartSession(ctx context.Context, t *Task, prompt agent.Prompt)
 		return nil, err
 	}
 
-	tlog.Info("starting session", "hns", t.Harness)
+	tlog.InfoContext(ctx, "starting session", "hns", t.Harness)
 	session, err := r.backend(t.Harness).Start(ctx, &agent.Options{
 		Container:     t.Container,
 		Dir:           r.containerDir(),
 		Model:         t.Model,
+		PlanOnly:      t.planMode(),
+		ReadOnly:      t.ReadOnly,
+		Thinking:      t.Thinking,
 		InitialPrompt: prompt,
+		Endpoint:      t.Endpoint,
+		Instructions:  t.Instructions,
 	}, msgCh, logW)
 	if err != nil {
 		_ = logW.Close()
 		close(msgCh)
 		<-dispatchDone
-		tlog.Error("session start failed", "err", err)
+		tlog.ErrorContext(ctx, "session start failed", "err", err)
 		return nil, err
 	}
 
@@ -663,6 +752,7 @@ func (r *Runner) StartSession(ctx context.Context, t *Task, prompt agent.Prompt)
 // Harness, Model, and other immutable fields set. The method fills in
 // Container, Repos[*].Branch, and starts the session.
 func (r *Runner) ForkTask(ctx context.Context, source, fork *Task, forkOpts *ForkOptions) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", fork.ID, "src", source.ID)
 	r.initDefaults()
 	if r.Container == nil {
 		return nil, errors.New("runner has no container backend configured")
@@ -679,7 +769,7 @@ func (r *Runner) ForkTask(ctx context.Context, source, fork *Task, forkOpts *For
 
 	// 1. Fork the container. Branch names are generated by md.
 	fork.SetState(StateProvisioning)
-	tlog.Info("forking container")
+	tlog.InfoContext(ctx, "forking container")
 	forkOpts.LogWriter = &provisioningWriter{ctx: ctx, t: fork}
 	forkName, forkRepos, err := r.Container.Fork(ctx, source.Container, source.MDRepos(), forkOpts)
 	if err != nil {
@@ -687,17 +777,21 @@ func (r *Runner) ForkTask(ctx context.Context, source, fork *Task, forkOpts *For
 		return nil, fmt.Errorf("fork container: %w", err)
 	}
 	fork.Container = forkName
+	if err := r.restrictNetwork(ctx, fork, forkName); err != nil {
+		fork.SetState(StateFailed)
+		return nil, fmt.Errorf("restrict network: %w", err)
+	}
 	for i := range fork.Repos {
 		if i < len(forkRepos) {
 			fork.Repos[i].Branch = forkRepos[i].Branch
 		}
 	}
-	tlog.Info("fork container ready", "ctr", forkName)
+	tlog.InfoContext(ctx, "fork container ready", "ctr", forkName)
 
 	// 2. Clean relay state from the source container's snapshot so the
 	// forked task starts with an empty output.jsonl.
 	if err := agent.CleanRelayState(ctx, forkName); err != nil {
-		tlog.Warn("clean relay state failed (non-fatal)", "err", err)
+		tlog.WarnContext(ctx, "clean relay state failed (non-fatal)", "err", err)
 	}
 
 	// 3. Start a fresh agent session with the fork prompt.
@@ -708,10 +802,32 @@ func (r *Runner) ForkTask(ctx context.Context, source, fork *Task, forkOpts *For
 		fork.SetState(StateFailed)
 		return nil, fmt.Errorf("start session on fork: %w", err)
 	}
-	tlog.Info("fork session running", "ctr", forkName)
+	tlog.InfoContext(ctx, "fork session running", "ctr", forkName)
 	return h, nil
 }
 
+// Container labels written when caic creates a task's container. Adoption
+// after a server restart reconstructs the task from them and its JSONL log.
+const (
+	LabelTaskID  = "caic" // Task ID; proves caic started the container.
+	LabelHarness = "harness"
+	LabelRepo    = "caic.repo"   // Primary repository, relative to the repos root.
+	LabelBranch  = "caic.branch" // Primary branch; absent on forks, whose branch md picks.
+)
+
+// ContainerLabels returns the "key=value" labels describing t for its
+// container.
+func ContainerLabels(t *Task) []string {
+	labels := []string{LabelTaskID + "=" + t.ID.String(), LabelHarness + "=" + string(t.Harness)}
+	if p := t.Primary(); p != nil {
+		labels = append(labels, LabelRepo+"="+p.Name)
+		if p.Branch != "" {
+			labels = append(labels, LabelBranch+"="+p.Branch)
+		}
+	}
+	return labels
+}
+
 // setupResult holds the outputs of setup: the container name and optional Tailscale FQDN.
 // The primary branch is written directly into t.Repos[0].Branch during setup.
 type setupResult struct {
@@ -721,7 +837,7 @@ type setupResult struct {
 
 // allocateBranchLocked fetches origin, resolves the start point, and creates
 // the task branch. Must be called under branchMu. Used by AllocateBranch for
-// extra repos; primary repo branch allocation uses reserveBranchID + fetchAndCreateBranch.
+// extra repos; primary repo branch allocation uses reserveBranch + fetchAndCreateBranch.
 func (r *Runner) allocateBranchLocked(ctx context.Context, t *Task) (string, error) {
 	detached := context.WithoutCancel(ctx)
 	gitCtx, gitCancel := context.WithTimeout(detached, r.GitTimeout)
@@ -741,22 +857,12 @@ func (r *Runner) allocateBranchLocked(ctx context.Context, t *Task) (string, err
 	if _, err := gitutil.RevParse(gitCtx, r.Dir, startPoint); err != nil {
 		startPoint = effectiveBase
 	}
-	// Assign a sequential branch name, skipping existing ones.
-	var branch string
-	var err error
-	for range 100 {
-		if gitCtx.Err() != nil {
-			return "", gitCtx.Err()
-		}
-		branch = fmt.Sprintf("caic-%d", r.nextID)
-		r.nextID++
-		r.log.Info("creating branch", "br", branch, "base", effectiveBase)
-		err = gitutil.CreateBranch(gitCtx, r.Dir, branch, startPoint)
-		if err == nil {
-			break
-		}
-	}
+	branch, err := r.nextBranchLocked(gitCtx)
 	if err != nil {
+		return "", err
+	}
+	r.log.InfoContext(ctx, "creating branch", "br", branch, "base", effectiveBase)
+	if err := gitutil.CreateBranch(gitCtx, r.Dir, branch, startPoint); err != nil {
 		return "", fmt.Errorf("create branch: %w", err)
 	}
 	return branch, nil
@@ -773,7 +879,7 @@ func (r *Runner) AllocateBranch(ctx context.Context) (string, error) {
 }
 
 // fetchAndCreateBranch fetches origin and creates the given branch from the
-// resolved base. Acquires branchMu to serialize git operations across concurrent
+// resolved base. Chat tasks get a bare ref outside refs/heads instead (see chatRef). Acquires branchMu to serialize git operations across concurrent
 // task setups on the same repo (git fetch/branch are not safe to run in parallel
 // on the same working tree). Container.Launch can still run concurrently since it
 // does not touch the repo.
@@ -793,42 +899,119 @@ func (r *Runner) fetchAndCreateBranch(ctx context.Context, t *Task, branch strin
 	if _, err := gitutil.RevParse(gitCtx, r.Dir, startPoint); err != nil {
 		startPoint = effectiveBase
 	}
-	r.log.Info("creating branch", "br", branch, "base", effectiveBase)
+	if t.Chat {
+		r.log.InfoContext(ctx, "creating chat ref", "br", branch, "base", effectiveBase)
+		if _, err := gitutil.RunGit(gitCtx, r.Dir, "update-ref", "refs/"+branch, startPoint); err != nil {
+			return fmt.Errorf("create chat ref: %w", err)
+		}
+		return nil
+	}
+	if branch == effectiveBase {
+		// The task continues its base branch: reset the local branch to the
+		// pushed one, which is ahead when another task left it.
+		r.log.InfoContext(ctx, "continuing branch", "br", branch)
+		if _, err := gitutil.RunGit(gitCtx, r.Dir, "branch", "-f", branch, startPoint); err != nil {
+			return fmt.Errorf("reset branch: %w", err)
+		}
+		return nil
+	}
+	r.log.InfoContext(ctx, "creating branch", "br", branch, "base", effectiveBase)
 	if err := gitutil.CreateBranch(gitCtx, r.Dir, branch, startPoint); err != nil {
 		return fmt.Errorf("create branch: %w", err)
 	}
 	return nil
 }
 
+// reserveBranch reserves the name of the task's branch without touching the
+// network, unless already reserved. The branch itself is created by setup.
+func (r *Runner) reserveBranch(ctx context.Context, t *Task) error {
+	if r.Dir == "" {
+		return nil
+	}
+	r.branchMu.Lock()
+	defer r.branchMu.Unlock()
+	if t.Chat {
+		t.Repos[0].Branch = chatRef(t)
+	} else if t.Repos[0].Branch == "" {
+		// A preset branch, e.g. the head of a PR under review, is kept.
+		gitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+		defer cancel()
+		branch, err := r.nextBranchLocked(gitCtx)
+		if err != nil {
+			return err
+		}
+		t.Repos[0].Branch = branch
+	}
+	return nil
+}
+
+// nextBranchLocked allocates the name of a new caic-N branch, from BranchIDs
+// when set, skipping the names already taken by a local branch or by a branch
+// of the remote as of its last fetch: another instance, or another clone of
+// the same remote, may have created it. Must be called under branchMu.
+func (r *Runner) nextBranchLocked(ctx context.Context) (string, error) {
+	for range 100 {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		n := r.nextID
+		if r.BranchIDs != nil {
+			id, err := r.BranchIDs.Next(ctx, cmp.Or(r.idKey, r.Dir), r.nextID)
+			if err != nil {
+				r.log.WarnContext(ctx, "branch ID store unavailable", "err", err)
+			} else {
+				n = id
+			}
+		}
+		r.nextID = n + 1
+		branch := fmt.Sprintf("caic-%d", n)
+		out, err := gitutil.RunGit(ctx, r.Dir, "for-each-ref", "--format=%(refname)", "refs/heads/"+branch, "refs/remotes/origin/"+branch)
+		if err != nil {
+			return "", fmt.Errorf("check branch: %w", err)
+		}
+		if out == "" {
+			return branch, nil
+		}
+		r.log.InfoContext(ctx, "branch name taken", "br", branch)
+	}
+	return "", errors.New("no free branch name")
+}
+
 // setup reserves a branch name, starts the container (Phase A) and creates the
 // git branch concurrently, then completes container startup (Phase B).
 // Phase A (docker run) and git fetch+branch-create overlap, cutting the
 // branch-allocation time off the critical path.
-func (r *Runner) setup(ctx context.Context, t *Task, labels []string) (setupResult, error) {
-	// Reserve the branch ID instantly (under lock, ~µs). The branch itself is
-	// created concurrently with docker run in Phase A.
+func (r *Runner) setup(ctx context.Context, t *Task) (setupResult, error) {
+	if err := r.reserveBranch(ctx, t); err != nil {
+		return setupResult{}, err
+	}
+	t.SetState(StateProvisioning)
+	var prepareBranch func(context.Context) error
 	if r.Dir != "" {
-		r.branchMu.Lock()
-		t.Repos[0].Branch = fmt.Sprintf("caic-%d", r.nextID)
-		r.nextID++
-		r.branchMu.Unlock()
+		branch := t.Repos[0].Branch
+		prepareBranch = func(ctx context.Context) error { return r.fetchAndCreateBranch(ctx, t, branch) }
 	}
+	return r.provision(ctx, t, prepareBranch)
+}
 
-	t.SetState(StateProvisioning)
+// provision starts the task's container (Phase A), running prepareBranch
+// concurrently when not nil, then completes the container startup (Phase B).
+// The primary branch must exist on the host once prepareBranch returns.
+func (r *Runner) provision(ctx context.Context, t *Task, prepareBranch func(context.Context) error) (setupResult, error) {
 	detached := context.WithoutCancel(ctx)
 	var primaryBranch string
 	if p := t.Primary(); p != nil {
 		primaryBranch = p.Branch
 	}
-	r.log.Info("starting container", "br", primaryBranch, "img", t.DockerImage, "hns", t.Harness, "ts", t.Tailscale, "usb", t.USB, "dpy", t.Display)
+	r.log.InfoContext(ctx, "starting container", "br", primaryBranch, "img", t.DockerImage, "hns", t.Harness, "ts", t.Tailscale, "usb", t.USB, "dpy", t.Display)
 	tContainer := time.Now()
 	startCtx, startCancel := context.WithTimeout(detached, r.ContainerStartTimeout)
 	defer startCancel()
 
 	opts := &StartOptions{
 		DockerImage: t.DockerImage, Harness: t.Harness, Tailscale: t.Tailscale, USB: t.USB, Display: t.Display,
-		GitHubToken: t.GitHubToken,
-		LogWriter:   &provisioningWriter{ctx: ctx, t: t},
+		ReadOnly: t.ReadOnly, GitHubToken: t.GitHubToken, ExtraEnv: t.EnvPairs(),
+		LogWriter: &provisioningWriter{ctx: ctx, t: t},
 	}
 
 	// Phase A: docker run + SSH config. Branch creation runs concurrently so
@@ -837,9 +1020,13 @@ func (r *Runner) setup(ctx context.Context, t *Task, labels []string) (setupResu
 	if r.Dir != "" {
 		repos = t.MDRepos()
 	}
+	labels := ContainerLabels(t)
 	var containerName string
 	eg, egCtx := errgroup.WithContext(startCtx)
 	eg.Go(func() error {
+		if err := r.pullImage(egCtx, t, opts); err != nil {
+			return err
+		}
 		name, err := r.Container.Launch(egCtx, repos, labels, opts)
 		if err != nil {
 			return err
@@ -847,9 +1034,9 @@ func (r *Runner) setup(ctx context.Context, t *Task, labels []string) (setupResu
 		containerName = name
 		return nil
 	})
-	if r.Dir != "" {
+	if prepareBranch != nil {
 		eg.Go(func() error {
-			return r.fetchAndCreateBranch(egCtx, t, primaryBranch)
+			return prepareBranch(egCtx)
 		})
 	}
 	if err := eg.Wait(); err != nil {
@@ -861,29 +1048,101 @@ func (r *Runner) setup(ctx context.Context, t *Task, labels []string) (setupResu
 	if err != nil {
 		return setupResult{}, fmt.Errorf("start container: %w", err)
 	}
-	r.log.Info("container started", "br", primaryBranch, "dur", time.Since(tContainer))
+	if err := r.installDeployKeys(startCtx, t, containerName); err != nil {
+		return setupResult{}, err
+	}
+	if err := r.restrictNetwork(startCtx, t, containerName); err != nil {
+		return setupResult{}, fmt.Errorf("restrict network: %w", err)
+	}
+	r.log.InfoContext(ctx, "container started", "br", primaryBranch, "dur", time.Since(tContainer))
 	return setupResult{Container: containerName, TailscaleFQDN: tailscaleFQDN}, nil
 }
 
+// pullImage pulls the task's base image when it is missing locally, in the
+// SubStateImagePull sub-state so that a slow pull is told apart from the rest
+// of the provisioning.
+func (r *Runner) pullImage(ctx context.Context, t *Task, opts *StartOptions) error {
+	start := time.Now()
+	pulled, err := r.Container.PullImage(ctx, opts, func() { t.SetSubState(SubStateImagePull) })
+	if err != nil {
+		return fmt.Errorf("pull image: %w", err)
+	}
+	if pulled {
+		t.SetSubState(SubStateNone)
+		r.log.InfoContext(ctx, "image pulled", "img", opts.DockerImage, "dur", time.Since(start))
+	}
+	return nil
+}
+
 // SyncToOrigin fetches changes from the container, runs safety checks, and
-// pushes the container's remote-tracking ref to origin. If safety issues are
-// found and force is false, it returns the issues without pushing.
-func (r *Runner) SyncToOrigin(ctx context.Context, branch, container string, force bool, extraRepos []md.Repo) (agent.DiffStat, []SafetyIssue, error) {
+// pushes the container's remote-tracking ref to origin, or to remote when not
+// empty, e.g. a fork. If safety issues are found and force is false, it
+// returns the issues without pushing.
+func (r *Runner) SyncToOrigin(ctx context.Context, branch, remote, container string, force bool, extraRepos []md.Repo) (agent.DiffStat, []SafetyIssue, error) {
 	r.initDefaults()
 	if r.Dir == "" {
 		return nil, nil, errors.New("sync is not supported for no-repo tasks")
 	}
+	ds, err := r.fetchForSync(ctx, branch, extraRepos)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.pushToOrigin(ctx, branch, remote, container, ds, force)
+}
+
+// SyncFetchedToOrigin is SyncToOrigin for an extra repo of a multi-repo task.
+// The container's ref must already have been fetched, which the primary
+// runner's SyncToOrigin does for every repo of the task.
+func (r *Runner) SyncFetchedToOrigin(ctx context.Context, branch, remote, container string, force bool) (agent.DiffStat, []SafetyIssue, error) {
+	r.initDefaults()
+	if r.Dir == "" {
+		return nil, nil, errors.New("sync is not supported for no-repo tasks")
+	}
+	return r.pushToOrigin(ctx, branch, remote, container, r.refDiffStat(ctx, container, branch), force)
+}
+
+// SyncToDefault fetches changes from the container, runs safety checks, and
+// squash-pushes onto the repo's default branch. Safety issues always block
+// (no force override). The commit message is built from the task title.
+func (r *Runner) SyncToDefault(ctx context.Context, branch, container, message string, extraRepos []md.Repo) (agent.DiffStat, []SafetyIssue, error) {
+	r.initDefaults()
+	if r.Dir == "" {
+		return nil, nil, errors.New("sync is not supported for no-repo tasks")
+	}
+	ds, err := r.fetchForSync(ctx, branch, extraRepos)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.squashToDefault(ctx, branch, container, message, ds)
+}
+
+// SyncFetchedToDefault is SyncToDefault for an extra repo of a multi-repo
+// task; see SyncFetchedToOrigin.
+func (r *Runner) SyncFetchedToDefault(ctx context.Context, branch, container, message string) (agent.DiffStat, []SafetyIssue, error) {
+	r.initDefaults()
+	if r.Dir == "" {
+		return nil, nil, errors.New("sync is not supported for no-repo tasks")
+	}
+	return r.squashToDefault(ctx, branch, container, message, r.refDiffStat(ctx, container, branch))
+}
+
+// fetchForSync fetches the task's repos from the container and returns the
+// diff stat of the primary branch.
+func (r *Runner) fetchForSync(ctx context.Context, branch string, extraRepos []md.Repo) (agent.DiffStat, error) {
 	fetchCtx, fetchCancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
 	defer fetchCancel()
 	r.branchMu.Lock()
-	r.log.Info("fetch", "br", branch)
+	defer r.branchMu.Unlock()
+	r.log.InfoContext(ctx, "fetch", "br", branch)
 	if err := r.Container.Fetch(fetchCtx, append([]md.Repo{{GitRoot: r.Dir, Branch: branch}}, extraRepos...)); err != nil {
-		r.branchMu.Unlock()
-		return nil, nil, err
+		return nil, err
 	}
-	ds := r.diffStat(fetchCtx, branch)
-	r.branchMu.Unlock()
+	return r.diffStat(fetchCtx, branch), nil
+}
 
+// pushToOrigin runs the safety checks on the fetched container ref and pushes
+// it to remote, origin when empty, unless blocked.
+func (r *Runner) pushToOrigin(ctx context.Context, branch, remote, container string, ds agent.DiffStat, force bool) (agent.DiffStat, []SafetyIssue, error) {
 	ref := "refs/remotes/" + container + "/" + branch
 	safetyCtx, safetyCancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
 	defer safetyCancel()
@@ -897,31 +1156,16 @@ func (r *Runner) SyncToOrigin(ctx context.Context, branch, container string, for
 
 	pushCtx, pushCancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
 	defer pushCancel()
-	if err := gitutil.PushRef(pushCtx, r.Dir, ref, branch, true); err != nil {
-		return ds, issues, fmt.Errorf("push to origin: %w", err)
+	defer r.journal(ctx, &JournalEntry{Op: JournalPush, Dir: r.Dir, Branch: branch, Remote: remote})()
+	if err := pushRef(pushCtx, r.Dir, remote, ref, branch); err != nil {
+		return ds, issues, fmt.Errorf("push to %s: %w", cmp.Or(remote, "origin"), err)
 	}
 	return ds, issues, nil
 }
 
-// SyncToDefault fetches changes from the container, runs safety checks, and
-// squash-pushes onto the repo's default branch. Safety issues always block
-// (no force override). The commit message is built from the task title.
-func (r *Runner) SyncToDefault(ctx context.Context, branch, container, message string, extraRepos []md.Repo) (agent.DiffStat, []SafetyIssue, error) {
-	r.initDefaults()
-	if r.Dir == "" {
-		return nil, nil, errors.New("sync is not supported for no-repo tasks")
-	}
-	fetchCtx, fetchCancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
-	defer fetchCancel()
-	r.branchMu.Lock()
-	r.log.Info("fetch for default sync", "br", branch)
-	if err := r.Container.Fetch(fetchCtx, append([]md.Repo{{GitRoot: r.Dir, Branch: branch}}, extraRepos...)); err != nil {
-		r.branchMu.Unlock()
-		return nil, nil, err
-	}
-	ds := r.diffStat(fetchCtx, branch)
-	r.branchMu.Unlock()
-
+// squashToDefault runs the safety checks on the fetched container ref and
+// squash-pushes it onto the default branch unless blocked.
+func (r *Runner) squashToDefault(ctx context.Context, branch, container, message string, ds agent.DiffStat) (agent.DiffStat, []SafetyIssue, error) {
 	ref := "refs/remotes/" + container + "/" + branch
 	safetyCtx, safetyCancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
 	defer safetyCancel()
@@ -934,6 +1178,7 @@ func (r *Runner) SyncToDefault(ctx context.Context, branch, container, message s
 	}
 	squashCtx, squashCancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
 	defer squashCancel()
+	defer r.journal(ctx, &JournalEntry{Op: JournalPush, Dir: r.Dir, Branch: r.BaseBranch})()
 	if err := gitutil.SquashOnto(squashCtx, r.Dir, ref, r.BaseBranch, message); err != nil {
 		return ds, issues, fmt.Errorf("squash onto %s: %w", r.BaseBranch, err)
 	}
@@ -944,10 +1189,17 @@ func (r *Runner) SyncToDefault(ctx context.Context, branch, container, message s
 // the same container with a new prompt. Returns the new SessionHandle so the
 // caller can start a session watcher.
 func (r *Runner) RestartSession(ctx context.Context, t *Task, prompt agent.Prompt) (*SessionHandle, error) {
+	return r.restartSession(ctx, t, prompt, prompt)
+}
+
+// restartSession implements RestartSession. The agent receives prompt while
+// the transcript records input, the user's own words.
+func (r *Runner) restartSession(ctx context.Context, t *Task, prompt, input agent.Prompt) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
 	r.initDefaults()
 
 	state := t.GetState()
-	if state != StateWaiting && state != StateAsking && state != StateHasPlan {
+	if state != StateWaiting && state != StateAsking && state != StateHasPlan && state != StatePlanReview {
 		return nil, fmt.Errorf("cannot restart in state %s", state)
 	}
 
@@ -984,12 +1236,17 @@ func (r *Runner) RestartSession(ctx context.Context, t *Task, prompt agent.Promp
 		restartBranch = p.Branch
 	}
 	tlog := r.log.With("br", restartBranch, "ctr", t.Container)
-	tlog.Info("restarting session", "hns", t.Harness)
+	tlog.InfoContext(ctx, "restarting session", "hns", t.Harness)
 	session, err := r.backend(t.Harness).Start(ctx, &agent.Options{
 		Container:     t.Container,
 		Dir:           r.containerDir(),
 		Model:         t.Model,
+		PlanOnly:      t.planMode(),
+		ReadOnly:      t.ReadOnly,
+		Thinking:      t.Thinking,
 		InitialPrompt: prompt,
+		Endpoint:      t.Endpoint,
+		Instructions:  t.Instructions,
 	}, msgCh, logW)
 	if err != nil {
 		_ = logW.Close()
@@ -1003,10 +1260,10 @@ func (r *Runner) RestartSession(ctx context.Context, t *Task, prompt agent.Promp
 	h := &SessionHandle{Session: session, MsgCh: msgCh, DispatchDone: dispatchDone, LogW: logW}
 	t.AttachSession(h)
 
-	t.addMessage(ctx, syntheticUserInput(prompt), false)
+	t.addMessage(ctx, syntheticUserInput(input), false)
 
 	t.SetState(StateRunning)
-	tlog.Info("session restarted")
+	tlog.InfoContext(ctx, "session restarted")
 	return h, nil
 }
 
@@ -1014,10 +1271,11 @@ func (r *Runner) RestartSession(ctx context.Context, t *Task, prompt agent.Promp
 // in the same container without a prompt. The task transitions to StateWaiting
 // so the user can send a new message when ready.
 func (r *Runner) ClearContextSession(ctx context.Context, t *Task) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
 	r.initDefaults()
 
 	state := t.GetState()
-	if state != StateWaiting && state != StateAsking && state != StateHasPlan {
+	if state != StateWaiting && state != StateAsking && state != StateHasPlan && state != StatePlanReview {
 		return nil, fmt.Errorf("cannot clear context in state %s", state)
 	}
 
@@ -1052,11 +1310,16 @@ func (r *Runner) ClearContextSession(ctx context.Context, t *Task) (*SessionHand
 		clearBranch = p.Branch
 	}
 	tlog := r.log.With("br", clearBranch, "ctr", t.Container)
-	tlog.Info("clearing context", "hns", t.Harness)
+	tlog.InfoContext(ctx, "clearing context", "hns", t.Harness)
 	session, err := r.backend(t.Harness).Start(ctx, &agent.Options{
-		Container: t.Container,
-		Dir:       r.containerDir(),
-		Model:     t.Model,
+		Container:    t.Container,
+		Dir:          r.containerDir(),
+		Model:        t.Model,
+		PlanOnly:     t.planMode(),
+		ReadOnly:     t.ReadOnly,
+		Thinking:     t.Thinking,
+		Endpoint:     t.Endpoint,
+		Instructions: t.Instructions,
 	}, msgCh, logW)
 	if err != nil {
 		_ = logW.Close()
@@ -1070,7 +1333,7 @@ func (r *Runner) ClearContextSession(ctx context.Context, t *Task) (*SessionHand
 	h := &SessionHandle{Session: session, MsgCh: msgCh, DispatchDone: dispatchDone, LogW: logW}
 	t.AttachSession(h)
 	t.SetState(StateWaiting)
-	tlog.Info("context cleared")
+	tlog.InfoContext(ctx, "context cleared")
 	return h, nil
 }
 
@@ -1125,21 +1388,38 @@ var mutatingTools = map[string]struct{}{
 	"NotebookEdit": {},
 }
 
+// committingTools lists the tools of harnesses that commit their own changes
+// (agent.Backend.CommitsChanges) after which the diff stat is refreshed.
+// Fetching after their edits would commit the changes before the harness
+// does.
+var committingTools = map[string]struct{}{
+	"Commit": {},
+}
+
 // startMessageDispatch starts a goroutine that reads from msgCh and dispatches
 // to t.addMessage. For ResultMessages, it fetches from the container first and
 // attaches the diff stat. For tool results following a mutating tool (Edit,
 // Bash, Write, NotebookEdit), it also fetches and emits a DiffStatMessage.
+// After each ResultMessage it checks whether the context window needs
+// compaction (see maybeAutoCompact).
 // When skipSideEffects is true, fetch+diff and title generation are suppressed
-// (used during adoption where these are handled once at the end).
+// (used during adoption where these are handled once at the end). Plan-only
+// and chat tasks have no diff to show so fetch+diff is skipped for them too.
 // Returns the message channel and a done channel that closes when the
 // goroutine exits (after msgCh is fully drained).
 func (r *Runner) startMessageDispatch(ctx context.Context, t *Task, skipSideEffects bool) (msgCh chan agent.Message, dispatchDone <-chan struct{}) {
+	ctx = logctx.With(ctx, "task", t.ID)
 	// Capture branch and extra repos outside the goroutine to avoid races.
 	primaryBranch := ""
 	if p := t.Primary(); p != nil {
 		primaryBranch = p.Branch
 	}
 	extraRepos := t.ExtraMDRepos()
+	fetchDiff := !skipSideEffects && !t.PlanOnly && !t.Chat && r.Container != nil && r.Dir != ""
+	mutating := mutatingTools
+	if b := r.backend(t.Harness); b != nil && b.CommitsChanges() {
+		mutating = committingTools
+	}
 	msgCh = make(chan agent.Message, 256)
 	done := make(chan struct{})
 	dispatchDone = done
@@ -1150,22 +1430,22 @@ func (r *Runner) startMessageDispatch(ctx context.Context, t *Task, skipSideEffe
 		for m := range msgCh {
 			switch msg := m.(type) {
 			case *agent.ToolUseMessage:
-				if _, ok := mutatingTools[msg.Name]; ok {
+				if _, ok := mutating[msg.Name]; ok {
 					pendingMutating[msg.ToolUseID] = struct{}{}
 				}
 			case *agent.ToolResultMessage:
-				if !skipSideEffects && r.Container != nil && r.Dir != "" {
+				if fetchDiff {
 					if _, ok := pendingMutating[msg.ToolUseID]; ok {
 						delete(pendingMutating, msg.ToolUseID)
 						r.fetchDiffStatBranch(ctx, t, primaryBranch, extraRepos)
 					}
 				}
 			case *agent.ResultMessage:
-				if !skipSideEffects && r.Container != nil && r.Dir != "" {
+				if fetchDiff {
 					fetchCtx, fetchCancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
 					r.branchMu.Lock()
 					if err := r.Container.Fetch(fetchCtx, append([]md.Repo{{GitRoot: r.Dir, Branch: primaryBranch}}, extraRepos...)); err != nil {
-						r.log.Warn("fetch on result failed", "br", primaryBranch, "err", err)
+						r.log.WarnContext(ctx, "fetch on result failed", "br", primaryBranch, "err", err)
 					}
 					msg.DiffStat = r.diffStat(fetchCtx, primaryBranch)
 					r.branchMu.Unlock()
@@ -1173,6 +1453,12 @@ func (r *Runner) startMessageDispatch(ctx context.Context, t *Task, skipSideEffe
 				}
 			}
 			t.addMessage(ctx, m, skipSideEffects)
+			if tu, ok := m.(*agent.ToolUseMessage); ok && !skipSideEffects && t.Policy != nil {
+				r.enforcePolicy(ctx, t, tu)
+			}
+			if rm, ok := m.(*agent.ResultMessage); ok && !skipSideEffects {
+				r.maybeAutoCompact(ctx, t, rm)
+			}
 		}
 	}()
 	return
@@ -1188,7 +1474,7 @@ func (r *Runner) fetchDiffStatBranch(ctx context.Context, t *Task, branch string
 	r.branchMu.Lock()
 	defer r.branchMu.Unlock()
 	if err := r.Container.Fetch(fetchCtx, append([]md.Repo{{GitRoot: r.Dir, Branch: branch}}, extraRepos...)); err != nil {
-		r.log.Warn("fetch on tool result failed", "br", branch, "err", err)
+		r.log.WarnContext(ctx, "fetch on tool result failed", "br", branch, "err", err)
 		return
 	}
 	ds := r.diffStat(fetchCtx, branch)
@@ -1201,8 +1487,31 @@ func (r *Runner) fetchDiffStatBranch(ctx context.Context, t *Task, branch string
 	}, false)
 }
 
+// RefreshDiffStat computes the diff stat inside the running container, without
+// a fetch, and emits a DiffStatMessage into the task's message stream when it
+// differs from the live one. Used for on-demand refreshes while the task runs.
+func (r *Runner) RefreshDiffStat(ctx context.Context, t *Task, branch string) (agent.DiffStat, error) {
+	r.initDefaults()
+	if r.Container == nil || r.Dir == "" {
+		return nil, errors.New("diff is not supported for no-repo tasks")
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	r.branchMu.Lock()
+	numstat, err := r.Container.Diff(ctx, md.Repo{GitRoot: r.Dir, Branch: branch}, diffStatArgs...)
+	r.branchMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	ds := ParseDiffRawNumstat(numstat)
+	if !slices.Equal(ds, t.LiveDiffStat()) {
+		t.addMessage(ctx, &agent.DiffStatMessage{MessageType: "caic_diff_stat", DiffStat: ds}, false)
+	}
+	return ds, nil
+}
+
 // BranchDiffStat fetches from the container and returns the host-side branch
-// diff stat (md diff --numstat). Unlike the relay's diff_watcher which only
+// diff stat (md diff --raw --numstat). Unlike the relay's diff_watcher which only
 // tracks uncommitted changes, this captures the full branch diff relative to
 // the base. Used by adoptOne to restore the diff stat after server restart.
 func (r *Runner) BranchDiffStat(ctx context.Context, branch string, extraRepos []md.Repo) agent.DiffStat {
@@ -1215,23 +1524,55 @@ func (r *Runner) BranchDiffStat(ctx context.Context, branch string, extraRepos [
 	r.branchMu.Lock()
 	defer r.branchMu.Unlock()
 	if err := r.Container.Fetch(fetchCtx, append([]md.Repo{{GitRoot: r.Dir, Branch: branch}}, extraRepos...)); err != nil {
-		r.log.Warn("fetch for branch diff stat failed", "br", branch, "err", err)
+		r.log.WarnContext(ctx, "fetch for branch diff stat failed", "br", branch, "err", err)
 		return nil
 	}
 	return r.diffStat(fetchCtx, branch)
 }
 
-// diffStat runs Diff("--numstat") and parses the output. Returns nil for no-repo runners.
+// diffStat runs Diff(diffStatArgs...) and parses the output. Returns nil for no-repo runners.
 func (r *Runner) diffStat(ctx context.Context, branch string) agent.DiffStat {
 	if r.Dir == "" {
 		return nil
 	}
-	numstat, err := r.Container.Diff(ctx, md.Repo{GitRoot: r.Dir, Branch: branch}, "--numstat")
+	numstat, err := r.Container.Diff(ctx, md.Repo{GitRoot: r.Dir, Branch: branch}, diffStatArgs...)
 	if err != nil {
-		r.log.Warn("diff numstat failed", "br", branch, "err", err)
+		r.log.WarnContext(ctx, "diff numstat failed", "br", branch, "err", err)
 		return nil
 	}
-	return ParseDiffNumstat(numstat)
+	return ParseDiffRawNumstat(numstat)
+}
+
+// refDiffStat computes the diff stat of an already fetched container ref on
+// the host, for repos the container backend cannot diff on their own.
+func (r *Runner) refDiffStat(ctx context.Context, container, branch string) agent.DiffStat {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	args := append(append([]string{"diff"}, diffStatArgs...), "origin/"+r.BaseBranch+"...refs/remotes/"+container+"/"+branch)
+	cmd := exec.CommandContext(ctx, "git", args...) //nolint:gosec // branch names are from internal git state.
+	cmd.Dir = r.Dir
+	out, err := cmd.Output()
+	if err != nil {
+		r.log.WarnContext(ctx, "diff numstat failed", "br", branch, "err", err)
+		return nil
+	}
+	return ParseDiffRawNumstat(string(out))
+}
+
+// Verify runs a verification script in the task's primary repo inside its
+// container and returns the combined output. A non-zero exit is an error.
+func (r *Runner) Verify(ctx context.Context, t *Task, script string) (string, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
+	r.initDefaults()
+	if r.Container == nil || t.Container == "" {
+		return "", errors.New("task has no container")
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), verifyTimeout)
+	defer cancel()
+	start := time.Now()
+	out, err := r.Container.Exec(ctx, t.Container, r.containerDir(), script)
+	t.recordVerification(start, time.Now(), err)
+	return out, err
 }
 
 // openLog creates a JSONL log file in LogDir and writes a metadata header as
@@ -1240,40 +1581,73 @@ func (r *Runner) openLog(t *Task) (io.WriteCloser, error) {
 	if err := os.MkdirAll(r.LogDir, 0o750); err != nil {
 		return nil, fmt.Errorf("create log dir: %w", err)
 	}
+	f, err := os.OpenFile(filepath.Join(r.LogDir, logName(t)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec // name is derived from ksid, not arbitrary user input.
+	if err != nil {
+		return nil, fmt.Errorf("create log file: %w", err)
+	}
+	// Write metadata header as the first line.
+	if data, err := json.Marshal(logMeta(t)); err == nil {
+		_, _ = f.Write(append(data, '\n'))
+	}
+	return f, nil
+}
+
+// logName returns the name of the task's log file in the log directory.
+func logName(t *Task) string {
 	safeRepo := ""
 	safeBranch := ""
 	if p := t.Primary(); p != nil {
 		safeRepo = strings.ReplaceAll(p.Name, "/", "-")
 		safeBranch = strings.ReplaceAll(p.Branch, "/", "-")
 	}
-	name := t.ID.String() + "-" + safeRepo + "-" + safeBranch + ".jsonl"
-	f, err := os.OpenFile(filepath.Join(r.LogDir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec // name is derived from ksid, not arbitrary user input.
-	if err != nil {
-		return nil, fmt.Errorf("create log file: %w", err)
-	}
-	// Write metadata header as the first line.
+	return t.ID.String() + "-" + safeRepo + "-" + safeBranch + ".jsonl"
+}
+
+// logMeta returns the metadata header of the task's log.
+func logMeta(t *Task) *agent.MetaMessage {
 	metaRepos := make([]agent.MetaRepo, len(t.Repos))
 	for i, r := range t.Repos {
-		metaRepos[i] = agent.MetaRepo{Name: r.Name, BaseBranch: r.BaseBranch, Branch: r.Branch}
+		metaRepos[i] = agent.MetaRepo{Name: r.Name, BaseBranch: r.BaseBranch, Branch: r.Branch, PushRemote: r.PushRemote}
 	}
 	meta := agent.MetaMessage{
-		MessageType: "caic_meta",
-		Version:     1,
-		Prompt:      t.InitialPrompt.Text,
-		Title:       t.Title(),
-		Repos:       metaRepos,
-		Harness:     t.Harness,
-		Model:       t.Model,
-		StartedAt:   t.StartedAt,
-		ForgeIssue:  t.ForgeIssue,
-		Tailscale:   t.Tailscale,
-		USB:         t.USB,
-		Display:     t.Display,
-	}
-	if data, err := json.Marshal(meta); err == nil {
-		_, _ = f.Write(append(data, '\n'))
-	}
-	return f, nil
+		MessageType:  "caic_meta",
+		Version:      1,
+		TaskID:       t.ID.String(),
+		Alias:        t.Alias,
+		Prompt:       t.InitialPrompt.Text,
+		Title:        t.Title(),
+		Repos:        metaRepos,
+		Harness:      t.Harness,
+		Model:        t.Model,
+		StartedAt:    t.StartedAt,
+		ForgeIssue:   t.ForgeIssue,
+		Tailscale:    t.Tailscale,
+		USB:          t.USB,
+		Display:      t.Display,
+		PlanOnly:     t.PlanOnly,
+		RequirePlan:  t.RequirePlan,
+		ReadOnly:     t.ReadOnly,
+		Chat:         t.Chat,
+		Thinking:     t.Thinking,
+		Network:      string(t.Network),
+		NetworkAllow: t.NetworkAllow,
+		PushPolicy:   string(t.Push),
+		Env:          t.Env,
+		Instructions: t.Instructions,
+	}
+	if t.Idle != nil {
+		meta.IdleAction, meta.IdleHours = string(t.Idle.Action), t.Idle.Hours
+	}
+	if t.Issue != nil {
+		meta.IssueTracker, meta.IssueKey, meta.IssueURL = t.Issue.Tracker, t.Issue.Key, t.Issue.URL
+	}
+	if t.Slack != nil {
+		meta.SlackChannel, meta.SlackThreadTS = t.Slack.Channel, t.Slack.TS
+	}
+	if f := t.Snapshot().Failover; f != nil {
+		meta.FailoverHarness, meta.FailoverModel, meta.FailoverError = f.FromHarness, f.FromModel, f.Err
+	}
+	return &meta
 }
 
 // reopenLog opens an existing log file for appending without writing a new
@@ -1283,14 +1657,7 @@ func (r *Runner) reopenLog(t *Task) (io.WriteCloser, error) {
 	if r.LogDir == "" {
 		return nil, errors.New("no log dir")
 	}
-	safeRepo := ""
-	safeBranch := ""
-	if p := t.Primary(); p != nil {
-		safeRepo = strings.ReplaceAll(p.Name, "/", "-")
-		safeBranch = strings.ReplaceAll(p.Branch, "/", "-")
-	}
-	name := t.ID.String() + "-" + safeRepo + "-" + safeBranch + ".jsonl"
-	return os.OpenFile(filepath.Join(r.LogDir, name), os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec // name is derived from ksid, not arbitrary user input.
+	return os.OpenFile(filepath.Join(r.LogDir, logName(t)), os.O_WRONLY|os.O_APPEND, 0o600) //nolint:gosec // name is derived from ksid, not arbitrary user input.
 }
 
 // writeLogTrailer appends a MetaResultMessage to the log file.
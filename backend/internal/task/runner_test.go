@@ -8,13 +8,17 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	"github.com/caic-xyz/caic/backend/internal/agent/claudecode"
+	"github.com/caic-xyz/caic/backend/internal/policy"
 	"github.com/caic-xyz/md"
+	"github.com/caic-xyz/md/gitutil"
 	"github.com/maruel/ksid"
 )
 
@@ -50,6 +54,8 @@ func (b *testBackend) NewParser() func([]byte) ([]agent.Message, error) {
 	return claudecode.New().NewParser()
 }
 
+func (b *testBackend) Binary() string { return "cat" }
+
 func (b *testBackend) Models() []string { return []string{"test-model"} }
 
 // SupportsImages always returns false in the test backend.
@@ -57,6 +63,14 @@ func (b *testBackend) SupportsImages() bool { return false }
 
 func (b *testBackend) SupportsCompact() bool { return false }
 
+func (b *testBackend) SupportsInterrupt() bool { return false }
+
+func (b *testBackend) SupportsPlanOnly() bool { return false }
+
+func (b *testBackend) SupportsThinking() bool { return false }
+
+func (b *testBackend) CommitsChanges() bool { return false }
+
 func (b *testBackend) ContextWindowLimit(string) int { return 180_000 }
 
 // testWire implements agent.WireFormat for testing.
@@ -196,7 +210,7 @@ func TestRunner(t *testing.T) {
 				Harness:       agent.Claude,
 			}
 
-			if _, err := r.setup(t.Context(), tk, nil); err != nil {
+			if _, err := r.setup(t.Context(), tk); err != nil {
 				t.Fatal(err)
 			}
 
@@ -239,7 +253,7 @@ func TestRunner(t *testing.T) {
 				Harness:       agent.Claude,
 			}
 
-			if _, err := r.setup(t.Context(), tk, nil); err != nil {
+			if _, err := r.setup(t.Context(), tk); err != nil {
 				t.Fatal(err)
 			}
 
@@ -252,6 +266,97 @@ func TestRunner(t *testing.T) {
 				t.Errorf("local.txt content = %q, want %q", string(out), "local\n")
 			}
 		})
+		t.Run("ContinueBranch", func(t *testing.T) {
+			// A preset branch is reset to its pushed head, not allocated.
+			clone := initTestRepo(t, "main")
+			runGit(t, clone, "checkout", "-b", "pr-head")
+			if err := os.WriteFile(filepath.Join(clone, "review.txt"), []byte("fix\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			runGit(t, clone, "add", ".")
+			runGit(t, clone, "commit", "-m", "pushed by another task")
+			runGit(t, clone, "push", "origin", "pr-head")
+			runGit(t, clone, "reset", "--hard", "HEAD~1")
+			runGit(t, clone, "checkout", "main")
+
+			r := &Runner{BaseBranch: "main", Dir: clone, LogDir: t.TempDir(), Container: &stubContainer{}}
+			r.initDefaults()
+			tk := &Task{
+				ID:            ksid.NewID(),
+				InitialPrompt: agent.Prompt{Text: "address the review"},
+				Repos:         []RepoMount{{Name: "org/repo", BaseBranch: "pr-head", Branch: "pr-head"}},
+				Harness:       agent.Claude,
+			}
+			if _, err := r.setup(t.Context(), tk); err != nil {
+				t.Fatal(err)
+			}
+			if tk.Repos[0].Branch != "pr-head" || r.nextID != 0 {
+				t.Errorf("branch = %q, nextID = %d; want pr-head, 0", tk.Repos[0].Branch, r.nextID)
+			}
+			runGit(t, clone, "cat-file", "-e", "pr-head:review.txt")
+		})
+		t.Run("Chat", func(t *testing.T) {
+			// Chat tasks get a ref outside refs/heads, no caic-N branch, and
+			// Cleanup removes the ref.
+			clone := initTestRepo(t, "main")
+			r := &Runner{
+				BaseBranch: "main",
+				Dir:        clone,
+				LogDir:     t.TempDir(),
+				Container:  &stubContainer{},
+			}
+			r.initDefaults()
+
+			tk := &Task{
+				ID:            ksid.NewID(),
+				InitialPrompt: agent.Prompt{Text: "where is auth handled?"},
+				Repos:         []RepoMount{{Name: "org/repo"}},
+				Harness:       agent.Claude,
+				Chat:          true,
+			}
+			if _, err := r.setup(t.Context(), tk); err != nil {
+				t.Fatal(err)
+			}
+			ref := tk.Repos[0].Branch
+			if !strings.HasPrefix(ref, "caic-chat-") {
+				t.Fatalf("branch = %q, want a caic-chat- ref", ref)
+			}
+			if r.nextID != 0 {
+				t.Errorf("nextID = %d, want 0: chat tasks must not consume branch numbers", r.nextID)
+			}
+			if out, err := exec.Command("git", "-C", clone, "branch", "--list", "caic-*").Output(); err != nil || len(out) != 0 { //nolint:gosec // controlled test args
+				t.Errorf("git branch = %q, %v; want no task branch", out, err)
+			}
+			runGit(t, clone, "rev-parse", "--verify", ref)
+
+			r.Cleanup(t.Context(), tk, StatePurged)
+			if err := exec.Command("git", "-C", clone, "rev-parse", "--verify", "refs/"+ref).Run(); err == nil { //nolint:gosec // controlled test args
+				t.Errorf("ref %s still exists after cleanup", ref)
+			}
+		})
+		t.Run("ImagePull", func(t *testing.T) {
+			// A missing base image is pulled in the image_pull sub-state,
+			// which ends with the pull.
+			clone := initTestRepo(t, "main")
+			tk := &Task{
+				ID:            ksid.NewID(),
+				InitialPrompt: agent.Prompt{Text: "test"},
+				Repos:         []RepoMount{{Name: "org/repo"}},
+				Harness:       agent.Claude,
+			}
+			stub := &stubContainer{missingImage: true, pullTask: tk}
+			r := &Runner{BaseBranch: "main", Dir: clone, LogDir: t.TempDir(), Container: stub}
+			r.initDefaults()
+			if _, err := r.setup(t.Context(), tk); err != nil {
+				t.Fatal(err)
+			}
+			if stub.pullSubState != SubStateImagePull {
+				t.Errorf("sub-state during the pull = %q, want %q", stub.pullSubState, SubStateImagePull)
+			}
+			if snap := tk.Snapshot(); snap.State != StateProvisioning || snap.SubState != SubStateNone {
+				t.Errorf("after setup: state = %v, sub-state = %q", snap.State, snap.SubState)
+			}
+		})
 	})
 
 	t.Run("Cleanup", func(t *testing.T) {
@@ -578,6 +683,47 @@ func TestRunner(t *testing.T) {
 			}
 		})
 
+		t.Run("PolicyViolation", func(t *testing.T) {
+			stub := &stubContainer{}
+			violated := make(chan *policy.Violation, 1)
+			r := &Runner{Container: stub, OnPolicyViolation: func(_ *Task, v *policy.Violation) { violated <- v }}
+			r.initDefaults()
+			p, err := r.Policy([]policy.Rule{{Name: "no-network", Kind: policy.KindNetwork}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tk := &Task{InitialPrompt: agent.Prompt{Text: "test"}, Container: "md-caic-1", Policy: p}
+			tk.SetState(StateRunning)
+			_, ch, unsub := tk.Subscribe(t.Context())
+			defer unsub()
+
+			msgCh, done := r.startMessageDispatch(t.Context(), tk, false)
+			msgCh <- &agent.ToolUseMessage{ToolUseID: "t1", Name: "Bash", Input: json.RawMessage(`{"command":"ls"}`)}
+			recvMsg(t, ch)
+			msgCh <- &agent.ToolUseMessage{ToolUseID: "t2", Name: "Bash", Input: json.RawMessage(`{"command":"curl https://example.com"}`)}
+			recvMsg(t, ch)
+			if sm, ok := recvMsg(t, ch).(*agent.SystemMessage); !ok || sm.Subtype != "caic_policy_violation" {
+				t.Errorf("got %+v, want a caic_policy_violation message", sm)
+			}
+			if v := <-violated; v.Detail != "network access to example.com" {
+				t.Errorf("violation = %+v", v)
+			}
+			close(msgCh)
+			<-done
+			if !stub.paused || tk.Snapshot().PolicyViolation == nil {
+				t.Fatal("task not paused")
+			}
+			if _, err := r.ResolvePolicyViolation(t.Context(), tk); err != nil {
+				t.Fatal(err)
+			}
+			if stub.paused || tk.Snapshot().PolicyViolation != nil {
+				t.Error("task still paused")
+			}
+			if _, err := r.ResolvePolicyViolation(t.Context(), tk); err == nil {
+				t.Error("expected error when not paused")
+			}
+		})
+
 		t.Run("DispatchDrainBeforeClose", func(t *testing.T) {
 			r := &Runner{}
 			r.initDefaults()
@@ -746,6 +892,98 @@ func TestRunner(t *testing.T) {
 		}
 	})
 
+	t.Run("AutoCompact", func(t *testing.T) {
+		newTask := func() *Task {
+			tk := &Task{
+				ID:            ksid.NewID(),
+				InitialPrompt: agent.Prompt{Text: "test"},
+				Repos:         []RepoMount{{Name: "org/repo", Branch: "caic-0"}},
+				Harness:       "test",
+				Container:     "fake-container",
+			}
+			tk.SetState(StateWaiting)
+			return tk
+		}
+		hasAutoCompact := func(tk *Task) bool {
+			for _, m := range tk.Messages() {
+				if sm, ok := m.(*agent.SystemMessage); ok && sm.Subtype == "caic_auto_compact" {
+					return true
+				}
+			}
+			return false
+		}
+		t.Run("BelowThreshold", func(t *testing.T) {
+			r := &Runner{LogDir: t.TempDir(), Backends: map[agent.Harness]agent.Backend{"test": &testBackend{}}}
+			r.initDefaults()
+			tk := newTask()
+			tk.addMessage(t.Context(), &agent.UsageMessage{Usage: agent.Usage{InputTokens: 1000}}, true)
+			r.maybeAutoCompact(t.Context(), tk, &agent.ResultMessage{MessageType: "result"})
+			if hasAutoCompact(tk) {
+				t.Error("unexpected auto compact below threshold")
+			}
+		})
+		t.Run("Disabled", func(t *testing.T) {
+			r := &Runner{LogDir: t.TempDir(), Backends: map[agent.Harness]agent.Backend{"test": &testBackend{}}, AutoCompactThreshold: -1}
+			r.initDefaults()
+			tk := newTask()
+			tk.addMessage(t.Context(), &agent.UsageMessage{Usage: agent.Usage{InputTokens: 179_000}}, true)
+			r.maybeAutoCompact(t.Context(), tk, &agent.ResultMessage{MessageType: "result"})
+			if hasAutoCompact(tk) {
+				t.Error("unexpected auto compact when disabled")
+			}
+		})
+		t.Run("RestartWithSummary", func(t *testing.T) {
+			restarted := make(chan *SessionHandle, 1)
+			r := &Runner{
+				LogDir:             t.TempDir(),
+				Backends:           map[agent.Harness]agent.Backend{"test": &testBackend{}},
+				OnSessionRestarted: func(_ *Task, h *SessionHandle) { restarted <- h },
+			}
+			tk := newTask()
+			h1, err := r.RestartSession(t.Context(), tk, agent.Prompt{Text: "work"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			h1.MsgCh <- &agent.UsageMessage{Usage: agent.Usage{InputTokens: 100_000, CacheReadInputTokens: 60_000}}
+			h1.MsgCh <- &agent.ResultMessage{MessageType: "result", Result: "done"}
+
+			// The runner asks for a summary; the summary result triggers the restart.
+			deadline := time.Now().Add(2 * time.Second)
+			for !hasAutoCompact(tk) || tk.GetState() != StateRunning {
+				if time.Now().After(deadline) {
+					t.Fatalf("no summary request; state = %s", tk.GetState())
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			h1.MsgCh <- &agent.ResultMessage{MessageType: "result", Result: "the summary"}
+			var h2 *SessionHandle
+			select {
+			case h2 = <-restarted:
+			case <-time.After(2 * time.Second):
+				t.Fatal("session was not restarted")
+			}
+			defer tk.CloseAndDetachSession()
+			if h2 == h1 {
+				t.Error("expected a new session handle")
+			}
+			found := false
+			for _, m := range tk.Messages() {
+				if u, ok := m.(*agent.UserInputMessage); ok && strings.Contains(u.Text, "the summary") {
+					found = true
+				}
+			}
+			if !found {
+				t.Error("restarted session was not prompted with the summary")
+			}
+			if !hasAutoCompact(tk) {
+				t.Error("missing auto compact marker after restart")
+			}
+			if used, _ := tk.contextFill(0); used != 0 {
+				t.Errorf("context fill = %d after restart, want 0", used)
+			}
+		})
+	})
+
 	t.Run("BranchDiffStat", func(t *testing.T) {
 		sc := &stubContainer{}
 		r := &Runner{Container: sc, Dir: "/repo"}
@@ -757,6 +995,436 @@ func TestRunner(t *testing.T) {
 			t.Errorf("BranchDiffStat = %+v, want [{main.go +5 -1}]", ds)
 		}
 	})
+	t.Run("RefreshDiffStat", func(t *testing.T) {
+		r := &Runner{Container: &stubContainer{}, Dir: "/repo"}
+		tk := &Task{InitialPrompt: agent.Prompt{Text: "test"}}
+		ds, err := r.RefreshDiffStat(t.Context(), tk, "feature")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ds) != 1 || ds[0].Path != "main.go" || ds[0].Added != 5 || ds[0].Deleted != 1 {
+			t.Errorf("RefreshDiffStat = %+v, want [{main.go +5 -1}]", ds)
+		}
+		if live := tk.LiveDiffStat(); !slices.Equal(live, ds) {
+			t.Errorf("LiveDiffStat = %+v, want %+v", live, ds)
+		}
+		// An unchanged diff doesn't emit another message.
+		n := len(tk.Messages())
+		if _, err := r.RefreshDiffStat(t.Context(), tk, "feature"); err != nil {
+			t.Fatal(err)
+		}
+		if got := len(tk.Messages()); got != n {
+			t.Errorf("messages = %d, want %d", got, n)
+		}
+		if _, err := (&Runner{}).RefreshDiffStat(t.Context(), tk, "feature"); err == nil {
+			t.Error("expected error for no-repo runner")
+		}
+	})
+	t.Run("Commits", func(t *testing.T) {
+		clone := initTestRepo(t, "main")
+		runGit(t, clone, "checkout", "-b", "caic-1")
+		for i, name := range []string{"a.go", "b.go"} {
+			if err := os.WriteFile(filepath.Join(clone, name), []byte("package a\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			runGit(t, clone, "add", ".")
+			runGit(t, clone, "commit", "-m", "step "+strconv.Itoa(i+1)+"\n\nDetails.")
+		}
+		runGit(t, clone, "update-ref", "refs/remotes/md-api-caic-1/caic-1", "caic-1")
+		runGit(t, clone, "checkout", "main")
+
+		sc := &stubContainer{fetchErr: errors.New("container stopped")}
+		r := &Runner{BaseBranch: "main", Dir: clone, Container: sc}
+		commits, err := r.Commits(t.Context(), "caic-1", "", "md-api-caic-1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sc.fetched {
+			t.Error("Commits did not call Fetch")
+		}
+		if len(commits) != 2 {
+			t.Fatalf("commits = %+v, want 2", commits)
+		}
+		c := commits[0]
+		if c.Message != "step 2\n\nDetails." || len(c.SHA) != 40 || c.Authored.IsZero() || c.Committed.IsZero() {
+			t.Errorf("commit = %+v", c)
+		}
+		if len(c.Files) != 1 || c.Files[0].Path != "b.go" || c.Files[0].Added != 1 {
+			t.Errorf("files = %+v, want [{b.go +1}]", c.Files)
+		}
+		if commits[1].Message != "step 1\n\nDetails." {
+			t.Errorf("commits not newest first: %+v", commits)
+		}
+	})
+	t.Run("RevertCommit", func(t *testing.T) {
+		clone := initTestRepo(t, "main")
+		runGit(t, clone, "branch", "base")
+		runGit(t, clone, "checkout", "-b", "caic-1")
+		var shas []string
+		for i, content := range []string{"a", "b", "c"} {
+			name := "a.go"
+			if i > 0 {
+				name = "b.go"
+			}
+			if err := os.WriteFile(filepath.Join(clone, name), []byte(content+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			runGit(t, clone, "add", ".")
+			runGit(t, clone, "commit", "-m", "step "+strconv.Itoa(i+1))
+			out, err := exec.Command("git", "-C", clone, "rev-parse", "HEAD").Output()
+			if err != nil {
+				t.Fatal(err)
+			}
+			shas = append(shas, strings.TrimSpace(string(out)))
+		}
+		r := &Runner{BaseBranch: "main", Dir: clone, Container: &execContainer{dir: clone}}
+		tk := &Task{InitialPrompt: agent.Prompt{Text: "test"}, Container: "md-caic-1"}
+
+		revert, subject, err := r.RevertCommit(t.Context(), tk, shas[0][:12])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revert) != 40 || subject != "step 1" {
+			t.Errorf("revert = %q, subject = %q", revert, subject)
+		}
+		if _, err := os.Stat(filepath.Join(clone, "a.go")); !os.IsNotExist(err) {
+			t.Errorf("a.go not reverted: %v", err)
+		}
+		// b.go was modified after step 2 was committed.
+		if _, _, err := r.RevertCommit(t.Context(), tk, shas[1]); err == nil || !strings.Contains(err.Error(), "conflicts") {
+			t.Errorf("err = %v, want conflict", err)
+		}
+		if out, err := exec.Command("git", "-C", clone, "status", "--porcelain").Output(); err != nil || len(out) != 0 {
+			t.Errorf("tree not clean after aborted revert: %q %v", out, err)
+		}
+		base, err := exec.Command("git", "-C", clone, "rev-parse", "main").Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := r.RevertCommit(t.Context(), tk, strings.TrimSpace(string(base))); err == nil || !strings.Contains(err.Error(), "base branch") {
+			t.Errorf("err = %v, want base branch refusal", err)
+		}
+		if _, _, err := r.RevertCommit(t.Context(), tk, "HEAD; rm -rf /"); err == nil {
+			t.Error("expected error for invalid sha")
+		}
+	})
+	t.Run("RestrictNetwork", func(t *testing.T) {
+		stub := &stubContainer{}
+		r := &Runner{Container: stub}
+		r.initDefaults()
+		tk := &Task{Harness: agent.Codex, NetworkAllow: []string{"pypi.org"}}
+		if err := r.restrictNetwork(t.Context(), tk, "md-caic-1"); err != nil || stub.allowedHosts != nil {
+			t.Fatalf("full network restricted: %v %q", err, stub.allowedHosts)
+		}
+		tk.Network = NetworkNone
+		if err := r.restrictNetwork(t.Context(), tk, "md-caic-1"); err != nil {
+			t.Fatal(err)
+		}
+		if want := harnessHosts[agent.Codex]; !slices.Equal(stub.allowedHosts, want) {
+			t.Errorf("hosts = %q, want %q", stub.allowedHosts, want)
+		}
+		tk.Network = NetworkAllowlist
+		if err := r.restrictNetwork(t.Context(), tk, "md-caic-1"); err != nil {
+			t.Fatal(err)
+		}
+		if got := stub.allowedHosts; len(got) == 0 || got[len(got)-1] != "pypi.org" {
+			t.Errorf("hosts = %q, want the allowlist last", got)
+		}
+	})
+	t.Run("RepoInstructions", func(t *testing.T) {
+		clone := initTestRepo(t, "main")
+		r := &Runner{BaseBranch: "main", Dir: clone}
+		if got := r.RepoInstructions(t.Context(), ""); got != "" {
+			t.Errorf("without file: %q", got)
+		}
+		if err := os.MkdirAll(filepath.Join(clone, ".caic"), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(clone, InstructionsPath), []byte("Run make lint.\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, clone, "add", ".")
+		runGit(t, clone, "commit", "-m", "instructions")
+		// Only committed locally: origin/main doesn't have it yet.
+		if got := r.RepoInstructions(t.Context(), "main"); got != "Run make lint.\n" {
+			t.Errorf("local branch: %q", got)
+		}
+		if got := r.RepoInstructions(t.Context(), "nope"); got != "" {
+			t.Errorf("unknown branch: %q", got)
+		}
+	})
+	t.Run("InstallDeployKeys", func(t *testing.T) {
+		clone := initTestRepo(t, "main")
+		t.Setenv("HOME", t.TempDir())
+		runGit(t, clone, "remote", "set-url", "origin", "https://github.com/org/repo.git")
+		keyFile := filepath.Join(t.TempDir(), "key")
+		if err := os.WriteFile(keyFile, []byte("PRIVATE\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		r := &Runner{BaseBranch: "main", Dir: clone, Container: &execContainer{dir: clone}}
+		r.initDefaults()
+		tk := &Task{Repos: []RepoMount{{Name: "org/repo", GitRoot: clone, DeployKey: keyFile}}}
+		if err := r.installDeployKeys(t.Context(), tk, "md-caic-1"); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".ssh", "caic_deploy_key_0")); err != nil || string(got) != "PRIVATE\n" {
+			t.Errorf("key = %q, %v", got, err)
+		}
+		out, err := exec.Command("git", "-C", clone, "config", "core.sshCommand").Output()
+		if want := DeployKeySSHCommand("~/.ssh/caic_deploy_key_0"); err != nil || strings.TrimSpace(string(out)) != want {
+			t.Errorf("core.sshCommand = %q, %v; want %q", out, err, want)
+		}
+		out, err = exec.Command("git", "-C", clone, "remote", "get-url", "--push", "origin").Output()
+		if err != nil || strings.TrimSpace(string(out)) != "git@github.com:org/repo.git" {
+			t.Errorf("push URL = %q, %v", out, err)
+		}
+	})
+	t.Run("Snapshots", func(t *testing.T) {
+		clone := initTestRepo(t, "main")
+		write := func(name, content string) {
+			t.Helper()
+			if err := os.WriteFile(filepath.Join(clone, name), []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r := &Runner{BaseBranch: "main", Dir: clone, Container: &execContainer{dir: clone}}
+		tk := &Task{InitialPrompt: agent.Prompt{Text: "test"}, Container: "md-caic-1"}
+		write("README.md", "edited\n")
+		write("notes.txt", "untracked\n")
+		snap, err := r.CreateSnapshot(t.Context(), tk, "before-refactor")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snap.Name != "before-refactor" || len(snap.Commit) != 40 || snap.Created.IsZero() {
+			t.Errorf("snapshot = %+v", snap)
+		}
+		if out, err := exec.Command("git", "-C", clone, "status", "--porcelain").Output(); err != nil || string(out) != " M README.md\n?? notes.txt\n" {
+			t.Errorf("snapshot changed the index: %q %v", out, err)
+		}
+		if _, err := r.CreateSnapshot(t.Context(), tk, "before-refactor"); err == nil || !strings.Contains(err.Error(), "already exists") {
+			t.Errorf("err = %v, want already exists", err)
+		}
+		if _, err := r.CreateSnapshot(t.Context(), tk, "../x"); err == nil {
+			t.Error("expected error for invalid name")
+		}
+
+		// The experiment commits, deletes and adds files.
+		runGit(t, clone, "add", ".")
+		runGit(t, clone, "commit", "-m", "experiment")
+		write("extra.go", "package x\n")
+		if err := os.Remove(filepath.Join(clone, "notes.txt")); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.RestoreSnapshot(t.Context(), tk, "before-refactor"); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command("git", "-C", clone, "status", "--porcelain").Output(); err != nil || string(out) != " M README.md\n?? notes.txt\n" {
+			t.Errorf("status after restore = %q %v", out, err)
+		}
+		if out, err := exec.Command("git", "-C", clone, "log", "-1", "--format=%s").Output(); err != nil || string(out) != "init\n" {
+			t.Errorf("HEAD after restore = %q %v", out, err)
+		}
+		if err := r.RestoreSnapshot(t.Context(), tk, "nope"); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("err = %v, want not found", err)
+		}
+		snaps, err := r.WorkspaceSnapshots(t.Context(), tk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snaps) != 1 || snaps[0] != *snap {
+			t.Errorf("snapshots = %+v, want [%+v]", snaps, *snap)
+		}
+	})
+	t.Run("Workspace", func(t *testing.T) {
+		// The host fetches the workspace from the container, then restores it
+		// into a fresh container cloned from the host.
+		host := initTestRepo(t, "main")
+		ctr := filepath.Join(t.TempDir(), "ctr")
+		runGit(t, "", "clone", "-q", host, ctr)
+		runGit(t, ctr, "config", "user.name", "Test")
+		runGit(t, ctr, "config", "user.email", "test@test.com")
+		runGit(t, ctr, "checkout", "-b", "caic-1")
+		runGit(t, host, "remote", "add", "md-caic-1", ctr)
+		write := func(name, content string) {
+			t.Helper()
+			if err := os.WriteFile(filepath.Join(ctr, name), []byte(content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		write("main.go", "package main\n")
+		runGit(t, ctr, "add", ".")
+		runGit(t, ctr, "commit", "-q", "-m", "work")
+		write("README.md", "edited\n")
+		write("notes.txt", "untracked\n")
+
+		r := &Runner{BaseBranch: "main", Dir: host, Container: &execContainer{dir: ctr}}
+		tk := &Task{ID: ksid.NewID(), Container: "md-caic-1", Repos: []RepoMount{{Name: "r", Branch: "caic-1"}}}
+		if r.HasSavedWorkspace(t.Context(), tk) {
+			t.Fatal("workspace saved before SaveWorkspace")
+		}
+		commit, err := r.SaveWorkspace(t.Context(), tk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(commit) != 40 || !r.HasSavedWorkspace(t.Context(), tk) {
+			t.Fatalf("commit = %q, saved = %t", commit, r.HasSavedWorkspace(t.Context(), tk))
+		}
+		if out, err := exec.Command("git", "-C", ctr, "status", "--porcelain").Output(); err != nil || string(out) != " M README.md\n?? notes.txt\n" {
+			t.Errorf("save changed the container's index: %q %v", out, err)
+		}
+
+		ctr2 := filepath.Join(t.TempDir(), "ctr2")
+		runGit(t, "", "clone", "-q", host, ctr2)
+		runGit(t, host, "remote", "add", "md-caic-2", ctr2)
+		r.Container = &execContainer{dir: ctr2}
+		tk.Container = "md-caic-2"
+		if err := r.restoreWorkspace(t.Context(), tk); err != nil {
+			t.Fatal(err)
+		}
+		if out, err := exec.Command("git", "-C", ctr2, "status", "--porcelain").Output(); err != nil || string(out) != " M README.md\n?? notes.txt\n" {
+			t.Errorf("status after restore = %q %v", out, err)
+		}
+		if out, err := exec.Command("git", "-C", ctr2, "log", "-1", "--format=%s").Output(); err != nil || string(out) != "work\n" {
+			t.Errorf("HEAD after restore = %q %v", out, err)
+		}
+
+		if err := r.DeleteSavedWorkspace(t.Context(), tk); err != nil {
+			t.Fatal(err)
+		}
+		if r.HasSavedWorkspace(t.Context(), tk) {
+			t.Error("workspace still saved after delete")
+		}
+		if _, err := r.RehydrateTask(t.Context(), tk); err == nil {
+			t.Error("rehydrate without a saved workspace succeeded")
+		}
+	})
+	t.Run("SyncFetchedToOrigin", func(t *testing.T) {
+		// An extra repo of a multi-repo task pushes the ref fetched by the
+		// primary's sync without fetching again.
+		clone := initTestRepo(t, "main")
+		runGit(t, clone, "checkout", "-b", "caic-1")
+		if err := os.WriteFile(filepath.Join(clone, "client.go"), []byte("package client\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, clone, "add", ".")
+		runGit(t, clone, "commit", "-m", "client change")
+		runGit(t, clone, "update-ref", "refs/remotes/md-api-caic-1/caic-1", "caic-1")
+		runGit(t, clone, "checkout", "main")
+
+		sc := &stubContainer{}
+		j, err := OpenJournal(filepath.Join(t.TempDir(), "journal.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = j.Close() }()
+		r := &Runner{BaseBranch: "main", Dir: clone, Container: sc, Journal: j}
+		ds, issues, err := r.SyncFetchedToOrigin(t.Context(), "caic-1", "", "md-api-caic-1", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sc.fetched {
+			t.Error("SyncFetchedToOrigin must not fetch")
+		}
+		if len(issues) != 0 {
+			t.Errorf("issues = %+v, want none", issues)
+		}
+		if len(ds) != 1 || ds[0].Path != "client.go" || ds[0].Added != 1 {
+			t.Errorf("DiffStat = %+v, want [{client.go +1}]", ds)
+		}
+		runGit(t, clone, "ls-remote", "--exit-code", "origin", "refs/heads/caic-1")
+		if p := j.Pending(); len(p) != 0 {
+			t.Errorf("pending intents after the push = %+v", p)
+		}
+
+		// Replaying an interrupted push updates the remote-tracking ref.
+		runGit(t, clone, "update-ref", "-d", "refs/remotes/origin/caic-1")
+		if _, err := j.Begin(&JournalEntry{Op: JournalPush, Dir: clone, Branch: "caic-1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := j.Replay(t.Context(), t.TempDir()); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, clone, "rev-parse", "--verify", "refs/remotes/origin/caic-1")
+	})
+	t.Run("Remotes", func(t *testing.T) {
+		clone := initTestRepo(t, "main")
+		fork := filepath.Join(t.TempDir(), "fork.git")
+		runGit(t, "", "init", "--bare", fork)
+		if err := SetRemote(t.Context(), clone, "fork", fork); err != nil {
+			t.Fatal(err)
+		}
+		if err := SetRemote(t.Context(), clone, "md-x", fork); err == nil {
+			t.Error("SetRemote accepted a container remote name")
+		}
+		runGit(t, clone, "remote", "add", "md-api-caic-1", fork)
+		remotes, err := ListRemotes(t.Context(), clone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(remotes) != 2 || remotes[0].Name != "fork" || remotes[1].Name != "origin" {
+			t.Errorf("remotes = %+v, want fork and origin", remotes)
+		}
+		if got := PushRemote(t.Context(), clone); got != "origin" {
+			t.Errorf("PushRemote = %q, want origin", got)
+		}
+		if err := SetPushRemote(t.Context(), clone, "nope"); err == nil {
+			t.Error("SetPushRemote accepted an unknown remote")
+		}
+		if err := SetPushRemote(t.Context(), clone, "fork"); err != nil {
+			t.Fatal(err)
+		}
+		if got := PushRemote(t.Context(), clone); got != "fork" {
+			t.Errorf("PushRemote = %q, want fork", got)
+		}
+
+		// The work branch goes to the fork only.
+		runGit(t, clone, "checkout", "-b", "caic-1")
+		runGit(t, clone, "commit", "--allow-empty", "-m", "change")
+		runGit(t, clone, "update-ref", "refs/remotes/md-api-caic-1/caic-1", "caic-1")
+		runGit(t, clone, "checkout", "main")
+		r := &Runner{BaseBranch: "main", Dir: clone, Container: &stubContainer{}}
+		if _, _, err := r.SyncFetchedToOrigin(t.Context(), "caic-1", "fork", "md-api-caic-1", false); err != nil {
+			t.Fatal(err)
+		}
+		runGit(t, clone, "ls-remote", "--exit-code", "fork", "refs/heads/caic-1")
+		if err := exec.Command("git", "-C", clone, "ls-remote", "--exit-code", "origin", "refs/heads/caic-1").Run(); err == nil {
+			t.Error("branch pushed to origin")
+		}
+
+		// Tasks branching off it start from the local branch, moved to the
+		// pushed commit.
+		runGit(t, clone, "branch", "-f", "caic-1", "main")
+		if err := r.TrackPushedBranch(t.Context(), "caic-1", "md-api-caic-1"); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := gitutil.RevParse(t.Context(), clone, "caic-1")
+		if want, _ := gitutil.RevParse(t.Context(), clone, "refs/remotes/md-api-caic-1/caic-1"); got == "" || got != want {
+			t.Errorf("caic-1 = %s, want %s", got, want)
+		}
+
+		if err := RemoveRemote(t.Context(), clone, "origin"); err == nil {
+			t.Error("RemoveRemote removed origin")
+		}
+		if err := RemoveRemote(t.Context(), clone, "fork"); err != nil {
+			t.Fatal(err)
+		}
+		if got := PushRemote(t.Context(), clone); got != "origin" {
+			t.Errorf("PushRemote after removal = %q, want origin", got)
+		}
+	})
+	t.Run("Verify", func(t *testing.T) {
+		r := &Runner{Container: &stubContainer{}, Dir: "/repo"}
+		if _, err := r.Verify(t.Context(), &Task{}, "true"); err == nil {
+			t.Error("expected error for task without container")
+		}
+		if _, err := r.Verify(t.Context(), &Task{Container: "md-repo-caic-0"}, "true"); err != nil {
+			t.Errorf("Verify = %v, want nil", err)
+		}
+		r.Container = &stubContainer{execErr: errors.New("exit status 1")}
+		if _, err := r.Verify(t.Context(), &Task{Container: "md-repo-caic-0"}, "false"); err == nil {
+			t.Error("expected verification failure")
+		}
+	})
 	t.Run("ReadRelayOutput_UnknownHarness", func(t *testing.T) {
 		r := &Runner{
 			Backends: map[agent.Harness]agent.Backend{
@@ -775,6 +1443,25 @@ func TestRunner(t *testing.T) {
 type stubContainer struct {
 	fetched  bool
 	fetchErr error // If set, Fetch returns this error.
+	execErr  error // If set, Exec returns this error.
+	paused   bool  // Last value passed to SetPaused.
+
+	allowedHosts []string // Last hosts passed to RestrictNetwork.
+
+	missingImage bool     // PullImage pulls the image.
+	pullSubState SubState // Sub-state of the task during the pull.
+	pullTask     *Task    // Task whose sub-state is recorded.
+}
+
+func (s *stubContainer) PullImage(_ context.Context, _ *StartOptions, pulling func()) (bool, error) {
+	if !s.missingImage {
+		return false, nil
+	}
+	pulling()
+	if s.pullTask != nil {
+		s.pullSubState = s.pullTask.Snapshot().SubState
+	}
+	return true, nil
 }
 
 func (s *stubContainer) Launch(_ context.Context, _ []md.Repo, _ []string, _ *StartOptions) (string, error) {
@@ -785,7 +1472,10 @@ func (s *stubContainer) Connect(_ context.Context, _ string, _ []md.Repo, _ *Sta
 	return "", nil
 }
 
-func (s *stubContainer) Diff(_ context.Context, _ md.Repo, _ ...string) (string, error) {
+func (s *stubContainer) Diff(_ context.Context, _ md.Repo, args ...string) (string, error) {
+	if slices.Contains(args, "-z") {
+		return ":100644 100644 aaaaaaa bbbbbbb M\x00main.go\x005\t1\tmain.go\x00", nil
+	}
 	return "5\t1\tmain.go\n", nil
 }
 
@@ -797,6 +1487,37 @@ func (s *stubContainer) Fetch(_ context.Context, repos []md.Repo) error {
 	return nil
 }
 
+func (s *stubContainer) Exec(_ context.Context, _, _, _ string) (string, error) {
+	return "", s.execErr
+}
+
+func (s *stubContainer) SetPaused(_ context.Context, _ string, paused bool) error {
+	s.paused = paused
+	return nil
+}
+
+func (s *stubContainer) RestrictNetwork(_ context.Context, _ string, hosts []string) error {
+	s.allowedHosts = hosts
+	return nil
+}
+
+func (s *stubContainer) Console(_ context.Context, _ string, _ int, _ func(ConsoleLine)) error {
+	return nil
+}
+
+// execContainer is a stubContainer that runs Exec scripts locally in dir.
+type execContainer struct {
+	stubContainer
+	dir string
+}
+
+func (e *execContainer) Exec(ctx context.Context, _, _, script string) (string, error) {
+	cmd := exec.CommandContext(ctx, "bash", "-c", script)
+	cmd.Dir = e.dir
+	out, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(out)), err
+}
+
 func (s *stubContainer) Stop(_ context.Context, _ string) error                { return nil }
 func (s *stubContainer) Purge(_ context.Context, _ string, _ []md.Repo) error  { return nil }
 func (s *stubContainer) Revive(_ context.Context, _ string, _ []md.Repo) error { return nil }
@@ -17,7 +17,7 @@ import (
 // SafetyIssue describes a potential problem detected before pushing to origin.
 type SafetyIssue struct {
 	File   string
-	Kind   string // "large_binary" or "secret"
+	Kind   string // "large_binary" or "secret"; the server adds "pre_push_check"
 	Detail string // Human-readable description.
 }
 
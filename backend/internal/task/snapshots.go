@@ -0,0 +1,127 @@
+// Named snapshots of a task's workspace, restorable later to roll back an
+// experiment without discarding the task.
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/logctx"
+)
+
+// snapshotRefPrefix is where snapshots are stored in the container's repo.
+// They are commits whose parent is HEAD at the time of the snapshot and whose
+// tree includes the uncommitted and untracked files. Refs outside of
+// refs/heads and refs/tags are never pushed.
+const snapshotRefPrefix = "refs/caic/snapshots/"
+
+// snapshotNameRe matches valid snapshot names.
+var snapshotNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// WorkspaceSnapshot is a saved state of a task's workspace.
+type WorkspaceSnapshot struct {
+	Name    string
+	Commit  string
+	Created time.Time
+}
+
+const createSnapshotScript = `set -e
+ref=` + snapshotRefPrefix + `%[1]s
+if git rev-parse -q --verify "$ref" >/dev/null; then echo "snapshot %[1]s already exists" >&2; exit 1; fi
+# Stage everything in a scratch index to leave the agent's index alone.
+idx=$(git rev-parse --git-path index)
+export GIT_INDEX_FILE="$idx.caic-snapshot"
+trap 'rm -f "$GIT_INDEX_FILE"' EXIT
+cp "$idx" "$GIT_INDEX_FILE" 2>/dev/null || rm -f "$GIT_INDEX_FILE"
+git add -A
+commit=$(git commit-tree "$(git write-tree)" -p HEAD -m "caic snapshot %[1]s")
+git update-ref "$ref" "$commit" ""
+git log -1 --format='%%H %%ct' "$commit"
+`
+
+// restoreSnapshotScript resets the branch to the snapshot's parent, then
+// checks out the snapshot's tree and unstages it, so the files that were
+// uncommitted are uncommitted again.
+const restoreSnapshotScript = `set -e
+ref=` + snapshotRefPrefix + `%[1]s
+git rev-parse -q --verify "$ref" >/dev/null || { echo "snapshot %[1]s not found" >&2; exit 1; }
+git reset -q --hard "$ref^"
+git clean -q -fd
+git checkout "$ref" -- .
+git reset -q
+`
+
+const listSnapshotsScript = `git for-each-ref --sort=creatordate --format='%(refname:lstrip=3) %(objectname) %(creatordate:unix)' ` + snapshotRefPrefix
+
+// CreateSnapshot saves the task's workspace, including uncommitted and
+// untracked files, under name.
+func (r *Runner) CreateSnapshot(ctx context.Context, t *Task, name string) (*WorkspaceSnapshot, error) {
+	if !snapshotNameRe.MatchString(name) {
+		return nil, fmt.Errorf("invalid snapshot name %q", name)
+	}
+	out, err := r.snapshotExec(ctx, t, fmt.Sprintf(createSnapshotScript, name))
+	if err != nil {
+		return nil, err
+	}
+	commit, created, _ := strings.Cut(out, " ")
+	s := &WorkspaceSnapshot{Name: name, Commit: commit}
+	if sec, err := strconv.ParseInt(created, 10, 64); err == nil {
+		s.Created = time.Unix(sec, 0)
+	}
+	return s, nil
+}
+
+// RestoreSnapshot resets the task's workspace to a snapshot. Commits and
+// changes made since are discarded, although the snapshots remain.
+func (r *Runner) RestoreSnapshot(ctx context.Context, t *Task, name string) error {
+	if !snapshotNameRe.MatchString(name) {
+		return fmt.Errorf("invalid snapshot name %q", name)
+	}
+	_, err := r.snapshotExec(ctx, t, fmt.Sprintf(restoreSnapshotScript, name))
+	return err
+}
+
+// WorkspaceSnapshots lists the task's snapshots, oldest first.
+func (r *Runner) WorkspaceSnapshots(ctx context.Context, t *Task) ([]WorkspaceSnapshot, error) {
+	out, err := r.snapshotExec(ctx, t, listSnapshotsScript)
+	if err != nil {
+		return nil, err
+	}
+	snaps := []WorkspaceSnapshot{}
+	for line := range strings.SplitSeq(out, "\n") {
+		f := strings.Fields(line)
+		if len(f) != 3 {
+			continue
+		}
+		s := WorkspaceSnapshot{Name: f[0], Commit: f[1]}
+		if sec, err := strconv.ParseInt(f[2], 10, 64); err == nil {
+			s.Created = time.Unix(sec, 0)
+		}
+		snaps = append(snaps, s)
+	}
+	return snaps, nil
+}
+
+// snapshotExec runs a snapshot script in the task's repo inside its
+// container. The output is the error message when the script fails.
+func (r *Runner) snapshotExec(ctx context.Context, t *Task, script string) (string, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
+	r.initDefaults()
+	if r.Container == nil || t.Container == "" || r.Dir == "" {
+		return "", errors.New("task has no container")
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	r.branchMu.Lock()
+	defer r.branchMu.Unlock()
+	out, err := r.Container.Exec(ctx, t.Container, r.containerDir(), script)
+	if err != nil && out != "" {
+		return "", errors.New(out)
+	}
+	return out, err
+}
@@ -0,0 +1,59 @@
+// Stall detection of the running tasks: a harness hung on a dead connection
+// looks like one thinking for long, except that it sends nothing at all.
+package task
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// StallPolicy acts on a running task that sent no message for Minutes.
+type StallPolicy struct {
+	Minutes   int  // 0 disables the detection.
+	Interrupt bool // Interrupt the turn besides flagging the task.
+}
+
+// Timeout returns how long a running task may stay silent, 0 when the policy
+// is disabled.
+func (p StallPolicy) Timeout() time.Duration {
+	return time.Duration(p.Minutes) * time.Minute
+}
+
+// isHeartbeat reports whether m shows that the agent is alive: any message but
+// the notices caic adds to the transcript.
+func isHeartbeat(m agent.Message) bool {
+	sm, ok := m.(*agent.SystemMessage)
+	return !ok || !strings.HasPrefix(sm.Subtype, "caic_")
+}
+
+// recordHeartbeat notes the activity of the agent and clears the stalled flag.
+// The caller must hold t.mu.
+func (t *Task) recordHeartbeat(m agent.Message) {
+	if isHeartbeat(m) {
+		t.lastActivityAt = time.Now().UTC()
+		t.stalled = false
+	}
+}
+
+// MarkStalled flags the running task as stalled and posts detail in its
+// transcript. It returns false when the task is not running, is already
+// flagged, is paused on a policy violation, or was active after since, i.e.
+// the detection raced a message.
+func (t *Task) MarkStalled(ctx context.Context, since time.Time, detail string) bool {
+	t.mu.Lock()
+	if t.state != StateRunning || t.stalled || t.policyViolation != nil || t.lastActivityAt.After(since) || t.turnStartedAt.After(since) {
+		t.mu.Unlock()
+		return false
+	}
+	t.stalled = true
+	t.mu.Unlock()
+	t.addMessage(ctx, &agent.SystemMessage{
+		MessageType: "system",
+		Subtype:     "caic_stalled",
+		Detail:      detail,
+	}, false)
+	return true
+}
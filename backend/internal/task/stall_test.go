@@ -0,0 +1,72 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/policy"
+)
+
+func TestMarkStalled(t *testing.T) {
+	newRunning := func() *Task {
+		tk := &Task{}
+		tk.SetState(StateRunning)
+		return tk
+	}
+	t.Run("Flag", func(t *testing.T) {
+		tk := newRunning()
+		since := tk.Snapshot().LastActivityAt
+		if since.IsZero() {
+			t.Fatal("a running task has no activity time")
+		}
+		if !tk.MarkStalled(t.Context(), since, "hung") {
+			t.Fatal("not flagged")
+		}
+		if tk.MarkStalled(t.Context(), since, "hung") {
+			t.Error("flagged twice")
+		}
+		snap := tk.Snapshot()
+		if !snap.Stalled || !snap.LastActivityAt.Equal(since) {
+			t.Errorf("snapshot = stalled %v at %v, want the notice not to count as activity", snap.Stalled, snap.LastActivityAt)
+		}
+		tk.addMessage(t.Context(), &agent.TextDeltaMessage{Text: "back"}, true)
+		if snap := tk.Snapshot(); snap.Stalled || !snap.LastActivityAt.After(since) {
+			t.Errorf("snapshot = stalled %v at %v, want cleared by the message", snap.Stalled, snap.LastActivityAt)
+		}
+	})
+	t.Run("Race", func(t *testing.T) {
+		tk := newRunning()
+		since := tk.Snapshot().LastActivityAt
+		time.Sleep(time.Millisecond)
+		tk.addMessage(t.Context(), &agent.UsageMessage{}, true)
+		if tk.MarkStalled(t.Context(), since, "hung") {
+			t.Error("flagged despite a newer message")
+		}
+	})
+	t.Run("PolicyViolation", func(t *testing.T) {
+		tk := newRunning()
+		tk.Container = "md-caic-1"
+		since := tk.Snapshot().LastActivityAt
+		tk.policyViolation = &policy.Violation{Rule: "no-network"}
+		if tk.MarkStalled(t.Context(), since, "hung") {
+			t.Error("a task paused on a policy violation was flagged")
+		}
+		time.Sleep(time.Millisecond)
+		r := &Runner{Container: &stubContainer{}}
+		if _, err := r.ResolvePolicyViolation(t.Context(), tk); err != nil {
+			t.Fatal(err)
+		}
+		if !tk.Snapshot().LastActivityAt.After(since) {
+			t.Error("the pause counted as silence")
+		}
+	})
+	t.Run("NotRunning", func(t *testing.T) {
+		tk := newRunning()
+		since := tk.Snapshot().LastActivityAt
+		tk.SetState(StateWaiting)
+		if tk.MarkStalled(t.Context(), since, "hung") || !tk.Snapshot().LastActivityAt.IsZero() {
+			t.Error("a waiting task was flagged")
+		}
+	})
+}
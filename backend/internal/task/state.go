@@ -0,0 +1,294 @@
+// Task lifecycle state machine: states, validated transitions, hooks and history.
+package task
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// State represents the lifecycle state of a task.
+type State int
+
+// Task lifecycle states.
+const (
+	StatePending      State = iota
+	StateBranching          // Creating git branch.
+	StateProvisioning       // Starting docker container.
+	StateStarting           // Launching agent session.
+	StateRunning            // Agent is executing.
+	StateWaiting            // Agent completed a turn, awaiting user input or purge.
+	StateAsking             // Agent asked a question (AskUserQuestion), needs answer.
+	StateHasPlan            // Agent finished planning (ExitPlanMode with plan content), awaiting approval.
+	StatePlanReview         // RequirePlan task finished a planning turn; writes stay blocked until the plan is approved.
+	StateRateLimited        // Turn failed on a provider rate limit; resumed automatically after a backoff.
+	StatePulling            // Pulling changes from container.
+	StatePushing            // Pushing to origin.
+	StateStopping           // Graceful stop in progress (container being stopped, preserved for revival).
+	StateStopped            // Container stopped but not deleted; can be revived.
+	StatePurging            // User requested purge; cleanup in progress.
+	StateFailed             // Failed at some stage.
+	StatePurged             // Container deleted, task is final.
+)
+
+func (s State) String() string {
+	switch s {
+	case StatePending:
+		return "pending"
+	case StateBranching:
+		return "branching"
+	case StateProvisioning:
+		return "provisioning"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateWaiting:
+		return "waiting"
+	case StateAsking:
+		return "asking"
+	case StateHasPlan:
+		return "has_plan"
+	case StatePlanReview:
+		return "plan_review"
+	case StateRateLimited:
+		return "rate_limited"
+	case StatePulling:
+		return "pulling"
+	case StatePushing:
+		return "pushing"
+	case StateStopping:
+		return "stopping"
+	case StateStopped:
+		return "stopped"
+	case StatePurging:
+		return "purging"
+	case StateFailed:
+		return "failed"
+	case StatePurged:
+		return "purged"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseState converts a State name back to its value. It is the inverse of
+// String.
+func ParseState(s string) (State, bool) {
+	for st := StatePending; st <= StatePurged; st++ {
+		if st.String() == s {
+			return st, true
+		}
+	}
+	return 0, false
+}
+
+// active reports whether s is a state of a task whose container may still be
+// in use. Active tasks can move to any state except StatePending.
+func (s State) active() bool {
+	switch s {
+	case StatePending, StateBranching, StateProvisioning, StateStarting, StateRunning, StateWaiting,
+		StateAsking, StateHasPlan, StatePlanReview, StateRateLimited, StatePulling, StatePushing:
+		return true
+	case StateStopping, StateStopped, StatePurging, StateFailed, StatePurged:
+		return false
+	default:
+		return false
+	}
+}
+
+// CanTransition reports whether a task may move from one state to another.
+// Staying in the same state is always allowed.
+//
+// Once a task is stopping, stopped, purging or failed it can only be stopped,
+// revived, purged or failed. StatePurged is final, except that failed and
+// purged tasks go back to StateProvisioning when their saved workspace is
+// rehydrated; see Runner.RehydrateTask.
+func CanTransition(from, to State) bool {
+	if from == to {
+		return true
+	}
+	if from.active() {
+		return to != StatePending
+	}
+	switch from {
+	case StateStopping:
+		return to == StateStopped || to == StatePurging || to == StatePurged || to == StateFailed
+	case StateStopped:
+		return to == StateProvisioning || to == StatePurging || to == StatePurged || to == StateFailed
+	case StatePurging:
+		return to == StatePurged || to == StateFailed
+	case StateFailed:
+		return to == StateProvisioning || to == StatePurging || to == StatePurged
+	case StatePurged:
+		return to == StateProvisioning
+	default:
+		return false
+	}
+}
+
+// maxTransitions caps the transition history kept per task.
+const maxTransitions = 256
+
+// Transition is one recorded state change of a task.
+type Transition struct {
+	From State
+	To   State
+	At   time.Time
+}
+
+// OnTransition registers fn to be called after each validated state change.
+// fn runs with the task locked: it must not call back into the task nor
+// block, and should hand off work (events, webhooks, metrics) to another
+// goroutine.
+func (t *Task) OnTransition(fn func(Transition)) {
+	t.mu.Lock()
+	t.hooks = append(t.hooks, fn)
+	t.mu.Unlock()
+}
+
+// Transitions returns a copy of the state transition history, oldest first.
+func (t *Task) Transitions() []Transition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Transition(nil), t.transitions...)
+}
+
+// RestoreTransitions replaces the transition history with the one read back
+// from the task's log, e.g. after a server restart. The restored entries are
+// not logged again.
+func (t *Task) RestoreTransitions(trs []Transition) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.transitions = append([]Transition(nil), trs[max(0, len(trs)-maxTransitions):]...)
+	t.unlogged = nil
+}
+
+// setState validates and applies a state change, records it in the history
+// and runs the transition hooks. Illegal transitions are logged and ignored.
+// Returns whether the state is now s. The caller must hold t.mu.
+func (t *Task) setState(s State) bool {
+	now := time.Now().UTC()
+	from := t.state
+	if !CanTransition(from, s) {
+		slog.Error("illegal state transition", "task", t.ID, "ctr", t.Container, "from", from, "to", s)
+		return false
+	}
+	if s == StateRunning && from != StateRunning {
+		t.turnStartedAt = now
+	} else if s != StateRunning {
+		t.turnStartedAt = time.Time{}
+	}
+	if s != from {
+		t.stalled = false
+	}
+	t.state = s
+	t.stateUpdatedAt = now
+	t.subState = SubStateNone
+	slog.Debug("container", "state", s, "task", t.ID, "ctr", t.Container)
+	if from == s {
+		return true
+	}
+	tr := Transition{From: from, To: s, At: now}
+	t.transitions = append(t.transitions, tr)
+	if n := len(t.transitions) - maxTransitions; n > 0 {
+		t.transitions = t.transitions[n:]
+	}
+	t.unlogged = append(t.unlogged, tr)
+	if n := len(t.unlogged) - maxTransitions; n > 0 {
+		t.unlogged = t.unlogged[n:]
+	}
+	t.logTransitions()
+	for _, fn := range t.hooks {
+		fn(tr)
+	}
+	return true
+}
+
+// logTransitions appends the transitions not yet persisted to the session
+// log, if one is open. Transitions made while no session is attached are
+// written when the next one attaches. The caller must hold t.mu.
+func (t *Task) logTransitions() {
+	if len(t.unlogged) == 0 || t.handle == nil || t.handle.LogW == nil {
+		return
+	}
+	for _, tr := range t.unlogged {
+		data, err := agent.MarshalMessage(&agent.MetaStateMessage{
+			MessageType: "caic_state",
+			From:        tr.From.String(),
+			To:          tr.To.String(),
+			Ts:          float64(tr.At.UnixMilli()) / 1e3,
+		})
+		if err != nil {
+			continue
+		}
+		_, _ = t.handle.LogW.Write(append(data, '\n'))
+	}
+	t.unlogged = nil
+}
+
+// SetState validates and applies a state change under the mutex.
+func (t *Task) SetState(s State) {
+	t.mu.Lock()
+	t.setState(s)
+	t.mu.Unlock()
+}
+
+// SetStateAt sets the state under the mutex with an explicit timestamp.
+// Used during adoption to restore a state read from logs or a container; it
+// bypasses transition validation and is not recorded in the history.
+func (t *Task) SetStateAt(s State, at time.Time) {
+	t.mu.Lock()
+	if s != StateRunning {
+		t.turnStartedAt = time.Time{}
+	}
+	t.state = s
+	t.stateUpdatedAt = at
+	t.mu.Unlock()
+}
+
+// SetTurnStartedAt sets the turn start time if the task is currently running.
+// Called during adoption to estimate when the current mid-turn started.
+func (t *Task) SetTurnStartedAt(at time.Time) {
+	t.mu.Lock()
+	if t.state == StateRunning {
+		t.turnStartedAt = at
+	}
+	t.mu.Unlock()
+}
+
+// SetStateIf atomically transitions the state to next only if the current
+// state equals expected and the transition is legal. Returns true if the
+// transition occurred.
+func (t *Task) SetStateIf(expected, next State) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.state != expected {
+		return false
+	}
+	return t.setState(next)
+}
+
+// SubState details the step of a task within its state.
+type SubState string
+
+// Task sub-states. Every state change resets the sub-state.
+const (
+	SubStateNone      SubState = ""
+	SubStateImagePull SubState = "image_pull" // StateProvisioning: pulling the base image.
+)
+
+// SetSubState sets the step of the task within its current state.
+func (t *Task) SetSubState(s SubState) {
+	t.mu.Lock()
+	t.subState = s
+	t.mu.Unlock()
+}
+
+// GetState returns the current state under the mutex.
+func (t *Task) GetState() State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.state
+}
@@ -0,0 +1,115 @@
+package task
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+type bufCloser struct{ bytes.Buffer }
+
+func (*bufCloser) Close() error { return nil }
+
+func TestCanTransition(t *testing.T) {
+	for _, tc := range []struct {
+		from, to State
+		want     bool
+	}{
+		{StatePending, StateBranching, true},
+		{StateRunning, StateWaiting, true},
+		{StateWaiting, StateRunning, true},
+		{StateRunning, StatePending, false},
+		{StateStopped, StateProvisioning, true},
+		{StateStopped, StateRunning, false},
+		{StateStopping, StateStopped, true},
+		{StatePurging, StatePurged, true},
+		{StatePurging, StateStopped, false},
+		{StateFailed, StatePurging, true},
+		{StateFailed, StateRunning, false},
+		{StateFailed, StateProvisioning, true},
+		{StatePurged, StateRunning, false},
+		{StatePurged, StateProvisioning, true},
+		{StatePurged, StateStopped, false},
+		{StatePurged, StatePurged, true},
+	} {
+		t.Run(tc.from.String()+"_"+tc.to.String(), func(t *testing.T) {
+			if got := CanTransition(tc.from, tc.to); got != tc.want {
+				t.Errorf("CanTransition(%v, %v) = %v, want %v", tc.from, tc.to, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetState(t *testing.T) {
+	t.Run("History", func(t *testing.T) {
+		tk := &Task{}
+		var hooked []Transition
+		tk.OnTransition(func(tr Transition) { hooked = append(hooked, tr) })
+		tk.SetState(StateRunning)
+		tk.SetState(StateRunning)
+		tk.SetState(StateWaiting)
+		trs := tk.Transitions()
+		if len(trs) != 2 || trs[0].From != StatePending || trs[0].To != StateRunning || trs[1].To != StateWaiting {
+			t.Fatalf("Transitions = %+v, want pending→running→waiting", trs)
+		}
+		if len(hooked) != 2 {
+			t.Errorf("hook called %d times, want 2", len(hooked))
+		}
+	})
+	t.Run("IllegalIgnored", func(t *testing.T) {
+		tk := &Task{}
+		tk.SetState(StatePurging)
+		tk.SetState(StatePurged)
+		tk.SetState(StateRunning)
+		if got := tk.GetState(); got != StatePurged {
+			t.Errorf("state = %v, want %v", got, StatePurged)
+		}
+		if tk.SetStateIf(StatePurged, StateWaiting) {
+			t.Error("SetStateIf allowed purged→waiting")
+		}
+		if n := len(tk.Transitions()); n != 2 {
+			t.Errorf("len(Transitions) = %d, want 2", n)
+		}
+	})
+	t.Run("Logged", func(t *testing.T) {
+		tk := &Task{}
+		tk.SetState(StateProvisioning)
+		w := &bufCloser{}
+		tk.AttachSession(&SessionHandle{LogW: w})
+		tk.SetState(StateRunning)
+		lines := strings.Split(strings.TrimSpace(w.String()), "\n")
+		if len(lines) != 2 || !strings.Contains(lines[0], `"to":"provisioning"`) || !strings.Contains(lines[1], `"to":"running"`) {
+			t.Errorf("log = %q, want the provisioning then running transitions", w.String())
+		}
+	})
+}
+
+func TestLoadTransitions(t *testing.T) {
+	dir := t.TempDir()
+	meta := mustJSON(t, agent.MetaMessage{MessageType: "caic_meta", Version: 1, Prompt: "p", Repos: []agent.MetaRepo{{Name: "r", Branch: "caic-0"}}, Harness: "claude"})
+	st1 := mustJSON(t, agent.MetaStateMessage{MessageType: "caic_state", From: "pending", To: "running", Ts: 1000})
+	st2 := mustJSON(t, agent.MetaStateMessage{MessageType: "caic_state", From: "running", To: "waiting", Ts: 1001.5})
+	writeLogFile(t, dir, "a.jsonl", meta, st1, claudeInit(t, "sid"), st2)
+	tasks, err := LoadLogs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setClaudeParser(tasks)
+	if err := tasks[0].LoadMessages(); err != nil {
+		t.Fatal(err)
+	}
+	trs := tasks[0].Transitions
+	if len(trs) != 2 || trs[1].From != StateRunning || trs[1].To != StateWaiting || trs[1].At.UnixMilli() != 1001500 {
+		t.Errorf("Transitions = %+v", trs)
+	}
+	if n := len(tasks[0].Msgs); n != 1 {
+		t.Errorf("len(Msgs) = %d, want 1 (caic_state is not a message)", n)
+	}
+	for st := StatePending; st <= StatePurged; st++ {
+		if got, ok := ParseState(st.String()); !ok || got != st {
+			t.Errorf("ParseState(%q) = %v, %v", st, got, ok)
+		}
+	}
+}
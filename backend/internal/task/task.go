@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/caic-xyz/caic/backend/internal/agent"
 	"github.com/caic-xyz/caic/backend/internal/forge"
+	"github.com/caic-xyz/caic/backend/internal/policy"
 	"github.com/caic-xyz/md"
 	"github.com/maruel/genai"
 	"github.com/maruel/ksid"
@@ -42,65 +45,6 @@ type statsSub struct {
 
 func (s *statsSub) close() { s.once.Do(func() { close(s.ch) }) }
 
-// State represents the lifecycle state of a task.
-type State int
-
-// Task lifecycle states.
-const (
-	StatePending      State = iota
-	StateBranching          // Creating git branch.
-	StateProvisioning       // Starting docker container.
-	StateStarting           // Launching agent session.
-	StateRunning            // Agent is executing.
-	StateWaiting            // Agent completed a turn, awaiting user input or purge.
-	StateAsking             // Agent asked a question (AskUserQuestion), needs answer.
-	StateHasPlan            // Agent finished planning (ExitPlanMode with plan content), awaiting approval.
-	StatePulling            // Pulling changes from container.
-	StatePushing            // Pushing to origin.
-	StateStopping           // Graceful stop in progress (container being stopped, preserved for revival).
-	StateStopped            // Container stopped but not deleted; can be revived.
-	StatePurging            // User requested purge; cleanup in progress.
-	StateFailed             // Failed at some stage.
-	StatePurged             // Container deleted, task is final.
-)
-
-func (s State) String() string {
-	switch s {
-	case StatePending:
-		return "pending"
-	case StateBranching:
-		return "branching"
-	case StateProvisioning:
-		return "provisioning"
-	case StateStarting:
-		return "starting"
-	case StateRunning:
-		return "running"
-	case StateWaiting:
-		return "waiting"
-	case StateAsking:
-		return "asking"
-	case StateHasPlan:
-		return "has_plan"
-	case StatePulling:
-		return "pulling"
-	case StatePushing:
-		return "pushing"
-	case StateStopping:
-		return "stopping"
-	case StateStopped:
-		return "stopped"
-	case StatePurging:
-		return "purging"
-	case StateFailed:
-		return "failed"
-	case StatePurged:
-		return "purged"
-	default:
-		return "unknown"
-	}
-}
-
 // SessionHandle bundles the resources associated with an active agent session:
 // the SSH session, the message dispatch channel, and the log writer.
 // DispatchDone is closed when the dispatch goroutine exits after MsgCh is closed.
@@ -126,12 +70,30 @@ type RepoMount struct {
 	BaseBranch string // branch to fork from; empty = runner default
 	Branch     string // allocated branch, e.g. "caic-0"
 	GitRoot    string // absolute host path; empty in purged-task entries
+	DeployKey  string // SSH private key file installed for git in the container; empty means none.
+	PushRemote string // remote the branch is pushed to, e.g. a fork; empty means origin
+}
+
+// IssueLink is the Jira or Linear issue a task works on; the task posts its
+// progress there.
+type IssueLink struct {
+	Tracker string // "jira" or "linear"
+	Key     string // e.g. "PROJ-123"
+	URL     string
+}
+
+// SlackThread is the Slack thread a task was created from; its results are
+// posted there and replies are sent to it as input.
+type SlackThread struct {
+	Channel string // Channel ID.
+	TS      string // Timestamp of the thread's root message.
 }
 
 // Task represents a single unit of work.
 type Task struct {
 	// Immutable fields — set at creation, never modified.
 	ID            ksid.ID
+	Alias         string        // Short human-friendly alias, e.g. "w42"; empty for tasks predating aliases.
 	InitialPrompt agent.Prompt  // Initial prompt text and optional images.
 	Repos         []RepoMount   // index 0 = primary; empty = no-repo
 	Harness       agent.Harness // Agent harness ("claude", "gemini", etc.).
@@ -141,10 +103,28 @@ type Task struct {
 	Tailscale     bool          // Enable Tailscale networking in the container.
 	USB           bool          // Enable USB passthrough in the container.
 	Display       bool          // Enable Xvfb display in the container.
+	PlanOnly      bool          // Read-only plan mode: the agent proposes a plan and never writes; diff and push are skipped.
+	RequirePlan   bool          // Two-phase: sessions run in plan mode until the plan is approved via ApprovePlan.
+	ReadOnly      bool          // Repos are locked read-only in the container and write tools are denied; nothing to sync.
+	Network       NetworkMode   // Outgoing connections of the container; "" means NetworkFull.
+	NetworkAllow  []string      // Hosts reachable with NetworkAllowlist, besides the harness's.
+	Chat          bool          // Conversation only: no branch, diff or push; see chatRef.
+	Thinking      bool          // Request extended thinking from the harness.
+	Persist       bool          // Save the workspace to the host repo before the container is stopped or purged; see SaveWorkspace.
+	Idle          *IdlePolicy   // Overrides the idle policy of the preferences; nil follows them.
+	Push          PushPolicy    // Overrides the push policy of the preferences; "" follows them.
+	Issue         *IssueLink    // Linked Jira or Linear issue; nil = none.
+	Slack         *SlackThread  // Slack thread the task reports to; nil = none.
+	DependsOn     []ksid.ID     // Prerequisite tasks that had to be done before this one started.
 	StartedAt     time.Time     // When the task was created.
 	OwnerID       string        // Internal user ID of the creator; empty in no-auth mode.
 	ForgeIssue    int           // Originating issue number for bot comment callbacks; 0 = none.
+	Instructions  string        // Custom instructions appended to the agent's system prompt.
+	Env           map[string]string
 	Provider      genai.Provider
+	Policy        *policy.Policy  // Tool call rules; nil means no restrictions.
+	Endpoint      *agent.Endpoint // API of the generic harness; nil for the others.
+	Fallback      *Fallback       // Harness and model to fail over to; nil disables failover.
 
 	// Write-once fields — set during setup/adoption, never modified after.
 	Container     string
@@ -159,6 +139,7 @@ type Task struct {
 	statsSubs             []*statsSub
 	state                 State
 	stateUpdatedAt        time.Time // UTC timestamp of the last state transition.
+	subState              SubState  // Step within state.
 	sessionID             string    // Agent session ID, captured from SystemInitMessage.
 	reportedModel         string    // Model reported by SystemInitMessage (may differ from Model).
 	agentVersion          string    // Agent version, captured from SystemInitMessage.
@@ -167,6 +148,7 @@ type Task struct {
 	planContent           string    // Content of the plan file, captured from Write tool_use input.
 	planDismissed         bool      // True after ClearMessages; suppresses plan tracking until the next ResultMessage.
 	inPlanMode            bool      // True while the agent is in plan mode (between EnterPlanMode and ExitPlanMode).
+	planApproved          bool      // RequirePlan only: set by ApprovePlan or restored from a caic_plan_approved marker.
 	title                 string    // LLM-generated short title; set via SetTitle.
 	msgs                  []agent.Message
 	subs                  []*sub         // active SSE subscribers
@@ -181,12 +163,27 @@ type Task struct {
 	liveUsage             agent.Usage
 	lastUsage             agent.Usage    // Most recent ResultMessage usage (active context).
 	lastAPIUsage          agent.Usage    // Most recent per-API-call usage from AssistantMessage (context window fill).
+	compactPhase          compactPhase   // In-flight automatic compaction, see maybeAutoCompact.
 	liveDiffStat          agent.DiffStat // Updated by DiffStatMessage from relay.
 	forgeOwner            string
 	forgeRepo             string
 	forgePR               int
 	ciStatus              forge.CIStatus
 	ciChecks              []forge.Check
+	transitions           []Transition       // State history, oldest first; capped at maxTransitions.
+	unlogged              []Transition       // Transitions not yet written to a session log.
+	turnOutputs           []turnOutput       // First agent output of the recent turns; see Timeline.
+	verifications         []Phase            // Verification runs, oldest first; see Timeline.
+	hooks                 []func(Transition) // Called by setState; see OnTransition.
+	revision              uint64             // Bumped by each API mutation; see BumpRevision.
+	policyViolation       *policy.Violation  // Tool call the container is paused on; see enforcePolicy.
+	lastActivityAt        time.Time          // Last message from the agent; see recordHeartbeat.
+	stalled               bool               // Running but silent; see MarkStalled.
+	failover              *Failover          // Substitution of the harness and model; see startAgent.
+	// Provider rate limits; see RateLimitResumeAt.
+	rateLimit       agent.RateLimitMessage // Last rate limit status reported by the agent.
+	rateLimitSeenAt time.Time              // When rateLimit was received; zero when never.
+	rateLimitStreak int                    // Consecutive turns that failed on a rate limit.
 }
 
 // Primary returns a pointer to the primary RepoMount (Repos[0]), or nil for no-repo tasks.
@@ -197,6 +194,16 @@ func (t *Task) Primary() *RepoMount {
 	return &t.Repos[0]
 }
 
+// EnvPairs returns Env, the environment variables set in the container and
+// the agent, as sorted KEY=VALUE pairs.
+func (t *Task) EnvPairs() []string {
+	out := make([]string, 0, len(t.Env))
+	for _, k := range slices.Sorted(maps.Keys(t.Env)) {
+		out = append(out, k+"="+t.Env[k])
+	}
+	return out
+}
+
 // MDRepos returns all repos as []md.Repo for use with the container backend.
 func (t *Task) MDRepos() []md.Repo {
 	out := make([]md.Repo, len(t.Repos))
@@ -214,67 +221,6 @@ func (t *Task) ExtraMDRepos() []md.Repo {
 	return t.MDRepos()[1:]
 }
 
-// setState updates the state and records the transition time. The caller must
-// hold t.mu when called from a locked context, or ensure exclusive access.
-func (t *Task) setState(s State) {
-	if s == StateRunning && t.state != StateRunning {
-		t.turnStartedAt = time.Now().UTC()
-	} else if s != StateRunning {
-		t.turnStartedAt = time.Time{}
-	}
-	t.state = s
-	t.stateUpdatedAt = time.Now().UTC()
-	slog.Debug("container", "state", s, "task", t.ID, "ctr", t.Container)
-}
-
-// SetState updates the state under the mutex and records the transition time.
-func (t *Task) SetState(s State) {
-	t.mu.Lock()
-	t.setState(s)
-	t.mu.Unlock()
-}
-
-// SetStateAt updates the state under the mutex with an explicit timestamp.
-// Used during adoption to preserve the original transition time.
-func (t *Task) SetStateAt(s State, at time.Time) {
-	t.mu.Lock()
-	if s != StateRunning {
-		t.turnStartedAt = time.Time{}
-	}
-	t.state = s
-	t.stateUpdatedAt = at
-	t.mu.Unlock()
-}
-
-// SetTurnStartedAt sets the turn start time if the task is currently running.
-// Called during adoption to estimate when the current mid-turn started.
-func (t *Task) SetTurnStartedAt(at time.Time) {
-	t.mu.Lock()
-	if t.state == StateRunning {
-		t.turnStartedAt = at
-	}
-	t.mu.Unlock()
-}
-
-// SetStateIf atomically transitions the state to next only if the current
-// state equals expected. Returns true if the transition occurred.
-func (t *Task) SetStateIf(expected, next State) bool {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	if t.state != expected {
-		return false
-	}
-	t.setState(next)
-	return true
-}
-
-// GetState returns the current state under the mutex.
-func (t *Task) GetState() State {
-	t.mu.Lock()
-	defer t.mu.Unlock()
-	return t.state
-}
-
 // GetSessionID returns the agent session ID under the mutex.
 func (t *Task) GetSessionID() string {
 	t.mu.Lock()
@@ -385,10 +331,12 @@ func (t *Task) Title() string {
 // addMessage/RestoreMessages modify concurrently.
 type Snapshot struct {
 	State              State
+	SubState           SubState
 	StateUpdatedAt     time.Time
 	TurnStartedAt      time.Time // non-zero only while state is Running
 	Title              string
 	SessionID          string
+	Harness            agent.Harness // Changes on failover.
 	Model              string
 	AgentVersion       string
 	ContextWindowLimit int // Non-zero when reported by the agent at runtime.
@@ -408,6 +356,11 @@ type Snapshot struct {
 	ForgeIssue         int
 	CIStatus           forge.CIStatus
 	CIChecks           []forge.Check
+	PolicyViolation    *policy.Violation // Non-nil while paused pending approval.
+	LastActivityAt     time.Time         // Last agent message of the running turn, or its start; zero unless running.
+	Stalled            bool              // Running but silent for longer than the stall policy allows.
+	Failover           *Failover         // Non-nil when the task failed over to its fallback.
+	Revision           uint64
 }
 
 // Snapshot returns a consistent read of all volatile fields under the mutex.
@@ -418,12 +371,21 @@ func (t *Task) Snapshot() Snapshot {
 	if model == "" {
 		model = t.Model
 	}
+	var activity time.Time
+	if t.state == StateRunning {
+		activity = t.turnStartedAt
+		if t.lastActivityAt.After(activity) {
+			activity = t.lastActivityAt
+		}
+	}
 	return Snapshot{
 		State:              t.state,
+		SubState:           t.subState,
 		StateUpdatedAt:     t.stateUpdatedAt,
 		TurnStartedAt:      t.turnStartedAt,
 		Title:              t.title,
 		SessionID:          t.sessionID,
+		Harness:            t.Harness,
 		Model:              model,
 		AgentVersion:       t.agentVersion,
 		ContextWindowLimit: t.reportedContextWindow,
@@ -443,7 +405,39 @@ func (t *Task) Snapshot() Snapshot {
 		ForgeIssue:         t.ForgeIssue,
 		CIStatus:           t.ciStatus,
 		CIChecks:           append([]forge.Check(nil), t.ciChecks...),
+		PolicyViolation:    t.policyViolation,
+		LastActivityAt:     activity,
+		Stalled:            t.stalled,
+		Failover:           t.failover,
+		Revision:           t.revision,
+	}
+}
+
+// BumpRevision advances the task revision used for optimistic concurrency and
+// returns the new value. When want is non-nil and differs from the current
+// revision, the revision is left unchanged and BumpRevision returns the
+// current value and false. Checking and bumping under one lock guarantees
+// that of two requests made against the same revision, only one proceeds.
+func (t *Task) BumpRevision(want *uint64) (uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if want != nil && *want != t.revision {
+		return t.revision, false
+	}
+	t.revision++
+	return t.revision, true
+}
+
+// RevertRevision undoes the BumpRevision that returned rev because the
+// mutation failed, unless another one advanced the revision since. It returns
+// the current revision.
+func (t *Task) RevertRevision(rev uint64) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.revision == rev {
+		t.revision--
 	}
+	return t.revision
 }
 
 // Messages returns a copy of all received agent messages.
@@ -453,6 +447,17 @@ func (t *Task) Messages() []agent.Message {
 	return append([]agent.Message(nil), t.msgs...)
 }
 
+// MessagesPage returns a copy of up to limit messages starting at index after,
+// and the total number of messages.
+func (t *Task) MessagesPage(after, limit int) (msgs []agent.Message, total int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	total = len(t.msgs)
+	after = min(max(after, 0), total)
+	end := min(after+max(limit, 0), total)
+	return append([]agent.Message(nil), t.msgs[after:end]...), total
+}
+
 // RestoreMessages sets the initial message history from previously saved logs.
 // It also extracts metadata from the last SystemInitMessage, if any, and
 // infers the task state from the trailing messages: a trailing ResultMessage
@@ -482,6 +487,9 @@ func (t *Task) RestoreMessages(msgs []agent.Message) {
 		if sm, ok := m.(*agent.SystemMessage); ok && sm.Subtype == "model_rerouted" && sm.Model != "" {
 			t.reportedModel = sm.Model
 		}
+		if sm, ok := m.(*agent.SystemMessage); ok && sm.Subtype == planApprovedSubtype {
+			t.planApproved = true
+		}
 	}
 	// Restore plan state from tool_use events. A context_cleared marker
 	// resets plan state — it means ClearMessages was called (e.g. "Clear
@@ -561,6 +569,11 @@ func (t *Task) RestoreMessages(msgs []agent.Message) {
 		t.liveCostUSD = t.priorCostUSD + computeCost(rm.TotalCostUSD, rm.Usage)
 		t.liveNumTurns += rm.NumTurns
 		t.liveDuration += time.Duration(rm.DurationMs) * time.Millisecond
+		if rm.RateLimited {
+			t.rateLimitStreak++
+		} else if !rm.IsError {
+			t.rateLimitStreak = 0
+		}
 	}
 	// Infer state: if the last agent-emitted message is a ResultMessage, the
 	// agent finished its turn and is waiting for user input (or asking a
@@ -569,10 +582,14 @@ func (t *Task) RestoreMessages(msgs []agent.Message) {
 	// Only override non-terminal states — purged/failed tasks loaded from
 	// logs must keep their recorded state.
 	if len(msgs) > 0 && t.state != StatePurged && t.state != StateFailed && t.state != StatePurging {
-		if lastAgentMessage(msgs) != nil {
+		if rm := lastAgentMessage(msgs); rm != nil {
 			switch {
+			case rm.RateLimited:
+				t.setState(StateRateLimited)
 			case lastTurnHasAsk(msgs):
 				t.setState(StateAsking)
+			case t.RequirePlan && !t.planApproved:
+				t.setState(StatePlanReview)
 			case lastTurnHasExitPlan(msgs) && t.planContent != "":
 				t.setState(StateHasPlan)
 			default:
@@ -622,10 +639,16 @@ func (t *Task) addMessage(ctx context.Context, m agent.Message, skipTitleGen boo
 	// new turn on the relay before we reattached.
 	switch m.(type) {
 	case *agent.TextMessage, *agent.ToolUseMessage, *agent.AskMessage, *agent.TodoMessage:
-		if t.state == StateWaiting || t.state == StateAsking || t.state == StateHasPlan {
+		if t.state == StateWaiting || t.state == StateAsking || t.state == StateHasPlan || t.state == StatePlanReview || t.state == StateRateLimited {
 			t.setState(StateRunning)
 		}
 	}
+	t.recordOutput(m)
+	t.recordHeartbeat(m)
+	if rl, ok := m.(*agent.RateLimitMessage); ok {
+		t.rateLimit = *rl
+		t.rateLimitSeenAt = time.Now().UTC()
+	}
 	// Update live diff stat from relay polling.
 	if ds, ok := m.(*agent.DiffStatMessage); ok {
 		t.liveDiffStat = ds.DiffStat
@@ -640,6 +663,10 @@ func (t *Task) addMessage(ctx context.Context, m agent.Message, skipTitleGen boo
 		t.priorNumTurns = t.liveNumTurns
 		t.priorDuration = t.liveDuration
 	}
+	// The context fill is unknown after compaction until the next API call.
+	if sm, ok := m.(*agent.SystemMessage); ok && (sm.Subtype == "compact_boundary" || sm.Subtype == "context_compaction") {
+		t.lastAPIUsage = agent.Usage{}
+	}
 	// Transition to waiting/asking when a result arrives.
 	if rm, ok := m.(*agent.ResultMessage); ok {
 		if len(rm.DiffStat) > 0 {
@@ -656,6 +683,11 @@ func (t *Task) addMessage(ctx context.Context, m agent.Message, skipTitleGen boo
 		t.liveNumTurns += rm.NumTurns
 		t.liveDuration += time.Duration(rm.DurationMs) * time.Millisecond
 		t.planDismissed = false
+		if rm.RateLimited {
+			t.rateLimitStreak++
+		} else if !rm.IsError {
+			t.rateLimitStreak = 0
+		}
 		// Transition Running→Waiting/Asking/HasPlan. Also handle
 		// Running/Waiting because watchSession may have already set
 		// Waiting before the dispatch goroutine processed this
@@ -663,8 +695,12 @@ func (t *Task) addMessage(ctx context.Context, m agent.Message, skipTitleGen boo
 		// we still need to distinguish Waiting from Asking/HasPlan.
 		if t.state == StateRunning || t.state == StateWaiting {
 			switch {
+			case rm.RateLimited:
+				t.setState(StateRateLimited)
 			case lastTurnHasAsk(t.msgs):
 				t.setState(StateAsking)
+			case t.RequirePlan && !t.planApproved:
+				t.setState(StatePlanReview)
 			case lastTurnHasExitPlan(t.msgs) && t.planContent != "":
 				t.setState(StateHasPlan)
 			default:
@@ -675,17 +711,7 @@ func (t *Task) addMessage(ctx context.Context, m agent.Message, skipTitleGen boo
 			go t.GenerateTitle(ctx)
 		}
 	}
-	// Fan out to subscribers (non-blocking).
-	for i := 0; i < len(t.subs); i++ {
-		select {
-		case t.subs[i].ch <- m:
-		default:
-			// Slow subscriber — drop and remove.
-			t.subs[i].close()
-			t.subs = append(t.subs[:i], t.subs[i+1:]...)
-			i--
-		}
-	}
+	t.fanOut(m)
 }
 
 // writeToolInput is the JSON input schema for the Write tool_use block.
@@ -750,6 +776,7 @@ func syntheticContextCleared() *agent.SystemMessage {
 func (t *Task) AttachSession(h *SessionHandle) {
 	t.mu.Lock()
 	t.handle = h
+	t.logTransitions()
 	t.mu.Unlock()
 }
 
@@ -812,6 +839,8 @@ func (t *Task) ClearMessages(ctx context.Context) {
 	t.planFile = ""
 	t.planContent = ""
 	t.planDismissed = true
+	t.lastAPIUsage = agent.Usage{}
+	t.compactPhase = compactIdle
 	// Clear PlanContent on all ExitPlanMode messages so new subscribers
 	// do not see stale plan content after context is cleared.
 	for _, m := range t.msgs {
@@ -903,53 +932,6 @@ func lastTurnHasExitPlan(msgs []agent.Message) bool {
 	return false
 }
 
-// sub is an SSE subscriber with a once-guarded close to prevent double-close
-// panics when both the fan-out (slow subscriber drop) and context cancellation
-// race to close the channel.
-type sub struct {
-	ch   chan agent.Message
-	once sync.Once
-}
-
-func (s *sub) close() {
-	s.once.Do(func() { close(s.ch) })
-}
-
-// Subscribe returns a snapshot of the message history and a channel that
-// receives only live messages arriving after the snapshot. The caller must
-// write the history to the client first, then range over the channel.
-// The returned function unsubscribes and must be called exactly once.
-func (t *Task) Subscribe(ctx context.Context) (history []agent.Message, live <-chan agent.Message, unsubFn func()) {
-	s := &sub{ch: make(chan agent.Message, 256)}
-
-	t.mu.Lock()
-	// Snapshot history under lock — no channel writes, so no deadlock risk
-	// regardless of history size.
-	history = append([]agent.Message(nil), t.msgs...)
-	t.subs = append(t.subs, s)
-	t.mu.Unlock()
-
-	unsub := func() {
-		t.mu.Lock()
-		defer t.mu.Unlock()
-		for i, ss := range t.subs {
-			if ss == s {
-				t.subs = append(t.subs[:i], t.subs[i+1:]...)
-				break
-			}
-		}
-	}
-
-	// Close channel when context is done.
-	go func() {
-		<-ctx.Done()
-		unsub()
-		s.close()
-	}()
-
-	return history, s.ch, unsub
-}
-
 // PushStats records a container stats snapshot and notifies live subscribers.
 func (t *Task) PushStats(s *ContainerStats) {
 	t.mu.Lock()
@@ -1043,7 +1025,7 @@ func (t *Task) SendInput(ctx context.Context, p agent.Prompt) error {
 		}
 	}
 	state := t.state
-	if h != nil && (state == StateWaiting || state == StateAsking || state == StateHasPlan) {
+	if h != nil && (state == StateWaiting || state == StateAsking || state == StateHasPlan || state == StatePlanReview || state == StateRateLimited) {
 		t.setState(StateRunning)
 		// Plan content is preserved — the UI hides naturally while the
 		// task is Running (isWaiting is false). When the agent finishes,
@@ -1083,6 +1065,34 @@ func (t *Task) SendCompact(ctx context.Context, instructions string) error {
 	return h.Session.SendCompact(instructions)
 }
 
+// Interrupt cancels the agent's current turn without ending the session. The
+// agent ends the turn with a result, which returns the task to StateWaiting;
+// the turn's partial output stays in the transcript. Returns an error if the
+// task is not running or the backend cannot interrupt a turn.
+func (t *Task) Interrupt(ctx context.Context) error {
+	_ = ctx
+	t.mu.Lock()
+	h := t.handle
+	sessionStatus := SessionNone
+	if h != nil {
+		select {
+		case <-h.Session.Done():
+			sessionStatus = SessionExited
+			h = nil
+		default:
+		}
+	}
+	state := t.state
+	t.mu.Unlock()
+	if state != StateRunning {
+		return fmt.Errorf("task is not running (state=%s)", state)
+	}
+	if h == nil {
+		return fmt.Errorf("no active session (state=%s session=%s)", state, sessionStatus)
+	}
+	return h.Session.SendInterrupt()
+}
+
 // computeCost returns the true USD cost for a Claude API result by adding the
 // cache-read surcharge that TotalCostUSD omits.
 //
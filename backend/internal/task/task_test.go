@@ -14,30 +14,44 @@ import (
 
 func TestTask(t *testing.T) {
 	t.Run("Subscribe", func(t *testing.T) {
-		t.Run("SlowSubscriberThenCancel", func(t *testing.T) {
-			// Regression test: if the fan-out drops a slow subscriber
-			// (buffer full) and closes its channel, the context-done
-			// goroutine must not panic on a double close.
+		t.Run("SlowSubscriberOverflow", func(t *testing.T) {
+			// A subscriber that does not read loses the oldest messages and
+			// gets an OverflowMessage instead of stalling the producer.
 			tk := &Task{InitialPrompt: agent.Prompt{Text: "test"}}
 			ctx, cancel := context.WithCancel(t.Context())
 			_, ch, unsub := tk.Subscribe(ctx)
 			defer unsub()
 
-			// Fill the subscriber buffer (256) so the next send overflows.
-			for range 256 {
+			const n = subRingSize + 10
+			for range n {
 				tk.addMessage(t.Context(), &agent.SystemMessage{MessageType: "system", Subtype: "status"}, false)
 			}
-			// This send should trigger the slow-subscriber drop+close.
-			tk.addMessage(t.Context(), &agent.SystemMessage{MessageType: "system", Subtype: "status"}, false)
+			if st := tk.SubscriberStats(); st.Subscribers != 1 || st.Dropped == 0 || st.MaxLag != subRingSize {
+				t.Errorf("SubscriberStats() = %+v", st)
+			}
 
-			// Drain to confirm channel was closed by fan-out.
-			for range ch {
+			// The pump may have taken one message before the ring filled up.
+			got, dropped := 0, 0
+			for got+dropped < n {
+				select {
+				case m := <-ch:
+					if ov, ok := m.(*OverflowMessage); ok {
+						dropped += ov.Dropped
+					} else {
+						got++
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("timed out after %d messages, %d dropped", got, dropped)
+				}
+			}
+			if dropped != 10 && dropped != 11 {
+				t.Errorf("dropped = %d, want 10 or 11", dropped)
 			}
 
-			// Cancel the context. The goroutine must not panic.
+			// Cancelling closes the channel.
 			cancel()
-			// Give the goroutine time to execute.
-			time.Sleep(50 * time.Millisecond)
+			for range ch {
+			}
 		})
 		t.Run("Replay", func(t *testing.T) {
 			tk := &Task{InitialPrompt: agent.Prompt{Text: "test"}}
@@ -471,6 +485,17 @@ func TestTask(t *testing.T) {
 				t.Errorf("state = %v, want %v", tk.GetState(), StateHasPlan)
 			}
 		})
+		t.Run("TransitionsToPlanReview", func(t *testing.T) {
+			tk := &Task{InitialPrompt: agent.Prompt{Text: "test"}, RequirePlan: true}
+			tk.SetState(StateRunning)
+			tk.addMessage(t.Context(), &agent.ResultMessage{MessageType: "result", Result: "the plan"}, false)
+			if tk.GetState() != StatePlanReview {
+				t.Errorf("state = %v, want %v", tk.GetState(), StatePlanReview)
+			}
+			if !tk.planMode() {
+				t.Error("planMode() = false before approval")
+			}
+		})
 		t.Run("AskingTakesPriorityOverHasPlan", func(t *testing.T) {
 			// Both AskMessage and ExitPlanMode in same turn → StateAsking.
 			tk := &Task{InitialPrompt: agent.Prompt{Text: "test"}}
@@ -1188,6 +1213,26 @@ func TestTask(t *testing.T) {
 				t.Errorf("state = %v, want %v", tk.GetState(), StateHasPlan)
 			}
 		})
+		t.Run("PlanApproved", func(t *testing.T) {
+			// The caic_plan_approved marker ends the review phase on reload.
+			for _, tt := range []struct {
+				name string
+				msgs []agent.Message
+				want State
+			}{
+				{"Pending", []agent.Message{&agent.ResultMessage{MessageType: "result"}}, StatePlanReview},
+				{"Approved", []agent.Message{syntheticPlanApproved(), &agent.ResultMessage{MessageType: "result"}}, StateWaiting},
+			} {
+				t.Run(tt.name, func(t *testing.T) {
+					tk := &Task{InitialPrompt: agent.Prompt{Text: "test"}, RequirePlan: true}
+					tk.SetState(StateRunning)
+					tk.RestoreMessages(tt.msgs)
+					if tk.GetState() != tt.want {
+						t.Errorf("state = %v, want %v", tk.GetState(), tt.want)
+					}
+				})
+			}
+		})
 		t.Run("SkipsTrailingDiffStat", func(t *testing.T) {
 			// The relay emits DiffStatMessage after the ResultMessage.
 			// RestoreMessages should skip it and still infer Waiting.
@@ -1441,6 +1486,7 @@ func TestState(t *testing.T) {
 			{StateWaiting, "waiting"},
 			{StateAsking, "asking"},
 			{StateHasPlan, "has_plan"},
+			{StatePlanReview, "plan_review"},
 			{StatePulling, "pulling"},
 			{StatePushing, "pushing"},
 			{StatePurging, "purging"},
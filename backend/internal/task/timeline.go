@@ -0,0 +1,178 @@
+// Task timeline: where the wall-clock time of a task went, phase by phase,
+// derived from its state transitions and the output of its turns.
+package task
+
+import (
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+// Timeline phase names.
+const (
+	PhaseQueued         = "queued"          // Pending: queue, dependencies, execution window.
+	PhaseBranching      = "branching"       // Creating the git branch.
+	PhaseContainerStart = "container_start" // Starting the container.
+	PhaseAgentStart     = "agent_start"     // Launching the agent session.
+	PhaseTurn           = "turn"            // The agent is executing.
+	PhaseRateLimited    = "rate_limited"    // Backing off a provider rate limit.
+	PhaseAwaitingInput  = "awaiting_input"  // Waiting for the user: idle, question or plan.
+	PhasePull           = "pull"            // Pulling changes from the container.
+	PhasePush           = "push"            // Pushing to the remote.
+	PhaseTeardown       = "teardown"        // Stopping or purging the container.
+	PhaseVerification   = "verification"    // A verification script, e.g. pre-push checks.
+)
+
+// Phase is a span of the timeline of a task.
+type Phase struct {
+	Name  string
+	Start time.Time
+	End   time.Time // Zero while in progress.
+	// Turn is the 1-based number of a PhaseTurn.
+	Turn int
+	// FirstOutput is when the agent first produced output in a PhaseTurn;
+	// zero when it produced none or the turn predates a server restart.
+	FirstOutput time.Time
+	// Err is the error of a failed PhaseVerification.
+	Err string
+}
+
+// turnOutput records when the agent first produced output in the turn started
+// at start.
+type turnOutput struct {
+	start, first time.Time
+}
+
+// statePhase returns the name of the phase a task spends in state s, or ""
+// for the final states.
+func statePhase(s State) string {
+	switch s {
+	case StatePending:
+		return PhaseQueued
+	case StateBranching:
+		return PhaseBranching
+	case StateProvisioning:
+		return PhaseContainerStart
+	case StateStarting:
+		return PhaseAgentStart
+	case StateRunning:
+		return PhaseTurn
+	case StateRateLimited:
+		return PhaseRateLimited
+	case StateWaiting, StateAsking, StateHasPlan, StatePlanReview:
+		return PhaseAwaitingInput
+	case StatePulling:
+		return PhasePull
+	case StatePushing:
+		return PhasePush
+	case StateStopping, StatePurging:
+		return PhaseTeardown
+	case StateStopped, StateFailed, StatePurged:
+		return ""
+	default:
+		return ""
+	}
+}
+
+// isAgentOutput reports whether m is output of the agent working on a turn,
+// as opposed to bookkeeping of the session.
+func isAgentOutput(m agent.Message) bool {
+	switch m.(type) {
+	case *agent.TextMessage, *agent.TextDeltaMessage, *agent.ThinkingMessage, *agent.ThinkingDeltaMessage,
+		*agent.ToolUseMessage, *agent.AskMessage, *agent.TodoMessage, *agent.WidgetMessage, *agent.WidgetDeltaMessage:
+		return true
+	default:
+		return false
+	}
+}
+
+// recordOutput notes the first output of the current turn. The caller must
+// hold t.mu.
+func (t *Task) recordOutput(m agent.Message) {
+	if t.turnStartedAt.IsZero() || !isAgentOutput(m) {
+		return
+	}
+	if n := len(t.turnOutputs); n > 0 && t.turnOutputs[n-1].start.Equal(t.turnStartedAt) {
+		return
+	}
+	t.turnOutputs = append(t.turnOutputs, turnOutput{start: t.turnStartedAt, first: time.Now().UTC()})
+	if n := len(t.turnOutputs) - maxTransitions; n > 0 {
+		t.turnOutputs = t.turnOutputs[n:]
+	}
+}
+
+// recordVerification adds a verification run to the timeline.
+func (t *Task) recordVerification(start, end time.Time, err error) {
+	p := Phase{Name: PhaseVerification, Start: start.UTC(), End: end.UTC()}
+	if err != nil {
+		p.Err = err.Error()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.verifications = append(t.verifications, p)
+	if n := len(t.verifications) - maxTransitions; n > 0 {
+		t.verifications = t.verifications[n:]
+	}
+}
+
+// Timeline returns the phases of the task, oldest first. Consecutive states
+// of the same phase are merged, and the verification runs, which overlap the
+// state phases, are listed where they started.
+func (t *Task) Timeline() []Phase {
+	t.mu.Lock()
+	trs := t.transitions
+	outputs := t.turnOutputs
+	verifications := t.verifications
+	state := t.state
+	t.mu.Unlock()
+
+	var out []Phase
+	open := func(s State, at time.Time) {
+		name := statePhase(s)
+		if n := len(out); n > 0 && out[n-1].End.IsZero() {
+			if out[n-1].Name == name && name != PhaseTurn {
+				return
+			}
+			out[n-1].End = at
+		}
+		if name == "" {
+			return
+		}
+		p := Phase{Name: name, Start: at}
+		if name == PhaseTurn {
+			p.Turn = 1
+			for _, q := range out {
+				if q.Name == PhaseTurn {
+					p.Turn++
+				}
+			}
+			for _, o := range outputs {
+				if o.start.Equal(at) {
+					p.FirstOutput = o.first
+				}
+			}
+		}
+		out = append(out, p)
+	}
+	if len(trs) == 0 {
+		if !t.StartedAt.IsZero() {
+			open(state, t.StartedAt.UTC())
+		}
+	} else {
+		// The history starts with the task: it was pending since created.
+		if first := trs[0]; first.From == StatePending && !t.StartedAt.IsZero() && t.StartedAt.Before(first.At) {
+			open(StatePending, t.StartedAt.UTC())
+		}
+		for _, tr := range trs {
+			open(tr.To, tr.At)
+		}
+	}
+	for _, v := range verifications {
+		i := len(out)
+		for i > 0 && out[i-1].Start.After(v.Start) {
+			i--
+		}
+		out = append(out[:i], append([]Phase{v}, out[i:]...)...)
+	}
+	return out
+}
@@ -0,0 +1,71 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+)
+
+func TestTimeline(t *testing.T) {
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	at := func(s int) time.Time { return t0.Add(time.Duration(s) * time.Second) }
+	t.Run("Phases", func(t *testing.T) {
+		tk := &Task{StartedAt: at(0)}
+		tk.RestoreTransitions([]Transition{
+			{From: StatePending, To: StateBranching, At: at(60)},
+			{From: StateBranching, To: StateProvisioning, At: at(61)},
+			{From: StateProvisioning, To: StateStarting, At: at(90)},
+			{From: StateStarting, To: StateRunning, At: at(95)},
+			{From: StateRunning, To: StateWaiting, At: at(200)},
+			{From: StateWaiting, To: StateAsking, At: at(210)},
+			{From: StateAsking, To: StateRunning, At: at(300)},
+			{From: StateRunning, To: StatePushing, At: at(400)},
+			{From: StatePushing, To: StateWaiting, At: at(410)},
+			{From: StateWaiting, To: StatePurged, At: at(500)},
+		})
+		tk.turnOutputs = []turnOutput{{start: at(95), first: at(120)}}
+		tk.recordVerification(at(395), at(399), errors.New("exit 1"))
+		got := tk.Timeline()
+		want := []Phase{
+			{Name: PhaseQueued, Start: at(0), End: at(60)},
+			{Name: PhaseBranching, Start: at(60), End: at(61)},
+			{Name: PhaseContainerStart, Start: at(61), End: at(90)},
+			{Name: PhaseAgentStart, Start: at(90), End: at(95)},
+			{Name: PhaseTurn, Start: at(95), End: at(200), Turn: 1, FirstOutput: at(120)},
+			{Name: PhaseAwaitingInput, Start: at(200), End: at(300)},
+			{Name: PhaseTurn, Start: at(300), End: at(400), Turn: 2},
+			{Name: PhaseVerification, Start: at(395), End: at(399), Err: "exit 1"},
+			{Name: PhasePush, Start: at(400), End: at(410)},
+			{Name: PhaseAwaitingInput, Start: at(410), End: at(500)},
+		}
+		if len(got) != len(want) {
+			t.Fatalf("timeline = %+v", got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("phase %d = %+v, want %+v", i, got[i], want[i])
+			}
+		}
+	})
+	t.Run("FirstOutput", func(t *testing.T) {
+		tk := &Task{StartedAt: time.Now().UTC()}
+		tk.SetState(StateRunning)
+		// Bookkeeping isn't output.
+		tk.addMessage(context.Background(), &agent.UsageMessage{}, true)
+		if len(tk.turnOutputs) != 0 {
+			t.Fatalf("outputs = %+v", tk.turnOutputs)
+		}
+		tk.addMessage(context.Background(), &agent.TextDeltaMessage{Text: "hi"}, true)
+		tk.addMessage(context.Background(), &agent.TextMessage{Text: "hi"}, true)
+		got := tk.Timeline()
+		if n := len(got); n == 0 || got[n-1].Name != PhaseTurn || got[n-1].FirstOutput.IsZero() || !got[n-1].End.IsZero() {
+			t.Fatalf("timeline = %+v", got)
+		}
+		if len(tk.turnOutputs) != 1 {
+			t.Errorf("outputs = %+v, want the first one only", tk.turnOutputs)
+		}
+	})
+}
@@ -0,0 +1,179 @@
+// Saved workspaces: the workspace of a task kept in the host repo so that it
+// survives its container and can be rehydrated into a fresh one.
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/caic-xyz/caic/backend/internal/agent"
+	"github.com/caic-xyz/caic/backend/internal/logctx"
+	"github.com/caic-xyz/md/gitutil"
+)
+
+// workspaceContainerRef is where the workspace commit is staged in the
+// container's repo, to be fetched by the host or restored from.
+const workspaceContainerRef = "refs/caic/workspace"
+
+// saveWorkspaceScript commits the workspace like createSnapshotScript does,
+// overwriting the previous workspace commit.
+const saveWorkspaceScript = `set -e
+idx=$(git rev-parse --git-path index)
+export GIT_INDEX_FILE="$idx.caic-workspace"
+trap 'rm -f "$GIT_INDEX_FILE"' EXIT
+cp "$idx" "$GIT_INDEX_FILE" 2>/dev/null || rm -f "$GIT_INDEX_FILE"
+git add -A
+commit=$(git commit-tree "$(git write-tree)" -p HEAD -m "caic workspace")
+git update-ref ` + workspaceContainerRef + ` "$commit"
+echo "$commit"
+`
+
+// restoreWorkspaceScript is restoreSnapshotScript for the workspace commit.
+const restoreWorkspaceScript = `set -e
+ref=` + workspaceContainerRef + `
+git reset -q --hard "$ref^"
+git clean -q -fd
+git checkout "$ref" -- .
+git reset -q
+`
+
+// WorkspaceRef returns the host ref holding the saved workspace of t. It is a
+// commit whose parent is the last commit of the task's branch and whose tree
+// includes the uncommitted and untracked files.
+func WorkspaceRef(t *Task) string {
+	return "refs/caic/workspaces/" + t.ID.String()
+}
+
+// SaveWorkspace commits the workspace of the task's primary repo, including
+// uncommitted and untracked files, in its container and fetches the commit
+// into the host repo under WorkspaceRef. The container must be running.
+func (r *Runner) SaveWorkspace(ctx context.Context, t *Task) (string, error) {
+	if t.Chat || t.ReadOnly {
+		return "", errors.New("task has no workspace to save")
+	}
+	commit, err := r.snapshotExec(ctx, t, saveWorkspaceScript)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	r.branchMu.Lock()
+	defer r.branchMu.Unlock()
+	if _, err := gitutil.RunGit(ctx, r.Dir, "fetch", "-q", t.Container, "+"+workspaceContainerRef+":"+WorkspaceRef(t)); err != nil {
+		return "", fmt.Errorf("fetch workspace: %w", err)
+	}
+	return commit, nil
+}
+
+// HasSavedWorkspace reports whether SaveWorkspace saved the task's workspace.
+func (r *Runner) HasSavedWorkspace(ctx context.Context, t *Task) bool {
+	if r.Dir == "" {
+		return false
+	}
+	r.initDefaults()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	_, err := gitutil.RevParse(ctx, r.Dir, WorkspaceRef(t)+"^{commit}")
+	return err == nil
+}
+
+// DeleteSavedWorkspace deletes the task's saved workspace, if any.
+func (r *Runner) DeleteSavedWorkspace(ctx context.Context, t *Task) error {
+	if r.Dir == "" {
+		return nil
+	}
+	r.initDefaults()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	_, err := gitutil.RunGit(ctx, r.Dir, "update-ref", "-d", WorkspaceRef(t))
+	return err
+}
+
+// saveWorkspace saves the workspace when the task asks for it, before its
+// container goes away. Failures are logged: the task ends regardless.
+func (r *Runner) saveWorkspace(ctx context.Context, t *Task) {
+	if !t.Persist || t.Container == "" || r.Container == nil || r.Dir == "" || t.Chat || t.ReadOnly {
+		return
+	}
+	if commit, err := r.SaveWorkspace(ctx, t); err != nil {
+		r.log.WarnContext(ctx, "save workspace failed", "ctr", t.Container, "err", err)
+	} else {
+		r.log.InfoContext(ctx, "workspace saved", "ctr", t.Container, "commit", commit)
+	}
+}
+
+// restoreWorkspace pushes the saved workspace into the task's container and
+// restores it, so the files that were uncommitted are uncommitted again.
+func (r *Runner) restoreWorkspace(ctx context.Context, t *Task) error {
+	gitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+	defer cancel()
+	if _, err := gitutil.RunGit(gitCtx, r.Dir, "push", "-q", "-f", t.Container, WorkspaceRef(t)+":"+workspaceContainerRef); err != nil {
+		return fmt.Errorf("push workspace: %w", err)
+	}
+	_, err := r.snapshotExec(ctx, t, restoreWorkspaceScript)
+	return err
+}
+
+// RehydrateTask starts a fresh container for a task whose container is gone,
+// e.g. removed while the task was stopped, and restores the workspace saved
+// by SaveWorkspace into it. The task's branch is reset to the saved commits.
+// Only the primary repo's workspace is restored; the extra repos restart from
+// their branches. The agent's session was lost with the container, so a new
+// idle one starts and the task waits for input.
+func (r *Runner) RehydrateTask(ctx context.Context, t *Task) (*SessionHandle, error) {
+	ctx = logctx.With(ctx, "task", t.ID)
+	r.initDefaults()
+	if r.Container == nil {
+		return nil, errors.New("runner has no container backend configured")
+	}
+	if r.Dir == "" || t.Chat || !r.HasSavedWorkspace(ctx, t) {
+		return nil, errors.New("task has no saved workspace")
+	}
+	p := t.Primary()
+	tlog := r.log.With("br", p.Branch, "ctr", t.Container)
+
+	// 1. Remove what is left of the previous container, notably the host's git
+	// remote named after it, since the new one takes its name.
+	t.SetState(StateProvisioning)
+	if t.Container != "" {
+		if err := r.PurgeContainer(ctx, t.Container, p.Branch, t.ExtraMDRepos()); err != nil {
+			tlog.InfoContext(ctx, "purge previous container", "err", err)
+		}
+	}
+
+	// 2. Start a fresh container on the branch reset to the saved commits.
+	tlog.InfoContext(ctx, "rehydrating workspace")
+	sr, err := r.provision(ctx, t, func(ctx context.Context) error {
+		r.branchMu.Lock()
+		defer r.branchMu.Unlock()
+		gitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.GitTimeout)
+		defer cancel()
+		_, err := gitutil.RunGit(gitCtx, r.Dir, "branch", "-f", p.Branch, WorkspaceRef(t)+"^")
+		return err
+	})
+	if err != nil {
+		t.SetState(StateFailed)
+		return nil, err
+	}
+	t.Container = sr.Container
+	t.TailscaleFQDN = sr.TailscaleFQDN
+	tlog = r.log.With("br", p.Branch, "ctr", t.Container)
+	if err := r.restoreWorkspace(ctx, t); err != nil {
+		t.SetState(StateFailed)
+		return nil, fmt.Errorf("restore workspace: %w", err)
+	}
+
+	// 3. Start a new session.
+	t.SetState(StateWaiting)
+	h, err := r.StartSession(ctx, t, agent.Prompt{})
+	if err != nil {
+		t.SetState(StateFailed)
+		return nil, err
+	}
+	if ds := r.BranchDiffStat(ctx, p.Branch, t.ExtraMDRepos()); len(ds) > 0 {
+		t.SetLiveDiffStat(ds)
+	}
+	tlog.InfoContext(ctx, "agent ready after rehydrate", "state", t.GetState())
+	return h, nil
+}
@@ -0,0 +1,105 @@
+// Jira Cloud and Data Center issues through the REST API v2, whose texts are plain.
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/maruel/roundtrippers"
+)
+
+// Jira is a Jira client authenticated with an account's email and API token.
+type Jira struct {
+	HTTPClient *http.Client
+	BaseURL    string // e.g. "https://example.atlassian.net"
+}
+
+var _ Tracker = (*Jira)(nil)
+
+// NewJira returns a client of the Jira site at baseURL.
+func NewJira(baseURL, email, token string) *Jira {
+	return &Jira{
+		HTTPClient: &http.Client{
+			Transport: &roundtrippers.Header{
+				Transport: &roundtrippers.Retry{Transport: http.DefaultTransport},
+				Header: http.Header{
+					"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(email+":"+token))},
+					"Accept":        {"application/json"},
+					"Content-Type":  {"application/json"},
+				},
+			},
+		},
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Kind implements Tracker.
+func (j *Jira) Kind() Kind { return KindJira }
+
+// jiraIssue is the relevant subset of GET /rest/api/2/issue/{key}.
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+	} `json:"fields"`
+}
+
+// Issue implements Tracker.
+func (j *Jira) Issue(ctx context.Context, key string) (*Issue, error) {
+	apiURL := j.BaseURL + "/rest/api/2/issue/" + url.PathEscape(key) + "?fields=summary,description"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jira get issue: status %d: %s", resp.StatusCode, data)
+	}
+	var r jiraIssue
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &Issue{Key: r.Key, Title: r.Fields.Summary, Description: r.Fields.Description, URL: j.BaseURL + "/browse/" + r.Key}, nil
+}
+
+// Comment implements Tracker.
+func (j *Jira) Comment(ctx context.Context, key, body string) error {
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return err
+	}
+	apiURL := j.BaseURL + "/rest/api/2/issue/" + url.PathEscape(key) + "/comment"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp, err := j.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusCreated {
+		data, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("jira comment: status %d: %s", resp.StatusCode, data)
+	}
+	return nil
+}
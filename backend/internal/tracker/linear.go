@@ -0,0 +1,130 @@
+// Linear issues through its GraphQL API.
+package tracker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/maruel/roundtrippers"
+)
+
+// Linear is a Linear client authenticated with a personal API key.
+type Linear struct {
+	HTTPClient *http.Client
+	Endpoint   string // GraphQL endpoint; defaults to linearEndpoint.
+}
+
+var _ Tracker = (*Linear)(nil)
+
+const linearEndpoint = "https://api.linear.app/graphql"
+
+// NewLinear returns a Linear client authenticated with apiKey.
+func NewLinear(apiKey string) *Linear {
+	return &Linear{
+		HTTPClient: &http.Client{
+			Transport: &roundtrippers.Header{
+				Transport: &roundtrippers.Retry{Transport: http.DefaultTransport},
+				Header: http.Header{
+					"Authorization": {apiKey},
+					"Content-Type":  {"application/json"},
+				},
+			},
+		},
+		Endpoint: linearEndpoint,
+	}
+}
+
+// Kind implements Tracker.
+func (l *Linear) Kind() Kind { return KindLinear }
+
+// linearIssue is the issue selected by the queries.
+type linearIssue struct {
+	ID          string `json:"id"`
+	Identifier  string `json:"identifier"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+}
+
+// Issue implements Tracker. Linear accepts the identifier, e.g. "ENG-123",
+// in place of the issue ID.
+func (l *Linear) Issue(ctx context.Context, key string) (*Issue, error) {
+	var r struct {
+		Issue *linearIssue `json:"issue"`
+	}
+	const q = `query($id: String!) { issue(id: $id) { id identifier title description url } }`
+	if err := l.query(ctx, q, map[string]any{"id": key}, &r); err != nil {
+		return nil, err
+	}
+	if r.Issue == nil {
+		return nil, ErrNotFound
+	}
+	return &Issue{Key: r.Issue.Identifier, Title: r.Issue.Title, Description: r.Issue.Description, URL: r.Issue.URL}, nil
+}
+
+// Comment implements Tracker.
+func (l *Linear) Comment(ctx context.Context, key, body string) error {
+	var r struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+		} `json:"commentCreate"`
+	}
+	const q = `mutation($id: String!, $body: String!) { commentCreate(input: {issueId: $id, body: $body}) { success } }`
+	if err := l.query(ctx, q, map[string]any{"id": key, "body": body}, &r); err != nil {
+		return err
+	}
+	if !r.CommentCreate.Success {
+		return errors.New("linear comment: not created")
+	}
+	return nil
+}
+
+// query runs a GraphQL query and decodes its data into out.
+func (l *Linear) query(ctx context.Context, q string, vars map[string]any, out any) error {
+	payload, err := json.Marshal(map[string]any{"query": q, "variables": vars})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, l.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp, err := l.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("linear: status %d: %s", resp.StatusCode, data)
+	}
+	var r struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message    string `json:"message"`
+			Extensions struct {
+				Code string `json:"code"`
+			} `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
+	}
+	if len(r.Errors) != 0 {
+		e := r.Errors[0]
+		if e.Extensions.Code == "ENTITY_NOT_FOUND" || strings.Contains(strings.ToLower(e.Message), "not found") {
+			return ErrNotFound
+		}
+		return fmt.Errorf("linear: %s", e.Message)
+	}
+	return json.Unmarshal(r.Data, out)
+}
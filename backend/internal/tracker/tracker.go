@@ -0,0 +1,80 @@
+// Package tracker links tasks to the issues of Jira and Linear and posts
+// their progress there.
+package tracker
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// ErrNotFound is returned when an issue does not exist in a tracker.
+var ErrNotFound = errors.New("issue not found")
+
+// Kind identifies an issue tracker.
+type Kind string
+
+// Supported issue trackers.
+const (
+	KindJira   Kind = "jira"
+	KindLinear Kind = "linear"
+)
+
+// Issue is an issue of a tracker.
+type Issue struct {
+	Key         string // e.g. "PROJ-123"
+	Title       string
+	Description string
+	URL         string
+}
+
+// Tracker is an issue tracker API.
+type Tracker interface {
+	Kind() Kind
+	// Issue returns the issue key, or ErrNotFound.
+	Issue(ctx context.Context, key string) (*Issue, error)
+	// Comment posts a plain text comment on the issue key.
+	Comment(ctx context.Context, key, body string) error
+}
+
+// keyRe matches issue keys, e.g. "PROJ-123": both Jira and Linear use the
+// project or team key, a dash and the issue number.
+var keyRe = regexp.MustCompile(`\b[A-Z][A-Z0-9]{1,9}-[1-9][0-9]{0,6}\b`)
+
+// FindKeys returns the distinct issue keys mentioned in text, in order.
+func FindKeys(text string) []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, k := range keyRe.FindAllString(text, -1) {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// IsKey reports whether s is an issue key.
+func IsKey(s string) bool {
+	m := keyRe.FindString(s)
+	return m != "" && m == s
+}
+
+// Resolve returns the first issue among keys found in one of trackers, with
+// the tracker it is in. It returns nil when none is found; other errors are
+// returned only when no issue is found.
+func Resolve(ctx context.Context, trackers []Tracker, keys []string) (Tracker, *Issue, error) {
+	var errs []error
+	for _, key := range keys {
+		for _, t := range trackers {
+			issue, err := t.Issue(ctx, key)
+			if err == nil {
+				return t, issue, nil
+			}
+			if !errors.Is(err, ErrNotFound) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return nil, nil, errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+// Tests for the issue trackers.
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindKeys(t *testing.T) {
+	got := FindKeys("Fix PROJ-123 and ENG-7, see PROJ-123; not abc-1, A-1, X1-0 or PROJ-123x.")
+	if want := []string{"PROJ-123", "ENG-7"}; !slices.Equal(got, want) {
+		t.Errorf("FindKeys = %q, want %q", got, want)
+	}
+	for in, want := range map[string]bool{"ENG-42": true, "eng-42": false, "ENG-42 ": false, "ENG": false} {
+		if got := IsKey(in); got != want {
+			t.Errorf("IsKey(%q) = %t, want %t", in, got, want)
+		}
+	}
+}
+
+func TestJira(t *testing.T) {
+	var comment string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "me@example.com" || p != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /rest/api/2/issue/PROJ-1":
+			_, _ = io.WriteString(w, `{"key":"PROJ-1","fields":{"summary":"Crash","description":"It crashes."}}`)
+		case "POST /rest/api/2/issue/PROJ-1/comment":
+			var b struct{ Body string }
+			_ = json.NewDecoder(r.Body).Decode(&b)
+			comment = b.Body
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	j := NewJira(srv.URL+"/", "me@example.com", "tok")
+	issue, err := j.Issue(t.Context(), "PROJ-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Issue{Key: "PROJ-1", Title: "Crash", Description: "It crashes.", URL: srv.URL + "/browse/PROJ-1"}); *issue != want {
+		t.Errorf("Issue = %+v, want %+v", *issue, want)
+	}
+	if _, err := j.Issue(t.Context(), "PROJ-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing issue: err = %v, want ErrNotFound", err)
+	}
+	if err := j.Comment(t.Context(), "PROJ-1", "started"); err != nil || comment != "started" {
+		t.Errorf("Comment = %v, posted %q", err, comment)
+	}
+}
+
+func TestLinear(t *testing.T) {
+	var comment string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var q struct {
+			Query     string
+			Variables map[string]string
+		}
+		_ = json.NewDecoder(r.Body).Decode(&q)
+		switch {
+		case strings.HasPrefix(q.Query, "mutation"):
+			comment = q.Variables["id"] + ": " + q.Variables["body"]
+			_, _ = io.WriteString(w, `{"data":{"commentCreate":{"success":true}}}`)
+		case q.Variables["id"] == "ENG-1":
+			_, _ = io.WriteString(w, `{"data":{"issue":{"id":"u1","identifier":"ENG-1","title":"Slow","description":"Too slow.","url":"https://linear.app/x/issue/ENG-1"}}}`)
+		default:
+			_, _ = io.WriteString(w, `{"data":null,"errors":[{"message":"Entity not found: Issue","extensions":{"code":"ENTITY_NOT_FOUND"}}]}`)
+		}
+	}))
+	defer srv.Close()
+	l := NewLinear("lin_key")
+	l.Endpoint = srv.URL
+	issue, err := l.Issue(t.Context(), "ENG-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Issue{Key: "ENG-1", Title: "Slow", Description: "Too slow.", URL: "https://linear.app/x/issue/ENG-1"}); *issue != want {
+		t.Errorf("Issue = %+v, want %+v", *issue, want)
+	}
+	if _, err := l.Issue(t.Context(), "ENG-2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing issue: err = %v, want ErrNotFound", err)
+	}
+	if err := l.Comment(t.Context(), "ENG-1", "done"); err != nil || comment != "ENG-1: done" {
+		t.Errorf("Comment = %v, posted %q", err, comment)
+	}
+
+	tr, got, err := Resolve(t.Context(), []Tracker{l}, []string{"ENG-2", "ENG-1"})
+	if err != nil || tr != l || got == nil || got.Key != "ENG-1" {
+		t.Errorf("Resolve = %v, %+v, %v", tr, got, err)
+	}
+}
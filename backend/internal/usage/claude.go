@@ -163,6 +163,30 @@ func (f *ClaudeFetcher) Get(ctx context.Context) *v1.ClaudeUsage {
 	return resp
 }
 
+// CheckCredentials verifies that the token read from ~/.claude/.credentials.json is accepted
+// by the usage API. A fresh cached response counts as success.
+func (f *ClaudeFetcher) CheckCredentials(ctx context.Context) error {
+	if f == nil {
+		return ErrNoCredentials
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token == "" {
+		return ErrNoCredentials
+	}
+	if f.cached != nil && time.Since(f.fetchAt) < CacheTTL {
+		return nil
+	}
+	resp, err := f.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	f.backoff = 0
+	f.cached = resp
+	f.fetchAt = time.Now()
+	return nil
+}
+
 func (f *ClaudeFetcher) fetch(ctx context.Context) (*v1.ClaudeUsage, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, usageAPIURL, http.NoBody)
 	if err != nil {
@@ -159,6 +159,30 @@ func (f *CodexFetcher) Get(ctx context.Context) *v1.CodexUsage {
 	return resp
 }
 
+// CheckCredentials verifies that the token read from ~/.codex/auth.json is accepted
+// by the usage API. A fresh cached response counts as success.
+func (f *CodexFetcher) CheckCredentials(ctx context.Context) error {
+	if f == nil {
+		return ErrNoCredentials
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token == "" {
+		return ErrNoCredentials
+	}
+	if f.cached != nil && time.Since(f.fetchAt) < CacheTTL {
+		return nil
+	}
+	resp, err := f.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	f.backoff = 0
+	f.cached = resp
+	f.fetchAt = time.Now()
+	return nil
+}
+
 func (f *CodexFetcher) fetch(ctx context.Context) (*v1.CodexUsage, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, codexUsageAPIURL, http.NoBody)
 	if err != nil {
@@ -193,7 +217,7 @@ func (f *CodexFetcher) fetch(ctx context.Context) (*v1.CodexUsage, error) {
 				UsedPercent:        raw.RateLimit.PrimaryWindow.UsedPercent,
 				LimitWindowSeconds: raw.RateLimit.PrimaryWindow.LimitWindowSeconds,
 				ResetAfterSeconds:  raw.RateLimit.PrimaryWindow.ResetAfterSeconds,
-				ResetAt:            raw.RateLimit.PrimaryWindow.ResetAt,
+				ResetAt:            unixTime(raw.RateLimit.PrimaryWindow.ResetAt),
 			}
 		}
 		if raw.RateLimit.SecondaryWindow != nil {
@@ -201,7 +225,7 @@ func (f *CodexFetcher) fetch(ctx context.Context) (*v1.CodexUsage, error) {
 				UsedPercent:        raw.RateLimit.SecondaryWindow.UsedPercent,
 				LimitWindowSeconds: raw.RateLimit.SecondaryWindow.LimitWindowSeconds,
 				ResetAfterSeconds:  raw.RateLimit.SecondaryWindow.ResetAfterSeconds,
-				ResetAt:            raw.RateLimit.SecondaryWindow.ResetAt,
+				ResetAt:            unixTime(raw.RateLimit.SecondaryWindow.ResetAt),
 			}
 		}
 	}
@@ -262,3 +286,11 @@ type codexCreditDetails struct {
 	Unlimited  bool   `json:"unlimited"`
 	Balance    string `json:"balance"`
 }
+
+// unixTime converts Unix epoch seconds to a time; 0 is unset.
+func unixTime(sec int) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(sec), 0).UTC()
+}
@@ -1,7 +1,10 @@
 // Package usage provides cached fetchers for coding agent usage quotas.
 package usage
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 const (
 	// CacheTTL is the duration before cached usage data is considered stale.
@@ -11,3 +14,6 @@ const (
 	backoffMin = 5 * time.Minute
 	backoffMax = 1 * time.Hour
 )
+
+// ErrNoCredentials is returned by CheckCredentials when no token was found.
+var ErrNoCredentials = errors.New("no credentials found")
@@ -21,8 +21,8 @@ vi.mock("./api", () => ({
   listRepoBranches: vi.fn(),
   cloneRepo: vi.fn(),
   createTask: vi.fn(),
-  stopTask: vi.fn(),
-  purgeTask: vi.fn(),
+  getTask: vi.fn(() => Promise.resolve({})),
+  ifMatch: vi.fn(() => ({ stopTask: vi.fn(), purgeTask: vi.fn() })),
   reviveTask: vi.fn(),
 }));
 
@@ -1,9 +1,9 @@
 // Main application component for caic web UI.
-import { createEffect, createSignal, For, Show, Switch, Match, onCleanup } from "solid-js";
+import { createEffect, createSignal, For, Show, Switch, Match, on, onCleanup } from "solid-js";
 import { Portal } from "solid-js/web";
 import { useNavigate, useLocation } from "@solidjs/router";
-import type { Harness, HarnessInfo, Repo, Task, TaskListEvent, UsageResp, ImageData as APIImageData, CacheMappingResp, WellKnownCachesResp } from "@sdk/types.gen";
-import { getConfig, getPreferences, updatePreferences, listHarnesses, listCaches, listRepos, createTask, cloneRepo, getUsage, forkTask, stopTask, purgeTask, reviveTask, botFixCI } from "./api";
+import type { Harness, HarnessInfo, Repo, Task, TaskListEvent, UsageResp, ImageData as APIImageData, CacheMappingResp, WellKnownCachesResp, UserSettings, PendingImage } from "@sdk/types.gen";
+import { getConfig, getPreferences, updatePreferences, listHarnesses, listCaches, listRepos, createTask, cloneRepo, getTask, getUsage, forkTask, ifMatch, reviveTask, setTaskPriority, validateBaseImage, botFixCI } from "./api";
 import RepoChipStrip from "./RepoChipStrip";
 import type { RepoEntry } from "./RepoChipStrip";
 import { useAuth } from "./AuthContext";
@@ -86,6 +86,9 @@ export default function App() {
   const [selectedRepos, setSelectedRepos] = createSignal<RepoEntry[]>([]);
   const [selectedModel, setSelectedModel] = createSignal("");
   const [selectedImage, setSelectedImage] = createSignal("");
+  const [validateImage, setValidateImage] = createSignal(false);
+  const [persistWorkspace, setPersistWorkspace] = createSignal(false);
+  const [pendingImage, setPendingImage] = createSignal<PendingImage>();
   const [harnesses, setHarnesses] = createSignal<HarnessInfo[]>([]);
   const [selectedHarness, setSelectedHarness] = createSignal("");
   const [sidebarOpen, setSidebarOpen] = createSignal(true);
@@ -95,26 +98,48 @@ export default function App() {
   const [tailscaleEnabled, setTailscaleEnabled] = createSignal(false);
   const [usbAvailable, setUSBAvailable] = createSignal(false);
   const [usbEnabled, setUSBEnabled] = createSignal(false);
+  const [network, setNetwork] = createSignal<"" | "full" | "none">("");
   const [displayAvailable, setDisplayAvailable] = createSignal(false);
   const [displayEnabled, setDisplayEnabled] = createSignal(false);
+  const [thinkingEnabled, setThinkingEnabled] = createSignal(false);
   const [recentCount, setRecentCount] = createSignal(0);
   const [actionId, setActionId] = createSignal<string | null>(null);
 
   const [autoFixCI, setAutoFixCI] = createSignal(false);
   const [autoFixPR, setAutoFixPR] = createSignal(false);
+  const [autoRespondReviews, setAutoRespondReviews] = createSignal(false);
+  const [idleHours, setIdleHours] = createSignal(0);
+  const [idleAction, setIdleAction] = createSignal("notify");
+  const [windowStart, setWindowStart] = createSignal("");
+  const [windowEnd, setWindowEnd] = createSignal("");
   const [gitHubTokenAccess, setGitHubTokenAccess] = createSignal("");
   const [useDefaultCaches, setUseDefaultCaches] = createSignal(true);
   const [wellKnownCaches, setWellKnownCaches] = createSignal<Record<string, boolean | undefined>>({});
   const [wellKnownCachesList, setWellKnownCachesList] = createSignal<WellKnownCachesResp["wellKnown"]>([]);
   const [cacheMappings, setCacheMappings] = createSignal<CacheMappingResp[]>([]);
+  const [genericURL, setGenericURL] = createSignal("");
+  const [genericKey, setGenericKey] = createSignal("");
+  const [genericHasKey, setGenericHasKey] = createSignal(false);
+  const [genericModel, setGenericModel] = createSignal("");
+  // Settings loaded from the server, so that those without a control here are
+  // not reset when saving.
+  let loadedSettings: Partial<UserSettings> = {};
   const [settingsOpen, setSettingsOpen] = createSignal(false);
 
   /** Build the current settings payload for updatePreferences, with optional overrides. */
   const currentSettings = (overrides: Partial<Parameters<typeof updatePreferences>[0]["settings"]> = {}) => ({
     settings: {
+      ...loadedSettings,
+      // An empty API key keeps the saved one.
+      genericHarness: genericURL() && genericModel() ? { baseURL: genericURL(), apiKey: genericKey() || undefined, model: genericModel() } : undefined,
       autoFixOnCIFailure: autoFixCI(),
       autoFixOnPROpen: autoFixPR(),
+      autoRespondToReviews: autoRespondReviews(),
+      idlePolicy: idleHours() > 0 ? { hours: idleHours(), action: idleAction() } : undefined,
+      executionWindow: windowStart() && windowEnd() && windowStart() !== windowEnd() ? { ...loadedSettings.executionWindow, start: windowStart(), end: windowEnd() } : undefined,
       baseImage: selectedImage() || "",
+      validateBaseImage: validateImage(),
+      persistWorkspace: persistWorkspace(),
       gitHubTokenAccess: gitHubTokenAccess() || undefined,
       useDefaultCaches: useDefaultCaches(),
       wellKnownCaches: wellKnownCaches() as Record<string, boolean>,
@@ -123,6 +148,15 @@ export default function App() {
     },
   });
 
+  /** Saves the generic harness and refreshes the harness list it appears in. */
+  const saveGenericHarness = async () => {
+    if (genericURL() && !genericModel()) return;
+    const prefs = await updatePreferences(currentSettings());
+    setGenericKey("");
+    setGenericHasKey(prefs.settings.genericHarness?.hasAPIKey ?? false);
+    setHarnesses(await listHarnesses());
+  };
+
   // Clone repo dialog state.
   const [cloneOpen, setCloneOpen] = createSignal(false);
   const [cloning, setCloning] = createSignal(false);
@@ -147,6 +181,7 @@ export default function App() {
   }
 
   const harnessSupportsImages = () => harnesses().find((h) => h.name === selectedHarness())?.supportsImages ?? false;
+  const harnessSupportsThinking = () => harnesses().find((h) => h.name === selectedHarness())?.supportsThinking ?? false;
 
   // Ref to the main prompt textarea for focusing after Escape.
   let promptRef: HTMLElement | undefined;
@@ -214,17 +249,51 @@ export default function App() {
     onCleanup(() => clearInterval(timer));
   }
 
-  const selectedId = (): string | null => taskIdFromPath(location.pathname);
+  // Links may name the task by its alias (e.g. /task/@w42); resolve it to the ID.
+  const pathRef = (): string | null => taskIdFromPath(location.pathname);
+  const selectedId = (): string | null => {
+    const ref = pathRef();
+    return ref === null ? null : (tasks().find((t) => t.alias === ref)?.id ?? ref);
+  };
   const selectedTask = (): Task | null => {
     const id = selectedId();
     return id !== null ? (tasks().find((t) => t.id === id) ?? null) : null;
   };
 
+  // The task list streams summaries without the prompt and plan; fetch them
+  // for the task on screen whenever its state changes.
+  const [taskDetail, setTaskDetail] = createSignal<Task | null>(null);
+  createEffect(
+    on(
+      () => [selectedId(), selectedTask()?.stateUpdatedAt, selectedTask()?.inPlanMode] as const,
+      ([id]) => {
+        if (id === null) {
+          setTaskDetail(null);
+          return;
+        }
+        getTask(id)
+          .then((t) => {
+            if (selectedId() === id) setTaskDetail(t);
+          })
+          .catch(() => {});
+      },
+    ),
+  );
+
   // Re-open sidebar when task view is closed while sidebar is collapsed.
   createEffect(() => {
     if (selectedId() === null) setSidebarOpen(true);
   });
 
+  // Replace an alias link with the task's canonical URL.
+  createEffect(() => {
+    const t = selectedTask();
+    if (t && pathRef() !== t.id) {
+      const path = taskPath(t.id, t.repos?.[0]?.name ?? "", t.repos?.[0]?.branch ?? "", t.title);
+      navigate(isDiffPath(location.pathname) ? path + "/diff" : path, { replace: true });
+    }
+  });
+
   // Redirect to home when a task URL points to a non-existent task.
   // Guard on connected() to avoid spurious redirects during reconnection.
   createEffect(() => {
@@ -285,7 +354,12 @@ export default function App() {
           const lastModel = prefModels[harness];
           if (lastModel && models.includes(lastModel)) setSelectedModel(lastModel);
         }
-        if (prefs?.settings?.baseImage) setSelectedImage(prefs.settings.baseImage);
+        // A pending image is shown as the chosen one; saving it again keeps it pending.
+        const image = prefs?.settings?.pendingBaseImage?.image ?? prefs?.settings?.baseImage;
+        if (image) setSelectedImage(image);
+        setPendingImage(prefs?.settings?.pendingBaseImage);
+        setValidateImage(prefs?.settings?.validateBaseImage ?? false);
+        setPersistWorkspace(prefs?.settings?.persistWorkspace ?? false);
         if (config) {
           if (config.version) setServerVersion(config.version);
           setTailscaleAvailable(config.tailscaleAvailable);
@@ -295,10 +369,19 @@ export default function App() {
         if (prefs?.settings) {
           setAutoFixCI(prefs.settings.autoFixOnCIFailure);
           setAutoFixPR(prefs.settings.autoFixOnPROpen);
+          setAutoRespondReviews(prefs.settings.autoRespondToReviews);
+          setIdleHours(prefs.settings.idlePolicy?.hours ?? 0);
+          setIdleAction(prefs.settings.idlePolicy?.action ?? "notify");
+          setWindowStart(prefs.settings.executionWindow?.start ?? "");
+          setWindowEnd(prefs.settings.executionWindow?.end ?? "");
           setGitHubTokenAccess(prefs.settings.gitHubTokenAccess ?? "");
           setUseDefaultCaches(prefs.settings.useDefaultCaches ?? true);
           setWellKnownCaches(prefs.settings.wellKnownCaches ?? {});
           setCacheMappings(prefs.settings.cacheMappings ?? []);
+          loadedSettings = prefs.settings;
+          setGenericURL(prefs.settings.genericHarness?.baseURL ?? "");
+          setGenericHasKey(prefs.settings.genericHarness?.hasAPIKey ?? false);
+          setGenericModel(prefs.settings.genericHarness?.model ?? "");
         }
         if (usageData) setUsage(usageData);
       } finally {
@@ -341,7 +424,7 @@ export default function App() {
     }
 
     function connectTasks() {
-      taskES = new EventSource("/api/v1/server/tasks/events");
+      taskES = new EventSource("/api/v1/server/tasks/events?view=summary");
       taskES.addEventListener("open", () => {
         onOpen();
         taskDelay = 500;
@@ -360,9 +443,9 @@ export default function App() {
         try {
           const event = JSON.parse(e.data) as TaskListEvent;
           const checkAndNotify = (t: Task) => {
-            const needsInput = t.state === "waiting" || t.state === "asking" || t.state === "has_plan";
+            const needsInput = t.state === "waiting" || t.state === "asking" || t.state === "has_plan" || t.state === "plan_review";
             const prevState = prevStates.get(t.id);
-            const prevNeedsInput = prevState === "waiting" || prevState === "asking" || prevState === "has_plan";
+            const prevNeedsInput = prevState === "waiting" || prevState === "asking" || prevState === "has_plan" || prevState === "plan_review";
             if (needsInput && prevState === "running") {
               notifyWaiting(t.id, t.title);
             } else if (!needsInput && prevNeedsInput) {
@@ -526,7 +609,7 @@ export default function App() {
     if (actionId()) return;
     setActionId(id);
     try {
-      await stopTask(id);
+      await ifMatch(tasks().find((t) => t.id === id)?.revision).stopTask(id);
     } catch {
       setActionId(null);
     }
@@ -536,12 +619,33 @@ export default function App() {
     if (actionId()) return;
     setActionId(id);
     try {
-      await purgeTask(id);
+      await ifMatch(tasks().find((t) => t.id === id)?.revision).purgeTask(id);
     } catch {
       setActionId(null);
     }
   }
 
+  async function handleArchive(id: string) {
+    if (actionId()) return;
+    setActionId(id);
+    try {
+      await ifMatch(tasks().find((t) => t.id === id)?.revision).updateTask(id, { archived: true });
+    } finally {
+      setActionId(null);
+    }
+  }
+
+  async function handleTogglePin(id: string) {
+    const task = tasks().find((t) => t.id === id);
+    if (!task) return;
+    const client = ifMatch(task.revision);
+    await (task.pinned ? client.unpinTask(id) : client.pinTask(id));
+  }
+
+  async function handleBump(id: string) {
+    await setTaskPriority(id, { priority: "high" });
+  }
+
   async function handleRevive(id: string) {
     if (actionId()) return;
     setActionId(id);
@@ -594,7 +698,7 @@ export default function App() {
         model: m !== (sourceTask?.model ?? "") ? m : undefined,
         extraRepos: extras.length > 0 ? extras.map((r) => ({ name: r.path, ...(r.branch ? { baseBranch: r.branch } : {}) })) : undefined,
       });
-      navigate(`/task/${resp.id}`);
+      navigate(`/task/@${resp.id}`);
     } catch {
       // Fork failed — no state to clean up.
     }
@@ -624,9 +728,11 @@ export default function App() {
       const ts = tailscaleEnabled();
       const usb = usbEnabled();
       const disp = displayEnabled();
+      const think = thinkingEnabled() && harnessSupportsThinking();
+      const net = network();
       const harness = selectedHarness();
       const repoSpecs = selRepos.length > 0 ? selRepos.map((r) => ({ name: r.path, ...(r.branch ? { baseBranch: r.branch } : {}) })) : undefined;
-      const data = await createTask({ initialPrompt: { text: p, ...(imgs.length > 0 ? { images: imgs } : {}) }, repos: repoSpecs, harness, ...(model ? { model } : {}), ...(ts ? { tailscale: true } : {}), ...(usb ? { usb: true } : {}), ...(disp ? { display: true } : {}) });
+      const data = await createTask({ initialPrompt: { text: p, ...(imgs.length > 0 ? { images: imgs } : {}) }, repos: repoSpecs, harness, ...(model ? { model } : {}), ...(ts ? { tailscale: true } : {}), ...(usb ? { usb: true } : {}), ...(disp ? { display: true } : {}), ...(think ? { thinking: true } : {}), ...(net ? { network: net } : {}) });
       if (model) prefModels[harness] = model;
       else delete prefModels[harness];
       setPrompt("");
@@ -753,6 +859,16 @@ export default function App() {
             </For>
           </select>
         </Show>
+        <select
+          value={network()}
+          onChange={(e) => setNetwork(e.currentTarget.value as "" | "full" | "none")}
+          class={styles.modelSelect}
+          title="Outgoing network access of the container"
+        >
+          <option value="">Default network</option>
+          <option value="full">Full network</option>
+          <option value="none">Model API only</option>
+        </select>
         <Show when={tailscaleAvailable()}>
           <label class={styles.checkboxLabel} title="Enable Tailscale networking">
             <input
@@ -783,6 +899,16 @@ export default function App() {
             <DisplayIcon width="1.2em" height="1.2em" />
           </label>
         </Show>
+        <Show when={harnessSupportsThinking()}>
+          <label class={styles.checkboxLabel} title="Request extended thinking">
+            <input
+              type="checkbox"
+              checked={thinkingEnabled()}
+              onChange={(e) => setThinkingEnabled(e.currentTarget.checked)}
+            />
+            Think
+          </label>
+        </Show>
         <PromptInput
           value={prompt()}
           onInput={setPrompt}
@@ -826,6 +952,9 @@ export default function App() {
           onStop={handleStop}
           onPurge={handlePurge}
           onRevive={handleRevive}
+          onArchive={handleArchive}
+          onTogglePin={handleTogglePin}
+          onBump={handleBump}
           actionId={actionId}
           onDiffClick={(id) => {
             const found = tasks().find((t) => t.id === id);
@@ -866,10 +995,11 @@ export default function App() {
                 <TaskDetail
                   taskId={id}
                   taskState={selectedTask()?.state ?? "pending"}
+                  revision={selectedTask()?.revision}
                   title={selectedTask()?.title}
-                  initialPrompt={selectedTask()?.initialPrompt}
+                  initialPrompt={taskDetail()?.initialPrompt}
                   inPlanMode={selectedTask()?.inPlanMode}
-                  planContent={selectedTask()?.planContent}
+                  planContent={taskDetail()?.planContent}
                   repo={selectedTask()?.repos?.[0]?.name ?? ""}
                   remoteURL={selectedTask()?.repos?.[0]?.remoteURL}
                   forge={selectedTask()?.repos?.[0]?.forge}
@@ -883,8 +1013,10 @@ export default function App() {
                   harness={selectedTask()?.harness ?? ""}
                   model={selectedTask()?.model}
                   diffStat={selectedTask()?.diffStat}
+                  policyViolation={selectedTask()?.policyViolation}
                   supportsImages={harnesses().find((h) => h.name === (selectedTask()?.harness ?? ""))?.supportsImages}
                   supportsCompact={harnesses().find((h) => h.name === (selectedTask()?.harness ?? ""))?.supportsCompact}
+                  supportsInterrupt={harnesses().find((h) => h.name === (selectedTask()?.harness ?? ""))?.supportsInterrupt}
                   onFork={handleFork}
                   onClose={() => navigate("/")}
                   inputDraft={inputDrafts().get(id) ?? ""}
@@ -989,10 +1121,54 @@ export default function App() {
                   value={selectedImage() || ""}
                   onChange={(e) => setSelectedImage(e.currentTarget.value)}
                   onBlur={async () => {
+                    const prefs = await updatePreferences(currentSettings());
+                    setPendingImage(prefs.settings.pendingBaseImage);
+                  }}
+                />
+              </label>
+              <label class={styles.checkboxLabel}>
+                <input
+                  type="checkbox"
+                  checked={validateImage()}
+                  onChange={async (e) => {
+                    setValidateImage(e.currentTarget.checked);
+                    const prefs = await updatePreferences(currentSettings());
+                    setPendingImage(prefs.settings.pendingBaseImage);
+                  }}
+                />
+                Smoke test a new image before tasks use it
+              </label>
+              <label class={styles.checkboxLabel}>
+                <input
+                  type="checkbox"
+                  checked={persistWorkspace()}
+                  onChange={async (e) => {
+                    setPersistWorkspace(e.currentTarget.checked);
                     await updatePreferences(currentSettings());
                   }}
                 />
+                Save the workspace of tasks when their container is stopped or purged
               </label>
+              <Show when={pendingImage()} keyed>
+                {(pi) => (
+                  <p class={styles.settingsDescription}>
+                    {pi.status === "failed" ? `${pi.image} failed validation: ${pi.error ?? "unknown error"}. ` : `Validating ${pi.image}; new tasks use the previous image until it passes. `}
+                    <Show when={pi.status === "failed"}>
+                      <button
+                        type="button"
+                        class={styles.settingsButton}
+                        onClick={async () => {
+                          setPendingImage({ ...pi, status: "validating", error: undefined });
+                          const res = await validateBaseImage({});
+                          setPendingImage(res.promoted ? undefined : (await getPreferences()).settings.pendingBaseImage);
+                        }}
+                      >
+                        Retry
+                      </button>
+                    </Show>
+                  </p>
+                )}
+              </Show>
               <label class={styles.settingsLabel}>
                 GitHub token access
                 <select
@@ -1100,6 +1276,42 @@ export default function App() {
                 + Add mapping
               </button>
             </div>
+            <div class={styles.settingsSection}>
+              <h3 class={styles.settingsSectionTitle}>Generic harness</h3>
+              <label class={styles.settingsLabel}>
+                Base URL
+                <input
+                  type="url"
+                  class={styles.settingsInput}
+                  placeholder="https://api.example.com/v1"
+                  value={genericURL()}
+                  onChange={(e) => setGenericURL(e.currentTarget.value.trim())}
+                  onBlur={saveGenericHarness}
+                />
+              </label>
+              <label class={styles.settingsLabel}>
+                API key
+                <input
+                  type="password"
+                  class={styles.settingsInput}
+                  placeholder={genericHasKey() ? "(saved)" : "Optional"}
+                  value={genericKey()}
+                  onChange={(e) => setGenericKey(e.currentTarget.value.trim())}
+                  onBlur={saveGenericHarness}
+                />
+              </label>
+              <label class={styles.settingsLabel}>
+                Model
+                <input
+                  type="text"
+                  class={styles.settingsInput}
+                  value={genericModel()}
+                  onChange={(e) => setGenericModel(e.currentTarget.value.trim())}
+                  onBlur={saveGenericHarness}
+                />
+              </label>
+              <p class={styles.settingsDescription}>Runs a built-in agent against any OpenAI-compatible chat completions API. Clear the base URL to remove it.</p>
+            </div>
             <div class={styles.settingsSection}>
               <h3 class={styles.settingsSectionTitle}>Automation</h3>
               <label class={styles.settingsLabel}>
@@ -1128,6 +1340,73 @@ export default function App() {
                 Auto-fix PRs
               </label>
               <p class={styles.settingsDescription}>When a pull request is opened or reopened, automatically start a task to review and fix it.</p>
+              <label class={styles.settingsLabel}>
+                <input
+                  type="checkbox"
+                  checked={autoRespondReviews()}
+                  onChange={async (e) => {
+                    const val = e.currentTarget.checked;
+                    setAutoRespondReviews(val);
+                    await updatePreferences(currentSettings({ autoRespondToReviews: val }));
+                  }}
+                />
+                Respond to PR reviews
+              </label>
+              <p class={styles.settingsDescription}>When a reviewer comments on a task's pull request, send the feedback to its agent, or start a follow-up task on the PR branch once the agent has finished.</p>
+              <label class={styles.settingsLabel}>
+                Idle hours
+                <input
+                  type="number"
+                  min="0"
+                  class={styles.settingsInput}
+                  value={idleHours()}
+                  onChange={async (e) => {
+                    setIdleHours(Math.max(0, Math.floor(Number(e.currentTarget.value) || 0)));
+                    await updatePreferences(currentSettings());
+                  }}
+                />
+              </label>
+              <label class={styles.settingsLabel}>
+                When idle
+                <select
+                  class={styles.settingsInput}
+                  value={idleAction()}
+                  disabled={idleHours() === 0}
+                  onChange={async (e) => {
+                    setIdleAction(e.currentTarget.value);
+                    await updatePreferences(currentSettings());
+                  }}
+                >
+                  <option value="notify">Notify</option>
+                  <option value="finish">Push and stop</option>
+                </select>
+              </label>
+              <p class={styles.settingsDescription}>Acts on tasks waiting for input longer than this; 0 disables it. Push and stop warns in the transcript first. Repositories and tasks can override it through the API.</p>
+              <label class={styles.settingsLabel}>
+                Run tasks from
+                <input
+                  type="time"
+                  class={styles.settingsInput}
+                  value={windowStart()}
+                  onChange={async (e) => {
+                    setWindowStart(e.currentTarget.value);
+                    await updatePreferences(currentSettings());
+                  }}
+                />
+              </label>
+              <label class={styles.settingsLabel}>
+                Until
+                <input
+                  type="time"
+                  class={styles.settingsInput}
+                  value={windowEnd()}
+                  onChange={async (e) => {
+                    setWindowEnd(e.currentTarget.value);
+                    await updatePreferences(currentSettings());
+                  }}
+                />
+              </label>
+              <p class={styles.settingsDescription}>New tasks created outside this window stay pending until it opens, e.g. 20:00 to 08:00 for off-peak API limits. Clear either time to run tasks immediately.</p>
             </div>
             <Show when={serverVersion()}>
               <p class={styles.settingsVersion}>caic v{serverVersion()}</p>
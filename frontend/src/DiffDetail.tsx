@@ -1,5 +1,5 @@
 // Full-page diff viewer for a task's file changes.
-import { createSignal, createEffect, createMemo, For, Show } from "solid-js";
+import { createSignal, createEffect, createMemo, on, For, Show } from "solid-js";
 import { useNavigate } from "@solidjs/router";
 import type { DiffFileStat } from "@sdk/types.gen";
 import { getTaskDiff } from "./api";
@@ -57,12 +57,23 @@ export default function DiffDetail(props: Props) {
     setLoading(true);
     setError(null);
     setCollapsedFiles(new Set());
-    getTaskDiff(id)
+    // live refreshes the task's diff stat, which then streams back as a
+    // diffStat event while the agent keeps editing.
+    getTaskDiff(id, "", "true")
       .then((d) => setFullDiff(d.diff))
       .catch((e) => setError(e instanceof Error ? e.message : "Unknown error"))
       .finally(() => setLoading(false));
   });
 
+  // Refetch quietly when the diff stat changes so a running task's diff stays
+  // current without resetting the collapsed files.
+  createEffect(on(() => props.diffStat, () => {
+    if (loading()) return;
+    getTaskDiff(props.taskId, "", "")
+      .then((d) => setFullDiff(d.diff))
+      .catch(() => {});
+  }, { defer: true }));
+
   const fileDiffs = createMemo(() => {
     const raw = fullDiff();
     if (!raw) return [];
@@ -3,17 +3,9 @@
 import { createSignal, For, Show } from "solid-js";
 import type { EventStats } from "@sdk/types.gen";
 import type { Session } from "./grouping";
-import { formatDuration, formatTokens } from "./formatting";
+import { formatBytes, formatDuration, formatTokens } from "./formatting";
 import styles from "./StatsIcon.module.css";
 
-function formatBytes(bytes: number): string {
-  if (bytes <= 0) return "0 B";
-  const units = ["B", "KB", "MB", "GB", "TB"];
-  const i = Math.min(Math.floor(Math.log2(bytes) / 10), units.length - 1);
-  const val = bytes / Math.pow(1024, i);
-  return `${val < 10 ? val.toFixed(1) : Math.round(val)} ${units[i]}`;
-}
-
 // Color for CPU/MEM bars: ratio is 0–1 of a hard limit.
 function barColor(ratio: number): string {
   if (ratio >= 0.85) return "var(--color-danger)";
@@ -1,26 +1,32 @@
 // Compact card for a single task, used in the sidebar task list.
 import { For, Show, createSignal, onMount, onCleanup } from "solid-js";
 import type { Accessor } from "solid-js";
-import type { DiffStat, CIStatus, ForgeCheck, TaskRepo } from "@sdk/types.gen";
+import type { DiffStat, CIStatus, ForgeCheck, IssueLink, TaskRepo } from "@sdk/types.gen";
 import CIDot from "./CIDot";
 import Tooltip from "./Tooltip";
 import TailscaleIcon from "./tailscale.svg?solid";
 import DisplayIcon from "@material-symbols/svg-400/outlined/desktop_windows.svg?solid";
 import DeleteIcon from "@material-symbols/svg-400/outlined/delete.svg?solid";
 import RestoreIcon from "@material-symbols/svg-400/outlined/restart_alt.svg?solid";
+import ArchiveIcon from "@material-symbols/svg-400/outlined/archive.svg?solid";
+import PinIcon from "@material-symbols/svg-400/outlined/push_pin.svg?solid";
 import TimerIcon from "@material-symbols/svg-400/outlined/timer.svg?solid";
 import styles from "./TaskCard.module.css";
 import { formatElapsed, formatTokens, tokenColor, stateColor, staleStateColor, isCacheStale } from "./formatting";
 
 export interface TaskCardProps {
   id: string;
+  alias?: string;
   title: string;
   state: string;
-  stateUpdatedAt: number;
+  subState?: string;
+  stateUpdatedAt: string;
   repos?: TaskRepo[];
   harness?: string;
   model?: string;
   costUSD: number;
+  estimatedCostUSD?: number;
+  costDiscrepancy?: boolean;
   duration: number;
   numTurns: number;
   activeInputTokens: number;
@@ -30,15 +36,21 @@ export interface TaskCardProps {
   cumulativeCacheReadInputTokens: number;
   cumulativeOutputTokens: number;
   contextWindowLimit: number;
-  startedAt?: number;
-  turnStartedAt?: number;
+  startedAt?: string;
+  turnStartedAt?: string;
   diffStat?: DiffStat;
   error?: string;
   inPlanMode?: boolean;
+  heldReason?: string;
+  resumesAt?: string;
+  stalled?: boolean;
+  lastActivityAt?: string;
+  priority?: string;
   tailscale?: string;
   usb?: boolean;
   display?: boolean;
   forgePR?: number;
+  issue?: IssueLink;
   ciStatus?: CIStatus;
   ciChecks?: ForgeCheck[];
   autoFixPR?: boolean;
@@ -48,6 +60,10 @@ export interface TaskCardProps {
   onStop?: () => void;
   onPurge?: () => void;
   onRevive?: () => void;
+  onArchive?: () => void;
+  pinned?: boolean;
+  onTogglePin?: () => void;
+  onBump?: () => void;
   actionLoading?: boolean;
   onDiffClick?: () => void;
 }
@@ -91,6 +107,9 @@ export default function TaskCard(props: TaskCardProps) {
           <strong ref={titleRef} class={styles.title}>{props.title}</strong>
         </Tooltip>
         <span class={styles.stateGroup}>
+          <Show when={props.alias}>
+            <span class={styles.featureBadge} title="Task alias; /task/{alias} links here">{props.alias}</span>
+          </Show>
           <Show when={props.tailscale} keyed>
             {(ts) => ts.startsWith("https://")
               ? <a class={styles.featureIconBadge} href={ts} target="_blank" rel="noopener" title="Tailscale" onClick={(e) => e.stopPropagation()}><TailscaleIcon width="0.7rem" height="0.7rem" /></a>
@@ -103,6 +122,28 @@ export default function TaskCard(props: TaskCardProps) {
           <Show when={props.display}>
             <span class={styles.featureIconBadge} title="Display"><DisplayIcon width="0.7rem" height="0.7rem" /></span>
           </Show>
+          <Show when={props.heldReason} keyed>
+            {(reason) => <span class={styles.featureBadge} title={`Held: ${reason}`}>held</span>}
+          </Show>
+          <Show when={props.resumesAt} keyed>
+            {(at) => <span class={styles.featureBadge} title={`Provider rate limit; resumes at ${new Date(at).toLocaleTimeString()}`}>backoff</span>}
+          </Show>
+          <Show when={props.stalled}>
+            <span class={styles.featureBadge} title={`No output from the agent since ${props.lastActivityAt ? new Date(props.lastActivityAt).toLocaleTimeString() : "the turn started"}; it may be hung`}>stalled</span>
+          </Show>
+          <Show when={props.state === "pending" ? props.priority : undefined} keyed>
+            {(priority) => <span class={styles.featureBadge} title="Start order while queued">{priority}</span>}
+          </Show>
+          <Show when={props.state === "pending" && props.priority !== "high" && props.onBump}>
+            <button
+              class={styles.featureBadge}
+              disabled={props.actionLoading}
+              onClick={(e) => { e.stopPropagation(); props.onBump?.(); }}
+              title="Start before the other queued tasks"
+            >
+              start sooner
+            </button>
+          </Show>
           {/* Stopped: revive + purge buttons */}
           <Show when={props.state === "stopped"}>
             <Show when={props.onRevive}>
@@ -134,6 +175,33 @@ export default function TaskCard(props: TaskCardProps) {
               </span>
             </Show>
           </Show>
+          <Show when={props.onTogglePin}>
+            <span class={`${styles.pinBtn} ${props.pinned ? styles.pinned : ""}`}>
+              <button
+                class={styles.pinIcon}
+                disabled={props.actionLoading}
+                onClick={(e) => { e.stopPropagation(); props.onTogglePin?.(); }}
+                title={props.pinned ? "Unpin" : "Pin to top"}
+                data-testid="pin-task"
+              >
+                <PinIcon width="0.85rem" height="0.85rem" />
+              </button>
+            </span>
+          </Show>
+          {/* Finished: archive button hides the task from the list; logs are kept. */}
+          <Show when={(props.state === "purged" || props.state === "failed") && props.onArchive}>
+            <span class={styles.archiveBtn}>
+              <button
+                class={styles.archiveIcon}
+                disabled={props.actionLoading}
+                onClick={(e) => { e.stopPropagation(); props.onArchive?.(); }}
+                title="Archive"
+                data-testid="archive-task"
+              >
+                <ArchiveIcon width="0.85rem" height="0.85rem" />
+              </button>
+            </span>
+          </Show>
           {/* Active states: stop button (trash can). Shift-click or double-click/tap skips stop and goes straight to purge. */}
           <Show when={props.state !== "stopped" && props.onStop && !terminalStates.has(props.state)}>
             <span class={styles.purgeBtn}>
@@ -173,10 +241,10 @@ export default function TaskCard(props: TaskCardProps) {
       {(() => {
         const multiRepo = (props.repos?.length ?? 0) > 1;
         const timePair = () => (
-          <Show when={(!isTerminal() && props.stateUpdatedAt > 0) || props.duration > 0}>
+          <Show when={(!isTerminal() && props.stateUpdatedAt) || props.duration > 0}>
             <span class={styles.timePair}>
               <TimerIcon width="0.65rem" height="0.65rem" class={styles.timerIcon} />
-              <Show when={!isTerminal() && props.stateUpdatedAt > 0}>
+              <Show when={!isTerminal() && props.stateUpdatedAt}>
                 <StateDuration stateUpdatedAt={props.stateUpdatedAt} now={props.now} />
                 <Show when={props.duration > 0 || props.state === "running"}>
                   <span class={styles.timeSep}>/</span>
@@ -189,6 +257,9 @@ export default function TaskCard(props: TaskCardProps) {
           </Show>
         );
         const statusBadges = () => <>
+          <Show when={props.issue} keyed>
+            {(issue) => <a class={styles.prBadge} href={issue.url} target="_blank" rel="noopener" title={`Linked ${issue.tracker} issue`} onClick={(e) => e.stopPropagation()}>{issue.key}</a>}
+          </Show>
           <Show when={props.forgePR}>
             <span class={styles.prBadge} title={`PR #${props.forgePR}`}>PR</span>
           </Show>
@@ -200,7 +271,7 @@ export default function TaskCard(props: TaskCardProps) {
           </Show>
           <Tooltip text="Prompt cache likely expired — continuing may use more tokens" disabled={!stale()}>
             <span class={styles.badge} style={{ background: stale() ? staleStateColor(props.state) : stateColor(props.state) }}>
-              {props.state}
+              {props.subState === "image_pull" ? "pulling image" : props.state}
             </span>
           </Tooltip>
         </>;
@@ -276,7 +347,17 @@ export default function TaskCard(props: TaskCardProps) {
               </Tooltip>
             </Show>
             <Show when={props.costUSD > 0}>
-              {" · "}${props.costUSD.toFixed(2)}
+              {" · "}
+              <Show when={props.estimatedCostUSD} fallback={<>${props.costUSD.toFixed(2)}</>}>
+                {(est) => (
+                  <Tooltip text={`Estimated from token prices: $${est().toFixed(2)}${props.costDiscrepancy ? " — differs from the reported cost" : ""}`}>
+                    <span>${props.costUSD.toFixed(2)}{props.costDiscrepancy ? " ⚠" : ""}</span>
+                  </Tooltip>
+                )}
+              </Show>
+            </Show>
+            <Show when={props.costUSD === 0 && props.estimatedCostUSD}>
+              {(est) => <>{" · "}~${est().toFixed(2)}</>}
             </Show>
           </span>
         </div>
@@ -316,17 +397,17 @@ export default function TaskCard(props: TaskCardProps) {
   );
 }
 
-function StateDuration(props: { stateUpdatedAt: number; now: Accessor<number> }) {
-  const elapsed = () => Math.max(0, props.now() - props.stateUpdatedAt * 1000);
+function StateDuration(props: { stateUpdatedAt: string; now: Accessor<number> }) {
+  const elapsed = () => Math.max(0, props.now() - Date.parse(props.stateUpdatedAt));
   return <span>{formatElapsed(elapsed())}</span>;
 }
 
-function ThinkTime(props: { duration: number; state: string; stateUpdatedAt: number; turnStartedAt?: number; now: Accessor<number> }) {
+function ThinkTime(props: { duration: number; state: string; stateUpdatedAt: string; turnStartedAt?: string; now: Accessor<number> }) {
   const thinkMs = () => {
     const base = props.duration * 1000;
     if (props.state === "running") {
-      const turnStart = (props.turnStartedAt ?? 0) > 0 ? (props.turnStartedAt as number) : props.stateUpdatedAt;
-      return base + Math.max(0, props.now() - turnStart * 1000);
+      const turnStart = Date.parse(props.turnStartedAt || props.stateUpdatedAt);
+      return base + Math.max(0, props.now() - turnStart);
     }
     return base;
   };
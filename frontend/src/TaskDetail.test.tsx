@@ -45,8 +45,10 @@ vi.mock("./api", () => ({
   restartTask: vi.fn(),
   clearContext: vi.fn(() => Promise.resolve({ status: "cleared" })),
   compactContext: vi.fn(() => Promise.resolve({ status: "compacting" })),
-  syncTask: vi.fn(),
+  ifMatch: vi.fn(() => ({ syncTask: vi.fn() })),
   getTaskDiff: vi.fn(),
+  getTaskMessages: vi.fn(),
+  getTaskMessageContent: vi.fn(),
 }));
 
 // Import after mocks are set up.
@@ -195,11 +197,11 @@ describe("SSE connection", () => {
     if (!capturedCb.value) throw new Error("taskEvents callback not captured");
 
     const cb = capturedCb.value;
-    cb({ kind: "thinking", ts: 1, thinking: { text: "planning tool 1" } });
-    cb({ kind: "toolUse", ts: 2, toolUse: { toolUseID: "t1", name: "Read", input: {} } });
-    cb({ kind: "usage", ts: 3, usage: { inputTokens: 10, outputTokens: 5, cacheCreationInputTokens: 0, cacheReadInputTokens: 0, model: "m" } });
-    cb({ kind: "thinking", ts: 4, thinking: { text: "planning tool 2" } });
-    cb({ kind: "toolUse", ts: 5, toolUse: { toolUseID: "t2", name: "Bash", input: {} } });
+    cb({ kind: "thinking", ts: "2026-01-01T00:00:01Z", thinking: { text: "planning tool 1" } });
+    cb({ kind: "toolUse", ts: "2026-01-01T00:00:02Z", toolUse: { toolUseID: "t1", name: "Read", input: {} } });
+    cb({ kind: "usage", ts: "2026-01-01T00:00:03Z", usage: { inputTokens: 10, outputTokens: 5, cacheCreationInputTokens: 0, cacheReadInputTokens: 0, model: "m" } });
+    cb({ kind: "thinking", ts: "2026-01-01T00:00:04Z", thinking: { text: "planning tool 2" } });
+    cb({ kind: "toolUse", ts: "2026-01-01T00:00:05Z", toolUse: { toolUseID: "t2", name: "Bash", input: {} } });
     vi.advanceTimersByTime(20);
 
     expect(document.body.textContent).toContain("planning tool 1");
@@ -219,7 +221,7 @@ describe("SSE connection", () => {
 
     // Push a textDelta live event (component is in live mode because ready fired).
     if (!capturedCb.value) throw new Error("taskEvents callback not captured");
-    capturedCb.value({ kind: "textDelta", ts: 1, textDelta: { text: "agent reply" } });
+    capturedCb.value({ kind: "textDelta", ts: "2026-01-01T00:00:01Z", textDelta: { text: "agent reply" } });
 
     // Flush the rAF batch: vi.useFakeTimers() polyfills rAF as setTimeout(fn, 16).
     vi.advanceTimersByTime(20);
@@ -1,8 +1,8 @@
 // TaskDetail renders the real-time agent output stream for a single task.
-import { createSignal, createMemo, createEffect, For, Index, Show, onCleanup, onMount, untrack, Switch, Match, type Accessor } from "solid-js";
+import { batch, createSignal, createMemo, createEffect, For, Index, Show, onCleanup, onMount, untrack, Switch, Match, type Accessor } from "solid-js";
 import { A, useNavigate, useLocation } from "@solidjs/router";
-import { sendInput as apiSendInput, restartTask as apiRestartTask, clearContext as apiClearContext, compactContext as apiCompactContext, syncTask as apiSyncTask, taskEvents, getTaskToolInput, botFixPR } from "./api";
-import type { EventMessage, EventResult, AskQuestion, EventAsk, EventTextDelta, SafetyIssue, ImageData as APIImageData, SyncTarget, DiffFileStat, ForgeCheck, EventStats } from "@sdk/types.gen";
+import { sendInput as apiSendInput, restartTask as apiRestartTask, approvePlan as apiApprovePlan, clearContext as apiClearContext, compactContext as apiCompactContext, interruptTask as apiInterruptTask, rewindTask as apiRewindTask, approveTaskPolicy, denyTaskPolicy, ifMatch, taskEvents, getTaskMessages, getTaskMessageContent, getTaskToolInput, botFixPR } from "./api";
+import type { EventMessage, ContentRef, EventResult, AskQuestion, EventAsk, EventTextDelta, SafetyIssue, ImageData as APIImageData, SyncTarget, DiffFileStat, ForgeCheck, EventStats, PolicyViolation } from "@sdk/types.gen";
 import { groupMessages, groupSessions, isSessionBoundary, buildPastSessionItems, buildTurnItems, toolCountSummary, turnSummary, sessionSummary, type MsgItem, type MessageGroup, type Session } from "./grouping";
 import { formatDuration, formatElapsed, formatTokens, toolCallDetail } from "./formatting";
 import type { ToolCall } from "./grouping";
@@ -28,6 +28,14 @@ function jitteredDelay(base: number): number {
   return base * (0.75 + Math.random() * 0.5);
 }
 
+// Long transcripts replay only their last HISTORY_TAIL messages on connect;
+// older messages are fetched HISTORY_PAGE at a time on demand.
+const HISTORY_TAIL = 1000;
+const HISTORY_PAGE = 500;
+
+// Live events are coalesced server-side into batches at most this often.
+const EVENT_BATCH_MS = 50;
+
 // Module-level store for <details> open/closed state (tool calls, thinking blocks).
 // Keys: toolUseID, "group:<firstToolUseID>", "thinking:<firstEventTs>".
 // Survives component remounts on task switching.
@@ -42,6 +50,7 @@ const expandedSessionsByTask = new Map<string, Set<string>>();
 interface Props {
   taskId: string;
   taskState: string;
+  revision?: number;
   title?: string;
   initialPrompt?: string;
   inPlanMode?: boolean;
@@ -59,8 +68,10 @@ interface Props {
   harness: string;
   model?: string;
   diffStat?: DiffFileStat[];
+  policyViolation?: PolicyViolation;
   supportsImages?: boolean;
   supportsCompact?: boolean;
+  supportsInterrupt?: boolean;
   onFork?: (id: string) => void;
   onClose: () => void;
   inputDraft: string;
@@ -122,8 +133,11 @@ function ciActionsURL(remoteURL?: string, forge?: string): string | undefined {
 export default function TaskDetail(props: Props) {
   const location = useLocation();
   const [messages, setMessages] = createSignal<EventMessage[]>([]);
+  // Index of the first history message in messages(); older ones are loaded on demand.
+  const [historyStart, setHistoryStart] = createSignal(0);
+  const [loadingOlder, setLoadingOlder] = createSignal(false);
   const [sending, setSending] = createSignal(false);
-  const [pendingAction, setPendingAction] = createSignal<"sync" | "restart" | "clear-context" | "compact" | null>(null);
+  const [pendingAction, setPendingAction] = createSignal<"sync" | "restart" | "clear-context" | "compact" | "interrupt" | "rewind" | "policy" | null>(null);
   const [actionError, setActionError] = createSignal<string | null>(null);
   const [safetyIssues, setSafetyIssues] = createSignal<SafetyIssue[]>([]);
   const [syncMenuOpen, setSyncMenuOpen] = createSignal(false);
@@ -343,21 +357,39 @@ export default function TaskDetail(props: Props) {
       buf = [];
       live = false;
       es = taskEvents(id, (ev) => {
+        // The server skipped messages we were too slow to read; the
+        // transcript has a gap, so replay the history again.
+        if (ev.kind === "overflow") {
+          connect();
+          return;
+        }
         if (live) {
           pendingLive.push(ev);
           if (rafId === null) rafId = requestAnimationFrame(flushLive);
         } else {
           buf.push(ev);
         }
-      });
+      }, { from: -HISTORY_TAIL, batchMs: EVENT_BATCH_MS });
       es.addEventListener("open", () => {
         delay = 500;
       });
-      // The server sends a "ready" event after replaying full history.
+      // The server sends a "ready" event after replaying the history tail,
+      // with the index of the first replayed message.
       // Swap the buffer in atomically to avoid a flash of empty content.
-      es.addEventListener("ready", () => {
+      es.addEventListener("ready", (e?: Event) => {
         live = true;
-        setMessages(buf);
+        let from = 0;
+        try {
+          from = (JSON.parse((e as MessageEvent | undefined)?.data ?? "{}") as { from?: number }).from ?? 0;
+        } catch {
+          // Older servers send no replay info.
+        }
+        batch(() => {
+          setSplitIdx(0);
+          setCompletedMsgs([]);
+          setHistoryStart(from);
+          setMessages(buf);
+        });
       });
       es.onerror = () => {
         es?.close();
@@ -389,6 +421,34 @@ export default function TaskDetail(props: Props) {
     });
   });
 
+  // loadOlder prepends the page of history preceding historyStart().
+  async function loadOlder() {
+    const id = props.taskId;
+    const start = historyStart();
+    if (start <= 0 || loadingOlder()) return;
+    setLoadingOlder(true);
+    const after = Math.max(0, start - HISTORY_PAGE);
+    try {
+      const resp = await getTaskMessages(id, String(after), String(start - after));
+      if (id !== props.taskId || start !== historyStart()) return;
+      const msgs = [...resp.events, ...messages()];
+      let idx = 0;
+      for (let i = 0; i < msgs.length; i++) {
+        if (msgs[i].kind === "result") idx = i + 1;
+      }
+      batch(() => {
+        setSplitIdx(idx);
+        setCompletedMsgs(msgs.slice(0, idx));
+        setHistoryStart(after);
+        setMessages(msgs);
+      });
+    } catch {
+      // Keep the button; the user can retry.
+    } finally {
+      setLoadingOlder(false);
+    }
+  }
+
   async function sendInput() {
     const text = props.inputDraft.trim();
     const imgs = props.inputImages;
@@ -423,10 +483,10 @@ export default function TaskDetail(props: Props) {
 
   const isActive = () => {
     const s = props.taskState;
-    return s === "running" || s === "branching" || s === "provisioning" || s === "starting" || s === "waiting" || s === "asking" || s === "has_plan" || s === "purging";
+    return s === "running" || s === "branching" || s === "provisioning" || s === "starting" || s === "waiting" || s === "asking" || s === "has_plan" || s === "plan_review" || s === "rate_limited" || s === "purging";
   };
 
-  const isWaiting = () => props.taskState === "waiting" || props.taskState === "asking" || props.taskState === "has_plan";
+  const isWaiting = () => props.taskState === "waiting" || props.taskState === "asking" || props.taskState === "has_plan" || props.taskState === "plan_review" || props.taskState === "rate_limited";
   const prURL = () => {
     const owner = props.forgeOwner;
     const repo = props.forgeRepo;
@@ -446,7 +506,11 @@ export default function TaskDetail(props: Props) {
     const prompt = props.inputDraft.trim();
     // eslint-disable-next-line solid/reactivity -- only called from onClick
     runAction("restart", async () => {
-      await apiRestartTask(props.taskId, { prompt: { text: prompt } });
+      if (props.taskState === "plan_review") {
+        await apiApprovePlan(props.taskId, { prompt: { text: prompt } });
+      } else {
+        await apiRestartTask(props.taskId, { prompt: { text: prompt } });
+      }
       props.onInputDraft("");
     });
   }
@@ -461,6 +525,23 @@ export default function TaskDetail(props: Props) {
     runAction("compact", () => apiCompactContext(props.taskId, {}));
   }
 
+  // Replaces the last user message with the draft.
+  function doRewind() {
+    const text = props.inputDraft.trim();
+    const imgs = props.inputImages;
+    // eslint-disable-next-line solid/reactivity -- only called from onClick
+    runAction("rewind", async () => {
+      await apiRewindTask(props.taskId, { prompt: { text, ...(imgs.length > 0 ? { images: imgs } : {}) } });
+      props.onInputDraft("");
+      props.onInputImages([]);
+    });
+  }
+
+  function doInterrupt() {
+    // eslint-disable-next-line solid/reactivity -- only called from onClick
+    runAction("interrupt", () => apiInterruptTask(props.taskId));
+  }
+
   async function doSync(force: boolean, target?: SyncTarget) {
     if (pendingAction()) return;
     setPendingAction("sync");
@@ -468,9 +549,12 @@ export default function TaskDetail(props: Props) {
     setSafetyIssues([]);
     setSyncMenuOpen(false);
     try {
-      const resp = await apiSyncTask(props.taskId, { force, ...(target ? { target } : {}) });
-      if (resp.status === "blocked" && resp.safetyIssues?.length) {
-        setSafetyIssues(resp.safetyIssues);
+      const resp = await ifMatch(props.revision).syncTask(props.taskId, { force, ...(target ? { target } : {}) });
+      const issues = resp.repos?.length
+        ? resp.repos.flatMap((r) => (r.safetyIssues ?? []).map((i) => ({ ...i, file: `${r.name}/${i.file}` })))
+        : resp.safetyIssues;
+      if (resp.status === "blocked" && issues?.length) {
+        setSafetyIssues(issues);
       }
     } catch (e) {
       const msg = e instanceof Error ? e.message : "Unknown error";
@@ -496,7 +580,12 @@ export default function TaskDetail(props: Props) {
     }
   }
 
-  async function runAction(name: "sync" | "restart" | "clear-context" | "compact", fn: () => Promise<unknown>) {
+  function doPolicy(approve: boolean) {
+    // eslint-disable-next-line solid/reactivity -- only called from onClick
+    runAction("policy", () => (approve ? approveTaskPolicy(props.taskId) : denyTaskPolicy(props.taskId)));
+  }
+
+  async function runAction(name: "sync" | "restart" | "clear-context" | "compact" | "interrupt" | "rewind" | "policy", fn: () => Promise<unknown>) {
     if (pendingAction()) return;
     setPendingAction(name);
     setActionError(null);
@@ -592,6 +681,11 @@ export default function TaskDetail(props: Props) {
         <StatsIcon stats={statsHistory()} sessions={allCompletedSessions()} />
       </div>
       <div class={styles.messageArea} ref={messageAreaRef} onScroll={handleScroll}>
+        <Show when={historyStart() > 0}>
+          <button class={styles.elidedTurn} disabled={loadingOlder()} onClick={() => void loadOlder()}>
+            {loadingOlder() ? "Loading…" : `${historyStart()} earlier messages`}
+          </button>
+        </Show>
         <Index each={items()}>
           {(item) => {
             // Type-narrowing accessors for the MsgItem discriminated union.
@@ -672,6 +766,19 @@ export default function TaskDetail(props: Props) {
 
       <ProgressPanel messages={messages()} />
 
+      <Show when={props.policyViolation} keyed>
+        {(v) => (
+          <div class={styles.safetyWarning} data-testid="policy-violation">
+            <strong>Paused by tool policy rule "{v.rule}":</strong> {v.tool} {v.detail}
+            <div>
+              <Button type="button" loading={pendingAction() === "policy"} disabled={!!pendingAction()} onClick={() => doPolicy(true)}>Allow and resume</Button>
+              {" "}
+              <Button type="button" variant="red" disabled={!!pendingAction()} onClick={() => doPolicy(false)}>Deny and stop</Button>
+            </div>
+          </div>
+        )}
+      </Show>
+
       <Show when={isActive() || !!pendingAction()}>
         <form onSubmit={(e) => { e.preventDefault(); sendInput(); }} class={styles.inputForm} data-testid="task-detail-form">
           <PromptInput
@@ -707,7 +814,7 @@ export default function TaskDetail(props: Props) {
               </Show>
             </div>
             <div class={styles.syncButtonGroup}>
-              <Show when={pendingAction() === "clear-context" || pendingAction() === "compact"} fallback={
+              <Show when={pendingAction() === "clear-context" || pendingAction() === "compact" || pendingAction() === "interrupt" || pendingAction() === "rewind"} fallback={
                 <button type="button" class={styles.contextMenuToggle} disabled={!!pendingAction()} onClick={() => setContextMenuOpen((v) => !v)} aria-label="Context actions" title="Context actions">&#8942;</button>
               }>
                 <button type="button" class={styles.contextMenuToggle} disabled>&#8987;</button>
@@ -715,6 +822,10 @@ export default function TaskDetail(props: Props) {
               <Show when={contextMenuOpen()}>
                 <div class={styles.syncDropdown}>
                   <button type="button" class={`${styles.syncDropdownItem} ${styles.syncDropdownItemDisabled}`} disabled onClick={() => { setContextMenuOpen(false); doClearContext(); }}>Clear context</button>
+                  <button type="button" class={`${styles.syncDropdownItem} ${!isWaiting() || !props.inputDraft.trim() ? styles.syncDropdownItemDisabled : ""}`} disabled={!isWaiting() || !props.inputDraft.trim()} onClick={() => { setContextMenuOpen(false); doRewind(); }} title="Send the draft in place of your last message">Replace last message</button>
+                  <Show when={props.supportsInterrupt}>
+                    <button type="button" class={`${styles.syncDropdownItem} ${props.taskState !== "running" ? styles.syncDropdownItemDisabled : ""}`} disabled={props.taskState !== "running"} onClick={() => { setContextMenuOpen(false); doInterrupt(); }} title="Cancel the current turn and keep the session">Interrupt turn</button>
+                  </Show>
                   <Show when={props.supportsCompact}>
                     <button type="button" class={`${styles.syncDropdownItem} ${!isWaiting() ? styles.syncDropdownItemDisabled : ""}`} disabled={!isWaiting()} onClick={() => { setContextMenuOpen(false); doCompact(); }}>Compact context</button>
                   </Show>
@@ -791,7 +902,7 @@ function GroupContent(props: {
         </Show>
       </Match>
       <Match when={group().kind === "text"}>
-        <TextMessageGroup events={group().events} />
+        <TextMessageGroup events={group().events} taskId={props.taskId} />
       </Match>
       <Match when={group().kind === "widget"}>
         <WidgetCard group={group()} />
@@ -830,7 +941,7 @@ function RateLimitBanner(props: { ev: EventMessage }) {
   const resetsLabel = () => {
     const r = rl()?.isUsingOverage ? rl()?.overageResetsAt : rl()?.resetsAt;
     if (!r) return "";
-    const d = new Date(r * 1000);
+    const d = new Date(r);
     return ` · resets ${d.toLocaleTimeString()}`;
   };
   const rejectedLabel = () => {
@@ -1079,13 +1190,14 @@ function ShadowHTML(props: { html: string }) {
 }
 
 // Renders a text group, combining textDelta fragments into a single view.
-function TextMessageGroup(props: { events: EventMessage[] }) {
+function TextMessageGroup(props: { events: EventMessage[]; taskId: string }) {
   const thinkingEvents = createMemo(() =>
     props.events.filter((e) => e.kind === "thinking" || e.kind === "thinkingDelta"),
   );
+  const finalEv = createMemo(() => props.events.findLast((e) => e.kind === "text"));
   const text = createMemo(() => {
-    const finalEv = props.events.findLast((e) => e.kind === "text");
-    if (finalEv?.text) return finalEv.text.text;
+    const ev = finalEv();
+    if (ev?.text) return ev.text.text;
     return props.events
       .filter((e): e is EventMessage & { textDelta: EventTextDelta } => e.kind === "textDelta" && !!e.textDelta)
       .map((e) => e.textDelta.text)
@@ -1105,10 +1217,71 @@ function TextMessageGroup(props: { events: EventMessage[] }) {
           <ShadowHTML html={text()} />
         </Show>
       </Show>
+      <Show when={finalEv()?.contentRef} keyed>
+        {(ref) => <TruncatedContent taskId={props.taskId} contentRef={ref} />}
+      </Show>
     </>
   );
 }
 
+// Renders the activity of the sub-agent a tool call spawned: its text and its
+// own tool calls, which nest further when they spawn sub-agents themselves.
+function SubagentActivity(props: { events: EventMessage[]; taskId: string }) {
+  const groups = createMemo(() => groupMessages(props.events));
+  return (
+    <div class={styles.subagentActivity}>
+      <For each={groups()}>
+        {(g) => (
+          <Switch>
+            <Match when={g.kind === "text"}>
+              <TextMessageGroup events={g.events} taskId={props.taskId} />
+            </Match>
+            <Match when={g.kind === "action"}>
+              <For each={g.toolCalls}>
+                {(call) => <ToolCallCard call={call} taskId={props.taskId}
+                  open={detailsOpenState.get(call.use.toolUseID) ?? false}
+                  onToggle={(v) => detailsOpenState.set(call.use.toolUseID, v)} />}
+              </For>
+            </Match>
+          </Switch>
+        )}
+      </For>
+    </div>
+  );
+}
+
+// Offers to fetch the full content of an event the server truncated to a
+// preview. Text is shown inline; binary content is offered as a download.
+function TruncatedContent(props: { taskId: string; contentRef: ContentRef }) {
+  const [content, setContent] = createSignal<{ content: string; encoding?: string } | null>(null);
+  const [loading, setLoading] = createSignal(false);
+
+  async function load() {
+    setLoading(true);
+    try {
+      setContent(await getTaskMessageContent(props.taskId, String(props.contentRef.index)));
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  return (
+    <Show when={content()} keyed fallback={
+      <button class={styles.loadInputBtn} onClick={load} disabled={loading()}>
+        {loading() ? "Loading…" : `Load full content (${Math.ceil(props.contentRef.size / 1024)} KiB)`}
+      </button>
+    }>
+      {(c) => (
+        <Show when={c.encoding === "base64"} fallback={<pre class={styles.toolOutputDelta}>{c.content}</pre>}>
+          <a class={styles.loadInputBtn} href={`data:application/octet-stream;base64,${c.content}`} download={`message-${props.contentRef.index}.bin`}>
+            Download binary content
+          </a>
+        </Show>
+      )}
+    </Show>
+  );
+}
+
 // Returns true if every value in the object is a scalar (string, number, boolean, null).
 function isFlat(obj: Record<string, unknown>): boolean {
   return Object.values(obj).every(
@@ -1158,7 +1331,7 @@ function ToolCallCard(props: { call: ToolCall; taskId: string; open: boolean; on
   const error = () => props.call.result?.error ?? "";
   const effectiveInput = (): Record<string, unknown> =>
     (loadedInput() ?? props.call.use.input ?? {}) as Record<string, unknown>;
-  const detail = () => toolCallDetail(props.call.use.name, effectiveInput());
+  const detail = () => toolCallDetail(props.call.use.name, effectiveInput(), props.call.use.call);
   const showLoadBtn = () => props.call.use.inputTruncated && !loadedInput();
 
   async function loadInput() {
@@ -1205,8 +1378,14 @@ function ToolCallCard(props: { call: ToolCall; taskId: string; open: boolean; on
         <Show when={error()}>
           <pre class={styles.toolErrorPre}>{error()}</pre>
         </Show>
+        <Show when={(props.call.children?.length ?? 0) > 0}>
+          <SubagentActivity events={props.call.children ?? []} taskId={props.taskId} />
+        </Show>
         <Show when={(props.outputDeltaEvents?.length ?? 0) > 0}>
           <pre class={styles.toolOutputDelta}>{props.outputDeltaEvents?.map((e) => e.toolOutputDelta?.delta ?? "").join("")}</pre>
+          <For each={props.outputDeltaEvents?.filter((e) => e.contentRef)}>
+            {(e) => <TruncatedContent taskId={props.taskId} contentRef={e.contentRef as ContentRef} />}
+          </For>
         </Show>
       </details>
       <Show when={!props.suppressPlanContent && props.call.use.planContent} keyed>
@@ -21,6 +21,9 @@ export interface TaskListProps {
   onStop: (id: string) => void;
   onPurge: (id: string) => void;
   onRevive: (id: string) => void;
+  onArchive: (id: string) => void;
+  onTogglePin: (id: string) => void;
+  onBump: (id: string) => void;
   actionId: Accessor<string | null>;
   onDiffClick?: (id: string) => void;
   autoFixCI: Accessor<boolean>;
@@ -31,7 +34,11 @@ export interface TaskListProps {
 const naturalCompare = (a: string, b: string) =>
   a.localeCompare(b, undefined, { numeric: true, sensitivity: "base" });
 
-/** Sort tasks according to sidebar grouping: active by ID desc, stopped/purged by last state change desc. */
+// pinnedFirst orders pinned tasks before the others, then by cmp.
+const pinnedFirst = (cmp: (a: Task, b: Task) => number) => (a: Task, b: Task) =>
+  a.pinned === b.pinned ? cmp(a, b) : a.pinned ? -1 : 1;
+
+/** Sort tasks according to sidebar grouping: active by ID desc, stopped/purged by last state change desc; pinned tasks first in each. */
 export function sortTasks(tasks: Task[]): Task[] {
   const active = tasks.filter((t) => t.state !== "stopped" && t.state !== "purged" && t.state !== "failed");
   const stopped = tasks.filter((t) => t.state === "stopped");
@@ -40,12 +47,12 @@ export function sortTasks(tasks: Task[]): Task[] {
   // Sort by length first (longer = larger numeric value), then lexicographically.
   // Plain lexicographic comparison fails across different lengths: "B" > "1A" in
   // ASCII even though the numeric value of "B" (11) < "1A" (42).
-  const idDesc = (a: Task, b: Task) => {
+  const idDesc = pinnedFirst((a: Task, b: Task) => {
     const lc = b.id.length - a.id.length;
     if (lc !== 0) return lc;
     return b.id > a.id ? 1 : b.id < a.id ? -1 : 0;
-  };
-  const stateUpdatedDesc = (a: Task, b: Task) => b.stateUpdatedAt - a.stateUpdatedAt;
+  });
+  const stateUpdatedDesc = pinnedFirst((a: Task, b: Task) => Date.parse(b.stateUpdatedAt) - Date.parse(a.stateUpdatedAt));
   active.sort(idDesc);
   stopped.sort(stateUpdatedDesc);
   purged.sort(stateUpdatedDesc);
@@ -122,12 +129,12 @@ export default function TaskList(props: TaskListProps) {
       }
     }
 
-    const idDesc = (a: Task, b: Task) => {
+    const idDesc = pinnedFirst((a: Task, b: Task) => {
       const lc = b.id.length - a.id.length;
       if (lc !== 0) return lc;
       return b.id > a.id ? 1 : b.id < a.id ? -1 : 0;
-    };
-    const stateUpdatedDesc = (a: Task, b: Task) => b.stateUpdatedAt - a.stateUpdatedAt;
+    });
+    const stateUpdatedDesc = pinnedFirst((a: Task, b: Task) => Date.parse(b.stateUpdatedAt) - Date.parse(a.stateUpdatedAt));
     const sortedGroups = Object.values(groups).sort((a, b) => naturalCompare(a.repo, b.repo));
     for (const g of sortedGroups) {
       g.active.sort(idDesc);
@@ -172,13 +179,17 @@ export default function TaskList(props: TaskListProps) {
   const renderTask = (t: () => Task) => (
     <TaskCard
       id={t().id}
+      alias={t().alias}
       title={t().title}
       state={t().state}
+      subState={t().subState}
       stateUpdatedAt={t().stateUpdatedAt}
       repos={t().repos}
       harness={t().harness}
       model={t().model}
       costUSD={t().costUSD}
+      estimatedCostUSD={t().estimatedCostUSD}
+      costDiscrepancy={t().costDiscrepancy}
       duration={t().duration}
       numTurns={t().numTurns}
       activeInputTokens={t().activeInputTokens}
@@ -193,10 +204,16 @@ export default function TaskList(props: TaskListProps) {
       diffStat={t().diffStat}
       error={t().error}
       inPlanMode={t().inPlanMode}
+      heldReason={t().heldReason}
+      resumesAt={t().resumesAt}
+      stalled={t().stalled}
+      lastActivityAt={t().lastActivityAt}
+      priority={t().priority}
       tailscale={t().tailscale}
       usb={t().usb}
       display={t().display}
       forgePR={t().forgePR}
+      issue={t().issue}
       ciStatus={t().ciStatus}
       ciChecks={t().ciChecks}
       autoFixPR={props.autoFixPR()}
@@ -206,6 +223,10 @@ export default function TaskList(props: TaskListProps) {
       onStop={() => props.onStop(t().id)}
       onPurge={() => props.onPurge(t().id)}
       onRevive={() => props.onRevive(t().id)}
+      onArchive={() => props.onArchive(t().id)}
+      pinned={t().pinned}
+      onTogglePin={() => props.onTogglePin(t().id)}
+      onBump={() => props.onBump(t().id)}
       actionLoading={props.actionId() === t().id}
       onDiffClick={props.onDiffClick ? () => { const fn = props.onDiffClick; if (fn) fn(t().id); } : undefined}
     />
@@ -1,7 +1,8 @@
 // Usage badges showing API utilization with color-coded thresholds.
 import { Show } from "solid-js";
 import type { Accessor } from "solid-js";
-import type { ClaudeExtraUsage, ClaudeUsageWindow, CodexRateLimitWindow, CodexUsage, UsageResp } from "@sdk/types.gen";
+import type { ClaudeExtraUsage, ClaudeUsageWindow, CodexRateLimitWindow, CodexUsage, DiskUsage, UsageResp } from "@sdk/types.gen";
+import { formatBytes } from "./formatting";
 import Tooltip from "./Tooltip";
 import styles from "./UsageBadges.module.css";
 
@@ -105,6 +106,23 @@ function ExtraBadge(props: { extra: ClaudeExtraUsage }) {
   );
 }
 
+function DiskBadge(props: { disk: DiskUsage }) {
+  const usedPct = () => Math.round(100 - (props.disk.freeBytes / props.disk.totalBytes) * 100);
+  const cls = () => (props.disk.low ? styles.red : usedPct() >= 90 ? styles.yellow : styles.green);
+  const title = () => {
+    let t = `Containers: ${formatBytes(props.disk.containerBytes)}, logs: ${formatBytes(props.disk.logBytes)}`;
+    if (props.disk.minFreeBytes) t += `; task creation pauses below ${formatBytes(props.disk.minFreeBytes)} free`;
+    return t;
+  };
+  return (
+    <Tooltip text={title()}>
+      <span class={`${styles.badge} ${cls()}`}>
+        Disk: {formatBytes(props.disk.freeBytes)} free
+      </span>
+    </Tooltip>
+  );
+}
+
 export default function UsageBadges(props: { usage: Accessor<UsageResp | null>; now: Accessor<number> }) {
   return (
     <span class={styles.usageRow}>
@@ -123,6 +141,9 @@ export default function UsageBadges(props: { usage: Accessor<UsageResp | null>;
             <Show when={u.codex}>
               {(c) => <CodexBadges codex={c()} />}
             </Show>
+            <Show when={u.disk}>
+              {(d) => <DiskBadge disk={d()} />}
+            </Show>
           </>
         )}
       </Show>
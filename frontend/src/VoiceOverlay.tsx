@@ -339,9 +339,12 @@ function buildNotification(task: Task, session: VoiceSession): string | null {
     case "asking":
     case "waiting":
     case "has_plan":
+    case "plan_review":
       return `[Task #${num} (${shortName}) — ${task.state}]`;
     case "purged":
       return task.result ? `[Task #${num} (${shortName}) — completed: ${task.result}]` : null;
+    case "rate_limited":
+      return `[Task #${num} (${shortName}) — rate limited, will resume]`;
     case "stopped":
       return `[Task #${num} (${shortName}) — stopped: container died]`;
     case "failed":
@@ -39,6 +39,7 @@ const SYSTEM_INSTRUCTION =
   "- waiting: agent completed a turn, awaiting user input\n" +
   "- asking: agent asked a question, needs the user to answer\n" +
   "- has_plan: agent produced a plan, awaiting approval\n" +
+  "- rate_limited: the provider rate limited the agent; it resumes automatically\n" +
   "- pulling: pulling changes from container\n" +
   "- pushing: pushing changes to remote\n" +
   "- purging: cleanup in progress, container being deleted\n" +
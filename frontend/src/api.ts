@@ -1,37 +1,75 @@
 // Singleton API client for the caic web UI.
 import { createApiClient } from "@sdk/api.gen";
+import type { EventMessage } from "@sdk/types.gen";
 
 export const api = createApiClient();
 
+// ifMatch returns a client whose task mutations only apply while the task is
+// still at revision, i.e. no other session changed it since the UI last saw
+// it; the server answers 409 otherwise. Without a revision it returns api.
+export function ifMatch(revision: number | undefined) {
+  if (revision === undefined) return api;
+  return createApiClient((url, init) =>
+    fetch(url, { ...init, headers: { ...(init?.headers as Record<string, string>), "If-Match": `"${revision}"` } }),
+  );
+}
+
 export const {
   getConfig,
   getMe,
   logout,
   getPreferences,
   updatePreferences,
+  validateBaseImage,
   listHarnesses,
   listCaches,
   listRepos,
   cloneRepo,
+  listRepoRemotes,
+  setRepoRemote,
+  deleteRepoRemote,
+  createDeployKey,
+  deleteDeployKey,
   listRepoBranches,
+  searchRepo,
+  semanticSearchRepo,
   botFixCI,
   botFixPR,
   listTasks,
+  exportTasks,
+  getTask,
   createTask,
   taskRawEvents,
-  taskEvents,
   sendInput,
   taskFixPR,
   restartTask,
+  rewindTask,
+  approvePlan,
   clearContext,
   compactContext,
+  interruptTask,
+  setTaskPriority,
   forkTask,
   stopTask,
   purgeTask,
   reviveTask,
   getTaskCILog,
   syncTask,
+  pushTask,
   getTaskDiff,
+  getTaskDiffHunks,
+  getTaskCommits,
+  revertTaskCommit,
+  createTaskSnapshot,
+  listTaskSnapshots,
+  restoreTaskSnapshot,
+  approveTaskPolicy,
+  denyTaskPolicy,
+  getTaskMessages,
+  getTaskMessageContent,
+  annotateTaskMessage,
+  listTaskAnnotations,
+  deleteTaskAnnotation,
   getTaskToolInput,
   globalTaskEvents,
   globalUsageEvents,
@@ -40,3 +78,30 @@ export const {
   voiceRTCOffer,
   webFetch,
 } = api;
+
+// TaskEventsOptions tunes the task event stream.
+export interface TaskEventsOptions {
+  // from starts the history replay at that message index; when negative, only
+  // the last -from messages are replayed.
+  from?: number;
+  // batchMs makes the server coalesce events into arrays sent at most every
+  // batchMs milliseconds, reducing per-event overhead while output streams.
+  batchMs?: number;
+}
+
+// taskEvents streams a task's events like the generated method, with the
+// query parameters in opts.
+export function taskEvents(id: string, onMessage: (event: EventMessage) => void, opts: TaskEventsOptions = {}): EventSource {
+  const q = new URLSearchParams();
+  if (opts.from !== undefined) q.set("from", String(opts.from));
+  if (opts.batchMs !== undefined) q.set("batch", String(opts.batchMs));
+  const qs = q.toString();
+  const es = new EventSource(`/api/v1/tasks/${id}/events${qs ? `?${qs}` : ""}`);
+  es.addEventListener("message", (e) => {
+    onMessage(JSON.parse(e.data) as EventMessage);
+  });
+  es.addEventListener("batch", (e) => {
+    for (const ev of JSON.parse(e.data) as EventMessage[]) onMessage(ev);
+  });
+  return es;
+}
@@ -2,6 +2,8 @@
 // Note: formatElapsed takes milliseconds (JS timestamps); the Android
 // equivalent takes seconds. Call formatElapsed(seconds * 1000) for API durations.
 
+import type { ToolCall } from "@sdk/types.gen";
+
 export function formatCost(usd: number): string {
   return usd < 0.01 ? "<$0.01" : `$${usd.toFixed(2)}`;
 }
@@ -12,6 +14,14 @@ export function formatTokens(n: number): string {
   return `${n}t`;
 }
 
+export function formatBytes(bytes: number): string {
+  if (bytes <= 0) return "0 B";
+  const units = ["B", "KB", "MB", "GB", "TB"];
+  const i = Math.min(Math.floor(Math.log2(bytes) / 10), units.length - 1);
+  const val = bytes / Math.pow(1024, i);
+  return `${val < 10 ? val.toFixed(1) : Math.round(val)} ${units[i]}`;
+}
+
 export function formatDuration(seconds: number): string {
   if (seconds < 1) return `${Math.round(seconds * 1000)}ms`;
   return `${seconds.toFixed(1)}s`;
@@ -42,7 +52,10 @@ export function stateColor(state: string): string {
     case "asking":
       return "#cce5ff";
     case "has_plan":
+    case "plan_review":
       return "#ede9fe";
+    case "rate_limited":
+      return "#fde2c8";
     case "failed":
       return "#f8d7da";
     case "purging":
@@ -61,8 +74,9 @@ export function stateColor(state: string): string {
 const STALE_THRESHOLD_MS = 3_600_000; // 1 hour
 
 /** True when the last state change is older than 1 hour. */
-export function isCacheStale(stateUpdatedAtSec: number, nowMs: number): boolean {
-  return stateUpdatedAtSec > 0 && nowMs - stateUpdatedAtSec * 1000 > STALE_THRESHOLD_MS;
+export function isCacheStale(stateUpdatedAt: string, nowMs: number): boolean {
+  const ms = Date.parse(stateUpdatedAt);
+  return ms > 0 && nowMs - ms > STALE_THRESHOLD_MS;
 }
 
 /** Blend a hex color toward a target hex by `amount` (0–1). */
@@ -80,7 +94,13 @@ export function staleStateColor(state: string): string {
   return blendHex(stateColor(state), "#dc3545", 0.25);
 }
 
-export function toolCallDetail(name: string, input: Record<string, unknown>): string {
+/**
+ * Returns a brief detail string for a tool call. The normalized call is used
+ * when present; name and input are the fallback for events recorded before
+ * the server normalized tool calls.
+ */
+export function toolCallDetail(name: string, input: Record<string, unknown>, call?: ToolCall): string {
+  if (call) return normalizedToolCallDetail(call);
   switch (name.toLowerCase()) {
     case "read":
     case "write":
@@ -88,11 +108,7 @@ export function toolCallDetail(name: string, input: Record<string, unknown>): st
     case "edit":
       return typeof input.file_path === "string" ? input.file_path.replace(/^.*\//, "") : "";
     case "bash":
-      if (typeof input.command === "string") {
-        const cmd = input.command.trimStart();
-        return cmd.length > 60 ? cmd.slice(0, 57) + "..." : cmd;
-      }
-      return "";
+      return typeof input.command === "string" ? shortCommand(input.command) : "";
     case "grep":
       return typeof input.pattern === "string" ? input.pattern : "";
     case "glob":
@@ -109,3 +125,34 @@ export function toolCallDetail(name: string, input: Record<string, unknown>): st
       return "";
   }
 }
+
+/** Returns a brief detail string for a normalized tool call of any harness. */
+function normalizedToolCallDetail(call: ToolCall): string {
+  switch (call.kind) {
+    case "read":
+    case "write":
+    case "edit": {
+      const paths = call.paths ?? [];
+      if (paths.length === 0) return "";
+      const name = paths[0].replace(/^.*\//, "");
+      return paths.length > 1 ? `${name} +${paths.length - 1}` : name;
+    }
+    case "shell":
+      return call.command ? shortCommand(call.command) : "";
+    case "search":
+      return call.pattern ?? "";
+    case "fetch":
+      return call.url ?? "";
+    case "webSearch":
+      return call.query ?? "";
+    case "agent":
+      return call.description ?? "";
+    default:
+      return "";
+  }
+}
+
+function shortCommand(command: string): string {
+  const cmd = command.trimStart();
+  return cmd.length > 60 ? cmd.slice(0, 57) + "..." : cmd;
+}
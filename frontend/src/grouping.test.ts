@@ -4,27 +4,27 @@ import { groupMessages, groupTurns, groupSessions, turnSummary, buildTurnItems }
 import type { EventMessage } from "@sdk/types.gen";
 
 function toolUseEvent(id: string, name: string): EventMessage {
-  return { kind: "toolUse", ts: 0, toolUse: { toolUseID: id, name, input: {} } };
+  return { kind: "toolUse", ts: "2026-01-01T00:00:00Z", toolUse: { toolUseID: id, name, input: {} } };
 }
 
 function toolResultEvent(id: string): EventMessage {
-  return { kind: "toolResult", ts: 0, toolResult: { toolUseID: id, duration: 0.1 } };
+  return { kind: "toolResult", ts: "2026-01-01T00:00:00Z", toolResult: { toolUseID: id, duration: 0.1 } };
 }
 
 function textDeltaEvent(text: string): EventMessage {
-  return { kind: "textDelta", ts: 0, textDelta: { text } };
+  return { kind: "textDelta", ts: "2026-01-01T00:00:00Z", textDelta: { text } };
 }
 
 function usageEvent(): EventMessage {
   return {
-    kind: "usage", ts: 0,
+    kind: "usage", ts: "2026-01-01T00:00:00Z",
     usage: { inputTokens: 100, outputTokens: 50, cacheCreationInputTokens: 0, cacheReadInputTokens: 0, model: "test" },
   };
 }
 
 function resultEvent(): EventMessage {
   return {
-    kind: "result", ts: 0,
+    kind: "result", ts: "2026-01-01T00:00:00Z",
     result: {
       subtype: "success", isError: false, result: "done",
       totalCostUSD: 0.01, duration: 1.0, durationAPI: 0.9,
@@ -105,10 +105,10 @@ describe("groupMessages", () => {
     const groups = groupMessages([
       toolUseEvent("t1", "Read"),
       usageEvent(),
-      { kind: "thinking", ts: 0, thinking: { text: "hmm" } },
-      { kind: "subagentStart", ts: 0, subagentStart: { taskID: "sa1", description: "explore" } },
+      { kind: "thinking", ts: "2026-01-01T00:00:00Z", thinking: { text: "hmm" } },
+      { kind: "subagentStart", ts: "2026-01-01T00:00:00Z", subagentStart: { taskID: "sa1", description: "explore" } },
       toolUseEvent("t2", "Bash"),
-      { kind: "subagentEnd", ts: 0, subagentEnd: { taskID: "sa1", status: "completed" } },
+      { kind: "subagentEnd", ts: "2026-01-01T00:00:00Z", subagentEnd: { taskID: "sa1", status: "completed" } },
     ]);
     // Thinking is absorbed into the merged tool group; no standalone thinking group.
     const toolGroup = groups.find((g) => g.kind === "action");
@@ -122,7 +122,7 @@ describe("groupMessages", () => {
     // usage after a thinking-only group must not create an OTHER barrier that
     // prevents the merge pass from absorbing thinking into the tool group.
     const groups = groupMessages([
-      { kind: "thinkingDelta", ts: 0, thinkingDelta: { text: "thinking..." } },
+      { kind: "thinkingDelta", ts: "2026-01-01T00:00:00Z", thinkingDelta: { text: "thinking..." } },
       usageEvent(),
       toolUseEvent("t1", "Read"),
     ]);
@@ -140,7 +140,7 @@ describe("groupMessages", () => {
     const groups = groupMessages([
       toolUseEvent("t1", "Read"),
       usageEvent(),
-      { kind: "thinkingDelta", ts: 0, thinkingDelta: { text: "analyzing..." } },
+      { kind: "thinkingDelta", ts: "2026-01-01T00:00:00Z", thinkingDelta: { text: "analyzing..." } },
     ]);
     expect(groups).toHaveLength(1);
     expect(groups[0].kind).toBe("action");
@@ -156,7 +156,7 @@ describe("groupMessages", () => {
     const groups = groupMessages([
       toolUseEvent("t1", "Read"),
       usageEvent(),
-      { kind: "thinking", ts: 0, thinking: { text: "reflecting" } },
+      { kind: "thinking", ts: "2026-01-01T00:00:00Z", thinking: { text: "reflecting" } },
       textDeltaEvent("The result is..."),
     ]);
     expect(groups).toHaveLength(2); // [tool group, text group]
@@ -170,7 +170,7 @@ describe("groupMessages", () => {
     // Standalone thinking before text commentary (no tools) must not produce a
     // separate Thinking block; it should be inside the text group instead.
     const groups = groupMessages([
-      { kind: "thinkingDelta", ts: 0, thinkingDelta: { text: "thinking..." } },
+      { kind: "thinkingDelta", ts: "2026-01-01T00:00:00Z", thinkingDelta: { text: "thinking..." } },
       textDeltaEvent("hello"),
     ]);
     expect(groups).toHaveLength(1);
@@ -181,8 +181,8 @@ describe("groupMessages", () => {
 
   it("widgetDelta events create a widget group", () => {
     const groups = groupMessages([
-      { kind: "widgetDelta", ts: 0, widgetDelta: { toolUseID: "w1", delta: "<h1>" } },
-      { kind: "widgetDelta", ts: 0, widgetDelta: { toolUseID: "w1", delta: "Hi</h1>" } },
+      { kind: "widgetDelta", ts: "2026-01-01T00:00:00Z", widgetDelta: { toolUseID: "w1", delta: "<h1>" } },
+      { kind: "widgetDelta", ts: "2026-01-01T00:00:00Z", widgetDelta: { toolUseID: "w1", delta: "Hi</h1>" } },
     ]);
     expect(groups).toHaveLength(1);
     expect(groups[0].kind).toBe("widget");
@@ -193,8 +193,8 @@ describe("groupMessages", () => {
 
   it("widget event finalises widget group from deltas", () => {
     const groups = groupMessages([
-      { kind: "widgetDelta", ts: 0, widgetDelta: { toolUseID: "w1", delta: "<h1>" } },
-      { kind: "widget", ts: 0, widget: { toolUseID: "w1", title: "Chart", html: "<h1>Done</h1>" } },
+      { kind: "widgetDelta", ts: "2026-01-01T00:00:00Z", widgetDelta: { toolUseID: "w1", delta: "<h1>" } },
+      { kind: "widget", ts: "2026-01-01T00:00:00Z", widget: { toolUseID: "w1", title: "Chart", html: "<h1>Done</h1>" } },
     ]);
     expect(groups).toHaveLength(1);
     expect(groups[0].kind).toBe("widget");
@@ -204,7 +204,7 @@ describe("groupMessages", () => {
 
   it("widget event alone creates a widget group (replay)", () => {
     const groups = groupMessages([
-      { kind: "widget", ts: 0, widget: { toolUseID: "w1", title: "Test", html: "<p>hi</p>" } },
+      { kind: "widget", ts: "2026-01-01T00:00:00Z", widget: { toolUseID: "w1", title: "Test", html: "<p>hi</p>" } },
     ]);
     expect(groups).toHaveLength(1);
     expect(groups[0].kind).toBe("widget");
@@ -214,8 +214,8 @@ describe("groupMessages", () => {
 
   it("toolResult for widget marks widgetDone", () => {
     const groups = groupMessages([
-      { kind: "widgetDelta", ts: 0, widgetDelta: { toolUseID: "w1", delta: "<p>x</p>" } },
-      { kind: "toolResult", ts: 0, toolResult: { toolUseID: "w1", duration: 0.1 } },
+      { kind: "widgetDelta", ts: "2026-01-01T00:00:00Z", widgetDelta: { toolUseID: "w1", delta: "<p>x</p>" } },
+      { kind: "toolResult", ts: "2026-01-01T00:00:00Z", toolResult: { toolUseID: "w1", duration: 0.1 } },
     ]);
     expect(groups).toHaveLength(1);
     expect(groups[0].kind).toBe("widget");
@@ -224,20 +224,41 @@ describe("groupMessages", () => {
 
   it("userInput after ask+result is grouped with the ask", () => {
     const askEvent: EventMessage = {
-      kind: "ask", ts: 1,
+      kind: "ask", ts: "2026-01-01T00:00:01Z",
       ask: {
         toolUseID: "ask_1",
         questions: [{ question: "Which?", options: [{ label: "A" }, { label: "B" }] }],
       },
     };
-    const groups = groupMessages([askEvent, resultEvent(), { kind: "userInput", ts: 3, userInput: { text: "A" } }]);
+    const groups = groupMessages([askEvent, resultEvent(), { kind: "userInput", ts: "2026-01-01T00:00:03Z", userInput: { text: "A" } }]);
     const askGroup = groups.find((g) => g.kind === "ask");
     expect(askGroup?.answerText).toBe("A");
   });
 
+  it("sub-agent events nest under the tool call that spawned them", () => {
+    const sub = (ev: EventMessage, parent: string): EventMessage => ({ ...ev, parentToolUseID: parent });
+    const groups = groupMessages([
+      toolUseEvent("task", "Task"),
+      sub(toolUseEvent("s1", "Task"), "task"),
+      sub(toolUseEvent("s2", "Grep"), "s1"),
+      sub(toolResultEvent("s2"), "s1"),
+      sub(toolResultEvent("s1"), "task"),
+      toolResultEvent("task"),
+    ]);
+    expect(groups).toHaveLength(1);
+    const call = groups[0].toolCalls[0];
+    expect(groups[0].toolCalls).toHaveLength(1);
+    expect(call.done).toBe(true);
+    expect(call.children).toHaveLength(4);
+    // Grouping the children nests the second-level sub-agent's events.
+    const nested = groupMessages(call.children ?? []);
+    expect(nested[0].toolCalls.map((c) => c.use.toolUseID)).toEqual(["s1"]);
+    expect(nested[0].toolCalls[0].children?.map((e) => e.kind)).toEqual(["toolUse", "toolResult"]);
+  });
+
   it("rateLimit warning creates other group", () => {
     const groups = groupMessages([
-      { kind: "rateLimit", ts: 1, rateLimit: { status: "allowed_warning", resetsAt: 0, rateLimitType: "five_hour", utilization: 0.8 } },
+      { kind: "rateLimit", ts: "2026-01-01T00:00:01Z", rateLimit: { status: "allowed_warning", rateLimitType: "five_hour", utilization: 0.8 } },
     ]);
     expect(groups).toHaveLength(1);
     expect(groups[0].kind).toBe("other");
@@ -245,14 +266,14 @@ describe("groupMessages", () => {
 
   it("rateLimit allowed is filtered out", () => {
     const groups = groupMessages([
-      { kind: "rateLimit", ts: 1, rateLimit: { status: "allowed", resetsAt: 0, rateLimitType: "five_hour", utilization: 0.3 } },
+      { kind: "rateLimit", ts: "2026-01-01T00:00:01Z", rateLimit: { status: "allowed", rateLimitType: "five_hour", utilization: 0.3 } },
     ]);
     expect(groups).toHaveLength(0);
   });
 
   it("rateLimit rejected creates other group", () => {
     const groups = groupMessages([
-      { kind: "rateLimit", ts: 1, rateLimit: { status: "rejected", resetsAt: 1711000000, rateLimitType: "seven_day", utilization: 1.0 } },
+      { kind: "rateLimit", ts: "2026-01-01T00:00:01Z", rateLimit: { status: "rejected", resetsAt: "2024-03-21T05:46:40Z", rateLimitType: "seven_day", utilization: 1.0 } },
     ]);
     expect(groups).toHaveLength(1);
     expect(groups[0].kind).toBe("other");
@@ -262,10 +283,10 @@ describe("groupMessages", () => {
 describe("groupSessions", () => {
   it("splits on init events", () => {
     const msgs: EventMessage[] = [
-      { kind: "init", ts: 1, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "init", ts: "2026-01-01T00:00:01Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("session 1"),
       resultEvent(),
-      { kind: "init", ts: 2, init: { model: "m", agentVersion: "1", sessionID: "s2", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "init", ts: "2026-01-01T00:00:02Z", init: { model: "m", agentVersion: "1", sessionID: "s2", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("session 2"),
     ];
     const sessions = groupSessions(msgs);
@@ -278,10 +299,10 @@ describe("groupSessions", () => {
 
   it("splits on compact_boundary system events", () => {
     const msgs: EventMessage[] = [
-      { kind: "init", ts: 1, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "init", ts: "2026-01-01T00:00:01Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("before compact"),
       resultEvent(),
-      { kind: "system", ts: 2, system: { subtype: "compact_boundary" } },
+      { kind: "system", ts: "2026-01-01T00:00:02Z", system: { subtype: "compact_boundary" } },
       textDeltaEvent("after compact"),
     ];
     const sessions = groupSessions(msgs);
@@ -306,8 +327,8 @@ describe("groupSessions", () => {
     // The user's initial prompt arrives as a userInput event before the init event.
     // It must appear in the same session as the init, not as a phantom "Compacted session".
     const msgs: EventMessage[] = [
-      { kind: "userInput", ts: 0, userInput: { text: "hello" } },
-      { kind: "init", ts: 1, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "userInput", ts: "2026-01-01T00:00:00Z", userInput: { text: "hello" } },
+      { kind: "init", ts: "2026-01-01T00:00:01Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("response"),
     ];
     const sessions = groupSessions(msgs);
@@ -322,11 +343,11 @@ describe("groupSessions", () => {
     // After a session result, the user types a message, then a new init arrives.
     // The userInput should appear in session 2 (the one it triggered), not session 1.
     const msgs: EventMessage[] = [
-      { kind: "init", ts: 1, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "init", ts: "2026-01-01T00:00:01Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("response"),
       resultEvent(),
-      { kind: "userInput", ts: 2, userInput: { text: "follow-up" } },
-      { kind: "init", ts: 3, init: { model: "m", agentVersion: "1", sessionID: "s2", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "userInput", ts: "2026-01-01T00:00:02Z", userInput: { text: "follow-up" } },
+      { kind: "init", ts: "2026-01-01T00:00:03Z", init: { model: "m", agentVersion: "1", sessionID: "s2", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("session 2 response"),
     ];
     const sessions = groupSessions(msgs);
@@ -341,11 +362,11 @@ describe("groupSessions", () => {
     // After carrying the userInput into the next session, the resulting turn should have
     // textCount > 0 (agent replied), so turnSummary does not return "empty turn".
     const msgs: EventMessage[] = [
-      { kind: "init", ts: 1, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "init", ts: "2026-01-01T00:00:01Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("first response"),
       resultEvent(),
-      { kind: "userInput", ts: 2, userInput: { text: "follow-up" } },
-      { kind: "init", ts: 3, init: { model: "m", agentVersion: "1", sessionID: "s2", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "userInput", ts: "2026-01-01T00:00:02Z", userInput: { text: "follow-up" } },
+      { kind: "init", ts: "2026-01-01T00:00:03Z", init: { model: "m", agentVersion: "1", sessionID: "s2", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("second response"),
       resultEvent(),
     ];
@@ -357,11 +378,11 @@ describe("groupSessions", () => {
 
   it("userInput before compact_boundary is carried into the compacted session", () => {
     const msgs: EventMessage[] = [
-      { kind: "init", ts: 1, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "init", ts: "2026-01-01T00:00:01Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("first response"),
       resultEvent(),
-      { kind: "userInput", ts: 2, userInput: { text: "continue" } },
-      { kind: "system", ts: 3, system: { subtype: "compact_boundary" } },
+      { kind: "userInput", ts: "2026-01-01T00:00:02Z", userInput: { text: "continue" } },
+      { kind: "system", ts: "2026-01-01T00:00:03Z", system: { subtype: "compact_boundary" } },
       textDeltaEvent("compacted response"),
     ];
     const sessions = groupSessions(msgs);
@@ -374,11 +395,11 @@ describe("groupSessions", () => {
     // Claude Code re-invocations within the same conversation reuse the same sessionID.
     // Only a different sessionID or compact_boundary should create a new top-level group.
     const msgs: EventMessage[] = [
-      { kind: "init", ts: 1, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "init", ts: "2026-01-01T00:00:01Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("first response"),
       resultEvent(),
-      { kind: "userInput", ts: 2, userInput: { text: "follow-up" } },
-      { kind: "init", ts: 3, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "userInput", ts: "2026-01-01T00:00:02Z", userInput: { text: "follow-up" } },
+      { kind: "init", ts: "2026-01-01T00:00:03Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
       textDeltaEvent("second response"),
       resultEvent(),
     ];
@@ -389,7 +410,7 @@ describe("groupSessions", () => {
 
   it("boundary event alone produces a session with empty turns", () => {
     const msgs: EventMessage[] = [
-      { kind: "init", ts: 1, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "init", ts: "2026-01-01T00:00:01Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
     ];
     const sessions = groupSessions(msgs);
     expect(sessions).toHaveLength(1);
@@ -398,7 +419,7 @@ describe("groupSessions", () => {
 
   it("session toolCount and textCount aggregate turns", () => {
     const msgs: EventMessage[] = [
-      { kind: "init", ts: 1, init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
+      { kind: "init", ts: "2026-01-01T00:00:01Z", init: { model: "m", agentVersion: "1", sessionID: "s1", tools: [], cwd: "/", harness: "claude" } },
       toolUseEvent("t1", "Read"),
       resultEvent(),
       textDeltaEvent("text"),
@@ -439,7 +460,7 @@ describe("groupTurns", () => {
   it("durationMs uses result.duration directly (per-invocation, not cumulative)", () => {
     // ResultMessage.DurationMs is per-invocation wall-clock time for that turn.
     const makeResult = (duration: number): EventMessage => ({
-      kind: "result", ts: 0,
+      kind: "result", ts: "2026-01-01T00:00:00Z",
       result: {
         subtype: "success", isError: false, result: "done",
         totalCostUSD: 0.01, duration, durationAPI: duration * 0.9,
@@ -25,6 +25,9 @@ export interface ToolCall {
   use: EventToolUse;
   result?: EventToolResult;
   done: boolean;
+  // Events of the sub-agent this call spawned (events whose parentToolUseID is
+  // the call's toolUseID), in order; group them with groupMessages.
+  children?: EventMessage[];
 }
 
 // A turn is a sequence of message groups between user interactions.
@@ -65,8 +68,23 @@ export function groupMessages(msgs: EventMessage[]): MessageGroup[] {
   }
 
   let usageSinceLastTool = false;
+  // Every tool call seen so far, by toolUseID, including nested ones, so
+  // sub-agent events can be attached to the call that spawned them.
+  const callsByID = new Map<string, ToolCall>();
 
   for (const ev of msgs) {
+    if (ev.parentToolUseID) {
+      const parent = callsByID.get(ev.parentToolUseID);
+      if (parent) {
+        (parent.children ??= []).push(ev);
+        if (ev.kind === "toolUse" && ev.toolUse) {
+          // Nested sub-agents' events go to the outermost call too; grouping
+          // its children nests them one level further.
+          callsByID.set(ev.toolUse.toolUseID, parent);
+        }
+        continue;
+      }
+    }
     switch (ev.kind) {
       case "text": {
         // A final text event replaces any preceding textDelta group.
@@ -101,6 +119,7 @@ export function groupMessages(msgs: EventMessage[]): MessageGroup[] {
           // All tool calls start as pending; they become done when a toolResult
           // arrives or when later events prove the agent moved on (implicit done).
           const call: ToolCall = { use: ev.toolUse, done: false };
+          callsByID.set(call.use.toolUseID, call);
           const last = lastGroup();
           if (last && last.kind === "action" && last.toolCalls.length > 0 && !usageSinceLastTool) {
             // Consecutive toolUse in the same AssistantMessage — merge.
@@ -444,8 +463,8 @@ export function groupTurns(groups: MessageGroup[]): Turn[] {
       textCount++;
     }
     for (const ev of g.events) {
-      if (!hasTs) { firstTs = ev.ts; hasTs = true; }
-      lastTs = ev.ts;
+      if (!hasTs) { firstTs = Date.parse(ev.ts); hasTs = true; }
+      lastTs = Date.parse(ev.ts);
       if (ev.kind === "result" && ev.result !== undefined) {
         hasResultEvent = true;
         resultPayload = ev.result;
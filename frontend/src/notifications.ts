@@ -40,7 +40,7 @@ export function notifyWaiting(taskId: string, taskName: string): void {
 
 /**
  * Dismiss a pending notification for the given task, if any.
- * Call when the task state changes away from waiting/asking/has_plan.
+ * Call when the task state changes away from waiting/asking/has_plan/plan_review.
  */
 export function dismissNotification(taskId: string): void {
   const n = activeNotifications.get(taskId);
@@ -27,6 +27,7 @@ class ApiException(
     val code: String,
     message: String,
     val details: Map<String, kotlinx.serialization.json.JsonElement>? = null,
+    val params: Map<String, String>? = null,
 ) : Exception(message)
 
 class ApiClient(
@@ -63,7 +64,7 @@ class ApiClient(
                             try {
                                 val err = json.decodeFromString<ErrorResponse>(responseBody)
                                 cont.resumeWithException(
-                                    ApiException(resp.code, err.error.code, err.error.message, err.details)
+                                    ApiException(resp.code, err.error.code, err.error.message, err.details, err.error.params)
                                 )
                             } catch (_: Exception) {
                                 cont.resumeWithException(
@@ -94,49 +95,161 @@ class ApiClient(
     suspend fun getPreferences(): PreferencesResp = request("GET", "/api/v1/server/preferences")
     /** Updates server settings and preferences. */
     suspend fun updatePreferences(req: UpdatePreferencesReq): PreferencesResp = request("POST", "/api/v1/server/preferences", json.encodeToString(req))
+    /** Lists previously used prompts, most recent first. ?repo= limits to a repo; ?prefix= keeps prompts starting with it, ignoring case; ?limit= defaults to 20. */
+    suspend fun listRecentPrompts(repo: String, prefix: String, limit: String): List<RecentPrompt> = request("GET", "/api/v1/prompts/recent?repo=$repo&prefix=$prefix&limit=$limit")
+    /** Analyzes a draft prompt before creating a task and returns suggestions to improve it. */
+    suspend fun lintPrompt(req: LintPromptReq): LintPromptResp = request("POST", "/api/v1/prompts/lint", json.encodeToString(req))
     /** Lists available coding agent harnesses. */
     suspend fun listHarnesses(): List<HarnessInfo> = request("GET", "/api/v1/server/harnesses")
+    /** Reports server liveness and readiness; answers 503 when not ready. */
+    suspend fun getHealth(): HealthResp = request("GET", "/api/v1/health")
+    /** Returns a goroutine and heap snapshot of the server; admin only. */
+    suspend fun getRuntime(): RuntimeResp = request("GET", "/api/v1/admin/runtime")
+    /** Reports the source and hash of the served web UI build; admin only. */
+    suspend fun getFrontendBuild(): FrontendBuildResp = request("GET", "/api/v1/admin/frontend")
+    /** Checks each harness binary in the container image and its API credentials. */
+    suspend fun listHarnessHealth(): List<HarnessHealth> = request("GET", "/api/v1/health/harnesses")
+    /** Smoke tests a base image in a throwaway container; a passing pending base image becomes the default. */
+    suspend fun validateBaseImage(req: ValidateImageReq): ImageValidationResp = request("POST", "/api/v1/server/image/validate", json.encodeToString(req))
+    /** Lists the base images the caller pinned to a registry digest. */
+    suspend fun listImagePins(): ImagePinsResp = request("GET", "/api/v1/server/image/pins")
+    /** Pulls a base image, the caller's by default, and pins the new tasks using it to the digest it resolves to. Pinning it again adopts the latest digest. */
+    suspend fun pinImage(req: ImagePinReq): ImagePinsResp = request("POST", "/api/v1/server/image/pins", json.encodeToString(req))
+    /** Removes the digest pin of a base image; new tasks use its tag again. */
+    suspend fun unpinImage(req: ImagePinReq): ImagePinsResp = request("POST", "/api/v1/server/image/pins/delete", json.encodeToString(req))
+    /** Pulls a base image, the caller's by default, and reports whether its tag now resolves to another digest than the pinned one. */
+    suspend fun checkImageUpdate(req: ImagePinReq): ImageUpdateResp = request("POST", "/api/v1/server/image/check-update", json.encodeToString(req))
+    /** Returns the effective model price table, including preference overrides. */
+    suspend fun listPrices(): List<PriceEntry> = request("GET", "/api/v1/server/prices")
     /** Lists well-known cache configurations. */
     suspend fun listCaches(): WellKnownCachesResp = request("GET", "/api/v1/server/caches")
+    /** Returns the server log level. */
+    suspend fun getLogLevel(): LogLevelResp = request("GET", "/api/v1/server/log-level")
+    /** Changes the server log level until the next restart. */
+    suspend fun setLogLevel(req: LogLevelReq): LogLevelResp = request("POST", "/api/v1/server/log-level", json.encodeToString(req))
     /** Lists all discovered repositories. */
     suspend fun listRepos(): List<Repo> = request("GET", "/api/v1/server/repos")
     /** Clones a repository into the server's root directory. */
     suspend fun cloneRepo(req: CloneRepoReq): Repo = request("POST", "/api/v1/server/repos", json.encodeToString(req))
+    /** Lists the git remotes of a repository and the one task branches are pushed to by default. */
+    suspend fun listRepoRemotes(repo: String): RepoRemotesResp = request("GET", "/api/v1/server/repos/remotes?repo=$repo")
+    /** Adds a git remote to a repository, e.g. a fork, or changes its URL, and optionally makes it the default push target of the task branches. PRs of branches pushed to a fork are opened against origin. */
+    suspend fun setRepoRemote(req: SetRepoRemoteReq): RepoRemotesResp = request("POST", "/api/v1/server/repos/remotes", json.encodeToString(req))
+    /** Removes a git remote of a repository. Task branches go back to origin when it was their push target. */
+    suspend fun deleteRepoRemote(req: DeleteRepoRemoteReq): RepoRemotesResp = request("POST", "/api/v1/server/repos/remotes/delete", json.encodeToString(req))
+    /** Generates the SSH deploy key of a repository, or returns the existing one, and uses it for the repository's fetches and pushes, on the server and in task containers. Add the public key on the forge with write access. */
+    suspend fun createDeployKey(req: DeployKeyReq): DeployKeyResp = request("POST", "/api/v1/server/repos/deploykey", json.encodeToString(req))
+    /** Deletes the deploy key of a repository, which goes back to the server's credentials. */
+    suspend fun deleteDeployKey(req: DeleteDeployKeyReq): StatusResp = request("POST", "/api/v1/server/repos/deploykey/delete", json.encodeToString(req))
     /** Lists branches for a repository. */
     suspend fun listRepoBranches(repo: String): RepoBranchesResp = request("GET", "/api/v1/server/repos/branches?repo=$repo")
+    /** Searches the files of a repository's base branch for a literal, case-insensitive query. Queries shorter than 3 bytes only match paths. */
+    suspend fun searchRepo(repo: String, q: String, limit: String): RepoSearchResp = request("GET", "/api/v1/server/repos/search?repo=$repo&q=$q&limit=$limit")
+    /** Searches a repository's base branch for code similar in meaning to the query. Requires an embedding provider; see Config.SemanticSearch. */
+    suspend fun semanticSearchRepo(repo: String, q: String, limit: String): RepoSemanticSearchResp = request("GET", "/api/v1/server/repos/semantic-search?repo=$repo&q=$q&limit=$limit")
     /** Creates a task to fix a failing CI pipeline. */
     suspend fun botFixCI(req: BotFixCIReq): CreateTaskResp = request("POST", "/api/v1/bot/fix-ci", json.encodeToString(req))
     /** Injects a CI fix command into an existing task's PR. */
     suspend fun botFixPR(req: BotFixPRReq): StatusResp = request("POST", "/api/v1/bot/fix-pr", json.encodeToString(req))
-    /** Returns all tasks. */
+    /** Lists the eval runs since the server started. */
+    suspend fun listEvals(): List<EvalReport> = request("GET", "/api/v1/evals")
+    /** Runs an eval suite across harness/model targets. */
+    suspend fun createEval(req: CreateEvalReq): EvalReport = request("POST", "/api/v1/evals", json.encodeToString(req))
+    /** Returns the report of an eval run. */
+    suspend fun getEval(id: String): EvalReport = request("GET", "/api/v1/evals/$id")
+    /** Lists the pipeline definitions saved as JSON or YAML files. */
+    suspend fun listPipelines(): List<Pipeline> = request("GET", "/api/v1/pipelines")
+    /** Lists the user's saved task list views. */
+    suspend fun listTaskViews(): TaskViewsResp = request("GET", "/api/v1/views")
+    /** Saves a named task list view, replacing the view of the same name. Its query holds the filter and sort query parameters of listTasks. Making it the default unsets the previous default. */
+    suspend fun saveTaskView(req: TaskView): TaskViewsResp = request("POST", "/api/v1/views", json.encodeToString(req))
+    /** Deletes a saved task list view. */
+    suspend fun deleteTaskView(req: DeleteTaskViewReq): TaskViewsResp = request("POST", "/api/v1/views/delete", json.encodeToString(req))
+    /** Returns all tasks except archived ones, pinned first. ?includeArchived=true includes them; ?pinned=true keeps only pinned tasks; ?state=a,b keeps the tasks in these states; ?repo= keeps the tasks of a repo; ?project= keeps the tasks with a repo of the project in the user's settings; ?createdWithin=168h and ?stateOlderThan=24h keep the tasks created less than, or in their state for more than, a Go duration; ?sort=created|updated|cost|duration|state with ?order=asc|desc sorts them, by creation ascending by default; ?fields=a,b keeps only the listed fields; ?view=summary drops the prompt and plan. */
     suspend fun listTasks(): List<Task> = request("GET", "/api/v1/tasks")
+    /** Exports the metadata of the tasks created in [?from, ?to), dates or RFC 3339 times, for reporting. ?project= keeps the tasks of a project of the user's settings. ?format=csv returns a CSV file with the same columns instead of JSON. */
+    suspend fun exportTasks(format: String, from: String, to: String, project: String): List<TaskExport> = request("GET", "/api/v1/tasks/export?format=$format&from=$from&to=$to&project=$project")
+    /** Returns a task with every field, including those dropped from the summary view. */
+    suspend fun getTask(id: String): Task = request("GET", "/api/v1/tasks/$id")
+    /** Updates the mutable attributes of a task, e.g. archives it. */
+    suspend fun updateTask(id: String, req: UpdateTaskReq): Task = request("PATCH", "/api/v1/tasks/$id", json.encodeToString(req))
     /** Creates and starts a new coding agent task. */
     suspend fun createTask(req: CreateTaskReq): CreateTaskResp = request("POST", "/api/v1/tasks", json.encodeToString(req))
     /** Sends user input to a running task. */
     suspend fun sendInput(id: String, req: InputReq): StatusResp = request("POST", "/api/v1/tasks/$id/input", json.encodeToString(req))
     /** Restarts a completed or errored task with a new prompt. */
     suspend fun restartTask(id: String, req: RestartReq): StatusResp = request("POST", "/api/v1/tasks/$id/restart", json.encodeToString(req))
+    /** Replaces the last user input of a waiting task and restarts its session from the turn before. */
+    suspend fun rewindTask(id: String, req: RewindReq): StatusResp = request("POST", "/api/v1/tasks/$id/rewind", json.encodeToString(req))
     /** Clears context and restarts the agent session without a prompt. */
     suspend fun clearContext(id: String): StatusResp = request("POST", "/api/v1/tasks/$id/clear-context")
     /** Sends a compact command to reduce the agent's context window usage. */
     suspend fun compactContext(id: String, req: CompactReq): StatusResp = request("POST", "/api/v1/tasks/$id/compact", json.encodeToString(req))
+    /** Cancels the agent's current turn; the task returns to waiting for input. */
+    suspend fun interruptTask(id: String): StatusResp = request("POST", "/api/v1/tasks/$id/interrupt")
+    /** Changes the priority of a pending task, moving it in the start queue. */
+    suspend fun setTaskPriority(id: String, req: SetPriorityReq): StatusResp = request("POST", "/api/v1/tasks/$id/priority", json.encodeToString(req))
     /** Requests graceful stop of a running task. */
     suspend fun stopTask(id: String): StatusResp = request("POST", "/api/v1/tasks/$id/stop")
     /** Permanently deletes a task and its container. */
     suspend fun purgeTask(id: String): StatusResp = request("POST", "/api/v1/tasks/$id/purge")
+    /** Creates a task from a prompt alone, inferring the repo, harness and model from recent use. */
+    suspend fun quickCreateTask(req: QuickCreateTaskReq): QuickCreateTaskResp = request("POST", "/api/v1/tasks/quick", json.encodeToString(req))
+    /** Pins a task to the top of the task list. */
+    suspend fun pinTask(id: String): Task = request("POST", "/api/v1/tasks/$id/pin")
+    /** Unpins a task. */
+    suspend fun unpinTask(id: String): Task = request("POST", "/api/v1/tasks/$id/unpin")
     /** Reconnects to an orphaned task container. */
     suspend fun reviveTask(id: String): StatusResp = request("POST", "/api/v1/tasks/$id/revive")
+    /** Starts a fresh container for a task whose container is gone and restores its saved workspace. Requires the persistWorkspace setting when the task ended. */
+    suspend fun rehydrateTask(id: String): StatusResp = request("POST", "/api/v1/tasks/$id/rehydrate")
     /** Returns the log tail of a failed CI check run. */
     suspend fun getTaskCILog(id: String, jobID: String): CILogResp = request("GET", "/api/v1/tasks/$id/ci-log?jobID=$jobID")
     /** Pushes task changes to the remote repository. */
     suspend fun syncTask(id: String, req: SyncReq): SyncResp = request("POST", "/api/v1/tasks/$id/sync", json.encodeToString(req))
+    /** Pushes the task branch to its push remote without opening a PR, for the tasks whose push policy is ask. Rejected when the policy is never. */
+    suspend fun pushTask(id: String, req: PushReq): SyncResp = request("POST", "/api/v1/tasks/$id/push", json.encodeToString(req))
     /** Forks a task by snapshotting its container and creating a new task on a derived branch. */
     suspend fun forkTask(id: String, req: ForkTaskReq): CreateTaskResp = request("POST", "/api/v1/tasks/$id/fork", json.encodeToString(req))
-    /** Returns the unified diff for a task's branch. */
-    suspend fun getTaskDiff(id: String): DiffResp = request("GET", "/api/v1/tasks/$id/diff")
+    /** Approves, optionally edited, the plan of a task in plan_review and lets the agent make changes. */
+    suspend fun approvePlan(id: String, req: ApprovePlanReq): StatusResp = request("POST", "/api/v1/tasks/$id/plan", json.encodeToString(req))
+    /** Creates a real task from a plan-only task, seeded with its approved plan. */
+    suspend fun promoteTask(id: String, req: PromoteTaskReq): CreateTaskResp = request("POST", "/api/v1/tasks/$id/promote", json.encodeToString(req))
+    /** Returns the unified diff for a task's branch, computed inside its container, optionally limited to one path. ?live=true also refreshes the task's diff stat and broadcasts it as a diffStat event when it changed, so running tasks show progress. */
+    suspend fun getTaskDiff(id: String, path: String, live: String): DiffResp = request("GET", "/api/v1/tasks/$id/diff?path=$path&live=$live")
+    /** Returns the task diff parsed into files and hunks, with each file's language, optionally limited to one path. ?intraline=true also splits paired changed lines into changed and unchanged words. */
+    suspend fun getTaskDiffHunks(id: String, path: String, intraline: String): DiffHunksResp = request("GET", "/api/v1/tasks/$id/diff/hunks?path=$path&intraline=$intraline")
+    /** Returns the commits on the task branch that are not on its base branch, newest first, with the files each one changed. */
+    suspend fun getTaskCommits(id: String): List<TaskCommit> = request("GET", "/api/v1/tasks/$id/commits")
+    /** Reverts one commit of the task branch inside the container with a new commit, and tells the agent so it doesn't reintroduce the change. */
+    suspend fun revertTaskCommit(id: String, sha: String, req: RevertCommitReq): RevertCommitResp = request("POST", "/api/v1/tasks/$id/commits/$sha/revert", json.encodeToString(req))
+    /** Saves the task's workspace, including uncommitted and untracked files, as a named snapshot inside the container. */
+    suspend fun createTaskSnapshot(id: String, req: CreateSnapshotReq): TaskSnapshot = request("POST", "/api/v1/tasks/$id/snapshots", json.encodeToString(req))
+    /** Returns the task's workspace snapshots, oldest first. */
+    suspend fun listTaskSnapshots(id: String): List<TaskSnapshot> = request("GET", "/api/v1/tasks/$id/snapshots")
+    /** Resets the task's workspace to a snapshot, discarding the commits and changes made since, and tells the agent. The task must not be running. */
+    suspend fun restoreTaskSnapshot(id: String, name: String, req: RestoreSnapshotReq): StatusResp = request("POST", "/api/v1/tasks/$id/snapshots/$name/restore", json.encodeToString(req))
+    /** Approves the tool call the task is paused on for violating the tool policy and resumes the task. */
+    suspend fun approveTaskPolicy(id: String): StatusResp = request("POST", "/api/v1/tasks/$id/policy/approve")
+    /** Denies the tool call the task is paused on for violating the tool policy and stops the task. */
+    suspend fun denyTaskPolicy(id: String): StatusResp = request("POST", "/api/v1/tasks/$id/policy/deny")
+    /** Returns the task's state transition history, oldest first. */
+    suspend fun getTaskTransitions(id: String): List<StateTransition> = request("GET", "/api/v1/tasks/$id/transitions")
+    /** Returns the phases of the task with their durations: queue, container start, each turn with its time to first output, verification, push. */
+    suspend fun getTaskTimeline(id: String): TaskTimeline = request("GET", "/api/v1/tasks/$id/timeline")
+    /** Returns a page of the task transcript: up to limit messages from message index after, with their annotations. */
+    suspend fun getTaskMessages(id: String, after: String, limit: String): TaskMessagesResp = request("GET", "/api/v1/tasks/$id/messages?after=$after&limit=$limit")
+    /** Returns the full content of a message whose events were truncated to a preview. */
+    suspend fun getTaskMessageContent(id: String, index: String): MessageContentResp = request("GET", "/api/v1/tasks/$id/messages/$index/content")
+    /** Attaches a note or a bookmark to a message of the task transcript. */
+    suspend fun annotateTaskMessage(id: String, index: String, req: AnnotateMessageReq): Annotation = request("POST", "/api/v1/tasks/$id/messages/$index/annotations", json.encodeToString(req))
+    /** Returns the annotations of the task transcript, by message index. */
+    suspend fun listTaskAnnotations(id: String): List<Annotation> = request("GET", "/api/v1/tasks/$id/annotations")
+    /** Deletes an annotation of the task transcript. */
+    suspend fun deleteTaskAnnotation(id: String, annotationID: String, req: DeleteAnnotationReq): StatusResp = request("POST", "/api/v1/tasks/$id/annotations/$annotationID/delete", json.encodeToString(req))
     /** Returns the full (untruncated) input for a tool call. */
     suspend fun getTaskToolInput(id: String, toolUseID: String): TaskToolInputResp = request("GET", "/api/v1/tasks/$id/tool/$toolUseID")
-    /** Returns current usage quota statistics. */
+    /** Returns current usage quota statistics. ?project= sums the cost and tokens of, and lists the rate limited, tasks with a repo of the project in the user's settings only; the quota utilization stays account-wide. */
     suspend fun getUsage(): UsageResp = request("GET", "/api/v1/usage")
     /** Returns a short-lived voice API token. */
     suspend fun getVoiceToken(): VoiceTokenResp = request("GET", "/api/v1/voice/token")
@@ -148,11 +261,13 @@ class ApiClient(
     // SSE endpoints
     /** Streams raw backend-specific task events via SSE. */
     fun taskRawEvents(id: String): Flow<EventMessage> = sseFlow<EventMessage>("/api/v1/tasks/$id/raw_events")
-    /** Streams backend-neutral task events via SSE. */
+    /** Streams backend-neutral task events via SSE. ?from=N starts the history replay at message index N; a negative N replays only the last -N messages. ?batch=MS coalesces events into "batch" events holding JSON arrays, written at most every MS milliseconds. ?replay=realtime replays the history with its original pacing, sped up by ?speed=X (at most 100), for watching a past run. The "ready" event ends the replay with the index it started at, the history length and the transcript annotations. */
     fun taskEvents(id: String): Flow<EventMessage> = sseFlow<EventMessage>("/api/v1/tasks/$id/events")
-    /** Streams task list updates for all tasks via SSE. */
+    /** Streams the raw console output of the task's container via SSE: its stdout/stderr and the stderr of the agent process, starting with the last ?tail=N lines of each (default 200). The output of the commands the agent runs as tools is not included; it is in the tool results of the task's messages. The stream ends when the container stops. */
+    fun taskConsole(id: String): Flow<ConsoleLine> = sseFlow<ConsoleLine>("/api/v1/tasks/$id/console")
+    /** Streams task list updates for all tasks via SSE. ?includeArchived, ?pinned, ?state, ?repo, ?project, ?createdWithin and ?stateOlderThan filter tasks as for listTasks. */
     fun globalTaskEvents(): Flow<TaskListEvent> = sseFlow<TaskListEvent>("/api/v1/server/tasks/events")
-    /** Streams usage quota updates via SSE. */
+    /** Streams usage quota updates via SSE. ?project= counts only the tasks with a repo of the project, as for getUsage. */
     fun globalUsageEvents(): Flow<UsageResp> = sseFlow<UsageResp>("/api/v1/server/usage/events")
 
     private inline fun <reified T> sseFlow(path: String): Flow<T> = callbackFlow {
@@ -184,11 +299,13 @@ class ApiClient(
     // Reconnecting SSE wrappers with exponential backoff.
     /** Streams raw backend-specific task events via SSE. */
     fun taskRawEventsReconnecting(id: String): Flow<EventMessage> = reconnectingFlow { taskRawEvents(id) }
-    /** Streams backend-neutral task events via SSE. */
+    /** Streams backend-neutral task events via SSE. ?from=N starts the history replay at message index N; a negative N replays only the last -N messages. ?batch=MS coalesces events into "batch" events holding JSON arrays, written at most every MS milliseconds. ?replay=realtime replays the history with its original pacing, sped up by ?speed=X (at most 100), for watching a past run. The "ready" event ends the replay with the index it started at, the history length and the transcript annotations. */
     fun taskEventsReconnecting(id: String): Flow<EventMessage> = reconnectingFlow { taskEvents(id) }
-    /** Streams task list updates for all tasks via SSE. */
+    /** Streams the raw console output of the task's container via SSE: its stdout/stderr and the stderr of the agent process, starting with the last ?tail=N lines of each (default 200). The output of the commands the agent runs as tools is not included; it is in the tool results of the task's messages. The stream ends when the container stops. */
+    fun taskConsoleReconnecting(id: String): Flow<ConsoleLine> = reconnectingFlow { taskConsole(id) }
+    /** Streams task list updates for all tasks via SSE. ?includeArchived, ?pinned, ?state, ?repo, ?project, ?createdWithin and ?stateOlderThan filter tasks as for listTasks. */
     fun globalTaskEventsReconnecting(): Flow<TaskListEvent> = reconnectingFlow { globalTaskEvents() }
-    /** Streams usage quota updates via SSE. */
+    /** Streams usage quota updates via SSE. ?project= counts only the tasks with a repo of the project, as for getUsage. */
     fun globalUsageEventsReconnecting(): Flow<UsageResp> = reconnectingFlow { globalUsageEvents() }
 
     private fun <T> reconnectingFlow(connect: () -> Flow<T>): Flow<T> = flow {
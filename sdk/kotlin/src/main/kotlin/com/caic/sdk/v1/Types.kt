@@ -8,9 +8,12 @@ import kotlinx.serialization.json.JsonElement
 typealias Harness = String
 
 object Harnesses {
+    const val Aider: Harness = "aider"
     const val Claude: Harness = "claude"
     const val Codex: Harness = "codex"
+    const val Generic: Harness = "generic"
     const val Gemini: Harness = "gemini"
+    const val Local: Harness = "local"
     const val OpenCode: Harness = "opencode"
 }
 
@@ -56,6 +59,7 @@ data class Config(
     val displayAvailable: Boolean,
     val gitHubAppEnabled: Boolean? = null,
     val authProviders: List<String>? = null,
+    val semanticSearch: Boolean? = null,
 )
 
 /** UserResp is returned by GET /api/v1/auth/me. */
@@ -65,6 +69,7 @@ data class UserResp(
     val provider: String,
     val username: String,
     @SerialName("avatarURL") val avatarURL: String? = null,
+    val admin: Boolean? = null,
 )
 
 /** StatusResp is a common response for mutation endpoints. */
@@ -80,20 +85,128 @@ data class RepoPrefsResp(
     val model: String? = null,
 )
 
+/** PendingImage is a new base image that is smoke tested before tasks use it. */
+@Serializable
+data class PendingImage(
+    val image: String,
+    val status: String,
+    val error: String? = null,
+)
+
 /** CacheMappingResp represents a directory mapping for cache/state sharing. */
 @Serializable
 data class CacheMappingResp(val hostPath: String, val containerPath: String)
 
+/** ModelPrice is the price of a model in USD per million tokens. */
+@Serializable
+data class ModelPrice(
+    val input: Double,
+    val output: Double,
+    val cacheWrite: Double? = null,
+    val cacheRead: Double? = null,
+    val cacheReadIncluded: Boolean? = null,
+)
+
+/**
+ * PolicyRule is a tool policy rule. A tool call violating it pauses the task
+ * until the user approves or denies it.
+ */
+@Serializable
+data class PolicyRule(
+    val name: String,
+    val kind: String,
+    val pattern: String? = null,
+    val allow: List<String>? = null,
+)
+
+/** IdlePolicy acts on tasks left waiting for input. */
+@Serializable
+data class IdlePolicy(val hours: Int, val action: String)
+
+/**
+ * StallPolicy flags the running tasks whose agent sent no message for a
+ * while: a hung harness otherwise looks like one thinking for long.
+ */
+@Serializable
+data class StallPolicy(val minutes: Int, val interrupt: Boolean? = null)
+
+/**
+ * Fallback is the harness and model used when those of a task fail to start,
+ * e.g. during a provider outage.
+ */
+@Serializable
+data class Fallback(val harness: Harness? = null, val model: String? = null)
+
+/** ExecutionWindow is the time of day during which new tasks may start. */
+@Serializable
+data class ExecutionWindow(
+    val start: String,
+    val end: String,
+    val timezone: String? = null,
+)
+
+/**
+ * NotifyChannel is a named chat channel. Secret is the webhook URL of Slack
+ * and Discord, the access token of Matrix and the bot token of Telegram. It
+ * is write-only: it is never returned, and an empty secret keeps the saved
+ * one of the channel with the same name and kind.
+ */
+@Serializable
+data class NotifyChannel(
+    val name: String,
+    val kind: String,
+    val url: String? = null,
+    val secret: String? = null,
+    val hasSecret: Boolean? = null,
+    val target: String? = null,
+)
+
+/**
+ * GenericHarness configures the "generic" harness, an agent loop against an
+ * OpenAI-compatible chat completions API. The API key is write-only: it is
+ * never returned, and an empty key keeps the saved one for the same URL.
+ */
+@Serializable
+data class GenericHarness(
+    @SerialName("baseURL") val baseURL: String,
+    val apiKey: String? = null,
+    @SerialName("hasAPIKey") val hasAPIKey: Boolean? = null,
+    val model: String,
+)
+
 /** UserSettings holds user-configurable behavioral settings. */
 @Serializable
 data class UserSettings(
     @SerialName("autoFixOnCIFailure") val autoFixOnCIFailure: Boolean,
     @SerialName("autoFixOnPROpen") val autoFixOnPROpen: Boolean,
+    val autoRespondToReviews: Boolean,
     val baseImage: String? = null,
+    val validateBaseImage: Boolean? = null,
+    val pendingBaseImage: PendingImage? = null,
+    val persistWorkspace: Boolean? = null,
     val gitHubTokenAccess: String? = null,
     val useDefaultCaches: Boolean? = null,
     val wellKnownCaches: Map<String, Boolean>? = null,
     val cacheMappings: List<CacheMappingResp>? = null,
+    val modelPrices: Map<String, ModelPrice>? = null,
+    val network: String? = null,
+    val networkAllow: List<String>? = null,
+    val toolPolicy: List<PolicyRule>? = null,
+    val repoToolPolicies: Map<String, List<PolicyRule>>? = null,
+    val idlePolicy: IdlePolicy? = null,
+    val repoIdlePolicies: Map<String, IdlePolicy>? = null,
+    val stallPolicy: StallPolicy? = null,
+    val fallback: Fallback? = null,
+    val repoPushPolicies: Map<String, String>? = null,
+    val repoPrePushChecks: Map<String, String>? = null,
+    val repoInstructions: Map<String, String>? = null,
+    val repoProjects: Map<String, String>? = null,
+    val executionWindow: ExecutionWindow? = null,
+    val notifyChannels: List<NotifyChannel>? = null,
+    val notifyRoutes: Map<String, List<String>>? = null,
+    val envAllowlist: List<String>? = null,
+    val timezone: String? = null,
+    val genericHarness: GenericHarness? = null,
 )
 
 /** PreferencesResp is the response for GET /api/v1/server/preferences. */
@@ -109,6 +222,37 @@ data class PreferencesResp(
 @Serializable
 data class UpdatePreferencesReq(val settings: UserSettings)
 
+/**
+ * RecentPrompt is a previously used task prompt, returned by GET
+ * /api/v1/prompts/recent.
+ */
+@Serializable
+data class RecentPrompt(
+    val repo: String? = null,
+    val text: String,
+    val lastUsed: String,
+)
+
+/** LintPromptReq is the request body for POST /api/v1/prompts/lint. */
+@Serializable
+data class LintPromptReq(
+    val text: String,
+    val repo: String? = null,
+    @SerialName("useLLM") val useLLM: Boolean? = null,
+)
+
+/** PromptSuggestion is one improvement proposed for a draft prompt. */
+@Serializable
+data class PromptSuggestion(
+    val kind: String,
+    val severity: String,
+    val message: String,
+)
+
+/** LintPromptResp is the response for POST /api/v1/prompts/lint. */
+@Serializable
+data class LintPromptResp(val suggestions: List<PromptSuggestion>, val llm: Boolean? = null)
+
 /** HarnessInfo is the JSON representation of an available harness. */
 @Serializable
 data class HarnessInfo(
@@ -116,6 +260,129 @@ data class HarnessInfo(
     val models: List<String>,
     val supportsImages: Boolean,
     val supportsCompact: Boolean,
+    val supportsInterrupt: Boolean,
+    val supportsThinking: Boolean,
+)
+
+/** HealthCheck is the outcome of a single readiness probe. */
+@Serializable
+data class HealthCheck(
+    val name: String,
+    val ok: Boolean,
+    val detail: String? = null,
+)
+
+/**
+ * HealthResp is returned by GET /api/v1/health. The endpoint answers 200 when
+ * the server is ready to run tasks and 503 otherwise.
+ */
+@Serializable
+data class HealthResp(
+    val status: String,
+    val version: String? = null,
+    val live: Boolean,
+    val ready: Boolean,
+    val checks: List<HealthCheck>,
+)
+
+/** GoroutineGroup is a set of goroutines sharing the same stack. */
+@Serializable
+data class GoroutineGroup(val count: Int, val stack: List<String>)
+
+/**
+ * RuntimeResp is the response for GET /api/v1/admin/runtime: a snapshot of
+ * the Go runtime to diagnose hangs and leaks.
+ */
+@Serializable
+data class RuntimeResp(
+    val goroutines: Int,
+    val heapAllocBytes: Long,
+    val heapInuseBytes: Long,
+    val heapObjects: Long,
+    val sysBytes: Long,
+    @SerialName("numGC") val numGC: uint32,
+    val gcPauseTotal: Double,
+    val tasks: Int,
+    val goroutineGroups: List<GoroutineGroup>,
+)
+
+/** FrontendBuildResp identifies the served web UI build. */
+@Serializable
+data class FrontendBuildResp(
+    val source: String,
+    val dir: String? = null,
+    val hash: String,
+    val changedAt: String? = null,
+)
+
+/**
+ * HarnessHealth reports whether a harness can run tasks: its binary is
+ * installed in the container image and its API credentials are valid.
+ */
+@Serializable
+data class HarnessHealth(
+    val name: String,
+    val image: String,
+    val binary: String,
+    val installed: Boolean,
+    val version: String? = null,
+    val installError: String? = null,
+    @SerialName("credentialsOK") val credentialsOK: Boolean,
+    val credentialsError: String? = null,
+    val ok: Boolean,
+)
+
+/** ValidateImageReq is the request body for POST /api/v1/server/image/validate. */
+@Serializable
+data class ValidateImageReq(val image: String? = null)
+
+/** ImageCheck is one step of a base image smoke test. */
+@Serializable
+data class ImageCheck(
+    val name: String,
+    val ok: Boolean,
+    val detail: String? = null,
+)
+
+/** ImageValidationResp is the outcome of a base image smoke test. */
+@Serializable
+data class ImageValidationResp(
+    val image: String,
+    val ok: Boolean,
+    val checks: List<ImageCheck>,
+    val promoted: Boolean? = null,
+)
+
+/** ImagePin is a base image pinned to a registry digest. */
+@Serializable
+data class ImagePin(val image: String, val digest: String)
+
+/** ImagePinsResp is the response of the /api/v1/server/image/pins endpoints. */
+@Serializable
+data class ImagePinsResp(val pins: List<ImagePin>)
+
+/**
+ * ImagePinReq is the request body of the /api/v1/server/image/pins and
+ * /api/v1/server/image/check-update endpoints.
+ */
+@Serializable
+data class ImagePinReq(val image: String? = null)
+
+/** ImageUpdateResp is the response for POST /api/v1/server/image/check-update. */
+@Serializable
+data class ImageUpdateResp(
+    val image: String,
+    val pinned: String? = null,
+    val latest: String,
+    val updateAvailable: Boolean,
+)
+
+/** PriceEntry is one row of the effective price table. */
+@Serializable
+data class PriceEntry(
+    val model: String,
+    val price: ModelPrice,
+    val override: Boolean? = null,
 )
 
 /** WellKnownCache describes a single well-known cache. */
@@ -130,6 +397,14 @@ data class WellKnownCache(
 @Serializable
 data class WellKnownCachesResp(val harnessMounts: List<String>, val wellKnown: List<WellKnownCache>)
 
+/** LogLevelResp is the response for GET and POST /api/v1/server/log-level. */
+@Serializable
+data class LogLevelResp(val level: String)
+
+/** LogLevelReq is the request body for POST /api/v1/server/log-level. */
+@Serializable
+data class LogLevelReq(val level: String)
+
 /** BranchInfo describes a single branch with its origin. */
 @Serializable
 data class BranchInfo(val name: String, val remote: String? = null)
@@ -153,6 +428,10 @@ data class ForgeCheck(
 @Serializable
 data class Repo(
     val path: String,
+    val root: String? = null,
+    val mirror: Boolean? = null,
+    val deployKey: Boolean? = null,
+    val pushRemote: String? = null,
     val baseBranch: BranchInfo,
     @SerialName("remoteURL") val remoteURL: String? = null,
     val forge: String? = null,
@@ -166,12 +445,93 @@ data class CloneRepoReq(
     val url: String,
     val path: String? = null,
     val depth: Int? = null,
+    val mirror: Boolean? = null,
+)
+
+/** RepoRemote is a git remote of a repository. */
+@Serializable
+data class RepoRemote(val name: String, val url: String)
+
+/**
+ * RepoRemotesResp is the response for the /api/v1/server/repos/remotes
+ * endpoints.
+ */
+@Serializable
+data class RepoRemotesResp(val remotes: List<RepoRemote>, val pushRemote: String)
+
+/** SetRepoRemoteReq is the request body for POST /api/v1/server/repos/remotes. */
+@Serializable
+data class SetRepoRemoteReq(
+    val repo: String,
+    val name: String,
+    val url: String? = null,
+    val push: Boolean? = null,
 )
 
+/**
+ * DeleteRepoRemoteReq is the request body for POST
+ * /api/v1/server/repos/remotes/delete.
+ */
+@Serializable
+data class DeleteRepoRemoteReq(val repo: String, val name: String)
+
+/** DeployKeyReq is the request body for POST /api/v1/server/repos/deploykey. */
+@Serializable
+data class DeployKeyReq(val repo: String, val rotate: Boolean? = null)
+
+/** DeployKeyResp is the response for POST /api/v1/server/repos/deploykey. */
+@Serializable
+data class DeployKeyResp(
+    val repo: String,
+    val publicKey: String,
+    val fingerprint: String,
+    @SerialName("settingsURL") val settingsURL: String? = null,
+)
+
+/**
+ * DeleteDeployKeyReq is the request body for POST
+ * /api/v1/server/repos/deploykey/delete.
+ */
+@Serializable
+data class DeleteDeployKeyReq(val repo: String)
+
 /** RepoBranchesResp is the response for GET /api/v1/server/repos/branches. */
 @Serializable
 data class RepoBranchesResp(val branches: List<BranchInfo>)
 
+/** RepoSearchMatch is a line of a file containing the search query. */
+@Serializable
+data class RepoSearchMatch(
+    val path: String,
+    val line: Int,
+    val text: String,
+)
+
+/** RepoSearchResp is the response for GET /api/v1/server/repos/search. */
+@Serializable
+data class RepoSearchResp(
+    val commit: String,
+    val paths: List<String>,
+    val matches: List<RepoSearchMatch>,
+    val truncated: Boolean? = null,
+)
+
+/** SemanticSearchResult is a range of lines similar in meaning to the query. */
+@Serializable
+data class SemanticSearchResult(
+    val path: String,
+    val startLine: Int,
+    val endLine: Int,
+    val score: Double,
+)
+
+/**
+ * RepoSemanticSearchResp is the response for GET
+ * /api/v1/server/repos/semantic-search.
+ */
+@Serializable
+data class RepoSemanticSearchResp(val commit: String, val results: List<SemanticSearchResult>)
+
 /**
  * BotFixCIReq is the request body for POST /api/v1/bot/fix-ci.
  * The server fetches CI logs, builds a prompt, and creates a fix task.
@@ -190,6 +550,111 @@ data class CreateTaskResp(val status: String, val id: String)
 @Serializable
 data class BotFixPRReq(val taskId: String)
 
+/** EvalTarget is a harness/model combination an eval suite runs against. */
+@Serializable
+data class EvalTarget(val harness: Harness, val model: String? = null)
+
+/** EvalResult is the outcome of one case on one target. */
+@Serializable
+data class EvalResult(
+    val case: String,
+    val target: EvalTarget,
+    val status: String,
+    val taskId: String? = null,
+    @SerialName("costUSD") val costUSD: Double,
+    val duration: Double,
+    val output: String? = null,
+    val error: String? = null,
+)
+
+/** EvalSummary aggregates the results of one target. */
+@Serializable
+data class EvalSummary(
+    val target: EvalTarget,
+    val passed: Int,
+    val failed: Int,
+    val errored: Int,
+    @SerialName("costUSD") val costUSD: Double,
+    val duration: Double,
+)
+
+/** EvalReport is the outcome of an eval run, updated as cases complete. */
+@Serializable
+data class EvalReport(
+    val id: String,
+    val suite: String,
+    val status: String,
+    val startedAt: String,
+    val finishedAt: String? = null,
+    val results: List<EvalResult>,
+    val summary: List<EvalSummary>,
+)
+
+/**
+ * EvalCase is one benchmark: a prompt run on a repo at a given ref, judged by
+ * a verification script.
+ */
+@Serializable
+data class EvalCase(
+    val name: String,
+    val repo: String,
+    val ref: String? = null,
+    val prompt: String,
+    val verify: String,
+)
+
+/**
+ * EvalSuite is a corpus of benchmark cases run against harness/model
+ * combinations.
+ */
+@Serializable
+data class EvalSuite(val name: String, val cases: List<EvalCase>)
+
+/** CreateEvalReq is the request body for POST /api/v1/evals. */
+@Serializable
+data class CreateEvalReq(
+    val suite: EvalSuite,
+    val targets: List<EvalTarget>,
+    val parallel: Int? = null,
+)
+
+/**
+ * PipelineStep is one task of a pipeline.
+ *
+ * Prompt is a Go text/template rendered with .Input (the task's initial
+ * prompt), .Previous (the previous step's result), .Verification (the
+ * previous step's verification output) and .Step (0-based index).
+ */
+@Serializable
+data class PipelineStep(
+    val name: String? = null,
+    val prompt: String,
+    val verify: String? = null,
+)
+
+/**
+ * Pipeline is a reusable multi-step workflow, e.g. write tests, implement,
+ * lint, then write the changelog.
+ */
+@Serializable
+data class Pipeline(val name: String, val steps: List<PipelineStep>)
+
+/** TaskView is a saved combination of task list filters. */
+@Serializable
+data class TaskView(
+    val name: String,
+    val query: String? = null,
+    val default: Boolean? = null,
+)
+
+/** TaskViewsResp is the response of the /api/v1/views endpoints. */
+@Serializable
+data class TaskViewsResp(val views: List<TaskView>)
+
+/** DeleteTaskViewReq is the request body for POST /api/v1/views/delete. */
+@Serializable
+data class DeleteTaskViewReq(val name: String)
+
 /** TaskRepo describes a repository associated with a task in the API response. */
 @Serializable
 data class TaskRepo(
@@ -198,6 +663,7 @@ data class TaskRepo(
     val branch: String,
     @SerialName("remoteURL") val remoteURL: String? = null,
     val forge: String? = null,
+    val pushRemote: String? = null,
 )
 
 /** DiffFileStat describes changes to a single file. */
@@ -207,20 +673,79 @@ data class DiffFileStat(
     val added: Int,
     val deleted: Int,
     val binary: Boolean? = null,
+    val status: String? = null,
+    val oldPath: String? = null,
+)
+
+/**
+ * IssueLink is the Jira or Linear issue a task is linked to; the task
+ * comments there when it starts, opens a PR and finishes.
+ */
+@Serializable
+data class IssueLink(
+    val tracker: String,
+    val key: String,
+    val url: String? = null,
+)
+
+/** PipelineProgress reports how far a pipeline got. */
+@Serializable
+data class PipelineProgress(
+    val name: String,
+    val step: Int,
+    val steps: Int,
+    val status: String,
+    val error: String? = null,
+    val tasks: List<String>? = null,
+)
+
+/** ReviewProgress reports the state of a task's review loop. */
+@Serializable
+data class ReviewProgress(
+    val round: Int,
+    val maxRounds: Int,
+    val status: String,
+    val reviewers: List<String>? = null,
+    val feedback: String? = null,
+    val error: String? = null,
+)
+
+/** PolicyViolation is a tool call denied by a tool policy rule. */
+@Serializable
+data class PolicyViolation(
+    val rule: String,
+    val tool: String,
+    val detail: String,
+)
+
+/** Failover records that a task switched to the fallback harness and model. */
+@Serializable
+data class Failover(
+    val fromHarness: Harness,
+    val fromModel: String? = null,
+    val toHarness: Harness,
+    val toModel: String? = null,
+    val error: String,
+    val at: String,
 )
 
 /** Task is the JSON representation sent to the frontend. */
 @Serializable
 data class Task(
     val id: String,
+    val alias: String? = null,
     val initialPrompt: String,
     val title: String,
     val repos: List<TaskRepo>? = null,
     val container: String,
     val state: String,
-    val stateUpdatedAt: Double,
+    val subState: String? = null,
+    val stateUpdatedAt: String,
+    val revision: Long,
     val diffStat: List<DiffFileStat>? = null,
     @SerialName("costUSD") val costUSD: Double,
+    @SerialName("estimatedCostUSD") val estimatedCostUSD: Double? = null,
+    val costDiscrepancy: Boolean? = null,
     val duration: Double,
     val numTurns: Int,
     val cumulativeInputTokens: Int,
@@ -236,22 +761,77 @@ data class Task(
     val forgeRepo: String? = null,
     @SerialName("forgePR") val forgePR: Int? = null,
     val forgeIssue: Int? = null,
+    val issue: IssueLink? = null,
     val ciStatus: String? = null,
     val ciChecks: List<ForgeCheck>? = null,
     val owner: String? = null,
+    val diskBytes: Long? = null,
+    val logBytes: Long? = null,
+    val archived: Boolean? = null,
+    val pinned: Boolean? = null,
     val harness: Harness,
     val model: String? = null,
     val agentVersion: String? = null,
     @SerialName("sessionID") val sessionID: String? = null,
-    val startedAt: Double? = null,
-    val turnStartedAt: Double? = null,
+    val startedAt: String? = null,
+    val turnStartedAt: String? = null,
+    val resumesAt: String? = null,
     val inPlanMode: Boolean? = null,
     val planContent: String? = null,
     val tailscale: String? = null,
     val usb: Boolean? = null,
     val display: Boolean? = null,
+    val planOnly: Boolean? = null,
+    val requirePlan: Boolean? = null,
+    val readOnly: Boolean? = null,
+    val chat: Boolean? = null,
+    val thinking: Boolean? = null,
+    val idlePolicy: IdlePolicy? = null,
+    val pushPolicy: String? = null,
+    val dependsOn: List<String>? = null,
+    val heldReason: String? = null,
+    val priority: String? = null,
+    val pipeline: PipelineProgress? = null,
+    val review: ReviewProgress? = null,
+    val network: String? = null,
+    val policyViolation: PolicyViolation? = null,
+    val lastActivityAt: String? = null,
+    val stalled: Boolean? = null,
+    val failover: Failover? = null,
+)
+
+/**
+ * TaskExport is a row of GET /api/v1/tasks/export: the task metadata used
+ * for reporting.
+ */
+@Serializable
+data class TaskExport(
+    val id: String,
+    val alias: String? = null,
+    val title: String,
+    val repo: String? = null,
+    val branch: String? = null,
+    val state: String,
+    val harness: Harness,
+    val model: String? = null,
+    val owner: String? = null,
+    val startedAt: String,
+    val stateUpdatedAt: String,
+    val duration: Double,
+    val numTurns: Int,
+    @SerialName("costUSD") val costUSD: Double,
+    @SerialName("prURL") val prURL: String? = null,
+    val timezone: String,
+    val project: String? = null,
 )
 
+/**
+ * UpdateTaskReq is the request body for PATCH /api/v1/tasks/{id}. Omitted
+ * fields are left unchanged.
+ */
+@Serializable
+data class UpdateTaskReq(val archived: Boolean? = null)
+
 /** ImageData carries a single base64-encoded image. */
 @Serializable
 data class ImageData(val mediaType: String, val data: String)
@@ -262,7 +842,19 @@ data class Prompt(val text: String, val images: List<ImageData>? = null)
 
 /** RepoSpec describes a repository to associate with a task at creation time. */
 @Serializable
-data class RepoSpec(val name: String, val baseBranch: String? = null)
+data class RepoSpec(
+    val name: String,
+    val baseBranch: String? = null,
+    val pushRemote: String? = null,
+)
+
+/** ReviewSpec configures the agent-to-agent review loop. */
+@Serializable
+data class ReviewSpec(
+    val harness: Harness,
+    val model: String? = null,
+    val maxRounds: Int? = null,
+)
 
 /** CreateTaskReq is the request body for POST /api/v1/tasks. */
 @Serializable
@@ -274,6 +866,25 @@ data class CreateTaskReq(
     val tailscale: Boolean? = null,
     val usb: Boolean? = null,
     val display: Boolean? = null,
+    val planOnly: Boolean? = null,
+    val requirePlan: Boolean? = null,
+    val readOnly: Boolean? = null,
+    val chat: Boolean? = null,
+    val thinking: Boolean? = null,
+    val idlePolicy: IdlePolicy? = null,
+    val pushPolicy: String? = null,
+    val gatherContext: Boolean? = null,
+    val dependsOn: List<String>? = null,
+    val inheritBranch: Boolean? = null,
+    val onDependencyFailure: String? = null,
+    val priority: String? = null,
+    val pipeline: Pipeline? = null,
+    val review: ReviewSpec? = null,
+    val network: String? = null,
+    val networkAllow: List<String>? = null,
+    val issue: String? = null,
+    val env: Map<String, String>? = null,
+    val instructions: String? = null,
 )
 
 /**
@@ -298,12 +909,32 @@ data class EventText(val text: String)
 @Serializable
 data class EventTextDelta(val text: String)
 
-/** EventToolUse is emitted when the assistant invokes a tool. */
+/**
+ * ToolCall is the harness-neutral form of a tool call's input. Only the
+ * fields relevant to Kind are set.
+ */
+@Serializable
+data class ToolCall(
+    val kind: String,
+    val command: String? = null,
+    val paths: List<String>? = null,
+    val pattern: String? = null,
+    val url: String? = null,
+    val query: String? = null,
+    val description: String? = null,
+)
+
+/**
+ * EventToolUse is emitted when the assistant invokes a tool. Tool names are
+ * normalized to Claude Code's where the harness has an equivalent, but Input
+ * keeps the harness's own shape for debugging; clients should use Call.
+ */
 @Serializable
 data class EventToolUse(
     @SerialName("toolUseID") val toolUseID: String,
     val name: String,
     val input: JsonElement,
+    val call: ToolCall? = null,
     val planContent: String? = null,
     val inputTruncated: Boolean? = null,
     val background: Boolean? = null,
@@ -453,17 +1084,17 @@ data class EventWidgetDelta(
 @Serializable
 data class EventRateLimit(
     val status: String,
-    val resetsAt: Double,
+    val resetsAt: String? = null,
     val rateLimitType: String,
     val utilization: Double,
     val isUsingOverage: Boolean? = null,
-    val overageResetsAt: Double? = null,
+    val overageResetsAt: String? = null,
 )
 
 /** EventStats is a container resource usage snapshot emitted periodically. */
 @Serializable
 data class EventStats(
-    val ts: Long,
+    val ts: String,
     val cpuPerc: Double,
     val memUsed: Long,
     val memLimit: Long,
@@ -475,6 +1106,21 @@ data class EventStats(
     val diskUsed: Long,
 )
 
+/**
+ * EventOverflow is emitted when the client fell too far behind the live
+ * stream and Dropped messages were skipped. The client should refetch the
+ * history, e.g. by reconnecting.
+ */
+@Serializable
+data class EventOverflow(val dropped: Int)
+
+/**
+ * ContentRef points at the task history message holding the full content of
+ * a truncated event.
+ */
+@Serializable
+data class ContentRef(val index: Int, val size: Int)
+
 // Backend-neutral event types
 
 /**
@@ -484,7 +1130,8 @@ data class EventStats(
 @Serializable
 data class EventMessage(
     val kind: EventKind,
-    val ts: Long,
+    val ts: String,
+    @SerialName("parentToolUseID") val parentToolUseID: String? = null,
     val init: EventInit? = null,
     val text: EventText? = null,
     val textDelta: EventTextDelta? = null,
@@ -508,6 +1155,8 @@ data class EventMessage(
     val widgetDelta: EventWidgetDelta? = null,
     val rateLimit: EventRateLimit? = null,
     val stats: EventStats? = null,
+    val overflow: EventOverflow? = null,
+    val contentRef: ContentRef? = null,
 )
 
 /** InputReq is the request body for POST /api/v1/tasks/{id}/input. */
@@ -518,10 +1167,39 @@ data class InputReq(val prompt: Prompt)
 @Serializable
 data class RestartReq(val prompt: Prompt)
 
+/** RewindReq is the request body for POST /api/v1/tasks/{id}/rewind. */
+@Serializable
+data class RewindReq(val prompt: Prompt)
+
 /** CompactReq is the request body for POST /api/v1/tasks/{id}/compact. */
 @Serializable
 data class CompactReq(val instructions: String? = null)
 
+/** SetPriorityReq is the request body for POST /api/v1/tasks/{id}/priority. */
+@Serializable
+data class SetPriorityReq(val priority: String)
+
+/**
+ * QuickCreateTaskReq is the request body for POST /api/v1/tasks/quick. The
+ * repo, harness and model are inferred from the user's recent preferences.
+ */
+@Serializable
+data class QuickCreateTaskReq(val initialPrompt: Prompt)
+
+/**
+ * QuickCreateTaskResp is the response for POST /api/v1/tasks/quick. It
+ * reports what was inferred so the client can confirm it to the user.
+ */
+@Serializable
+data class QuickCreateTaskResp(
+    val status: String,
+    val id: String,
+    val repo: String? = null,
+    val baseBranch: String? = null,
+    val harness: Harness,
+    val model: String? = null,
+)
+
 /**
  * CILogResp is the response for GET /api/v1/tasks/{id}/ci-log.
  * It contains the name of the first failed CI step and its log tail.
@@ -541,7 +1219,22 @@ data class SafetyIssue(
     val detail: String,
 )
 
-/** SyncResp is the response for POST /api/v1/tasks/{id}/sync. */
+/** RepoSyncResult is the sync outcome for a single repo of a multi-repo task. */
+@Serializable
+data class RepoSyncResult(
+    val name: String,
+    val status: String,
+    val branch: String? = null,
+    val diffStat: List<DiffFileStat>? = null,
+    val safetyIssues: List<SafetyIssue>? = null,
+    val prNumber: Int? = null,
+    val error: String? = null,
+)
+
+/**
+ * SyncResp is the response for POST /api/v1/tasks/{id}/sync and
+ * POST /api/v1/tasks/{id}/push.
+ */
 @Serializable
 data class SyncResp(
     val status: String,
@@ -549,8 +1242,13 @@ data class SyncResp(
     val diffStat: List<DiffFileStat>? = null,
     val safetyIssues: List<SafetyIssue>? = null,
     val prNumber: Int? = null,
+    val repos: List<RepoSyncResult>? = null,
 )
 
+/** PushReq is the request body for POST /api/v1/tasks/{id}/push. */
+@Serializable
+data class PushReq(val force: Boolean? = null)
+
 /** ForkTaskReq is the request body for POST /api/v1/tasks/{id}/fork. */
 @Serializable
 data class ForkTaskReq(
@@ -560,9 +1258,211 @@ data class ForkTaskReq(
     val extraRepos: List<RepoSpec>? = null,
 )
 
+/** ApprovePlanReq is the request body for POST /api/v1/tasks/{id}/plan. */
+@Serializable
+data class ApprovePlanReq(val prompt: Prompt? = null)
+
+/** PromoteTaskReq is the request body for POST /api/v1/tasks/{id}/promote. */
+@Serializable
+data class PromoteTaskReq(val prompt: Prompt? = null)
+
+/**
+ * DiffDir is a directory of a DiffStat laid out as a tree, with the counts of
+ * all the files below it rolled up. Directories with a single subdirectory
+ * and no files are folded into their child, e.g. "backend/internal".
+ */
+@Serializable
+data class DiffDir(
+    val name: String,
+    val path: String,
+    val added: Int,
+    val deleted: Int,
+    val dirs: List<DiffDir>? = null,
+    val files: List<DiffFileStat>? = null,
+)
+
 /** DiffResp is the response for GET /api/v1/tasks/{id}/diff. */
 @Serializable
-data class DiffResp(val diff: String)
+data class DiffResp(
+    val diff: String,
+    val diffStat: List<DiffFileStat>? = null,
+    val tree: DiffDir? = null,
+)
+
+/** DiffSegment is a run of a DiffLine's text. */
+@Serializable
+data class DiffSegment(val text: String, val changed: Boolean? = null)
+
+/** DiffLine is a line of a hunk, without its +/-/space prefix. */
+@Serializable
+data class DiffLine(
+    val kind: String,
+    val text: String,
+    val oldLine: Int? = null,
+    val newLine: Int? = null,
+    @SerialName("noEOL") val noEOL: Boolean? = null,
+    val segments: List<DiffSegment>? = null,
+)
+
+/** DiffHunk is one "@@" section of a file diff. */
+@Serializable
+data class DiffHunk(
+    val oldStart: Int,
+    val oldLines: Int,
+    val newStart: Int,
+    val newLines: Int,
+    val section: String? = null,
+    val lines: List<DiffLine>,
+)
+
+/** DiffFile is the parsed diff of one file. */
+@Serializable
+data class DiffFile(
+    val path: String,
+    val oldPath: String? = null,
+    val status: String,
+    val language: String? = null,
+    val binary: Boolean? = null,
+    val hunks: List<DiffHunk>? = null,
+)
+
+/** DiffHunksResp is the response for GET /api/v1/tasks/{id}/diff/hunks. */
+@Serializable
+data class DiffHunksResp(val files: List<DiffFile>)
+
+/** TaskCommit is a commit the agent made on the task branch. */
+@Serializable
+data class TaskCommit(
+    val sha: String,
+    val author: String,
+    val message: String,
+    val authoredAt: String,
+    val committedAt: String,
+    val files: List<DiffFileStat>? = null,
+)
+
+/**
+ * RevertCommitReq is the request for POST
+ * /api/v1/tasks/{id}/commits/{sha}/revert.
+ */
+@Serializable
+data class RevertCommitReq()
+
+/**
+ * RevertCommitResp is the response for POST
+ * /api/v1/tasks/{id}/commits/{sha}/revert.
+ */
+@Serializable
+data class RevertCommitResp(val revert: String, val notified: Boolean)
+
+/** CreateSnapshotReq is the request for POST /api/v1/tasks/{id}/snapshots. */
+@Serializable
+data class CreateSnapshotReq(val name: String)
+
+/**
+ * TaskSnapshot is a named snapshot of a task's workspace, including the
+ * uncommitted files.
+ */
+@Serializable
+data class TaskSnapshot(
+    val name: String,
+    val commit: String,
+    val created: String,
+)
+
+/**
+ * RestoreSnapshotReq is the request for POST
+ * /api/v1/tasks/{id}/snapshots/{name}/restore.
+ */
+@Serializable
+data class RestoreSnapshotReq()
+
+/** StateTransition is one entry of a task's state history. */
+@Serializable
+data class StateTransition(
+    val from: String,
+    val to: String,
+    val at: String,
+)
+
+/** TimelinePhase is a span of a task's timeline. */
+@Serializable
+data class TimelinePhase(
+    val name: String,
+    val start: String,
+    val end: String? = null,
+    val durationMs: Long,
+    val turn: Int? = null,
+    val firstOutputMs: Long? = null,
+    val error: String? = null,
+)
+
+/** TaskTimeline is where the wall-clock time of a task went. */
+@Serializable
+data class TaskTimeline(
+    val phases: List<TimelinePhase>,
+    val totalMs: Long,
+    val phaseMs: Map<String, Long>,
+)
+
+/**
+ * ConsoleLine is a line of the raw console output of a task's container,
+ * outside of the agent's message stream.
+ */
+@Serializable
+data class ConsoleLine(
+    val source: String,
+    val text: String,
+    val at: String,
+)
+
+/**
+ * Annotation is a note or bookmark a reviewer attached to a message of a task
+ * transcript, e.g. to mark where the agent went wrong.
+ */
+@Serializable
+data class Annotation(
+    val id: String,
+    val index: Int,
+    val note: String? = null,
+    val bookmark: Boolean? = null,
+    val author: String,
+    val createdAt: String,
+)
+
+/**
+ * TaskMessagesResp is the response for GET /api/v1/tasks/{id}/messages: a
+ * page of the transcript for lazy-loading older history.
+ */
+@Serializable
+data class TaskMessagesResp(
+    val events: List<EventMessage>,
+    val next: Int,
+    val total: Int,
+    val annotations: List<Annotation>? = null,
+)
+
+/**
+ * MessageContentResp is the response for
+ * GET /api/v1/tasks/{id}/messages/{index}/content: the full content of a
+ * message whose events were truncated.
+ */
+@Serializable
+data class MessageContentResp(val content: String, val encoding: String? = null)
+
+/**
+ * AnnotateMessageReq is the request for POST
+ * /api/v1/tasks/{id}/messages/{index}/annotations.
+ */
+@Serializable
+data class AnnotateMessageReq(val note: String? = null, val bookmark: Boolean? = null)
+
+/**
+ * DeleteAnnotationReq is the request for POST
+ * /api/v1/tasks/{id}/annotations/{annotationID}/delete.
+ */
+@Serializable
+data class DeleteAnnotationReq()
 
 /**
  * TaskToolInputResp is the response for GET /api/v1/tasks/{id}/tool/{toolUseID}.
@@ -630,7 +1530,7 @@ data class CodexRateLimitWindow(
     val usedPercent: Int,
     val limitWindowSeconds: Int,
     val resetAfterSeconds: Int,
-    val resetAt: Int,
+    val resetAt: String,
 )
 
 /** CodexCredits represents Codex credit/balance information. */
@@ -650,9 +1550,44 @@ data class CodexUsage(
     val credits: CodexCredits,
 )
 
+/**
+ * DiskUsage reports the disk space used by tasks and left on the filesystem
+ * holding the logs.
+ */
+@Serializable
+data class DiskUsage(
+    val containerBytes: Long,
+    val logBytes: Long,
+    val freeBytes: Long,
+    val totalBytes: Long,
+    val minFreeBytes: Long? = null,
+    val low: Boolean? = null,
+)
+
+/**
+ * HarnessRateLimit is the provider rate limit headroom of a harness, as last
+ * reported by its agents, and the tasks backing off from it.
+ */
+@Serializable
+data class HarnessRateLimit(
+    val harness: Harness,
+    val status: String? = null,
+    val rateLimitType: String? = null,
+    val utilization: Double? = null,
+    val resetsAt: String? = null,
+    val reportedAt: String? = null,
+    val limitedTasks: Int,
+    val resumesAt: String? = null,
+)
+
 /** UsageResp is the response for GET /api/v1/usage. */
 @Serializable
-data class UsageResp(val claude: ClaudeUsage? = null, val codex: CodexUsage? = null)
+data class UsageResp(
+    val claude: ClaudeUsage? = null,
+    val codex: CodexUsage? = null,
+    val disk: DiskUsage? = null,
+    val rateLimits: List<HarnessRateLimit>? = null,
+)
 
 /** VoiceTokenResp is the response for GET /api/v1/voice/token. */
 @Serializable
@@ -682,7 +1617,20 @@ data class VoiceRTCAnswerResp(
 )
 
 @Serializable
-data class ErrorDetails(val code: String, val message: String)
+data class FieldError(
+    val field: String,
+    val rule: String,
+    val message: String,
+    val params: Map<String, String>? = null,
+)
+
+@Serializable
+data class ErrorDetails(
+    val code: String,
+    val message: String,
+    val params: Map<String, String>? = null,
+    val fields: List<FieldError>? = null,
+)
 
 @Serializable
 data class ErrorResponse(val error: ErrorDetails, val details: Map<String, JsonElement>? = null)
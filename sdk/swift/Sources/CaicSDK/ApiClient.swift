@@ -6,6 +6,7 @@ public struct ApiError: Error {
     public let code: String
     public let message: String
     public let details: [String: JSONValue]?
+    public var params: [String: String]? = nil
 }
 
 public final class ApiClient {
@@ -39,7 +40,7 @@ public final class ApiClient {
         guard (200..<300).contains(httpResponse.statusCode) else {
             if let errResp = try? decoder.decode(ErrorResponse.self, from: data) {
                 throw ApiError(statusCode: httpResponse.statusCode, code: errResp.error.code,
-                               message: errResp.error.message, details: nil)
+                               message: errResp.error.message, details: nil, params: errResp.error.params)
             }
             throw ApiError(statusCode: httpResponse.statusCode, code: "UNKNOWN",
                            message: String(data: data, encoding: .utf8) ?? "", details: nil)
@@ -127,14 +128,70 @@ public final class ApiClient {
     public func updatePreferences(req: UpdatePreferencesReq) async throws -> PreferencesResp {
         try await request("POST", path: "/api/v1/server/preferences", body: try encoder.encode(req))
     }
+    /// Lists previously used prompts, most recent first. ?repo= limits to a repo; ?prefix= keeps prompts starting with it, ignoring case; ?limit= defaults to 20.
+    public func listRecentPrompts(repo: String, prefix: String, limit: String) async throws -> [RecentPrompt] {
+        try await request("GET", path: "/api/v1/prompts/recent?repo=\(repo.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? repo)&prefix=\(prefix.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? prefix)&limit=\(limit.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? limit)")
+    }
+    /// Analyzes a draft prompt before creating a task and returns suggestions to improve it.
+    public func lintPrompt(req: LintPromptReq) async throws -> LintPromptResp {
+        try await request("POST", path: "/api/v1/prompts/lint", body: try encoder.encode(req))
+    }
     /// Lists available coding agent harnesses.
     public func listHarnesses() async throws -> [HarnessInfo] {
         try await request("GET", path: "/api/v1/server/harnesses")
     }
+    /// Reports server liveness and readiness; answers 503 when not ready.
+    public func getHealth() async throws -> HealthResp {
+        try await request("GET", path: "/api/v1/health")
+    }
+    /// Returns a goroutine and heap snapshot of the server; admin only.
+    public func getRuntime() async throws -> RuntimeResp {
+        try await request("GET", path: "/api/v1/admin/runtime")
+    }
+    /// Reports the source and hash of the served web UI build; admin only.
+    public func getFrontendBuild() async throws -> FrontendBuildResp {
+        try await request("GET", path: "/api/v1/admin/frontend")
+    }
+    /// Checks each harness binary in the container image and its API credentials.
+    public func listHarnessHealth() async throws -> [HarnessHealth] {
+        try await request("GET", path: "/api/v1/health/harnesses")
+    }
+    /// Smoke tests a base image in a throwaway container; a passing pending base image becomes the default.
+    public func validateBaseImage(req: ValidateImageReq) async throws -> ImageValidationResp {
+        try await request("POST", path: "/api/v1/server/image/validate", body: try encoder.encode(req))
+    }
+    /// Lists the base images the caller pinned to a registry digest.
+    public func listImagePins() async throws -> ImagePinsResp {
+        try await request("GET", path: "/api/v1/server/image/pins")
+    }
+    /// Pulls a base image, the caller's by default, and pins the new tasks using it to the digest it resolves to. Pinning it again adopts the latest digest.
+    public func pinImage(req: ImagePinReq) async throws -> ImagePinsResp {
+        try await request("POST", path: "/api/v1/server/image/pins", body: try encoder.encode(req))
+    }
+    /// Removes the digest pin of a base image; new tasks use its tag again.
+    public func unpinImage(req: ImagePinReq) async throws -> ImagePinsResp {
+        try await request("POST", path: "/api/v1/server/image/pins/delete", body: try encoder.encode(req))
+    }
+    /// Pulls a base image, the caller's by default, and reports whether its tag now resolves to another digest than the pinned one.
+    public func checkImageUpdate(req: ImagePinReq) async throws -> ImageUpdateResp {
+        try await request("POST", path: "/api/v1/server/image/check-update", body: try encoder.encode(req))
+    }
+    /// Returns the effective model price table, including preference overrides.
+    public func listPrices() async throws -> [PriceEntry] {
+        try await request("GET", path: "/api/v1/server/prices")
+    }
     /// Lists well-known cache configurations.
     public func listCaches() async throws -> WellKnownCachesResp {
         try await request("GET", path: "/api/v1/server/caches")
     }
+    /// Returns the server log level.
+    public func getLogLevel() async throws -> LogLevelResp {
+        try await request("GET", path: "/api/v1/server/log-level")
+    }
+    /// Changes the server log level until the next restart.
+    public func setLogLevel(req: LogLevelReq) async throws -> LogLevelResp {
+        try await request("POST", path: "/api/v1/server/log-level", body: try encoder.encode(req))
+    }
     /// Lists all discovered repositories.
     public func listRepos() async throws -> [Repo] {
         try await request("GET", path: "/api/v1/server/repos")
@@ -143,10 +200,38 @@ public final class ApiClient {
     public func cloneRepo(req: CloneRepoReq) async throws -> Repo {
         try await request("POST", path: "/api/v1/server/repos", body: try encoder.encode(req))
     }
+    /// Lists the git remotes of a repository and the one task branches are pushed to by default.
+    public func listRepoRemotes(repo: String) async throws -> RepoRemotesResp {
+        try await request("GET", path: "/api/v1/server/repos/remotes?repo=\(repo.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? repo)")
+    }
+    /// Adds a git remote to a repository, e.g. a fork, or changes its URL, and optionally makes it the default push target of the task branches. PRs of branches pushed to a fork are opened against origin.
+    public func setRepoRemote(req: SetRepoRemoteReq) async throws -> RepoRemotesResp {
+        try await request("POST", path: "/api/v1/server/repos/remotes", body: try encoder.encode(req))
+    }
+    /// Removes a git remote of a repository. Task branches go back to origin when it was their push target.
+    public func deleteRepoRemote(req: DeleteRepoRemoteReq) async throws -> RepoRemotesResp {
+        try await request("POST", path: "/api/v1/server/repos/remotes/delete", body: try encoder.encode(req))
+    }
+    /// Generates the SSH deploy key of a repository, or returns the existing one, and uses it for the repository's fetches and pushes, on the server and in task containers. Add the public key on the forge with write access.
+    public func createDeployKey(req: DeployKeyReq) async throws -> DeployKeyResp {
+        try await request("POST", path: "/api/v1/server/repos/deploykey", body: try encoder.encode(req))
+    }
+    /// Deletes the deploy key of a repository, which goes back to the server's credentials.
+    public func deleteDeployKey(req: DeleteDeployKeyReq) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/server/repos/deploykey/delete", body: try encoder.encode(req))
+    }
     /// Lists branches for a repository.
     public func listRepoBranches(repo: String) async throws -> RepoBranchesResp {
         try await request("GET", path: "/api/v1/server/repos/branches?repo=\(repo.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? repo)")
     }
+    /// Searches the files of a repository's base branch for a literal, case-insensitive query. Queries shorter than 3 bytes only match paths.
+    public func searchRepo(repo: String, q: String, limit: String) async throws -> RepoSearchResp {
+        try await request("GET", path: "/api/v1/server/repos/search?repo=\(repo.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? repo)&q=\(q.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? q)&limit=\(limit.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? limit)")
+    }
+    /// Searches a repository's base branch for code similar in meaning to the query. Requires an embedding provider; see Config.SemanticSearch.
+    public func semanticSearchRepo(repo: String, q: String, limit: String) async throws -> RepoSemanticSearchResp {
+        try await request("GET", path: "/api/v1/server/repos/semantic-search?repo=\(repo.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? repo)&q=\(q.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? q)&limit=\(limit.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? limit)")
+    }
     /// Creates a task to fix a failing CI pipeline.
     public func botFixCI(req: BotFixCIReq) async throws -> CreateTaskResp {
         try await request("POST", path: "/api/v1/bot/fix-ci", body: try encoder.encode(req))
@@ -155,10 +240,50 @@ public final class ApiClient {
     public func botFixPR(req: BotFixPRReq) async throws -> StatusResp {
         try await request("POST", path: "/api/v1/bot/fix-pr", body: try encoder.encode(req))
     }
-    /// Returns all tasks.
+    /// Lists the eval runs since the server started.
+    public func listEvals() async throws -> [EvalReport] {
+        try await request("GET", path: "/api/v1/evals")
+    }
+    /// Runs an eval suite across harness/model targets.
+    public func createEval(req: CreateEvalReq) async throws -> EvalReport {
+        try await request("POST", path: "/api/v1/evals", body: try encoder.encode(req))
+    }
+    /// Returns the report of an eval run.
+    public func getEval(id: String) async throws -> EvalReport {
+        try await request("GET", path: "/api/v1/evals/\(id)")
+    }
+    /// Lists the pipeline definitions saved as JSON or YAML files.
+    public func listPipelines() async throws -> [Pipeline] {
+        try await request("GET", path: "/api/v1/pipelines")
+    }
+    /// Lists the user's saved task list views.
+    public func listTaskViews() async throws -> TaskViewsResp {
+        try await request("GET", path: "/api/v1/views")
+    }
+    /// Saves a named task list view, replacing the view of the same name. Its query holds the filter and sort query parameters of listTasks. Making it the default unsets the previous default.
+    public func saveTaskView(req: TaskView) async throws -> TaskViewsResp {
+        try await request("POST", path: "/api/v1/views", body: try encoder.encode(req))
+    }
+    /// Deletes a saved task list view.
+    public func deleteTaskView(req: DeleteTaskViewReq) async throws -> TaskViewsResp {
+        try await request("POST", path: "/api/v1/views/delete", body: try encoder.encode(req))
+    }
+    /// Returns all tasks except archived ones, pinned first. ?includeArchived=true includes them; ?pinned=true keeps only pinned tasks; ?state=a,b keeps the tasks in these states; ?repo= keeps the tasks of a repo; ?project= keeps the tasks with a repo of the project in the user's settings; ?createdWithin=168h and ?stateOlderThan=24h keep the tasks created less than, or in their state for more than, a Go duration; ?sort=created|updated|cost|duration|state with ?order=asc|desc sorts them, by creation ascending by default; ?fields=a,b keeps only the listed fields; ?view=summary drops the prompt and plan.
     public func listTasks() async throws -> [Task] {
         try await request("GET", path: "/api/v1/tasks")
     }
+    /// Exports the metadata of the tasks created in [?from, ?to), dates or RFC 3339 times, for reporting. ?project= keeps the tasks of a project of the user's settings. ?format=csv returns a CSV file with the same columns instead of JSON.
+    public func exportTasks(format: String, from: String, to: String, project: String) async throws -> [TaskExport] {
+        try await request("GET", path: "/api/v1/tasks/export?format=\(format.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? format)&from=\(from.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? from)&to=\(to.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? to)&project=\(project.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? project)")
+    }
+    /// Returns a task with every field, including those dropped from the summary view.
+    public func getTask(id: String) async throws -> Task {
+        try await request("GET", path: "/api/v1/tasks/\(id)")
+    }
+    /// Updates the mutable attributes of a task, e.g. archives it.
+    public func updateTask(id: String, req: UpdateTaskReq) async throws -> Task {
+        try await request("PATCH", path: "/api/v1/tasks/\(id)", body: try encoder.encode(req))
+    }
     /// Creates and starts a new coding agent task.
     public func createTask(req: CreateTaskReq) async throws -> CreateTaskResp {
         try await request("POST", path: "/api/v1/tasks", body: try encoder.encode(req))
@@ -171,6 +296,10 @@ public final class ApiClient {
     public func restartTask(id: String, req: RestartReq) async throws -> StatusResp {
         try await request("POST", path: "/api/v1/tasks/\(id)/restart", body: try encoder.encode(req))
     }
+    /// Replaces the last user input of a waiting task and restarts its session from the turn before.
+    public func rewindTask(id: String, req: RewindReq) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/rewind", body: try encoder.encode(req))
+    }
     /// Clears context and restarts the agent session without a prompt.
     public func clearContext(id: String) async throws -> StatusResp {
         try await request("POST", path: "/api/v1/tasks/\(id)/clear-context")
@@ -179,6 +308,14 @@ public final class ApiClient {
     public func compactContext(id: String, req: CompactReq) async throws -> StatusResp {
         try await request("POST", path: "/api/v1/tasks/\(id)/compact", body: try encoder.encode(req))
     }
+    /// Cancels the agent's current turn; the task returns to waiting for input.
+    public func interruptTask(id: String) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/interrupt")
+    }
+    /// Changes the priority of a pending task, moving it in the start queue.
+    public func setTaskPriority(id: String, req: SetPriorityReq) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/priority", body: try encoder.encode(req))
+    }
     /// Requests graceful stop of a running task.
     public func stopTask(id: String) async throws -> StatusResp {
         try await request("POST", path: "/api/v1/tasks/\(id)/stop")
@@ -187,10 +324,26 @@ public final class ApiClient {
     public func purgeTask(id: String) async throws -> StatusResp {
         try await request("POST", path: "/api/v1/tasks/\(id)/purge")
     }
+    /// Creates a task from a prompt alone, inferring the repo, harness and model from recent use.
+    public func quickCreateTask(req: QuickCreateTaskReq) async throws -> QuickCreateTaskResp {
+        try await request("POST", path: "/api/v1/tasks/quick", body: try encoder.encode(req))
+    }
+    /// Pins a task to the top of the task list.
+    public func pinTask(id: String) async throws -> Task {
+        try await request("POST", path: "/api/v1/tasks/\(id)/pin")
+    }
+    /// Unpins a task.
+    public func unpinTask(id: String) async throws -> Task {
+        try await request("POST", path: "/api/v1/tasks/\(id)/unpin")
+    }
     /// Reconnects to an orphaned task container.
     public func reviveTask(id: String) async throws -> StatusResp {
         try await request("POST", path: "/api/v1/tasks/\(id)/revive")
     }
+    /// Starts a fresh container for a task whose container is gone and restores its saved workspace. Requires the persistWorkspace setting when the task ended.
+    public func rehydrateTask(id: String) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/rehydrate")
+    }
     /// Returns the log tail of a failed CI check run.
     public func getTaskCILog(id: String, jobID: String) async throws -> CILogResp {
         try await request("GET", path: "/api/v1/tasks/\(id)/ci-log?jobID=\(jobID.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? jobID)")
@@ -199,19 +352,91 @@ public final class ApiClient {
     public func syncTask(id: String, req: SyncReq) async throws -> SyncResp {
         try await request("POST", path: "/api/v1/tasks/\(id)/sync", body: try encoder.encode(req))
     }
+    /// Pushes the task branch to its push remote without opening a PR, for the tasks whose push policy is ask. Rejected when the policy is never.
+    public func pushTask(id: String, req: PushReq) async throws -> SyncResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/push", body: try encoder.encode(req))
+    }
     /// Forks a task by snapshotting its container and creating a new task on a derived branch.
     public func forkTask(id: String, req: ForkTaskReq) async throws -> CreateTaskResp {
         try await request("POST", path: "/api/v1/tasks/\(id)/fork", body: try encoder.encode(req))
     }
-    /// Returns the unified diff for a task's branch.
-    public func getTaskDiff(id: String) async throws -> DiffResp {
-        try await request("GET", path: "/api/v1/tasks/\(id)/diff")
+    /// Approves, optionally edited, the plan of a task in plan_review and lets the agent make changes.
+    public func approvePlan(id: String, req: ApprovePlanReq) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/plan", body: try encoder.encode(req))
+    }
+    /// Creates a real task from a plan-only task, seeded with its approved plan.
+    public func promoteTask(id: String, req: PromoteTaskReq) async throws -> CreateTaskResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/promote", body: try encoder.encode(req))
+    }
+    /// Returns the unified diff for a task's branch, computed inside its container, optionally limited to one path. ?live=true also refreshes the task's diff stat and broadcasts it as a diffStat event when it changed, so running tasks show progress.
+    public func getTaskDiff(id: String, path: String, live: String) async throws -> DiffResp {
+        try await request("GET", path: "/api/v1/tasks/\(id)/diff?path=\(path.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? path)&live=\(live.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? live)")
+    }
+    /// Returns the task diff parsed into files and hunks, with each file's language, optionally limited to one path. ?intraline=true also splits paired changed lines into changed and unchanged words.
+    public func getTaskDiffHunks(id: String, path: String, intraline: String) async throws -> DiffHunksResp {
+        try await request("GET", path: "/api/v1/tasks/\(id)/diff/hunks?path=\(path.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? path)&intraline=\(intraline.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? intraline)")
+    }
+    /// Returns the commits on the task branch that are not on its base branch, newest first, with the files each one changed.
+    public func getTaskCommits(id: String) async throws -> [TaskCommit] {
+        try await request("GET", path: "/api/v1/tasks/\(id)/commits")
+    }
+    /// Reverts one commit of the task branch inside the container with a new commit, and tells the agent so it doesn't reintroduce the change.
+    public func revertTaskCommit(id: String, sha: String, req: RevertCommitReq) async throws -> RevertCommitResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/commits/\(sha)/revert", body: try encoder.encode(req))
+    }
+    /// Saves the task's workspace, including uncommitted and untracked files, as a named snapshot inside the container.
+    public func createTaskSnapshot(id: String, req: CreateSnapshotReq) async throws -> TaskSnapshot {
+        try await request("POST", path: "/api/v1/tasks/\(id)/snapshots", body: try encoder.encode(req))
+    }
+    /// Returns the task's workspace snapshots, oldest first.
+    public func listTaskSnapshots(id: String) async throws -> [TaskSnapshot] {
+        try await request("GET", path: "/api/v1/tasks/\(id)/snapshots")
+    }
+    /// Resets the task's workspace to a snapshot, discarding the commits and changes made since, and tells the agent. The task must not be running.
+    public func restoreTaskSnapshot(id: String, name: String, req: RestoreSnapshotReq) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/snapshots/\(name)/restore", body: try encoder.encode(req))
+    }
+    /// Approves the tool call the task is paused on for violating the tool policy and resumes the task.
+    public func approveTaskPolicy(id: String) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/policy/approve")
+    }
+    /// Denies the tool call the task is paused on for violating the tool policy and stops the task.
+    public func denyTaskPolicy(id: String) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/policy/deny")
+    }
+    /// Returns the task's state transition history, oldest first.
+    public func getTaskTransitions(id: String) async throws -> [StateTransition] {
+        try await request("GET", path: "/api/v1/tasks/\(id)/transitions")
+    }
+    /// Returns the phases of the task with their durations: queue, container start, each turn with its time to first output, verification, push.
+    public func getTaskTimeline(id: String) async throws -> TaskTimeline {
+        try await request("GET", path: "/api/v1/tasks/\(id)/timeline")
+    }
+    /// Returns a page of the task transcript: up to limit messages from message index after, with their annotations.
+    public func getTaskMessages(id: String, after: String, limit: String) async throws -> TaskMessagesResp {
+        try await request("GET", path: "/api/v1/tasks/\(id)/messages?after=\(after.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? after)&limit=\(limit.addingPercentEncoding(withAllowedCharacters: .urlQueryAllowed) ?? limit)")
+    }
+    /// Returns the full content of a message whose events were truncated to a preview.
+    public func getTaskMessageContent(id: String, index: String) async throws -> MessageContentResp {
+        try await request("GET", path: "/api/v1/tasks/\(id)/messages/\(index)/content")
+    }
+    /// Attaches a note or a bookmark to a message of the task transcript.
+    public func annotateTaskMessage(id: String, index: String, req: AnnotateMessageReq) async throws -> Annotation {
+        try await request("POST", path: "/api/v1/tasks/\(id)/messages/\(index)/annotations", body: try encoder.encode(req))
+    }
+    /// Returns the annotations of the task transcript, by message index.
+    public func listTaskAnnotations(id: String) async throws -> [Annotation] {
+        try await request("GET", path: "/api/v1/tasks/\(id)/annotations")
+    }
+    /// Deletes an annotation of the task transcript.
+    public func deleteTaskAnnotation(id: String, annotationID: String, req: DeleteAnnotationReq) async throws -> StatusResp {
+        try await request("POST", path: "/api/v1/tasks/\(id)/annotations/\(annotationID)/delete", body: try encoder.encode(req))
     }
     /// Returns the full (untruncated) input for a tool call.
     public func getTaskToolInput(id: String, toolUseID: String) async throws -> TaskToolInputResp {
         try await request("GET", path: "/api/v1/tasks/\(id)/tool/\(toolUseID)")
     }
-    /// Returns current usage quota statistics.
+    /// Returns current usage quota statistics. ?project= sums the cost and tokens of, and lists the rate limited, tasks with a repo of the project in the user's settings only; the quota utilization stays account-wide.
     public func getUsage() async throws -> UsageResp {
         try await request("GET", path: "/api/v1/usage")
     }
@@ -233,15 +458,19 @@ public final class ApiClient {
     public func taskRawEvents(id: String) -> AsyncThrowingStream<EventMessage, Error> {
         sseStream(path: "/api/v1/tasks/\(id)/raw_events")
     }
-    /// Streams backend-neutral task events via SSE.
+    /// Streams backend-neutral task events via SSE. ?from=N starts the history replay at message index N; a negative N replays only the last -N messages. ?batch=MS coalesces events into "batch" events holding JSON arrays, written at most every MS milliseconds. ?replay=realtime replays the history with its original pacing, sped up by ?speed=X (at most 100), for watching a past run. The "ready" event ends the replay with the index it started at, the history length and the transcript annotations.
     public func taskEvents(id: String) -> AsyncThrowingStream<EventMessage, Error> {
         sseStream(path: "/api/v1/tasks/\(id)/events")
     }
-    /// Streams task list updates for all tasks via SSE.
+    /// Streams the raw console output of the task's container via SSE: its stdout/stderr and the stderr of the agent process, starting with the last ?tail=N lines of each (default 200). The output of the commands the agent runs as tools is not included; it is in the tool results of the task's messages. The stream ends when the container stops.
+    public func taskConsole(id: String) -> AsyncThrowingStream<ConsoleLine, Error> {
+        sseStream(path: "/api/v1/tasks/\(id)/console")
+    }
+    /// Streams task list updates for all tasks via SSE. ?includeArchived, ?pinned, ?state, ?repo, ?project, ?createdWithin and ?stateOlderThan filter tasks as for listTasks.
     public func globalTaskEvents() -> AsyncThrowingStream<TaskListEvent, Error> {
         sseStream(path: "/api/v1/server/tasks/events")
     }
-    /// Streams usage quota updates via SSE.
+    /// Streams usage quota updates via SSE. ?project= counts only the tasks with a repo of the project, as for getUsage.
     public func globalUsageEvents() -> AsyncThrowingStream<UsageResp, Error> {
         sseStream(path: "/api/v1/server/usage/events")
     }
@@ -253,6 +482,9 @@ public final class ApiClient {
     public func taskEventsReconnecting(id: String) -> AsyncThrowingStream<EventMessage, Error> {
         reconnectingStream { self.taskEvents(id: id) }
     }
+    public func taskConsoleReconnecting(id: String) -> AsyncThrowingStream<ConsoleLine, Error> {
+        reconnectingStream { self.taskConsole(id: id) }
+    }
     public func globalTaskEventsReconnecting() -> AsyncThrowingStream<TaskListEvent, Error> {
         reconnectingStream { self.globalTaskEvents() }
     }
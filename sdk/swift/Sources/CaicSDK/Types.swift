@@ -37,9 +37,12 @@ public enum JSONValue: Codable, Equatable {
 public typealias Harness = String
 
 public enum Harnesses {
+    public static let Aider: Harness = "aider"
     public static let Claude: Harness = "claude"
     public static let Codex: Harness = "codex"
+    public static let Generic: Harness = "generic"
     public static let Gemini: Harness = "gemini"
+    public static let Local: Harness = "local"
     public static let OpenCode: Harness = "opencode"
 }
 
@@ -85,6 +88,8 @@ public struct Config: Codable {
     public let gitHubAppEnabled: Bool?
     /// e.g. ["github","gitlab"]
     public let authProviders: [String]?
+    /// GET /api/v1/server/repos/semantic-search is available.
+    public let semanticSearch: Bool?
 }
 
 /// UserResp is returned by GET /api/v1/auth/me.
@@ -93,6 +98,8 @@ public struct UserResp: Codable {
     public let provider: String
     public let username: String
     public let avatarURL: String?
+    /// May use the /api/v1/admin endpoints.
+    public let admin: Bool?
 }
 
 /// StatusResp is a common response for mutation endpoints.
@@ -108,12 +115,104 @@ public struct RepoPrefsResp: Codable {
     public let model: String?
 }
 
+/// PendingImage is a new base image that is smoke tested before tasks use it.
+public struct PendingImage: Codable {
+    public let image: String
+    public let status: String
+    /// Why the validation failed.
+    public let error: String?
+}
+
 /// CacheMappingResp represents a directory mapping for cache/state sharing.
 public struct CacheMappingResp: Codable {
     public let hostPath: String
     public let containerPath: String
 }
 
+/// ModelPrice is the price of a model in USD per million tokens.
+public struct ModelPrice: Codable {
+    public let input: Double
+    public let output: Double
+    public let cacheWrite: Double?
+    public let cacheRead: Double?
+    /// CacheReadIncluded is set when the harness counts cache reads as part of
+    /// the input tokens (OpenAI convention).
+    public let cacheReadIncluded: Bool?
+}
+
+/// PolicyRule is a tool policy rule. A tool call violating it pauses the task
+/// until the user approves or denies it.
+public struct PolicyRule: Codable {
+    public let name: String
+    public let kind: String
+    /// Regular expression for "command" rules.
+    public let pattern: String?
+    /// Host globs for "network" rules, absolute directories for "path" rules.
+    public let allow: [String]?
+}
+
+/// IdlePolicy acts on tasks left waiting for input.
+public struct IdlePolicy: Codable {
+    /// Hours waiting before the action; 0 disables the policy.
+    public let hours: Int
+    public let action: String
+}
+
+/// StallPolicy flags the running tasks whose agent sent no message for a
+/// while: a hung harness otherwise looks like one thinking for long.
+public struct StallPolicy: Codable {
+    /// Minutes of silence before the task is flagged; 0 disables the policy.
+    public let minutes: Int
+    /// Also interrupt the turn of a stalled task.
+    public let interrupt: Bool?
+}
+
+/// Fallback is the harness and model used when those of a task fail to start,
+/// e.g. during a provider outage.
+public struct Fallback: Codable {
+    /// Empty keeps the task's harness.
+    public let harness: Harness?
+    /// Empty uses the harness default.
+    public let model: String?
+}
+
+/// ExecutionWindow is the time of day during which new tasks may start.
+public struct ExecutionWindow: Codable {
+    /// "15:04" clock time.
+    public let start: String
+    /// "15:04" clock time; before Start spans midnight.
+    public let end: String
+    /// IANA name; empty is the server's local time.
+    public let timezone: String?
+}
+
+/// NotifyChannel is a named chat channel. Secret is the webhook URL of Slack
+/// and Discord, the access token of Matrix and the bot token of Telegram. It
+/// is write-only: it is never returned, and an empty secret keeps the saved
+/// one of the channel with the same name and kind.
+public struct NotifyChannel: Codable {
+    public let name: String
+    public let kind: String
+    /// Matrix homeserver URL.
+    public let url: String?
+    public let secret: String?
+    /// Response only.
+    public let hasSecret: Bool?
+    /// Matrix room ID or Telegram chat ID.
+    public let target: String?
+}
+
+/// GenericHarness configures the "generic" harness, an agent loop against an
+/// OpenAI-compatible chat completions API. The API key is write-only: it is
+/// never returned, and an empty key keeps the saved one for the same URL.
+public struct GenericHarness: Codable {
+    public let baseURL: String
+    public let apiKey: String?
+    /// Response only.
+    public let hasAPIKey: Bool?
+    public let model: String
+}
+
 /// UserSettings holds user-configurable behavioral settings.
 public struct UserSettings: Codable {
     /// AutoFixOnCIFailure automatically starts a new task to fix CI when a
@@ -123,9 +222,26 @@ public struct UserSettings: Codable {
     /// AutoFixOnPROpen automatically creates a task to review and fix a pull
     /// request when it is opened or reopened via a forge webhook.
     public let autoFixOnPROpen: Bool
+    /// AutoRespondToReviews hands review feedback left on the PR of a task
+    /// back to its agent, or to a follow-up task on the PR branch when the
+    /// agent can no longer receive input. Only effective with the GitHub App
+    /// webhook.
+    public let autoRespondToReviews: Bool
     /// BaseImage overrides the default container base image. Empty means use
     /// the default.
     public let baseImage: String?
+    /// ValidateBaseImage smoke tests a changed BaseImage in a throwaway
+    /// container before new tasks use it.
+    public let validateBaseImage: Bool?
+    /// PendingBaseImage is the changed BaseImage while it is validated or
+    /// after it failed; BaseImage keeps the previous image meanwhile.
+    /// Response only.
+    public let pendingBaseImage: PendingImage?
+    /// PersistWorkspace saves the workspace of a task, uncommitted files
+    /// included, before its container is stopped or purged, so that the task
+    /// can be rehydrated into a fresh container with
+    /// POST /api/v1/tasks/{id}/rehydrate.
+    public let persistWorkspace: Bool?
     /// GitHubTokenAccess controls the GitHub token injected into containers.
     /// "none" (default): no token. "read-write": passes the parent token.
     public let gitHubTokenAccess: String?
@@ -137,6 +253,60 @@ public struct UserSettings: Codable {
     public let wellKnownCaches: [String: Bool]?
     /// CacheMappings are custom host-to-container directory mappings.
     public let cacheMappings: [CacheMappingResp]?
+    /// ModelPrices overrides the built-in price table, keyed by model name
+    /// prefix, e.g. for enterprise pricing.
+    public let modelPrices: [String: ModelPrice]?
+    /// Network is the default network mode of new tasks; empty means full.
+    public let network: String?
+    /// NetworkAllow is the default host allowlist of new tasks.
+    public let networkAllow: [String]?
+    /// ToolPolicy are the rules applied to the tool calls of every task.
+    public let toolPolicy: [PolicyRule]?
+    /// RepoToolPolicies are additional rules keyed by repository path.
+    public let repoToolPolicies: [String: [PolicyRule]]?
+    /// IdlePolicy acts on tasks left waiting for input. Nil disables it.
+    public let idlePolicy: IdlePolicy?
+    /// RepoIdlePolicies override IdlePolicy, keyed by repository path.
+    public let repoIdlePolicies: [String: IdlePolicy]?
+    /// StallPolicy flags running tasks whose agent stays silent. Nil disables
+    /// it.
+    public let stallPolicy: StallPolicy?
+    /// Fallback is the harness and model a task switches to when its own fail
+    /// to start. Nil disables the failover.
+    public let fallback: Fallback?
+    /// RepoPushPolicies are the push policies keyed by repository path; the
+    /// other repos use auto.
+    public let repoPushPolicies: [String: String]?
+    /// RepoPrePushChecks are local CI scripts keyed by repository path, e.g.
+    /// "make test" or "act push". Each runs in the task's container before its
+    /// branch is pushed; a failure blocks the push unless forced. The output is
+    /// posted in the task's transcript.
+    public let repoPrePushChecks: [String: String]?
+    /// RepoInstructions are custom instructions appended to the system prompt
+    /// of the agents of a repo's tasks, keyed by repository path, after the
+    /// repo's own .caic/instructions.md.
+    public let repoInstructions: [String: String]?
+    /// RepoProjects maps repository paths to the name of their project. The
+    /// task list and the export filter by project with ?project=.
+    public let repoProjects: [String: String]?
+    /// ExecutionWindow holds new tasks in "pending" until it is open. Nil runs
+    /// them immediately.
+    public let executionWindow: ExecutionWindow?
+    /// NotifyChannels are the chat channels task notifications can be sent to.
+    public let notifyChannels: [NotifyChannel]?
+    /// NotifyRoutes maps a task state to the names of the channels notified
+    /// when a task enters it, e.g. "waiting" to a direct message channel and
+    /// "failed" to the team's channel. "stalled" routes the tasks flagged by
+    /// the stall policy.
+    public let notifyRoutes: [String: [String]]?
+    /// EnvAllowlist are the names of the environment variables tasks may set
+    /// through CreateTaskReq.Env, or glob patterns like "FEATURE_*".
+    public let envAllowlist: [String]?
+    /// Timezone is the IANA time zone, e.g. "Europe/Paris", the notifications
+    /// and the exports render times in. Empty means UTC.
+    public let timezone: String?
+    /// GenericHarness configures the "generic" harness. Nil disables it.
+    public let genericHarness: GenericHarness?
 }
 
 /// PreferencesResp is the response for GET /api/v1/server/preferences.
@@ -152,12 +322,184 @@ public struct UpdatePreferencesReq: Codable {
     public let settings: UserSettings
 }
 
+/// RecentPrompt is a previously used task prompt, returned by GET
+/// /api/v1/prompts/recent.
+public struct RecentPrompt: Codable {
+    /// Empty for no-repo tasks.
+    public let repo: String?
+    public let text: String
+    public let lastUsed: String
+}
+
+/// LintPromptReq is the request body for POST /api/v1/prompts/lint.
+public struct LintPromptReq: Codable {
+    public let text: String
+    /// Primary repo; enables file reference checks.
+    public let repo: String?
+    /// Also ask the configured LLM for suggestions.
+    public let useLLM: Bool?
+}
+
+/// PromptSuggestion is one improvement proposed for a draft prompt.
+public struct PromptSuggestion: Codable {
+    public let kind: String
+    public let severity: String
+    public let message: String
+}
+
+/// LintPromptResp is the response for POST /api/v1/prompts/lint.
+public struct LintPromptResp: Codable {
+    public let suggestions: [PromptSuggestion]
+    /// The LLM was consulted.
+    public let llm: Bool?
+}
+
 /// HarnessInfo is the JSON representation of an available harness.
 public struct HarnessInfo: Codable {
     public let name: String
     public let models: [String]
     public let supportsImages: Bool
     public let supportsCompact: Bool
+    /// SupportsInterrupt reports whether a running turn can be interrupted.
+    public let supportsInterrupt: Bool
+    /// SupportsThinking reports whether CreateTaskReq.Thinking is honored.
+    public let supportsThinking: Bool
+}
+
+/// HealthCheck is the outcome of a single readiness probe.
+public struct HealthCheck: Codable {
+    public let name: String
+    public let ok: Bool
+    public let detail: String?
+}
+
+/// HealthResp is returned by GET /api/v1/health. The endpoint answers 200 when
+/// the server is ready to run tasks and 503 otherwise.
+public struct HealthResp: Codable {
+    /// "ok" or "unavailable".
+    public let status: String
+    public let version: String?
+    /// Always true when the server answers.
+    public let live: Bool
+    /// All checks passed.
+    public let ready: Bool
+    public let checks: [HealthCheck]
+}
+
+/// GoroutineGroup is a set of goroutines sharing the same stack.
+public struct GoroutineGroup: Codable {
+    public let count: Int
+    /// Innermost frame first, as "function file:line"; empty for goroutines not started yet.
+    public let stack: [String]
+}
+
+/// RuntimeResp is the response for GET /api/v1/admin/runtime: a snapshot of
+/// the Go runtime to diagnose hangs and leaks.
+public struct RuntimeResp: Codable {
+    public let goroutines: Int
+    public let heapAllocBytes: uint64
+    public let heapInuseBytes: uint64
+    public let heapObjects: uint64
+    /// Memory obtained from the OS.
+    public let sysBytes: uint64
+    public let numGC: uint32
+    /// Seconds.
+    public let gcPauseTotal: Double
+    public let tasks: Int
+    /// Most common stacks first.
+    public let goroutineGroups: [GoroutineGroup]
+}
+
+/// FrontendBuildResp identifies the served web UI build.
+public struct FrontendBuildResp: Codable {
+    public let source: String
+    public let dir: String?
+    /// Short SHA-256 of the build's files.
+    public let hash: String
+    /// Last rebuild seen; dir only.
+    public let changedAt: String?
+}
+
+/// HarnessHealth reports whether a harness can run tasks: its binary is
+/// installed in the container image and its API credentials are valid.
+public struct HarnessHealth: Codable {
+    public let name: String
+    public let image: String
+    public let binary: String
+    public let installed: Bool
+    /// First line of "<binary> --version".
+    public let version: String?
+    public let installError: String?
+    public let credentialsOK: Bool
+    public let credentialsError: String?
+    /// Installed && CredentialsOK.
+    public let ok: Bool
+}
+
+/// ValidateImageReq is the request body for POST /api/v1/server/image/validate.
+public struct ValidateImageReq: Codable {
+    /// Image is the base image to smoke test. Empty means the caller's
+    /// pending base image.
+    public let image: String?
+}
+
+/// ImageCheck is one step of a base image smoke test.
+public struct ImageCheck: Codable {
+    /// "pull", "clone" or "harness <name>".
+    public let name: String
+    public let ok: Bool
+    /// What was found, or the error.
+    public let detail: String?
+}
+
+/// ImageValidationResp is the outcome of a base image smoke test.
+public struct ImageValidationResp: Codable {
+    public let image: String
+    /// All checks passed.
+    public let ok: Bool
+    public let checks: [ImageCheck]
+    /// The pending base image passed and became the base image.
+    public let promoted: Bool?
+}
+
+/// ImagePin is a base image pinned to a registry digest.
+public struct ImagePin: Codable {
+    public let image: String
+    /// e.g. "sha256:0123..."
+    public let digest: String
+}
+
+/// ImagePinsResp is the response of the /api/v1/server/image/pins endpoints.
+public struct ImagePinsResp: Codable {
+    public let pins: [ImagePin]
+}
+
+/// ImagePinReq is the request body of the /api/v1/server/image/pins and
+/// /api/v1/server/image/check-update endpoints.
+public struct ImagePinReq: Codable {
+    /// Image is the base image reference, e.g. "ghcr.io/org/img:latest".
+    /// Empty means the caller's base image.
+    public let image: String?
+}
+
+/// ImageUpdateResp is the response for POST /api/v1/server/image/check-update.
+public struct ImageUpdateResp: Codable {
+    public let image: String
+    /// Pinned digest; empty when the image is not pinned.
+    public let pinned: String?
+    /// Digest the image reference currently resolves to.
+    public let latest: String
+    /// The image is pinned to another digest than Latest.
+    public let updateAvailable: Bool
+}
+
+/// PriceEntry is one row of the effective price table.
+public struct PriceEntry: Codable {
+    /// Model name prefix.
+    public let model: String
+    public let price: ModelPrice
+    /// Set from the user's preferences.
+    public let override: Bool?
 }
 
 /// WellKnownCache describes a single well-known cache.
@@ -175,6 +517,17 @@ public struct WellKnownCachesResp: Codable {
     public let wellKnown: [WellKnownCache]
 }
 
+/// LogLevelResp is the response for GET and POST /api/v1/server/log-level.
+public struct LogLevelResp: Codable {
+    public let level: String
+}
+
+/// LogLevelReq is the request body for POST /api/v1/server/log-level.
+public struct LogLevelReq: Codable {
+    /// "debug", "info", "warn" or "error".
+    public let level: String
+}
+
 /// BranchInfo describes a single branch with its origin.
 public struct BranchInfo: Codable {
     public let name: String
@@ -204,7 +557,16 @@ public struct ForgeCheck: Codable {
 
 /// Repo is the JSON representation of a discovered repo.
 public struct Repo: Codable {
+    /// "<root>:<path>" for the repos of a source root other than the primary one.
     public let path: String
+    /// Name of the source root; empty for the primary root.
+    public let root: String?
+    /// Bare clone managed and fetched by caic.
+    public let mirror: Bool?
+    /// Fetched and pushed with a deploy key; see createDeployKey.
+    public let deployKey: Bool?
+    /// Remote task branches are pushed to by default, e.g. a fork; empty means origin.
+    public let pushRemote: String?
     public let baseBranch: BranchInfo
     public let remoteURL: String?
     /// "github", "gitlab", or empty if unknown.
@@ -217,9 +579,70 @@ public struct Repo: Codable {
 public struct CloneRepoReq: Codable {
     /// Git clone URL (HTTPS or SSH).
     public let url: String
-    /// Target subdirectory under rootDir; defaults to repo basename.
+    /// Target subdirectory under rootDir, or "<root>:<subdirectory>" for another source root; defaults to repo basename.
     public let path: String?
     public let depth: Int?
+    /// Mirror creates a managed mirror instead: a full bare clone that caic
+    /// fetches periodically. Path then defaults to the host and path of the URL,
+    /// e.g. "github.com/org/repo".
+    public let mirror: Bool?
+}
+
+/// RepoRemote is a git remote of a repository.
+public struct RepoRemote: Codable {
+    public let name: String
+    public let url: String
+}
+
+/// RepoRemotesResp is the response for the /api/v1/server/repos/remotes
+/// endpoints.
+public struct RepoRemotesResp: Codable {
+    public let remotes: [RepoRemote]
+    /// Remote task branches are pushed to by default.
+    public let pushRemote: String
+}
+
+/// SetRepoRemoteReq is the request body for POST /api/v1/server/repos/remotes.
+public struct SetRepoRemoteReq: Codable {
+    public let repo: String
+    public let name: String
+    /// URL adds the remote or changes its URL; empty keeps an existing
+    /// remote's.
+    public let url: String?
+    /// Push makes the remote the default push target of the task branches.
+    public let push: Bool?
+}
+
+/// DeleteRepoRemoteReq is the request body for POST
+/// /api/v1/server/repos/remotes/delete.
+public struct DeleteRepoRemoteReq: Codable {
+    public let repo: String
+    public let name: String
+}
+
+/// DeployKeyReq is the request body for POST /api/v1/server/repos/deploykey.
+public struct DeployKeyReq: Codable {
+    public let repo: String
+    /// Rotate replaces the existing key; the new one must be added to the
+    /// forge again.
+    public let rotate: Bool?
+}
+
+/// DeployKeyResp is the response for POST /api/v1/server/repos/deploykey.
+public struct DeployKeyResp: Codable {
+    public let repo: String
+    /// OpenSSH authorized_keys line to add as a deploy key.
+    public let publicKey: String
+    /// SHA256 fingerprint, as shown by the forge.
+    public let fingerprint: String
+    /// Forge page to add the key on; empty if the forge is unknown.
+    public let settingsURL: String?
+}
+
+/// DeleteDeployKeyReq is the request body for POST
+/// /api/v1/server/repos/deploykey/delete.
+public struct DeleteDeployKeyReq: Codable {
+    public let repo: String
 }
 
 /// RepoBranchesResp is the response for GET /api/v1/server/repos/branches.
@@ -227,6 +650,45 @@ public struct RepoBranchesResp: Codable {
     public let branches: [BranchInfo]
 }
 
+/// RepoSearchMatch is a line of a file containing the search query.
+public struct RepoSearchMatch: Codable {
+    public let path: String
+    /// 1-based.
+    public let line: Int
+    public let text: String
+}
+
+/// RepoSearchResp is the response for GET /api/v1/server/repos/search.
+public struct RepoSearchResp: Codable {
+    /// Indexed commit of the base branch.
+    public let commit: String
+    /// File paths containing the query.
+    public let paths: [String]
+    /// Lines containing the query.
+    public let matches: [RepoSearchMatch]
+    /// The limit was reached.
+    public let truncated: Bool?
+}
+
+/// SemanticSearchResult is a range of lines similar in meaning to the query.
+public struct SemanticSearchResult: Codable {
+    public let path: String
+    /// 1-based.
+    public let startLine: Int
+    /// Inclusive.
+    public let endLine: Int
+    /// Cosine similarity; higher is closer.
+    public let score: Double
+}
+
+/// RepoSemanticSearchResp is the response for GET
+/// /api/v1/server/repos/semantic-search.
+public struct RepoSemanticSearchResp: Codable {
+    /// Indexed commit of the base branch.
+    public let commit: String
+    public let results: [SemanticSearchResult]
+}
+
 /// BotFixCIReq is the request body for POST /api/v1/bot/fix-ci.
 /// The server fetches CI logs, builds a prompt, and creates a fix task.
 public struct BotFixCIReq: Codable {
@@ -245,6 +707,124 @@ public struct BotFixPRReq: Codable {
     public let taskId: String
 }
 
+/// EvalTarget is a harness/model combination an eval suite runs against.
+public struct EvalTarget: Codable {
+    public let harness: Harness
+    /// Empty means the harness default.
+    public let model: String?
+}
+
+/// EvalResult is the outcome of one case on one target.
+public struct EvalResult: Codable {
+    public let `case`: String
+    public let target: EvalTarget
+    /// "pending", "running", "passed", "failed", or "error"
+    public let status: String
+    public let taskId: String?
+    public let costUSD: Double
+    /// Seconds, from task creation to verification.
+    public let duration: Double
+    /// Tail of the verification output.
+    public let output: String?
+    public let error: String?
+}
+
+/// EvalSummary aggregates the results of one target.
+public struct EvalSummary: Codable {
+    public let target: EvalTarget
+    public let passed: Int
+    public let failed: Int
+    public let errored: Int
+    public let costUSD: Double
+    /// Seconds, summed over cases.
+    public let duration: Double
+}
+
+/// EvalReport is the outcome of an eval run, updated as cases complete.
+public struct EvalReport: Codable {
+    public let id: String
+    public let suite: String
+    /// "running" or "done"
+    public let status: String
+    public let startedAt: String
+    /// Unset while running.
+    public let finishedAt: String?
+    /// One per case and target, in suite order.
+    public let results: [EvalResult]
+    /// One per target.
+    public let summary: [EvalSummary]
+}
+
+/// EvalCase is one benchmark: a prompt run on a repo at a given ref, judged by
+/// a verification script.
+public struct EvalCase: Codable {
+    public let name: String
+    /// Repo path relative to the root, as in RepoSpec.Name.
+    public let repo: String
+    /// Branch or commit the task starts from; empty means the default branch.
+    public let ref: String?
+    /// Prompt is sent as the task's initial prompt.
+    public let prompt: String
+    /// Verify is a shell command run in the repo inside the container after
+    /// the turn; exit code 0 means the case passed.
+    public let verify: String
+}
+
+/// EvalSuite is a corpus of benchmark cases run against harness/model
+/// combinations.
+public struct EvalSuite: Codable {
+    public let name: String
+    public let cases: [EvalCase]
+}
+
+/// CreateEvalReq is the request body for POST /api/v1/evals.
+public struct CreateEvalReq: Codable {
+    public let suite: EvalSuite
+    public let targets: [EvalTarget]
+    /// Parallel is the number of cases run concurrently; 0 means 1.
+    public let parallel: Int?
+}
+
+/// PipelineStep is one task of a pipeline.
+///
+/// Prompt is a Go text/template rendered with .Input (the task's initial
+/// prompt), .Previous (the previous step's result), .Verification (the
+/// previous step's verification output) and .Step (0-based index).
+public struct PipelineStep: Codable {
+    public let name: String?
+    public let prompt: String
+    /// Verify is an optional shell command run in the repo inside the
+    /// container after the step's first turn; a non-zero exit fails the
+    /// pipeline.
+    public let verify: String?
+}
+
+/// Pipeline is a reusable multi-step workflow, e.g. write tests, implement,
+/// lint, then write the changelog.
+public struct Pipeline: Codable {
+    public let name: String
+    public let steps: [PipelineStep]
+}
+
+/// TaskView is a saved combination of task list filters.
+public struct TaskView: Codable {
+    public let name: String
+    /// Query parameters of GET /api/v1/tasks, e.g. "state=failed&createdWithin=168h".
+    public let query: String?
+    /// The task list opens with this view; at most one view is the default.
+    public let `default`: Bool?
+}
+
+/// TaskViewsResp is the response of the /api/v1/views endpoints.
+public struct TaskViewsResp: Codable {
+    public let views: [TaskView]
+}
+
+/// DeleteTaskViewReq is the request body for POST /api/v1/views/delete.
+public struct DeleteTaskViewReq: Codable {
+    public let name: String
+}
+
 /// TaskRepo describes a repository associated with a task in the API response.
 public struct TaskRepo: Codable {
     public let name: String
@@ -253,6 +833,8 @@ public struct TaskRepo: Codable {
     public let remoteURL: String?
     /// "github", "gitlab", or empty if unknown.
     public let forge: String?
+    /// Remote the branch is pushed to; empty means origin.
+    public let pushRemote: String?
 }
 
 /// DiffFileStat describes changes to a single file.
@@ -261,20 +843,89 @@ public struct DiffFileStat: Codable {
     public let added: Int
     public let deleted: Int
     public let binary: Bool?
+    /// Empty when unknown.
+    public let status: String?
+    /// Source path of a rename.
+    public let oldPath: String?
+}
+
+/// IssueLink is the Jira or Linear issue a task is linked to; the task
+/// comments there when it starts, opens a PR and finishes.
+public struct IssueLink: Codable {
+    /// "jira" or "linear"
+    public let tracker: String
+    public let key: String
+    public let url: String?
+}
+
+/// PipelineProgress reports how far a pipeline got.
+public struct PipelineProgress: Codable {
+    public let name: String
+    /// 0-based index of the current or last step.
+    public let step: Int
+    public let steps: Int
+    /// "running", "done", "failed", or "aborted"
+    public let status: String
+    public let error: String?
+    /// Task of each started step, in order.
+    public let tasks: [String]?
+}
+
+/// ReviewProgress reports the state of a task's review loop.
+public struct ReviewProgress: Codable {
+    /// 1-based round of the current or last review.
+    public let round: Int
+    public let maxRounds: Int
+    /// "pending", "reviewing", "changes_requested", "approved", "exhausted", "failed", or "aborted"
+    public let status: String
+    /// Reviewer task per round; their transcripts are kept.
+    public let reviewers: [String]?
+    /// Last feedback from the reviewer.
+    public let feedback: String?
+    public let error: String?
+}
+
+/// PolicyViolation is a tool call denied by a tool policy rule.
+public struct PolicyViolation: Codable {
+    public let rule: String
+    public let tool: String
+    public let detail: String
+}
+
+/// Failover records that a task switched to the fallback harness and model.
+public struct Failover: Codable {
+    public let fromHarness: Harness
+    public let fromModel: String?
+    public let toHarness: Harness
+    public let toModel: String?
+    /// Last start error of the replaced harness and model.
+    public let error: String
+    public let at: String
 }
 
 /// Task is the JSON representation sent to the frontend.
 public struct Task: Codable {
     public let id: String
+    /// Short human-friendly alias, e.g. "w42"; also accepted in place of the ID.
+    public let alias: String?
     public let initialPrompt: String
     public let title: String
     public let repos: [TaskRepo]?
     public let container: String
     public let state: String
-    /// Unix epoch seconds (ms precision) of last state change.
-    public let stateUpdatedAt: Double
+    /// Step within state: "image_pull" while provisioning pulls the base image.
+    public let subState: String?
+    /// Last state change.
+    public let stateUpdatedAt: String
+    /// Advanced by each successful mutation; send as optional If-Match to detect concurrent changes.
+    public let revision: uint64
     public let diffStat: [DiffFileStat]?
+    /// As reported by the harness.
     public let costUSD: Double
+    /// Computed from token usage and the price table; zero when the model has no price.
+    public let estimatedCostUSD: Double?
+    /// The reported and estimated costs disagree.
+    public let costDiscrepancy: Bool?
     /// Seconds.
     public let duration: Double
     public let numTurns: Int
@@ -294,25 +945,108 @@ public struct Task: Codable {
     public let forgeRepo: String?
     public let forgePR: Int?
     public let forgeIssue: Int?
+    /// Linked Jira or Linear issue.
+    public let issue: IssueLink?
     public let ciStatus: String?
     public let ciChecks: [ForgeCheck]?
     /// username of creator; omitted in no-auth mode
     public let owner: String?
+    /// Size of the container's writable layer.
+    public let diskBytes: Int?
+    /// Size of the task's log files.
+    public let logBytes: Int?
+    /// Hidden from the task list unless includeArchived is set.
+    public let archived: Bool?
+    /// Sorted first in the task list.
+    public let pinned: Bool?
     /// Per-task harness/container metadata.
     public let harness: Harness
     public let model: String?
     public let agentVersion: String?
     public let sessionID: String?
-    /// Unix epoch seconds (ms precision) when the container started.
-    public let startedAt: Double?
-    /// Unix epoch seconds; non-zero only while state is "running".
-    public let turnStartedAt: Double?
+    /// When the container started.
+    public let startedAt: String?
+    /// Set only while state is "running".
+    public let turnStartedAt: String?
+    /// When a "rate_limited" task resumes.
+    public let resumesAt: String?
     public let inPlanMode: Bool?
     public let planContent: String?
     /// Tailscale URL (https://fqdn) or "true" if enabled but FQDN unknown.
     public let tailscale: String?
     public let usb: Bool?
     public let display: Bool?
+    /// Read-only plan task; promote it to implement the plan.
+    public let planOnly: Bool?
+    /// Two-phase task; in state "plan_review" until the plan is approved.
+    public let requirePlan: Bool?
+    /// Repos are read-only in the container; nothing to sync.
+    public let readOnly: Bool?
+    /// Conversation only; never enters branching, pulling or pushing.
+    public let chat: Bool?
+    /// Extended thinking was requested.
+    public let thinking: Bool?
+    /// The task's override of the idle policy.
+    public let idlePolicy: IdlePolicy?
+    /// Effective push policy; "ask" shows a push action.
+    public let pushPolicy: String?
+    /// Prerequisites; the task stays "pending" until they are done.
+    public let dependsOn: [String]?
+    /// Why the "pending" task has not started yet.
+    public let heldReason: String?
+    /// Start order while "pending"; omitted when normal.
+    public let priority: String?
+    public let pipeline: PipelineProgress?
+    public let review: ReviewProgress?
+    /// Omitted when unrestricted.
+    public let network: String?
+    /// PolicyViolation is set while the task's container is paused on a tool
+    /// call denied by the tool policy, pending approval.
+    public let policyViolation: PolicyViolation?
+    /// LastActivityAt is when the agent of a running task last sent a
+    /// message, or when its turn started.
+    public let lastActivityAt: String?
+    /// Stalled is set while a running task has been silent for longer than
+    /// the stall policy allows.
+    public let stalled: Bool?
+    /// Failover is set when the task switched to the fallback harness and
+    /// model because its own failed to start.
+    public let failover: Failover?
+}
+
+/// TaskExport is a row of GET /api/v1/tasks/export: the task metadata used
+/// for reporting.
+public struct TaskExport: Codable {
+    public let id: String
+    public let alias: String?
+    public let title: String
+    /// Primary repository.
+    public let repo: String?
+    /// Task branch of the primary repository.
+    public let branch: String?
+    public let state: String
+    public let harness: Harness
+    public let model: String?
+    public let owner: String?
+    /// When the task was created.
+    public let startedAt: String
+    /// Last state change.
+    public let stateUpdatedAt: String
+    /// Seconds.
+    public let duration: Double
+    public let numTurns: Int
+    public let costUSD: Double
+    public let prURL: String?
+    /// IANA time zone of the timestamps, from ?tz= or the user's settings.
+    public let timezone: String
+    /// Project of the primary repository, from the user's settings.
+    public let project: String?
+}
+
+/// UpdateTaskReq is the request body for PATCH /api/v1/tasks/{id}. Omitted
+/// fields are left unchanged.
+public struct UpdateTaskReq: Codable {
+    public let archived: Bool?
 }
 
 /// ImageData carries a single base64-encoded image.
@@ -333,6 +1067,19 @@ public struct Prompt: Codable {
 public struct RepoSpec: Codable {
     public let name: String
     public let baseBranch: String?
+    /// PushRemote is the remote the task branch is pushed to, e.g. a fork, from
+    /// which PRs are opened against origin. Defaults to the repo's push remote.
+    public let pushRemote: String?
+}
+
+/// ReviewSpec configures the agent-to-agent review loop.
+public struct ReviewSpec: Codable {
+    /// Reviewer harness; may differ from the task's.
+    public let harness: Harness
+    /// Reviewer model; empty means the harness default.
+    public let model: String?
+    /// Review rounds before giving up; 0 means 3.
+    public let maxRounds: Int?
 }
 
 /// CreateTaskReq is the request body for POST /api/v1/tasks.
@@ -344,6 +1091,60 @@ public struct CreateTaskReq: Codable {
     public let tailscale: Bool?
     public let usb: Bool?
     public let display: Bool?
+    /// Explore and propose a plan without writing; no diff or push.
+    public let planOnly: Bool?
+    /// Plan first; changes start only after POST /api/v1/tasks/{id}/plan.
+    public let requirePlan: Bool?
+    /// Mount repos read-only and deny write tools, for questions about the code.
+    public let readOnly: Bool?
+    /// Lightweight conversation over the repo: no branch, diff or push.
+    public let chat: Bool?
+    /// Request extended thinking; see HarnessInfo.SupportsThinking.
+    public let thinking: Bool?
+    /// IdlePolicy overrides the idle policy of the preferences for this task;
+    /// hours 0 exempts it.
+    public let idlePolicy: IdlePolicy?
+    /// PushPolicy overrides the push policy of the preferences for this task.
+    public let pushPolicy: String?
+    /// GatherContext searches the primary repo for code-like terms of the
+    /// prompt and prepends a short list of the relevant files to it.
+    public let gatherContext: Bool?
+    /// DependsOn lists task IDs that must reach done (finished a turn
+    /// successfully) before this task starts; until then it stays pending.
+    public let dependsOn: [String]?
+    /// InheritBranch pushes the first prerequisite's branch and uses it as the
+    /// base branch of this task's primary repo, stacking the changes.
+    public let inheritBranch: Bool?
+    public let onDependencyFailure: String?
+    /// Priority orders the start of queued tasks: a task held by its
+    /// dependencies or the execution window starts after the ready tasks of
+    /// higher priority. Empty means normal.
+    public let priority: String?
+    /// Pipeline runs the steps as chained tasks: this task runs the first step
+    /// and each next step's task is stacked on the previous one's branch like
+    /// with inheritBranch. The initial prompt text is the pipeline input; a
+    /// review runs on the last step's task.
+    public let pipeline: Pipeline?
+    /// Review has a second agent review the diff after each turn; its
+    /// feedback is sent back to this task until it approves.
+    public let review: ReviewSpec?
+    /// Network restricts the container's outgoing connections. Empty uses the
+    /// network setting of the user's preferences.
+    public let network: String?
+    /// NetworkAllow lists the hosts reachable with the "allowlist" mode.
+    public let networkAllow: [String]?
+    /// Issue links the task to a Jira or Linear issue key, e.g. "ENG-123";
+    /// an empty prompt is filled from the issue. Without it, the task is
+    /// linked to the first issue its prompt mentions.
+    public let issue: String?
+    /// Env sets environment variables in the container and the agent, e.g. a
+    /// feature flag or a staging API URL. Each name must match the envAllowlist
+    /// of the user's settings.
+    public let env: [String: String]?
+    /// Instructions are appended to the agent's system prompt after the repo's
+    /// .caic/instructions.md and the repoInstructions of the user's settings,
+    /// so they take precedence.
+    public let instructions: String?
 }
 
 /// EventInit is emitted once at the start of a session. It includes a Harness
@@ -367,11 +1168,32 @@ public struct EventTextDelta: Codable {
     public let text: String
 }
 
-/// EventToolUse is emitted when the assistant invokes a tool.
+/// ToolCall is the harness-neutral form of a tool call's input. Only the
+/// fields relevant to Kind are set.
+public struct ToolCall: Codable {
+    public let kind: String
+    /// shell.
+    public let command: String?
+    /// Files for read, write and edit; the searched directory for search.
+    public let paths: [String]?
+    /// search: glob or regexp.
+    public let pattern: String?
+    /// fetch.
+    public let url: String?
+    /// webSearch.
+    public let query: String?
+    /// agent.
+    public let description: String?
+}
+
+/// EventToolUse is emitted when the assistant invokes a tool. Tool names are
+/// normalized to Claude Code's where the harness has an equivalent, but Input
+/// keeps the harness's own shape for debugging; clients should use Call.
 public struct EventToolUse: Codable {
     public let toolUseID: String
     public let name: String
     public let input: JSONValue
+    public let call: ToolCall?
     /// Snapshot of plan content for ExitPlanMode events.
     public let planContent: String?
     /// True when Input was omitted due to size; fetch via GET /api/v1/tasks/{id}/tool/{toolUseID}.
@@ -526,21 +1348,21 @@ public struct EventWidgetDelta: Codable {
 public struct EventRateLimit: Codable {
     /// "allowed", "allowed_warning", "rejected".
     public let status: String
-    /// Unix epoch seconds; 0 if unknown.
-    public let resetsAt: Double
+    /// Unset if unknown.
+    public let resetsAt: String?
     /// "five_hour", "seven_day", etc.
     public let rateLimitType: String
     /// 0.0–1.0.
     public let utilization: Double
     /// True when extra/overage usage is active.
     public let isUsingOverage: Bool?
-    /// Unix epoch seconds; 0 if not using overage.
-    public let overageResetsAt: Double?
+    /// Unset if not using overage.
+    public let overageResetsAt: String?
 }
 
 /// EventStats is a container resource usage snapshot emitted periodically.
 public struct EventStats: Codable {
-    public let ts: Int
+    public let ts: String
     public let cpuPerc: Double
     public let memUsed: uint64
     public let memLimit: uint64
@@ -552,13 +1374,33 @@ public struct EventStats: Codable {
     public let diskUsed: Int
 }
 
+/// EventOverflow is emitted when the client fell too far behind the live
+/// stream and Dropped messages were skipped. The client should refetch the
+/// history, e.g. by reconnecting.
+public struct EventOverflow: Codable {
+    public let dropped: Int
+}
+
+/// ContentRef points at the task history message holding the full content of
+/// a truncated event.
+public struct ContentRef: Codable {
+    /// Message index in the task history.
+    public let index: Int
+    /// Full content length in bytes.
+    public let size: Int
+}
+
 // Backend-neutral event types
 
 /// EventMessage is a single SSE event in the backend-neutral stream
 /// (/api/v1/tasks/{id}/events). All backends produce these events.
 public struct EventMessage: Codable {
     public let kind: EventKind
-    public let ts: Int
+    public let ts: String
+    /// ParentToolUseID is set on the text, thinking, toolUse and toolResult
+    /// events of a sub-agent to the toolUseID of the tool call that spawned
+    /// it, so clients can nest the sub-agent's activity under that call.
+    public let parentToolUseID: String?
     public let `init`: EventInit?
     public let text: EventText?
     public let textDelta: EventTextDelta?
@@ -582,6 +1424,11 @@ public struct EventMessage: Codable {
     public let widgetDelta: EventWidgetDelta?
     public let rateLimit: EventRateLimit?
     public let stats: EventStats?
+    public let overflow: EventOverflow?
+    /// ContentRef is set when the event's text, thinking or tool output was
+    /// truncated to a preview; fetch the full content with
+    /// GET /api/v1/tasks/{id}/messages/{index}/content.
+    public let contentRef: ContentRef?
 }
 
 /// InputReq is the request body for POST /api/v1/tasks/{id}/input.
@@ -594,11 +1441,41 @@ public struct RestartReq: Codable {
     public let prompt: Prompt
 }
 
+/// RewindReq is the request body for POST /api/v1/tasks/{id}/rewind.
+public struct RewindReq: Codable {
+    /// Prompt replaces the last user input.
+    public let prompt: Prompt
+}
+
 /// CompactReq is the request body for POST /api/v1/tasks/{id}/compact.
 public struct CompactReq: Codable {
     public let instructions: String?
 }
 
+/// SetPriorityReq is the request body for POST /api/v1/tasks/{id}/priority.
+public struct SetPriorityReq: Codable {
+    public let priority: String
+}
+
+/// QuickCreateTaskReq is the request body for POST /api/v1/tasks/quick. The
+/// repo, harness and model are inferred from the user's recent preferences.
+public struct QuickCreateTaskReq: Codable {
+    public let initialPrompt: Prompt
+}
+
+/// QuickCreateTaskResp is the response for POST /api/v1/tasks/quick. It
+/// reports what was inferred so the client can confirm it to the user.
+public struct QuickCreateTaskResp: Codable {
+    public let status: String
+    public let id: String
+    /// Empty for a no-repo task.
+    public let repo: String?
+    public let baseBranch: String?
+    public let harness: Harness
+    /// Empty means the harness default.
+    public let model: String?
+}
+
 /// CILogResp is the response for GET /api/v1/tasks/{id}/ci-log.
 /// It contains the name of the first failed CI step and its log tail.
 public struct CILogResp: Codable {
@@ -615,21 +1492,43 @@ public struct SyncReq: Codable {
 /// SafetyIssue describes a potential problem detected before pushing to origin.
 public struct SafetyIssue: Codable {
     public let file: String
-    /// "large_binary" or "secret"
+    /// "large_binary", "secret" or "pre_push_check"
     public let kind: String
     /// Human-readable description.
     public let detail: String
 }
 
-/// SyncResp is the response for POST /api/v1/tasks/{id}/sync.
+/// RepoSyncResult is the sync outcome for a single repo of a multi-repo task.
+public struct RepoSyncResult: Codable {
+    public let name: String
+    /// "synced", "blocked", "empty", or "failed"
+    public let status: String
+    public let branch: String?
+    public let diffStat: [DiffFileStat]?
+    public let safetyIssues: [SafetyIssue]?
+    public let prNumber: Int?
+    public let error: String?
+}
+
+/// SyncResp is the response for POST /api/v1/tasks/{id}/sync and
+/// POST /api/v1/tasks/{id}/push.
 public struct SyncResp: Codable {
-    /// "synced", "blocked", or "empty"
+    /// "synced", "blocked", "empty", or "failed" (multi-repo only)
     public let status: String
     public let branch: String?
     public let diffStat: [DiffFileStat]?
     public let safetyIssues: [SafetyIssue]?
     /// non-zero if a PR/MR was created
     public let prNumber: Int?
+    /// Repos holds one result per repo of a multi-repo task, primary first.
+    /// The top-level fields describe the primary repo; Status is combined.
+    public let repos: [RepoSyncResult]?
+}
+
+/// PushReq is the request body for POST /api/v1/tasks/{id}/push.
+public struct PushReq: Codable {
+    /// Push despite safety issues.
+    public let force: Bool?
 }
 
 /// ForkTaskReq is the request body for POST /api/v1/tasks/{id}/fork.
@@ -644,9 +1543,228 @@ public struct ForkTaskReq: Codable {
     public let extraRepos: [RepoSpec]?
 }
 
+/// ApprovePlanReq is the request body for POST /api/v1/tasks/{id}/plan.
+public struct ApprovePlanReq: Codable {
+    /// Edited plan; empty means approve the plan as proposed.
+    public let prompt: Prompt?
+}
+
+/// PromoteTaskReq is the request body for POST /api/v1/tasks/{id}/promote.
+public struct PromoteTaskReq: Codable {
+    /// Approved plan; empty means use the plan the task proposed.
+    public let prompt: Prompt?
+}
+
+/// DiffDir is a directory of a DiffStat laid out as a tree, with the counts of
+/// all the files below it rolled up. Directories with a single subdirectory
+/// and no files are folded into their child, e.g. "backend/internal".
+public struct DiffDir: Codable {
+    /// Empty for the root.
+    public let name: String
+    /// Empty for the root.
+    public let path: String
+    public let added: Int
+    public let deleted: Int
+    /// Sorted by name.
+    public let dirs: [DiffDir]?
+    /// Sorted by path.
+    public let files: [DiffFileStat]?
+}
+
 /// DiffResp is the response for GET /api/v1/tasks/{id}/diff.
 public struct DiffResp: Codable {
     public let diff: String
+    /// DiffStat is the stat computed along the diff; only set with ?live=true.
+    public let diffStat: [DiffFileStat]?
+    /// Tree is the task's diff stat as a file tree; not set when the diff is
+    /// limited to a path.
+    public let tree: DiffDir?
+}
+
+/// DiffSegment is a run of a DiffLine's text.
+public struct DiffSegment: Codable {
+    public let text: String
+    public let changed: Bool?
+}
+
+/// DiffLine is a line of a hunk, without its +/-/space prefix.
+public struct DiffLine: Codable {
+    public let kind: String
+    public let text: String
+    /// 1-based; 0 for added lines.
+    public let oldLine: Int?
+    /// 1-based; 0 for deleted lines.
+    public let newLine: Int?
+    /// The file does not end with a newline after this line.
+    public let noEOL: Bool?
+    /// Segments splits Text into changed and unchanged words when this line
+    /// replaces, or is replaced by, a similar line. Only set with
+    /// ?intraline=true.
+    public let segments: [DiffSegment]?
+}
+
+/// DiffHunk is one "@@" section of a file diff.
+public struct DiffHunk: Codable {
+    public let oldStart: Int
+    public let oldLines: Int
+    public let newStart: Int
+    public let newLines: Int
+    /// Enclosing function or section git shows after the "@@".
+    public let section: String?
+    public let lines: [DiffLine]
+}
+
+/// DiffFile is the parsed diff of one file.
+public struct DiffFile: Codable {
+    public let path: String
+    /// Source path of a rename.
+    public let oldPath: String?
+    public let status: String
+    /// e.g. "go" or "typescript"; empty when unknown.
+    public let language: String?
+    public let binary: Bool?
+    public let hunks: [DiffHunk]?
+}
+
+/// DiffHunksResp is the response for GET /api/v1/tasks/{id}/diff/hunks.
+public struct DiffHunksResp: Codable {
+    public let files: [DiffFile]
+}
+
+/// TaskCommit is a commit the agent made on the task branch.
+public struct TaskCommit: Codable {
+    public let sha: String
+    public let author: String
+    /// Full message, subject first.
+    public let message: String
+    public let authoredAt: String
+    public let committedAt: String
+    public let files: [DiffFileStat]?
+}
+
+/// RevertCommitReq is the request for POST
+/// /api/v1/tasks/{id}/commits/{sha}/revert.
+public struct RevertCommitReq: Codable {
+}
+
+/// RevertCommitResp is the response for POST
+/// /api/v1/tasks/{id}/commits/{sha}/revert.
+public struct RevertCommitResp: Codable {
+    /// Hash of the new revert commit.
+    public let revert: String
+    /// Notified is true when the agent session was told about the revert.
+    public let notified: Bool
+}
+
+/// CreateSnapshotReq is the request for POST /api/v1/tasks/{id}/snapshots.
+public struct CreateSnapshotReq: Codable {
+    public let name: String
+}
+
+/// TaskSnapshot is a named snapshot of a task's workspace, including the
+/// uncommitted files.
+public struct TaskSnapshot: Codable {
+    public let name: String
+    /// Snapshot commit, whose parent is the branch head at the time.
+    public let commit: String
+    public let created: String
+}
+
+/// RestoreSnapshotReq is the request for POST
+/// /api/v1/tasks/{id}/snapshots/{name}/restore.
+public struct RestoreSnapshotReq: Codable {
+}
+
+/// StateTransition is one entry of a task's state history.
+public struct StateTransition: Codable {
+    public let from: String
+    public let to: String
+    public let at: String
+}
+
+/// TimelinePhase is a span of a task's timeline.
+public struct TimelinePhase: Codable {
+    /// Name is queued, branching, container_start, agent_start, turn,
+    /// rate_limited, awaiting_input, pull, push, teardown or verification.
+    public let name: String
+    public let start: String
+    /// Unset while in progress.
+    public let end: String?
+    /// Up to now while in progress.
+    public let durationMs: Int
+    public let turn: Int?
+    /// FirstOutputMs is the time from the start of a turn to the agent's first
+    /// output; unset when unknown.
+    public let firstOutputMs: Int?
+    /// Failed verification.
+    public let error: String?
+}
+
+/// TaskTimeline is where the wall-clock time of a task went.
+public struct TaskTimeline: Codable {
+    public let phases: [TimelinePhase]
+    /// TotalMs is the time from the first phase to the end of the last one,
+    /// or now.
+    public let totalMs: Int
+    /// PhaseMs sums the durations per phase name. Verification runs overlap
+    /// the other phases.
+    public let phaseMs: [String: Int]
+}
+
+/// ConsoleLine is a line of the raw console output of a task's container,
+/// outside of the agent's message stream.
+public struct ConsoleLine: Codable {
+    /// Source is "container" for the stdout/stderr of the container, or
+    /// "agent" for the stderr of the agent process.
+    public let source: String
+    public let text: String
+    public let at: String
+}
+
+/// Annotation is a note or bookmark a reviewer attached to a message of a task
+/// transcript, e.g. to mark where the agent went wrong.
+public struct Annotation: Codable {
+    public let id: String
+    /// History index of the annotated message.
+    public let index: Int
+    public let note: String?
+    public let bookmark: Bool?
+    /// ID of the user who created it.
+    public let author: String
+    public let createdAt: String
+}
+
+/// TaskMessagesResp is the response for GET /api/v1/tasks/{id}/messages: a
+/// page of the transcript for lazy-loading older history.
+public struct TaskMessagesResp: Codable {
+    public let events: [EventMessage]
+    /// Message index to pass as after for the following page.
+    public let next: Int
+    /// Number of messages in the task history.
+    public let total: Int
+    /// Annotations are the annotations of the messages in the page.
+    public let annotations: [Annotation]?
+}
+
+/// MessageContentResp is the response for
+/// GET /api/v1/tasks/{id}/messages/{index}/content: the full content of a
+/// message whose events were truncated.
+public struct MessageContentResp: Codable {
+    public let content: String
+    /// "base64" when the content is not valid UTF-8.
+    public let encoding: String?
+}
+
+/// AnnotateMessageReq is the request for POST
+/// /api/v1/tasks/{id}/messages/{index}/annotations.
+public struct AnnotateMessageReq: Codable {
+    public let note: String?
+    public let bookmark: Bool?
+}
+
+/// DeleteAnnotationReq is the request for POST
+/// /api/v1/tasks/{id}/annotations/{annotationID}/delete.
+public struct DeleteAnnotationReq: Codable {
 }
 
 /// TaskToolInputResp is the response for GET /api/v1/tasks/{id}/tool/{toolUseID}.
@@ -705,8 +1823,7 @@ public struct CodexRateLimitWindow: Codable {
     public let usedPercent: Int
     public let limitWindowSeconds: Int
     public let resetAfterSeconds: Int
-    /// unix timestamp
-    public let resetAt: Int
+    public let resetAt: String
 }
 
 /// CodexCredits represents Codex credit/balance information.
@@ -724,10 +1841,47 @@ public struct CodexUsage: Codable {
     public let credits: CodexCredits
 }
 
+/// DiskUsage reports the disk space used by tasks and left on the filesystem
+/// holding the logs.
+public struct DiskUsage: Codable {
+    /// Sum of the writable layers of task containers.
+    public let containerBytes: Int
+    /// Sum of the task log files.
+    public let logBytes: Int
+    public let freeBytes: Int
+    public let totalBytes: Int
+    /// Quota; task creation is paused below it.
+    public let minFreeBytes: Int?
+    /// FreeBytes is below MinFreeBytes.
+    public let low: Bool?
+}
+
+/// HarnessRateLimit is the provider rate limit headroom of a harness, as last
+/// reported by its agents, and the tasks backing off from it.
+public struct HarnessRateLimit: Codable {
+    public let harness: Harness
+    /// "allowed", "allowed_warning", "rejected"; empty when never reported.
+    public let status: String?
+    /// "five_hour", "seven_day", etc.
+    public let rateLimitType: String?
+    /// 0.0–1.0.
+    public let utilization: Double?
+    public let resetsAt: String?
+    public let reportedAt: String?
+    /// Tasks in state "rate_limited".
+    public let limitedTasks: Int
+    /// Earliest automatic resume of those tasks.
+    public let resumesAt: String?
+}
+
 /// UsageResp is the response for GET /api/v1/usage.
 public struct UsageResp: Codable {
     public let claude: ClaudeUsage?
     public let codex: CodexUsage?
+    public let disk: DiskUsage?
+    /// RateLimits is the rate limit headroom of the harnesses that reported
+    /// one or have rate limited tasks.
+    public let rateLimits: [HarnessRateLimit]?
 }
 
 /// VoiceTokenResp is the response for GET /api/v1/voice/token.
@@ -759,9 +1913,18 @@ public struct VoiceRTCAnswerResp: Codable {
     public let sessionID: String
 }
 
+public struct FieldError: Codable {
+    public let field: String
+    public let rule: String
+    public let message: String
+    public let params: [String: String]?
+}
+
 public struct ErrorDetails: Codable {
     public let code: String
     public let message: String
+    public let params: [String: String]?
+    public let fields: [FieldError]?
 }
 
 public struct ErrorResponse: Codable {
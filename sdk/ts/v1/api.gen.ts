@@ -1,11 +1,12 @@
 // Code generated by gen-api-sdk. DO NOT EDIT.
-import type { BotFixCIReq, BotFixPRReq, CILogResp, CloneRepoReq, CompactReq, Config, CreateTaskReq, CreateTaskResp, DiffResp, ErrorResponse, EventMessage, ForkTaskReq, HarnessInfo, InputReq, PreferencesResp, Repo, RepoBranchesResp, RestartReq, StatusResp, SyncReq, SyncResp, Task, TaskListEvent, TaskToolInputResp, UpdatePreferencesReq, UsageResp, UserResp, VoiceRTCAnswerResp, VoiceRTCOfferReq, VoiceTokenResp, WebFetchReq, WebFetchResp, WellKnownCachesResp } from "./types.gen";
+import type { AnnotateMessageReq, Annotation, ApprovePlanReq, BotFixCIReq, BotFixPRReq, CILogResp, CloneRepoReq, CompactReq, Config, ConsoleLine, CreateEvalReq, CreateSnapshotReq, CreateTaskReq, CreateTaskResp, DeleteAnnotationReq, DeleteDeployKeyReq, DeleteRepoRemoteReq, DeleteTaskViewReq, DeployKeyReq, DeployKeyResp, DiffHunksResp, DiffResp, ErrorResponse, EvalReport, EventMessage, ForkTaskReq, FrontendBuildResp, HarnessHealth, HarnessInfo, HealthResp, ImagePinReq, ImagePinsResp, ImageUpdateResp, ImageValidationResp, InputReq, LintPromptReq, LintPromptResp, LogLevelReq, LogLevelResp, MessageContentResp, Pipeline, PreferencesResp, PriceEntry, PromoteTaskReq, PushReq, QuickCreateTaskReq, QuickCreateTaskResp, RecentPrompt, Repo, RepoBranchesResp, RepoRemotesResp, RepoSearchResp, RepoSemanticSearchResp, RestartReq, RestoreSnapshotReq, RevertCommitReq, RevertCommitResp, RewindReq, RuntimeResp, SetPriorityReq, SetRepoRemoteReq, StateTransition, StatusResp, SyncReq, SyncResp, Task, TaskCommit, TaskExport, TaskListEvent, TaskMessagesResp, TaskSnapshot, TaskTimeline, TaskToolInputResp, TaskView, TaskViewsResp, UpdatePreferencesReq, UpdateTaskReq, UsageResp, UserResp, ValidateImageReq, VoiceRTCAnswerResp, VoiceRTCOfferReq, VoiceTokenResp, WebFetchReq, WebFetchResp, WellKnownCachesResp } from "./types.gen";
 
 export class APIError extends Error {
   constructor(
     public status: number,
     public code: string,
     public details?: Record<string, unknown>,
+    public params?: Record<string, string>,
   ) {
     super(code);
   }
@@ -20,7 +21,7 @@ function makeRequester(fetchFn: FetchFn) {
     const res = await fetchFn(path, init);
     if (!res.ok) {
       const err = (await res.json()) as ErrorResponse;
-      const e = new APIError(res.status, err.error.code, err.details);
+      const e = new APIError(res.status, err.error.code, err.details, err.error.params);
       e.message = err.error.message;
       throw e;
     }
@@ -44,22 +45,84 @@ export function createApiClient(fetchFn: FetchFn = (globalThis as any).fetch.bin
     getPreferences: (): Promise<PreferencesResp> => request<PreferencesResp>("GET", "/api/v1/server/preferences"),
     /** Updates server settings and preferences. */
     updatePreferences: (req: UpdatePreferencesReq): Promise<PreferencesResp> => request<PreferencesResp>("POST", "/api/v1/server/preferences", req),
+    /** Lists previously used prompts, most recent first. ?repo= limits to a repo; ?prefix= keeps prompts starting with it, ignoring case; ?limit= defaults to 20. */
+    listRecentPrompts: (repo: string, prefix: string, limit: string): Promise<RecentPrompt[]> => request<RecentPrompt[]>("GET", `/api/v1/prompts/recent?repo=${encodeURIComponent(repo)}&prefix=${encodeURIComponent(prefix)}&limit=${encodeURIComponent(limit)}`),
+    /** Analyzes a draft prompt before creating a task and returns suggestions to improve it. */
+    lintPrompt: (req: LintPromptReq): Promise<LintPromptResp> => request<LintPromptResp>("POST", "/api/v1/prompts/lint", req),
     /** Lists available coding agent harnesses. */
     listHarnesses: (): Promise<HarnessInfo[]> => request<HarnessInfo[]>("GET", "/api/v1/server/harnesses"),
+    /** Reports server liveness and readiness; answers 503 when not ready. */
+    getHealth: (): Promise<HealthResp> => request<HealthResp>("GET", "/api/v1/health"),
+    /** Returns a goroutine and heap snapshot of the server; admin only. */
+    getRuntime: (): Promise<RuntimeResp> => request<RuntimeResp>("GET", "/api/v1/admin/runtime"),
+    /** Reports the source and hash of the served web UI build; admin only. */
+    getFrontendBuild: (): Promise<FrontendBuildResp> => request<FrontendBuildResp>("GET", "/api/v1/admin/frontend"),
+    /** Checks each harness binary in the container image and its API credentials. */
+    listHarnessHealth: (): Promise<HarnessHealth[]> => request<HarnessHealth[]>("GET", "/api/v1/health/harnesses"),
+    /** Smoke tests a base image in a throwaway container; a passing pending base image becomes the default. */
+    validateBaseImage: (req: ValidateImageReq): Promise<ImageValidationResp> => request<ImageValidationResp>("POST", "/api/v1/server/image/validate", req),
+    /** Lists the base images the caller pinned to a registry digest. */
+    listImagePins: (): Promise<ImagePinsResp> => request<ImagePinsResp>("GET", "/api/v1/server/image/pins"),
+    /** Pulls a base image, the caller's by default, and pins the new tasks using it to the digest it resolves to. Pinning it again adopts the latest digest. */
+    pinImage: (req: ImagePinReq): Promise<ImagePinsResp> => request<ImagePinsResp>("POST", "/api/v1/server/image/pins", req),
+    /** Removes the digest pin of a base image; new tasks use its tag again. */
+    unpinImage: (req: ImagePinReq): Promise<ImagePinsResp> => request<ImagePinsResp>("POST", "/api/v1/server/image/pins/delete", req),
+    /** Pulls a base image, the caller's by default, and reports whether its tag now resolves to another digest than the pinned one. */
+    checkImageUpdate: (req: ImagePinReq): Promise<ImageUpdateResp> => request<ImageUpdateResp>("POST", "/api/v1/server/image/check-update", req),
+    /** Returns the effective model price table, including preference overrides. */
+    listPrices: (): Promise<PriceEntry[]> => request<PriceEntry[]>("GET", "/api/v1/server/prices"),
     /** Lists well-known cache configurations. */
     listCaches: (): Promise<WellKnownCachesResp> => request<WellKnownCachesResp>("GET", "/api/v1/server/caches"),
+    /** Returns the server log level. */
+    getLogLevel: (): Promise<LogLevelResp> => request<LogLevelResp>("GET", "/api/v1/server/log-level"),
+    /** Changes the server log level until the next restart. */
+    setLogLevel: (req: LogLevelReq): Promise<LogLevelResp> => request<LogLevelResp>("POST", "/api/v1/server/log-level", req),
     /** Lists all discovered repositories. */
     listRepos: (): Promise<Repo[]> => request<Repo[]>("GET", "/api/v1/server/repos"),
     /** Clones a repository into the server's root directory. */
     cloneRepo: (req: CloneRepoReq): Promise<Repo> => request<Repo>("POST", "/api/v1/server/repos", req),
+    /** Lists the git remotes of a repository and the one task branches are pushed to by default. */
+    listRepoRemotes: (repo: string): Promise<RepoRemotesResp> => request<RepoRemotesResp>("GET", `/api/v1/server/repos/remotes?repo=${encodeURIComponent(repo)}`),
+    /** Adds a git remote to a repository, e.g. a fork, or changes its URL, and optionally makes it the default push target of the task branches. PRs of branches pushed to a fork are opened against origin. */
+    setRepoRemote: (req: SetRepoRemoteReq): Promise<RepoRemotesResp> => request<RepoRemotesResp>("POST", "/api/v1/server/repos/remotes", req),
+    /** Removes a git remote of a repository. Task branches go back to origin when it was their push target. */
+    deleteRepoRemote: (req: DeleteRepoRemoteReq): Promise<RepoRemotesResp> => request<RepoRemotesResp>("POST", "/api/v1/server/repos/remotes/delete", req),
+    /** Generates the SSH deploy key of a repository, or returns the existing one, and uses it for the repository's fetches and pushes, on the server and in task containers. Add the public key on the forge with write access. */
+    createDeployKey: (req: DeployKeyReq): Promise<DeployKeyResp> => request<DeployKeyResp>("POST", "/api/v1/server/repos/deploykey", req),
+    /** Deletes the deploy key of a repository, which goes back to the server's credentials. */
+    deleteDeployKey: (req: DeleteDeployKeyReq): Promise<StatusResp> => request<StatusResp>("POST", "/api/v1/server/repos/deploykey/delete", req),
     /** Lists branches for a repository. */
     listRepoBranches: (repo: string): Promise<RepoBranchesResp> => request<RepoBranchesResp>("GET", `/api/v1/server/repos/branches?repo=${encodeURIComponent(repo)}`),
+    /** Searches the files of a repository's base branch for a literal, case-insensitive query. Queries shorter than 3 bytes only match paths. */
+    searchRepo: (repo: string, q: string, limit: string): Promise<RepoSearchResp> => request<RepoSearchResp>("GET", `/api/v1/server/repos/search?repo=${encodeURIComponent(repo)}&q=${encodeURIComponent(q)}&limit=${encodeURIComponent(limit)}`),
+    /** Searches a repository's base branch for code similar in meaning to the query. Requires an embedding provider; see Config.SemanticSearch. */
+    semanticSearchRepo: (repo: string, q: string, limit: string): Promise<RepoSemanticSearchResp> => request<RepoSemanticSearchResp>("GET", `/api/v1/server/repos/semantic-search?repo=${encodeURIComponent(repo)}&q=${encodeURIComponent(q)}&limit=${encodeURIComponent(limit)}`),
     /** Creates a task to fix a failing CI pipeline. */
     botFixCI: (req: BotFixCIReq): Promise<CreateTaskResp> => request<CreateTaskResp>("POST", "/api/v1/bot/fix-ci", req),
     /** Injects a CI fix command into an existing task's PR. */
     botFixPR: (req: BotFixPRReq): Promise<StatusResp> => request<StatusResp>("POST", "/api/v1/bot/fix-pr", req),
-    /** Returns all tasks. */
+    /** Lists the eval runs since the server started. */
+    listEvals: (): Promise<EvalReport[]> => request<EvalReport[]>("GET", "/api/v1/evals"),
+    /** Runs an eval suite across harness/model targets. */
+    createEval: (req: CreateEvalReq): Promise<EvalReport> => request<EvalReport>("POST", "/api/v1/evals", req),
+    /** Returns the report of an eval run. */
+    getEval: (id: string): Promise<EvalReport> => request<EvalReport>("GET", `/api/v1/evals/${id}`),
+    /** Lists the pipeline definitions saved as JSON or YAML files. */
+    listPipelines: (): Promise<Pipeline[]> => request<Pipeline[]>("GET", "/api/v1/pipelines"),
+    /** Lists the user's saved task list views. */
+    listTaskViews: (): Promise<TaskViewsResp> => request<TaskViewsResp>("GET", "/api/v1/views"),
+    /** Saves a named task list view, replacing the view of the same name. Its query holds the filter and sort query parameters of listTasks. Making it the default unsets the previous default. */
+    saveTaskView: (req: TaskView): Promise<TaskViewsResp> => request<TaskViewsResp>("POST", "/api/v1/views", req),
+    /** Deletes a saved task list view. */
+    deleteTaskView: (req: DeleteTaskViewReq): Promise<TaskViewsResp> => request<TaskViewsResp>("POST", "/api/v1/views/delete", req),
+    /** Returns all tasks except archived ones, pinned first. ?includeArchived=true includes them; ?pinned=true keeps only pinned tasks; ?state=a,b keeps the tasks in these states; ?repo= keeps the tasks of a repo; ?project= keeps the tasks with a repo of the project in the user's settings; ?createdWithin=168h and ?stateOlderThan=24h keep the tasks created less than, or in their state for more than, a Go duration; ?sort=created|updated|cost|duration|state with ?order=asc|desc sorts them, by creation ascending by default; ?fields=a,b keeps only the listed fields; ?view=summary drops the prompt and plan. */
     listTasks: (): Promise<Task[]> => request<Task[]>("GET", "/api/v1/tasks"),
+    /** Exports the metadata of the tasks created in [?from, ?to), dates or RFC 3339 times, for reporting. ?project= keeps the tasks of a project of the user's settings. ?format=csv returns a CSV file with the same columns instead of JSON. */
+    exportTasks: (format: string, from: string, to: string, project: string): Promise<TaskExport[]> => request<TaskExport[]>("GET", `/api/v1/tasks/export?format=${encodeURIComponent(format)}&from=${encodeURIComponent(from)}&to=${encodeURIComponent(to)}&project=${encodeURIComponent(project)}`),
+    /** Returns a task with every field, including those dropped from the summary view. */
+    getTask: (id: string): Promise<Task> => request<Task>("GET", `/api/v1/tasks/${id}`),
+    /** Updates the mutable attributes of a task, e.g. archives it. */
+    updateTask: (id: string, req: UpdateTaskReq): Promise<Task> => request<Task>("PATCH", `/api/v1/tasks/${id}`, req),
     /** Creates and starts a new coding agent task. */
     createTask: (req: CreateTaskReq): Promise<CreateTaskResp> => request<CreateTaskResp>("POST", "/api/v1/tasks", req),
     /** Streams raw backend-specific task events via SSE. */
@@ -70,7 +133,7 @@ export function createApiClient(fetchFn: FetchFn = (globalThis as any).fetch.bin
       });
       return es;
     },
-    /** Streams backend-neutral task events via SSE. */
+    /** Streams backend-neutral task events via SSE. ?from=N starts the history replay at message index N; a negative N replays only the last -N messages. ?batch=MS coalesces events into "batch" events holding JSON arrays, written at most every MS milliseconds. ?replay=realtime replays the history with its original pacing, sped up by ?speed=X (at most 100), for watching a past run. The "ready" event ends the replay with the index it started at, the history length and the transcript annotations. */
     taskEvents: (id: string, onMessage: (event: EventMessage) => void): EventSource => {
       const es = new EventSource(`/api/v1/tasks/${id}/events`);
       es.addEventListener("message", (e) => {
@@ -82,27 +145,85 @@ export function createApiClient(fetchFn: FetchFn = (globalThis as any).fetch.bin
     sendInput: (id: string, req: InputReq): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/input`, req),
     /** Restarts a completed or errored task with a new prompt. */
     restartTask: (id: string, req: RestartReq): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/restart`, req),
+    /** Replaces the last user input of a waiting task and restarts its session from the turn before. */
+    rewindTask: (id: string, req: RewindReq): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/rewind`, req),
     /** Clears context and restarts the agent session without a prompt. */
     clearContext: (id: string): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/clear-context`),
     /** Sends a compact command to reduce the agent's context window usage. */
     compactContext: (id: string, req: CompactReq): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/compact`, req),
+    /** Cancels the agent's current turn; the task returns to waiting for input. */
+    interruptTask: (id: string): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/interrupt`),
+    /** Changes the priority of a pending task, moving it in the start queue. */
+    setTaskPriority: (id: string, req: SetPriorityReq): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/priority`, req),
     /** Requests graceful stop of a running task. */
     stopTask: (id: string): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/stop`),
     /** Permanently deletes a task and its container. */
     purgeTask: (id: string): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/purge`),
+    /** Creates a task from a prompt alone, inferring the repo, harness and model from recent use. */
+    quickCreateTask: (req: QuickCreateTaskReq): Promise<QuickCreateTaskResp> => request<QuickCreateTaskResp>("POST", "/api/v1/tasks/quick", req),
+    /** Pins a task to the top of the task list. */
+    pinTask: (id: string): Promise<Task> => request<Task>("POST", `/api/v1/tasks/${id}/pin`),
+    /** Unpins a task. */
+    unpinTask: (id: string): Promise<Task> => request<Task>("POST", `/api/v1/tasks/${id}/unpin`),
     /** Reconnects to an orphaned task container. */
     reviveTask: (id: string): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/revive`),
+    /** Starts a fresh container for a task whose container is gone and restores its saved workspace. Requires the persistWorkspace setting when the task ended. */
+    rehydrateTask: (id: string): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/rehydrate`),
     /** Returns the log tail of a failed CI check run. */
     getTaskCILog: (id: string, jobID: string): Promise<CILogResp> => request<CILogResp>("GET", `/api/v1/tasks/${id}/ci-log?jobID=${encodeURIComponent(jobID)}`),
     /** Pushes task changes to the remote repository. */
     syncTask: (id: string, req: SyncReq): Promise<SyncResp> => request<SyncResp>("POST", `/api/v1/tasks/${id}/sync`, req),
+    /** Pushes the task branch to its push remote without opening a PR, for the tasks whose push policy is ask. Rejected when the policy is never. */
+    pushTask: (id: string, req: PushReq): Promise<SyncResp> => request<SyncResp>("POST", `/api/v1/tasks/${id}/push`, req),
     /** Forks a task by snapshotting its container and creating a new task on a derived branch. */
     forkTask: (id: string, req: ForkTaskReq): Promise<CreateTaskResp> => request<CreateTaskResp>("POST", `/api/v1/tasks/${id}/fork`, req),
-    /** Returns the unified diff for a task's branch. */
-    getTaskDiff: (id: string): Promise<DiffResp> => request<DiffResp>("GET", `/api/v1/tasks/${id}/diff`),
+    /** Approves, optionally edited, the plan of a task in plan_review and lets the agent make changes. */
+    approvePlan: (id: string, req: ApprovePlanReq): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/plan`, req),
+    /** Creates a real task from a plan-only task, seeded with its approved plan. */
+    promoteTask: (id: string, req: PromoteTaskReq): Promise<CreateTaskResp> => request<CreateTaskResp>("POST", `/api/v1/tasks/${id}/promote`, req),
+    /** Returns the unified diff for a task's branch, computed inside its container, optionally limited to one path. ?live=true also refreshes the task's diff stat and broadcasts it as a diffStat event when it changed, so running tasks show progress. */
+    getTaskDiff: (id: string, path: string, live: string): Promise<DiffResp> => request<DiffResp>("GET", `/api/v1/tasks/${id}/diff?path=${encodeURIComponent(path)}&live=${encodeURIComponent(live)}`),
+    /** Returns the task diff parsed into files and hunks, with each file's language, optionally limited to one path. ?intraline=true also splits paired changed lines into changed and unchanged words. */
+    getTaskDiffHunks: (id: string, path: string, intraline: string): Promise<DiffHunksResp> => request<DiffHunksResp>("GET", `/api/v1/tasks/${id}/diff/hunks?path=${encodeURIComponent(path)}&intraline=${encodeURIComponent(intraline)}`),
+    /** Returns the commits on the task branch that are not on its base branch, newest first, with the files each one changed. */
+    getTaskCommits: (id: string): Promise<TaskCommit[]> => request<TaskCommit[]>("GET", `/api/v1/tasks/${id}/commits`),
+    /** Reverts one commit of the task branch inside the container with a new commit, and tells the agent so it doesn't reintroduce the change. */
+    revertTaskCommit: (id: string, sha: string, req: RevertCommitReq): Promise<RevertCommitResp> => request<RevertCommitResp>("POST", `/api/v1/tasks/${id}/commits/${sha}/revert`, req),
+    /** Saves the task's workspace, including uncommitted and untracked files, as a named snapshot inside the container. */
+    createTaskSnapshot: (id: string, req: CreateSnapshotReq): Promise<TaskSnapshot> => request<TaskSnapshot>("POST", `/api/v1/tasks/${id}/snapshots`, req),
+    /** Returns the task's workspace snapshots, oldest first. */
+    listTaskSnapshots: (id: string): Promise<TaskSnapshot[]> => request<TaskSnapshot[]>("GET", `/api/v1/tasks/${id}/snapshots`),
+    /** Resets the task's workspace to a snapshot, discarding the commits and changes made since, and tells the agent. The task must not be running. */
+    restoreTaskSnapshot: (id: string, name: string, req: RestoreSnapshotReq): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/snapshots/${name}/restore`, req),
+    /** Approves the tool call the task is paused on for violating the tool policy and resumes the task. */
+    approveTaskPolicy: (id: string): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/policy/approve`),
+    /** Denies the tool call the task is paused on for violating the tool policy and stops the task. */
+    denyTaskPolicy: (id: string): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/policy/deny`),
+    /** Returns the task's state transition history, oldest first. */
+    getTaskTransitions: (id: string): Promise<StateTransition[]> => request<StateTransition[]>("GET", `/api/v1/tasks/${id}/transitions`),
+    /** Returns the phases of the task with their durations: queue, container start, each turn with its time to first output, verification, push. */
+    getTaskTimeline: (id: string): Promise<TaskTimeline> => request<TaskTimeline>("GET", `/api/v1/tasks/${id}/timeline`),
+    /** Streams the raw console output of the task's container via SSE: its stdout/stderr and the stderr of the agent process, starting with the last ?tail=N lines of each (default 200). The output of the commands the agent runs as tools is not included; it is in the tool results of the task's messages. The stream ends when the container stops. */
+    taskConsole: (id: string, onMessage: (event: ConsoleLine) => void): EventSource => {
+      const es = new EventSource(`/api/v1/tasks/${id}/console`);
+      es.addEventListener("message", (e) => {
+        onMessage(JSON.parse(e.data) as ConsoleLine);
+      });
+      return es;
+    },
+    /** Returns a page of the task transcript: up to limit messages from message index after, with their annotations. */
+    getTaskMessages: (id: string, after: string, limit: string): Promise<TaskMessagesResp> => request<TaskMessagesResp>("GET", `/api/v1/tasks/${id}/messages?after=${encodeURIComponent(after)}&limit=${encodeURIComponent(limit)}`),
+    /** Returns the full content of a message whose events were truncated to a preview. */
+    getTaskMessageContent: (id: string, index: string): Promise<MessageContentResp> => request<MessageContentResp>("GET", `/api/v1/tasks/${id}/messages/${index}/content`),
+    /** Attaches a note or a bookmark to a message of the task transcript. */
+    annotateTaskMessage: (id: string, index: string, req: AnnotateMessageReq): Promise<Annotation> => request<Annotation>("POST", `/api/v1/tasks/${id}/messages/${index}/annotations`, req),
+    /** Returns the annotations of the task transcript, by message index. */
+    listTaskAnnotations: (id: string): Promise<Annotation[]> => request<Annotation[]>("GET", `/api/v1/tasks/${id}/annotations`),
+    /** Deletes an annotation of the task transcript. */
+    deleteTaskAnnotation: (id: string, annotationID: string, req: DeleteAnnotationReq): Promise<StatusResp> => request<StatusResp>("POST", `/api/v1/tasks/${id}/annotations/${annotationID}/delete`, req),
     /** Returns the full (untruncated) input for a tool call. */
     getTaskToolInput: (id: string, toolUseID: string): Promise<TaskToolInputResp> => request<TaskToolInputResp>("GET", `/api/v1/tasks/${id}/tool/${toolUseID}`),
-    /** Streams task list updates for all tasks via SSE. */
+    /** Streams task list updates for all tasks via SSE. ?includeArchived, ?pinned, ?state, ?repo, ?project, ?createdWithin and ?stateOlderThan filter tasks as for listTasks. */
     globalTaskEvents: (onMessage: (event: TaskListEvent) => void): EventSource => {
       const es = new EventSource("/api/v1/server/tasks/events");
       es.addEventListener("message", (e) => {
@@ -110,7 +231,7 @@ export function createApiClient(fetchFn: FetchFn = (globalThis as any).fetch.bin
       });
       return es;
     },
-    /** Streams usage quota updates via SSE. */
+    /** Streams usage quota updates via SSE. ?project= counts only the tasks with a repo of the project, as for getUsage. */
     globalUsageEvents: (onMessage: (event: UsageResp) => void): EventSource => {
       const es = new EventSource("/api/v1/server/usage/events");
       es.addEventListener("message", (e) => {
@@ -118,7 +239,7 @@ export function createApiClient(fetchFn: FetchFn = (globalThis as any).fetch.bin
       });
       return es;
     },
-    /** Returns current usage quota statistics. */
+    /** Returns current usage quota statistics. ?project= sums the cost and tokens of, and lists the rate limited, tasks with a repo of the project in the user's settings only; the quota utilization stays account-wide. */
     getUsage: (): Promise<UsageResp> => request<UsageResp>("GET", "/api/v1/usage"),
     /** Returns a short-lived voice API token. */
     getVoiceToken: (): Promise<VoiceTokenResp> => request<VoiceTokenResp>("GET", "/api/v1/voice/token"),
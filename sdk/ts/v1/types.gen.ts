@@ -109,13 +109,23 @@ export const EventKindRateLimit: EventKind = "rateLimit";
  * Event kind constants.
  */
 export const EventKindStats: EventKind = "stats";
+/**
+ * Event kind constants.
+ */
+export const EventKindOverflow: EventKind = "overflow";
 /**
  * EventMessage is a single SSE event in the backend-neutral stream
  * (/api/v1/tasks/{id}/events). All backends produce these events.
  */
 export interface EventMessage {
   kind: EventKind;
-  ts: number /* int64 */;
+  ts: string;
+  /**
+   * ParentToolUseID is set on the text, thinking, toolUse and toolResult
+   * events of a sub-agent to the toolUseID of the tool call that spawned
+   * it, so clients can nest the sub-agent's activity under that call.
+   */
+  parentToolUseID?: string;
   init?: EventInit;
   text?: EventText;
   textDelta?: EventTextDelta;
@@ -139,6 +149,13 @@ export interface EventMessage {
   widgetDelta?: EventWidgetDelta;
   rateLimit?: EventRateLimit;
   stats?: EventStats;
+  overflow?: EventOverflow;
+  /**
+   * ContentRef is set when the event's text, thinking or tool output was
+   * truncated to a preview; fetch the full content with
+   * GET /api/v1/tasks/{id}/messages/{index}/content.
+   */
+  contentRef?: ContentRef;
 }
 /**
  * EventInit is emitted once at the start of a session. It includes a Harness
@@ -165,16 +182,72 @@ export interface EventTextDelta {
   text: string;
 }
 /**
- * EventToolUse is emitted when the assistant invokes a tool.
+ * EventToolUse is emitted when the assistant invokes a tool. Tool names are
+ * normalized to Claude Code's where the harness has an equivalent, but Input
+ * keeps the harness's own shape for debugging; clients should use Call.
  */
 export interface EventToolUse {
   toolUseID: string;
   name: string;
   input: any /* json.RawMessage */;
+  call?: ToolCall;
   planContent?: string; // Snapshot of plan content for ExitPlanMode events.
   inputTruncated?: boolean; // True when Input was omitted due to size; fetch via GET /api/v1/tasks/{id}/tool/{toolUseID}.
   background?: boolean; // True when the tool runs in the background (Bash/Agent run_in_background).
 }
+/**
+ * ToolKind classifies what a tool call does.
+ */
+export type ToolKind = string;
+/**
+ * Tool kinds.
+ */
+export const ToolKindShell: ToolKind = "shell";
+/**
+ * Tool kinds.
+ */
+export const ToolKindRead: ToolKind = "read";
+/**
+ * Tool kinds.
+ */
+export const ToolKindWrite: ToolKind = "write";
+/**
+ * Tool kinds.
+ */
+export const ToolKindEdit: ToolKind = "edit";
+/**
+ * Tool kinds.
+ */
+export const ToolKindSearch: ToolKind = "search";
+/**
+ * Tool kinds.
+ */
+export const ToolKindFetch: ToolKind = "fetch";
+/**
+ * Tool kinds.
+ */
+export const ToolKindWebSearch: ToolKind = "webSearch";
+/**
+ * Tool kinds.
+ */
+export const ToolKindAgent: ToolKind = "agent";
+/**
+ * Tool kinds.
+ */
+export const ToolKindOther: ToolKind = "other";
+/**
+ * ToolCall is the harness-neutral form of a tool call's input. Only the
+ * fields relevant to Kind are set.
+ */
+export interface ToolCall {
+  kind: ToolKind;
+  command?: string; // shell.
+  paths?: string[]; // Files for read, write and edit; the searched directory for search.
+  pattern?: string; // search: glob or regexp.
+  url?: string; // fetch.
+  query?: string; // webSearch.
+  description?: string; // agent.
+}
 /**
  * EventToolResult is emitted when a tool call completes.
  */
@@ -336,17 +409,33 @@ export interface EventWidgetDelta {
  */
 export interface EventRateLimit {
   status: string; // "allowed", "allowed_warning", "rejected".
-  resetsAt: number /* float64 */; // Unix epoch seconds; 0 if unknown.
+  resetsAt?: string; // Unset if unknown.
   rateLimitType: string; // "five_hour", "seven_day", etc.
   utilization: number /* float64 */; // 0.0–1.0.
   isUsingOverage?: boolean; // True when extra/overage usage is active.
-  overageResetsAt?: number /* float64 */; // Unix epoch seconds; 0 if not using overage.
+  overageResetsAt?: string; // Unset if not using overage.
+}
+/**
+ * EventOverflow is emitted when the client fell too far behind the live
+ * stream and Dropped messages were skipped. The client should refetch the
+ * history, e.g. by reconnecting.
+ */
+export interface EventOverflow {
+  dropped: number /* int */;
+}
+/**
+ * ContentRef points at the task history message holding the full content of
+ * a truncated event.
+ */
+export interface ContentRef {
+  index: number /* int */; // Message index in the task history.
+  size: number /* int */; // Full content length in bytes.
 }
 /**
  * EventStats is a container resource usage snapshot emitted periodically.
  */
 export interface EventStats {
-  ts: number /* int64 */;
+  ts: string;
   cpuPerc: number /* float64 */;
   memUsed: number /* uint64 */;
   memLimit: number /* uint64 */;
@@ -382,6 +471,10 @@ export const ForgeGitLab: Forge = "gitlab";
  * Values must match agent.Harness constants.
  */
 export type Harness = string;
+/**
+ * Supported agent harnesses.
+ */
+export const HarnessAider: Harness = "aider";
 /**
  * Supported agent harnesses.
  */
@@ -390,6 +483,10 @@ export const HarnessClaude: Harness = "claude";
  * Supported agent harnesses.
  */
 export const HarnessCodex: Harness = "codex";
+/**
+ * Supported agent harnesses.
+ */
+export const HarnessGeneric: Harness = "generic";
 /**
  * Supported agent harnesses.
  */
@@ -398,6 +495,10 @@ export const HarnessGemini: Harness = "gemini";
  * Supported agent harnesses.
  */
 export const HarnessKilo: Harness = "kilo";
+/**
+ * Supported agent harnesses.
+ */
+export const HarnessLocal: Harness = "local";
 /**
  * Supported agent harnesses.
  */
@@ -410,6 +511,171 @@ export interface HarnessInfo {
   models: string[];
   supportsImages: boolean;
   supportsCompact: boolean;
+  /**
+   * SupportsInterrupt reports whether a running turn can be interrupted.
+   */
+  supportsInterrupt: boolean;
+  /**
+   * SupportsThinking reports whether CreateTaskReq.Thinking is honored.
+   */
+  supportsThinking: boolean;
+}
+/**
+ * HealthResp is returned by GET /api/v1/health. The endpoint answers 200 when
+ * the server is ready to run tasks and 503 otherwise.
+ */
+export interface HealthResp {
+  status: string; // "ok" or "unavailable".
+  version?: string;
+  live: boolean; // Always true when the server answers.
+  ready: boolean; // All checks passed.
+  checks: HealthCheck[];
+}
+/**
+ * RuntimeResp is the response for GET /api/v1/admin/runtime: a snapshot of
+ * the Go runtime to diagnose hangs and leaks.
+ */
+export interface RuntimeResp {
+  goroutines: number /* int */;
+  heapAllocBytes: number /* uint64 */;
+  heapInuseBytes: number /* uint64 */;
+  heapObjects: number /* uint64 */;
+  sysBytes: number /* uint64 */; // Memory obtained from the OS.
+  numGC: number /* uint32 */;
+  gcPauseTotal: number /* float64 */; // Seconds.
+  tasks: number /* int */;
+  goroutineGroups: GoroutineGroup[]; // Most common stacks first.
+}
+/**
+ * FrontendSource is where the served web UI comes from.
+ */
+export type FrontendSource = string;
+/**
+ * Frontend sources.
+ */
+export const FrontendEmbedded: FrontendSource = "embedded"; // Compiled into the server.
+/**
+ * Frontend sources.
+ */
+export const FrontendDir: FrontendSource = "dir"; // A local directory; see the -frontend-dir flag.
+/**
+ * FrontendBuildResp identifies the served web UI build.
+ */
+export interface FrontendBuildResp {
+  source: FrontendSource;
+  dir?: string;
+  hash: string; // Short SHA-256 of the build's files.
+  changedAt?: string; // Last rebuild seen; dir only.
+}
+/**
+ * GoroutineGroup is a set of goroutines sharing the same stack.
+ */
+export interface GoroutineGroup {
+  count: number /* int */;
+  stack: string[]; // Innermost frame first, as "function file:line"; empty for goroutines not started yet.
+}
+/**
+ * HealthCheck is the outcome of a single readiness probe.
+ */
+export interface HealthCheck {
+  name: string;
+  ok: boolean;
+  detail?: string;
+}
+/**
+ * HarnessHealth reports whether a harness can run tasks: its binary is
+ * installed in the container image and its API credentials are valid.
+ */
+export interface HarnessHealth {
+  name: string;
+  image: string;
+  binary: string;
+  installed: boolean;
+  version?: string; // First line of "<binary> --version".
+  installError?: string;
+  credentialsOK: boolean;
+  credentialsError?: string;
+  ok: boolean; // Installed && CredentialsOK.
+}
+/**
+ * ImageCheck is one step of a base image smoke test.
+ */
+export interface ImageCheck {
+  name: string; // "pull", "clone" or "harness <name>".
+  ok: boolean;
+  detail?: string; // What was found, or the error.
+}
+/**
+ * ValidateImageReq is the request body for POST /api/v1/server/image/validate.
+ */
+export interface ValidateImageReq {
+  /**
+   * Image is the base image to smoke test. Empty means the caller's
+   * pending base image.
+   */
+  image?: string;
+}
+/**
+ * ImageValidationResp is the outcome of a base image smoke test.
+ */
+export interface ImageValidationResp {
+  image: string;
+  ok: boolean; // All checks passed.
+  checks: ImageCheck[];
+  promoted?: boolean; // The pending base image passed and became the base image.
+}
+/**
+ * ImageStatus is the validation status of a pending base image.
+ */
+export type ImageStatus = string;
+/**
+ * Image validation statuses.
+ */
+export const ImageValidating: ImageStatus = "validating";
+/**
+ * Image validation statuses.
+ */
+export const ImageFailed: ImageStatus = "failed";
+/**
+ * PendingImage is a new base image that is smoke tested before tasks use it.
+ */
+export interface PendingImage {
+  image: string;
+  status: ImageStatus;
+  error?: string; // Why the validation failed.
+}
+/**
+ * ImagePinReq is the request body of the /api/v1/server/image/pins and
+ * /api/v1/server/image/check-update endpoints.
+ */
+export interface ImagePinReq {
+  /**
+   * Image is the base image reference, e.g. "ghcr.io/org/img:latest".
+   * Empty means the caller's base image.
+   */
+  image?: string;
+}
+/**
+ * ImagePin is a base image pinned to a registry digest.
+ */
+export interface ImagePin {
+  image: string;
+  digest: string; // e.g. "sha256:0123..."
+}
+/**
+ * ImagePinsResp is the response of the /api/v1/server/image/pins endpoints.
+ */
+export interface ImagePinsResp {
+  pins: ImagePin[];
+}
+/**
+ * ImageUpdateResp is the response for POST /api/v1/server/image/check-update.
+ */
+export interface ImageUpdateResp {
+  image: string;
+  pinned?: string; // Pinned digest; empty when the image is not pinned.
+  latest: string; // Digest the image reference currently resolves to.
+  updateAvailable: boolean; // The image is pinned to another digest than Latest.
 }
 /**
  * ImageData carries a single base64-encoded image.
@@ -435,6 +701,7 @@ export interface Config {
   displayAvailable: boolean;
   gitHubAppEnabled?: boolean;
   authProviders?: string[]; // e.g. ["github","gitlab"]
+  semanticSearch?: boolean; // GET /api/v1/server/repos/semantic-search is available.
 }
 /**
  * UserResp is returned by GET /api/v1/auth/me.
@@ -444,6 +711,7 @@ export interface UserResp {
   provider: string;
   username: string;
   avatarURL?: string;
+  admin?: boolean; // May use the /api/v1/admin endpoints.
 }
 /**
  * CIStatus is the CI check state for a task or repo default branch.
@@ -532,7 +800,11 @@ export interface ForgeCheck {
  * Repo is the JSON representation of a discovered repo.
  */
 export interface Repo {
-  path: string;
+  path: string; // "<root>:<path>" for the repos of a source root other than the primary one.
+  root?: string; // Name of the source root; empty for the primary root.
+  mirror?: boolean; // Bare clone managed and fetched by caic.
+  deployKey?: boolean; // Fetched and pushed with a deploy key; see createDeployKey.
+  pushRemote?: string; // Remote task branches are pushed to by default, e.g. a fork; empty means origin.
   baseBranch: BranchInfo;
   remoteURL?: string;
   forge?: Forge; // "github", "gitlab", or empty if unknown.
@@ -545,6 +817,11 @@ export interface Repo {
 export interface RepoSpec {
   name: string;
   baseBranch?: string;
+  /**
+   * PushRemote is the remote the task branch is pushed to, e.g. a fork, from
+   * which PRs are opened against origin. Defaults to the repo's push remote.
+   */
+  pushRemote?: string;
 }
 /**
  * TaskRepo describes a repository associated with a task in the API response.
@@ -555,20 +832,26 @@ export interface TaskRepo {
   branch: string;
   remoteURL?: string;
   forge?: Forge; // "github", "gitlab", or empty if unknown.
+  pushRemote?: string; // Remote the branch is pushed to; empty means origin.
 }
 /**
  * Task is the JSON representation sent to the frontend.
  */
 export interface Task {
   id: string;
+  alias?: string; // Short human-friendly alias, e.g. "w42"; also accepted in place of the ID.
   initialPrompt: string;
   title: string;
   repos?: TaskRepo[];
   container: string;
   state: string;
-  stateUpdatedAt: number /* float64 */; // Unix epoch seconds (ms precision) of last state change.
+  subState?: string; // Step within state: "image_pull" while provisioning pulls the base image.
+  stateUpdatedAt: string; // Last state change.
+  revision: number /* uint64 */; // Advanced by each successful mutation; send as optional If-Match to detect concurrent changes.
   diffStat?: DiffStat;
-  costUSD: number /* float64 */;
+  costUSD: number /* float64 */; // As reported by the harness.
+  estimatedCostUSD?: number /* float64 */; // Computed from token usage and the price table; zero when the model has no price.
+  costDiscrepancy?: boolean; // The reported and estimated costs disagree.
   duration: number /* float64 */; // Seconds.
   numTurns: number /* int */;
   cumulativeInputTokens: number /* int */;
@@ -584,9 +867,14 @@ export interface Task {
   forgeRepo?: string;
   forgePR?: number /* int */;
   forgeIssue?: number /* int */;
+  issue?: IssueLink; // Linked Jira or Linear issue.
   ciStatus?: CIStatus;
   ciChecks?: ForgeCheck[];
   owner?: string; // username of creator; omitted in no-auth mode
+  diskBytes?: number /* int64 */; // Size of the container's writable layer.
+  logBytes?: number /* int64 */; // Size of the task's log files.
+  archived?: boolean; // Hidden from the task list unless includeArchived is set.
+  pinned?: boolean; // Sorted first in the task list.
   /**
    * Per-task harness/container metadata.
    */
@@ -594,13 +882,55 @@ export interface Task {
   model?: string;
   agentVersion?: string;
   sessionID?: string;
-  startedAt?: number /* float64 */; // Unix epoch seconds (ms precision) when the container started.
-  turnStartedAt?: number /* float64 */; // Unix epoch seconds; non-zero only while state is "running".
+  startedAt?: string; // When the container started.
+  turnStartedAt?: string; // Set only while state is "running".
+  resumesAt?: string; // When a "rate_limited" task resumes.
   inPlanMode?: boolean;
   planContent?: string;
   tailscale?: string; // Tailscale URL (https://fqdn) or "true" if enabled but FQDN unknown.
   usb?: boolean;
   display?: boolean;
+  planOnly?: boolean; // Read-only plan task; promote it to implement the plan.
+  requirePlan?: boolean; // Two-phase task; in state "plan_review" until the plan is approved.
+  readOnly?: boolean; // Repos are read-only in the container; nothing to sync.
+  chat?: boolean; // Conversation only; never enters branching, pulling or pushing.
+  thinking?: boolean; // Extended thinking was requested.
+  idlePolicy?: IdlePolicy; // The task's override of the idle policy.
+  pushPolicy?: PushPolicy; // Effective push policy; "ask" shows a push action.
+  dependsOn?: string[]; // Prerequisites; the task stays "pending" until they are done.
+  heldReason?: string; // Why the "pending" task has not started yet.
+  priority?: TaskPriority; // Start order while "pending"; omitted when normal.
+  pipeline?: PipelineProgress;
+  review?: ReviewProgress;
+  network?: NetworkMode; // Omitted when unrestricted.
+  /**
+   * PolicyViolation is set while the task's container is paused on a tool
+   * call denied by the tool policy, pending approval.
+   */
+  policyViolation?: PolicyViolation;
+  /**
+   * LastActivityAt is when the agent of a running task last sent a
+   * message, or when its turn started.
+   */
+  lastActivityAt?: string;
+  /**
+   * Stalled is set while a running task has been silent for longer than
+   * the stall policy allows.
+   */
+  stalled?: boolean;
+  /**
+   * Failover is set when the task switched to the fallback harness and
+   * model because its own failed to start.
+   */
+  failover?: Failover;
+}
+/**
+ * PolicyViolation is a tool call denied by a tool policy rule.
+ */
+export interface PolicyViolation {
+  rule: string;
+  tool: string;
+  detail: string;
 }
 /**
  * TaskListEvent is a discriminated-union event for the task list SSE stream.
@@ -660,178 +990,942 @@ export interface CreateTaskReq {
   tailscale?: boolean;
   usb?: boolean;
   display?: boolean;
+  planOnly?: boolean; // Explore and propose a plan without writing; no diff or push.
+  requirePlan?: boolean; // Plan first; changes start only after POST /api/v1/tasks/{id}/plan.
+  readOnly?: boolean; // Mount repos read-only and deny write tools, for questions about the code.
+  chat?: boolean; // Lightweight conversation over the repo: no branch, diff or push.
+  thinking?: boolean; // Request extended thinking; see HarnessInfo.SupportsThinking.
+  /**
+   * IdlePolicy overrides the idle policy of the preferences for this task;
+   * hours 0 exempts it.
+   */
+  idlePolicy?: IdlePolicy;
+  /**
+   * PushPolicy overrides the push policy of the preferences for this task.
+   */
+  pushPolicy?: PushPolicy;
+  /**
+   * GatherContext searches the primary repo for code-like terms of the
+   * prompt and prepends a short list of the relevant files to it.
+   */
+  gatherContext?: boolean;
+  /**
+   * DependsOn lists task IDs that must reach done (finished a turn
+   * successfully) before this task starts; until then it stays pending.
+   */
+  dependsOn?: string[];
+  /**
+   * InheritBranch pushes the first prerequisite's branch and uses it as the
+   * base branch of this task's primary repo, stacking the changes.
+   */
+  inheritBranch?: boolean;
+  onDependencyFailure?: DependencyFailurePolicy;
+  /**
+   * Priority orders the start of queued tasks: a task held by its
+   * dependencies or the execution window starts after the ready tasks of
+   * higher priority. Empty means normal.
+   */
+  priority?: TaskPriority;
+  /**
+   * Pipeline runs the steps as chained tasks: this task runs the first step
+   * and each next step's task is stacked on the previous one's branch like
+   * with inheritBranch. The initial prompt text is the pipeline input; a
+   * review runs on the last step's task.
+   */
+  pipeline?: Pipeline;
+  /**
+   * Review has a second agent review the diff after each turn; its
+   * feedback is sent back to this task until it approves.
+   */
+  review?: ReviewSpec;
+  /**
+   * Network restricts the container's outgoing connections. Empty uses the
+   * network setting of the user's preferences.
+   */
+  network?: NetworkMode;
+  /**
+   * NetworkAllow lists the hosts reachable with the "allowlist" mode.
+   */
+  networkAllow?: string[];
+  /**
+   * Issue links the task to a Jira or Linear issue key, e.g. "ENG-123";
+   * an empty prompt is filled from the issue. Without it, the task is
+   * linked to the first issue its prompt mentions.
+   */
+  issue?: string;
+  /**
+   * Env sets environment variables in the container and the agent, e.g. a
+   * feature flag or a staging API URL. Each name must match the envAllowlist
+   * of the user's settings.
+   */
+  env?: { [key: string]: string};
+  /**
+   * Instructions are appended to the agent's system prompt after the repo's
+   * .caic/instructions.md and the repoInstructions of the user's settings,
+   * so they take precedence.
+   */
+  instructions?: string;
 }
 /**
- * ForkTaskReq is the request body for POST /api/v1/tasks/{id}/fork.
+ * IssueLink is the Jira or Linear issue a task is linked to; the task
+ * comments there when it starts, opens a PR and finishes.
  */
-export interface ForkTaskReq {
-  prompt: Prompt; // Initial prompt for the forked task.
-  harness?: Harness; // Override harness; empty means inherit from source.
-  model?: string; // Override model; empty means inherit from source.
-  extraRepos?: RepoSpec[]; // Additional repos to map into the fork.
+export interface IssueLink {
+  tracker: string; // "jira" or "linear"
+  key: string;
+  url?: string;
 }
 /**
- * BotFixCIReq is the request body for POST /api/v1/bot/fix-ci.
- * The server fetches CI logs, builds a prompt, and creates a fix task.
+ * QuickCreateTaskReq is the request body for POST /api/v1/tasks/quick. The
+ * repo, harness and model are inferred from the user's recent preferences.
  */
-export interface BotFixCIReq {
-  repo: string;
+export interface QuickCreateTaskReq {
+  initialPrompt: Prompt;
 }
 /**
- * BotFixPRReq is the request body for POST /api/v1/bot/fix-pr.
- * The server fetches CI logs for the task's PR and injects a fix command.
+ * QuickCreateTaskResp is the response for POST /api/v1/tasks/quick. It
+ * reports what was inferred so the client can confirm it to the user.
  */
-export interface BotFixPRReq {
-  taskId: string;
+export interface QuickCreateTaskResp {
+  status: string;
+  id: string;
+  repo?: string; // Empty for a no-repo task.
+  baseBranch?: string;
+  harness: Harness;
+  model?: string; // Empty means the harness default.
 }
 /**
- * InputReq is the request body for POST /api/v1/tasks/{id}/input.
+ * ReviewSpec configures the agent-to-agent review loop.
  */
-export interface InputReq {
-  prompt: Prompt;
+export interface ReviewSpec {
+  harness: Harness; // Reviewer harness; may differ from the task's.
+  model?: string; // Reviewer model; empty means the harness default.
+  maxRounds?: number /* int */; // Review rounds before giving up; 0 means 3.
 }
 /**
- * RestartReq is the request body for POST /api/v1/tasks/{id}/restart.
+ * ReviewProgress reports the state of a task's review loop.
  */
-export interface RestartReq {
-  prompt: Prompt;
+export interface ReviewProgress {
+  round: number /* int */; // 1-based round of the current or last review.
+  maxRounds: number /* int */;
+  status: string; // "pending", "reviewing", "changes_requested", "approved", "exhausted", "failed", or "aborted"
+  reviewers?: string[]; // Reviewer task per round; their transcripts are kept.
+  feedback?: string; // Last feedback from the reviewer.
+  error?: string;
 }
 /**
- * CompactReq is the request body for POST /api/v1/tasks/{id}/compact.
+ * Pipeline is a reusable multi-step workflow, e.g. write tests, implement,
+ * lint, then write the changelog.
  */
-export interface CompactReq {
-  instructions?: string;
+export interface Pipeline {
+  name: string;
+  steps: PipelineStep[];
 }
 /**
- * DiffFileStat describes changes to a single file.
+ * PipelineStep is one task of a pipeline.
+ * Prompt is a Go text/template rendered with .Input (the task's initial
+ * prompt), .Previous (the previous step's result), .Verification (the
+ * previous step's verification output) and .Step (0-based index).
  */
-export interface DiffFileStat {
-  path: string;
-  added: number /* int */;
-  deleted: number /* int */;
-  binary?: boolean;
+export interface PipelineStep {
+  name?: string;
+  prompt: string;
+  /**
+   * Verify is an optional shell command run in the repo inside the
+   * container after the step's first turn; a non-zero exit fails the
+   * pipeline.
+   */
+  verify?: string;
 }
 /**
- * DiffStat summarises the changes in a branch relative to its base.
- */
-export type DiffStat = DiffFileStat[];
-/**
- * SafetyIssue describes a potential problem detected before pushing to origin.
+ * PipelineProgress reports how far a pipeline got.
  */
-export interface SafetyIssue {
-  file: string;
-  kind: string; // "large_binary" or "secret"
-  detail: string; // Human-readable description.
+export interface PipelineProgress {
+  name: string;
+  step: number /* int */; // 0-based index of the current or last step.
+  steps: number /* int */;
+  status: string; // "running", "done", "failed", or "aborted"
+  error?: string;
+  tasks?: string[]; // Task of each started step, in order.
 }
 /**
- * SyncTarget selects where to push changes.
- */
-export type SyncTarget = string;
-/**
- * Supported sync targets.
+ * StateTransition is one entry of a task's state history.
  */
-export const SyncTargetBranch: SyncTarget = "branch"; // Push to the task's own branch (default).
+export interface StateTransition {
+  from: string;
+  to: string;
+  at: string;
+}
 /**
- * Supported sync targets.
+ * TimelinePhase is a span of a task's timeline.
  */
-export const SyncTargetDefault: SyncTarget = "default"; // Squash-push to the repo's default branch.
+export interface TimelinePhase {
+  /**
+   * Name is queued, branching, container_start, agent_start, turn,
+   * rate_limited, awaiting_input, pull, push, teardown or verification.
+   */
+  name: string;
+  start: string;
+  end?: string; // Unset while in progress.
+  durationMs: number /* int64 */; // Up to now while in progress.
+  turn?: number /* int */;
+  /**
+   * FirstOutputMs is the time from the start of a turn to the agent's first
+   * output; unset when unknown.
+   */
+  firstOutputMs?: number /* int64 */;
+  error?: string; // Failed verification.
+}
 /**
- * SyncReq is the request body for POST /api/v1/tasks/{id}/sync.
+ * TaskTimeline is where the wall-clock time of a task went.
  */
-export interface SyncReq {
-  force?: boolean;
-  target?: SyncTarget;
+export interface TaskTimeline {
+  phases: TimelinePhase[];
+  /**
+   * TotalMs is the time from the first phase to the end of the last one,
+   * or now.
+   */
+  totalMs: number /* int64 */;
+  /**
+   * PhaseMs sums the durations per phase name. Verification runs overlap
+   * the other phases.
+   */
+  phaseMs: { [key: string]: number /* int64 */};
 }
 /**
- * SyncResp is the response for POST /api/v1/tasks/{id}/sync.
+ * ConsoleLine is a line of the raw console output of a task's container,
+ * outside of the agent's message stream.
  */
-export interface SyncResp {
-  status: string; // "synced", "blocked", or "empty"
-  branch?: string;
-  diffStat?: DiffStat;
-  safetyIssues?: SafetyIssue[];
-  prNumber?: number /* int */; // non-zero if a PR/MR was created
+export interface ConsoleLine {
+  /**
+   * Source is "container" for the stdout/stderr of the container, or
+   * "agent" for the stderr of the agent process.
+   */
+  source: string;
+  text: string;
+  at: string;
 }
 /**
- * ClaudeUsage holds local task cost and rate-limit quota data for Claude.
+ * EvalSuite is a corpus of benchmark cases run against harness/model
+ * combinations.
  */
-export interface ClaudeUsage {
-  fiveHour: ClaudeUsageWindow;
-  sevenDay: ClaudeUsageWindow;
-  extraUsage: ClaudeExtraUsage;
+export interface EvalSuite {
+  name: string;
+  cases: EvalCase[];
 }
 /**
- * ClaudeUsageWindow represents a single Claude usage window (5-hour or 7-day)
- * combining local task cost with OAuth rate-limit quota.
+ * EvalCase is one benchmark: a prompt run on a repo at a given ref, judged by
+ * a verification script.
  */
-export interface ClaudeUsageWindow {
+export interface EvalCase {
+  name: string;
+  repo: string; // Repo path relative to the root, as in RepoSpec.Name.
+  ref?: string; // Branch or commit the task starts from; empty means the default branch.
   /**
-   * Local task cost (always populated).
+   * Prompt is sent as the task's initial prompt.
    */
-  costUSD: number /* float64 */;
-  inputTokens: number /* int */;
-  outputTokens: number /* int */;
+  prompt: string;
   /**
-   * OAuth rate-limit quota; zero when OAuth unavailable.
+   * Verify is a shell command run in the repo inside the container after
+   * the turn; exit code 0 means the case passed.
    */
-  utilization: number /* float64 */;
-  resetsAt: string;
+  verify: string;
 }
 /**
- * ClaudeExtraUsage represents the Claude extra (pay-as-you-go) usage state.
+ * EvalTarget is a harness/model combination an eval suite runs against.
  */
-export interface ClaudeExtraUsage {
-  isEnabled: boolean;
-  monthlyLimit: number /* float64 */;
-  usedCredits: number /* float64 */;
-  utilization: number /* float64 */;
+export interface EvalTarget {
+  harness: Harness;
+  model?: string; // Empty means the harness default.
 }
 /**
- * CodexRateLimitWindow represents a single Codex rate-limit window snapshot.
+ * CreateEvalReq is the request body for POST /api/v1/evals.
+ */
+export interface CreateEvalReq {
+  suite: EvalSuite;
+  targets: EvalTarget[];
+  /**
+   * Parallel is the number of cases run concurrently; 0 means 1.
+   */
+  parallel?: number /* int */;
+}
+/**
+ * GetEvalReq is the request for GET /api/v1/evals/{id}.
+ */
+export interface GetEvalReq {
+}
+/**
+ * EvalReport is the outcome of an eval run, updated as cases complete.
+ */
+export interface EvalReport {
+  id: string;
+  suite: string;
+  status: string; // "running" or "done"
+  startedAt: string;
+  finishedAt?: string; // Unset while running.
+  results: EvalResult[]; // One per case and target, in suite order.
+  summary: EvalSummary[]; // One per target.
+}
+/**
+ * EvalResult is the outcome of one case on one target.
+ */
+export interface EvalResult {
+  case: string;
+  target: EvalTarget;
+  status: string; // "pending", "running", "passed", "failed", or "error"
+  taskId?: string;
+  costUSD: number /* float64 */;
+  duration: number /* float64 */; // Seconds, from task creation to verification.
+  output?: string; // Tail of the verification output.
+  error?: string;
+}
+/**
+ * EvalSummary aggregates the results of one target.
+ */
+export interface EvalSummary {
+  target: EvalTarget;
+  passed: number /* int */;
+  failed: number /* int */;
+  errored: number /* int */;
+  costUSD: number /* float64 */;
+  duration: number /* float64 */; // Seconds, summed over cases.
+}
+/**
+ * DependencyFailurePolicy selects what happens to a pending task when one of
+ * its prerequisites fails.
+ */
+export type DependencyFailurePolicy = string;
+/**
+ * Supported dependency failure policies.
+ */
+export const DependencyFailureCancel: DependencyFailurePolicy = "cancel"; // Fail the dependent task (default).
+/**
+ * Supported dependency failure policies.
+ */
+export const DependencyFailureHold: DependencyFailurePolicy = "hold"; // Keep the dependent pending until it is purged.
+/**
+ * TaskPriority orders the start of queued tasks.
+ */
+export type TaskPriority = string;
+/**
+ * Supported task priorities.
+ */
+export const PriorityLow: TaskPriority = "low";
+/**
+ * Supported task priorities.
+ */
+export const PriorityNormal: TaskPriority = "normal";
+/**
+ * Supported task priorities.
+ */
+export const PriorityHigh: TaskPriority = "high";
+/**
+ * NetworkMode controls the outgoing connections of a task's container. The
+ * agent's model API stays reachable in every mode.
+ */
+export type NetworkMode = string;
+/**
+ * Supported network modes.
+ */
+export const NetworkFull: NetworkMode = "full"; // No restriction (default).
+/**
+ * Supported network modes.
+ */
+export const NetworkNone: NetworkMode = "none"; // Only the agent's model API.
+/**
+ * Supported network modes.
+ */
+export const NetworkAllowlist: NetworkMode = "allowlist"; // The model API and networkAllow.
+/**
+ * IdleAction is what happens to a task left waiting for input longer than
+ * its idle policy allows.
+ */
+export type IdleAction = string;
+/**
+ * Supported idle actions.
+ */
+export const IdleNotify: IdleAction = "notify"; // Post a notice in the transcript.
+/**
+ * Supported idle actions.
+ */
+export const IdleFinish: IdleAction = "finish"; // Push the branch and stop the task, after a warning.
+/**
+ * IdlePolicy acts on tasks left waiting for input.
+ */
+export interface IdlePolicy {
+  hours: number /* int */; // Hours waiting before the action; 0 disables the policy.
+  action: IdleAction;
+}
+/**
+ * StallPolicy flags the running tasks whose agent sent no message for a
+ * while: a hung harness otherwise looks like one thinking for long.
+ */
+export interface StallPolicy {
+  minutes: number /* int */; // Minutes of silence before the task is flagged; 0 disables the policy.
+  interrupt?: boolean; // Also interrupt the turn of a stalled task.
+}
+/**
+ * Fallback is the harness and model used when those of a task fail to start,
+ * e.g. during a provider outage.
+ */
+export interface Fallback {
+  harness?: Harness; // Empty keeps the task's harness.
+  model?: string; // Empty uses the harness default.
+}
+/**
+ * Failover records that a task switched to the fallback harness and model.
+ */
+export interface Failover {
+  fromHarness: Harness;
+  fromModel?: string;
+  toHarness: Harness;
+  toModel?: string;
+  error: string; // Last start error of the replaced harness and model.
+  at: string;
+}
+/**
+ * PushPolicy controls when the branch of a task is pushed.
+ */
+export type PushPolicy = string;
+/**
+ * Supported push policies.
+ */
+export const PushAuto: PushPolicy = "auto"; // Push on request and when the idle policy finishes the task or after a CI fix (default).
+/**
+ * Supported push policies.
+ */
+export const PushAsk: PushPolicy = "ask"; // Push only on request, through sync or push.
+/**
+ * Supported push policies.
+ */
+export const PushNever: PushPolicy = "never"; // Never push; sync and push are rejected.
+/**
+ * ExecutionWindow is the time of day during which new tasks may start.
+ */
+export interface ExecutionWindow {
+  start: string; // "15:04" clock time.
+  end: string; // "15:04" clock time; before Start spans midnight.
+  timezone?: string; // IANA name; empty is the server's local time.
+}
+/**
+ * NotifyKind is the chat service of a notification channel.
+ */
+export type NotifyKind = string;
+/**
+ * Supported notification channel kinds.
+ */
+export const NotifySlack: NotifyKind = "slack"; // Slack incoming webhook.
+/**
+ * Supported notification channel kinds.
+ */
+export const NotifyDiscord: NotifyKind = "discord"; // Discord webhook.
+/**
+ * Supported notification channel kinds.
+ */
+export const NotifyMatrix: NotifyKind = "matrix"; // Matrix room.
+/**
+ * Supported notification channel kinds.
+ */
+export const NotifyTelegram: NotifyKind = "telegram"; // Telegram chat with a bot.
+/**
+ * NotifyChannel is a named chat channel. Secret is the webhook URL of Slack
+ * and Discord, the access token of Matrix and the bot token of Telegram. It
+ * is write-only: it is never returned, and an empty secret keeps the saved
+ * one of the channel with the same name and kind.
+ */
+export interface NotifyChannel {
+  name: string;
+  kind: NotifyKind;
+  url?: string; // Matrix homeserver URL.
+  secret?: string;
+  hasSecret?: boolean; // Response only.
+  target?: string; // Matrix room ID or Telegram chat ID.
+}
+/**
+ * ForkTaskReq is the request body for POST /api/v1/tasks/{id}/fork.
+ */
+export interface ForkTaskReq {
+  prompt: Prompt; // Initial prompt for the forked task.
+  harness?: Harness; // Override harness; empty means inherit from source.
+  model?: string; // Override model; empty means inherit from source.
+  extraRepos?: RepoSpec[]; // Additional repos to map into the fork.
+}
+/**
+ * PromoteTaskReq is the request body for POST /api/v1/tasks/{id}/promote.
+ */
+export interface PromoteTaskReq {
+  prompt?: Prompt; // Approved plan; empty means use the plan the task proposed.
+}
+/**
+ * ApprovePlanReq is the request body for POST /api/v1/tasks/{id}/plan.
+ */
+export interface ApprovePlanReq {
+  prompt?: Prompt; // Edited plan; empty means approve the plan as proposed.
+}
+/**
+ * BotFixCIReq is the request body for POST /api/v1/bot/fix-ci.
+ * The server fetches CI logs, builds a prompt, and creates a fix task.
+ */
+export interface BotFixCIReq {
+  repo: string;
+}
+/**
+ * BotFixPRReq is the request body for POST /api/v1/bot/fix-pr.
+ * The server fetches CI logs for the task's PR and injects a fix command.
+ */
+export interface BotFixPRReq {
+  taskId: string;
+}
+/**
+ * InputReq is the request body for POST /api/v1/tasks/{id}/input.
+ */
+export interface InputReq {
+  prompt: Prompt;
+}
+/**
+ * RestartReq is the request body for POST /api/v1/tasks/{id}/restart.
+ */
+export interface RestartReq {
+  prompt: Prompt;
+}
+/**
+ * RewindReq is the request body for POST /api/v1/tasks/{id}/rewind.
+ */
+export interface RewindReq {
+  /**
+   * Prompt replaces the last user input.
+   */
+  prompt: Prompt;
+}
+/**
+ * SetPriorityReq is the request body for POST /api/v1/tasks/{id}/priority.
+ */
+export interface SetPriorityReq {
+  priority: TaskPriority;
+}
+/**
+ * CompactReq is the request body for POST /api/v1/tasks/{id}/compact.
+ */
+export interface CompactReq {
+  instructions?: string;
+}
+/**
+ * DiffFileStat describes changes to a single file.
+ */
+export interface DiffFileStat {
+  path: string;
+  added: number /* int */;
+  deleted: number /* int */;
+  binary?: boolean;
+  status?: DiffFileStatus; // Empty when unknown.
+  oldPath?: string; // Source path of a rename.
+}
+/**
+ * DiffFileStatus is how a file changed.
+ */
+export type DiffFileStatus = string;
+/**
+ * File change statuses.
+ */
+export const FileAdded: DiffFileStatus = "added";
+/**
+ * File change statuses.
+ */
+export const FileModified: DiffFileStatus = "modified";
+/**
+ * File change statuses.
+ */
+export const FileDeleted: DiffFileStatus = "deleted";
+/**
+ * File change statuses.
+ */
+export const FileRenamed: DiffFileStatus = "renamed";
+/**
+ * DiffStat summarises the changes in a branch relative to its base.
+ */
+export type DiffStat = DiffFileStat[];
+/**
+ * DiffDir is a directory of a DiffStat laid out as a tree, with the counts of
+ * all the files below it rolled up. Directories with a single subdirectory
+ * and no files are folded into their child, e.g. "backend/internal".
+ */
+export interface DiffDir {
+  name: string; // Empty for the root.
+  path: string; // Empty for the root.
+  added: number /* int */;
+  deleted: number /* int */;
+  dirs?: DiffDir[]; // Sorted by name.
+  files?: DiffFileStat[]; // Sorted by path.
+}
+/**
+ * SafetyIssue describes a potential problem detected before pushing to origin.
+ */
+export interface SafetyIssue {
+  file: string;
+  kind: string; // "large_binary", "secret" or "pre_push_check"
+  detail: string; // Human-readable description.
+}
+/**
+ * SyncTarget selects where to push changes.
+ */
+export type SyncTarget = string;
+/**
+ * Supported sync targets.
+ */
+export const SyncTargetBranch: SyncTarget = "branch"; // Push to the task's own branch (default).
+/**
+ * Supported sync targets.
+ */
+export const SyncTargetDefault: SyncTarget = "default"; // Squash-push to the repo's default branch.
+/**
+ * SyncReq is the request body for POST /api/v1/tasks/{id}/sync.
+ */
+export interface SyncReq {
+  force?: boolean;
+  target?: SyncTarget;
+}
+/**
+ * PushReq is the request body for POST /api/v1/tasks/{id}/push.
+ */
+export interface PushReq {
+  force?: boolean; // Push despite safety issues.
+}
+/**
+ * SyncResp is the response for POST /api/v1/tasks/{id}/sync and
+ * POST /api/v1/tasks/{id}/push.
+ */
+export interface SyncResp {
+  status: string; // "synced", "blocked", "empty", or "failed" (multi-repo only)
+  branch?: string;
+  diffStat?: DiffStat;
+  safetyIssues?: SafetyIssue[];
+  prNumber?: number /* int */; // non-zero if a PR/MR was created
+  /**
+   * Repos holds one result per repo of a multi-repo task, primary first.
+   * The top-level fields describe the primary repo; Status is combined.
+   */
+  repos?: RepoSyncResult[];
+}
+/**
+ * RepoSyncResult is the sync outcome for a single repo of a multi-repo task.
+ */
+export interface RepoSyncResult {
+  name: string;
+  status: string; // "synced", "blocked", "empty", or "failed"
+  branch?: string;
+  diffStat?: DiffStat;
+  safetyIssues?: SafetyIssue[];
+  prNumber?: number /* int */;
+  error?: string;
+}
+/**
+ * ClaudeUsage holds local task cost and rate-limit quota data for Claude.
+ */
+export interface ClaudeUsage {
+  fiveHour: ClaudeUsageWindow;
+  sevenDay: ClaudeUsageWindow;
+  extraUsage: ClaudeExtraUsage;
+}
+/**
+ * ClaudeUsageWindow represents a single Claude usage window (5-hour or 7-day)
+ * combining local task cost with OAuth rate-limit quota.
+ */
+export interface ClaudeUsageWindow {
+  /**
+   * Local task cost (always populated).
+   */
+  costUSD: number /* float64 */;
+  inputTokens: number /* int */;
+  outputTokens: number /* int */;
+  /**
+   * OAuth rate-limit quota; zero when OAuth unavailable.
+   */
+  utilization: number /* float64 */;
+  resetsAt: string;
+}
+/**
+ * ClaudeExtraUsage represents the Claude extra (pay-as-you-go) usage state.
+ */
+export interface ClaudeExtraUsage {
+  isEnabled: boolean;
+  monthlyLimit: number /* float64 */;
+  usedCredits: number /* float64 */;
+  utilization: number /* float64 */;
+}
+/**
+ * CodexRateLimitWindow represents a single Codex rate-limit window snapshot.
  */
 export interface CodexRateLimitWindow {
   usedPercent: number /* int */;
   limitWindowSeconds: number /* int */;
   resetAfterSeconds: number /* int */;
-  resetAt: number /* int */; // unix timestamp
+  resetAt: string;
+}
+/**
+ * CodexCredits represents Codex credit/balance information.
+ */
+export interface CodexCredits {
+  hasCredits: boolean;
+  unlimited: boolean;
+  balance: string;
+}
+/**
+ * CodexUsage holds rate-limit quota data from the Codex usage API.
+ */
+export interface CodexUsage {
+  planType: string;
+  primary?: CodexRateLimitWindow;
+  secondary?: CodexRateLimitWindow;
+  credits: CodexCredits;
+}
+/**
+ * DiskUsage reports the disk space used by tasks and left on the filesystem
+ * holding the logs.
+ */
+export interface DiskUsage {
+  containerBytes: number /* int64 */; // Sum of the writable layers of task containers.
+  logBytes: number /* int64 */; // Sum of the task log files.
+  freeBytes: number /* int64 */;
+  totalBytes: number /* int64 */;
+  minFreeBytes?: number /* int64 */; // Quota; task creation is paused below it.
+  low?: boolean; // FreeBytes is below MinFreeBytes.
+}
+/**
+ * UsageResp is the response for GET /api/v1/usage.
+ */
+export interface UsageResp {
+  claude?: ClaudeUsage;
+  codex?: CodexUsage;
+  disk?: DiskUsage;
+  /**
+   * RateLimits is the rate limit headroom of the harnesses that reported
+   * one or have rate limited tasks.
+   */
+  rateLimits?: HarnessRateLimit[];
+}
+/**
+ * HarnessRateLimit is the provider rate limit headroom of a harness, as last
+ * reported by its agents, and the tasks backing off from it.
+ */
+export interface HarnessRateLimit {
+  harness: Harness;
+  status?: string; // "allowed", "allowed_warning", "rejected"; empty when never reported.
+  rateLimitType?: string; // "five_hour", "seven_day", etc.
+  utilization?: number /* float64 */; // 0.0–1.0.
+  resetsAt?: string;
+  reportedAt?: string;
+  limitedTasks: number /* int */; // Tasks in state "rate_limited".
+  resumesAt?: string; // Earliest automatic resume of those tasks.
+}
+/**
+ * VoiceTokenResp is the response for GET /api/v1/voice/token.
+ */
+export interface VoiceTokenResp {
+  token: string;
+  expiresAt: string;
+  ephemeral: boolean;
+}
+/**
+ * TaskExport is a row of GET /api/v1/tasks/export: the task metadata used
+ * for reporting.
+ */
+export interface TaskExport {
+  id: string;
+  alias?: string;
+  title: string;
+  repo?: string; // Primary repository.
+  branch?: string; // Task branch of the primary repository.
+  state: string;
+  harness: Harness;
+  model?: string;
+  owner?: string;
+  startedAt: string; // When the task was created.
+  stateUpdatedAt: string; // Last state change.
+  duration: number /* float64 */; // Seconds.
+  numTurns: number /* int */;
+  costUSD: number /* float64 */;
+  prURL?: string;
+  timezone: string; // IANA time zone of the timestamps, from ?tz= or the user's settings.
+  project?: string; // Project of the primary repository, from the user's settings.
+}
+/**
+ * TaskMessagesResp is the response for GET /api/v1/tasks/{id}/messages: a
+ * page of the transcript for lazy-loading older history.
+ */
+export interface TaskMessagesResp {
+  events: EventMessage[];
+  next: number /* int */; // Message index to pass as after for the following page.
+  total: number /* int */; // Number of messages in the task history.
+  /**
+   * Annotations are the annotations of the messages in the page.
+   */
+  annotations?: Annotation[];
 }
 /**
- * CodexCredits represents Codex credit/balance information.
+ * MessageContentResp is the response for
+ * GET /api/v1/tasks/{id}/messages/{index}/content: the full content of a
+ * message whose events were truncated.
  */
-export interface CodexCredits {
-  hasCredits: boolean;
-  unlimited: boolean;
-  balance: string;
+export interface MessageContentResp {
+  content: string;
+  encoding?: string; // "base64" when the content is not valid UTF-8.
 }
 /**
- * CodexUsage holds rate-limit quota data from the Codex usage API.
+ * Annotation is a note or bookmark a reviewer attached to a message of a task
+ * transcript, e.g. to mark where the agent went wrong.
  */
-export interface CodexUsage {
-  planType: string;
-  primary?: CodexRateLimitWindow;
-  secondary?: CodexRateLimitWindow;
-  credits: CodexCredits;
+export interface Annotation {
+  id: string;
+  index: number /* int */; // History index of the annotated message.
+  note?: string;
+  bookmark?: boolean;
+  author: string; // ID of the user who created it.
+  createdAt: string;
 }
 /**
- * UsageResp is the response for GET /api/v1/usage.
+ * AnnotateMessageReq is the request for POST
+ * /api/v1/tasks/{id}/messages/{index}/annotations.
  */
-export interface UsageResp {
-  claude?: ClaudeUsage;
-  codex?: CodexUsage;
+export interface AnnotateMessageReq {
+  note?: string;
+  bookmark?: boolean;
 }
 /**
- * VoiceTokenResp is the response for GET /api/v1/voice/token.
+ * DeleteAnnotationReq is the request for POST
+ * /api/v1/tasks/{id}/annotations/{annotationID}/delete.
  */
-export interface VoiceTokenResp {
-  token: string;
-  expiresAt: string;
-  ephemeral: boolean;
+export interface DeleteAnnotationReq {
 }
 /**
  * DiffResp is the response for GET /api/v1/tasks/{id}/diff.
  */
 export interface DiffResp {
   diff: string;
+  /**
+   * DiffStat is the stat computed along the diff; only set with ?live=true.
+   */
+  diffStat?: DiffStat;
+  /**
+   * Tree is the task's diff stat as a file tree; not set when the diff is
+   * limited to a path.
+   */
+  tree?: DiffDir;
+}
+/**
+ * TaskCommit is a commit the agent made on the task branch.
+ */
+export interface TaskCommit {
+  sha: string;
+  author: string;
+  message: string; // Full message, subject first.
+  authoredAt: string;
+  committedAt: string;
+  files?: DiffStat;
+}
+/**
+ * RevertCommitReq is the request for POST
+ * /api/v1/tasks/{id}/commits/{sha}/revert.
+ */
+export interface RevertCommitReq {
+}
+/**
+ * RevertCommitResp is the response for POST
+ * /api/v1/tasks/{id}/commits/{sha}/revert.
+ */
+export interface RevertCommitResp {
+  revert: string; // Hash of the new revert commit.
+  /**
+   * Notified is true when the agent session was told about the revert.
+   */
+  notified: boolean;
+}
+/**
+ * TaskSnapshot is a named snapshot of a task's workspace, including the
+ * uncommitted files.
+ */
+export interface TaskSnapshot {
+  name: string;
+  commit: string; // Snapshot commit, whose parent is the branch head at the time.
+  created: string;
+}
+/**
+ * CreateSnapshotReq is the request for POST /api/v1/tasks/{id}/snapshots.
+ */
+export interface CreateSnapshotReq {
+  name: string;
+}
+/**
+ * RestoreSnapshotReq is the request for POST
+ * /api/v1/tasks/{id}/snapshots/{name}/restore.
+ */
+export interface RestoreSnapshotReq {
+}
+/**
+ * DiffHunksResp is the response for GET /api/v1/tasks/{id}/diff/hunks.
+ */
+export interface DiffHunksResp {
+  files: DiffFile[];
+}
+/**
+ * DiffFile is the parsed diff of one file.
+ */
+export interface DiffFile {
+  path: string;
+  oldPath?: string; // Source path of a rename.
+  status: DiffFileStatus;
+  language?: string; // e.g. "go" or "typescript"; empty when unknown.
+  binary?: boolean;
+  hunks?: DiffHunk[];
+}
+/**
+ * DiffHunk is one "@@" section of a file diff.
+ */
+export interface DiffHunk {
+  oldStart: number /* int */;
+  oldLines: number /* int */;
+  newStart: number /* int */;
+  newLines: number /* int */;
+  section?: string; // Enclosing function or section git shows after the "@@".
+  lines: DiffLine[];
+}
+/**
+ * DiffLineKind is the kind of a diff line.
+ */
+export type DiffLineKind = string;
+/**
+ * Diff line kinds.
+ */
+export const DiffLineContext: DiffLineKind = "context";
+/**
+ * Diff line kinds.
+ */
+export const DiffLineAdded: DiffLineKind = "added";
+/**
+ * Diff line kinds.
+ */
+export const DiffLineDeleted: DiffLineKind = "deleted";
+/**
+ * DiffLine is a line of a hunk, without its +/-/space prefix.
+ */
+export interface DiffLine {
+  kind: DiffLineKind;
+  text: string;
+  oldLine?: number /* int */; // 1-based; 0 for added lines.
+  newLine?: number /* int */; // 1-based; 0 for deleted lines.
+  noEOL?: boolean; // The file does not end with a newline after this line.
+  /**
+   * Segments splits Text into changed and unchanged words when this line
+   * replaces, or is replaced by, a similar line. Only set with
+   * ?intraline=true.
+   */
+  segments?: DiffSegment[];
+}
+/**
+ * DiffSegment is a run of a DiffLine's text.
+ */
+export interface DiffSegment {
+  text: string;
+  changed?: boolean;
 }
 /**
  * RepoPrefsResp holds per-repository preferences.
@@ -842,6 +1936,74 @@ export interface RepoPrefsResp {
   harness?: string;
   model?: string;
 }
+/**
+ * LintPromptReq is the request body for POST /api/v1/prompts/lint.
+ */
+export interface LintPromptReq {
+  text: string;
+  repo?: string; // Primary repo; enables file reference checks.
+  useLLM?: boolean; // Also ask the configured LLM for suggestions.
+}
+/**
+ * LintPromptResp is the response for POST /api/v1/prompts/lint.
+ */
+export interface LintPromptResp {
+  suggestions: PromptSuggestion[];
+  llm?: boolean; // The LLM was consulted.
+}
+/**
+ * PromptSuggestionKind identifies the issue found in a draft prompt.
+ */
+export type PromptSuggestionKind = string;
+/**
+ * Prompt suggestion kinds.
+ */
+export const PromptVague: PromptSuggestionKind = "vague";
+/**
+ * Prompt suggestion kinds.
+ */
+export const PromptLargePaste: PromptSuggestionKind = "large_paste";
+/**
+ * Prompt suggestion kinds.
+ */
+export const PromptNoFileReference: PromptSuggestionKind = "no_file_reference";
+/**
+ * Prompt suggestion kinds.
+ */
+export const PromptUnknownFile: PromptSuggestionKind = "unknown_file";
+/**
+ * Prompt suggestion kinds.
+ */
+export const PromptLLM: PromptSuggestionKind = "llm";
+/**
+ * SuggestionSeverity ranks a prompt suggestion.
+ */
+export type SuggestionSeverity = string;
+/**
+ * Suggestion severities.
+ */
+export const SeverityInfo: SuggestionSeverity = "info";
+/**
+ * Suggestion severities.
+ */
+export const SeverityWarning: SuggestionSeverity = "warning";
+/**
+ * PromptSuggestion is one improvement proposed for a draft prompt.
+ */
+export interface PromptSuggestion {
+  kind: PromptSuggestionKind;
+  severity: SuggestionSeverity;
+  message: string;
+}
+/**
+ * RecentPrompt is a previously used task prompt, returned by GET
+ * /api/v1/prompts/recent.
+ */
+export interface RecentPrompt {
+  repo?: string; // Empty for no-repo tasks.
+  text: string;
+  lastUsed: string;
+}
 /**
  * CacheMappingResp represents a directory mapping for cache/state sharing.
  */
@@ -864,11 +2026,36 @@ export interface UserSettings {
    * request when it is opened or reopened via a forge webhook.
    */
   autoFixOnPROpen: boolean;
+  /**
+   * AutoRespondToReviews hands review feedback left on the PR of a task
+   * back to its agent, or to a follow-up task on the PR branch when the
+   * agent can no longer receive input. Only effective with the GitHub App
+   * webhook.
+   */
+  autoRespondToReviews: boolean;
   /**
    * BaseImage overrides the default container base image. Empty means use
    * the default.
    */
   baseImage?: string;
+  /**
+   * ValidateBaseImage smoke tests a changed BaseImage in a throwaway
+   * container before new tasks use it.
+   */
+  validateBaseImage?: boolean;
+  /**
+   * PendingBaseImage is the changed BaseImage while it is validated or
+   * after it failed; BaseImage keeps the previous image meanwhile.
+   * Response only.
+   */
+  pendingBaseImage?: PendingImage;
+  /**
+   * PersistWorkspace saves the workspace of a task, uncommitted files
+   * included, before its container is stopped or purged, so that the task
+   * can be rehydrated into a fresh container with
+   * POST /api/v1/tasks/{id}/rehydrate.
+   */
+  persistWorkspace?: boolean;
   /**
    * GitHubTokenAccess controls the GitHub token injected into containers.
    * "none" (default): no token. "read-write": passes the parent token.
@@ -888,6 +2075,157 @@ export interface UserSettings {
    * CacheMappings are custom host-to-container directory mappings.
    */
   cacheMappings?: CacheMappingResp[];
+  /**
+   * ModelPrices overrides the built-in price table, keyed by model name
+   * prefix, e.g. for enterprise pricing.
+   */
+  modelPrices?: { [key: string]: ModelPrice};
+  /**
+   * Network is the default network mode of new tasks; empty means full.
+   */
+  network?: NetworkMode;
+  /**
+   * NetworkAllow is the default host allowlist of new tasks.
+   */
+  networkAllow?: string[];
+  /**
+   * ToolPolicy are the rules applied to the tool calls of every task.
+   */
+  toolPolicy?: PolicyRule[];
+  /**
+   * RepoToolPolicies are additional rules keyed by repository path.
+   */
+  repoToolPolicies?: { [key: string]: PolicyRule[]};
+  /**
+   * IdlePolicy acts on tasks left waiting for input. Nil disables it.
+   */
+  idlePolicy?: IdlePolicy;
+  /**
+   * RepoIdlePolicies override IdlePolicy, keyed by repository path.
+   */
+  repoIdlePolicies?: { [key: string]: IdlePolicy};
+  /**
+   * StallPolicy flags running tasks whose agent stays silent. Nil disables
+   * it.
+   */
+  stallPolicy?: StallPolicy;
+  /**
+   * Fallback is the harness and model a task switches to when its own fail
+   * to start. Nil disables the failover.
+   */
+  fallback?: Fallback;
+  /**
+   * RepoPushPolicies are the push policies keyed by repository path; the
+   * other repos use auto.
+   */
+  repoPushPolicies?: { [key: string]: PushPolicy};
+  /**
+   * RepoPrePushChecks are local CI scripts keyed by repository path, e.g.
+   * "make test" or "act push". Each runs in the task's container before its
+   * branch is pushed; a failure blocks the push unless forced. The output is
+   * posted in the task's transcript.
+   */
+  repoPrePushChecks?: { [key: string]: string};
+  /**
+   * RepoInstructions are custom instructions appended to the system prompt
+   * of the agents of a repo's tasks, keyed by repository path, after the
+   * repo's own .caic/instructions.md.
+   */
+  repoInstructions?: { [key: string]: string};
+  /**
+   * RepoProjects maps repository paths to the name of their project. The
+   * task list and the export filter by project with ?project=.
+   */
+  repoProjects?: { [key: string]: string};
+  /**
+   * ExecutionWindow holds new tasks in "pending" until it is open. Nil runs
+   * them immediately.
+   */
+  executionWindow?: ExecutionWindow;
+  /**
+   * NotifyChannels are the chat channels task notifications can be sent to.
+   */
+  notifyChannels?: NotifyChannel[];
+  /**
+   * NotifyRoutes maps a task state to the names of the channels notified
+   * when a task enters it, e.g. "waiting" to a direct message channel and
+   * "failed" to the team's channel. "stalled" routes the tasks flagged by
+   * the stall policy.
+   */
+  notifyRoutes?: { [key: string]: string[]};
+  /**
+   * EnvAllowlist are the names of the environment variables tasks may set
+   * through CreateTaskReq.Env, or glob patterns like "FEATURE_*".
+   */
+  envAllowlist?: string[];
+  /**
+   * Timezone is the IANA time zone, e.g. "Europe/Paris", the notifications
+   * and the exports render times in. Empty means UTC.
+   */
+  timezone?: string;
+  /**
+   * GenericHarness configures the "generic" harness. Nil disables it.
+   */
+  genericHarness?: GenericHarness;
+}
+/**
+ * GenericHarness configures the "generic" harness, an agent loop against an
+ * OpenAI-compatible chat completions API. The API key is write-only: it is
+ * never returned, and an empty key keeps the saved one for the same URL.
+ */
+export interface GenericHarness {
+  baseURL: string;
+  apiKey?: string;
+  hasAPIKey?: boolean; // Response only.
+  model: string;
+}
+/**
+ * PolicyRuleKind is the kind of a tool policy rule.
+ */
+export type PolicyRuleKind = string;
+/**
+ * Tool policy rule kinds.
+ */
+export const PolicyRuleNetwork: PolicyRuleKind = "network"; // Denies network access to hosts not in allow.
+/**
+ * Tool policy rule kinds.
+ */
+export const PolicyRuleCommand: PolicyRuleKind = "command"; // Denies shell commands matching pattern.
+/**
+ * Tool policy rule kinds.
+ */
+export const PolicyRulePath: PolicyRuleKind = "path"; // Denies writes outside the workspace and allow.
+/**
+ * PolicyRule is a tool policy rule. A tool call violating it pauses the task
+ * until the user approves or denies it.
+ */
+export interface PolicyRule {
+  name: string;
+  kind: PolicyRuleKind;
+  pattern?: string; // Regular expression for "command" rules.
+  allow?: string[]; // Host globs for "network" rules, absolute directories for "path" rules.
+}
+/**
+ * ModelPrice is the price of a model in USD per million tokens.
+ */
+export interface ModelPrice {
+  input: number /* float64 */;
+  output: number /* float64 */;
+  cacheWrite?: number /* float64 */;
+  cacheRead?: number /* float64 */;
+  /**
+   * CacheReadIncluded is set when the harness counts cache reads as part of
+   * the input tokens (OpenAI convention).
+   */
+  cacheReadIncluded?: boolean;
+}
+/**
+ * PriceEntry is one row of the effective price table.
+ */
+export interface PriceEntry {
+  model: string; // Model name prefix.
+  price: ModelPrice;
+  override?: boolean; // Set from the user's preferences.
 }
 /**
  * PreferencesResp is the response for GET /api/v1/server/preferences.
@@ -904,13 +2242,92 @@ export interface PreferencesResp {
 export interface UpdatePreferencesReq {
   settings: UserSettings;
 }
+/**
+ * UpdateTaskReq is the request body for PATCH /api/v1/tasks/{id}. Omitted
+ * fields are left unchanged.
+ */
+export interface UpdateTaskReq {
+  archived?: boolean;
+}
 /**
  * CloneRepoReq is the request body for POST /api/v1/server/repos.
  */
 export interface CloneRepoReq {
   url: string; // Git clone URL (HTTPS or SSH).
-  path?: string; // Target subdirectory under rootDir; defaults to repo basename.
+  path?: string; // Target subdirectory under rootDir, or "<root>:<subdirectory>" for another source root; defaults to repo basename.
   depth?: number /* int */;
+  /**
+   * Mirror creates a managed mirror instead: a full bare clone that caic
+   * fetches periodically. Path then defaults to the host and path of the URL,
+   * e.g. "github.com/org/repo".
+   */
+  mirror?: boolean;
+}
+/**
+ * RepoRemote is a git remote of a repository.
+ */
+export interface RepoRemote {
+  name: string;
+  url: string;
+}
+/**
+ * RepoRemotesResp is the response for the /api/v1/server/repos/remotes
+ * endpoints.
+ */
+export interface RepoRemotesResp {
+  remotes: RepoRemote[];
+  pushRemote: string; // Remote task branches are pushed to by default.
+}
+/**
+ * SetRepoRemoteReq is the request body for POST /api/v1/server/repos/remotes.
+ */
+export interface SetRepoRemoteReq {
+  repo: string;
+  name: string;
+  /**
+   * URL adds the remote or changes its URL; empty keeps an existing
+   * remote's.
+   */
+  url?: string;
+  /**
+   * Push makes the remote the default push target of the task branches.
+   */
+  push?: boolean;
+}
+/**
+ * DeleteRepoRemoteReq is the request body for POST
+ * /api/v1/server/repos/remotes/delete.
+ */
+export interface DeleteRepoRemoteReq {
+  repo: string;
+  name: string;
+}
+/**
+ * DeployKeyReq is the request body for POST /api/v1/server/repos/deploykey.
+ */
+export interface DeployKeyReq {
+  repo: string;
+  /**
+   * Rotate replaces the existing key; the new one must be added to the
+   * forge again.
+   */
+  rotate?: boolean;
+}
+/**
+ * DeployKeyResp is the response for POST /api/v1/server/repos/deploykey.
+ */
+export interface DeployKeyResp {
+  repo: string;
+  publicKey: string; // OpenSSH authorized_keys line to add as a deploy key.
+  fingerprint: string; // SHA256 fingerprint, as shown by the forge.
+  settingsURL?: string; // Forge page to add the key on; empty if the forge is unknown.
+}
+/**
+ * DeleteDeployKeyReq is the request body for POST
+ * /api/v1/server/repos/deploykey/delete.
+ */
+export interface DeleteDeployKeyReq {
+  repo: string;
 }
 /**
  * WebFetchReq is the request body for POST /api/v1/web/fetch.
@@ -938,6 +2355,40 @@ export interface BranchInfo {
 export interface RepoBranchesResp {
   branches: BranchInfo[];
 }
+/**
+ * RepoSearchResp is the response for GET /api/v1/server/repos/search.
+ */
+export interface RepoSearchResp {
+  commit: string; // Indexed commit of the base branch.
+  paths: string[]; // File paths containing the query.
+  matches: RepoSearchMatch[]; // Lines containing the query.
+  truncated?: boolean; // The limit was reached.
+}
+/**
+ * RepoSemanticSearchResp is the response for GET
+ * /api/v1/server/repos/semantic-search.
+ */
+export interface RepoSemanticSearchResp {
+  commit: string; // Indexed commit of the base branch.
+  results: SemanticSearchResult[];
+}
+/**
+ * SemanticSearchResult is a range of lines similar in meaning to the query.
+ */
+export interface SemanticSearchResult {
+  path: string;
+  startLine: number /* int */; // 1-based.
+  endLine: number /* int */; // Inclusive.
+  score: number /* float64 */; // Cosine similarity; higher is closer.
+}
+/**
+ * RepoSearchMatch is a line of a file containing the search query.
+ */
+export interface RepoSearchMatch {
+  path: string;
+  line: number /* int */; // 1-based.
+  text: string;
+}
 /**
  * WellKnownCache describes a single well-known cache.
  */
@@ -953,6 +2404,38 @@ export interface WellKnownCachesResp {
   harnessMounts: string[]; // e.g. "~/.claude", "~/.codex"
   wellKnown: WellKnownCache[];
 }
+/**
+ * LogLevelReq is the request body for POST /api/v1/server/log-level.
+ */
+export interface LogLevelReq {
+  level: string; // "debug", "info", "warn" or "error".
+}
+/**
+ * LogLevelResp is the response for GET and POST /api/v1/server/log-level.
+ */
+export interface LogLevelResp {
+  level: string;
+}
+/**
+ * TaskView is a saved combination of task list filters.
+ */
+export interface TaskView {
+  name: string;
+  query?: string; // Query parameters of GET /api/v1/tasks, e.g. "state=failed&createdWithin=168h".
+  default?: boolean; // The task list opens with this view; at most one view is the default.
+}
+/**
+ * TaskViewsResp is the response of the /api/v1/views endpoints.
+ */
+export interface TaskViewsResp {
+  views: TaskView[];
+}
+/**
+ * DeleteTaskViewReq is the request body for POST /api/v1/views/delete.
+ */
+export interface DeleteTaskViewReq {
+  name: string;
+}
 /**
  * VoiceRTCOfferReq is the request body for POST /api/v1/voice/rtc/offer.
  */
@@ -15,6 +15,8 @@ export default defineConfig({
   build: {
     outDir: "../backend/frontend/dist",
     emptyOutDir: true,
+    // Read by the server to send early hints for the entry bundles.
+    manifest: "asset-manifest.json",
     reportCompressedSize: false,
   },
   server: {